@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"smsleopard/internal/config"
+	"smsleopard/internal/graph"
 	"smsleopard/internal/handler"
-	"smsleopard/internal/middleware"
+	"smsleopard/internal/maintenance"
+	"smsleopard/internal/metrics"
+	"smsleopard/internal/migrate"
+	"smsleopard/internal/notify"
 	"smsleopard/internal/queue"
 	"smsleopard/internal/repository"
 	"smsleopard/internal/service"
@@ -40,6 +46,35 @@ func main() {
 	}
 	log.Println("✅ Connected to database")
 
+	// Apply pending migrations (optional); replicas wait on the advisory lock for the first
+	if cfg.Database.AutoMigrate {
+		applied, err := migrate.Up(db, cfg.Database.MigrationsDir)
+		if err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
+		log.Printf("✅ Migrations up to date (%d applied)", len(applied))
+	}
+
+	// Scheduling decisions use database time; warn when this server's clock has drifted from it
+	if _, err := service.CheckClockSkew(context.Background(), repository.NewClockRepository(db), cfg.Database.ClockSkewWarn); err != nil {
+		log.Printf("Warning: Failed to check clock skew: %v", err)
+	}
+
+	// Connect to read replica (optional)
+	var replicaDB *sql.DB
+	if cfg.Database.ReplicaDSN != "" {
+		replicaDB, err = sql.Open("postgres", cfg.Database.ReplicaDSN)
+		if err != nil {
+			log.Fatalf("Failed to connect to read replica: %v", err)
+		}
+		defer replicaDB.Close()
+
+		if err := replicaDB.Ping(); err != nil {
+			log.Fatalf("Failed to ping read replica: %v", err)
+		}
+		log.Println("✅ Connected to read replica")
+	}
+
 	// Connect to RabbitMQ
 	rabbitmqURL := cfg.GetRabbitMQURL()
 
@@ -58,14 +93,40 @@ func main() {
 
 	log.Println("✅ Connected to RabbitMQ")
 
-	// Initialize repositories
-	customerRepo := repository.NewCustomerRepository(db)
-	campaignRepo := repository.NewCampaignRepository(db)
-	messageRepo := repository.NewMessageRepository(db)
+	// Wrap connections with query timing (optional)
+	var primary repository.Database = db
+	var reader repository.Database
+	if replicaDB != nil {
+		reader = replicaDB
+	}
+	if cfg.Database.QueryLogging {
+		slowThreshold := time.Duration(cfg.Database.SlowQueryMs) * time.Millisecond
+		primary = repository.NewInstrumentedDB(db, slowThreshold)
+		if replicaDB != nil {
+			reader = repository.NewInstrumentedDB(replicaDB, slowThreshold)
+		}
+		log.Printf("✅ Query logging enabled (slow threshold %s)", slowThreshold)
+	}
+
+	// Sample connection pool usage, so pool exhaustion can be told apart from slow queries
+	go metrics.NewDBStatsSampler("primary", db).Run(context.Background(), metrics.DBStatsInterval)
+	if replicaDB != nil {
+		go metrics.NewDBStatsSampler("replica", replicaDB).Run(context.Background(), metrics.DBStatsInterval)
+	}
+
+	// Initialize repositories, timing every call of the three busiest
+	customerRepo := repository.NewTimedCustomerRepository(repository.NewCustomerRepositoryWithChunkSize(primary, reader, cfg.Limits.LookupChunkSize))
+	campaignRepo := repository.NewTimedCampaignRepository(repository.NewCampaignRepositoryWithReader(primary, reader))
+	messageRepo := repository.NewTimedMessageRepository(repository.NewEncryptedMessageRepository(primary, reader, cfg.Encryption.Keyring))
+	processingErrorRepo := repository.NewProcessingErrorRepository(primary)
+	if cfg.Encryption.Keyring != nil {
+		log.Printf("✅ Message content encryption enabled (key %s)", cfg.Encryption.Keyring.CurrentKeyID())
+	}
 
 	// Initialize services
-	templateService := service.NewTemplateService()
-	healthService := service.NewHealthService(db, rabbitmqURL, "1.0.0")
+	templateService := service.NewTemplateServiceWithLimits(cfg.Limits)
+	templateService.SetSyntax(cfg.Template.Syntax)
+	healthService := service.NewHealthService(db, replicaDB, rabbitmqURL, "1.0.0")
 	campaignService := service.NewCampaignService(
 		campaignRepo,
 		customerRepo,
@@ -73,31 +134,163 @@ func main() {
 		templateService,
 		publisher,
 		db,
+		cfg.Approval,
+	)
+	campaignService.SetClock(repository.NewClockRepository(primary))
+	campaignService.SetDuplicateContent(cfg.Duplicate)
+	campaignService.SetFrequencyCap(cfg.FrequencyCap)
+	campaignService.SetSuppressions(repository.NewSuppressionRepository(primary))
+	// Refuse new sends while the send queue or never-published backlog is saturated
+	admission := service.NewSendAdmission(publisher.Depth, messageRepo, cfg.Backpressure)
+	admission.SetDispatchOrder(cfg.Dispatch)
+	campaignService.SetAdmission(admission)
+	// Refuse every send while outbound sending is switched off; workers read the same setting
+	sendingSwitch := service.NewSendingSwitch(repository.NewSettingsRepository(primary))
+	campaignService.SetSendingSwitch(sendingSwitch)
+	healthService.SetSendingSwitch(sendingSwitch)
+	// Refuse campaigns on channels the workers' sender provider cannot deliver on
+	channelSupport := service.NewChannelSupport(cfg.Worker.SenderProvider, service.NewSender(cfg.Worker, service.NewSenderService(0.95)))
+	campaignService.SetChannelSupport(channelSupport)
+	healthService.SetChannelSupport(channelSupport)
+	// Lifecycle events for the data warehouse and/or webhook (optional)
+	events, err := notify.NewEventsFromConfig(cfg.Events, cfg.Notify)
+	if err != nil {
+		log.Fatalf("Failed to create event sinks: %v", err)
+	}
+	defer events.Close()
+	if events != nil {
+		campaignService.SetEvents(events)
+		log.Printf("✅ Lifecycle events enabled (%s)", strings.Join(cfg.Events.Sinks, ", "))
+	}
+	customerService := service.NewCustomerService(customerRepo, cfg.Limits)
+	if cfg.CustomerRules != nil {
+		campaignService.SetCustomerRules(cfg.CustomerRules)
+		customerService.SetCustomerRules(cfg.CustomerRules)
+		log.Printf("✅ Customer validation rules enabled (%d fields)", len(cfg.CustomerRules.Fields))
+	}
+	readinessService := service.NewReadinessService(
+		campaignRepo,
+		customerRepo,
+		messageRepo,
+		templateService,
+		healthService,
+		cfg.Quiet,
 	)
+	readinessService.SetChannelSupport(channelSupport)
+	simulationService := service.NewSimulationService(
+		campaignRepo,
+		customerRepo,
+		messageRepo,
+		cfg.Sending,
+		cfg.Quiet,
+	)
+	// Preview, dry runs, simulation and readiness share one delivery and cost estimator
+	deliveryEstimator := service.NewDeliveryEstimator(templateService, cfg.Sending)
+	campaignService.SetDeliveryEstimator(deliveryEstimator)
+	readinessService.SetDeliveryEstimator(deliveryEstimator)
+	simulationService.SetDeliveryEstimator(deliveryEstimator)
+	exportService := service.NewExportService(campaignRepo, cfg.Sending)
 
-	// Initialize handlers
-	healthHandler := handler.NewHealthHandler(healthService)
-	campaignHandler := handler.NewCampaignHandler(campaignService)
-	previewHandler := handler.NewPreviewHandler(campaignService)
+	// Campaign message exports, generated in the background to EXPORT_DIR
+	exportStore, err := service.NewDirExportStore(cfg.Export.Dir)
+	if err != nil {
+		log.Fatalf("Failed to create export store: %v", err)
+	}
+	exportJobService := service.NewExportJobService(
+		repository.NewExportJobRepository(primary),
+		campaignRepo,
+		messageRepo,
+		exportStore,
+		cfg.Export,
+	)
+	go exportJobService.Run(context.Background(), service.ExportJobPollInterval)
 
-	// Create router
-	router := mux.NewRouter()
+	// Recurring campaigns: each due occurrence is cloned and sent like any campaign
+	recurrenceService := service.NewRecurrenceService(repository.NewRecurrenceRepository(primary), campaignRepo, campaignService)
+	go recurrenceService.Run(context.Background(), service.RecurrenceInterval)
 
-	// Apply middleware
-	router.Use(middleware.Recovery)
-	router.Use(middleware.Logger)
+	// Daily ops digest of campaigns needing attention (optional)
+	var notifier notify.Notifier
+	if cfg.Notify.WebhookURL != "" {
+		webhookNotifier, err := notify.NewWebhookNotifier(cfg.Notify.WebhookURL)
+		if err != nil {
+			log.Fatalf("Failed to create notifier: %v", err)
+		}
+		notifier = webhookNotifier
+	}
+	attentionService := service.NewAttentionService(campaignRepo, notifier)
+	if notifier != nil {
+		go attentionService.RunDigest(context.Background(), service.AttentionDigestInterval)
+		log.Println("✅ Daily attention digest enabled")
+	}
+
+	// Campaign send events, long-polled by external consumers; waiting requests are woken
+	// by the campaign_events trigger over a dedicated LISTEN connection
+	eventRepo := repository.NewCampaignEventRepository(primary)
+	campaignService.SetCampaignEvents(eventRepo)
+	eventHub := service.NewCampaignEventHub()
+	eventListener := pq.NewListener(cfg.GetDatabaseDSN(), 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Warning: Campaign events listener: %v", err)
+		}
+	})
+	defer eventListener.Close()
+	if err := eventListener.Listen(repository.CampaignEventsChannel); err != nil {
+		log.Fatalf("Failed to listen for campaign events: %v", err)
+	}
+	go eventHub.Run(context.Background(), eventListener.Notify)
+	campaignEventService := service.NewCampaignEventService(eventRepo, campaignRepo, eventHub)
 
-	// Health endpoint (public, no authentication)
-	router.HandleFunc("/health", healthHandler.HandleHealth).Methods("GET")
+	// Issued API keys, accepted alongside the API_KEYS bootstrap keys from an in-memory copy
+	// reloaded every 30 seconds
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(primary))
+	if err := apiKeyService.Refresh(context.Background()); err != nil {
+		log.Fatalf("Failed to load API keys: %v", err)
+	}
+	go apiKeyService.Run(context.Background(), service.APIKeyRefreshInterval)
 
-	// Campaign routes
-	router.HandleFunc("/campaigns", campaignHandler.Create).Methods("POST")
-	router.HandleFunc("/campaigns", campaignHandler.List).Methods("GET")
-	router.HandleFunc("/campaigns/{id:[0-9]+}", campaignHandler.GetByID).Methods("GET")
-	router.HandleFunc("/campaigns/{id:[0-9]+}/send", campaignHandler.Send).Methods("POST")
+	// Maintenance switch shared by the write guard and its admin toggle
+	readOnly := maintenance.NewReadOnly(cfg.Server.ReadOnly)
 
-	// Preview route
-	router.HandleFunc("/campaigns/{id:[0-9]+}/personalized-preview", previewHandler.Preview).Methods("POST")
+	// Initialize handlers
+	processingErrorService := service.NewProcessingErrorService(processingErrorRepo, "")
+	deps := &handler.RouterDeps{
+		Health:          handler.NewHealthHandler(healthService),
+		Campaign:        handler.NewCampaignHandler(campaignService),
+		Preview:         handler.NewPreviewHandler(campaignService),
+		Template:        handler.NewTemplateHandler(templateService),
+		Webhook:         handler.NewWebhookHandler(),
+		Simulation:      handler.NewSimulationHandler(simulationService),
+		Readiness:       handler.NewReadinessHandler(readinessService),
+		Customer:        handler.NewCustomerHandler(customerService),
+		Admin:           handler.NewAdminHandler(attentionService, processingErrorService),
+		Export:          handler.NewExportHandler(exportService),
+		ExportJob:       handler.NewExportJobHandler(exportJobService),
+		CampaignEvent:   handler.NewCampaignEventHandler(campaignEventService),
+		Stats:           handler.NewStatsHandler(service.NewStatsService(messageRepo)),
+		ReadOnly:        handler.NewReadOnlyHandler(readOnly),
+		QueueStatus:     handler.NewQueueStatusHandler(admission),
+		Link:            handler.NewLinkHandler(service.NewLinkService(repository.NewLinkRepository(primary))),
+		MessageReassign: handler.NewMessageReassignHandler(service.NewMessageReassigner(campaignRepo, repository.NewMessageReassignmentRepository(primary), service.DefaultReassignBatchSize)),
+		GraphQL:         handler.NewGraphQLHandler(graph.NewExecutor(campaignRepo, customerRepo, messageRepo)),
+		Worker:          handler.NewWorkerHandler(service.NewWorkerActivityService(repository.NewWorkerRepository(primary))),
+		APIKey:          handler.NewAPIKeyHandler(apiKeyService),
+		Sending:         handler.NewSendingHandler(sendingSwitch),
+		Recurrence:      handler.NewRecurrenceHandler(recurrenceService, campaignService),
+		ReadOnlyMode:    readOnly,
+		IssuedKeys:      apiKeyService,
+	}
+
+	// Create router
+	router := handler.BuildRouter(deps, cfg)
+	if readOnly.Enabled() {
+		log.Printf("🔒 Read-only mode: writes are refused")
+	}
+	if len(cfg.Auth.APIKeys) > 0 {
+		log.Printf("✅ API key authentication enabled (%d bootstrap keys)", len(cfg.Auth.APIKeys))
+	} else if apiKeyService.HasKeys() {
+		log.Printf("⚠️  Issued API keys are ignored: authentication is disabled until API_KEYS is set")
+	}
 
 	// Start server
 	port := ":" + cfg.Server.Port
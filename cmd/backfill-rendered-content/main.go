@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"smsleopard/internal/clitool"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+)
+
+// Command-line flags
+var (
+	campaignID = flag.Int("campaign", 0, "Backfill the messages of this campaign")
+	all        = flag.Bool("all", false, "Backfill messages of every campaign (requires -before)")
+	before     = flag.String("before", "", "Only backfill messages created before this date (YYYY-MM-DD)")
+	afterID    = flag.Int("after", 0, "Resume after this message ID (the last ID printed by a previous run)")
+	batchSize  = flag.Int("batch-size", 500, "Number of messages rendered per batch")
+	pause      = flag.Duration("pause", 100*time.Millisecond, "Pause between batches to limit database load")
+	showHelp   = flag.Bool("help", false, "Show usage information")
+)
+
+func main() {
+	clitool.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *showHelp {
+		printUsage()
+		os.Exit(0)
+	}
+
+	clitool.PrintInfo("=== SMSLeopard Rendered Content Backfill ===\n")
+
+	filters, err := parseFilters()
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	if *batchSize <= 0 {
+		clitool.Fatal("-batch-size must be greater than 0")
+	}
+
+	// Load configuration and connect to database
+	cfg, db, err := clitool.Bootstrap()
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	defer db.Close()
+
+	messageRepo := repository.NewEncryptedMessageRepository(db, nil, cfg.Encryption.Keyring)
+	templateSvc := service.NewTemplateServiceWithLimits(cfg.Limits)
+	templateSvc.SetSyntax(cfg.Template.Syntax)
+	backfill := service.NewContentBackfill(messageRepo, templateSvc, *batchSize)
+	ctx := context.Background()
+
+	lastID, scanned, updated, failed := *afterID, 0, 0, 0
+	for {
+		batch, err := backfill.RunBatch(ctx, filters, lastID)
+		if err != nil {
+			clitool.Fatal(fmt.Sprintf("Failed after message ID %d (rerun with -after=%d): %v", lastID, lastID, err))
+		}
+		if batch.Scanned == 0 {
+			break
+		}
+
+		scanned += batch.Scanned
+		updated += batch.Updated
+		failed += len(batch.Failed)
+		lastID = batch.LastID
+		for _, failure := range batch.Failed {
+			clitool.PrintWarning(fmt.Sprintf("  ⚠ Not rendered: %s", failure))
+		}
+
+		clitool.PrintInfo(fmt.Sprintf("  ✓ Backfilled %d messages (%d so far, through ID %d)", batch.Updated, updated, lastID))
+		time.Sleep(*pause)
+	}
+
+	// Print summary
+	clitool.PrintInfo("\n=== Backfill Summary ===")
+	clitool.PrintSuccess(fmt.Sprintf("✓ Messages backfilled: %d", updated))
+	if failed > 0 {
+		clitool.PrintWarning(fmt.Sprintf("⚠ Messages whose template could not be rendered: %d", failed))
+	}
+	if skipped := scanned - updated - failed; skipped > 0 {
+		clitool.PrintWarning(fmt.Sprintf("⚠ Messages given content during the run and left as written: %d", skipped))
+	}
+	clitool.PrintInfo("\nBackfill completed successfully!")
+}
+
+// parseFilters checks that exactly one of -campaign and -all is given and reads -before
+func parseFilters() (repository.ContentBackfillFilters, error) {
+	filters := repository.ContentBackfillFilters{}
+
+	switch {
+	case *campaignID > 0 && *all:
+		return filters, fmt.Errorf("use either -campaign or -all, not both")
+	case *campaignID > 0:
+		filters.CampaignID = campaignID
+	case *all:
+		if *before == "" {
+			return filters, fmt.Errorf("-all requires -before")
+		}
+	default:
+		return filters, fmt.Errorf("one of -campaign <id> or -all -before <date> is required")
+	}
+
+	if *before != "" {
+		date, err := time.Parse("2006-01-02", *before)
+		if err != nil {
+			return filters, fmt.Errorf("-before must be a date in YYYY-MM-DD format")
+		}
+		filters.Before = &date
+	}
+
+	return filters, nil
+}
+
+func printUsage() {
+	clitool.PrintInfo("=== SMSLeopard Rendered Content Backfill ===\n")
+	fmt.Println("Usage: go run ./cmd/backfill-rendered-content (-campaign <id> | -all -before <date>) [flags]")
+	fmt.Println("\nFlags:")
+	flag.PrintDefaults()
+	fmt.Println("\nExamples:")
+	fmt.Println("  go run ./cmd/backfill-rendered-content -campaign=42")
+	fmt.Println("  go run ./cmd/backfill-rendered-content -all -before=2025-06-01")
+	fmt.Println("  go run ./cmd/backfill-rendered-content -all -before=2025-06-01 -after=120500")
+	fmt.Println("\nNotes:")
+	fmt.Println("  - Fills rendered_content of sent messages stored without it and sets backfilled = true")
+	fmt.Println("  - Content is rendered from the campaign's current template and the customer's current data,")
+	fmt.Println("    so it is a reconstruction and may differ from what was sent")
+	fmt.Println("  - Safe to stop and rerun: messages that already have content are skipped; -after skips ahead")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"smsleopard/internal/clitool"
+	"smsleopard/internal/repository"
+)
+
+// Command-line flags
+var (
+	batchSize = flag.Int("batch-size", 500, "Number of messages encrypted per batch")
+	pause     = flag.Duration("pause", 100*time.Millisecond, "Pause between batches to limit database load")
+	showHelp  = flag.Bool("help", false, "Show usage information")
+)
+
+func main() {
+	clitool.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *showHelp {
+		printUsage()
+		os.Exit(0)
+	}
+
+	clitool.PrintInfo("=== SMSLeopard Message Encryption Backfill ===\n")
+
+	if *batchSize <= 0 {
+		clitool.Fatal("-batch-size must be greater than 0")
+	}
+
+	// Load configuration and connect to database
+	cfg, db, err := clitool.Bootstrap()
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	defer db.Close()
+
+	keyring := cfg.Encryption.Keyring
+	if keyring == nil {
+		clitool.Fatal("MESSAGE_ENCRYPTION_KEY is not set; nothing to encrypt with")
+	}
+	clitool.PrintInfo(fmt.Sprintf("Encrypting rendered content with key %s...", keyring.CurrentKeyID()))
+
+	messageRepo := repository.NewEncryptedMessageRepository(db, nil, keyring)
+	ctx := context.Background()
+
+	scanned, updated, lastID := 0, 0, 0
+	for {
+		batch, err := messageRepo.ReencryptContent(ctx, lastID, *batchSize)
+		if batch != nil {
+			scanned += batch.Scanned
+			updated += batch.Updated
+			lastID = batch.LastID
+		}
+		if err != nil {
+			clitool.Fatal(fmt.Sprintf("Failed after message ID %d: %v", lastID, err))
+		}
+		if batch.Scanned == 0 {
+			break
+		}
+
+		clitool.PrintInfo(fmt.Sprintf("  ✓ Encrypted %d messages (through ID %d)", batch.Updated, lastID))
+		time.Sleep(*pause)
+	}
+
+	// Print summary
+	clitool.PrintInfo("\n=== Backfill Summary ===")
+	clitool.PrintSuccess(fmt.Sprintf("✓ Messages encrypted: %d", updated))
+	if skipped := scanned - updated; skipped > 0 {
+		clitool.PrintWarning(fmt.Sprintf("⚠ Messages changed during the run and left as written: %d", skipped))
+	}
+	clitool.PrintInfo("\nBackfill completed successfully!")
+}
+
+func printUsage() {
+	clitool.PrintInfo("=== SMSLeopard Message Encryption Backfill ===\n")
+	fmt.Println("Usage: go run ./cmd/encrypt-messages [flags]")
+	fmt.Println("\nFlags:")
+	flag.PrintDefaults()
+	fmt.Println("\nExamples:")
+	fmt.Println("  go run ./cmd/encrypt-messages")
+	fmt.Println("  go run ./cmd/encrypt-messages -batch-size=1000 -pause=0")
+	fmt.Println("\nNotes:")
+	fmt.Println("  - Encrypts plaintext rendered_content and re-encrypts rows under old keys with MESSAGE_ENCRYPTION_KEY")
+	fmt.Println("  - Old keys must stay in MESSAGE_ENCRYPTION_OLD_KEYS until this has finished")
+	fmt.Println("  - Safe to stop and rerun: rows already under the current key are skipped")
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"smsleopard/internal/clitool"
+	"smsleopard/internal/migrate"
+)
+
+// Migration directories
+const (
+	migrationsDir = "migrations"
+	seedDir       = "migrations/seed"
+)
+
+func main() {
+	clitool.RegisterFlags(flag.CommandLine)
+	flag.Usage = printUsage
+	flag.Parse()
+
+	clitool.PrintInfo("=== SMSLeopard Migration Runner ===\n")
+
+	// Parse command
+	command := "help"
+	if flag.NArg() > 0 {
+		command = flag.Arg(0)
+	}
+
+	// Show help for invalid commands
+	if command != "up" && command != "down" && command != "status" && command != "reset" && command != "seed" {
+		printUsage()
+		if command != "help" {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Load configuration and connect to database
+	_, db, err := clitool.Bootstrap()
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	defer db.Close()
+
+	// Execute command
+	switch command {
+	case "up":
+		if err := runUp(db); err != nil {
+			clitool.Fatal(fmt.Sprintf("Migration failed: %v", err))
+		}
+	case "down":
+		if err := runDown(db); err != nil {
+			clitool.Fatal(fmt.Sprintf("Rollback failed: %v", err))
+		}
+	case "status":
+		if err := showMigrationStatus(db); err != nil {
+			clitool.Fatal(fmt.Sprintf("Failed to show status: %v", err))
+		}
+	case "reset":
+		if err := runReset(db); err != nil {
+			clitool.Fatal(fmt.Sprintf("Reset failed: %v", err))
+		}
+	case "seed":
+		if err := runSeedMigrations(db); err != nil {
+			clitool.Fatal(fmt.Sprintf("Seed failed: %v", err))
+		}
+	}
+
+	clitool.PrintInfo("\n✨ Operation completed successfully!")
+}
+
+// runUp applies all pending migrations
+func runUp(db *sql.DB) error {
+	clitool.PrintInfo("Running pending migrations...\n")
+
+	applied, err := migrate.Up(db, migrationsDir)
+	for _, migration := range applied {
+		clitool.PrintSuccess(fmt.Sprintf("  ✓ Migration %03d_%s applied", migration.Version, migration.Name))
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		clitool.PrintSuccess("✓ All migrations are up to date")
+		return nil
+	}
+
+	clitool.PrintSuccess(fmt.Sprintf("\n✓ Successfully applied %d migration(s)", len(applied)))
+	return nil
+}
+
+// runDown rolls back the last applied migration
+func runDown(db *sql.DB) error {
+	clitool.PrintInfo("Rolling back last migration...\n")
+
+	rolledBack, err := migrate.Down(db, migrationsDir, 1)
+	if err != nil {
+		return err
+	}
+
+	if len(rolledBack) == 0 {
+		clitool.PrintWarning("No migrations to rollback")
+		return nil
+	}
+
+	clitool.PrintSuccess(fmt.Sprintf("✓ Successfully rolled back migration %03d_%s", rolledBack[0].Version, rolledBack[0].Name))
+	return nil
+}
+
+// runReset rolls back all migrations and reapplies them
+func runReset(db *sql.DB) error {
+	clitool.PrintWarning("Resetting database (rollback all + reapply all)...\n")
+
+	migrations, err := migrate.Status(db, migrationsDir)
+	if err != nil {
+		return err
+	}
+
+	// Rollback all migrations in reverse order
+	rolledBack, err := migrate.Down(db, migrationsDir, len(migrations))
+	for _, migration := range rolledBack {
+		clitool.PrintSuccess(fmt.Sprintf("  ✓ Migration %03d_%s rolled back", migration.Version, migration.Name))
+	}
+	if err != nil {
+		return err
+	}
+	if len(rolledBack) > 0 {
+		clitool.PrintSuccess("\n✓ All migrations rolled back\n")
+	}
+
+	// Reapply all migrations
+	clitool.PrintInfo("Reapplying all migrations...")
+	return runUp(db)
+}
+
+// showMigrationStatus displays the current migration status
+func showMigrationStatus(db *sql.DB) error {
+	clitool.PrintInfo("Migration Status:\n")
+
+	migrations, err := migrate.Status(db, migrationsDir)
+	if err != nil {
+		return err
+	}
+
+	if len(migrations) == 0 {
+		clitool.PrintWarning("No migration files found in migrations/ directory")
+		return nil
+	}
+
+	// Print table header
+	fmt.Println(clitool.Colorize(clitool.ColorBold,
+		fmt.Sprintf("%-10s %-40s %-12s %-20s", "VERSION", "NAME", "STATUS", "APPLIED AT")))
+	fmt.Println(strings.Repeat("-", 85))
+
+	// Print each migration
+	appliedCount := 0
+	for _, migration := range migrations {
+		version := fmt.Sprintf("%03d", migration.Version)
+		status := "pending"
+		statusColor := clitool.ColorYellow
+		appliedAt := "-"
+
+		if migration.Applied {
+			appliedCount++
+			status = "applied"
+			statusColor = clitool.ColorGreen
+			if migration.AppliedAt != nil {
+				appliedAt = migration.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+		}
+
+		fmt.Printf("%-10s %-40s %s %-20s\n",
+			version, migration.Name, clitool.Colorize(statusColor, fmt.Sprintf("%-12s", status)), appliedAt)
+	}
+
+	// Print summary
+	fmt.Println(strings.Repeat("-", 85))
+	clitool.PrintInfo(fmt.Sprintf("\nSummary: %d/%d migrations applied", appliedCount, len(migrations)))
+
+	return nil
+}
+
+// runSeedMigrations executes seed data migrations
+func runSeedMigrations(db *sql.DB) error {
+	clitool.PrintInfo("Running seed migrations...\n")
+
+	// Get seed migration files
+	seedMigrations, err := migrate.Files(seedDir)
+	if err != nil {
+		return err
+	}
+
+	if len(seedMigrations) == 0 {
+		clitool.PrintWarning("No seed migration files found in migrations/seed/ directory")
+		return nil
+	}
+
+	// Run each seed migration
+	for _, migration := range seedMigrations {
+		clitool.PrintInfo(fmt.Sprintf("Running seed %03d_%s...", migration.Version, migration.Name))
+
+		// Read seed file
+		content, err := os.ReadFile(migration.FilePath)
+		if err != nil {
+			return fmt.Errorf("failed to read seed file: %w", err)
+		}
+
+		// Execute seed SQL (no transaction tracking needed for seeds)
+		if _, err := db.Exec(string(content)); err != nil {
+			return fmt.Errorf("failed to execute seed SQL: %w", err)
+		}
+
+		clitool.PrintSuccess(fmt.Sprintf("  ✓ Seed %03d applied successfully", migration.Version))
+	}
+
+	clitool.PrintSuccess(fmt.Sprintf("\n✓ Successfully ran %d seed migration(s)", len(seedMigrations)))
+	return nil
+}
+
+func printUsage() {
+	clitool.PrintInfo("=== SMSLeopard Migration Runner ===\n")
+	fmt.Println("Usage: go run ./cmd/migrate [flags] [command]")
+	fmt.Println("\nCommands:")
+	fmt.Println("  up       - Apply all pending migrations")
+	fmt.Println("  down     - Rollback the last applied migration")
+	fmt.Println("  status   - Show current migration status")
+	fmt.Println("  reset    - Rollback all migrations and reapply them")
+	fmt.Println("  seed     - Run seed data migrations only")
+	fmt.Println("  help     - Show this help message")
+	fmt.Println("\nFlags:")
+	flag.PrintDefaults()
+	fmt.Println("\nExamples:")
+	fmt.Println("  go run ./cmd/migrate up")
+	fmt.Println("  go run ./cmd/migrate status")
+	fmt.Println("  go run ./cmd/migrate down")
+	fmt.Println("  go run ./cmd/migrate reset")
+	fmt.Println("  go run ./cmd/migrate seed")
+	fmt.Println("\nMigration Files:")
+	fmt.Println("  Schema:  migrations/*.sql (001_*, 002_*, 003_*)")
+	fmt.Println("  Seeds:   migrations/seed/*.sql")
+	fmt.Println("\nNotes:")
+	fmt.Println("  - Migrations are tracked in the 'schema_migrations' table")
+	fmt.Println("  - Each migration runs in a transaction")
+	fmt.Println("  - Rollback drops tables in reverse dependency order")
+	fmt.Println("  - Runs hold an advisory lock, so they wait for AUTO_MIGRATE at API/worker startup")
+	fmt.Println("  - Seed migrations can be run independently with 'seed' command")
+}
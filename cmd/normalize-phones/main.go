@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"smsleopard/internal/clitool"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+)
+
+// Command-line flags
+var (
+	table    = flag.String("table", "all", "Normalize phones of customers, suppressions or all")
+	dryRun   = flag.Bool("dry-run", false, "Report what would change without writing")
+	showHelp = flag.Bool("help", false, "Show usage information")
+)
+
+func main() {
+	clitool.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *showHelp {
+		printUsage()
+		os.Exit(0)
+	}
+
+	clitool.PrintInfo("=== SMSLeopard Phone Normalization ===\n")
+
+	if *table != "all" && *table != "customers" && *table != "suppressions" {
+		clitool.Fatal("-table must be customers, suppressions or all")
+	}
+	if *dryRun {
+		clitool.PrintWarning("Dry run: nothing will be written\n")
+	}
+
+	// Load configuration and connect to database
+	_, db, err := clitool.Bootstrap()
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	defer db.Close()
+
+	normalizer := service.NewPhoneNormalizer(repository.NewPhoneNormalizationRepository(db))
+	ctx := context.Background()
+
+	reports := []*service.PhoneNormalizationReport{}
+	if *table != "suppressions" {
+		report, err := normalizer.NormalizeCustomers(ctx, *dryRun)
+		if err != nil {
+			clitool.Fatal(fmt.Sprintf("Failed to normalize customer phones: %v", err))
+		}
+		reports = append(reports, report)
+	}
+	if *table != "customers" {
+		report, err := normalizer.NormalizeSuppressions(ctx, *dryRun)
+		if err != nil {
+			clitool.Fatal(fmt.Sprintf("Failed to normalize suppressed phones: %v", err))
+		}
+		reports = append(reports, report)
+	}
+
+	for _, report := range reports {
+		printReport(report)
+	}
+
+	if *dryRun {
+		clitool.PrintInfo("\nDry run completed; rerun without -dry-run to apply")
+		return
+	}
+	clitool.PrintInfo("\nNormalization completed successfully!")
+}
+
+// printReport prints one table's counts, then every collision and invalid phone
+func printReport(report *service.PhoneNormalizationReport) {
+	clitool.PrintInfo(fmt.Sprintf("\n=== %s ===", report.Table))
+	clitool.PrintInfo(fmt.Sprintf("Phones scanned: %d", report.Scanned))
+	clitool.PrintSuccess(fmt.Sprintf("✓ Phones normalized: %d", report.Rewritten))
+	if report.Removed > 0 {
+		clitool.PrintSuccess(fmt.Sprintf("✓ Duplicate suppressions removed: %d", report.Removed))
+	}
+
+	for _, collision := range report.Collisions {
+		clitool.PrintWarning(fmt.Sprintf("  ⚠ %s: kept %s", collision.Phone, service.DescribeStoredPhone(collision.Kept)))
+		for _, other := range collision.Others {
+			if other.CampaignID != 0 {
+				clitool.PrintWarning(fmt.Sprintf("      removed %s", service.DescribeStoredPhone(other)))
+			} else {
+				clitool.PrintWarning(fmt.Sprintf("      left %s; merge it by hand", service.DescribeStoredPhone(other)))
+			}
+		}
+	}
+	for _, invalid := range report.Invalid {
+		clitool.PrintWarning(fmt.Sprintf("  ⚠ Not normalized: %s", invalid))
+	}
+}
+
+func printUsage() {
+	clitool.PrintInfo("=== SMSLeopard Phone Normalization ===\n")
+	fmt.Println("Usage: go run ./cmd/normalize-phones [flags]")
+	fmt.Println("\nFlags:")
+	flag.PrintDefaults()
+	fmt.Println("\nExamples:")
+	fmt.Println("  go run ./cmd/normalize-phones -dry-run")
+	fmt.Println("  go run ./cmd/normalize-phones -table=suppressions")
+	fmt.Println("\nNotes:")
+	fmt.Println("  - Rewrites phones such as 0712345678 to +254712345678, as new customers and suppressions are stored")
+	fmt.Println("  - When several rows collapse to one number the oldest is kept: other customers are left as")
+	fmt.Println("    stored and reported for merging, other suppressions of the same campaign are removed")
+	fmt.Println("  - Phones that cannot be normalized are reported and left as stored")
+	fmt.Println("  - Each table is rewritten in one transaction; safe to rerun")
+}
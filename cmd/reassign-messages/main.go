@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"smsleopard/internal/clitool"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+)
+
+// Command-line flags
+var (
+	fromID      = flag.Int("from", 0, "Move messages of this campaign")
+	toID        = flag.Int("to", 0, "Move messages to this campaign")
+	status      = flag.String("status", string(models.MessageStatusPending), "Move messages in this status (pending or failed)")
+	requestedBy = flag.String("by", "", "Who is moving the messages, for the audit log")
+	batchSize   = flag.Int("batch-size", service.DefaultReassignBatchSize, "Number of messages moved per transaction")
+	pause       = flag.Duration("pause", 100*time.Millisecond, "Pause between batches to limit database load")
+	showHelp    = flag.Bool("help", false, "Show usage information")
+)
+
+func main() {
+	clitool.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *showHelp {
+		printUsage()
+		os.Exit(0)
+	}
+
+	clitool.PrintInfo("=== SMSLeopard Message Reassignment ===\n")
+
+	if *fromID <= 0 || *toID <= 0 {
+		clitool.Fatal("-from <id> and -to <id> are required")
+	}
+	if *batchSize <= 0 {
+		clitool.Fatal("-batch-size must be greater than 0")
+	}
+
+	// Load configuration and connect to database
+	_, db, err := clitool.Bootstrap()
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	defer db.Close()
+
+	reassigner := service.NewMessageReassigner(
+		repository.NewCampaignRepository(db),
+		repository.NewMessageReassignmentRepository(db),
+		*batchSize,
+	)
+	req := &service.ReassignMessagesRequest{
+		FromCampaignID: *fromID,
+		ToCampaignID:   *toID,
+		Status:         models.MessageStatus(*status),
+		RequestedBy:    *requestedBy,
+	}
+
+	reassignment, err := reassigner.Reassign(context.Background(), req, func(moved int) {
+		clitool.PrintInfo(fmt.Sprintf("  ✓ Moved %d messages so far", moved))
+		time.Sleep(*pause)
+	})
+	if err != nil {
+		if reassignment != nil {
+			clitool.Fatal(fmt.Sprintf("%v (reassignment %d; rerun to move the rest)", err, reassignment.ID))
+		}
+		clitool.Fatal(err.Error())
+	}
+
+	// Print summary
+	clitool.PrintInfo("\n=== Reassignment Summary ===")
+	clitool.PrintSuccess(fmt.Sprintf("✓ %s messages moved from campaign %d to campaign %d: %d",
+		reassignment.Status, reassignment.FromCampaignID, reassignment.ToCampaignID, reassignment.MessagesMoved))
+	clitool.PrintInfo(fmt.Sprintf("Logged as reassignment %d", reassignment.ID))
+	clitool.PrintInfo("\nReassignment completed successfully!")
+}
+
+func printUsage() {
+	clitool.PrintInfo("=== SMSLeopard Message Reassignment ===\n")
+	fmt.Println("Usage: go run ./cmd/reassign-messages -from <id> -to <id> [flags]")
+	fmt.Println("\nFlags:")
+	flag.PrintDefaults()
+	fmt.Println("\nExamples:")
+	fmt.Println("  go run ./cmd/reassign-messages -from=41 -to=42 -by=ops@example.com")
+	fmt.Println("  go run ./cmd/reassign-messages -from=41 -to=42 -status=failed")
+	fmt.Println("\nNotes:")
+	fmt.Println("  - Both campaigns must have the same channel, and the target must not have finished")
+	fmt.Println("  - Pending messages are rendered from the target's template when the worker sends them")
+	fmt.Println("  - Each batch and its audit count are written in one transaction; safe to stop and rerun")
+}
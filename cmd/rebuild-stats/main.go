@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"smsleopard/internal/clitool"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+)
+
+// Command-line flags
+var (
+	campaignID = flag.Int("campaign", 0, "Rebuild the stats of this campaign")
+	dryRun     = flag.Bool("dry-run", false, "Report discrepancies without correcting them")
+	showHelp   = flag.Bool("help", false, "Show usage information")
+)
+
+func main() {
+	clitool.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *showHelp {
+		printUsage()
+		os.Exit(0)
+	}
+
+	clitool.PrintInfo("=== SMSLeopard Stats Rebuild ===\n")
+
+	if *campaignID <= 0 {
+		clitool.Fatal("-campaign <id> is required")
+	}
+	if *dryRun {
+		clitool.PrintWarning("Dry run: nothing will be written\n")
+	}
+
+	// Load configuration and connect to database
+	cfg, db, err := clitool.Bootstrap()
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	defer db.Close()
+
+	rebuilder := service.NewStatsRebuilder(
+		repository.NewCampaignRepository(db),
+		repository.NewCampaignEventRepository(db),
+		repository.NewStatsRebuildRepository(db),
+		cfg.Sending,
+	)
+
+	report, err := rebuilder.Rebuild(context.Background(), *campaignID, *dryRun)
+	if report != nil {
+		printReport(report)
+	}
+	if err != nil {
+		clitool.Fatal(fmt.Sprintf("Failed to rebuild stats: %v", err))
+	}
+
+	if *dryRun {
+		clitool.PrintInfo("\nDry run completed; rerun without -dry-run to correct")
+		return
+	}
+	clitool.PrintInfo("\nRebuild completed successfully!")
+}
+
+// printReport prints the replay's counts, then every discrepancy and unmatched message
+func printReport(report *service.StatsRebuildReport) {
+	clitool.PrintInfo(fmt.Sprintf("=== Campaign %d ===", report.CampaignID))
+	clitool.PrintInfo(fmt.Sprintf("Events replayed: %d", report.EventsReplayed))
+	clitool.PrintInfo(fmt.Sprintf("Messages: %d", report.Messages))
+
+	if len(report.Discrepancies) == 0 {
+		clitool.PrintSuccess("✓ No discrepancies found")
+	}
+	for _, d := range report.Discrepancies {
+		field := d.Field
+		if d.MessageID != nil {
+			field = fmt.Sprintf("message %d %s", *d.MessageID, d.Field)
+		}
+		clitool.PrintWarning(fmt.Sprintf("  ⚠ %s: recorded %s, replayed %s", field, d.Recorded, d.Replayed))
+	}
+	if !report.DryRun && report.Corrected > 0 {
+		clitool.PrintSuccess(fmt.Sprintf("✓ Corrected: %d", report.Corrected))
+	}
+
+	for _, id := range report.UntrackedMessages {
+		clitool.PrintWarning(fmt.Sprintf("  ⚠ Message %d has no events; left as stored", id))
+	}
+	for _, id := range report.MissingMessages {
+		clitool.PrintWarning(fmt.Sprintf("  ⚠ Message %d has events but is not stored; cannot be restored", id))
+	}
+}
+
+func printUsage() {
+	clitool.PrintInfo("=== SMSLeopard Stats Rebuild ===\n")
+	fmt.Println("Usage: go run ./cmd/rebuild-stats -campaign <id> [flags]")
+	fmt.Println("\nFlags:")
+	flag.PrintDefaults()
+	fmt.Println("\nExamples:")
+	fmt.Println("  go run ./cmd/rebuild-stats -campaign=42 -dry-run")
+	fmt.Println("  go run ./cmd/rebuild-stats -campaign=42")
+	fmt.Println("\nNotes:")
+	fmt.Println("  - Replays the campaign's event log to rebuild each message's status, retry count and")
+	fmt.Println("    last error, the campaign's status and, for campaigns with a budget, its spend")
+	fmt.Println("  - Spend is priced at the current per-channel costs; simulated sends cost nothing")
+	fmt.Println("  - A sending or cancelling campaign can only be checked with -dry-run")
+	fmt.Println("  - Corrections are recorded in the event log themselves; safe to rerun")
+}
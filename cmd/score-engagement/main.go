@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"smsleopard/internal/clitool"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+)
+
+// Command-line flags
+var (
+	afterID   = flag.Int("after", 0, "Resume after this customer ID (the last ID printed by a previous run)")
+	batchSize = flag.Int("batch-size", 0, "Number of customers scored per batch (default ENGAGEMENT_BATCH_SIZE)")
+	pause     = flag.Duration("pause", 100*time.Millisecond, "Pause between batches to limit database load")
+	showHelp  = flag.Bool("help", false, "Show usage information")
+)
+
+func main() {
+	clitool.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *showHelp {
+		printUsage()
+		os.Exit(0)
+	}
+
+	clitool.PrintInfo("=== SMSLeopard Engagement Scoring ===\n")
+
+	if *batchSize < 0 {
+		clitool.Fatal("-batch-size cannot be negative")
+	}
+
+	// Load configuration and connect to database
+	cfg, db, err := clitool.Bootstrap()
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	defer db.Close()
+
+	weights := cfg.Engagement
+	if *batchSize > 0 {
+		weights.BatchSize = *batchSize
+	}
+	clitool.PrintInfo(fmt.Sprintf("Weights: %g per sent message, halved every %s; -%g per failed message; last %s",
+		weights.SentWeight, weights.HalfLife, weights.FailurePenalty, weights.Lookback))
+
+	scorer := service.NewEngagementScorer(repository.NewEngagementRepository(db), weights)
+	ctx := context.Background()
+
+	lastID, scored := *afterID, 0
+	for {
+		batch, err := scorer.RunBatch(ctx, lastID)
+		if err != nil {
+			clitool.Fatal(fmt.Sprintf("Failed after customer ID %d (rerun with -after=%d): %v", lastID, lastID, err))
+		}
+		if batch.Scored == 0 {
+			break
+		}
+
+		scored += batch.Scored
+		lastID = batch.LastID
+		clitool.PrintInfo(fmt.Sprintf("  ✓ Scored %d customers (%d so far, through ID %d)", batch.Scored, scored, lastID))
+		time.Sleep(*pause)
+	}
+
+	clitool.PrintInfo("\n=== Scoring Summary ===")
+	clitool.PrintSuccess(fmt.Sprintf("✓ Customers scored: %d", scored))
+	clitool.PrintInfo("\nScoring completed successfully!")
+}
+
+func printUsage() {
+	clitool.PrintInfo("=== SMSLeopard Engagement Scoring ===\n")
+	fmt.Println("Usage: go run ./cmd/score-engagement [flags]")
+	fmt.Println("\nFlags:")
+	flag.PrintDefaults()
+	fmt.Println("\nExamples:")
+	fmt.Println("  go run ./cmd/score-engagement")
+	fmt.Println("  go run ./cmd/score-engagement -after=120500 -batch-size=1000")
+	fmt.Println("\nNotes:")
+	fmt.Println("  - Sets engagement_score on every customer from their sent and failed messages, with the")
+	fmt.Println("    ENGAGEMENT_* weights; meant to run nightly, or set ENGAGEMENT_SCORE_INTERVAL on the worker")
+	fmt.Println("  - Customers with no messages in ENGAGEMENT_LOOKBACK score 0")
+	fmt.Println("  - Safe to stop and rerun: each batch is stored as it is scored; -after skips ahead")
+}
@@ -1,25 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"fmt"
 	"os"
 	"time"
 
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
-
-	"smsleopard/internal/config"
-)
-
-// ANSI color codes for terminal output
-const (
-	colorReset  = "\033[0m"
-	colorRed    = "\033[31m"
-	colorGreen  = "\033[32m"
-	colorYellow = "\033[33m"
-	colorCyan   = "\033[36m"
+	"smsleopard/internal/clitool"
+	"smsleopard/internal/snapshot"
 )
 
 // Command-line flags
@@ -27,10 +17,12 @@ var (
 	customersCount = flag.Int("customers", 12, "Number of customers to create")
 	campaignsCount = flag.Int("campaigns", 3, "Number of campaigns to create")
 	clearData      = flag.Bool("clear", false, "Clear existing seed data before inserting")
+	snapshotPath   = flag.String("snapshot", "", "Load this YAML snapshot instead of generated data (e.g. fixtures/dev.yaml)")
 	showHelp       = flag.Bool("help", false, "Show usage information")
 )
 
 func main() {
+	clitool.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
 	if *showHelp {
@@ -38,66 +30,77 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Load .env file (ignore error if not present)
-	_ = godotenv.Load()
-
-	printInfo("=== SMSLeopard Database Seeder ===\n")
+	clitool.PrintInfo("=== SMSLeopard Database Seeder ===\n")
 
-	// Load configuration
-	cfg, err := config.Load()
+	// Load configuration and connect to database
+	_, db, err := clitool.Bootstrap()
 	if err != nil {
-		printError(fmt.Sprintf("Failed to load configuration: %v", err))
-		os.Exit(1)
-	}
-
-	// Connect to database
-	printInfo("Connecting to database...")
-	db, err := sql.Open("postgres", cfg.GetDatabaseDSN())
-	if err != nil {
-		printError(fmt.Sprintf("Failed to open database connection: %v", err))
-		os.Exit(1)
+		clitool.Fatal(err.Error())
 	}
 	defer db.Close()
 
-	// Test connection
-	if err := db.Ping(); err != nil {
-		printError(fmt.Sprintf("Failed to ping database: %v", err))
-		os.Exit(1)
+	if *snapshotPath != "" {
+		loadSnapshot(db, *snapshotPath)
+		return
 	}
-	printSuccess("✓ Connected to database\n")
 
 	// Clear data if requested
 	if *clearData {
 		if err := clearSeedData(db); err != nil {
-			printError(fmt.Sprintf("Failed to clear seed data: %v", err))
-			os.Exit(1)
+			clitool.Fatal(fmt.Sprintf("Failed to clear seed data: %v", err))
 		}
 	}
 
 	// Seed customers
 	customersCreated, err := seedCustomers(db, *customersCount)
 	if err != nil {
-		printError(fmt.Sprintf("Failed to seed customers: %v", err))
-		os.Exit(1)
+		clitool.Fatal(fmt.Sprintf("Failed to seed customers: %v", err))
 	}
 
 	// Seed campaigns
 	campaignsCreated, err := seedCampaigns(db, *campaignsCount)
 	if err != nil {
-		printError(fmt.Sprintf("Failed to seed campaigns: %v", err))
-		os.Exit(1)
+		clitool.Fatal(fmt.Sprintf("Failed to seed campaigns: %v", err))
 	}
 
 	// Print summary
-	printInfo("\n=== Seeding Summary ===")
-	printSuccess(fmt.Sprintf("✓ Customers created: %d", customersCreated))
-	printSuccess(fmt.Sprintf("✓ Campaigns created: %d", campaignsCreated))
-	printInfo("\nSeeding completed successfully!")
+	clitool.PrintInfo("\n=== Seeding Summary ===")
+	clitool.PrintSuccess(fmt.Sprintf("✓ Customers created: %d", customersCreated))
+	clitool.PrintSuccess(fmt.Sprintf("✓ Campaigns created: %d", campaignsCreated))
+	clitool.PrintInfo("\nSeeding completed successfully!")
+}
+
+// loadSnapshot loads a snapshot file, replacing the rows it describes
+func loadSnapshot(db *sql.DB, path string) {
+	if *clearData {
+		clitool.Fatal("-clear cannot be combined with -snapshot; loading a snapshot already replaces its rows")
+	}
+
+	snap, err := snapshot.ReadFile(path)
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+
+	clitool.PrintInfo(fmt.Sprintf("Loading snapshot %s...", path))
+	result, err := snapshot.Load(context.Background(), db, snap, time.Now())
+	if err != nil {
+		clitool.Fatal(fmt.Sprintf("Failed to load snapshot: %v", err))
+	}
+
+	// Print summary
+	clitool.PrintInfo("\n=== Snapshot Summary ===")
+	clitool.PrintSuccess(fmt.Sprintf("✓ Customers loaded: %d", result.Customers))
+	clitool.PrintSuccess(fmt.Sprintf("✓ Campaigns loaded: %d", result.Campaigns))
+	clitool.PrintSuccess(fmt.Sprintf("✓ Messages loaded: %d", result.Messages))
+	if result.MessagesRemoved > 0 {
+		clitool.PrintWarning(fmt.Sprintf("⚠ Messages not in the snapshot removed from its campaigns: %d", result.MessagesRemoved))
+	}
+	clitool.PrintInfo("\nSnapshot loaded successfully!")
 }
 
 // clearSeedData removes existing seed data
 func clearSeedData(db *sql.DB) error {
-	printWarning("Clearing existing seed data...")
+	clitool.PrintWarning("Clearing existing seed data...")
 
 	tx, err := db.Begin()
 	if err != nil {
@@ -105,6 +108,15 @@ func clearSeedData(db *sql.DB) error {
 	}
 	defer tx.Rollback()
 
+	// Messages block campaign and customer deletes, so remove the seeded ones first
+	_, err = tx.Exec(`
+		DELETE FROM outbound_messages
+		WHERE campaign_id IN (SELECT id FROM campaigns WHERE name LIKE 'Weekend Sale%' OR name LIKE 'New Arrivals%' OR name LIKE 'Customer Appreciation%')
+		OR customer_id IN (SELECT id FROM customers WHERE phone LIKE '+254700010%')`)
+	if err != nil {
+		return fmt.Errorf("failed to delete messages: %w", err)
+	}
+
 	// Delete campaigns with Go-seeded naming pattern
 	_, err = tx.Exec("DELETE FROM campaigns WHERE name LIKE 'Weekend Sale%' OR name LIKE 'New Arrivals%' OR name LIKE 'Customer Appreciation%'")
 	if err != nil {
@@ -121,13 +133,13 @@ func clearSeedData(db *sql.DB) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	printSuccess("✓ Seed data cleared\n")
+	clitool.PrintSuccess("✓ Seed data cleared\n")
 	return nil
 }
 
 // seedCustomers generates and inserts customer data
 func seedCustomers(db *sql.DB, count int) (int, error) {
-	printInfo(fmt.Sprintf("Seeding %d customers...", count))
+	clitool.PrintInfo(fmt.Sprintf("Seeding %d customers...", count))
 
 	// Realistic Kenyan data
 	firstNames := []string{"Michael", "Sophia", "James", "Olivia", "Daniel", "Emma", "Benjamin", "Ava", "Lucas", "Mia", "Noah", "Isabella", "William", "Charlotte", "Alexander"}
@@ -180,13 +192,13 @@ func seedCustomers(db *sql.DB, count int) (int, error) {
 		}
 	}
 
-	printSuccess(fmt.Sprintf("✓ Seeded %d customers (skipped %d existing)", created, count-created))
+	clitool.PrintSuccess(fmt.Sprintf("✓ Seeded %d customers (skipped %d existing)", created, count-created))
 	return created, nil
 }
 
 // seedCampaigns generates and inserts campaign data
 func seedCampaigns(db *sql.DB, count int) (int, error) {
-	printInfo(fmt.Sprintf("Seeding %d campaigns...", count))
+	clitool.PrintInfo(fmt.Sprintf("Seeding %d campaigns...", count))
 
 	// Define campaign templates with different variations
 	campaigns := []struct {
@@ -251,7 +263,7 @@ func seedCampaigns(db *sql.DB, count int) (int, error) {
 		}
 	}
 
-	printSuccess(fmt.Sprintf("✓ Seeded %d campaigns (skipped %d existing)", created, count-created))
+	clitool.PrintSuccess(fmt.Sprintf("✓ Seeded %d campaigns (skipped %d existing)", created, count-created))
 	return created, nil
 }
 
@@ -267,39 +279,22 @@ func timePtr(t time.Time) *time.Time {
 	return &t
 }
 
-// printSuccess prints a success message in green
-func printSuccess(msg string) {
-	fmt.Printf("%s%s%s\n", colorGreen, msg, colorReset)
-}
-
-// printError prints an error message in red
-func printError(msg string) {
-	fmt.Fprintf(os.Stderr, "%s%s%s\n", colorRed, msg, colorReset)
-}
-
-// printInfo prints an info message in cyan
-func printInfo(msg string) {
-	fmt.Printf("%s%s%s\n", colorCyan, msg, colorReset)
-}
-
-// printWarning prints a warning message in yellow
-func printWarning(msg string) {
-	fmt.Printf("%s%s%s\n", colorYellow, msg, colorReset)
-}
-
 // printUsage displays usage information
 func printUsage() {
-	printInfo("=== SMSLeopard Database Seeder ===\n")
-	fmt.Println("Usage: go run scripts/seed.go [flags]")
+	clitool.PrintInfo("=== SMSLeopard Database Seeder ===\n")
+	fmt.Println("Usage: go run ./cmd/seed [flags]")
 	fmt.Println("\nFlags:")
 	flag.PrintDefaults()
 	fmt.Println("\nExamples:")
-	fmt.Println("  go run scripts/seed.go")
-	fmt.Println("  go run scripts/seed.go -customers=20 -campaigns=5")
-	fmt.Println("  go run scripts/seed.go -clear")
-	fmt.Println("  go run scripts/seed.go -clear -customers=50")
+	fmt.Println("  go run ./cmd/seed")
+	fmt.Println("  go run ./cmd/seed -customers=20 -campaigns=5")
+	fmt.Println("  go run ./cmd/seed -clear")
+	fmt.Println("  go run ./cmd/seed -clear -customers=50")
+	fmt.Println("  go run ./cmd/seed --quiet --no-color")
+	fmt.Println("  go run ./cmd/seed -snapshot fixtures/dev.yaml")
 	fmt.Println("\nNotes:")
 	fmt.Println("  - Customers use phone pattern: +2547000010XXX (different from SQL seeds)")
 	fmt.Println("  - The script is idempotent - running multiple times won't create duplicates")
 	fmt.Println("  - Use -clear to remove existing seed data before inserting new data")
+	fmt.Println("  - -snapshot loads fixed IDs and relative timestamps from YAML; reloading gives the same state")
 }
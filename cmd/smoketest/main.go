@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"smsleopard/internal/clitool"
+	"smsleopard/internal/smoketest"
+)
+
+// Command-line flags
+var (
+	baseURL      = flag.String("url", "http://localhost:8080", "API base URL")
+	apiKey       = flag.String("api-key", os.Getenv("SMOKETEST_API_KEY"), "API key (defaults to $SMOKETEST_API_KEY)")
+	testPhone    = flag.String("test-phone", "", "Phone sent one real message; without it the real send is skipped")
+	skipRealSend = flag.Bool("skip-real-send", false, "Skip the real send even when -test-phone is set")
+	timeout      = flag.Duration("timeout", smoketest.DefaultTimeout, "Timeout for each request")
+	jsonOutput   = flag.Bool("json", false, "Print the report as JSON instead of text")
+	showHelp     = flag.Bool("help", false, "Show usage information")
+)
+
+func main() {
+	clitool.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *showHelp {
+		printUsage()
+		os.Exit(0)
+	}
+	if *timeout <= 0 {
+		clitool.Fatal("-timeout must be greater than 0")
+	}
+
+	if !*jsonOutput {
+		clitool.PrintInfo("=== SMSLeopard Smoke Test ===\n")
+		clitool.PrintInfo(fmt.Sprintf("Target: %s\n", *baseURL))
+	}
+
+	runner := smoketest.NewRunner(smoketest.Options{
+		BaseURL:      *baseURL,
+		APIKey:       *apiKey,
+		TestPhone:    *testPhone,
+		SkipRealSend: *skipRealSend,
+		Timeout:      *timeout,
+	})
+	report := runner.Run(context.Background())
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			clitool.Fatal(fmt.Sprintf("Failed to write report: %v", err))
+		}
+	} else {
+		printReport(report)
+	}
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
+
+// printReport prints each step with its timing, then the overall result
+func printReport(report *smoketest.Report) {
+	for _, step := range report.Steps {
+		line := fmt.Sprintf("%-16s %8s", step.Name, formatDuration(step.DurationMS))
+		switch step.Status {
+		case smoketest.StepPassed:
+			clitool.PrintSuccess(fmt.Sprintf("✓ %s  %s", line, step.Detail))
+		case smoketest.StepFailed:
+			clitool.PrintError(fmt.Sprintf("✗ %s  %s", line, step.Error))
+		default:
+			clitool.PrintWarning(fmt.Sprintf("- %-16s %8s  skipped: %s", step.Name, "", step.Detail))
+		}
+	}
+
+	total := formatDuration(report.DurationMS)
+	if report.Passed {
+		clitool.PrintSuccess(fmt.Sprintf("\n✓ Smoke test passed in %s", total))
+		return
+	}
+	clitool.PrintError(fmt.Sprintf("\n✗ Smoke test failed after %s", total))
+}
+
+func formatDuration(ms float64) string {
+	return (time.Duration(ms * float64(time.Millisecond))).Round(time.Millisecond).String()
+}
+
+func printUsage() {
+	clitool.PrintInfo("=== SMSLeopard Smoke Test ===\n")
+	fmt.Println("Usage: go run ./cmd/smoketest [flags]")
+	fmt.Println("\nFlags:")
+	flag.PrintDefaults()
+	fmt.Println("\nExamples:")
+	fmt.Println("  go run ./cmd/smoketest -url=https://api.example.com -api-key=$KEY")
+	fmt.Println("  go run ./cmd/smoketest -url=https://api.example.com -test-phone=+254712345678")
+	fmt.Println("  go run ./cmd/smoketest -url=https://api.example.com -json > smoketest.json")
+	fmt.Println("\nSteps:")
+	fmt.Println("  health, create_campaign, preview, dry_run, real_send, verify_list, cleanup")
+	fmt.Println("\nNotes:")
+	fmt.Println("  - The campaign is named smoketest-<time> and tagged smoketest; cleanup force-deletes it,")
+	fmt.Println("    and runs even when an earlier step failed")
+	fmt.Println("  - The preview uses an inline customer and the dry run is POST /campaigns/{id}/simulate;")
+	fmt.Println("    neither stores a customer or sends anything")
+	fmt.Println("  - The real send is an ordinary send to an inline customer with -test-phone, which stores")
+	fmt.Println("    that customer if it is new")
+	fmt.Println("  - Exits 1 when any step fails")
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"smsleopard/internal/clitool"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+)
+
+// Command-line flags
+var (
+	queueName = flag.String("queue", "campaign_sends", "Queue to verify")
+	dryRun    = flag.Bool("dry-run", false, "Report disagreements without moving or republishing anything")
+	grace     = flag.Duration("grace", service.QueueVerifyGracePeriod, "Leave pending messages published within this long alone")
+	limit     = flag.Int("limit", 100000, "Most jobs taken off the queue for the check")
+	showHelp  = flag.Bool("help", false, "Show usage information")
+)
+
+func main() {
+	clitool.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *showHelp {
+		printUsage()
+		os.Exit(0)
+	}
+
+	clitool.PrintInfo("=== SMSLeopard Queue Verification ===\n")
+	if *limit <= 0 {
+		clitool.Fatal("-limit must be greater than 0")
+	}
+	if *grace < 0 {
+		clitool.Fatal("-grace cannot be negative")
+	}
+
+	// Load configuration and connect to database
+	cfg, db, err := clitool.Bootstrap()
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	defer db.Close()
+
+	clitool.PrintInfo("Connecting to RabbitMQ...")
+	conn, err := queue.NewConnection(cfg.GetRabbitMQURL())
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	defer conn.Close()
+	publisher, err := queue.NewPublisher(conn, *queueName)
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	clitool.PrintSuccess("✓ Connected to RabbitMQ\n")
+
+	drain, err := queue.NewDrain(conn, *queueName, *limit)
+	if err != nil {
+		clitool.Fatal(err.Error())
+	}
+	jobs := len(drain.Jobs())
+	clitool.PrintInfo(fmt.Sprintf("Holding %d waiting job(s) off %s", jobs, *queueName))
+	if jobs == *limit {
+		clitool.PrintWarning(fmt.Sprintf("⚠ Stopped at -limit=%d; reporting only, as messages with jobs beyond it would look missing", *limit))
+	}
+
+	messageRepo := repository.NewEncryptedMessageRepository(db, nil, cfg.Encryption.Keyring)
+	verifier := service.NewQueueVerifier(messageRepo, *grace)
+	publish := func(message *models.OutboundMessage) error {
+		return publisher.PublishMessage(message.ID, message.CampaignID, message.CustomerID)
+	}
+
+	report, err := verifier.Verify(context.Background(), drain, publish, *dryRun || jobs == *limit)
+	// Give back anything Verify did not get to
+	if closeErr := drain.Close(); closeErr != nil {
+		clitool.PrintError(fmt.Sprintf("Failed to requeue jobs: %v", closeErr))
+	}
+	if err != nil {
+		clitool.Fatal(fmt.Sprintf("Verification failed; every job was requeued: %v", err))
+	}
+
+	printReport(report)
+}
+
+// printReport prints the reconciliation summary
+func printReport(report *service.QueueVerifyReport) {
+	clitool.PrintInfo("\n=== Reconciliation Summary ===")
+	if report.DryRun {
+		clitool.PrintWarning("Dry run: nothing was moved or republished")
+	}
+	clitool.PrintInfo(fmt.Sprintf("Jobs in queue:            %d", report.Jobs))
+	clitool.PrintSuccess(fmt.Sprintf("✓ Jobs for pending messages: %d", report.Matched))
+	if len(report.Stale) > 0 {
+		clitool.PrintInfo(fmt.Sprintf("Jobs for messages no longer pending (left for the worker to skip): %d %v", len(report.Stale), report.Stale))
+	}
+
+	action := "moved to the poison queue"
+	if report.DryRun {
+		action = "would be moved to the poison queue"
+	}
+	if len(report.OrphanJobs) > 0 {
+		clitool.PrintWarning(fmt.Sprintf("⚠ Jobs for messages that do not exist, %s: %d %v", action, len(report.OrphanJobs), report.OrphanJobs))
+	}
+	if report.Undecodable > 0 {
+		clitool.PrintWarning(fmt.Sprintf("⚠ Jobs that are not message jobs, %s: %d", action, report.Undecodable))
+	}
+
+	action = "republished"
+	if report.DryRun {
+		action = "would be republished"
+	}
+	if len(report.OrphanRows) > 0 {
+		clitool.PrintWarning(fmt.Sprintf("⚠ Pending messages missing from the queue, %s: %d %v", action, len(report.OrphanRows), report.OrphanRows))
+	}
+
+	if report.Failed > 0 {
+		clitool.PrintError(fmt.Sprintf("✗ Fixes that failed (rerun to retry): %d", report.Failed))
+		os.Exit(1)
+	}
+	if len(report.OrphanJobs) == 0 && report.Undecodable == 0 && len(report.OrphanRows) == 0 {
+		clitool.PrintSuccess("\n✓ Queue and database agree")
+	}
+}
+
+func printUsage() {
+	clitool.PrintInfo("=== SMSLeopard Queue Verification ===\n")
+	fmt.Println("Usage: go run ./cmd/verify-queue [flags]")
+	fmt.Println("\nFlags:")
+	flag.PrintDefaults()
+	fmt.Println("\nExamples:")
+	fmt.Println("  go run ./cmd/verify-queue -dry-run")
+	fmt.Println("  go run ./cmd/verify-queue")
+	fmt.Println("  go run ./cmd/verify-queue -grace=30m")
+	fmt.Println("\nNotes:")
+	fmt.Println("  - Takes the waiting jobs off the queue unacknowledged, checks them against outbound_messages")
+	fmt.Println("    and requeues them; if the tool dies the broker requeues them itself")
+	fmt.Println("  - Jobs whose message does not exist are moved to <queue>.poison")
+	fmt.Println("  - Pending messages of sending campaigns published over -grace ago but not in the queue")
+	fmt.Println("    are republished; jobs being handled by a worker are not in the queue, hence the grace")
+	fmt.Println("  - Workers can keep running, but pick up nothing while the jobs are held")
+}
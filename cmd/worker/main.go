@@ -3,18 +3,28 @@ package main
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 
 	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/maintenance"
+	"smsleopard/internal/metrics"
+	"smsleopard/internal/middleware"
+	"smsleopard/internal/migrate"
 	"smsleopard/internal/models"
+	"smsleopard/internal/notify"
 	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
 	"smsleopard/internal/service"
 )
 
@@ -28,6 +38,10 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Tag every log line with this worker's identity, so interleaved logs from several
+	// workers can be told apart
+	log.SetPrefix("[" + cfg.Worker.ID + "] ")
+
 	// Connect to database
 	db, err := sql.Open("postgres", cfg.GetDatabaseDSN())
 	if err != nil {
@@ -35,223 +49,328 @@ func main() {
 	}
 	defer db.Close()
 
-	// Verify database connection
-	if err := db.Ping(); err != nil {
+	// Wait for the database, which may still be starting alongside the worker
+	startupCtx := context.Background()
+	if err := service.WaitUntilReady(startupCtx, "database", cfg.Worker.StartupHealthTimeout, cfg.Worker.HealthCheckInterval, db.PingContext); err != nil {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 	log.Println("✅ Connected to database")
 
+	// Apply pending migrations (optional); replicas wait on the advisory lock for the first
+	if cfg.Database.AutoMigrate {
+		applied, err := migrate.Up(db, cfg.Database.MigrationsDir)
+		if err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
+		log.Printf("✅ Migrations up to date (%d applied)", len(applied))
+	}
+
+	// Scheduling decisions use database time; warn when this server's clock has drifted from it
+	if _, err := service.CheckClockSkew(context.Background(), repository.NewClockRepository(db), cfg.Database.ClockSkewWarn); err != nil {
+		log.Printf("Warning: Failed to check clock skew: %v", err)
+	}
+
 	// Initialize services
-	templateSvc := service.NewTemplateService()
-	senderSvc := service.NewSenderService(0.95) // 95% success rate
+	templateSvc := service.NewTemplateServiceWithLimits(cfg.Limits)
+	templateSvc.SetSyntax(cfg.Template.Syntax)
+	senderSvc := service.NewSender(cfg.Worker, service.NewSenderService(0.95)) // 95% success rate
 	log.Println("✅ Services initialized")
+	if cfg.Worker.Mode == config.WorkerModeSimulate {
+		log.Printf("🧪 Simulate mode: messages are marked sent without calling any provider")
+	}
 
-	// Connect to RabbitMQ
+	// Connect to RabbitMQ, waiting for it like the database
 	rabbitmqURL := cfg.GetRabbitMQURL()
-	conn, err := queue.NewConnection(rabbitmqURL)
+	var conn *queue.Connection
+	err = service.WaitUntilReady(startupCtx, "RabbitMQ", cfg.Worker.StartupHealthTimeout, cfg.Worker.HealthCheckInterval, func(ctx context.Context) error {
+		connected, err := queue.NewConnection(rabbitmqURL)
+		conn = connected
+		return err
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
 	defer conn.Close()
 	log.Println("✅ Connected to RabbitMQ")
 
-	// Create message handler
-	handler := createMessageHandler(db, templateSvc, senderSvc)
+	// Wrap the connection with query timing (optional)
+	var store repository.Database = db
+	if cfg.Database.QueryLogging {
+		slowThreshold := time.Duration(cfg.Database.SlowQueryMs) * time.Millisecond
+		store = repository.NewInstrumentedDB(db, slowThreshold)
+		log.Printf("✅ Query logging enabled (slow threshold %s)", slowThreshold)
+	}
 
-	// Start consumer
-	queueName := "campaign_sends"
-	consumer, err := queue.NewConsumer(conn, queueName, handler)
+	// Lifecycle events for the data warehouse and/or webhook (optional)
+	events, err := notify.NewEventsFromConfig(cfg.Events, cfg.Notify)
 	if err != nil {
-		log.Fatalf("Failed to create consumer: %v", err)
+		log.Fatalf("Failed to create event sinks: %v", err)
+	}
+	if events != nil {
+		log.Printf("✅ Lifecycle events enabled (%s)", strings.Join(cfg.Events.Sinks, ", "))
 	}
 
-	err = consumer.Start()
+	// Sample connection pool usage, so pool exhaustion can be told apart from slow queries
+	go metrics.NewDBStatsSampler("primary", db).Run(context.Background(), metrics.DBStatsInterval)
+
+	// Create message handler, timing every call of the message and campaign repositories
+	messageRepo := repository.NewTimedMessageRepository(repository.NewEncryptedMessageRepository(store, nil, cfg.Encryption.Keyring))
+	campaignRepo := repository.NewTimedCampaignRepository(repository.NewCampaignRepository(store))
+	budget := service.NewAttemptBudget(messageRepo, cfg.Worker.DailyAttemptBudget)
+	processor := service.NewMessageProcessor(store, messageRepo, templateSvc, senderSvc, budget, events)
+	processor.SetContactWindowZone(cfg.Quiet.Location)
+	processor.SetCampaignBudget(service.NewCampaignBudget(campaignRepo, messageRepo, cfg.Sending))
+	processor.SetSuppressions(repository.NewSuppressionRepository(store))
+	// Defer every message while outbound sending is switched off (POST /admin/sending/disable)
+	sendingSwitch := service.NewSendingSwitch(repository.NewSettingsRepository(store))
+	processor.SetSendingSwitch(sendingSwitch)
+	processor.SetDuplicateSendWindow(cfg.Duplicate.SendWindow)
+	processor.SetProcessBySkewTolerance(cfg.Worker.ProcessBySkew)
+	if cfg.DemoOverridesEnabled() {
+		processor.SetDemoOverrides(true)
+		log.Printf("🎭 Demo overrides enabled: campaigns' demo_failure_rate replaces the mock sender's")
+	}
+	processor.SetLinkTracker(service.NewLinkTracker(repository.NewLinkRepository(store), cfg.Sending.LinkBaseURL))
+	if throttle := service.NewCarrierThrottle(cfg.Sending); throttle != nil {
+		processor.SetCarrierThrottle(throttle)
+		log.Printf("🚦 Carrier throttling enabled for %d prefix bucket(s)", len(cfg.Sending.PrefixLimits))
+	}
+	if cfg.Worker.Faults != nil {
+		processor.SetFaults(cfg.Worker.Faults)
+		log.Printf("💥 Fault injection enabled: %s", cfg.Worker.Faults)
+	}
+
+	// Record errors other than send failures for triage over their own connection,
+	// so a wedged or exhausted main pool does not stop them being written
+	errorsDB, err := sql.Open("postgres", cfg.GetDatabaseDSN())
 	if err != nil {
-		log.Fatalf("Failed to start consumer: %v", err)
+		log.Fatalf("Failed to open processing errors connection: %v", err)
+	}
+	defer errorsDB.Close()
+	errorsDB.SetMaxOpenConns(1)
+	go metrics.NewDBStatsSampler("processing_errors", errorsDB).Run(context.Background(), metrics.DBStatsInterval)
+	processor.SetProcessingErrors(service.NewProcessingErrorService(repository.NewProcessingErrorRepository(errorsDB), cfg.Worker.ID))
+
+	// Claim each message as this worker before handling it, so a redelivery picked up by
+	// another worker while this one is still at work is skipped instead of sent twice
+	workerRepo := repository.NewWorkerRepository(store)
+	processor.SetClaims(workerRepo, cfg.Worker.ID, cfg.Worker.ClaimTTL)
+	if released, err := workerRepo.ReleaseAll(context.Background(), cfg.Worker.ID); err != nil {
+		log.Printf("Warning: Failed to release claims left by an earlier run: %v", err)
+	} else if released > 0 {
+		log.Printf("♻️  Released %d claims left by an earlier run", released)
 	}
-	log.Printf("✅ Worker started, consuming from queue: %s", queueName)
 
-	// Graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	// Start consumer
+	queueName := "campaign_sends"
+	consumer, err := queue.NewConsumer(conn, queueName, processor.Handle)
+	if err != nil {
+		log.Fatalf("Failed to create consumer: %v", err)
+	}
 
-	log.Println("🛑 Shutting down gracefully...")
+	// Ordered campaigns each have a queue of their own, consumed one job at a time across workers
+	orderedConsumers := queue.NewOrderedConsumers(conn, queueName, processor.Handle)
 
-	// Stop consumer
-	if err := consumer.Stop(); err != nil {
-		log.Printf("Error stopping consumer: %v", err)
+	// Requeue jobs whose handler is stuck instead of holding them unacknowledged forever
+	watchdog := queue.NewWatchdog(cfg.Worker.AckDeadline, processor.HandleExpired)
+	consumer.SetWatchdog(watchdog)
+	orderedConsumers.SetWatchdog(watchdog)
+	if cfg.Worker.AckDeadline > 0 {
+		log.Printf("✅ Ack deadline: %s", cfg.Worker.AckDeadline)
 	}
 
-	// Close connections
-	conn.Close()
-	db.Close()
+	// Count the jobs being handled, so a drain before a deploy (SIGUSR1 or POST /admin/drain)
+	// can stop consuming, let them finish and only then exit
+	inFlight := queue.NewInFlight()
+	consumer.SetInFlight(inFlight)
+	orderedConsumers.SetInFlight(inFlight)
+	shutdownDrain := queue.NewShutdownDrain(inFlight, cfg.Worker.ShutdownDrainTimeout, consumer, orderedConsumers)
+
+	// After repeated infrastructure failures stop pulling messages until the database and
+	// broker are healthy again, instead of failing every message and burning its retries
+	health := service.NewWorkerHealth(cfg.Worker.DrainAfterFailures, cfg.Worker.HealthCheckInterval)
+	health.AddCheck("database", db.PingContext)
+	health.AddCheck("rabbitmq", func(ctx context.Context) error {
+		if !conn.IsConnected() {
+			return errors.New("connection closed")
+		}
+		return nil
+	})
+	processor.SetHealth(health)
+	if cfg.Worker.DrainAfterFailures > 0 {
+		log.Printf("✅ Drain after %d consecutive infrastructure failures", cfg.Worker.DrainAfterFailures)
+	}
 
-	log.Println("✅ Worker stopped")
-}
+	// In read-only mode stop pulling messages rather than failing and requeueing them
+	readOnly := maintenance.NewReadOnly(cfg.Server.ReadOnly)
+	if readOnly.Enabled() {
+		consumer.Pause()
+		orderedConsumers.Pause()
+		log.Printf("🔒 Read-only mode: consumption paused")
+	}
 
-// createMessageHandler creates the message processing handler
-func createMessageHandler(db *sql.DB, templateSvc *service.TemplateService, senderSvc *service.SenderService) queue.MessageHandler {
-	return func(job *queue.MessageJob) error {
-		ctx := context.Background()
+	// Consume only while neither read-only nor drained, and never again once a shutdown drain starts
+	paused := func() bool {
+		return readOnly.Enabled() || health.Draining() || shutdownDrain.Draining()
+	}
+	applyPaused := func() {
+		toggle, toggleOrdered := consumer.Resume, orderedConsumers.Resume
+		if paused() {
+			toggle, toggleOrdered = consumer.Pause, orderedConsumers.Pause
+		}
+		if err := toggle(); err != nil {
+			log.Printf("Warning: Failed to apply read-only %t, draining %t to consumer: %v", readOnly.Enabled(), health.Draining(), err)
+		}
+		if err := toggleOrdered(); err != nil {
+			log.Printf("Warning: Failed to apply read-only %t, draining %t to ordered consumers: %v", readOnly.Enabled(), health.Draining(), err)
+		}
+	}
+	readOnly.OnChange(func(enabled bool) { applyPaused() })
+	health.OnChange(func(draining bool) { applyPaused() })
 
-		log.Printf("📨 Processing message ID: %d", job.MessageID)
+	err = consumer.Start()
+	if err != nil {
+		log.Fatalf("Failed to start consumer: %v", err)
+	}
+	log.Printf("✅ Worker started, consuming from queue: %s", queueName)
 
-		// Fetch message with campaign and customer
-		message, campaign, customer, err := fetchMessageData(ctx, db, job.MessageID)
-		if err != nil {
-			log.Printf("❌ Failed to fetch message data: %v", err)
-			return err
-		}
+	// Consume the queues of ordered campaigns with messages left, deleting each once its
+	// campaign completes
+	orderedQueues := service.NewOrderedQueues(campaignRepo)
+	if err := orderedQueues.Sync(startupCtx, orderedConsumers.Sync); err != nil {
+		log.Printf("Warning: Failed to sync ordered campaign queues: %v", err)
+	}
 
-		// Check retry limit
-		if message.RetryCount >= 3 {
-			log.Printf("⚠️  Message ID %d exceeded retry limit, marking as permanently failed", job.MessageID)
-			if err := updateMessagePermanentFailure(ctx, db, job.MessageID); err != nil {
-				log.Printf("❌ Failed to update permanent failure: %v", err)
-			}
-			// Return nil to ACK and remove from queue
-			return nil
+	// Requeue messages deferred by the per-customer attempt budget or a customer contact window
+	requeueCtx, stopRequeue := context.WithCancel(context.Background())
+	defer stopRequeue()
+	// Check dependencies while drained, resuming consumption once they recover
+	go health.Run(requeueCtx)
+	// Report this worker alive for GET /admin/workers/{id}/activity
+	go service.NewWorkerActivityService(workerRepo).RunHeartbeat(requeueCtx, cfg.Worker.ID, service.WorkerHeartbeatInterval)
+	publisher, err := queue.NewPublisher(conn, queueName)
+	if err != nil {
+		log.Fatalf("Failed to create publisher: %v", err)
+	}
+	publish := func(message *models.OutboundMessage) error {
+		if orderedConsumers.Has(message.CampaignID) {
+			return publisher.PublishOrderedMessage(message.ID, message.CampaignID, message.CustomerID)
 		}
+		return publisher.PublishMessage(message.ID, message.CampaignID, message.CustomerID)
+	}
+	// Publish each later part of a multi-part send once the part before it is sent
+	processor.SetNextPartPublisher(publish)
+	budget.SetRequeuePaused(paused)
+	go budget.RunRequeue(requeueCtx, service.DeferredRequeueInterval, publish)
+
+	// Publish pending messages that never reached the broker (e.g. it was down during the send)
+	reconciler := service.NewPublishReconciler(messageRepo)
+	reconciler.SetPaused(paused)
+	reconciler.SetDispatchOrder(cfg.Dispatch)
+	eventRepo := repository.NewCampaignEventRepository(store)
+	reconciler.SetCampaignEvents(eventRepo)
+	// Finalize cancellations whose undo window has passed
+	reconciler.SetCancellations(campaignRepo)
+	// Spread each batch over the interval at no more than the send rate, rather than at once
+	pacer := service.NewPublishPacer(cfg.Pacing, service.EffectiveRatePerSecond(cfg.Sending))
+	reconciler.SetPacing(pacer, service.PublishReconcileInterval)
+	if pacer != nil {
+		log.Printf("✅ Publish pacing: %.1f jobs/s, ±%.0f%% jitter", pacer.Rate(), cfg.Pacing.Jitter*100)
+	}
+	go reconciler.Run(requeueCtx, service.PublishReconcileInterval, publish)
+	// Progress and completion events for the campaign events stream
+	progressReporter := service.NewCampaignProgressReporter(eventRepo)
+	progressReporter.SetSendRate(service.EffectiveRatePerSecond(cfg.Sending))
+	go progressReporter.Run(requeueCtx, service.CampaignProgressInterval)
+	go orderedQueues.Run(requeueCtx, service.OrderedQueueSyncInterval, orderedConsumers.Sync)
+	if cfg.Worker.DailyAttemptBudget > 0 {
+		log.Printf("✅ Customer attempt budget: %d per day", cfg.Worker.DailyAttemptBudget)
+	}
 
-		// Render template
-		rendered, err := templateSvc.Render(campaign.BaseTemplate, customer)
+	// Nightly digest of the previous day's sending activity (optional)
+	if cfg.Digest.Enabled() {
+		digestNotifier, err := notify.NewWebhookNotifier(cfg.Digest.WebhookURL)
 		if err != nil {
-			log.Printf("❌ Failed to render template: %v", err)
-			updateErr := updateMessageFailure(ctx, db, job.MessageID, err.Error())
-			if updateErr != nil {
-				log.Printf("❌ Failed to update message failure: %v", updateErr)
-			}
-			return err
+			log.Fatalf("Failed to create digest notifier: %v", err)
 		}
+		digestService := service.NewActivityDigestService(repository.NewDigestRepository(store), digestNotifier, cfg.Sending, cfg.Digest)
+		go digestService.Run(requeueCtx)
+		log.Printf("✅ Activity digest enabled (daily at %02d:00 %s)", cfg.Digest.SendHour, cfg.Digest.Location)
+	}
 
-		log.Printf("📝 Rendered message for customer %s: %s", customer.Phone, rendered)
+	// Periodic customer engagement scoring (optional; cmd/score-engagement runs it on demand)
+	if cfg.Engagement.Interval > 0 {
+		scorer := service.NewEngagementScorer(repository.NewEngagementRepository(store), cfg.Engagement)
+		go scorer.Run(requeueCtx, cfg.Engagement.Interval)
+		log.Printf("✅ Engagement scoring every %s", cfg.Engagement.Interval)
+	}
 
-		// Send message
-		result := senderSvc.Send(campaign.Channel, customer.Phone, rendered)
+	// Expose Prometheus metrics if enabled
+	if cfg.Metrics.WorkerPort != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler())
+
+			// Runtime read-only toggle, as on the API
+			readOnlyHandler := handler.NewReadOnlyHandler(readOnly)
+			mux.HandleFunc("GET /admin/read-only", readOnlyHandler.Get)
+			mux.Handle("POST /admin/read-only", middleware.RequireAdminKey(cfg.Admin.APIKey)(http.HandlerFunc(readOnlyHandler.Set)))
+
+			// The sending kill switch and its recent changes; it is toggled through the API
+			mux.HandleFunc("GET /admin/sending", handler.NewSendingHandler(sendingSwitch).Get)
+
+			// Drain before a deploy; /ready turns 503 as soon as the drain starts
+			drainHandler := handler.NewDrainHandler(shutdownDrain)
+			mux.HandleFunc("GET /ready", drainHandler.Ready)
+			mux.HandleFunc("GET /admin/drain", drainHandler.Get)
+			mux.Handle("POST /admin/drain", middleware.RequireAdminKey(cfg.Admin.APIKey)(http.HandlerFunc(drainHandler.Start)))
+			log.Printf("📊 Metrics available on :%s/metrics", cfg.Metrics.WorkerPort)
+			if err := http.ListenAndServe(":"+cfg.Metrics.WorkerPort, mux); err != nil {
+				log.Printf("Metrics server failed: %v", err)
+			}
+		}()
+	}
 
-		if result.Success {
-			// Update as sent
-			log.Printf("✅ Message sent successfully to %s (latency: %v)", customer.Phone, result.Latency)
-			if err := updateMessageSuccess(ctx, db, job.MessageID); err != nil {
-				log.Printf("❌ Failed to update message success: %v", err)
-				return err
+	// Graceful shutdown: SIGINT and SIGTERM stop at once, cutting short a drain under way;
+	// SIGUSR1 drains first, as does POST /admin/drain
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1)
+wait:
+	for {
+		select {
+		case sig := <-sigChan:
+			if sig != syscall.SIGUSR1 {
+				break wait
 			}
-			return nil
-		} else {
-			// Update as failed with retry
-			errMsg := result.Error.Error()
-			log.Printf("❌ Send failed for %s: %s (retry count: %d)", customer.Phone, errMsg, message.RetryCount+1)
-			if err := updateMessageFailure(ctx, db, job.MessageID, errMsg); err != nil {
-				log.Printf("❌ Failed to update message failure: %v", err)
+			if shutdownDrain.Start() {
+				log.Printf("🚰 Drain requested by SIGUSR1")
 			}
-			return fmt.Errorf("send failed: %s", errMsg)
+		case <-shutdownDrain.Done():
+			break wait
 		}
 	}
-}
-
-// fetchMessageData fetches message with campaign and customer
-func fetchMessageData(ctx context.Context, db *sql.DB, messageID int) (*models.OutboundMessage, *models.Campaign, *models.Customer, error) {
-	query := `
-		SELECT 
-			om.id, om.campaign_id, om.customer_id, om.status, 
-			om.rendered_content, om.retry_count, om.created_at, om.updated_at,
-			c.id, c.name, c.channel, c.status, c.base_template, c.scheduled_at, c.created_at, c.updated_at,
-			cust.id, cust.phone, cust.first_name, cust.last_name, cust.location, cust.preferred_product, cust.created_at
-		FROM outbound_messages om
-		JOIN campaigns c ON om.campaign_id = c.id
-		JOIN customers cust ON om.customer_id = cust.id
-		WHERE om.id = $1
-	`
-
-	var message models.OutboundMessage
-	var campaign models.Campaign
-	var customer models.Customer
-
-	err := db.QueryRowContext(ctx, query, messageID).Scan(
-		// OutboundMessage fields
-		&message.ID,
-		&message.CampaignID,
-		&message.CustomerID,
-		&message.Status,
-		&message.RenderedContent,
-		&message.RetryCount,
-		&message.CreatedAt,
-		&message.UpdatedAt,
-		// Campaign fields
-		&campaign.ID,
-		&campaign.Name,
-		&campaign.Channel,
-		&campaign.Status,
-		&campaign.BaseTemplate,
-		&campaign.ScheduledAt,
-		&campaign.CreatedAt,
-		&campaign.UpdatedAt,
-		// Customer fields
-		&customer.ID,
-		&customer.Phone,
-		&customer.FirstName,
-		&customer.LastName,
-		&customer.Location,
-		&customer.PreferredProduct,
-		&customer.CreatedAt,
-	)
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to fetch message data: %w", err)
-	}
-
-	return &message, &campaign, &customer, nil
-}
 
-// updateMessageSuccess updates message as sent
-func updateMessageSuccess(ctx context.Context, db *sql.DB, messageID int) error {
-	query := `
-		UPDATE outbound_messages 
-		SET status = 'sent', updated_at = NOW()
-		WHERE id = $1
-	`
+	log.Println("🛑 Shutting down gracefully...")
 
-	_, err := db.ExecContext(ctx, query, messageID)
-	if err != nil {
-		return fmt.Errorf("failed to update message success: %w", err)
+	// Stop requeueing, the digest and consuming; after a drain that timed out the handlers
+	// still running have had their jobs requeued, so they are not waited for
+	stopRequeue()
+	if summary := shutdownDrain.Summary(); summary == nil || !summary.TimedOut {
+		if err := consumer.Stop(); err != nil {
+			log.Printf("Error stopping consumer: %v", err)
+		}
+		orderedConsumers.Stop()
 	}
 
-	return nil
-}
-
-// updateMessageFailure updates message as failed with retry
-func updateMessageFailure(ctx context.Context, db *sql.DB, messageID int, errorMsg string) error {
-	query := `
-		UPDATE outbound_messages 
-		SET status = 'failed', 
-			retry_count = retry_count + 1,
-			last_error = $2,
-			updated_at = NOW()
-		WHERE id = $1
-	`
-
-	_, err := db.ExecContext(ctx, query, messageID, errorMsg)
-	if err != nil {
-		return fmt.Errorf("failed to update message failure: %w", err)
+	// Flush queued events
+	if err := events.Close(); err != nil {
+		log.Printf("Error closing event sinks: %v", err)
 	}
 
-	return nil
-}
-
-// updateMessagePermanentFailure marks message as permanently failed
-func updateMessagePermanentFailure(ctx context.Context, db *sql.DB, messageID int) error {
-	query := `
-		UPDATE outbound_messages 
-		SET status = 'failed',
-			last_error = 'Exceeded maximum retry attempts (3)',
-			updated_at = NOW()
-		WHERE id = $1
-	`
-
-	_, err := db.ExecContext(ctx, query, messageID)
-	if err != nil {
-		return fmt.Errorf("failed to update permanent failure: %w", err)
-	}
+	// Close connections
+	conn.Close()
+	errorsDB.Close()
+	db.Close()
 
-	return nil
+	log.Println("✅ Worker stopped")
 }
@@ -0,0 +1,57 @@
+package clitool
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+
+	"smsleopard/internal/config"
+)
+
+// LoadConfig loads the .env file (if present) and the application configuration
+func LoadConfig() (*config.Config, error) {
+	// Load .env file (ignore error if not present)
+	_ = godotenv.Load()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// OpenDatabase opens a PostgreSQL connection and verifies it with a ping
+func OpenDatabase(cfg *config.Config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.GetDatabaseDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+// Bootstrap loads configuration and connects to the database, printing progress
+// It is the common startup sequence shared by all command-line tools
+func Bootstrap() (*config.Config, *sql.DB, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	PrintInfo("Connecting to database...")
+	db, err := OpenDatabase(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	PrintSuccess("✓ Connected to database\n")
+
+	return cfg, db, nil
+}
@@ -0,0 +1,121 @@
+package clitool
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ANSI color codes for terminal output
+const (
+	ColorReset  = "\033[0m"
+	ColorRed    = "\033[31m"
+	ColorGreen  = "\033[32m"
+	ColorYellow = "\033[33m"
+	ColorCyan   = "\033[36m"
+	ColorBold   = "\033[1m"
+)
+
+// Printer writes colored status messages for command-line tools
+type Printer struct {
+	Out     io.Writer
+	Err     io.Writer
+	NoColor bool // Print plain text without ANSI codes
+	Quiet   bool // Suppress everything except errors
+}
+
+// NewPrinter creates a printer writing to stdout and stderr
+func NewPrinter() *Printer {
+	return &Printer{
+		Out: os.Stdout,
+		Err: os.Stderr,
+	}
+}
+
+// Colorize wraps text in the given color unless colors are disabled
+func (p *Printer) Colorize(color, text string) string {
+	if p.NoColor {
+		return text
+	}
+	return color + text + ColorReset
+}
+
+// Success prints a success message in green
+func (p *Printer) Success(msg string) {
+	if p.Quiet {
+		return
+	}
+	fmt.Fprintln(p.Out, p.Colorize(ColorGreen, msg))
+}
+
+// Error prints an error message in red (never suppressed by Quiet)
+func (p *Printer) Error(msg string) {
+	fmt.Fprintln(p.Err, p.Colorize(ColorRed, msg))
+}
+
+// Info prints an info message in cyan
+func (p *Printer) Info(msg string) {
+	if p.Quiet {
+		return
+	}
+	fmt.Fprintln(p.Out, p.Colorize(ColorCyan, msg))
+}
+
+// Warning prints a warning message in yellow
+func (p *Printer) Warning(msg string) {
+	if p.Quiet {
+		return
+	}
+	fmt.Fprintln(p.Out, p.Colorize(ColorYellow, msg))
+}
+
+// RegisterFlags registers the shared --no-color and --quiet flags on fs
+func (p *Printer) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&p.NoColor, "no-color", false, "Disable colored output")
+	fs.BoolVar(&p.Quiet, "quiet", false, "Only print errors")
+}
+
+// std is the printer used by the package-level helpers
+var std = NewPrinter()
+
+// Default returns the printer used by the package-level helpers
+func Default() *Printer {
+	return std
+}
+
+// RegisterFlags registers --no-color and --quiet for the default printer
+func RegisterFlags(fs *flag.FlagSet) {
+	std.RegisterFlags(fs)
+}
+
+// Colorize wraps text in the given color using the default printer settings
+func Colorize(color, text string) string {
+	return std.Colorize(color, text)
+}
+
+// PrintSuccess prints a success message with the default printer
+func PrintSuccess(msg string) {
+	std.Success(msg)
+}
+
+// PrintError prints an error message with the default printer
+func PrintError(msg string) {
+	std.Error(msg)
+}
+
+// PrintInfo prints an info message with the default printer
+func PrintInfo(msg string) {
+	std.Info(msg)
+}
+
+// PrintWarning prints a warning message with the default printer
+func PrintWarning(msg string) {
+	std.Warning(msg)
+}
+
+// Fatal prints an error message and exits with status 1
+func Fatal(msg string) {
+	std.Error(msg)
+	os.Exit(1)
+}
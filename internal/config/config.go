@@ -3,29 +3,70 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+
+	"smsleopard/internal/crypto"
+	"smsleopard/internal/faults"
+	"smsleopard/internal/models"
 )
 
 // Config holds all application configuration
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	RabbitMQ RabbitMQConfig
-	Env      string
+	Server       ServerConfig
+	Database     DatabaseConfig
+	RabbitMQ     RabbitMQConfig
+	Sending      SendingConfig
+	Worker       WorkerConfig
+	Metrics      MetricsConfig
+	Notify       NotifyConfig
+	Digest       DigestConfig
+	Events       EventsConfig
+	Approval     ApprovalConfig
+	Duplicate    DuplicateContentConfig
+	FrequencyCap FrequencyCapConfig
+	Quiet        QuietHoursConfig
+	Admin        AdminConfig
+	Auth         AuthConfig
+	Limits       LimitsConfig
+	Template     TemplateConfig
+	Encryption   EncryptionConfig
+	Export       ExportConfig
+	Backpressure BackpressureConfig
+	Dispatch     models.DispatchOrder // Order pending messages are pulled from the database in
+	Pacing       PublishPacingConfig
+	Engagement   EngagementConfig
+	Env          string
+
+	// CustomerRules are the deployment's own customer field rules (nil without any)
+	CustomerRules *CustomerRules
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Port string
+	Port     string
+	ReadOnly bool // Start refusing writes, and with the worker paused, for a maintenance window
 }
 
 // DatabaseConfig holds PostgreSQL configuration
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
+	Host       string
+	Port       string
+	User       string
+	Password   string
+	DBName     string
+	ReplicaDSN string // Read replica for reporting queries (primary used when empty)
+
+	QueryLogging bool // Time statements and log slow ones
+	SlowQueryMs  int  // Threshold above which a statement is logged as slow
+
+	AutoMigrate   bool   // Apply pending migrations at API/worker startup
+	MigrationsDir string // Directory the startup migrations are read from
+
+	ClockSkewWarn time.Duration // Log a startup warning when app and database clocks differ by more (0 disables)
 }
 
 // RabbitMQConfig holds RabbitMQ configuration
@@ -36,18 +77,279 @@ type RabbitMQConfig struct {
 	Password string
 }
 
+// SendingConfig holds message throughput and pricing settings
+type SendingConfig struct {
+	RateLimitPerSecond int     // Maximum messages dispatched per second
+	WorkerConcurrency  int     // Number of messages processed in parallel
+	AvgSendLatencyMs   int     // Average provider latency per message
+	CostPerSMS         float64 // Price of a single SMS message
+	CostPerWhatsApp    float64 // Price of a single WhatsApp message
+	PrefixLimits       []PrefixLimit
+	LinkBaseURL        string // Public URL of the API, which tracked links redirect through
+}
+
+// PrefixLimit paces sends to phone numbers starting with any of its prefixes, for carriers
+// that throttle their own throughput; the prefixes share one bucket
+type PrefixLimit struct {
+	Prefixes    []string // e.g. "+25470", matched against normalized phones
+	PerSecond   int      // Maximum sends per second to the bucket
+	MaxInFlight int      // Maximum concurrent sends to the bucket, 0 for no limit
+}
+
+// PublishPacingConfig holds how the publish reconciler spreads each batch of never-published
+// messages over its interval, rather than publishing the whole batch at once
+type PublishPacingConfig struct {
+	Enabled bool
+	Rate    float64 // Jobs published per second; 0 paces at the effective send rate, which also caps it
+	Jitter  float64 // Fraction of the even spacing each publish may move off its slot, below 0.5
+}
+
+// BackpressureConfig holds the backlog thresholds above which new sends are refused
+// A threshold of 0 is not checked
+type BackpressureConfig struct {
+	MaxQueueDepth  int           // Jobs waiting in the send queue
+	MaxUnpublished int           // Pending messages never published to the queue
+	RetryAfter     time.Duration // Suggested wait before retrying a refused send
+}
+
+// Worker modes
+const (
+	WorkerModeLive     = "live"
+	WorkerModeSimulate = "simulate"
+)
+
+// SenderProviderMock is the built-in mock provider live sends go through
+const SenderProviderMock = "mock"
+
+// DefaultAckDeadline is how long a job may be handled before it is requeued
+// It is well above a send's worst case: a provider call plus a few status updates
+const DefaultAckDeadline = 2 * time.Minute
+
+// DefaultClaimTTL is how long a worker's claim on a message blocks other workers if the
+// worker dies without releasing it; it outlasts the ack deadline so a slow send keeps its claim
+const DefaultClaimTTL = 5 * time.Minute
+
+// WorkerConfig holds message worker settings
+type WorkerConfig struct {
+	ID                       string           // Identity recorded on claims, heartbeats, processing errors and logs
+	Mode                     string           // live sends through the provider; simulate marks messages sent without sending
+	SenderProvider           string           // Provider live sends go through; only SenderProviderMock so far
+	SimulatedLatencyMs       int              // Mean latency of a simulated send
+	SimulatedLatencyJitterMs int              // Standard deviation of simulated latency
+	DailyAttemptBudget       int              // Send attempts per customer per UTC day before messages are deferred (0 disables)
+	Faults                   *faults.Injector // Development-only fault injection (nil unless FAULTS is set)
+	AckDeadline              time.Duration    // How long a job may be handled before it is requeued (0 disables)
+	ClaimTTL                 time.Duration    // How long an unreleased message claim blocks other workers
+
+	StartupHealthTimeout time.Duration // How long startup waits for the database and broker before giving up
+	DrainAfterFailures   int           // Consecutive infrastructure failures before consumption stops (0 disables)
+	HealthCheckInterval  time.Duration // How often dependencies are checked at startup and while drained
+	ShutdownDrainTimeout time.Duration // How long a shutdown drain waits for in-flight jobs before requeueing them
+	// ProcessBySkew is how long past a job's process_by deadline its message is still sent,
+	// allowing for the API's and worker's clocks disagreeing
+	ProcessBySkew time.Duration
+}
+
+// MetricsConfig holds Prometheus metrics settings
+type MetricsConfig struct {
+	WorkerPort string // Port for the worker's /metrics endpoint (disabled when empty)
+}
+
+// NotifyConfig holds operator notification settings
+type NotifyConfig struct {
+	WebhookURL string // Destination for ops digests (digests disabled when empty)
+}
+
+// DigestConfig holds nightly activity digest settings
+type DigestConfig struct {
+	WebhookURL string         // Destination for the digest (digest disabled when empty)
+	SendHour   int            // Hour (0-23) the previous day's digest is sent
+	Location   *time.Location // Time zone days and SendHour are read in
+}
+
+// Enabled reports whether the digest is sent
+func (d DigestConfig) Enabled() bool {
+	return d.WebhookURL != ""
+}
+
+// NextRun returns the first send time after t
+func (d DigestConfig) NextRun(t time.Time) time.Time {
+	return nextHour(t, d.SendHour, d.Location)
+}
+
+// Event sinks accepted in EVENT_SINK
+const (
+	EventSinkNone    = "none"
+	EventSinkKafka   = "kafka"
+	EventSinkWebhook = "webhook"
+)
+
+// EventsConfig holds campaign and message lifecycle event settings
+type EventsConfig struct {
+	Sinks           []string // Enabled sinks (events disabled when empty)
+	KafkaBrokers    []string
+	KafkaTopic      string
+	WebhookTemplate string // Go text/template for webhook event bodies (the default envelope when empty)
+}
+
+// ApprovalConfig holds send approval settings
+type ApprovalConfig struct {
+	RequiredAbove int // Sends to more customers than this wait for approval (0 disables)
+}
+
+// DuplicateContentConfig holds the check that stops the same content reaching the same audience twice
+type DuplicateContentConfig struct {
+	Window    time.Duration // How far back earlier sends of the same template and channel are looked for (0 disables)
+	Threshold float64       // Fraction of the audience that may already have the content before a send is blocked
+	// SendWindow is how recently a customer may have been sent the same content before the
+	// worker skips their message (0 disables)
+	SendWindow time.Duration
+}
+
+// EngagementConfig holds the weights of the customer engagement score and how it is computed
+// A customer's score is the sum over their sent messages of SentWeight halved for every
+// HalfLife since the send, less FailurePenalty per permanently failed message, counting
+// messages within Lookback
+type EngagementConfig struct {
+	SentWeight     float64       // Points a message is worth when just sent
+	HalfLife       time.Duration // Age at which a sent message is worth half its points
+	FailurePenalty float64       // Points taken off per permanently failed message
+	Lookback       time.Duration // How far back messages are scored
+	Interval       time.Duration // How often the worker rescores every customer (0 leaves it to cmd/score-engagement)
+	BatchSize      int           // Customers scored per batch
+}
+
+// FrequencyCapConfig holds the limit on how many messages a customer receives across all campaigns
+type FrequencyCapConfig struct {
+	MaxMessages int           // Sent messages a customer may receive per window before sends skip them (0 disables)
+	Window      time.Duration // How far back sent messages are counted
+}
+
+// QuietHoursConfig holds the hours during which customers should not be messaged
+type QuietHoursConfig struct {
+	Enabled  bool
+	Start    int // Hour quiet hours begin (0-23)
+	End      int // Hour quiet hours end (0-23, may be earlier than Start to wrap midnight)
+	Location *time.Location
+}
+
+// Contains reports whether t falls within quiet hours
+func (q QuietHoursConfig) Contains(t time.Time) bool {
+	if !q.Enabled {
+		return false
+	}
+
+	hour := t.In(q.Location).Hour()
+	if q.Start < q.End {
+		return hour >= q.Start && hour < q.End
+	}
+	return hour >= q.Start || hour < q.End
+}
+
+// NextOpen returns t if it is outside quiet hours, otherwise when the current quiet window ends
+func (q QuietHoursConfig) NextOpen(t time.Time) time.Time {
+	if !q.Contains(t) {
+		return t
+	}
+	return nextHour(t, q.End, q.Location)
+}
+
+// NextQuiet returns when the next quiet window starts after t (zero when quiet hours are disabled)
+func (q QuietHoursConfig) NextQuiet(t time.Time) time.Time {
+	if !q.Enabled {
+		return time.Time{}
+	}
+	return nextHour(t, q.Start, q.Location)
+}
+
+// nextHour returns the first time after t that the clock in loc reads hour:00
+func nextHour(t time.Time, hour int, loc *time.Location) time.Time {
+	local := t.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, 0, 0, 0, loc)
+	if !next.After(t) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, 0, 0, 0, loc)
+	}
+	return next
+}
+
+// AdminConfig holds admin endpoint settings
+type AdminConfig struct {
+	APIKey string // Key required in the X-Admin-Key header (admin endpoints disabled when empty)
+}
+
+// Caller roles
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// APIKey maps an API key to the caller it identifies
+type APIKey struct {
+	Key    string
+	UserID string
+	Role   string // admin or member
+	Team   string // Optional
+}
+
+// AuthConfig holds API key authentication settings
+type AuthConfig struct {
+	APIKeys []APIKey // Keys accepted in the X-API-Key header (authentication disabled when empty)
+}
+
+// ExportConfig holds background message export settings
+type ExportConfig struct {
+	Dir       string        // Directory generated export files are written to
+	Retention time.Duration // How long a finished export can be downloaded before it is deleted
+}
+
+// EncryptionConfig holds encryption at rest settings for message content
+type EncryptionConfig struct {
+	Keyring *crypto.Keyring // Encrypts rendered content (stored as plaintext when nil)
+}
+
+// TemplateConfig holds template placeholder settings
+type TemplateConfig struct {
+	Syntax models.TemplateSyntax // Placeholder style of campaigns that do not declare one
+}
+
+// Customer field overflow policies
+const (
+	OverflowReject   = "reject"
+	OverflowTruncate = "truncate"
+)
+
+// LimitsConfig holds content length limits, counted in characters
+type LimitsConfig struct {
+	MaxTemplateLength      int    // Longest base_template accepted
+	MaxCustomerFieldLength int    // Longest customer string field accepted
+	CustomerFieldOverflow  string // What to do with longer customer fields: reject or truncate
+	MaxRenderedLength      int    // Longer rendered messages fail permanently instead of sending
+	MaxCustomerExportRows  int    // Most customers one CSV export may contain
+	LookupChunkSize        int    // Customer IDs or phones looked up per query
+}
+
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
 	config := &Config{
 		Server: ServerConfig{
-			Port: getEnv("PORT", "8080"),
+			Port:     getEnv("PORT", "8080"),
+			ReadOnly: getEnvAsBool("READ_ONLY", false),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("POSTGRES_HOST", "localhost"),
-			Port:     getEnv("POSTGRES_PORT", "5432"),
-			User:     getEnv("POSTGRES_USER", "smsleopard"),
-			Password: getEnv("POSTGRES_PASSWORD", ""),
-			DBName:   getEnv("POSTGRES_DB", "smsleopard_db"),
+			Host:       getEnv("POSTGRES_HOST", "localhost"),
+			Port:       getEnv("POSTGRES_PORT", "5432"),
+			User:       getEnv("POSTGRES_USER", "smsleopard"),
+			Password:   getEnv("POSTGRES_PASSWORD", ""),
+			DBName:     getEnv("POSTGRES_DB", "smsleopard_db"),
+			ReplicaDSN: getEnv("DATABASE_REPLICA_DSN", ""),
+
+			QueryLogging: getEnvAsBool("QUERY_LOGGING_ENABLED", false),
+			SlowQueryMs:  getEnvAsInt("SLOW_QUERY_MS", 200),
+
+			AutoMigrate:   getEnvAsBool("AUTO_MIGRATE", false),
+			MigrationsDir: getEnv("MIGRATIONS_DIR", "migrations"),
+
+			ClockSkewWarn: getEnvAsDuration("CLOCK_SKEW_WARN_THRESHOLD", 5*time.Second),
 		},
 		RabbitMQ: RabbitMQConfig{
 			Host:     getEnv("RABBITMQ_HOST", "localhost"),
@@ -55,6 +357,92 @@ func Load() (*Config, error) {
 			User:     getEnv("RABBITMQ_DEFAULT_USER", "guest"),
 			Password: getEnv("RABBITMQ_DEFAULT_PASS", "guest"),
 		},
+		Sending: SendingConfig{
+			RateLimitPerSecond: getEnvAsInt("SEND_RATE_LIMIT_PER_SECOND", 10),
+			WorkerConcurrency:  getEnvAsInt("WORKER_CONCURRENCY", 1),
+			AvgSendLatencyMs:   getEnvAsInt("AVG_SEND_LATENCY_MS", 125),
+			CostPerSMS:         getEnvAsFloat("COST_PER_SMS", 0.80),
+			CostPerWhatsApp:    getEnvAsFloat("COST_PER_WHATSAPP", 0.50),
+			LinkBaseURL:        getEnv("LINK_TRACKING_BASE_URL", "http://localhost:8080"),
+		},
+		Worker: WorkerConfig{
+			ID:                       getEnv("WORKER_ID", defaultWorkerID()),
+			Mode:                     getEnv("WORKER_MODE", WorkerModeLive),
+			SenderProvider:           getEnv("SENDER_PROVIDER", SenderProviderMock),
+			SimulatedLatencyMs:       getEnvAsInt("SIMULATED_LATENCY_MS", 125),
+			SimulatedLatencyJitterMs: getEnvAsInt("SIMULATED_LATENCY_JITTER_MS", 40),
+			DailyAttemptBudget:       getEnvAsInt("CUSTOMER_DAILY_ATTEMPT_BUDGET", 5),
+			AckDeadline:              getEnvAsDuration("WORKER_ACK_DEADLINE", DefaultAckDeadline),
+			ClaimTTL:                 getEnvAsDuration("WORKER_CLAIM_TTL", DefaultClaimTTL),
+
+			StartupHealthTimeout: getEnvAsDuration("WORKER_STARTUP_HEALTH_TIMEOUT", 2*time.Minute),
+			DrainAfterFailures:   getEnvAsInt("WORKER_DRAIN_AFTER_FAILURES", 10),
+			HealthCheckInterval:  getEnvAsDuration("WORKER_HEALTH_CHECK_INTERVAL", 5*time.Second),
+			ShutdownDrainTimeout: getEnvAsDuration("WORKER_SHUTDOWN_DRAIN_TIMEOUT", 30*time.Second),
+			ProcessBySkew:        getEnvAsDuration("WORKER_PROCESS_BY_SKEW_TOLERANCE", 5*time.Second),
+		},
+		Metrics: MetricsConfig{
+			WorkerPort: getEnv("WORKER_METRICS_PORT", ""),
+		},
+		Notify: NotifyConfig{
+			WebhookURL: getEnv("NOTIFY_WEBHOOK_URL", ""),
+		},
+		Digest: DigestConfig{
+			WebhookURL: getEnv("DIGEST_WEBHOOK_URL", ""),
+			SendHour:   getEnvAsInt("DIGEST_SEND_HOUR", 7),
+		},
+		Approval: ApprovalConfig{
+			RequiredAbove: getEnvAsInt("APPROVAL_REQUIRED_ABOVE", 50000),
+		},
+		Duplicate: DuplicateContentConfig{
+			Window:     getEnvAsDuration("DUPLICATE_CONTENT_WINDOW", 24*time.Hour),
+			Threshold:  getEnvAsFloat("DUPLICATE_CONTENT_THRESHOLD", 0.1),
+			SendWindow: getEnvAsDuration("DUPLICATE_SEND_WINDOW", 6*time.Hour),
+		},
+		FrequencyCap: FrequencyCapConfig{
+			MaxMessages: getEnvAsInt("FREQUENCY_CAP_MAX_MESSAGES", 2),
+			Window:      time.Duration(getEnvAsInt("FREQUENCY_CAP_WINDOW_DAYS", 7)) * 24 * time.Hour,
+		},
+		Admin: AdminConfig{
+			APIKey: getEnv("ADMIN_API_KEY", ""),
+		},
+		Limits: LimitsConfig{
+			MaxTemplateLength:      getEnvAsInt("MAX_TEMPLATE_LENGTH", 2000),
+			MaxCustomerFieldLength: getEnvAsInt("MAX_CUSTOMER_FIELD_LENGTH", 255),
+			CustomerFieldOverflow:  getEnv("CUSTOMER_FIELD_OVERFLOW", OverflowReject),
+			MaxRenderedLength:      getEnvAsInt("MAX_RENDERED_LENGTH", 1600),
+			MaxCustomerExportRows:  getEnvAsInt("MAX_CUSTOMER_EXPORT_ROWS", 1000000),
+			LookupChunkSize:        getEnvAsInt("LOOKUP_CHUNK_SIZE", 1000),
+		},
+		Template: TemplateConfig{
+			Syntax: models.TemplateSyntax(getEnv("TEMPLATE_SYNTAX", string(models.TemplateSyntaxBraces))),
+		},
+		Export: ExportConfig{
+			Dir:       getEnv("EXPORT_DIR", filepath.Join(os.TempDir(), "smsleopard-exports")),
+			Retention: getEnvAsDuration("EXPORT_RETENTION", 24*time.Hour),
+		},
+		Backpressure: BackpressureConfig{
+			MaxQueueDepth:  getEnvAsInt("QUEUE_SATURATION_MAX_DEPTH", 200000),
+			MaxUnpublished: getEnvAsInt("QUEUE_SATURATION_MAX_UNPUBLISHED", 50000),
+			RetryAfter:     getEnvAsDuration("QUEUE_SATURATION_RETRY_AFTER", 5*time.Minute),
+		},
+		Dispatch: models.DispatchOrder{
+			Policy:    models.DispatchPolicy(getEnv("DISPATCH_POLICY", string(models.DispatchFIFO))),
+			FairShare: getEnvAsInt("DISPATCH_FAIR_SHARE", 10),
+		},
+		Pacing: PublishPacingConfig{
+			Enabled: getEnvAsBool("PUBLISH_PACING_ENABLED", true),
+			Rate:    getEnvAsFloat("PUBLISH_PACING_RATE", 0),
+			Jitter:  getEnvAsFloat("PUBLISH_PACING_JITTER", 0.2),
+		},
+		Engagement: EngagementConfig{
+			SentWeight:     getEnvAsFloat("ENGAGEMENT_SENT_WEIGHT", 1),
+			HalfLife:       getEnvAsDuration("ENGAGEMENT_HALF_LIFE", 30*24*time.Hour),
+			FailurePenalty: getEnvAsFloat("ENGAGEMENT_FAILURE_PENALTY", 2),
+			Lookback:       getEnvAsDuration("ENGAGEMENT_LOOKBACK", 180*24*time.Hour),
+			Interval:       getEnvAsDuration("ENGAGEMENT_SCORE_INTERVAL", 0),
+			BatchSize:      getEnvAsInt("ENGAGEMENT_BATCH_SIZE", 500),
+		},
 		Env: getEnv("ENV", "development"),
 	}
 
@@ -62,6 +450,149 @@ func Load() (*Config, error) {
 	if config.Database.Password == "" {
 		return nil, fmt.Errorf("POSTGRES_PASSWORD is required")
 	}
+	if mode := config.Worker.Mode; mode != WorkerModeLive && mode != WorkerModeSimulate {
+		return nil, fmt.Errorf("WORKER_MODE must be %q or %q", WorkerModeLive, WorkerModeSimulate)
+	}
+	if config.Worker.SenderProvider != SenderProviderMock {
+		return nil, fmt.Errorf("SENDER_PROVIDER must be %q", SenderProviderMock)
+	}
+	if config.Worker.DailyAttemptBudget < 0 {
+		return nil, fmt.Errorf("CUSTOMER_DAILY_ATTEMPT_BUDGET cannot be negative")
+	}
+	if config.Worker.StartupHealthTimeout < 0 {
+		return nil, fmt.Errorf("WORKER_STARTUP_HEALTH_TIMEOUT cannot be negative")
+	}
+	if config.Worker.DrainAfterFailures < 0 {
+		return nil, fmt.Errorf("WORKER_DRAIN_AFTER_FAILURES cannot be negative")
+	}
+	if config.Worker.HealthCheckInterval <= 0 {
+		return nil, fmt.Errorf("WORKER_HEALTH_CHECK_INTERVAL must be positive")
+	}
+	if config.Worker.ShutdownDrainTimeout <= 0 {
+		return nil, fmt.Errorf("WORKER_SHUTDOWN_DRAIN_TIMEOUT must be positive")
+	}
+	if config.Worker.ProcessBySkew < 0 {
+		return nil, fmt.Errorf("WORKER_PROCESS_BY_SKEW_TOLERANCE cannot be negative")
+	}
+	if config.Database.ClockSkewWarn < 0 {
+		return nil, fmt.Errorf("CLOCK_SKEW_WARN_THRESHOLD cannot be negative")
+	}
+	if config.Worker.AckDeadline < 0 {
+		return nil, fmt.Errorf("WORKER_ACK_DEADLINE cannot be negative")
+	}
+	if config.Worker.ClaimTTL <= 0 {
+		return nil, fmt.Errorf("WORKER_CLAIM_TTL must be positive")
+	}
+	if len(config.Worker.ID) > 255 {
+		return nil, fmt.Errorf("WORKER_ID cannot be longer than 255 characters")
+	}
+	if config.Duplicate.Window < 0 {
+		return nil, fmt.Errorf("DUPLICATE_CONTENT_WINDOW cannot be negative")
+	}
+	if threshold := config.Duplicate.Threshold; threshold < 0 || threshold > 1 {
+		return nil, fmt.Errorf("DUPLICATE_CONTENT_THRESHOLD must be between 0 and 1")
+	}
+	if config.Duplicate.SendWindow < 0 {
+		return nil, fmt.Errorf("DUPLICATE_SEND_WINDOW cannot be negative")
+	}
+	if config.Limits.LookupChunkSize <= 0 {
+		return nil, fmt.Errorf("LOOKUP_CHUNK_SIZE must be positive")
+	}
+	if !config.Template.Syntax.IsValid() {
+		return nil, fmt.Errorf("TEMPLATE_SYNTAX must be braces, double_braces, brackets or percent")
+	}
+	if config.Export.Retention <= 0 {
+		return nil, fmt.Errorf("EXPORT_RETENTION must be positive")
+	}
+	if config.Backpressure.MaxQueueDepth < 0 {
+		return nil, fmt.Errorf("QUEUE_SATURATION_MAX_DEPTH cannot be negative")
+	}
+	if config.Backpressure.MaxUnpublished < 0 {
+		return nil, fmt.Errorf("QUEUE_SATURATION_MAX_UNPUBLISHED cannot be negative")
+	}
+	if config.Backpressure.RetryAfter <= 0 {
+		return nil, fmt.Errorf("QUEUE_SATURATION_RETRY_AFTER must be positive")
+	}
+	if !config.Dispatch.Policy.IsValid() {
+		return nil, fmt.Errorf("DISPATCH_POLICY must be fifo, fair or newest_campaign_first")
+	}
+	if config.Dispatch.FairShare <= 0 {
+		return nil, fmt.Errorf("DISPATCH_FAIR_SHARE must be positive")
+	}
+	if config.Pacing.Rate < 0 {
+		return nil, fmt.Errorf("PUBLISH_PACING_RATE cannot be negative")
+	}
+	if jitter := config.Pacing.Jitter; jitter < 0 || jitter >= 0.5 {
+		return nil, fmt.Errorf("PUBLISH_PACING_JITTER must be at least 0 and below 0.5")
+	}
+	if config.Engagement.SentWeight < 0 || config.Engagement.FailurePenalty < 0 {
+		return nil, fmt.Errorf("ENGAGEMENT_SENT_WEIGHT and ENGAGEMENT_FAILURE_PENALTY cannot be negative")
+	}
+	if config.Engagement.HalfLife <= 0 || config.Engagement.Lookback <= 0 {
+		return nil, fmt.Errorf("ENGAGEMENT_HALF_LIFE and ENGAGEMENT_LOOKBACK must be positive")
+	}
+	if config.Engagement.Interval < 0 {
+		return nil, fmt.Errorf("ENGAGEMENT_SCORE_INTERVAL cannot be negative")
+	}
+	if config.Engagement.BatchSize <= 0 {
+		return nil, fmt.Errorf("ENGAGEMENT_BATCH_SIZE must be positive")
+	}
+	if config.FrequencyCap.MaxMessages < 0 {
+		return nil, fmt.Errorf("FREQUENCY_CAP_MAX_MESSAGES cannot be negative")
+	}
+	if config.FrequencyCap.MaxMessages > 0 && config.FrequencyCap.Window <= 0 {
+		return nil, fmt.Errorf("FREQUENCY_CAP_WINDOW_DAYS must be positive")
+	}
+	injector, err := faults.Parse(getEnv("FAULTS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("FAULTS is invalid: %w", err)
+	}
+	if injector != nil && config.Env == "production" {
+		return nil, fmt.Errorf("FAULTS cannot be set in production")
+	}
+	config.Worker.Faults = injector
+	quiet, err := parseQuietHours(getEnv("QUIET_HOURS", ""), getEnv("QUIET_HOURS_TZ", "UTC"))
+	if err != nil {
+		return nil, err
+	}
+	config.Quiet = quiet
+	if hour := config.Digest.SendHour; hour < 0 || hour > 23 {
+		return nil, fmt.Errorf("DIGEST_SEND_HOUR must be an hour from 0 to 23")
+	}
+	config.Digest.Location, err = time.LoadLocation(getEnv("DIGEST_TZ", "UTC"))
+	if err != nil {
+		return nil, fmt.Errorf("DIGEST_TZ is invalid: %w", err)
+	}
+	config.Sending.PrefixLimits, err = parsePrefixLimits(getEnv("SEND_RATE_LIMITS_BY_PREFIX", ""))
+	if err != nil {
+		return nil, fmt.Errorf("SEND_RATE_LIMITS_BY_PREFIX is invalid: %w", err)
+	}
+	apiKeys, err := parseAPIKeys(getEnv("API_KEYS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("API_KEYS is invalid: %w", err)
+	}
+	config.Auth.APIKeys = apiKeys
+	keyring, err := parseEncryptionKeys(getEnv("MESSAGE_ENCRYPTION_KEY", ""), getEnv("MESSAGE_ENCRYPTION_OLD_KEYS", ""))
+	if err != nil {
+		return nil, err
+	}
+	config.Encryption.Keyring = keyring
+	events, err := parseEvents(getEnv("EVENT_SINK", EventSinkNone), getEnv("KAFKA_BROKERS", ""), getEnv("KAFKA_TOPIC", "smsleopard.events"), config.Notify.WebhookURL)
+	if err != nil {
+		return nil, err
+	}
+	events.WebhookTemplate = getEnv("EVENT_WEBHOOK_TEMPLATE", "")
+	if events.WebhookTemplate != "" && !slices.Contains(events.Sinks, EventSinkWebhook) {
+		return nil, fmt.Errorf("EVENT_WEBHOOK_TEMPLATE requires EVENT_SINK=webhook")
+	}
+	config.Events = events
+	if overflow := config.Limits.CustomerFieldOverflow; overflow != OverflowReject && overflow != OverflowTruncate {
+		return nil, fmt.Errorf("CUSTOMER_FIELD_OVERFLOW must be %q or %q", OverflowReject, OverflowTruncate)
+	}
+	config.CustomerRules, err = loadCustomerRules(getEnv("CUSTOMER_VALIDATION_RULES", ""), getEnv("CUSTOMER_VALIDATION_RULES_FILE", ""))
+	if err != nil {
+		return nil, err
+	}
 
 	return config, nil
 }
@@ -94,7 +625,153 @@ func (c *Config) IsDevelopment() bool {
 	return c.Env == "development"
 }
 
+// DemoOverridesEnabled returns true if campaigns' demo_failure_rate may change how the
+// mock sender behaves: never in production, and never with a real provider
+func (c *Config) DemoOverridesEnabled() bool {
+	return c.Worker.SenderProvider == SenderProviderMock && c.Env != "production"
+}
+
+// parseQuietHours parses a start-end hour range such as "21-8" in the given time zone
+func parseQuietHours(value, zone string) (QuietHoursConfig, error) {
+	quiet := QuietHoursConfig{Location: time.UTC}
+	if value == "" {
+		return quiet, nil
+	}
+
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return quiet, fmt.Errorf("QUIET_HOURS_TZ is invalid: %w", err)
+	}
+
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return quiet, fmt.Errorf("QUIET_HOURS must be start-end hours, e.g. 21-8")
+	}
+	start, startErr := strconv.Atoi(strings.TrimSpace(parts[0]))
+	end, endErr := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if startErr != nil || endErr != nil || start < 0 || start > 23 || end < 0 || end > 23 || start == end {
+		return quiet, fmt.Errorf("QUIET_HOURS must be two different hours from 0 to 23, e.g. 21-8")
+	}
+
+	return QuietHoursConfig{Enabled: true, Start: start, End: end, Location: location}, nil
+}
+
+// parseAPIKeys parses comma-separated key:user:role[:team] entries
+func parseAPIKeys(value string) ([]APIKey, error) {
+	keys := []APIKey{}
+	seen := map[string]bool{}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 3 || len(parts) > 4 {
+			return nil, fmt.Errorf("entry %d must be key:user:role[:team]", len(keys)+1)
+		}
+
+		key := APIKey{Key: parts[0], UserID: parts[1], Role: parts[2]}
+		if len(parts) == 4 {
+			key.Team = parts[3]
+		}
+
+		if key.Key == "" || key.UserID == "" {
+			return nil, fmt.Errorf("entry %d has an empty key or user", len(keys)+1)
+		}
+		if key.Role != RoleAdmin && key.Role != RoleMember {
+			return nil, fmt.Errorf("entry %d role must be %q or %q", len(keys)+1, RoleAdmin, RoleMember)
+		}
+		if seen[key.Key] {
+			return nil, fmt.Errorf("entry %d repeats an earlier key", len(keys)+1)
+		}
+		seen[key.Key] = true
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// parsePrefixLimits parses semicolon-separated "prefix[,prefix...]: N/s [max M]" entries,
+// e.g. "+25470,+25471: 50/s; +25473: 20/s max 5"
+func parsePrefixLimits(value string) ([]PrefixLimit, error) {
+	limits := []PrefixLimit{}
+	seen := map[string]bool{}
+
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		n := len(limits) + 1
+
+		prefixes, rate, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("entry %d must be prefix[,prefix...]: N/s [max M]", n)
+		}
+
+		limit := PrefixLimit{}
+		for _, prefix := range strings.Split(prefixes, ",") {
+			prefix = strings.TrimSpace(prefix)
+			if !validPhonePrefix(prefix) {
+				return nil, fmt.Errorf("entry %d prefix %q must be + followed by 1 to 15 digits", n, prefix)
+			}
+			if seen[prefix] {
+				return nil, fmt.Errorf("entry %d repeats prefix %s", n, prefix)
+			}
+			seen[prefix] = true
+			limit.Prefixes = append(limit.Prefixes, prefix)
+		}
+
+		fields := strings.Fields(rate)
+		if len(fields) != 1 && len(fields) != 3 {
+			return nil, fmt.Errorf("entry %d must be prefix[,prefix...]: N/s [max M]", n)
+		}
+		perSecond, err := strconv.Atoi(strings.TrimSuffix(fields[0], "/s"))
+		if err != nil || !strings.HasSuffix(fields[0], "/s") || perSecond <= 0 {
+			return nil, fmt.Errorf("entry %d rate %q must be a positive number per second, e.g. 20/s", n, fields[0])
+		}
+		limit.PerSecond = perSecond
+
+		if len(fields) == 3 {
+			maxInFlight, err := strconv.Atoi(fields[2])
+			if fields[1] != "max" || err != nil || maxInFlight <= 0 {
+				return nil, fmt.Errorf("entry %d must end in max M with M a positive number", n)
+			}
+			limit.MaxInFlight = maxInFlight
+		}
+
+		limits = append(limits, limit)
+	}
+
+	return limits, nil
+}
+
+// validPhonePrefix reports whether prefix is + followed by 1 to 15 digits
+func validPhonePrefix(prefix string) bool {
+	if len(prefix) < 2 || len(prefix) > 16 || prefix[0] != '+' {
+		return false
+	}
+	for _, r := range prefix[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // getEnv gets environment variable or returns default
+// defaultWorkerID identifies a worker by host and process when WORKER_ID is not set
+func defaultWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -111,3 +788,105 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsFloat gets environment variable as float or returns default
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsBool gets environment variable as boolean or returns default
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsDuration gets environment variable as a duration such as "24h" or returns default
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if durationValue, err := time.ParseDuration(value); err == nil {
+			return durationValue
+		}
+	}
+	return defaultValue
+}
+
+// parseEncryptionKeys builds the message keyring from the current key and comma-separated old keys
+// Each key is <key id>:<base64 32-byte key>; old keys are only used to decrypt
+func parseEncryptionKeys(current, old string) (*crypto.Keyring, error) {
+	if strings.TrimSpace(current) == "" {
+		if strings.TrimSpace(old) != "" {
+			return nil, fmt.Errorf("MESSAGE_ENCRYPTION_OLD_KEYS requires MESSAGE_ENCRYPTION_KEY")
+		}
+		return nil, nil
+	}
+
+	currentKey, err := crypto.ParseKey(current)
+	if err != nil {
+		return nil, fmt.Errorf("MESSAGE_ENCRYPTION_KEY is invalid: %w", err)
+	}
+
+	oldKeys := []crypto.Key{}
+	for _, spec := range strings.Split(old, ",") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		key, err := crypto.ParseKey(spec)
+		if err != nil {
+			return nil, fmt.Errorf("MESSAGE_ENCRYPTION_OLD_KEYS is invalid: %w", err)
+		}
+		oldKeys = append(oldKeys, key)
+	}
+
+	keyring, err := crypto.NewKeyring(currentKey, oldKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("message encryption keys are invalid: %w", err)
+	}
+	return keyring, nil
+}
+
+// parseEvents reads the comma-separated EVENT_SINK list and checks each sink is configured
+// "none" (or an empty list) disables events; kafka and webhook may both be enabled
+func parseEvents(sinks, brokers, topic, webhookURL string) (EventsConfig, error) {
+	events := EventsConfig{KafkaTopic: strings.TrimSpace(topic)}
+	for _, broker := range strings.Split(brokers, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			events.KafkaBrokers = append(events.KafkaBrokers, broker)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, sink := range strings.Split(sinks, ",") {
+		sink = strings.ToLower(strings.TrimSpace(sink))
+		if sink == "" || sink == EventSinkNone || seen[sink] {
+			continue
+		}
+		switch sink {
+		case EventSinkKafka:
+			if len(events.KafkaBrokers) == 0 {
+				return EventsConfig{}, fmt.Errorf("EVENT_SINK=kafka requires KAFKA_BROKERS")
+			}
+			if events.KafkaTopic == "" {
+				return EventsConfig{}, fmt.Errorf("EVENT_SINK=kafka requires KAFKA_TOPIC")
+			}
+		case EventSinkWebhook:
+			if webhookURL == "" {
+				return EventsConfig{}, fmt.Errorf("EVENT_SINK=webhook requires NOTIFY_WEBHOOK_URL")
+			}
+		default:
+			return EventsConfig{}, fmt.Errorf("EVENT_SINK entries must be %q, %q or %q", EventSinkNone, EventSinkKafka, EventSinkWebhook)
+		}
+		seen[sink] = true
+		events.Sinks = append(events.Sinks, sink)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"smsleopard/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Customer rule names, as reported in violations
+const (
+	CustomerRuleRequired  = "required"
+	CustomerRuleMaxLength = "max_length"
+	CustomerRuleAllowed   = "allowed"
+	CustomerRuleForbidden = "forbidden"
+	CustomerRulePattern   = "pattern"
+)
+
+// customerRuleFields are the customer fields rules may be declared for, in the order
+// violations are reported
+var customerRuleFields = []string{"phone", "first_name", "last_name", "location", "preferred_product"}
+
+// CustomerRules are a deployment's own requirements on customer fields, checked wherever
+// customers are created, updated or imported
+type CustomerRules struct {
+	Fields map[string]*CustomerFieldRule `yaml:"fields"`
+}
+
+// CustomerFieldRule declares the requirements on one customer field
+// Allowed and forbidden values are compared ignoring case; only required applies to a
+// field that is missing or blank
+type CustomerFieldRule struct {
+	Required  bool     `yaml:"required"`
+	MaxLength int      `yaml:"max_length"` // Characters; 0 is no limit
+	Allowed   []string `yaml:"allowed"`
+	Forbidden []string `yaml:"forbidden"`
+	Pattern   string   `yaml:"pattern"` // Regular expression the value must match
+
+	pattern *regexp.Regexp
+}
+
+// CustomerRuleViolation is a customer field breaking one of the configured rules
+type CustomerRuleViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ParseCustomerRules parses YAML (or JSON) customer rules such as
+//
+//	fields:
+//	  last_name: {required: true}
+//	  location: {forbidden: [Mars], max_length: 50}
+//
+// Unknown fields or rules, negative lengths and invalid patterns are errors
+func ParseCustomerRules(data []byte) (*CustomerRules, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	rules := &CustomerRules{}
+	if err := decoder.Decode(rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	for name, rule := range rules.Fields {
+		if !isCustomerRuleField(name) {
+			return nil, fmt.Errorf("unknown field %q: rules can be set for %s", name, strings.Join(customerRuleFields, ", "))
+		}
+		if rule == nil {
+			return nil, fmt.Errorf("fields.%s has no rules", name)
+		}
+		if rule.MaxLength < 0 {
+			return nil, fmt.Errorf("fields.%s.max_length cannot be negative", name)
+		}
+		if len(rule.Allowed) > 0 && len(rule.Forbidden) > 0 {
+			return nil, fmt.Errorf("fields.%s sets both allowed and forbidden; use one", name)
+		}
+		for _, value := range append(rule.Allowed, rule.Forbidden...) {
+			if strings.TrimSpace(value) == "" {
+				return nil, fmt.Errorf("fields.%s lists an empty value", name)
+			}
+		}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("fields.%s.pattern is invalid: %w", name, err)
+			}
+			rule.pattern = pattern
+		}
+	}
+
+	return rules, nil
+}
+
+// isCustomerRuleField reports whether rules may be declared for the field
+func isCustomerRuleField(name string) bool {
+	for _, field := range customerRuleFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns every rule the customer breaks, in field order; nil rules allow anything
+func (r *CustomerRules) Check(customer *models.Customer) []*CustomerRuleViolation {
+	if r == nil {
+		return nil
+	}
+
+	values := map[string]*string{
+		"phone":             &customer.Phone,
+		"first_name":        customer.FirstName,
+		"last_name":         customer.LastName,
+		"location":          customer.Location,
+		"preferred_product": customer.PreferredProduct,
+	}
+
+	violations := []*CustomerRuleViolation{}
+	for _, field := range customerRuleFields {
+		if rule, ok := r.Fields[field]; ok {
+			violations = append(violations, rule.check(field, values[field])...)
+		}
+	}
+	return violations
+}
+
+// check returns the rules a field's value breaks
+func (rule *CustomerFieldRule) check(field string, value *string) []*CustomerRuleViolation {
+	violation := func(name, format string, args ...interface{}) *CustomerRuleViolation {
+		return &CustomerRuleViolation{Field: field, Rule: name, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if value == nil || strings.TrimSpace(*value) == "" {
+		if rule.Required {
+			return []*CustomerRuleViolation{violation(CustomerRuleRequired, "%s is required", field)}
+		}
+		return nil
+	}
+
+	violations := []*CustomerRuleViolation{}
+	if length := utf8.RuneCountInString(*value); rule.MaxLength > 0 && length > rule.MaxLength {
+		violations = append(violations, violation(CustomerRuleMaxLength, "%s is %d characters, maximum is %d", field, length, rule.MaxLength))
+	}
+	if len(rule.Allowed) > 0 && !containsFold(rule.Allowed, *value) {
+		violations = append(violations, violation(CustomerRuleAllowed, "%s must be one of %s", field, strings.Join(rule.Allowed, ", ")))
+	}
+	if containsFold(rule.Forbidden, *value) {
+		violations = append(violations, violation(CustomerRuleForbidden, "%s %q is not allowed", field, *value))
+	}
+	if rule.pattern != nil && !rule.pattern.MatchString(*value) {
+		violations = append(violations, violation(CustomerRulePattern, "%s does not match %s", field, rule.Pattern))
+	}
+	return violations
+}
+
+// containsFold reports whether values holds value, ignoring case and surrounding spaces
+func containsFold(values []string, value string) bool {
+	value = strings.TrimSpace(value)
+	for _, candidate := range values {
+		if strings.EqualFold(strings.TrimSpace(candidate), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCustomerRules reads customer rules given inline or as a file; neither means no rules
+func loadCustomerRules(inline, path string) (*CustomerRules, error) {
+	switch {
+	case inline != "" && path != "":
+		return nil, fmt.Errorf("set CUSTOMER_VALIDATION_RULES or CUSTOMER_VALIDATION_RULES_FILE, not both")
+	case inline != "":
+		rules, err := ParseCustomerRules([]byte(inline))
+		if err != nil {
+			return nil, fmt.Errorf("CUSTOMER_VALIDATION_RULES is invalid: %w", err)
+		}
+		return rules, nil
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("CUSTOMER_VALIDATION_RULES_FILE could not be read: %w", err)
+		}
+		rules, err := ParseCustomerRules(data)
+		if err != nil {
+			return nil, fmt.Errorf("CUSTOMER_VALIDATION_RULES_FILE %s is invalid: %w", path, err)
+		}
+		return rules, nil
+	default:
+		return nil, nil
+	}
+}
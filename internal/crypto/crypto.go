@@ -0,0 +1,150 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Prefix marks an encrypted value; the full format is enc:v1:<key id>:<base64(nonce || ciphertext)>
+// Values without it are treated as plaintext written before encryption was enabled
+const Prefix = "enc:v1:"
+
+// KeySize is the AES-256 key length in bytes
+const KeySize = 32
+
+// keyIDPattern keeps key IDs short and free of the ':' separator
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ErrUnknownKey is returned when a value was encrypted with a key that is not configured
+var ErrUnknownKey = errors.New("encrypted with an unknown key")
+
+// Key is a named AES-256 key
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// ParseKey parses a key written as <key id>:<base64 of 32 bytes>
+func ParseKey(spec string) (Key, error) {
+	id, encoded, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok {
+		return Key{}, errors.New("key must be written as <key id>:<base64 key>")
+	}
+	if !keyIDPattern.MatchString(id) {
+		return Key{}, fmt.Errorf("key id %q must be 1-32 letters, digits, '-' or '_'", id)
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return Key{}, fmt.Errorf("key %s is not valid base64: %w", id, err)
+	}
+	if len(secret) != KeySize {
+		return Key{}, fmt.Errorf("key %s is %d bytes, must be %d", id, len(secret), KeySize)
+	}
+
+	return Key{ID: id, Secret: secret}, nil
+}
+
+// Keyring encrypts with its current key and decrypts with any of its keys
+// Old keys stay in the ring after a rotation until every row has been re-encrypted
+type Keyring struct {
+	currentID string
+	aeads     map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring that encrypts with current and can also decrypt with old
+func NewKeyring(current Key, old ...Key) (*Keyring, error) {
+	k := &Keyring{
+		currentID: current.ID,
+		aeads:     make(map[string]cipher.AEAD, len(old)+1),
+	}
+
+	for _, key := range append([]Key{current}, old...) {
+		if _, ok := k.aeads[key.ID]; ok {
+			return nil, fmt.Errorf("key id %s is used more than once", key.ID)
+		}
+
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", key.ID, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", key.ID, err)
+		}
+		k.aeads[key.ID] = aead
+	}
+
+	return k, nil
+}
+
+// CurrentKeyID returns the ID of the key new values are encrypted with
+func (k *Keyring) CurrentKeyID() string {
+	return k.currentID
+}
+
+// Encrypt encrypts plaintext with the current key
+// The prefix and key ID are authenticated, so a value cannot be relabeled with another key
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	aead := k.aeads[k.currentID]
+	header := Prefix + k.currentID + ":"
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(header))
+	return header + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of an encrypted value
+// Values without the prefix are returned unchanged
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	if !ok {
+		return "", errors.New("encrypted value has no key id")
+	}
+
+	aead, ok := k.aeads[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("encrypted value is not valid base64: %w", err)
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return "", errors.New("encrypted value is too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(Prefix+keyID+":"))
+	if err != nil {
+		return "", errors.New("encrypted value failed authentication (wrong key or tampered)")
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether a value is plaintext or encrypted with a key other than the current one
+func (k *Keyring) NeedsRotation(value string) bool {
+	return !strings.HasPrefix(value, Prefix+k.currentID+":")
+}
+
+// IsEncrypted reports whether a value carries the encryption prefix
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
@@ -0,0 +1,266 @@
+package csvimport
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// sniffSize is how much of the file is buffered to detect encoding and delimiter
+// The header row must fit in it
+const sniffSize = 64 * 1024
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	utf8BOM   = []byte{0xef, 0xbb, 0xbf}
+	utf16LE   = []byte{0xff, 0xfe}
+	utf16BE   = []byte{0xfe, 0xff}
+)
+
+// Options controls how a file is parsed
+type Options struct {
+	// ContentEncoding is the request Content-Encoding header ("gzip" forces decompression)
+	ContentEncoding string
+	// RequiredColumns must all be present in the header (compared after normalization)
+	RequiredColumns []string
+}
+
+// Record is one data row keyed by normalized column name
+type Record struct {
+	Line   int
+	Fields map[string]string
+}
+
+// RowError describes a problem with one row
+// Rows with errors are skipped; the rest of the file is still parsed
+type RowError struct {
+	Line    int    `json:"line"`
+	Problem string `json:"problem"`
+	Rule    string `json:"rule,omitempty"` // Customer validation rule broken, when one was
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Problem)
+}
+
+// Result is the outcome of parsing a file
+type Result struct {
+	Header     []string
+	Delimiter  rune
+	Compressed bool
+	Records    []*Record
+	Errors     []*RowError
+}
+
+// Parse reads a customer CSV file, transparently handling gzip compression,
+// a UTF-8 BOM, CRLF line endings, comma or semicolon delimiters and quoted
+// fields spanning several lines
+// It returns an error only when the file as a whole cannot be read; problems
+// with individual rows are collected in Result.Errors
+func Parse(r io.Reader, opts Options) (*Result, error) {
+	result := &Result{}
+
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var src io.Reader = br
+	if strings.EqualFold(strings.TrimSpace(opts.ContentEncoding), "gzip") || bytes.Equal(head, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("file looks gzip-compressed but could not be decompressed: %w", err)
+		}
+		defer gz.Close()
+		src = gz
+		result.Compressed = true
+	}
+
+	body := bufio.NewReaderSize(src, sniffSize)
+	if err := skipBOM(body); err != nil {
+		return nil, err
+	}
+
+	delimiter, err := sniffDelimiter(body)
+	if err != nil {
+		return nil, err
+	}
+	result.Delimiter = delimiter
+
+	reader := csv.NewReader(body)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1 // Field counts are checked per row below
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header row: %w", describeParseError(err))
+	}
+
+	result.Header, err = normalizeHeader(header, opts.RequiredColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			line := 0
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				line = parseErr.StartLine
+			}
+			result.Errors = append(result.Errors, &RowError{Line: line, Problem: describeParseError(err).Error()})
+			if errors.Is(err, csv.ErrQuote) {
+				// An unterminated quote swallows the rest of the file
+				break
+			}
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		if rowErr := checkRow(line, row, len(result.Header)); rowErr != nil {
+			result.Errors = append(result.Errors, rowErr)
+			continue
+		}
+
+		record := &Record{Line: line, Fields: make(map[string]string, len(row))}
+		for i, value := range row {
+			record.Fields[result.Header[i]] = strings.TrimSpace(value)
+		}
+		result.Records = append(result.Records, record)
+	}
+
+	return result, nil
+}
+
+// skipBOM discards a UTF-8 byte order mark and rejects UTF-16 files
+func skipBOM(br *bufio.Reader) error {
+	head, err := br.Peek(len(utf8BOM))
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if bytes.HasPrefix(head, utf16LE) || bytes.HasPrefix(head, utf16BE) {
+		return errors.New("file is UTF-16 encoded: save it as \"CSV UTF-8\" and upload again")
+	}
+	if bytes.Equal(head, utf8BOM) {
+		_, _ = br.Discard(len(utf8BOM))
+	}
+	return nil
+}
+
+// sniffDelimiter picks ',' or ';' by counting them outside quotes in the header row
+func sniffDelimiter(br *bufio.Reader) (rune, error) {
+	buf, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return 0, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	commas, semicolons := 0, 0
+	inQuotes := false
+	for _, b := range buf {
+		if b == '"' {
+			inQuotes = !inQuotes
+			continue
+		}
+		if inQuotes {
+			continue
+		}
+		if b == '\n' {
+			break
+		}
+		switch b {
+		case ',':
+			commas++
+		case ';':
+			semicolons++
+		}
+	}
+
+	if semicolons > commas {
+		return ';', nil
+	}
+	return ',', nil
+}
+
+// normalizeHeader lowercases and trims column names and checks them for problems
+func normalizeHeader(header []string, required []string) ([]string, error) {
+	normalized := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return nil, fmt.Errorf("header column %d is empty", i+1)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("header column %q appears more than once", name)
+		}
+		seen[name] = true
+		normalized[i] = name
+	}
+
+	missing := []string{}
+	for _, name := range required {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("header is missing required columns: %s", strings.Join(missing, ", "))
+	}
+
+	return normalized, nil
+}
+
+// checkRow validates the field count and encoding of a data row
+func checkRow(line int, row []string, columns int) *RowError {
+	if len(row) != columns {
+		return &RowError{
+			Line:    line,
+			Problem: fmt.Sprintf("expected %d fields but found %d (check the delimiter and quoting)", columns, len(row)),
+		}
+	}
+
+	for _, value := range row {
+		if !utf8.ValidString(value) {
+			return &RowError{
+				Line:    line,
+				Problem: "contains invalid UTF-8 (the file may be saved as Latin-1 or Windows-1252; save it as \"CSV UTF-8\")",
+			}
+		}
+	}
+
+	return nil
+}
+
+// describeParseError rewrites encoding/csv errors in terms of the file contents
+func describeParseError(err error) error {
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) {
+		return err
+	}
+
+	switch parseErr.Err {
+	case csv.ErrQuote:
+		return errors.New("quoted field is never closed")
+	case csv.ErrBareQuote:
+		return fmt.Errorf("stray quote in unquoted field at column %d (wrap the field in quotes and double any quotes inside it)", parseErr.Column)
+	default:
+		return parseErr.Err
+	}
+}
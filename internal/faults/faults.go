@@ -0,0 +1,128 @@
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Point names a place in the worker where a fault can be injected
+type Point string
+
+// Injection points
+const (
+	// FailDBAfterSend fails the status update after the provider accepted a message
+	FailDBAfterSend Point = "fail_db_after_send"
+	// PanicBeforeAck panics in the handler after the status update, before the job is acknowledged
+	PanicBeforeAck Point = "panic_before_ack"
+)
+
+// Points lists every injection point
+var Points = []Point{FailDBAfterSend, PanicBeforeAck}
+
+// ErrInjected is returned (wrapped) by Fail when a fault fires
+var ErrInjected = errors.New("injected fault")
+
+// Injector decides whether a fault fires at a point, for exercising recovery runbooks in development
+// A nil Injector never fires, so callers need no checks when faults are off
+type Injector struct {
+	probabilities map[Point]float64
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// New creates an injector firing each point with the given probability (0 to 1)
+func New(probabilities map[Point]float64, source rand.Source) *Injector {
+	return &Injector{
+		probabilities: probabilities,
+		rand:          rand.New(source),
+	}
+}
+
+// Parse builds an injector from a spec such as "fail_db_after_send:0.1,panic_before_ack:0.01"
+// An empty spec returns nil
+func Parse(spec string) (*Injector, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	known := make(map[Point]bool, len(Points))
+	for _, point := range Points {
+		known[point] = true
+	}
+
+	probabilities := make(map[Point]float64)
+	for _, entry := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, fmt.Errorf("fault %q must be <point>:<probability>", entry)
+		}
+
+		point := Point(strings.TrimSpace(name))
+		if !known[point] {
+			return nil, fmt.Errorf("unknown fault point %q", point)
+		}
+
+		probability, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || probability < 0 || probability > 1 {
+			return nil, fmt.Errorf("fault %s probability must be between 0 and 1", point)
+		}
+		probabilities[point] = probability
+	}
+
+	return New(probabilities, rand.NewSource(time.Now().UnixNano())), nil
+}
+
+// Fires reports whether the fault at point fires this time
+func (i *Injector) Fires(point Point) bool {
+	if i == nil {
+		return false
+	}
+
+	probability := i.probabilities[point]
+	if probability <= 0 {
+		return false
+	}
+	if probability >= 1 {
+		return true
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rand.Float64() < probability
+}
+
+// Fail returns an error wrapping ErrInjected when the fault at point fires
+func (i *Injector) Fail(point Point) error {
+	if i.Fires(point) {
+		return fmt.Errorf("%w: %s", ErrInjected, point)
+	}
+	return nil
+}
+
+// Panic panics when the fault at point fires
+func (i *Injector) Panic(point Point) {
+	if i.Fires(point) {
+		panic(fmt.Sprintf("%s: %s", ErrInjected, point))
+	}
+}
+
+// String lists the enabled points, e.g. "fail_db_after_send:0.1"
+func (i *Injector) String() string {
+	if i == nil {
+		return ""
+	}
+
+	entries := make([]string, 0, len(i.probabilities))
+	for point, probability := range i.probabilities {
+		entries = append(entries, string(point)+":"+strconv.FormatFloat(probability, 'g', -1, 64))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
@@ -0,0 +1,208 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// batch loads values by ID, fetching every ID it has been told about in one call
+// Resolvers prime it with sibling IDs as soon as a list is resolved; the first
+// sibling to ask for a value fetches the whole batch while the rest wait and
+// read the result, so a list of N parents costs one query instead of N
+type batch[V any] struct {
+	fetchMu sync.Mutex // Held for the duration of a fetch
+	mu      sync.Mutex // Guards the fields below; never held during a fetch
+	fetch   func(ctx context.Context, ids []int) (map[int]V, error)
+	pending []int
+	queued  map[int]bool
+	done    map[int]V
+}
+
+func newBatch[V any](fetch func(ctx context.Context, ids []int) (map[int]V, error)) *batch[V] {
+	return &batch[V]{
+		fetch:  fetch,
+		queued: make(map[int]bool),
+		done:   make(map[int]V),
+	}
+}
+
+// prime queues IDs to be fetched with the next load
+// It never waits for a fetch in progress
+func (b *batch[V]) prime(ids ...int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, id := range ids {
+		if _, ok := b.done[id]; ok || b.queued[id] {
+			continue
+		}
+		b.queued[id] = true
+		b.pending = append(b.pending, id)
+	}
+}
+
+// load returns the value for id, fetching it along with all queued IDs if needed
+// IDs the fetch does not return load as the zero value
+func (b *batch[V]) load(ctx context.Context, id int) (V, error) {
+	b.fetchMu.Lock()
+	defer b.fetchMu.Unlock()
+
+	b.mu.Lock()
+	if value, ok := b.done[id]; ok {
+		b.mu.Unlock()
+		return value, nil
+	}
+	ids := b.pending
+	if !b.queued[id] {
+		ids = append(ids, id)
+	}
+	b.pending = nil
+	b.queued = make(map[int]bool)
+	b.mu.Unlock()
+
+	values, err := b.fetch(ctx, ids)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, fetched := range ids {
+		b.done[fetched] = values[fetched]
+	}
+	return b.done[id], nil
+}
+
+// messageKey identifies a messages field's arguments; each distinct set gets its own batch
+type messageKey struct {
+	status  models.MessageStatus
+	afterID int
+	limit   int
+}
+
+func (k messageKey) filters() repository.MessageFilters {
+	filters := repository.MessageFilters{AfterID: k.afterID, Limit: k.limit}
+	if k.status != "" {
+		status := k.status
+		filters.Status = &status
+	}
+	return filters
+}
+
+// loaders holds the batches for one request
+// They cache for the lifetime of the request only, so results are never stale across requests
+type loaders struct {
+	messageRepo repository.MessageRepository
+
+	stats     *batch[*models.CampaignStats]
+	customers *batch[*models.Customer]
+
+	mu               sync.Mutex
+	campaignIDs      []int
+	customerIDs      []int
+	campaignMessages map[messageKey]*batch[[]*models.OutboundMessage]
+	customerMessages map[messageKey]*batch[[]*models.OutboundMessage]
+}
+
+func newLoaders(campaignRepo repository.CampaignRepository, customerRepo repository.CustomerRepository, messageRepo repository.MessageRepository) *loaders {
+	l := &loaders{
+		messageRepo:      messageRepo,
+		campaignMessages: make(map[messageKey]*batch[[]*models.OutboundMessage]),
+		customerMessages: make(map[messageKey]*batch[[]*models.OutboundMessage]),
+	}
+
+	l.stats = newBatch(campaignRepo.GetStatsByIDs)
+	l.customers = newBatch(func(ctx context.Context, ids []int) (map[int]*models.Customer, error) {
+		customers, err := customerRepo.GetByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[int]*models.Customer, len(customers))
+		for _, customer := range customers {
+			byID[customer.ID] = customer
+		}
+		return byID, nil
+	})
+
+	return l
+}
+
+// seeCampaigns primes every campaign batch with campaigns about to be resolved
+func (l *loaders) seeCampaigns(ids ...int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.campaignIDs = append(l.campaignIDs, ids...)
+	l.stats.prime(ids...)
+	for _, b := range l.campaignMessages {
+		b.prime(ids...)
+	}
+}
+
+// seeCustomers primes every customer batch with customers about to be resolved
+func (l *loaders) seeCustomers(ids ...int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.customerIDs = append(l.customerIDs, ids...)
+	l.customers.prime(ids...)
+	for _, b := range l.customerMessages {
+		b.prime(ids...)
+	}
+}
+
+// campaignMessagesBatch returns the batch for a campaign messages field with the given arguments
+func (l *loaders) campaignMessagesBatch(key messageKey) *batch[[]*models.OutboundMessage] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.campaignMessages[key]
+	if !ok {
+		b = newBatch(l.groupMessages(l.messageRepo.ListByCampaignIDs, key, func(m *models.OutboundMessage) int { return m.CampaignID }))
+		b.prime(l.campaignIDs...)
+		l.campaignMessages[key] = b
+	}
+	return b
+}
+
+// customerMessagesBatch returns the batch for a customer messages field with the given arguments
+func (l *loaders) customerMessagesBatch(key messageKey) *batch[[]*models.OutboundMessage] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.customerMessages[key]
+	if !ok {
+		b = newBatch(l.groupMessages(l.messageRepo.ListByCustomerIDs, key, func(m *models.OutboundMessage) int { return m.CustomerID }))
+		b.prime(l.customerIDs...)
+		l.customerMessages[key] = b
+	}
+	return b
+}
+
+// groupMessages adapts a partitioned message list to a batch fetch keyed by parent ID
+func (l *loaders) groupMessages(
+	list func(ctx context.Context, ids []int, filters repository.MessageFilters) ([]*models.OutboundMessage, error),
+	key messageKey,
+	parent func(m *models.OutboundMessage) int,
+) func(ctx context.Context, ids []int) (map[int][]*models.OutboundMessage, error) {
+	return func(ctx context.Context, ids []int) (map[int][]*models.OutboundMessage, error) {
+		messages, err := list(ctx, ids, key.filters())
+		if err != nil {
+			return nil, err
+		}
+		grouped := make(map[int][]*models.OutboundMessage, len(ids))
+		customerIDs := make([]int, 0, len(messages))
+		for _, message := range messages {
+			grouped[parent(message)] = append(grouped[parent(message)], message)
+			customerIDs = append(customerIDs, message.CustomerID)
+		}
+
+		// Every message's customer is fetched together, not once per parent
+		l.seeCustomers(customerIDs...)
+		return grouped, nil
+	}
+}
@@ -0,0 +1,462 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// MaxDepth bounds query nesting (customer -> messages -> customer -> ... is otherwise unbounded)
+const MaxDepth = 8
+
+// MaxPageSize caps list and connection sizes, matching the REST per_page limit
+const MaxPageSize = 100
+
+// errInternal hides database errors from clients; the cause is logged
+var errInternal = errors.New("internal error")
+
+// Executor runs read-only queries against the schema
+// Every query gets fresh loaders so related rows are batched within it
+type Executor struct {
+	schema   *graphql.Schema
+	resolver *Resolver
+}
+
+// NewExecutor creates an Executor backed by the given repositories
+func NewExecutor(
+	campaignRepo repository.CampaignRepository,
+	customerRepo repository.CustomerRepository,
+	messageRepo repository.MessageRepository,
+) *Executor {
+	resolver := &Resolver{
+		campaignRepo: campaignRepo,
+		customerRepo: customerRepo,
+		messageRepo:  messageRepo,
+	}
+
+	return &Executor{
+		schema:   graphql.MustParseSchema(Schema, resolver, graphql.MaxDepth(MaxDepth)),
+		resolver: resolver,
+	}
+}
+
+// Execute runs a query and returns its response, including any field errors
+func (e *Executor) Execute(ctx context.Context, query, operationName string, variables map[string]interface{}) *graphql.Response {
+	ctx = context.WithValue(ctx, loadersKey{}, newLoaders(e.resolver.campaignRepo, e.resolver.customerRepo, e.resolver.messageRepo))
+	return e.schema.Exec(ctx, query, operationName, variables)
+}
+
+type loadersKey struct{}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// Resolver resolves the root Query fields
+type Resolver struct {
+	campaignRepo repository.CampaignRepository
+	customerRepo repository.CustomerRepository
+	messageRepo  repository.MessageRepository
+}
+
+// Campaign resolves campaign(id)
+func (r *Resolver) Campaign(ctx context.Context, args struct{ ID graphql.ID }) (*campaignResolver, error) {
+	id, err := parseID(args.ID, "campaign")
+	if err != nil {
+		return nil, err
+	}
+
+	campaign, err := r.campaignRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err)
+	}
+
+	l := loadersFrom(ctx)
+	l.seeCampaigns(campaign.ID)
+	return &campaignResolver{campaign: campaign, loaders: l}, nil
+}
+
+// Campaigns resolves campaigns(...) with the same filters as GET /campaigns
+func (r *Resolver) Campaigns(ctx context.Context, args struct {
+	Channel  *string
+	Status   *string
+	Tags     *[]string
+	Page     int32
+	PageSize int32
+}) (*campaignListResolver, error) {
+	if args.Page < 1 {
+		return nil, errors.New("page must be at least 1")
+	}
+	if args.PageSize < 1 || args.PageSize > MaxPageSize {
+		return nil, fmt.Errorf("pageSize must be between 1 and %d", MaxPageSize)
+	}
+
+	filters := repository.CampaignFilters{
+		Page:     int(args.Page),
+		PageSize: int(args.PageSize),
+	}
+	if args.Channel != nil {
+		channel := models.Channel(*args.Channel)
+		filters.Channel = &channel
+	}
+	if args.Status != nil {
+		status := models.CampaignStatus(*args.Status)
+		filters.Status = &status
+	}
+	if args.Tags != nil && len(*args.Tags) > 0 {
+		if err := models.ValidateTags(*args.Tags); err != nil {
+			return nil, err
+		}
+		filters.Tags = models.NormalizeTags(*args.Tags)
+	}
+
+	campaigns, total, err := r.campaignRepo.List(ctx, filters)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	l := loadersFrom(ctx)
+	ids := make([]int, len(campaigns))
+	resolvers := make([]*campaignResolver, len(campaigns))
+	for i, campaign := range campaigns {
+		ids[i] = campaign.ID
+		resolvers[i] = &campaignResolver{campaign: campaign, loaders: l}
+	}
+	l.seeCampaigns(ids...)
+
+	return &campaignListResolver{
+		campaigns: resolvers,
+		page:      args.Page,
+		pageSize:  args.PageSize,
+		total:     int32(total),
+	}, nil
+}
+
+// Customer resolves customer(id)
+func (r *Resolver) Customer(ctx context.Context, args struct{ ID graphql.ID }) (*customerResolver, error) {
+	id, err := parseID(args.ID, "customer")
+	if err != nil {
+		return nil, err
+	}
+
+	customer, err := r.customerRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err)
+	}
+
+	l := loadersFrom(ctx)
+	l.seeCustomers(customer.ID)
+	return &customerResolver{customer: customer, loaders: l}, nil
+}
+
+// Customers resolves customers(limit, offset)
+func (r *Resolver) Customers(ctx context.Context, args struct {
+	Limit  int32
+	Offset int32
+}) ([]*customerResolver, error) {
+	if args.Limit < 1 || args.Limit > MaxPageSize {
+		return nil, fmt.Errorf("limit must be between 1 and %d", MaxPageSize)
+	}
+	if args.Offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+
+	customers, err := r.customerRepo.List(ctx, int(args.Limit), int(args.Offset))
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	l := loadersFrom(ctx)
+	ids := make([]int, len(customers))
+	resolvers := make([]*customerResolver, len(customers))
+	for i, customer := range customers {
+		ids[i] = customer.ID
+		resolvers[i] = &customerResolver{customer: customer, loaders: l}
+	}
+	l.seeCustomers(ids...)
+
+	return resolvers, nil
+}
+
+// Message resolves message(id)
+func (r *Resolver) Message(ctx context.Context, args struct{ ID graphql.ID }) (*messageResolver, error) {
+	id, err := parseID(args.ID, "message")
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := r.messageRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err)
+	}
+
+	l := loadersFrom(ctx)
+	l.seeCustomers(message.CustomerID)
+	return &messageResolver{message: message, loaders: l}, nil
+}
+
+type campaignListResolver struct {
+	campaigns []*campaignResolver
+	page      int32
+	pageSize  int32
+	total     int32
+}
+
+func (r *campaignListResolver) Campaigns() []*campaignResolver { return r.campaigns }
+func (r *campaignListResolver) Page() int32                    { return r.page }
+func (r *campaignListResolver) PageSize() int32                { return r.pageSize }
+func (r *campaignListResolver) TotalCount() int32              { return r.total }
+
+func (r *campaignListResolver) TotalPages() int32 {
+	return (r.total + r.pageSize - 1) / r.pageSize
+}
+
+type campaignResolver struct {
+	campaign *models.Campaign
+	loaders  *loaders
+}
+
+func (r *campaignResolver) ID() graphql.ID             { return intID(r.campaign.ID) }
+func (r *campaignResolver) Name() string               { return r.campaign.Name }
+func (r *campaignResolver) Channel() string            { return string(r.campaign.Channel) }
+func (r *campaignResolver) Status() string             { return string(r.campaign.Status) }
+func (r *campaignResolver) BaseTemplate() string       { return r.campaign.BaseTemplate }
+func (r *campaignResolver) ScheduledAt() *graphql.Time { return optionalTime(r.campaign.ScheduledAt) }
+func (r *campaignResolver) Tags() []string             { return models.NormalizeTags(r.campaign.Tags) }
+func (r *campaignResolver) CreatedBy() *string         { return r.campaign.CreatedBy }
+func (r *campaignResolver) Team() *string              { return r.campaign.Team }
+func (r *campaignResolver) CreatedAt() graphql.Time    { return graphql.Time{Time: r.campaign.CreatedAt} }
+func (r *campaignResolver) UpdatedAt() graphql.Time    { return graphql.Time{Time: r.campaign.UpdatedAt} }
+
+// Stats is batched across every campaign in the response
+func (r *campaignResolver) Stats(ctx context.Context) (*statsResolver, error) {
+	stats, err := r.loaders.stats.load(ctx, r.campaign.ID)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if stats == nil {
+		stats = &models.CampaignStats{}
+	}
+	return &statsResolver{stats: stats}, nil
+}
+
+// Messages is batched across every campaign in the response
+func (r *campaignResolver) Messages(ctx context.Context, args messagesArgs) (*connectionResolver, error) {
+	key, err := args.key()
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := r.loaders.campaignMessagesBatch(key).load(ctx, r.campaign.ID)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return newConnection(messages, int(args.First), r.loaders), nil
+}
+
+type statsResolver struct {
+	stats *models.CampaignStats
+}
+
+func (r *statsResolver) Total() int32       { return int32(r.stats.Total) }
+func (r *statsResolver) Pending() int32     { return int32(r.stats.Pending) }
+func (r *statsResolver) Queued() int32      { return int32(r.stats.Queued) }
+func (r *statsResolver) Unpublished() int32 { return int32(r.stats.Unpublished) }
+func (r *statsResolver) Sent() int32        { return int32(r.stats.Sent) }
+func (r *statsResolver) Failed() int32      { return int32(r.stats.Failed) }
+func (r *statsResolver) Simulated() int32   { return int32(r.stats.Simulated) }
+
+func (r *statsResolver) SkippedDuplicateContent() int32 {
+	return int32(r.stats.SkippedDuplicateContent)
+}
+
+func (r *statsResolver) Expired() int32 { return int32(r.stats.Expired) }
+
+func (r *statsResolver) P95QueueLatencySeconds() *float64 {
+	return r.stats.P95QueueLatencySeconds
+}
+
+type customerResolver struct {
+	customer *models.Customer
+	loaders  *loaders
+}
+
+func (r *customerResolver) ID() graphql.ID            { return intID(r.customer.ID) }
+func (r *customerResolver) Phone() string             { return r.customer.Phone }
+func (r *customerResolver) FirstName() *string        { return r.customer.FirstName }
+func (r *customerResolver) LastName() *string         { return r.customer.LastName }
+func (r *customerResolver) Location() *string         { return r.customer.Location }
+func (r *customerResolver) PreferredProduct() *string { return r.customer.PreferredProduct }
+func (r *customerResolver) CreatedAt() graphql.Time   { return graphql.Time{Time: r.customer.CreatedAt} }
+
+// Messages is batched across every customer in the response
+func (r *customerResolver) Messages(ctx context.Context, args messagesArgs) (*connectionResolver, error) {
+	key, err := args.key()
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := r.loaders.customerMessagesBatch(key).load(ctx, r.customer.ID)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return newConnection(messages, int(args.First), r.loaders), nil
+}
+
+type messageResolver struct {
+	message *models.OutboundMessage
+	loaders *loaders
+}
+
+func (r *messageResolver) ID() graphql.ID             { return intID(r.message.ID) }
+func (r *messageResolver) CampaignID() graphql.ID     { return intID(r.message.CampaignID) }
+func (r *messageResolver) Status() string             { return string(r.message.Status) }
+func (r *messageResolver) RenderedContent() *string   { return r.message.RenderedContent }
+func (r *messageResolver) LastError() *string         { return r.message.LastError }
+func (r *messageResolver) RetryCount() int32          { return int32(r.message.RetryCount) }
+func (r *messageResolver) PublishedAt() *graphql.Time { return optionalTime(r.message.PublishedAt) }
+func (r *messageResolver) CreatedAt() graphql.Time    { return graphql.Time{Time: r.message.CreatedAt} }
+func (r *messageResolver) UpdatedAt() graphql.Time    { return graphql.Time{Time: r.message.UpdatedAt} }
+
+// Customer is batched across every message in the response
+// It is null if the customer has since been deleted
+func (r *messageResolver) Customer(ctx context.Context) (*customerResolver, error) {
+	customer, err := r.loaders.customers.load(ctx, r.message.CustomerID)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if customer == nil {
+		return nil, nil
+	}
+	return &customerResolver{customer: customer, loaders: r.loaders}, nil
+}
+
+// messagesArgs are the arguments of a messages connection field
+type messagesArgs struct {
+	Status *string
+	First  int32
+	After  *string
+}
+
+// key validates the arguments and converts them to a batch key
+// One extra message is fetched per parent to tell whether there is a next page
+func (a messagesArgs) key() (messageKey, error) {
+	if a.First < 1 || a.First > MaxPageSize {
+		return messageKey{}, fmt.Errorf("first must be between 1 and %d", MaxPageSize)
+	}
+
+	key := messageKey{limit: int(a.First) + 1}
+	if a.Status != nil {
+		key.status = models.MessageStatus(*a.Status)
+	}
+	if a.After != nil {
+		afterID, err := decodeCursor(*a.After)
+		if err != nil {
+			return messageKey{}, err
+		}
+		key.afterID = afterID
+	}
+	return key, nil
+}
+
+type connectionResolver struct {
+	edges       []*edgeResolver
+	hasNextPage bool
+}
+
+func newConnection(messages []*models.OutboundMessage, first int, l *loaders) *connectionResolver {
+	conn := &connectionResolver{edges: []*edgeResolver{}}
+	if len(messages) > first {
+		messages = messages[:first]
+		conn.hasNextPage = true
+	}
+	for _, message := range messages {
+		conn.edges = append(conn.edges, &edgeResolver{message: &messageResolver{message: message, loaders: l}})
+	}
+	return conn
+}
+
+func (r *connectionResolver) Edges() []*edgeResolver { return r.edges }
+
+func (r *connectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNextPage: r.hasNextPage}
+	if len(r.edges) > 0 {
+		cursor := r.edges[len(r.edges)-1].Cursor()
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+type edgeResolver struct {
+	message *messageResolver
+}
+
+func (r *edgeResolver) Cursor() string         { return encodeCursor(r.message.message.ID) }
+func (r *edgeResolver) Node() *messageResolver { return r.message }
+
+type pageInfoResolver struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool  { return r.hasNextPage }
+func (r *pageInfoResolver) EndCursor() *string { return r.endCursor }
+
+// cursorPrefix keeps cursors opaque so clients do not build them from IDs
+const cursorPrefix = "message:"
+
+func encodeCursor(id int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(id)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(raw), cursorPrefix) {
+		if id, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix)); err == nil && id > 0 {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid cursor: %q", cursor)
+}
+
+func parseID(id graphql.ID, resource string) (int, error) {
+	parsed, err := strconv.Atoi(string(id))
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid %s ID: %q", resource, string(id))
+	}
+	return parsed, nil
+}
+
+func intID(id int) graphql.ID {
+	return graphql.ID(strconv.Itoa(id))
+}
+
+func optionalTime(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
+
+// lookupError turns a missing row into a null result and hides anything else
+func lookupError(err error) error {
+	if strings.HasSuffix(err.Error(), "not found") {
+		return nil
+	}
+	return internalError(err)
+}
+
+func internalError(err error) error {
+	log.Printf("ERROR: GraphQL resolver: %v", err)
+	return errInternal
+}
@@ -0,0 +1,123 @@
+package graph
+
+// Schema is the read-only GraphQL schema served at /graphql
+// Enum values match the REST API's status and channel strings
+const Schema = `
+schema {
+	query: Query
+}
+
+scalar Time
+
+enum Channel {
+	sms
+	whatsapp
+}
+
+enum CampaignStatus {
+	draft
+	scheduled
+	pending_approval
+	sending
+	canary
+	paused
+	sent
+	failed
+	cancelling
+	cancelled
+	recurring
+}
+
+enum MessageStatus {
+	pending
+	sent
+	failed
+	cancelled
+	skipped_duplicate_content
+	expired
+}
+
+type Query {
+	campaign(id: ID!): Campaign
+	campaigns(channel: Channel, status: CampaignStatus, tags: [String!], page: Int = 1, pageSize: Int = 20): CampaignList!
+	customer(id: ID!): Customer
+	customers(limit: Int = 20, offset: Int = 0): [Customer!]!
+	message(id: ID!): OutboundMessage
+}
+
+type CampaignList {
+	campaigns: [Campaign!]!
+	page: Int!
+	pageSize: Int!
+	totalCount: Int!
+	totalPages: Int!
+}
+
+type Campaign {
+	id: ID!
+	name: String!
+	channel: Channel!
+	status: CampaignStatus!
+	baseTemplate: String!
+	scheduledAt: Time
+	tags: [String!]!
+	createdBy: String
+	team: String
+	createdAt: Time!
+	updatedAt: Time!
+	stats: CampaignStats!
+	messages(status: MessageStatus, first: Int = 20, after: String): MessageConnection!
+}
+
+type CampaignStats {
+	total: Int!
+	pending: Int!
+	queued: Int!
+	unpublished: Int!
+	sent: Int!
+	failed: Int!
+	simulated: Int!
+	skippedDuplicateContent: Int!
+	expired: Int!
+	p95QueueLatencySeconds: Float
+}
+
+type Customer {
+	id: ID!
+	phone: String!
+	firstName: String
+	lastName: String
+	location: String
+	preferredProduct: String
+	createdAt: Time!
+	messages(status: MessageStatus, first: Int = 20, after: String): MessageConnection!
+}
+
+type OutboundMessage {
+	id: ID!
+	campaignId: ID!
+	status: MessageStatus!
+	renderedContent: String
+	lastError: String
+	retryCount: Int!
+	publishedAt: Time
+	createdAt: Time!
+	updatedAt: Time!
+	customer: Customer
+}
+
+type MessageConnection {
+	edges: [MessageEdge!]!
+	pageInfo: PageInfo!
+}
+
+type MessageEdge {
+	cursor: String!
+	node: OutboundMessage!
+}
+
+type PageInfo {
+	hasNextPage: Boolean!
+	endCursor: String
+}
+`
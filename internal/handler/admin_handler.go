@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"smsleopard/internal/service"
+)
+
+// AdminHandler handles HTTP requests for operational endpoints
+type AdminHandler struct {
+	attentionService       *service.AttentionService
+	processingErrorService *service.ProcessingErrorService
+}
+
+// NewAdminHandler creates a new AdminHandler instance
+func NewAdminHandler(attentionService *service.AttentionService, processingErrorService *service.ProcessingErrorService) *AdminHandler {
+	return &AdminHandler{
+		attentionService:       attentionService,
+		processingErrorService: processingErrorService,
+	}
+}
+
+// CampaignsNeedingAttention handles GET /admin/campaigns/attention
+// It lists stalled, overdue and failing campaigns tagged with the reason
+func (h *AdminHandler) CampaignsNeedingAttention(w http.ResponseWriter, r *http.Request) {
+	digest, err := h.attentionService.ListNeedingAttention(r.Context())
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, digest)
+}
+
+// ProcessingErrors handles GET /admin/processing-errors
+// Supports optional query parameter: since (RFC3339, defaults to the last 24 hours)
+func (h *AdminHandler) ProcessingErrors(w http.ResponseWriter, r *http.Request) {
+	var since *time.Time
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, sinceStr)
+		if err != nil {
+			WriteValidationError(w, "invalid since: must be an RFC3339 timestamp")
+			return
+		}
+		since = &parsed
+	}
+
+	processingErrors, err := h.processingErrorService.List(r.Context(), since)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, processingErrors)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+
+	"smsleopard/internal/service"
+)
+
+// APIKeyHandler handles HTTP requests that issue, list and revoke API keys
+type APIKeyHandler struct {
+	keyService *service.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler instance
+func NewAPIKeyHandler(keyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{keyService: keyService}
+}
+
+// Create handles POST /admin/api-keys
+// Body: {"label": "crm sync", "user_id": "crm", "role": "member", "team": "growth"}
+// The response carries the key in "key"; it is not stored and cannot be shown again
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req service.CreateAPIKeyRequest
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	created, err := h.keyService.Create(r.Context(), &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteCreated(w, created)
+}
+
+// List handles GET /admin/api-keys
+// It returns every issued key, revoked ones included, with when each was last used
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keyService.List(r.Context())
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, keys)
+}
+
+// Revoke handles DELETE /admin/api-keys/{id}
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	key, err := h.keyService.Revoke(r.Context(), id)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, key)
+}
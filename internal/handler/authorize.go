@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"smsleopard/internal/reqctx"
+	"smsleopard/internal/service"
+)
+
+// authorize checks that the caller may modify the campaign, writing an error response when not
+// Every caller is allowed when authentication is disabled
+func (h *CampaignHandler) authorize(w http.ResponseWriter, r *http.Request, campaignID int) bool {
+	return authorizeCampaign(w, r, h.campaignService, campaignID)
+}
+
+// authorizeCampaign is authorize for handlers other than CampaignHandler
+func authorizeCampaign(w http.ResponseWriter, r *http.Request, campaignService *service.CampaignService, campaignID int) bool {
+	identity, _ := reqctx.IdentityFrom(r.Context())
+	if identity == nil || identity.IsAdmin() {
+		return true
+	}
+
+	campaign, err := campaignService.GetCampaign(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return false
+	}
+
+	if !identity.CanModify(campaign) {
+		WriteForbiddenError(w, "only the campaign owner or an admin can modify this campaign")
+		return false
+	}
+
+	return true
+}
+
+// authorizeSaturationOverride checks that the caller may send while the send backlog is
+// saturated, writing an error response when not
+// Only admins may, or every caller when authentication is disabled
+func authorizeSaturationOverride(w http.ResponseWriter, r *http.Request) bool {
+	identity, _ := reqctx.IdentityFrom(r.Context())
+	if identity == nil || identity.IsAdmin() {
+		return true
+	}
+
+	WriteForbiddenError(w, "only an admin can override queue saturation")
+	return false
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"smsleopard/internal/service"
+)
+
+// CampaignEventHandler handles HTTP requests for a campaign's send events
+type CampaignEventHandler struct {
+	eventService *service.CampaignEventService
+}
+
+// NewCampaignEventHandler creates a new CampaignEventHandler instance
+func NewCampaignEventHandler(eventService *service.CampaignEventService) *CampaignEventHandler {
+	return &CampaignEventHandler{
+		eventService: eventService,
+	}
+}
+
+// Events handles GET /campaigns/{id}/events?since_id=N - long-polls for the campaign's events
+// after since_id, returning them oldest first as soon as there are any, or an empty array
+// once the wait times out
+func (h *CampaignEventHandler) Events(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	var sinceID int64
+	if value := r.URL.Query().Get("since_id"); value != "" {
+		sinceID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || sinceID < 0 {
+			WriteValidationError(w, "since_id must be a non-negative integer")
+			return
+		}
+	}
+
+	events, err := h.eventService.Wait(r.Context(), campaignID, sinceID)
+	if errors.Is(err, context.Canceled) {
+		// The client went away; nobody is left to respond to
+		return
+	}
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, events)
+}
@@ -1,16 +1,15 @@
 package handler
 
 import (
-	"encoding/json"
-	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
 	"smsleopard/internal/models"
 	"smsleopard/internal/repository"
+	"smsleopard/internal/reqctx"
 	"smsleopard/internal/service"
-
-	"github.com/gorilla/mux"
 )
 
 // CampaignHandler handles HTTP requests for campaign operations
@@ -29,16 +28,18 @@ func NewCampaignHandler(campaignService *service.CampaignService) *CampaignHandl
 func (h *CampaignHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req service.CreateCampaignRequest
 
-	// Parse JSON body
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		if err == io.EOF {
-			WriteError(w, http.StatusBadRequest, "INVALID_JSON", "Request body is empty")
-			return
-		}
-		WriteError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON format")
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
 		return
 	}
 
+	// The caller owns the campaign; their team applies unless the request names one
+	if identity, ok := reqctx.IdentityFrom(r.Context()); ok {
+		req.CreatedBy = identity.UserID
+		if req.Team == "" {
+			req.Team = identity.Team
+		}
+	}
+
 	// Call service to create campaign
 	campaign, err := h.campaignService.CreateCampaign(r.Context(), &req)
 	if err != nil {
@@ -46,8 +47,11 @@ func (h *CampaignHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Return 201 Created
-	WriteCreated(w, campaign)
+	// Return 201 Created, with any template mistakes worth fixing
+	response := presentCampaign(campaign)
+	response.TemplateWarnings = h.campaignService.TemplateWarnings(campaign)
+	response.LinkWarning = service.CheckLinks(campaign.BaseTemplate, campaign.TrackLinks)
+	WriteCreated(w, response)
 }
 
 // List handles GET /campaigns - lists campaigns with filters
@@ -84,16 +88,22 @@ func (h *CampaignHandler) List(w http.ResponseWriter, r *http.Request) {
 	if statusStr := query.Get("status"); statusStr != "" {
 		// Validate status
 		validStatuses := map[string]models.CampaignStatus{
-			"draft":     models.CampaignStatusDraft,
-			"scheduled": models.CampaignStatusScheduled,
-			"sending":   models.CampaignStatusSending,
-			"sent":      models.CampaignStatusSent,
-			"failed":    models.CampaignStatusFailed,
+			"draft":            models.CampaignStatusDraft,
+			"scheduled":        models.CampaignStatusScheduled,
+			"pending_approval": models.CampaignStatusPendingApproval,
+			"sending":          models.CampaignStatusSending,
+			"canary":           models.CampaignStatusCanary,
+			"paused":           models.CampaignStatusPaused,
+			"sent":             models.CampaignStatusSent,
+			"failed":           models.CampaignStatusFailed,
+			"cancelling":       models.CampaignStatusCancelling,
+			"cancelled":        models.CampaignStatusCancelled,
+			"recurring":        models.CampaignStatusRecurring,
 		}
 		if status, ok := validStatuses[statusStr]; ok {
 			filters.Status = &status
 		} else {
-			WriteValidationError(w, "invalid status: must be one of draft, scheduled, sending, sent, failed")
+			WriteValidationError(w, "invalid status: must be one of draft, scheduled, pending_approval, sending, canary, paused, sent, failed, cancelling, cancelled, recurring")
 			return
 		}
 	}
@@ -113,6 +123,15 @@ func (h *CampaignHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Parse tag filter (repeatable, campaigns must have every tag)
+	if tags := query["tag"]; len(tags) > 0 {
+		if err := models.ValidateTags(tags); err != nil {
+			WriteValidationError(w, err.Error())
+			return
+		}
+		filters.Tags = models.NormalizeTags(tags)
+	}
+
 	// Call service to list campaigns
 	campaigns, pagination, err := h.campaignService.ListCampaigns(r.Context(), filters)
 	if err != nil {
@@ -122,7 +141,7 @@ func (h *CampaignHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	// Create response
 	response := ListCampaignsResponse{
-		Campaigns:  campaigns,
+		Campaigns:  presentCampaigns(campaigns),
 		Pagination: pagination,
 	}
 
@@ -132,20 +151,9 @@ func (h *CampaignHandler) List(w http.ResponseWriter, r *http.Request) {
 
 // GetByID handles GET /campaigns/{id} - gets a campaign by ID
 func (h *CampaignHandler) GetByID(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from URL
-	vars := mux.Vars(r)
-	idStr := vars["id"]
-
-	// Convert to integer
-	id, err := strconv.Atoi(idStr)
+	id, err := ParseIDParam(r, "id")
 	if err != nil {
-		WriteValidationError(w, "invalid campaign ID format")
-		return
-	}
-
-	// Validate ID > 0
-	if id <= 0 {
-		WriteValidationError(w, "campaign ID must be greater than 0")
+		WriteValidationError(w, err.Error())
 		return
 	}
 
@@ -157,65 +165,584 @@ func (h *CampaignHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return 200 OK
-	WriteOK(w, campaign)
+	WriteOK(w, presentCampaignWithStats(campaign))
 }
 
 // Send handles POST /campaigns/{id}/send - sends a campaign to customers
 func (h *CampaignHandler) Send(w http.ResponseWriter, r *http.Request) {
-	// Extract campaign ID from URL
-	vars := mux.Vars(r)
-	idStr := vars["id"]
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Parse JSON body
+	var req SendCampaignRequest
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	// Validate an audience was given
+	if len(req.CustomerIDs) == 0 && len(req.Customers) == 0 {
+		WriteValidationError(w, "customer_ids and customers cannot both be empty")
+		return
+	}
+
+	if !h.authorize(w, r, campaignID) {
+		return
+	}
+	if req.OverrideSaturation && !authorizeSaturationOverride(w, r) {
+		return
+	}
+
+	opts := service.SendOptions{
+		Customers:             req.Customers,
+		AllowDuplicateContent: req.AllowDuplicateContent,
+		OverrideSaturation:    req.OverrideSaturation,
+		MinEngagementScore:    req.MinEngagementScore,
+		ProcessBy:             req.ProcessBy,
+	}
+	if req.CanaryPercent != nil || req.CanaryMin != nil {
+		opts.Canary = &models.CanaryOptions{}
+		if req.CanaryPercent != nil {
+			opts.Canary.Percent = *req.CanaryPercent
+		}
+		if req.CanaryMin != nil {
+			opts.Canary.Min = *req.CanaryMin
+		}
+	}
+	if identity, ok := reqctx.IdentityFrom(r.Context()); ok {
+		opts.RequestedBy = identity.UserID
+	}
+
+	// Call service to send campaign
+	result, err := h.campaignService.SendCampaign(r.Context(), campaignID, req.CustomerIDs, opts)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	// Return 202 Accepted when the send is waiting for approval
+	if result.Status == models.CampaignStatusPendingApproval {
+		WriteJSON(w, http.StatusAccepted, result)
+		return
+	}
 
-	// Convert to integer
-	campaignID, err := strconv.Atoi(idStr)
+	// Return 200 OK
+	WriteOK(w, result)
+}
+
+// SendHistory handles GET /campaigns/{id}/send-history
+// It lists every send request made against the campaign with its targeting, newest first
+func (h *CampaignHandler) SendHistory(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
 	if err != nil {
-		WriteValidationError(w, "invalid campaign ID format")
+		WriteValidationError(w, err.Error())
 		return
 	}
 
-	// Validate ID > 0
-	if campaignID <= 0 {
-		WriteValidationError(w, "campaign ID must be greater than 0")
+	history, err := h.campaignService.GetSendHistory(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, history)
+}
+
+// MaxCSVUploadBytes caps the size of a recipient CSV upload
+const MaxCSVUploadBytes = 32 << 20
+
+// SendCSV handles POST /campaigns/{id}/send-csv - sends a campaign to the phones in an uploaded CSV
+// The multipart form carries the file as "file" and optional create_unknown=true, allow_duplicate_content=true
+// and override_saturation=true
+func (h *CampaignHandler) SendCSV(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxCSVUploadBytes)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		WriteValidationError(w, "a CSV file is required in the \"file\" form field")
+		return
+	}
+	defer file.Close()
+
+	req := service.SendCampaignCSVRequest{ContentEncoding: header.Header.Get("Content-Encoding")}
+	if value := r.FormValue("create_unknown"); value != "" {
+		req.CreateUnknown, err = strconv.ParseBool(value)
+		if err != nil {
+			WriteValidationError(w, "create_unknown must be true or false")
+			return
+		}
+	}
+	if value := r.FormValue("allow_duplicate_content"); value != "" {
+		req.AllowDuplicateContent, err = strconv.ParseBool(value)
+		if err != nil {
+			WriteValidationError(w, "allow_duplicate_content must be true or false")
+			return
+		}
+	}
+	if value := r.FormValue("override_saturation"); value != "" {
+		req.OverrideSaturation, err = strconv.ParseBool(value)
+		if err != nil {
+			WriteValidationError(w, "override_saturation must be true or false")
+			return
+		}
+	}
+
+	if !h.authorize(w, r, campaignID) {
+		return
+	}
+	if req.OverrideSaturation && !authorizeSaturationOverride(w, r) {
+		return
+	}
+
+	if identity, ok := reqctx.IdentityFrom(r.Context()); ok {
+		req.RequestedBy = identity.UserID
+	}
+
+	result, err := h.campaignService.SendCampaignCSV(r.Context(), campaignID, file, &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	// Return 202 Accepted when the send is waiting for approval
+	if result.Status == models.CampaignStatusPendingApproval {
+		WriteJSON(w, http.StatusAccepted, result)
+		return
+	}
+
+	WriteOK(w, result)
+}
+
+// ReRender handles POST /campaigns/{id}/re-render
+// The body is optional; {"dry_run": true} returns a rendered sample without clearing anything
+func (h *CampaignHandler) ReRender(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Parse optional JSON body
+	var req service.ReRenderRequest
+	if err := DecodeOptionalJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	if !h.authorize(w, r, campaignID) {
+		return
+	}
+
+	result, err := h.campaignService.ReRenderCampaign(r.Context(), campaignID, &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
+
+// ResendMessage handles POST /messages/{id}/resend
+// Body (optional): {"rerender": true} renders the resend from the current template instead
+// of sending the original's stored content; returns the new message
+func (h *CampaignHandler) ResendMessage(w http.ResponseWriter, r *http.Request) {
+	messageID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Parse optional JSON body
+	var req service.ResendMessageRequest
+	if err := DecodeOptionalJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	original, err := h.campaignService.GetMessage(r.Context(), messageID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+	if !h.authorize(w, r, original.CampaignID) {
+		return
+	}
+
+	resend, err := h.campaignService.ResendMessage(r.Context(), original, &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteCreated(w, resend)
+}
+
+// PlaceholderCoverage handles POST /campaigns/{id}/placeholder-coverage
+// It reports how much of the targeted audience can fill each template placeholder
+func (h *CampaignHandler) PlaceholderCoverage(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
 		return
 	}
 
 	// Parse JSON body
-	var req SendCampaignRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		if err == io.EOF {
-			WriteError(w, http.StatusBadRequest, "INVALID_JSON", "Request body is empty")
+	var req service.PlaceholderCoverageRequest
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	result, err := h.campaignService.PlaceholderCoverage(r.Context(), campaignID, &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
+
+// Approve handles POST /campaigns/{id}/approve
+// It executes the send plan stored when the send required approval
+func (h *CampaignHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	result, err := h.campaignService.ApproveCampaign(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
+
+// Reject handles POST /campaigns/{id}/reject
+// It discards the stored send plan and returns the campaign to draft
+func (h *CampaignHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	campaign, err := h.campaignService.RejectCampaign(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, presentCampaign(campaign))
+}
+
+// Resume handles POST /campaigns/{id}/resume
+// It restarts a paused campaign, optionally with a new budget, and releases its held messages
+func (h *CampaignHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Parse optional JSON body
+	var req service.ResumeCampaignRequest
+	if err := DecodeOptionalJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	if !h.authorize(w, r, campaignID) {
+		return
+	}
+
+	result, err := h.campaignService.ResumeCampaign(r.Context(), campaignID, &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
+
+// Cancel handles POST /campaigns/{id}/cancel
+// grace_seconds (default 60, 0 cancels at once) is how long the cancellation can still be undone
+func (h *CampaignHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	grace := service.DefaultCancelGrace
+	if value := r.URL.Query().Get("grace_seconds"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			WriteValidationError(w, "grace_seconds must be a whole number of seconds")
 			return
 		}
-		WriteError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON format")
+		grace = time.Duration(seconds) * time.Second
+	}
+
+	if !h.authorize(w, r, campaignID) {
+		return
+	}
+
+	result, err := h.campaignService.CancelCampaign(r.Context(), campaignID, grace)
+	if err != nil {
+		HandleServiceError(w, err)
 		return
 	}
 
-	// Validate customer_ids not empty
-	if len(req.CustomerIDs) == 0 {
-		WriteValidationError(w, "customer_ids cannot be empty")
+	WriteOK(w, result)
+}
+
+// UndoCancel handles POST /campaigns/{id}/undo-cancel
+func (h *CampaignHandler) UndoCancel(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
 		return
 	}
 
-	// Call service to send campaign
-	result, err := h.campaignService.SendCampaign(r.Context(), campaignID, req.CustomerIDs)
+	if !h.authorize(w, r, campaignID) {
+		return
+	}
+
+	result, err := h.campaignService.UndoCancel(r.Context(), campaignID)
 	if err != nil {
 		HandleServiceError(w, err)
 		return
 	}
 
-	// Return 200 OK
 	WriteOK(w, result)
 }
 
+// Continue handles POST /campaigns/{id}/continue
+// It sends the rest of the audience a canary send held back
+func (h *CampaignHandler) Continue(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	if !h.authorize(w, r, campaignID) {
+		return
+	}
+
+	result, err := h.campaignService.ContinueCampaign(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
+
+// Abort handles POST /campaigns/{id}/abort
+// It cancels a campaign holding a canary at once, so the audience held back is never sent
+func (h *CampaignHandler) Abort(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	if !h.authorize(w, r, campaignID) {
+		return
+	}
+
+	result, err := h.campaignService.AbortCampaign(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
+
+// Delete handles DELETE /campaigns/{id}
+// A campaign with messages is refused with 409 unless force=true, which deletes its messages too
+func (h *CampaignHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	force, ok := parseForce(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.authorize(w, r, campaignID) {
+		return
+	}
+
+	result, err := h.campaignService.DeleteCampaign(r.Context(), campaignID, force)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
+
+// AddSuppressions handles POST /campaigns/{id}/suppressions
+// The body is a JSON array of phones, a text/csv body, or a multipart form with the CSV as "file";
+// CSVs need a phone column. Phones are normalized and the campaign will not message them
+func (h *CampaignHandler) AddSuppressions(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxCSVUploadBytes)
+
+	var addedBy string
+	if identity, ok := reqctx.IdentityFrom(r.Context()); ok {
+		addedBy = identity.UserID
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			WriteValidationError(w, "a CSV file is required in the \"file\" form field")
+			return
+		}
+		defer file.Close()
+
+		if !h.authorize(w, r, campaignID) {
+			return
+		}
+
+		result, err := h.campaignService.AddSuppressionsCSV(r.Context(), campaignID, file, header.Header.Get("Content-Encoding"), addedBy)
+		if err != nil {
+			HandleServiceError(w, err)
+			return
+		}
+		WriteOK(w, result)
+
+	case "text/csv":
+		if !h.authorize(w, r, campaignID) {
+			return
+		}
+
+		result, err := h.campaignService.AddSuppressionsCSV(r.Context(), campaignID, r.Body, r.Header.Get("Content-Encoding"), addedBy)
+		if err != nil {
+			HandleServiceError(w, err)
+			return
+		}
+		WriteOK(w, result)
+
+	default:
+		var phones []string
+		if err := DecodeJSONBody(w, r, &phones, MaxCSVUploadBytes); err != nil {
+			return
+		}
+
+		if !h.authorize(w, r, campaignID) {
+			return
+		}
+
+		result, err := h.campaignService.AddSuppressions(r.Context(), campaignID, phones, addedBy)
+		if err != nil {
+			HandleServiceError(w, err)
+			return
+		}
+		WriteOK(w, result)
+	}
+}
+
+// ListSuppressions handles GET /campaigns/{id}/suppressions
+func (h *CampaignHandler) ListSuppressions(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	if !h.authorize(w, r, campaignID) {
+		return
+	}
+
+	list, err := h.campaignService.ListSuppressions(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, list)
+}
+
+// ClearSuppressions handles DELETE /campaigns/{id}/suppressions - empties the campaign's suppression list
+func (h *CampaignHandler) ClearSuppressions(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	if !h.authorize(w, r, campaignID) {
+		return
+	}
+
+	result, err := h.campaignService.ClearSuppressions(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
+
+// parseForce reads the optional force query parameter of a delete, writing an error response when invalid
+func parseForce(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	value := r.URL.Query().Get("force")
+	if value == "" {
+		return false, true
+	}
+
+	force, err := strconv.ParseBool(value)
+	if err != nil {
+		WriteValidationError(w, "force must be true or false")
+		return false, false
+	}
+	return force, true
+}
+
 // Request/Response types
 
 // ListCampaignsResponse represents the response for listing campaigns
 type ListCampaignsResponse struct {
-	Campaigns  []*models.Campaign      `json:"campaigns"`
+	Campaigns  []*CampaignResponse     `json:"campaigns"`
 	Pagination *service.PaginationInfo `json:"pagination"`
 }
 
 // SendCampaignRequest represents the request to send a campaign
 type SendCampaignRequest struct {
-	CustomerIDs []int `json:"customer_ids"`
+	CustomerIDs           []int                    `json:"customer_ids"`
+	Customers             []service.InlineCustomer `json:"customers"`
+	AllowDuplicateContent bool                     `json:"allow_duplicate_content"`
+	OverrideSaturation    bool                     `json:"override_saturation"`
+
+	// Send to canary_percent of the audience, at least canary_min customers, first and hold
+	// the rest back until POST /campaigns/{id}/continue
+	CanaryPercent *float64 `json:"canary_percent"`
+	CanaryMin     *int     `json:"canary_min"`
+
+	// Leave out customers scoring below min_engagement_score, or not yet scored
+	MinEngagementScore *float64 `json:"min_engagement_score"`
+
+	// Expire messages the worker has not sent by process_by instead of sending them late
+	ProcessBy *time.Time `json:"process_by"`
 }
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+)
+
+// campaignStatusLabels are the display names of campaign statuses
+var campaignStatusLabels = map[models.CampaignStatus]string{
+	models.CampaignStatusDraft:           "Draft",
+	models.CampaignStatusScheduled:       "Scheduled",
+	models.CampaignStatusPendingApproval: "Pending approval",
+	models.CampaignStatusSending:         "Sending",
+	models.CampaignStatusCanary:          "Canary",
+	models.CampaignStatusPaused:          "Paused",
+	models.CampaignStatusSent:            "Sent",
+	models.CampaignStatusFailed:          "Failed",
+	models.CampaignStatusCancelling:      "Cancelling",
+	models.CampaignStatusCancelled:       "Cancelled",
+	models.CampaignStatusRecurring:       "Recurring",
+}
+
+// StatusInfo tells clients how to show a campaign's status and which buttons to offer
+type StatusInfo struct {
+	Value          models.CampaignStatus   `json:"value"`
+	Label          string                  `json:"label"`
+	Terminal       bool                    `json:"terminal"`
+	AllowedActions []models.CampaignAction `json:"allowed_actions"`
+}
+
+// CampaignResponse is a campaign as returned by the API
+type CampaignResponse struct {
+	*models.Campaign
+	StatusInfo StatusInfo `json:"status_info"`
+
+	// TemplateWarnings lists likely template mistakes (only set when the campaign is created)
+	TemplateWarnings []service.TemplateWarning `json:"template_warnings,omitempty"`
+	// LinkWarning lists the links in the template (only set when the campaign is created)
+	LinkWarning *service.LinkWarning `json:"link_warning,omitempty"`
+}
+
+// CampaignWithStatsResponse is a campaign with statistics as returned by the API
+type CampaignWithStatsResponse struct {
+	*models.CampaignWithStats
+	StatusInfo StatusInfo `json:"status_info"`
+}
+
+// NewStatusInfo describes a status using the same action rules the service enforces
+func NewStatusInfo(status models.CampaignStatus) StatusInfo {
+	label, ok := campaignStatusLabels[status]
+	if !ok {
+		label = string(status)
+	}
+
+	return StatusInfo{
+		Value:          status,
+		Label:          label,
+		Terminal:       status.IsTerminal(),
+		AllowedActions: status.AllowedActions(),
+	}
+}
+
+// presentCampaign adds status info to a campaign
+func presentCampaign(campaign *models.Campaign) *CampaignResponse {
+	return &CampaignResponse{
+		Campaign:   campaign,
+		StatusInfo: NewStatusInfo(campaign.Status),
+	}
+}
+
+// presentCampaigns adds status info to each campaign
+func presentCampaigns(campaigns []*models.Campaign) []*CampaignResponse {
+	responses := make([]*CampaignResponse, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		responses = append(responses, presentCampaign(campaign))
+	}
+	return responses
+}
+
+// presentCampaignWithStats adds status info to a campaign with statistics
+func presentCampaignWithStats(campaign *models.CampaignWithStats) *CampaignWithStatsResponse {
+	return &CampaignWithStatsResponse{
+		CampaignWithStats: campaign,
+		StatusInfo:        NewStatusInfo(campaign.Status),
+	}
+}
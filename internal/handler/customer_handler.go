@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/reqctx"
+	"smsleopard/internal/service"
+)
+
+// CustomerHandler handles HTTP requests for customer operations
+type CustomerHandler struct {
+	customerService *service.CustomerService
+}
+
+// NewCustomerHandler creates a new CustomerHandler instance
+func NewCustomerHandler(customerService *service.CustomerService) *CustomerHandler {
+	return &CustomerHandler{
+		customerService: customerService,
+	}
+}
+
+// Stats handles GET /customers/stats
+// Supports optional query parameter: location (scopes the product breakdown)
+func (h *CustomerHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.customerService.GetStats(r.Context(), r.URL.Query().Get("location"))
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, stats)
+}
+
+// List handles GET /customers
+// Supports optional query parameters: page, per_page, q (phone or name substring), location,
+// product, min_score (lowest engagement score)
+func (h *CustomerHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filters, err := parseCustomerFilters(r)
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	page := 1
+	if pageStr := query.Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+
+	perPage := 20
+	if perPageStr := query.Get("per_page"); perPageStr != "" {
+		if pp, err := strconv.Atoi(perPageStr); err == nil && pp > 0 {
+			perPage = pp
+		}
+	}
+	if perPage > 100 {
+		perPage = 100
+	}
+
+	customers, pagination, err := h.customerService.ListCustomers(r.Context(), filters, page, perPage)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, CustomerListResponse{Customers: customers, Pagination: pagination})
+}
+
+// CustomerListResponse represents a page of customers
+type CustomerListResponse struct {
+	Customers  []*models.Customer      `json:"customers"`
+	Pagination *service.PaginationInfo `json:"pagination"`
+}
+
+// parseCustomerFilters reads the customer filters shared by the list and export
+func parseCustomerFilters(r *http.Request) (repository.CustomerFilters, error) {
+	query := r.URL.Query()
+	filters := repository.CustomerFilters{
+		Query:    query.Get("q"),
+		Location: query.Get("location"),
+		Product:  query.Get("product"),
+	}
+
+	if minScoreStr := query.Get("min_score"); minScoreStr != "" {
+		minScore, err := strconv.ParseFloat(minScoreStr, 64)
+		if err != nil || math.IsNaN(minScore) || math.IsInf(minScore, 0) {
+			return filters, fmt.Errorf("invalid min_score: must be a number")
+		}
+		filters.MinScore = &minScore
+	}
+
+	return filters, nil
+}
+
+// Export handles GET /customers/export.csv
+// Supports optional query parameters: q (phone or name substring), location, product, min_score
+func (h *CustomerHandler) Export(w http.ResponseWriter, r *http.Request) {
+	filters, err := parseCustomerFilters(r)
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", exportContentTypes[service.ExportFormatCSV])
+	w.Header().Set("Content-Disposition", `attachment; filename="customers.csv"`)
+
+	out := &exportWriter{ResponseWriter: w}
+	if err := h.customerService.ExportCustomersCSV(r.Context(), filters, out); err != nil {
+		if !out.written {
+			w.Header().Del("Content-Disposition")
+			HandleServiceError(w, err)
+			return
+		}
+		// Headers went out with the first row, so the stream can only end early
+		log.Printf("ERROR: Customer export stopped early: %v", err)
+	}
+}
+
+// Timeline handles GET /customers/{id}/timeline
+// Supports optional query parameters: cursor (next_cursor of the previous page), limit
+func (h *CustomerHandler) Timeline(w http.ResponseWriter, r *http.Request) {
+	customerID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+
+	var cursor *models.TimelineCursor
+	if token := query.Get("cursor"); token != "" {
+		cursor, err = models.ParseTimelineCursor(token)
+		if err != nil {
+			WriteValidationError(w, "invalid cursor: must be next_cursor from a previous page")
+			return
+		}
+	}
+
+	limit := 0
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	page, err := h.customerService.GetTimeline(r.Context(), customerID, cursor, limit)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, page)
+}
+
+// Delete handles DELETE /customers/{id}
+// A customer with messages is refused with 409 unless force=true, which deletes their messages too
+func (h *CustomerHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	customerID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	force, ok := parseForce(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.customerService.DeleteCustomer(r.Context(), customerID, force)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
+
+// Get handles GET /customers/{id}
+// Returns the customer with their block status and history
+func (h *CustomerHandler) Get(w http.ResponseWriter, r *http.Request) {
+	customerID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	detail, err := h.customerService.GetCustomer(r.Context(), customerID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, detail)
+}
+
+// Block handles POST /customers/{id}/block
+// Body: {"reason": "..."} blocks the customer from every future send
+func (h *CustomerHandler) Block(w http.ResponseWriter, r *http.Request) {
+	customerID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	var req service.BlockCustomerRequest
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+	if identity, ok := reqctx.IdentityFrom(r.Context()); ok {
+		req.BlockedBy = identity.UserID
+	}
+
+	detail, err := h.customerService.BlockCustomer(r.Context(), customerID, &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	log.Printf("⛔ Blocked customer %d", customerID)
+	WriteOK(w, detail)
+}
+
+// Unblock handles DELETE /customers/{id}/block
+func (h *CustomerHandler) Unblock(w http.ResponseWriter, r *http.Request) {
+	customerID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	unblockedBy := ""
+	if identity, ok := reqctx.IdentityFrom(r.Context()); ok {
+		unblockedBy = identity.UserID
+	}
+
+	detail, err := h.customerService.UnblockCustomer(r.Context(), customerID, unblockedBy)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	log.Printf("✅ Unblocked customer %d", customerID)
+	WriteOK(w, detail)
+}
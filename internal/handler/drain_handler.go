@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"smsleopard/internal/queue"
+)
+
+// DrainHandler handles HTTP requests that drain the worker ahead of a deploy, and its
+// readiness probe
+type DrainHandler struct {
+	drain *queue.ShutdownDrain
+}
+
+// NewDrainHandler creates a new DrainHandler instance
+func NewDrainHandler(drain *queue.ShutdownDrain) *DrainHandler {
+	return &DrainHandler{drain: drain}
+}
+
+// DrainState is the worker's shutdown drain as returned by the API
+type DrainState struct {
+	Draining bool                `json:"draining"`
+	Drained  bool                `json:"drained"`
+	Summary  *queue.DrainSummary `json:"summary,omitempty"`
+}
+
+// state returns the drain's current state
+func (h *DrainHandler) state() DrainState {
+	summary := h.drain.Summary()
+	return DrainState{
+		Draining: summary != nil && summary.FinishedAt == nil,
+		Drained:  summary != nil && summary.FinishedAt != nil,
+		Summary:  summary,
+	}
+}
+
+// Get handles GET /admin/drain
+func (h *DrainHandler) Get(w http.ResponseWriter, r *http.Request) {
+	WriteOK(w, h.state())
+}
+
+// Start handles POST /admin/drain
+// The worker stops taking messages, finishes those in flight and exits; poll GET /admin/drain
+// for progress. A second request returns the drain already under way
+func (h *DrainHandler) Start(w http.ResponseWriter, r *http.Request) {
+	if h.drain.Start() {
+		log.Printf("🚰 Drain requested over HTTP")
+	}
+	WriteJSON(w, http.StatusAccepted, h.state())
+}
+
+// Ready handles GET /ready, the readiness probe: 503 from the moment a drain starts
+func (h *DrainHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.drain.Draining() {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]bool{"ready": false})
+		return
+	}
+	WriteOK(w, map[string]bool{"ready": true})
+}
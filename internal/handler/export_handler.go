@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smsleopard/internal/service"
+)
+
+// exportContentTypes maps each export format to its Content-Type
+var exportContentTypes = map[string]string{
+	service.ExportFormatCSV:    "text/csv; charset=utf-8",
+	service.ExportFormatNDJSON: "application/x-ndjson",
+}
+
+// ExportHandler handles HTTP requests for reporting exports
+type ExportHandler struct {
+	exportService *service.ExportService
+}
+
+// NewExportHandler creates a new ExportHandler instance
+func NewExportHandler(exportService *service.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// Campaigns handles GET /campaigns/export
+// Supports optional query parameters: format (csv, ndjson), from and to (YYYY-MM-DD, inclusive)
+// Without format the Accept header picks one, defaulting to CSV
+func (h *ExportHandler) Campaigns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	req := &service.ExportCampaignsRequest{Format: query.Get("format")}
+	if req.Format == "" {
+		req.Format = negotiateExportFormat(r.Header.Get("Accept"))
+	}
+
+	for _, param := range []struct {
+		name string
+		dest **time.Time
+	}{{"from", &req.From}, {"to", &req.To}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(service.ExportDateLayout, value)
+		if err != nil {
+			WriteValidationError(w, "invalid "+param.name+": must be a date in YYYY-MM-DD format")
+			return
+		}
+		*param.dest = &parsed
+	}
+
+	if err := req.Validate(); err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", exportContentTypes[req.Format])
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": req.Filename()}))
+
+	out := &exportWriter{ResponseWriter: w}
+	if err := h.exportService.ExportCampaigns(r.Context(), req, out); err != nil {
+		if !out.written {
+			w.Header().Del("Content-Disposition")
+			HandleServiceError(w, err)
+			return
+		}
+		// Headers went out with the first row, so the stream can only end early
+		log.Printf("ERROR: Campaign export stopped early: %v", err)
+	}
+}
+
+// Compare handles GET /campaigns/compare
+// Campaigns are given as ?ids=4,9,13 (2-5 distinct IDs); deltas are against the first
+func (h *ExportHandler) Compare(w http.ResponseWriter, r *http.Request) {
+	// Accept both comma-separated and repeated ids
+	ids := []int{}
+	for _, value := range r.URL.Query()["ids"] {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || id <= 0 {
+				WriteValidationError(w, "ids must be positive integers")
+				return
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	if err := service.ValidateCompareIDs(ids); err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	comparison, err := h.exportService.CompareCampaigns(r.Context(), ids)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, comparison)
+}
+
+// exportWriter records whether any of the export has reached the client
+type exportWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends buffered rows to the client when the connection supports it
+func (w *exportWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// negotiateExportFormat picks an export format from an Accept header
+func negotiateExportFormat(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/x-ndjson", "application/ndjson":
+			return service.ExportFormatNDJSON
+		case "text/csv":
+			return service.ExportFormatCSV
+		}
+	}
+	return service.ExportFormatCSV
+}
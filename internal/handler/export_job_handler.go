@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/reqctx"
+	"smsleopard/internal/service"
+)
+
+// ExportJobHandler handles HTTP requests for background campaign message exports
+type ExportJobHandler struct {
+	exportJobService *service.ExportJobService
+}
+
+// NewExportJobHandler creates a new ExportJobHandler instance
+func NewExportJobHandler(exportJobService *service.ExportJobService) *ExportJobHandler {
+	return &ExportJobHandler{
+		exportJobService: exportJobService,
+	}
+}
+
+// Create handles POST /campaigns/{id}/exports - queues an export of the campaign's messages
+func (h *ExportJobHandler) Create(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	var requestedBy string
+	if identity, ok := reqctx.IdentityFrom(r.Context()); ok {
+		requestedBy = identity.UserID
+	}
+
+	job, err := h.exportJobService.CreateJob(r.Context(), campaignID, requestedBy)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", exportJobPath(job))
+	WriteJSON(w, http.StatusAccepted, newExportJobResponse(job, time.Now()))
+}
+
+// Get handles GET /campaigns/{id}/exports/{job_id} - reports an export's progress and,
+// once complete, its download URL
+func (h *ExportJobHandler) Get(w http.ResponseWriter, r *http.Request) {
+	campaignID, jobID, ok := exportJobIDs(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.exportJobService.GetJob(r.Context(), campaignID, jobID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, newExportJobResponse(job, time.Now()))
+}
+
+// Download handles GET /campaigns/{id}/exports/{job_id}/download - streams a completed export
+// Range requests are supported, so an interrupted download resumes where it stopped
+func (h *ExportJobHandler) Download(w http.ResponseWriter, r *http.Request) {
+	campaignID, jobID, ok := exportJobIDs(w, r)
+	if !ok {
+		return
+	}
+
+	job, file, err := h.exportJobService.OpenDownload(r.Context(), campaignID, jobID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Warning: Failed to close export file: %v", err)
+		}
+	}()
+
+	// The ETag lets If-Range confirm a resumed download continues the same file
+	w.Header().Set("ETag", fmt.Sprintf(`"export-%d-%d"`, job.ID, job.CompletedAt.Unix()))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="campaign-%d-messages.csv"`, job.CampaignID))
+	http.ServeContent(w, r, "", *job.CompletedAt, file)
+}
+
+// exportJobIDs parses the campaign and export job IDs of a request, writing an error response when invalid
+func exportJobIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return 0, 0, false
+	}
+
+	jobID, err := ParseIDParam(r, "job_id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return 0, 0, false
+	}
+	return campaignID, jobID, true
+}
+
+// exportJobPath is the status URL of an export job
+func exportJobPath(job *models.ExportJob) string {
+	return fmt.Sprintf("/campaigns/%d/exports/%d", job.CampaignID, job.ID)
+}
+
+// ExportJobResponse is an export job with its progress and, while downloadable, its download URL
+type ExportJobResponse struct {
+	*models.ExportJob
+	Progress    float64 `json:"progress"`
+	DownloadURL *string `json:"download_url,omitempty"`
+}
+
+// newExportJobResponse builds the response for a job as of now
+func newExportJobResponse(job *models.ExportJob, now time.Time) *ExportJobResponse {
+	response := &ExportJobResponse{
+		ExportJob: job,
+		Progress:  job.Progress(),
+	}
+	if job.Status == models.ExportJobCompleted && job.FileName != nil && !job.Expired(now) {
+		url := exportJobPath(job) + "/download"
+		response.DownloadURL = &url
+	}
+	return response
+}
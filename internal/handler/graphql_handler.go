@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"smsleopard/internal/graph"
+)
+
+// MaxGraphQLRequestBytes limits the size of a POSTed query document
+const MaxGraphQLRequestBytes = 64 << 10
+
+// GraphQLHandler handles HTTP requests for the read-only GraphQL endpoint
+type GraphQLHandler struct {
+	executor *graph.Executor
+}
+
+// NewGraphQLHandler creates a new GraphQLHandler instance
+func NewGraphQLHandler(executor *graph.Executor) *GraphQLHandler {
+	return &GraphQLHandler{
+		executor: executor,
+	}
+}
+
+// GraphQLRequest is the standard GraphQL-over-HTTP request body
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Query handles GET and POST /graphql
+// GET takes query, operationName and variables (JSON) as URL parameters; POST takes a JSON body
+// Query errors are reported in the response's errors array with 200 OK, as GraphQL clients expect
+func (h *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	var req GraphQLRequest
+
+	if r.Method == http.MethodGet {
+		params := r.URL.Query()
+		req.Query = params.Get("query")
+		req.OperationName = params.Get("operationName")
+		if variables := params.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				WriteValidationError(w, "variables must be a JSON object")
+				return
+			}
+		}
+	} else {
+		if err := DecodeJSONBody(w, r, &req, MaxGraphQLRequestBytes); err != nil {
+			return
+		}
+	}
+
+	if req.Query == "" {
+		WriteValidationError(w, "query is required")
+		return
+	}
+
+	response := h.executor.Execute(r.Context(), req.Query, req.OperationName, req.Variables)
+	WriteOK(w, response)
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"smsleopard/internal/service"
@@ -23,9 +22,7 @@ func NewHealthHandler(healthService *service.HealthChecker) *HealthHandler {
 func (h *HealthHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	// Only accept GET requests
 	if r.Method != http.MethodGet {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{
+		WriteJSON(w, http.StatusMethodNotAllowed, map[string]string{
 			"error": "Method not allowed",
 		})
 		return
@@ -35,31 +32,21 @@ func (h *HealthHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	healthStatus, err := h.healthService.CheckHealth()
 	if err != nil {
 		// Handle health check error with 500 status
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
+		WriteJSON(w, http.StatusInternalServerError, map[string]string{
 			"error": "Failed to perform health check",
 		})
 		return
 	}
 
-	// Set Content-Type header
-	w.Header().Set("Content-Type", "application/json")
-
 	// Determine HTTP status code based on health status
+	status := http.StatusInternalServerError
 	switch healthStatus.Status {
 	case service.StatusHealthy:
-		w.WriteHeader(http.StatusOK)
+		status = http.StatusOK
 	case service.StatusDegraded, service.StatusUnhealthy:
-		w.WriteHeader(http.StatusServiceUnavailable)
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
+		status = http.StatusServiceUnavailable
 	}
 
-	// Encode and send health status response
-	if err := json.NewEncoder(w).Encode(healthStatus); err != nil {
-		// If encoding fails, log it but response is already sent
-		// Nothing we can do at this point
-		return
-	}
+	// Encode and send health status response; if encoding fails the status is already sent
+	WriteJSON(w, status, healthStatus)
 }
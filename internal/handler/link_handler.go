@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"smsleopard/internal/service"
+)
+
+// LinkHandler handles visits to tracked links in sent messages
+type LinkHandler struct {
+	linkService *service.LinkService
+}
+
+// NewLinkHandler creates a new LinkHandler instance
+func NewLinkHandler(linkService *service.LinkService) *LinkHandler {
+	return &LinkHandler{linkService: linkService}
+}
+
+// Redirect handles GET /r/{token}
+// It records the click and redirects to the original link; unknown tokens are a plain 404
+// since the visitor is a message recipient, not an API client
+func (h *LinkHandler) Redirect(w http.ResponseWriter, r *http.Request) {
+	url, err := h.linkService.Click(r.Context(), mux.Vars(r)["token"], r.UserAgent())
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+	if url == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.Redirect(w, r, url, http.StatusFound)
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"smsleopard/internal/reqctx"
+	"smsleopard/internal/service"
+)
+
+// MessageReassignHandler handles HTTP requests that move messages between campaigns
+type MessageReassignHandler struct {
+	reassigner *service.MessageReassigner
+}
+
+// NewMessageReassignHandler creates a new MessageReassignHandler instance
+func NewMessageReassignHandler(reassigner *service.MessageReassigner) *MessageReassignHandler {
+	return &MessageReassignHandler{reassigner: reassigner}
+}
+
+// Reassign handles POST /admin/messages/reassign
+// Body: {"from_campaign_id": 1, "to_campaign_id": 2, "status": "pending"} moves the first
+// campaign's pending messages to the second and returns the audit entry
+func (h *MessageReassignHandler) Reassign(w http.ResponseWriter, r *http.Request) {
+	var req service.ReassignMessagesRequest
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+	if identity, ok := reqctx.IdentityFrom(r.Context()); ok {
+		req.RequestedBy = identity.UserID
+	}
+
+	reassignment, err := h.reassigner.Reassign(r.Context(), &req, nil)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	log.Printf("🔀 Moved %d %s messages from campaign %d to campaign %d", reassignment.MessagesMoved, reassignment.Status, reassignment.FromCampaignID, reassignment.ToCampaignID)
+	WriteOK(w, reassignment)
+}
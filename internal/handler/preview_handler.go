@@ -1,13 +1,9 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"smsleopard/internal/service"
-
-	"github.com/gorilla/mux"
 )
 
 // PreviewHandler handles HTTP requests for message preview functionality
@@ -23,39 +19,36 @@ func NewPreviewHandler(campaignService *service.CampaignService) *PreviewHandler
 }
 
 // PreviewRequest represents the request body for message preview
+// Exactly one of CustomerID and Customer is given
 type PreviewRequest struct {
-	CustomerID       int     `json:"customer_id"`
-	OverrideTemplate *string `json:"override_template,omitempty"`
+	CustomerID       int                     `json:"customer_id"`
+	Customer         *service.InlineCustomer `json:"customer,omitempty"` // Made-up customer, never stored
+	OverrideTemplate *string                 `json:"override_template,omitempty"`
+	Strict           bool                    `json:"strict,omitempty"` // Fail on template lint warnings
 }
 
 // Preview handles POST /campaigns/{id}/personalized-preview
-// It previews how a message will render for a specific customer
+// It previews how a message will render for a stored customer or one given inline
 func (h *PreviewHandler) Preview(w http.ResponseWriter, r *http.Request) {
-	// Extract campaign ID from URL
-	campaignIDStr := mux.Vars(r)["id"]
-
-	// Convert campaign ID to integer and validate
-	campaignID, err := strconv.Atoi(campaignIDStr)
+	campaignID, err := ParseIDParam(r, "id")
 	if err != nil {
-		WriteError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid campaign ID")
-		return
-	}
-
-	if campaignID <= 0 {
-		WriteError(w, http.StatusBadRequest, "VALIDATION_ERROR", "campaign ID must be positive")
+		WriteValidationError(w, err.Error())
 		return
 	}
 
 	// Parse JSON body
 	var req PreviewRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteError(w, http.StatusBadRequest, "INVALID_JSON", "invalid request body")
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
 		return
 	}
 
-	// Validate customer_id
-	if req.CustomerID <= 0 {
-		WriteError(w, http.StatusBadRequest, "VALIDATION_ERROR", "customer_id is required and must be positive")
+	// Validate customer_id, or the inline customer given instead
+	if req.Customer != nil && req.CustomerID != 0 {
+		WriteError(w, http.StatusBadRequest, "VALIDATION_ERROR", "give either customer_id or customer, not both")
+		return
+	}
+	if req.Customer == nil && req.CustomerID <= 0 {
+		WriteError(w, http.StatusBadRequest, "VALIDATION_ERROR", "customer_id is required and must be positive, unless a customer is given inline")
 		return
 	}
 
@@ -63,7 +56,9 @@ func (h *PreviewHandler) Preview(w http.ResponseWriter, r *http.Request) {
 	previewReq := &service.PreviewMessageRequest{
 		CampaignID:       campaignID,
 		CustomerID:       req.CustomerID,
+		Customer:         req.Customer,
 		OverrideTemplate: req.OverrideTemplate,
+		Strict:           req.Strict,
 	}
 
 	// Call service to generate preview
@@ -77,3 +72,44 @@ func (h *PreviewHandler) Preview(w http.ResponseWriter, r *http.Request) {
 	// Return success response with preview result
 	WriteOK(w, result)
 }
+
+// PreviewDiffRequest represents the request body for a template preview diff
+type PreviewDiffRequest struct {
+	CustomerID  int    `json:"customer_id"`
+	NewTemplate string `json:"new_template"`
+}
+
+// PreviewDiff handles POST /campaigns/{id}/preview-diff
+// It renders the current and a proposed template side by side for a customer
+func (h *PreviewHandler) PreviewDiff(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Parse JSON body
+	var req PreviewDiffRequest
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	// Validate customer_id
+	if req.CustomerID <= 0 {
+		WriteError(w, http.StatusBadRequest, "VALIDATION_ERROR", "customer_id is required and must be positive")
+		return
+	}
+
+	// Call service to render both templates
+	result, err := h.campaignService.PreviewDiff(r.Context(), &service.PreviewDiffRequest{
+		CampaignID:  campaignID,
+		CustomerID:  req.CustomerID,
+		NewTemplate: req.NewTemplate,
+	})
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"smsleopard/internal/service"
+)
+
+// QueueStatusHandler handles HTTP requests for the send backlog new sends are admitted under
+type QueueStatusHandler struct {
+	admission *service.SendAdmission
+}
+
+// NewQueueStatusHandler creates a new QueueStatusHandler instance
+func NewQueueStatusHandler(admission *service.SendAdmission) *QueueStatusHandler {
+	return &QueueStatusHandler{admission: admission}
+}
+
+// Get handles GET /admin/queue-status
+// It reports the send queue depth and never-published backlog against their thresholds,
+// and whether new sends are being refused
+func (h *QueueStatusHandler) Get(w http.ResponseWriter, r *http.Request) {
+	status, err := h.admission.Status(r.Context())
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, status)
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"smsleopard/internal/maintenance"
+)
+
+// ReadOnlyHandler handles HTTP requests that show and toggle read-only mode
+type ReadOnlyHandler struct {
+	mode *maintenance.ReadOnly
+}
+
+// NewReadOnlyHandler creates a new ReadOnlyHandler instance
+func NewReadOnlyHandler(mode *maintenance.ReadOnly) *ReadOnlyHandler {
+	return &ReadOnlyHandler{mode: mode}
+}
+
+// ReadOnlyState is the read-only mode as returned by the API
+type ReadOnlyState struct {
+	ReadOnly bool `json:"read_only"`
+}
+
+// Get handles GET /admin/read-only
+func (h *ReadOnlyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	WriteOK(w, ReadOnlyState{ReadOnly: h.mode.Enabled()})
+}
+
+// Set handles POST /admin/read-only
+// Body: {"read_only": true} refuses writes until {"read_only": false}
+func (h *ReadOnlyHandler) Set(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ReadOnly *bool `json:"read_only"`
+	}
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+	if req.ReadOnly == nil {
+		WriteValidationError(w, "read_only is required")
+		return
+	}
+
+	if h.mode.Set(*req.ReadOnly) {
+		log.Printf("🔒 Read-only mode set to %t", *req.ReadOnly)
+	}
+
+	WriteOK(w, ReadOnlyState{ReadOnly: h.mode.Enabled()})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"smsleopard/internal/service"
+)
+
+// ReadinessHandler handles HTTP requests for the pre-send checklist
+type ReadinessHandler struct {
+	readinessService *service.ReadinessService
+}
+
+// NewReadinessHandler creates a new ReadinessHandler instance
+func NewReadinessHandler(readinessService *service.ReadinessService) *ReadinessHandler {
+	return &ReadinessHandler{
+		readinessService: readinessService,
+	}
+}
+
+// Readiness handles GET /campaigns/{id}/readiness
+// The audience is given as ?customer_ids=1,2,3 (optional for campaigns awaiting approval)
+func (h *ReadinessHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Accept both comma-separated and repeated customer_ids
+	customerIDs := []int{}
+	for _, value := range r.URL.Query()["customer_ids"] {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || id <= 0 {
+				WriteValidationError(w, "customer_ids must be positive integers")
+				return
+			}
+			customerIDs = append(customerIDs, id)
+		}
+	}
+
+	result, err := h.readinessService.CheckReadiness(r.Context(), campaignID, customerIDs)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
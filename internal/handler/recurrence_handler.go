@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"smsleopard/internal/service"
+)
+
+// RecurrenceHandler handles HTTP requests for recurring campaigns
+type RecurrenceHandler struct {
+	recurrenceService *service.RecurrenceService
+	campaignService   *service.CampaignService
+}
+
+// NewRecurrenceHandler creates a new RecurrenceHandler instance
+func NewRecurrenceHandler(recurrenceService *service.RecurrenceService, campaignService *service.CampaignService) *RecurrenceHandler {
+	return &RecurrenceHandler{
+		recurrenceService: recurrenceService,
+		campaignService:   campaignService,
+	}
+}
+
+// Recur handles POST /campaigns/{id}/recurrence - makes a draft or scheduled campaign recurring
+// Body: {"rule": {"frequency": "weekly", "day_of_week": 5, "time": "09:00", "timezone": "Africa/Nairobi"}, "customer_ids": [1, 2]}
+func (h *RecurrenceHandler) Recur(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	var req service.RecurCampaignRequest
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+	if !authorizeCampaign(w, r, h.campaignService, campaignID) {
+		return
+	}
+
+	recurrence, err := h.recurrenceService.Recur(r.Context(), campaignID, &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	log.Printf("🔁 Campaign %d is recurring %s, first occurrence at %s", campaignID, recurrence.Rule.Frequency, recurrence.NextOccurrenceAt)
+	WriteCreated(w, recurrence)
+}
+
+// Get handles GET /campaigns/{id}/recurrence
+func (h *RecurrenceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	recurrence, err := h.recurrenceService.GetRecurrence(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, recurrence)
+}
+
+// Pause handles POST /campaigns/{id}/recurrence/pause
+func (h *RecurrenceHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+	if !authorizeCampaign(w, r, h.campaignService, campaignID) {
+		return
+	}
+
+	recurrence, err := h.recurrenceService.Pause(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, recurrence)
+}
+
+// Resume handles POST /campaigns/{id}/recurrence/resume
+func (h *RecurrenceHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+	if !authorizeCampaign(w, r, h.campaignService, campaignID) {
+		return
+	}
+
+	recurrence, err := h.recurrenceService.Resume(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, recurrence)
+}
+
+// Occurrences handles GET /campaigns/{id}/occurrences - the campaigns sent for a recurring
+// campaign, newest first
+func (h *RecurrenceHandler) Occurrences(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	list, err := h.recurrenceService.ListOccurrences(r.Context(), campaignID)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, list)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// MaxJSONBodyBytes limits the size of a JSON request body unless a handler needs a different limit
+const MaxJSONBodyBytes = 1 << 20
+
+// ErrEmptyBody is returned by DecodeJSONBody for a request with no body
+var ErrEmptyBody = errors.New("request body is empty")
+
+// ParseIDParam reads the named route variable as an ID, which must be a positive integer
+// The error is a message for the client, written with WriteValidationError
+func ParseIDParam(r *http.Request, name string) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", name)
+	}
+	return id, nil
+}
+
+// DecodeJSONBody decodes a JSON request body of at most maxBytes into dst
+// On failure it writes the error response and returns the error, so the handler only returns:
+// 400 INVALID_JSON for an empty (ErrEmptyBody) or malformed body, 413 REQUEST_TOO_LARGE for one over maxBytes
+func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	err := decodeJSON(w, r, dst, maxBytes)
+	if err != nil {
+		writeBodyError(w, err, maxBytes)
+	}
+	return err
+}
+
+// DecodeOptionalJSONBody is DecodeJSONBody for a body that may be left out: an empty body
+// leaves dst as it is and is not an error
+func DecodeOptionalJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	err := decodeJSON(w, r, dst, maxBytes)
+	if errors.Is(err, ErrEmptyBody) {
+		return nil
+	}
+	if err != nil {
+		writeBodyError(w, err, maxBytes)
+	}
+	return err
+}
+
+// decodeJSON decodes a body of at most maxBytes into dst without writing a response
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		if err == io.EOF {
+			return ErrEmptyBody
+		}
+		return err
+	}
+	return nil
+}
+
+// writeBodyError writes the response for a body that could not be decoded
+func writeBodyError(w http.ResponseWriter, err error, maxBytes int64) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrEmptyBody):
+		WriteError(w, http.StatusBadRequest, "INVALID_JSON", "Request body is empty")
+	case errors.As(err, &tooLarge):
+		WriteError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", fmt.Sprintf("Request body is larger than %d bytes", maxBytes))
+	default:
+		WriteError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON format")
+	}
+}
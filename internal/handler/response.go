@@ -1,59 +1,35 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 
+	"smsleopard/internal/config"
+	"smsleopard/internal/httpjson"
+	"smsleopard/internal/models"
 	"smsleopard/internal/service"
 )
 
 // ErrorResponse represents the standard error response structure
-type ErrorResponse struct {
-	Error ErrorDetail `json:"error"`
-}
+type ErrorResponse = httpjson.ErrorResponse
 
 // ErrorDetail contains the error code and message
-type ErrorDetail struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-}
+type ErrorDetail = httpjson.ErrorDetail
 
 // WriteJSON writes a JSON response with the given status code
-// It sets the Content-Type header, writes the status code, and encodes the data to JSON
+// It sets the Content-Type header (application/json; charset=utf-8), writes the status code,
+// and encodes the data to JSON
 func WriteJSON(w http.ResponseWriter, status int, data interface{}) error {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-
-	if data == nil {
-		return nil
-	}
-
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("ERROR: Failed to encode JSON response: %v", err)
-		return err
-	}
-
-	return nil
+	return httpjson.Write(w, status, data)
 }
 
 // WriteError writes a structured JSON error response
 // It creates an ErrorResponse with the given code and message
 func WriteError(w http.ResponseWriter, status int, code, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-
-	errResp := ErrorResponse{
-		Error: ErrorDetail{
-			Code:    code,
-			Message: message,
-		},
-	}
-
-	if err := json.NewEncoder(w).Encode(errResp); err != nil {
-		log.Printf("ERROR: Failed to write error response: %v", err)
-	}
+	httpjson.WriteError(w, status, code, message)
 }
 
 // WriteCreated writes a 201 Created response with the given data
@@ -76,6 +52,16 @@ func WriteValidationError(w http.ResponseWriter, message string) {
 	WriteError(w, http.StatusBadRequest, "VALIDATION_ERROR", message)
 }
 
+// WriteRuleViolations writes a 400 Bad Request response with VALIDATION_ERROR code, listing
+// each broken customer rule under errors
+func WriteRuleViolations(w http.ResponseWriter, message string, violations []*config.CustomerRuleViolation) {
+	fieldErrors := make([]httpjson.FieldError, len(violations))
+	for i, violation := range violations {
+		fieldErrors[i] = httpjson.FieldError{Field: violation.Field, Rule: violation.Rule, Message: violation.Message}
+	}
+	httpjson.WriteFieldErrors(w, http.StatusBadRequest, "VALIDATION_ERROR", message, fieldErrors)
+}
+
 // WriteNotFoundError writes a 404 Not Found response with RESOURCE_NOT_FOUND code
 func WriteNotFoundError(w http.ResponseWriter, resource string, id int) {
 	message := fmt.Sprintf("%s with ID %d not found", resource, id)
@@ -93,23 +79,77 @@ func WriteBusinessLogicError(w http.ResponseWriter, message string) {
 	WriteError(w, http.StatusBadRequest, "BUSINESS_LOGIC_ERROR", message)
 }
 
+// WriteInvalidTransitionError writes a 422 Unprocessable Entity response with INVALID_STATUS_TRANSITION code
+func WriteInvalidTransitionError(w http.ResponseWriter, message string) {
+	WriteError(w, http.StatusUnprocessableEntity, "INVALID_STATUS_TRANSITION", message)
+}
+
+// WriteDuplicateContentError writes a 422 Unprocessable Entity response with DUPLICATE_CONTENT code
+func WriteDuplicateContentError(w http.ResponseWriter, message string) {
+	WriteError(w, http.StatusUnprocessableEntity, "DUPLICATE_CONTENT", message)
+}
+
+// WriteUnsupportedChannelError writes a 422 Unprocessable Entity response with UNSUPPORTED_CHANNEL code
+func WriteUnsupportedChannelError(w http.ResponseWriter, message string) {
+	WriteError(w, http.StatusUnprocessableEntity, "UNSUPPORTED_CHANNEL", message)
+}
+
+// WriteForbiddenError writes a 403 Forbidden response with FORBIDDEN code
+func WriteForbiddenError(w http.ResponseWriter, message string) {
+	WriteError(w, http.StatusForbidden, "FORBIDDEN", message)
+}
+
 // WriteConflictError writes a 409 Conflict response with CONFLICT code
 func WriteConflictError(w http.ResponseWriter, message string) {
 	WriteError(w, http.StatusConflict, "CONFLICT", message)
 }
 
+// WriteQueueSaturatedError writes a 503 Service Unavailable for a send refused while the
+// send backlog is saturated, with a Retry-After hint in seconds
+func WriteQueueSaturatedError(w http.ResponseWriter, err *service.QueueSaturatedError) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(err.RetryAfter.Seconds())))
+	WriteError(w, http.StatusServiceUnavailable, "QUEUE_SATURATED", err.Error())
+}
+
+// WriteSendingDisabledError writes a 503 Service Unavailable for a send refused while all
+// outbound sending is switched off
+func WriteSendingDisabledError(w http.ResponseWriter, err *service.SendingDisabledError) {
+	WriteError(w, http.StatusServiceUnavailable, "SENDING_DISABLED", err.Error())
+}
+
 // HandleServiceError maps service layer errors to appropriate HTTP responses
 // It uses type assertions to determine the error type and calls the appropriate write function
 func HandleServiceError(w http.ResponseWriter, err error) {
+	// Transition errors come up from the repository wrapped in context
+	var transitionErr *models.InvalidTransitionError
+	if errors.As(err, &transitionErr) {
+		WriteInvalidTransitionError(w, transitionErr.Error())
+		return
+	}
+
 	switch e := err.(type) {
 	case *service.NotFoundError:
 		WriteNotFoundError(w, e.Resource, e.ID)
+	case *service.WorkerNotFoundError:
+		WriteError(w, http.StatusNotFound, "RESOURCE_NOT_FOUND", e.Error())
 	case *service.ValidationError:
+		if len(e.Violations) > 0 {
+			WriteRuleViolations(w, e.Message, e.Violations)
+			return
+		}
 		WriteValidationError(w, e.Message)
 	case *service.BusinessLogicError:
 		WriteBusinessLogicError(w, e.Message)
 	case *service.ConflictError:
 		WriteConflictError(w, e.Message)
+	case *service.DuplicateContentError:
+		WriteDuplicateContentError(w, e.Error())
+	case *service.UnsupportedChannelError:
+		WriteUnsupportedChannelError(w, e.Error())
+	case *service.QueueSaturatedError:
+		WriteQueueSaturatedError(w, e)
+	case *service.SendingDisabledError:
+		WriteSendingDisabledError(w, e)
 	default:
 		// Log the actual error for debugging
 		log.Printf("ERROR: Unhandled service error: %v", err)
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/maintenance"
+	"smsleopard/internal/metrics"
+	"smsleopard/internal/middleware"
+	"smsleopard/internal/service"
+)
+
+// RouterDeps holds the handlers the API serves, the maintenance switch its write guard reads
+// and the issued keys its authentication accepts
+type RouterDeps struct {
+	Health          *HealthHandler
+	Campaign        *CampaignHandler
+	Preview         *PreviewHandler
+	Template        *TemplateHandler
+	Webhook         *WebhookHandler
+	Simulation      *SimulationHandler
+	Readiness       *ReadinessHandler
+	Customer        *CustomerHandler
+	Admin           *AdminHandler
+	Export          *ExportHandler
+	ExportJob       *ExportJobHandler
+	CampaignEvent   *CampaignEventHandler
+	Stats           *StatsHandler
+	ReadOnly        *ReadOnlyHandler
+	QueueStatus     *QueueStatusHandler
+	Link            *LinkHandler
+	MessageReassign *MessageReassignHandler
+	GraphQL         *GraphQLHandler
+	Worker          *WorkerHandler
+	APIKey          *APIKeyHandler
+	Sending         *SendingHandler
+	Recurrence      *RecurrenceHandler
+
+	ReadOnlyMode *maintenance.ReadOnly
+	IssuedKeys   middleware.IssuedKeys // Keys issued at runtime, accepted alongside API_KEYS (may be nil)
+}
+
+// BuildRouter creates the API router: every route behind the middleware stack the server
+// runs with, so tests exercise the same recovery, authentication and body checks
+func BuildRouter(deps *RouterDeps, cfg *config.Config) *mux.Router {
+	router := mux.NewRouter()
+
+	// Apply middleware
+	// Give every request an ID first, so each later middleware can log it
+	router.Use(middleware.RequestID)
+	router.Use(middleware.Recovery)
+	router.Use(middleware.Logger)
+
+	// Legacy clients get camelCase keys in a {"data", "error"} envelope when they ask for it
+	router.Use(middleware.LegacyFormat)
+
+	// Refuse writes during maintenance windows (READ_ONLY, or toggled at runtime)
+	router.Use(middleware.RejectWritesWhenReadOnly(deps.ReadOnlyMode, "/health", "/admin/read-only", "/graphql"))
+
+	// Request bodies must be JSON, except the CSV and multipart uploads
+	router.Use(middleware.RequireJSONBody("/campaigns/{id:[0-9]+}/send-csv", "/campaigns/{id:[0-9]+}/suppressions"))
+
+	// Health endpoint (public, no authentication)
+	router.HandleFunc("/health", deps.Health.HandleHealth).Methods("GET")
+
+	// Prometheus metrics
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Tracked link redirects (public, followed by message recipients)
+	router.HandleFunc(service.LinkRedirectPrefix+"{token}", deps.Link.Redirect).Methods("GET")
+
+	// Everything else requires an API key when API_KEYS is set or keys have been issued
+	api := router.PathPrefix("/").Subrouter()
+	api.Use(middleware.Authenticate(cfg.Auth.APIKeys, deps.IssuedKeys))
+
+	// Campaign routes
+	api.HandleFunc("/campaigns", deps.Campaign.Create).Methods("POST")
+	api.HandleFunc("/campaigns", deps.Campaign.List).Methods("GET")
+	api.HandleFunc("/campaigns/export", deps.Export.Campaigns).Methods("GET")
+	api.HandleFunc("/campaigns/compare", deps.Export.Compare).Methods("GET")
+	api.HandleFunc("/campaigns/{id:[0-9]+}", deps.Campaign.GetByID).Methods("GET")
+	api.HandleFunc("/campaigns/{id:[0-9]+}", deps.Campaign.Delete).Methods("DELETE")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/send", deps.Campaign.Send).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/send-csv", deps.Campaign.SendCSV).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/send-history", deps.Campaign.SendHistory).Methods("GET")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/events", deps.CampaignEvent.Events).Methods("GET")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/suppressions", deps.Campaign.AddSuppressions).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/suppressions", deps.Campaign.ListSuppressions).Methods("GET")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/suppressions", deps.Campaign.ClearSuppressions).Methods("DELETE")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/exports", deps.ExportJob.Create).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/exports/{job_id:[0-9]+}", deps.ExportJob.Get).Methods("GET")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/exports/{job_id:[0-9]+}/download", deps.ExportJob.Download).Methods("GET", "HEAD")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/simulate", deps.Simulation.Simulate).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/eta", deps.Simulation.ETA).Methods("GET")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/readiness", deps.Readiness.Readiness).Methods("GET")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/re-render", deps.Campaign.ReRender).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/resume", deps.Campaign.Resume).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/cancel", deps.Campaign.Cancel).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/undo-cancel", deps.Campaign.UndoCancel).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/continue", deps.Campaign.Continue).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/abort", deps.Campaign.Abort).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/placeholder-coverage", deps.Campaign.PlaceholderCoverage).Methods("POST")
+
+	// Recurring campaigns
+	api.HandleFunc("/campaigns/{id:[0-9]+}/recurrence", deps.Recurrence.Recur).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/recurrence", deps.Recurrence.Get).Methods("GET")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/recurrence/pause", deps.Recurrence.Pause).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/recurrence/resume", deps.Recurrence.Resume).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/occurrences", deps.Recurrence.Occurrences).Methods("GET")
+
+	// Messages
+	api.HandleFunc("/messages/{id:[0-9]+}/resend", deps.Campaign.ResendMessage).Methods("POST")
+
+	// Approval routes (admin key required)
+	requireAdmin := middleware.RequireAdminKey(cfg.Admin.APIKey)
+	api.Handle("/campaigns/{id:[0-9]+}/approve", requireAdmin(http.HandlerFunc(deps.Campaign.Approve))).Methods("POST")
+	api.Handle("/campaigns/{id:[0-9]+}/reject", requireAdmin(http.HandlerFunc(deps.Campaign.Reject))).Methods("POST")
+
+	// Customer routes
+	api.HandleFunc("/customers", deps.Customer.List).Methods("GET")
+	api.HandleFunc("/customers/stats", deps.Customer.Stats).Methods("GET")
+	api.HandleFunc("/customers/export.csv", deps.Customer.Export).Methods("GET")
+	api.HandleFunc("/customers/{id:[0-9]+}", deps.Customer.Get).Methods("GET")
+	api.HandleFunc("/customers/{id:[0-9]+}", deps.Customer.Delete).Methods("DELETE")
+	api.HandleFunc("/customers/{id:[0-9]+}/timeline", deps.Customer.Timeline).Methods("GET")
+	api.Handle("/customers/{id:[0-9]+}/block", requireAdmin(http.HandlerFunc(deps.Customer.Block))).Methods("POST")
+	api.Handle("/customers/{id:[0-9]+}/block", requireAdmin(http.HandlerFunc(deps.Customer.Unblock))).Methods("DELETE")
+
+	// Cross-campaign stats
+	api.HandleFunc("/stats/retry-effectiveness", deps.Stats.RetryEffectiveness).Methods("GET")
+
+	// Template checks
+	api.HandleFunc("/templates/validate", deps.Template.Validate).Methods("POST")
+	api.HandleFunc("/webhooks/validate-template", deps.Webhook.ValidateTemplate).Methods("POST")
+
+	// Preview route
+	api.HandleFunc("/campaigns/{id:[0-9]+}/personalized-preview", deps.Preview.Preview).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/preview-diff", deps.Preview.PreviewDiff).Methods("POST")
+
+	// Admin routes
+	api.HandleFunc("/admin/campaigns/attention", deps.Admin.CampaignsNeedingAttention).Methods("GET")
+	api.HandleFunc("/admin/processing-errors", deps.Admin.ProcessingErrors).Methods("GET")
+	api.HandleFunc("/admin/messages/pending", deps.Stats.PendingBacklog).Methods("GET")
+	api.HandleFunc("/admin/queue-status", deps.QueueStatus.Get).Methods("GET")
+	api.HandleFunc("/admin/workers/{id}/activity", deps.Worker.Activity).Methods("GET")
+	api.HandleFunc("/admin/read-only", deps.ReadOnly.Get).Methods("GET")
+	api.Handle("/admin/read-only", requireAdmin(http.HandlerFunc(deps.ReadOnly.Set))).Methods("POST")
+	api.Handle("/admin/messages/reassign", requireAdmin(http.HandlerFunc(deps.MessageReassign.Reassign))).Methods("POST")
+	api.Handle("/admin/api-keys", requireAdmin(http.HandlerFunc(deps.APIKey.Create))).Methods("POST")
+	api.Handle("/admin/api-keys", requireAdmin(http.HandlerFunc(deps.APIKey.List))).Methods("GET")
+	api.Handle("/admin/api-keys/{id:[0-9]+}", requireAdmin(http.HandlerFunc(deps.APIKey.Revoke))).Methods("DELETE")
+	api.HandleFunc("/admin/sending", deps.Sending.Get).Methods("GET")
+	api.Handle("/admin/sending/enable", requireAdmin(http.HandlerFunc(deps.Sending.Enable))).Methods("POST")
+	api.Handle("/admin/sending/disable", requireAdmin(http.HandlerFunc(deps.Sending.Disable))).Methods("POST")
+
+	// Read-only GraphQL queries over campaigns, customers and messages
+	api.HandleFunc("/graphql", deps.GraphQL.Query).Methods("GET", "POST")
+
+	return router
+}
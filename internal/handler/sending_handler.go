@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"smsleopard/internal/reqctx"
+	"smsleopard/internal/service"
+)
+
+// SendingHandler handles HTTP requests that show and toggle the kill switch for all outbound sending
+type SendingHandler struct {
+	sending *service.SendingSwitch
+}
+
+// NewSendingHandler creates a new SendingHandler instance
+func NewSendingHandler(sending *service.SendingSwitch) *SendingHandler {
+	return &SendingHandler{sending: sending}
+}
+
+// Get handles GET /admin/sending
+// It reports whether outbound sending is on, with its recent changes
+func (h *SendingHandler) Get(w http.ResponseWriter, r *http.Request) {
+	status, err := h.sending.Status(r.Context())
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, status)
+}
+
+// Enable handles POST /admin/sending/enable
+// Body: {"reason": "..."} turns outbound sending back on
+func (h *SendingHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	h.set(w, r, true)
+}
+
+// Disable handles POST /admin/sending/disable
+// Body: {"reason": "..."} stops all outbound sending until it is enabled again
+func (h *SendingHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.set(w, r, false)
+}
+
+// set enables or disables sending, recording the reason and caller
+func (h *SendingHandler) set(w http.ResponseWriter, r *http.Request, enabled bool) {
+	var req service.SetSendingRequest
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+	if identity, ok := reqctx.IdentityFrom(r.Context()); ok {
+		req.Actor = identity.UserID
+	}
+
+	var status *service.SendingStatus
+	var err error
+	if enabled {
+		status, err = h.sending.Enable(r.Context(), &req)
+	} else {
+		status, err = h.sending.Disable(r.Context(), &req)
+	}
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	if enabled {
+		log.Printf("▶️  Outbound sending enabled: %s", req.Reason)
+	} else {
+		log.Printf("🛑 Outbound sending disabled: %s", req.Reason)
+	}
+	WriteOK(w, status)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"smsleopard/internal/service"
+)
+
+// SimulationHandler handles HTTP requests for campaign send simulations
+type SimulationHandler struct {
+	simulationService *service.SimulationService
+}
+
+// NewSimulationHandler creates a new SimulationHandler instance
+func NewSimulationHandler(simulationService *service.SimulationService) *SimulationHandler {
+	return &SimulationHandler{
+		simulationService: simulationService,
+	}
+}
+
+// Simulate handles POST /campaigns/{id}/simulate
+// It estimates duration, expected failures and cost without sending anything
+func (h *SimulationHandler) Simulate(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Parse JSON body
+	var req service.SimulateCampaignRequest
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	// Call service to run the simulation
+	result, err := h.simulationService.Simulate(r.Context(), campaignID, &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	// Return 200 OK
+	WriteOK(w, result)
+}
+
+// ETA handles GET /campaigns/{id}/eta
+// It projects when the campaign will finish sending given the rate limit, workers and quiet hours
+func (h *SimulationHandler) ETA(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Audience size for campaigns that have not been sent yet
+	audienceSize := 0
+	if value := r.URL.Query().Get("audience_size"); value != "" {
+		audienceSize, err = strconv.Atoi(value)
+		if err != nil {
+			WriteValidationError(w, "audience_size must be an integer")
+			return
+		}
+	}
+
+	result, err := h.simulationService.EstimateCompletion(r.Context(), campaignID, audienceSize)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, result)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"smsleopard/internal/service"
+)
+
+// StatsHandler handles HTTP requests for cross-campaign statistics
+type StatsHandler struct {
+	statsService *service.StatsService
+}
+
+// NewStatsHandler creates a new StatsHandler instance
+func NewStatsHandler(statsService *service.StatsService) *StatsHandler {
+	return &StatsHandler{statsService: statsService}
+}
+
+// RetryEffectiveness handles GET /stats/retry-effectiveness
+// Supports optional query parameters: from, to (RFC3339; defaults to the last 30 days)
+func (h *StatsHandler) RetryEffectiveness(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var from, to *time.Time
+	for _, param := range []struct {
+		name string
+		dest **time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			WriteValidationError(w, "invalid "+param.name+": must be an RFC3339 timestamp")
+			return
+		}
+		*param.dest = &parsed
+	}
+
+	report, err := h.statsService.RetryEffectiveness(r.Context(), from, to)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, report)
+}
+
+// PendingBacklog handles GET /admin/messages/pending
+// It reports pending messages queued for the worker apart from those never published
+func (h *StatsHandler) PendingBacklog(w http.ResponseWriter, r *http.Request) {
+	report, err := h.statsService.PendingBacklog(r.Context())
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, report)
+}
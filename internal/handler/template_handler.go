@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+)
+
+// TemplateHandler handles HTTP requests for checking templates before they are used
+type TemplateHandler struct {
+	templateService *service.TemplateService
+}
+
+// NewTemplateHandler creates a new TemplateHandler instance
+func NewTemplateHandler(templateService *service.TemplateService) *TemplateHandler {
+	return &TemplateHandler{templateService: templateService}
+}
+
+// ValidateTemplateRequest represents the request body for validating a template
+type ValidateTemplateRequest struct {
+	Template string                 `json:"template"`
+	Strict   bool                   `json:"strict"`           // Report lint warnings as errors
+	Syntax   *models.TemplateSyntax `json:"syntax,omitempty"` // Placeholder style to read the template in (server default when omitted)
+}
+
+// Validate handles POST /templates/validate
+// It reports syntax errors and lint warnings with suggested fixes; an invalid template is still a 200
+func (h *TemplateHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	var req ValidateTemplateRequest
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	if req.Syntax != nil && !req.Syntax.IsValid() {
+		WriteValidationError(w, "invalid syntax: must be braces, double_braces, brackets or percent")
+		return
+	}
+
+	WriteOK(w, h.templateService.ForSyntax(req.Syntax).Check(req.Template, req.Strict))
+}
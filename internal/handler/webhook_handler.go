@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"smsleopard/internal/notify"
+)
+
+// WebhookHandler handles HTTP requests for checking webhook configuration
+type WebhookHandler struct{}
+
+// NewWebhookHandler creates a new WebhookHandler instance
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{}
+}
+
+// ValidatePayloadTemplateRequest represents the request body for validating a webhook payload template
+type ValidatePayloadTemplateRequest struct {
+	PayloadTemplate string `json:"payload_template"`
+}
+
+// PayloadTemplateCheck is the result of validating a webhook payload template
+type PayloadTemplateCheck struct {
+	Valid  bool            `json:"valid"`
+	Errors []string        `json:"errors"`
+	Sample json.RawMessage `json:"sample,omitempty"` // The body a sample event renders to
+}
+
+// ValidateTemplate handles POST /webhooks/validate-template
+// It compiles the template and renders a sample event, as the API and worker do at
+// startup for EVENT_WEBHOOK_TEMPLATE; an invalid template is still a 200
+func (h *WebhookHandler) ValidateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req ValidatePayloadTemplateRequest
+	if err := DecodeJSONBody(w, r, &req, MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	payloadTemplate, err := notify.ParsePayloadTemplate(req.PayloadTemplate)
+	if err != nil {
+		WriteOK(w, PayloadTemplateCheck{Valid: false, Errors: []string{err.Error()}})
+		return
+	}
+
+	WriteOK(w, PayloadTemplateCheck{Valid: true, Errors: []string{}, Sample: payloadTemplate.Sample()})
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"smsleopard/internal/service"
+)
+
+// WorkerHandler handles HTTP requests about message workers
+type WorkerHandler struct {
+	activityService *service.WorkerActivityService
+}
+
+// NewWorkerHandler creates a new WorkerHandler instance
+func NewWorkerHandler(activityService *service.WorkerActivityService) *WorkerHandler {
+	return &WorkerHandler{activityService: activityService}
+}
+
+// Activity handles GET /admin/workers/{id}/activity
+// It returns the worker's last heartbeat, its claims' outcomes over the last hour and day,
+// and its most recent claims
+// Supports optional query parameter: limit (recent claims, default 50, at most 500)
+func (h *WorkerHandler) Activity(w http.ResponseWriter, r *http.Request) {
+	workerID := mux.Vars(r)["id"]
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			WriteValidationError(w, "invalid limit: must be a positive integer")
+			return
+		}
+		limit = parsed
+	}
+
+	activity, err := h.activityService.GetActivity(r.Context(), workerID, limit)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	WriteOK(w, activity)
+}
@@ -0,0 +1,73 @@
+// Package httpjson writes JSON responses with the API's standard Content-Type and error shape
+// It sits below both the handlers and the middleware so every response is written the same way
+package httpjson
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// ContentType is the Content-Type of every JSON response
+const ContentType = "application/json; charset=utf-8"
+
+// ErrorResponse represents the standard error response structure
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail contains the error code and message
+// Errors lists each problem separately when a request has several, such as broken customer rules
+type ErrorDetail struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is one field's problem within an error response
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// Write sets the Content-Type header, writes the status code, and encodes data as JSON
+// A nil data writes no body
+func Write(w http.ResponseWriter, status int, data interface{}) error {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+
+	if data == nil {
+		return nil
+	}
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("ERROR: Failed to encode JSON response: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// WriteError writes a structured JSON error response with the given code and message
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	WriteFieldErrors(w, status, code, message, nil)
+}
+
+// WriteFieldErrors writes a structured JSON error response listing each field's problem
+func WriteFieldErrors(w http.ResponseWriter, status int, code, message string, fieldErrors []FieldError) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+
+	errResp := ErrorResponse{
+		Error: ErrorDetail{
+			Code:    code,
+			Message: message,
+			Errors:  fieldErrors,
+		},
+	}
+
+	if err := json.NewEncoder(w).Encode(errResp); err != nil {
+		log.Printf("ERROR: Failed to write error response: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package maintenance
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ReadOnly is the switch that stops the API and worker writing during a maintenance window
+// It is safe for concurrent use; a nil ReadOnly is never enabled
+type ReadOnly struct {
+	enabled atomic.Bool
+
+	mu    sync.Mutex
+	hooks []func(enabled bool)
+}
+
+// NewReadOnly creates a switch in the given state (READ_ONLY at startup)
+func NewReadOnly(enabled bool) *ReadOnly {
+	r := &ReadOnly{}
+	r.enabled.Store(enabled)
+	return r
+}
+
+// Enabled reports whether writes are currently refused
+func (r *ReadOnly) Enabled() bool {
+	return r != nil && r.enabled.Load()
+}
+
+// Set turns read-only mode on or off and reports whether that changed it
+// Hooks run, in the order added, only on a change
+func (r *ReadOnly) Set(enabled bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.enabled.Swap(enabled) == enabled {
+		return false
+	}
+	for _, hook := range r.hooks {
+		hook(enabled)
+	}
+	return true
+}
+
+// OnChange adds a hook called with the new state whenever the mode changes
+func (r *ReadOnly) OnChange(hook func(enabled bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, hook)
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// DBStatsInterval is how often connection pool statistics are sampled
+const DBStatsInterval = 10 * time.Second
+
+// DBStatsSampler copies a connection pool's statistics into the pool metrics
+// A slow API with connections all in use and waits climbing is pool exhaustion; with
+// connections to spare it is slow queries
+type DBStatsSampler struct {
+	name string
+	db   *sql.DB
+	last sql.DBStats
+}
+
+// NewDBStatsSampler creates a sampler for db, labelled name in the metrics
+func NewDBStatsSampler(name string, db *sql.DB) *DBStatsSampler {
+	return &DBStatsSampler{name: name, db: db}
+}
+
+// Sample records the pool's current statistics
+// Waits are cumulative in sql.DBStats, so the counters are advanced by the change since the
+// previous sample
+func (s *DBStatsSampler) Sample() {
+	stats := s.db.Stats()
+
+	DBConnectionsInUse.WithLabelValues(s.name).Set(float64(stats.InUse))
+	DBConnectionsIdle.WithLabelValues(s.name).Set(float64(stats.Idle))
+	DBConnectionsMaxOpen.WithLabelValues(s.name).Set(float64(stats.MaxOpenConnections))
+	if waits := stats.WaitCount - s.last.WaitCount; waits > 0 {
+		DBConnectionWaits.WithLabelValues(s.name).Add(float64(waits))
+	}
+	if waited := stats.WaitDuration - s.last.WaitDuration; waited > 0 {
+		DBConnectionWaitSeconds.WithLabelValues(s.name).Add(waited.Seconds())
+	}
+
+	s.last = stats
+}
+
+// Run samples every interval until ctx is cancelled, starting at once
+func (s *DBStatsSampler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Sample()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
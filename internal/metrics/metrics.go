@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// latencyBuckets covers 50ms up to roughly 28 minutes
+var latencyBuckets = prometheus.ExponentialBuckets(0.05, 2, 16)
+
+// MessageQueueLatency measures time from job publish to successful send
+var MessageQueueLatency = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "smsleopard_message_queue_latency_seconds",
+		Help:    "Time from a message job being published to the message being sent",
+		Buckets: latencyBuckets,
+	},
+	[]string{"channel"},
+)
+
+// MessageTotalLatency measures time from message creation to successful send
+var MessageTotalLatency = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "smsleopard_message_total_latency_seconds",
+		Help:    "Time from a message being created by /send to the message being sent",
+		Buckets: latencyBuckets,
+	},
+	[]string{"channel"},
+)
+
+// SkippedMessages counts jobs acknowledged without sending because a record is gone, the message
+// was already sent or claimed by another worker, its campaign was cancelled, its customer
+// was blocked, all outbound sending was disabled, the customer was recently sent the same content
+// or its send's deadline had passed
+var SkippedMessages = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "smsleopard_worker_skipped_messages_total",
+		Help: "Message jobs skipped because the message, campaign or customer no longer exists, the message was already sent or claimed by another worker, its campaign was cancelled, its customer was blocked, all outbound sending was disabled, the customer was recently sent the same content or its send's deadline had passed",
+	},
+	[]string{"reason"},
+)
+
+// UnrecordedSends counts sends whose message was deleted, e.g. with its campaign, before
+// the outcome could be recorded; the job is acknowledged since the send already happened
+var UnrecordedSends = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "smsleopard_worker_unrecorded_sends_total",
+		Help: "Sends whose message no longer existed when the outcome was recorded, by outcome (sent or failed)",
+	},
+	[]string{"outcome"},
+)
+
+// ProcessingErrors counts message jobs that failed and were requeued, by error class
+var ProcessingErrors = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "smsleopard_worker_processing_errors_total",
+		Help: "Message jobs that returned an error and were requeued, labelled by error class (infra, render, send, panic, timeout)",
+	},
+	[]string{"error_class"},
+)
+
+// WorkerDrains counts times a worker stopped consuming after repeated infrastructure failures
+var WorkerDrains = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "smsleopard_worker_drains_total",
+		Help: "Times the worker stopped consuming after consecutive infrastructure failures",
+	},
+)
+
+// WorkerDraining is 1 while the worker is drained waiting for its dependencies to recover
+var WorkerDraining = promauto.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "smsleopard_worker_draining",
+		Help: "1 while the worker has stopped consuming until the database and broker are healthy again",
+	},
+)
+
+// QueryDuration measures database statement time by query fingerprint
+var QueryDuration = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "smsleopard_db_query_duration_seconds",
+		Help:    "Time spent executing database statements, labelled by a short query fingerprint",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+	},
+	[]string{"query"},
+)
+
+// RepositoryCallDuration measures repository method calls, including row iteration and any
+// transaction, labelled by repository and method
+var RepositoryCallDuration = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "smsleopard_repository_call_duration_seconds",
+		Help:    "Time spent in repository method calls, labelled by repository and method",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+	},
+	[]string{"repository", "method"},
+)
+
+// DBConnectionsInUse, DBConnectionsIdle and DBConnectionsMaxOpen are the connection pool's
+// state at its last sample, labelled by pool (primary, replica, processing_errors)
+var (
+	DBConnectionsInUse = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "smsleopard_db_connections_in_use",
+			Help: "Database connections currently in use, labelled by pool",
+		},
+		[]string{"db"},
+	)
+	DBConnectionsIdle = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "smsleopard_db_connections_idle",
+			Help: "Idle database connections, labelled by pool",
+		},
+		[]string{"db"},
+	)
+	DBConnectionsMaxOpen = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "smsleopard_db_connections_max_open",
+			Help: "Maximum open database connections (0 is unlimited), labelled by pool",
+		},
+		[]string{"db"},
+	)
+)
+
+// DBConnectionWaits and DBConnectionWaitSeconds count waits for a free pooled connection
+// and the time spent waiting, labelled by pool
+var (
+	DBConnectionWaits = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smsleopard_db_connection_waits_total",
+			Help: "Times a statement waited for a free database connection, labelled by pool",
+		},
+		[]string{"db"},
+	)
+	DBConnectionWaitSeconds = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smsleopard_db_connection_wait_seconds_total",
+			Help: "Time spent waiting for a free database connection, labelled by pool",
+		},
+		[]string{"db"},
+	)
+)
+
+// DroppedEvents counts lifecycle events that never reached a sink
+var DroppedEvents = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "smsleopard_events_dropped_total",
+		Help: "Lifecycle events dropped because the sink was unavailable or its queue was full",
+	},
+	[]string{"sink"},
+)
+
+// CarrierThrottleWait measures how long sends wait for their destination prefix's bucket
+var CarrierThrottleWait = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "smsleopard_carrier_throttle_wait_seconds",
+		Help:    "Time a send waited for its per-prefix rate or concurrency limit, labelled by bucket",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+	},
+	[]string{"bucket"},
+)
+
+// PublishPacingRate is the rate the publish reconciler is publishing at, from the gap before
+// its latest publish; 0 between batches
+var PublishPacingRate = promauto.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "smsleopard_publish_pacing_rate",
+		Help: "Instantaneous rate in jobs per second the publish reconciler publishes a batch at (0 when idle)",
+	},
+)
+
+// PublishPacingPublished counts the jobs the publish reconciler published on its paced schedule
+var PublishPacingPublished = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "smsleopard_publish_pacing_published_total",
+		Help: "Jobs the publish reconciler published spread over its interval",
+	},
+)
+
+// ObserveMessageLatency records queue and total latency for a sent message
+func ObserveMessageLatency(channel string, queueLatency time.Duration, hasQueueLatency bool, totalLatency time.Duration) {
+	if hasQueueLatency {
+		MessageQueueLatency.WithLabelValues(channel).Observe(queueLatency.Seconds())
+	}
+	MessageTotalLatency.WithLabelValues(channel).Observe(totalLatency.Seconds())
+}
+
+// Handler returns the HTTP handler serving metrics in Prometheus format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"smsleopard/internal/httpjson"
+)
+
+// AdminKeyHeader is the header carrying the admin API key
+const AdminKeyHeader = "X-Admin-Key"
+
+// RequireAdminKey is middleware that only allows requests carrying the admin API key
+// All requests are rejected when no key is configured
+func RequireAdminKey(apiKey string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := r.Header.Get(AdminKeyHeader)
+			if apiKey == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) != 1 {
+				httpjson.WriteError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Valid admin key required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
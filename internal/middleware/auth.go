@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/httpjson"
+	"smsleopard/internal/models"
+	"smsleopard/internal/reqctx"
+)
+
+// APIKeyHeader is the header carrying the caller's API key
+const APIKeyHeader = "X-API-Key"
+
+// IssuedKeys looks up API keys issued at runtime, kept alongside the configured bootstrap keys
+type IssuedKeys interface {
+	Lookup(key string) *models.APIKey
+}
+
+// Authenticate is middleware that resolves the caller from the X-API-Key header, checked
+// against the configured keys and then the issued ones (issued may be nil)
+// Authentication is on when bootstrap keys are configured, so issuing or revoking keys never
+// turns it on or off; without any, all requests pass through without an identity
+func Authenticate(keys []config.APIKey, issued IssuedKeys) func(http.Handler) http.Handler {
+	enabled := len(keys) > 0
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			provided := r.Header.Get(APIKeyHeader)
+			identity := lookupAPIKey(keys, provided)
+			if identity == nil && issued != nil && provided != "" {
+				if key := issued.Lookup(provided); key != nil {
+					identity = &reqctx.Identity{UserID: key.UserID, Role: key.Role}
+					if key.Team != nil {
+						identity.Team = *key.Team
+					}
+				}
+			}
+			if identity == nil {
+				httpjson.WriteError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Valid API key required")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(reqctx.WithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+// lookupAPIKey finds the identity for a key, comparing against every key in constant time
+func lookupAPIKey(keys []config.APIKey, provided string) *reqctx.Identity {
+	var identity *reqctx.Identity
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(key.Key)) == 1 {
+			identity = &reqctx.Identity{UserID: key.UserID, Team: key.Team, Role: key.Role}
+		}
+	}
+	return identity
+}
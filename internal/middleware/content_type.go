@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+
+	"smsleopard/internal/httpjson"
+
+	"github.com/gorilla/mux"
+)
+
+// RequireJSONBody is middleware that refuses POST, PUT, PATCH and DELETE bodies that are not
+// application/json with 415. Writes without a body pass, as do routes whose path template
+// is exempt (e.g. CSV and multipart uploads, which check their own Content-Type)
+func RequireJSONBody(exempt ...string) func(http.Handler) http.Handler {
+	exemptTemplates := make(map[string]bool, len(exempt))
+	for _, template := range exempt {
+		exemptTemplates[template] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isWrite(r.Method) && r.ContentLength != 0 && !exemptTemplates[routeTemplate(r)] {
+				mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if err != nil || mediaType != "application/json" {
+					httpjson.WriteError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "Content-Type must be application/json")
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeTemplate returns the path template of the matched route, or the request path without one
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// LegacyMediaType is the Accept type that selects the legacy response format
+const LegacyMediaType = "application/vnd.smsleopard.legacy+json"
+
+// LegacyFormatParam is the query parameter value (?format=legacy) that selects the legacy response format
+const LegacyFormatParam = "legacy"
+
+// WantsLegacyFormat reports whether a request asked for the legacy response format,
+// by Accept: application/vnd.smsleopard.legacy+json or ?format=legacy
+func WantsLegacyFormat(r *http.Request) bool {
+	if r.URL.Query().Get("format") == LegacyFormatParam {
+		return true
+	}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == LegacyMediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// LegacyFormat is middleware that serves JSON responses in the format clients of the replaced
+// system expect when they ask for it: keys in camelCase inside a {"data": ..., "error": null}
+// envelope, and errors as {"data": null, "error": {"code": ..., "message": ...}}
+// Handlers are unaware of it; other requests, and responses that are not JSON, pass through
+func LegacyFormat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !WantsLegacyFormat(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		lw := &legacyWriter{ResponseWriter: w}
+		next.ServeHTTP(lw, r)
+		lw.finish()
+	})
+}
+
+// legacyWriter holds back JSON responses so they can be rewritten in the legacy format
+type legacyWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	rewrite     bool
+	body        bytes.Buffer
+}
+
+// WriteHeader decides from the Content-Type whether the response is rewritten
+func (w *legacyWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if mediaType == "application/json" {
+		w.rewrite = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *legacyWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.rewrite {
+		return w.body.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush passes through for streamed responses, which are never rewritten
+func (w *legacyWriter) Flush() {
+	if w.rewrite {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// finish writes the held-back response in the legacy format
+func (w *legacyWriter) finish() {
+	if !w.rewrite {
+		return
+	}
+
+	body, err := LegacyBody(w.status, w.body.Bytes())
+	if err != nil {
+		// Better the original response than none
+		log.Printf("ERROR: Failed to rewrite response in legacy format: %v", err)
+		body = bytes.TrimRight(w.body.Bytes(), "\n")
+	} else {
+		w.Header().Set("Content-Type", LegacyMediaType)
+	}
+
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(append(body, '\n'))
+}
+
+// LegacyBody rewrites a JSON response body in the legacy format
+// Error statuses carrying the standard {"error": {...}} body become the legacy error;
+// anything else becomes the data
+func LegacyBody(status int, body []byte) ([]byte, error) {
+	data, err := CamelCaseKeys(body)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := struct {
+		Data  json.RawMessage `json:"data"`
+		Error json.RawMessage `json:"error"`
+	}{
+		Data:  json.RawMessage("null"),
+		Error: json.RawMessage("null"),
+	}
+
+	if status >= http.StatusBadRequest {
+		var errResp struct {
+			Error json.RawMessage `json:"error"`
+		}
+		if json.Unmarshal(data, &errResp) == nil && len(errResp.Error) > 0 {
+			envelope.Error = errResp.Error
+			return json.Marshal(envelope)
+		}
+	}
+
+	if len(data) > 0 {
+		envelope.Data = data
+	}
+	return json.Marshal(envelope)
+}
+
+// CamelCaseKeys rewrites every object key of a JSON document from snake_case to camelCase,
+// keeping key order and values as they are; an empty document stays empty
+func CamelCaseKeys(body []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var out bytes.Buffer
+	// Per open container: whether it is an object, and how many tokens it has had
+	type container struct {
+		object bool
+		tokens int
+	}
+	stack := []container{}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if len(stack) > 0 {
+				return nil, fmt.Errorf("invalid JSON: %w", io.ErrUnexpectedEOF)
+			}
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			out.WriteRune(rune(delim))
+			continue
+		}
+
+		isKey := false
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			isKey = top.object && top.tokens%2 == 0
+			switch {
+			case isKey && top.tokens > 0, !top.object && top.tokens > 0:
+				out.WriteByte(',')
+			case !isKey && top.object:
+				out.WriteByte(':')
+			}
+			top.tokens++
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			out.WriteRune(rune(v))
+			stack = append(stack, container{object: v == '{'})
+		case string:
+			if isKey {
+				v = camelCase(v)
+			}
+			encoded, _ := json.Marshal(v)
+			out.Write(encoded)
+		case json.Number:
+			out.WriteString(v.String())
+		case bool:
+			out.WriteString(strconv.FormatBool(v))
+		case nil:
+			out.WriteString("null")
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// camelCase turns a snake_case key such as "skipped_frequency_cap" into "skippedFrequencyCap"
+func camelCase(key string) string {
+	if !strings.Contains(key, "_") {
+		return key
+	}
+
+	var b strings.Builder
+	upper := false
+	for i, r := range key {
+		if r == '_' && i > 0 {
+			upper = true
+			continue
+		}
+		if upper {
+			b.WriteString(strings.ToUpper(string(r)))
+			upper = false
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"smsleopard/internal/reqctx"
 )
 
 // responseWriter wraps http.ResponseWriter to capture the status code
@@ -36,7 +38,8 @@ func Logger(next http.Handler) http.Handler {
 
 		// Log after response
 		duration := time.Since(start)
-		log.Printf("[%s] %s %d %v",
+		log.Printf("%s[%s] %s %d %v",
+			reqctx.Prefix(r.Context()),
 			r.Method,
 			r.URL.Path,
 			wrapped.statusCode,
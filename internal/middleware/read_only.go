@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"smsleopard/internal/httpjson"
+	"smsleopard/internal/maintenance"
+)
+
+// ReadOnlyRetryAfter is the Retry-After (seconds) sent with writes refused in read-only mode
+const ReadOnlyRetryAfter = 120
+
+// RejectWritesWhenReadOnly is middleware that refuses POST, PUT, PATCH and DELETE with 503
+// while read-only mode is on; reads, and the exempt paths, are always served
+func RejectWritesWhenReadOnly(mode *maintenance.ReadOnly, exempt ...string) func(http.Handler) http.Handler {
+	exemptPaths := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if mode.Enabled() && isWrite(r.Method) && !exemptPaths[r.URL.Path] {
+				w.Header().Set("Retry-After", strconv.Itoa(ReadOnlyRetryAfter))
+				httpjson.WriteError(w, http.StatusServiceUnavailable, "SERVICE_READ_ONLY", "The service is read-only for maintenance; try again later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isWrite reports whether a request method changes data
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
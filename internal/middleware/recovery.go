@@ -3,6 +3,9 @@ package middleware
 import (
 	"log"
 	"net/http"
+
+	"smsleopard/internal/httpjson"
+	"smsleopard/internal/reqctx"
 )
 
 // Recovery is middleware that recovers from panics and returns a 500 error
@@ -11,12 +14,10 @@ func Recovery(next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				// Log the panic with details
-				log.Printf("PANIC: %v", err)
+				log.Printf("%sPANIC: %v", reqctx.Prefix(r.Context()), err)
 
 				// Return 500 error to client
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(`{"error":{"code":"INTERNAL_ERROR","message":"Internal server error"}}`))
+				httpjson.WriteError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
 			}
 		}()
 
@@ -0,0 +1,282 @@
+// Package migrate applies and rolls back the SQL schema migrations in a directory,
+// tracking them in the schema_migrations table
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// LockKey is the Postgres advisory lock held while migrating, so replicas starting together
+// apply each migration once: the others wait, then find nothing left to do
+const LockKey int64 = 0x736d736c656f7061 // "smsleopa"
+
+// Migration is a migration file and whether it has been applied
+type Migration struct {
+	Version   int
+	Name      string
+	FilePath  string // Empty for an applied migration whose file is gone
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// filePattern matches migration file names such as 001_create_customers.sql
+var filePattern = regexp.MustCompile(`^(\d{3})_(.+)\.sql$`)
+
+// Up applies the pending migrations in dir in version order, each in its own transaction,
+// and returns those applied; when one fails, those applied before it are returned with the error
+func Up(db *sql.DB, dir string) ([]Migration, error) {
+	var applied []Migration
+	err := withLock(db, func(ctx context.Context, conn *sql.Conn) error {
+		migrations, err := status(ctx, conn, dir)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if migration.Applied {
+				continue
+			}
+			if err := apply(ctx, conn, migration); err != nil {
+				return fmt.Errorf("failed to apply migration %03d_%s: %w", migration.Version, migration.Name, err)
+			}
+			migration.Applied = true
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back up to steps of the most recently applied migrations, newest first, and
+// returns those rolled back; when one fails, those rolled back before it are returned with the error
+func Down(db *sql.DB, dir string, steps int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := withLock(db, func(ctx context.Context, conn *sql.Conn) error {
+		migrations, err := status(ctx, conn, dir)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			migration := migrations[i]
+			if !migration.Applied {
+				continue
+			}
+			if err := rollback(ctx, conn, migration.Version); err != nil {
+				return fmt.Errorf("failed to rollback migration %03d_%s: %w", migration.Version, migration.Name, err)
+			}
+			migration.Applied = false
+			migration.AppliedAt = nil
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status returns every migration in dir and every applied one, in version order, with
+// whether and when it was applied
+func Status(db *sql.DB, dir string) ([]Migration, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := createTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	return status(ctx, conn, dir)
+}
+
+// Files returns the migration files in dir in version order; a missing dir has none
+func Files(dir string) ([]Migration, error) {
+	var migrations []Migration
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return migrations, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := filePattern.FindStringSubmatch(entry.Name())
+		if len(matches) != 3 {
+			continue
+		}
+		version, err := strconv.Atoi(matches[1])
+		if err != nil {
+			continue
+		}
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     matches[2],
+			FilePath: filepath.Join(dir, entry.Name()),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// withLock runs fn on one connection holding the advisory lock, creating the tracking
+// table first
+func withLock(db *sql.DB, fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Session-level, so it is held across the per-migration transactions
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", LockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", LockKey)
+
+	if err := createTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(ctx, conn)
+}
+
+// createTable creates the schema_migrations tracking table
+func createTable(ctx context.Context, conn *sql.Conn) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`
+
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return nil
+}
+
+// status merges the files in dir with the applied migrations
+func status(ctx context.Context, conn *sql.Conn, dir string) ([]Migration, error) {
+	files, err := Files(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]Migration)
+	for rows.Next() {
+		var m Migration
+		if err := rows.Scan(&m.Version, &m.Name, &m.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration row: %w", err)
+		}
+		m.Applied = true
+		applied[m.Version] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating applied migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	for _, file := range files {
+		if m, ok := applied[file.Version]; ok {
+			file.Applied = true
+			file.AppliedAt = m.AppliedAt
+			delete(applied, file.Version)
+		}
+		migrations = append(migrations, file)
+	}
+	for _, m := range applied {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// apply runs a migration file and records it in one transaction
+func apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	content, err := os.ReadFile(migration.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read migration file: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(content)); err != nil {
+		return fmt.Errorf("failed to execute migration SQL: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+		migration.Version,
+		migration.Name,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// rollback runs a migration's rollback and forgets it in one transaction
+func rollback(ctx context.Context, conn *sql.Conn, version int) error {
+	dropSQL, ok := rollbackSQL[version]
+	if !ok {
+		return fmt.Errorf("no rollback defined for migration version %d", version)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, dropSQL); err != nil {
+		return fmt.Errorf("failed to execute rollback SQL: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", version); err != nil {
+		return fmt.Errorf("failed to remove migration record: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package migrate
+
+// rollbackSQL undoes each migration, by version; a new migration needs an entry here
+var rollbackSQL = map[int]string{
+	1: "DROP TABLE IF EXISTS customers CASCADE;",
+	2: "DROP TABLE IF EXISTS campaigns CASCADE;",
+	3: "DROP TABLE IF EXISTS outbound_messages CASCADE;",
+	4: "ALTER TABLE outbound_messages DROP COLUMN IF EXISTS published_at;",
+	5: `
+		UPDATE campaigns SET status = 'draft' WHERE status = 'pending_approval';
+		ALTER TABLE campaigns DROP COLUMN IF EXISTS send_plan;
+		ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS campaigns_status_check;
+		ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+			CHECK (status IN ('draft', 'scheduled', 'sending', 'sent', 'failed'));`,
+	6: `
+		DROP INDEX IF EXISTS idx_campaigns_tags;
+		ALTER TABLE campaigns DROP COLUMN IF EXISTS tags;`,
+	7: `
+		ALTER TABLE customers
+			ALTER COLUMN first_name TYPE VARCHAR(100) USING LEFT(first_name, 100),
+			ALTER COLUMN last_name TYPE VARCHAR(100) USING LEFT(last_name, 100),
+			ALTER COLUMN location TYPE VARCHAR(100) USING LEFT(location, 100),
+			ALTER COLUMN preferred_product TYPE VARCHAR(200) USING LEFT(preferred_product, 200);`,
+	8: `
+		DROP INDEX IF EXISTS idx_campaigns_created_by;
+		ALTER TABLE campaigns DROP COLUMN IF EXISTS team;
+		ALTER TABLE campaigns DROP COLUMN IF EXISTS created_by;`,
+	9: `ALTER TABLE outbound_messages DROP COLUMN IF EXISTS simulated;`,
+	10: `
+		DROP INDEX IF EXISTS idx_outbound_messages_deliver_after;
+		ALTER TABLE outbound_messages DROP COLUMN IF EXISTS deliver_after;
+		DROP TABLE IF EXISTS customer_daily_attempts;`,
+	11: `
+		ALTER TABLE outbound_messages DROP CONSTRAINT IF EXISTS outbound_messages_campaign_id_fkey;
+		ALTER TABLE outbound_messages ADD CONSTRAINT outbound_messages_campaign_id_fkey
+			FOREIGN KEY (campaign_id) REFERENCES campaigns(id) ON DELETE CASCADE;
+		ALTER TABLE outbound_messages DROP CONSTRAINT IF EXISTS outbound_messages_customer_id_fkey;
+		ALTER TABLE outbound_messages ADD CONSTRAINT outbound_messages_customer_id_fkey
+			FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE;`,
+	12: `
+		DROP INDEX IF EXISTS idx_outbound_messages_fingerprint;
+		ALTER TABLE outbound_messages DROP COLUMN IF EXISTS content_fingerprint;`,
+	13: "DROP TABLE IF EXISTS processing_errors CASCADE;",
+	14: `
+		ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_contact_window_check;
+		ALTER TABLE customers DROP COLUMN IF EXISTS contact_window_end;
+		ALTER TABLE customers DROP COLUMN IF EXISTS contact_window_start;`,
+	15: "ALTER TABLE outbound_messages DROP COLUMN IF EXISTS backfilled;",
+	16: `
+		UPDATE outbound_messages SET deliver_after = CURRENT_TIMESTAMP WHERE deliver_after = 'infinity';
+		UPDATE campaigns SET status = 'sending' WHERE status = 'paused';
+		ALTER TABLE campaigns DROP COLUMN IF EXISTS paused_reason;
+		ALTER TABLE campaigns DROP COLUMN IF EXISTS spend;
+		ALTER TABLE campaigns DROP COLUMN IF EXISTS budget;
+		ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS campaigns_status_check;
+		ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+			CHECK (status IN ('draft', 'scheduled', 'pending_approval', 'sending', 'sent', 'failed'));`,
+	17: "DROP TABLE IF EXISTS campaign_sends_log CASCADE;",
+	18: `
+		DROP INDEX IF EXISTS idx_outbound_messages_frequency_cap;
+		ALTER TABLE campaigns DROP COLUMN IF EXISTS frequency_cap_exempt;`,
+	19: "DROP TABLE IF EXISTS campaign_suppressions CASCADE;",
+	20: "DROP TABLE IF EXISTS activity_digests CASCADE;",
+	21: "DROP TABLE IF EXISTS export_jobs CASCADE;",
+	22: "DROP INDEX IF EXISTS idx_outbound_messages_unpublished;",
+	23: "DROP TABLE IF EXISTS campaign_events CASCADE; DROP FUNCTION IF EXISTS notify_campaign_event();",
+	24: `
+		DROP TABLE IF EXISTS link_clicks CASCADE;
+		DROP TABLE IF EXISTS tracked_links CASCADE;
+		ALTER TABLE campaigns DROP COLUMN IF EXISTS track_links;`,
+	25: "DROP TABLE IF EXISTS message_reassignments CASCADE;",
+	26: "DROP TABLE IF EXISTS campaign_send_metrics CASCADE;",
+	27: "ALTER TABLE campaigns DROP COLUMN IF EXISTS template_syntax;",
+	28: `
+		UPDATE outbound_messages SET status = 'failed', last_error = 'Campaign cancelled' WHERE status = 'cancelled';
+		UPDATE campaigns SET status = 'sending' WHERE status = 'cancelling';
+		UPDATE campaigns SET status = 'failed' WHERE status = 'cancelled';
+		DROP INDEX IF EXISTS idx_campaigns_cancelling;
+		ALTER TABLE campaigns DROP COLUMN IF EXISTS cancel_at;
+		ALTER TABLE outbound_messages DROP CONSTRAINT IF EXISTS outbound_messages_status_check;
+		ALTER TABLE outbound_messages ADD CONSTRAINT outbound_messages_status_check
+			CHECK (status IN ('pending', 'sent', 'failed'));
+		ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS campaigns_status_check;
+		ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+			CHECK (status IN ('draft', 'scheduled', 'pending_approval', 'sending', 'paused', 'sent', 'failed'));`,
+	29: `
+		DROP INDEX IF EXISTS idx_campaign_suppressions_normalized_phone;
+		DROP INDEX IF EXISTS idx_customers_normalized_phone;
+		DROP FUNCTION IF EXISTS normalize_phone(TEXT);`,
+	30: "ALTER TABLE campaigns DROP COLUMN IF EXISTS demo_failure_rate;",
+	31: `
+		DROP TABLE IF EXISTS customer_block_events CASCADE;
+		DROP INDEX IF EXISTS idx_customers_blocked;
+		ALTER TABLE customers DROP COLUMN IF EXISTS blocked_at;
+		ALTER TABLE customers DROP COLUMN IF EXISTS blocked_by;
+		ALTER TABLE customers DROP COLUMN IF EXISTS blocked_reason;
+		ALTER TABLE customers DROP COLUMN IF EXISTS blocked;`,
+	32: `
+		DROP TRIGGER IF EXISTS campaigns_status_events ON campaigns;
+		DROP TRIGGER IF EXISTS outbound_messages_events ON outbound_messages;
+		DROP FUNCTION IF EXISTS record_campaign_status_event();
+		DROP FUNCTION IF EXISTS record_message_event();
+		DROP FUNCTION IF EXISTS message_event_payload(TEXT, INTEGER, TEXT, BOOLEAN);
+		DROP FUNCTION IF EXISTS message_event_type(TEXT);
+		DROP TRIGGER IF EXISTS campaign_events_append_only ON campaign_events;
+		DROP FUNCTION IF EXISTS guard_campaign_events();
+		DELETE FROM campaign_events WHERE type NOT IN ('queued', 'progress', 'completed');
+		DROP INDEX IF EXISTS idx_campaign_events_message;
+		ALTER TABLE campaign_events DROP COLUMN IF EXISTS message_id;
+		ALTER TABLE campaign_events DROP COLUMN IF EXISTS version;
+		ALTER TABLE campaign_events DROP CONSTRAINT IF EXISTS campaign_events_type_check;
+		ALTER TABLE campaign_events ADD CONSTRAINT campaign_events_type_check
+			CHECK (type IN ('queued', 'progress', 'completed'));
+		DROP TRIGGER IF EXISTS campaign_events_notify ON campaign_events;
+		CREATE TRIGGER campaign_events_notify
+			AFTER INSERT ON campaign_events
+			FOR EACH ROW EXECUTE FUNCTION notify_campaign_event();`,
+	33: "DROP TABLE IF EXISTS customer_phone_history CASCADE;",
+	34: `
+		DROP TABLE IF EXISTS worker_heartbeats CASCADE;
+		DROP INDEX IF EXISTS idx_outbound_messages_claimed_by;
+		ALTER TABLE outbound_messages DROP COLUMN IF EXISTS claim_expires_at;
+		ALTER TABLE outbound_messages DROP COLUMN IF EXISTS claimed_at;
+		ALTER TABLE outbound_messages DROP COLUMN IF EXISTS claimed_by;`,
+	35: `
+		UPDATE campaigns SET status = 'sending', send_plan = NULL WHERE status = 'canary';
+		ALTER TABLE outbound_messages DROP COLUMN IF EXISTS canary;
+		ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS campaigns_status_check;
+		ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+			CHECK (status IN ('draft', 'scheduled', 'pending_approval', 'sending', 'paused', 'sent', 'failed', 'cancelling', 'cancelled'));`,
+	36: "ALTER TABLE campaigns DROP COLUMN IF EXISTS ordered;",
+	37: "DROP TABLE IF EXISTS api_keys CASCADE;",
+	38: `
+		DROP INDEX IF EXISTS idx_customers_engagement_score;
+		ALTER TABLE customers DROP COLUMN IF EXISTS engagement_scored_at;
+		ALTER TABLE customers DROP COLUMN IF EXISTS engagement_score;`,
+	39: `
+		DROP TABLE IF EXISTS settings_audit_log CASCADE;
+		DROP TABLE IF EXISTS settings CASCADE;`,
+	40: `
+		DROP INDEX IF EXISTS idx_outbound_messages_fingerprint_sent;
+		UPDATE outbound_messages SET status = 'cancelled', last_error = 'Skipped: duplicate content' WHERE status = 'skipped_duplicate_content';
+		ALTER TABLE campaign_events DISABLE TRIGGER campaign_events_append_only;
+		DELETE FROM campaign_events WHERE type = 'message_skipped';
+		ALTER TABLE campaign_events ENABLE TRIGGER campaign_events_append_only;
+		CREATE OR REPLACE FUNCTION message_event_type(status TEXT) RETURNS TEXT AS $$
+			SELECT CASE status
+				WHEN 'sent' THEN 'message_sent'
+				WHEN 'failed' THEN 'message_failed'
+				WHEN 'cancelled' THEN 'message_cancelled'
+				ELSE 'message_queued'
+			END;
+		$$ LANGUAGE SQL IMMUTABLE;
+		ALTER TABLE campaign_events DROP CONSTRAINT IF EXISTS campaign_events_type_check;
+		ALTER TABLE campaign_events ADD CONSTRAINT campaign_events_type_check
+			CHECK (type IN (
+				'queued', 'progress', 'completed',
+				'message_queued', 'message_sent', 'message_failed', 'message_cancelled',
+				'message_updated', 'message_moved', 'message_deleted', 'campaign_status'
+			));
+		ALTER TABLE outbound_messages DROP CONSTRAINT IF EXISTS outbound_messages_status_check;
+		ALTER TABLE outbound_messages ADD CONSTRAINT outbound_messages_status_check
+			CHECK (status IN ('pending', 'sent', 'failed', 'cancelled'));
+		ALTER TABLE outbound_messages ALTER COLUMN status TYPE VARCHAR(20);`,
+	41: `
+		DROP INDEX IF EXISTS idx_outbound_messages_group;
+		ALTER TABLE outbound_messages DROP COLUMN IF EXISTS part_count;
+		ALTER TABLE outbound_messages DROP COLUMN IF EXISTS part;
+		ALTER TABLE outbound_messages DROP COLUMN IF EXISTS group_id;
+		ALTER TABLE campaigns DROP COLUMN IF EXISTS part_delimiter;`,
+	42: `
+		DROP INDEX IF EXISTS idx_outbound_messages_resends;
+		ALTER TABLE outbound_messages DROP COLUMN IF EXISTS resent_from_message_id;`,
+	43: `
+		DROP TABLE IF EXISTS campaign_occurrences;
+		DROP TABLE IF EXISTS campaign_recurrences;
+		UPDATE campaigns SET status = 'draft', send_plan = NULL WHERE status = 'recurring';
+		ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS campaigns_status_check;
+		ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+			CHECK (status IN ('draft', 'scheduled', 'pending_approval', 'sending', 'canary', 'paused', 'sent', 'failed', 'cancelling', 'cancelled'));`,
+	44: `
+		UPDATE outbound_messages SET status = 'cancelled', last_error = 'Expired: process_by deadline passed' WHERE status = 'expired';
+		ALTER TABLE campaign_events DISABLE TRIGGER campaign_events_append_only;
+		DELETE FROM campaign_events WHERE type = 'message_expired';
+		ALTER TABLE campaign_events ENABLE TRIGGER campaign_events_append_only;
+		CREATE OR REPLACE FUNCTION message_event_type(status TEXT) RETURNS TEXT AS $$
+			SELECT CASE status
+				WHEN 'sent' THEN 'message_sent'
+				WHEN 'failed' THEN 'message_failed'
+				WHEN 'cancelled' THEN 'message_cancelled'
+				WHEN 'skipped_duplicate_content' THEN 'message_skipped'
+				ELSE 'message_queued'
+			END;
+		$$ LANGUAGE SQL IMMUTABLE;
+		ALTER TABLE campaign_events DROP CONSTRAINT IF EXISTS campaign_events_type_check;
+		ALTER TABLE campaign_events ADD CONSTRAINT campaign_events_type_check
+			CHECK (type IN (
+				'queued', 'progress', 'completed',
+				'message_queued', 'message_sent', 'message_failed', 'message_cancelled', 'message_skipped',
+				'message_updated', 'message_moved', 'message_deleted', 'campaign_status'
+			));
+		ALTER TABLE outbound_messages DROP CONSTRAINT IF EXISTS outbound_messages_status_check;
+		ALTER TABLE outbound_messages ADD CONSTRAINT outbound_messages_status_check
+			CHECK (status IN ('pending', 'sent', 'failed', 'cancelled', 'skipped_duplicate_content'));`,
+}
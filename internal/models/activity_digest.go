@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ActivityDigest summarizes one day of sending activity for stakeholders
+type ActivityDigest struct {
+	Date     string    `json:"date"`      // Day summarized, YYYY-MM-DD in TimeZone
+	TimeZone string    `json:"time_zone"` // e.g. Africa/Nairobi
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+
+	CampaignsCompleted int `json:"campaigns_completed"`
+	CampaignsFailed    int `json:"campaigns_failed"`
+
+	Channels  []*ChannelActivity `json:"channels"`
+	TopErrors []*ErrorCount      `json:"top_errors"`
+	Spend     float64            `json:"spend"` // Simulated sends cost nothing
+}
+
+// ChannelActivity counts the messages finished on one channel
+type ChannelActivity struct {
+	Channel   Channel `json:"channel"`
+	Sent      int     `json:"sent"`
+	Failed    int     `json:"failed"`
+	Simulated int     `json:"simulated"` // Included in Sent
+	Spend     float64 `json:"spend"`
+}
+
+// ErrorCount is how many messages failed with one error
+type ErrorCount struct {
+	Error string `json:"error"`
+	Count int    `json:"count"`
+}
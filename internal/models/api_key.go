@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// APIKey is a key issued through the admin endpoints, identifying a caller like an API_KEYS entry
+// Only the key's hash is stored
+type APIKey struct {
+	ID         int        `json:"id" db:"id"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Label      string     `json:"label" db:"label"`
+	UserID     string     `json:"user_id" db:"user_id"`
+	Role       string     `json:"role" db:"role"`
+	Team       *string    `json:"team,omitempty" db:"team"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+}
+
+// IsRevoked reports whether the key has been revoked
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
@@ -1,7 +1,12 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"math"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -9,13 +14,142 @@ import (
 type CampaignStatus string
 
 const (
-	CampaignStatusDraft     CampaignStatus = "draft"
-	CampaignStatusScheduled CampaignStatus = "scheduled"
-	CampaignStatusSending   CampaignStatus = "sending"
-	CampaignStatusSent      CampaignStatus = "sent"
-	CampaignStatusFailed    CampaignStatus = "failed"
+	CampaignStatusDraft           CampaignStatus = "draft"
+	CampaignStatusScheduled       CampaignStatus = "scheduled"
+	CampaignStatusPendingApproval CampaignStatus = "pending_approval"
+	CampaignStatusSending         CampaignStatus = "sending"
+	CampaignStatusCanary          CampaignStatus = "canary"
+	CampaignStatusPaused          CampaignStatus = "paused"
+	CampaignStatusSent            CampaignStatus = "sent"
+	CampaignStatusFailed          CampaignStatus = "failed"
+	CampaignStatusCancelling      CampaignStatus = "cancelling"
+	CampaignStatusCancelled       CampaignStatus = "cancelled"
+	CampaignStatusRecurring       CampaignStatus = "recurring"
 )
 
+// CampaignTransitions lists the statuses each campaign status may move to
+// Sent, failed and cancelled are terminal; cancelling moves back to sending when undone, and
+// canary holds a send back after its canary until it is continued or aborted
+// A recurring campaign stays the definition of its occurrences, each sent as a campaign of its
+// own; its recurrence is paused or ends rather than its status changing
+var CampaignTransitions = map[CampaignStatus][]CampaignStatus{
+	CampaignStatusDraft:           {CampaignStatusScheduled, CampaignStatusPendingApproval, CampaignStatusSending, CampaignStatusRecurring},
+	CampaignStatusScheduled:       {CampaignStatusDraft, CampaignStatusPendingApproval, CampaignStatusSending, CampaignStatusRecurring},
+	CampaignStatusPendingApproval: {CampaignStatusDraft, CampaignStatusSending},
+	CampaignStatusSending:         {CampaignStatusCanary, CampaignStatusPaused, CampaignStatusSent, CampaignStatusFailed, CampaignStatusCancelling},
+	CampaignStatusCanary:          {CampaignStatusSending, CampaignStatusCancelling},
+	CampaignStatusPaused:          {CampaignStatusSending, CampaignStatusCancelling},
+	CampaignStatusCancelling:      {CampaignStatusSending, CampaignStatusCancelled},
+	CampaignStatusSent:            {},
+	CampaignStatusFailed:          {},
+	CampaignStatusCancelled:       {},
+	CampaignStatusRecurring:       {},
+}
+
+// CanTransition checks if a campaign in this status may move to the given status
+func (s CampaignStatus) CanTransition(to CampaignStatus) bool {
+	for _, allowed := range CampaignTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether no further status change is possible
+func (s CampaignStatus) IsTerminal() bool {
+	return len(CampaignTransitions[s]) == 0
+}
+
+// CampaignAction is something a user can do to a campaign
+type CampaignAction string
+
+const (
+	CampaignActionSend       CampaignAction = "send"
+	CampaignActionApprove    CampaignAction = "approve"
+	CampaignActionReject     CampaignAction = "reject"
+	CampaignActionReRender   CampaignAction = "re_render"
+	CampaignActionResume     CampaignAction = "resume"
+	CampaignActionCancel     CampaignAction = "cancel"
+	CampaignActionUndoCancel CampaignAction = "undo_cancel"
+	CampaignActionContinue   CampaignAction = "continue"
+	CampaignActionAbort      CampaignAction = "abort"
+)
+
+// campaignActionRule gives the statuses an action starts from and the status it moves to
+// An empty To means the action leaves the status alone
+type campaignActionRule struct {
+	From []CampaignStatus
+	To   CampaignStatus
+}
+
+// CampaignActions lists the actions in the order clients should show them
+var CampaignActions = []CampaignAction{
+	CampaignActionSend,
+	CampaignActionApprove,
+	CampaignActionReject,
+	CampaignActionReRender,
+	CampaignActionResume,
+	CampaignActionCancel,
+	CampaignActionUndoCancel,
+	CampaignActionContinue,
+	CampaignActionAbort,
+}
+
+// campaignActionRules defines each action; a rule's move must also be in CampaignTransitions
+// Send may end in pending_approval instead when the audience needs approval, and cancel
+// without a grace period passes through cancelling straight to cancelled
+var campaignActionRules = map[CampaignAction]campaignActionRule{
+	CampaignActionSend:       {From: []CampaignStatus{CampaignStatusDraft, CampaignStatusScheduled}, To: CampaignStatusSending},
+	CampaignActionApprove:    {From: []CampaignStatus{CampaignStatusPendingApproval}, To: CampaignStatusSending},
+	CampaignActionReject:     {From: []CampaignStatus{CampaignStatusPendingApproval}, To: CampaignStatusDraft},
+	CampaignActionReRender:   {From: []CampaignStatus{CampaignStatusDraft, CampaignStatusScheduled, CampaignStatusPendingApproval, CampaignStatusSending}},
+	CampaignActionResume:     {From: []CampaignStatus{CampaignStatusPaused}, To: CampaignStatusSending},
+	CampaignActionCancel:     {From: []CampaignStatus{CampaignStatusSending, CampaignStatusPaused}, To: CampaignStatusCancelling},
+	CampaignActionUndoCancel: {From: []CampaignStatus{CampaignStatusCancelling}, To: CampaignStatusSending},
+	CampaignActionContinue:   {From: []CampaignStatus{CampaignStatusCanary}, To: CampaignStatusSending},
+	CampaignActionAbort:      {From: []CampaignStatus{CampaignStatusCanary}, To: CampaignStatusCancelling},
+}
+
+// Allows checks if an action may be taken on a campaign in this status
+func (s CampaignStatus) Allows(action CampaignAction) bool {
+	rule, ok := campaignActionRules[action]
+	if !ok {
+		return false
+	}
+	if rule.To != "" && !s.CanTransition(rule.To) {
+		return false
+	}
+	for _, from := range rule.From {
+		if from == s {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedActions lists the actions a campaign in this status allows, in CampaignActions order
+// It never returns nil
+func (s CampaignStatus) AllowedActions() []CampaignAction {
+	allowed := []CampaignAction{}
+	for _, action := range CampaignActions {
+		if s.Allows(action) {
+			allowed = append(allowed, action)
+		}
+	}
+	return allowed
+}
+
+// InvalidTransitionError is returned when a status change is not allowed
+type InvalidTransitionError struct {
+	From CampaignStatus
+	To   CampaignStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("invalid campaign status transition from %s to %s", e.From, e.To)
+}
+
 // Channel represents valid messaging channels
 type Channel string
 
@@ -32,8 +166,52 @@ type Campaign struct {
 	Status       CampaignStatus `json:"status" db:"status"`
 	BaseTemplate string         `json:"base_template" db:"base_template"`
 	ScheduledAt  *time.Time     `json:"scheduled_at,omitempty" db:"scheduled_at"`
+	Tags         []string       `json:"tags" db:"tags"`
+	CreatedBy    *string        `json:"created_by,omitempty" db:"created_by"`
+	Team         *string        `json:"team,omitempty" db:"team"`
 	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at" db:"updated_at"`
+
+	// Budget caps what the campaign's sends may cost (nil means no cap)
+	Budget *float64 `json:"budget,omitempty" db:"budget"`
+	// PausedReason says why a paused campaign stopped, e.g. PausedReasonBudgetExceeded
+	PausedReason *string `json:"paused_reason,omitempty" db:"paused_reason"`
+	// Spend is the cost of the campaign's sends so far; only loaded for a single campaign
+	Spend *float64 `json:"spend,omitempty" db:"spend"`
+	// FrequencyCapExempt lets transactional-style campaigns reach customers over the frequency cap;
+	// only loaded for a single campaign
+	FrequencyCapExempt bool `json:"frequency_cap_exempt,omitempty" db:"frequency_cap_exempt"`
+	// TrackLinks has the worker rewrite links in sent messages to tracked redirects;
+	// only loaded for a single campaign
+	TrackLinks bool `json:"track_links,omitempty" db:"track_links"`
+	// TemplateSyntax is the placeholder style of BaseTemplate (nil uses the configured default);
+	// only loaded for a single campaign
+	TemplateSyntax *TemplateSyntax `json:"template_syntax,omitempty" db:"template_syntax"`
+	// CancelAt is when a cancelling campaign's cancellation becomes final, or when a
+	// cancelled one was finalized; only loaded for a single campaign
+	CancelAt *time.Time `json:"cancel_at,omitempty" db:"cancel_at"`
+	// Ordered has the campaign's messages processed one at a time in creation order, through a
+	// queue of its own; only loaded for a single campaign
+	Ordered bool `json:"ordered,omitempty" db:"ordered"`
+	// PartDelimiter splits BaseTemplate into parts sent to each customer as separate messages,
+	// in order (nil sends it whole); only loaded for a single campaign
+	PartDelimiter *string `json:"part_delimiter,omitempty" db:"part_delimiter"`
+	// DemoFailureRate overrides the mock sender's failure rate for this campaign's sends, for
+	// demos; ignored in production and with a real provider; only loaded for a single campaign
+	DemoFailureRate *float64 `json:"demo_failure_rate,omitempty" db:"demo_failure_rate"`
+}
+
+// PausedReasonBudgetExceeded marks a campaign paused because its next send would exceed its budget
+const PausedReasonBudgetExceeded = "budget_exceeded"
+
+// RemainingBudget returns what is left of the budget, never below zero
+// It returns nil for a campaign without a budget or whose spend was not loaded
+func (c *Campaign) RemainingBudget() *float64 {
+	if c.Budget == nil || c.Spend == nil {
+		return nil
+	}
+	remaining := math.Max(math.Round((*c.Budget-*c.Spend)*100)/100, 0)
+	return &remaining
 }
 
 // CampaignStats represents campaign statistics
@@ -42,12 +220,237 @@ type CampaignStats struct {
 	Pending int `json:"pending"`
 	Sent    int `json:"sent"`
 	Failed  int `json:"failed"`
+
+	// Queued counts pending messages published to the broker and awaiting the worker;
+	// Unpublished counts pending messages never published, the backlog the reconciler
+	// republishes. Pending messages deferred to later are in neither
+	Queued      int `json:"queued"`
+	Unpublished int `json:"unpublished"`
+
+	// Simulated counts sent messages that a simulate-mode worker never handed to a provider
+	Simulated int `json:"simulated"`
+
+	// SkippedDuplicateContent counts messages the worker skipped because the customer was
+	// recently sent the same content by another campaign (DUPLICATE_SEND_WINDOW)
+	SkippedDuplicateContent int `json:"skipped_duplicate_content"`
+
+	// Expired counts messages the worker did not send because their send's process_by
+	// deadline had passed
+	Expired int `json:"expired"`
+
+	// P95QueueLatencySeconds is the 95th percentile of publish-to-sent time (nil until a message is sent)
+	P95QueueLatencySeconds *float64 `json:"p95_queue_latency_seconds,omitempty"`
+
+	// RetryDistribution counts finished messages by retry count (only loaded for a single campaign)
+	RetryDistribution *RetryDistribution `json:"retry_distribution,omitempty"`
+
+	// Clicks counts visits to the campaign's tracked links (only loaded for a single campaign)
+	Clicks *int `json:"clicks,omitempty"`
+
+	// Audience counts the distinct customers messaged (only loaded in bulk)
+	Audience *int `json:"audience,omitempty"`
+
+	// FirstMessageAt and LastFinishedAt span the send: the first message created and the
+	// last one sent or failed (only loaded in bulk, nil without messages)
+	FirstMessageAt *time.Time `json:"first_message_at,omitempty"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty"`
+
+	// Groups counts the multi-part sends of a campaign with a part delimiter (only loaded for
+	// a single campaign, nil without groups)
+	Groups *GroupStats `json:"groups,omitempty"`
+
+	// Resent counts messages resent as fresh attempts, which the counts above leave out so a
+	// resend never counts twice toward the send (only loaded for a single campaign, nil without resends)
+	Resent *ResentStats `json:"resent,omitempty"`
+}
+
+// ResentStats counts a campaign's resent messages by status
+type ResentStats struct {
+	Total   int `json:"total"`
+	Pending int `json:"pending"`
+	Sent    int `json:"sent"`
+	Failed  int `json:"failed"`
+}
+
+// GroupStats counts a campaign's multi-part sends, one per customer, by how many parts were sent
+type GroupStats struct {
+	Total    int `json:"total"`
+	Complete int `json:"complete"` // Every part sent
+	Partial  int `json:"partial"`  // Some parts sent, not all; a later part failed or is still to go
+}
+
+// RetryDistribution counts sent and failed messages keyed by their retry_count
+type RetryDistribution struct {
+	Sent   map[int]int `json:"sent"`
+	Failed map[int]int `json:"failed"`
 }
 
 // CampaignWithStats represents a campaign with its statistics
 type CampaignWithStats struct {
 	Campaign
 	Stats CampaignStats `json:"stats"`
+
+	// RemainingBudget is the budget less spend (nil without a budget)
+	RemainingBudget *float64 `json:"remaining_budget,omitempty"`
+
+	// SendMetrics is how long the send took (nil until it completes)
+	SendMetrics *CampaignSendMetrics `json:"send_metrics,omitempty"`
+
+	// Canary is how the canary of a canary send fared (nil when the send had none)
+	Canary *CanaryStats `json:"canary,omitempty"`
+}
+
+// CanaryStats counts the messages sent as a canary, the subset a canary send goes to first
+type CanaryStats struct {
+	Messages int `json:"messages"`
+	Pending  int `json:"pending"`
+	Sent     int `json:"sent"`
+	Failed   int `json:"failed"`
+
+	// HeldBack is the rest of the audience, waiting for the canary to be continued or aborted
+	// (nil once it was)
+	HeldBack *int `json:"held_back,omitempty"`
+}
+
+// CampaignSendMetrics is the actual duration of a completed send, with what it was estimated to take
+type CampaignSendMetrics struct {
+	StartedAt           time.Time `json:"started_at"`   // First message published, or created if never published
+	CompletedAt         time.Time `json:"completed_at"` // Last message sent or failed
+	DurationSeconds     float64   `json:"duration_seconds"`
+	Messages            int       `json:"messages"` // Sent or failed
+	ThroughputPerMinute *float64  `json:"throughput_per_minute,omitempty"`
+	// EstimatedDurationSeconds is what the same messages take at the configured send rate
+	EstimatedDurationSeconds *float64 `json:"estimated_duration_seconds,omitempty"`
+}
+
+// CampaignExportRow is a campaign with its stats and message time span, as exported for reporting
+type CampaignExportRow struct {
+	CampaignWithStats
+	FirstMessageAt *time.Time // When the first message was created
+	LastMessageAt  *time.Time // When the last message was sent or failed
+}
+
+// SendPlan is the planned targeting of a send awaiting approval, or the rest of the audience
+// a canary send holds back
+type SendPlan struct {
+	CustomerIDs  []int     `json:"customer_ids"`
+	AudienceSize int       `json:"audience_size"`
+	RequestedAt  time.Time `json:"requested_at"`
+
+	// Canary is the canary requested with a send awaiting approval, applied once it is approved
+	Canary *CanaryOptions `json:"canary,omitempty"`
+
+	// MinEngagementScore is the engagement score requested with a send awaiting approval,
+	// checked again once it is approved
+	MinEngagementScore *float64 `json:"min_engagement_score,omitempty"`
+
+	// ProcessBy is the deadline of the send the plan came from, carried by the messages
+	// queued once it is approved or continued
+	ProcessBy *time.Time `json:"process_by,omitempty"`
+}
+
+// CanaryOptions splits a send into a canary that goes out first and the rest, held back until
+// the canary is continued: the canary is Percent of the audience, but at least Min customers
+type CanaryOptions struct {
+	Percent float64 `json:"percent"`
+	Min     int     `json:"min"`
+}
+
+// Size is the canary of an audience of n customers; n or more means the whole audience
+func (o *CanaryOptions) Size(n int) int {
+	size := int(math.Ceil(float64(n) * o.Percent / 100))
+	return max(size, o.Min)
+}
+
+// SendSource is how a send request reached the API
+type SendSource string
+
+const (
+	SendSourceAPI SendSource = "api" // POST /campaigns/{id}/send
+	SendSourceCSV SendSource = "csv" // POST /campaigns/{id}/send-csv
+)
+
+// SendRecord is one send request against a campaign and its outcome, kept so the targeting can be audited
+type SendRecord struct {
+	ID             int             `json:"id"`
+	CampaignID     int             `json:"campaign_id"`
+	RequestedBy    *string         `json:"requested_by,omitempty"`
+	Source         SendSource      `json:"source"`
+	Request        SendRequestInfo `json:"request"`
+	AudienceSize   int             `json:"audience_size"`
+	MessagesQueued int             `json:"messages_queued"`
+	Status         CampaignStatus  `json:"status"` // sending, pending_approval when the send waited, canary when it held its audience back, or interrupted
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// SendStatusInterrupted is the status of a send stopped partway, e.g. because the client
+// disconnected; MessagesQueued is how far it got, and sending to the campaign again resumes it
+const SendStatusInterrupted CampaignStatus = "interrupted"
+
+// SendRequestInfo is the targeting of a send request, with the listed customer IDs cut to a sample
+type SendRequestInfo struct {
+	CustomerIDCount       int        `json:"customer_id_count"`
+	CustomerIDSample      []int      `json:"customer_id_sample"`
+	InlineCustomers       int        `json:"inline_customers"`
+	AllowDuplicateContent bool       `json:"allow_duplicate_content"`
+	ProcessBy             *time.Time `json:"process_by,omitempty"`
+}
+
+// AttentionReason describes why a campaign needs operator attention
+type AttentionReason string
+
+const (
+	AttentionStalledSending  AttentionReason = "stalled_sending"
+	AttentionOverdueSchedule AttentionReason = "overdue_schedule"
+	AttentionHighFailureRate AttentionReason = "high_failure_rate"
+)
+
+// AttentionFailureRateThreshold is the failure rate above which a campaign is flagged
+const AttentionFailureRateThreshold = 0.25
+
+// CampaignAttention is a campaign flagged for the ops digest, tagged with the reason
+type CampaignAttention struct {
+	Campaign       Campaign        `json:"campaign"`
+	Reason         AttentionReason `json:"reason"`
+	Processed      int             `json:"processed"`
+	Failed         int             `json:"failed"`
+	LastProgressAt *time.Time      `json:"last_progress_at,omitempty"`
+}
+
+// MaxCampaignTags is the number of tags a campaign may carry
+const MaxCampaignTags = 20
+
+// tagPattern allows lowercase letters, digits, dashes and underscores, up to 50 characters
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// NormalizeTags lowercases and trims tags, dropping blanks and duplicates
+// It never returns nil so an untagged campaign stores an empty array
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// ValidateTags checks tag count and format after normalization
+func ValidateTags(tags []string) error {
+	normalized := NormalizeTags(tags)
+	if len(normalized) > MaxCampaignTags {
+		return fmt.Errorf("too many tags: at most %d allowed", MaxCampaignTags)
+	}
+	for _, tag := range normalized {
+		if !tagPattern.MatchString(tag) {
+			return fmt.Errorf("invalid tag %q: use letters, digits, '-' or '_' (max 50 characters)", tag)
+		}
+	}
+	return nil
 }
 
 // Validate checks if the campaign fields are valid
@@ -66,10 +469,48 @@ func (c *Campaign) Validate() error {
 
 // IsScheduled checks if campaign is scheduled for future
 func (c *Campaign) IsScheduled() bool {
-	return c.ScheduledAt != nil && c.ScheduledAt.After(time.Now())
+	return c.IsScheduledAt(time.Now())
+}
+
+// IsScheduledAt checks if campaign is scheduled after now, e.g. the database's current time
+func (c *Campaign) IsScheduledAt(now time.Time) bool {
+	return c.ScheduledAt != nil && c.ScheduledAt.After(now)
 }
 
 // CanSend checks if campaign can be sent
 func (c *Campaign) CanSend() bool {
-	return c.Status == CampaignStatusDraft || c.Status == CampaignStatusScheduled
+	return c.Status.Allows(CampaignActionSend)
+}
+
+// IsTerminal checks if campaign has finished sending
+func (c *Campaign) IsTerminal() bool {
+	return c.Status.IsTerminal()
+}
+
+// IsPendingApproval checks if campaign is waiting for a send to be approved
+func (c *Campaign) IsPendingApproval() bool {
+	return c.Status == CampaignStatusPendingApproval
+}
+
+// TemplateParts splits BaseTemplate on PartDelimiter into the parts sent to each customer as
+// separate messages, each trimmed of surrounding whitespace; without a delimiter the template
+// is the only part
+func (c *Campaign) TemplateParts() []string {
+	if c.PartDelimiter == nil || *c.PartDelimiter == "" {
+		return []string{c.BaseTemplate}
+	}
+	parts := strings.Split(c.BaseTemplate, *c.PartDelimiter)
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+// ContentFingerprint identifies what the campaign sends: a SHA-256 of its channel and template
+// The template is lowercased and its whitespace collapsed, so a duplicated campaign with
+// cosmetic edits still matches
+func (c *Campaign) ContentFingerprint() string {
+	template := strings.Join(strings.Fields(strings.ToLower(c.BaseTemplate)), " ")
+	sum := sha256.Sum256([]byte(string(c.Channel) + "\n" + template))
+	return hex.EncodeToString(sum[:])
 }
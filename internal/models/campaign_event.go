@@ -0,0 +1,79 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CampaignEventType is the kind of event recorded for a campaign
+type CampaignEventType string
+
+// Send events, read by external consumers polling a campaign's events
+const (
+	CampaignEventQueued    CampaignEventType = "queued"    // A batch of messages was published to the queue
+	CampaignEventProgress  CampaignEventType = "progress"  // Another 1000 messages were sent
+	CampaignEventCompleted CampaignEventType = "completed" // No message is left to send or retry
+)
+
+// History events, written by database triggers and replayed to rebuild a campaign's stats
+// Every message event but moved and deleted carries the message's MessageEventPayload
+const (
+	CampaignEventMessageQueued    CampaignEventType = "message_queued"    // Created, moved in, or back to pending
+	CampaignEventMessageSent      CampaignEventType = "message_sent"      // Now sent
+	CampaignEventMessageFailed    CampaignEventType = "message_failed"    // A failed attempt, numbered by attempt
+	CampaignEventMessageCancelled CampaignEventType = "message_cancelled" // Now cancelled
+	CampaignEventMessageSkipped   CampaignEventType = "message_skipped"   // Skipped as a recent duplicate
+	CampaignEventMessageExpired   CampaignEventType = "message_expired"   // Not sent by its send's deadline
+	CampaignEventMessageUpdated   CampaignEventType = "message_updated"   // Retry count or error changed in place
+	CampaignEventMessageMoved     CampaignEventType = "message_moved"     // Moved to the campaign in to_campaign_id
+	CampaignEventMessageDeleted   CampaignEventType = "message_deleted"   // Deleted
+	CampaignEventStatus           CampaignEventType = "campaign_status"   // Campaign moved from one status to another
+)
+
+// CampaignEventVersion is the payload format of the events written now
+const CampaignEventVersion = 1
+
+// CampaignEvent is an event recorded for a campaign
+type CampaignEvent struct {
+	ID         int64             `json:"id" db:"id"`
+	CampaignID int               `json:"campaign_id" db:"campaign_id"`
+	Type       CampaignEventType `json:"type" db:"type"`
+	Payload    json.RawMessage   `json:"payload" db:"payload"`
+	DedupeKey  *string           `json:"-" db:"dedupe_key"`
+	MessageID  *int              `json:"-" db:"message_id"` // Message of a history event about one
+	Version    int               `json:"-" db:"version"`
+	CreatedAt  time.Time         `json:"created_at" db:"created_at"`
+}
+
+// MessageEventPayload is a message's summary fields as a history event recorded them
+type MessageEventPayload struct {
+	Status     MessageStatus `json:"status"`
+	RetryCount int           `json:"retry_count"`
+	LastError  *string       `json:"last_error"`
+	Simulated  bool          `json:"simulated"`
+	Attempt    int           `json:"attempt,omitempty"` // Failed events only
+}
+
+// CampaignStatusPayload is the transition a campaign_status event recorded
+// From is nil for a campaign's first status, and for the baseline written by the migration
+type CampaignStatusPayload struct {
+	From *CampaignStatus `json:"from"`
+	To   CampaignStatus  `json:"to"`
+}
+
+// MessageSummary is the summary fields of a message, as stored or as replayed from events
+type MessageSummary struct {
+	ID         int           `json:"id"`
+	Status     MessageStatus `json:"status"`
+	RetryCount int           `json:"retry_count"`
+	LastError  *string       `json:"last_error,omitempty"`
+	Simulated  bool          `json:"simulated"`
+}
+
+// CampaignProgress counts the messages of a sending campaign by outcome
+type CampaignProgress struct {
+	CampaignID  int `json:"campaign_id"`
+	Sent        int `json:"sent"`
+	Failed      int `json:"failed"`      // Failed for good: out of retries or rejected
+	Outstanding int `json:"outstanding"` // Pending, or failed with retries left
+}
@@ -1,16 +1,326 @@
 package models
 
-import "time"
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
 
 // Customer represents a customer in the system
 type Customer struct {
-	ID               int       `json:"id" db:"id"`
-	Phone            string    `json:"phone" db:"phone"`
-	FirstName        *string   `json:"first_name,omitempty" db:"first_name"`
-	LastName         *string   `json:"last_name,omitempty" db:"last_name"`
-	Location         *string   `json:"location,omitempty" db:"location"`
-	PreferredProduct *string   `json:"preferred_product,omitempty" db:"preferred_product"`
-	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	ID                 int       `json:"id" db:"id"`
+	Phone              string    `json:"phone" db:"phone"`
+	FirstName          *string   `json:"first_name,omitempty" db:"first_name"`
+	LastName           *string   `json:"last_name,omitempty" db:"last_name"`
+	Location           *string   `json:"location,omitempty" db:"location"`
+	PreferredProduct   *string   `json:"preferred_product,omitempty" db:"preferred_product"`
+	ContactWindowStart *string   `json:"contact_window_start,omitempty" db:"contact_window_start"`
+	ContactWindowEnd   *string   `json:"contact_window_end,omitempty" db:"contact_window_end"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+
+	// Block status is only loaded where it is acted on or shown: the customer detail,
+	// export and the worker's message details
+	Blocked       bool       `json:"blocked,omitempty" db:"blocked"`
+	BlockedReason *string    `json:"blocked_reason,omitempty" db:"blocked_reason"`
+	BlockedBy     *string    `json:"blocked_by,omitempty" db:"blocked_by"`
+	BlockedAt     *time.Time `json:"blocked_at,omitempty" db:"blocked_at"`
+
+	// Engagement is only loaded where it is shown: the customer list and detail
+	// Both are nil until the customer is first scored
+	EngagementScore    *float64   `json:"engagement_score,omitempty" db:"engagement_score"`
+	EngagementScoredAt *time.Time `json:"engagement_scored_at,omitempty" db:"engagement_scored_at"`
+}
+
+// EngagementHistory is the message history a customer's engagement score is computed from,
+// limited to the scoring lookback
+type EngagementHistory struct {
+	CustomerID int
+	SentAt     []time.Time // When each sent message was sent
+	Failed     int         // Messages that failed permanently
+}
+
+// CustomerBlockAction is what a customer block event did
+type CustomerBlockAction string
+
+const (
+	CustomerBlocked   CustomerBlockAction = "blocked"
+	CustomerUnblocked CustomerBlockAction = "unblocked"
+)
+
+// CustomerBlockEvent records an operator blocking or unblocking a customer
+type CustomerBlockEvent struct {
+	ID         int                 `json:"id" db:"id"`
+	CustomerID int                 `json:"customer_id" db:"customer_id"`
+	Action     CustomerBlockAction `json:"action" db:"action"`
+	Reason     *string             `json:"reason,omitempty" db:"reason"`
+	Actor      *string             `json:"actor,omitempty" db:"actor"`
+	CreatedAt  time.Time           `json:"created_at" db:"created_at"`
+}
+
+// EnforceFieldLengths checks the optional string fields against a maximum length
+// With truncate, longer values are cut to the limit and a warning is returned for each;
+// otherwise the first longer value is an error
+func (c *Customer) EnforceFieldLengths(maxLength int, truncate bool) ([]string, error) {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"first_name", c.FirstName},
+		{"last_name", c.LastName},
+		{"location", c.Location},
+		{"preferred_product", c.PreferredProduct},
+	}
+
+	warnings := []string{}
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+
+		length := utf8.RuneCountInString(*field.value)
+		if length <= maxLength {
+			continue
+		}
+
+		if !truncate {
+			return nil, fmt.Errorf("%s is %d characters, maximum is %d", field.name, length, maxLength)
+		}
+
+		*field.value = string([]rune(*field.value)[:maxLength])
+		warnings = append(warnings, fmt.Sprintf("%s truncated from %d to %d characters", field.name, length, maxLength))
+	}
+
+	return warnings, nil
+}
+
+// DefaultPhoneCountryCode is assumed for numbers written in local format (07..., 01...)
+const DefaultPhoneCountryCode = "254"
+
+// phoneFormatting are separators people put in phone numbers
+var phoneFormatting = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// NormalizePhone converts a phone number to the +<country><number> form customers are stored in
+// Local numbers with a leading 0 get DefaultPhoneCountryCode; 00 and bare country prefixes become +
+func NormalizePhone(raw string) (string, error) {
+	phone := phoneFormatting.Replace(strings.TrimSpace(raw))
+
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		phone = phone[1:]
+	case strings.HasPrefix(phone, "00"):
+		phone = phone[2:]
+	case strings.HasPrefix(phone, "0") && len(phone) == 10:
+		phone = DefaultPhoneCountryCode + phone[1:]
+	}
+
+	if len(phone) < 8 || len(phone) > 15 || phone[0] == '0' {
+		return "", fmt.Errorf("invalid phone number %q", raw)
+	}
+	for _, r := range phone {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("invalid phone number %q", raw)
+		}
+	}
+
+	return "+" + phone, nil
+}
+
+// PhoneKey returns the form phones are compared on: the normalized phone, or the phone as
+// given when it cannot be normalized. It matches the normalize_phone database function, so
+// "0712345678" and "+254712345678" are the same number
+func PhoneKey(phone string) string {
+	if normalized, err := NormalizePhone(phone); err == nil {
+		return normalized
+	}
+	return phone
+}
+
+// ContactWindow is the local time of day a customer agreed to be messaged, in minutes after midnight
+// End may be earlier than Start for a window crossing midnight (e.g. 20:00-08:00)
+type ContactWindow struct {
+	Start int
+	End   int
+}
+
+// ParseClock parses an HH:MM time of day into minutes after midnight
+func ParseClock(value string) (int, error) {
+	if len(value) != 5 || value[2] != ':' {
+		return 0, fmt.Errorf("%q must be a time in HH:MM format", value)
+	}
+
+	hours, minutes := 0, 0
+	for i, r := range value {
+		if i == 2 {
+			continue
+		}
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("%q must be a time in HH:MM format", value)
+		}
+		if i < 2 {
+			hours = hours*10 + int(r-'0')
+		} else {
+			minutes = minutes*10 + int(r-'0')
+		}
+	}
+	if hours > 23 || minutes > 59 {
+		return 0, fmt.Errorf("%q must be a time in HH:MM format", value)
+	}
+
+	return hours*60 + minutes, nil
+}
+
+// ContactWindow returns the customer's contact window, or nil when they have none
+// Start and end must be set together, as HH:MM, and differ
+func (c *Customer) ContactWindow() (*ContactWindow, error) {
+	if c.ContactWindowStart == nil && c.ContactWindowEnd == nil {
+		return nil, nil
+	}
+	if c.ContactWindowStart == nil || c.ContactWindowEnd == nil {
+		return nil, fmt.Errorf("contact_window_start and contact_window_end must be set together")
+	}
+
+	start, err := ParseClock(*c.ContactWindowStart)
+	if err != nil {
+		return nil, fmt.Errorf("contact_window_start %w", err)
+	}
+	end, err := ParseClock(*c.ContactWindowEnd)
+	if err != nil {
+		return nil, fmt.Errorf("contact_window_end %w", err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("contact_window_start and contact_window_end must differ")
+	}
+
+	return &ContactWindow{Start: start, End: end}, nil
+}
+
+// Contains reports whether t falls within the window, read as local time in loc
+func (w *ContactWindow) Contains(t time.Time, loc *time.Location) bool {
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	if w.Start < w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// NextOpen returns t if it is within the window, otherwise when the window next opens
+func (w *ContactWindow) NextOpen(t time.Time, loc *time.Location) time.Time {
+	if w.Contains(t, loc) {
+		return t
+	}
+
+	local := t.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), w.Start/60, w.Start%60, 0, 0, loc)
+	if !next.After(t) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, w.Start/60, w.Start%60, 0, 0, loc)
+	}
+	return next
+}
+
+// String formats the window as HH:MM-HH:MM
+func (w *ContactWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
+
+// CustomerTemplateFields are the customer fields a template can reference as {field}
+var CustomerTemplateFields = []string{"first_name", "last_name", "location", "preferred_product", "phone"}
+
+// IsCustomerTemplateField checks if a field name can be used as a placeholder
+func IsCustomerTemplateField(field string) bool {
+	for _, known := range CustomerTemplateFields {
+		if known == field {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldCompleteness counts, for an audience, how many customers lack each field
+// A field is missing when it is null or empty
+type FieldCompleteness struct {
+	Total   int
+	Missing map[string]int
+}
+
+// CustomerStatsUnknown is the bucket for customers with no value set
+const CustomerStatsUnknown = "unknown"
+
+// CustomerStatsTopN is the number of buckets returned per breakdown
+const CustomerStatsTopN = 20
+
+// CustomerStatsBucket is the number of customers sharing a value
+type CustomerStatsBucket struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// CustomerStats summarises the customer base for campaign planning
+type CustomerStats struct {
+	TotalCustomers int                    `json:"total_customers"`
+	ByProduct      []*CustomerStatsBucket `json:"by_preferred_product"`
+	ByLocation     []*CustomerStatsBucket `json:"by_location"`
+}
+
+// TimelineEventType discriminates entries in a customer timeline
+type TimelineEventType string
+
+const (
+	TimelineMessageQueued TimelineEventType = "message_queued"
+	TimelineMessageSent   TimelineEventType = "message_sent"
+	TimelineMessageFailed TimelineEventType = "message_failed"
+)
+
+// TimelineEvent is one entry in a customer's chronological history
+type TimelineEvent struct {
+	Type         TimelineEventType `json:"type"`
+	OccurredAt   time.Time         `json:"occurred_at"`
+	MessageID    *int              `json:"message_id,omitempty"`
+	CampaignID   *int              `json:"campaign_id,omitempty"`
+	CampaignName *string           `json:"campaign_name,omitempty"`
+	Detail       *string           `json:"detail,omitempty"`
+}
+
+// TimelineCursor is the position of a timeline event; a page from a cursor holds the events
+// after it, newest first. Events are ordered by when they happened, then message and type,
+// so events sharing a timestamp are neither skipped nor repeated across pages
+type TimelineCursor struct {
+	OccurredAt time.Time
+	MessageID  int
+	Type       TimelineEventType
+}
+
+// CursorAfter returns the cursor of the page following e
+func (e *TimelineEvent) CursorAfter() *TimelineCursor {
+	cursor := &TimelineCursor{OccurredAt: e.OccurredAt, Type: e.Type}
+	if e.MessageID != nil {
+		cursor.MessageID = *e.MessageID
+	}
+	return cursor
+}
+
+// String encodes the cursor as an opaque token, so clients do not build cursors themselves
+func (c *TimelineCursor) String() string {
+	raw := fmt.Sprintf("%s|%d|%s", c.OccurredAt.UTC().Format(time.RFC3339Nano), c.MessageID, c.Type)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseTimelineCursor decodes a cursor encoded by TimelineCursor.String
+func ParseTimelineCursor(token string) (*TimelineCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		if parts := strings.SplitN(string(raw), "|", 3); len(parts) == 3 {
+			occurredAt, timeErr := time.Parse(time.RFC3339Nano, parts[0])
+			messageID, idErr := strconv.Atoi(parts[1])
+			if timeErr == nil && idErr == nil {
+				return &TimelineCursor{OccurredAt: occurredAt, MessageID: messageID, Type: TimelineEventType(parts[2])}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("invalid cursor: %q", token)
 }
 
 // FullName returns the customer's full name
@@ -35,3 +345,13 @@ func (c *Customer) FullName() string {
 	}
 	return "Customer"
 }
+
+// CustomerPhoneChange records a customer's number changing from one phone to another
+type CustomerPhoneChange struct {
+	ID         int       `json:"id" db:"id"`
+	CustomerID int       `json:"customer_id" db:"customer_id"`
+	OldPhone   string    `json:"old_phone" db:"old_phone"`
+	NewPhone   string    `json:"new_phone" db:"new_phone"`
+	ChangedBy  *string   `json:"changed_by,omitempty" db:"changed_by"`
+	ChangedAt  time.Time `json:"changed_at" db:"changed_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// DispatchPolicy decides which pending messages are picked up first when they are pulled
+// from the database in batches
+type DispatchPolicy string
+
+// Dispatch policies
+const (
+	DispatchFIFO                DispatchPolicy = "fifo"                  // Oldest message first, whatever its campaign
+	DispatchFair                DispatchPolicy = "fair"                  // Round robin across campaigns, up to a fair share each per batch
+	DispatchNewestCampaignFirst DispatchPolicy = "newest_campaign_first" // Newest campaign's messages first, oldest first within it
+)
+
+// DispatchPolicies lists the valid dispatch policies
+var DispatchPolicies = []DispatchPolicy{DispatchFIFO, DispatchFair, DispatchNewestCampaignFirst}
+
+// IsValid checks if the policy is supported
+func (p DispatchPolicy) IsValid() bool {
+	for _, policy := range DispatchPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// DispatchOrder is the order pending messages are picked up in
+// The zero value is first in, first out
+type DispatchOrder struct {
+	Policy    DispatchPolicy `json:"policy"`
+	FairShare int            `json:"fair_share,omitempty"` // Most messages per campaign per batch under the fair policy
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// ExportJobStatus represents the state of a background export
+type ExportJobStatus string
+
+const (
+	ExportJobPending   ExportJobStatus = "pending"
+	ExportJobRunning   ExportJobStatus = "running"
+	ExportJobCompleted ExportJobStatus = "completed"
+	ExportJobFailed    ExportJobStatus = "failed"
+)
+
+// ExportJob is a campaign message export generated in the background
+type ExportJob struct {
+	ID          int             `json:"id" db:"id"`
+	CampaignID  int             `json:"campaign_id" db:"campaign_id"`
+	Status      ExportJobStatus `json:"status" db:"status"`
+	TotalRows   *int            `json:"total_rows" db:"total_rows"`
+	RowsWritten int             `json:"rows_written" db:"rows_written"`
+	FileName    *string         `json:"-" db:"file_name"`
+	SizeBytes   *int64          `json:"size_bytes,omitempty" db:"size_bytes"`
+	Error       *string         `json:"error,omitempty" db:"error"`
+	RequestedBy *string         `json:"requested_by,omitempty" db:"requested_by"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty" db:"completed_at"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty" db:"expires_at"`
+}
+
+// Progress returns the share of rows written, from 0 to 1
+// A job with no total yet has made no progress; a completed job is done even when empty
+func (j *ExportJob) Progress() float64 {
+	if j.Status == ExportJobCompleted {
+		return 1
+	}
+	if j.TotalRows == nil || *j.TotalRows == 0 {
+		return 0
+	}
+	progress := float64(j.RowsWritten) / float64(*j.TotalRows)
+	if progress > 1 {
+		return 1
+	}
+	return progress
+}
+
+// Expired reports whether the job's file is past its retention at t
+func (j *ExportJob) Expired(t time.Time) bool {
+	return j.ExpiresAt != nil && !t.Before(*j.ExpiresAt)
+}
@@ -9,19 +9,39 @@ const (
 	MessageStatusPending MessageStatus = "pending"
 	MessageStatusSent    MessageStatus = "sent"
 	MessageStatusFailed  MessageStatus = "failed"
+	// MessageStatusCancelled marks a message its campaign's cancellation stopped before it was sent
+	MessageStatusCancelled MessageStatus = "cancelled"
+	// MessageStatusSkippedDuplicateContent marks a message the worker did not send because the
+	// customer got the same content from another campaign shortly before
+	MessageStatusSkippedDuplicateContent MessageStatus = "skipped_duplicate_content"
+	// MessageStatusExpired marks a message the worker did not send because its send's
+	// process_by deadline had passed, late delivery being worse than none
+	MessageStatusExpired MessageStatus = "expired"
 )
 
 // OutboundMessage represents an outbound message
 type OutboundMessage struct {
-	ID              int           `json:"id" db:"id"`
-	CampaignID      int           `json:"campaign_id" db:"campaign_id"`
-	CustomerID      int           `json:"customer_id" db:"customer_id"`
-	Status          MessageStatus `json:"status" db:"status"`
-	RenderedContent *string       `json:"rendered_content,omitempty" db:"rendered_content"`
-	LastError       *string       `json:"last_error,omitempty" db:"last_error"`
-	RetryCount      int           `json:"retry_count" db:"retry_count"`
-	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time     `json:"updated_at" db:"updated_at"`
+	ID                 int           `json:"id" db:"id"`
+	CampaignID         int           `json:"campaign_id" db:"campaign_id"`
+	CustomerID         int           `json:"customer_id" db:"customer_id"`
+	Status             MessageStatus `json:"status" db:"status"`
+	RenderedContent    *string       `json:"rendered_content,omitempty" db:"rendered_content"`
+	LastError          *string       `json:"last_error,omitempty" db:"last_error"`
+	RetryCount         int           `json:"retry_count" db:"retry_count"`
+	PublishedAt        *time.Time    `json:"published_at,omitempty" db:"published_at"`
+	CreatedAt          time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at" db:"updated_at"`
+	ContentFingerprint *string       `json:"-" db:"content_fingerprint"` // Written on create for the duplicate content check
+
+	// Parts of a multi-part send share GroupID, the ID of part 1, and are sent in Part order;
+	// a message sent whole has no group and is part 1 of 1
+	GroupID   *int `json:"group_id,omitempty" db:"group_id"`
+	Part      int  `json:"part,omitempty" db:"part"`
+	PartCount int  `json:"part_count,omitempty" db:"part_count"`
+
+	// ResentFromMessageID is the message this one was resent from as a fresh attempt; resends
+	// are counted apart from the send's messages in campaign stats
+	ResentFromMessageID *int `json:"resent_from_message_id,omitempty" db:"resent_from_message_id"`
 }
 
 // OutboundMessageWithDetails includes campaign and customer info
@@ -31,6 +51,62 @@ type OutboundMessageWithDetails struct {
 	Customer Customer `json:"customer"`
 }
 
+// ChannelDeliveryStats aggregates delivery outcomes for a channel over a time window
+type ChannelDeliveryStats struct {
+	Channel Channel `json:"channel"`
+	Total   int     `json:"total"`
+	Failed  int     `json:"failed"`
+}
+
+// FailureRate returns the fraction of failed messages (0.0 when there is no history)
+func (s *ChannelDeliveryStats) FailureRate() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(s.Total)
+}
+
+// ChannelRetryEffectiveness counts a channel's sent messages and how many of them needed retries
+type ChannelRetryEffectiveness struct {
+	Channel        Channel `json:"channel"`
+	Sent           int     `json:"sent"`
+	SentAfterRetry int     `json:"sent_after_retry"`
+
+	// RetriedPercent is the percentage of sent messages that needed at least one retry
+	RetriedPercent float64 `json:"retried_percent"`
+}
+
+// CampaignPendingBacklog splits a campaign's pending messages by where they are waiting
+type CampaignPendingBacklog struct {
+	CampaignID int `json:"campaign_id"`
+
+	// Queued messages were published and are waiting for the worker
+	Queued int `json:"queued"`
+
+	// Unpublished messages never reached the broker; nothing will send them until republished
+	Unpublished int `json:"unpublished"`
+
+	// Deferred messages are held back until a later time or until their campaign resumes
+	Deferred int `json:"deferred"`
+
+	// OldestUnpublishedAt is when the oldest unpublished message was created (nil when none)
+	OldestUnpublishedAt *time.Time `json:"oldest_unpublished_at,omitempty"`
+}
+
+// QueueLatency returns the time between the job being published and now
+// The second return value is false if the message was never published
+func (m *OutboundMessage) QueueLatency(now time.Time) (time.Duration, bool) {
+	if m.PublishedAt == nil {
+		return 0, false
+	}
+	return now.Sub(*m.PublishedAt), true
+}
+
+// TotalLatency returns the time between the message being created and now
+func (m *OutboundMessage) TotalLatency(now time.Time) time.Duration {
+	return now.Sub(m.CreatedAt)
+}
+
 // CanRetry checks if message can be retried
 func (m *OutboundMessage) CanRetry() bool {
 	return m.Status == MessageStatusFailed && m.RetryCount < 3
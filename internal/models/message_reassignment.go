@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// MessageReassignment records messages moved from one campaign to another by an operator
+type MessageReassignment struct {
+	ID             int           `json:"id" db:"id"`
+	FromCampaignID int           `json:"from_campaign_id" db:"from_campaign_id"`
+	ToCampaignID   int           `json:"to_campaign_id" db:"to_campaign_id"`
+	Status         MessageStatus `json:"status" db:"status"`                 // Status of the messages moved
+	MessagesMoved  int           `json:"messages_moved" db:"messages_moved"` // Raised with each batch moved
+	RequestedBy    *string       `json:"requested_by,omitempty" db:"requested_by"`
+	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ProcessingError is a worker error recorded for triage
+type ProcessingError struct {
+	ID         int       `json:"id" db:"id"`
+	MessageID  *int      `json:"message_id,omitempty" db:"message_id"`
+	ErrorClass string    `json:"error_class" db:"error_class"`
+	Error      string    `json:"error" db:"error"`
+	WorkerID   string    `json:"worker_id" db:"worker_id"`
+	OccurredAt time.Time `json:"occurred_at" db:"occurred_at"`
+}
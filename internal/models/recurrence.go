@@ -0,0 +1,189 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// RecurrenceFrequency is how often a recurring campaign repeats
+type RecurrenceFrequency string
+
+const (
+	RecurrenceWeekly  RecurrenceFrequency = "weekly"
+	RecurrenceMonthly RecurrenceFrequency = "monthly"
+)
+
+// RecurrenceRule says when a recurring campaign's occurrences are sent
+// Time is a wall-clock time in Timezone, so occurrences keep their local time across DST changes
+type RecurrenceRule struct {
+	Frequency RecurrenceFrequency `json:"frequency"`
+	// DayOfWeek is the day of weekly occurrences, 0 (Sunday) to 6 (Saturday)
+	DayOfWeek *int `json:"day_of_week,omitempty"`
+	// DayOfMonth is the day of monthly occurrences, 1 to 31; shorter months use their last day
+	DayOfMonth *int `json:"day_of_month,omitempty"`
+	// Time is the local time of day, e.g. "09:00"
+	Time string `json:"time"`
+	// Timezone is an IANA time zone, e.g. "Africa/Nairobi"
+	Timezone string `json:"timezone"`
+	// EndDate is the last local date, e.g. "2026-12-31", an occurrence may fall on (nil never ends)
+	EndDate *string `json:"end_date,omitempty"`
+	// MaxOccurrences ends the recurrence after that many occurrences (nil never ends)
+	MaxOccurrences *int `json:"max_occurrences,omitempty"`
+}
+
+// recurrenceTimeLayout and recurrenceDateLayout are the formats of Time and EndDate
+const (
+	recurrenceTimeLayout = "15:04"
+	recurrenceDateLayout = "2006-01-02"
+)
+
+// Validate checks the rule is complete and each field is in range
+func (r *RecurrenceRule) Validate() error {
+	switch r.Frequency {
+	case RecurrenceWeekly:
+		if r.DayOfWeek == nil || *r.DayOfWeek < 0 || *r.DayOfWeek > 6 {
+			return fmt.Errorf("weekly recurrence requires day_of_week from 0 (Sunday) to 6 (Saturday)")
+		}
+		if r.DayOfMonth != nil {
+			return fmt.Errorf("weekly recurrence does not take day_of_month")
+		}
+	case RecurrenceMonthly:
+		if r.DayOfMonth == nil || *r.DayOfMonth < 1 || *r.DayOfMonth > 31 {
+			return fmt.Errorf("monthly recurrence requires day_of_month from 1 to 31")
+		}
+		if r.DayOfWeek != nil {
+			return fmt.Errorf("monthly recurrence does not take day_of_week")
+		}
+	default:
+		return fmt.Errorf("recurrence frequency must be %q or %q", RecurrenceWeekly, RecurrenceMonthly)
+	}
+
+	if _, err := time.Parse(recurrenceTimeLayout, r.Time); err != nil {
+		return fmt.Errorf("recurrence time must be HH:MM, e.g. \"09:00\"")
+	}
+	if r.Timezone == "" {
+		return fmt.Errorf("recurrence timezone is required, e.g. \"Africa/Nairobi\"")
+	}
+	if _, err := time.LoadLocation(r.Timezone); err != nil {
+		return fmt.Errorf("unknown recurrence timezone %q", r.Timezone)
+	}
+	if r.EndDate != nil {
+		if _, err := time.Parse(recurrenceDateLayout, *r.EndDate); err != nil {
+			return fmt.Errorf("recurrence end_date must be YYYY-MM-DD")
+		}
+	}
+	if r.MaxOccurrences != nil && *r.MaxOccurrences < 1 {
+		return fmt.Errorf("recurrence max_occurrences must be at least 1")
+	}
+
+	return nil
+}
+
+// Next returns the first occurrence strictly after the given time, in UTC, and false once
+// the rule has passed its end date; MaxOccurrences is left to the caller, which keeps count
+// A local time skipped by a DST change runs that much later, e.g. 02:30 becomes 03:30, and one
+// repeated by a DST change runs at its first instance
+func (r *RecurrenceRule) Next(after time.Time) (time.Time, bool) {
+	location, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+	clock, err := time.Parse(recurrenceTimeLayout, r.Time)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := after.In(location)
+	year, month, day := local.Date()
+
+	var next time.Time
+	switch r.Frequency {
+	case RecurrenceWeekly:
+		if r.DayOfWeek == nil {
+			return time.Time{}, false
+		}
+		// The first matching day from today, or a week later when today's time has passed
+		offset := (*r.DayOfWeek - int(local.Weekday()) + 7) % 7
+		next = wallClock(year, month, day+offset, clock, location)
+		if !next.After(after) {
+			next = wallClock(year, month, day+offset+7, clock, location)
+		}
+	case RecurrenceMonthly:
+		if r.DayOfMonth == nil {
+			return time.Time{}, false
+		}
+		next = wallClock(year, month, clampDay(year, month, *r.DayOfMonth), clock, location)
+		if !next.After(after) {
+			next = wallClock(year, month+1, clampDay(year, month+1, *r.DayOfMonth), clock, location)
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	if r.EndDate != nil {
+		end, err := time.ParseInLocation(recurrenceDateLayout, *r.EndDate, location)
+		if err != nil || !next.Before(end.AddDate(0, 0, 1)) {
+			return time.Time{}, false
+		}
+	}
+
+	return next.UTC(), true
+}
+
+// LocalDate returns the date of t in the rule's time zone, e.g. "2026-10-16"
+func (r *RecurrenceRule) LocalDate(t time.Time) string {
+	if location, err := time.LoadLocation(r.Timezone); err == nil {
+		t = t.In(location)
+	}
+	return t.Format(recurrenceDateLayout)
+}
+
+// wallClock returns the given local date at clock's time of day, moving a time skipped by a
+// DST change forward by the length of the gap
+func wallClock(year int, month time.Month, day int, clock time.Time, location *time.Location) time.Time {
+	at := time.Date(year, month, day, clock.Hour(), clock.Minute(), 0, 0, location)
+	// time.Date resolves a skipped time with the offset before the change, landing early
+	// (the day before, for a change at midnight)
+	if want, got := clock.Hour()*60+clock.Minute(), at.Hour()*60+at.Minute(); got != want {
+		gap := (want - got + 24*60) % (24 * 60)
+		at = at.Add(time.Duration(gap) * time.Minute)
+	}
+	return at
+}
+
+// clampDay returns day, or the last day of the month when the month is shorter
+// month may run past December; time.Date normalizes it into the next year
+func clampDay(year int, month time.Month, day int) int {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > last {
+		return last
+	}
+	return day
+}
+
+// CampaignRecurrence is a recurring campaign's rule and how far it has got
+type CampaignRecurrence struct {
+	CampaignID int            `json:"campaign_id"`
+	Rule       RecurrenceRule `json:"rule"`
+	Paused     bool           `json:"paused"`
+	// NextOccurrenceAt is when the next occurrence is sent (nil once the rule has ended)
+	NextOccurrenceAt *time.Time `json:"next_occurrence_at"`
+	Occurrences      int        `json:"occurrences"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// Ended reports whether no further occurrence will be sent
+func (r *CampaignRecurrence) Ended() bool {
+	return r.NextOccurrenceAt == nil
+}
+
+// CampaignOccurrence is one occurrence of a recurring campaign, sent as a campaign of its own
+type CampaignOccurrence struct {
+	CampaignID       int            `json:"campaign_id"`
+	ParentCampaignID int            `json:"parent_campaign_id"`
+	OccurrenceAt     time.Time      `json:"occurrence_at"`
+	Name             string         `json:"name"`
+	Status           CampaignStatus `json:"status"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
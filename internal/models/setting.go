@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// SettingSendingEnabled is the setting holding the kill switch for all outbound sending,
+// "true" or "false"
+const SettingSendingEnabled = "sending_enabled"
+
+// Setting is a runtime setting shared by the API and every worker
+type Setting struct {
+	Key       string    `json:"key" db:"key"`
+	Value     string    `json:"value" db:"value"`
+	UpdatedBy *string   `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SettingChange is one change of a setting in the audit log
+type SettingChange struct {
+	ID        int       `json:"id" db:"id"`
+	Key       string    `json:"key" db:"key"`
+	Value     string    `json:"value" db:"value"`
+	Reason    string    `json:"reason" db:"reason"`
+	Actor     *string   `json:"actor,omitempty" db:"actor"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
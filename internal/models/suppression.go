@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// CampaignSuppression is a phone a single campaign must not message
+type CampaignSuppression struct {
+	CampaignID int       `json:"campaign_id" db:"campaign_id"`
+	Phone      string    `json:"phone" db:"phone"`
+	AddedBy    *string   `json:"added_by,omitempty" db:"added_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
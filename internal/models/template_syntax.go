@@ -0,0 +1,42 @@
+package models
+
+// TemplateSyntax is the placeholder delimiter style a template is written in,
+// so templates imported from other systems can be sent without rewriting them
+type TemplateSyntax string
+
+const (
+	TemplateSyntaxBraces       TemplateSyntax = "braces"        // {first_name}
+	TemplateSyntaxDoubleBraces TemplateSyntax = "double_braces" // {{first_name}}
+	TemplateSyntaxBrackets     TemplateSyntax = "brackets"      // [[first_name]]
+	TemplateSyntaxPercent      TemplateSyntax = "percent"       // %first_name%
+)
+
+// TemplateSyntaxes lists the supported syntaxes
+var TemplateSyntaxes = []TemplateSyntax{
+	TemplateSyntaxBraces,
+	TemplateSyntaxDoubleBraces,
+	TemplateSyntaxBrackets,
+	TemplateSyntaxPercent,
+}
+
+// Delimiters returns the opening and closing delimiters of a placeholder; ok is false for an unknown syntax
+func (s TemplateSyntax) Delimiters() (open, close string, ok bool) {
+	switch s {
+	case TemplateSyntaxBraces:
+		return "{", "}", true
+	case TemplateSyntaxDoubleBraces:
+		return "{{", "}}", true
+	case TemplateSyntaxBrackets:
+		return "[[", "]]", true
+	case TemplateSyntaxPercent:
+		return "%", "%", true
+	default:
+		return "", "", false
+	}
+}
+
+// IsValid checks if the syntax is supported
+func (s TemplateSyntax) IsValid() bool {
+	_, _, ok := s.Delimiters()
+	return ok
+}
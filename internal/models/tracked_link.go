@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// TrackedLink is a link in a message rewritten to a /r/{token} redirect
+type TrackedLink struct {
+	ID         int       `json:"id" db:"id"`
+	MessageID  int       `json:"message_id" db:"message_id"`
+	CampaignID int       `json:"campaign_id" db:"-"`     // From the message; not stored on the link
+	Position   int       `json:"position" db:"position"` // Which link in the message, from 0
+	Token      string    `json:"token" db:"token"`
+	URL        string    `json:"url" db:"url"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// LinkClick is one visit to a tracked link
+type LinkClick struct {
+	ID         int64     `json:"id" db:"id"`
+	LinkID     int       `json:"link_id" db:"link_id"`
+	MessageID  int       `json:"message_id" db:"message_id"`
+	CampaignID int       `json:"campaign_id" db:"campaign_id"`
+	ClickedAt  time.Time `json:"clicked_at" db:"clicked_at"`
+	UserAgent  *string   `json:"user_agent,omitempty" db:"user_agent"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// WorkerClaim is a message a worker claimed for processing
+type WorkerClaim struct {
+	MessageID  int       `json:"message_id" db:"id"`
+	CampaignID int       `json:"campaign_id" db:"campaign_id"`
+	Status     string    `json:"status" db:"status"`
+	ClaimedAt  time.Time `json:"claimed_at" db:"claimed_at"`
+	InFlight   bool      `json:"in_flight"` // The claim is held and unexpired: the worker is still handling it
+}
+
+// WorkerThroughput counts a worker's claims over a trailing window
+type WorkerThroughput struct {
+	Claimed int `json:"claimed"`
+	Sent    int `json:"sent"`
+	Failed  int `json:"failed"`
+}
+
+// WorkerActivity is a worker's liveness, recent claims and throughput
+type WorkerActivity struct {
+	WorkerID     string           `json:"worker_id"`
+	StartedAt    *time.Time       `json:"started_at,omitempty"`
+	LastSeenAt   *time.Time       `json:"last_seen_at,omitempty"`
+	LastHour     WorkerThroughput `json:"last_hour"`
+	LastDay      WorkerThroughput `json:"last_day"`
+	RecentClaims []*WorkerClaim   `json:"recent_claims"`
+}
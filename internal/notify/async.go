@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"smsleopard/internal/metrics"
+)
+
+// AsyncDeliveryTimeout bounds how long one queued notification may take to deliver
+const AsyncDeliveryTimeout = 10 * time.Second
+
+// queuedNotification is a notification waiting to be delivered
+type queuedNotification struct {
+	subject string
+	payload interface{}
+}
+
+// AsyncNotifier delivers notifications from a bounded queue in the background
+// Notify never blocks the caller: when the queue is full, the notifier is closed
+// or delivery fails, the notification is dropped and counted instead
+type AsyncNotifier struct {
+	name    string
+	next    Notifier
+	queue   chan queuedNotification
+	done    chan struct{}
+	dropped atomic.Int64
+
+	mu     sync.RWMutex // Guards closed against sends on a closed queue
+	closed bool
+}
+
+// NewAsyncNotifier starts delivering to next from a queue holding up to size notifications
+// name labels dropped notifications in the smsleopard_events_dropped_total metric
+func NewAsyncNotifier(name string, next Notifier, size int) *AsyncNotifier {
+	n := &AsyncNotifier{
+		name:  name,
+		next:  next,
+		queue: make(chan queuedNotification, size),
+		done:  make(chan struct{}),
+	}
+	go n.run()
+	return n
+}
+
+// Notify queues the notification and returns immediately
+// The caller's context is not used for delivery, which happens after the caller has moved on
+func (n *AsyncNotifier) Notify(ctx context.Context, subject string, payload interface{}) error {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.closed {
+		n.drop()
+		return nil
+	}
+
+	select {
+	case n.queue <- queuedNotification{subject: subject, payload: payload}:
+	default:
+		n.drop()
+	}
+	return nil
+}
+
+// Dropped returns how many notifications were not delivered
+func (n *AsyncNotifier) Dropped() int64 {
+	return n.dropped.Load()
+}
+
+// Close delivers what is already queued, then closes the wrapped notifier if it holds resources
+func (n *AsyncNotifier) Close() error {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+
+	<-n.done
+	if closer, ok := n.next.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// run delivers queued notifications until the queue is closed
+// Failures are logged when a sink goes down and when it recovers, not once per notification
+func (n *AsyncNotifier) run() {
+	defer close(n.done)
+
+	failing := false
+	for item := range n.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), AsyncDeliveryTimeout)
+		err := n.next.Notify(ctx, item.subject, item.payload)
+		cancel()
+
+		switch {
+		case err != nil:
+			n.drop()
+			if !failing {
+				log.Printf("Warning: %s sink unavailable, dropping events: %v", n.name, err)
+				failing = true
+			}
+		case failing:
+			log.Printf("✅ %s sink recovered (%d events dropped so far)", n.name, n.Dropped())
+			failing = false
+		}
+	}
+}
+
+func (n *AsyncNotifier) drop() {
+	n.dropped.Add(1)
+	metrics.DroppedEvents.WithLabelValues(n.name).Inc()
+}
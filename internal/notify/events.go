@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"smsleopard/internal/config"
+)
+
+// EventQueueSize is how many events each sink buffers before dropping new ones
+const EventQueueSize = 10000
+
+// Lifecycle event types
+const (
+	EventCampaignCreated = "campaign.created"
+	EventCampaignSending = "campaign.sending"
+	EventMessageSent     = "message.sent"
+	EventMessageFailed   = "message.failed"
+)
+
+// Event is a campaign or message lifecycle event
+type Event struct {
+	Type           string    `json:"type"`
+	CampaignID     int       `json:"campaign_id"`
+	MessageID      int       `json:"message_id,omitempty"`
+	CustomerID     int       `json:"customer_id,omitempty"`
+	Channel        string    `json:"channel,omitempty"`
+	Status         string    `json:"status,omitempty"`
+	MessagesQueued int       `json:"messages_queued,omitempty"`
+	RetryCount     int       `json:"retry_count,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	Simulated      bool      `json:"simulated,omitempty"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+// Key groups events by campaign so sinks can keep a campaign's events in order
+func (e Event) Key() string {
+	return strconv.Itoa(e.CampaignID)
+}
+
+// Events publishes lifecycle events to every enabled sink
+// Publishing never blocks or fails the caller; a nil *Events publishes nothing
+type Events struct {
+	sinks []Notifier
+}
+
+// NewEvents creates an event publisher over the given sinks
+// Sinks must return quickly; wrap slow ones in an AsyncNotifier
+func NewEvents(sinks ...Notifier) *Events {
+	return &Events{sinks: sinks}
+}
+
+// NewEventsFromConfig creates the sinks named in EVENT_SINK, each behind its own queue
+// It returns nil when no sink is enabled
+func NewEventsFromConfig(cfg config.EventsConfig, notifyCfg config.NotifyConfig) (*Events, error) {
+	if len(cfg.Sinks) == 0 {
+		return nil, nil
+	}
+
+	sinks := make([]Notifier, 0, len(cfg.Sinks))
+	for _, name := range cfg.Sinks {
+		var sink Notifier
+		switch name {
+		case config.EventSinkKafka:
+			kafkaNotifier, err := NewKafkaNotifier(cfg.KafkaBrokers, cfg.KafkaTopic)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create kafka event sink: %w", err)
+			}
+			sink = kafkaNotifier
+		case config.EventSinkWebhook:
+			webhookNotifier, err := NewWebhookNotifier(notifyCfg.WebhookURL)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create webhook event sink: %w", err)
+			}
+			if cfg.WebhookTemplate != "" {
+				payloadTemplate, err := ParsePayloadTemplate(cfg.WebhookTemplate)
+				if err != nil {
+					return nil, fmt.Errorf("EVENT_WEBHOOK_TEMPLATE is invalid: %w", err)
+				}
+				webhookNotifier.SetPayloadTemplate(payloadTemplate)
+			}
+			sink = webhookNotifier
+		default:
+			return nil, fmt.Errorf("unknown event sink %q", name)
+		}
+		sinks = append(sinks, NewAsyncNotifier(name, sink, EventQueueSize))
+	}
+
+	return NewEvents(sinks...), nil
+}
+
+// Publish sends the event to every sink, stamping OccurredAt if it is unset
+func (e *Events) Publish(ctx context.Context, event Event) {
+	if e == nil {
+		return
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	for _, sink := range e.sinks {
+		if err := sink.Notify(ctx, event.Type, event); err != nil {
+			log.Printf("Warning: Failed to publish %s event: %v", event.Type, err)
+		}
+	}
+}
+
+// Close flushes queued events and closes every sink that holds resources
+func (e *Events) Close() error {
+	if e == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, sink := range e.sinks {
+		if closer, ok := sink.(interface{ Close() error }); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
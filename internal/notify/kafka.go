@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"smsleopard/internal/metrics"
+)
+
+// KafkaWriter is the part of kafka.Writer the notifier uses
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaNotifier publishes notifications as JSON messages to a Kafka topic
+// The subject is sent as the "type" header; payloads with a Key method are
+// keyed by it so related events land on the same partition in order
+type KafkaNotifier struct {
+	writer  KafkaWriter
+	dropped atomic.Int64
+}
+
+// NewKafkaNotifier creates a notifier with an async writer for the given brokers and topic
+// Batches the brokers reject after retries are dropped and counted, never retried by the caller
+func NewKafkaNotifier(brokers []string, topic string) (*KafkaNotifier, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("kafka brokers cannot be empty")
+	}
+	if topic == "" {
+		return nil, errors.New("kafka topic cannot be empty")
+	}
+
+	n := &KafkaNotifier{}
+	n.writer = &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+		BatchTimeout: 50 * time.Millisecond,
+		Async:        true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				n.dropped.Add(int64(len(messages)))
+				metrics.DroppedEvents.WithLabelValues("kafka").Add(float64(len(messages)))
+			}
+		},
+	}
+	return n, nil
+}
+
+// NewKafkaNotifierWithWriter creates a notifier that writes through the given writer
+func NewKafkaNotifierWithWriter(writer KafkaWriter) *KafkaNotifier {
+	return &KafkaNotifier{writer: writer}
+}
+
+// Notify hands the notification to the writer
+func (n *KafkaNotifier) Notify(ctx context.Context, subject string, payload interface{}) error {
+	value, err := json.Marshal(Notification{
+		Subject: subject,
+		Payload: payload,
+		SentAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	message := kafka.Message{
+		Value:   value,
+		Headers: []kafka.Header{{Key: "type", Value: []byte(subject)}},
+	}
+	if keyed, ok := payload.(interface{ Key() string }); ok {
+		message.Key = []byte(keyed.Key())
+	}
+
+	if err := n.writer.WriteMessages(ctx, message); err != nil {
+		return fmt.Errorf("failed to write to kafka: %w", err)
+	}
+
+	return nil
+}
+
+// Dropped returns how many messages the brokers rejected after the writer accepted them
+func (n *KafkaNotifier) Dropped() int64 {
+	return n.dropped.Load()
+}
+
+// Close flushes pending messages and closes the writer
+func (n *KafkaNotifier) Close() error {
+	return n.writer.Close()
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Notification is a message delivered to operators
+type Notification struct {
+	Subject string      `json:"subject"`
+	Payload interface{} `json:"payload"`
+	SentAt  time.Time   `json:"sent_at"`
+}
+
+// Notifier delivers operator notifications
+type Notifier interface {
+	Notify(ctx context.Context, subject string, payload interface{}) error
+}
+
+// WebhookNotifier posts notifications as JSON to a webhook URL
+type WebhookNotifier struct {
+	url      string
+	client   *http.Client
+	template *PayloadTemplate
+}
+
+// NewWebhookNotifier creates a new webhook notifier
+func NewWebhookNotifier(url string) (*WebhookNotifier, error) {
+	if url == "" {
+		return nil, errors.New("webhook url cannot be empty")
+	}
+
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// SetPayloadTemplate renders lifecycle events with t instead of the default envelope
+// Other notifications, such as digests, are still sent in the envelope
+func (n *WebhookNotifier) SetPayloadTemplate(t *PayloadTemplate) {
+	n.template = t
+}
+
+// Notify posts the notification to the webhook
+func (n *WebhookNotifier) Notify(ctx context.Context, subject string, payload interface{}) error {
+	body, err := n.body(subject, payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// body renders an event with the payload template when one is set, or marshals the envelope
+func (n *WebhookNotifier) body(subject string, payload interface{}) ([]byte, error) {
+	sentAt := time.Now().UTC()
+	if event, ok := payload.(Event); ok && n.template != nil {
+		return n.template.Render(PayloadData{Event: event, SentAt: sentAt})
+	}
+
+	body, err := json.Marshal(Notification{
+		Subject: subject,
+		Payload: payload,
+		SentAt:  sentAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	return body, nil
+}
@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// PayloadData is what a webhook payload template is rendered against: the event's
+// fields (e.g. {{.CampaignID}}, {{.Type}}, {{.OccurredAt}}) plus the delivery time
+type PayloadData struct {
+	Event
+	SentAt time.Time
+}
+
+// payloadFuncs is the whole function map a payload template may use besides the
+// text/template builtins; templates cannot reach the network, files or environment
+var payloadFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"rfc3339": func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+	"unix": func(t time.Time) int64 {
+		return t.Unix()
+	},
+}
+
+// samplePayload fills every event field so a template referencing a field that
+// does not exist fails validation rather than delivery
+var samplePayload = PayloadData{
+	Event: Event{
+		Type:           EventCampaignSending,
+		CampaignID:     42,
+		MessageID:      7,
+		CustomerID:     3,
+		Channel:        "sms",
+		Status:         "sent",
+		MessagesQueued: 1000,
+		RetryCount:     1,
+		Error:          "provider timeout",
+		Simulated:      true,
+		OccurredAt:     time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	},
+	SentAt: time.Date(2025, 1, 1, 12, 0, 1, 0, time.UTC),
+}
+
+// payloadTemplates caches compiled templates by their text
+var payloadTemplates sync.Map
+
+// PayloadTemplate renders webhook bodies in the shape a consumer expects instead of the default envelope
+type PayloadTemplate struct {
+	text string
+	tmpl *template.Template
+}
+
+// ParsePayloadTemplate compiles and validates a payload template
+// The template must render a sample event without error, which catches unknown
+// fields and functions, and must produce valid JSON; compiled templates are cached
+func ParsePayloadTemplate(text string) (*PayloadTemplate, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("payload template cannot be empty")
+	}
+	if cached, ok := payloadTemplates.Load(text); ok {
+		return cached.(*PayloadTemplate), nil
+	}
+
+	tmpl, err := template.New("payload").Funcs(payloadFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload template: %w", err)
+	}
+
+	t := &PayloadTemplate{text: text, tmpl: tmpl}
+	if _, err := t.Render(samplePayload); err != nil {
+		return nil, err
+	}
+
+	payloadTemplates.Store(text, t)
+	return t, nil
+}
+
+// Render executes the template and checks the result is JSON
+func (t *PayloadTemplate) Render(data PayloadData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render payload template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("payload template must render valid JSON, got %q", buf.String())
+	}
+	return buf.Bytes(), nil
+}
+
+// Sample renders the template against the sample event used for validation
+func (t *PayloadTemplate) Sample() json.RawMessage {
+	body, _ := t.Render(samplePayload)
+	return body
+}
@@ -64,6 +64,64 @@ func (c *Connection) Channel() (*amqp.Channel, error) {
 	return c.channel, nil
 }
 
+// QueueDepth returns how many jobs are waiting in the queue, declaring it passively so a
+// missing queue is an error instead of being created
+// It uses a channel of its own, as the broker closes the channel a passive declare fails on
+func (c *Connection) QueueDepth(queueName string) (int, error) {
+	// Reconnects if needed
+	if _, err := c.Channel(); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return 0, errors.New("connection is closed")
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer ch.Close()
+
+	queue, err := ch.QueueDeclarePassive(queueName, true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect queue %s: %w", queueName, err)
+	}
+
+	return queue.Messages, nil
+}
+
+// DeleteQueue deletes a queue if it holds no jobs; a queue already gone is not an error
+// Like QueueDepth it uses a channel of its own, as the broker closes the channel a refused
+// delete fails on
+func (c *Connection) DeleteQueue(queueName string) error {
+	// Reconnects if needed
+	if _, err := c.Channel(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return errors.New("connection is closed")
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer ch.Close()
+
+	if _, err := ch.QueueDelete(queueName, false, true, false); err != nil {
+		return fmt.Errorf("failed to delete queue %s: %w", queueName, err)
+	}
+	return nil
+}
+
 // reconnect is an internal method to reconnect to RabbitMQ
 func (c *Connection) reconnect() error {
 	// Close existing connection/channel if any
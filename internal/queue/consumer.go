@@ -1,21 +1,31 @@
 package queue
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"os"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 // Consumer consumes messages from RabbitMQ queue
 type Consumer struct {
-	conn      *Connection
-	queueName string
-	handler   MessageHandler
-	stopChan  chan struct{}
-	doneChan  chan struct{}
+	conn       *Connection
+	queueName  string
+	handler    MessageHandler
+	stopChan   chan struct{}
+	doneChan   chan struct{}
+	deliveries chan (<-chan amqp.Delivery)
+	watchdog   *Watchdog
+	inFlight   *InFlight
+
+	mu      sync.Mutex
+	channel *amqp.Channel
+	tag     string
+	paused  bool
 }
 
 // MessageHandler is a function that processes a message
@@ -23,6 +33,11 @@ type MessageHandler func(job *MessageJob) error
 
 // NewConsumer creates a new consumer instance
 func NewConsumer(conn *Connection, queueName string, handler MessageHandler) (*Consumer, error) {
+	return newConsumer(conn, queueName, nil, handler)
+}
+
+// newConsumer creates a consumer of a queue declared with the given arguments
+func newConsumer(conn *Connection, queueName string, args amqp.Table, handler MessageHandler) (*Consumer, error) {
 	// Validate conn is not nil
 	if conn == nil {
 		return nil, errors.New("connection cannot be nil")
@@ -51,7 +66,7 @@ func NewConsumer(conn *Connection, queueName string, handler MessageHandler) (*C
 		false, // auto-delete
 		false, // exclusive
 		false, // no-wait
-		nil,   // arguments
+		args,  // arguments
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to declare queue: %w", err)
@@ -63,15 +78,18 @@ func NewConsumer(conn *Connection, queueName string, handler MessageHandler) (*C
 
 	// Return Consumer instance
 	return &Consumer{
-		conn:      conn,
-		queueName: queueName,
-		handler:   handler,
-		stopChan:  stopChan,
-		doneChan:  doneChan,
+		conn:       conn,
+		queueName:  queueName,
+		handler:    handler,
+		stopChan:   stopChan,
+		doneChan:   doneChan,
+		deliveries: make(chan (<-chan amqp.Delivery), 1),
+		tag:        fmt.Sprintf("%s-%d-%d", queueName, os.Getpid(), time.Now().UnixNano()),
 	}, nil
 }
 
 // Start starts consuming messages from the queue
+// A consumer paused before Start starts without pulling messages until resumed
 func (c *Consumer) Start() error {
 	// Get channel from connection
 	ch, err := c.conn.Channel()
@@ -89,10 +107,29 @@ func (c *Consumer) Start() error {
 		return fmt.Errorf("failed to set QoS: %w", err)
 	}
 
-	// Start consuming
-	msgs, err := ch.Consume(
+	c.mu.Lock()
+	c.channel = ch
+	if !c.paused {
+		err = c.consume()
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Process messages in goroutine
+	go c.run()
+
+	log.Printf("Consumer started, listening on queue: %s", c.queueName)
+	return nil
+}
+
+// consume asks the broker for deliveries and hands them to the processing loop
+// Callers must hold c.mu
+func (c *Consumer) consume() error {
+	msgs, err := c.channel.Consume(
 		c.queueName,
-		"",    // consumer tag (auto-generated)
+		c.tag, // consumer tag, kept so a pause can cancel it
 		false, // auto-ack (manual acknowledgement)
 		false, // exclusive
 		false, // no-local
@@ -103,40 +140,124 @@ func (c *Consumer) Start() error {
 		return fmt.Errorf("failed to start consuming: %w", err)
 	}
 
-	// Process messages in goroutine
-	go func() {
-		defer close(c.doneChan)
+	// Replace deliveries the loop has not picked up yet, so this never blocks
+	select {
+	case <-c.deliveries:
+	default:
+	}
+	c.deliveries <- msgs
+	return nil
+}
 
-		for {
-			select {
-			case <-c.stopChan:
-				log.Println("Consumer stopping...")
-				return
-			case d, ok := <-msgs:
-				if !ok {
-					log.Println("Delivery channel closed")
-					return
-				}
+// run processes deliveries until the consumer is stopped
+func (c *Consumer) run() {
+	defer close(c.doneChan)
 
-				// Process message
-				err := c.processMessage(d)
-				if err != nil {
-					log.Printf("Error processing message: %v", err)
-					// Requeue for retry
-					// In Phase 5.4, we'll add retry count checking
-					d.Nack(false, true)
-				} else {
-					// Acknowledge successful processing
-					d.Ack(false)
+	var msgs <-chan amqp.Delivery
+	for {
+		select {
+		case <-c.stopChan:
+			log.Println("Consumer stopping...")
+			return
+		case next := <-c.deliveries:
+			// Finish what a cancelled consumer already received before switching
+			if msgs != nil {
+				for d := range msgs {
+					c.handle(d)
+				}
+			}
+			msgs = next
+		case d, ok := <-msgs:
+			if !ok {
+				if c.awaitingDeliveries() {
+					// Cancelled by Pause; Resume hands over new deliveries
+					msgs = nil
+					continue
 				}
+				log.Println("Delivery channel closed")
+				return
 			}
+			c.handle(d)
 		}
-	}()
+	}
+}
 
-	log.Printf("Consumer started, listening on queue: %s", c.queueName)
+// handle processes one delivery and acknowledges it, or requeues it on error or
+// when the watchdog gives up on the handler
+func (c *Consumer) handle(d amqp.Delivery) {
+	d, done := c.inFlight.Track(d)
+	defer done()
+	c.watchdog.Dispatch(d, c.processMessage)
+}
+
+// SetWatchdog sets the ack deadline enforced on handlers; call it before Start
+// Without one the consumer waits for every handler however long it takes
+func (c *Consumer) SetWatchdog(watchdog *Watchdog) {
+	c.watchdog = watchdog
+}
+
+// SetInFlight counts the deliveries being handled in inFlight, for a shutdown drain to
+// wait on; call it before Start
+func (c *Consumer) SetInFlight(inFlight *InFlight) {
+	c.inFlight = inFlight
+}
+
+// Pause stops pulling messages: the broker stops delivering to this consumer and keeps
+// the rest of the queue. A message already being handled is finished and acknowledged
+func (c *Consumer) Pause() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		return nil
+	}
+	c.paused = true
+
+	// Not started yet: Start will not consume
+	if c.channel == nil {
+		return nil
+	}
+
+	if err := c.channel.Cancel(c.tag, false); err != nil {
+		return fmt.Errorf("failed to cancel consumer: %w", err)
+	}
+
+	log.Printf("Consumer paused on queue: %s", c.queueName)
 	return nil
 }
 
+// Resume starts pulling messages again after Pause
+func (c *Consumer) Resume() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.paused {
+		return nil
+	}
+
+	// Not started yet: Start will consume
+	if c.channel == nil {
+		c.paused = false
+		return nil
+	}
+
+	if err := c.consume(); err != nil {
+		return err
+	}
+	c.paused = false
+
+	log.Printf("Consumer resumed on queue: %s", c.queueName)
+	return nil
+}
+
+// awaitingDeliveries reports whether closed deliveries were cancelled by Pause
+// rather than lost, i.e. the consumer is paused or has been resumed since
+func (c *Consumer) awaitingDeliveries() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused || len(c.deliveries) > 0
+}
+
 // Stop stops consuming messages gracefully
 func (c *Consumer) Stop() error {
 	// Send signal to stopChan
@@ -151,15 +272,31 @@ func (c *Consumer) Stop() error {
 
 // processMessage processes a single message
 func (c *Consumer) processMessage(d amqp.Delivery) error {
-	// Parse JSON body into MessageJob
-	var job MessageJob
-	err := json.Unmarshal(d.Body, &job)
+	// Parse JSON body into MessageJob, with its deadline from the headers
+	job, err := DecodeDelivery(d)
+	if job == nil {
+		return err
+	}
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal message job: %w", err)
+		log.Printf("⚠️  %v; processing it without a deadline", err)
+	}
+
+	// A job too new to understand is set aside rather than requeued forever or sent wrongly
+	if err := job.CheckVersion(); err != nil {
+		log.Printf("⚠️  Message %d: %v; moving it to the poison queue", job.MessageID, err)
+		ch, chErr := c.conn.Channel()
+		if chErr != nil {
+			return fmt.Errorf("failed to get channel: %w", chErr)
+		}
+		// Returning nil acks it off the queue once the copy is published
+		return publishToPoisonQueue(ch, c.queueName, d, err.Error())
+	}
+	if job.Version > CurrentJobVersion {
+		log.Printf("Message %d: job v%d is newer than v%d; processing the fields this build knows", job.MessageID, job.Version, CurrentJobVersion)
 	}
 
 	// Call handler with MessageJob
-	err = c.handler(&job)
+	err = c.handler(job)
 	if err != nil {
 		return fmt.Errorf("handler failed: %w", err)
 	}
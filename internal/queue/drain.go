@@ -0,0 +1,130 @@
+package queue
+
+import (
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// PoisonQueueSuffix is appended to a queue's name to name the queue its unprocessable jobs are moved to
+const PoisonQueueSuffix = ".poison"
+
+// PoisonReasonHeader is the header recording why a job was moved to the poison queue
+const PoisonReasonHeader = "x-poison-reason"
+
+// QueuedJob is a job taken off a queue by a Drain and not yet given back
+type QueuedJob struct {
+	Job  *MessageJob // nil when the body is not a message job
+	Body []byte
+
+	delivery amqp.Delivery
+	resolved bool
+}
+
+// Drain holds the jobs waiting in a queue, taken off it unacknowledged, so they can be
+// inspected as a whole. Each job is then requeued or moved to the poison queue; jobs still
+// held when the drain is closed are requeued, and the broker requeues them all if the
+// process dies first. Jobs being handled by a worker are not waiting and are not drained
+type Drain struct {
+	channel   *amqp.Channel
+	queueName string
+	jobs      []*QueuedJob
+}
+
+// NewDrain takes up to limit waiting jobs off the queue
+func NewDrain(conn *Connection, queueName string, limit int) (*Drain, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("connection cannot be nil")
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel: %w", err)
+	}
+
+	d := &Drain{channel: ch, queueName: queueName}
+	for len(d.jobs) < limit {
+		delivery, ok, err := ch.Get(queueName, false)
+		if err != nil {
+			d.Close()
+			return nil, fmt.Errorf("failed to get job: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		job := &QueuedJob{Body: delivery.Body, delivery: delivery}
+		if decoded, err := DecodeMessageJob(delivery.Body); err == nil {
+			job.Job = decoded
+		}
+		d.jobs = append(d.jobs, job)
+	}
+
+	return d, nil
+}
+
+// Jobs returns the drained jobs in queue order
+func (d *Drain) Jobs() []*QueuedJob {
+	return d.jobs
+}
+
+// Requeue gives a job back to the queue
+func (d *Drain) Requeue(job *QueuedJob) error {
+	if job.resolved {
+		return nil
+	}
+	if err := job.delivery.Nack(false, true); err != nil {
+		return fmt.Errorf("failed to requeue job: %w", err)
+	}
+	job.resolved = true
+	return nil
+}
+
+// Quarantine moves a job to the poison queue with the reason it was removed
+func (d *Drain) Quarantine(job *QueuedJob, reason string) error {
+	if job.resolved {
+		return nil
+	}
+
+	if err := publishToPoisonQueue(d.channel, d.queueName, job.delivery, reason); err != nil {
+		return err
+	}
+
+	// Published before acked, so a failure in between duplicates the job rather than losing it
+	if err := job.delivery.Ack(false); err != nil {
+		return fmt.Errorf("failed to remove job from queue: %w", err)
+	}
+	job.resolved = true
+	return nil
+}
+
+// Close requeues every job not yet requeued or quarantined
+func (d *Drain) Close() error {
+	var firstErr error
+	for _, job := range d.jobs {
+		if err := d.Requeue(job); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// publishToPoisonQueue copies a delivery to its queue's poison queue with the reason; the
+// caller acks it off the queue afterwards
+func publishToPoisonQueue(ch *amqp.Channel, queueName string, delivery amqp.Delivery, reason string) error {
+	poisonQueue := queueName + PoisonQueueSuffix
+	if _, err := ch.QueueDeclare(poisonQueue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare poison queue: %w", err)
+	}
+
+	err := ch.Publish("", poisonQueue, false, false, amqp.Publishing{
+		DeliveryMode: amqp.Persistent,
+		ContentType:  delivery.ContentType,
+		Headers:      amqp.Table{PoisonReasonHeader: reason},
+		Body:         delivery.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish job to poison queue: %w", err)
+	}
+	return nil
+}
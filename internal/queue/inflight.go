@@ -0,0 +1,172 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// InFlight counts the deliveries consumers are handling, so a shutdown drain can wait for
+// them to finish. Each tracked delivery is resolved once: whichever of its handler and
+// Abandon acks or nacks it first wins, and the other's result is discarded
+// A nil InFlight tracks nothing
+type InFlight struct {
+	mu        sync.Mutex
+	tracked   map[*onceAcknowledger]struct{}
+	changed   chan struct{} // closed, and replaced, whenever a delivery stops being tracked
+	completed int
+}
+
+// NewInFlight creates an empty in-flight counter
+func NewInFlight() *InFlight {
+	return &InFlight{
+		tracked: make(map[*onceAcknowledger]struct{}),
+		changed: make(chan struct{}),
+	}
+}
+
+// Track counts d as in flight until done is called; the returned delivery must be handled
+// in its place, so Abandon can requeue it without the handler resolving it again
+func (f *InFlight) Track(d amqp.Delivery) (amqp.Delivery, func()) {
+	if f == nil || d.Acknowledger == nil {
+		return d, func() {}
+	}
+
+	ack := &onceAcknowledger{inner: d.Acknowledger, tag: d.DeliveryTag}
+	d.Acknowledger = ack
+
+	f.mu.Lock()
+	f.tracked[ack] = struct{}{}
+	f.mu.Unlock()
+
+	done := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.tracked[ack]; !ok {
+			return
+		}
+		delete(f.tracked, ack)
+		if !ack.abandoned.Load() {
+			f.completed++
+		}
+		close(f.changed)
+		f.changed = make(chan struct{})
+	}
+	return d, done
+}
+
+// Count returns how many deliveries are being handled
+func (f *InFlight) Count() int {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.tracked)
+}
+
+// Completed returns how many tracked deliveries their handlers have finished, ever
+func (f *InFlight) Completed() int {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.completed
+}
+
+// Wait returns true once nothing is in flight, or false if ctx is done first
+func (f *InFlight) Wait(ctx context.Context) bool {
+	if f == nil {
+		return true
+	}
+	for {
+		f.mu.Lock()
+		if len(f.tracked) == 0 {
+			f.mu.Unlock()
+			return true
+		}
+		changed := f.changed
+		f.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-changed:
+		}
+	}
+}
+
+// Abandon requeues every delivery still in flight and returns how many it requeued
+// Their handlers keep running; whatever they return is discarded
+func (f *InFlight) Abandon() int {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	requeued := 0
+	for ack := range f.tracked {
+		if ack.abandon() {
+			requeued++
+		}
+	}
+	return requeued
+}
+
+// onceAcknowledger passes on the first ack, nack or reject of a delivery and drops the rest
+type onceAcknowledger struct {
+	inner     amqp.Acknowledger
+	tag       uint64
+	resolved  atomic.Bool
+	abandoned atomic.Bool
+}
+
+func (a *onceAcknowledger) Ack(tag uint64, multiple bool) error {
+	if !a.claim(tag) {
+		return nil
+	}
+	return a.inner.Ack(tag, multiple)
+}
+
+func (a *onceAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	if !a.claim(tag) {
+		return nil
+	}
+	return a.inner.Nack(tag, multiple, requeue)
+}
+
+func (a *onceAcknowledger) Reject(tag uint64, requeue bool) error {
+	if !a.claim(tag) {
+		return nil
+	}
+	return a.inner.Reject(tag, requeue)
+}
+
+// claim reports whether this resolution is the delivery's first
+func (a *onceAcknowledger) claim(tag uint64) bool {
+	if !a.resolved.CompareAndSwap(false, true) {
+		if a.abandoned.Load() {
+			log.Printf("Handler for delivery %d returned after a drain requeued it; result discarded", tag)
+		}
+		return false
+	}
+	return true
+}
+
+// abandon requeues the delivery unless it has already been resolved
+func (a *onceAcknowledger) abandon() bool {
+	if !a.resolved.CompareAndSwap(false, true) {
+		return false
+	}
+	a.abandoned.Store(true)
+	if err := a.inner.Nack(a.tag, false, true); err != nil {
+		log.Printf("Warning: Failed to requeue delivery %d: %v", a.tag, err)
+		return false
+	}
+	return true
+}
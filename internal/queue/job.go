@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// JobVersion is the schema version a message job was published with, carried as "v"
+//
+// The upgrade contract between publishers and consumers:
+//   - Fields are only ever added. A consumer ignores fields it does not know, and a job
+//     published without a field a consumer expects decodes with its zero value
+//   - A change a consumer must understand to process the job correctly bumps the version
+//   - A consumer processes jobs up to JobVersionTolerance versions newer than its own,
+//     logging them, so publishers can be rolled out before workers; newer jobs are moved
+//     to the poison queue rather than processed wrongly or requeued forever
+//   - Jobs published before versioning carry no "v" and are JobVersion1
+type JobVersion int
+
+const (
+	// JobVersion1 is the message, campaign and customer IDs of the message to send
+	JobVersion1 JobVersion = 1
+
+	// CurrentJobVersion is the version this build publishes and fully understands
+	CurrentJobVersion = JobVersion1
+
+	// JobVersionTolerance is how many versions past CurrentJobVersion are still processed
+	JobVersionTolerance JobVersion = 1
+)
+
+// ProcessByHeader is the header carrying a job's deadline, as RFC 3339; a job published
+// without it has none
+const ProcessByHeader = "x-process-by"
+
+// ErrUnsupportedJobVersion is returned by CheckVersion for a job too new or too old to process
+var ErrUnsupportedJobVersion = errors.New("unsupported message job version")
+
+// MessageJob represents a message job to be processed
+type MessageJob struct {
+	Version    JobVersion `json:"v"`
+	MessageID  int        `json:"message_id"`
+	CampaignID int        `json:"campaign_id"`
+	CustomerID int        `json:"customer_id"`
+
+	// ProcessBy is when the job stops being worth sending; it travels in ProcessByHeader, not
+	// the body, so workers that predate it still read the job. Nil means no deadline
+	ProcessBy *time.Time `json:"-"`
+}
+
+// NewMessageJob creates a job for a message, stamped with CurrentJobVersion
+func NewMessageJob(messageID, campaignID, customerID int) MessageJob {
+	return MessageJob{
+		Version:    CurrentJobVersion,
+		MessageID:  messageID,
+		CampaignID: campaignID,
+		CustomerID: customerID,
+	}
+}
+
+// CheckVersion returns ErrUnsupportedJobVersion unless the job's version is at least
+// JobVersion1 and at most JobVersionTolerance past CurrentJobVersion
+func (j *MessageJob) CheckVersion() error {
+	if j.Version < JobVersion1 || j.Version > CurrentJobVersion+JobVersionTolerance {
+		return fmt.Errorf("%w: v%d (this build handles v%d to v%d)",
+			ErrUnsupportedJobVersion, j.Version, JobVersion1, CurrentJobVersion+JobVersionTolerance)
+	}
+	return nil
+}
+
+// DecodeMessageJob parses a message job from a delivery body, ignoring unknown fields
+// A job without a version is JobVersion1; the version is not checked here
+func DecodeMessageJob(body []byte) (*MessageJob, error) {
+	job := &MessageJob{}
+	if err := json.Unmarshal(body, job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message job: %w", err)
+	}
+	if job.Version == 0 {
+		job.Version = JobVersion1
+	}
+	return job, nil
+}
+
+// DecodeDelivery parses a message job from a delivery, reading its deadline from the headers
+// A deadline that cannot be read is reported with the job, which is returned without it
+func DecodeDelivery(d amqp.Delivery) (*MessageJob, error) {
+	job, err := DecodeMessageJob(d.Body)
+	if err != nil {
+		return nil, err
+	}
+	job.ProcessBy, err = processByFromHeaders(d.Headers)
+	if err != nil {
+		return job, fmt.Errorf("message %d: %w", job.MessageID, err)
+	}
+	return job, nil
+}
+
+// headers returns the headers a job is published with, nil when it has none
+func (j *MessageJob) headers() amqp.Table {
+	if j.ProcessBy == nil {
+		return nil
+	}
+	return amqp.Table{ProcessByHeader: j.ProcessBy.UTC().Format(time.RFC3339Nano)}
+}
+
+// processByFromHeaders reads the deadline in ProcessByHeader, nil when there is none
+func processByFromHeaders(headers amqp.Table) (*time.Time, error) {
+	value, ok := headers[ProcessByHeader]
+	if !ok {
+		return nil, nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s header: %v", ProcessByHeader, value)
+	}
+	processBy, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header: %w", ProcessByHeader, err)
+	}
+	return &processBy, nil
+}
@@ -0,0 +1,209 @@
+package queue
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// OrderedQueueName is the queue an ordered campaign's jobs are published to
+func OrderedQueueName(queueName string, campaignID int) string {
+	return fmt.Sprintf("%s.campaign.%d", queueName, campaignID)
+}
+
+// orderedQueueArgs make the broker deliver an ordered queue to one consumer at a time, so
+// every worker can consume it while jobs are still handled in order
+var orderedQueueArgs = amqp.Table{"x-single-active-consumer": true}
+
+// declareOrderedQueue declares an ordered campaign's queue; the publisher and consumers
+// must declare it with the same arguments or the broker refuses the declare
+func declareOrderedQueue(ch *amqp.Channel, name string) error {
+	_, err := ch.QueueDeclare(
+		name,
+		true,  // durable
+		false, // auto-delete
+		false, // exclusive
+		false, // no-wait
+		orderedQueueArgs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare ordered queue %s: %w", name, err)
+	}
+	return nil
+}
+
+// NewOrderedConsumer creates a consumer of an ordered campaign's queue
+// With a prefetch of one and a single active consumer across workers, jobs are handled one
+// at a time in the order they were published
+func NewOrderedConsumer(conn *Connection, queueName string, campaignID int, handler MessageHandler) (*Consumer, error) {
+	return newConsumer(conn, OrderedQueueName(queueName, campaignID), orderedQueueArgs, handler)
+}
+
+// OrderedConsumers runs a consumer for each ordered campaign's queue alongside the main
+// consumer, starting and stopping them as Sync is given the campaigns with work left
+type OrderedConsumers struct {
+	conn      *Connection
+	queueName string
+	handler   MessageHandler
+	watchdog  *Watchdog
+	inFlight  *InFlight
+
+	mu        sync.Mutex
+	consumers map[int]*Consumer
+	paused    bool
+}
+
+// NewOrderedConsumers creates a group of ordered campaign consumers on queueName's ordered queues
+func NewOrderedConsumers(conn *Connection, queueName string, handler MessageHandler) *OrderedConsumers {
+	return &OrderedConsumers{
+		conn:      conn,
+		queueName: queueName,
+		handler:   handler,
+		consumers: make(map[int]*Consumer),
+	}
+}
+
+// SetWatchdog sets the ack deadline enforced on the consumers started after it is called
+func (g *OrderedConsumers) SetWatchdog(watchdog *Watchdog) {
+	g.watchdog = watchdog
+}
+
+// SetInFlight counts the deliveries of the consumers started after it is called in inFlight
+func (g *OrderedConsumers) SetInFlight(inFlight *InFlight) {
+	g.inFlight = inFlight
+}
+
+// Has reports whether the campaign's ordered queue is being consumed
+func (g *OrderedConsumers) Has(campaignID int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.consumers[campaignID]
+	return ok
+}
+
+// CampaignIDs returns the campaigns whose ordered queues are being consumed, in ID order
+func (g *OrderedConsumers) CampaignIDs() []int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ids := make([]int, 0, len(g.consumers))
+	for id := range g.consumers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Sync consumes the ordered queues of exactly campaignIDs: queues of campaigns new to the list
+// are declared and consumed, and those of campaigns no longer listed, which have completed,
+// stop being consumed and are deleted once empty
+// Every campaign is synced even when one fails; the first error is returned
+func (g *OrderedConsumers) Sync(campaignIDs []int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var firstErr error
+	keep := make(map[int]bool, len(campaignIDs))
+	for _, id := range campaignIDs {
+		keep[id] = true
+		if _, ok := g.consumers[id]; ok {
+			continue
+		}
+		if err := g.start(id); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for id, consumer := range g.consumers {
+		if keep[id] {
+			continue
+		}
+		if err := g.finish(id, consumer); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// start consumes a campaign's ordered queue, paused when the group is
+// Callers must hold g.mu
+func (g *OrderedConsumers) start(campaignID int) error {
+	consumer, err := NewOrderedConsumer(g.conn, g.queueName, campaignID, g.handler)
+	if err != nil {
+		return fmt.Errorf("campaign %d: %w", campaignID, err)
+	}
+	consumer.SetWatchdog(g.watchdog)
+	consumer.SetInFlight(g.inFlight)
+	if g.paused {
+		consumer.Pause()
+	}
+	if err := consumer.Start(); err != nil {
+		return fmt.Errorf("campaign %d: %w", campaignID, err)
+	}
+
+	g.consumers[campaignID] = consumer
+	log.Printf("Consuming ordered queue of campaign %d", campaignID)
+	return nil
+}
+
+// finish stops consuming a completed campaign's ordered queue and deletes it
+// A queue still holding jobs is kept; the broker refuses to delete it
+// Callers must hold g.mu
+func (g *OrderedConsumers) finish(campaignID int, consumer *Consumer) error {
+	delete(g.consumers, campaignID)
+	if err := consumer.Pause(); err != nil {
+		log.Printf("Warning: Failed to cancel ordered consumer of campaign %d: %v", campaignID, err)
+	}
+	consumer.Stop()
+
+	if err := g.conn.DeleteQueue(OrderedQueueName(g.queueName, campaignID)); err != nil {
+		return fmt.Errorf("campaign %d: %w", campaignID, err)
+	}
+	log.Printf("Deleted ordered queue of completed campaign %d", campaignID)
+	return nil
+}
+
+// Pause stops every ordered consumer pulling messages, including those started later
+func (g *OrderedConsumers) Pause() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.paused = true
+	var firstErr error
+	for _, consumer := range g.consumers {
+		if err := consumer.Pause(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Resume starts every ordered consumer pulling messages again after Pause
+func (g *OrderedConsumers) Resume() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.paused = false
+	var firstErr error
+	for _, consumer := range g.consumers {
+		if err := consumer.Resume(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Stop stops every ordered consumer, leaving their queues for the next worker to consume
+func (g *OrderedConsumers) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for id, consumer := range g.consumers {
+		consumer.Stop()
+		delete(g.consumers, id)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
@@ -12,13 +13,9 @@ import (
 type Publisher struct {
 	conn      *Connection
 	queueName string
-}
 
-// MessageJob represents a message job to be processed
-type MessageJob struct {
-	MessageID  int `json:"message_id"`
-	CampaignID int `json:"campaign_id"`
-	CustomerID int `json:"customer_id"`
+	mu      sync.Mutex
+	ordered map[string]bool // Ordered queues declared so far
 }
 
 // NewPublisher creates a new publisher instance
@@ -56,18 +53,57 @@ func NewPublisher(conn *Connection, queueName string) (*Publisher, error) {
 	return &Publisher{
 		conn:      conn,
 		queueName: queueName,
+		ordered:   make(map[string]bool),
 	}, nil
 }
 
+// Depth returns how many jobs are waiting in the publisher's queue
+func (p *Publisher) Depth() (int, error) {
+	return p.conn.QueueDepth(p.queueName)
+}
+
 // PublishMessage publishes a message job to the queue
 func (p *Publisher) PublishMessage(messageID, campaignID, customerID int) error {
-	// Create MessageJob struct with provided IDs
-	job := MessageJob{
-		MessageID:  messageID,
-		CampaignID: campaignID,
-		CustomerID: customerID,
+	return p.PublishJob(NewMessageJob(messageID, campaignID, customerID))
+}
+
+// PublishOrderedMessage publishes a message job to its campaign's ordered queue, declaring
+// the queue the first time
+func (p *Publisher) PublishOrderedMessage(messageID, campaignID, customerID int) error {
+	return p.PublishOrderedJob(NewMessageJob(messageID, campaignID, customerID))
+}
+
+// PublishJob publishes a job, with its deadline if it has one, to the queue
+func (p *Publisher) PublishJob(job MessageJob) error {
+	return p.publish(p.queueName, job)
+}
+
+// PublishOrderedJob publishes a job to its campaign's ordered queue, declaring the queue the
+// first time
+func (p *Publisher) PublishOrderedJob(job MessageJob) error {
+	queueName := OrderedQueueName(p.queueName, job.CampaignID)
+
+	p.mu.Lock()
+	declared := p.ordered[queueName]
+	p.mu.Unlock()
+	if !declared {
+		ch, err := p.conn.Channel()
+		if err != nil {
+			return fmt.Errorf("failed to get channel: %w", err)
+		}
+		if err := declareOrderedQueue(ch, queueName); err != nil {
+			return err
+		}
+		p.mu.Lock()
+		p.ordered[queueName] = true
+		p.mu.Unlock()
 	}
 
+	return p.publish(queueName, job)
+}
+
+// publish publishes a message job to the named queue
+func (p *Publisher) publish(queueName string, job MessageJob) error {
 	// Marshal to JSON
 	body, err := json.Marshal(job)
 	if err != nil {
@@ -82,13 +118,14 @@ func (p *Publisher) PublishMessage(messageID, campaignID, customerID int) error
 
 	// Publish message
 	err = ch.Publish(
-		"",          // exchange (default)
-		p.queueName, // routing key
-		false,       // mandatory
-		false,       // immediate
+		"",        // exchange (default)
+		queueName, // routing key
+		false,     // mandatory
+		false,     // immediate
 		amqp.Publishing{
 			DeliveryMode: amqp.Persistent, // 2 - persistent
 			ContentType:  "application/json",
+			Headers:      job.headers(),
 			Body:         body,
 		},
 	)
@@ -0,0 +1,126 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Pausable is a consumer, or group of consumers, that can stop pulling messages
+type Pausable interface {
+	Pause() error
+}
+
+// DrainSummary is how a shutdown drain went
+type DrainSummary struct {
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// InFlight is how many deliveries were being handled when the drain started
+	InFlight int `json:"in_flight"`
+	// Completed is how many deliveries their handlers finished during the drain
+	Completed int `json:"completed"`
+	// Requeued is how many deliveries were still being handled at the timeout and were
+	// given back to the broker for redelivery
+	Requeued int  `json:"requeued"`
+	TimedOut bool `json:"timed_out"`
+}
+
+// ShutdownDrain empties a worker ahead of a deploy: it stops its consumers taking new
+// deliveries, leaving their channels open so the deliveries being handled can still be
+// acknowledged, and waits for those to finish. Deliveries still being handled after the
+// timeout are requeued for another worker. Once started the worker is no longer ready, and
+// a drain cannot be undone; the worker exits when it is done
+type ShutdownDrain struct {
+	inFlight  *InFlight
+	timeout   time.Duration
+	consumers []Pausable
+	done      chan struct{}
+
+	mu      sync.Mutex
+	summary *DrainSummary
+}
+
+// NewShutdownDrain creates a drain of the consumers, waiting up to timeout for the
+// deliveries counted by inFlight
+func NewShutdownDrain(inFlight *InFlight, timeout time.Duration, consumers ...Pausable) *ShutdownDrain {
+	return &ShutdownDrain{
+		inFlight:  inFlight,
+		timeout:   timeout,
+		consumers: consumers,
+		done:      make(chan struct{}),
+	}
+}
+
+// Start begins draining in the background; it returns false if a drain has already started
+func (d *ShutdownDrain) Start() bool {
+	d.mu.Lock()
+	if d.summary != nil {
+		d.mu.Unlock()
+		return false
+	}
+	// The completed baseline is taken with the in-flight count, so a delivery finishing
+	// before run starts is still counted as completed during the drain
+	completedBefore := d.inFlight.Completed()
+	d.summary = &DrainSummary{StartedAt: time.Now(), InFlight: d.inFlight.Count()}
+	d.mu.Unlock()
+
+	go d.run(completedBefore)
+	return true
+}
+
+// run stops the consumers, waits for the deliveries in flight and records the outcome,
+// counting as completed the deliveries finished since completedBefore
+func (d *ShutdownDrain) run(completedBefore int) {
+	defer close(d.done)
+
+	for _, consumer := range d.consumers {
+		if err := consumer.Pause(); err != nil {
+			log.Printf("Warning: Failed to stop consumer for drain: %v", err)
+		}
+	}
+	log.Printf("🚰 Draining: consumption stopped, waiting up to %s for %d in-flight deliveries", d.timeout, d.inFlight.Count())
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	emptied := d.inFlight.Wait(ctx)
+	cancel()
+	requeued := 0
+	if !emptied {
+		requeued = d.inFlight.Abandon()
+	}
+
+	finishedAt := time.Now()
+	d.mu.Lock()
+	d.summary.FinishedAt = &finishedAt
+	d.summary.Completed = d.inFlight.Completed() - completedBefore
+	d.summary.Requeued = requeued
+	d.summary.TimedOut = !emptied
+	summary := *d.summary
+	d.mu.Unlock()
+
+	log.Printf("🚰 Drained in %s: %d in flight at start, %d completed, %d requeued (timed out: %t)",
+		finishedAt.Sub(summary.StartedAt).Round(time.Millisecond), summary.InFlight, summary.Completed, summary.Requeued, summary.TimedOut)
+}
+
+// Draining reports whether a drain has started, finished or not
+func (d *ShutdownDrain) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.summary != nil
+}
+
+// Done is closed once the drain has finished
+func (d *ShutdownDrain) Done() <-chan struct{} {
+	return d.done
+}
+
+// Summary returns the drain's progress so far, or nil before it starts
+func (d *ShutdownDrain) Summary() *DrainSummary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.summary == nil {
+		return nil
+	}
+	summary := *d.summary
+	return &summary
+}
@@ -0,0 +1,94 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrAckDeadlineExceeded is given to the expiry hook when a handler overruns its ack deadline
+var ErrAckDeadlineExceeded = errors.New("ack deadline exceeded")
+
+// Watchdog resolves every delivery exactly once: acked or requeued by its handler, or
+// requeued when the handler has not returned by the deadline. Without it a stuck handler
+// holds its delivery forever under manual ack and the worker silently stops consuming
+type Watchdog struct {
+	deadline time.Duration
+	onExpire func(job *MessageJob, err error)
+}
+
+// NewWatchdog creates a watchdog that requeues deliveries whose handler runs past deadline
+// and then calls onExpire (which may be nil); a zero deadline waits for handlers forever
+func NewWatchdog(deadline time.Duration, onExpire func(job *MessageJob, err error)) *Watchdog {
+	return &Watchdog{
+		deadline: deadline,
+		onExpire: onExpire,
+	}
+}
+
+// Dispatch runs handle for d and acks the delivery on success or requeues it on error
+// If handle overruns the deadline the delivery is requeued and Dispatch returns true while
+// handle keeps running; whatever it returns later is discarded
+func (w *Watchdog) Dispatch(d amqp.Delivery, handle func(d amqp.Delivery) error) bool {
+	if w == nil || w.deadline <= 0 {
+		resolve(d, handle(d))
+		return false
+	}
+
+	// Whichever of the handler and the timer claims the delivery first acks or nacks it
+	var resolved atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := handle(d)
+		if !resolved.CompareAndSwap(false, true) {
+			log.Printf("Handler for delivery %d returned after its ack deadline; result discarded (err: %v)", d.DeliveryTag, err)
+			return
+		}
+		resolve(d, err)
+	}()
+
+	timer := time.NewTimer(w.deadline)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return false
+	case <-timer.C:
+		if !resolved.CompareAndSwap(false, true) {
+			// The handler returned just as the timer fired and is resolving the delivery
+			<-done
+			return false
+		}
+	}
+
+	err := fmt.Errorf("%w: handler still running after %s", ErrAckDeadlineExceeded, w.deadline)
+	log.Printf("⏰ Delivery %d: %v; requeueing", d.DeliveryTag, err)
+	d.Nack(false, true)
+
+	if w.onExpire != nil {
+		job, decodeErr := DecodeMessageJob(d.Body)
+		if decodeErr != nil {
+			job = &MessageJob{}
+		}
+		w.onExpire(job, err)
+	}
+	return true
+}
+
+// resolve acknowledges a handled delivery, or requeues it on error
+func resolve(d amqp.Delivery, err error) {
+	if err != nil {
+		log.Printf("Error processing message: %v", err)
+		// Requeue for retry
+		d.Nack(false, true)
+		return
+	}
+
+	// Acknowledge successful processing
+	d.Ack(false)
+}
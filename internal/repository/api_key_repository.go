@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"smsleopard/internal/models"
+)
+
+type apiKeyRepository struct {
+	db DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// apiKeyColumns are the columns scanned by scanAPIKey, in order
+const apiKeyColumns = "id, key_hash, label, user_id, role, team, created_at, revoked_at, last_used_at"
+
+// scanAPIKey scans a row of apiKeyColumns
+func scanAPIKey(scan func(dest ...interface{}) error) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := scan(&key.ID, &key.KeyHash, &key.Label, &key.UserID, &key.Role, &key.Team, &key.CreatedAt, &key.RevokedAt, &key.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Create stores a new key by its hash
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (key_hash, label, user_id, role, team)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, key.KeyHash, key.Label, key.UserID, key.Role, key.Team).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves every issued key, revoked ones included, newest first
+func (r *apiKeyRepository) List(ctx context.Context) ([]*models.APIKey, error) {
+	return r.list(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC, id DESC`)
+}
+
+// ListActive retrieves the keys that have not been revoked
+func (r *apiKeyRepository) ListActive(ctx context.Context) ([]*models.APIKey, error) {
+	return r.list(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE revoked_at IS NULL ORDER BY id`)
+}
+
+func (r *apiKeyRepository) list(ctx context.Context, query string) ([]*models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke revokes a key and returns it; revoking a revoked key keeps its original revoked_at
+// Returns ErrAPIKeyNotFound when no key has the ID
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int) (*models.APIKey, error) {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+		WHERE id = $1
+		RETURNING ` + apiKeyColumns
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// MarkUsed records that the keys were used at usedAt, leaving any later use recorded in place
+func (r *apiKeyRepository) MarkUsed(ctx context.Context, ids []int, usedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE api_keys
+		SET last_used_at = $2
+		WHERE id = ANY($1) AND (last_used_at IS NULL OR last_used_at < $2)
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, pq.Array(ids), usedAt.UTC()); err != nil {
+		return fmt.Errorf("failed to record api key use: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"smsleopard/internal/models"
+)
+
+// CampaignEventsChannel is the NOTIFY channel the campaign_events trigger signals with
+// the campaign ID of each event written
+const CampaignEventsChannel = "campaign_events"
+
+type campaignEventRepository struct {
+	db DB
+}
+
+// NewCampaignEventRepository creates a new campaign event repository
+func NewCampaignEventRepository(db DB) CampaignEventRepository {
+	return &campaignEventRepository{db: db}
+}
+
+// Create writes an event and reports whether it was written; an event whose dedupe key
+// the campaign already has is skipped
+func (r *campaignEventRepository) Create(ctx context.Context, event *models.CampaignEvent) (bool, error) {
+	query := `
+		INSERT INTO campaign_events (campaign_id, type, payload, dedupe_key)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (campaign_id, dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING
+		RETURNING id, created_at
+	`
+
+	payload := event.Payload
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+	err := r.db.QueryRowContext(ctx, query, event.CampaignID, event.Type, []byte(payload), event.DedupeKey).
+		Scan(&event.ID, &event.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create campaign event: %w", err)
+	}
+
+	return true, nil
+}
+
+// ListSince retrieves up to limit of a campaign's send events after sinceID, oldest first
+// History events are left out; consumers only see the send events
+func (r *campaignEventRepository) ListSince(ctx context.Context, campaignID int, sinceID int64, limit int) ([]*models.CampaignEvent, error) {
+	query := `
+		SELECT id, campaign_id, type, payload, dedupe_key, created_at
+		FROM campaign_events
+		WHERE campaign_id = $1 AND id > $2 AND type IN ('queued', 'progress', 'completed')
+		ORDER BY id
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, campaignID, sinceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.CampaignEvent{}
+	for rows.Next() {
+		event := &models.CampaignEvent{}
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.CampaignID, &event.Type, &payload, &event.DedupeKey, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign event: %w", err)
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating campaign events: %w", err)
+	}
+
+	return events, nil
+}
+
+// ListReplay retrieves up to limit of all a campaign's events after afterID, oldest first,
+// for replaying its history
+func (r *campaignEventRepository) ListReplay(ctx context.Context, campaignID int, afterID int64, limit int) ([]*models.CampaignEvent, error) {
+	query := `
+		SELECT id, campaign_id, type, payload, message_id, version, created_at
+		FROM campaign_events
+		WHERE campaign_id = $1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, campaignID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign events for replay: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.CampaignEvent{}
+	for rows.Next() {
+		event := &models.CampaignEvent{}
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.CampaignID, &event.Type, &payload, &event.MessageID, &event.Version, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign event: %w", err)
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating campaign events: %w", err)
+	}
+
+	return events, nil
+}
+
+// ListSendingProgress counts the messages of every sending campaign by outcome
+// A failed message with retries left is outstanding, as the worker will try it again
+func (r *campaignEventRepository) ListSendingProgress(ctx context.Context) ([]*models.CampaignProgress, error) {
+	query := `
+		SELECT
+			m.campaign_id,
+			COUNT(*) FILTER (WHERE m.status = 'sent'),
+			COUNT(*) FILTER (WHERE m.status = 'failed' AND m.retry_count >= 3),
+			COUNT(*) FILTER (WHERE m.status = 'pending' OR (m.status = 'failed' AND m.retry_count < 3))
+		FROM outbound_messages m
+		JOIN campaigns c ON c.id = m.campaign_id
+		WHERE c.status = 'sending'
+		GROUP BY m.campaign_id
+		ORDER BY m.campaign_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign progress: %w", err)
+	}
+	defer rows.Close()
+
+	progress := []*models.CampaignProgress{}
+	for rows.Next() {
+		p := &models.CampaignProgress{}
+		if err := rows.Scan(&p.CampaignID, &p.Sent, &p.Failed, &p.Outstanding); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign progress: %w", err)
+		}
+		progress = append(progress, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating campaign progress: %w", err)
+	}
+
+	return progress, nil
+}
+
+// RecordSendMetrics writes how long a campaign's send took, from its sent and failed messages,
+// replacing what an earlier completion recorded; ratePerSecond gives the estimate (0 for none)
+// It writes nothing for a campaign without sent or failed messages, or whose recorded
+// metrics are still current
+func (r *campaignEventRepository) RecordSendMetrics(ctx context.Context, campaignID int, ratePerSecond float64) error {
+	query := `
+		INSERT INTO campaign_send_metrics (
+			campaign_id, started_at, completed_at, duration_seconds, messages,
+			throughput_per_minute, estimated_duration_seconds
+		)
+		SELECT
+			span.campaign_id, span.started_at, span.completed_at, ROUND(span.duration, 2), span.messages,
+			CASE WHEN span.duration > 0 THEN ROUND(span.messages / span.duration * 60, 2) END,
+			CASE WHEN $2::numeric > 0 THEN ROUND(span.messages / $2::numeric, 2) END
+		FROM (
+			SELECT
+				m.campaign_id,
+				MIN(COALESCE(m.published_at, m.created_at)) as started_at,
+				MAX(m.updated_at) as completed_at,
+				EXTRACT(EPOCH FROM MAX(m.updated_at) - MIN(COALESCE(m.published_at, m.created_at)))::numeric as duration,
+				COUNT(*) as messages
+			FROM outbound_messages m
+			WHERE m.campaign_id = $1 AND m.status IN ('sent', 'failed')
+			GROUP BY m.campaign_id
+		) span
+		ON CONFLICT (campaign_id) DO UPDATE SET
+			started_at = EXCLUDED.started_at,
+			completed_at = EXCLUDED.completed_at,
+			duration_seconds = EXCLUDED.duration_seconds,
+			messages = EXCLUDED.messages,
+			throughput_per_minute = EXCLUDED.throughput_per_minute,
+			estimated_duration_seconds = EXCLUDED.estimated_duration_seconds,
+			recorded_at = CURRENT_TIMESTAMP
+		WHERE campaign_send_metrics.messages <> EXCLUDED.messages
+			OR campaign_send_metrics.completed_at <> EXCLUDED.completed_at
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, campaignID, ratePerSecond); err != nil {
+		return fmt.Errorf("failed to record send metrics: %w", err)
+	}
+
+	return nil
+}
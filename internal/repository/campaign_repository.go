@@ -3,29 +3,55 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"smsleopard/internal/models"
+
+	"github.com/lib/pq"
 )
 
 type campaignRepository struct {
-	db *sql.DB
+	db       Database
+	readerDB Database // Read replica for reporting queries (nil uses db)
 }
 
 // NewCampaignRepository creates a new campaign repository
-func NewCampaignRepository(db *sql.DB) CampaignRepository {
+func NewCampaignRepository(db Database) CampaignRepository {
 	return &campaignRepository{db: db}
 }
 
+// NewCampaignRepositoryWithReader creates a campaign repository that sends
+// reporting reads to a read replica
+// A nil reader falls back to the primary
+func NewCampaignRepositoryWithReader(db, readerDB Database) CampaignRepository {
+	return &campaignRepository{db: db, readerDB: readerDB}
+}
+
+// reader returns the database for replica-safe reads
+func (r *campaignRepository) reader() Database {
+	if r.readerDB != nil {
+		return r.readerDB
+	}
+	return r.db
+}
+
 // Create creates a new campaign
 func (r *campaignRepository) Create(ctx context.Context, campaign *models.Campaign) error {
 	query := `
-		INSERT INTO campaigns (name, channel, status, base_template, scheduled_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO campaigns (name, channel, status, base_template, scheduled_at, tags, created_by, team, budget, frequency_cap_exempt, track_links, template_syntax, demo_failure_rate, ordered, part_delimiter)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 		RETURNING id, created_at, updated_at
 	`
 
+	// Untagged campaigns store an empty array rather than NULL
+	if campaign.Tags == nil {
+		campaign.Tags = []string{}
+	}
+
 	err := r.db.QueryRowContext(
 		ctx,
 		query,
@@ -34,6 +60,16 @@ func (r *campaignRepository) Create(ctx context.Context, campaign *models.Campai
 		campaign.Status,
 		campaign.BaseTemplate,
 		campaign.ScheduledAt,
+		pq.Array(campaign.Tags),
+		campaign.CreatedBy,
+		campaign.Team,
+		campaign.Budget,
+		campaign.FrequencyCapExempt,
+		campaign.TrackLinks,
+		campaign.TemplateSyntax,
+		campaign.DemoFailureRate,
+		campaign.Ordered,
+		campaign.PartDelimiter,
 	).Scan(&campaign.ID, &campaign.CreatedAt, &campaign.UpdatedAt)
 
 	if err != nil {
@@ -44,15 +80,37 @@ func (r *campaignRepository) Create(ctx context.Context, campaign *models.Campai
 }
 
 // GetByID retrieves a campaign by ID
+// Reads the primary since callers act on the status right away
 func (r *campaignRepository) GetByID(ctx context.Context, id int) (*models.Campaign, error) {
+	return r.getByID(ctx, r.db, id)
+}
+
+// getByID retrieves a campaign by ID, with its budget, spend, frequency cap exemption, link tracking,
+// template syntax, cancellation deadline, demo failure rate, ordering and part delimiter, from the given database
+func (r *campaignRepository) getByID(ctx context.Context, db DB, id int) (*models.Campaign, error) {
 	query := `
-		SELECT id, name, channel, status, base_template, scheduled_at, created_at, updated_at
+		SELECT id, name, channel, status, base_template, scheduled_at, created_at, updated_at, tags, created_by, team,
+			budget, spend, paused_reason, frequency_cap_exempt, track_links, template_syntax, cancel_at, demo_failure_rate, ordered, part_delimiter
 		FROM campaigns
 		WHERE id = $1
 	`
 
 	campaign := &models.Campaign{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := db.QueryRowContext(ctx, query, id).Scan(campaignFields(campaign)...)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("campaign not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	return campaign, nil
+}
+
+// campaignFields returns the scan destinations of the columns getByID selects, in order
+func campaignFields(campaign *models.Campaign) []interface{} {
+	return []interface{}{
 		&campaign.ID,
 		&campaign.Name,
 		&campaign.Channel,
@@ -61,59 +119,333 @@ func (r *campaignRepository) GetByID(ctx context.Context, id int) (*models.Campa
 		&campaign.ScheduledAt,
 		&campaign.CreatedAt,
 		&campaign.UpdatedAt,
+		pq.Array(&campaign.Tags),
+		&campaign.CreatedBy,
+		&campaign.Team,
+		&campaign.Budget,
+		&campaign.Spend,
+		&campaign.PausedReason,
+		&campaign.FrequencyCapExempt,
+		&campaign.TrackLinks,
+		&campaign.TemplateSyntax,
+		&campaign.CancelAt,
+		&campaign.DemoFailureRate,
+		&campaign.Ordered,
+		&campaign.PartDelimiter,
+	}
+}
+
+// GetWithStats retrieves a campaign with statistics in one query
+// Aggregates without GROUP BY always return a row, so a campaign without messages gets zero counts
+// Reads the replica; stats may lag the primary slightly
+func (r *campaignRepository) GetWithStats(ctx context.Context, id int) (*models.CampaignWithStats, error) {
+	query := `
+		SELECT c.id, c.name, c.channel, c.status, c.base_template, c.scheduled_at, c.created_at, c.updated_at, c.tags, c.created_by, c.team,
+			c.budget, c.spend, c.paused_reason, c.frequency_cap_exempt, c.track_links, c.template_syntax, c.cancel_at, c.demo_failure_rate, c.ordered, c.part_delimiter,
+			s.total_messages, s.pending, s.sent, s.failed, s.queued, s.unpublished, s.simulated, s.p95_queue_latency,
+			(SELECT COUNT(*) FROM link_clicks WHERE campaign_id = c.id) as clicks,
+			d.retry_distribution,
+			sm.started_at, sm.completed_at, sm.duration_seconds, sm.messages, sm.throughput_per_minute, sm.estimated_duration_seconds,
+			s.canary_messages, s.canary_pending, s.canary_sent, s.canary_failed,
+			CASE WHEN c.status = 'canary' THEN (c.send_plan->>'audience_size')::int END as canary_held_back,
+			s.skipped_duplicate_content,
+			g.groups, g.groups_complete, g.groups_partial,
+			rs.resent, rs.resent_pending, rs.resent_sent, rs.resent_failed,
+			s.expired
+		FROM campaigns c
+		LEFT JOIN LATERAL (
+			SELECT
+				COUNT(*) as total_messages,
+				COUNT(*) FILTER (WHERE m.status = 'pending') as pending,
+				COUNT(*) FILTER (WHERE m.status = 'sent') as sent,
+				COUNT(*) FILTER (WHERE m.status = 'failed') as failed,
+				COUNT(*) FILTER (WHERE m.status = 'pending' AND m.deliver_after IS NULL AND m.published_at IS NOT NULL) as queued,
+				COUNT(*) FILTER (WHERE m.status = 'pending' AND m.deliver_after IS NULL AND m.published_at IS NULL) as unpublished,
+				COUNT(*) FILTER (WHERE m.status = 'sent' AND m.simulated) as simulated,
+				PERCENTILE_CONT(0.95) WITHIN GROUP (
+					ORDER BY EXTRACT(EPOCH FROM (m.updated_at - m.published_at))
+				) FILTER (WHERE m.status = 'sent' AND m.published_at IS NOT NULL) as p95_queue_latency,
+				COUNT(*) FILTER (WHERE m.canary) as canary_messages,
+				COUNT(*) FILTER (WHERE m.canary AND m.status = 'pending') as canary_pending,
+				COUNT(*) FILTER (WHERE m.canary AND m.status = 'sent') as canary_sent,
+				COUNT(*) FILTER (WHERE m.canary AND m.status = 'failed') as canary_failed,
+				COUNT(*) FILTER (WHERE m.status = 'skipped_duplicate_content') as skipped_duplicate_content,
+				COUNT(*) FILTER (WHERE m.status = 'expired') as expired
+			FROM outbound_messages m
+			WHERE m.campaign_id = c.id AND m.resent_from_message_id IS NULL
+		) s ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT json_agg(json_build_array(retries.retry_count, retries.sent, retries.failed) ORDER BY retries.retry_count) as retry_distribution
+			FROM (
+				SELECT
+					COALESCE(m.retry_count, 0) as retry_count,
+					COUNT(*) FILTER (WHERE m.status = 'sent') as sent,
+					COUNT(*) FILTER (WHERE m.status = 'failed') as failed
+				FROM outbound_messages m
+				WHERE m.campaign_id = c.id AND m.status IN ('sent', 'failed') AND m.resent_from_message_id IS NULL
+				GROUP BY 1
+			) retries
+		) d ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT
+				COUNT(*) as groups,
+				COUNT(*) FILTER (WHERE parts.sent = parts.part_count) as groups_complete,
+				COUNT(*) FILTER (WHERE parts.sent > 0 AND parts.sent < parts.part_count) as groups_partial
+			FROM (
+				SELECT MAX(m.part_count) as part_count, COUNT(*) FILTER (WHERE m.status = 'sent') as sent
+				FROM outbound_messages m
+				WHERE m.campaign_id = c.id AND m.group_id IS NOT NULL
+				GROUP BY m.group_id
+			) parts
+		) g ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT
+				COUNT(*) as resent,
+				COUNT(*) FILTER (WHERE m.status = 'pending') as resent_pending,
+				COUNT(*) FILTER (WHERE m.status = 'sent') as resent_sent,
+				COUNT(*) FILTER (WHERE m.status = 'failed') as resent_failed
+			FROM outbound_messages m
+			WHERE m.campaign_id = c.id AND m.resent_from_message_id IS NOT NULL
+		) rs ON TRUE
+		LEFT JOIN campaign_send_metrics sm ON sm.campaign_id = c.id
+		WHERE c.id = $1
+	`
+
+	campaign := &models.Campaign{}
+	stats := models.CampaignStats{Clicks: new(int)}
+	var distributionJSON []byte
+	var metrics sendMetricsColumns
+	fields := append(campaignFields(campaign),
+		&stats.Total,
+		&stats.Pending,
+		&stats.Sent,
+		&stats.Failed,
+		&stats.Queued,
+		&stats.Unpublished,
+		&stats.Simulated,
+		&stats.P95QueueLatencySeconds,
+		stats.Clicks,
+		&distributionJSON,
 	)
+	fields = append(fields, metrics.fields()...)
+	canary := &models.CanaryStats{}
+	fields = append(fields, &canary.Messages, &canary.Pending, &canary.Sent, &canary.Failed, &canary.HeldBack)
+	fields = append(fields, &stats.SkippedDuplicateContent)
+	groups := &models.GroupStats{}
+	fields = append(fields, &groups.Total, &groups.Complete, &groups.Partial)
+	resent := &models.ResentStats{}
+	fields = append(fields, &resent.Total, &resent.Pending, &resent.Sent, &resent.Failed)
+	fields = append(fields, &stats.Expired)
 
+	err := r.reader().QueryRowContext(ctx, query, id).Scan(fields...)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("campaign not found")
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get campaign: %w", err)
+		return nil, fmt.Errorf("failed to get campaign with stats: %w", err)
 	}
 
-	return campaign, nil
-}
-
-// GetWithStats retrieves a campaign with statistics
-func (r *campaignRepository) GetWithStats(ctx context.Context, id int) (*models.CampaignWithStats, error) {
-	campaign, err := r.GetByID(ctx, id)
+	stats.RetryDistribution, err = parseRetryDistribution(distributionJSON)
 	if err != nil {
 		return nil, err
 	}
 
-	statsQuery := `
+	result := &models.CampaignWithStats{
+		Campaign:        *campaign,
+		Stats:           stats,
+		RemainingBudget: campaign.RemainingBudget(),
+		SendMetrics:     metrics.metrics(),
+	}
+	if canary.Messages > 0 || canary.HeldBack != nil {
+		result.Canary = canary
+	}
+	if groups.Total > 0 {
+		result.Stats.Groups = groups
+	}
+	if resent.Total > 0 {
+		result.Stats.Resent = resent
+	}
+	return result, nil
+}
+
+// sendMetricsColumns scans the campaign_send_metrics columns of a campaign joined to them,
+// all NULL until its send completes
+type sendMetricsColumns struct {
+	startedAt   sql.NullTime
+	completedAt sql.NullTime
+	duration    sql.NullFloat64
+	messages    sql.NullInt64
+	throughput  *float64
+	estimated   *float64
+}
+
+// fields returns scan targets for started_at, completed_at, duration_seconds, messages,
+// throughput_per_minute and estimated_duration_seconds
+func (c *sendMetricsColumns) fields() []interface{} {
+	return []interface{}{&c.startedAt, &c.completedAt, &c.duration, &c.messages, &c.throughput, &c.estimated}
+}
+
+// metrics returns the scanned metrics, or nil when none were recorded
+func (c *sendMetricsColumns) metrics() *models.CampaignSendMetrics {
+	if !c.startedAt.Valid {
+		return nil
+	}
+	return &models.CampaignSendMetrics{
+		StartedAt:                c.startedAt.Time,
+		CompletedAt:              c.completedAt.Time,
+		DurationSeconds:          c.duration.Float64,
+		Messages:                 int(c.messages.Int64),
+		ThroughputPerMinute:      c.throughput,
+		EstimatedDurationSeconds: c.estimated,
+	}
+}
+
+// parseRetryDistribution reads the [retry_count, sent, failed] rows GetWithStats aggregates;
+// NULL means the campaign has no sent or failed messages
+func parseRetryDistribution(distributionJSON []byte) (*models.RetryDistribution, error) {
+	distribution := &models.RetryDistribution{Sent: map[int]int{}, Failed: map[int]int{}}
+	if distributionJSON == nil {
+		return distribution, nil
+	}
+
+	var rows [][3]int
+	if err := json.Unmarshal(distributionJSON, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse retry distribution: %w", err)
+	}
+
+	for _, row := range rows {
+		retryCount, sent, failed := row[0], row[1], row[2]
+		if sent > 0 {
+			distribution.Sent[retryCount] = sent
+		}
+		if failed > 0 {
+			distribution.Failed[retryCount] = failed
+		}
+	}
+
+	return distribution, nil
+}
+
+// GetStatsByIDs retrieves message statistics for several campaigns in one query
+// Campaigns without messages get zero stats; reads the replica
+func (r *campaignRepository) GetStatsByIDs(ctx context.Context, ids []int) (map[int]*models.CampaignStats, error) {
+	result := make(map[int]*models.CampaignStats, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	query := `
 		SELECT
+			campaign_id,
 			COUNT(*) as total,
 			COUNT(*) FILTER (WHERE status = 'pending') as pending,
 			COUNT(*) FILTER (WHERE status = 'sent') as sent,
-			COUNT(*) FILTER (WHERE status = 'failed') as failed
+			COUNT(*) FILTER (WHERE status = 'failed') as failed,
+			COUNT(*) FILTER (WHERE status = 'pending' AND deliver_after IS NULL AND published_at IS NOT NULL) as queued,
+			COUNT(*) FILTER (WHERE status = 'pending' AND deliver_after IS NULL AND published_at IS NULL) as unpublished,
+			COUNT(*) FILTER (WHERE status = 'sent' AND simulated) as simulated,
+			PERCENTILE_CONT(0.95) WITHIN GROUP (
+				ORDER BY EXTRACT(EPOCH FROM (updated_at - published_at))
+			) FILTER (WHERE status = 'sent' AND published_at IS NOT NULL) as p95_queue_latency,
+			COUNT(DISTINCT customer_id) as audience,
+			MIN(created_at) as first_message_at,
+			MAX(updated_at) FILTER (WHERE status IN ('sent', 'failed')) as last_finished_at,
+			COUNT(*) FILTER (WHERE status = 'skipped_duplicate_content') as skipped_duplicate_content,
+			COUNT(*) FILTER (WHERE status = 'expired') as expired
 		FROM outbound_messages
-		WHERE campaign_id = $1
+		WHERE campaign_id = ANY($1) AND resent_from_message_id IS NULL
+		GROUP BY campaign_id
 	`
 
-	stats := models.CampaignStats{}
-	err = r.db.QueryRowContext(ctx, statsQuery, id).Scan(
-		&stats.Total,
-		&stats.Pending,
-		&stats.Sent,
-		&stats.Failed,
-	)
-
-	if err != nil && err != sql.ErrNoRows {
+	rows, err := r.reader().QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
 		return nil, fmt.Errorf("failed to get campaign stats: %w", err)
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var campaignID, audience int
+		stats := &models.CampaignStats{Audience: &audience}
+		err := rows.Scan(
+			&campaignID,
+			&stats.Total,
+			&stats.Pending,
+			&stats.Sent,
+			&stats.Failed,
+			&stats.Queued,
+			&stats.Unpublished,
+			&stats.Simulated,
+			&stats.P95QueueLatencySeconds,
+			&audience,
+			&stats.FirstMessageAt,
+			&stats.LastFinishedAt,
+			&stats.SkippedDuplicateContent,
+			&stats.Expired,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign stats: %w", err)
+		}
+		result[campaignID] = stats
+	}
+
+	for _, id := range ids {
+		if _, ok := result[id]; !ok {
+			audience := 0
+			result[id] = &models.CampaignStats{Audience: &audience}
+		}
+	}
+
+	return result, nil
+}
+
+// GetFailureBreakdownByIDs counts the failed messages of several campaigns by error, most common first
+// Campaigns without failures get an empty breakdown; reads the replica
+func (r *campaignRepository) GetFailureBreakdownByIDs(ctx context.Context, ids []int) (map[int][]*models.ErrorCount, error) {
+	result := make(map[int][]*models.ErrorCount, len(ids))
+	for _, id := range ids {
+		result[id] = []*models.ErrorCount{}
+	}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	query := `
+		SELECT campaign_id, COALESCE(last_error, ''), COUNT(*)
+		FROM outbound_messages
+		WHERE campaign_id = ANY($1) AND status = 'failed'
+		GROUP BY campaign_id, COALESCE(last_error, '')
+		ORDER BY campaign_id, COUNT(*) DESC, COALESCE(last_error, '')
+	`
+
+	rows, err := r.reader().QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failure breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var campaignID int
+		errorCount := &models.ErrorCount{}
+		if err := rows.Scan(&campaignID, &errorCount.Error, &errorCount.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan failure breakdown: %w", err)
+		}
+		result[campaignID] = append(result[campaignID], errorCount)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating failure breakdown: %w", err)
+	}
 
-	return &models.CampaignWithStats{
-		Campaign: *campaign,
-		Stats:    stats,
-	}, nil
+	return result, nil
 }
 
 // List retrieves campaigns with filters and pagination
+// Reads the replica
 func (r *campaignRepository) List(ctx context.Context, filters CampaignFilters) ([]*models.Campaign, int, error) {
 	// Build query with filters
 	queryBuilder := strings.Builder{}
 	queryBuilder.WriteString(`
-		SELECT id, name, channel, status, base_template, scheduled_at, created_at, updated_at
+		SELECT id, name, channel, status, base_template, scheduled_at, created_at, updated_at, tags, created_by, team
 		FROM campaigns
 		WHERE 1=1
 	`)
@@ -133,6 +465,12 @@ func (r *campaignRepository) List(ctx context.Context, filters CampaignFilters)
 		argPos++
 	}
 
+	if len(filters.Tags) > 0 {
+		queryBuilder.WriteString(fmt.Sprintf(" AND tags @> $%d", argPos))
+		args = append(args, pq.Array(filters.Tags))
+		argPos++
+	}
+
 	// Order by ID DESC for stable pagination
 	queryBuilder.WriteString(" ORDER BY id DESC")
 
@@ -154,7 +492,7 @@ func (r *campaignRepository) List(ctx context.Context, filters CampaignFilters)
 	args = append(args, limit, offset)
 
 	// Execute query
-	rows, err := r.db.QueryContext(ctx, queryBuilder.String(), args...)
+	rows, err := r.reader().QueryContext(ctx, queryBuilder.String(), args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list campaigns: %w", err)
 	}
@@ -172,6 +510,9 @@ func (r *campaignRepository) List(ctx context.Context, filters CampaignFilters)
 			&campaign.ScheduledAt,
 			&campaign.CreatedAt,
 			&campaign.UpdatedAt,
+			pq.Array(&campaign.Tags),
+			&campaign.CreatedBy,
+			&campaign.Team,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan campaign: %w", err)
@@ -194,8 +535,14 @@ func (r *campaignRepository) List(ctx context.Context, filters CampaignFilters)
 		countArgs = append(countArgs, *filters.Status)
 	}
 
+	if len(filters.Tags) > 0 {
+		pos := len(countArgs) + 1
+		countQuery += fmt.Sprintf(" AND tags @> $%d", pos)
+		countArgs = append(countArgs, pq.Array(filters.Tags))
+	}
+
 	var totalCount int
-	err = r.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&totalCount)
+	err = r.reader().QueryRowContext(ctx, countQuery, countArgs...).Scan(&totalCount)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get total count: %w", err)
 	}
@@ -203,15 +550,122 @@ func (r *campaignRepository) List(ctx context.Context, filters CampaignFilters)
 	return campaigns, totalCount, nil
 }
 
-// UpdateStatus updates campaign status
-func (r *campaignRepository) UpdateStatus(ctx context.Context, id int, status models.CampaignStatus) error {
+// StreamWithStats calls fn for every campaign created in the filter window, oldest first,
+// with its message stats embedded; rows are read one at a time so large exports stay flat in memory
+// Stops at the first error from fn; reads the replica
+func (r *campaignRepository) StreamWithStats(ctx context.Context, filters CampaignExportFilters, fn func(row *models.CampaignExportRow) error) error {
+	queryBuilder := strings.Builder{}
+	queryBuilder.WriteString(`
+		SELECT
+			c.id, c.name, c.channel, c.status, c.base_template, c.scheduled_at, c.created_at, c.updated_at, c.tags, c.created_by, c.team,
+			COUNT(m.id) as total,
+			COUNT(m.id) FILTER (WHERE m.status = 'pending') as pending,
+			COUNT(m.id) FILTER (WHERE m.status = 'sent') as sent,
+			COUNT(m.id) FILTER (WHERE m.status = 'failed') as failed,
+			COUNT(m.id) FILTER (WHERE m.status = 'pending' AND m.deliver_after IS NULL AND m.published_at IS NOT NULL) as queued,
+			COUNT(m.id) FILTER (WHERE m.status = 'pending' AND m.deliver_after IS NULL AND m.published_at IS NULL) as unpublished,
+			COUNT(m.id) FILTER (WHERE m.status = 'sent' AND m.simulated) as simulated,
+			MIN(m.created_at) as first_message_at,
+			MAX(m.updated_at) FILTER (WHERE m.status IN ('sent', 'failed')) as last_message_at,
+			sm.started_at, sm.completed_at, sm.duration_seconds, sm.messages, sm.throughput_per_minute, sm.estimated_duration_seconds,
+			s.canary_messages, s.canary_pending, s.canary_sent, s.canary_failed,
+			CASE WHEN c.status = 'canary' THEN (c.send_plan->>'audience_size')::int END as canary_held_back
+		FROM campaigns c
+		LEFT JOIN outbound_messages m ON m.campaign_id = c.id
+		LEFT JOIN campaign_send_metrics sm ON sm.campaign_id = c.id
+		WHERE 1=1
+	`)
+
+	args := []interface{}{}
+	argPos := 1
+
+	if filters.From != nil {
+		queryBuilder.WriteString(fmt.Sprintf(" AND c.created_at >= $%d", argPos))
+		args = append(args, *filters.From)
+		argPos++
+	}
+
+	if filters.To != nil {
+		queryBuilder.WriteString(fmt.Sprintf(" AND c.created_at < $%d", argPos))
+		args = append(args, *filters.To)
+		argPos++
+	}
+
+	queryBuilder.WriteString(" GROUP BY c.id, sm.campaign_id ORDER BY c.created_at, c.id")
+
+	rows, err := r.reader().QueryContext(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		return fmt.Errorf("failed to export campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := &models.CampaignExportRow{}
+		var metrics sendMetricsColumns
+		fields := []interface{}{
+			&row.ID,
+			&row.Name,
+			&row.Channel,
+			&row.Status,
+			&row.BaseTemplate,
+			&row.ScheduledAt,
+			&row.CreatedAt,
+			&row.UpdatedAt,
+			pq.Array(&row.Tags),
+			&row.CreatedBy,
+			&row.Team,
+			&row.Stats.Total,
+			&row.Stats.Pending,
+			&row.Stats.Sent,
+			&row.Stats.Failed,
+			&row.Stats.Queued,
+			&row.Stats.Unpublished,
+			&row.Stats.Simulated,
+			&row.FirstMessageAt,
+			&row.LastMessageAt,
+		}
+		if err := rows.Scan(append(fields, metrics.fields()...)...); err != nil {
+			return fmt.Errorf("failed to scan campaign export row: %w", err)
+		}
+		row.SendMetrics = metrics.metrics()
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to export campaigns: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateStatusIf moves a campaign from one status to another
+// The change is rejected with *models.InvalidTransitionError when the transition
+// table forbids it or the campaign is no longer in the from status
+func (r *campaignRepository) UpdateStatusIf(ctx context.Context, id int, from, to models.CampaignStatus) error {
+	return updateStatusIf(ctx, r.db, id, from, to)
+}
+
+// UpdateStatusIfTx is UpdateStatusIf within tx, so the change commits or rolls back with
+// the caller's other writes
+func (r *campaignRepository) UpdateStatusIfTx(ctx context.Context, tx *sql.Tx, id int, from, to models.CampaignStatus) error {
+	return updateStatusIf(ctx, tx, id, from, to)
+}
+
+// updateStatusIf moves a campaign from one status to another on the given database
+func updateStatusIf(ctx context.Context, db DB, id int, from, to models.CampaignStatus) error {
+	if !from.CanTransition(to) {
+		return &models.InvalidTransitionError{From: from, To: to}
+	}
+
 	query := `
 		UPDATE campaigns
 		SET status = $1, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $2
+		WHERE id = $2 AND status = $3
 	`
 
-	result, err := r.db.ExecContext(ctx, query, status, id)
+	result, err := db.ExecContext(ctx, query, to, id, from)
 	if err != nil {
 		return fmt.Errorf("failed to update campaign status: %w", err)
 	}
@@ -221,6 +675,431 @@ func (r *campaignRepository) UpdateStatus(ctx context.Context, id int, status mo
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
+	if rows > 0 {
+		return nil
+	}
+
+	// Nothing matched: the campaign is gone or its status changed underneath us
+	var current models.CampaignStatus
+	err = db.QueryRowContext(ctx, `SELECT status FROM campaigns WHERE id = $1`, id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("campaign not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get campaign status: %w", err)
+	}
+
+	return &models.InvalidTransitionError{From: current, To: to}
+}
+
+// ReserveSpend adds cost to the campaign's spend unless that would take it over its budget
+// It returns false, reserving nothing, when the send would exceed the budget
+func (r *campaignRepository) ReserveSpend(ctx context.Context, id int, cost float64) (bool, error) {
+	query := `
+		UPDATE campaigns
+		SET spend = spend + $2
+		WHERE id = $1 AND (budget IS NULL OR spend + $2 <= budget)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, cost)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve campaign spend: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// ReleaseSpend gives back cost reserved for a send that did not go out
+func (r *campaignRepository) ReleaseSpend(ctx context.Context, id int, cost float64) error {
+	query := `
+		UPDATE campaigns
+		SET spend = GREATEST(spend - $2, 0)
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, cost); err != nil {
+		return fmt.Errorf("failed to release campaign spend: %w", err)
+	}
+
+	return nil
+}
+
+// Pause moves a sending campaign to paused with the reason
+// It returns false when the campaign was not sending, e.g. because another worker paused it first
+func (r *campaignRepository) Pause(ctx context.Context, id int, reason string) (bool, error) {
+	query := `
+		UPDATE campaigns
+		SET status = $2, paused_reason = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, models.CampaignStatusPaused, reason, models.CampaignStatusSending)
+	if err != nil {
+		return false, fmt.Errorf("failed to pause campaign: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// Resume moves a paused campaign back to sending, clearing the pause reason
+// A non-nil budget replaces the campaign's budget. The change is rejected with
+// *models.InvalidTransitionError when the campaign is no longer paused
+func (r *campaignRepository) Resume(ctx context.Context, id int, budget *float64) error {
+	query := `
+		UPDATE campaigns
+		SET status = $2, paused_reason = NULL, budget = COALESCE($3, budget), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, models.CampaignStatusSending, budget, models.CampaignStatusPaused)
+	if err != nil {
+		return fmt.Errorf("failed to resume campaign: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows > 0 {
+		return nil
+	}
+
+	var current models.CampaignStatus
+	err = r.db.QueryRowContext(ctx, `SELECT status FROM campaigns WHERE id = $1`, id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("campaign not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get campaign status: %w", err)
+	}
+
+	return &models.InvalidTransitionError{From: current, To: models.CampaignStatusSending}
+}
+
+// BeginCancel moves a sending, paused or canary campaign to cancelling, final grace after the
+// database's now, and returns that deadline. The change is rejected with
+// *models.InvalidTransitionError when the campaign cannot be cancelled
+func (r *campaignRepository) BeginCancel(ctx context.Context, id int, grace time.Duration) (time.Time, error) {
+	query := `
+		UPDATE campaigns
+		SET status = $2, cancel_at = CURRENT_TIMESTAMP + make_interval(secs => $3), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status IN ($4, $5, $6)
+		RETURNING cancel_at
+	`
+
+	var cancelAt time.Time
+	err := r.db.QueryRowContext(ctx, query, id, models.CampaignStatusCancelling, grace.Seconds(),
+		models.CampaignStatusSending, models.CampaignStatusPaused, models.CampaignStatusCanary).Scan(&cancelAt)
+	if err == nil {
+		return cancelAt, nil
+	}
+	if err != sql.ErrNoRows {
+		return time.Time{}, fmt.Errorf("failed to cancel campaign: %w", err)
+	}
+
+	current, err := r.currentStatus(ctx, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, &models.InvalidTransitionError{From: current, To: models.CampaignStatusCancelling}
+}
+
+// UndoCancel moves a cancelling campaign back to sending while its deadline is still ahead
+// It returns ErrCancelFinal once the deadline has passed, and *models.InvalidTransitionError
+// when the campaign is not cancelling
+func (r *campaignRepository) UndoCancel(ctx context.Context, id int) error {
+	query := `
+		UPDATE campaigns
+		SET status = $2, cancel_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $3 AND cancel_at > CURRENT_TIMESTAMP
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, models.CampaignStatusSending, models.CampaignStatusCancelling)
+	if err != nil {
+		return fmt.Errorf("failed to undo cancellation: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	current, err := r.currentStatus(ctx, id)
+	if err != nil {
+		return err
+	}
+	if current == models.CampaignStatusCancelling {
+		return ErrCancelFinal
+	}
+	return &models.InvalidTransitionError{From: current, To: models.CampaignStatusSending}
+}
+
+// ListDueCancellations returns the IDs of cancelling campaigns whose deadline has passed
+func (r *campaignRepository) ListDueCancellations(ctx context.Context) ([]int, error) {
+	query := `
+		SELECT id
+		FROM campaigns
+		WHERE status = $1 AND cancel_at <= CURRENT_TIMESTAMP
+		ORDER BY cancel_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, models.CampaignStatusCancelling)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due cancellations: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating due cancellations: %w", err)
+	}
+
+	return ids, nil
+}
+
+// ListOrderedOutstanding returns the IDs of ordered campaigns with messages still to send or
+// retry; an ordered campaign drops out once it completes
+func (r *campaignRepository) ListOrderedOutstanding(ctx context.Context) ([]int, error) {
+	query := `
+		SELECT c.id
+		FROM campaigns c
+		WHERE c.ordered AND EXISTS (
+			SELECT 1 FROM outbound_messages m
+			WHERE m.campaign_id = c.id
+				AND (m.status = 'pending' OR (m.status = 'failed' AND m.retry_count < 3))
+		)
+		ORDER BY c.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ordered campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ordered campaigns: %w", err)
+	}
+
+	return ids, nil
+}
+
+// FinalizeCancellation moves a cancelling campaign past its deadline to cancelled and marks its
+// pending messages cancelled, in one transaction, returning how many messages were cancelled
+// It returns ErrCancelNotDue when the campaign is not cancelling or its deadline is ahead,
+// e.g. because the cancellation was undone
+func (r *campaignRepository) FinalizeCancellation(ctx context.Context, id int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE campaigns
+		SET status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $3 AND cancel_at <= CURRENT_TIMESTAMP
+	`, id, models.CampaignStatusCancelled, models.CampaignStatusCancelling)
+	if err != nil {
+		return 0, fmt.Errorf("failed to finalize cancellation: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return 0, ErrCancelNotDue
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		UPDATE outbound_messages
+		SET status = $2, deliver_after = NULL, last_error = 'Campaign cancelled', updated_at = CURRENT_TIMESTAMP
+		WHERE campaign_id = $1 AND status = $3
+	`, id, models.MessageStatusCancelled, models.MessageStatusPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel campaign messages: %w", err)
+	}
+	messages, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int(messages), nil
+}
+
+// currentStatus reads a campaign's status to explain a conditional update that matched nothing
+func (r *campaignRepository) currentStatus(ctx context.Context, id int) (models.CampaignStatus, error) {
+	var current models.CampaignStatus
+	err := r.db.QueryRowContext(ctx, `SELECT status FROM campaigns WHERE id = $1`, id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("campaign not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get campaign status: %w", err)
+	}
+	return current, nil
+}
+
+// AwaitApproval moves a campaign from the from status to pending approval, storing plan as
+// its send plan in the same update
+// The change is rejected with *models.InvalidTransitionError when the transition table
+// forbids it or the campaign is no longer in the from status, and the plan is not stored
+func (r *campaignRepository) AwaitApproval(ctx context.Context, id int, from models.CampaignStatus, plan *models.SendPlan) error {
+	if !from.CanTransition(models.CampaignStatusPendingApproval) {
+		return &models.InvalidTransitionError{From: from, To: models.CampaignStatusPendingApproval}
+	}
+
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		return fmt.Errorf("failed to marshal send plan: %w", err)
+	}
+
+	query := `
+		UPDATE campaigns
+		SET status = $2, send_plan = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, models.CampaignStatusPendingApproval, planJSON, from)
+	if err != nil {
+		return fmt.Errorf("failed to save send plan: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	current, err := r.currentStatus(ctx, id)
+	if err != nil {
+		return err
+	}
+	return &models.InvalidTransitionError{From: current, To: models.CampaignStatusPendingApproval}
+}
+
+// HoldCanary moves a sending campaign to canary once its canary is queued, storing the rest
+// of the audience as its send plan and marking the messages queued so far as the canary
+// The change is rejected with *models.InvalidTransitionError when the campaign is no longer
+// sending, e.g. because it was cancelled while the canary was queued
+func (r *campaignRepository) HoldCanary(ctx context.Context, id int, plan *models.SendPlan) error {
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		return fmt.Errorf("failed to marshal send plan: %w", err)
+	}
+
+	query := `
+		WITH held AS (
+			UPDATE campaigns
+			SET status = $2, send_plan = $3, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND status = $4
+			RETURNING id
+		)
+		UPDATE outbound_messages
+		SET canary = true
+		WHERE campaign_id IN (SELECT id FROM held)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, models.CampaignStatusCanary, planJSON, models.CampaignStatusSending)
+	if err != nil {
+		return fmt.Errorf("failed to hold campaign canary: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	current, err := r.currentStatus(ctx, id)
+	if err != nil {
+		return err
+	}
+	return &models.InvalidTransitionError{From: current, To: models.CampaignStatusCanary}
+}
+
+// GetSendPlan retrieves the send plan stored for a campaign awaiting approval or holding a canary
+func (r *campaignRepository) GetSendPlan(ctx context.Context, id int) (*models.SendPlan, error) {
+	query := `SELECT send_plan FROM campaigns WHERE id = $1`
+
+	var planJSON []byte
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&planJSON)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("campaign not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get send plan: %w", err)
+	}
+
+	if planJSON == nil {
+		return nil, fmt.Errorf("send plan not found")
+	}
+
+	plan := &models.SendPlan{}
+	if err := json.Unmarshal(planJSON, plan); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal send plan: %w", err)
+	}
+
+	return plan, nil
+}
+
+// ClearSendPlan removes the stored send plan
+func (r *campaignRepository) ClearSendPlan(ctx context.Context, id int) error {
+	query := `
+		UPDATE campaigns
+		SET send_plan = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to clear send plan: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
 	if rows == 0 {
 		return fmt.Errorf("campaign not found")
 	}
@@ -228,12 +1107,118 @@ func (r *campaignRepository) UpdateStatus(ctx context.Context, id int, status mo
 	return nil
 }
 
+// RecordSend stores a send request and its outcome
+func (r *campaignRepository) RecordSend(ctx context.Context, record *models.SendRecord) error {
+	requestJSON, err := json.Marshal(record.Request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal send request: %w", err)
+	}
+
+	query := `
+		INSERT INTO campaign_sends_log (campaign_id, requested_by, source, request, audience_size, messages_queued, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err = r.db.QueryRowContext(ctx, query,
+		record.CampaignID,
+		record.RequestedBy,
+		record.Source,
+		requestJSON,
+		record.AudienceSize,
+		record.MessagesQueued,
+		record.Status,
+	).Scan(&record.ID, &record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record send: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSendProgress updates how many messages a logged send has queued and its status
+func (r *campaignRepository) UpdateSendProgress(ctx context.Context, id int, messagesQueued int, status models.CampaignStatus) error {
+	query := `
+		UPDATE campaign_sends_log
+		SET messages_queued = $2, status = $3
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, messagesQueued, status)
+	if err != nil {
+		return fmt.Errorf("failed to update send progress: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("send not found")
+	}
+
+	return nil
+}
+
+// ListSends returns a campaign's send requests, newest first
+func (r *campaignRepository) ListSends(ctx context.Context, campaignID int) ([]*models.SendRecord, error) {
+	query := `
+		SELECT id, campaign_id, requested_by, source, request, audience_size, messages_queued, status, created_at
+		FROM campaign_sends_log
+		WHERE campaign_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sends: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*models.SendRecord{}
+	for rows.Next() {
+		record := &models.SendRecord{}
+		var requestJSON []byte
+		err := rows.Scan(
+			&record.ID,
+			&record.CampaignID,
+			&record.RequestedBy,
+			&record.Source,
+			&requestJSON,
+			&record.AudienceSize,
+			&record.MessagesQueued,
+			&record.Status,
+			&record.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan send: %w", err)
+		}
+		if err := json.Unmarshal(requestJSON, &record.Request); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal send request: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sends: %w", err)
+	}
+
+	return records, nil
+}
+
 // Delete deletes a campaign
+// Returns *HasDependentsError (matching ErrHasDependents) while messages still reference it,
+// and ErrCampaignNotFound when it does not exist
 func (r *campaignRepository) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM campaigns WHERE id = $1`
 
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return r.dependentsError(ctx, id)
+		}
 		return fmt.Errorf("failed to delete campaign: %w", err)
 	}
 
@@ -243,8 +1228,126 @@ func (r *campaignRepository) Delete(ctx context.Context, id int) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("campaign not found")
+		return ErrCampaignNotFound
 	}
 
 	return nil
 }
+
+// dependentsError counts the messages that blocked a delete
+func (r *campaignRepository) dependentsError(ctx context.Context, id int) error {
+	var messages int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM outbound_messages WHERE campaign_id = $1`, id).Scan(&messages)
+	if err != nil {
+		return fmt.Errorf("failed to count campaign messages: %w", err)
+	}
+	return &HasDependentsError{Resource: "campaign", Messages: messages}
+}
+
+// DeleteWithMessages deletes a campaign and every message referencing it in one transaction
+// Returns the number of messages deleted, or ErrCampaignNotFound when the campaign does not exist
+func (r *campaignRepository) DeleteWithMessages(ctx context.Context, id int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM outbound_messages WHERE campaign_id = $1`, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete campaign messages: %w", err)
+	}
+	messages, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	result, err = tx.ExecContext(ctx, `DELETE FROM campaigns WHERE id = $1`, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete campaign: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return 0, ErrCampaignNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int(messages), nil
+}
+
+// ListNeedingAttention retrieves campaigns that are stalled, overdue or failing
+// A campaign matching several conditions is returned once per reason
+// Reads the replica
+func (r *campaignRepository) ListNeedingAttention(ctx context.Context) ([]*models.CampaignAttention, error) {
+	query := `
+		WITH message_stats AS (
+			SELECT
+				campaign_id,
+				COUNT(*) FILTER (WHERE status IN ('sent', 'failed')) as processed,
+				COUNT(*) FILTER (WHERE status = 'failed') as failed,
+				MAX(updated_at) FILTER (WHERE status IN ('sent', 'failed')) as last_progress_at
+			FROM outbound_messages
+			GROUP BY campaign_id
+		)
+		SELECT
+			c.id, c.name, c.channel, c.status, c.base_template, c.scheduled_at, c.created_at, c.updated_at, c.tags, c.created_by, c.team,
+			r.reason,
+			COALESCE(s.processed, 0),
+			COALESCE(s.failed, 0),
+			s.last_progress_at
+		FROM campaigns c
+		LEFT JOIN message_stats s ON s.campaign_id = c.id
+		CROSS JOIN LATERAL (VALUES
+			(CASE WHEN c.status = 'sending'
+				AND c.updated_at < NOW() - INTERVAL '1 hour'
+				AND (s.last_progress_at IS NULL OR s.last_progress_at < NOW() - INTERVAL '1 hour')
+				THEN 'stalled_sending' END),
+			(CASE WHEN c.status = 'scheduled' AND c.scheduled_at < NOW()
+				THEN 'overdue_schedule' END),
+			(CASE WHEN s.processed > 0 AND s.failed::float / s.processed > $1
+				THEN 'high_failure_rate' END)
+		) AS r(reason)
+		WHERE r.reason IS NOT NULL
+		ORDER BY c.id, r.reason
+	`
+
+	rows, err := r.reader().QueryContext(ctx, query, models.AttentionFailureRateThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns needing attention: %w", err)
+	}
+	defer rows.Close()
+
+	results := []*models.CampaignAttention{}
+	for rows.Next() {
+		item := &models.CampaignAttention{}
+		err := rows.Scan(
+			&item.Campaign.ID,
+			&item.Campaign.Name,
+			&item.Campaign.Channel,
+			&item.Campaign.Status,
+			&item.Campaign.BaseTemplate,
+			&item.Campaign.ScheduledAt,
+			&item.Campaign.CreatedAt,
+			&item.Campaign.UpdatedAt,
+			pq.Array(&item.Campaign.Tags),
+			&item.Campaign.CreatedBy,
+			&item.Campaign.Team,
+			&item.Reason,
+			&item.Processed,
+			&item.Failed,
+			&item.LastProgressAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign attention: %w", err)
+		}
+		results = append(results, item)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+// DefaultLookupChunkSize is how many IDs or phones one lookup query takes
+// Longer lists are split so no single array parameter or result set grows without bound
+const DefaultLookupChunkSize = 1000
+
+// chunks splits values into consecutive chunks of at most size values, dropping repeats so a
+// value is only looked up once even when its repeats would land in different chunks
+// It returns no chunks for no values
+func chunks[T comparable](values []T, size int) [][]T {
+	if size < 1 {
+		size = DefaultLookupChunkSize
+	}
+
+	seen := make(map[T]bool, len(values))
+	unique := make([]T, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+
+	result := make([][]T, 0, (len(unique)+size-1)/size)
+	for start := 0; start < len(unique); start += size {
+		end := min(start+size, len(unique))
+		result = append(result, unique[start:end])
+	}
+	return result
+}
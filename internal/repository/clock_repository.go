@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type clockRepository struct {
+	db DB
+}
+
+// NewClockRepository creates a new database clock repository
+func NewClockRepository(db DB) ClockRepository {
+	return &clockRepository{db: db}
+}
+
+// Now returns the database server's current time
+func (r *clockRepository) Now(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	if err := r.db.QueryRowContext(ctx, `SELECT NOW()`).Scan(&now); err != nil {
+		return time.Time{}, fmt.Errorf("failed to read database time: %w", err)
+	}
+	return now, nil
+}
@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 
 	"smsleopard/internal/models"
 
@@ -11,19 +13,46 @@ import (
 )
 
 type customerRepository struct {
-	db *sql.DB
+	db        Database
+	readerDB  Database // Read replica for reporting queries (nil uses db)
+	chunkSize int      // IDs or phones per lookup query
 }
 
 // NewCustomerRepository creates a new customer repository
-func NewCustomerRepository(db *sql.DB) CustomerRepository {
-	return &customerRepository{db: db}
+func NewCustomerRepository(db Database) CustomerRepository {
+	return &customerRepository{db: db, chunkSize: DefaultLookupChunkSize}
+}
+
+// NewCustomerRepositoryWithReader creates a customer repository that sends
+// reporting reads to a read replica
+// A nil reader falls back to the primary
+func NewCustomerRepositoryWithReader(db, readerDB Database) CustomerRepository {
+	return &customerRepository{db: db, readerDB: readerDB, chunkSize: DefaultLookupChunkSize}
+}
+
+// NewCustomerRepositoryWithChunkSize creates a customer repository like
+// NewCustomerRepositoryWithReader whose ID and phone lookups query chunkSize values at a time
+// A chunkSize below 1 uses DefaultLookupChunkSize
+func NewCustomerRepositoryWithChunkSize(db, readerDB Database, chunkSize int) CustomerRepository {
+	if chunkSize < 1 {
+		chunkSize = DefaultLookupChunkSize
+	}
+	return &customerRepository{db: db, readerDB: readerDB, chunkSize: chunkSize}
+}
+
+// reader returns the database for replica-safe reads
+func (r *customerRepository) reader() Database {
+	if r.readerDB != nil {
+		return r.readerDB
+	}
+	return r.db
 }
 
 // Create creates a new customer
 func (r *customerRepository) Create(ctx context.Context, customer *models.Customer) error {
 	query := `
-		INSERT INTO customers (phone, first_name, last_name, location, preferred_product)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO customers (phone, first_name, last_name, location, preferred_product, contact_window_start, contact_window_end)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at
 	`
 
@@ -35,6 +64,8 @@ func (r *customerRepository) Create(ctx context.Context, customer *models.Custom
 		customer.LastName,
 		customer.Location,
 		customer.PreferredProduct,
+		customer.ContactWindowStart,
+		customer.ContactWindowEnd,
 	).Scan(&customer.ID, &customer.CreatedAt)
 
 	if err != nil {
@@ -44,10 +75,66 @@ func (r *customerRepository) Create(ctx context.Context, customer *models.Custom
 	return nil
 }
 
+// UpsertByPhone creates a customer or, when the phone already exists, fills in the given fields
+// Fields left nil keep their stored values. Reports whether the customer was created
+// The phone must already be normalized; an existing customer stored in another format is
+// matched on its normalized phone (the oldest, if several share it)
+func (r *customerRepository) UpsertByPhone(ctx context.Context, customer *models.Customer) (bool, error) {
+	query := `
+		WITH existing AS (
+			UPDATE customers SET
+				first_name = COALESCE($2, first_name),
+				last_name = COALESCE($3, last_name),
+				location = COALESCE($4, location),
+				preferred_product = COALESCE($5, preferred_product),
+				contact_window_start = COALESCE($6, contact_window_start),
+				contact_window_end = COALESCE($7, contact_window_end)
+			WHERE id = (
+				SELECT id FROM customers WHERE normalize_phone(phone) = $1 ORDER BY created_at, id LIMIT 1
+			)
+			RETURNING id, created_at, false AS created
+		), inserted AS (
+			INSERT INTO customers (phone, first_name, last_name, location, preferred_product, contact_window_start, contact_window_end)
+			SELECT $1, $2, $3, $4, $5, $6, $7
+			WHERE NOT EXISTS (SELECT 1 FROM existing)
+			ON CONFLICT (phone) DO UPDATE SET
+				first_name = COALESCE(EXCLUDED.first_name, customers.first_name),
+				last_name = COALESCE(EXCLUDED.last_name, customers.last_name),
+				location = COALESCE(EXCLUDED.location, customers.location),
+				preferred_product = COALESCE(EXCLUDED.preferred_product, customers.preferred_product),
+				contact_window_start = COALESCE(EXCLUDED.contact_window_start, customers.contact_window_start),
+				contact_window_end = COALESCE(EXCLUDED.contact_window_end, customers.contact_window_end)
+			RETURNING id, created_at, xmax = 0 AS created
+		)
+		SELECT id, created_at, created FROM existing
+		UNION ALL
+		SELECT id, created_at, created FROM inserted
+	`
+
+	var created bool
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		customer.Phone,
+		customer.FirstName,
+		customer.LastName,
+		customer.Location,
+		customer.PreferredProduct,
+		customer.ContactWindowStart,
+		customer.ContactWindowEnd,
+	).Scan(&customer.ID, &customer.CreatedAt, &created)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to upsert customer: %w", err)
+	}
+
+	return created, nil
+}
+
 // GetByID retrieves a customer by ID
 func (r *customerRepository) GetByID(ctx context.Context, id int) (*models.Customer, error) {
 	query := `
-		SELECT id, phone, first_name, last_name, location, preferred_product, created_at
+		SELECT id, phone, first_name, last_name, location, preferred_product, contact_window_start, contact_window_end, created_at
 		FROM customers
 		WHERE id = $1
 	`
@@ -60,6 +147,8 @@ func (r *customerRepository) GetByID(ctx context.Context, id int) (*models.Custo
 		&customer.LastName,
 		&customer.Location,
 		&customer.PreferredProduct,
+		&customer.ContactWindowStart,
+		&customer.ContactWindowEnd,
 		&customer.CreatedAt,
 	)
 
@@ -73,14 +162,24 @@ func (r *customerRepository) GetByID(ctx context.Context, id int) (*models.Custo
 	return customer, nil
 }
 
-// GetByIDs retrieves multiple customers by IDs
+// GetByIDs retrieves multiple customers by IDs; IDs without a customer are absent from the result
+// Large lists are queried in chunks, each customer returned once however often its ID is given
 func (r *customerRepository) GetByIDs(ctx context.Context, ids []int) ([]*models.Customer, error) {
-	if len(ids) == 0 {
-		return []*models.Customer{}, nil
+	customers := []*models.Customer{}
+	for _, chunk := range chunks(ids, r.chunkSize) {
+		found, err := r.getByIDs(ctx, chunk)
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, found...)
 	}
+	return customers, nil
+}
 
+// getByIDs retrieves the customers with any of the given IDs in one query
+func (r *customerRepository) getByIDs(ctx context.Context, ids []int) ([]*models.Customer, error) {
 	query := `
-		SELECT id, phone, first_name, last_name, location, preferred_product, created_at
+		SELECT id, phone, first_name, last_name, location, preferred_product, contact_window_start, contact_window_end, created_at
 		FROM customers
 		WHERE id = ANY($1)
 	`
@@ -101,6 +200,61 @@ func (r *customerRepository) GetByIDs(ctx context.Context, ids []int) ([]*models
 			&customer.LastName,
 			&customer.Location,
 			&customer.PreferredProduct,
+			&customer.ContactWindowStart,
+			&customer.ContactWindowEnd,
+			&customer.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan customer: %w", err)
+		}
+		customers = append(customers, customer)
+	}
+
+	return customers, nil
+}
+
+// GetByPhones retrieves the customers with any of the given phone numbers
+// Phones must already be normalized; stored phones are compared in normalized form, so a
+// customer stored as "0712345678" is found by "+254712345678". Unknown phones are simply
+// absent from the result. Large lists are queried in chunks like GetByIDs
+func (r *customerRepository) GetByPhones(ctx context.Context, phones []string) ([]*models.Customer, error) {
+	customers := []*models.Customer{}
+	for _, chunk := range chunks(phones, r.chunkSize) {
+		found, err := r.getByPhones(ctx, chunk)
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, found...)
+	}
+	return customers, nil
+}
+
+// getByPhones retrieves the customers with any of the given phones in one query
+func (r *customerRepository) getByPhones(ctx context.Context, phones []string) ([]*models.Customer, error) {
+	query := `
+		SELECT id, phone, first_name, last_name, location, preferred_product, contact_window_start, contact_window_end, created_at
+		FROM customers
+		WHERE normalize_phone(phone) = ANY($1)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(phones))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customers by phone: %w", err)
+	}
+	defer rows.Close()
+
+	customers := []*models.Customer{}
+	for rows.Next() {
+		customer := &models.Customer{}
+		err := rows.Scan(
+			&customer.ID,
+			&customer.Phone,
+			&customer.FirstName,
+			&customer.LastName,
+			&customer.Location,
+			&customer.PreferredProduct,
+			&customer.ContactWindowStart,
+			&customer.ContactWindowEnd,
 			&customer.CreatedAt,
 		)
 		if err != nil {
@@ -115,7 +269,7 @@ func (r *customerRepository) GetByIDs(ctx context.Context, ids []int) ([]*models
 // List retrieves customers with pagination
 func (r *customerRepository) List(ctx context.Context, limit, offset int) ([]*models.Customer, error) {
 	query := `
-		SELECT id, phone, first_name, last_name, location, preferred_product, created_at
+		SELECT id, phone, first_name, last_name, location, preferred_product, contact_window_start, contact_window_end, created_at
 		FROM customers
 		ORDER BY id DESC
 		LIMIT $1 OFFSET $2
@@ -137,6 +291,8 @@ func (r *customerRepository) List(ctx context.Context, limit, offset int) ([]*mo
 			&customer.LastName,
 			&customer.Location,
 			&customer.PreferredProduct,
+			&customer.ContactWindowStart,
+			&customer.ContactWindowEnd,
 			&customer.CreatedAt,
 		)
 		if err != nil {
@@ -148,47 +304,75 @@ func (r *customerRepository) List(ctx context.Context, limit, offset int) ([]*mo
 	return customers, nil
 }
 
-// Update updates a customer
-func (r *customerRepository) Update(ctx context.Context, customer *models.Customer) error {
-	query := `
+// Update updates a customer and, when their number changed, logs the old and new phones and
+// who changed them, in one transaction. Reformatting the same number is not logged
+// Returns ErrCustomerNotFound when the customer does not exist
+func (r *customerRepository) Update(ctx context.Context, customer *models.Customer, changedBy *string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldPhone string
+	err = tx.QueryRowContext(ctx, `SELECT phone FROM customers WHERE id = $1 FOR UPDATE`, customer.ID).Scan(&oldPhone)
+	if err == sql.ErrNoRows {
+		return ErrCustomerNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	update := `
 		UPDATE customers
-		SET phone = $1, first_name = $2, last_name = $3, location = $4, preferred_product = $5
-		WHERE id = $6
+		SET phone = $1, first_name = $2, last_name = $3, location = $4, preferred_product = $5,
+			contact_window_start = $6, contact_window_end = $7
+		WHERE id = $8
 	`
-
-	result, err := r.db.ExecContext(
+	_, err = tx.ExecContext(
 		ctx,
-		query,
+		update,
 		customer.Phone,
 		customer.FirstName,
 		customer.LastName,
 		customer.Location,
 		customer.PreferredProduct,
+		customer.ContactWindowStart,
+		customer.ContactWindowEnd,
 		customer.ID,
 	)
-
 	if err != nil {
 		return fmt.Errorf("failed to update customer: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	insert := `
+		INSERT INTO customer_phone_history (customer_id, old_phone, new_phone, changed_by)
+		SELECT $1, $2, $3, $4
+		WHERE normalize_phone($2) IS DISTINCT FROM normalize_phone($3)
+	`
+	if _, err := tx.ExecContext(ctx, insert, customer.ID, oldPhone, customer.Phone, changedBy); err != nil {
+		return fmt.Errorf("failed to log customer phone change: %w", err)
 	}
 
-	if rows == 0 {
-		return fmt.Errorf("customer not found")
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
 // Delete deletes a customer
+// Returns *HasDependentsError (matching ErrHasDependents) while messages still reference it,
+// and ErrCustomerNotFound when it does not exist
 func (r *customerRepository) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM customers WHERE id = $1`
 
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return r.dependentsError(ctx, id)
+		}
 		return fmt.Errorf("failed to delete customer: %w", err)
 	}
 
@@ -198,8 +382,608 @@ func (r *customerRepository) Delete(ctx context.Context, id int) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("customer not found")
+		return ErrCustomerNotFound
 	}
 
 	return nil
 }
+
+// dependentsError counts the messages that blocked a delete
+func (r *customerRepository) dependentsError(ctx context.Context, id int) error {
+	var messages int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM outbound_messages WHERE customer_id = $1`, id).Scan(&messages)
+	if err != nil {
+		return fmt.Errorf("failed to count customer messages: %w", err)
+	}
+	return &HasDependentsError{Resource: "customer", Messages: messages}
+}
+
+// DeleteWithMessages deletes a customer and every message referencing it in one transaction
+// Returns the number of messages deleted, or ErrCustomerNotFound when the customer does not exist
+func (r *customerRepository) DeleteWithMessages(ctx context.Context, id int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM outbound_messages WHERE customer_id = $1`, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete customer messages: %w", err)
+	}
+	messages, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	result, err = tx.ExecContext(ctx, `DELETE FROM customers WHERE id = $1`, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete customer: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return 0, ErrCustomerNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int(messages), nil
+}
+
+// GetStats counts customers by preferred product and by location (top N each)
+// Missing values are counted as "unknown"; location scopes the product breakdown only
+func (r *customerRepository) GetStats(ctx context.Context, location *string) (*models.CustomerStats, error) {
+	query := `
+		WITH buckets AS (
+			SELECT 'product' as dimension, COALESCE(NULLIF(preferred_product, ''), $1) as value, COUNT(*) as count
+			FROM customers
+			WHERE $2::text IS NULL OR COALESCE(NULLIF(location, ''), $1) = $2::text
+			GROUP BY 2
+			UNION ALL
+			SELECT 'location', COALESCE(NULLIF(location, ''), $1), COUNT(*)
+			FROM customers
+			GROUP BY 2
+			UNION ALL
+			SELECT 'total', '', COUNT(*)
+			FROM customers
+		),
+		ranked AS (
+			SELECT dimension, value, count,
+				ROW_NUMBER() OVER (PARTITION BY dimension ORDER BY count DESC, value ASC) as rank
+			FROM buckets
+		)
+		SELECT dimension, value, count
+		FROM ranked
+		WHERE rank <= $3
+		ORDER BY dimension, rank
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, models.CustomerStatsUnknown, location, models.CustomerStatsTopN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &models.CustomerStats{
+		ByProduct:  []*models.CustomerStatsBucket{},
+		ByLocation: []*models.CustomerStatsBucket{},
+	}
+	for rows.Next() {
+		var dimension string
+		bucket := &models.CustomerStatsBucket{}
+		if err := rows.Scan(&dimension, &bucket.Value, &bucket.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan customer stats: %w", err)
+		}
+
+		switch dimension {
+		case "total":
+			stats.TotalCustomers = bucket.Count
+		case "product":
+			stats.ByProduct = append(stats.ByProduct, bucket)
+		case "location":
+			stats.ByLocation = append(stats.ByLocation, bucket)
+		}
+	}
+
+	return stats, nil
+}
+
+// fieldColumns whitelists the customer columns CountMissingFields may check
+// Only these column names are ever interpolated into SQL
+var fieldColumns = map[string]string{
+	"first_name":        "first_name",
+	"last_name":         "last_name",
+	"location":          "location",
+	"preferred_product": "preferred_product",
+	"phone":             "phone",
+}
+
+// CountMissingFields counts the customers among ids and, per field, those with
+// the field null or empty, in one aggregate query
+// Reads the replica
+func (r *customerRepository) CountMissingFields(ctx context.Context, ids []int, fields []string) (*models.FieldCompleteness, error) {
+	selects := make([]string, 0, len(fields)+1)
+	selects = append(selects, "COUNT(*)")
+	for _, field := range fields {
+		column, ok := fieldColumns[field]
+		if !ok {
+			return nil, fmt.Errorf("unsupported customer field: %s", field)
+		}
+		selects = append(selects, fmt.Sprintf("COUNT(*) FILTER (WHERE %s IS NULL OR %s = '')", column, column))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM customers
+		WHERE id = ANY($1)
+	`, strings.Join(selects, ", "))
+
+	completeness := &models.FieldCompleteness{Missing: make(map[string]int, len(fields))}
+	counts := make([]int, len(fields))
+	dest := make([]interface{}, 0, len(fields)+1)
+	dest = append(dest, &completeness.Total)
+	for i := range counts {
+		dest = append(dest, &counts[i])
+	}
+
+	if err := r.reader().QueryRowContext(ctx, query, pq.Array(ids)).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to count missing fields: %w", err)
+	}
+
+	for i, field := range fields {
+		completeness.Missing[field] = counts[i]
+	}
+
+	return completeness, nil
+}
+
+// GetTimeline retrieves a customer's events after the cursor, newest first, starting from the
+// most recent when after is nil
+// Each message contributes a queued event and, once processed, a sent or failed event
+// Reads the replica
+func (r *customerRepository) GetTimeline(ctx context.Context, customerID int, after *models.TimelineCursor, limit int) ([]*models.TimelineEvent, error) {
+	args := []interface{}{customerID, limit}
+	cursor := ""
+	if after != nil {
+		args = append(args, after.OccurredAt, after.MessageID, string(after.Type))
+		cursor = "WHERE (occurred_at, message_id, type) < ($3, $4, $5::text)"
+	}
+
+	query := `
+		SELECT type, occurred_at, message_id, campaign_id, campaign_name, detail
+		FROM (
+			SELECT 'message_queued' as type, m.created_at as occurred_at,
+				m.id as message_id, c.id as campaign_id, c.name as campaign_name, NULL as detail
+			FROM outbound_messages m
+			JOIN campaigns c ON c.id = m.campaign_id
+			WHERE m.customer_id = $1
+			UNION ALL
+			SELECT 'message_' || m.status, m.updated_at,
+				m.id, c.id, c.name, m.last_error
+			FROM outbound_messages m
+			JOIN campaigns c ON c.id = m.campaign_id
+			WHERE m.customer_id = $1 AND m.status IN ('sent', 'failed')
+		) events
+		` + cursor + `
+		ORDER BY occurred_at DESC, message_id DESC, type DESC
+		LIMIT $2
+	`
+
+	rows, err := r.reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer timeline: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.TimelineEvent{}
+	for rows.Next() {
+		event := &models.TimelineEvent{}
+		err := rows.Scan(
+			&event.Type,
+			&event.OccurredAt,
+			&event.MessageID,
+			&event.CampaignID,
+			&event.CampaignName,
+			&event.Detail,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan timeline event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// customerFilterClause builds the WHERE clause shared by CountFiltered, ListFiltered and
+// StreamFiltered, so a count always matches the rows listed or streamed
+func customerFilterClause(filters CustomerFilters) (string, []interface{}) {
+	conditions := []string{"1=1"}
+	args := []interface{}{}
+
+	if filters.Query != "" {
+		args = append(args, "%"+likeEscaper.Replace(filters.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf(
+			"(phone ILIKE $%[1]d OR first_name ILIKE $%[1]d OR last_name ILIKE $%[1]d)", len(args)))
+	}
+
+	if filters.Location != "" {
+		args = append(args, filters.Location)
+		conditions = append(conditions, fmt.Sprintf("LOWER(location) = LOWER($%d)", len(args)))
+	}
+
+	if filters.Product != "" {
+		args = append(args, filters.Product)
+		conditions = append(conditions, fmt.Sprintf("LOWER(preferred_product) = LOWER($%d)", len(args)))
+	}
+
+	if filters.MinScore != nil {
+		args = append(args, *filters.MinScore)
+		conditions = append(conditions, fmt.Sprintf("engagement_score >= $%d", len(args)))
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// likeEscaper escapes LIKE wildcards so a search term matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// CountFiltered counts the customers matching filters
+// Reads the replica
+func (r *customerRepository) CountFiltered(ctx context.Context, filters CustomerFilters) (int, error) {
+	where, args := customerFilterClause(filters)
+
+	var count int
+	if err := r.reader().QueryRowContext(ctx, "SELECT COUNT(*) FROM customers"+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count customers: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListFiltered retrieves a page of the customers matching filters, newest first, with their
+// engagement scores
+// Reads the replica
+func (r *customerRepository) ListFiltered(ctx context.Context, filters CustomerFilters, limit, offset int) ([]*models.Customer, error) {
+	where, args := customerFilterClause(filters)
+	args = append(args, limit, offset)
+
+	query := `
+		SELECT id, phone, first_name, last_name, location, preferred_product, contact_window_start, contact_window_end, created_at,
+			engagement_score, engagement_scored_at
+		FROM customers` + where + fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list customers: %w", err)
+	}
+	defer rows.Close()
+
+	customers := []*models.Customer{}
+	for rows.Next() {
+		customer := &models.Customer{}
+		err := rows.Scan(
+			&customer.ID,
+			&customer.Phone,
+			&customer.FirstName,
+			&customer.LastName,
+			&customer.Location,
+			&customer.PreferredProduct,
+			&customer.ContactWindowStart,
+			&customer.ContactWindowEnd,
+			&customer.CreatedAt,
+			&customer.EngagementScore,
+			&customer.EngagementScoredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan customer: %w", err)
+		}
+		customers = append(customers, customer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating customers: %w", err)
+	}
+
+	return customers, nil
+}
+
+// StreamFiltered calls fn for each customer matching filters, oldest first, stopping after
+// limit rows (no limit when 0) or at fn's first error
+// Reads the replica
+func (r *customerRepository) StreamFiltered(ctx context.Context, filters CustomerFilters, limit int, fn func(customer *models.Customer) error) error {
+	where, args := customerFilterClause(filters)
+
+	query := `
+		SELECT id, phone, first_name, last_name, location, preferred_product, contact_window_start, contact_window_end, created_at,
+			blocked, blocked_reason
+		FROM customers` + where + " ORDER BY id"
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to export customers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		customer := &models.Customer{}
+		err := rows.Scan(
+			&customer.ID,
+			&customer.Phone,
+			&customer.FirstName,
+			&customer.LastName,
+			&customer.Location,
+			&customer.PreferredProduct,
+			&customer.ContactWindowStart,
+			&customer.ContactWindowEnd,
+			&customer.CreatedAt,
+			&customer.Blocked,
+			&customer.BlockedReason,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan customer: %w", err)
+		}
+		if err := fn(customer); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating customers: %w", err)
+	}
+
+	return nil
+}
+
+// GetDetail retrieves a customer by ID with their block status and engagement score
+func (r *customerRepository) GetDetail(ctx context.Context, id int) (*models.Customer, error) {
+	query := `
+		SELECT id, phone, first_name, last_name, location, preferred_product, contact_window_start, contact_window_end, created_at,
+			blocked, blocked_reason, blocked_by, blocked_at, engagement_score, engagement_scored_at
+		FROM customers
+		WHERE id = $1
+	`
+
+	customer := &models.Customer{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&customer.ID,
+		&customer.Phone,
+		&customer.FirstName,
+		&customer.LastName,
+		&customer.Location,
+		&customer.PreferredProduct,
+		&customer.ContactWindowStart,
+		&customer.ContactWindowEnd,
+		&customer.CreatedAt,
+		&customer.Blocked,
+		&customer.BlockedReason,
+		&customer.BlockedBy,
+		&customer.BlockedAt,
+		&customer.EngagementScore,
+		&customer.EngagementScoredAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	return customer, nil
+}
+
+// Block blocks a customer from every future send and logs who blocked them and why, in one
+// transaction. Blocking a blocked customer replaces the reason and is logged again
+// Returns ErrCustomerNotFound when the customer does not exist
+func (r *customerRepository) Block(ctx context.Context, id int, reason string, actor *string) error {
+	return r.setBlocked(ctx, id, models.CustomerBlocked, &reason, actor)
+}
+
+// Unblock lifts a customer's block and logs who lifted it, in one transaction
+// Returns ErrCustomerNotFound when the customer does not exist
+func (r *customerRepository) Unblock(ctx context.Context, id int, actor *string) error {
+	return r.setBlocked(ctx, id, models.CustomerUnblocked, nil, actor)
+}
+
+// setBlocked stores a customer's block status and logs the change
+func (r *customerRepository) setBlocked(ctx context.Context, id int, action models.CustomerBlockAction, reason, actor *string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	update := `
+		UPDATE customers
+		SET blocked = $2, blocked_reason = $3, blocked_by = $4,
+			blocked_at = CASE WHEN $2 THEN CURRENT_TIMESTAMP END
+		WHERE id = $1
+	`
+	// An unblocked customer keeps no reason or blocker; the log still has both
+	blocked := action == models.CustomerBlocked
+	blockedBy := actor
+	if !blocked {
+		blockedBy = nil
+	}
+	result, err := tx.ExecContext(ctx, update, id, blocked, reason, blockedBy)
+	if err != nil {
+		return fmt.Errorf("failed to update customer block: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrCustomerNotFound
+	}
+
+	insert := `
+		INSERT INTO customer_block_events (customer_id, action, reason, actor)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := tx.ExecContext(ctx, insert, id, action, reason, actor); err != nil {
+		return fmt.Errorf("failed to log customer block: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ListBlocked returns the blocked customers among ids
+// Large lists are queried in chunks like GetByIDs
+func (r *customerRepository) ListBlocked(ctx context.Context, ids []int) ([]int, error) {
+	blocked := []int{}
+	for _, chunk := range chunks(ids, r.chunkSize) {
+		rows, err := r.db.QueryContext(ctx, `SELECT id FROM customers WHERE blocked AND id = ANY($1)`, pq.Array(chunk))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list blocked customers: %w", err)
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan blocked customer: %w", err)
+			}
+			blocked = append(blocked, id)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error iterating blocked customers: %w", err)
+		}
+	}
+	return blocked, nil
+}
+
+// ListBelowScore returns the customers among ids scoring below minScore or not yet scored
+// Large lists are queried in chunks like GetByIDs
+func (r *customerRepository) ListBelowScore(ctx context.Context, ids []int, minScore float64) ([]int, error) {
+	below := []int{}
+	for _, chunk := range chunks(ids, r.chunkSize) {
+		rows, err := r.db.QueryContext(ctx,
+			`SELECT id FROM customers WHERE id = ANY($1) AND (engagement_score IS NULL OR engagement_score < $2)`,
+			pq.Array(chunk), minScore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list customers below score: %w", err)
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan customer below score: %w", err)
+			}
+			below = append(below, id)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error iterating customers below score: %w", err)
+		}
+	}
+	return below, nil
+}
+
+// ListBlockedPhones returns the phones, among the given normalized phones, of blocked customers
+// Stored phones are compared in normalized form, as in GetByPhones
+func (r *customerRepository) ListBlockedPhones(ctx context.Context, phones []string) ([]string, error) {
+	blocked := []string{}
+	for _, chunk := range chunks(phones, r.chunkSize) {
+		rows, err := r.db.QueryContext(ctx,
+			`SELECT DISTINCT normalize_phone(phone) FROM customers WHERE blocked AND normalize_phone(phone) = ANY($1)`,
+			pq.Array(chunk))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list blocked phones: %w", err)
+		}
+		for rows.Next() {
+			var phone string
+			if err := rows.Scan(&phone); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan blocked phone: %w", err)
+			}
+			blocked = append(blocked, phone)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error iterating blocked phones: %w", err)
+		}
+	}
+	return blocked, nil
+}
+
+// ListBlockEvents returns a customer's blocks and unblocks, newest first
+func (r *customerRepository) ListBlockEvents(ctx context.Context, id int) ([]*models.CustomerBlockEvent, error) {
+	query := `
+		SELECT id, customer_id, action, reason, actor, created_at
+		FROM customer_block_events
+		WHERE customer_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list customer block events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.CustomerBlockEvent{}
+	for rows.Next() {
+		event := &models.CustomerBlockEvent{}
+		err := rows.Scan(&event.ID, &event.CustomerID, &event.Action, &event.Reason, &event.Actor, &event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan customer block event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating customer block events: %w", err)
+	}
+
+	return events, nil
+}
+
+// ListPhoneHistory returns a customer's phone changes, newest first
+func (r *customerRepository) ListPhoneHistory(ctx context.Context, id int) ([]*models.CustomerPhoneChange, error) {
+	query := `
+		SELECT id, customer_id, old_phone, new_phone, changed_by, changed_at
+		FROM customer_phone_history
+		WHERE customer_id = $1
+		ORDER BY changed_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list customer phone history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []*models.CustomerPhoneChange{}
+	for rows.Next() {
+		change := &models.CustomerPhoneChange{}
+		err := rows.Scan(&change.ID, &change.CustomerID, &change.OldPhone, &change.NewPhone, &change.ChangedBy, &change.ChangedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan customer phone change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating customer phone history: %w", err)
+	}
+
+	return changes, nil
+}
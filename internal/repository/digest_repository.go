@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"smsleopard/internal/models"
+)
+
+type digestRepository struct {
+	db DB
+}
+
+// NewDigestRepository creates a new activity digest repository
+func NewDigestRepository(db DB) DigestRepository {
+	return &digestRepository{db: db}
+}
+
+// CountFinishedCampaigns counts campaigns that finished sending, or failed, in [from, to)
+func (r *digestRepository) CountFinishedCampaigns(ctx context.Context, from, to time.Time) (int, int, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'sent'),
+			COUNT(*) FILTER (WHERE status = 'failed')
+		FROM campaigns
+		WHERE updated_at >= $1 AND updated_at < $2
+	`
+
+	var completed, failed int
+	if err := r.db.QueryRowContext(ctx, query, from, to).Scan(&completed, &failed); err != nil {
+		return 0, 0, fmt.Errorf("failed to count finished campaigns: %w", err)
+	}
+
+	return completed, failed, nil
+}
+
+// ListChannelActivity counts the messages sent and failed per channel in [from, to)
+func (r *digestRepository) ListChannelActivity(ctx context.Context, from, to time.Time) ([]*models.ChannelActivity, error) {
+	query := `
+		SELECT
+			c.channel,
+			COUNT(*) FILTER (WHERE m.status = 'sent'),
+			COUNT(*) FILTER (WHERE m.status = 'failed'),
+			COUNT(*) FILTER (WHERE m.status = 'sent' AND m.simulated)
+		FROM outbound_messages m
+		JOIN campaigns c ON c.id = m.campaign_id
+		WHERE m.status IN ('sent', 'failed') AND m.updated_at >= $1 AND m.updated_at < $2
+		GROUP BY c.channel
+		ORDER BY c.channel
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list channel activity: %w", err)
+	}
+	defer rows.Close()
+
+	activity := []*models.ChannelActivity{}
+	for rows.Next() {
+		channel := &models.ChannelActivity{}
+		if err := rows.Scan(&channel.Channel, &channel.Sent, &channel.Failed, &channel.Simulated); err != nil {
+			return nil, fmt.Errorf("failed to scan channel activity: %w", err)
+		}
+		activity = append(activity, channel)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating channel activity: %w", err)
+	}
+
+	return activity, nil
+}
+
+// ListTopErrors returns the most common errors of messages that failed in [from, to)
+func (r *digestRepository) ListTopErrors(ctx context.Context, from, to time.Time, limit int) ([]*models.ErrorCount, error) {
+	query := `
+		SELECT last_error, COUNT(*)
+		FROM outbound_messages
+		WHERE status = 'failed' AND last_error IS NOT NULL
+			AND updated_at >= $1 AND updated_at < $2
+		GROUP BY last_error
+		ORDER BY COUNT(*) DESC, last_error
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list top errors: %w", err)
+	}
+	defer rows.Close()
+
+	errorCounts := []*models.ErrorCount{}
+	for rows.Next() {
+		errorCount := &models.ErrorCount{}
+		if err := rows.Scan(&errorCount.Error, &errorCount.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan error count: %w", err)
+		}
+		errorCounts = append(errorCounts, errorCount)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating error counts: %w", err)
+	}
+
+	return errorCounts, nil
+}
+
+// Claim reserves the digest of a date (YYYY-MM-DD) for sending and reports whether it was claimed
+// A date already sent is never claimed again; an unsent claim older than staleAfter
+// (its sender died) is taken over
+func (r *digestRepository) Claim(ctx context.Context, date string, staleAfter time.Duration) (bool, error) {
+	query := `
+		INSERT INTO activity_digests (digest_date)
+		VALUES ($1)
+		ON CONFLICT (digest_date) DO UPDATE SET claimed_at = CURRENT_TIMESTAMP
+		WHERE activity_digests.sent_at IS NULL
+			AND activity_digests.claimed_at < CURRENT_TIMESTAMP - make_interval(secs => $2)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, date, staleAfter.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim digest: %w", err)
+	}
+
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return claimed == 1, nil
+}
+
+// MarkSent records a claimed digest as delivered with the payload sent
+func (r *digestRepository) MarkSent(ctx context.Context, date string, payload []byte) error {
+	query := `
+		UPDATE activity_digests
+		SET payload = $2, sent_at = CURRENT_TIMESTAMP
+		WHERE digest_date = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, date, payload); err != nil {
+		return fmt.Errorf("failed to mark digest sent: %w", err)
+	}
+
+	return nil
+}
+
+// Release gives up an unsent claim so the digest can be retried
+func (r *digestRepository) Release(ctx context.Context, date string) error {
+	query := `DELETE FROM activity_digests WHERE digest_date = $1 AND sent_at IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, date); err != nil {
+		return fmt.Errorf("failed to release digest: %w", err)
+	}
+
+	return nil
+}
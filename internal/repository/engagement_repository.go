@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lib/pq"
+
+	"smsleopard/internal/models"
+)
+
+type engagementRepository struct {
+	db Database
+}
+
+// NewEngagementRepository creates a new engagement repository
+func NewEngagementRepository(db Database) EngagementRepository {
+	return &engagementRepository{db: db}
+}
+
+// ListEngagementHistory retrieves the message history since since of up to limit customers
+// after afterID, in ID order; customers without messages are listed with an empty history
+// Simulated sends are left out, as from delivery stats
+func (r *engagementRepository) ListEngagementHistory(ctx context.Context, afterID, limit int, since time.Time) ([]*models.EngagementHistory, error) {
+	query := `
+		SELECT c.id,
+			ARRAY(
+				SELECT EXTRACT(EPOCH FROM m.updated_at)::float8
+				FROM outbound_messages m
+				WHERE m.customer_id = c.id AND m.status = 'sent' AND NOT m.simulated AND m.updated_at >= $3
+			),
+			(
+				SELECT COUNT(*)
+				FROM outbound_messages m
+				WHERE m.customer_id = c.id AND m.status = 'failed' AND m.updated_at >= $3
+			)
+		FROM customers c
+		WHERE c.id > $1
+		ORDER BY c.id
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list engagement history: %w", err)
+	}
+	defer rows.Close()
+
+	histories := []*models.EngagementHistory{}
+	for rows.Next() {
+		history := &models.EngagementHistory{}
+		var sentAt pq.Float64Array
+		if err := rows.Scan(&history.CustomerID, &sentAt, &history.Failed); err != nil {
+			return nil, fmt.Errorf("failed to scan engagement history: %w", err)
+		}
+
+		history.SentAt = make([]time.Time, len(sentAt))
+		for i, epoch := range sentAt {
+			seconds, fraction := math.Modf(epoch)
+			history.SentAt[i] = time.Unix(int64(seconds), int64(fraction*float64(time.Second))).UTC()
+		}
+		histories = append(histories, history)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating engagement history: %w", err)
+	}
+
+	return histories, nil
+}
+
+// StoreEngagementScores sets the engagement score of each customer in scores in one statement
+func (r *engagementRepository) StoreEngagementScores(ctx context.Context, scores map[int]float64, scoredAt time.Time) error {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(scores))
+	values := make([]float64, 0, len(scores))
+	for id, score := range scores {
+		ids = append(ids, int64(id))
+		values = append(values, score)
+	}
+
+	query := `
+		UPDATE customers c
+		SET engagement_score = s.score, engagement_scored_at = $3
+		FROM unnest($1::int[], $2::float8[]) AS s(id, score)
+		WHERE c.id = s.id
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, pq.Array(ids), pq.Array(values), scoredAt.UTC()); err != nil {
+		return fmt.Errorf("failed to store engagement scores: %w", err)
+	}
+
+	return nil
+}
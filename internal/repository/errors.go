@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/lib/pq"
+)
+
+// Sentinel errors for an outbound message or the records it references being gone
+// They are permanent conditions, unlike connection or query errors
+var (
+	ErrMessageNotFound  = errors.New("message not found")
+	ErrCampaignNotFound = errors.New("campaign not found")
+	ErrCustomerNotFound = errors.New("customer not found")
+)
+
+// Sentinel errors for a cancellation whose grace period has ended (ErrCancelFinal, so it can
+// no longer be undone) or has not (ErrCancelNotDue, so it cannot be finalized yet)
+var (
+	ErrCancelFinal  = errors.New("cancellation grace period has ended")
+	ErrCancelNotDue = errors.New("campaign is not awaiting a due cancellation")
+)
+
+// ErrWorkerNotFound is returned for a worker identity that has neither reported itself alive
+// nor claimed a message
+var ErrWorkerNotFound = errors.New("worker not found")
+
+// ErrAPIKeyNotFound is returned for an API key ID that was never issued
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// ErrSettingNotFound is returned for a setting that has never been stored
+var ErrSettingNotFound = errors.New("setting not found")
+
+// ErrRecurrenceNotFound is returned for a campaign that was never made recurring
+var ErrRecurrenceNotFound = errors.New("recurrence not found")
+
+// ErrHasDependents is matched by a delete blocked by messages that reference the record
+var ErrHasDependents = errors.New("record has dependent messages")
+
+// foreignKeyViolation is the Postgres error code for a restricted delete
+const foreignKeyViolation = "23503"
+
+// HasDependentsError reports how many messages block a campaign or customer delete
+type HasDependentsError struct {
+	Resource string
+	Messages int
+}
+
+func (e *HasDependentsError) Error() string {
+	return fmt.Sprintf("%s has %d messages", e.Resource, e.Messages)
+}
+
+// Is makes errors.Is(err, ErrHasDependents) match
+func (e *HasDependentsError) Is(target error) bool {
+	return target == ErrHasDependents
+}
+
+// ErrTransient is matched by database errors expected to clear on their own within seconds,
+// such as those of a managed Postgres failing over; the statement can be run again
+var ErrTransient = errors.New("transient database error")
+
+// transientCodes are the Postgres error codes of a failover or restart, by condition name
+// During a failover the old primary briefly accepts connections read-only, then shuts down
+var transientCodes = map[pq.ErrorCode]string{
+	"25006": "read_only_sql_transaction",
+	"57P01": "admin_shutdown",
+	"57P02": "crash_shutdown",
+	"57P03": "cannot_connect_now",
+	"08000": "connection_exception",
+	"08001": "sqlclient_unable_to_establish_sqlconnection",
+	"08003": "connection_does_not_exist",
+	"08004": "sqlserver_rejected_establishment_of_sqlconnection",
+	"08006": "connection_failure",
+}
+
+// unappliedConditions are the transient conditions raised before a statement ran, so running
+// it again cannot apply it twice: the server refused the connection or the write, or the
+// driver reported the connection bad before using it. A shutdown or a connection lost
+// mid-statement leaves unknown whether the statement was applied, so it is not among them
+var unappliedConditions = map[string]bool{
+	"read_only_sql_transaction":                         true,
+	"cannot_connect_now":                                true,
+	"sqlclient_unable_to_establish_sqlconnection":       true,
+	"sqlserver_rejected_establishment_of_sqlconnection": true,
+	"connection_refused":                                true,
+	"bad_connection":                                    true,
+}
+
+// TransientError is a database error expected to clear once a failover completes
+type TransientError struct {
+	Condition string
+	Err       error
+}
+
+func (e *TransientError) Error() string {
+	return fmt.Sprintf("%s (%s)", e.Err.Error(), e.Condition)
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrTransient) match
+func (e *TransientError) Is(target error) bool {
+	return target == ErrTransient
+}
+
+// IsTransient reports whether err is a database error expected to clear on its own
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrTransient) || transientCondition(err) != ""
+}
+
+// notApplied reports whether err is a transient error raised before its statement ran
+func notApplied(err error) bool {
+	var transientErr *TransientError
+	if errors.As(err, &transientErr) {
+		return unappliedConditions[transientErr.Condition]
+	}
+	return unappliedConditions[transientCondition(err)]
+}
+
+// classifyError wraps an error of the transient family in a *TransientError so callers can
+// match ErrTransient; any other error is returned unchanged
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, ErrTransient) {
+		return err
+	}
+	if condition := transientCondition(err); condition != "" {
+		return &TransientError{Condition: condition, Err: err}
+	}
+	return err
+}
+
+// transientCondition names the failover condition behind err, or returns "" for any other error
+func transientCondition(err error) string {
+	var pqErr *pq.Error
+	switch {
+	case errors.As(err, &pqErr):
+		return transientCodes[pqErr.Code]
+	case errors.Is(err, driver.ErrBadConn):
+		return "bad_connection"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "connection_reset"
+	default:
+		return ""
+	}
+}
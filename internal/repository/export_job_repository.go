@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"smsleopard/internal/models"
+)
+
+type exportJobRepository struct {
+	db DB
+}
+
+// NewExportJobRepository creates a new export job repository
+func NewExportJobRepository(db DB) ExportJobRepository {
+	return &exportJobRepository{db: db}
+}
+
+// exportJobColumns is the column list scanned by scanExportJob
+const exportJobColumns = `
+	id, campaign_id, status, total_rows, rows_written, file_name, size_bytes, error,
+	requested_by, created_at, updated_at, completed_at, expires_at
+`
+
+// Create creates a pending export job
+func (r *exportJobRepository) Create(ctx context.Context, job *models.ExportJob) error {
+	query := `
+		INSERT INTO export_jobs (campaign_id, requested_by)
+		VALUES ($1, $2)
+		RETURNING ` + exportJobColumns
+
+	row := r.db.QueryRowContext(ctx, query, job.CampaignID, job.RequestedBy)
+	if err := scanExportJob(row, job); err != nil {
+		return fmt.Errorf("failed to create export job: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an export job by ID
+func (r *exportJobRepository) GetByID(ctx context.Context, id int) (*models.ExportJob, error) {
+	query := `SELECT ` + exportJobColumns + ` FROM export_jobs WHERE id = $1`
+
+	job := &models.ExportJob{}
+	err := scanExportJob(r.db.QueryRowContext(ctx, query, id), job)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("export job not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ClaimNext marks the oldest pending job running, with the campaign's message count as its
+// total, and returns it; nil when there is none
+// A running job whose progress has not moved for staleAfter (its generator died) is restarted
+func (r *exportJobRepository) ClaimNext(ctx context.Context, staleAfter time.Duration) (*models.ExportJob, error) {
+	query := `
+		UPDATE export_jobs j
+		SET status = 'running',
+			rows_written = 0,
+			total_rows = (SELECT COUNT(*) FROM outbound_messages m WHERE m.campaign_id = j.campaign_id),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE j.id = (
+			SELECT id FROM export_jobs
+			WHERE status = 'pending'
+				OR (status = 'running' AND updated_at < CURRENT_TIMESTAMP - make_interval(secs => $1))
+			ORDER BY created_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportJobColumns
+
+	job := &models.ExportJob{}
+	err := scanExportJob(r.db.QueryRowContext(ctx, query, staleAfter.Seconds()), job)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim export job: %w", err)
+	}
+
+	return job, nil
+}
+
+// UpdateProgress records how many rows a running job has written
+func (r *exportJobRepository) UpdateProgress(ctx context.Context, id, rowsWritten int) error {
+	query := `
+		UPDATE export_jobs
+		SET rows_written = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'running'
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, rowsWritten); err != nil {
+		return fmt.Errorf("failed to update export job progress: %w", err)
+	}
+
+	return nil
+}
+
+// Complete records a job's finished file and when it expires
+func (r *exportJobRepository) Complete(ctx context.Context, id int, fileName string, sizeBytes int64, expiresAt time.Time) error {
+	query := `
+		UPDATE export_jobs
+		SET status = 'completed', rows_written = COALESCE(total_rows, rows_written),
+			file_name = $2, size_bytes = $3, expires_at = $4,
+			completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, fileName, sizeBytes, expiresAt); err != nil {
+		return fmt.Errorf("failed to complete export job: %w", err)
+	}
+
+	return nil
+}
+
+// Fail records why a job could not be generated
+func (r *exportJobRepository) Fail(ctx context.Context, id int, message string) error {
+	query := `
+		UPDATE export_jobs
+		SET status = 'failed', error = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, message); err != nil {
+		return fmt.Errorf("failed to fail export job: %w", err)
+	}
+
+	return nil
+}
+
+// ListExpired lists completed jobs whose file is past its expiry at now and not yet deleted
+func (r *exportJobRepository) ListExpired(ctx context.Context, now time.Time) ([]*models.ExportJob, error) {
+	query := `
+		SELECT ` + exportJobColumns + `
+		FROM export_jobs
+		WHERE file_name IS NOT NULL AND expires_at <= $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.ExportJob{}
+	for rows.Next() {
+		job := &models.ExportJob{}
+		if err := scanExportJob(rows, job); err != nil {
+			return nil, fmt.Errorf("failed to scan export job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expired export jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ClearFile forgets a job's file once it has been deleted
+func (r *exportJobRepository) ClearFile(ctx context.Context, id int) error {
+	query := `UPDATE export_jobs SET file_name = NULL WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to clear export job file: %w", err)
+	}
+
+	return nil
+}
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanExportJob scans the exportJobColumns of a row into job
+func scanExportJob(row rowScanner, job *models.ExportJob) error {
+	return row.Scan(
+		&job.ID,
+		&job.CampaignID,
+		&job.Status,
+		&job.TotalRows,
+		&job.RowsWritten,
+		&job.FileName,
+		&job.SizeBytes,
+		&job.Error,
+		&job.RequestedBy,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.CompletedAt,
+		&job.ExpiresAt,
+	)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"smsleopard/internal/metrics"
+)
+
+// DefaultSlowQueryThreshold is used when no threshold is configured
+const DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+var (
+	stringLiteralPattern = regexp.MustCompile(`'(?:[^']|'')*'`)
+	tableNamePattern     = regexp.MustCompile(`(?i)\b(?:from|into|update|join)\s+([a-z_][a-z0-9_]*)`)
+)
+
+// InstrumentedDB wraps *sql.DB, timing every statement and logging slow ones
+type InstrumentedDB struct {
+	db            *sql.DB
+	slowThreshold time.Duration
+}
+
+// NewInstrumentedDB creates a new instrumented database wrapper
+func NewInstrumentedDB(db *sql.DB, slowThreshold time.Duration) *InstrumentedDB {
+	if slowThreshold <= 0 {
+		slowThreshold = DefaultSlowQueryThreshold
+	}
+
+	return &InstrumentedDB{
+		db:            db,
+		slowThreshold: slowThreshold,
+	}
+}
+
+// ExecContext executes a statement and records its duration
+func (i *InstrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := i.db.ExecContext(ctx, query, args...)
+	i.observe(query, len(args), time.Since(start))
+	return result, err
+}
+
+// QueryContext runs a query and records its duration
+// The duration covers the round trip to the first row, not row iteration
+func (i *InstrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := i.db.QueryContext(ctx, query, args...)
+	i.observe(query, len(args), time.Since(start))
+	return rows, err
+}
+
+// QueryRowContext runs a single row query and records its duration
+func (i *InstrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := i.db.QueryRowContext(ctx, query, args...)
+	i.observe(query, len(args), time.Since(start))
+	return row
+}
+
+// BeginTx starts a transaction on the underlying database
+// Statements inside the transaction are not instrumented
+func (i *InstrumentedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return i.db.BeginTx(ctx, opts)
+}
+
+// observe records the statement duration and logs it when over the threshold
+func (i *InstrumentedDB) observe(query string, argCount int, elapsed time.Duration) {
+	normalized := NormalizeQuery(query)
+	metrics.QueryDuration.WithLabelValues(QueryFingerprint(normalized)).Observe(elapsed.Seconds())
+
+	if elapsed >= i.slowThreshold {
+		log.Printf("Slow query (%dms, %d args): %s", elapsed.Milliseconds(), argCount, normalized)
+	}
+}
+
+// NormalizeQuery collapses whitespace and redacts string literals
+// Bound parameters are never part of the query text so they are not logged
+func NormalizeQuery(query string) string {
+	redacted := stringLiteralPattern.ReplaceAllString(query, "'?'")
+	return strings.Join(strings.Fields(redacted), " ")
+}
+
+// QueryFingerprint returns a short, stable label for a normalized query
+// It has the form verb_table_hash, e.g. select_campaigns_1a2b3c
+func QueryFingerprint(normalized string) string {
+	verb := "query"
+	if fields := strings.Fields(normalized); len(fields) > 0 {
+		verb = strings.ToLower(fields[0])
+	}
+
+	table := "none"
+	if match := tableNamePattern.FindStringSubmatch(normalized); match != nil {
+		table = strings.ToLower(match[1])
+	}
+
+	sum := sha1.Sum([]byte(normalized))
+	return verb + "_" + table + "_" + hex.EncodeToString(sum[:3])
+}
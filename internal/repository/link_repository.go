@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"smsleopard/internal/models"
+)
+
+type linkRepository struct {
+	db DB
+}
+
+// NewLinkRepository creates a new tracked link repository
+func NewLinkRepository(db DB) LinkRepository {
+	return &linkRepository{db: db}
+}
+
+// EnsureLinks stores a message's links, all of the same message; a position the message
+// already has keeps its stored token and URL, which are copied into the link, so a retried
+// message rewrites to the same redirects
+func (r *linkRepository) EnsureLinks(ctx context.Context, links []*models.TrackedLink) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(links))
+	args := make([]interface{}, 0, len(links)*4)
+	for i, link := range links {
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d)", i*4+1, i*4+2, i*4+3, i*4+4))
+		args = append(args, link.MessageID, link.Position, link.Token, link.URL)
+	}
+
+	insert := `
+		INSERT INTO tracked_links (message_id, position, token, url)
+		VALUES ` + strings.Join(values, ", ") + `
+		ON CONFLICT (message_id, position) DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, insert, args...); err != nil {
+		return fmt.Errorf("failed to store tracked links: %w", err)
+	}
+
+	query := `
+		SELECT id, position, token, url, created_at
+		FROM tracked_links
+		WHERE message_id = $1
+	`
+	rows, err := r.db.QueryContext(ctx, query, links[0].MessageID)
+	if err != nil {
+		return fmt.Errorf("failed to get tracked links: %w", err)
+	}
+	defer rows.Close()
+
+	byPosition := make(map[int]*models.TrackedLink, len(links))
+	for _, link := range links {
+		byPosition[link.Position] = link
+	}
+	for rows.Next() {
+		stored := &models.TrackedLink{}
+		if err := rows.Scan(&stored.ID, &stored.Position, &stored.Token, &stored.URL, &stored.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan tracked link: %w", err)
+		}
+		if link, ok := byPosition[stored.Position]; ok {
+			link.ID, link.Token, link.URL, link.CreatedAt = stored.ID, stored.Token, stored.URL, stored.CreatedAt
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating tracked links: %w", err)
+	}
+
+	return nil
+}
+
+// GetByToken retrieves a tracked link with its message's campaign, or nil when no link has the token
+func (r *linkRepository) GetByToken(ctx context.Context, token string) (*models.TrackedLink, error) {
+	query := `
+		SELECT l.id, l.message_id, m.campaign_id, l.position, l.token, l.url, l.created_at
+		FROM tracked_links l
+		JOIN outbound_messages m ON m.id = l.message_id
+		WHERE l.token = $1
+	`
+
+	link := &models.TrackedLink{}
+	err := r.db.QueryRowContext(ctx, query, token).Scan(
+		&link.ID,
+		&link.MessageID,
+		&link.CampaignID,
+		&link.Position,
+		&link.Token,
+		&link.URL,
+		&link.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracked link: %w", err)
+	}
+
+	return link, nil
+}
+
+// RecordClick stores a visit to a tracked link
+func (r *linkRepository) RecordClick(ctx context.Context, click *models.LinkClick) error {
+	query := `
+		INSERT INTO link_clicks (link_id, message_id, campaign_id, user_agent)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, clicked_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, click.LinkID, click.MessageID, click.CampaignID, click.UserAgent).
+		Scan(&click.ID, &click.ClickedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record link click: %w", err)
+	}
+
+	return nil
+}
@@ -4,35 +4,73 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"smsleopard/internal/crypto"
 	"smsleopard/internal/models"
+
+	"github.com/lib/pq"
 )
 
 type messageRepository struct {
-	db *sql.DB
+	db       Database
+	readerDB Database        // Read replica for reporting queries (nil uses db)
+	keyring  *crypto.Keyring // Encrypts rendered content at rest (nil stores plaintext)
 }
 
 // NewMessageRepository creates a new message repository
-func NewMessageRepository(db *sql.DB) MessageRepository {
+func NewMessageRepository(db Database) MessageRepository {
 	return &messageRepository{db: db}
 }
 
+// NewMessageRepositoryWithReader creates a message repository that sends
+// reporting reads to a read replica
+// A nil reader falls back to the primary
+func NewMessageRepositoryWithReader(db, readerDB Database) MessageRepository {
+	return &messageRepository{db: db, readerDB: readerDB}
+}
+
+// NewEncryptedMessageRepository creates a message repository that encrypts
+// rendered content on write and decrypts it on read
+// A nil keyring stores plaintext; a nil reader falls back to the primary
+func NewEncryptedMessageRepository(db, readerDB Database, keyring *crypto.Keyring) MessageRepository {
+	return &messageRepository{db: db, readerDB: readerDB, keyring: keyring}
+}
+
+// reader returns the database for replica-safe reads
+func (r *messageRepository) reader() Database {
+	if r.readerDB != nil {
+		return r.readerDB
+	}
+	return r.db
+}
+
 // Create creates a new outbound message
 func (r *messageRepository) Create(ctx context.Context, message *models.OutboundMessage) error {
 	query := `
-		INSERT INTO outbound_messages (campaign_id, customer_id, status, rendered_content)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO outbound_messages (campaign_id, customer_id, status, rendered_content, content_fingerprint)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, updated_at
 	`
 
-	err := r.db.QueryRowContext(
-		ctx,
-		query,
-		message.CampaignID,
-		message.CustomerID,
-		message.Status,
-		message.RenderedContent,
-	).Scan(&message.ID, &message.CreatedAt, &message.UpdatedAt)
+	content, err := r.sealContent(message.RenderedContent)
+	if err != nil {
+		return err
+	}
+
+	err = retryTransient(ctx, func() error {
+		return r.db.QueryRowContext(
+			ctx,
+			query,
+			message.CampaignID,
+			message.CustomerID,
+			message.Status,
+			content,
+			message.ContentFingerprint,
+		).Scan(&message.ID, &message.CreatedAt, &message.UpdatedAt)
+	})
 
 	if err != nil {
 		return fmt.Errorf("failed to create message: %w", err)
@@ -42,20 +80,47 @@ func (r *messageRepository) Create(ctx context.Context, message *models.Outbound
 }
 
 // CreateBatch creates multiple outbound messages
+// The batch is one transaction, which is run again whole when a failover refuses it
 func (r *messageRepository) CreateBatch(ctx context.Context, messages []*models.OutboundMessage) error {
 	if len(messages) == 0 {
 		return nil
 	}
 
+	return retryTransient(ctx, func() error {
+		return r.createBatch(ctx, messages)
+	})
+}
+
+// createBatch creates messages in one transaction
+func (r *messageRepository) createBatch(ctx context.Context, messages []*models.OutboundMessage) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	if err := r.CreateBatchTx(ctx, tx, messages); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CreateBatchTx creates messages within tx, so they are only kept if the caller commits
+// The parts of a multi-part send follow each other, part 1 first; each part is given the ID of
+// its part 1 as group ID
+func (r *messageRepository) CreateBatchTx(ctx context.Context, tx *sql.Tx, messages []*models.OutboundMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO outbound_messages (campaign_id, customer_id, status, rendered_content)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO outbound_messages (campaign_id, customer_id, status, rendered_content, content_fingerprint)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, updated_at
 	`)
 	if err != nil {
@@ -64,12 +129,18 @@ func (r *messageRepository) CreateBatch(ctx context.Context, messages []*models.
 	defer stmt.Close()
 
 	for _, message := range messages {
-		err := stmt.QueryRowContext(
+		content, err := r.sealContent(message.RenderedContent)
+		if err != nil {
+			return err
+		}
+
+		err = stmt.QueryRowContext(
 			ctx,
 			message.CampaignID,
 			message.CustomerID,
 			message.Status,
-			message.RenderedContent,
+			content,
+			message.ContentFingerprint,
 		).Scan(&message.ID, &message.CreatedAt, &message.UpdatedAt)
 
 		if err != nil {
@@ -77,8 +148,40 @@ func (r *messageRepository) CreateBatch(ctx context.Context, messages []*models.
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	return groupParts(ctx, tx, messages)
+}
+
+// groupParts records the group and position of the created messages that are parts of a
+// multi-part send, in one statement; batches without parts write nothing
+func groupParts(ctx context.Context, tx *sql.Tx, messages []*models.OutboundMessage) error {
+	var ids, groupIDs, parts, partCounts []int64
+	var groupID int
+	for _, message := range messages {
+		if message.PartCount <= 1 {
+			continue
+		}
+		if message.Part <= 1 {
+			groupID = message.ID
+		}
+		group := groupID
+		message.GroupID = &group
+		ids = append(ids, int64(message.ID))
+		groupIDs = append(groupIDs, int64(groupID))
+		parts = append(parts, int64(message.Part))
+		partCounts = append(partCounts, int64(message.PartCount))
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE outbound_messages m
+		SET group_id = parts.group_id, part = parts.part, part_count = parts.part_count
+		FROM unnest($1::int[], $2::int[], $3::int[], $4::int[]) AS parts(id, group_id, part, part_count)
+		WHERE m.id = parts.id
+	`
+	if _, err := tx.ExecContext(ctx, query, pq.Array(ids), pq.Array(groupIDs), pq.Array(parts), pq.Array(partCounts)); err != nil {
+		return fmt.Errorf("failed to group message parts: %w", err)
 	}
 
 	return nil
@@ -87,7 +190,7 @@ func (r *messageRepository) CreateBatch(ctx context.Context, messages []*models.
 // GetByID retrieves a message by ID
 func (r *messageRepository) GetByID(ctx context.Context, id int) (*models.OutboundMessage, error) {
 	query := `
-		SELECT id, campaign_id, customer_id, status, rendered_content, last_error, retry_count, created_at, updated_at
+		SELECT id, campaign_id, customer_id, status, rendered_content, last_error, retry_count, published_at, created_at, updated_at
 		FROM outbound_messages
 		WHERE id = $1
 	`
@@ -101,27 +204,34 @@ func (r *messageRepository) GetByID(ctx context.Context, id int) (*models.Outbou
 		&message.RenderedContent,
 		&message.LastError,
 		&message.RetryCount,
+		&message.PublishedAt,
 		&message.CreatedAt,
 		&message.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("message not found")
+		return nil, ErrMessageNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get message: %w", err)
 	}
+	if err := r.openContent(message); err != nil {
+		return nil, err
+	}
 
 	return message, nil
 }
 
 // GetWithDetails retrieves a message with campaign and customer details
+// Returns ErrMessageNotFound, ErrCampaignNotFound or ErrCustomerNotFound when a row is missing
 func (r *messageRepository) GetWithDetails(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
 	query := `
 		SELECT 
-			m.id, m.campaign_id, m.customer_id, m.status, m.rendered_content, m.last_error, m.retry_count, m.created_at, m.updated_at,
-			c.id, c.name, c.channel, c.status, c.base_template, c.scheduled_at, c.created_at, c.updated_at,
-			cu.id, cu.phone, cu.first_name, cu.last_name, cu.location, cu.preferred_product, cu.created_at
+			m.id, m.campaign_id, m.customer_id, m.status, m.rendered_content, m.last_error, m.retry_count, m.published_at, m.created_at, m.updated_at,
+			m.group_id, m.part, m.part_count, m.resent_from_message_id,
+			c.id, c.name, c.channel, c.status, c.base_template, c.scheduled_at, c.created_at, c.updated_at, c.track_links, c.template_syntax, c.demo_failure_rate,
+			c.part_delimiter,
+			cu.id, cu.phone, cu.first_name, cu.last_name, cu.location, cu.preferred_product, cu.contact_window_start, cu.contact_window_end, cu.created_at, cu.blocked
 		FROM outbound_messages m
 		JOIN campaigns c ON m.campaign_id = c.id
 		JOIN customers cu ON m.customer_id = cu.id
@@ -137,8 +247,13 @@ func (r *messageRepository) GetWithDetails(ctx context.Context, id int) (*models
 		&result.RenderedContent,
 		&result.LastError,
 		&result.RetryCount,
+		&result.PublishedAt,
 		&result.CreatedAt,
 		&result.UpdatedAt,
+		&result.GroupID,
+		&result.Part,
+		&result.PartCount,
+		&result.ResentFromMessageID,
 		&result.Campaign.ID,
 		&result.Campaign.Name,
 		&result.Campaign.Channel,
@@ -147,25 +262,65 @@ func (r *messageRepository) GetWithDetails(ctx context.Context, id int) (*models
 		&result.Campaign.ScheduledAt,
 		&result.Campaign.CreatedAt,
 		&result.Campaign.UpdatedAt,
+		&result.Campaign.TrackLinks,
+		&result.Campaign.TemplateSyntax,
+		&result.Campaign.DemoFailureRate,
+		&result.Campaign.PartDelimiter,
 		&result.Customer.ID,
 		&result.Customer.Phone,
 		&result.Customer.FirstName,
 		&result.Customer.LastName,
 		&result.Customer.Location,
 		&result.Customer.PreferredProduct,
+		&result.Customer.ContactWindowStart,
+		&result.Customer.ContactWindowEnd,
 		&result.Customer.CreatedAt,
+		&result.Customer.Blocked,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("message not found")
+		return nil, r.missingReference(ctx, id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get message with details: %w", err)
 	}
+	if err := r.openContent(&result.OutboundMessage); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
 
+// missingReference works out which row is missing when the joined fetch finds nothing
+func (r *messageRepository) missingReference(ctx context.Context, id int) error {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM campaigns WHERE id = m.campaign_id),
+			EXISTS (SELECT 1 FROM customers WHERE id = m.customer_id)
+		FROM outbound_messages m
+		WHERE m.id = $1
+	`
+
+	var campaignExists, customerExists bool
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&campaignExists, &customerExists)
+	if err == sql.ErrNoRows {
+		return ErrMessageNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check message references: %w", err)
+	}
+
+	if !campaignExists {
+		return ErrCampaignNotFound
+	}
+	if !customerExists {
+		return ErrCustomerNotFound
+	}
+
+	// Rows reappeared between the two queries; let the caller retry
+	return fmt.Errorf("failed to get message with details: references changed during fetch")
+}
+
 // UpdateStatus updates message status and error
 func (r *messageRepository) UpdateStatus(ctx context.Context, id int, status models.MessageStatus, lastError *string) error {
 	query := `
@@ -174,7 +329,7 @@ func (r *messageRepository) UpdateStatus(ctx context.Context, id int, status mod
 		WHERE id = $3
 	`
 
-	result, err := r.db.ExecContext(ctx, query, status, lastError, id)
+	result, err := ExecWithRetry(ctx, r.db, query, status, lastError, id)
 	if err != nil {
 		return fmt.Errorf("failed to update message status: %w", err)
 	}
@@ -185,23 +340,313 @@ func (r *messageRepository) UpdateStatus(ctx context.Context, id int, status mod
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("message not found")
+		return ErrMessageNotFound
 	}
 
 	return nil
 }
 
-// GetPendingMessages retrieves pending messages for processing
-func (r *messageRepository) GetPendingMessages(ctx context.Context, limit int) ([]*models.OutboundMessage, error) {
+// MarkPublished records that the jobs for the given messages were published to the queue
+func (r *messageRepository) MarkPublished(ctx context.Context, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE outbound_messages
+		SET published_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1)
+	`
+
+	if _, err := ExecWithRetry(ctx, r.db, query, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to mark messages published: %w", err)
+	}
+
+	return nil
+}
+
+// ReserveAttempt counts a send attempt against the customer's budget for day
+// It returns false without counting when the customer already has budget attempts that day
+func (r *messageRepository) ReserveAttempt(ctx context.Context, customerID int, day time.Time, budget int) (bool, error) {
+	query := `
+		INSERT INTO customer_daily_attempts (customer_id, day, attempts)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (customer_id, day) DO UPDATE
+		SET attempts = customer_daily_attempts.attempts + 1
+		WHERE customer_daily_attempts.attempts < $3
+		RETURNING attempts
+	`
+
+	var attempts int
+	err := r.db.QueryRowContext(ctx, query, customerID, day.Format("2006-01-02"), budget).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve send attempt: %w", err)
+	}
+
+	return true, nil
+}
+
+// DeferUntil holds a message back until the given time, recording why in last_error
+func (r *messageRepository) DeferUntil(ctx context.Context, id int, until time.Time, reason string) error {
+	query := `
+		UPDATE outbound_messages
+		SET deliver_after = $2, last_error = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	if _, err := ExecWithRetry(ctx, r.db, query, id, until.UTC(), reason); err != nil {
+		return fmt.Errorf("failed to defer message: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueDeferred clears deliver_after on up to limit messages whose time has come and returns them
+// Claimed messages are marked published; concurrent callers never claim the same message
+func (r *messageRepository) ClaimDueDeferred(ctx context.Context, now time.Time, limit int) ([]*models.OutboundMessage, error) {
+	query := `
+		UPDATE outbound_messages
+		SET deliver_after = NULL, published_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT id FROM outbound_messages
+			WHERE deliver_after <= $1
+			ORDER BY deliver_after, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, campaign_id, customer_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deferred messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*models.OutboundMessage{}
+	for rows.Next() {
+		message := &models.OutboundMessage{}
+		if err := rows.Scan(&message.ID, &message.CampaignID, &message.CustomerID); err != nil {
+			return nil, fmt.Errorf("failed to scan deferred message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deferred messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// dispatchSource returns the FROM and ORDER BY of a query picking messages (m) matching where
+// to dispatch, in the order's sequence; campaigns are joined as c
+// The fair policy ranks each campaign's messages by age and takes them rank by rank, at most
+// the fair share per campaign, with the share as parameter shareParam. The ranking is a
+// subquery, so the outer query can still lock the messages it picks
+func dispatchSource(order models.DispatchOrder, where string, shareParam int) (from, orderBy string, fair bool) {
+	switch order.Policy {
+	case models.DispatchFair:
+		from = fmt.Sprintf(`outbound_messages m
+			JOIN (
+				SELECT m.id, ROW_NUMBER() OVER (PARTITION BY m.campaign_id ORDER BY m.created_at, m.id) as campaign_rank
+				FROM outbound_messages m
+				JOIN campaigns c ON c.id = m.campaign_id
+				WHERE %s
+			) ranked ON ranked.id = m.id
+			WHERE ranked.campaign_rank <= $%d`, where, shareParam)
+		return from, "ranked.campaign_rank, m.created_at, m.id", true
+	case models.DispatchNewestCampaignFirst:
+		from = `outbound_messages m
+			JOIN campaigns c ON c.id = m.campaign_id
+			WHERE ` + where
+		return from, "c.created_at DESC, c.id DESC, m.created_at, m.id", false
+	default:
+		from = `outbound_messages m
+			JOIN campaigns c ON c.id = m.campaign_id
+			WHERE ` + where
+		return from, "m.created_at, m.id", false
+	}
+}
+
+// ClaimUnpublished marks up to limit pending messages that were never published, created before
+// createdBefore, as published and returns them for publishing, picked in the dispatch order
+// Only campaigns still sending or sending a canary are reconciled, and deferred messages are left to ClaimDueDeferred;
+// a later part of a multi-part send waits until the part before it is sent;
+// concurrent callers never claim the same message
+func (r *messageRepository) ClaimUnpublished(ctx context.Context, createdBefore time.Time, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error) {
+	from, orderBy, fair := dispatchSource(order, `m.status = 'pending'
+				AND m.published_at IS NULL
+				AND m.deliver_after IS NULL
+				AND m.created_at < $1
+				AND c.status IN ('sending', 'canary')
+				AND (m.group_id IS NULL OR m.part = 1 OR EXISTS (
+					SELECT 1 FROM outbound_messages prev
+					WHERE prev.group_id = m.group_id AND prev.part = m.part - 1 AND prev.status = 'sent'
+				))`, 3)
+	query := `
+		UPDATE outbound_messages
+		SET published_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT m.id FROM ` + from + `
+			ORDER BY ` + orderBy + `
+			LIMIT $2
+			FOR UPDATE OF m SKIP LOCKED
+		)
+		RETURNING id, campaign_id, customer_id
+	`
+
+	args := []interface{}{createdBefore.UTC(), limit}
+	if fair {
+		args = append(args, order.FairShare)
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim unpublished messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*models.OutboundMessage{}
+	for rows.Next() {
+		message := &models.OutboundMessage{}
+		if err := rows.Scan(&message.ID, &message.CampaignID, &message.CustomerID); err != nil {
+			return nil, fmt.Errorf("failed to scan unpublished message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating unpublished messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// ClaimNextPart marks the given part of a multi-part send as published and returns it for
+// publishing, or nil when it is no longer pending or was already published
+// Concurrent callers, including ClaimUnpublished, never claim the same part
+func (r *messageRepository) ClaimNextPart(ctx context.Context, groupID, part int) (*models.OutboundMessage, error) {
+	query := `
+		UPDATE outbound_messages
+		SET published_at = CURRENT_TIMESTAMP
+		WHERE group_id = $1
+			AND part = $2
+			AND status = 'pending'
+			AND published_at IS NULL
+			AND deliver_after IS NULL
+		RETURNING id, campaign_id, customer_id
+	`
+
+	message := &models.OutboundMessage{GroupID: &groupID, Part: part}
+	err := retryTransient(ctx, func() error {
+		return r.db.QueryRowContext(ctx, query, groupID, part).Scan(&message.ID, &message.CampaignID, &message.CustomerID)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim next message part: %w", err)
+	}
+
+	return message, nil
+}
+
+// CreateResend stores a pending copy of a message, to the same customer for the same campaign,
+// resent from it; the original is left as it is
+// A verbatim copy keeps the original's stored content, otherwise the worker renders it again;
+// fingerprint replaces the original's content fingerprint when not nil
+// Returns ErrMessageNotFound when the original does not exist
+func (r *messageRepository) CreateResend(ctx context.Context, originalID int, verbatim bool, fingerprint *string) (*models.OutboundMessage, error) {
 	query := `
-		SELECT id, campaign_id, customer_id, status, rendered_content, last_error, retry_count, created_at, updated_at
+		INSERT INTO outbound_messages (campaign_id, customer_id, status, rendered_content, content_fingerprint, part, part_count, resent_from_message_id)
+		SELECT campaign_id, customer_id, 'pending', CASE WHEN $2 THEN rendered_content END, COALESCE($3, content_fingerprint), part, part_count, id
 		FROM outbound_messages
-		WHERE status = 'pending' AND retry_count < 3
-		ORDER BY created_at ASC
+		WHERE id = $1
+		RETURNING id, campaign_id, customer_id, status, rendered_content, retry_count, part, part_count, resent_from_message_id, created_at, updated_at
+	`
+
+	message := &models.OutboundMessage{}
+	err := retryTransient(ctx, func() error {
+		return r.db.QueryRowContext(ctx, query, originalID, verbatim, fingerprint).Scan(
+			&message.ID,
+			&message.CampaignID,
+			&message.CustomerID,
+			&message.Status,
+			&message.RenderedContent,
+			&message.RetryCount,
+			&message.Part,
+			&message.PartCount,
+			&message.ResentFromMessageID,
+			&message.CreatedAt,
+			&message.UpdatedAt,
+		)
+	})
+	if err == sql.ErrNoRows {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resend: %w", err)
+	}
+	if err := r.openContent(message); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// Hold defers a message until its campaign is resumed; ClaimDueDeferred never picks it up
+func (r *messageRepository) Hold(ctx context.Context, id int, reason string) error {
+	query := `
+		UPDATE outbound_messages
+		SET deliver_after = 'infinity', last_error = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	if _, err := ExecWithRetry(ctx, r.db, query, id, reason); err != nil {
+		return fmt.Errorf("failed to hold message: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseHeld makes a campaign's held messages due now, so the deferred requeue publishes them
+// Returns how many were released
+func (r *messageRepository) ReleaseHeld(ctx context.Context, campaignID int) (int, error) {
+	query := `
+		UPDATE outbound_messages
+		SET deliver_after = CURRENT_TIMESTAMP
+		WHERE campaign_id = $1 AND deliver_after = 'infinity'
+	`
+
+	result, err := ExecWithRetry(ctx, r.db, query, campaignID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release held messages: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(affected), nil
+}
+
+// GetPendingMessages retrieves pending messages for processing, in the dispatch order
+func (r *messageRepository) GetPendingMessages(ctx context.Context, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error) {
+	from, orderBy, fair := dispatchSource(order, "m.status = 'pending' AND m.retry_count < 3", 2)
+	query := `
+		SELECT m.id, m.campaign_id, m.customer_id, m.status, m.rendered_content, m.last_error, m.retry_count, m.published_at, m.created_at, m.updated_at
+		FROM ` + from + `
+		ORDER BY ` + orderBy + `
 		LIMIT $1
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit)
+	args := []interface{}{limit}
+	if fair {
+		args = append(args, order.FairShare)
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pending messages: %w", err)
 	}
@@ -218,12 +663,16 @@ func (r *messageRepository) GetPendingMessages(ctx context.Context, limit int) (
 			&message.RenderedContent,
 			&message.LastError,
 			&message.RetryCount,
+			&message.PublishedAt,
 			&message.CreatedAt,
 			&message.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
+		if err := r.openContent(message); err != nil {
+			return nil, err
+		}
 		messages = append(messages, message)
 	}
 
@@ -233,7 +682,7 @@ func (r *messageRepository) GetPendingMessages(ctx context.Context, limit int) (
 // GetByCampaignID retrieves all messages for a campaign
 func (r *messageRepository) GetByCampaignID(ctx context.Context, campaignID int) ([]*models.OutboundMessage, error) {
 	query := `
-		SELECT id, campaign_id, customer_id, status, rendered_content, last_error, retry_count, created_at, updated_at
+		SELECT id, campaign_id, customer_id, status, rendered_content, last_error, retry_count, published_at, created_at, updated_at
 		FROM outbound_messages
 		WHERE campaign_id = $1
 		ORDER BY created_at DESC
@@ -256,14 +705,738 @@ func (r *messageRepository) GetByCampaignID(ctx context.Context, campaignID int)
 			&message.RenderedContent,
 			&message.LastError,
 			&message.RetryCount,
+			&message.PublishedAt,
+			&message.CreatedAt,
+			&message.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if err := r.openContent(message); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// GetPendingByCampaignID retrieves up to limit pending messages for a campaign
+func (r *messageRepository) GetPendingByCampaignID(ctx context.Context, campaignID, limit int) ([]*models.OutboundMessage, error) {
+	query := `
+		SELECT id, campaign_id, customer_id, status, rendered_content, last_error, retry_count, published_at, created_at, updated_at
+		FROM outbound_messages
+		WHERE campaign_id = $1 AND status = 'pending'
+		ORDER BY id ASC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, campaignID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending messages by campaign: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*models.OutboundMessage{}
+	for rows.Next() {
+		message := &models.OutboundMessage{}
+		err := rows.Scan(
+			&message.ID,
+			&message.CampaignID,
+			&message.CustomerID,
+			&message.Status,
+			&message.RenderedContent,
+			&message.LastError,
+			&message.RetryCount,
+			&message.PublishedAt,
 			&message.CreatedAt,
 			&message.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
+		if err := r.openContent(message); err != nil {
+			return nil, err
+		}
 		messages = append(messages, message)
 	}
 
 	return messages, nil
 }
+
+// ClearPendingRenderedContent clears rendered content of a campaign's pending messages
+// so the worker renders them again from the current template
+func (r *messageRepository) ClearPendingRenderedContent(ctx context.Context, campaignID int) (int, error) {
+	query := `
+		UPDATE outbound_messages
+		SET rendered_content = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE campaign_id = $1 AND status = 'pending'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, campaignID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear rendered content: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rows), nil
+}
+
+// GetDeliveryStatsByChannel aggregates sent/failed outcomes per channel since the given time
+// Simulated sends are left out since they never fail
+// Reads the replica
+func (r *messageRepository) GetDeliveryStatsByChannel(ctx context.Context, since time.Time) ([]*models.ChannelDeliveryStats, error) {
+	query := `
+		SELECT
+			c.channel,
+			COUNT(*) as total,
+			COUNT(*) FILTER (WHERE m.status = 'failed') as failed
+		FROM outbound_messages m
+		JOIN campaigns c ON m.campaign_id = c.id
+		WHERE m.status IN ('sent', 'failed') AND NOT m.simulated AND m.created_at >= $1
+		GROUP BY c.channel
+	`
+
+	rows, err := r.reader().QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []*models.ChannelDeliveryStats{}
+	for rows.Next() {
+		stat := &models.ChannelDeliveryStats{}
+		if err := rows.Scan(&stat.Channel, &stat.Total, &stat.Failed); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery stats: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
+// GetRetryEffectiveness counts per channel the messages sent in [from, to) and how many needed a retry
+// Simulated sends are left out since they never retry
+// Reads the replica
+func (r *messageRepository) GetRetryEffectiveness(ctx context.Context, from, to time.Time) ([]*models.ChannelRetryEffectiveness, error) {
+	query := `
+		SELECT
+			c.channel,
+			COUNT(*) as sent,
+			COUNT(*) FILTER (WHERE m.retry_count > 0) as sent_after_retry
+		FROM outbound_messages m
+		JOIN campaigns c ON m.campaign_id = c.id
+		WHERE m.status = 'sent' AND NOT m.simulated AND m.updated_at >= $1 AND m.updated_at < $2
+		GROUP BY c.channel
+		ORDER BY c.channel
+	`
+
+	rows, err := r.reader().QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retry effectiveness: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []*models.ChannelRetryEffectiveness{}
+	for rows.Next() {
+		stat := &models.ChannelRetryEffectiveness{}
+		if err := rows.Scan(&stat.Channel, &stat.Sent, &stat.SentAfterRetry); err != nil {
+			return nil, fmt.Errorf("failed to scan retry effectiveness: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
+// CountRecentRecipients counts the given customers messaged by any other campaign since the given time
+// Reads the replica
+func (r *messageRepository) CountRecentRecipients(ctx context.Context, customerIDs []int, excludeCampaignID int, since time.Time) (int, error) {
+	if len(customerIDs) == 0 {
+		return 0, nil
+	}
+
+	query := `
+		SELECT COUNT(DISTINCT customer_id)
+		FROM outbound_messages
+		WHERE customer_id = ANY($1) AND campaign_id <> $2 AND created_at >= $3
+	`
+
+	var count int
+	err := r.reader().QueryRowContext(ctx, query, pq.Array(customerIDs), excludeCampaignID, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recent recipients: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountRecentFingerprintRecipients counts the given customers sent a message with the same
+// content fingerprint by any other campaign since the given time
+// Reads the replica
+func (r *messageRepository) CountRecentFingerprintRecipients(ctx context.Context, customerIDs []int, fingerprint string, excludeCampaignID int, since time.Time) (int, error) {
+	if len(customerIDs) == 0 {
+		return 0, nil
+	}
+
+	query := `
+		SELECT COUNT(*)
+		FROM unnest($1::int[]) AS target(customer_id)
+		WHERE EXISTS (
+			SELECT 1
+			FROM outbound_messages m
+			WHERE m.customer_id = target.customer_id
+				AND m.content_fingerprint = $2
+				AND m.campaign_id <> $3
+				AND m.created_at >= $4
+		)
+	`
+
+	var count int
+	err := r.reader().QueryRowContext(ctx, query, pq.Array(customerIDs), fingerprint, excludeCampaignID, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recent duplicate recipients: %w", err)
+	}
+
+	return count, nil
+}
+
+// HasRecentFingerprintSend reports whether the customer was sent a message with the same
+// content fingerprint, other than the given one, since the given time
+// Reads the primary, so a send another worker just recorded is seen
+func (r *messageRepository) HasRecentFingerprintSend(ctx context.Context, customerID int, fingerprint string, excludeMessageID int, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM outbound_messages
+			WHERE customer_id = $1
+				AND content_fingerprint = $2
+				AND updated_at >= $3
+				AND status = 'sent'
+				AND id <> $4
+		)
+	`
+
+	var exists bool
+	err := r.db.QueryRowContext(ctx, query, customerID, fingerprint, since, excludeMessageID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for a recent duplicate send: %w", err)
+	}
+
+	return exists, nil
+}
+
+// ListFrequencyCapped returns the given customers that were sent at least maxMessages
+// messages, by any campaign, since the given time
+// Reads the replica
+func (r *messageRepository) ListFrequencyCapped(ctx context.Context, customerIDs []int, since time.Time, maxMessages int) ([]int, error) {
+	if len(customerIDs) == 0 {
+		return []int{}, nil
+	}
+
+	query := `
+		SELECT customer_id
+		FROM outbound_messages
+		WHERE customer_id = ANY($1)
+			AND status = 'sent'
+			AND updated_at >= $2
+		GROUP BY customer_id
+		HAVING COUNT(*) >= $3
+	`
+
+	rows, err := r.reader().QueryContext(ctx, query, pq.Array(customerIDs), since, maxMessages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list frequency capped customers: %w", err)
+	}
+	defer rows.Close()
+
+	capped := []int{}
+	for rows.Next() {
+		var customerID int
+		if err := rows.Scan(&customerID); err != nil {
+			return nil, fmt.Errorf("failed to scan frequency capped customer: %w", err)
+		}
+		capped = append(capped, customerID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating frequency capped customers: %w", err)
+	}
+
+	return capped, nil
+}
+
+// ListByCampaignIDs retrieves up to filters.Limit messages for each campaign, newest first
+// Reads the replica
+func (r *messageRepository) ListByCampaignIDs(ctx context.Context, campaignIDs []int, filters MessageFilters) ([]*models.OutboundMessage, error) {
+	return r.listPartitioned(ctx, "campaign_id", campaignIDs, filters)
+}
+
+// ListByCustomerIDs retrieves up to filters.Limit messages for each customer, newest first
+// Reads the replica
+func (r *messageRepository) ListByCustomerIDs(ctx context.Context, customerIDs []int, filters MessageFilters) ([]*models.OutboundMessage, error) {
+	return r.listPartitioned(ctx, "customer_id", customerIDs, filters)
+}
+
+// listPartitioned lists messages for several parents in one query, limiting each parent separately
+// column is always a literal from the callers above, never user input
+func (r *messageRepository) listPartitioned(ctx context.Context, column string, ids []int, filters MessageFilters) ([]*models.OutboundMessage, error) {
+	if len(ids) == 0 {
+		return []*models.OutboundMessage{}, nil
+	}
+
+	queryBuilder := strings.Builder{}
+	queryBuilder.WriteString(fmt.Sprintf(`
+		SELECT id, campaign_id, customer_id, status, rendered_content, last_error, retry_count, published_at, created_at, updated_at
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY %[1]s ORDER BY id DESC) AS rn
+			FROM outbound_messages
+			WHERE %[1]s = ANY($1)
+	`, column))
+
+	args := []interface{}{pq.Array(ids)}
+	argPos := 2
+
+	if filters.Status != nil {
+		queryBuilder.WriteString(fmt.Sprintf(" AND status = $%d", argPos))
+		args = append(args, *filters.Status)
+		argPos++
+	}
+
+	if filters.AfterID > 0 {
+		queryBuilder.WriteString(fmt.Sprintf(" AND id < $%d", argPos))
+		args = append(args, filters.AfterID)
+		argPos++
+	}
+
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	queryBuilder.WriteString(fmt.Sprintf(") m WHERE rn <= $%d ORDER BY %s, id DESC", argPos, column))
+	args = append(args, limit)
+
+	rows, err := r.reader().QueryContext(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*models.OutboundMessage{}
+	for rows.Next() {
+		message := &models.OutboundMessage{}
+		err := rows.Scan(
+			&message.ID,
+			&message.CampaignID,
+			&message.CustomerID,
+			&message.Status,
+			&message.RenderedContent,
+			&message.LastError,
+			&message.RetryCount,
+			&message.PublishedAt,
+			&message.CreatedAt,
+			&message.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if err := r.openContent(message); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// ReencryptContent encrypts rendered content with the current key for up to limit messages after afterID
+// Plaintext rows and rows under an old key are rewritten; updated_at is left alone so delivery
+// latency stats are unaffected. Call repeatedly with the returned LastID until Scanned is 0
+func (r *messageRepository) ReencryptContent(ctx context.Context, afterID, limit int) (*ReencryptBatch, error) {
+	if r.keyring == nil {
+		return nil, fmt.Errorf("message encryption is not configured")
+	}
+
+	query := `
+		SELECT id, rendered_content
+		FROM outbound_messages
+		WHERE id > $1 AND rendered_content IS NOT NULL AND rendered_content NOT LIKE $2
+		ORDER BY id ASC
+		LIMIT $3
+	`
+
+	currentPrefix := crypto.Prefix + r.keyring.CurrentKeyID() + ":%"
+	rows, err := r.db.QueryContext(ctx, query, afterID, currentPrefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages to encrypt: %w", err)
+	}
+
+	type pendingRow struct {
+		id      int
+		content string
+	}
+	pending := []pendingRow{}
+	for rows.Next() {
+		var row pendingRow
+		if err := rows.Scan(&row.id, &row.content); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		pending = append(pending, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get messages to encrypt: %w", err)
+	}
+
+	batch := &ReencryptBatch{Scanned: len(pending), LastID: afterID}
+	update := `
+		UPDATE outbound_messages
+		SET rendered_content = $2
+		WHERE id = $1 AND rendered_content = $3
+	`
+
+	for _, row := range pending {
+		batch.LastID = row.id
+
+		plaintext, err := r.keyring.Decrypt(row.content)
+		if err != nil {
+			return batch, fmt.Errorf("failed to decrypt message %d: %w", row.id, err)
+		}
+		sealed, err := r.keyring.Encrypt(plaintext)
+		if err != nil {
+			return batch, fmt.Errorf("failed to encrypt message %d: %w", row.id, err)
+		}
+
+		// Compare-and-set so a concurrent write is not overwritten with stale content
+		result, err := r.db.ExecContext(ctx, update, row.id, sealed, row.content)
+		if err != nil {
+			return batch, fmt.Errorf("failed to update message %d: %w", row.id, err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+			batch.Updated++
+		}
+	}
+
+	return batch, nil
+}
+
+// ListMissingContent retrieves up to limit sent messages after afterID that have no rendered content,
+// with their campaign's current template and their customer's current data
+func (r *messageRepository) ListMissingContent(ctx context.Context, filters ContentBackfillFilters, afterID, limit int) ([]*models.OutboundMessageWithDetails, error) {
+	query := `
+		SELECT
+			m.id, m.campaign_id, m.customer_id, m.status, m.created_at,
+			c.id, c.channel, c.base_template, c.template_syntax,
+			cu.id, cu.phone, cu.first_name, cu.last_name, cu.location, cu.preferred_product
+		FROM outbound_messages m
+		JOIN campaigns c ON m.campaign_id = c.id
+		JOIN customers cu ON m.customer_id = cu.id
+		WHERE m.id > $1
+			AND m.status = 'sent'
+			AND m.rendered_content IS NULL
+			AND ($2::int IS NULL OR m.campaign_id = $2::int)
+			AND ($3::timestamp IS NULL OR m.created_at < $3::timestamp)
+		ORDER BY m.id ASC
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, afterID, filters.CampaignID, filters.Before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages missing content: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*models.OutboundMessageWithDetails{}
+	for rows.Next() {
+		message := &models.OutboundMessageWithDetails{}
+		err := rows.Scan(
+			&message.ID,
+			&message.CampaignID,
+			&message.CustomerID,
+			&message.Status,
+			&message.CreatedAt,
+			&message.Campaign.ID,
+			&message.Campaign.Channel,
+			&message.Campaign.BaseTemplate,
+			&message.Campaign.TemplateSyntax,
+			&message.Customer.ID,
+			&message.Customer.Phone,
+			&message.Customer.FirstName,
+			&message.Customer.LastName,
+			&message.Customer.Location,
+			&message.Customer.PreferredProduct,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating messages missing content: %w", err)
+	}
+
+	return messages, nil
+}
+
+// StoreBackfilledContent writes reconstructed rendered content, keyed by message ID, and flags it backfilled
+// Only rows still without content are written, so content recorded concurrently is never replaced.
+// updated_at is left alone so delivery latency stats are unaffected. Returns the rows written
+func (r *messageRepository) StoreBackfilledContent(ctx context.Context, contents map[int]string) (int, error) {
+	if len(contents) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int, 0, len(contents))
+	for id := range contents {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	sealed := make([]string, len(ids))
+	for i, id := range ids {
+		content := contents[id]
+		stored, err := r.sealContent(&content)
+		if err != nil {
+			return 0, err
+		}
+		sealed[i] = *stored
+	}
+
+	query := `
+		UPDATE outbound_messages m
+		SET rendered_content = backfill.content, backfilled = TRUE
+		FROM unnest($1::int[], $2::text[]) AS backfill(id, content)
+		WHERE m.id = backfill.id AND m.rendered_content IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, pq.Array(ids), pq.Array(sealed))
+	if err != nil {
+		return 0, fmt.Errorf("failed to store backfilled content: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(affected), nil
+}
+
+// CountUnpublished counts pending messages never published to the queue, across campaigns
+// Reads the primary, as sends are admitted on it
+func (r *messageRepository) CountUnpublished(ctx context.Context) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM outbound_messages
+		WHERE status = 'pending' AND published_at IS NULL AND deliver_after IS NULL
+	`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count unpublished messages: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetPendingBacklog counts each campaign's pending messages by whether they are queued,
+// never published or deferred; campaigns without pending messages are left out
+// Reads the replica
+func (r *messageRepository) GetPendingBacklog(ctx context.Context) ([]*models.CampaignPendingBacklog, error) {
+	query := `
+		SELECT
+			campaign_id,
+			COUNT(*) FILTER (WHERE deliver_after IS NULL AND published_at IS NOT NULL) as queued,
+			COUNT(*) FILTER (WHERE deliver_after IS NULL AND published_at IS NULL) as unpublished,
+			COUNT(*) FILTER (WHERE deliver_after IS NOT NULL) as deferred,
+			MIN(created_at) FILTER (WHERE deliver_after IS NULL AND published_at IS NULL) as oldest_unpublished_at
+		FROM outbound_messages
+		WHERE status = 'pending'
+		GROUP BY campaign_id
+		ORDER BY campaign_id
+	`
+
+	rows, err := r.reader().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending backlog: %w", err)
+	}
+	defer rows.Close()
+
+	backlog := []*models.CampaignPendingBacklog{}
+	for rows.Next() {
+		campaign := &models.CampaignPendingBacklog{}
+		err := rows.Scan(
+			&campaign.CampaignID,
+			&campaign.Queued,
+			&campaign.Unpublished,
+			&campaign.Deferred,
+			&campaign.OldestUnpublishedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pending backlog: %w", err)
+		}
+		backlog = append(backlog, campaign)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pending backlog: %w", err)
+	}
+
+	return backlog, nil
+}
+
+// GetStatuses returns the status of each of the messages that exists, by ID
+func (r *messageRepository) GetStatuses(ctx context.Context, ids []int) (map[int]models.MessageStatus, error) {
+	statuses := map[int]models.MessageStatus{}
+	if len(ids) == 0 {
+		return statuses, nil
+	}
+
+	query := `SELECT id, status FROM outbound_messages WHERE id = ANY($1)`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message statuses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var status models.MessageStatus
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan message status: %w", err)
+		}
+		statuses[id] = status
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating message statuses: %w", err)
+	}
+
+	return statuses, nil
+}
+
+// ListQueued retrieves the pending messages of sending and canary campaigns published before
+// publishedBefore and not deferred, i.e. those that should be waiting in the queue
+func (r *messageRepository) ListQueued(ctx context.Context, publishedBefore time.Time) ([]*models.OutboundMessage, error) {
+	query := `
+		SELECT m.id, m.campaign_id, m.customer_id
+		FROM outbound_messages m
+		JOIN campaigns c ON c.id = m.campaign_id
+		WHERE m.status = 'pending'
+			AND m.published_at < $1
+			AND m.deliver_after IS NULL
+			AND c.status IN ('sending', 'canary')
+		ORDER BY m.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, publishedBefore.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queued messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*models.OutboundMessage{}
+	for rows.Next() {
+		message := &models.OutboundMessage{}
+		if err := rows.Scan(&message.ID, &message.CampaignID, &message.CustomerID); err != nil {
+			return nil, fmt.Errorf("failed to scan queued message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating queued messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// ListForExport retrieves up to limit of a campaign's messages after afterID, in ID order,
+// with their customer's phone; content is decrypted
+func (r *messageRepository) ListForExport(ctx context.Context, campaignID, afterID, limit int) ([]*models.OutboundMessageWithDetails, error) {
+	query := `
+		SELECT
+			m.id, m.campaign_id, m.customer_id, m.status, m.rendered_content, m.last_error,
+			m.retry_count, m.created_at, m.updated_at,
+			cu.id, cu.phone
+		FROM outbound_messages m
+		JOIN customers cu ON m.customer_id = cu.id
+		WHERE m.campaign_id = $1 AND m.id > $2
+		ORDER BY m.id ASC
+		LIMIT $3
+	`
+
+	rows, err := r.reader().QueryContext(ctx, query, campaignID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages for export: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*models.OutboundMessageWithDetails{}
+	for rows.Next() {
+		message := &models.OutboundMessageWithDetails{}
+		err := rows.Scan(
+			&message.ID,
+			&message.CampaignID,
+			&message.CustomerID,
+			&message.Status,
+			&message.RenderedContent,
+			&message.LastError,
+			&message.RetryCount,
+			&message.CreatedAt,
+			&message.UpdatedAt,
+			&message.Customer.ID,
+			&message.Customer.Phone,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if err := r.openContent(&message.OutboundMessage); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating messages for export: %w", err)
+	}
+
+	return messages, nil
+}
+
+// sealContent encrypts rendered content for storage when a keyring is configured
+func (r *messageRepository) sealContent(content *string) (*string, error) {
+	if content == nil || r.keyring == nil {
+		return content, nil
+	}
+
+	sealed, err := r.keyring.Encrypt(*content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt rendered content: %w", err)
+	}
+	return &sealed, nil
+}
+
+// openContent decrypts a scanned message's rendered content in place
+// Plaintext written before encryption was enabled is returned as is
+func (r *messageRepository) openContent(message *models.OutboundMessage) error {
+	if message.RenderedContent == nil || !crypto.IsEncrypted(*message.RenderedContent) {
+		return nil
+	}
+	if r.keyring == nil {
+		return fmt.Errorf("message %d content is encrypted but MESSAGE_ENCRYPTION_KEY is not set", message.ID)
+	}
+
+	plaintext, err := r.keyring.Decrypt(*message.RenderedContent)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt message %d: %w", message.ID, err)
+	}
+	message.RenderedContent = &plaintext
+	return nil
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+type phoneNormalizationRepository struct {
+	db Database
+}
+
+// NewPhoneNormalizationRepository creates a new phone normalization repository
+func NewPhoneNormalizationRepository(db Database) PhoneNormalizationRepository {
+	return &phoneNormalizationRepository{db: db}
+}
+
+// ListCustomerPhones returns every customer's phone, oldest customer first
+func (r *phoneNormalizationRepository) ListCustomerPhones(ctx context.Context) ([]*StoredPhone, error) {
+	query := `
+		SELECT id, phone, created_at
+		FROM customers
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list customer phones: %w", err)
+	}
+	defer rows.Close()
+
+	phones := []*StoredPhone{}
+	for rows.Next() {
+		phone := &StoredPhone{}
+		if err := rows.Scan(&phone.CustomerID, &phone.Phone, &phone.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan customer phone: %w", err)
+		}
+		phones = append(phones, phone)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating customer phones: %w", err)
+	}
+
+	return phones, nil
+}
+
+// ListSuppressionPhones returns every suppressed phone by campaign, oldest suppression first
+func (r *phoneNormalizationRepository) ListSuppressionPhones(ctx context.Context) ([]*StoredPhone, error) {
+	query := `
+		SELECT campaign_id, phone, created_at
+		FROM campaign_suppressions
+		ORDER BY campaign_id, created_at, phone
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suppression phones: %w", err)
+	}
+	defer rows.Close()
+
+	phones := []*StoredPhone{}
+	for rows.Next() {
+		phone := &StoredPhone{}
+		if err := rows.Scan(&phone.CampaignID, &phone.Phone, &phone.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan suppression phone: %w", err)
+		}
+		phones = append(phones, phone)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating suppression phones: %w", err)
+	}
+
+	return phones, nil
+}
+
+// RewriteCustomerPhones stores customers' normalized phones in one transaction and returns
+// how many were rewritten; a customer whose phone changed since it was listed is left alone
+func (r *phoneNormalizationRepository) RewriteCustomerPhones(ctx context.Context, rewrites []*PhoneRewrite) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rewritten := 0
+	for _, rewrite := range rewrites {
+		result, err := tx.ExecContext(ctx, `UPDATE customers SET phone = $3 WHERE id = $1 AND phone = $2`,
+			rewrite.Row.CustomerID, rewrite.Row.Phone, rewrite.To)
+		if err != nil {
+			return 0, fmt.Errorf("failed to rewrite phone of customer %d: %w", rewrite.Row.CustomerID, err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		rewritten += int(rows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return rewritten, nil
+}
+
+// RewriteSuppressionPhones removes duplicate suppressions, then stores the normalized phones
+// of the rest, in one transaction; it returns how many were rewritten and removed
+func (r *phoneNormalizationRepository) RewriteSuppressionPhones(ctx context.Context, rewrites []*PhoneRewrite, duplicates []*StoredPhone) (int, int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	removed := 0
+	for _, duplicate := range duplicates {
+		result, err := tx.ExecContext(ctx, `DELETE FROM campaign_suppressions WHERE campaign_id = $1 AND phone = $2`,
+			duplicate.CampaignID, duplicate.Phone)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to remove duplicate suppression: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		removed += int(rows)
+	}
+
+	rewritten := 0
+	for _, rewrite := range rewrites {
+		result, err := tx.ExecContext(ctx, `UPDATE campaign_suppressions SET phone = $3 WHERE campaign_id = $1 AND phone = $2`,
+			rewrite.Row.CampaignID, rewrite.Row.Phone, rewrite.To)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to rewrite suppressed phone: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		rewritten += int(rows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return rewritten, removed, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"smsleopard/internal/models"
+)
+
+type processingErrorRepository struct {
+	db DB
+}
+
+// NewProcessingErrorRepository creates a new processing error repository
+func NewProcessingErrorRepository(db DB) ProcessingErrorRepository {
+	return &processingErrorRepository{db: db}
+}
+
+// Create records a processing error
+func (r *processingErrorRepository) Create(ctx context.Context, processingError *models.ProcessingError) error {
+	query := `
+		INSERT INTO processing_errors (message_id, error_class, error, worker_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, occurred_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		processingError.MessageID,
+		processingError.ErrorClass,
+		processingError.Error,
+		processingError.WorkerID,
+	).Scan(&processingError.ID, &processingError.OccurredAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create processing error: %w", err)
+	}
+
+	return nil
+}
+
+// ListSince returns processing errors that occurred at or after since, newest first
+func (r *processingErrorRepository) ListSince(ctx context.Context, since time.Time, limit int) ([]*models.ProcessingError, error) {
+	query := `
+		SELECT id, message_id, error_class, error, worker_id, occurred_at
+		FROM processing_errors
+		WHERE occurred_at >= $1
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processing errors: %w", err)
+	}
+	defer rows.Close()
+
+	processingErrors := []*models.ProcessingError{}
+	for rows.Next() {
+		processingError := &models.ProcessingError{}
+		err := rows.Scan(
+			&processingError.ID,
+			&processingError.MessageID,
+			&processingError.ErrorClass,
+			&processingError.Error,
+			&processingError.WorkerID,
+			&processingError.OccurredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan processing error: %w", err)
+		}
+		processingErrors = append(processingErrors, processingError)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating processing errors: %w", err)
+	}
+
+	return processingErrors, nil
+}
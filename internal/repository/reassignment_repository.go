@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"smsleopard/internal/models"
+)
+
+type reassignmentRepository struct {
+	db Database
+}
+
+// NewMessageReassignmentRepository creates a new message reassignment repository
+func NewMessageReassignmentRepository(db Database) MessageReassignmentRepository {
+	return &reassignmentRepository{db: db}
+}
+
+// Create logs a reassignment before any message is moved
+func (r *reassignmentRepository) Create(ctx context.Context, reassignment *models.MessageReassignment) error {
+	query := `
+		INSERT INTO message_reassignments (from_campaign_id, to_campaign_id, status, messages_moved, requested_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		reassignment.FromCampaignID,
+		reassignment.ToCampaignID,
+		reassignment.Status,
+		reassignment.MessagesMoved,
+		reassignment.RequestedBy,
+	).Scan(&reassignment.ID, &reassignment.CreatedAt, &reassignment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create message reassignment: %w", err)
+	}
+
+	return nil
+}
+
+// MoveBatch moves up to limit of the source campaign's messages in the reassignment's status
+// to the target campaign and adds them to the reassignment's count, in one transaction
+// Moved messages take the target's content fingerprint; pending ones also lose their
+// rendered content so the worker renders them from the target's template
+// Messages locked by another transaction are skipped, so it returns 0 only when none are left
+func (r *reassignmentRepository) MoveBatch(ctx context.Context, reassignment *models.MessageReassignment, fingerprint string, limit int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE outbound_messages
+		SET campaign_id = $2,
+			content_fingerprint = $3,
+			rendered_content = CASE WHEN status = 'pending' THEN NULL ELSE rendered_content END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT id FROM outbound_messages
+			WHERE campaign_id = $1 AND status = $4
+			ORDER BY id
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+	result, err := tx.ExecContext(ctx, query,
+		reassignment.FromCampaignID,
+		reassignment.ToCampaignID,
+		fingerprint,
+		reassignment.Status,
+		limit,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move messages: %w", err)
+	}
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if moved == 0 {
+		return 0, nil
+	}
+
+	update := `
+		UPDATE message_reassignments
+		SET messages_moved = messages_moved + $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	if _, err := tx.ExecContext(ctx, update, reassignment.ID, moved); err != nil {
+		return 0, fmt.Errorf("failed to update message reassignment: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	reassignment.MessagesMoved += int(moved)
+	return int(moved), nil
+}
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"smsleopard/internal/models"
+)
+
+type recurrenceRepository struct {
+	db Database
+}
+
+// NewRecurrenceRepository creates a new recurring campaign repository
+func NewRecurrenceRepository(db Database) RecurrenceRepository {
+	return &recurrenceRepository{db: db}
+}
+
+// recurrenceColumns is the column list scanned by scanRecurrence
+const recurrenceColumns = `campaign_id, rule, paused, next_occurrence_at, occurrences, created_at, updated_at`
+
+// scanRecurrence scans a row of recurrenceColumns
+func scanRecurrence(row interface{ Scan(...interface{}) error }, recurrence *models.CampaignRecurrence) error {
+	var ruleJSON []byte
+	if err := row.Scan(
+		&recurrence.CampaignID, &ruleJSON, &recurrence.Paused, &recurrence.NextOccurrenceAt,
+		&recurrence.Occurrences, &recurrence.CreatedAt, &recurrence.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(ruleJSON, &recurrence.Rule); err != nil {
+		return fmt.Errorf("failed to unmarshal recurrence rule: %w", err)
+	}
+	return nil
+}
+
+// Create makes a draft or scheduled campaign recurring: it moves to recurring with plan as the
+// audience of every occurrence, the first of which is sent at next, in one transaction
+// It returns *models.InvalidTransitionError when the campaign is in any other status
+func (r *recurrenceRepository) Create(ctx context.Context, campaignID int, rule *models.RecurrenceRule, plan *models.SendPlan, next time.Time) (*models.CampaignRecurrence, error) {
+	ruleJSON, err := json.Marshal(rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal recurrence rule: %w", err)
+	}
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal send plan: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE campaigns
+		SET status = $2, send_plan = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status IN ($4, $5)
+	`, campaignID, models.CampaignStatusRecurring, planJSON, models.CampaignStatusDraft, models.CampaignStatusScheduled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make campaign recurring: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		var current models.CampaignStatus
+		err := tx.QueryRowContext(ctx, `SELECT status FROM campaigns WHERE id = $1`, campaignID).Scan(&current)
+		if err == sql.ErrNoRows {
+			return nil, ErrCampaignNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get campaign status: %w", err)
+		}
+		return nil, &models.InvalidTransitionError{From: current, To: models.CampaignStatusRecurring}
+	}
+
+	recurrence := &models.CampaignRecurrence{}
+	err = scanRecurrence(tx.QueryRowContext(ctx, `
+		INSERT INTO campaign_recurrences (campaign_id, rule, next_occurrence_at)
+		VALUES ($1, $2, $3)
+		RETURNING `+recurrenceColumns,
+		campaignID, ruleJSON, next,
+	), recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recurrence: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return recurrence, nil
+}
+
+// Get returns a recurring campaign's recurrence, or ErrRecurrenceNotFound
+func (r *recurrenceRepository) Get(ctx context.Context, campaignID int) (*models.CampaignRecurrence, error) {
+	recurrence := &models.CampaignRecurrence{}
+	err := scanRecurrence(r.db.QueryRowContext(ctx,
+		`SELECT `+recurrenceColumns+` FROM campaign_recurrences WHERE campaign_id = $1`, campaignID,
+	), recurrence)
+	if err == sql.ErrNoRows {
+		return nil, ErrRecurrenceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recurrence: %w", err)
+	}
+	return recurrence, nil
+}
+
+// Pause stops occurrences being sent until the recurrence is resumed
+func (r *recurrenceRepository) Pause(ctx context.Context, campaignID int) (*models.CampaignRecurrence, error) {
+	return r.update(ctx, `
+		UPDATE campaign_recurrences
+		SET paused = TRUE, updated_at = CURRENT_TIMESTAMP
+		WHERE campaign_id = $1
+		RETURNING `+recurrenceColumns, campaignID)
+}
+
+// Resume sends occurrences again from next; occurrences missed while paused are not sent
+func (r *recurrenceRepository) Resume(ctx context.Context, campaignID int, next *time.Time) (*models.CampaignRecurrence, error) {
+	return r.update(ctx, `
+		UPDATE campaign_recurrences
+		SET paused = FALSE, next_occurrence_at = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE campaign_id = $1
+		RETURNING `+recurrenceColumns, campaignID, next)
+}
+
+// update runs an UPDATE returning recurrenceColumns, or ErrRecurrenceNotFound
+func (r *recurrenceRepository) update(ctx context.Context, query string, args ...interface{}) (*models.CampaignRecurrence, error) {
+	recurrence := &models.CampaignRecurrence{}
+	err := scanRecurrence(r.db.QueryRowContext(ctx, query, args...), recurrence)
+	if err == sql.ErrNoRows {
+		return nil, ErrRecurrenceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update recurrence: %w", err)
+	}
+	return recurrence, nil
+}
+
+// ListDue returns up to limit running recurrences whose next occurrence is at or before now,
+// earliest first
+func (r *recurrenceRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*models.CampaignRecurrence, error) {
+	query := `
+		SELECT ` + recurrenceColumns + `
+		FROM campaign_recurrences
+		WHERE NOT paused AND next_occurrence_at <= $1
+		ORDER BY next_occurrence_at, campaign_id
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due recurrences: %w", err)
+	}
+	defer rows.Close()
+
+	recurrences := []*models.CampaignRecurrence{}
+	for rows.Next() {
+		recurrence := &models.CampaignRecurrence{}
+		if err := scanRecurrence(rows, recurrence); err != nil {
+			return nil, fmt.Errorf("failed to scan recurrence: %w", err)
+		}
+		recurrences = append(recurrences, recurrence)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating recurrences: %w", err)
+	}
+
+	return recurrences, nil
+}
+
+// CreateOccurrence clones a recurring campaign into a draft campaign named name for the
+// occurrence at occurrenceAt, and moves the recurrence on to next (nil ends it), in one
+// transaction; it returns the new campaign's ID
+// It returns 0 without creating anything when the recurrence is no longer due at
+// occurrenceAt, because another instance sent the occurrence or it was paused meanwhile
+func (r *recurrenceRepository) CreateOccurrence(ctx context.Context, parentID int, occurrenceAt time.Time, name string, next *time.Time) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE campaign_recurrences
+		SET next_occurrence_at = $3, occurrences = occurrences + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE campaign_id = $1 AND next_occurrence_at = $2 AND NOT paused
+	`, parentID, occurrenceAt, next)
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance recurrence: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return 0, nil
+	}
+
+	var campaignID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO campaigns (name, channel, status, base_template, tags, created_by, team, budget, frequency_cap_exempt, track_links, template_syntax, demo_failure_rate, ordered, part_delimiter)
+		SELECT $2, channel, $3, base_template, tags, created_by, team, budget, frequency_cap_exempt, track_links, template_syntax, demo_failure_rate, ordered, part_delimiter
+		FROM campaigns
+		WHERE id = $1
+		RETURNING id
+	`, parentID, name, models.CampaignStatusDraft).Scan(&campaignID)
+	if err == sql.ErrNoRows {
+		return 0, ErrCampaignNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to clone campaign: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO campaign_occurrences (parent_campaign_id, campaign_id, occurrence_at)
+		VALUES ($1, $2, $3)
+	`, parentID, campaignID, occurrenceAt); err != nil {
+		return 0, fmt.Errorf("failed to record occurrence: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return campaignID, nil
+}
+
+// ListOccurrences returns a recurring campaign's occurrences with their campaigns' names and
+// statuses, newest first
+func (r *recurrenceRepository) ListOccurrences(ctx context.Context, parentID int) ([]*models.CampaignOccurrence, error) {
+	query := `
+		SELECT o.campaign_id, o.parent_campaign_id, o.occurrence_at, c.name, c.status, o.created_at
+		FROM campaign_occurrences o
+		JOIN campaigns c ON c.id = o.campaign_id
+		WHERE o.parent_campaign_id = $1
+		ORDER BY o.occurrence_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list occurrences: %w", err)
+	}
+	defer rows.Close()
+
+	occurrences := []*models.CampaignOccurrence{}
+	for rows.Next() {
+		occurrence := &models.CampaignOccurrence{}
+		if err := rows.Scan(
+			&occurrence.CampaignID, &occurrence.ParentCampaignID, &occurrence.OccurrenceAt,
+			&occurrence.Name, &occurrence.Status, &occurrence.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan occurrence: %w", err)
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating occurrences: %w", err)
+	}
+
+	return occurrences, nil
+}
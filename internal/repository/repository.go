@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"smsleopard/internal/models"
 )
@@ -10,11 +11,36 @@ import (
 // CustomerRepository defines customer data access operations
 type CustomerRepository interface {
 	Create(ctx context.Context, customer *models.Customer) error
+	UpsertByPhone(ctx context.Context, customer *models.Customer) (bool, error)
 	GetByID(ctx context.Context, id int) (*models.Customer, error)
 	GetByIDs(ctx context.Context, ids []int) ([]*models.Customer, error)
+	GetByPhones(ctx context.Context, phones []string) ([]*models.Customer, error)
 	List(ctx context.Context, limit, offset int) ([]*models.Customer, error)
-	Update(ctx context.Context, customer *models.Customer) error
+	ListFiltered(ctx context.Context, filters CustomerFilters, limit, offset int) ([]*models.Customer, error)
+	Update(ctx context.Context, customer *models.Customer, changedBy *string) error
 	Delete(ctx context.Context, id int) error
+	DeleteWithMessages(ctx context.Context, id int) (int, error)
+	GetStats(ctx context.Context, location *string) (*models.CustomerStats, error)
+	GetTimeline(ctx context.Context, customerID int, after *models.TimelineCursor, limit int) ([]*models.TimelineEvent, error)
+	CountMissingFields(ctx context.Context, ids []int, fields []string) (*models.FieldCompleteness, error)
+	CountFiltered(ctx context.Context, filters CustomerFilters) (int, error)
+	StreamFiltered(ctx context.Context, filters CustomerFilters, limit int, fn func(customer *models.Customer) error) error
+	GetDetail(ctx context.Context, id int) (*models.Customer, error)
+	Block(ctx context.Context, id int, reason string, actor *string) error
+	Unblock(ctx context.Context, id int, actor *string) error
+	ListBlocked(ctx context.Context, ids []int) ([]int, error)
+	ListBelowScore(ctx context.Context, ids []int, minScore float64) ([]int, error)
+	ListBlockedPhones(ctx context.Context, phones []string) ([]string, error)
+	ListBlockEvents(ctx context.Context, id int) ([]*models.CustomerBlockEvent, error)
+	ListPhoneHistory(ctx context.Context, id int) ([]*models.CustomerPhoneChange, error)
+}
+
+// CustomerFilters selects customers for the list or an export; empty fields do not filter
+type CustomerFilters struct {
+	Query    string   // Substring of the phone, first or last name, case-insensitive
+	Location string   // Exact location, case-insensitive
+	Product  string   // Exact preferred product, case-insensitive
+	MinScore *float64 // Lowest engagement score; customers not yet scored never match
 }
 
 // CampaignRepository defines campaign data access operations
@@ -23,8 +49,30 @@ type CampaignRepository interface {
 	GetByID(ctx context.Context, id int) (*models.Campaign, error)
 	GetWithStats(ctx context.Context, id int) (*models.CampaignWithStats, error)
 	List(ctx context.Context, filters CampaignFilters) ([]*models.Campaign, int, error)
-	UpdateStatus(ctx context.Context, id int, status models.CampaignStatus) error
+	UpdateStatusIf(ctx context.Context, id int, from, to models.CampaignStatus) error
+	UpdateStatusIfTx(ctx context.Context, tx *sql.Tx, id int, from, to models.CampaignStatus) error
+	AwaitApproval(ctx context.Context, id int, from models.CampaignStatus, plan *models.SendPlan) error
+	GetSendPlan(ctx context.Context, id int) (*models.SendPlan, error)
+	ClearSendPlan(ctx context.Context, id int) error
+	HoldCanary(ctx context.Context, id int, plan *models.SendPlan) error
+	RecordSend(ctx context.Context, record *models.SendRecord) error
+	UpdateSendProgress(ctx context.Context, id int, messagesQueued int, status models.CampaignStatus) error
+	ListSends(ctx context.Context, campaignID int) ([]*models.SendRecord, error)
 	Delete(ctx context.Context, id int) error
+	DeleteWithMessages(ctx context.Context, id int) (int, error)
+	ListNeedingAttention(ctx context.Context) ([]*models.CampaignAttention, error)
+	GetStatsByIDs(ctx context.Context, ids []int) (map[int]*models.CampaignStats, error)
+	GetFailureBreakdownByIDs(ctx context.Context, ids []int) (map[int][]*models.ErrorCount, error)
+	StreamWithStats(ctx context.Context, filters CampaignExportFilters, fn func(row *models.CampaignExportRow) error) error
+	ReserveSpend(ctx context.Context, id int, cost float64) (bool, error)
+	ReleaseSpend(ctx context.Context, id int, cost float64) error
+	Pause(ctx context.Context, id int, reason string) (bool, error)
+	BeginCancel(ctx context.Context, id int, grace time.Duration) (time.Time, error)
+	UndoCancel(ctx context.Context, id int) error
+	ListDueCancellations(ctx context.Context) ([]int, error)
+	FinalizeCancellation(ctx context.Context, id int) (int, error)
+	Resume(ctx context.Context, id int, budget *float64) error
+	ListOrderedOutstanding(ctx context.Context) ([]int, error)
 }
 
 // CampaignFilters defines filters for listing campaigns
@@ -33,17 +81,210 @@ type CampaignFilters struct {
 	PageSize int
 	Channel  *models.Channel
 	Status   *models.CampaignStatus
+	Tags     []string // Campaigns must have every tag
+}
+
+// CampaignExportFilters limits an export to campaigns created in [From, To)
+type CampaignExportFilters struct {
+	From *time.Time
+	To   *time.Time
 }
 
 // MessageRepository defines outbound message data access operations
 type MessageRepository interface {
 	Create(ctx context.Context, message *models.OutboundMessage) error
 	CreateBatch(ctx context.Context, messages []*models.OutboundMessage) error
+	CreateBatchTx(ctx context.Context, tx *sql.Tx, messages []*models.OutboundMessage) error
 	GetByID(ctx context.Context, id int) (*models.OutboundMessage, error)
 	GetWithDetails(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error)
 	UpdateStatus(ctx context.Context, id int, status models.MessageStatus, lastError *string) error
-	GetPendingMessages(ctx context.Context, limit int) ([]*models.OutboundMessage, error)
+	MarkPublished(ctx context.Context, ids []int) error
+	ReserveAttempt(ctx context.Context, customerID int, day time.Time, budget int) (bool, error)
+	DeferUntil(ctx context.Context, id int, until time.Time, reason string) error
+	ClaimDueDeferred(ctx context.Context, now time.Time, limit int) ([]*models.OutboundMessage, error)
+	Hold(ctx context.Context, id int, reason string) error
+	ReleaseHeld(ctx context.Context, campaignID int) (int, error)
+	GetPendingMessages(ctx context.Context, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error)
 	GetByCampaignID(ctx context.Context, campaignID int) ([]*models.OutboundMessage, error)
+	GetPendingByCampaignID(ctx context.Context, campaignID, limit int) ([]*models.OutboundMessage, error)
+	ClearPendingRenderedContent(ctx context.Context, campaignID int) (int, error)
+	GetDeliveryStatsByChannel(ctx context.Context, since time.Time) ([]*models.ChannelDeliveryStats, error)
+	GetRetryEffectiveness(ctx context.Context, from, to time.Time) ([]*models.ChannelRetryEffectiveness, error)
+	CountRecentRecipients(ctx context.Context, customerIDs []int, excludeCampaignID int, since time.Time) (int, error)
+	CountRecentFingerprintRecipients(ctx context.Context, customerIDs []int, fingerprint string, excludeCampaignID int, since time.Time) (int, error)
+	HasRecentFingerprintSend(ctx context.Context, customerID int, fingerprint string, excludeMessageID int, since time.Time) (bool, error)
+	ClaimNextPart(ctx context.Context, groupID, part int) (*models.OutboundMessage, error)
+	CreateResend(ctx context.Context, originalID int, verbatim bool, fingerprint *string) (*models.OutboundMessage, error)
+	ListFrequencyCapped(ctx context.Context, customerIDs []int, since time.Time, maxMessages int) ([]int, error)
+	ListByCampaignIDs(ctx context.Context, campaignIDs []int, filters MessageFilters) ([]*models.OutboundMessage, error)
+	ListByCustomerIDs(ctx context.Context, customerIDs []int, filters MessageFilters) ([]*models.OutboundMessage, error)
+	ReencryptContent(ctx context.Context, afterID, limit int) (*ReencryptBatch, error)
+	ListMissingContent(ctx context.Context, filters ContentBackfillFilters, afterID, limit int) ([]*models.OutboundMessageWithDetails, error)
+	StoreBackfilledContent(ctx context.Context, contents map[int]string) (int, error)
+	ListForExport(ctx context.Context, campaignID, afterID, limit int) ([]*models.OutboundMessageWithDetails, error)
+	ClaimUnpublished(ctx context.Context, createdBefore time.Time, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error)
+	GetPendingBacklog(ctx context.Context) ([]*models.CampaignPendingBacklog, error)
+	CountUnpublished(ctx context.Context) (int, error)
+	GetStatuses(ctx context.Context, ids []int) (map[int]models.MessageStatus, error)
+	ListQueued(ctx context.Context, publishedBefore time.Time) ([]*models.OutboundMessage, error)
+}
+
+// ContentBackfillFilters limits which sent messages without rendered content are backfilled
+type ContentBackfillFilters struct {
+	CampaignID *int
+	Before     *time.Time // Only messages created before this time
+}
+
+// ProcessingErrorRepository defines worker processing error data access operations
+type ProcessingErrorRepository interface {
+	Create(ctx context.Context, processingError *models.ProcessingError) error
+	ListSince(ctx context.Context, since time.Time, limit int) ([]*models.ProcessingError, error)
+}
+
+// WorkerRepository defines worker liveness and message claim data access operations
+type WorkerRepository interface {
+	Heartbeat(ctx context.Context, workerID string, startedAt time.Time) error
+	ReleaseAll(ctx context.Context, workerID string) (int64, error)
+	Claim(ctx context.Context, messageID int, workerID string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, messageID int, workerID string) error
+	GetActivity(ctx context.Context, workerID string, limit int) (*models.WorkerActivity, error)
+}
+
+// APIKeyRepository defines issued API key data access operations
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	List(ctx context.Context) ([]*models.APIKey, error)
+	ListActive(ctx context.Context) ([]*models.APIKey, error)
+	Revoke(ctx context.Context, id int) (*models.APIKey, error)
+	MarkUsed(ctx context.Context, ids []int, usedAt time.Time) error
+}
+
+// SettingsRepository defines runtime settings data access operations
+type SettingsRepository interface {
+	Get(ctx context.Context, key string) (*models.Setting, error)
+	Set(ctx context.Context, key, value, reason string, actor *string) (*models.Setting, error)
+	ListChanges(ctx context.Context, key string, limit int) ([]*models.SettingChange, error)
+}
+
+// SuppressionRepository defines per-campaign phone suppression data access operations
+type SuppressionRepository interface {
+	Add(ctx context.Context, campaignID int, phones []string, addedBy *string) (int, error)
+	List(ctx context.Context, campaignID int) ([]*models.CampaignSuppression, error)
+	Clear(ctx context.Context, campaignID int) (int, error)
+	FilterSuppressed(ctx context.Context, campaignID int, phones []string) ([]string, error)
+}
+
+// RecurrenceRepository defines recurring campaign and occurrence data access operations
+type RecurrenceRepository interface {
+	Create(ctx context.Context, campaignID int, rule *models.RecurrenceRule, plan *models.SendPlan, next time.Time) (*models.CampaignRecurrence, error)
+	Get(ctx context.Context, campaignID int) (*models.CampaignRecurrence, error)
+	Pause(ctx context.Context, campaignID int) (*models.CampaignRecurrence, error)
+	Resume(ctx context.Context, campaignID int, next *time.Time) (*models.CampaignRecurrence, error)
+	ListDue(ctx context.Context, now time.Time, limit int) ([]*models.CampaignRecurrence, error)
+	CreateOccurrence(ctx context.Context, parentID int, occurrenceAt time.Time, name string, next *time.Time) (int, error)
+	ListOccurrences(ctx context.Context, parentID int) ([]*models.CampaignOccurrence, error)
+}
+
+// DigestRepository defines the aggregation and bookkeeping of nightly activity digests
+type DigestRepository interface {
+	CountFinishedCampaigns(ctx context.Context, from, to time.Time) (completed int, failed int, err error)
+	ListChannelActivity(ctx context.Context, from, to time.Time) ([]*models.ChannelActivity, error)
+	ListTopErrors(ctx context.Context, from, to time.Time, limit int) ([]*models.ErrorCount, error)
+	Claim(ctx context.Context, date string, staleAfter time.Duration) (bool, error)
+	MarkSent(ctx context.Context, date string, payload []byte) error
+	Release(ctx context.Context, date string) error
+}
+
+// ExportJobRepository defines background message export job data access operations
+type ExportJobRepository interface {
+	Create(ctx context.Context, job *models.ExportJob) error
+	GetByID(ctx context.Context, id int) (*models.ExportJob, error)
+	ClaimNext(ctx context.Context, staleAfter time.Duration) (*models.ExportJob, error)
+	UpdateProgress(ctx context.Context, id, rowsWritten int) error
+	Complete(ctx context.Context, id int, fileName string, sizeBytes int64, expiresAt time.Time) error
+	Fail(ctx context.Context, id int, message string) error
+	ListExpired(ctx context.Context, now time.Time) ([]*models.ExportJob, error)
+	ClearFile(ctx context.Context, id int) error
+}
+
+// CampaignEventRepository defines campaign event data access operations
+type CampaignEventRepository interface {
+	Create(ctx context.Context, event *models.CampaignEvent) (bool, error)
+	ListSince(ctx context.Context, campaignID int, sinceID int64, limit int) ([]*models.CampaignEvent, error)
+	ListReplay(ctx context.Context, campaignID int, afterID int64, limit int) ([]*models.CampaignEvent, error)
+	ListSendingProgress(ctx context.Context) ([]*models.CampaignProgress, error)
+	RecordSendMetrics(ctx context.Context, campaignID int, ratePerSecond float64) error
+}
+
+// LinkRepository defines tracked link and click data access operations
+type LinkRepository interface {
+	EnsureLinks(ctx context.Context, links []*models.TrackedLink) error
+	GetByToken(ctx context.Context, token string) (*models.TrackedLink, error)
+	RecordClick(ctx context.Context, click *models.LinkClick) error
+}
+
+// StatsRebuildRepository defines reading and correcting the stats rebuilt from campaign events
+type StatsRebuildRepository interface {
+	ListMessageSummaries(ctx context.Context, campaignID int) ([]*models.MessageSummary, error)
+	CorrectMessage(ctx context.Context, campaignID int, summary *models.MessageSummary) error
+	CorrectCampaignStatus(ctx context.Context, campaignID int, status models.CampaignStatus) error
+	CorrectSpend(ctx context.Context, campaignID int, spend float64) error
+}
+
+// MessageReassignmentRepository defines moving messages between campaigns and its audit log
+type MessageReassignmentRepository interface {
+	Create(ctx context.Context, reassignment *models.MessageReassignment) error
+	MoveBatch(ctx context.Context, reassignment *models.MessageReassignment, fingerprint string, limit int) (int, error)
+}
+
+// EngagementRepository defines the reads and writes of the customer engagement scorer
+type EngagementRepository interface {
+	ListEngagementHistory(ctx context.Context, afterID, limit int, since time.Time) ([]*models.EngagementHistory, error)
+	StoreEngagementScores(ctx context.Context, scores map[int]float64, scoredAt time.Time) error
+}
+
+// PhoneNormalizationRepository defines the one-time rewrite of phones stored before they were
+// normalized on write, for customers and campaign suppression lists
+type PhoneNormalizationRepository interface {
+	ListCustomerPhones(ctx context.Context) ([]*StoredPhone, error)
+	ListSuppressionPhones(ctx context.Context) ([]*StoredPhone, error)
+	RewriteCustomerPhones(ctx context.Context, rewrites []*PhoneRewrite) (int, error)
+	RewriteSuppressionPhones(ctx context.Context, rewrites []*PhoneRewrite, duplicates []*StoredPhone) (rewritten int, removed int, err error)
+}
+
+// StoredPhone is a phone as stored: a customer's (CustomerID set) or one on a campaign's
+// suppression list (CampaignID set)
+type StoredPhone struct {
+	CustomerID int
+	CampaignID int
+	Phone      string
+	CreatedAt  time.Time
+}
+
+// PhoneRewrite replaces a stored phone with its normalized form
+type PhoneRewrite struct {
+	Row *StoredPhone
+	To  string
+}
+
+// ClockRepository reads the database's current time, which time-sensitive decisions use
+// instead of the app server's clock
+type ClockRepository interface {
+	Now(ctx context.Context) (time.Time, error)
+}
+
+// ReencryptBatch is the outcome of encrypting one batch of stored message content
+type ReencryptBatch struct {
+	Scanned int // Rows found needing encryption
+	Updated int // Rows rewritten (fewer than Scanned if rows changed concurrently)
+	LastID  int // Resume after this ID
+}
+
+// MessageFilters defines filters for listing messages of several campaigns or customers
+type MessageFilters struct {
+	Status  *models.MessageStatus
+	AfterID int // Only messages older than this ID (lists are newest first)
+	Limit   int // Per campaign or customer
 }
 
 // DB is a wrapper around *sql.DB to allow passing in transaction
@@ -52,3 +293,10 @@ type DB interface {
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
+
+// Database is a DB that can also start transactions
+// Both *sql.DB and *InstrumentedDB implement it
+type Database interface {
+	DB
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Writes failing with a transient error before they ran are run again this many more times,
+// this long apart, which covers the few seconds a managed Postgres spends failing over
+const (
+	transientRetries      = 2
+	transientRetryBackoff = 500 * time.Millisecond
+)
+
+// retryTransient runs op, running it again while it fails with a transient error raised
+// before its statement ran (see notApplied), up to transientRetries more times. op is a single
+// statement or a transaction, which such an error rolls back whole; one failing once it may
+// have been applied, e.g. on a reset connection, is not run again, as a write like
+// retry_count + 1 would be applied twice. The last error is returned classified, so callers
+// can match ErrTransient either way
+func retryTransient(ctx context.Context, op func() error) error {
+	err := op()
+	for attempt := 1; attempt <= transientRetries && notApplied(err); attempt++ {
+		log.Printf("Transient database error, retrying in %v (%d/%d): %v", transientRetryBackoff, attempt, transientRetries, err)
+		select {
+		case <-ctx.Done():
+			return classifyError(err)
+		case <-time.After(transientRetryBackoff):
+		}
+		err = op()
+	}
+	return classifyError(err)
+}
+
+// ExecWithRetry executes a write on db, retrying it through a failover (see retryTransient)
+// It must not be used inside a transaction, which a failover aborts
+func ExecWithRetry(ctx context.Context, db DB, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := retryTransient(ctx, func() error {
+		var err error
+		result, err = db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
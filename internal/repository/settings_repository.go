@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"smsleopard/internal/models"
+)
+
+type settingsRepository struct {
+	db DB
+}
+
+// NewSettingsRepository creates a new settings repository
+func NewSettingsRepository(db DB) SettingsRepository {
+	return &settingsRepository{db: db}
+}
+
+// Get retrieves a setting by key
+// Returns ErrSettingNotFound when it has never been stored
+func (r *settingsRepository) Get(ctx context.Context, key string) (*models.Setting, error) {
+	query := `
+		SELECT key, value, updated_by, updated_at
+		FROM settings
+		WHERE key = $1
+	`
+
+	setting := &models.Setting{}
+	err := r.db.QueryRowContext(ctx, query, key).Scan(&setting.Key, &setting.Value, &setting.UpdatedBy, &setting.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSettingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get setting: %w", err)
+	}
+
+	return setting, nil
+}
+
+// Set stores a setting and records the change, with its reason, in the audit log
+// Both are written by one statement, so a change is never left unrecorded
+func (r *settingsRepository) Set(ctx context.Context, key, value, reason string, actor *string) (*models.Setting, error) {
+	query := `
+		WITH updated AS (
+			INSERT INTO settings (key, value, updated_by, updated_at)
+			VALUES ($1, $2, $4, CURRENT_TIMESTAMP)
+			ON CONFLICT (key) DO UPDATE
+			SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+			RETURNING key, value, updated_by, updated_at
+		), logged AS (
+			INSERT INTO settings_audit_log (key, value, reason, actor)
+			VALUES ($1, $2, $3, $4)
+		)
+		SELECT key, value, updated_by, updated_at FROM updated
+	`
+
+	setting := &models.Setting{}
+	err := retryTransient(ctx, func() error {
+		return r.db.QueryRowContext(ctx, query, key, value, reason, actor).
+			Scan(&setting.Key, &setting.Value, &setting.UpdatedBy, &setting.UpdatedAt)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set setting: %w", err)
+	}
+
+	return setting, nil
+}
+
+// ListChanges retrieves the most recent changes of a setting, newest first
+func (r *settingsRepository) ListChanges(ctx context.Context, key string, limit int) ([]*models.SettingChange, error) {
+	query := `
+		SELECT id, key, value, reason, actor, created_at
+		FROM settings_audit_log
+		WHERE key = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, key, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list setting changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []*models.SettingChange{}
+	for rows.Next() {
+		change := &models.SettingChange{}
+		if err := rows.Scan(&change.ID, &change.Key, &change.Value, &change.Reason, &change.Actor, &change.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan setting change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating setting changes: %w", err)
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"smsleopard/internal/models"
+)
+
+type statsRebuildRepository struct {
+	db DB
+}
+
+// NewStatsRebuildRepository creates a new stats rebuild repository
+func NewStatsRebuildRepository(db DB) StatsRebuildRepository {
+	return &statsRebuildRepository{db: db}
+}
+
+// ListMessageSummaries retrieves the summary fields of all a campaign's messages, by ID
+func (r *statsRebuildRepository) ListMessageSummaries(ctx context.Context, campaignID int) ([]*models.MessageSummary, error) {
+	query := `
+		SELECT id, status, retry_count, last_error, simulated
+		FROM outbound_messages
+		WHERE campaign_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list message summaries: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []*models.MessageSummary{}
+	for rows.Next() {
+		summary := &models.MessageSummary{}
+		if err := rows.Scan(&summary.ID, &summary.Status, &summary.RetryCount, &summary.LastError, &summary.Simulated); err != nil {
+			return nil, fmt.Errorf("failed to scan message summary: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating message summaries: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// CorrectMessage sets a campaign's message's summary fields to the ones given
+// The campaign_events trigger records the correction, so replaying again agrees with it
+func (r *statsRebuildRepository) CorrectMessage(ctx context.Context, campaignID int, summary *models.MessageSummary) error {
+	query := `
+		UPDATE outbound_messages
+		SET status = $3, retry_count = $4, last_error = $5, simulated = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND campaign_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, summary.ID, campaignID, summary.Status, summary.RetryCount, summary.LastError, summary.Simulated)
+	if err != nil {
+		return fmt.Errorf("failed to correct message: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrMessageNotFound
+	}
+
+	return nil
+}
+
+// CorrectCampaignStatus sets a campaign's status, without the checks of a transition
+func (r *statsRebuildRepository) CorrectCampaignStatus(ctx context.Context, campaignID int, status models.CampaignStatus) error {
+	query := `
+		UPDATE campaigns
+		SET status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	return r.correctCampaign(ctx, query, campaignID, status)
+}
+
+// CorrectSpend sets a campaign's spend counter
+func (r *statsRebuildRepository) CorrectSpend(ctx context.Context, campaignID int, spend float64) error {
+	query := `
+		UPDATE campaigns
+		SET spend = $2
+		WHERE id = $1
+	`
+
+	return r.correctCampaign(ctx, query, campaignID, spend)
+}
+
+func (r *statsRebuildRepository) correctCampaign(ctx context.Context, query string, campaignID int, value interface{}) error {
+	result, err := r.db.ExecContext(ctx, query, campaignID, value)
+	if err != nil {
+		return fmt.Errorf("failed to correct campaign: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrCampaignNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"smsleopard/internal/models"
+)
+
+type suppressionRepository struct {
+	db DB
+}
+
+// NewSuppressionRepository creates a new campaign suppression repository
+func NewSuppressionRepository(db DB) SuppressionRepository {
+	return &suppressionRepository{db: db}
+}
+
+// Add suppresses phones for a campaign and returns how many were not already suppressed
+// Phones must already be normalized
+func (r *suppressionRepository) Add(ctx context.Context, campaignID int, phones []string, addedBy *string) (int, error) {
+	if len(phones) == 0 {
+		return 0, nil
+	}
+
+	query := `
+		INSERT INTO campaign_suppressions (campaign_id, phone, added_by)
+		SELECT $1, phone, $3 FROM unnest($2::text[]) AS phone
+		ON CONFLICT (campaign_id, phone) DO NOTHING
+	`
+
+	result, err := r.db.ExecContext(ctx, query, campaignID, pq.Array(phones), addedBy)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add suppressions: %w", err)
+	}
+
+	added, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(added), nil
+}
+
+// List returns a campaign's suppressed phones, newest first
+func (r *suppressionRepository) List(ctx context.Context, campaignID int) ([]*models.CampaignSuppression, error) {
+	query := `
+		SELECT campaign_id, phone, added_by, created_at
+		FROM campaign_suppressions
+		WHERE campaign_id = $1
+		ORDER BY created_at DESC, phone
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suppressions: %w", err)
+	}
+	defer rows.Close()
+
+	suppressions := []*models.CampaignSuppression{}
+	for rows.Next() {
+		suppression := &models.CampaignSuppression{}
+		if err := rows.Scan(&suppression.CampaignID, &suppression.Phone, &suppression.AddedBy, &suppression.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan suppression: %w", err)
+		}
+		suppressions = append(suppressions, suppression)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating suppressions: %w", err)
+	}
+
+	return suppressions, nil
+}
+
+// Clear removes every suppression of a campaign and returns how many were removed
+func (r *suppressionRepository) Clear(ctx context.Context, campaignID int) (int, error) {
+	query := `DELETE FROM campaign_suppressions WHERE campaign_id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, campaignID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear suppressions: %w", err)
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(removed), nil
+}
+
+// FilterSuppressed returns which of the given phones a campaign suppresses
+// Phones must already be normalized; stored phones are compared in normalized form, so
+// suppressions stored before normalization (e.g. "0712345678") still match
+func (r *suppressionRepository) FilterSuppressed(ctx context.Context, campaignID int, phones []string) ([]string, error) {
+	if len(phones) == 0 {
+		return []string{}, nil
+	}
+
+	query := `
+		SELECT DISTINCT normalize_phone(phone)
+		FROM campaign_suppressions
+		WHERE campaign_id = $1 AND normalize_phone(phone) = ANY($2)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, campaignID, pq.Array(phones))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check suppressions: %w", err)
+	}
+	defer rows.Close()
+
+	suppressed := []string{}
+	for rows.Next() {
+		var phone string
+		if err := rows.Scan(&phone); err != nil {
+			return nil, fmt.Errorf("failed to scan suppressed phone: %w", err)
+		}
+		suppressed = append(suppressed, phone)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating suppressed phones: %w", err)
+	}
+
+	return suppressed, nil
+}
@@ -0,0 +1,476 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"smsleopard/internal/metrics"
+	"smsleopard/internal/models"
+)
+
+// Timed repositories wrap the customer, campaign and message repositories, recording every
+// call's duration by repository and method in metrics.RepositoryCallDuration
+// Unlike InstrumentedDB they time whole calls: row iteration, transactions and callbacks
+
+// observeCall records a call to repository.method that started at start
+func observeCall(repository, method string, start time.Time) {
+	metrics.RepositoryCallDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+}
+
+type timedCustomerRepository struct {
+	next CustomerRepository
+}
+
+// NewTimedCustomerRepository wraps next, timing every call under the repository label "customer"
+func NewTimedCustomerRepository(next CustomerRepository) CustomerRepository {
+	return &timedCustomerRepository{next: next}
+}
+
+func (r *timedCustomerRepository) Create(ctx context.Context, customer *models.Customer) error {
+	defer observeCall("customer", "Create", time.Now())
+	return r.next.Create(ctx, customer)
+}
+
+func (r *timedCustomerRepository) UpsertByPhone(ctx context.Context, customer *models.Customer) (bool, error) {
+	defer observeCall("customer", "UpsertByPhone", time.Now())
+	return r.next.UpsertByPhone(ctx, customer)
+}
+
+func (r *timedCustomerRepository) GetByID(ctx context.Context, id int) (*models.Customer, error) {
+	defer observeCall("customer", "GetByID", time.Now())
+	return r.next.GetByID(ctx, id)
+}
+
+func (r *timedCustomerRepository) GetByIDs(ctx context.Context, ids []int) ([]*models.Customer, error) {
+	defer observeCall("customer", "GetByIDs", time.Now())
+	return r.next.GetByIDs(ctx, ids)
+}
+
+func (r *timedCustomerRepository) GetByPhones(ctx context.Context, phones []string) ([]*models.Customer, error) {
+	defer observeCall("customer", "GetByPhones", time.Now())
+	return r.next.GetByPhones(ctx, phones)
+}
+
+func (r *timedCustomerRepository) List(ctx context.Context, limit, offset int) ([]*models.Customer, error) {
+	defer observeCall("customer", "List", time.Now())
+	return r.next.List(ctx, limit, offset)
+}
+
+func (r *timedCustomerRepository) ListFiltered(ctx context.Context, filters CustomerFilters, limit, offset int) ([]*models.Customer, error) {
+	defer observeCall("customer", "ListFiltered", time.Now())
+	return r.next.ListFiltered(ctx, filters, limit, offset)
+}
+
+func (r *timedCustomerRepository) Update(ctx context.Context, customer *models.Customer, changedBy *string) error {
+	defer observeCall("customer", "Update", time.Now())
+	return r.next.Update(ctx, customer, changedBy)
+}
+
+func (r *timedCustomerRepository) Delete(ctx context.Context, id int) error {
+	defer observeCall("customer", "Delete", time.Now())
+	return r.next.Delete(ctx, id)
+}
+
+func (r *timedCustomerRepository) DeleteWithMessages(ctx context.Context, id int) (int, error) {
+	defer observeCall("customer", "DeleteWithMessages", time.Now())
+	return r.next.DeleteWithMessages(ctx, id)
+}
+
+func (r *timedCustomerRepository) GetStats(ctx context.Context, location *string) (*models.CustomerStats, error) {
+	defer observeCall("customer", "GetStats", time.Now())
+	return r.next.GetStats(ctx, location)
+}
+
+func (r *timedCustomerRepository) GetTimeline(ctx context.Context, customerID int, after *models.TimelineCursor, limit int) ([]*models.TimelineEvent, error) {
+	defer observeCall("customer", "GetTimeline", time.Now())
+	return r.next.GetTimeline(ctx, customerID, after, limit)
+}
+
+func (r *timedCustomerRepository) CountMissingFields(ctx context.Context, ids []int, fields []string) (*models.FieldCompleteness, error) {
+	defer observeCall("customer", "CountMissingFields", time.Now())
+	return r.next.CountMissingFields(ctx, ids, fields)
+}
+
+func (r *timedCustomerRepository) CountFiltered(ctx context.Context, filters CustomerFilters) (int, error) {
+	defer observeCall("customer", "CountFiltered", time.Now())
+	return r.next.CountFiltered(ctx, filters)
+}
+
+func (r *timedCustomerRepository) StreamFiltered(ctx context.Context, filters CustomerFilters, limit int, fn func(customer *models.Customer) error) error {
+	defer observeCall("customer", "StreamFiltered", time.Now())
+	return r.next.StreamFiltered(ctx, filters, limit, fn)
+}
+
+func (r *timedCustomerRepository) GetDetail(ctx context.Context, id int) (*models.Customer, error) {
+	defer observeCall("customer", "GetDetail", time.Now())
+	return r.next.GetDetail(ctx, id)
+}
+
+func (r *timedCustomerRepository) Block(ctx context.Context, id int, reason string, actor *string) error {
+	defer observeCall("customer", "Block", time.Now())
+	return r.next.Block(ctx, id, reason, actor)
+}
+
+func (r *timedCustomerRepository) Unblock(ctx context.Context, id int, actor *string) error {
+	defer observeCall("customer", "Unblock", time.Now())
+	return r.next.Unblock(ctx, id, actor)
+}
+
+func (r *timedCustomerRepository) ListBlocked(ctx context.Context, ids []int) ([]int, error) {
+	defer observeCall("customer", "ListBlocked", time.Now())
+	return r.next.ListBlocked(ctx, ids)
+}
+
+func (r *timedCustomerRepository) ListBelowScore(ctx context.Context, ids []int, minScore float64) ([]int, error) {
+	defer observeCall("customer", "ListBelowScore", time.Now())
+	return r.next.ListBelowScore(ctx, ids, minScore)
+}
+
+func (r *timedCustomerRepository) ListBlockedPhones(ctx context.Context, phones []string) ([]string, error) {
+	defer observeCall("customer", "ListBlockedPhones", time.Now())
+	return r.next.ListBlockedPhones(ctx, phones)
+}
+
+func (r *timedCustomerRepository) ListBlockEvents(ctx context.Context, id int) ([]*models.CustomerBlockEvent, error) {
+	defer observeCall("customer", "ListBlockEvents", time.Now())
+	return r.next.ListBlockEvents(ctx, id)
+}
+
+func (r *timedCustomerRepository) ListPhoneHistory(ctx context.Context, id int) ([]*models.CustomerPhoneChange, error) {
+	defer observeCall("customer", "ListPhoneHistory", time.Now())
+	return r.next.ListPhoneHistory(ctx, id)
+}
+
+type timedCampaignRepository struct {
+	next CampaignRepository
+}
+
+// NewTimedCampaignRepository wraps next, timing every call under the repository label "campaign"
+func NewTimedCampaignRepository(next CampaignRepository) CampaignRepository {
+	return &timedCampaignRepository{next: next}
+}
+
+func (r *timedCampaignRepository) Create(ctx context.Context, campaign *models.Campaign) error {
+	defer observeCall("campaign", "Create", time.Now())
+	return r.next.Create(ctx, campaign)
+}
+
+func (r *timedCampaignRepository) GetByID(ctx context.Context, id int) (*models.Campaign, error) {
+	defer observeCall("campaign", "GetByID", time.Now())
+	return r.next.GetByID(ctx, id)
+}
+
+func (r *timedCampaignRepository) GetWithStats(ctx context.Context, id int) (*models.CampaignWithStats, error) {
+	defer observeCall("campaign", "GetWithStats", time.Now())
+	return r.next.GetWithStats(ctx, id)
+}
+
+func (r *timedCampaignRepository) List(ctx context.Context, filters CampaignFilters) ([]*models.Campaign, int, error) {
+	defer observeCall("campaign", "List", time.Now())
+	return r.next.List(ctx, filters)
+}
+
+func (r *timedCampaignRepository) UpdateStatusIf(ctx context.Context, id int, from, to models.CampaignStatus) error {
+	defer observeCall("campaign", "UpdateStatusIf", time.Now())
+	return r.next.UpdateStatusIf(ctx, id, from, to)
+}
+
+func (r *timedCampaignRepository) UpdateStatusIfTx(ctx context.Context, tx *sql.Tx, id int, from, to models.CampaignStatus) error {
+	defer observeCall("campaign", "UpdateStatusIfTx", time.Now())
+	return r.next.UpdateStatusIfTx(ctx, tx, id, from, to)
+}
+
+func (r *timedCampaignRepository) AwaitApproval(ctx context.Context, id int, from models.CampaignStatus, plan *models.SendPlan) error {
+	defer observeCall("campaign", "AwaitApproval", time.Now())
+	return r.next.AwaitApproval(ctx, id, from, plan)
+}
+
+func (r *timedCampaignRepository) GetSendPlan(ctx context.Context, id int) (*models.SendPlan, error) {
+	defer observeCall("campaign", "GetSendPlan", time.Now())
+	return r.next.GetSendPlan(ctx, id)
+}
+
+func (r *timedCampaignRepository) ClearSendPlan(ctx context.Context, id int) error {
+	defer observeCall("campaign", "ClearSendPlan", time.Now())
+	return r.next.ClearSendPlan(ctx, id)
+}
+
+func (r *timedCampaignRepository) HoldCanary(ctx context.Context, id int, plan *models.SendPlan) error {
+	defer observeCall("campaign", "HoldCanary", time.Now())
+	return r.next.HoldCanary(ctx, id, plan)
+}
+
+func (r *timedCampaignRepository) RecordSend(ctx context.Context, record *models.SendRecord) error {
+	defer observeCall("campaign", "RecordSend", time.Now())
+	return r.next.RecordSend(ctx, record)
+}
+
+func (r *timedCampaignRepository) UpdateSendProgress(ctx context.Context, id int, messagesQueued int, status models.CampaignStatus) error {
+	defer observeCall("campaign", "UpdateSendProgress", time.Now())
+	return r.next.UpdateSendProgress(ctx, id, messagesQueued, status)
+}
+
+func (r *timedCampaignRepository) ListSends(ctx context.Context, campaignID int) ([]*models.SendRecord, error) {
+	defer observeCall("campaign", "ListSends", time.Now())
+	return r.next.ListSends(ctx, campaignID)
+}
+
+func (r *timedCampaignRepository) Delete(ctx context.Context, id int) error {
+	defer observeCall("campaign", "Delete", time.Now())
+	return r.next.Delete(ctx, id)
+}
+
+func (r *timedCampaignRepository) DeleteWithMessages(ctx context.Context, id int) (int, error) {
+	defer observeCall("campaign", "DeleteWithMessages", time.Now())
+	return r.next.DeleteWithMessages(ctx, id)
+}
+
+func (r *timedCampaignRepository) ListNeedingAttention(ctx context.Context) ([]*models.CampaignAttention, error) {
+	defer observeCall("campaign", "ListNeedingAttention", time.Now())
+	return r.next.ListNeedingAttention(ctx)
+}
+
+func (r *timedCampaignRepository) GetStatsByIDs(ctx context.Context, ids []int) (map[int]*models.CampaignStats, error) {
+	defer observeCall("campaign", "GetStatsByIDs", time.Now())
+	return r.next.GetStatsByIDs(ctx, ids)
+}
+
+func (r *timedCampaignRepository) GetFailureBreakdownByIDs(ctx context.Context, ids []int) (map[int][]*models.ErrorCount, error) {
+	defer observeCall("campaign", "GetFailureBreakdownByIDs", time.Now())
+	return r.next.GetFailureBreakdownByIDs(ctx, ids)
+}
+
+func (r *timedCampaignRepository) StreamWithStats(ctx context.Context, filters CampaignExportFilters, fn func(row *models.CampaignExportRow) error) error {
+	defer observeCall("campaign", "StreamWithStats", time.Now())
+	return r.next.StreamWithStats(ctx, filters, fn)
+}
+
+func (r *timedCampaignRepository) ReserveSpend(ctx context.Context, id int, cost float64) (bool, error) {
+	defer observeCall("campaign", "ReserveSpend", time.Now())
+	return r.next.ReserveSpend(ctx, id, cost)
+}
+
+func (r *timedCampaignRepository) ReleaseSpend(ctx context.Context, id int, cost float64) error {
+	defer observeCall("campaign", "ReleaseSpend", time.Now())
+	return r.next.ReleaseSpend(ctx, id, cost)
+}
+
+func (r *timedCampaignRepository) Pause(ctx context.Context, id int, reason string) (bool, error) {
+	defer observeCall("campaign", "Pause", time.Now())
+	return r.next.Pause(ctx, id, reason)
+}
+
+func (r *timedCampaignRepository) BeginCancel(ctx context.Context, id int, grace time.Duration) (time.Time, error) {
+	defer observeCall("campaign", "BeginCancel", time.Now())
+	return r.next.BeginCancel(ctx, id, grace)
+}
+
+func (r *timedCampaignRepository) UndoCancel(ctx context.Context, id int) error {
+	defer observeCall("campaign", "UndoCancel", time.Now())
+	return r.next.UndoCancel(ctx, id)
+}
+
+func (r *timedCampaignRepository) ListDueCancellations(ctx context.Context) ([]int, error) {
+	defer observeCall("campaign", "ListDueCancellations", time.Now())
+	return r.next.ListDueCancellations(ctx)
+}
+
+func (r *timedCampaignRepository) ListOrderedOutstanding(ctx context.Context) ([]int, error) {
+	defer observeCall("campaign", "ListOrderedOutstanding", time.Now())
+	return r.next.ListOrderedOutstanding(ctx)
+}
+
+func (r *timedCampaignRepository) FinalizeCancellation(ctx context.Context, id int) (int, error) {
+	defer observeCall("campaign", "FinalizeCancellation", time.Now())
+	return r.next.FinalizeCancellation(ctx, id)
+}
+
+func (r *timedCampaignRepository) Resume(ctx context.Context, id int, budget *float64) error {
+	defer observeCall("campaign", "Resume", time.Now())
+	return r.next.Resume(ctx, id, budget)
+}
+
+type timedMessageRepository struct {
+	next MessageRepository
+}
+
+// NewTimedMessageRepository wraps next, timing every call under the repository label "message"
+func NewTimedMessageRepository(next MessageRepository) MessageRepository {
+	return &timedMessageRepository{next: next}
+}
+
+func (r *timedMessageRepository) Create(ctx context.Context, message *models.OutboundMessage) error {
+	defer observeCall("message", "Create", time.Now())
+	return r.next.Create(ctx, message)
+}
+
+func (r *timedMessageRepository) CreateBatch(ctx context.Context, messages []*models.OutboundMessage) error {
+	defer observeCall("message", "CreateBatch", time.Now())
+	return r.next.CreateBatch(ctx, messages)
+}
+
+func (r *timedMessageRepository) CreateBatchTx(ctx context.Context, tx *sql.Tx, messages []*models.OutboundMessage) error {
+	defer observeCall("message", "CreateBatchTx", time.Now())
+	return r.next.CreateBatchTx(ctx, tx, messages)
+}
+
+func (r *timedMessageRepository) GetByID(ctx context.Context, id int) (*models.OutboundMessage, error) {
+	defer observeCall("message", "GetByID", time.Now())
+	return r.next.GetByID(ctx, id)
+}
+
+func (r *timedMessageRepository) GetWithDetails(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+	defer observeCall("message", "GetWithDetails", time.Now())
+	return r.next.GetWithDetails(ctx, id)
+}
+
+func (r *timedMessageRepository) UpdateStatus(ctx context.Context, id int, status models.MessageStatus, lastError *string) error {
+	defer observeCall("message", "UpdateStatus", time.Now())
+	return r.next.UpdateStatus(ctx, id, status, lastError)
+}
+
+func (r *timedMessageRepository) MarkPublished(ctx context.Context, ids []int) error {
+	defer observeCall("message", "MarkPublished", time.Now())
+	return r.next.MarkPublished(ctx, ids)
+}
+
+func (r *timedMessageRepository) ReserveAttempt(ctx context.Context, customerID int, day time.Time, budget int) (bool, error) {
+	defer observeCall("message", "ReserveAttempt", time.Now())
+	return r.next.ReserveAttempt(ctx, customerID, day, budget)
+}
+
+func (r *timedMessageRepository) DeferUntil(ctx context.Context, id int, until time.Time, reason string) error {
+	defer observeCall("message", "DeferUntil", time.Now())
+	return r.next.DeferUntil(ctx, id, until, reason)
+}
+
+func (r *timedMessageRepository) ClaimDueDeferred(ctx context.Context, now time.Time, limit int) ([]*models.OutboundMessage, error) {
+	defer observeCall("message", "ClaimDueDeferred", time.Now())
+	return r.next.ClaimDueDeferred(ctx, now, limit)
+}
+
+func (r *timedMessageRepository) Hold(ctx context.Context, id int, reason string) error {
+	defer observeCall("message", "Hold", time.Now())
+	return r.next.Hold(ctx, id, reason)
+}
+
+func (r *timedMessageRepository) ReleaseHeld(ctx context.Context, campaignID int) (int, error) {
+	defer observeCall("message", "ReleaseHeld", time.Now())
+	return r.next.ReleaseHeld(ctx, campaignID)
+}
+
+func (r *timedMessageRepository) GetPendingMessages(ctx context.Context, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error) {
+	defer observeCall("message", "GetPendingMessages", time.Now())
+	return r.next.GetPendingMessages(ctx, limit, order)
+}
+
+func (r *timedMessageRepository) GetByCampaignID(ctx context.Context, campaignID int) ([]*models.OutboundMessage, error) {
+	defer observeCall("message", "GetByCampaignID", time.Now())
+	return r.next.GetByCampaignID(ctx, campaignID)
+}
+
+func (r *timedMessageRepository) GetPendingByCampaignID(ctx context.Context, campaignID, limit int) ([]*models.OutboundMessage, error) {
+	defer observeCall("message", "GetPendingByCampaignID", time.Now())
+	return r.next.GetPendingByCampaignID(ctx, campaignID, limit)
+}
+
+func (r *timedMessageRepository) ClearPendingRenderedContent(ctx context.Context, campaignID int) (int, error) {
+	defer observeCall("message", "ClearPendingRenderedContent", time.Now())
+	return r.next.ClearPendingRenderedContent(ctx, campaignID)
+}
+
+func (r *timedMessageRepository) GetDeliveryStatsByChannel(ctx context.Context, since time.Time) ([]*models.ChannelDeliveryStats, error) {
+	defer observeCall("message", "GetDeliveryStatsByChannel", time.Now())
+	return r.next.GetDeliveryStatsByChannel(ctx, since)
+}
+
+func (r *timedMessageRepository) GetRetryEffectiveness(ctx context.Context, from, to time.Time) ([]*models.ChannelRetryEffectiveness, error) {
+	defer observeCall("message", "GetRetryEffectiveness", time.Now())
+	return r.next.GetRetryEffectiveness(ctx, from, to)
+}
+
+func (r *timedMessageRepository) CountRecentRecipients(ctx context.Context, customerIDs []int, excludeCampaignID int, since time.Time) (int, error) {
+	defer observeCall("message", "CountRecentRecipients", time.Now())
+	return r.next.CountRecentRecipients(ctx, customerIDs, excludeCampaignID, since)
+}
+
+func (r *timedMessageRepository) CountRecentFingerprintRecipients(ctx context.Context, customerIDs []int, fingerprint string, excludeCampaignID int, since time.Time) (int, error) {
+	defer observeCall("message", "CountRecentFingerprintRecipients", time.Now())
+	return r.next.CountRecentFingerprintRecipients(ctx, customerIDs, fingerprint, excludeCampaignID, since)
+}
+
+func (r *timedMessageRepository) ClaimNextPart(ctx context.Context, groupID, part int) (*models.OutboundMessage, error) {
+	defer observeCall("message", "ClaimNextPart", time.Now())
+	return r.next.ClaimNextPart(ctx, groupID, part)
+}
+
+func (r *timedMessageRepository) CreateResend(ctx context.Context, originalID int, verbatim bool, fingerprint *string) (*models.OutboundMessage, error) {
+	defer observeCall("message", "CreateResend", time.Now())
+	return r.next.CreateResend(ctx, originalID, verbatim, fingerprint)
+}
+
+func (r *timedMessageRepository) HasRecentFingerprintSend(ctx context.Context, customerID int, fingerprint string, excludeMessageID int, since time.Time) (bool, error) {
+	defer observeCall("message", "HasRecentFingerprintSend", time.Now())
+	return r.next.HasRecentFingerprintSend(ctx, customerID, fingerprint, excludeMessageID, since)
+}
+
+func (r *timedMessageRepository) ListFrequencyCapped(ctx context.Context, customerIDs []int, since time.Time, maxMessages int) ([]int, error) {
+	defer observeCall("message", "ListFrequencyCapped", time.Now())
+	return r.next.ListFrequencyCapped(ctx, customerIDs, since, maxMessages)
+}
+
+func (r *timedMessageRepository) ListByCampaignIDs(ctx context.Context, campaignIDs []int, filters MessageFilters) ([]*models.OutboundMessage, error) {
+	defer observeCall("message", "ListByCampaignIDs", time.Now())
+	return r.next.ListByCampaignIDs(ctx, campaignIDs, filters)
+}
+
+func (r *timedMessageRepository) ListByCustomerIDs(ctx context.Context, customerIDs []int, filters MessageFilters) ([]*models.OutboundMessage, error) {
+	defer observeCall("message", "ListByCustomerIDs", time.Now())
+	return r.next.ListByCustomerIDs(ctx, customerIDs, filters)
+}
+
+func (r *timedMessageRepository) ReencryptContent(ctx context.Context, afterID, limit int) (*ReencryptBatch, error) {
+	defer observeCall("message", "ReencryptContent", time.Now())
+	return r.next.ReencryptContent(ctx, afterID, limit)
+}
+
+func (r *timedMessageRepository) ListMissingContent(ctx context.Context, filters ContentBackfillFilters, afterID, limit int) ([]*models.OutboundMessageWithDetails, error) {
+	defer observeCall("message", "ListMissingContent", time.Now())
+	return r.next.ListMissingContent(ctx, filters, afterID, limit)
+}
+
+func (r *timedMessageRepository) StoreBackfilledContent(ctx context.Context, contents map[int]string) (int, error) {
+	defer observeCall("message", "StoreBackfilledContent", time.Now())
+	return r.next.StoreBackfilledContent(ctx, contents)
+}
+
+func (r *timedMessageRepository) ListForExport(ctx context.Context, campaignID, afterID, limit int) ([]*models.OutboundMessageWithDetails, error) {
+	defer observeCall("message", "ListForExport", time.Now())
+	return r.next.ListForExport(ctx, campaignID, afterID, limit)
+}
+
+func (r *timedMessageRepository) ClaimUnpublished(ctx context.Context, createdBefore time.Time, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error) {
+	defer observeCall("message", "ClaimUnpublished", time.Now())
+	return r.next.ClaimUnpublished(ctx, createdBefore, limit, order)
+}
+
+func (r *timedMessageRepository) GetPendingBacklog(ctx context.Context) ([]*models.CampaignPendingBacklog, error) {
+	defer observeCall("message", "GetPendingBacklog", time.Now())
+	return r.next.GetPendingBacklog(ctx)
+}
+
+func (r *timedMessageRepository) CountUnpublished(ctx context.Context) (int, error) {
+	defer observeCall("message", "CountUnpublished", time.Now())
+	return r.next.CountUnpublished(ctx)
+}
+
+func (r *timedMessageRepository) GetStatuses(ctx context.Context, ids []int) (map[int]models.MessageStatus, error) {
+	defer observeCall("message", "GetStatuses", time.Now())
+	return r.next.GetStatuses(ctx, ids)
+}
+
+func (r *timedMessageRepository) ListQueued(ctx context.Context, publishedBefore time.Time) ([]*models.OutboundMessage, error) {
+	defer observeCall("message", "ListQueued", time.Now())
+	return r.next.ListQueued(ctx, publishedBefore)
+}
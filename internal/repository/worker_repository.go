@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"smsleopard/internal/models"
+)
+
+type workerRepository struct {
+	db DB
+}
+
+// NewWorkerRepository creates a new worker repository
+func NewWorkerRepository(db DB) WorkerRepository {
+	return &workerRepository{db: db}
+}
+
+// Heartbeat records that workerID, running since startedAt, is alive
+func (r *workerRepository) Heartbeat(ctx context.Context, workerID string, startedAt time.Time) error {
+	query := `
+		INSERT INTO worker_heartbeats (worker_id, started_at, last_seen_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (worker_id) DO UPDATE
+		SET started_at = EXCLUDED.started_at, last_seen_at = CURRENT_TIMESTAMP
+	`
+
+	if _, err := ExecWithRetry(ctx, r.db, query, workerID, startedAt); err != nil {
+		return fmt.Errorf("failed to record worker heartbeat: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseAll releases every claim workerID still holds and returns how many there were
+// A restarted worker with a fixed WORKER_ID calls it so its earlier run's claims do not
+// block other workers until they expire
+func (r *workerRepository) ReleaseAll(ctx context.Context, workerID string) (int64, error) {
+	query := `
+		UPDATE outbound_messages
+		SET claim_expires_at = NULL
+		WHERE claimed_by = $1 AND claim_expires_at IS NOT NULL
+	`
+
+	result, err := ExecWithRetry(ctx, r.db, query, workerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release worker claims: %w", err)
+	}
+
+	released, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return released, nil
+}
+
+// Claim marks workerID as handling messageID for up to ttl and reports whether it was claimed
+// A claimed message, even one claimed by workerID itself (a job requeued while its handler is
+// stuck), is not claimed again until that claim is released or expires; concurrent callers
+// never both claim the same message
+func (r *workerRepository) Claim(ctx context.Context, messageID int, workerID string, ttl time.Duration) (bool, error) {
+	query := `
+		UPDATE outbound_messages
+		SET claimed_by = $2,
+			claimed_at = CURRENT_TIMESTAMP,
+			claim_expires_at = CURRENT_TIMESTAMP + make_interval(secs => $3)
+		WHERE id = $1
+			AND (claim_expires_at IS NULL OR claim_expires_at < CURRENT_TIMESTAMP)
+	`
+
+	result, err := ExecWithRetry(ctx, r.db, query, messageID, workerID, ttl.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim message: %w", err)
+	}
+
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return claimed == 1, nil
+}
+
+// Release gives up workerID's claim on messageID, keeping claimed_by as the record of who
+// handled it
+func (r *workerRepository) Release(ctx context.Context, messageID int, workerID string) error {
+	query := `
+		UPDATE outbound_messages
+		SET claim_expires_at = NULL
+		WHERE id = $1 AND claimed_by = $2
+	`
+
+	if _, err := ExecWithRetry(ctx, r.db, query, messageID, workerID); err != nil {
+		return fmt.Errorf("failed to release message claim: %w", err)
+	}
+
+	return nil
+}
+
+// GetActivity returns workerID's last heartbeat, its claims' outcomes over the last hour and
+// day, and its limit most recent claims
+func (r *workerRepository) GetActivity(ctx context.Context, workerID string, limit int) (*models.WorkerActivity, error) {
+	activity := &models.WorkerActivity{WorkerID: workerID, RecentClaims: []*models.WorkerClaim{}}
+
+	heartbeatQuery := `SELECT started_at, last_seen_at FROM worker_heartbeats WHERE worker_id = $1`
+	var startedAt, lastSeenAt time.Time
+	err := r.db.QueryRowContext(ctx, heartbeatQuery, workerID).Scan(&startedAt, &lastSeenAt)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, fmt.Errorf("failed to get worker heartbeat: %w", err)
+	default:
+		activity.StartedAt = &startedAt
+		activity.LastSeenAt = &lastSeenAt
+	}
+
+	throughputQuery := `
+		SELECT
+			COUNT(*) FILTER (WHERE claimed_at >= CURRENT_TIMESTAMP - INTERVAL '1 hour'),
+			COUNT(*) FILTER (WHERE claimed_at >= CURRENT_TIMESTAMP - INTERVAL '1 hour' AND status = 'sent'),
+			COUNT(*) FILTER (WHERE claimed_at >= CURRENT_TIMESTAMP - INTERVAL '1 hour' AND status = 'failed'),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'sent'),
+			COUNT(*) FILTER (WHERE status = 'failed')
+		FROM outbound_messages
+		WHERE claimed_by = $1 AND claimed_at >= CURRENT_TIMESTAMP - INTERVAL '1 day'
+	`
+	err = r.db.QueryRowContext(ctx, throughputQuery, workerID).Scan(
+		&activity.LastHour.Claimed, &activity.LastHour.Sent, &activity.LastHour.Failed,
+		&activity.LastDay.Claimed, &activity.LastDay.Sent, &activity.LastDay.Failed,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count worker claims: %w", err)
+	}
+
+	claimsQuery := `
+		SELECT id, campaign_id, status, claimed_at,
+			COALESCE(claim_expires_at > CURRENT_TIMESTAMP, FALSE)
+		FROM outbound_messages
+		WHERE claimed_by = $1
+		ORDER BY claimed_at DESC, id DESC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, claimsQuery, workerID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worker claims: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		claim := &models.WorkerClaim{}
+		if err := rows.Scan(&claim.MessageID, &claim.CampaignID, &claim.Status, &claim.ClaimedAt, &claim.InFlight); err != nil {
+			return nil, fmt.Errorf("failed to scan worker claim: %w", err)
+		}
+		activity.RecentClaims = append(activity.RecentClaims, claim)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating worker claims: %w", err)
+	}
+
+	if activity.LastSeenAt == nil && len(activity.RecentClaims) == 0 {
+		return nil, ErrWorkerNotFound
+	}
+
+	return activity, nil
+}
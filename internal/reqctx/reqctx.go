@@ -0,0 +1,114 @@
+// Package reqctx carries request-scoped values in a context under typed keys
+// Each value has a With* setter and an accessor reporting whether it was set, so a missing
+// or mistyped value is never a panic
+package reqctx
+
+import (
+	"context"
+	"log"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+)
+
+// Unexported key types; no other package can set or collide with them
+type (
+	requestIDKey struct{}
+	identityKey  struct{}
+	orgIDKey     struct{}
+	localeKey    struct{}
+)
+
+// Identity is the authenticated caller behind a request
+type Identity struct {
+	UserID string
+	Team   string
+	Role   string
+}
+
+// IsAdmin reports whether the caller has the admin role
+func (i *Identity) IsAdmin() bool {
+	return i.Role == config.RoleAdmin
+}
+
+// CanModify reports whether the caller may send or change the campaign
+// Admins may modify any campaign; members only those they created or that belong to their team
+func (i *Identity) CanModify(campaign *models.Campaign) bool {
+	if i.IsAdmin() {
+		return true
+	}
+	if campaign.CreatedBy != nil && *campaign.CreatedBy == i.UserID {
+		return true
+	}
+	return i.Team != "" && campaign.Team != nil && *campaign.Team == i.Team
+}
+
+// WithRequestID returns a copy of ctx carrying the request's ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request's ID and whether one was set
+func RequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// WithIdentity returns a copy of ctx carrying the caller's identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFrom returns the caller's identity and whether one was set; there is none when
+// authentication is disabled
+func IdentityFrom(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok && identity != nil
+}
+
+// WithOrgID returns a copy of ctx carrying the ID of the organization the request acts for
+func WithOrgID(ctx context.Context, orgID int) context.Context {
+	return context.WithValue(ctx, orgIDKey{}, orgID)
+}
+
+// OrgID returns the ID of the organization the request acts for and whether one was set
+func OrgID(ctx context.Context) (int, bool) {
+	orgID, ok := ctx.Value(orgIDKey{}).(int)
+	return orgID, ok
+}
+
+// WithLocale returns a copy of ctx carrying the caller's locale, e.g. "en-KE"
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// Locale returns the caller's locale and whether one was set
+func Locale(ctx context.Context) (string, bool) {
+	locale, ok := ctx.Value(localeKey{}).(string)
+	return locale, ok && locale != ""
+}
+
+// Require logs that a value the caller needs is missing from ctx, naming the request when it
+// has an ID, and returns ok; callers carry on with the zero value instead of panicking
+//
+//	orgID, ok := reqctx.OrgID(ctx)
+//	if !reqctx.Require(ctx, "org ID", ok) { ... }
+func Require(ctx context.Context, name string, ok bool) bool {
+	if ok {
+		return true
+	}
+	if id, hasID := RequestID(ctx); hasID {
+		log.Printf("⚠️  Request %s has no %s in its context", id, name)
+	} else {
+		log.Printf("⚠️  Request has no %s in its context", name)
+	}
+	return false
+}
+
+// Prefix returns "[<request ID>] " for log lines about the request, or "" without an ID
+func Prefix(ctx context.Context) string {
+	if id, ok := RequestID(ctx); ok {
+		return "[" + id + "] "
+	}
+	return ""
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/notify"
+	"smsleopard/internal/repository"
+)
+
+// DigestTopErrors is how many of the most common failure reasons a digest lists
+const DigestTopErrors = 5
+
+// DigestClaimTimeout is how long a digest claimed by a worker that never finished sending
+// it is held before another worker may send it
+const DigestClaimTimeout = 15 * time.Minute
+
+// DigestRetryInterval is how long the worker waits before retrying a digest that failed to deliver
+const DigestRetryInterval = 15 * time.Minute
+
+// ActivityDigestService summarizes each day's sending activity and delivers it once per day
+type ActivityDigestService struct {
+	digestRepo repository.DigestRepository
+	notifier   notify.Notifier
+	sending    config.SendingConfig
+	digest     config.DigestConfig
+	now        func() time.Time
+}
+
+// NewActivityDigestService creates a new activity digest service
+func NewActivityDigestService(digestRepo repository.DigestRepository, notifier notify.Notifier, sending config.SendingConfig, digest config.DigestConfig) *ActivityDigestService {
+	return &ActivityDigestService{
+		digestRepo: digestRepo,
+		notifier:   notifier,
+		sending:    sending,
+		digest:     digest,
+		now:        time.Now,
+	}
+}
+
+// SetClock overrides time.Now (for testing)
+func (s *ActivityDigestService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Build aggregates the activity of the day containing day, midnight to midnight in DIGEST_TZ
+func (s *ActivityDigestService) Build(ctx context.Context, day time.Time) (*models.ActivityDigest, error) {
+	local := day.In(s.digest.Location)
+	from := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.digest.Location)
+	to := from.AddDate(0, 0, 1)
+
+	digest := &models.ActivityDigest{
+		Date:     from.Format("2006-01-02"),
+		TimeZone: s.digest.Location.String(),
+		From:     from,
+		To:       to,
+	}
+
+	var err error
+	digest.CampaignsCompleted, digest.CampaignsFailed, err = s.digestRepo.CountFinishedCampaigns(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	digest.Channels, err = s.digestRepo.ListChannelActivity(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var spend float64
+	for _, channel := range digest.Channels {
+		channel.Spend = roundTo(float64(channel.Sent-channel.Simulated)*costPerMessage(s.sending, channel.Channel), 2)
+		spend += channel.Spend
+	}
+	digest.Spend = roundTo(spend, 2)
+
+	digest.TopErrors, err = s.digestRepo.ListTopErrors(ctx, from, to, DigestTopErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	return digest, nil
+}
+
+// Send delivers the digest of the day containing day and reports whether it was sent
+// A day is delivered at most once: reruns, and other workers, skip a day already sent
+// or being sent. A failed delivery releases the day so the next run retries it
+func (s *ActivityDigestService) Send(ctx context.Context, day time.Time) (bool, error) {
+	if s.notifier == nil {
+		return false, fmt.Errorf("no notifier configured")
+	}
+
+	date := day.In(s.digest.Location).Format("2006-01-02")
+	claimed, err := s.digestRepo.Claim(ctx, date, DigestClaimTimeout)
+	if err != nil {
+		return false, err
+	}
+	if !claimed {
+		return false, nil
+	}
+
+	digest, err := s.Build(ctx, day)
+	if err == nil {
+		err = s.deliver(ctx, digest)
+	}
+	if err != nil {
+		if releaseErr := s.digestRepo.Release(ctx, date); releaseErr != nil {
+			log.Printf("Warning: Failed to release digest for %s: %v", date, releaseErr)
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// deliver notifies the digest and records it as sent
+func (s *ActivityDigestService) deliver(ctx context.Context, digest *models.ActivityDigest) error {
+	var sent, failed int
+	for _, channel := range digest.Channels {
+		sent += channel.Sent
+		failed += channel.Failed
+	}
+
+	subject := fmt.Sprintf("Sending digest for %s: %d sent, %d failed", digest.Date, sent, failed)
+	if err := s.notifier.Notify(ctx, subject, digest); err != nil {
+		return fmt.Errorf("failed to send activity digest: %w", err)
+	}
+
+	payload, err := json.Marshal(digest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal activity digest: %w", err)
+	}
+	// The digest went out; failing to record it only risks sending it again
+	if err := s.digestRepo.MarkSent(ctx, digest.Date, payload); err != nil {
+		log.Printf("Warning: Activity digest for %s sent but not recorded: %v", digest.Date, err)
+	}
+
+	return nil
+}
+
+// Run sends the previous day's digest at DIGEST_SEND_HOUR every day until ctx is cancelled
+// A worker starting after the send hour sends a digest still missing for yesterday, and
+// a failed delivery is retried every DigestRetryInterval until the next day's digest is due
+func (s *ActivityDigestService) Run(ctx context.Context) {
+	var failed time.Time // Day whose delivery failed, zero when none
+	if now := s.now(); now.In(s.digest.Location).Hour() >= s.digest.SendHour {
+		failed = s.sendDay(ctx, previousDay(now, s.digest.Location))
+	}
+
+	for {
+		next := s.digest.NextRun(s.now())
+		wait := time.Until(next)
+		if !failed.IsZero() && DigestRetryInterval < wait {
+			wait = DigestRetryInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !failed.IsZero() && s.now().Before(next) {
+			failed = s.sendDay(ctx, failed)
+			continue
+		}
+		failed = s.sendDay(ctx, previousDay(next, s.digest.Location))
+	}
+}
+
+// sendDay sends a day's digest, logging the outcome, and returns the day when delivery failed
+func (s *ActivityDigestService) sendDay(ctx context.Context, day time.Time) time.Time {
+	date := day.Format("2006-01-02")
+	sent, err := s.Send(ctx, day)
+	if err != nil {
+		log.Printf("Warning: Failed to send activity digest for %s: %v", date, err)
+		return day
+	}
+	if sent {
+		log.Printf("📊 Activity digest for %s sent", date)
+	}
+	return time.Time{}
+}
+
+// previousDay returns the day before t in loc
+func previousDay(t time.Time, loc *time.Location) time.Time {
+	return t.In(loc).AddDate(0, 0, -1)
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// APIKeyRefreshInterval is how often each API process reloads the issued keys, so a key
+// revoked on another process stops working within it
+const APIKeyRefreshInterval = 30 * time.Second
+
+// apiKeyBytes is the random bytes in an issued key (43 characters encoded)
+const apiKeyBytes = 32
+
+// maxAPIKeyField is the longest label, user or team an issued key may carry
+const maxAPIKeyField = 255
+
+// CreateAPIKeyRequest represents the request to issue an API key
+type CreateAPIKeyRequest struct {
+	Label  string  `json:"label"`
+	UserID string  `json:"user_id"`
+	Role   string  `json:"role"`
+	Team   *string `json:"team,omitempty"`
+}
+
+// CreatedAPIKey is a newly issued key with its plaintext, which is never shown again
+type CreatedAPIKey struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyService issues and revokes API keys and authenticates them from an in-memory copy of
+// the active keys, refreshed every APIKeyRefreshInterval
+// Uses are remembered and written to last_used_at on each refresh, not on every request
+type APIKeyService struct {
+	repo repository.APIKeyRepository
+	now  func() time.Time
+
+	mu     sync.Mutex
+	byHash map[string]*models.APIKey
+	used   map[int]bool
+}
+
+// NewAPIKeyService creates a new API key service
+// No issued key is accepted until Refresh loads them
+func NewAPIKeyService(repo repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		repo:   repo,
+		now:    time.Now,
+		byHash: make(map[string]*models.APIKey),
+		used:   make(map[int]bool),
+	}
+}
+
+// hashAPIKey returns the hex SHA-256 a key is stored and looked up by
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// newAPIKey returns a random URL-safe key
+func newAPIKey() (string, error) {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Create issues a key; the plaintext is returned once and only its hash is stored
+// The key is accepted by this process straight away and by others after their next refresh
+func (s *APIKeyService) Create(ctx context.Context, req *CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	req.Label = strings.TrimSpace(req.Label)
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.Label == "" {
+		return nil, &ValidationError{Message: "label is required"}
+	}
+	if req.UserID == "" {
+		return nil, &ValidationError{Message: "user_id is required"}
+	}
+	if req.Role != config.RoleAdmin && req.Role != config.RoleMember {
+		return nil, &ValidationError{Message: fmt.Sprintf("role must be %q or %q", config.RoleAdmin, config.RoleMember)}
+	}
+	if req.Team != nil && strings.TrimSpace(*req.Team) == "" {
+		req.Team = nil
+	}
+	if len(req.Label) > maxAPIKeyField || len(req.UserID) > maxAPIKeyField || (req.Team != nil && len(*req.Team) > maxAPIKeyField) {
+		return nil, &ValidationError{Message: fmt.Sprintf("label, user_id and team must be at most %d characters", maxAPIKeyField)}
+	}
+
+	plaintext, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	key := &models.APIKey{
+		KeyHash: hashAPIKey(plaintext),
+		Label:   req.Label,
+		UserID:  req.UserID,
+		Role:    req.Role,
+		Team:    req.Team,
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.byHash[key.KeyHash] = key
+	s.mu.Unlock()
+
+	log.Printf("🔑 API key %d (%s) issued for %s as %s", key.ID, key.Label, key.UserID, key.Role)
+	return &CreatedAPIKey{APIKey: key, Key: plaintext}, nil
+}
+
+// List returns every issued key, revoked ones included, newest first
+func (s *APIKeyService) List(ctx context.Context) ([]*models.APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+// Revoke revokes a key; this process refuses it straight away and others after their next refresh
+func (s *APIKeyService) Revoke(ctx context.Context, id int) (*models.APIKey, error) {
+	key, err := s.repo.Revoke(ctx, id)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, &NotFoundError{Resource: "API key", ID: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.byHash, key.KeyHash)
+	s.mu.Unlock()
+
+	log.Printf("🔑 API key %d (%s) revoked", key.ID, key.Label)
+	return key, nil
+}
+
+// HasKeys reports whether any issued key is active
+func (s *APIKeyService) HasKeys() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.byHash) > 0
+}
+
+// Lookup returns the active issued key matching key, or nil, and remembers the use
+func (s *APIKeyService) Lookup(key string) *models.APIKey {
+	hash := hashAPIKey(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found, ok := s.byHash[hash]
+	if !ok {
+		return nil
+	}
+	s.used[found.ID] = true
+	return found
+}
+
+// Refresh records the uses since the last refresh and reloads the active keys
+// A failed write of uses is logged and retried on the next refresh
+func (s *APIKeyService) Refresh(ctx context.Context) error {
+	s.flushUsed(ctx)
+
+	keys, err := s.repo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	byHash := make(map[string]*models.APIKey, len(keys))
+	for _, key := range keys {
+		byHash[key.KeyHash] = key
+	}
+
+	s.mu.Lock()
+	s.byHash = byHash
+	s.mu.Unlock()
+	return nil
+}
+
+// flushUsed writes last_used_at for the keys used since the last flush
+func (s *APIKeyService) flushUsed(ctx context.Context) {
+	s.mu.Lock()
+	ids := make([]int, 0, len(s.used))
+	for id := range s.used {
+		ids = append(ids, id)
+	}
+	s.used = make(map[int]bool)
+	s.mu.Unlock()
+
+	if err := s.repo.MarkUsed(ctx, ids, s.now()); err != nil {
+		log.Printf("Warning: Failed to record API key use: %v", err)
+
+		s.mu.Lock()
+		for _, id := range ids {
+			s.used[id] = true
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Run refreshes every interval until ctx is cancelled, then records the last uses
+func (s *APIKeyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.flushUsed(context.Background())
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				log.Printf("Warning: Failed to refresh API keys: %v", err)
+			}
+		}
+	}
+}
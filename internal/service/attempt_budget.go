@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// DeferredRequeueInterval is how often deferred messages are checked for requeueing
+const DeferredRequeueInterval = time.Minute
+
+// DeferredRequeueBatchSize is the most deferred messages requeued per check
+const DeferredRequeueBatchSize = 500
+
+// AttemptBudget caps how many send attempts one customer receives per UTC day
+// Retries to the same handset count too, since carriers treat bursts of them as spam
+type AttemptBudget struct {
+	messageRepo repository.MessageRepository
+	limit       int
+	now         func() time.Time
+	paused      func() bool
+}
+
+// NewAttemptBudget creates a budget of limit attempts per customer per day (0 disables it)
+func NewAttemptBudget(messageRepo repository.MessageRepository, limit int) *AttemptBudget {
+	return &AttemptBudget{
+		messageRepo: messageRepo,
+		limit:       limit,
+		now:         time.Now,
+	}
+}
+
+// SetClock overrides time.Now (for testing)
+func (b *AttemptBudget) SetClock(now func() time.Time) {
+	b.now = now
+}
+
+// SetRequeuePaused sets a check that skips requeueing while it returns true, e.g. in read-only mode
+func (b *AttemptBudget) SetRequeuePaused(paused func() bool) {
+	b.paused = paused
+}
+
+// Reserve counts an attempt for the message's customer today
+// When the budget is already spent the message is deferred to the start of the
+// next UTC day and false is returned; the caller must not send it
+func (b *AttemptBudget) Reserve(ctx context.Context, message *models.OutboundMessage) (bool, error) {
+	if b.limit <= 0 {
+		return true, nil
+	}
+
+	now := b.now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	ok, err := b.messageRepo.ReserveAttempt(ctx, message.CustomerID, day, b.limit)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return true, nil
+	}
+
+	tomorrow := day.AddDate(0, 0, 1)
+	reason := fmt.Sprintf("Deferred to %s: customer reached daily attempt budget (%d)", tomorrow.Format("2006-01-02"), b.limit)
+	if err := b.messageRepo.DeferUntil(ctx, message.ID, tomorrow, reason); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// RequeueDue publishes deferred messages whose time has come and returns how many were published
+// Messages that fail to publish are deferred again so the next check retries them
+// Nothing is requeued while requeueing is paused
+func (b *AttemptBudget) RequeueDue(ctx context.Context, publish func(message *models.OutboundMessage) error) (int, error) {
+	if b.paused != nil && b.paused() {
+		return 0, nil
+	}
+
+	now := b.now()
+	messages, err := b.messageRepo.ClaimDueDeferred(ctx, now, DeferredRequeueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, message := range messages {
+		if err := publish(message); err != nil {
+			log.Printf("Warning: Failed to requeue deferred message %d: %v", message.ID, err)
+			if deferErr := b.messageRepo.DeferUntil(ctx, message.ID, now, "Requeue failed: "+err.Error()); deferErr != nil {
+				log.Printf("Warning: Failed to re-defer message %d: %v", message.ID, deferErr)
+			}
+			continue
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+// RunRequeue requeues due deferred messages every interval until ctx is cancelled
+func (b *AttemptBudget) RunRequeue(ctx context.Context, interval time.Duration, publish func(message *models.OutboundMessage) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := b.RequeueDue(ctx, publish)
+			if err != nil {
+				log.Printf("Warning: Failed to requeue deferred messages: %v", err)
+				continue
+			}
+			if count > 0 {
+				log.Printf("⏰ Requeued %d deferred message(s)", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/notify"
+	"smsleopard/internal/repository"
+)
+
+// AttentionDigestInterval is how often the ops digest is sent
+const AttentionDigestInterval = 24 * time.Hour
+
+// AttentionService finds problem campaigns and reports them to operators
+type AttentionService struct {
+	campaignRepo repository.CampaignRepository
+	notifier     notify.Notifier
+}
+
+// NewAttentionService creates a new attention service
+// notifier may be nil if the digest is not used
+func NewAttentionService(campaignRepo repository.CampaignRepository, notifier notify.Notifier) *AttentionService {
+	return &AttentionService{
+		campaignRepo: campaignRepo,
+		notifier:     notifier,
+	}
+}
+
+// ListNeedingAttention returns campaigns that are stalled, overdue or failing
+func (s *AttentionService) ListNeedingAttention(ctx context.Context) (*AttentionDigest, error) {
+	items, err := s.campaignRepo.ListNeedingAttention(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns needing attention: %w", err)
+	}
+
+	return &AttentionDigest{
+		Count:     len(items),
+		Campaigns: items,
+	}, nil
+}
+
+// SendDigest sends the current attention list through the notifier
+// Nothing is sent when no campaign needs attention
+func (s *AttentionService) SendDigest(ctx context.Context) error {
+	if s.notifier == nil {
+		return fmt.Errorf("no notifier configured")
+	}
+
+	digest, err := s.ListNeedingAttention(ctx)
+	if err != nil {
+		return err
+	}
+
+	if digest.Count == 0 {
+		return nil
+	}
+
+	subject := fmt.Sprintf("%d campaign issue(s) need attention", digest.Count)
+	if err := s.notifier.Notify(ctx, subject, digest); err != nil {
+		return fmt.Errorf("failed to send attention digest: %w", err)
+	}
+
+	return nil
+}
+
+// RunDigest sends the digest every interval until ctx is cancelled
+func (s *AttentionService) RunDigest(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.SendDigest(ctx); err != nil {
+				log.Printf("Warning: Failed to send attention digest: %v", err)
+			}
+		}
+	}
+}
+
+// AttentionDigest is the list of campaigns needing attention
+type AttentionDigest struct {
+	Count     int                         `json:"count"`
+	Campaigns []*models.CampaignAttention `json:"campaigns"`
+}
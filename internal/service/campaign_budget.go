@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// CampaignBudget stops a campaign before its sends cost more than its budget
+// Spend is reserved on the campaign's counter before each send, so no send sums past messages
+type CampaignBudget struct {
+	campaignRepo repository.CampaignRepository
+	messageRepo  repository.MessageRepository
+	sending      config.SendingConfig
+}
+
+// NewCampaignBudget creates a budget check pricing sends with the configured per-channel costs
+func NewCampaignBudget(campaignRepo repository.CampaignRepository, messageRepo repository.MessageRepository, sending config.SendingConfig) *CampaignBudget {
+	return &CampaignBudget{
+		campaignRepo: campaignRepo,
+		messageRepo:  messageRepo,
+		sending:      sending,
+	}
+}
+
+// Reserve adds the cost of sending the message to its campaign's spend
+// When that would exceed the budget the campaign is paused, the message is held until
+// the campaign is resumed and false is returned; the caller must not send it
+// A nil CampaignBudget allows every send
+func (b *CampaignBudget) Reserve(ctx context.Context, message *models.OutboundMessage, channel models.Channel) (bool, error) {
+	if b == nil {
+		return true, nil
+	}
+
+	ok, err := b.campaignRepo.ReserveSpend(ctx, message.CampaignID, costPerMessage(b.sending, channel))
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return true, nil
+	}
+
+	paused, err := b.campaignRepo.Pause(ctx, message.CampaignID, models.PausedReasonBudgetExceeded)
+	if err != nil {
+		return false, err
+	}
+	if paused {
+		log.Printf("⏸️  Campaign %d paused: budget exceeded", message.CampaignID)
+	}
+
+	if err := b.messageRepo.Hold(ctx, message.ID, "Held: campaign budget exceeded"); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// Release gives back the cost reserved for a message that was not sent
+// Failures are logged; they only leave the spend counter slightly high
+func (b *CampaignBudget) Release(ctx context.Context, message *models.OutboundMessage, channel models.Channel) {
+	if b == nil {
+		return
+	}
+
+	if err := b.campaignRepo.ReleaseSpend(ctx, message.CampaignID, costPerMessage(b.sending, channel)); err != nil {
+		log.Printf("Warning: Failed to release spend of message %d: %v", message.ID, err)
+	}
+}
+
+// costPerMessage returns the configured price for a single message on a channel
+func costPerMessage(sending config.SendingConfig, channel models.Channel) float64 {
+	if channel == models.ChannelWhatsApp {
+		return sending.CostPerWhatsApp
+	}
+	return sending.CostPerSMS
+}
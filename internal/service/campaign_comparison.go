@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"smsleopard/internal/models"
+)
+
+// Bounds on how many campaigns one comparison covers
+const (
+	MinCompareCampaigns = 2
+	MaxCompareCampaigns = 5
+)
+
+// CampaignComparison lines up campaigns in the order requested; the first is the baseline
+type CampaignComparison struct {
+	BaselineID int                 `json:"baseline_id"`
+	Campaigns  []*ComparedCampaign `json:"campaigns"`
+}
+
+// ComparedCampaign is one campaign's outcome in a comparison
+// Rates are fractions; they and the duration are nil until there is something to measure
+type ComparedCampaign struct {
+	ID               int                   `json:"id"`
+	Name             string                `json:"name"`
+	Channel          models.Channel        `json:"channel"`
+	Status           models.CampaignStatus `json:"status"`
+	Stats            models.CampaignStats  `json:"stats"`
+	AudienceSize     int                   `json:"audience_size"`
+	DurationSeconds  *float64              `json:"duration_seconds,omitempty"`
+	Cost             float64               `json:"cost"`
+	CostPerSent      *float64              `json:"cost_per_sent,omitempty"`
+	SuccessRate      *float64              `json:"success_rate,omitempty"` // Sent out of sent and failed
+	FailureBreakdown []*models.ErrorCount  `json:"failure_breakdown"`
+
+	// Delta is the difference from the baseline (nil for the baseline itself)
+	Delta *ComparisonDelta `json:"delta,omitempty"`
+}
+
+// ComparisonDelta is a campaign's metric less the baseline's, nil when either side has none
+type ComparisonDelta struct {
+	SuccessRate *float64 `json:"success_rate"`
+	CostPerSent *float64 `json:"cost_per_sent"`
+}
+
+// ValidateCompareIDs checks that a comparison names 2-5 distinct campaigns
+func ValidateCompareIDs(ids []int) error {
+	if len(ids) < MinCompareCampaigns || len(ids) > MaxCompareCampaigns {
+		return &ValidationError{Message: fmt.Sprintf("ids must list %d to %d campaigns", MinCompareCampaigns, MaxCompareCampaigns)}
+	}
+
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			return &ValidationError{Message: "ids must be positive integers"}
+		}
+		if seen[id] {
+			return &ValidationError{Message: fmt.Sprintf("campaign %d is listed more than once", id)}
+		}
+		seen[id] = true
+	}
+
+	return nil
+}
+
+// CompareCampaigns aggregates the stats, cost and failures of campaigns side by side,
+// with each campaign's success rate and cost per sent message compared to the first
+func (s *ExportService) CompareCampaigns(ctx context.Context, ids []int) (*CampaignComparison, error) {
+	if err := ValidateCompareIDs(ids); err != nil {
+		return nil, err
+	}
+
+	campaigns := make([]*models.Campaign, 0, len(ids))
+	for _, id := range ids {
+		campaign, err := s.campaignRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, &NotFoundError{Resource: "campaign", ID: id}
+		}
+		campaigns = append(campaigns, campaign)
+	}
+
+	stats, err := s.campaignRepo.GetStatsByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare campaigns: %w", err)
+	}
+	breakdowns, err := s.campaignRepo.GetFailureBreakdownByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare campaigns: %w", err)
+	}
+
+	comparison := &CampaignComparison{BaselineID: ids[0]}
+	for _, campaign := range campaigns {
+		compared := s.compared(campaign, stats[campaign.ID])
+		if breakdown := breakdowns[campaign.ID]; breakdown != nil {
+			compared.FailureBreakdown = breakdown
+		}
+		comparison.Campaigns = append(comparison.Campaigns, compared)
+	}
+
+	baseline := comparison.Campaigns[0]
+	for _, compared := range comparison.Campaigns[1:] {
+		compared.Delta = &ComparisonDelta{
+			SuccessRate: deltaOf(compared.SuccessRate, baseline.SuccessRate),
+			CostPerSent: deltaOf(compared.CostPerSent, baseline.CostPerSent),
+		}
+	}
+
+	return comparison, nil
+}
+
+// compared derives a campaign's comparison metrics from its stats
+func (s *ExportService) compared(campaign *models.Campaign, stats *models.CampaignStats) *ComparedCampaign {
+	if stats == nil {
+		stats = &models.CampaignStats{}
+	}
+
+	compared := &ComparedCampaign{
+		ID:               campaign.ID,
+		Name:             campaign.Name,
+		Channel:          campaign.Channel,
+		Status:           campaign.Status,
+		Stats:            *stats,
+		Cost:             s.cost(campaign.Channel, stats),
+		FailureBreakdown: []*models.ErrorCount{},
+	}
+	if stats.Audience != nil {
+		compared.AudienceSize = *stats.Audience
+	}
+
+	if stats.FirstMessageAt != nil && stats.LastFinishedAt != nil {
+		duration := stats.LastFinishedAt.Sub(*stats.FirstMessageAt).Seconds()
+		compared.DurationSeconds = &duration
+	}
+	if stats.Sent > 0 {
+		costPerSent := roundTo(compared.Cost/float64(stats.Sent), 4)
+		compared.CostPerSent = &costPerSent
+	}
+	if finished := stats.Sent + stats.Failed; finished > 0 {
+		successRate := roundTo(float64(stats.Sent)/float64(finished), 4)
+		compared.SuccessRate = &successRate
+	}
+
+	return compared
+}
+
+// deltaOf returns value less baseline, or nil when either is missing
+func deltaOf(value, baseline *float64) *float64 {
+	if value == nil || baseline == nil {
+		return nil
+	}
+	delta := roundTo(*value-*baseline, 4)
+	return &delta
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// CampaignEventWaitTimeout is how long a request for new campaign events waits before
+// returning none
+const CampaignEventWaitTimeout = 30 * time.Second
+
+// CampaignEventPollInterval is how often a waiting request checks for events it was not
+// woken for, e.g. while the listener is reconnecting
+const CampaignEventPollInterval = 5 * time.Second
+
+// CampaignEventPageSize is the most events returned per request
+const CampaignEventPageSize = 100
+
+// CampaignProgressInterval is how often sending campaigns are checked for progress events
+const CampaignProgressInterval = 5 * time.Second
+
+// CampaignProgressEvery is how many sends apart progress events are
+const CampaignProgressEvery = 1000
+
+// CampaignEventHub wakes requests waiting for a campaign's events
+// The API feeds it from the campaign_events NOTIFY channel with Run
+type CampaignEventHub struct {
+	mu      sync.Mutex
+	waiters map[int]map[chan struct{}]struct{}
+}
+
+// NewCampaignEventHub creates a hub with no waiters
+func NewCampaignEventHub() *CampaignEventHub {
+	return &CampaignEventHub{waiters: make(map[int]map[chan struct{}]struct{})}
+}
+
+// Subscribe returns a channel signalled when the campaign gets an event, and a function
+// to stop waiting
+func (h *CampaignEventHub) Subscribe(campaignID int) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	if h.waiters[campaignID] == nil {
+		h.waiters[campaignID] = make(map[chan struct{}]struct{})
+	}
+	h.waiters[campaignID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.waiters[campaignID], ch)
+		if len(h.waiters[campaignID]) == 0 {
+			delete(h.waiters, campaignID)
+		}
+	}
+}
+
+// Notify wakes every request waiting for the campaign's events
+func (h *CampaignEventHub) Notify(campaignID int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.waiters[campaignID] {
+		wake(ch)
+	}
+}
+
+// NotifyAll wakes every waiting request, e.g. after notifications may have been missed
+func (h *CampaignEventHub) NotifyAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, waiters := range h.waiters {
+		for ch := range waiters {
+			wake(ch)
+		}
+	}
+}
+
+// Run wakes waiters from campaign_events notifications (a pq.Listener's Notify channel)
+// until ctx is cancelled or the channel closes
+// pq sends nil after reconnecting, when notifications may have been lost, so all are woken
+func (h *CampaignEventHub) Run(ctx context.Context, notifications <-chan *pq.Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification, ok := <-notifications:
+			if !ok {
+				return
+			}
+			if notification == nil {
+				h.NotifyAll()
+				continue
+			}
+			campaignID, err := strconv.Atoi(notification.Extra)
+			if err != nil {
+				log.Printf("Warning: Ignoring campaign event notification %q: %v", notification.Extra, err)
+				continue
+			}
+			h.Notify(campaignID)
+		}
+	}
+}
+
+// wake signals ch without blocking; a pending signal already wakes its waiter
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// CampaignEventService serves a campaign's send events to long-polling consumers
+type CampaignEventService struct {
+	eventRepo    repository.CampaignEventRepository
+	campaignRepo repository.CampaignRepository
+	hub          *CampaignEventHub
+	waitTimeout  time.Duration
+	pollInterval time.Duration
+}
+
+// NewCampaignEventService creates a new campaign event service woken by hub
+func NewCampaignEventService(eventRepo repository.CampaignEventRepository, campaignRepo repository.CampaignRepository, hub *CampaignEventHub) *CampaignEventService {
+	return &CampaignEventService{
+		eventRepo:    eventRepo,
+		campaignRepo: campaignRepo,
+		hub:          hub,
+		waitTimeout:  CampaignEventWaitTimeout,
+		pollInterval: CampaignEventPollInterval,
+	}
+}
+
+// SetWaitTimeout overrides CampaignEventWaitTimeout and CampaignEventPollInterval (for testing)
+func (s *CampaignEventService) SetWaitTimeout(timeout, pollInterval time.Duration) {
+	s.waitTimeout = timeout
+	s.pollInterval = pollInterval
+}
+
+// Wait returns the campaign's events after sinceID, waiting for one to be written when
+// there are none yet; after the wait timeout it returns an empty list
+// It returns ctx's error when the caller goes away first
+func (s *CampaignEventService) Wait(ctx context.Context, campaignID int, sinceID int64) ([]*models.CampaignEvent, error) {
+	if _, err := s.campaignRepo.GetByID(ctx, campaignID); err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	// Subscribe before looking, so an event written in between still wakes us
+	notified, unsubscribe := s.hub.Subscribe(campaignID)
+	defer unsubscribe()
+
+	timeout := time.NewTimer(s.waitTimeout)
+	defer timeout.Stop()
+	poll := time.NewTicker(s.pollInterval)
+	defer poll.Stop()
+
+	for {
+		events, err := s.eventRepo.ListSince(ctx, campaignID, sinceID, CampaignEventPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) > 0 {
+			return events, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout.C:
+			return []*models.CampaignEvent{}, nil
+		case <-notified:
+		case <-poll.C:
+		}
+	}
+}
+
+// CampaignProgressReporter writes progress and completion events for sending campaigns
+// Every worker runs one; dedupe keys make each milestone a single event
+type CampaignProgressReporter struct {
+	eventRepo     repository.CampaignEventRepository
+	ratePerSecond float64
+}
+
+// NewCampaignProgressReporter creates a new campaign progress reporter
+func NewCampaignProgressReporter(eventRepo repository.CampaignEventRepository) *CampaignProgressReporter {
+	return &CampaignProgressReporter{eventRepo: eventRepo}
+}
+
+// SetSendRate sets the rate completed sends are compared against, e.g. EffectiveRatePerSecond
+// of the sending config; without it send metrics have no estimate
+func (r *CampaignProgressReporter) SetSendRate(ratePerSecond float64) {
+	r.ratePerSecond = ratePerSecond
+}
+
+// Report writes a progress event for each sending campaign past another CampaignProgressEvery
+// sends and a completed event for each with nothing left to send, and returns how many it wrote
+// A campaign sent to more customers later completes again at its new total
+// Each completed campaign also has its send metrics recorded
+func (r *CampaignProgressReporter) Report(ctx context.Context) (int, error) {
+	campaigns, err := r.eventRepo.ListSendingProgress(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for _, progress := range campaigns {
+		events := []*models.CampaignEvent{}
+		if milestone := progress.Sent / CampaignProgressEvery * CampaignProgressEvery; milestone > 0 {
+			events = append(events, newCampaignEvent(progress.CampaignID, models.CampaignEventProgress,
+				fmt.Sprintf("progress:%d", milestone), progress))
+		}
+		if progress.Outstanding == 0 {
+			// Send metrics are written before the completed event, so a failure is retried next time
+			if err := r.eventRepo.RecordSendMetrics(ctx, progress.CampaignID, r.ratePerSecond); err != nil {
+				return written, err
+			}
+			events = append(events, newCampaignEvent(progress.CampaignID, models.CampaignEventCompleted,
+				fmt.Sprintf("completed:%d", progress.Sent+progress.Failed), progress))
+		}
+
+		for _, event := range events {
+			created, err := r.eventRepo.Create(ctx, event)
+			if err != nil {
+				return written, err
+			}
+			if created {
+				written++
+			}
+		}
+	}
+
+	return written, nil
+}
+
+// Run reports every interval until ctx is cancelled
+func (r *CampaignProgressReporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Report(ctx); err != nil {
+				log.Printf("Warning: Failed to report campaign progress: %v", err)
+			}
+		}
+	}
+}
+
+// campaignQueuedPayload is the payload of a queued event
+type campaignQueuedPayload struct {
+	MessagesQueued int  `json:"messages_queued"`
+	Reconciled     bool `json:"reconciled,omitempty"` // Published by the reconciler after a failed send
+}
+
+// recordQueuedEvent writes a queued event for a batch of a campaign's messages
+// It only logs failures: the send already happened. A nil repository records nothing
+func recordQueuedEvent(ctx context.Context, eventRepo repository.CampaignEventRepository, campaignID int, payload campaignQueuedPayload) {
+	if eventRepo == nil || payload.MessagesQueued == 0 {
+		return
+	}
+	event := newCampaignEvent(campaignID, models.CampaignEventQueued, "", payload)
+	if _, err := eventRepo.Create(ctx, event); err != nil {
+		log.Printf("Warning: Failed to record queued event for campaign %d: %v", campaignID, err)
+	}
+}
+
+// newCampaignEvent builds an event with payload encoded as JSON; an empty dedupeKey
+// never collides
+func newCampaignEvent(campaignID int, eventType models.CampaignEventType, dedupeKey string, payload interface{}) *models.CampaignEvent {
+	event := &models.CampaignEvent{CampaignID: campaignID, Type: eventType}
+	if encoded, err := json.Marshal(payload); err == nil {
+		event.Payload = encoded
+	}
+	if dedupeKey != "" {
+		event.DedupeKey = &dedupeKey
+	}
+	return event
+}
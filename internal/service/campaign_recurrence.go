@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// RecurrenceInterval is how often the scheduler looks for due occurrences
+const RecurrenceInterval = time.Minute
+
+// RecurrenceBatchSize is how many due occurrences the scheduler sends per pass
+const RecurrenceBatchSize = 50
+
+// RecurCampaignRequest makes a campaign recurring
+type RecurCampaignRequest struct {
+	Rule models.RecurrenceRule `json:"rule"`
+	// CustomerIDs is the audience every occurrence is sent to
+	CustomerIDs []int `json:"customer_ids"`
+}
+
+// RecurrenceService runs recurring campaigns
+// A recurring campaign is the definition of its occurrences: when one is due the scheduler
+// clones the campaign into a draft of its own and sends that to the stored audience, through
+// the same checks as any send
+type RecurrenceService struct {
+	recurrenceRepo repository.RecurrenceRepository
+	campaignRepo   repository.CampaignRepository
+	campaigns      *CampaignService
+	now            func() time.Time
+}
+
+// NewRecurrenceService creates a new recurrence service
+func NewRecurrenceService(recurrenceRepo repository.RecurrenceRepository, campaignRepo repository.CampaignRepository, campaigns *CampaignService) *RecurrenceService {
+	return &RecurrenceService{
+		recurrenceRepo: recurrenceRepo,
+		campaignRepo:   campaignRepo,
+		campaigns:      campaigns,
+		now:            time.Now,
+	}
+}
+
+// SetClock overrides time.Now (for testing)
+func (s *RecurrenceService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Recur makes a draft or scheduled campaign recurring, with the audience of every occurrence
+func (s *RecurrenceService) Recur(ctx context.Context, campaignID int, req *RecurCampaignRequest) (*models.CampaignRecurrence, error) {
+	if err := req.Rule.Validate(); err != nil {
+		return nil, &ValidationError{Message: err.Error()}
+	}
+	if len(req.CustomerIDs) == 0 {
+		return nil, &ValidationError{Message: "customer_ids must list the audience of every occurrence"}
+	}
+
+	campaign, err := s.campaigns.GetCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.campaigns.channels.Check(campaign.Channel); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	next, ok := req.Rule.Next(now)
+	if !ok {
+		return nil, &ValidationError{Message: "recurrence ends before its first occurrence"}
+	}
+
+	plan := &models.SendPlan{
+		CustomerIDs:  req.CustomerIDs,
+		AudienceSize: len(req.CustomerIDs),
+		RequestedAt:  now,
+	}
+	recurrence, err := s.recurrenceRepo.Create(ctx, campaignID, &req.Rule, plan, next)
+	if errors.Is(err, repository.ErrCampaignNotFound) {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return recurrence, nil
+}
+
+// GetRecurrence returns a recurring campaign's recurrence
+func (s *RecurrenceService) GetRecurrence(ctx context.Context, campaignID int) (*models.CampaignRecurrence, error) {
+	recurrence, err := s.recurrenceRepo.Get(ctx, campaignID)
+	if errors.Is(err, repository.ErrRecurrenceNotFound) {
+		return nil, &NotFoundError{Resource: "recurrence", ID: campaignID}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return recurrence, nil
+}
+
+// Pause stops a recurring campaign's occurrences being sent until it is resumed
+func (s *RecurrenceService) Pause(ctx context.Context, campaignID int) (*models.CampaignRecurrence, error) {
+	recurrence, err := s.recurrenceRepo.Pause(ctx, campaignID)
+	if errors.Is(err, repository.ErrRecurrenceNotFound) {
+		return nil, &NotFoundError{Resource: "recurrence", ID: campaignID}
+	}
+	return recurrence, err
+}
+
+// Resume sends a paused recurring campaign's occurrences again, from the first one due after
+// now; occurrences that fell due while it was paused are skipped, not sent late
+func (s *RecurrenceService) Resume(ctx context.Context, campaignID int) (*models.CampaignRecurrence, error) {
+	recurrence, err := s.GetRecurrence(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	recurrence, err = s.recurrenceRepo.Resume(ctx, campaignID, s.following(recurrence, recurrence.Occurrences, s.now()))
+	if errors.Is(err, repository.ErrRecurrenceNotFound) {
+		return nil, &NotFoundError{Resource: "recurrence", ID: campaignID}
+	}
+	return recurrence, err
+}
+
+// OccurrenceList is the campaigns sent for a recurring campaign's occurrences
+type OccurrenceList struct {
+	CampaignID  int                          `json:"campaign_id"`
+	Total       int                          `json:"total"`
+	Occurrences []*models.CampaignOccurrence `json:"occurrences"`
+}
+
+// ListOccurrences returns the campaigns sent for a recurring campaign's occurrences, newest first
+func (s *RecurrenceService) ListOccurrences(ctx context.Context, campaignID int) (*OccurrenceList, error) {
+	if _, err := s.GetRecurrence(ctx, campaignID); err != nil {
+		return nil, err
+	}
+
+	occurrences, err := s.recurrenceRepo.ListOccurrences(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	return &OccurrenceList{CampaignID: campaignID, Total: len(occurrences), Occurrences: occurrences}, nil
+}
+
+// RunDue sends every due occurrence and returns how many were sent
+// An occurrence whose send is refused, e.g. while sending is switched off, is still counted:
+// its campaign is left as a draft to send by hand, and the recurrence moves on
+func (s *RecurrenceService) RunDue(ctx context.Context) (int, error) {
+	now := s.now()
+	due, err := s.recurrenceRepo.ListDue(ctx, now.UTC(), RecurrenceBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, recurrence := range due {
+		ok, err := s.runOccurrence(ctx, recurrence, now)
+		if err != nil {
+			log.Printf("Warning: Failed to send occurrence of recurring campaign %d: %v", recurrence.CampaignID, err)
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// runOccurrence clones a recurring campaign for its due occurrence and sends the clone
+// It returns false when another instance got to the occurrence first
+func (s *RecurrenceService) runOccurrence(ctx context.Context, recurrence *models.CampaignRecurrence, now time.Time) (bool, error) {
+	occurrenceAt := *recurrence.NextOccurrenceAt
+	parent, err := s.campaignRepo.GetByID(ctx, recurrence.CampaignID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	// Occurrences missed while the scheduler was down are skipped rather than sent in a burst
+	next := s.following(recurrence, recurrence.Occurrences+1, now)
+	name := fmt.Sprintf("%s (%s)", parent.Name, recurrence.Rule.LocalDate(occurrenceAt))
+	campaignID, err := s.recurrenceRepo.CreateOccurrence(ctx, parent.ID, occurrenceAt, name, next)
+	if err != nil {
+		return false, err
+	}
+	if campaignID == 0 {
+		return false, nil
+	}
+
+	plan, err := s.campaignRepo.GetSendPlan(ctx, parent.ID)
+	if err != nil {
+		return true, fmt.Errorf("occurrence campaign %d left as a draft: %w", campaignID, err)
+	}
+	result, err := s.campaigns.SendCampaign(ctx, campaignID, plan.CustomerIDs, SendOptions{})
+	if err != nil {
+		return true, fmt.Errorf("occurrence campaign %d left as a draft: %w", campaignID, err)
+	}
+
+	log.Printf("🔁 Sent occurrence %s of recurring campaign %d as campaign %d (%d messages)",
+		occurrenceAt.Format(time.RFC3339), parent.ID, campaignID, result.MessagesQueued)
+	return true, nil
+}
+
+// following returns when the occurrence after now is due, or nil once the rule has ended or
+// the given number of occurrences reaches its maximum
+func (s *RecurrenceService) following(recurrence *models.CampaignRecurrence, occurrences int, now time.Time) *time.Time {
+	if max := recurrence.Rule.MaxOccurrences; max != nil && occurrences >= *max {
+		return nil
+	}
+	next, ok := recurrence.Rule.Next(now)
+	if !ok {
+		return nil
+	}
+	return &next
+}
+
+// Run sends due occurrences every interval until ctx is cancelled
+func (s *RecurrenceService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.RunDue(ctx); err != nil {
+				log.Printf("Warning: Failed to look for due recurring campaigns: %v", err)
+			}
+		}
+	}
+}
@@ -3,23 +3,49 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"strings"
 	"time"
 
+	"smsleopard/internal/config"
+	"smsleopard/internal/csvimport"
 	"smsleopard/internal/models"
+	"smsleopard/internal/notify"
 	"smsleopard/internal/queue"
 	"smsleopard/internal/repository"
+	"smsleopard/internal/reqctx"
 )
 
+// JobPublisher publishes message jobs to the send queues (a *queue.Publisher)
+type JobPublisher interface {
+	PublishJob(job queue.MessageJob) error
+	PublishOrderedJob(job queue.MessageJob) error
+}
+
 // CampaignService handles campaign business logic
 type CampaignService struct {
 	campaignRepo repository.CampaignRepository
 	customerRepo repository.CustomerRepository
 	messageRepo  repository.MessageRepository
 	templateSvc  *TemplateService
-	publisher    *queue.Publisher
+	publisher    JobPublisher
 	db           *sql.DB
+	approval     config.ApprovalConfig
+	duplicate    config.DuplicateContentConfig
+	frequencyCap config.FrequencyCapConfig
+	suppressions repository.SuppressionRepository
+	events       *notify.Events
+	sendEvents   repository.CampaignEventRepository
+	admission    *SendAdmission
+	sending      *SendingSwitch
+	clock        repository.ClockRepository
+	estimator    *DeliveryEstimator
+	rules        *config.CustomerRules
+	channels     *ChannelSupport
 }
 
 // NewCampaignService creates a new campaign service
@@ -28,8 +54,9 @@ func NewCampaignService(
 	customerRepo repository.CustomerRepository,
 	messageRepo repository.MessageRepository,
 	templateSvc *TemplateService,
-	publisher *queue.Publisher,
+	publisher JobPublisher,
 	db *sql.DB,
+	approval config.ApprovalConfig,
 ) *CampaignService {
 	return &CampaignService{
 		campaignRepo: campaignRepo,
@@ -38,7 +65,82 @@ func NewCampaignService(
 		templateSvc:  templateSvc,
 		publisher:    publisher,
 		db:           db,
+		approval:     approval,
+	}
+}
+
+// SetClock sets the clock scheduled_at is compared against, normally the database's so a
+// skewed app server cannot mark a future campaign as past (nil uses the app clock)
+func (s *CampaignService) SetClock(clock repository.ClockRepository) {
+	s.clock = clock
+}
+
+// TemplateWarnings lints a campaign's template in the campaign's syntax
+func (s *CampaignService) TemplateWarnings(campaign *models.Campaign) []TemplateWarning {
+	return s.templateSvc.ForCampaign(campaign).Lint(campaign.BaseTemplate)
+}
+
+// now returns the current time from the configured clock, or the app clock without one
+func (s *CampaignService) now(ctx context.Context) (time.Time, error) {
+	if s.clock == nil {
+		return time.Now(), nil
 	}
+	return s.clock.Now(ctx)
+}
+
+// SetEvents sets where campaign lifecycle events are published (nil disables them)
+func (s *CampaignService) SetEvents(events *notify.Events) {
+	s.events = events
+}
+
+// SetCampaignEvents sets where queued batches are recorded for the campaign events stream
+// (nil disables them)
+func (s *CampaignService) SetCampaignEvents(eventRepo repository.CampaignEventRepository) {
+	s.sendEvents = eventRepo
+}
+
+// SetAdmission sets the check that refuses sends while the send backlog is saturated
+// Every send is admitted until this is called
+func (s *CampaignService) SetAdmission(admission *SendAdmission) {
+	s.admission = admission
+}
+
+// SetSendingSwitch sets the kill switch that refuses every send while outbound sending is disabled
+// Sends are never refused by it until this is called
+func (s *CampaignService) SetSendingSwitch(sending *SendingSwitch) {
+	s.sending = sending
+}
+
+// SetDuplicateContent sets the check that blocks resending the same content to the same audience
+// The check is off until this is called
+func (s *CampaignService) SetDuplicateContent(duplicate config.DuplicateContentConfig) {
+	s.duplicate = duplicate
+}
+
+// SetFrequencyCap sets the limit on sent messages per customer across campaigns
+// The cap is off until this is called
+func (s *CampaignService) SetFrequencyCap(frequencyCap config.FrequencyCapConfig) {
+	s.frequencyCap = frequencyCap
+}
+
+// SetDeliveryEstimator sets the estimator of previews' and dry runs' delivery and cost
+// Results carry no delivery estimate until this is called
+func (s *CampaignService) SetDeliveryEstimator(estimator *DeliveryEstimator) {
+	s.estimator = estimator
+}
+
+// SetCustomerRules sets the deployment's customer field rules checked before inline or
+// imported customers are saved
+// No rules are checked until this is called
+func (s *CampaignService) SetCustomerRules(rules *config.CustomerRules) {
+	s.rules = rules
+}
+
+// SetChannelSupport sets the channels the sender provider delivers on; campaigns on other
+// channels are refused when created or sent
+// Every channel is accepted until this is called
+func (s *CampaignService) SetChannelSupport(channels *ChannelSupport) {
+	s.channels = channels
 }
 
 // CreateCampaign creates a new campaign
@@ -47,10 +149,13 @@ func (s *CampaignService) CreateCampaign(ctx context.Context, req *CreateCampaig
 	if err := req.Validate(); err != nil {
 		return nil, &ValidationError{Message: err.Error()}
 	}
+	if err := s.channels.Check(req.Channel); err != nil {
+		return nil, err
+	}
 
-	// Validate template syntax
-	if err := s.templateSvc.ValidateTemplate(req.BaseTemplate); err != nil {
-		return nil, &ValidationError{Message: fmt.Sprintf("invalid template: %v", err)}
+	// Validate template syntax; lint warnings only block the create when strict
+	if _, err := checkTemplate(s.templateSvc.ForSyntax(req.TemplateSyntax), "invalid template", req.BaseTemplate, req.StrictTemplate); err != nil {
+		return nil, err
 	}
 
 	// Create campaign model
@@ -60,13 +165,36 @@ func (s *CampaignService) CreateCampaign(ctx context.Context, req *CreateCampaig
 		Status:       models.CampaignStatusDraft,
 		BaseTemplate: req.BaseTemplate,
 		ScheduledAt:  req.ScheduledAt,
+		Tags:         models.NormalizeTags(req.Tags),
+		Budget:       req.Budget,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
+
+		FrequencyCapExempt: req.FrequencyCapExempt,
+		TrackLinks:         req.TrackLinks,
+		Ordered:            req.Ordered,
+		PartDelimiter:      req.PartDelimiter,
+		TemplateSyntax:     req.TemplateSyntax,
+		DemoFailureRate:    req.DemoFailureRate,
+	}
+
+	// Record ownership when the caller is known
+	if req.CreatedBy != "" {
+		campaign.CreatedBy = &req.CreatedBy
+	}
+	if team := strings.TrimSpace(req.Team); team != "" {
+		campaign.Team = &team
 	}
 
 	// Set status to scheduled if scheduled_at is in future
-	if campaign.IsScheduled() {
-		campaign.Status = models.CampaignStatusScheduled
+	if campaign.ScheduledAt != nil {
+		now, err := s.now(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if campaign.IsScheduledAt(now) {
+			campaign.Status = models.CampaignStatusScheduled
+		}
 	}
 
 	// Save to database
@@ -74,6 +202,13 @@ func (s *CampaignService) CreateCampaign(ctx context.Context, req *CreateCampaig
 		return nil, fmt.Errorf("failed to create campaign: %w", err)
 	}
 
+	s.events.Publish(ctx, notify.Event{
+		Type:       notify.EventCampaignCreated,
+		CampaignID: campaign.ID,
+		Channel:    string(campaign.Channel),
+		Status:     string(campaign.Status),
+	})
+
 	return campaign, nil
 }
 
@@ -118,23 +253,61 @@ func (s *CampaignService) ListCampaigns(ctx context.Context, filters repository.
 }
 
 // SendCampaign sends a campaign to specified customers
-func (s *CampaignService) SendCampaign(ctx context.Context, campaignID int, customerIDs []int) (*SendCampaignResult, error) {
+func (s *CampaignService) SendCampaign(ctx context.Context, campaignID int, customerIDs []int, opts SendOptions) (*SendCampaignResult, error) {
 	// Get campaign
 	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
 	if err != nil {
 		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
 	}
 
-	// Validate campaign can be sent
+	// Nothing is planned or queued while all outbound sending is switched off
+	if err := s.sending.Check(ctx); err != nil {
+		return nil, err
+	}
+
+	// Campaigns created before the provider changed may be on a channel it cannot deliver on
+	if err := s.channels.Check(campaign.Channel); err != nil {
+		return nil, err
+	}
+
+	// Validate campaign can be sent; an interrupted send is resumed by sending again
+	resuming := false
 	if !campaign.CanSend() {
-		return nil, &BusinessLogicError{
-			Message: fmt.Sprintf("campaign cannot be sent: status is %s", campaign.Status),
+		resuming, err = s.isInterrupted(ctx, campaign)
+		if err != nil {
+			return nil, err
+		}
+		if !resuming {
+			return nil, &BusinessLogicError{
+				Message: fmt.Sprintf("campaign cannot be sent: status is %s", campaign.Status),
+			}
 		}
 	}
 
-	// Validate customer IDs provided
-	if len(customerIDs) == 0 {
-		return nil, &ValidationError{Message: "at least one customer ID required"}
+	// Validate customers provided
+	if len(customerIDs) == 0 && len(opts.Customers) == 0 {
+		return nil, &ValidationError{Message: "at least one customer ID or inline customer required"}
+	}
+
+	if err := validateCanary(opts.Canary); err != nil {
+		return nil, &ValidationError{Message: err.Error()}
+	}
+	if opts.ProcessBy != nil && !opts.ProcessBy.After(time.Now()) {
+		return nil, &ValidationError{Message: "process_by must be in the future"}
+	}
+
+	// The IDs as requested, before inline customers join them, for the send log
+	requestedIDs := customerIDs
+
+	// Inline customers are created or updated by phone and join the audience
+	var inline *InlineCustomersResult
+	if len(opts.Customers) > 0 {
+		var inlineIDs []int
+		inlineIDs, inline, err = s.upsertInlineCustomers(ctx, opts.Customers)
+		if err != nil {
+			return nil, err
+		}
+		customerIDs = append(customerIDs[:len(customerIDs):len(customerIDs)], inlineIDs...)
 	}
 
 	// Get customers
@@ -147,145 +320,1881 @@ func (s *CampaignService) SendCampaign(ctx context.Context, campaignID int, cust
 		return nil, &ValidationError{Message: "no valid customers found"}
 	}
 
-	// Start transaction
-	tx, err := s.db.BeginTx(ctx, nil)
+	// Customers the interrupted send already queued are not messaged twice
+	var alreadyQueued int
+	if resuming {
+		customers, alreadyQueued, err = s.skipAlreadyQueued(ctx, campaign, customers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	customers, blocked, err := s.applyBlocks(ctx, customers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start transaction: %w", err)
+		return nil, err
 	}
-	defer tx.Rollback()
 
-	// Create outbound messages without rendered content (will be rendered by worker)
-	messages := make([]*models.OutboundMessage, 0, len(customers))
-	for _, customer := range customers {
-		message := &models.OutboundMessage{
-			CampaignID:      campaign.ID,
-			CustomerID:      customer.ID,
-			Status:          models.MessageStatusPending,
-			RenderedContent: nil, // Will be set by worker
-			RetryCount:      0,
-			CreatedAt:       time.Now(),
-			UpdatedAt:       time.Now(),
-		}
+	customers, suppressed, err := s.applySuppressions(ctx, campaign, customers)
+	if err != nil {
+		return nil, err
+	}
 
-		messages = append(messages, message)
+	customers, skipped, err := s.applyFrequencyCap(ctx, campaign, customers)
+	if err != nil {
+		return nil, err
 	}
 
-	// Save messages in batch
-	if err := s.messageRepo.CreateBatch(ctx, messages); err != nil {
-		return nil, fmt.Errorf("failed to create messages: %w", err)
+	customers, lowEngagement, err := s.applyEngagement(ctx, customers, opts.MinEngagementScore)
+	if err != nil {
+		return nil, err
 	}
 
-	// Update campaign status to sending
-	if err := s.campaignRepo.UpdateStatus(ctx, campaign.ID, models.CampaignStatusSending); err != nil {
-		return nil, fmt.Errorf("failed to update campaign status: %w", err)
+	if !opts.AllowDuplicateContent {
+		if err := s.checkDuplicateContent(ctx, campaign, customers); err != nil {
+			return nil, err
+		}
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	// Large sends wait for approval instead of going out; a resumed send already went out
+	if !resuming && s.approval.RequiredAbove > 0 && len(customers) > s.approval.RequiredAbove {
+		plan := &models.SendPlan{
+			CustomerIDs:  customerIDs,
+			AudienceSize: len(customers),
+			RequestedAt:  time.Now(),
+			Canary:       opts.Canary,
+			ProcessBy:    opts.ProcessBy,
+
+			MinEngagementScore: opts.MinEngagementScore,
+		}
+		// The plan is only stored if the campaign is still where this send found it
+		if err := s.campaignRepo.AwaitApproval(ctx, campaign.ID, campaign.Status, plan); err != nil {
+			return nil, fmt.Errorf("failed to update campaign status: %w", err)
+		}
+
+		result := &SendCampaignResult{
+			CampaignID:          campaign.ID,
+			AudienceSize:        plan.AudienceSize,
+			Status:              models.CampaignStatusPendingApproval,
+			InlineCustomers:     inline,
+			SkippedBlocked:      blocked,
+			SkippedSuppressed:   suppressed,
+			SkippedFrequencyCap: skipped,
+
+			SkippedLowEngagement: lowEngagement,
+		}
+		s.recordSend(ctx, newSendRecord(campaign.ID, requestedIDs, opts, len(customers), result.Status))
+		return result, nil
 	}
 
-	// Publish jobs to queue (outside transaction)
-	for _, message := range messages {
-		err := s.publisher.PublishMessage(message.ID, campaign.ID, message.CustomerID)
-		if err != nil {
-			// Log error but don't fail - worker will retry
-			log.Printf("Warning: Failed to publish message %d to queue: %v", message.ID, err)
+	// Messages queued now would only wait behind a saturated backlog
+	if !opts.OverrideSaturation {
+		if err := s.admission.Check(ctx); err != nil {
+			return nil, err
 		}
 	}
 
-	return &SendCampaignResult{
-		CampaignID:     campaign.ID,
-		MessagesQueued: len(messages),
-		Status:         models.CampaignStatusSending,
-	}, nil
+	// A canary send queues its canary now and holds the rest of the audience back
+	audienceSize := len(customers)
+	customers, heldBack := splitCanary(customers, opts.Canary)
+
+	// The send is logged once its first batch is queued and its progress kept up to date,
+	// so a send cut short shows how far it got
+	record := newSendRecord(campaign.ID, requestedIDs, opts, audienceSize, models.CampaignStatusSending)
+	// Progress outlives the request: it is what a cancelled send leaves behind
+	persistCtx := context.WithoutCancel(ctx)
+	logged := false
+	progress := func(queued int) {
+		record.MessagesQueued = queued
+		if !logged {
+			logged = true
+			s.recordSend(persistCtx, record)
+			return
+		}
+		s.updateSendProgress(persistCtx, record)
+	}
+
+	result, err := s.dispatch(ctx, campaign, customers, opts.ProcessBy, progress)
+	if err != nil {
+		var interrupted *SendInterruptedError
+		if errors.As(err, &interrupted) && interrupted.MessagesQueued > 0 {
+			record.Status = models.SendStatusInterrupted
+			s.updateSendProgress(persistCtx, record)
+		}
+		return nil, err
+	}
+	if len(heldBack) > 0 {
+		if err := s.holdCanary(ctx, campaign, heldBack, opts.ProcessBy, result); err != nil {
+			return nil, err
+		}
+		record.Status = result.Status
+		s.updateSendProgress(persistCtx, record)
+	}
+	result.InlineCustomers = inline
+	result.SkippedBlocked = blocked
+	result.SkippedSuppressed = suppressed
+	result.SkippedFrequencyCap = skipped
+	result.SkippedLowEngagement = lowEngagement
+	result.SkippedAlreadyQueued = alreadyQueued
+	return result, nil
 }
 
-// PreviewMessage previews how a message will render for a customer
-func (s *CampaignService) PreviewMessage(ctx context.Context, req *PreviewMessageRequest) (*PreviewMessageResult, error) {
-	// Get campaign
-	campaign, err := s.campaignRepo.GetByID(ctx, req.CampaignID)
+// newSendRecord builds the send log entry for a send request, with the listed customer IDs
+// cut to a sample
+func newSendRecord(campaignID int, customerIDs []int, opts SendOptions, audienceSize int, status models.CampaignStatus) *models.SendRecord {
+	sample := customerIDs
+	if len(sample) > SendLogIDSampleSize {
+		sample = sample[:SendLogIDSampleSize]
+	}
+
+	record := &models.SendRecord{
+		CampaignID: campaignID,
+		Source:     opts.Source,
+		Request: models.SendRequestInfo{
+			CustomerIDCount:       len(customerIDs),
+			CustomerIDSample:      append([]int{}, sample...),
+			InlineCustomers:       len(opts.Customers),
+			AllowDuplicateContent: opts.AllowDuplicateContent,
+			ProcessBy:             opts.ProcessBy,
+		},
+		AudienceSize: audienceSize,
+		Status:       status,
+	}
+	if record.Source == "" {
+		record.Source = models.SendSourceAPI
+	}
+	if opts.RequestedBy != "" {
+		record.RequestedBy = &opts.RequestedBy
+	}
+	return record
+}
+
+// recordSend logs a send request with its targeting and outcome
+// The send has already happened, so a failure to log it is only reported
+func (s *CampaignService) recordSend(ctx context.Context, record *models.SendRecord) {
+	if err := s.campaignRepo.RecordSend(ctx, record); err != nil {
+		log.Printf("%sWarning: Failed to record send for campaign %d: %v", reqctx.Prefix(ctx), record.CampaignID, err)
+	}
+}
+
+// updateSendProgress stores a logged send's progress; a send whose log entry could not be
+// written has nothing to update
+func (s *CampaignService) updateSendProgress(ctx context.Context, record *models.SendRecord) {
+	if record.ID == 0 {
+		return
+	}
+	if err := s.campaignRepo.UpdateSendProgress(ctx, record.ID, record.MessagesQueued, record.Status); err != nil {
+		log.Printf("%sWarning: Failed to update send %d progress for campaign %d: %v", reqctx.Prefix(ctx), record.ID, record.CampaignID, err)
+	}
+}
+
+// isInterrupted checks whether a sending campaign's latest send was interrupted, so sending
+// again resumes it
+func (s *CampaignService) isInterrupted(ctx context.Context, campaign *models.Campaign) (bool, error) {
+	if campaign.Status != models.CampaignStatusSending {
+		return false, nil
+	}
+
+	sends, err := s.campaignRepo.ListSends(ctx, campaign.ID)
 	if err != nil {
-		return nil, &NotFoundError{Resource: "campaign", ID: req.CampaignID}
+		return false, fmt.Errorf("failed to get send history: %w", err)
 	}
 
-	// Get customer
-	customer, err := s.customerRepo.GetByID(ctx, req.CustomerID)
+	return len(sends) > 0 && sends[0].Status == models.SendStatusInterrupted, nil
+}
+
+// skipAlreadyQueued leaves out the customers who already have a message from the campaign
+// and returns how many were left out
+func (s *CampaignService) skipAlreadyQueued(ctx context.Context, campaign *models.Campaign, customers []*models.Customer) ([]*models.Customer, int, error) {
+	messages, err := s.messageRepo.GetByCampaignID(ctx, campaign.ID)
 	if err != nil {
-		return nil, &NotFoundError{Resource: "customer", ID: req.CustomerID}
+		return nil, 0, fmt.Errorf("failed to get campaign messages: %w", err)
 	}
 
-	// Use override template if provided, otherwise use campaign template
-	template := campaign.BaseTemplate
-	if req.OverrideTemplate != nil && *req.OverrideTemplate != "" {
-		template = *req.OverrideTemplate
+	queued := make(map[int]bool, len(messages))
+	for _, message := range messages {
+		queued[message.CustomerID] = true
 	}
 
-	// Render template
-	renderedMessage, err := s.templateSvc.Render(template, customer)
+	remaining := make([]*models.Customer, 0, len(customers))
+	for _, customer := range customers {
+		if !queued[customer.ID] {
+			remaining = append(remaining, customer)
+		}
+	}
+
+	if len(remaining) == 0 {
+		return nil, 0, &ValidationError{Message: "every customer already has a message from this campaign"}
+	}
+
+	return remaining, len(customers) - len(remaining), nil
+}
+
+// GetSendHistory lists the send requests made against a campaign, newest first
+func (s *CampaignService) GetSendHistory(ctx context.Context, campaignID int) (*SendHistory, error) {
+	if _, err := s.campaignRepo.GetByID(ctx, campaignID); err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	sends, err := s.campaignRepo.ListSends(ctx, campaignID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to render template: %w", err)
+		return nil, fmt.Errorf("failed to get send history: %w", err)
 	}
 
-	return &PreviewMessageResult{
-		RenderedMessage: renderedMessage,
-		UsedTemplate:    template,
-		Customer: struct {
-			ID        int    `json:"id"`
-			FirstName string `json:"first_name"`
-		}{
-			ID:        customer.ID,
-			FirstName: customer.FullName(),
-		},
-	}, nil
+	return &SendHistory{CampaignID: campaignID, Sends: sends}, nil
 }
 
-// Request/Response types
+// upsertInlineCustomers creates or updates the inline customers of a send and returns their IDs
+// Every phone is checked before anything is written; repeated phones are upserted once
+func (s *CampaignService) upsertInlineCustomers(ctx context.Context, inline []InlineCustomer) ([]int, *InlineCustomersResult, error) {
+	if len(inline) > MaxInlineCustomers {
+		return nil, nil, &ValidationError{Message: fmt.Sprintf("at most %d inline customers per send", MaxInlineCustomers)}
+	}
 
-// CreateCampaignRequest represents a request to create a campaign
-type CreateCampaignRequest struct {
-	Name         string         `json:"name"`
-	Channel      models.Channel `json:"channel"`
-	BaseTemplate string         `json:"base_template"`
-	ScheduledAt  *time.Time     `json:"scheduled_at,omitempty"`
+	customers := make([]*models.Customer, 0, len(inline))
+	entries := make([]int, 0, len(inline))
+	seen := make(map[string]bool, len(inline))
+	for i, entry := range inline {
+		phone, err := models.NormalizePhone(entry.Phone)
+		if err != nil {
+			return nil, nil, &ValidationError{Message: fmt.Sprintf("customers[%d]: %v", i, err)}
+		}
+		if seen[phone] {
+			continue
+		}
+		seen[phone] = true
+
+		customer := &models.Customer{
+			Phone:              phone,
+			FirstName:          entry.FirstName,
+			LastName:           entry.LastName,
+			Location:           entry.Location,
+			PreferredProduct:   entry.PreferredProduct,
+			ContactWindowStart: entry.ContactWindowStart,
+			ContactWindowEnd:   entry.ContactWindowEnd,
+		}
+		if _, err := customer.ContactWindow(); err != nil {
+			return nil, nil, &ValidationError{Message: fmt.Sprintf("customers[%d]: %v", i, err)}
+		}
+		customers = append(customers, customer)
+		entries = append(entries, i)
+	}
+
+	// A blocked customer is not updated or messaged through an inline entry either
+	if err := s.refuseBlockedInline(ctx, customers); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.checkInlineRules(ctx, customers, entries); err != nil {
+		return nil, nil, err
+	}
+
+	result := &InlineCustomersResult{}
+	ids := make([]int, 0, len(customers))
+	for _, customer := range customers {
+		created, err := s.customerRepo.UpsertByPhone(ctx, customer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to upsert customer: %w", err)
+		}
+		if created {
+			result.Created++
+		} else {
+			result.Matched++
+		}
+		ids = append(ids, customer.ID)
+	}
+
+	return ids, result, nil
 }
 
-// Validate validates the create campaign request
-func (r *CreateCampaignRequest) Validate() error {
-	if r.Name == "" {
-		return fmt.Errorf("name is required")
+// checkInlineRules fails a send whose inline customers break the configured customer rules
+// An update keeps the fields an entry leaves out, so those are checked as already stored;
+// entries names each customer's position in the request
+func (s *CampaignService) checkInlineRules(ctx context.Context, customers []*models.Customer, entries []int) error {
+	if s.rules == nil {
+		return nil
 	}
-	if r.Channel != models.ChannelSMS && r.Channel != models.ChannelWhatsApp {
-		return fmt.Errorf("invalid channel: must be 'sms' or 'whatsapp'")
+
+	phones := make([]string, len(customers))
+	for i, customer := range customers {
+		phones[i] = customer.Phone
 	}
-	if r.BaseTemplate == "" {
-		return fmt.Errorf("base_template is required")
+	existing, err := s.customerRepo.GetByPhones(ctx, phones)
+	if err != nil {
+		return fmt.Errorf("failed to match inline customers: %w", err)
+	}
+	stored := make(map[string]*models.Customer, len(existing))
+	for _, customer := range existing {
+		stored[models.PhoneKey(customer.Phone)] = customer
+	}
+
+	violations := []*config.CustomerRuleViolation{}
+	for i, customer := range customers {
+		merged := *customer
+		if current, ok := stored[customer.Phone]; ok {
+			merged.FirstName = coalesce(customer.FirstName, current.FirstName)
+			merged.LastName = coalesce(customer.LastName, current.LastName)
+			merged.Location = coalesce(customer.Location, current.Location)
+			merged.PreferredProduct = coalesce(customer.PreferredProduct, current.PreferredProduct)
+		}
+		if broken := s.rules.Check(&merged); len(broken) > 0 {
+			violations = append(violations, prefixViolations(broken, fmt.Sprintf("customers[%d]", entries[i]))...)
+		}
+	}
+	if len(violations) > 0 {
+		return customerRulesError(violations)
 	}
 	return nil
 }
 
-// SendCampaignResult represents the result of sending a campaign
-type SendCampaignResult struct {
-	CampaignID     int                   `json:"campaign_id"`
-	MessagesQueued int                   `json:"messages_queued"`
-	Status         models.CampaignStatus `json:"status"`
+// coalesce returns value, or fallback when value is nil
+func coalesce(value, fallback *string) *string {
+	if value != nil {
+		return value
+	}
+	return fallback
 }
 
-// PreviewMessageRequest represents a request to preview a message
-type PreviewMessageRequest struct {
-	CampaignID       int     `json:"campaign_id"`
-	CustomerID       int     `json:"customer_id"`
-	OverrideTemplate *string `json:"override_template,omitempty"`
+// refuseBlockedInline fails a send whose inline customers include a blocked customer's phone
+func (s *CampaignService) refuseBlockedInline(ctx context.Context, customers []*models.Customer) error {
+	phones := make([]string, len(customers))
+	for i, customer := range customers {
+		phones[i] = customer.Phone
+	}
+
+	blocked, err := s.customerRepo.ListBlockedPhones(ctx, phones)
+	if err != nil {
+		return fmt.Errorf("failed to check blocked customers: %w", err)
+	}
+	if len(blocked) > 0 {
+		return &ValidationError{Message: fmt.Sprintf("inline customers include blocked customers: %s", strings.Join(blocked, ", "))}
+	}
+	return nil
 }
 
-// PreviewMessageResult represents the result of previewing a message
-type PreviewMessageResult struct {
-	RenderedMessage string `json:"rendered_message"`
-	UsedTemplate    string `json:"used_template"`
-	Customer        struct {
-		ID        int    `json:"id"`
-		FirstName string `json:"first_name"`
-	} `json:"customer"`
+// SendCampaignCSV sends a campaign to the customers whose phones are listed in an uploaded CSV
+// Phones are normalized and matched in batches; unknown phones are created as customers
+// when requested, otherwise reported. The matched audience then goes through SendCampaign,
+// so large uploads still wait for approval
+func (s *CampaignService) SendCampaignCSV(ctx context.Context, campaignID int, file io.Reader, req *SendCampaignCSVRequest) (*SendCampaignCSVResult, error) {
+	// Check the campaign first so nothing is created for a send that cannot happen
+	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+	if !campaign.CanSend() {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("campaign cannot be sent: status is %s", campaign.Status),
+		}
+	}
+
+	// Nothing is planned or queued while all outbound sending is switched off
+	if err := s.sending.Check(ctx); err != nil {
+		return nil, err
+	}
+
+	// Campaigns created before the provider changed may be on a channel it cannot deliver on
+	if err := s.channels.Check(campaign.Channel); err != nil {
+		return nil, err
+	}
+
+	parsed, err := csvimport.Parse(file, csvimport.Options{
+		ContentEncoding: req.ContentEncoding,
+		RequiredColumns: []string{"phone"},
+	})
+	if err != nil {
+		return nil, &ValidationError{Message: err.Error()}
+	}
+
+	result := &SendCampaignCSVResult{
+		Rows:            len(parsed.Records) + len(parsed.Errors),
+		UnmatchedPhones: []string{},
+		Errors:          parsed.Errors,
+	}
+
+	// Normalize and de-duplicate phones, reporting malformed ones by line
+	// Optional name, location, product and contact window columns are kept for customers
+	// created from the file
+	phones := []string{}
+	seen := make(map[string]bool)
+	rows := make(map[string]*models.Customer)
+	lines := make(map[string]int)
+	for _, record := range parsed.Records {
+		phone, err := models.NormalizePhone(record.Fields["phone"])
+		if err != nil {
+			result.Errors = append(result.Errors, &csvimport.RowError{Line: record.Line, Problem: err.Error()})
+			continue
+		}
+
+		row := &models.Customer{
+			Phone:              phone,
+			FirstName:          optionalField(record.Fields["first_name"]),
+			LastName:           optionalField(record.Fields["last_name"]),
+			Location:           optionalField(record.Fields["location"]),
+			PreferredProduct:   optionalField(record.Fields["preferred_product"]),
+			ContactWindowStart: optionalField(record.Fields["contact_window_start"]),
+			ContactWindowEnd:   optionalField(record.Fields["contact_window_end"]),
+		}
+		if _, err := row.ContactWindow(); err != nil {
+			result.Errors = append(result.Errors, &csvimport.RowError{Line: record.Line, Problem: err.Error()})
+			continue
+		}
+		if _, err := row.EnforceFieldLengths(DefaultMaxCustomerFieldLength, false); err != nil {
+			result.Errors = append(result.Errors, &csvimport.RowError{Line: record.Line, Problem: err.Error()})
+			continue
+		}
+
+		if !seen[phone] {
+			seen[phone] = true
+			phones = append(phones, phone)
+			rows[phone] = row
+			lines[phone] = record.Line
+		}
+	}
+	result.Invalid = len(result.Errors)
+
+	customerIDs := []int{}
+	for start := 0; start < len(phones); start += CSVPhoneBatchSize {
+		end := start + CSVPhoneBatchSize
+		if end > len(phones) {
+			end = len(phones)
+		}
+		batch := phones[start:end]
+
+		customers, err := s.customerRepo.GetByPhones(ctx, batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match phones: %w", err)
+		}
+
+		// Customers stored before normalization are matched on their normalized phone
+		matched := make(map[string]bool, len(customers))
+		for _, customer := range customers {
+			matched[models.PhoneKey(customer.Phone)] = true
+			customerIDs = append(customerIDs, customer.ID)
+		}
+		result.Matched += len(customers)
+
+		for _, phone := range batch {
+			if matched[phone] {
+				continue
+			}
+
+			if req.CreateUnknown {
+				// A row breaking the customer rules is reported, naming the rule, and not created
+				customer := rows[phone]
+				if violations := s.rules.Check(customer); len(violations) > 0 {
+					for _, violation := range violations {
+						result.Errors = append(result.Errors, &csvimport.RowError{Line: lines[phone], Problem: violation.Message, Rule: violation.Rule})
+					}
+					result.Invalid++
+					continue
+				}
+				if err := s.customerRepo.Create(ctx, customer); err != nil {
+					return nil, fmt.Errorf("failed to create customer: %w", err)
+				}
+				customerIDs = append(customerIDs, customer.ID)
+				result.Created++
+				continue
+			}
+
+			result.Unmatched++
+			if len(result.UnmatchedPhones) < CSVUnmatchedSampleSize {
+				result.UnmatchedPhones = append(result.UnmatchedPhones, phone)
+			}
+		}
+	}
+
+	if len(customerIDs) == 0 {
+		return nil, &ValidationError{Message: "no customers matched the uploaded phones"}
+	}
+
+	result.SendCampaignResult, err = s.SendCampaign(ctx, campaignID, customerIDs, SendOptions{
+		AllowDuplicateContent: req.AllowDuplicateContent,
+		OverrideSaturation:    req.OverrideSaturation,
+		RequestedBy:           req.RequestedBy,
+		Source:                models.SendSourceCSV,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// optionalField returns nil for an empty CSV field
+func optionalField(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// ApproveCampaign executes the send plan stored for a campaign awaiting approval
+func (s *CampaignService) ApproveCampaign(ctx context.Context, campaignID int) (*SendCampaignResult, error) {
+	campaign, err := s.getPendingApproval(ctx, campaignID, models.CampaignActionApprove)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing is planned or queued while all outbound sending is switched off
+	if err := s.sending.Check(ctx); err != nil {
+		return nil, err
+	}
+
+	// Campaigns created before the provider changed may be on a channel it cannot deliver on
+	if err := s.channels.Check(campaign.Channel); err != nil {
+		return nil, err
+	}
+
+	plan, err := s.campaignRepo.GetSendPlan(ctx, campaign.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get send plan: %w", err)
+	}
+
+	customers, err := s.customerRepo.GetByIDs(ctx, plan.CustomerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customers: %w", err)
+	}
+
+	if len(customers) == 0 {
+		return nil, &ValidationError{Message: "no valid customers found"}
+	}
+
+	// Customers may have been blocked, phones suppressed, customers reached the cap, or scores
+	// changed, while the send waited for approval
+	customers, blocked, err := s.applyBlocks(ctx, customers)
+	if err != nil {
+		return nil, err
+	}
+
+	customers, suppressed, err := s.applySuppressions(ctx, campaign, customers)
+	if err != nil {
+		return nil, err
+	}
+
+	customers, skipped, err := s.applyFrequencyCap(ctx, campaign, customers)
+	if err != nil {
+		return nil, err
+	}
+
+	customers, lowEngagement, err := s.applyEngagement(ctx, customers, plan.MinEngagementScore)
+	if err != nil {
+		return nil, err
+	}
+
+	customers, heldBack := splitCanary(customers, plan.Canary)
+
+	result, err := s.dispatch(ctx, campaign, customers, plan.ProcessBy, nil)
+	if err != nil {
+		return nil, err
+	}
+	result.SkippedBlocked = blocked
+	result.SkippedSuppressed = suppressed
+	result.SkippedFrequencyCap = skipped
+	result.SkippedLowEngagement = lowEngagement
+
+	// Holding the canary replaces the plan with the audience held back
+	if len(heldBack) > 0 {
+		if err := s.holdCanary(ctx, campaign, heldBack, plan.ProcessBy, result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	if err := s.campaignRepo.ClearSendPlan(ctx, campaign.ID); err != nil {
+		log.Printf("Warning: Failed to clear send plan for campaign %d: %v", campaign.ID, err)
+	}
+
+	return result, nil
+}
+
+// ContinueCampaign sends the rest of the audience a canary send held back and moves the
+// campaign back to sending
+// Customers blocked, suppressed or capped while the canary went out are left out
+func (s *CampaignService) ContinueCampaign(ctx context.Context, campaignID int) (*SendCampaignResult, error) {
+	campaign, err := s.getCanary(ctx, campaignID, models.CampaignActionContinue)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := s.campaignRepo.GetSendPlan(ctx, campaign.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get send plan: %w", err)
+	}
+
+	customers, err := s.customerRepo.GetByIDs(ctx, plan.CustomerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customers: %w", err)
+	}
+
+	customers, blocked, err := s.applyBlocks(ctx, customers)
+	if err != nil {
+		return nil, err
+	}
+
+	customers, suppressed, err := s.applySuppressions(ctx, campaign, customers)
+	if err != nil {
+		return nil, err
+	}
+
+	customers, skipped, err := s.applyFrequencyCap(ctx, campaign, customers)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *SendCampaignResult
+	if len(customers) > 0 {
+		result, err = s.dispatch(ctx, campaign, customers, plan.ProcessBy, nil)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Nobody held back can still be messaged, so the canary was the whole send
+		if err := s.campaignRepo.UpdateStatusIf(ctx, campaign.ID, campaign.Status, models.CampaignStatusSending); err != nil {
+			return nil, fmt.Errorf("failed to update campaign status: %w", err)
+		}
+		result = &SendCampaignResult{CampaignID: campaign.ID, Status: models.CampaignStatusSending}
+	}
+	result.SkippedBlocked = blocked
+	result.SkippedSuppressed = suppressed
+	result.SkippedFrequencyCap = skipped
+
+	if err := s.campaignRepo.ClearSendPlan(ctx, campaign.ID); err != nil {
+		log.Printf("Warning: Failed to clear send plan for campaign %d: %v", campaign.ID, err)
+	}
+
+	return result, nil
+}
+
+// AbortCampaign cancels a campaign holding a canary at once: the audience held back is never
+// sent and the canary's unsent messages are cancelled
+func (s *CampaignService) AbortCampaign(ctx context.Context, campaignID int) (*CancelCampaignResult, error) {
+	campaign, err := s.getCanary(ctx, campaignID, models.CampaignActionAbort)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelAt, err := s.campaignRepo.BeginCancel(ctx, campaign.ID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to abort campaign: %w", err)
+	}
+
+	cancelled, err := s.campaignRepo.FinalizeCancellation(ctx, campaign.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to abort campaign: %w", err)
+	}
+
+	if err := s.campaignRepo.ClearSendPlan(ctx, campaign.ID); err != nil {
+		log.Printf("Warning: Failed to clear send plan for campaign %d: %v", campaign.ID, err)
+	}
+
+	return &CancelCampaignResult{
+		CampaignID:        campaign.ID,
+		Status:            models.CampaignStatusCancelled,
+		CancelAt:          cancelAt,
+		MessagesCancelled: cancelled,
+	}, nil
+}
+
+// RejectCampaign discards the stored send plan and returns the campaign to draft
+func (s *CampaignService) RejectCampaign(ctx context.Context, campaignID int) (*models.Campaign, error) {
+	campaign, err := s.getPendingApproval(ctx, campaignID, models.CampaignActionReject)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.campaignRepo.UpdateStatusIf(ctx, campaign.ID, campaign.Status, models.CampaignStatusDraft); err != nil {
+		return nil, fmt.Errorf("failed to reject campaign: %w", err)
+	}
+
+	if err := s.campaignRepo.ClearSendPlan(ctx, campaign.ID); err != nil {
+		log.Printf("Warning: Failed to clear send plan for campaign %d: %v", campaign.ID, err)
+	}
+
+	campaign.Status = models.CampaignStatusDraft
+	return campaign, nil
+}
+
+// ResumeCampaign moves a paused campaign back to sending and releases the messages held while it was paused
+// A campaign whose budget is spent can only be resumed with a higher budget
+func (s *CampaignService) ResumeCampaign(ctx context.Context, campaignID int, req *ResumeCampaignRequest) (*ResumeCampaignResult, error) {
+	if err := validateBudget(req.Budget); err != nil {
+		return nil, &ValidationError{Message: err.Error()}
+	}
+
+	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	if !campaign.Status.Allows(models.CampaignActionResume) {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("campaign is not paused: status is %s", campaign.Status),
+		}
+	}
+
+	budget := campaign.Budget
+	if req.Budget != nil {
+		budget = req.Budget
+	}
+	if budget != nil && campaign.Spend != nil && *campaign.Spend >= *budget {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("campaign has spent %.2f of its %.2f budget; resume with a higher budget", *campaign.Spend, *budget),
+		}
+	}
+
+	if err := s.campaignRepo.Resume(ctx, campaign.ID, req.Budget); err != nil {
+		return nil, fmt.Errorf("failed to resume campaign: %w", err)
+	}
+
+	released, err := s.messageRepo.ReleaseHeld(ctx, campaign.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release held messages: %w", err)
+	}
+
+	return &ResumeCampaignResult{
+		CampaignID:       campaign.ID,
+		Status:           models.CampaignStatusSending,
+		Budget:           budget,
+		MessagesReleased: released,
+	}, nil
+}
+
+// CancelCampaign stops a sending or paused campaign
+// Without a grace period its pending messages are cancelled at once. With one the campaign is
+// cancelling until the deadline: nothing more is sent, but UndoCancel may still restore it,
+// and the worker's reconciler finalizes the cancellation once the deadline passes
+func (s *CampaignService) CancelCampaign(ctx context.Context, campaignID int, grace time.Duration) (*CancelCampaignResult, error) {
+	if grace < 0 || grace > MaxCancelGrace {
+		return nil, &ValidationError{Message: fmt.Sprintf("grace_seconds must be between 0 and %d", int(MaxCancelGrace.Seconds()))}
+	}
+
+	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	if !campaign.Status.Allows(models.CampaignActionCancel) {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("campaign cannot be cancelled: status is %s", campaign.Status),
+		}
+	}
+
+	cancelAt, err := s.campaignRepo.BeginCancel(ctx, campaign.ID, grace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel campaign: %w", err)
+	}
+
+	result := &CancelCampaignResult{
+		CampaignID: campaign.ID,
+		Status:     models.CampaignStatusCancelling,
+		CancelAt:   cancelAt,
+	}
+	if grace > 0 {
+		return result, nil
+	}
+
+	cancelled, err := s.campaignRepo.FinalizeCancellation(ctx, campaign.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel campaign: %w", err)
+	}
+	result.Status = models.CampaignStatusCancelled
+	result.MessagesCancelled = cancelled
+
+	return result, nil
+}
+
+// UndoCancel restores a cancelling campaign to sending while its grace period lasts,
+// releasing the messages the worker held meanwhile
+func (s *CampaignService) UndoCancel(ctx context.Context, campaignID int) (*UndoCancelResult, error) {
+	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	if !campaign.Status.Allows(models.CampaignActionUndoCancel) {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("campaign is not cancelling: status is %s", campaign.Status),
+		}
+	}
+
+	if err := s.campaignRepo.UndoCancel(ctx, campaign.ID); err != nil {
+		if errors.Is(err, repository.ErrCancelFinal) {
+			return nil, &BusinessLogicError{Message: "the cancellation grace period has ended; the campaign can no longer be restored"}
+		}
+		return nil, fmt.Errorf("failed to undo cancellation: %w", err)
+	}
+
+	released, err := s.messageRepo.ReleaseHeld(ctx, campaign.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release held messages: %w", err)
+	}
+
+	return &UndoCancelResult{
+		CampaignID:       campaign.ID,
+		Status:           models.CampaignStatusSending,
+		MessagesReleased: released,
+	}, nil
+}
+
+// DeleteCampaign deletes a campaign
+// A campaign with messages is only deleted, together with its messages, when force is set;
+// otherwise the delete is refused with a ConflictError so message history is not lost by accident
+func (s *CampaignService) DeleteCampaign(ctx context.Context, campaignID int, force bool) (*DeleteResult, error) {
+	return deleteWithMessages(ctx, "campaign", campaignID, force, s.campaignRepo.Delete, s.campaignRepo.DeleteWithMessages)
+}
+
+// deleteWithMessages deletes a campaign or customer, mapping repository errors to service errors
+// Without force a record still referenced by messages is refused with a ConflictError
+func deleteWithMessages(
+	ctx context.Context,
+	resource string,
+	id int,
+	force bool,
+	deleteOne func(ctx context.Context, id int) error,
+	deleteWithMessages func(ctx context.Context, id int) (int, error),
+) (*DeleteResult, error) {
+	result := &DeleteResult{ID: id}
+
+	var err error
+	if force {
+		result.MessagesDeleted, err = deleteWithMessages(ctx, id)
+	} else {
+		err = deleteOne(ctx, id)
+	}
+
+	var dependents *repository.HasDependentsError
+	switch {
+	case err == nil:
+		return result, nil
+	case errors.Is(err, repository.ErrCampaignNotFound), errors.Is(err, repository.ErrCustomerNotFound):
+		return nil, &NotFoundError{Resource: resource, ID: id}
+	case errors.As(err, &dependents):
+		return nil, &ConflictError{
+			Resource: resource,
+			Message:  fmt.Sprintf("%s has %d messages; delete with force=true to remove them too", resource, dependents.Messages),
+		}
+	default:
+		return nil, fmt.Errorf("failed to delete %s: %w", resource, err)
+	}
+}
+
+// ReRenderCampaign clears rendered content of pending messages so the worker
+// renders them from the current template
+// With DryRun nothing is cleared and a sample is rendered synchronously instead
+func (s *CampaignService) ReRenderCampaign(ctx context.Context, campaignID int, req *ReRenderRequest) (*ReRenderResult, error) {
+	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	if !campaign.Status.Allows(models.CampaignActionReRender) {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("campaign cannot be re-rendered: status is %s", campaign.Status),
+		}
+	}
+
+	result := &ReRenderResult{
+		CampaignID: campaign.ID,
+		DryRun:     req.DryRun,
+	}
+
+	if !req.DryRun {
+		cleared, err := s.messageRepo.ClearPendingRenderedContent(ctx, campaign.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clear rendered content: %w", err)
+		}
+		result.MessagesCleared = cleared
+		return result, nil
+	}
+
+	sample, err := s.renderSample(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	result.Sample = sample
+
+	return result, nil
+}
+
+// renderSample renders pending messages of a campaign with its current template
+func (s *CampaignService) renderSample(ctx context.Context, campaign *models.Campaign) ([]*ReRenderSample, error) {
+	messages, err := s.messageRepo.GetPendingByCampaignID(ctx, campaign.ID, ReRenderSampleSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending messages: %w", err)
+	}
+
+	if len(messages) == 0 {
+		return []*ReRenderSample{}, nil
+	}
+
+	customerIDs := make([]int, 0, len(messages))
+	for _, message := range messages {
+		customerIDs = append(customerIDs, message.CustomerID)
+	}
+
+	customers, err := s.customerRepo.GetByIDs(ctx, customerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customers: %w", err)
+	}
+
+	customersByID := make(map[int]*models.Customer, len(customers))
+	for _, customer := range customers {
+		customersByID[customer.ID] = customer
+	}
+
+	sample := make([]*ReRenderSample, 0, len(messages))
+	for _, message := range messages {
+		customer, ok := customersByID[message.CustomerID]
+		if !ok {
+			continue
+		}
+
+		rendered, err := s.templateSvc.ForCampaign(campaign).Render(campaign.BaseTemplate, customer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
+
+		sample = append(sample, &ReRenderSample{
+			MessageID:        message.ID,
+			CustomerID:       customer.ID,
+			RenderedContent:  rendered,
+			DeliveryEstimate: s.estimator.Estimate(campaign.Channel, rendered, 1),
+		})
+	}
+
+	return sample, nil
+}
+
+// getPendingApproval gets a campaign and checks it is waiting for the approval decision action
+func (s *CampaignService) getPendingApproval(ctx context.Context, campaignID int, action models.CampaignAction) (*models.Campaign, error) {
+	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	if !campaign.Status.Allows(action) {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("campaign is not pending approval: status is %s", campaign.Status),
+		}
+	}
+
+	return campaign, nil
+}
+
+// getCanary gets a campaign and checks it is holding a canary for the decision action
+func (s *CampaignService) getCanary(ctx context.Context, campaignID int, action models.CampaignAction) (*models.Campaign, error) {
+	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	if !campaign.Status.Allows(action) {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("campaign is not holding a canary: status is %s", campaign.Status),
+		}
+	}
+
+	return campaign, nil
+}
+
+// validateCanary checks the canary of a send, if it has one
+func validateCanary(canary *models.CanaryOptions) error {
+	if canary == nil {
+		return nil
+	}
+	if canary.Percent <= 0 || canary.Percent >= 100 {
+		return fmt.Errorf("canary_percent must be greater than 0 and less than 100")
+	}
+	if canary.Min < 0 {
+		return fmt.Errorf("canary_min cannot be negative")
+	}
+	return nil
+}
+
+// splitCanary splits an audience into the canary sent first and the rest held back
+// The canary is spread evenly through the audience rather than taken from its start. Without a
+// canary, or when the canary is the whole audience, nothing is held back
+func splitCanary(customers []*models.Customer, canary *models.CanaryOptions) ([]*models.Customer, []*models.Customer) {
+	if canary == nil {
+		return customers, nil
+	}
+	size := canary.Size(len(customers))
+	if size >= len(customers) {
+		return customers, nil
+	}
+
+	picked := make(map[int]bool, size)
+	for i := 0; i < size; i++ {
+		picked[i*len(customers)/size] = true
+	}
+
+	first := make([]*models.Customer, 0, size)
+	rest := make([]*models.Customer, 0, len(customers)-size)
+	for i, customer := range customers {
+		if picked[i] {
+			first = append(first, customer)
+		} else {
+			rest = append(rest, customer)
+		}
+	}
+	return first, rest
+}
+
+// holdCanary moves a campaign whose canary was queued to canary, storing the audience held
+// back, and the send's deadline, as its send plan, and updates the send result to match
+func (s *CampaignService) holdCanary(ctx context.Context, campaign *models.Campaign, heldBack []*models.Customer, processBy *time.Time, result *SendCampaignResult) error {
+	ids := make([]int, 0, len(heldBack))
+	for _, customer := range heldBack {
+		ids = append(ids, customer.ID)
+	}
+
+	plan := &models.SendPlan{
+		CustomerIDs:  ids,
+		AudienceSize: len(ids),
+		RequestedAt:  time.Now(),
+		ProcessBy:    processBy,
+	}
+	// The canary is already queued, so it is held even if the request went away meanwhile
+	if err := s.campaignRepo.HoldCanary(context.WithoutCancel(ctx), campaign.ID, plan); err != nil {
+		return fmt.Errorf("failed to hold campaign canary: %w", err)
+	}
+
+	result.Status = models.CampaignStatusCanary
+	result.CanaryHeldBack = len(ids)
+	return nil
+}
+
+// checkDuplicateContent fails the send when too much of the audience already got the same
+// template on the same channel from another campaign within the configured window
+func (s *CampaignService) checkDuplicateContent(ctx context.Context, campaign *models.Campaign, customers []*models.Customer) error {
+	if s.duplicate.Window <= 0 {
+		return nil
+	}
+
+	customerIDs := make([]int, len(customers))
+	for i, customer := range customers {
+		customerIDs[i] = customer.ID
+	}
+
+	since := time.Now().Add(-s.duplicate.Window)
+	count, err := s.messageRepo.CountRecentFingerprintRecipients(ctx, customerIDs, campaign.ContentFingerprint(), campaign.ID, since)
+	if err != nil {
+		return fmt.Errorf("failed to check for duplicate content: %w", err)
+	}
+
+	if float64(count) > s.duplicate.Threshold*float64(len(customers)) {
+		return &DuplicateContentError{Recipients: count, AudienceSize: len(customers), Window: s.duplicate.Window}
+	}
+
+	return nil
+}
+
+// applyFrequencyCap drops the customers already sent FREQUENCY_CAP_MAX_MESSAGES messages in the
+// window and returns the rest with how many were skipped; exempt campaigns reach everyone
+func (s *CampaignService) applyFrequencyCap(ctx context.Context, campaign *models.Campaign, customers []*models.Customer) ([]*models.Customer, int, error) {
+	if s.frequencyCap.MaxMessages <= 0 || campaign.FrequencyCapExempt {
+		return customers, 0, nil
+	}
+
+	customerIDs := make([]int, len(customers))
+	for i, customer := range customers {
+		customerIDs[i] = customer.ID
+	}
+
+	since := time.Now().Add(-s.frequencyCap.Window)
+	cappedIDs, err := s.messageRepo.ListFrequencyCapped(ctx, customerIDs, since, s.frequencyCap.MaxMessages)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to check frequency cap: %w", err)
+	}
+	if len(cappedIDs) == 0 {
+		return customers, 0, nil
+	}
+
+	capped := make(map[int]bool, len(cappedIDs))
+	for _, id := range cappedIDs {
+		capped[id] = true
+	}
+	allowed := make([]*models.Customer, 0, len(customers))
+	for _, customer := range customers {
+		if !capped[customer.ID] {
+			allowed = append(allowed, customer)
+		}
+	}
+
+	skipped := len(customers) - len(allowed)
+	if len(allowed) == 0 {
+		return nil, skipped, &BusinessLogicError{
+			Message: fmt.Sprintf("all %d customers already received %d messages in the last %d days", skipped, s.frequencyCap.MaxMessages, s.frequencyCap.Window/(24*time.Hour)),
+		}
+	}
+	return allowed, skipped, nil
+}
+
+// formatWindow writes a window in its largest whole unit, e.g. 24h rather than 24h0m0s
+func formatWindow(window time.Duration) string {
+	switch {
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%dh", window/time.Hour)
+	case window%time.Minute == 0:
+		return fmt.Sprintf("%dm", window/time.Minute)
+	default:
+		return window.String()
+	}
+}
+
+// dispatch creates outbound messages for the customers and publishes them to the queue,
+// DispatchBatchSize at a time, with processBy as their deadline when set; progress, when set,
+// is called after each batch with the messages queued so far
+// A send cancelled between batches, or failing after its first, stops with a
+// *SendInterruptedError; the batches already created stay queued
+func (s *CampaignService) dispatch(ctx context.Context, campaign *models.Campaign, customers []*models.Customer, processBy *time.Time, progress func(queued int)) (*SendCampaignResult, error) {
+	// A batch once started is finished even if the request goes away, so it is never left
+	// half created or created but unpublished; ctx is only checked between batches
+	persistCtx := context.WithoutCancel(ctx)
+	fingerprint := campaign.ContentFingerprint()
+
+	queued := 0
+	for start := 0; start < len(customers); start += DispatchBatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, interruptSend(ctx, campaign.ID, queued, len(customers), err)
+		}
+
+		end := min(start+DispatchBatchSize, len(customers))
+		messages, err := s.createMessages(persistCtx, campaign, customers[start:end], fingerprint, start == 0)
+		if err != nil {
+			// A send that queued nothing simply failed
+			if queued == 0 {
+				return nil, err
+			}
+			return nil, interruptSend(ctx, campaign.ID, queued, len(customers), err)
+		}
+
+		// Publish jobs to queue (outside transaction)
+		publishedIDs, err := s.publishMessages(persistCtx, campaign, messages, processBy)
+		recordQueuedEvent(persistCtx, s.sendEvents, campaign.ID, campaignQueuedPayload{MessagesQueued: len(publishedIDs)})
+
+		// The batch's messages exist either way; those left unpublished are the reconciler's
+		queued += len(messages)
+		if err != nil {
+			return nil, interruptSend(ctx, campaign.ID, queued, len(customers), err)
+		}
+		if progress != nil {
+			progress(queued)
+		}
+	}
+
+	s.events.Publish(ctx, notify.Event{
+		Type:           notify.EventCampaignSending,
+		CampaignID:     campaign.ID,
+		Channel:        string(campaign.Channel),
+		Status:         string(models.CampaignStatusSending),
+		MessagesQueued: queued,
+	})
+
+	return &SendCampaignResult{
+		CampaignID:     campaign.ID,
+		MessagesQueued: queued,
+		Status:         models.CampaignStatusSending,
+	}, nil
+}
+
+// interruptSend logs and returns a send stopped after queuing some of its messages
+func interruptSend(ctx context.Context, campaignID, queued, audienceSize int, err error) error {
+	log.Printf("%sWarning: Send to campaign %d stopped after queuing %d of %d messages: %v", reqctx.Prefix(ctx), campaignID, queued, audienceSize, err)
+	return &SendInterruptedError{CampaignID: campaignID, MessagesQueued: queued, AudienceSize: audienceSize, Err: err}
+}
+
+// createMessages creates a batch of outbound messages for the customers; the first batch of
+// a send also moves the campaign to sending, in the same transaction, so a send losing the
+// race to another leaves no messages behind
+func (s *CampaignService) createMessages(ctx context.Context, campaign *models.Campaign, customers []*models.Customer, fingerprint string, first bool) ([]*models.OutboundMessage, error) {
+	// Start transaction
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Create outbound messages without rendered content (will be rendered by worker); a
+	// template split into parts gets a message per part, in order
+	partCount := len(campaign.TemplateParts())
+	messages := make([]*models.OutboundMessage, 0, len(customers)*partCount)
+	for _, customer := range customers {
+		for part := 1; part <= partCount; part++ {
+			message := &models.OutboundMessage{
+				CampaignID:         campaign.ID,
+				CustomerID:         customer.ID,
+				Status:             models.MessageStatusPending,
+				RenderedContent:    nil, // Will be set by worker
+				RetryCount:         0,
+				CreatedAt:          time.Now(),
+				UpdatedAt:          time.Now(),
+				ContentFingerprint: &fingerprint,
+				Part:               part,
+				PartCount:          partCount,
+			}
+
+			messages = append(messages, message)
+		}
+	}
+
+	// Update campaign status to sending first, so a concurrent send waits on the campaign row
+	// and then finds it moved on; a resumed send is already sending
+	if first && campaign.Status != models.CampaignStatusSending {
+		if err := s.campaignRepo.UpdateStatusIfTx(ctx, tx, campaign.ID, campaign.Status, models.CampaignStatusSending); err != nil {
+			return nil, fmt.Errorf("failed to update campaign status: %w", err)
+		}
+	}
+
+	// Save messages in batch
+	if err := s.messageRepo.CreateBatchTx(ctx, tx, messages); err != nil {
+		return nil, fmt.Errorf("failed to create messages: %w", err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return messages, nil
+}
+
+// publishMessages publishes a job per message, carrying the send's deadline when it has one,
+// and returns the IDs that were published
+// Each message's publish time is recorded before its job is published, so a queued message
+// always has one and is never taken for unpublished; a message whose job then fails to
+// publish is deferred to now for the deferred requeue. Failing to record a publish time
+// stops publishing and is returned, leaving that message and the rest unpublished
+func (s *CampaignService) publishMessages(ctx context.Context, campaign *models.Campaign, messages []*models.OutboundMessage, processBy *time.Time) ([]int, error) {
+	publishedIDs := make([]int, 0, len(messages))
+
+	// Ordered campaigns go to a queue of their own, consumed by one worker at a time
+	publish := s.publisher.PublishJob
+	if campaign.Ordered {
+		publish = s.publisher.PublishOrderedJob
+	}
+
+	for _, message := range messages {
+		// Later parts of a multi-part send are published by the worker once the part before is sent
+		if message.GroupID != nil && message.Part > 1 {
+			continue
+		}
+
+		// Record publish time for queue latency tracking
+		if err := s.messageRepo.MarkPublished(ctx, []int{message.ID}); err != nil {
+			return publishedIDs, fmt.Errorf("failed to record publish time for message %d: %w", message.ID, err)
+		}
+
+		job := queue.NewMessageJob(message.ID, campaign.ID, message.CustomerID)
+		job.ProcessBy = processBy
+		if err := publish(job); err != nil {
+			log.Printf("Warning: Failed to publish message %d to queue: %v", message.ID, err)
+			if deferErr := s.messageRepo.DeferUntil(ctx, message.ID, time.Now(), "Publish failed: "+err.Error()); deferErr != nil {
+				log.Printf("Warning: Failed to defer message %d: %v", message.ID, deferErr)
+			}
+			continue
+		}
+		publishedIDs = append(publishedIDs, message.ID)
+	}
+
+	return publishedIDs, nil
+}
+
+// PreviewMessage previews how a message will render for a customer
+// An inline customer is rendered as given and never stored
+func (s *CampaignService) PreviewMessage(ctx context.Context, req *PreviewMessageRequest) (*PreviewMessageResult, error) {
+	// Get campaign
+	campaign, err := s.campaignRepo.GetByID(ctx, req.CampaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: req.CampaignID}
+	}
+
+	// Get customer, or build the one given inline
+	customer, source, err := s.previewCustomer(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use override template if provided, otherwise use campaign template
+	template := campaign.BaseTemplate
+	if req.OverrideTemplate != nil && *req.OverrideTemplate != "" {
+		template = *req.OverrideTemplate
+	}
+
+	// An override is read in the campaign's syntax too
+	templates := s.templateSvc.ForCampaign(campaign)
+	warnings := templates.Lint(template)
+	if req.Strict && len(warnings) > 0 {
+		return nil, &ValidationError{Message: fmt.Sprintf("template: %s", lintMessages(warnings))}
+	}
+
+	// Render template
+	renderedMessage, err := templates.Render(template, customer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return &PreviewMessageResult{
+		RenderedMessage:  renderedMessage,
+		UsedTemplate:     template,
+		TemplateWarnings: warnings,
+		DeliveryEstimate: s.estimator.Estimate(campaign.Channel, renderedMessage, 1),
+		Customer: struct {
+			ID        int    `json:"id"`
+			FirstName string `json:"first_name"`
+		}{
+			ID:        customer.ID,
+			FirstName: customer.FullName(),
+		},
+		CustomerSource: source,
+	}, nil
+}
+
+// previewCustomer returns the customer a preview renders for and where it came from
+func (s *CampaignService) previewCustomer(ctx context.Context, req *PreviewMessageRequest) (*models.Customer, string, error) {
+	if req.Customer == nil {
+		customer, err := s.customerRepo.GetByID(ctx, req.CustomerID)
+		if err != nil {
+			return nil, "", &NotFoundError{Resource: "customer", ID: req.CustomerID}
+		}
+		return customer, PreviewCustomerStored, nil
+	}
+
+	phone, err := models.NormalizePhone(req.Customer.Phone)
+	if err != nil {
+		return nil, "", &ValidationError{Message: fmt.Sprintf("customer: %v", err)}
+	}
+	customer := &models.Customer{
+		Phone:              phone,
+		FirstName:          req.Customer.FirstName,
+		LastName:           req.Customer.LastName,
+		Location:           req.Customer.Location,
+		PreferredProduct:   req.Customer.PreferredProduct,
+		ContactWindowStart: req.Customer.ContactWindowStart,
+		ContactWindowEnd:   req.Customer.ContactWindowEnd,
+	}
+	if _, err := customer.ContactWindow(); err != nil {
+		return nil, "", &ValidationError{Message: fmt.Sprintf("customer: %v", err)}
+	}
+	return customer, PreviewCustomerInline, nil
+}
+
+// PreviewDiff renders a customer's message with the current and a proposed template
+// Nothing is persisted
+func (s *CampaignService) PreviewDiff(ctx context.Context, req *PreviewDiffRequest) (*PreviewDiffResult, error) {
+	campaign, err := s.campaignRepo.GetByID(ctx, req.CampaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: req.CampaignID}
+	}
+
+	// The proposed template is read in the campaign's syntax
+	templates := s.templateSvc.ForCampaign(campaign)
+	if err := templates.ValidateTemplate(req.NewTemplate); err != nil {
+		return nil, &ValidationError{Message: fmt.Sprintf("new_template: %v", err)}
+	}
+
+	customer, err := s.customerRepo.GetByID(ctx, req.CustomerID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "customer", ID: req.CustomerID}
+	}
+
+	currentRender, err := templates.Render(campaign.BaseTemplate, customer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render current template: %w", err)
+	}
+
+	newRender, err := templates.Render(req.NewTemplate, customer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render new template: %w", err)
+	}
+
+	return &PreviewDiffResult{
+		CurrentRender:      currentRender,
+		NewRender:          newRender,
+		Changed:            currentRender != newRender,
+		PlaceholderChanges: diffPlaceholders(templates, campaign.BaseTemplate, req.NewTemplate),
+	}, nil
+}
+
+// PlaceholderCoverage reports, for the placeholders in a campaign's template,
+// how much of the targeted audience has a value to fill them with
+func (s *CampaignService) PlaceholderCoverage(ctx context.Context, campaignID int, req *PlaceholderCoverageRequest) (*PlaceholderCoverageResult, error) {
+	if len(req.CustomerIDs) == 0 {
+		return nil, &ValidationError{Message: "at least one customer ID required"}
+	}
+
+	warnBelow := DefaultCoverageWarnBelow
+	if req.WarnBelowPercent != nil {
+		warnBelow = *req.WarnBelowPercent
+	}
+	if warnBelow < 0 || warnBelow > 100 {
+		return nil, &ValidationError{Message: "warn_below_percent must be between 0 and 100"}
+	}
+
+	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	// Only known customer fields are counted; anything else is left as-is by Render
+	templates := s.templateSvc.ForCampaign(campaign)
+	fields := []string{}
+	warnings := []string{}
+	for _, placeholder := range uniquePlaceholders(templates, campaign.BaseTemplate) {
+		field := templates.PlaceholderField(placeholder)
+		if !models.IsCustomerTemplateField(field) {
+			warnings = append(warnings, fmt.Sprintf("%s is not a customer field and will be sent as written", placeholder))
+			continue
+		}
+		fields = append(fields, field)
+	}
+
+	completeness, err := s.customerRepo.CountMissingFields(ctx, req.CustomerIDs, fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count missing fields: %w", err)
+	}
+
+	if completeness.Total == 0 {
+		return nil, &ValidationError{Message: "no valid customers found"}
+	}
+
+	result := &PlaceholderCoverageResult{
+		CampaignID:       campaign.ID,
+		AudienceSize:     completeness.Total,
+		WarnBelowPercent: warnBelow,
+		Placeholders:     make([]*PlaceholderCoverage, 0, len(fields)),
+	}
+
+	for _, field := range fields {
+		missing := completeness.Missing[field]
+		coverage := &PlaceholderCoverage{
+			Placeholder:     templates.Placeholder(field),
+			Missing:         missing,
+			CoveragePercent: roundPercent(completeness.Total-missing, completeness.Total),
+		}
+		result.Placeholders = append(result.Placeholders, coverage)
+
+		if coverage.CoveragePercent < warnBelow {
+			warnings = append(warnings, fmt.Sprintf("%s is empty for %.1f%% of the audience (%d of %d customers)",
+				coverage.Placeholder, roundPercent(missing, completeness.Total), missing, completeness.Total))
+		}
+	}
+	result.Warnings = warnings
+
+	return result, nil
+}
+
+// roundPercent returns part as a percentage of total, rounded to one decimal
+func roundPercent(part, total int) float64 {
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
+
+// diffPlaceholders lists placeholders added to or removed from a template
+func diffPlaceholders(templates *TemplateService, current, proposed string) []PlaceholderChange {
+	currentPlaceholders := uniquePlaceholders(templates, current)
+	proposedPlaceholders := uniquePlaceholders(templates, proposed)
+
+	inCurrent := make(map[string]bool, len(currentPlaceholders))
+	for _, placeholder := range currentPlaceholders {
+		inCurrent[placeholder] = true
+	}
+	inProposed := make(map[string]bool, len(proposedPlaceholders))
+	for _, placeholder := range proposedPlaceholders {
+		inProposed[placeholder] = true
+	}
+
+	changes := []PlaceholderChange{}
+	for _, placeholder := range proposedPlaceholders {
+		if !inCurrent[placeholder] {
+			changes = append(changes, PlaceholderChange{Placeholder: placeholder, Change: PlaceholderAdded})
+		}
+	}
+	for _, placeholder := range currentPlaceholders {
+		if !inProposed[placeholder] {
+			changes = append(changes, PlaceholderChange{Placeholder: placeholder, Change: PlaceholderRemoved})
+		}
+	}
+
+	return changes
+}
+
+// uniquePlaceholders returns the placeholders of a template in order of first use
+func uniquePlaceholders(templates *TemplateService, template string) []string {
+	seen := make(map[string]bool)
+	unique := []string{}
+	for _, placeholder := range templates.GetPlaceholders(template) {
+		if !seen[placeholder] {
+			seen[placeholder] = true
+			unique = append(unique, placeholder)
+		}
+	}
+	return unique
+}
+
+// Request/Response types
+
+// CreateCampaignRequest represents a request to create a campaign
+type CreateCampaignRequest struct {
+	Name         string         `json:"name"`
+	Channel      models.Channel `json:"channel"`
+	BaseTemplate string         `json:"base_template"`
+	ScheduledAt  *time.Time     `json:"scheduled_at,omitempty"`
+	Tags         []string       `json:"tags,omitempty"`
+	Team         string         `json:"team,omitempty"`
+	Budget       *float64       `json:"budget,omitempty"` // Most the campaign's sends may cost
+
+	// FrequencyCapExempt lets transactional-style campaigns reach customers over the frequency cap
+	FrequencyCapExempt bool `json:"frequency_cap_exempt,omitempty"`
+
+	// TrackLinks sends each link in the template as a tracked redirect that counts its clicks
+	TrackLinks bool `json:"track_links,omitempty"`
+	// Ordered processes the campaign's messages one at a time in creation order, e.g. for one
+	// part of a multi-part announcement that must not overlap the next per recipient
+	Ordered bool `json:"ordered,omitempty"`
+	// PartDelimiter splits the template into 2 to MaxMessageParts parts, e.g. on "---", sent
+	// to each customer as separate messages in order
+	PartDelimiter *string `json:"part_delimiter,omitempty"`
+
+	// TemplateSyntax is the placeholder style of an imported template, e.g. brackets for [[first_name]]
+	// (omitted uses the server's TEMPLATE_SYNTAX)
+	TemplateSyntax *models.TemplateSyntax `json:"template_syntax,omitempty"`
+
+	// StrictTemplate rejects a template with lint warnings instead of returning them
+	StrictTemplate bool `json:"strict_template,omitempty"`
+
+	// DemoFailureRate makes the mock sender fail this share of the campaign's sends (0-1),
+	// for demos; stored everywhere but only honored outside production
+	DemoFailureRate *float64 `json:"demo_failure_rate,omitempty"`
+
+	// CreatedBy is the authenticated caller, set by the handler rather than the request body
+	CreatedBy string `json:"-"`
+}
+
+// Validate validates the create campaign request
+func (r *CreateCampaignRequest) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Channel != models.ChannelSMS && r.Channel != models.ChannelWhatsApp {
+		return fmt.Errorf("invalid channel: must be 'sms' or 'whatsapp'")
+	}
+	if r.BaseTemplate == "" {
+		return fmt.Errorf("base_template is required")
+	}
+	if err := models.ValidateTags(r.Tags); err != nil {
+		return err
+	}
+	if len(r.Team) > 100 {
+		return fmt.Errorf("team must be at most 100 characters")
+	}
+	if err := validateBudget(r.Budget); err != nil {
+		return err
+	}
+	if r.TemplateSyntax != nil && !r.TemplateSyntax.IsValid() {
+		return fmt.Errorf("invalid template_syntax: must be one of %s", templateSyntaxList())
+	}
+	if r.DemoFailureRate != nil && (*r.DemoFailureRate < 0 || *r.DemoFailureRate > 1) {
+		return fmt.Errorf("demo_failure_rate must be between 0 and 1")
+	}
+	if r.PartDelimiter != nil {
+		if err := validateParts(r.BaseTemplate, *r.PartDelimiter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MaxMessageParts is the most messages a template may be split into per customer
+const MaxMessageParts = 5
+
+// MaxPartDelimiterLength is the longest part delimiter the campaigns.part_delimiter column holds
+const MaxPartDelimiterLength = 20
+
+// validateParts checks that the delimiter splits the template into 2 to MaxMessageParts parts,
+// none of them blank
+func validateParts(template, delimiter string) error {
+	if strings.TrimSpace(delimiter) == "" || len(delimiter) > MaxPartDelimiterLength {
+		return fmt.Errorf("part_delimiter must be 1 to %d characters, not only whitespace", MaxPartDelimiterLength)
+	}
+	campaign := &models.Campaign{BaseTemplate: template, PartDelimiter: &delimiter}
+	parts := campaign.TemplateParts()
+	if len(parts) < 2 || len(parts) > MaxMessageParts {
+		return fmt.Errorf("base_template must split into 2 to %d parts on part_delimiter, not %d", MaxMessageParts, len(parts))
+	}
+	for i, part := range parts {
+		if part == "" {
+			return fmt.Errorf("part %d of base_template is empty", i+1)
+		}
+	}
+	return nil
+}
+
+// templateSyntaxList lists the supported template syntaxes for error messages
+func templateSyntaxList() string {
+	names := make([]string, len(models.TemplateSyntaxes))
+	for i, syntax := range models.TemplateSyntaxes {
+		names[i] = "'" + string(syntax) + "'"
+	}
+	return strings.Join(names, ", ")
+}
+
+// MaxCampaignBudget is the largest budget the campaigns.budget column holds
+const MaxCampaignBudget = 9999999999.99
+
+// validateBudget checks an optional campaign budget is positive and storable
+func validateBudget(budget *float64) error {
+	if budget == nil {
+		return nil
+	}
+	if *budget <= 0 || *budget > MaxCampaignBudget {
+		return fmt.Errorf("budget must be greater than 0 and at most %.2f", MaxCampaignBudget)
+	}
+	return nil
+}
+
+// ResumeCampaignRequest represents a request to resume a paused campaign
+type ResumeCampaignRequest struct {
+	Budget *float64 `json:"budget,omitempty"` // Replaces the budget, e.g. to raise a spent one
+}
+
+// ResumeCampaignResult represents the result of resuming a paused campaign
+type ResumeCampaignResult struct {
+	CampaignID       int                   `json:"campaign_id"`
+	Status           models.CampaignStatus `json:"status"`
+	Budget           *float64              `json:"budget,omitempty"`
+	MessagesReleased int                   `json:"messages_released"`
+}
+
+// Grace periods before a cancellation becomes final: the default when none is given, and the longest allowed
+const (
+	DefaultCancelGrace = time.Minute
+	MaxCancelGrace     = time.Hour
+)
+
+// CancelCampaignResult represents the result of cancelling a campaign
+// CancelAt is when the cancellation becomes final (or became final, without a grace period)
+type CancelCampaignResult struct {
+	CampaignID        int                   `json:"campaign_id"`
+	Status            models.CampaignStatus `json:"status"`
+	CancelAt          time.Time             `json:"cancel_at"`
+	MessagesCancelled int                   `json:"messages_cancelled"`
+}
+
+// UndoCancelResult represents the result of restoring a cancelling campaign
+type UndoCancelResult struct {
+	CampaignID       int                   `json:"campaign_id"`
+	Status           models.CampaignStatus `json:"status"`
+	MessagesReleased int                   `json:"messages_released"`
+}
+
+// SendCampaignResult represents the result of sending a campaign
+type SendCampaignResult struct {
+	CampaignID      int                    `json:"campaign_id"`
+	MessagesQueued  int                    `json:"messages_queued"`
+	AudienceSize    int                    `json:"audience_size,omitempty"` // Set when the send awaits approval
+	Status          models.CampaignStatus  `json:"status"`
+	InlineCustomers *InlineCustomersResult `json:"inline_customers,omitempty"` // Set when the send had inline customers
+
+	// SkippedBlocked counts customers left out because an operator blocked them
+	SkippedBlocked int `json:"skipped_blocked,omitempty"`
+	// SkippedSuppressed counts customers left out because their phone is on the campaign's suppression list
+	SkippedSuppressed int `json:"skipped_suppressed,omitempty"`
+	// SkippedFrequencyCap counts customers left out because they reached the frequency cap
+	SkippedFrequencyCap int `json:"skipped_frequency_cap,omitempty"`
+	// SkippedLowEngagement counts customers left out because they score below the send's
+	// min_engagement_score or are not yet scored
+	SkippedLowEngagement int `json:"skipped_low_engagement,omitempty"`
+	// SkippedAlreadyQueued counts customers left out of a resumed send because the
+	// interrupted send already queued their message
+	SkippedAlreadyQueued int `json:"skipped_already_queued,omitempty"`
+	// CanaryHeldBack counts customers a canary send held back until it is continued
+	CanaryHeldBack int `json:"canary_held_back,omitempty"`
+}
+
+// SendLogIDSampleSize is how many of a send's customer IDs are kept in the send log
+const SendLogIDSampleSize = 100
+
+// SendHistory is the send requests made against a campaign
+type SendHistory struct {
+	CampaignID int                  `json:"campaign_id"`
+	Sends      []*models.SendRecord `json:"sends"`
+}
+
+// MaxInlineCustomers caps the customers given inline in one send
+const MaxInlineCustomers = 1000
+
+// InlineCustomer is a customer given in full in a send instead of by ID
+type InlineCustomer struct {
+	Phone            string  `json:"phone"`
+	FirstName        *string `json:"first_name,omitempty"`
+	LastName         *string `json:"last_name,omitempty"`
+	Location         *string `json:"location,omitempty"`
+	PreferredProduct *string `json:"preferred_product,omitempty"`
+
+	// Local HH:MM times the customer may be messaged between; set both or neither
+	ContactWindowStart *string `json:"contact_window_start,omitempty"`
+	ContactWindowEnd   *string `json:"contact_window_end,omitempty"`
+}
+
+// InlineCustomersResult reports how the inline customers of a send were stored
+type InlineCustomersResult struct {
+	Created int `json:"created"`
+	Matched int `json:"matched"`
+}
+
+// DispatchBatchSize is the number of messages created and published together during a
+// send; a cancelled send stops between batches
+const DispatchBatchSize = 500
+
+// CSVPhoneBatchSize is the number of phones matched per customer lookup
+const CSVPhoneBatchSize = 1000
+
+// CSVUnmatchedSampleSize caps the unmatched phones listed in a CSV send result
+const CSVUnmatchedSampleSize = 100
+
+// SendCampaignCSVRequest holds the options for sending to an uploaded CSV
+type SendCampaignCSVRequest struct {
+	ContentEncoding       string // "gzip" when the upload is compressed
+	CreateUnknown         bool   // Create customers for phones that match nobody
+	AllowDuplicateContent bool   // Send even if the audience recently got the same content
+	OverrideSaturation    bool   // Send even while the send backlog is saturated (admins only)
+	RequestedBy           string // Authenticated caller, for the send log
+}
+
+// SendOptions holds the optional parts of a send
+type SendOptions struct {
+	Customers             []InlineCustomer      // Customers to create or update by phone and add to the audience
+	AllowDuplicateContent bool                  // Skip the duplicate content check
+	OverrideSaturation    bool                  // Send even while the send backlog is saturated (admins only)
+	RequestedBy           string                // Authenticated caller, for the send log
+	Source                models.SendSource     // How the send arrived; api when empty
+	Canary                *models.CanaryOptions // Send to a canary first and hold the rest back; nil sends to everyone
+	MinEngagementScore    *float64              // Skip customers scoring below it or not yet scored; nil sends to everyone
+	ProcessBy             *time.Time            // Expire messages still unsent by then instead of sending them late; nil never expires
+}
+
+// SendCampaignCSVResult reports how an uploaded CSV was matched and the resulting send
+type SendCampaignCSVResult struct {
+	*SendCampaignResult
+
+	Rows            int                   `json:"rows"`
+	Matched         int                   `json:"matched"`
+	Unmatched       int                   `json:"unmatched"`
+	Created         int                   `json:"created"`
+	Invalid         int                   `json:"invalid"`
+	UnmatchedPhones []string              `json:"unmatched_phones"` // First CSVUnmatchedSampleSize only
+	Errors          []*csvimport.RowError `json:"errors,omitempty"`
+}
+
+// ReRenderSampleSize is the number of messages rendered for a dry run
+const ReRenderSampleSize = 10
+
+// ReRenderRequest represents a request to re-render pending messages
+type ReRenderRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// DeleteResult represents the result of deleting a campaign or customer
+type DeleteResult struct {
+	ID              int `json:"id"`
+	MessagesDeleted int `json:"messages_deleted"`
+}
+
+// ReRenderResult represents the result of re-rendering pending messages
+type ReRenderResult struct {
+	CampaignID      int               `json:"campaign_id"`
+	DryRun          bool              `json:"dry_run"`
+	MessagesCleared int               `json:"messages_cleared"`
+	Sample          []*ReRenderSample `json:"sample,omitempty"`
+}
+
+// ReRenderSample is a pending message rendered with the current template
+type ReRenderSample struct {
+	MessageID        int               `json:"message_id"`
+	CustomerID       int               `json:"customer_id"`
+	RenderedContent  string            `json:"rendered_content"`
+	DeliveryEstimate *DeliveryEstimate `json:"delivery_estimate,omitempty"`
+}
+
+// PreviewMessageRequest represents a request to preview a message
+// Customer, when set, is rendered instead of the stored customer CustomerID
+type PreviewMessageRequest struct {
+	CampaignID       int             `json:"campaign_id"`
+	CustomerID       int             `json:"customer_id"`
+	Customer         *InlineCustomer `json:"customer,omitempty"`
+	OverrideTemplate *string         `json:"override_template,omitempty"`
+	Strict           bool            `json:"strict,omitempty"` // Fail on template lint warnings
+}
+
+// Where the customer of a preview came from
+const (
+	PreviewCustomerStored = "stored" // Loaded by customer_id
+	PreviewCustomerInline = "inline" // Given in the request
+)
+
+// PreviewMessageResult represents the result of previewing a message
+type PreviewMessageResult struct {
+	RenderedMessage  string            `json:"rendered_message"`
+	UsedTemplate     string            `json:"used_template"`
+	TemplateWarnings []TemplateWarning `json:"template_warnings"`
+	DeliveryEstimate *DeliveryEstimate `json:"delivery_estimate,omitempty"`
+	Customer         struct {
+		ID        int    `json:"id"`
+		FirstName string `json:"first_name"`
+	} `json:"customer"`
+	CustomerSource string `json:"customer_source"` // PreviewCustomerStored or PreviewCustomerInline
+}
+
+// PreviewDiffRequest represents a request to compare current and proposed renders
+type PreviewDiffRequest struct {
+	CampaignID  int    `json:"campaign_id"`
+	CustomerID  int    `json:"customer_id"`
+	NewTemplate string `json:"new_template"`
+}
+
+// Placeholder change types
+const (
+	PlaceholderAdded   = "added"
+	PlaceholderRemoved = "removed"
+)
+
+// PlaceholderChange is a placeholder added to or removed from the template
+type PlaceholderChange struct {
+	Placeholder string `json:"placeholder"`
+	Change      string `json:"change"`
+}
+
+// PreviewDiffResult represents the current and proposed renders side by side
+type PreviewDiffResult struct {
+	CurrentRender      string              `json:"current_render"`
+	NewRender          string              `json:"new_render"`
+	Changed            bool                `json:"changed"`
+	PlaceholderChanges []PlaceholderChange `json:"placeholder_changes"`
+}
+
+// DefaultCoverageWarnBelow is the coverage percentage under which a placeholder is flagged
+const DefaultCoverageWarnBelow = 90.0
+
+// PlaceholderCoverageRequest represents the audience to check a template against
+type PlaceholderCoverageRequest struct {
+	CustomerIDs      []int    `json:"customer_ids"`
+	WarnBelowPercent *float64 `json:"warn_below_percent,omitempty"`
+}
+
+// PlaceholderCoverage is the share of the audience with a value for one placeholder
+type PlaceholderCoverage struct {
+	Placeholder     string  `json:"placeholder"`
+	Missing         int     `json:"missing"`
+	CoveragePercent float64 `json:"coverage_percent"`
+}
+
+// PlaceholderCoverageResult represents placeholder coverage for an audience
+type PlaceholderCoverageResult struct {
+	CampaignID       int                    `json:"campaign_id"`
+	AudienceSize     int                    `json:"audience_size"`
+	WarnBelowPercent float64                `json:"warn_below_percent"`
+	Placeholders     []*PlaceholderCoverage `json:"placeholders"`
+	Warnings         []string               `json:"warnings"`
 }
 
 // PaginationInfo represents pagination metadata
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/metrics"
+	"smsleopard/internal/models"
+)
+
+// CarrierThrottleFallback names the bucket for phones matching no configured prefix
+const CarrierThrottleFallback = "default"
+
+// CarrierThrottle paces sends per destination prefix so carriers that throttle their own
+// throughput are not flooded. Each phone is sent through the bucket of its longest matching
+// prefix, or the fallback bucket at SEND_RATE_LIMIT_PER_SECOND when none match
+// It is safe for concurrent use: the prefix table is fixed at creation and each bucket locks itself
+type CarrierThrottle struct {
+	buckets   map[string]*throttleBucket // By prefix; prefixes of one limit share a bucket
+	fallback  *throttleBucket            // nil when unmatched phones are not paced
+	maxPrefix int
+	now       func() time.Time
+	sleep     func(ctx context.Context, d time.Duration) error
+}
+
+// throttleBucket spaces sends evenly at its rate and caps how many are in flight at once
+type throttleBucket struct {
+	name     string
+	interval time.Duration
+	slots    chan struct{} // nil when in-flight sends are not capped
+
+	mu   sync.Mutex
+	next time.Time // Earliest time the next send may start
+}
+
+// NewCarrierThrottle creates a throttle from the per-prefix limits, or returns nil, which
+// paces nothing, when none are configured
+func NewCarrierThrottle(sending config.SendingConfig) *CarrierThrottle {
+	if len(sending.PrefixLimits) == 0 {
+		return nil
+	}
+
+	t := &CarrierThrottle{
+		buckets: map[string]*throttleBucket{},
+		now:     time.Now,
+		sleep:   sleepContext,
+	}
+	for _, limit := range sending.PrefixLimits {
+		bucket := newThrottleBucket(strings.Join(limit.Prefixes, ","), limit.PerSecond, limit.MaxInFlight)
+		for _, prefix := range limit.Prefixes {
+			t.buckets[prefix] = bucket
+			if len(prefix) > t.maxPrefix {
+				t.maxPrefix = len(prefix)
+			}
+		}
+	}
+	if sending.RateLimitPerSecond > 0 {
+		t.fallback = newThrottleBucket(CarrierThrottleFallback, sending.RateLimitPerSecond, 0)
+	}
+	return t
+}
+
+func newThrottleBucket(name string, perSecond, maxInFlight int) *throttleBucket {
+	bucket := &throttleBucket{name: name, interval: time.Second / time.Duration(perSecond)}
+	if maxInFlight > 0 {
+		bucket.slots = make(chan struct{}, maxInFlight)
+	}
+	return bucket
+}
+
+// SetClock overrides time.Now and the wait between sends (for testing)
+func (t *CarrierThrottle) SetClock(now func() time.Time, sleep func(ctx context.Context, d time.Duration) error) {
+	t.now = now
+	t.sleep = sleep
+}
+
+// Bucket returns the name of the bucket a phone is paced by, or "" when it is not paced
+func (t *CarrierThrottle) Bucket(phone string) string {
+	if bucket := t.bucketFor(phone); bucket != nil {
+		return bucket.name
+	}
+	return ""
+}
+
+// Acquire waits until a send to phone is allowed by its bucket and returns the function that
+// ends the send, which must be called once it completes; a nil throttle allows every send
+func (t *CarrierThrottle) Acquire(ctx context.Context, phone string) (func(), error) {
+	bucket := t.bucketFor(phone)
+	if bucket == nil {
+		return func() {}, nil
+	}
+
+	start := t.now()
+	if bucket.slots != nil {
+		select {
+		case bucket.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if bucket.slots != nil {
+			<-bucket.slots
+		}
+	}
+
+	// Claim the next slot in the bucket's schedule, then wait for it outside the lock
+	now := t.now()
+	bucket.mu.Lock()
+	at := bucket.next
+	if at.Before(now) {
+		at = now
+	}
+	bucket.next = at.Add(bucket.interval)
+	bucket.mu.Unlock()
+
+	if wait := at.Sub(now); wait > 0 {
+		if err := t.sleep(ctx, wait); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	metrics.CarrierThrottleWait.WithLabelValues(bucket.name).Observe(t.now().Sub(start).Seconds())
+	return release, nil
+}
+
+// bucketFor finds the bucket of the longest configured prefix of the normalized phone
+func (t *CarrierThrottle) bucketFor(phone string) *throttleBucket {
+	if t == nil {
+		return nil
+	}
+	if normalized, err := models.NormalizePhone(phone); err == nil {
+		phone = normalized
+	}
+
+	for n := min(len(phone), t.maxPrefix); n > 1; n-- {
+		if bucket, ok := t.buckets[phone[:n]]; ok {
+			return bucket
+		}
+	}
+	return t.fallback
+}
+
+// sleepContext waits for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
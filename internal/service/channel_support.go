@@ -0,0 +1,43 @@
+package service
+
+import (
+	"smsleopard/internal/models"
+)
+
+// ChannelSupport is the channels the configured sender provider can deliver on
+// Campaigns on any other channel are refused when created or sent instead of every
+// message failing in the worker
+type ChannelSupport struct {
+	Provider string
+	Channels []models.Channel
+}
+
+// NewChannelSupport records the channels sender advertises under the provider's name
+func NewChannelSupport(provider string, sender Sender) *ChannelSupport {
+	return &ChannelSupport{
+		Provider: provider,
+		Channels: sender.Capabilities(),
+	}
+}
+
+// Supports reports whether the provider delivers on channel; a nil ChannelSupport
+// supports every channel
+func (c *ChannelSupport) Supports(channel models.Channel) bool {
+	if c == nil {
+		return true
+	}
+	for _, supported := range c.Channels {
+		if supported == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an UnsupportedChannelError naming the provider when it cannot deliver on channel
+func (c *ChannelSupport) Check(channel models.Channel) error {
+	if c.Supports(channel) {
+		return nil
+	}
+	return &UnsupportedChannelError{Provider: c.Provider, Channel: channel, Supported: c.Channels}
+}
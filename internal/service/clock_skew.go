@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"smsleopard/internal/repository"
+)
+
+// CheckClockSkew measures how far the database clock is ahead of the app server's (negative when
+// behind) and logs a warning when the difference exceeds threshold (0 disables the warning).
+// The app time compared is the midpoint of the round trip, so query latency is not counted as skew.
+func CheckClockSkew(ctx context.Context, clock repository.ClockRepository, threshold time.Duration) (time.Duration, error) {
+	before := time.Now()
+	remote, err := clock.Now(ctx)
+	if err != nil {
+		return 0, err
+	}
+	after := time.Now()
+
+	skew := remote.Sub(before.Add(after.Sub(before) / 2))
+	if threshold > 0 && (skew > threshold || skew < -threshold) {
+		log.Printf("Warning: App and database clocks differ by %s (threshold %s); scheduling decisions use database time",
+			skew.Round(time.Millisecond), threshold)
+	}
+	return skew, nil
+}
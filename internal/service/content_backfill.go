@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"smsleopard/internal/repository"
+)
+
+// ContentBackfill reconstructs rendered content for sent messages stored without it
+// Content is rendered from the campaign's current template and the customer's current data,
+// so it may differ from what was actually sent; stored rows are flagged backfilled
+type ContentBackfill struct {
+	messageRepo repository.MessageRepository
+	templateSvc *TemplateService
+	batchSize   int
+}
+
+// NewContentBackfill creates a backfill working through batchSize messages at a time
+func NewContentBackfill(messageRepo repository.MessageRepository, templateSvc *TemplateService, batchSize int) *ContentBackfill {
+	return &ContentBackfill{
+		messageRepo: messageRepo,
+		templateSvc: templateSvc,
+		batchSize:   batchSize,
+	}
+}
+
+// ContentBackfillBatch is the outcome of backfilling one batch
+type ContentBackfillBatch struct {
+	Scanned int      // Messages found without content
+	Updated int      // Messages written (fewer than rendered if content was recorded concurrently)
+	Failed  []string // Messages whose template could not be rendered, left without content
+	LastID  int      // Resume after this ID
+}
+
+// RunBatch backfills the next batch of messages after afterID
+// Call repeatedly with the returned LastID until Scanned is 0
+func (b *ContentBackfill) RunBatch(ctx context.Context, filters repository.ContentBackfillFilters, afterID int) (*ContentBackfillBatch, error) {
+	messages, err := b.messageRepo.ListMissingContent(ctx, filters, afterID, b.batchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &ContentBackfillBatch{Scanned: len(messages), LastID: afterID}
+	contents := make(map[int]string, len(messages))
+	for _, message := range messages {
+		batch.LastID = message.ID
+
+		rendered, err := b.templateSvc.ForCampaign(&message.Campaign).Render(message.Campaign.BaseTemplate, &message.Customer)
+		if err != nil {
+			batch.Failed = append(batch.Failed, fmt.Sprintf("message %d: %v", message.ID, err))
+			continue
+		}
+		contents[message.ID] = rendered
+	}
+
+	batch.Updated, err = b.messageRepo.StoreBackfilledContent(ctx, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// MaxBlockReasonLength bounds the reason recorded with a customer block
+const MaxBlockReasonLength = 1000
+
+// CustomerDetail is a customer with their block status and its history, and the numbers
+// they had before
+type CustomerDetail struct {
+	*models.Customer
+	BlockHistory []*models.CustomerBlockEvent  `json:"block_history"`
+	PhoneHistory []*models.CustomerPhoneChange `json:"phone_history"`
+}
+
+// BlockCustomerRequest blocks a customer from every future send
+type BlockCustomerRequest struct {
+	Reason    string `json:"reason"`
+	BlockedBy string `json:"-"` // Authenticated caller, for the audit log
+}
+
+// GetCustomer returns a customer with their block status and history, and phone changes
+func (s *CustomerService) GetCustomer(ctx context.Context, customerID int) (*CustomerDetail, error) {
+	customer, err := s.customerRepo.GetDetail(ctx, customerID)
+	if errors.Is(err, repository.ErrCustomerNotFound) {
+		return nil, &NotFoundError{Resource: "customer", ID: customerID}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	history, err := s.customerRepo.ListBlockEvents(ctx, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer block history: %w", err)
+	}
+
+	phones, err := s.customerRepo.ListPhoneHistory(ctx, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer phone history: %w", err)
+	}
+
+	return &CustomerDetail{Customer: customer, BlockHistory: history, PhoneHistory: phones}, nil
+}
+
+// BlockCustomer blocks a customer, recording why and who blocked them
+// Unlike an opt-out it is an operator's decision: the customer is left out of every send
+// planned afterwards and the worker refuses messages already queued for them
+// Blocking a blocked customer replaces the reason
+func (s *CustomerService) BlockCustomer(ctx context.Context, customerID int, req *BlockCustomerRequest) (*CustomerDetail, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, &ValidationError{Message: "reason is required"}
+	}
+	if len([]rune(reason)) > MaxBlockReasonLength {
+		return nil, &ValidationError{Message: fmt.Sprintf("reason must be at most %d characters", MaxBlockReasonLength)}
+	}
+
+	if err := s.customerRepo.Block(ctx, customerID, reason, optionalActor(req.BlockedBy)); err != nil {
+		if errors.Is(err, repository.ErrCustomerNotFound) {
+			return nil, &NotFoundError{Resource: "customer", ID: customerID}
+		}
+		return nil, fmt.Errorf("failed to block customer: %w", err)
+	}
+
+	return s.GetCustomer(ctx, customerID)
+}
+
+// UnblockCustomer lifts a customer's block, recording who lifted it
+// Messages refused while they were blocked stay refused
+func (s *CustomerService) UnblockCustomer(ctx context.Context, customerID int, unblockedBy string) (*CustomerDetail, error) {
+	customer, err := s.customerRepo.GetDetail(ctx, customerID)
+	if errors.Is(err, repository.ErrCustomerNotFound) {
+		return nil, &NotFoundError{Resource: "customer", ID: customerID}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+	if !customer.Blocked {
+		return nil, &ConflictError{Resource: "customer", Message: "customer is not blocked"}
+	}
+
+	if err := s.customerRepo.Unblock(ctx, customerID, optionalActor(unblockedBy)); err != nil {
+		if errors.Is(err, repository.ErrCustomerNotFound) {
+			return nil, &NotFoundError{Resource: "customer", ID: customerID}
+		}
+		return nil, fmt.Errorf("failed to unblock customer: %w", err)
+	}
+
+	return s.GetCustomer(ctx, customerID)
+}
+
+// optionalActor returns the caller for the audit log, or nil when the request was anonymous
+func optionalActor(actor string) *string {
+	if actor == "" {
+		return nil
+	}
+	return &actor
+}
+
+// applyBlocks leaves out blocked customers and returns how many were left out
+// Blocks apply to every campaign, with no exemption
+func (s *CampaignService) applyBlocks(ctx context.Context, customers []*models.Customer) ([]*models.Customer, int, error) {
+	customerIDs := make([]int, len(customers))
+	for i, customer := range customers {
+		customerIDs[i] = customer.ID
+	}
+
+	blockedIDs, err := s.customerRepo.ListBlocked(ctx, customerIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to check blocked customers: %w", err)
+	}
+	if len(blockedIDs) == 0 {
+		return customers, 0, nil
+	}
+
+	blocked := make(map[int]bool, len(blockedIDs))
+	for _, id := range blockedIDs {
+		blocked[id] = true
+	}
+	allowed := make([]*models.Customer, 0, len(customers))
+	for _, customer := range customers {
+		if !blocked[customer.ID] {
+			allowed = append(allowed, customer)
+		}
+	}
+
+	skipped := len(customers) - len(allowed)
+	if len(allowed) == 0 {
+		return nil, skipped, &BusinessLogicError{
+			Message: fmt.Sprintf("all %d customers are blocked", skipped),
+		}
+	}
+	return allowed, skipped, nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// customerExportCSVHeader is the column order of a customer CSV export
+var customerExportCSVHeader = []string{
+	"id", "phone", "first_name", "last_name", "location", "preferred_product",
+	"contact_window_start", "contact_window_end", "created_at", "blocked", "blocked_reason",
+}
+
+// ExportCustomersCSV writes the customers matching filters to w as CSV, one row at a time
+// Exports over the configured row cap are refused before anything is written; rows written
+// before a later error stay written, so callers that have sent headers can only log it
+func (s *CustomerService) ExportCustomersCSV(ctx context.Context, filters repository.CustomerFilters, w io.Writer) error {
+	filters.Query = strings.TrimSpace(filters.Query)
+	filters.Location = strings.TrimSpace(filters.Location)
+	filters.Product = strings.TrimSpace(filters.Product)
+
+	count, err := s.customerRepo.CountFiltered(ctx, filters)
+	if err != nil {
+		return fmt.Errorf("failed to count customers: %w", err)
+	}
+	if count > s.limits.MaxCustomerExportRows {
+		return &ValidationError{Message: fmt.Sprintf(
+			"export matches %d customers, over the limit of %d; narrow the filters", count, s.limits.MaxCustomerExportRows)}
+	}
+
+	flusher, _ := w.(interface{ Flush() })
+	writer := csv.NewWriter(w)
+	if err := writer.Write(customerExportCSVHeader); err != nil {
+		return fmt.Errorf("failed to write export header: %w", err)
+	}
+
+	written := 0
+	// The cap also bounds the stream, in case customers were added since the count
+	err = s.customerRepo.StreamFiltered(ctx, filters, s.limits.MaxCustomerExportRows, func(customer *models.Customer) error {
+		if err := writer.Write(customerExportCSVRecord(customer)); err != nil {
+			return fmt.Errorf("failed to write export row: %w", err)
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			writer.Flush()
+			if err := writer.Error(); err != nil {
+				return fmt.Errorf("failed to write export row: %w", err)
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export customers: %w", err)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+// customerExportCSVRecord flattens a customer into CSV columns; missing values are left empty
+func customerExportCSVRecord(customer *models.Customer) []string {
+	optional := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return spreadsheetSafe(*value)
+	}
+
+	return []string{
+		strconv.Itoa(customer.ID),
+		spreadsheetSafe(customer.Phone),
+		optional(customer.FirstName),
+		optional(customer.LastName),
+		optional(customer.Location),
+		optional(customer.PreferredProduct),
+		optional(customer.ContactWindowStart),
+		optional(customer.ContactWindowEnd),
+		customer.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.FormatBool(customer.Blocked),
+		optional(customer.BlockedReason),
+	}
+}
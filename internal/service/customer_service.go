@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// DefaultMaxCustomerFieldLength is used when no customer field limit is configured
+const DefaultMaxCustomerFieldLength = 255
+
+// DefaultMaxCustomerExportRows is used when no customer export cap is configured
+const DefaultMaxCustomerExportRows = 1000000
+
+// CustomerService handles customer business logic
+type CustomerService struct {
+	customerRepo repository.CustomerRepository
+	limits       config.LimitsConfig
+	rules        *config.CustomerRules
+}
+
+// NewCustomerService creates a new customer service
+func NewCustomerService(customerRepo repository.CustomerRepository, limits config.LimitsConfig) *CustomerService {
+	if limits.MaxCustomerFieldLength <= 0 {
+		limits.MaxCustomerFieldLength = DefaultMaxCustomerFieldLength
+	}
+	if limits.MaxCustomerExportRows <= 0 {
+		limits.MaxCustomerExportRows = DefaultMaxCustomerExportRows
+	}
+
+	return &CustomerService{
+		customerRepo: customerRepo,
+		limits:       limits,
+	}
+}
+
+// SetCustomerRules sets the deployment's customer field rules checked before a customer is saved
+// No rules are checked until this is called
+func (s *CustomerService) SetCustomerRules(rules *config.CustomerRules) {
+	s.rules = rules
+}
+
+// ListCustomers returns a page of the customers matching filters, newest first, with their
+// engagement scores
+func (s *CustomerService) ListCustomers(ctx context.Context, filters repository.CustomerFilters, page, pageSize int) ([]*models.Customer, *PaginationInfo, error) {
+	filters.Query = strings.TrimSpace(filters.Query)
+	filters.Location = strings.TrimSpace(filters.Location)
+	filters.Product = strings.TrimSpace(filters.Product)
+
+	total, err := s.customerRepo.CountFiltered(ctx, filters)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count customers: %w", err)
+	}
+
+	customers, err := s.customerRepo.ListFiltered(ctx, filters, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list customers: %w", err)
+	}
+
+	pagination := &PaginationInfo{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: total,
+		TotalPages: (total + pageSize - 1) / pageSize,
+	}
+
+	return customers, pagination, nil
+}
+
+// CreateCustomer saves a customer after applying the configured field length policy
+// It is the entry point for creating and importing customers; the returned warnings
+// list any fields that were truncated. The phone is stored normalized
+func (s *CustomerService) CreateCustomer(ctx context.Context, customer *models.Customer) ([]string, error) {
+	if strings.TrimSpace(customer.Phone) == "" {
+		return nil, &ValidationError{Message: "phone is required"}
+	}
+	phone, err := models.NormalizePhone(customer.Phone)
+	if err != nil {
+		return nil, &ValidationError{Message: err.Error()}
+	}
+	customer.Phone = phone
+
+	truncate := s.limits.CustomerFieldOverflow == config.OverflowTruncate
+	warnings, err := customer.EnforceFieldLengths(s.limits.MaxCustomerFieldLength, truncate)
+	if err != nil {
+		return nil, &ValidationError{Message: err.Error()}
+	}
+
+	if _, err := customer.ContactWindow(); err != nil {
+		return nil, &ValidationError{Message: err.Error()}
+	}
+
+	if violations := s.rules.Check(customer); len(violations) > 0 {
+		return nil, customerRulesError(violations)
+	}
+
+	if err := s.customerRepo.Create(ctx, customer); err != nil {
+		return nil, fmt.Errorf("failed to create customer: %w", err)
+	}
+
+	return warnings, nil
+}
+
+// DeleteCustomer deletes a customer
+// A customer with messages is only deleted, together with their messages, when force is set
+func (s *CustomerService) DeleteCustomer(ctx context.Context, customerID int, force bool) (*DeleteResult, error) {
+	return deleteWithMessages(ctx, "customer", customerID, force, s.customerRepo.Delete, s.customerRepo.DeleteWithMessages)
+}
+
+// GetStats returns customer counts by preferred product and location
+// A non-empty location scopes the product breakdown to that location
+func (s *CustomerService) GetStats(ctx context.Context, location string) (*models.CustomerStats, error) {
+	var locationFilter *string
+	if location = strings.TrimSpace(location); location != "" {
+		locationFilter = &location
+	}
+
+	stats, err := s.customerRepo.GetStats(ctx, locationFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// Timeline page size limits
+const (
+	DefaultTimelineLimit = 50
+	MaxTimelineLimit     = 200
+)
+
+// GetTimeline returns a page of a customer's events, newest first
+// A nil cursor starts from the most recent event
+func (s *CustomerService) GetTimeline(ctx context.Context, customerID int, cursor *models.TimelineCursor, limit int) (*TimelinePage, error) {
+	if _, err := s.customerRepo.GetByID(ctx, customerID); err != nil {
+		return nil, &NotFoundError{Resource: "customer", ID: customerID}
+	}
+
+	if limit <= 0 {
+		limit = DefaultTimelineLimit
+	}
+	if limit > MaxTimelineLimit {
+		limit = MaxTimelineLimit
+	}
+
+	events, err := s.customerRepo.GetTimeline(ctx, customerID, cursor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer timeline: %w", err)
+	}
+
+	page := &TimelinePage{
+		CustomerID: customerID,
+		Events:     events,
+	}
+	if len(events) == limit {
+		page.NextCursor = events[len(events)-1].CursorAfter().String()
+	}
+
+	return page, nil
+}
+
+// TimelinePage is a page of customer timeline events
+type TimelinePage struct {
+	CustomerID int                     `json:"customer_id"`
+	Events     []*models.TimelineEvent `json:"events"`
+	NextCursor string                  `json:"next_cursor,omitempty"` // Cursor for the next page (empty on the last page)
+}
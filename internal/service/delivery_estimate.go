@@ -0,0 +1,178 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+)
+
+// Message encodings
+const (
+	EncodingGSM7 = "gsm7" // SMS in the GSM 03.38 alphabet
+	EncodingUCS2 = "ucs2" // SMS with any character outside it
+	EncodingUTF8 = "utf8" // WhatsApp
+)
+
+// SMS part sizes: a message longer than one part is split into parts that each
+// give up room for the header joining them back together
+const (
+	gsm7SinglePart = 160 // Septets
+	gsm7MultiPart  = 153
+	ucs2SinglePart = 70 // UTF-16 code units
+	ucs2MultiPart  = 67
+)
+
+// WhatsAppMaxChars is the longest WhatsApp text message
+const WhatsAppMaxChars = 4096
+
+// gsm7Basic is the GSM 03.38 default alphabet; each character is one septet
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extended is the GSM 03.38 extension table; each character is an escape plus a septet
+const gsm7Extended = "^{}\\[~]|€\f"
+
+// DeliveryEstimator works out how a message will be delivered and what it will cost
+// Preview, re-render dry runs, simulation and readiness all estimate through it, so they agree
+//
+// Costs are per message as the budget charges them; an SMS split into parts is still one message
+// A nil DeliveryEstimator estimates nothing
+type DeliveryEstimator struct {
+	templateSvc *TemplateService
+	sending     config.SendingConfig
+}
+
+// NewDeliveryEstimator creates an estimator pricing messages with the configured per-channel costs
+func NewDeliveryEstimator(templateSvc *TemplateService, sending config.SendingConfig) *DeliveryEstimator {
+	return &DeliveryEstimator{
+		templateSvc: templateSvc,
+		sending:     sending,
+	}
+}
+
+// DeliveryEstimate is how a message will be delivered to its recipients and what it will cost
+type DeliveryEstimate struct {
+	Channel    models.Channel `json:"channel"`
+	CharCount  int            `json:"char_count"`
+	Encoding   string         `json:"encoding"`
+	Segments   *int           `json:"segments,omitempty"` // SMS only
+	Recipients int            `json:"recipients"`
+	UnitCost   float64        `json:"unit_cost"`
+	TotalCost  float64        `json:"total_cost"`
+	Warnings   []string       `json:"warnings"`
+}
+
+// Estimate estimates sending content on the channel to recipients
+func (e *DeliveryEstimator) Estimate(channel models.Channel, content string, recipients int) *DeliveryEstimate {
+	if e == nil {
+		return nil
+	}
+
+	unitCost := costPerMessage(e.sending, channel)
+	estimate := &DeliveryEstimate{
+		Channel:    channel,
+		CharCount:  len([]rune(content)),
+		Recipients: recipients,
+		UnitCost:   unitCost,
+		TotalCost:  roundTo(float64(recipients)*unitCost, 2),
+		Warnings:   []string{},
+	}
+
+	if channel == models.ChannelWhatsApp {
+		estimate.Encoding = EncodingUTF8
+		if estimate.CharCount > WhatsAppMaxChars {
+			estimate.Warnings = append(estimate.Warnings,
+				fmt.Sprintf("message is %d characters; WhatsApp allows at most %d", estimate.CharCount, WhatsAppMaxChars))
+		}
+		return estimate
+	}
+
+	segments := 0
+	if septets, ok := gsm7Septets(content); ok {
+		estimate.Encoding = EncodingGSM7
+		segments = smsSegments(septets, gsm7SinglePart, gsm7MultiPart)
+	} else {
+		estimate.Encoding = EncodingUCS2
+		segments = smsSegments(len(utf16.Encode([]rune(content))), ucs2SinglePart, ucs2MultiPart)
+		estimate.Warnings = append(estimate.Warnings,
+			fmt.Sprintf("%q is outside the GSM-7 alphabet, so the message is sent as UCS-2 with %d characters per part",
+				firstNonGSM7(content), ucs2SinglePart))
+	}
+	estimate.Segments = &segments
+	if segments > 1 {
+		estimate.Warnings = append(estimate.Warnings, fmt.Sprintf("message is sent as %d SMS parts", segments))
+	}
+
+	return estimate
+}
+
+// EstimateFor renders the template for customer in the campaign's syntax and estimates
+// sending it to recipients on the campaign's channel
+// Without a customer the template is estimated as written, with a warning
+func (e *DeliveryEstimator) EstimateFor(campaign *models.Campaign, template string, customer *models.Customer, recipients int) (*DeliveryEstimate, error) {
+	if e == nil {
+		return nil, nil
+	}
+
+	if customer == nil {
+		estimate := e.Estimate(campaign.Channel, template, recipients)
+		estimate.Warnings = append(estimate.Warnings, "estimated from the template as written; rendered length varies by customer")
+		return estimate, nil
+	}
+
+	rendered, err := e.templateSvc.ForCampaign(campaign).Render(template, customer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	return e.Estimate(campaign.Channel, rendered, recipients), nil
+}
+
+// gsm7Septets counts the septets content takes in the GSM-7 alphabet
+// ok is false when content has a character outside it
+func gsm7Septets(content string) (septets int, ok bool) {
+	for _, r := range content {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			septets++
+		case strings.ContainsRune(gsm7Extended, r):
+			septets += 2
+		default:
+			return 0, false
+		}
+	}
+	return septets, true
+}
+
+// firstNonGSM7 returns the first character of content outside the GSM-7 alphabet
+func firstNonGSM7(content string) string {
+	for _, r := range content {
+		if !strings.ContainsRune(gsm7Basic, r) && !strings.ContainsRune(gsm7Extended, r) {
+			return string(r)
+		}
+	}
+	return ""
+}
+
+// smsSegments counts the parts a message of length units is split into
+func smsSegments(length, singlePart, multiPart int) int {
+	if length <= singlePart {
+		return 1
+	}
+	return (length + multiPart - 1) / multiPart
+}
+
+// firstPlannedCustomer returns the customer of the first planned ID, or nil if it was not found
+func firstPlannedCustomer(customers []*models.Customer, customerIDs []int) *models.Customer {
+	if len(customerIDs) == 0 {
+		return nil
+	}
+	for _, customer := range customers {
+		if customer.ID == customerIDs[0] {
+			return customer
+		}
+	}
+	return nil
+}
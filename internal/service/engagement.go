@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// ScoreEngagement computes a customer's engagement score at now from their history: each
+// sent message is worth SentWeight, halved for every HalfLife since it was sent, and each
+// permanently failed message takes off FailurePenalty. Scores are rounded to two decimals
+// and may be negative
+// There is no bonus for replies, since inbound messages are not stored
+func ScoreEngagement(history *models.EngagementHistory, weights config.EngagementConfig, now time.Time) float64 {
+	score := 0.0
+	for _, sentAt := range history.SentAt {
+		age := max(now.Sub(sentAt), 0)
+		score += weights.SentWeight * math.Exp2(-age.Hours()/weights.HalfLife.Hours())
+	}
+	score -= weights.FailurePenalty * float64(history.Failed)
+
+	return math.Round(score*100) / 100
+}
+
+// EngagementScorer recomputes every customer's engagement score from their message history
+// Customers are scored a batch at a time in ID order, so a run can be resumed after the last
+// batch it stored
+type EngagementScorer struct {
+	repo    repository.EngagementRepository
+	weights config.EngagementConfig
+	now     func() time.Time
+}
+
+// NewEngagementScorer creates a scorer with the weights and batch size in cfg
+func NewEngagementScorer(repo repository.EngagementRepository, cfg config.EngagementConfig) *EngagementScorer {
+	return &EngagementScorer{
+		repo:    repo,
+		weights: cfg,
+		now:     time.Now,
+	}
+}
+
+// SetClock overrides time.Now (for testing)
+func (s *EngagementScorer) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// EngagementBatch is the outcome of scoring one batch of customers
+type EngagementBatch struct {
+	Scored int // Customers scored
+	LastID int // Resume after this ID
+}
+
+// RunBatch scores the next batch of customers after afterID
+// Call repeatedly with the returned LastID until Scored is 0
+func (s *EngagementScorer) RunBatch(ctx context.Context, afterID int) (*EngagementBatch, error) {
+	now := s.now()
+	histories, err := s.repo.ListEngagementHistory(ctx, afterID, s.weights.BatchSize, now.Add(-s.weights.Lookback))
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &EngagementBatch{Scored: len(histories), LastID: afterID}
+	scores := make(map[int]float64, len(histories))
+	for _, history := range histories {
+		batch.LastID = history.CustomerID
+		scores[history.CustomerID] = ScoreEngagement(history, s.weights, now)
+	}
+
+	if err := s.repo.StoreEngagementScores(ctx, scores, now); err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+// ScoreAll scores every customer and returns how many were scored
+func (s *EngagementScorer) ScoreAll(ctx context.Context) (int, error) {
+	lastID, scored := 0, 0
+	for {
+		batch, err := s.RunBatch(ctx, lastID)
+		if err != nil {
+			return scored, fmt.Errorf("failed after customer ID %d: %w", lastID, err)
+		}
+		if batch.Scored == 0 {
+			return scored, nil
+		}
+		scored += batch.Scored
+		lastID = batch.LastID
+	}
+}
+
+// Run scores every customer each interval until ctx is cancelled
+func (s *EngagementScorer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			scored, err := s.ScoreAll(ctx)
+			if err != nil {
+				log.Printf("Warning: Failed to score customer engagement: %v", err)
+				continue
+			}
+			log.Printf("📈 Engagement scored for %d customers", scored)
+		}
+	}
+}
+
+// applyEngagement drops the customers scoring below minScore, or not yet scored, and returns
+// the rest with how many were skipped; a nil minScore reaches everyone
+func (s *CampaignService) applyEngagement(ctx context.Context, customers []*models.Customer, minScore *float64) ([]*models.Customer, int, error) {
+	if minScore == nil {
+		return customers, 0, nil
+	}
+
+	customerIDs := make([]int, len(customers))
+	for i, customer := range customers {
+		customerIDs[i] = customer.ID
+	}
+
+	belowIDs, err := s.customerRepo.ListBelowScore(ctx, customerIDs, *minScore)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to check engagement scores: %w", err)
+	}
+	if len(belowIDs) == 0 {
+		return customers, 0, nil
+	}
+
+	below := make(map[int]bool, len(belowIDs))
+	for _, id := range belowIDs {
+		below[id] = true
+	}
+	allowed := make([]*models.Customer, 0, len(customers))
+	for _, customer := range customers {
+		if !below[customer.ID] {
+			allowed = append(allowed, customer)
+		}
+	}
+
+	skipped := len(customers) - len(allowed)
+	if len(allowed) == 0 {
+		return nil, skipped, &BusinessLogicError{
+			Message: fmt.Sprintf("all %d customers score below %g or are not yet scored", skipped, *minScore),
+		}
+	}
+	return allowed, skipped, nil
+}
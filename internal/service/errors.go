@@ -1,6 +1,13 @@
 package service
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+)
 
 // NotFoundError represents a resource not found error
 type NotFoundError struct {
@@ -12,15 +19,53 @@ func (e *NotFoundError) Error() string {
 	return fmt.Sprintf("%s with ID %d not found", e.Resource, e.ID)
 }
 
+// WorkerNotFoundError reports a worker identity that was never seen
+// Worker IDs are names, not the numeric IDs NotFoundError carries
+type WorkerNotFoundError struct {
+	WorkerID string
+}
+
+func (e *WorkerNotFoundError) Error() string {
+	return fmt.Sprintf("worker %q not found", e.WorkerID)
+}
+
 // ValidationError represents a validation error
+// Violations lists each broken customer rule when the error comes from the configured rules
 type ValidationError struct {
-	Message string
+	Message    string
+	Violations []*config.CustomerRuleViolation
 }
 
 func (e *ValidationError) Error() string {
 	return fmt.Sprintf("validation error: %s", e.Message)
 }
 
+// customerRulesError reports the customer rules broken by the customers being saved
+func customerRulesError(violations []*config.CustomerRuleViolation) *ValidationError {
+	messages := make([]string, len(violations))
+	for i, violation := range violations {
+		messages[i] = violation.Message
+	}
+	return &ValidationError{
+		Message:    fmt.Sprintf("customer breaks validation rules: %s", strings.Join(messages, "; ")),
+		Violations: violations,
+	}
+}
+
+// prefixViolations names the customer each violation belongs to when several are checked
+// together, as in "customers[2].last_name"
+func prefixViolations(violations []*config.CustomerRuleViolation, prefix string) []*config.CustomerRuleViolation {
+	prefixed := make([]*config.CustomerRuleViolation, len(violations))
+	for i, violation := range violations {
+		prefixed[i] = &config.CustomerRuleViolation{
+			Field:   prefix + "." + violation.Field,
+			Rule:    violation.Rule,
+			Message: prefix + ": " + violation.Message,
+		}
+	}
+	return prefixed
+}
+
 // BusinessLogicError represents a business logic error
 type BusinessLogicError struct {
 	Message string
@@ -39,3 +84,98 @@ type ConflictError struct {
 func (e *ConflictError) Error() string {
 	return fmt.Sprintf("conflict with %s: %s", e.Resource, e.Message)
 }
+
+// DuplicateContentError reports a send refused because its audience recently got the same content
+type DuplicateContentError struct {
+	Recipients   int           // Customers who already received the content
+	AudienceSize int           // Customers the send targets
+	Window       time.Duration // How far back earlier sends were looked for
+}
+
+func (e *DuplicateContentError) Error() string {
+	return fmt.Sprintf(
+		"%d of %d customers already received this content from another campaign in the last %s; set allow_duplicate_content to send anyway",
+		e.Recipients, e.AudienceSize, formatWindow(e.Window),
+	)
+}
+
+// QueueSaturatedError reports a send refused because the send backlog is over a threshold
+type QueueSaturatedError struct {
+	Status     *QueueStatus
+	RetryAfter time.Duration // Suggested wait before retrying
+}
+
+func (e *QueueSaturatedError) Error() string {
+	depth := "unknown"
+	if e.Status.QueueDepth != nil {
+		depth = fmt.Sprintf("%d", *e.Status.QueueDepth)
+	}
+	return fmt.Sprintf(
+		"send queue is saturated: %s jobs queued (limit %s), %d messages never published (limit %s); retry in %s",
+		depth, formatLimit(e.Status.MaxQueueDepth), e.Status.Unpublished, formatLimit(e.Status.MaxUnpublished), e.RetryAfter,
+	)
+}
+
+// UnsupportedChannelError reports a campaign whose channel the configured sender provider
+// cannot deliver on
+type UnsupportedChannelError struct {
+	Provider  string
+	Channel   models.Channel
+	Supported []models.Channel
+}
+
+func (e *UnsupportedChannelError) Error() string {
+	supported := make([]string, len(e.Supported))
+	for i, channel := range e.Supported {
+		supported[i] = string(channel)
+	}
+	return fmt.Sprintf(
+		"sender provider %q does not support %s campaigns; supported channels: %s",
+		e.Provider, e.Channel, strings.Join(supported, ", "),
+	)
+}
+
+// SendingDisabledError reports a send refused because all outbound sending is switched off
+type SendingDisabledError struct {
+	Reason     string // Why sending was disabled, from the audit log
+	DisabledBy *string
+}
+
+func (e *SendingDisabledError) Error() string {
+	message := "all outbound sending is disabled"
+	if e.DisabledBy != nil {
+		message += " by " + *e.DisabledBy
+	}
+	if e.Reason != "" {
+		message += ": " + e.Reason
+	}
+	return message
+}
+
+// SendInterruptedError reports a send stopped partway, e.g. because the client disconnected
+// The messages already queued stay queued; sending to the campaign again queues the rest
+type SendInterruptedError struct {
+	CampaignID     int
+	MessagesQueued int // Messages created and published before the send stopped
+	AudienceSize   int
+	Err            error // Why it stopped
+}
+
+func (e *SendInterruptedError) Error() string {
+	return fmt.Sprintf(
+		"send to campaign %d interrupted after queuing %d of %d messages: %v; send again to resume",
+		e.CampaignID, e.MessagesQueued, e.AudienceSize, e.Err,
+	)
+}
+
+func (e *SendInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// formatLimit formats a backpressure threshold, where 0 is none
+func formatLimit(limit int) string {
+	if limit == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d", limit)
+}
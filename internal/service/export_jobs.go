@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// ExportBatchSize is how many messages an export job reads per query
+const ExportBatchSize = 1000
+
+// ExportJobStaleAfter is how long a running export may go without progress before it
+// is assumed abandoned (its process died) and generated again
+const ExportJobStaleAfter = 10 * time.Minute
+
+// ExportJobPollInterval is how often the export runner looks for jobs it was not woken for
+const ExportJobPollInterval = 30 * time.Second
+
+// messageExportCSVHeader is the column order of a campaign message export
+var messageExportCSVHeader = []string{
+	"message_id", "customer_id", "phone", "status", "retry_count", "last_error",
+	"rendered_content", "created_at", "updated_at",
+}
+
+// ExportJobService generates campaign message exports in the background
+// A job is created pending, written batch by batch to the export store by Run, and
+// downloadable until its retention runs out
+type ExportJobService struct {
+	jobRepo      repository.ExportJobRepository
+	campaignRepo repository.CampaignRepository
+	messageRepo  repository.MessageRepository
+	store        ExportStore
+	retention    time.Duration
+	wake         chan struct{}
+	now          func() time.Time
+}
+
+// NewExportJobService creates a new export job service
+func NewExportJobService(jobRepo repository.ExportJobRepository, campaignRepo repository.CampaignRepository, messageRepo repository.MessageRepository, store ExportStore, export config.ExportConfig) *ExportJobService {
+	return &ExportJobService{
+		jobRepo:      jobRepo,
+		campaignRepo: campaignRepo,
+		messageRepo:  messageRepo,
+		store:        store,
+		retention:    export.Retention,
+		wake:         make(chan struct{}, 1),
+		now:          time.Now,
+	}
+}
+
+// SetClock overrides time.Now (for testing)
+func (s *ExportJobService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// CreateJob queues an export of every message of a campaign
+func (s *ExportJobService) CreateJob(ctx context.Context, campaignID int, requestedBy string) (*models.ExportJob, error) {
+	if _, err := s.campaignRepo.GetByID(ctx, campaignID); err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	job := &models.ExportJob{CampaignID: campaignID}
+	if requestedBy != "" {
+		job.RequestedBy = &requestedBy
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// Start it now rather than at the next poll; a runner already awake picks it up anyway
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// GetJob retrieves one of a campaign's export jobs
+func (s *ExportJobService) GetJob(ctx context.Context, campaignID, jobID int) (*models.ExportJob, error) {
+	job, err := s.jobRepo.GetByID(ctx, jobID)
+	if err != nil || job.CampaignID != campaignID {
+		return nil, &NotFoundError{Resource: "export job", ID: jobID}
+	}
+	return job, nil
+}
+
+// OpenDownload opens the file of a completed, unexpired export job
+// The caller closes the file
+func (s *ExportJobService) OpenDownload(ctx context.Context, campaignID, jobID int) (*models.ExportJob, io.ReadSeekCloser, error) {
+	job, err := s.GetJob(ctx, campaignID, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if job.Status != models.ExportJobCompleted {
+		return nil, nil, &ConflictError{
+			Resource: "export job",
+			Message:  fmt.Sprintf("export is %s; it can be downloaded once completed", job.Status),
+		}
+	}
+	if job.Expired(s.now()) || job.FileName == nil {
+		return nil, nil, &ConflictError{
+			Resource: "export job",
+			Message:  "export has expired; create a new export",
+		}
+	}
+
+	file, err := s.store.Open(*job.FileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open export file: %w", err)
+	}
+	return job, file, nil
+}
+
+// RunNext generates the next pending export and reports whether there was one
+// A job that fails to generate is recorded failed and its error returned
+func (s *ExportJobService) RunNext(ctx context.Context) (bool, error) {
+	job, err := s.jobRepo.ClaimNext(ctx, ExportJobStaleAfter)
+	if err != nil {
+		return false, err
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	name := fmt.Sprintf("campaign-%d-export-%d.csv", job.CampaignID, job.ID)
+	size, err := s.generate(ctx, job, name)
+	if err == nil {
+		err = s.jobRepo.Complete(ctx, job.ID, name, size, s.now().Add(s.retention))
+	}
+	if err != nil {
+		if removeErr := s.store.Remove(name); removeErr != nil {
+			log.Printf("Warning: Failed to remove export file %s: %v", name, removeErr)
+		}
+		if failErr := s.jobRepo.Fail(ctx, job.ID, err.Error()); failErr != nil {
+			log.Printf("Warning: Failed to record export job %d as failed: %v", job.ID, failErr)
+		}
+		return true, fmt.Errorf("export job %d failed: %w", job.ID, err)
+	}
+
+	return true, nil
+}
+
+// generate writes a job's CSV to the store in batches, recording progress after each,
+// and returns the file size
+func (s *ExportJobService) generate(ctx context.Context, job *models.ExportJob, name string) (int64, error) {
+	file, err := s.store.Create(name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	counter := &countingWriter{w: file}
+	writer := csv.NewWriter(counter)
+
+	write := func() error {
+		if err := writer.Write(messageExportCSVHeader); err != nil {
+			return fmt.Errorf("failed to write export header: %w", err)
+		}
+
+		written, afterID := 0, 0
+		for {
+			messages, err := s.messageRepo.ListForExport(ctx, job.CampaignID, afterID, ExportBatchSize)
+			if err != nil {
+				return err
+			}
+			if len(messages) == 0 {
+				break
+			}
+
+			for _, message := range messages {
+				writer.Write(messageExportCSVRecord(message))
+			}
+			writer.Flush()
+			if err := writer.Error(); err != nil {
+				return fmt.Errorf("failed to write export rows: %w", err)
+			}
+
+			written += len(messages)
+			afterID = messages[len(messages)-1].ID
+			if err := s.jobRepo.UpdateProgress(ctx, job.ID, written); err != nil {
+				return err
+			}
+			if len(messages) < ExportBatchSize {
+				break
+			}
+		}
+
+		writer.Flush()
+		return writer.Error()
+	}
+
+	err = write()
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close export file: %w", closeErr)
+	}
+	return counter.n, err
+}
+
+// PurgeExpired deletes the files of exports past their retention and returns how many
+func (s *ExportJobService) PurgeExpired(ctx context.Context) (int, error) {
+	jobs, err := s.jobRepo.ListExpired(ctx, s.now())
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, job := range jobs {
+		if err := s.store.Remove(*job.FileName); err != nil {
+			log.Printf("Warning: Failed to remove expired export file %s: %v", *job.FileName, err)
+			continue
+		}
+		if err := s.jobRepo.ClearFile(ctx, job.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// Run generates queued exports, and deletes expired ones, until ctx is cancelled
+// It works through every pending job whenever one is created and at least every interval
+func (s *ExportJobService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			ran, err := s.RunNext(ctx)
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			if !ran {
+				break
+			}
+		}
+
+		if purged, err := s.PurgeExpired(ctx); err != nil {
+			log.Printf("Warning: Failed to purge expired exports: %v", err)
+		} else if purged > 0 {
+			log.Printf("🧹 Deleted %d expired exports", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// messageExportCSVRecord converts an exported message to its CSV columns
+func messageExportCSVRecord(message *models.OutboundMessageWithDetails) []string {
+	var lastError, content string
+	if message.LastError != nil {
+		lastError = *message.LastError
+	}
+	if message.RenderedContent != nil {
+		content = *message.RenderedContent
+	}
+
+	return []string{
+		strconv.Itoa(message.ID),
+		strconv.Itoa(message.CustomerID),
+		message.Customer.Phone,
+		string(message.Status),
+		strconv.Itoa(message.RetryCount),
+		spreadsheetSafe(lastError),
+		spreadsheetSafe(content),
+		message.CreatedAt.UTC().Format(time.RFC3339),
+		message.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// Export formats
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// ExportDateLayout is the layout of the from and to export dates
+const ExportDateLayout = "2006-01-02"
+
+// exportFlushEvery is how many rows are written between flushes to the client
+const exportFlushEvery = 100
+
+// exportCSVHeader is the column order of a CSV export
+var exportCSVHeader = []string{
+	"id", "name", "channel", "status", "tags", "created_at", "scheduled_at",
+	"total", "pending", "sent", "failed", "simulated", "cost", "duration_seconds",
+	"throughput_per_minute", "estimated_duration_seconds",
+}
+
+// ExportService streams and compares campaigns with their stats for reporting
+type ExportService struct {
+	campaignRepo repository.CampaignRepository
+	sending      config.SendingConfig
+}
+
+// NewExportService creates a new export service
+func NewExportService(campaignRepo repository.CampaignRepository, sending config.SendingConfig) *ExportService {
+	return &ExportService{
+		campaignRepo: campaignRepo,
+		sending:      sending,
+	}
+}
+
+// ExportCampaignsRequest selects the format and creation-date window of an export
+// From and To are inclusive UTC days; either may be nil for an open end
+type ExportCampaignsRequest struct {
+	Format string
+	From   *time.Time
+	To     *time.Time
+}
+
+// Validate validates the export request
+func (r *ExportCampaignsRequest) Validate() error {
+	if r.Format != ExportFormatCSV && r.Format != ExportFormatNDJSON {
+		return &ValidationError{Message: fmt.Sprintf("format must be %q or %q", ExportFormatCSV, ExportFormatNDJSON)}
+	}
+
+	if r.From != nil && r.To != nil && r.To.Before(*r.From) {
+		return &ValidationError{Message: "to must not be before from"}
+	}
+
+	return nil
+}
+
+// Filename names the export after its date range, e.g. campaigns_2026-01-01_2026-01-31.csv
+func (r *ExportCampaignsRequest) Filename() string {
+	var span string
+	switch {
+	case r.From != nil && r.To != nil:
+		span = r.From.Format(ExportDateLayout) + "_" + r.To.Format(ExportDateLayout)
+	case r.From != nil:
+		span = r.From.Format(ExportDateLayout) + "_onward"
+	case r.To != nil:
+		span = "until_" + r.To.Format(ExportDateLayout)
+	default:
+		span = "all"
+	}
+	return "campaigns_" + span + "." + r.Format
+}
+
+// CampaignExport is one exported campaign as written to NDJSON
+type CampaignExport struct {
+	ID              int                   `json:"id"`
+	Name            string                `json:"name"`
+	Channel         models.Channel        `json:"channel"`
+	Status          models.CampaignStatus `json:"status"`
+	Tags            []string              `json:"tags"`
+	CreatedAt       time.Time             `json:"created_at"`
+	ScheduledAt     *time.Time            `json:"scheduled_at,omitempty"`
+	Stats           models.CampaignStats  `json:"stats"`
+	Cost            float64               `json:"cost"`
+	DurationSeconds *float64              `json:"duration_seconds,omitempty"`
+
+	// Recorded when the send completes
+	ThroughputPerMinute      *float64 `json:"throughput_per_minute,omitempty"`
+	EstimatedDurationSeconds *float64 `json:"estimated_duration_seconds,omitempty"`
+}
+
+// ExportCampaigns writes every campaign in the request window to w, one row at a time
+// Rows written before an error stay written; callers that have sent headers can only log it
+func (s *ExportService) ExportCampaigns(ctx context.Context, req *ExportCampaignsRequest, w io.Writer) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	filters := repository.CampaignExportFilters{From: req.From}
+	if req.To != nil {
+		// To is inclusive, so stop at the start of the following day
+		end := req.To.AddDate(0, 0, 1)
+		filters.To = &end
+	}
+
+	flusher, _ := w.(interface{ Flush() })
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	var write func(row *CampaignExport) error
+	var finish func() error
+	switch req.Format {
+	case ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportCSVHeader); err != nil {
+			return fmt.Errorf("failed to write export header: %w", err)
+		}
+		write = func(row *CampaignExport) error {
+			writer.Write(exportCSVRecord(row))
+			return writer.Error()
+		}
+		finish = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		encoder := json.NewEncoder(w)
+		write = func(row *CampaignExport) error {
+			return encoder.Encode(row)
+		}
+		finish = func() error { return nil }
+	}
+
+	written := 0
+	err := s.campaignRepo.StreamWithStats(ctx, filters, func(row *models.CampaignExportRow) error {
+		if err := write(s.exportRow(row)); err != nil {
+			return fmt.Errorf("failed to write export row: %w", err)
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			if err := finish(); err != nil {
+				return fmt.Errorf("failed to write export row: %w", err)
+			}
+			flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export campaigns: %w", err)
+	}
+
+	if err := finish(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	flush()
+
+	return nil
+}
+
+// exportRow adds cost, duration and recorded send metrics to a streamed campaign
+func (s *ExportService) exportRow(row *models.CampaignExportRow) *CampaignExport {
+	export := &CampaignExport{
+		ID:          row.ID,
+		Name:        row.Name,
+		Channel:     row.Channel,
+		Status:      row.Status,
+		Tags:        row.Tags,
+		CreatedAt:   row.CreatedAt,
+		ScheduledAt: row.ScheduledAt,
+		Stats:       row.Stats,
+		Cost:        s.cost(row.Channel, &row.Stats),
+	}
+	if export.Tags == nil {
+		export.Tags = []string{}
+	}
+
+	if row.FirstMessageAt != nil && row.LastMessageAt != nil {
+		duration := row.LastMessageAt.Sub(*row.FirstMessageAt).Seconds()
+		export.DurationSeconds = &duration
+	}
+	if row.SendMetrics != nil {
+		export.ThroughputPerMinute = row.SendMetrics.ThroughputPerMinute
+		export.EstimatedDurationSeconds = row.SendMetrics.EstimatedDurationSeconds
+	}
+
+	return export
+}
+
+// cost prices the messages a campaign handed to a provider; simulated sends are free
+func (s *ExportService) cost(channel models.Channel, stats *models.CampaignStats) float64 {
+	price := s.sending.CostPerSMS
+	if channel == models.ChannelWhatsApp {
+		price = s.sending.CostPerWhatsApp
+	}
+	return roundTo(float64(stats.Sent-stats.Simulated)*price, 2)
+}
+
+// exportCSVRecord flattens an exported campaign into CSV columns
+// Tags are joined with ";" and unknown values are left empty
+func exportCSVRecord(row *CampaignExport) []string {
+	scheduledAt := ""
+	if row.ScheduledAt != nil {
+		scheduledAt = row.ScheduledAt.UTC().Format(time.RFC3339)
+	}
+	duration := ""
+	if row.DurationSeconds != nil {
+		duration = strconv.FormatFloat(*row.DurationSeconds, 'f', 0, 64)
+	}
+	throughput := ""
+	if row.ThroughputPerMinute != nil {
+		throughput = strconv.FormatFloat(*row.ThroughputPerMinute, 'f', 2, 64)
+	}
+	estimated := ""
+	if row.EstimatedDurationSeconds != nil {
+		estimated = strconv.FormatFloat(*row.EstimatedDurationSeconds, 'f', 0, 64)
+	}
+
+	return []string{
+		strconv.Itoa(row.ID),
+		spreadsheetSafe(row.Name),
+		string(row.Channel),
+		string(row.Status),
+		strings.Join(row.Tags, ";"),
+		row.CreatedAt.UTC().Format(time.RFC3339),
+		scheduledAt,
+		strconv.Itoa(row.Stats.Total),
+		strconv.Itoa(row.Stats.Pending),
+		strconv.Itoa(row.Stats.Sent),
+		strconv.Itoa(row.Stats.Failed),
+		strconv.Itoa(row.Stats.Simulated),
+		strconv.FormatFloat(row.Cost, 'f', 2, 64),
+		duration,
+		throughput,
+		estimated,
+	}
+}
+
+// spreadsheetSafe stops spreadsheet apps evaluating a free-text cell as a formula
+func spreadsheetSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExportStore holds generated export files by name
+type ExportStore interface {
+	Create(name string) (io.WriteCloser, error)
+	Open(name string) (io.ReadSeekCloser, error)
+	Remove(name string) error
+}
+
+// DirExportStore keeps export files in a local directory
+// Downloads are served by the API process that generated them, so the directory must
+// be shared (e.g. a volume) when the API runs more than one replica
+type DirExportStore struct {
+	dir string
+}
+
+// NewDirExportStore creates a store in dir, creating the directory when missing
+func NewDirExportStore(dir string) (*DirExportStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	return &DirExportStore{dir: dir}, nil
+}
+
+// Create creates or truncates the named file
+func (s *DirExportStore) Create(name string) (io.WriteCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+}
+
+// Open opens the named file for reading
+func (s *DirExportStore) Open(name string) (io.ReadSeekCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Remove deletes the named file; a file already gone is not an error
+func (s *DirExportStore) Remove(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path resolves a file name inside the store's directory
+func (s *DirExportStore) path(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid export file name %q", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
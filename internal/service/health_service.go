@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"time"
 
+	"smsleopard/internal/models"
+
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -23,32 +25,54 @@ type HealthStatus struct {
 	Services  map[string]string `json:"services"`
 	Timestamp time.Time         `json:"timestamp"`
 	Version   string            `json:"version,omitempty"`
+
+	SendingEnabled *bool `json:"sending_enabled,omitempty"` // The outbound sending kill switch, when set
+
+	// The configured sender provider and the channels it delivers on, when set
+	SenderProvider    string           `json:"sender_provider,omitempty"`
+	SupportedChannels []models.Channel `json:"supported_channels,omitempty"`
 }
 
 // HealthChecker handles health check operations
 type HealthChecker struct {
-	db       *sql.DB
-	queueURL string
-	version  string
+	db        *sql.DB
+	replicaDB *sql.DB
+	queueURL  string
+	version   string
+	sending   *SendingSwitch
+	channels  *ChannelSupport
 }
 
 // NewHealthService creates a new HealthChecker instance
-func NewHealthService(db *sql.DB, queueURL, version string) *HealthChecker {
+// replicaDB is optional and only probed when set
+func NewHealthService(db, replicaDB *sql.DB, queueURL, version string) *HealthChecker {
 	return &HealthChecker{
-		db:       db,
-		queueURL: queueURL,
-		version:  version,
+		db:        db,
+		replicaDB: replicaDB,
+		queueURL:  queueURL,
+		version:   version,
 	}
 }
 
+// SetSendingSwitch sets the outbound sending kill switch reported with the health status
+// It does not change the overall status: sending being off is deliberate, not a failure
+func (h *HealthChecker) SetSendingSwitch(sending *SendingSwitch) {
+	h.sending = sending
+}
+
+// SetChannelSupport sets the sender provider and channels reported with the health status
+func (h *HealthChecker) SetChannelSupport(channels *ChannelSupport) {
+	h.channels = channels
+}
+
 // checkDatabase verifies PostgreSQL connectivity with a timeout
-func (h *HealthChecker) checkDatabase() string {
+func (h *HealthChecker) checkDatabase(db *sql.DB) string {
 	// Create context with 2-second timeout for database ping
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
 	// Attempt to ping the database
-	if err := h.db.PingContext(ctx); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		return StatusDisconnected
 	}
 
@@ -69,6 +93,11 @@ func (h *HealthChecker) checkQueue() string {
 	return StatusConnected
 }
 
+// CheckQueue reports whether RabbitMQ accepts connections
+func (h *HealthChecker) CheckQueue() string {
+	return h.checkQueue()
+}
+
 // determineOverallStatus calculates the overall health status based on service statuses
 func (h *HealthChecker) determineOverallStatus(services map[string]string) string {
 	databaseStatus := services["database"]
@@ -79,8 +108,8 @@ func (h *HealthChecker) determineOverallStatus(services map[string]string) strin
 		return StatusUnhealthy
 	}
 
-	// If queue is disconnected but database is connected, system is degraded
-	if queueStatus == StatusDisconnected {
+	// If queue or replica is disconnected but database is connected, system is degraded
+	if queueStatus == StatusDisconnected || services["database_replica"] == StatusDisconnected {
 		return StatusDegraded
 	}
 
@@ -92,9 +121,12 @@ func (h *HealthChecker) determineOverallStatus(services map[string]string) strin
 func (h *HealthChecker) CheckHealth() (*HealthStatus, error) {
 	// Check individual services
 	services := map[string]string{
-		"database": h.checkDatabase(),
+		"database": h.checkDatabase(h.db),
 		"queue":    h.checkQueue(),
 	}
+	if h.replicaDB != nil {
+		services["database_replica"] = h.checkDatabase(h.replicaDB)
+	}
 
 	// Determine overall system health
 	overallStatus := h.determineOverallStatus(services)
@@ -107,5 +139,16 @@ func (h *HealthChecker) CheckHealth() (*HealthStatus, error) {
 		Version:   h.version,
 	}
 
+	if h.sending != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		enabled := h.sending.Enabled(ctx)
+		healthStatus.SendingEnabled = &enabled
+	}
+	if h.channels != nil {
+		healthStatus.SenderProvider = h.channels.Provider
+		healthStatus.SupportedChannels = h.channels.Channels
+	}
+
 	return healthStatus, nil
 }
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// linkTokenBytes is the random bytes in a tracked link token (11 characters encoded)
+const linkTokenBytes = 8
+
+// LinkRedirectPrefix is the path tracked links redirect through, followed by the token
+const LinkRedirectPrefix = "/r/"
+
+// linkPattern matches http and https URLs; other schemes such as mailto: and tel: are not links
+// Braces are kept, so a template link with a placeholder in it is reported whole
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"']+`)
+
+// linkTrailingPunctuation is sentence punctuation stripped from the end of a matched URL
+const linkTrailingPunctuation = `.,;:!?)]`
+
+// LinkWarning lists the links found in a template, whose clicks are only counted with track_links
+type LinkWarning struct {
+	URLs    []string `json:"urls"`
+	Message string   `json:"message"`
+}
+
+// CheckLinks returns a warning listing the template's links, or nil when it has none
+func CheckLinks(template string, trackLinks bool) *LinkWarning {
+	spans := findLinks(template)
+	if len(spans) == 0 {
+		return nil
+	}
+
+	urls := make([]string, len(spans))
+	for i, span := range spans {
+		urls[i] = template[span[0]:span[1]]
+	}
+
+	message := fmt.Sprintf("template contains %d link(s): %s; set track_links to count clicks", len(urls), strings.Join(urls, ", "))
+	if trackLinks {
+		message = fmt.Sprintf("template contains %d link(s): %s; each is sent as a tracked redirect", len(urls), strings.Join(urls, ", "))
+	}
+	return &LinkWarning{URLs: urls, Message: message}
+}
+
+// findLinks returns the start and end of each link in text, in order
+func findLinks(text string) [][2]int {
+	spans := [][2]int{}
+	for _, m := range linkPattern.FindAllStringIndex(text, -1) {
+		end := m[1]
+		for end > m[0] && strings.ContainsRune(linkTrailingPunctuation, rune(text[end-1])) {
+			end--
+		}
+		// A bare scheme is not a link
+		if !strings.HasSuffix(text[m[0]:end], "//") {
+			spans = append(spans, [2]int{m[0], end})
+		}
+	}
+	return spans
+}
+
+// LinkTracker rewrites links in rendered messages to tracked redirects
+type LinkTracker struct {
+	linkRepo repository.LinkRepository
+	baseURL  string
+}
+
+// NewLinkTracker creates a link tracker whose redirects are served under baseURL,
+// e.g. https://sms.example.com
+func NewLinkTracker(linkRepo repository.LinkRepository, baseURL string) *LinkTracker {
+	return &LinkTracker{
+		linkRepo: linkRepo,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Rewrite replaces each link in a message's rendered content with a redirect to it,
+// storing the links first; a retried message gets the same redirects
+func (t *LinkTracker) Rewrite(ctx context.Context, messageID int, rendered string) (string, error) {
+	spans := findLinks(rendered)
+	if len(spans) == 0 {
+		return rendered, nil
+	}
+
+	links := make([]*models.TrackedLink, len(spans))
+	for i, span := range spans {
+		token, err := newLinkToken()
+		if err != nil {
+			return "", err
+		}
+		links[i] = &models.TrackedLink{MessageID: messageID, Position: i, Token: token, URL: rendered[span[0]:span[1]]}
+	}
+	if err := t.linkRepo.EnsureLinks(ctx, links); err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	last := 0
+	for i, span := range spans {
+		out.WriteString(rendered[last:span[0]])
+		out.WriteString(t.baseURL + LinkRedirectPrefix + links[i].Token)
+		last = span[1]
+	}
+	out.WriteString(rendered[last:])
+
+	return out.String(), nil
+}
+
+// newLinkToken returns a random URL-safe token
+func newLinkToken() (string, error) {
+	b := make([]byte, linkTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate link token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// LinkService resolves tracked link redirects
+type LinkService struct {
+	linkRepo repository.LinkRepository
+}
+
+// NewLinkService creates a new link service
+func NewLinkService(linkRepo repository.LinkRepository) *LinkService {
+	return &LinkService{linkRepo: linkRepo}
+}
+
+// Click records a visit to the tracked link with token and returns the URL to send the
+// visitor to, or "" when no link has the token
+// A failure to record the click is only logged: the visitor still reaches the link
+func (s *LinkService) Click(ctx context.Context, token, userAgent string) (string, error) {
+	link, err := s.linkRepo.GetByToken(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	if link == nil {
+		return "", nil
+	}
+
+	click := &models.LinkClick{LinkID: link.ID, MessageID: link.MessageID, CampaignID: link.CampaignID}
+	if userAgent != "" {
+		click.UserAgent = &userAgent
+	}
+	if err := s.linkRepo.RecordClick(ctx, click); err != nil {
+		log.Printf("Warning: Failed to record click on link %d: %v", link.ID, err)
+	}
+
+	return link.URL, nil
+}
@@ -0,0 +1,826 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"smsleopard/internal/faults"
+	"smsleopard/internal/metrics"
+	"smsleopard/internal/models"
+	"smsleopard/internal/notify"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+)
+
+// MessageProcessor renders and sends the message behind each queued job
+type MessageProcessor struct {
+	db               repository.DB
+	messageRepo      repository.MessageRepository
+	templateSvc      *TemplateService
+	sender           Sender
+	budget           *AttemptBudget
+	campaignBudget   *CampaignBudget
+	events           *notify.Events
+	faults           *faults.Injector
+	processingErrors *ProcessingErrorService
+	health           *WorkerHealth
+	suppressions     repository.SuppressionRepository
+	throttle         *CarrierThrottle
+	links            *LinkTracker
+	sending          *SendingSwitch
+	duplicateWindow  time.Duration
+	processBySkew    time.Duration
+	publishNext      func(message *models.OutboundMessage) error
+	claims           repository.WorkerRepository
+	workerID         string
+	claimTTL         time.Duration
+	demoOverrides    bool
+	zone             *time.Location
+	now              func() time.Time
+}
+
+// NewMessageProcessor creates a new message processor
+func NewMessageProcessor(
+	db repository.DB,
+	messageRepo repository.MessageRepository,
+	templateSvc *TemplateService,
+	sender Sender,
+	budget *AttemptBudget,
+	events *notify.Events,
+) *MessageProcessor {
+	return &MessageProcessor{
+		db:          db,
+		messageRepo: messageRepo,
+		templateSvc: templateSvc,
+		sender:      sender,
+		budget:      budget,
+		events:      events,
+		zone:        time.UTC,
+		now:         time.Now,
+	}
+}
+
+// SetContactWindowZone sets the time zone customer contact windows are read in (default UTC)
+func (p *MessageProcessor) SetContactWindowZone(zone *time.Location) {
+	p.zone = zone
+}
+
+// SetClock overrides time.Now (for testing)
+func (p *MessageProcessor) SetClock(now func() time.Time) {
+	p.now = now
+}
+
+// SetCampaignBudget sets the check that pauses campaigns before they exceed their budget (nil disables it)
+func (p *MessageProcessor) SetCampaignBudget(campaignBudget *CampaignBudget) {
+	p.campaignBudget = campaignBudget
+}
+
+// SetFaults sets the development faults injected while processing (nil disables them)
+func (p *MessageProcessor) SetFaults(injector *faults.Injector) {
+	p.faults = injector
+}
+
+// SetProcessingErrors sets where errors other than send failures are recorded (nil disables recording)
+func (p *MessageProcessor) SetProcessingErrors(processingErrors *ProcessingErrorService) {
+	p.processingErrors = processingErrors
+}
+
+// SetHealth sets the health gate told the outcome of every job, which drains the worker
+// after repeated infrastructure failures (nil never drains)
+func (p *MessageProcessor) SetHealth(health *WorkerHealth) {
+	p.health = health
+}
+
+// SetSuppressions sets the per-campaign suppression lists checked before each send (nil disables the check)
+// The check repeats the one made when the send was planned, catching phones suppressed since
+func (p *MessageProcessor) SetSuppressions(suppressions repository.SuppressionRepository) {
+	p.suppressions = suppressions
+}
+
+// SetCarrierThrottle sets the per-prefix pacing sends wait for (nil disables it)
+func (p *MessageProcessor) SetCarrierThrottle(throttle *CarrierThrottle) {
+	p.throttle = throttle
+}
+
+// SetLinkTracker sets the rewriting of links to tracked redirects for campaigns with
+// track_links (nil sends links as written)
+func (p *MessageProcessor) SetLinkTracker(links *LinkTracker) {
+	p.links = links
+}
+
+// SetSendingSwitch sets the kill switch checked before every send; while it is off messages
+// are deferred rather than sent (nil never defers)
+func (p *MessageProcessor) SetSendingSwitch(sending *SendingSwitch) {
+	p.sending = sending
+}
+
+// SetDuplicateSendWindow sets how recently a customer may have been sent the same content, by
+// any other campaign, before their message is skipped instead of sent (0 disables the check)
+func (p *MessageProcessor) SetDuplicateSendWindow(window time.Duration) {
+	p.duplicateWindow = window
+}
+
+// SetProcessBySkewTolerance sets how long past a job's process_by deadline its message is still
+// sent, allowing for the API's clock running ahead of the worker's
+func (p *MessageProcessor) SetProcessBySkewTolerance(tolerance time.Duration) {
+	p.processBySkew = tolerance
+}
+
+// SetNextPartPublisher sets how the next part of a multi-part send is published once the part
+// before it is sent (nil leaves it to the publish reconciler)
+func (p *MessageProcessor) SetNextPartPublisher(publish func(message *models.OutboundMessage) error) {
+	p.publishNext = publish
+}
+
+// SetClaims sets the repository each message is claimed through as workerID before it is
+// handled, so two workers given the same message never both send it (nil disables claims)
+// An unreleased claim stops blocking other workers after ttl
+func (p *MessageProcessor) SetClaims(claims repository.WorkerRepository, workerID string, ttl time.Duration) {
+	p.claims = claims
+	p.workerID = workerID
+	p.claimTTL = ttl
+}
+
+// SetDemoOverrides sets whether campaigns' demo_failure_rate is passed to the sender
+// It is off until this is called, and must stay off in production
+func (p *MessageProcessor) SetDemoOverrides(enabled bool) {
+	p.demoOverrides = enabled
+}
+
+// Handle processes one job; it is the worker's queue.MessageHandler
+// A nil error acknowledges the job, any other error requeues it
+// A panic is recovered and returned as a *PanicError so the job is requeued
+func (p *MessageProcessor) Handle(job *queue.MessageJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("💥 Recovered panic processing message ID %d: %v", job.MessageID, r)
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+		if err != nil {
+			p.recordError(job.MessageID, err)
+		}
+		p.health.Observe(err)
+	}()
+
+	return p.process(job)
+}
+
+// HandleExpired records a job whose handler overran its ack deadline; it is the
+// worker's queue.Watchdog expiry hook
+func (p *MessageProcessor) HandleExpired(job *queue.MessageJob, err error) {
+	p.recordError(job.MessageID, err)
+}
+
+// recordError counts a failed job by class and records it unless it is a send failure,
+// which is already stored on the message
+func (p *MessageProcessor) recordError(messageID int, err error) {
+	class := ClassifyProcessingError(err)
+	metrics.ProcessingErrors.WithLabelValues(class).Inc()
+	if class != ErrorClassSend {
+		p.processingErrors.Record(messageID, err)
+	}
+}
+
+// releaseClaim gives up this worker's claim on a message it has finished handling
+// A claim that fails to release only blocks other workers until it expires
+func (p *MessageProcessor) releaseClaim(messageID int) {
+	if err := p.claims.Release(context.Background(), messageID, p.workerID); err != nil {
+		log.Printf("Warning: Failed to release claim on message ID %d: %v", messageID, err)
+	}
+}
+
+// process renders and sends the message behind job
+func (p *MessageProcessor) process(job *queue.MessageJob) error {
+	ctx := context.Background()
+
+	log.Printf("📨 Processing message ID: %d", job.MessageID)
+
+	// Another worker handling the same message (a redelivery while it is still at work) sends
+	// it; claiming before the fetch means the status read below is never stale
+	if p.claims != nil {
+		claimed, err := p.claims.Claim(ctx, job.MessageID, p.workerID, p.claimTTL)
+		if err != nil {
+			log.Printf("❌ Failed to claim message: %v", err)
+			return err
+		}
+		if !claimed {
+			log.Printf("⚠️  Message ID %d claimed by another worker or gone, skipping", job.MessageID)
+			metrics.SkippedMessages.WithLabelValues("claimed_elsewhere").Inc()
+			return nil
+		}
+		defer p.releaseClaim(job.MessageID)
+	}
+
+	// Fetch message with campaign and customer
+	details, err := p.messageRepo.GetWithDetails(ctx, job.MessageID)
+	if err != nil {
+		return handleFetchError(ctx, p.db, job.MessageID, err)
+	}
+	message, campaign, customer := &details.OutboundMessage, &details.Campaign, &details.Customer
+
+	// A job redelivered after the worker died between send and ACK must not send twice
+	if message.Status == models.MessageStatusSent {
+		log.Printf("⚠️  Message ID %d already sent, skipping", job.MessageID)
+		metrics.SkippedMessages.WithLabelValues("already_sent").Inc()
+		return nil
+	}
+
+	// A cancelled campaign sends nothing more; while it is still cancelling its messages are
+	// held, so undoing the cancellation can release them
+	if message.Status == models.MessageStatusCancelled || campaign.Status == models.CampaignStatusCancelled {
+		log.Printf("🛑 Message ID %d not sent: campaign %d cancelled", job.MessageID, campaign.ID)
+		metrics.SkippedMessages.WithLabelValues("cancelled").Inc()
+		return nil
+	}
+	if campaign.Status == models.CampaignStatusCancelling {
+		log.Printf("⏸️  Message ID %d held: campaign %d is cancelling", job.MessageID, campaign.ID)
+		if err := p.messageRepo.Hold(ctx, job.MessageID, "Held: campaign cancelling"); err != nil {
+			log.Printf("❌ Failed to hold message: %v", err)
+			return err
+		}
+		// Return nil to ACK; the message is requeued if the cancellation is undone
+		return nil
+	}
+
+	// A message its send needed by a deadline now past is worse sent late than not at all
+	if p.isExpired(job) {
+		log.Printf("⌛ Message ID %d expired: not sent by %s", job.MessageID, job.ProcessBy.Format(time.RFC3339))
+		if err := updateMessageExpired(ctx, p.db, job.MessageID); err != nil {
+			log.Printf("❌ Failed to mark expired message: %v", err)
+			return err
+		}
+		if err := updateRemainingParts(ctx, p.db, message, models.MessageStatusExpired, "Expired: process_by deadline passed"); err != nil {
+			log.Printf("❌ Failed to expire the rest of the message group: %v", err)
+		}
+		metrics.SkippedMessages.WithLabelValues("expired").Inc()
+		// Return nil to ACK and remove from queue
+		return nil
+	}
+
+	// Check retry limit
+	if message.RetryCount >= 3 {
+		log.Printf("⚠️  Message ID %d exceeded retry limit, marking as permanently failed", job.MessageID)
+		if err := updateMessagePermanentFailure(ctx, p.db, job.MessageID); err != nil {
+			log.Printf("❌ Failed to update permanent failure: %v", err)
+		}
+		p.failRemainingParts(ctx, message)
+		p.events.Publish(ctx, failedEvent(message, campaign.Channel, "Exceeded maximum retry attempts (3)"))
+		// Return nil to ACK and remove from queue
+		return nil
+	}
+
+	// A customer blocked after the send was planned is refused outright, whatever the retries left
+	if customer.Blocked {
+		log.Printf("⛔ Message ID %d not sent: customer %d is blocked", job.MessageID, customer.ID)
+		metrics.SkippedMessages.WithLabelValues("blocked").Inc()
+		reason := "Customer blocked"
+		if updateErr := updateMessageRejected(ctx, p.db, job.MessageID, reason); updateErr != nil {
+			log.Printf("❌ Failed to mark rejected message: %v", updateErr)
+		}
+		p.failRemainingParts(ctx, message)
+		p.events.Publish(ctx, failedEvent(message, campaign.Channel, reason))
+		// Return nil to ACK and remove from queue
+		return nil
+	}
+
+	// Phones suppressed after the send was planned must still not be messaged
+	suppressed, err := p.isSuppressed(ctx, campaign.ID, customer.Phone)
+	if err != nil {
+		log.Printf("❌ Failed to check suppressions: %v", err)
+		return err
+	}
+	if suppressed {
+		log.Printf("🚫 Message ID %d not sent: phone suppressed for campaign %d", job.MessageID, campaign.ID)
+		metrics.SkippedMessages.WithLabelValues("suppressed").Inc()
+		reason := "Phone suppressed for this campaign"
+		if updateErr := updateMessageRejected(ctx, p.db, job.MessageID, reason); updateErr != nil {
+			log.Printf("❌ Failed to mark rejected message: %v", updateErr)
+		}
+		p.failRemainingParts(ctx, message)
+		p.events.Publish(ctx, failedEvent(message, campaign.Channel, reason))
+		// Return nil to ACK and remove from queue
+		return nil
+	}
+
+	// Hold the message until the customer's contact window opens
+	deferred, err := p.deferOutsideContactWindow(ctx, message, customer)
+	if err != nil {
+		log.Printf("❌ Failed to defer message: %v", err)
+		return err
+	}
+	if deferred {
+		// Return nil to ACK; the message is requeued once the window opens
+		return nil
+	}
+
+	// While all outbound sending is switched off the message waits, without using a retry
+	if !p.sending.Enabled(ctx) {
+		until := p.now().Add(SendingDisabledDeferral)
+		if err := p.messageRepo.DeferUntil(ctx, message.ID, until, "Deferred: outbound sending disabled"); err != nil {
+			log.Printf("❌ Failed to defer message: %v", err)
+			return err
+		}
+		log.Printf("⏸️  Message ID %d deferred to %s: outbound sending disabled", job.MessageID, until.Format(time.RFC3339))
+		metrics.SkippedMessages.WithLabelValues("sending_disabled").Inc()
+		// Return nil to ACK; the message is requeued once the deferral ends
+		return nil
+	}
+
+	// A customer just sent the same content by another campaign is not sent it again
+	duplicate, err := p.isRecentDuplicate(ctx, message, campaign)
+	if err != nil {
+		log.Printf("❌ Failed to check for duplicate content: %v", err)
+		return err
+	}
+	if duplicate {
+		log.Printf("🔁 Message ID %d skipped: customer %d was sent the same content within %s", job.MessageID, customer.ID, p.duplicateWindow)
+		if err := updateMessageSkippedDuplicate(ctx, p.db, job.MessageID); err != nil {
+			log.Printf("❌ Failed to mark skipped message: %v", err)
+			return err
+		}
+		if err := updateRemainingParts(ctx, p.db, message, models.MessageStatusSkippedDuplicateContent, "Skipped: same content sent to the customer recently"); err != nil {
+			log.Printf("❌ Failed to skip the rest of the message group: %v", err)
+		}
+		metrics.SkippedMessages.WithLabelValues("duplicate_content").Inc()
+		// Return nil to ACK and remove from queue
+		return nil
+	}
+
+	// Render template, or this message's part of it; a verbatim resend sends the content it
+	// was copied with, already rendered and with its links already rewritten
+	verbatim := message.ResentFromMessageID != nil && message.RenderedContent != nil
+	var rendered string
+	var template string
+	if verbatim {
+		rendered = *message.RenderedContent
+	} else if template, err = partTemplate(message, campaign); err == nil {
+		rendered, err = p.templateSvc.ForCampaign(campaign).Render(template, customer)
+	}
+	if err != nil {
+		log.Printf("❌ Failed to render template: %v", err)
+		updateErr := updateMessageFailure(ctx, p.db, job.MessageID, err.Error())
+		if updateErr != nil {
+			log.Printf("❌ Failed to update message failure: %v", updateErr)
+		}
+		message.RetryCount++
+		p.events.Publish(ctx, failedEvent(message, campaign.Channel, err.Error()))
+		return &RenderError{Err: err}
+	}
+
+	// Send links as tracked redirects; the rewritten message is what must fit the length limit
+	if campaign.TrackLinks && p.links != nil && !verbatim {
+		rendered, err = p.links.Rewrite(ctx, message.ID, rendered)
+		if err != nil {
+			log.Printf("❌ Failed to rewrite links: %v", err)
+			return err
+		}
+	}
+
+	// Over-length messages would be rejected by the provider on every retry
+	if err := p.templateSvc.CheckRenderedLength(rendered); err != nil {
+		log.Printf("❌ Message ID %d not sent: %v", job.MessageID, err)
+		if updateErr := updateMessageRejected(ctx, p.db, job.MessageID, err.Error()); updateErr != nil {
+			log.Printf("❌ Failed to mark rejected message: %v", updateErr)
+		}
+		p.failRemainingParts(ctx, message)
+		p.events.Publish(ctx, failedEvent(message, campaign.Channel, err.Error()))
+		// Return nil to ACK and remove from queue
+		return nil
+	}
+
+	log.Printf("📝 Rendered message for customer %s: %s", customer.Phone, rendered)
+
+	// Reserve the cost of the send, pausing the campaign if it would exceed its budget
+	// This comes before the attempt budget, so a held message does not use up an attempt
+	reserved, err := p.campaignBudget.Reserve(ctx, message, campaign.Channel)
+	if err != nil {
+		log.Printf("❌ Failed to check campaign budget: %v", err)
+		return err
+	}
+	if !reserved {
+		log.Printf("⏸️  Message ID %d held: campaign %d budget exceeded", job.MessageID, campaign.ID)
+		// Return nil to ACK; the message is requeued once the campaign is resumed
+		return nil
+	}
+
+	// Wait for the carrier's per-prefix limits, holding its in-flight slot for the send
+	// The wait comes before the attempt budget, so a wait that times out uses up no attempt
+	endSend, err := p.throttle.Acquire(ctx, customer.Phone)
+	if err != nil {
+		log.Printf("❌ Failed to wait for carrier throttle: %v", err)
+		p.campaignBudget.Release(ctx, message, campaign.Channel)
+		return err
+	}
+
+	// Hold the message until tomorrow if this customer has had too many attempts today
+	allowed, err := p.budget.Reserve(ctx, message)
+	if err != nil {
+		log.Printf("❌ Failed to check attempt budget: %v", err)
+		endSend()
+		p.campaignBudget.Release(ctx, message, campaign.Channel)
+		return err
+	}
+	if !allowed {
+		log.Printf("⏸️  Message ID %d deferred: customer %d reached the daily attempt budget", job.MessageID, customer.ID)
+		endSend()
+		p.campaignBudget.Release(ctx, message, campaign.Channel)
+		// Return nil to ACK; the message is requeued once the deferral ends
+		return nil
+	}
+
+	// Send message
+	opts := SenderOptions{}
+	if p.demoOverrides {
+		opts.FailureRate = campaign.DemoFailureRate
+	}
+	result := p.sender.Send(campaign.Channel, customer.Phone, rendered, opts)
+	endSend()
+
+	if result.Success {
+		// Update as sent
+		if result.Simulated {
+			log.Printf("🧪 Message simulated for %s (latency: %v)", customer.Phone, result.Latency)
+			// Simulated sends cost nothing
+			p.campaignBudget.Release(ctx, message, campaign.Channel)
+		} else {
+			log.Printf("✅ Message sent successfully to %s (latency: %v)", customer.Phone, result.Latency)
+		}
+		err := p.faults.Fail(faults.FailDBAfterSend)
+		if err == nil {
+			err = updateMessageSuccess(ctx, p.db, job.MessageID, result.Simulated)
+		}
+		if errors.Is(err, repository.ErrMessageNotFound) {
+			// Requeueing would send it again; there is nothing left to record the send on
+			log.Printf("⚠️  Message ID %d sent but no longer exists, not recorded", job.MessageID)
+			metrics.UnrecordedSends.WithLabelValues("sent").Inc()
+			return nil
+		}
+		if err != nil {
+			log.Printf("❌ Failed to update message success: %v", err)
+			return err
+		}
+		recordDeliveryLatency(message, campaign.Channel, time.Now())
+		event := messageEvent(notify.EventMessageSent, message, campaign.Channel)
+		event.Simulated = result.Simulated
+		p.events.Publish(ctx, event)
+		p.publishNextPart(ctx, message)
+		p.faults.Panic(faults.PanicBeforeAck)
+		return nil
+	} else {
+		// Update as failed with retry
+		errMsg := result.Error.Error()
+		log.Printf("❌ Send failed for %s: %s (retry count: %d)", customer.Phone, errMsg, message.RetryCount+1)
+		p.campaignBudget.Release(ctx, message, campaign.Channel)
+		if err := updateMessageFailure(ctx, p.db, job.MessageID, errMsg); err != nil {
+			if errors.Is(err, repository.ErrMessageNotFound) {
+				// Nothing is left to retry
+				log.Printf("⚠️  Message ID %d failed but no longer exists, not retried", job.MessageID)
+				metrics.UnrecordedSends.WithLabelValues("failed").Inc()
+				return nil
+			}
+			if repository.IsTransient(err) {
+				// The failure may not have been recorded, so requeue it as an infrastructure
+				// failure rather than a send failure, leaving retry_count as it was
+				log.Printf("❌ Message ID %d send failure not recorded during a database failover, requeueing: %v", job.MessageID, err)
+				return err
+			}
+			log.Printf("❌ Failed to update message failure: %v", err)
+		}
+		message.RetryCount++
+		p.events.Publish(ctx, failedEvent(message, campaign.Channel, errMsg))
+		return &SendError{Reason: errMsg}
+	}
+}
+
+// isSuppressed reports whether the campaign's suppression list holds the phone, in any format
+func (p *MessageProcessor) isSuppressed(ctx context.Context, campaignID int, phone string) (bool, error) {
+	if p.suppressions == nil {
+		return false, nil
+	}
+
+	key := models.PhoneKey(phone)
+	if key != phone {
+		log.Printf("Warning: Customer phone %q is not normalized; checking suppressions as %s (run cmd/normalize-phones)", phone, key)
+	}
+
+	suppressed, err := p.suppressions.FilterSuppressed(ctx, campaignID, []string{key})
+	if err != nil {
+		return false, err
+	}
+	return len(suppressed) > 0, nil
+}
+
+// isRecentDuplicate reports whether the customer was sent the campaign's content, by another
+// message, within the duplicate send window
+func (p *MessageProcessor) isRecentDuplicate(ctx context.Context, message *models.OutboundMessage, campaign *models.Campaign) (bool, error) {
+	// Later parts share the first part's content fingerprint and follow wherever it went;
+	// a resend repeats content on purpose
+	if p.duplicateWindow <= 0 || message.Part > 1 || message.ResentFromMessageID != nil {
+		return false, nil
+	}
+	since := p.now().Add(-p.duplicateWindow)
+	return p.messageRepo.HasRecentFingerprintSend(ctx, message.CustomerID, campaign.ContentFingerprint(), message.ID, since)
+}
+
+// partTemplate returns the template the message renders: the campaign's whole template, or the
+// message's part of it when the campaign sends each customer several messages
+func partTemplate(message *models.OutboundMessage, campaign *models.Campaign) (string, error) {
+	if message.PartCount <= 1 {
+		return campaign.BaseTemplate, nil
+	}
+	parts := campaign.TemplateParts()
+	if message.Part < 1 || message.Part > len(parts) {
+		return "", fmt.Errorf("message is part %d of %d, but the template has %d parts", message.Part, message.PartCount, len(parts))
+	}
+	return parts[message.Part-1], nil
+}
+
+// publishNextPart publishes the part after message in its group, now that message is sent
+// A part this fails to publish is left to the publish reconciler or the deferred requeue
+func (p *MessageProcessor) publishNextPart(ctx context.Context, message *models.OutboundMessage) {
+	if message.GroupID == nil || message.Part >= message.PartCount || p.publishNext == nil {
+		return
+	}
+	next, err := p.messageRepo.ClaimNextPart(ctx, *message.GroupID, message.Part+1)
+	if err != nil {
+		log.Printf("❌ Failed to claim part %d of message group %d: %v", message.Part+1, *message.GroupID, err)
+		return
+	}
+	if next == nil {
+		// Already published, deferred or no longer pending
+		return
+	}
+	if err := p.publishNext(next); err != nil {
+		log.Printf("❌ Failed to publish part %d of message group %d, deferring: %v", next.Part, *message.GroupID, err)
+		if err := p.messageRepo.DeferUntil(ctx, next.ID, p.now(), "Deferred: failed to publish"); err != nil {
+			log.Printf("❌ Failed to defer message: %v", err)
+		}
+		return
+	}
+	log.Printf("🧩 Published part %d of %d of message group %d", next.Part, message.PartCount, *message.GroupID)
+}
+
+// failRemainingParts fails the parts after message in its group, which can no longer be sent
+// in order once message has failed for good
+func (p *MessageProcessor) failRemainingParts(ctx context.Context, message *models.OutboundMessage) {
+	reason := fmt.Sprintf("Part %d of the message group failed", message.Part)
+	if err := updateRemainingParts(ctx, p.db, message, models.MessageStatusFailed, reason); err != nil {
+		log.Printf("❌ Failed to fail the rest of the message group: %v", err)
+	}
+}
+
+// isExpired reports whether the job's deadline, allowing for clock skew, has passed; a job
+// without one never expires
+func (p *MessageProcessor) isExpired(job *queue.MessageJob) bool {
+	return job.ProcessBy != nil && p.now().After(job.ProcessBy.Add(p.processBySkew))
+}
+
+// deferOutsideContactWindow defers the message to the next opening of the customer's contact window
+// and reports whether it did; customers without a window can be messaged any time
+func (p *MessageProcessor) deferOutsideContactWindow(ctx context.Context, message *models.OutboundMessage, customer *models.Customer) (bool, error) {
+	window, err := customer.ContactWindow()
+	if err != nil {
+		log.Printf("⚠️  Ignoring invalid contact window of customer %d: %v", customer.ID, err)
+		return false, nil
+	}
+	if window == nil {
+		return false, nil
+	}
+
+	now := p.now()
+	next := window.NextOpen(now, p.zone)
+	if next.Equal(now) {
+		return false, nil
+	}
+
+	reason := fmt.Sprintf("Deferred to %s: outside customer contact window (%s %s)", next.Format(time.RFC3339), window, p.zone)
+	if err := p.messageRepo.DeferUntil(ctx, message.ID, next, reason); err != nil {
+		return false, err
+	}
+
+	log.Printf("⏸️  Message ID %d deferred: customer %d contact window is %s", message.ID, customer.ID, window)
+	return true, nil
+}
+
+// recordDeliveryLatency logs and records queue and total latency for a sent message
+func recordDeliveryLatency(message *models.OutboundMessage, channel models.Channel, now time.Time) {
+	queueLatency, published := message.QueueLatency(now)
+	totalLatency := message.TotalLatency(now)
+
+	if published {
+		log.Printf("⏱️  Message ID %d latency: queue %v, total %v", message.ID, queueLatency, totalLatency)
+	} else {
+		log.Printf("⏱️  Message ID %d latency: total %v (no publish timestamp)", message.ID, totalLatency)
+	}
+
+	metrics.ObserveMessageLatency(string(channel), queueLatency, published, totalLatency)
+}
+
+// messageEvent builds a lifecycle event for a message
+func messageEvent(eventType string, message *models.OutboundMessage, channel models.Channel) notify.Event {
+	status := models.MessageStatusSent
+	if eventType == notify.EventMessageFailed {
+		status = models.MessageStatusFailed
+	}
+
+	return notify.Event{
+		Type:       eventType,
+		CampaignID: message.CampaignID,
+		MessageID:  message.ID,
+		CustomerID: message.CustomerID,
+		Channel:    string(channel),
+		Status:     string(status),
+		RetryCount: message.RetryCount,
+	}
+}
+
+// failedEvent builds a message.failed event carrying the failure reason
+func failedEvent(message *models.OutboundMessage, channel models.Channel, reason string) notify.Event {
+	event := messageEvent(notify.EventMessageFailed, message, channel)
+	event.Error = reason
+	return event
+}
+
+// handleFetchError decides whether a failed fetch should be retried
+// Missing rows are permanent: the job is acknowledged (nil) instead of requeued forever
+func handleFetchError(ctx context.Context, db repository.DB, messageID int, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrMessageNotFound):
+		// Campaign or customer delete already cascaded to the message
+		log.Printf("⚠️  Message ID %d no longer exists, skipping", messageID)
+		metrics.SkippedMessages.WithLabelValues("message_deleted").Inc()
+		return nil
+	case errors.Is(err, repository.ErrCampaignNotFound), errors.Is(err, repository.ErrCustomerNotFound):
+		log.Printf("⚠️  Message ID %d skipped: %v", messageID, err)
+		reason := "campaign_deleted"
+		if errors.Is(err, repository.ErrCustomerNotFound) {
+			reason = "customer_deleted"
+		}
+		metrics.SkippedMessages.WithLabelValues(reason).Inc()
+		if updateErr := updateMessageOrphaned(ctx, db, messageID, err.Error()); updateErr != nil {
+			log.Printf("❌ Failed to mark orphaned message: %v", updateErr)
+		}
+		return nil
+	default:
+		log.Printf("❌ Failed to fetch message data: %v", err)
+		return err
+	}
+}
+
+// updateMessageSuccess updates message as sent, flagging sends that were only simulated
+// Returns repository.ErrMessageNotFound when the message was deleted
+func updateMessageSuccess(ctx context.Context, db repository.DB, messageID int, simulated bool) error {
+	query := `
+		UPDATE outbound_messages 
+		SET status = 'sent', simulated = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := repository.ExecWithRetry(ctx, db, query, messageID, simulated)
+	if err != nil {
+		return fmt.Errorf("failed to update message success: %w", err)
+	}
+
+	return checkMessageUpdated(result)
+}
+
+// updateMessageFailure updates message as failed with retry
+// Returns repository.ErrMessageNotFound when the message was deleted
+func updateMessageFailure(ctx context.Context, db repository.DB, messageID int, errorMsg string) error {
+	query := `
+		UPDATE outbound_messages 
+		SET status = 'failed', 
+			retry_count = retry_count + 1,
+			last_error = $2,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := repository.ExecWithRetry(ctx, db, query, messageID, errorMsg)
+	if err != nil {
+		return fmt.Errorf("failed to update message failure: %w", err)
+	}
+
+	return checkMessageUpdated(result)
+}
+
+// checkMessageUpdated returns repository.ErrMessageNotFound when an update matched no message
+func checkMessageUpdated(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return repository.ErrMessageNotFound
+	}
+	return nil
+}
+
+// updateMessageSkippedDuplicate marks a message skipped as a recent duplicate of one the
+// customer was already sent; it is never sent
+func updateMessageSkippedDuplicate(ctx context.Context, db repository.DB, messageID int) error {
+	query := `
+		UPDATE outbound_messages 
+		SET status = 'skipped_duplicate_content',
+			last_error = 'Skipped: same content sent to the customer recently',
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := repository.ExecWithRetry(ctx, db, query, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to update skipped message: %w", err)
+	}
+
+	return nil
+}
+
+// updateMessageExpired marks a message not sent by its send's deadline; it is never sent
+func updateMessageExpired(ctx context.Context, db repository.DB, messageID int) error {
+	query := `
+		UPDATE outbound_messages 
+		SET status = 'expired',
+			last_error = 'Expired: process_by deadline passed',
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := repository.ExecWithRetry(ctx, db, query, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to update expired message: %w", err)
+	}
+
+	return nil
+}
+
+// updateMessagePermanentFailure marks message as permanently failed
+func updateMessagePermanentFailure(ctx context.Context, db repository.DB, messageID int) error {
+	query := `
+		UPDATE outbound_messages 
+		SET status = 'failed',
+			last_error = 'Exceeded maximum retry attempts (3)',
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := repository.ExecWithRetry(ctx, db, query, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to update permanent failure: %w", err)
+	}
+
+	return nil
+}
+
+// updateRemainingParts gives the pending parts after message in its group the status and
+// reason, raising the retry count to the limit so they are not retried
+func updateRemainingParts(ctx context.Context, db repository.DB, message *models.OutboundMessage, status models.MessageStatus, reason string) error {
+	if message.GroupID == nil || message.Part >= message.PartCount {
+		return nil
+	}
+
+	query := `
+		UPDATE outbound_messages 
+		SET status = $3,
+			retry_count = GREATEST(retry_count, 3),
+			last_error = $4,
+			updated_at = NOW()
+		WHERE group_id = $1 AND part > $2 AND status = 'pending'
+	`
+
+	_, err := repository.ExecWithRetry(ctx, db, query, *message.GroupID, message.Part, status, reason)
+	if err != nil {
+		return fmt.Errorf("failed to update remaining message parts: %w", err)
+	}
+
+	return nil
+}
+
+// updateMessageOrphaned marks a message whose campaign or customer is gone as permanently failed
+func updateMessageOrphaned(ctx context.Context, db repository.DB, messageID int, reason string) error {
+	return updateMessageRejected(ctx, db, messageID, "Referenced "+reason)
+}
+
+// updateMessageRejected marks a message that can never be sent as permanently failed
+// The retry count is raised to the limit so it is not retried
+func updateMessageRejected(ctx context.Context, db repository.DB, messageID int, reason string) error {
+	query := `
+		UPDATE outbound_messages 
+		SET status = 'failed',
+			retry_count = GREATEST(retry_count, 3),
+			last_error = $2,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := repository.ExecWithRetry(ctx, db, query, messageID, reason)
+	if err != nil {
+		return fmt.Errorf("failed to update rejected message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// DefaultReassignBatchSize is how many messages each transaction of a reassignment moves
+const DefaultReassignBatchSize = 1000
+
+// MessageReassigner moves messages from one campaign to another, e.g. from a campaign created
+// with the wrong template to a corrected one, without cancelling and rebuilding the send
+type MessageReassigner struct {
+	campaignRepo     repository.CampaignRepository
+	reassignmentRepo repository.MessageReassignmentRepository
+	batchSize        int
+}
+
+// NewMessageReassigner creates a reassigner moving batchSize messages per transaction
+func NewMessageReassigner(campaignRepo repository.CampaignRepository, reassignmentRepo repository.MessageReassignmentRepository, batchSize int) *MessageReassigner {
+	if batchSize <= 0 {
+		batchSize = DefaultReassignBatchSize
+	}
+	return &MessageReassigner{
+		campaignRepo:     campaignRepo,
+		reassignmentRepo: reassignmentRepo,
+		batchSize:        batchSize,
+	}
+}
+
+// ReassignMessagesRequest selects the messages to move
+type ReassignMessagesRequest struct {
+	FromCampaignID int                  `json:"from_campaign_id"`
+	ToCampaignID   int                  `json:"to_campaign_id"`
+	Status         models.MessageStatus `json:"status"` // pending (default) or failed
+	RequestedBy    string               `json:"-"`      // Authenticated caller, for the audit log
+}
+
+// Reassign moves the source campaign's messages in the requested status to the target
+// campaign in batches, logging the move first and raising its count with each batch
+// progress, if set, is called after each batch with the messages moved so far
+// If a batch fails, the messages already moved stay moved and the log says how many;
+// running it again moves the rest
+func (m *MessageReassigner) Reassign(ctx context.Context, req *ReassignMessagesRequest, progress func(moved int)) (*models.MessageReassignment, error) {
+	target, err := m.validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	reassignment := &models.MessageReassignment{
+		FromCampaignID: req.FromCampaignID,
+		ToCampaignID:   req.ToCampaignID,
+		Status:         req.Status,
+	}
+	if req.RequestedBy != "" {
+		reassignment.RequestedBy = &req.RequestedBy
+	}
+	if err := m.reassignmentRepo.Create(ctx, reassignment); err != nil {
+		return nil, err
+	}
+
+	fingerprint := target.ContentFingerprint()
+	for {
+		if err := ctx.Err(); err != nil {
+			return reassignment, fmt.Errorf("reassignment stopped after moving %d messages: %w", reassignment.MessagesMoved, err)
+		}
+		moved, err := m.reassignmentRepo.MoveBatch(ctx, reassignment, fingerprint, m.batchSize)
+		if err != nil {
+			return reassignment, fmt.Errorf("reassignment failed after moving %d messages: %w", reassignment.MessagesMoved, err)
+		}
+		if moved == 0 {
+			return reassignment, nil
+		}
+		if progress != nil {
+			progress(reassignment.MessagesMoved)
+		}
+	}
+}
+
+// validate checks the request and both campaigns, defaulting the status to pending,
+// and returns the target campaign
+func (m *MessageReassigner) validate(ctx context.Context, req *ReassignMessagesRequest) (*models.Campaign, error) {
+	if req.FromCampaignID <= 0 || req.ToCampaignID <= 0 {
+		return nil, &ValidationError{Message: "from_campaign_id and to_campaign_id are required"}
+	}
+	if req.FromCampaignID == req.ToCampaignID {
+		return nil, &ValidationError{Message: "from_campaign_id and to_campaign_id must differ"}
+	}
+	if req.Status == "" {
+		req.Status = models.MessageStatusPending
+	}
+	if req.Status != models.MessageStatusPending && req.Status != models.MessageStatusFailed {
+		return nil, &ValidationError{Message: "status must be pending or failed"}
+	}
+
+	source, err := m.campaignRepo.GetByID(ctx, req.FromCampaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: req.FromCampaignID}
+	}
+	target, err := m.campaignRepo.GetByID(ctx, req.ToCampaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: req.ToCampaignID}
+	}
+
+	if source.Channel != target.Channel {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("campaigns must share a channel: campaign %d is %s, campaign %d is %s", source.ID, source.Channel, target.ID, target.Channel),
+		}
+	}
+	if target.IsTerminal() {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("messages cannot be moved to campaign %d: status is %s", target.ID, target.Status),
+		}
+	}
+
+	return target, nil
+}
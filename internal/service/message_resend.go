@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// ResendMessageRequest chooses what a resent message says
+type ResendMessageRequest struct {
+	// Rerender renders the resend from the campaign's current template and the customer's
+	// current details; otherwise the original's stored content is sent verbatim
+	Rerender bool `json:"rerender"`
+}
+
+// GetMessage returns a message, without its campaign or customer
+func (s *CampaignService) GetMessage(ctx context.Context, messageID int) (*models.OutboundMessage, error) {
+	message, err := s.messageRepo.GetByID(ctx, messageID)
+	if errors.Is(err, repository.ErrMessageNotFound) {
+		return nil, &NotFoundError{Resource: "message", ID: messageID}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	return message, nil
+}
+
+// ResendMessage sends a sent or failed message to its customer again as a new message,
+// resent_from_message_id pointing at the original, which is left as it is for audit
+// The resend is published straight away and counted in its campaign's resent stats, apart
+// from the send's messages; a verbatim resend needs the original's stored content
+func (s *CampaignService) ResendMessage(ctx context.Context, original *models.OutboundMessage, req *ResendMessageRequest) (*models.OutboundMessage, error) {
+	if original.Status != models.MessageStatusSent && original.Status != models.MessageStatusFailed {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("only sent or failed messages can be resent; message %d is %s", original.ID, original.Status),
+		}
+	}
+	if !req.Rerender && original.RenderedContent == nil {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("message %d has no stored content to resend verbatim; resend with \"rerender\": true", original.ID),
+		}
+	}
+
+	campaign, err := s.GetCampaign(ctx, original.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.Status == models.CampaignStatusCancelled || campaign.Status == models.CampaignStatusCancelling {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("campaign %d is %s; its messages cannot be resent", campaign.ID, campaign.Status),
+		}
+	}
+
+	// A re-rendered resend says what the current template says
+	var fingerprint *string
+	if req.Rerender {
+		current := campaign.ContentFingerprint()
+		fingerprint = &current
+	}
+	resend, err := s.messageRepo.CreateResend(ctx, original.ID, !req.Rerender, fingerprint)
+	if errors.Is(err, repository.ErrMessageNotFound) {
+		return nil, &NotFoundError{Resource: "message", ID: original.ID}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A resend whose job fails to publish is deferred by publishMessages; one whose publish
+	// time could not be recorded is deferred here, as the reconciler only picks up messages of
+	// campaigns still sending
+	if _, err := s.publishMessages(ctx, campaign, []*models.OutboundMessage{resend}, nil); err != nil {
+		if deferErr := s.messageRepo.DeferUntil(ctx, resend.ID, time.Now(), "Deferred: failed to publish"); deferErr != nil {
+			return nil, fmt.Errorf("failed to queue resend %d: %w", resend.ID, err)
+		}
+	}
+
+	return resend, nil
+}
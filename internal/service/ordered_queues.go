@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"smsleopard/internal/repository"
+)
+
+// OrderedQueueSyncInterval is how often workers look for ordered campaigns to consume
+const OrderedQueueSyncInterval = 5 * time.Second
+
+// OrderedQueues keeps a worker consuming the dedicated queue of every ordered campaign with
+// messages left, and lets go of each queue once its campaign completes
+type OrderedQueues struct {
+	campaignRepo repository.CampaignRepository
+}
+
+// NewOrderedQueues creates a new ordered queue syncer
+func NewOrderedQueues(campaignRepo repository.CampaignRepository) *OrderedQueues {
+	return &OrderedQueues{campaignRepo: campaignRepo}
+}
+
+// Sync passes consume the ordered campaigns with messages left to send or retry
+// Campaigns left out have completed, so consume can stop consuming and delete their queues
+func (q *OrderedQueues) Sync(ctx context.Context, consume func(campaignIDs []int) error) error {
+	campaignIDs, err := q.campaignRepo.ListOrderedOutstanding(ctx)
+	if err != nil {
+		return err
+	}
+	if err := consume(campaignIDs); err != nil {
+		return fmt.Errorf("failed to sync ordered queues: %w", err)
+	}
+	return nil
+}
+
+// Run syncs every interval until ctx is cancelled
+func (q *OrderedQueues) Run(ctx context.Context, interval time.Duration, consume func(campaignIDs []int) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.Sync(ctx, consume); err != nil {
+				log.Printf("Warning: Failed to sync ordered campaign queues: %v", err)
+			}
+		}
+	}
+}
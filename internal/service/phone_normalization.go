@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// PhoneNormalizer rewrites phones stored before normalization on write (e.g. "0712345678")
+// into normalized form ("+254712345678"), for customers and campaign suppression lists
+//
+// Rows whose phones normalize to the same number collide. The oldest is kept and given the
+// normalized phone; the rest are reported. Colliding customers are left as stored, since
+// they own messages and need merging by hand; colliding suppressions are duplicates of the
+// kept one and are removed. Phones that cannot be normalized are reported and left as stored
+type PhoneNormalizer struct {
+	repo repository.PhoneNormalizationRepository
+}
+
+// NewPhoneNormalizer creates a phone normalizer
+func NewPhoneNormalizer(repo repository.PhoneNormalizationRepository) *PhoneNormalizer {
+	return &PhoneNormalizer{repo: repo}
+}
+
+// PhoneNormalizationReport is the outcome of normalizing one table's phones
+// In a dry run the counts are what would change
+type PhoneNormalizationReport struct {
+	Table      string
+	Scanned    int
+	Rewritten  int      // Rows given their normalized phone
+	Removed    int      // Duplicate suppressions removed
+	Invalid    []string // Rows whose phone cannot be normalized
+	Collisions []*PhoneCollision
+}
+
+// PhoneCollision is rows whose phones normalize to the same number, oldest first
+type PhoneCollision struct {
+	Phone  string // The normalized phone
+	Kept   *repository.StoredPhone
+	Others []*repository.StoredPhone
+}
+
+// NormalizeCustomers normalizes customers' phones
+func (n *PhoneNormalizer) NormalizeCustomers(ctx context.Context, dryRun bool) (*PhoneNormalizationReport, error) {
+	rows, err := n.repo.ListCustomerPhones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report, rewrites, _ := planPhoneNormalization("customers", rows, false)
+	if dryRun || len(rewrites) == 0 {
+		return report, nil
+	}
+
+	report.Rewritten, err = n.repo.RewriteCustomerPhones(ctx, rewrites)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// NormalizeSuppressions normalizes the phones on campaign suppression lists
+func (n *PhoneNormalizer) NormalizeSuppressions(ctx context.Context, dryRun bool) (*PhoneNormalizationReport, error) {
+	rows, err := n.repo.ListSuppressionPhones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report, rewrites, duplicates := planPhoneNormalization("campaign_suppressions", rows, true)
+	if dryRun || (len(rewrites) == 0 && len(duplicates) == 0) {
+		return report, nil
+	}
+
+	report.Rewritten, report.Removed, err = n.repo.RewriteSuppressionPhones(ctx, rewrites, duplicates)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// planPhoneNormalization works out the rewrites, and with removeDuplicates the rows to remove,
+// for rows listed oldest first; suppressions only collide within a campaign
+func planPhoneNormalization(table string, rows []*repository.StoredPhone, removeDuplicates bool) (*PhoneNormalizationReport, []*repository.PhoneRewrite, []*repository.StoredPhone) {
+	report := &PhoneNormalizationReport{Table: table, Scanned: len(rows), Invalid: []string{}, Collisions: []*PhoneCollision{}}
+
+	type group struct {
+		phone string
+		rows  []*repository.StoredPhone
+	}
+	groups := map[string]*group{}
+	order := []*group{}
+	for _, row := range rows {
+		phone, err := models.NormalizePhone(row.Phone)
+		if err != nil {
+			report.Invalid = append(report.Invalid, fmt.Sprintf("%s: %v", DescribeStoredPhone(row), err))
+			continue
+		}
+
+		key := fmt.Sprintf("%d:%s", row.CampaignID, phone)
+		g, ok := groups[key]
+		if !ok {
+			g = &group{phone: phone}
+			groups[key] = g
+			order = append(order, g)
+		}
+		g.rows = append(g.rows, row)
+	}
+
+	rewrites := []*repository.PhoneRewrite{}
+	duplicates := []*repository.StoredPhone{}
+	for _, g := range order {
+		kept, others := g.rows[0], g.rows[1:]
+		if len(others) > 0 {
+			report.Collisions = append(report.Collisions, &PhoneCollision{Phone: g.phone, Kept: kept, Others: others})
+		}
+
+		// A colliding customer may already hold the normalized phone, which is unique
+		taken := false
+		if removeDuplicates {
+			duplicates = append(duplicates, others...)
+		} else {
+			for _, other := range others {
+				taken = taken || other.Phone == g.phone
+			}
+		}
+
+		if kept.Phone != g.phone && !taken {
+			rewrites = append(rewrites, &repository.PhoneRewrite{Row: kept, To: g.phone})
+		}
+	}
+
+	report.Rewritten = len(rewrites)
+	report.Removed = len(duplicates)
+	return report, rewrites, duplicates
+}
+
+// DescribeStoredPhone names a stored phone for reports, e.g. `customer 12 ("0712345678")`
+func DescribeStoredPhone(row *repository.StoredPhone) string {
+	if row.CampaignID != 0 {
+		return fmt.Sprintf("campaign %d suppression %q", row.CampaignID, row.Phone)
+	}
+	return fmt.Sprintf("customer %d (%q)", row.CustomerID, row.Phone)
+}
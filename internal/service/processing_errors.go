@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+)
+
+// Processing error classes
+const (
+	ErrorClassInfra   = "infra"   // Database, queue or other dependency failures
+	ErrorClassRender  = "render"  // The campaign template could not be rendered for the customer
+	ErrorClassSend    = "send"    // The provider failed the send; already recorded on the message
+	ErrorClassPanic   = "panic"   // The handler panicked and was recovered
+	ErrorClassTimeout = "timeout" // The handler overran its ack deadline and the job was requeued
+)
+
+// Processing error listing limits
+const (
+	DefaultProcessingErrorsWindow = 24 * time.Hour
+	MaxProcessingErrors           = 500
+)
+
+// processingErrorRecordTimeout bounds how long recording can hold up a job
+const processingErrorRecordTimeout = 2 * time.Second
+
+// SendError is a provider send failure
+type SendError struct {
+	Reason string
+}
+
+func (e *SendError) Error() string {
+	return "send failed: " + e.Reason
+}
+
+// RenderError is a template render failure
+type RenderError struct {
+	Err error
+}
+
+func (e *RenderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is a panic recovered while handling a job
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// ClassifyProcessingError returns the class of an error returned by the message processor
+// Anything not known to be a timeout, send, render or panic error is treated as infrastructure
+func ClassifyProcessingError(err error) string {
+	var sendErr *SendError
+	var renderErr *RenderError
+	var panicErr *PanicError
+
+	switch {
+	case errors.Is(err, queue.ErrAckDeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.As(err, &panicErr):
+		return ErrorClassPanic
+	case errors.As(err, &renderErr):
+		return ErrorClassRender
+	case errors.As(err, &sendErr):
+		return ErrorClassSend
+	default:
+		return ErrorClassInfra
+	}
+}
+
+// ProcessingErrorService records worker processing errors and lists them for triage
+type ProcessingErrorService struct {
+	repo     repository.ProcessingErrorRepository
+	workerID string
+}
+
+// NewProcessingErrorService creates a new processing error service
+// workerID identifies the recording worker and may be empty when only listing
+func NewProcessingErrorService(repo repository.ProcessingErrorRepository, workerID string) *ProcessingErrorService {
+	return &ProcessingErrorService{
+		repo:     repo,
+		workerID: workerID,
+	}
+}
+
+// Record stores err for messageID (0 when unknown)
+// It is best-effort: a failure to record is logged and never changes how the job is handled
+func (s *ProcessingErrorService) Record(messageID int, err error) {
+	if s == nil {
+		return
+	}
+
+	processingError := &models.ProcessingError{
+		ErrorClass: ClassifyProcessingError(err),
+		Error:      err.Error(),
+		WorkerID:   s.workerID,
+	}
+	if messageID > 0 {
+		processingError.MessageID = &messageID
+	}
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) && len(panicErr.Stack) > 0 {
+		processingError.Error += "\n" + string(panicErr.Stack)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), processingErrorRecordTimeout)
+	defer cancel()
+
+	if createErr := s.repo.Create(ctx, processingError); createErr != nil {
+		log.Printf("⚠️  Failed to record processing error for message ID %d: %v", messageID, createErr)
+	}
+}
+
+// List returns processing errors since the given time, newest first
+// A nil since covers the last DefaultProcessingErrorsWindow
+func (s *ProcessingErrorService) List(ctx context.Context, since *time.Time) ([]*models.ProcessingError, error) {
+	from := time.Now().Add(-DefaultProcessingErrorsWindow)
+	if since != nil {
+		from = *since
+	}
+
+	processingErrors, err := s.repo.ListSince(ctx, from, MaxProcessingErrors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processing errors: %w", err)
+	}
+
+	return processingErrors, nil
+}
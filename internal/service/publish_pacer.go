@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/metrics"
+)
+
+// PublishPacer spreads a batch of publishes evenly at its rate, each moved off its even slot by
+// up to the jitter, so workers and the provider see a steady flow rather than a burst and
+// then nothing
+// The slots are fixed from the start of the batch, so jitter never adds up to a late batch
+type PublishPacer struct {
+	rate   float64 // Publishes per second
+	jitter float64 // Fraction of the spacing a publish may move off its slot, below 0.5
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+	random func() float64
+}
+
+// NewPublishPacer creates a pacer from the pacing settings and the effective send rate, or
+// returns nil, which paces nothing, when pacing is off or there is no rate to pace at
+// The rate is capped at the send rate: jobs published faster only wait in the queue for the
+// carrier throttle, which is the burst pacing is meant to avoid
+func NewPublishPacer(pacing config.PublishPacingConfig, sendRate float64) *PublishPacer {
+	if !pacing.Enabled {
+		return nil
+	}
+
+	rate := pacing.Rate
+	if rate == 0 || (sendRate > 0 && rate > sendRate) {
+		rate = sendRate
+	}
+	if rate <= 0 {
+		return nil
+	}
+
+	return &PublishPacer{
+		rate:   rate,
+		jitter: pacing.Jitter,
+		now:    time.Now,
+		sleep:  sleepContext,
+		random: rand.Float64,
+	}
+}
+
+// SetClock overrides time.Now and the wait between publishes (for testing)
+func (p *PublishPacer) SetClock(now func() time.Time, sleep func(ctx context.Context, d time.Duration) error) {
+	p.now = now
+	p.sleep = sleep
+}
+
+// SetRandom overrides the source of jitter, which must return values in [0, 1) (for testing)
+func (p *PublishPacer) SetRandom(random func() float64) {
+	p.random = random
+}
+
+// Rate returns the publishes per second the pacer spaces batches at
+func (p *PublishPacer) Rate() float64 {
+	return p.rate
+}
+
+// BatchLimit returns the most publishes that fit in interval at the pacer's rate, at least one
+func (p *PublishPacer) BatchLimit(interval time.Duration) int {
+	return max(int(p.rate*interval.Seconds()), 1)
+}
+
+// Schedule returns when each of n publishes is due, as offsets from the start of the batch
+// The first is due straight away; the others are evenly spaced at the rate, each moved by up
+// to ±jitter of the spacing, so they stay in order
+func (p *PublishPacer) Schedule(n int) []time.Duration {
+	spacing := float64(time.Second) / p.rate
+	offsets := make([]time.Duration, n)
+	for i := 1; i < n; i++ {
+		shift := (2*p.random() - 1) * p.jitter * spacing
+		offsets[i] = time.Duration(float64(i)*spacing + shift)
+	}
+	return offsets
+}
+
+// Pace calls publish with each index from 0 to n-1 at its slot in the schedule, and returns
+// how many were called; a nil pacer calls them all at once
+// When ctx is cancelled while waiting for a slot, Pace stops and returns ctx's error
+func (p *PublishPacer) Pace(ctx context.Context, n int, publish func(i int)) (int, error) {
+	if p == nil {
+		for i := 0; i < n; i++ {
+			publish(i)
+		}
+		return n, nil
+	}
+	defer metrics.PublishPacingRate.Set(0)
+
+	start := p.now()
+	var last time.Time
+	for i, offset := range p.Schedule(n) {
+		if wait := start.Add(offset).Sub(p.now()); wait > 0 {
+			if err := p.sleep(ctx, wait); err != nil {
+				return i, err
+			}
+		}
+
+		now := p.now()
+		if i > 0 {
+			if gap := now.Sub(last); gap > 0 {
+				metrics.PublishPacingRate.Set(float64(time.Second) / float64(gap))
+			}
+		}
+		last = now
+
+		publish(i)
+		metrics.PublishPacingPublished.Inc()
+	}
+	return n, nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// UnpublishedGracePeriod is how long a pending message may go unpublished before the
+// reconciler publishes it; a send marks each message published moments after creating it
+const UnpublishedGracePeriod = 5 * time.Minute
+
+// PublishReconcileInterval is how often never-published messages are looked for
+const PublishReconcileInterval = time.Minute
+
+// PublishReconcileBatchSize is the most never-published messages published per check
+const PublishReconcileBatchSize = 500
+
+// PublishReconciler publishes pending messages that never reached the broker, e.g. because
+// the broker was down when their campaign was sent. Messages published and awaiting the
+// worker are left alone: they are already queued
+// A message's publish time is always recorded before its job is published, by the send or
+// by claiming it, so one without is known never to have been queued and publishing it
+// cannot send it twice
+// It also finalizes cancellations whose grace period has ended
+type PublishReconciler struct {
+	messageRepo   repository.MessageRepository
+	now           func() time.Time
+	paused        func() bool
+	events        repository.CampaignEventRepository
+	cancellations repository.CampaignRepository
+	order         models.DispatchOrder
+	pacer         *PublishPacer
+	pacedOver     time.Duration
+}
+
+// NewPublishReconciler creates a new publish reconciler
+func NewPublishReconciler(messageRepo repository.MessageRepository) *PublishReconciler {
+	return &PublishReconciler{
+		messageRepo: messageRepo,
+		now:         time.Now,
+	}
+}
+
+// SetClock overrides time.Now (for testing)
+func (r *PublishReconciler) SetClock(now func() time.Time) {
+	r.now = now
+}
+
+// SetPaused makes Reconcile a no-op while paused returns true, e.g. during maintenance
+func (r *PublishReconciler) SetPaused(paused func() bool) {
+	r.paused = paused
+}
+
+// SetCampaignEvents sets where published messages are recorded as queued events
+// (nil disables them)
+func (r *PublishReconciler) SetCampaignEvents(eventRepo repository.CampaignEventRepository) {
+	r.events = eventRepo
+}
+
+// SetDispatchOrder sets the order never-published messages are claimed in; first in, first
+// out until this is called
+func (r *PublishReconciler) SetDispatchOrder(order models.DispatchOrder) {
+	r.order = order
+}
+
+// SetPacing spreads each check's publishes over interval at the pacer's rate, claiming at most
+// as many as fit; batches are published at once, up to PublishReconcileBatchSize, until this
+// is called
+func (r *PublishReconciler) SetPacing(pacer *PublishPacer, interval time.Duration) {
+	r.pacer = pacer
+	r.pacedOver = interval
+}
+
+// SetCancellations sets the campaigns whose due cancellations are finalized on each check
+// (nil leaves cancelling campaigns alone)
+func (r *PublishReconciler) SetCancellations(campaignRepo repository.CampaignRepository) {
+	r.cancellations = campaignRepo
+}
+
+// Reconcile publishes pending messages never published within UnpublishedGracePeriod of
+// being created and returns how many were published
+// Messages that fail to publish are deferred to now, so the deferred requeue retries them,
+// as are those left unpublished when ctx is cancelled while pacing
+func (r *PublishReconciler) Reconcile(ctx context.Context, publish func(message *models.OutboundMessage) error) (int, error) {
+	if r.paused != nil && r.paused() {
+		return 0, nil
+	}
+
+	r.finalizeCancellations(ctx)
+
+	now := r.now()
+	limit := PublishReconcileBatchSize
+	if r.pacer != nil {
+		limit = min(limit, r.pacer.BatchLimit(r.pacedOver))
+	}
+	messages, err := r.messageRepo.ClaimUnpublished(ctx, now.Add(-UnpublishedGracePeriod), limit, r.order)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	perCampaign := map[int]int{}
+	reached, paceErr := r.pacer.Pace(ctx, len(messages), func(i int) {
+		message := messages[i]
+		if err := publish(message); err != nil {
+			log.Printf("Warning: Failed to publish unpublished message %d: %v", message.ID, err)
+			if deferErr := r.messageRepo.DeferUntil(ctx, message.ID, now, "Publish failed: "+err.Error()); deferErr != nil {
+				log.Printf("Warning: Failed to defer message %d: %v", message.ID, deferErr)
+			}
+			return
+		}
+		published++
+		perCampaign[message.CampaignID]++
+	})
+	if paceErr != nil {
+		// Claimed but never published: hand them to the deferred requeue
+		deferCtx := context.WithoutCancel(ctx)
+		for _, message := range messages[reached:] {
+			if err := r.messageRepo.DeferUntil(deferCtx, message.ID, now, "Publish interrupted"); err != nil {
+				log.Printf("Warning: Failed to defer message %d: %v", message.ID, err)
+			}
+		}
+		log.Printf("Publishing interrupted: %d never-published message(s) deferred", len(messages)-reached)
+	}
+
+	for campaignID, count := range perCampaign {
+		recordQueuedEvent(context.WithoutCancel(ctx), r.events, campaignID, campaignQueuedPayload{MessagesQueued: count, Reconciled: true})
+	}
+
+	return published, nil
+}
+
+// finalizeCancellations cancels the pending messages of campaigns whose cancellation grace
+// period has ended; failures are logged and retried on the next check
+func (r *PublishReconciler) finalizeCancellations(ctx context.Context) {
+	if r.cancellations == nil {
+		return
+	}
+
+	ids, err := r.cancellations.ListDueCancellations(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to list due cancellations: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		cancelled, err := r.cancellations.FinalizeCancellation(ctx, id)
+		if errors.Is(err, repository.ErrCancelNotDue) {
+			// Undone since it was listed
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to finalize cancellation of campaign %d: %v", id, err)
+			continue
+		}
+		log.Printf("🛑 Campaign %d cancelled: %d pending message(s) cancelled", id, cancelled)
+	}
+}
+
+// Run reconciles every interval until ctx is cancelled
+func (r *PublishReconciler) Run(ctx context.Context, interval time.Duration, publish func(message *models.OutboundMessage) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := r.Reconcile(ctx, publish)
+			if err != nil {
+				log.Printf("Warning: Failed to reconcile unpublished messages: %v", err)
+				continue
+			}
+			if count > 0 {
+				log.Printf("📤 Published %d never-published message(s)", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+)
+
+// QueueVerifyGracePeriod is how long after being published a pending message missing from
+// the queue is left alone by default, as its job may still be on its way or with a worker
+const QueueVerifyGracePeriod = 10 * time.Minute
+
+// QueueDrain is a queue's waiting jobs held off it for inspection (a *queue.Drain)
+type QueueDrain interface {
+	Jobs() []*queue.QueuedJob
+	Requeue(job *queue.QueuedJob) error
+	Quarantine(job *queue.QueuedJob, reason string) error
+}
+
+// QueueVerifyReport summarizes how the queue and the database disagreed and what was done
+type QueueVerifyReport struct {
+	DryRun      bool
+	Jobs        int   // Jobs waiting in the queue
+	Matched     int   // Jobs for pending messages
+	Stale       []int // Messages no longer pending whose jobs were left for the worker to skip
+	OrphanJobs  []int // Messages with jobs but no row, whose jobs were moved to the poison queue
+	Undecodable int   // Jobs that are not message jobs, moved to the poison queue
+	OrphanRows  []int // Pending messages missing from the queue, republished
+	Failed      int   // Fixes that failed; the job or message is left as it was
+}
+
+// QueueVerifier cross-checks the send queue against outbound_messages after an incident:
+// jobs whose message does not exist are moved to the poison queue, and pending messages
+// published a while ago but not in the queue are republished
+type QueueVerifier struct {
+	messageRepo repository.MessageRepository
+	grace       time.Duration
+	now         func() time.Time
+}
+
+// NewQueueVerifier creates a verifier leaving messages published within grace alone
+func NewQueueVerifier(messageRepo repository.MessageRepository, grace time.Duration) *QueueVerifier {
+	return &QueueVerifier{
+		messageRepo: messageRepo,
+		grace:       grace,
+		now:         time.Now,
+	}
+}
+
+// SetClock overrides time.Now (for testing)
+func (v *QueueVerifier) SetClock(now func() time.Time) {
+	v.now = now
+}
+
+// Verify reconciles the drained jobs with the database and gives every job back to the
+// queue except orphans; a dry run only reports, requeueing every job and publishing nothing
+func (v *QueueVerifier) Verify(ctx context.Context, drain QueueDrain, publish func(message *models.OutboundMessage) error, dryRun bool) (*QueueVerifyReport, error) {
+	// Read before the database so a message published meanwhile falls inside the grace period
+	publishedBefore := v.now().Add(-v.grace)
+	jobs := drain.Jobs()
+	report := &QueueVerifyReport{DryRun: dryRun, Jobs: len(jobs)}
+
+	ids := []int{}
+	for _, job := range jobs {
+		if job.Job != nil {
+			ids = append(ids, job.Job.MessageID)
+		}
+	}
+	statuses, err := v.messageRepo.GetStatuses(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	queued := map[int]bool{}
+	for _, job := range jobs {
+		var reason string
+		switch status, exists := statuses[jobMessageID(job)]; {
+		case job.Job == nil:
+			report.Undecodable++
+			reason = "not a message job"
+		case !exists:
+			report.OrphanJobs = append(report.OrphanJobs, job.Job.MessageID)
+			reason = fmt.Sprintf("message %d does not exist", job.Job.MessageID)
+		case status != models.MessageStatusPending:
+			report.Stale = append(report.Stale, job.Job.MessageID)
+			queued[job.Job.MessageID] = true
+		default:
+			report.Matched++
+			queued[job.Job.MessageID] = true
+		}
+
+		if reason != "" && !dryRun {
+			err = drain.Quarantine(job, reason)
+		} else {
+			err = drain.Requeue(job)
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to resolve job for message %d: %v", jobMessageID(job), err)
+			report.Failed++
+		}
+	}
+
+	messages, err := v.messageRepo.ListQueued(ctx, publishedBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	republished := []int{}
+	for _, message := range messages {
+		if queued[message.ID] {
+			continue
+		}
+		report.OrphanRows = append(report.OrphanRows, message.ID)
+		if dryRun {
+			continue
+		}
+		if err := publish(message); err != nil {
+			log.Printf("Warning: Failed to republish message %d: %v", message.ID, err)
+			report.Failed++
+			continue
+		}
+		republished = append(republished, message.ID)
+	}
+	// Restart the grace period so a rerun does not publish them again
+	if err := v.messageRepo.MarkPublished(ctx, republished); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// jobMessageID returns the message a job is for, 0 when it is not a message job
+func jobMessageID(job *queue.QueuedJob) int {
+	if job.Job == nil {
+		return 0
+	}
+	return job.Job.MessageID
+}
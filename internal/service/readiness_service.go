@@ -0,0 +1,480 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// ReadinessTimeout bounds how long the readiness checks may run together
+const ReadinessTimeout = 3 * time.Second
+
+// RecentOverlapWindow is how far back other campaigns to the same audience are looked for
+const RecentOverlapWindow = 24 * time.Hour
+
+// ContactWindowWarnFraction is the share of the audience outside their contact window at send time
+// above which readiness warns
+const ContactWindowWarnFraction = 0.2
+
+// CanaryFailFraction is the share of a canary's messages failed above which readiness fails,
+// so the rest of the audience is not sent without a look at what went wrong
+const CanaryFailFraction = 0.1
+
+// Readiness check outcomes
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// Readiness check names
+const (
+	CheckTemplateValid       = "template_valid"
+	CheckTemplateLiteralText = "template_literal_text"
+	CheckAudience            = "audience"
+	CheckQuietHours          = "quiet_hours"
+	CheckSenderPipeline      = "sender_pipeline"
+	CheckRecentOverlap       = "recent_overlap"
+	CheckContactWindows      = "contact_windows"
+	CheckCanary              = "canary"
+	CheckChannelSupported    = "channel_supported"
+)
+
+// QueueChecker reports whether the queue feeding the sender is reachable
+type QueueChecker interface {
+	CheckQueue() string
+}
+
+// ReadinessService runs the pre-send checklist for a campaign
+type ReadinessService struct {
+	campaignRepo repository.CampaignRepository
+	customerRepo repository.CustomerRepository
+	messageRepo  repository.MessageRepository
+	templateSvc  *TemplateService
+	queue        QueueChecker
+	quiet        config.QuietHoursConfig
+	timeout      time.Duration
+	estimator    *DeliveryEstimator
+	channels     *ChannelSupport
+}
+
+// NewReadinessService creates a new readiness service
+// queue is optional; without it the sender pipeline check is a warning
+func NewReadinessService(
+	campaignRepo repository.CampaignRepository,
+	customerRepo repository.CustomerRepository,
+	messageRepo repository.MessageRepository,
+	templateSvc *TemplateService,
+	queue QueueChecker,
+	quiet config.QuietHoursConfig,
+) *ReadinessService {
+	return &ReadinessService{
+		campaignRepo: campaignRepo,
+		customerRepo: customerRepo,
+		messageRepo:  messageRepo,
+		templateSvc:  templateSvc,
+		queue:        queue,
+		quiet:        quiet,
+		timeout:      ReadinessTimeout,
+	}
+}
+
+// SetTimeout overrides ReadinessTimeout (for testing)
+func (s *ReadinessService) SetTimeout(timeout time.Duration) {
+	s.timeout = timeout
+}
+
+// SetDeliveryEstimator sets the estimator of the campaign message's delivery and cost
+// Results carry no delivery estimate until this is called
+func (s *ReadinessService) SetDeliveryEstimator(estimator *DeliveryEstimator) {
+	s.estimator = estimator
+}
+
+// SetChannelSupport sets the channels the sender provider delivers on; the checklist then
+// checks the campaign's channel and lists the supported ones
+func (s *ReadinessService) SetChannelSupport(channels *ChannelSupport) {
+	s.channels = channels
+}
+
+// ReadinessCheck is one item of the pre-send checklist
+type ReadinessCheck struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// ReadinessResult is the pre-send checklist for a campaign
+// Ready is false when any check fails; warnings do not block sending
+type ReadinessResult struct {
+	CampaignID       int               `json:"campaign_id"`
+	Ready            bool              `json:"ready"`
+	Checks           []*ReadinessCheck `json:"checks"`
+	DeliveryEstimate *DeliveryEstimate `json:"delivery_estimate,omitempty"`
+	// SupportedChannels is what the sender provider delivers on, so the UI can hide the rest
+	SupportedChannels []models.Channel `json:"supported_channels,omitempty"`
+}
+
+// readinessCheckFunc runs one check
+type readinessCheckFunc func(ctx context.Context) *ReadinessCheck
+
+// CheckReadiness runs every check concurrently and returns them in a fixed order
+// customerIDs is the intended audience; when empty the send plan of a campaign awaiting approval,
+// or the audience a canary holds back, is used. A campaign holding a canary also has its canary checked
+func (s *ReadinessService) CheckReadiness(ctx context.Context, campaignID int, customerIDs []int) (*ReadinessResult, error) {
+	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	// A campaign awaiting approval or holding a canary already has its audience stored
+	holdingCanary := campaign.Status == models.CampaignStatusCanary
+	if len(customerIDs) == 0 && (campaign.Status == models.CampaignStatusPendingApproval || holdingCanary) {
+		plan, err := s.campaignRepo.GetSendPlan(ctx, campaignID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get send plan: %w", err)
+		}
+		customerIDs = plan.CustomerIDs
+	}
+
+	estimate, err := s.estimateDelivery(ctx, campaign, customerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The audience is loaded once, by whichever check needs it first
+	audience := &readinessAudience{repo: s.customerRepo, customerIDs: customerIDs}
+
+	names := []string{
+		CheckTemplateValid,
+		CheckTemplateLiteralText,
+		CheckAudience,
+		CheckQuietHours,
+		CheckSenderPipeline,
+		CheckRecentOverlap,
+		CheckContactWindows,
+	}
+	checks := map[string]readinessCheckFunc{
+		CheckTemplateValid:       func(ctx context.Context) *ReadinessCheck { return s.checkTemplateValid(campaign) },
+		CheckTemplateLiteralText: func(ctx context.Context) *ReadinessCheck { return s.checkLiteralText(campaign) },
+		CheckAudience:            func(ctx context.Context) *ReadinessCheck { return s.checkAudience(ctx, audience) },
+		CheckQuietHours:          func(ctx context.Context) *ReadinessCheck { return s.checkQuietHours(campaign) },
+		CheckSenderPipeline:      func(ctx context.Context) *ReadinessCheck { return s.checkSenderPipeline() },
+		CheckRecentOverlap:       func(ctx context.Context) *ReadinessCheck { return s.checkRecentOverlap(ctx, campaign, customerIDs) },
+		CheckContactWindows:      func(ctx context.Context) *ReadinessCheck { return s.checkContactWindows(ctx, campaign, audience) },
+		CheckCanary:              func(ctx context.Context) *ReadinessCheck { return s.checkCanary(ctx, campaign) },
+	}
+	if holdingCanary {
+		names = append(names, CheckCanary)
+	}
+	if s.channels != nil {
+		names = append(names, CheckChannelSupported)
+		checks[CheckChannelSupported] = func(ctx context.Context) *ReadinessCheck { return s.checkChannelSupported(campaign) }
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	type outcome struct {
+		index int
+		check *ReadinessCheck
+	}
+	done := make(chan outcome, len(names))
+	for i, name := range names {
+		go func(i int, run readinessCheckFunc) {
+			done <- outcome{index: i, check: run(ctx)}
+		}(i, checks[name])
+	}
+
+	result := &ReadinessResult{
+		CampaignID:       campaignID,
+		Ready:            true,
+		Checks:           make([]*ReadinessCheck, len(names)),
+		DeliveryEstimate: estimate,
+	}
+	if s.channels != nil {
+		result.SupportedChannels = s.channels.Channels
+	}
+
+	for remaining := len(names); remaining > 0; remaining-- {
+		select {
+		case o := <-done:
+			result.Checks[o.index] = o.check
+		case <-ctx.Done():
+			remaining = 0
+		}
+	}
+
+	for i, check := range result.Checks {
+		if check == nil {
+			result.Checks[i] = &ReadinessCheck{
+				Check:  names[i],
+				Status: CheckWarn,
+				Detail: fmt.Sprintf("check did not finish within %s", s.timeout),
+			}
+		}
+		if result.Checks[i].Status == CheckFail {
+			result.Ready = false
+		}
+	}
+
+	return result, nil
+}
+
+// estimateDelivery estimates the message as rendered for the first planned customer
+// A template that does not render is reported by the template check, not estimated
+func (s *ReadinessService) estimateDelivery(ctx context.Context, campaign *models.Campaign, customerIDs []int) (*DeliveryEstimate, error) {
+	if s.estimator == nil {
+		return nil, nil
+	}
+
+	var customers []*models.Customer
+	if len(customerIDs) > 0 {
+		var err error
+		customers, err = s.customerRepo.GetByIDs(ctx, customerIDs[:1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get customers: %w", err)
+		}
+	}
+
+	estimate, err := s.estimator.EstimateFor(campaign, campaign.BaseTemplate, firstPlannedCustomer(customers, customerIDs), len(customerIDs))
+	if err != nil {
+		return nil, nil
+	}
+	return estimate, nil
+}
+
+// checkTemplateValid validates the template syntax and length
+func (s *ReadinessService) checkTemplateValid(campaign *models.Campaign) *ReadinessCheck {
+	if err := s.templateSvc.ForCampaign(campaign).ValidateTemplate(campaign.BaseTemplate); err != nil {
+		return &ReadinessCheck{Check: CheckTemplateValid, Status: CheckFail, Detail: err.Error()}
+	}
+	return &ReadinessCheck{Check: CheckTemplateValid, Status: CheckPass, Detail: "template is valid"}
+}
+
+// checkLiteralText warns when the template is nothing but placeholders
+func (s *ReadinessService) checkLiteralText(campaign *models.Campaign) *ReadinessCheck {
+	literal := campaign.BaseTemplate
+	for _, placeholder := range s.templateSvc.ForCampaign(campaign).GetPlaceholders(literal) {
+		literal = strings.ReplaceAll(literal, placeholder, "")
+	}
+
+	for _, r := range literal {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return &ReadinessCheck{Check: CheckTemplateLiteralText, Status: CheckPass, Detail: "template has text besides placeholders"}
+		}
+	}
+	return &ReadinessCheck{
+		Check:  CheckTemplateLiteralText,
+		Status: CheckWarn,
+		Detail: "template has no text besides placeholders",
+	}
+}
+
+// readinessAudience is the intended audience, shared by the checks that run concurrently
+// The customers are loaded on first use and the load is not repeated
+type readinessAudience struct {
+	repo        repository.CustomerRepository
+	customerIDs []int
+
+	once      sync.Once
+	customers []*models.Customer
+	err       error
+}
+
+// load returns the audience's customers, waiting for a load another check started
+func (a *readinessAudience) load(ctx context.Context) ([]*models.Customer, error) {
+	a.once.Do(func() {
+		a.customers, a.err = a.repo.GetByIDs(ctx, a.customerIDs)
+	})
+	return a.customers, a.err
+}
+
+// checkAudience resolves the intended audience
+func (s *ReadinessService) checkAudience(ctx context.Context, audience *readinessAudience) *ReadinessCheck {
+	customerIDs := audience.customerIDs
+	if len(customerIDs) == 0 {
+		return &ReadinessCheck{
+			Check:  CheckAudience,
+			Status: CheckWarn,
+			Detail: "no audience given: pass customer_ids to check it",
+		}
+	}
+
+	customers, err := audience.load(ctx)
+	if err != nil {
+		return &ReadinessCheck{Check: CheckAudience, Status: CheckFail, Detail: "audience could not be resolved"}
+	}
+
+	switch {
+	case len(customers) == 0:
+		return &ReadinessCheck{Check: CheckAudience, Status: CheckFail, Detail: "none of the customers exist"}
+	case len(customers) < len(customerIDs):
+		return &ReadinessCheck{
+			Check:  CheckAudience,
+			Status: CheckWarn,
+			Detail: fmt.Sprintf("%d of %d customers exist", len(customers), len(customerIDs)),
+		}
+	default:
+		return &ReadinessCheck{Check: CheckAudience, Status: CheckPass, Detail: fmt.Sprintf("%d customers", len(customers))}
+	}
+}
+
+// checkCanary reports how the canary of a campaign holding one fared: it fails when too many
+// canary messages failed and warns while any are still pending or some failed
+func (s *ReadinessService) checkCanary(ctx context.Context, campaign *models.Campaign) *ReadinessCheck {
+	stats, err := s.campaignRepo.GetWithStats(ctx, campaign.ID)
+	if err != nil || stats.Canary == nil {
+		return &ReadinessCheck{Check: CheckCanary, Status: CheckWarn, Detail: "canary results could not be loaded"}
+	}
+
+	canary := stats.Canary
+	switch {
+	case canary.Messages > 0 && float64(canary.Failed)/float64(canary.Messages) > CanaryFailFraction:
+		return &ReadinessCheck{
+			Check:  CheckCanary,
+			Status: CheckFail,
+			Detail: fmt.Sprintf("%d of %d canary messages failed", canary.Failed, canary.Messages),
+		}
+	case canary.Pending > 0:
+		return &ReadinessCheck{
+			Check:  CheckCanary,
+			Status: CheckWarn,
+			Detail: fmt.Sprintf("%d of %d canary messages are still pending", canary.Pending, canary.Messages),
+		}
+	case canary.Failed > 0:
+		return &ReadinessCheck{
+			Check:  CheckCanary,
+			Status: CheckWarn,
+			Detail: fmt.Sprintf("%d of %d canary messages failed", canary.Failed, canary.Messages),
+		}
+	default:
+		return &ReadinessCheck{Check: CheckCanary, Status: CheckPass, Detail: fmt.Sprintf("all %d canary messages sent", canary.Messages)}
+	}
+}
+
+// checkQuietHours warns when the campaign would go out during quiet hours
+func (s *ReadinessService) checkQuietHours(campaign *models.Campaign) *ReadinessCheck {
+	if !s.quiet.Enabled {
+		return &ReadinessCheck{Check: CheckQuietHours, Status: CheckPass, Detail: "no quiet hours configured"}
+	}
+
+	sendAt := time.Now()
+	when := "now"
+	if campaign.ScheduledAt != nil {
+		sendAt = *campaign.ScheduledAt
+		when = "scheduled_at"
+	}
+
+	if s.quiet.Contains(sendAt) {
+		return &ReadinessCheck{
+			Check:  CheckQuietHours,
+			Status: CheckWarn,
+			Detail: fmt.Sprintf("%s falls within quiet hours (%02d:00-%02d:00 %s)", when, s.quiet.Start, s.quiet.End, s.quiet.Location),
+		}
+	}
+	return &ReadinessCheck{Check: CheckQuietHours, Status: CheckPass, Detail: fmt.Sprintf("%s is outside quiet hours", when)}
+}
+
+// checkSenderPipeline confirms the queue the worker sends from is reachable
+// The provider itself sits behind the worker and is not probed from the API
+func (s *ReadinessService) checkSenderPipeline() *ReadinessCheck {
+	if s.queue == nil {
+		return &ReadinessCheck{Check: CheckSenderPipeline, Status: CheckWarn, Detail: "queue not checked"}
+	}
+	if s.queue.CheckQueue() != StatusConnected {
+		return &ReadinessCheck{Check: CheckSenderPipeline, Status: CheckFail, Detail: "message queue is unreachable"}
+	}
+	return &ReadinessCheck{Check: CheckSenderPipeline, Status: CheckPass, Detail: "message queue is reachable"}
+}
+
+// checkChannelSupported fails a campaign on a channel the sender provider cannot deliver on
+func (s *ReadinessService) checkChannelSupported(campaign *models.Campaign) *ReadinessCheck {
+	if err := s.channels.Check(campaign.Channel); err != nil {
+		return &ReadinessCheck{Check: CheckChannelSupported, Status: CheckFail, Detail: err.Error()}
+	}
+	return &ReadinessCheck{
+		Check:  CheckChannelSupported,
+		Status: CheckPass,
+		Detail: fmt.Sprintf("sender provider %q delivers %s messages", s.channels.Provider, campaign.Channel),
+	}
+}
+
+// checkRecentOverlap warns when the audience was messaged by another campaign recently
+func (s *ReadinessService) checkRecentOverlap(ctx context.Context, campaign *models.Campaign, customerIDs []int) *ReadinessCheck {
+	if len(customerIDs) == 0 {
+		return &ReadinessCheck{Check: CheckRecentOverlap, Status: CheckWarn, Detail: "no audience given"}
+	}
+
+	count, err := s.messageRepo.CountRecentRecipients(ctx, customerIDs, campaign.ID, time.Now().Add(-RecentOverlapWindow))
+	if err != nil {
+		return &ReadinessCheck{Check: CheckRecentOverlap, Status: CheckWarn, Detail: "recent campaigns could not be checked"}
+	}
+
+	if count > 0 {
+		return &ReadinessCheck{
+			Check:  CheckRecentOverlap,
+			Status: CheckWarn,
+			Detail: fmt.Sprintf("%d of %d customers were messaged by another campaign in the last 24h", count, len(customerIDs)),
+		}
+	}
+	return &ReadinessCheck{Check: CheckRecentOverlap, Status: CheckPass, Detail: "no other campaign reached this audience in the last 24h"}
+}
+
+// checkContactWindows warns when much of the audience would be outside their contact window at send time
+// Those messages are not lost, but the worker defers them until each window opens
+func (s *ReadinessService) checkContactWindows(ctx context.Context, campaign *models.Campaign, audience *readinessAudience) *ReadinessCheck {
+	if len(audience.customerIDs) == 0 {
+		return &ReadinessCheck{Check: CheckContactWindows, Status: CheckWarn, Detail: "no audience given"}
+	}
+
+	customers, err := audience.load(ctx)
+	if err != nil {
+		return &ReadinessCheck{Check: CheckContactWindows, Status: CheckWarn, Detail: "contact windows could not be checked"}
+	}
+	if len(customers) == 0 {
+		return &ReadinessCheck{Check: CheckContactWindows, Status: CheckPass, Detail: "no customers to check"}
+	}
+
+	sendAt := time.Now()
+	when := "now"
+	if campaign.ScheduledAt != nil {
+		sendAt = *campaign.ScheduledAt
+		when = "scheduled_at"
+	}
+
+	zone := s.quiet.Location
+	if zone == nil {
+		zone = time.UTC
+	}
+
+	outside := 0
+	for _, customer := range customers {
+		window, err := customer.ContactWindow()
+		if err != nil || window == nil {
+			continue
+		}
+		if !window.Contains(sendAt, zone) {
+			outside++
+		}
+	}
+
+	if outside > 0 && float64(outside) > ContactWindowWarnFraction*float64(len(customers)) {
+		return &ReadinessCheck{
+			Check:  CheckContactWindows,
+			Status: CheckWarn,
+			Detail: fmt.Sprintf("%d of %d customers are outside their contact window at %s and will be deferred", outside, len(customers), when),
+		}
+	}
+	return &ReadinessCheck{
+		Check:  CheckContactWindows,
+		Status: CheckPass,
+		Detail: fmt.Sprintf("%d of %d customers are outside their contact window at %s", outside, len(customers), when),
+	}
+}
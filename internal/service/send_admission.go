@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// QueueDepthFunc reports how many jobs are waiting in the send queue (a *queue.Publisher's Depth)
+type QueueDepthFunc func() (int, error)
+
+// QueueStatus is the send backlog against the thresholds new sends are admitted under
+type QueueStatus struct {
+	QueueDepth     *int   `json:"queue_depth"`           // Jobs waiting in the send queue; nil when the broker could not be asked
+	QueueError     string `json:"queue_error,omitempty"` // Why the broker could not be asked
+	Unpublished    int    `json:"unpublished"`           // Pending messages never published to the queue
+	MaxQueueDepth  int    `json:"max_queue_depth"`
+	MaxUnpublished int    `json:"max_unpublished"`
+	Saturated      bool   `json:"saturated"` // New sends are refused
+
+	Dispatch models.DispatchOrder `json:"dispatch"` // Order workers claim never-published messages in
+}
+
+// SendAdmission refuses new sends while the send queue or the never-published backlog is
+// over its threshold, so a backed-up system is not buried deeper
+type SendAdmission struct {
+	depth       QueueDepthFunc
+	messageRepo repository.MessageRepository
+	cfg         config.BackpressureConfig
+	dispatch    models.DispatchOrder
+}
+
+// NewSendAdmission creates an admission check over the queue depth and the database backlog
+func NewSendAdmission(depth QueueDepthFunc, messageRepo repository.MessageRepository, cfg config.BackpressureConfig) *SendAdmission {
+	return &SendAdmission{
+		depth:       depth,
+		messageRepo: messageRepo,
+		cfg:         cfg,
+		dispatch:    models.DispatchOrder{Policy: models.DispatchFIFO},
+	}
+}
+
+// SetDispatchOrder sets the dispatch order reported with the backlog; the workers read the
+// same configuration
+func (a *SendAdmission) SetDispatchOrder(order models.DispatchOrder) {
+	a.dispatch = order
+}
+
+// Status reports the send backlog; a broker that cannot be asked leaves the depth unknown
+// rather than failing, so the database backlog is still reported and checked
+func (a *SendAdmission) Status(ctx context.Context) (*QueueStatus, error) {
+	unpublished, err := a.messageRepo.CountUnpublished(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &QueueStatus{
+		Unpublished:    unpublished,
+		MaxQueueDepth:  a.cfg.MaxQueueDepth,
+		MaxUnpublished: a.cfg.MaxUnpublished,
+		Dispatch:       a.dispatch,
+	}
+	if depth, err := a.depth(); err != nil {
+		status.QueueError = err.Error()
+	} else {
+		status.QueueDepth = &depth
+	}
+
+	overDepth := a.cfg.MaxQueueDepth > 0 && status.QueueDepth != nil && *status.QueueDepth > a.cfg.MaxQueueDepth
+	overUnpublished := a.cfg.MaxUnpublished > 0 && status.Unpublished > a.cfg.MaxUnpublished
+	status.Saturated = overDepth || overUnpublished
+
+	return status, nil
+}
+
+// Check returns a *QueueSaturatedError when the backlog is over a threshold
+// Sends are let through when the backlog cannot be measured; a nil *SendAdmission admits everything
+func (a *SendAdmission) Check(ctx context.Context) error {
+	if a == nil {
+		return nil
+	}
+
+	status, err := a.Status(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to check send backlog, admitting send: %v", err)
+		return nil
+	}
+	if status.QueueError != "" {
+		log.Printf("Warning: Failed to check send queue depth: %s", status.QueueError)
+	}
+	if status.Saturated {
+		return &QueueSaturatedError{Status: status, RetryAfter: a.cfg.RetryAfter}
+	}
+
+	return nil
+}
@@ -1,72 +1,138 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 
 	"smsleopard/internal/models"
 )
 
+// Sender delivers a rendered message through a provider
+type Sender interface {
+	Send(channel models.Channel, phone string, content string, opts SenderOptions) *SendResult
+	// Capabilities returns the channels the provider can deliver on
+	Capabilities() []models.Channel
+}
+
+// SenderOptions adjusts a single send
+type SenderOptions struct {
+	// FailureRate replaces the mock sender's failure rate for this send (nil keeps the configured
+	// rate), so a demo campaign can visibly fail; the worker only sets it outside production
+	FailureRate *float64
+}
+
 // SenderService handles message sending
+// By default each send waits a random latency and succeeds at the configured rate;
+// a scripted sender returns given results in order instead, for deterministic tests
+// Sends may run concurrently with each other and with SetSuccessRate
 type SenderService struct {
+	noLatency bool // Skip the simulated network latency
+
+	mu          sync.Mutex
 	successRate float64 // 0.0 to 1.0 (e.g., 0.95 = 95% success)
 	rand        *rand.Rand
+	script      []SendResult // Results returned in order (nil in random mode)
+	calls       int
 }
 
 // NewSenderService creates a new sender service
 // successRate: probability of successful send (0.0 to 1.0)
 // Default: 0.95 (95% success rate)
 func NewSenderService(successRate float64) *SenderService {
-	if successRate < 0.0 {
-		successRate = 0.0
-	}
-	if successRate > 1.0 {
-		successRate = 1.0
+	return &SenderService{
+		successRate: clampRate(successRate),
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
+}
 
+// NewSenderServiceScripted creates a sender that returns results in order, one per call, without latency
+// A failed result's Error is reported as the provider's reason, e.g. "rate limit exceeded";
+// calls beyond the script fail with ErrSenderScriptExhausted
+func NewSenderServiceScripted(results ...SendResult) *SenderService {
 	return &SenderService{
-		successRate: successRate,
-		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		script:    results,
+		noLatency: true,
 	}
 }
 
+// ErrSenderScriptExhausted is returned by a scripted sender called more times than it has results
+var ErrSenderScriptExhausted = errors.New("sender script exhausted")
+
+// WithNoLatency makes sends return immediately instead of waiting a simulated network latency
+func (s *SenderService) WithNoLatency() *SenderService {
+	s.noLatency = true
+	return s
+}
+
+// Calls returns how many sends have been made
+func (s *SenderService) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
 // SendResult represents the result of a send attempt
 type SendResult struct {
-	Success bool
-	Error   error
-	Latency time.Duration
+	Success   bool
+	Error     error
+	Latency   time.Duration
+	Simulated bool // No provider was called
 }
 
 // SendSMS simulates sending an SMS message
 func (s *SenderService) SendSMS(phone string, content string) *SendResult {
-	return s.send("SMS", phone, content)
+	return s.send("SMS", phone, content, SenderOptions{})
 }
 
 // SendWhatsApp simulates sending a WhatsApp message
 func (s *SenderService) SendWhatsApp(phone string, content string) *SendResult {
-	return s.send("WhatsApp", phone, content)
+	return s.send("WhatsApp", phone, content, SenderOptions{})
 }
 
 // Send sends a message via the specified channel
-func (s *SenderService) Send(channel models.Channel, phone string, content string) *SendResult {
+func (s *SenderService) Send(channel models.Channel, phone string, content string, opts SenderOptions) *SendResult {
 	if channel == models.ChannelSMS {
-		return s.SendSMS(phone, content)
+		return s.send("SMS", phone, content, opts)
 	}
-	return s.SendWhatsApp(phone, content)
+	return s.send("WhatsApp", phone, content, opts)
+}
+
+// Capabilities returns both channels; the mock delivers on either
+func (s *SenderService) Capabilities() []models.Channel {
+	return []models.Channel{models.ChannelSMS, models.ChannelWhatsApp}
 }
 
 // send is the internal mock implementation
-func (s *SenderService) send(channelType string, phone string, content string) *SendResult {
+func (s *SenderService) send(channelType string, phone string, content string, opts SenderOptions) *SendResult {
+	s.mu.Lock()
+	call := s.calls
+	s.calls++
+	if s.script != nil {
+		s.mu.Unlock()
+		return s.scripted(call, channelType, phone)
+	}
+
+	// Draw the latency and outcome up front; rand.Rand is not safe for concurrent use
+	latency := time.Duration(50+s.rand.Intn(150)) * time.Millisecond
+	randomValue := s.rand.Float64()
+	successRate := s.successRate
+	if opts.FailureRate != nil {
+		successRate = 1 - clampRate(*opts.FailureRate)
+	}
+	s.mu.Unlock()
+
 	start := time.Now()
 
 	// Simulate network latency (50-200ms)
-	latency := time.Duration(50+s.rand.Intn(150)) * time.Millisecond
-	time.Sleep(latency)
+	if !s.noLatency {
+		time.Sleep(latency)
+	}
 
-	// Determine success based on configured success rate
-	randomValue := s.rand.Float64()
-	success := randomValue < s.successRate
+	// Determine success based on the success rate
+	success := randomValue < successRate
 
 	result := &SendResult{
 		Success: success,
@@ -82,25 +148,53 @@ func (s *SenderService) send(channelType string, phone string, content string) *
 			"service temporarily unavailable",
 			"insufficient balance",
 		}
+		s.mu.Lock()
 		failureReason := failures[s.rand.Intn(len(failures))]
+		s.mu.Unlock()
 		result.Error = fmt.Errorf("failed to send %s to %s: %s", channelType, phone, failureReason)
 	}
 
 	return result
 }
 
+// scripted returns the scripted result for a call, with failures worded like random ones
+func (s *SenderService) scripted(call int, channelType string, phone string) *SendResult {
+	if call >= len(s.script) {
+		return &SendResult{Error: fmt.Errorf("%w after %d calls", ErrSenderScriptExhausted, len(s.script))}
+	}
+
+	result := s.script[call]
+	if !result.Success {
+		reason := result.Error
+		if reason == nil {
+			reason = errors.New("scripted failure")
+		}
+		result.Error = fmt.Errorf("failed to send %s to %s: %w", channelType, phone, reason)
+	}
+	return &result
+}
+
 // GetSuccessRate returns the configured success rate
 func (s *SenderService) GetSuccessRate() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.successRate
 }
 
-// SetSuccessRate updates the success rate (for testing)
+// SetSuccessRate updates the success rate, including while sends are in flight
 func (s *SenderService) SetSuccessRate(rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.successRate = clampRate(rate)
+}
+
+// clampRate limits a rate to between 0.0 and 1.0
+func clampRate(rate float64) float64 {
 	if rate < 0.0 {
-		rate = 0.0
+		return 0.0
 	}
 	if rate > 1.0 {
-		rate = 1.0
+		return 1.0
 	}
-	s.successRate = rate
+	return rate
 }
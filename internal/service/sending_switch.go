@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// SendingSwitchCacheTTL is how long the sending_enabled setting, once read, is trusted before
+// it is read again; a change reaches every worker within it
+const SendingSwitchCacheTTL = 5 * time.Second
+
+// SendingDisabledDeferral is how long the worker defers a message it finds while sending is
+// disabled; it is picked up again then and sent, or deferred again
+const SendingDisabledDeferral = time.Minute
+
+// Sending switch limits
+const (
+	MaxSendingReasonLength = 1000
+	SendingHistoryLimit    = 20
+)
+
+// SendingStatus is the kill switch for all outbound sending and its recent changes
+type SendingStatus struct {
+	SendingEnabled bool                    `json:"sending_enabled"`
+	UpdatedBy      *string                 `json:"updated_by,omitempty"`
+	UpdatedAt      *time.Time              `json:"updated_at,omitempty"`
+	History        []*models.SettingChange `json:"history"`
+}
+
+// SetSendingRequest enables or disables all outbound sending
+type SetSendingRequest struct {
+	Reason string `json:"reason"`
+	Actor  string `json:"-"` // Authenticated caller, for the audit log
+}
+
+// SendingSwitch is the kill switch for all outbound sending, stored in the settings table so
+// it survives restarts and applies to the API and every worker
+// Reads are cached for SendingSwitchCacheTTL; a nil *SendingSwitch is always enabled
+type SendingSwitch struct {
+	repo repository.SettingsRepository
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	enabled bool
+	setting *models.Setting // Last read; nil before the first read or when never stored
+	readAt  time.Time
+	read    bool
+}
+
+// NewSendingSwitch creates a switch over the sending_enabled setting
+func NewSendingSwitch(repo repository.SettingsRepository) *SendingSwitch {
+	return &SendingSwitch{
+		repo:    repo,
+		ttl:     SendingSwitchCacheTTL,
+		now:     time.Now,
+		enabled: true,
+	}
+}
+
+// SetClock overrides time.Now (for testing)
+func (s *SendingSwitch) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Enabled reports whether outbound sending is on, reading the setting at most once per TTL
+// A setting that cannot be read keeps the last known state (enabled before the first read)
+func (s *SendingSwitch) Enabled(ctx context.Context) bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.read && now.Sub(s.readAt) < s.ttl {
+		return s.enabled
+	}
+
+	setting, err := s.repo.Get(ctx, models.SettingSendingEnabled)
+	if err != nil && !errors.Is(err, repository.ErrSettingNotFound) {
+		log.Printf("Warning: Failed to read sending switch, keeping sending_enabled=%t: %v", s.enabled, err)
+		return s.enabled
+	}
+	s.store(setting, now)
+	return s.enabled
+}
+
+// store caches a read or written setting; one never stored is enabled
+func (s *SendingSwitch) store(setting *models.Setting, readAt time.Time) {
+	s.setting = setting
+	s.enabled = setting == nil || setting.Value != "false"
+	s.readAt = readAt
+	s.read = true
+}
+
+// Check returns a *SendingDisabledError, with why and by whom, while sending is disabled
+func (s *SendingSwitch) Check(ctx context.Context) error {
+	if s.Enabled(ctx) {
+		return nil
+	}
+
+	disabled := &SendingDisabledError{}
+	s.mu.Lock()
+	if s.setting != nil {
+		disabled.DisabledBy = s.setting.UpdatedBy
+	}
+	s.mu.Unlock()
+
+	changes, err := s.repo.ListChanges(ctx, models.SettingSendingEnabled, 1)
+	if err != nil {
+		log.Printf("Warning: Failed to read why sending was disabled: %v", err)
+	} else if len(changes) > 0 {
+		disabled.Reason = changes[0].Reason
+	}
+	return disabled
+}
+
+// Status reads the switch afresh with its recent changes, newest first
+func (s *SendingSwitch) Status(ctx context.Context) (*SendingStatus, error) {
+	setting, err := s.repo.Get(ctx, models.SettingSendingEnabled)
+	if err != nil && !errors.Is(err, repository.ErrSettingNotFound) {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.store(setting, s.now())
+	s.mu.Unlock()
+
+	changes, err := s.repo.ListChanges(ctx, models.SettingSendingEnabled, SendingHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &SendingStatus{SendingEnabled: true, History: changes}
+	if setting != nil {
+		status.SendingEnabled = setting.Value != "false"
+		status.UpdatedBy = setting.UpdatedBy
+		status.UpdatedAt = &setting.UpdatedAt
+	}
+	return status, nil
+}
+
+// Enable turns outbound sending back on, recording why and who turned it on
+func (s *SendingSwitch) Enable(ctx context.Context, req *SetSendingRequest) (*SendingStatus, error) {
+	return s.set(ctx, true, req)
+}
+
+// Disable stops all outbound sending: new sends are refused and workers defer every message
+// they pick up until sending is enabled again. Why and who disabled it are recorded
+func (s *SendingSwitch) Disable(ctx context.Context, req *SetSendingRequest) (*SendingStatus, error) {
+	return s.set(ctx, false, req)
+}
+
+// set changes the switch, refusing a change to the state it is already in
+func (s *SendingSwitch) set(ctx context.Context, enabled bool, req *SetSendingRequest) (*SendingStatus, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, &ValidationError{Message: "reason is required"}
+	}
+	if len([]rune(reason)) > MaxSendingReasonLength {
+		return nil, &ValidationError{Message: fmt.Sprintf("reason must be at most %d characters", MaxSendingReasonLength)}
+	}
+
+	current, err := s.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sending switch: %w", err)
+	}
+	if current.SendingEnabled == enabled {
+		state := "disabled"
+		if enabled {
+			state = "enabled"
+		}
+		return nil, &ConflictError{Resource: "sending", Message: "sending is already " + state}
+	}
+
+	setting, err := s.repo.Set(ctx, models.SettingSendingEnabled, fmt.Sprintf("%t", enabled), reason, optionalActor(req.Actor))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set sending switch: %w", err)
+	}
+
+	// This process sees the change at once; other processes within the TTL
+	s.mu.Lock()
+	s.store(setting, s.now())
+	s.mu.Unlock()
+
+	return s.Status(ctx)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+)
+
+// SimulatedSender reports every message as sent without calling a provider
+// It is used by the worker in simulate mode so staging never sends real messages
+type SimulatedSender struct {
+	meanLatency time.Duration
+	jitter      time.Duration
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewSimulatedSender creates a sender whose latency is normally distributed around meanLatency
+func NewSimulatedSender(meanLatency, jitter time.Duration) *SimulatedSender {
+	return &SimulatedSender{
+		meanLatency: meanLatency,
+		jitter:      jitter,
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// NewSender returns the sender for the configured worker mode
+// In simulate mode the live sender is never called
+func NewSender(cfg config.WorkerConfig, live Sender) Sender {
+	if cfg.Mode == config.WorkerModeSimulate {
+		return NewSimulatedSender(
+			time.Duration(cfg.SimulatedLatencyMs)*time.Millisecond,
+			time.Duration(cfg.SimulatedLatencyJitterMs)*time.Millisecond,
+		)
+	}
+	return live
+}
+
+// Send waits a synthetic latency and reports success; options are ignored
+func (s *SimulatedSender) Send(channel models.Channel, phone string, content string, opts SenderOptions) *SendResult {
+	latency := s.latency()
+	time.Sleep(latency)
+
+	return &SendResult{
+		Success:   true,
+		Latency:   latency,
+		Simulated: true,
+	}
+}
+
+// Capabilities returns both channels; nothing is delivered on either
+func (s *SimulatedSender) Capabilities() []models.Channel {
+	return []models.Channel{models.ChannelSMS, models.ChannelWhatsApp}
+}
+
+// latency draws a latency from the configured distribution, never below zero
+func (s *SimulatedSender) latency() time.Duration {
+	s.mu.Lock()
+	sample := s.rand.NormFloat64()
+	s.mu.Unlock()
+
+	latency := s.meanLatency + time.Duration(sample*float64(s.jitter))
+	if latency < 0 {
+		return 0
+	}
+	return latency
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// DefaultFailureRate is used when a channel has no delivery history in the window
+// It matches the 95% success rate of the mock sender used by the worker
+const DefaultFailureRate = 0.05
+
+// FailureRateWindow is how far back delivery history is considered
+const FailureRateWindow = 7 * 24 * time.Hour
+
+// SimulationService estimates how a campaign send will play out before launch
+type SimulationService struct {
+	campaignRepo repository.CampaignRepository
+	customerRepo repository.CustomerRepository
+	messageRepo  repository.MessageRepository
+	sending      config.SendingConfig
+	quiet        config.QuietHoursConfig
+	estimator    *DeliveryEstimator
+}
+
+// NewSimulationService creates a new simulation service
+func NewSimulationService(
+	campaignRepo repository.CampaignRepository,
+	customerRepo repository.CustomerRepository,
+	messageRepo repository.MessageRepository,
+	sending config.SendingConfig,
+	quiet config.QuietHoursConfig,
+) *SimulationService {
+	return &SimulationService{
+		campaignRepo: campaignRepo,
+		customerRepo: customerRepo,
+		messageRepo:  messageRepo,
+		sending:      sending,
+		quiet:        quiet,
+	}
+}
+
+// SetDeliveryEstimator sets the estimator of the simulated message's delivery and cost
+// Results carry no delivery estimate until this is called
+func (s *SimulationService) SetDeliveryEstimator(estimator *DeliveryEstimator) {
+	s.estimator = estimator
+}
+
+// Simulate estimates duration, failures and cost for sending a campaign
+func (s *SimulationService) Simulate(ctx context.Context, campaignID int, req *SimulateCampaignRequest) (*SimulationResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, &ValidationError{Message: err.Error()}
+	}
+
+	// Get campaign
+	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	// Resolve audience size from the planned customer list if not given directly
+	audienceSize := req.AudienceSize
+	var customers []*models.Customer
+	if audienceSize == 0 {
+		customers, err = s.customerRepo.GetByIDs(ctx, req.CustomerIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get customers: %w", err)
+		}
+		audienceSize = len(customers)
+	}
+
+	// The message is estimated as rendered for the first planned customer
+	estimate, err := s.estimator.EstimateFor(campaign, campaign.BaseTemplate, firstPlannedCustomer(customers, req.CustomerIDs), audienceSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trailing failure rate for the campaign's channel
+	since := time.Now().Add(-FailureRateWindow)
+	history, err := s.messageRepo.GetDeliveryStatsByChannel(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery history: %w", err)
+	}
+
+	failureRate := DefaultFailureRate
+	sampleSize := 0
+	for _, stat := range history {
+		if stat.Channel == campaign.Channel && stat.Total > 0 {
+			failureRate = stat.FailureRate()
+			sampleSize = stat.Total
+		}
+	}
+
+	// Throughput is bounded by both the rate limit and what the workers can process
+	ratePerSecond := s.effectiveRatePerSecond()
+	duration := 0.0
+	if ratePerSecond > 0 {
+		duration = float64(audienceSize) / ratePerSecond
+	}
+
+	expectedFailures := int(math.Round(float64(audienceSize) * failureRate))
+	costPerMessage := s.costPerMessage(campaign.Channel)
+
+	return &SimulationResult{
+		CampaignID:               campaign.ID,
+		Channel:                  campaign.Channel,
+		AudienceSize:             audienceSize,
+		EffectiveRatePerSecond:   roundTo(ratePerSecond, 2),
+		EstimatedDurationSeconds: roundTo(duration, 2),
+		FailureRate:              roundTo(failureRate, 4),
+		FailureRateSampleSize:    sampleSize,
+		ExpectedFailures:         expectedFailures,
+		ExpectedDelivered:        audienceSize - expectedFailures,
+		CostPerMessage:           costPerMessage,
+		EstimatedCost:            roundTo(float64(audienceSize)*costPerMessage, 2),
+		DeliveryEstimate:         estimate,
+	}, nil
+}
+
+// EstimateCompletion projects when a campaign's remaining messages will have been sent
+// Campaigns that have not been sent yet have no messages; audienceSize (or the
+// size of a send awaiting approval) is used for them instead
+func (s *SimulationService) EstimateCompletion(ctx context.Context, campaignID int, audienceSize int) (*CampaignETA, error) {
+	if audienceSize < 0 {
+		return nil, &ValidationError{Message: "audience_size cannot be negative"}
+	}
+
+	campaign, err := s.campaignRepo.GetWithStats(ctx, campaignID)
+	if err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	now := time.Now()
+	ratePerSecond := s.effectiveRatePerSecond()
+	eta := &CampaignETA{
+		CampaignID:             campaign.ID,
+		Status:                 campaign.Status,
+		EffectiveRatePerSecond: roundTo(ratePerSecond, 2),
+		InQuietHours:           s.quiet.Contains(now),
+	}
+
+	start := now
+	switch campaign.Status {
+	case models.CampaignStatusSending, models.CampaignStatusCanary:
+		// A canary's held back audience only goes out once it is continued
+		eta.Pending = campaign.Stats.Pending
+	case models.CampaignStatusSent, models.CampaignStatusFailed, models.CampaignStatusCancelling, models.CampaignStatusCancelled:
+		// Nothing more will be sent; undoing a cancellation makes the campaign sending again
+		return eta, nil
+	case models.CampaignStatusRecurring:
+		// Each occurrence is sent as a campaign of its own, with an ETA of its own
+		return eta, nil
+	case models.CampaignStatusPaused:
+		// Nothing completes until the campaign is resumed
+		eta.Pending = campaign.Stats.Pending
+		return eta, nil
+	case models.CampaignStatusScheduled:
+		eta.Pending = audienceSize
+		if campaign.ScheduledAt != nil && campaign.ScheduledAt.After(now) {
+			start = *campaign.ScheduledAt
+		}
+		nextDispatch := s.quiet.NextOpen(start)
+		eta.NextDispatchAt = &nextDispatch
+	case models.CampaignStatusPendingApproval:
+		eta.Pending = audienceSize
+		if eta.Pending == 0 {
+			plan, err := s.campaignRepo.GetSendPlan(ctx, campaignID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get send plan: %w", err)
+			}
+			if plan != nil {
+				eta.Pending = plan.AudienceSize
+			}
+		}
+	default:
+		eta.Pending = audienceSize
+	}
+
+	if eta.Pending == 0 {
+		return eta, nil
+	}
+
+	_, completion := ProjectCompletion(start, eta.Pending, ratePerSecond, s.quiet)
+	eta.EstimatedCompletionAt = &completion
+	eta.QuietHoursDelaySeconds = roundTo(completion.Sub(start.Add(sendDuration(eta.Pending, ratePerSecond))).Seconds(), 0)
+	return eta, nil
+}
+
+// ProjectCompletion estimates when pending messages sent at ratePerSecond from start will be done
+// Sending pauses for quiet hours; it returns when the first and last messages go out
+func ProjectCompletion(start time.Time, pending int, ratePerSecond float64, quiet config.QuietHoursConfig) (time.Time, time.Time) {
+	t := quiet.NextOpen(start)
+	firstSend := t
+	remaining := sendDuration(pending, ratePerSecond)
+
+	for {
+		pause := quiet.NextQuiet(t)
+		if pause.IsZero() || !t.Add(remaining).After(pause) {
+			return firstSend, t.Add(remaining)
+		}
+		remaining -= pause.Sub(t)
+		t = quiet.NextOpen(pause)
+	}
+}
+
+// sendDuration returns how long count messages take at ratePerSecond
+func sendDuration(count int, ratePerSecond float64) time.Duration {
+	if ratePerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(count) / ratePerSecond * float64(time.Second))
+}
+
+// effectiveRatePerSecond returns the sustained send rate for the configured workers
+func (s *SimulationService) effectiveRatePerSecond() float64 {
+	return EffectiveRatePerSecond(s.sending)
+}
+
+// EffectiveRatePerSecond returns the sustained send rate for the configured workers:
+// what they can process, capped by the rate limit
+func EffectiveRatePerSecond(sending config.SendingConfig) float64 {
+	concurrency := sending.WorkerConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	workerRate := float64(concurrency)
+	if sending.AvgSendLatencyMs > 0 {
+		workerRate = float64(concurrency) * 1000 / float64(sending.AvgSendLatencyMs)
+	}
+
+	if sending.RateLimitPerSecond > 0 && float64(sending.RateLimitPerSecond) < workerRate {
+		return float64(sending.RateLimitPerSecond)
+	}
+	return workerRate
+}
+
+// costPerMessage returns the configured price for a single message on a channel
+func (s *SimulationService) costPerMessage(channel models.Channel) float64 {
+	return costPerMessage(s.sending, channel)
+}
+
+// roundTo rounds a value to the given number of decimal places
+func roundTo(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
+}
+
+// Request/Response types
+
+// SimulateCampaignRequest represents a request to simulate a campaign send
+// Either AudienceSize or CustomerIDs must be provided
+type SimulateCampaignRequest struct {
+	AudienceSize int   `json:"audience_size,omitempty"`
+	CustomerIDs  []int `json:"customer_ids,omitempty"`
+}
+
+// Validate validates the simulate campaign request
+func (r *SimulateCampaignRequest) Validate() error {
+	if r.AudienceSize < 0 {
+		return fmt.Errorf("audience_size cannot be negative")
+	}
+	if r.AudienceSize == 0 && len(r.CustomerIDs) == 0 {
+		return fmt.Errorf("audience_size or customer_ids is required")
+	}
+	return nil
+}
+
+// CampaignETA represents when a campaign is expected to finish sending
+type CampaignETA struct {
+	CampaignID             int                   `json:"campaign_id"`
+	Status                 models.CampaignStatus `json:"status"`
+	Pending                int                   `json:"pending"`
+	EffectiveRatePerSecond float64               `json:"effective_rate_per_second"`
+	InQuietHours           bool                  `json:"in_quiet_hours"`
+	NextDispatchAt         *time.Time            `json:"next_dispatch_at,omitempty"`
+	EstimatedCompletionAt  *time.Time            `json:"estimated_completion_at"`
+	QuietHoursDelaySeconds float64               `json:"quiet_hours_delay_seconds"`
+}
+
+// SimulationResult represents the estimated outcome of sending a campaign
+type SimulationResult struct {
+	CampaignID               int               `json:"campaign_id"`
+	Channel                  models.Channel    `json:"channel"`
+	AudienceSize             int               `json:"audience_size"`
+	EffectiveRatePerSecond   float64           `json:"effective_rate_per_second"`
+	EstimatedDurationSeconds float64           `json:"estimated_duration_seconds"`
+	FailureRate              float64           `json:"failure_rate"`
+	FailureRateSampleSize    int               `json:"failure_rate_sample_size"`
+	ExpectedFailures         int               `json:"expected_failures"`
+	ExpectedDelivered        int               `json:"expected_delivered"`
+	CostPerMessage           float64           `json:"cost_per_message"`
+	EstimatedCost            float64           `json:"estimated_cost"`
+	DeliveryEstimate         *DeliveryEstimate `json:"delivery_estimate,omitempty"`
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// StatsReplayPageSize is how many events each read of a campaign's history returns
+const StatsReplayPageSize = 1000
+
+// CampaignReplay is a campaign's state as its history events left it
+type CampaignReplay struct {
+	Status   *models.CampaignStatus         // Last status recorded; nil when none was
+	Messages map[int]*models.MessageSummary // Messages still in the campaign, by ID
+	Events   int                            // Events applied
+}
+
+// NewCampaignReplay creates a replay with no events applied
+func NewCampaignReplay() *CampaignReplay {
+	return &CampaignReplay{Messages: make(map[int]*models.MessageSummary)}
+}
+
+// Apply folds one event into the replay
+// Send events are skipped; they are derived from messages, not part of their history
+// An event of an unknown version or type is an error, as skipping it would rebuild
+// the wrong state
+func (r *CampaignReplay) Apply(event *models.CampaignEvent) error {
+	if event.Version != models.CampaignEventVersion {
+		return fmt.Errorf("event %d has unsupported version %d", event.ID, event.Version)
+	}
+
+	switch event.Type {
+	case models.CampaignEventQueued, models.CampaignEventProgress, models.CampaignEventCompleted:
+	case models.CampaignEventStatus:
+		var payload models.CampaignStatusPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("event %d has an invalid payload: %w", event.ID, err)
+		}
+		r.Status = &payload.To
+	case models.CampaignEventMessageQueued, models.CampaignEventMessageSent, models.CampaignEventMessageFailed,
+		models.CampaignEventMessageCancelled, models.CampaignEventMessageSkipped, models.CampaignEventMessageExpired,
+		models.CampaignEventMessageUpdated:
+		if event.MessageID == nil {
+			return fmt.Errorf("event %d has no message", event.ID)
+		}
+		var payload models.MessageEventPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("event %d has an invalid payload: %w", event.ID, err)
+		}
+		r.Messages[*event.MessageID] = &models.MessageSummary{
+			ID:         *event.MessageID,
+			Status:     payload.Status,
+			RetryCount: payload.RetryCount,
+			LastError:  payload.LastError,
+			Simulated:  payload.Simulated,
+		}
+	case models.CampaignEventMessageMoved, models.CampaignEventMessageDeleted:
+		if event.MessageID == nil {
+			return fmt.Errorf("event %d has no message", event.ID)
+		}
+		delete(r.Messages, *event.MessageID)
+	default:
+		return fmt.Errorf("event %d has unknown type %q", event.ID, event.Type)
+	}
+
+	r.Events++
+	return nil
+}
+
+// ReplayCampaignEvents folds a campaign's events, oldest first, into its state
+func ReplayCampaignEvents(events []*models.CampaignEvent) (*CampaignReplay, error) {
+	replay := NewCampaignReplay()
+	for _, event := range events {
+		if err := replay.Apply(event); err != nil {
+			return nil, err
+		}
+	}
+	return replay, nil
+}
+
+// StatsDiscrepancy is a stored value that differs from the one replayed from events
+type StatsDiscrepancy struct {
+	MessageID *int   `json:"message_id,omitempty"` // nil for a campaign field
+	Field     string `json:"field"`
+	Recorded  string `json:"recorded"`
+	Replayed  string `json:"replayed"`
+}
+
+// StatsRebuildReport is what a rebuild of a campaign's stats found and corrected
+type StatsRebuildReport struct {
+	CampaignID     int                `json:"campaign_id"`
+	DryRun         bool               `json:"dry_run"`
+	EventsReplayed int                `json:"events_replayed"`
+	Messages       int                `json:"messages"` // Messages the events say the campaign has
+	Discrepancies  []StatsDiscrepancy `json:"discrepancies"`
+	// UntrackedMessages are stored messages with no events, e.g. written with triggers
+	// disabled; they are reported but left alone
+	UntrackedMessages []int `json:"untracked_messages"`
+	// MissingMessages have events but are not stored, e.g. deleted with triggers
+	// disabled; they cannot be restored and are only reported
+	MissingMessages []int `json:"missing_messages"`
+	Corrected       int   `json:"corrected"` // Messages and campaign fields rewritten
+}
+
+// StatsRebuilder rebuilds a campaign's message summary fields, status and spend counter
+// by replaying its event history, correcting stored values that drifted from it
+// Corrections are ordinary updates, so the triggers record them and a second rebuild finds
+// nothing to correct
+type StatsRebuilder struct {
+	campaignRepo repository.CampaignRepository
+	eventRepo    repository.CampaignEventRepository
+	statsRepo    repository.StatsRebuildRepository
+	sending      config.SendingConfig
+}
+
+// NewStatsRebuilder creates a rebuilder pricing spend with the configured per-channel costs
+func NewStatsRebuilder(campaignRepo repository.CampaignRepository, eventRepo repository.CampaignEventRepository, statsRepo repository.StatsRebuildRepository, sending config.SendingConfig) *StatsRebuilder {
+	return &StatsRebuilder{
+		campaignRepo: campaignRepo,
+		eventRepo:    eventRepo,
+		statsRepo:    statsRepo,
+		sending:      sending,
+	}
+}
+
+// Rebuild replays a campaign's events and compares the result with its stored state,
+// correcting what differs unless dryRun is set
+// The spend counter is only rebuilt for campaigns with a budget, from their sent messages
+// priced at the current costs; simulated sends cost nothing
+// A campaign still sending, holding a canary or cancelling can only be checked with dryRun, as the worker
+// is changing what would be corrected
+func (b *StatsRebuilder) Rebuild(ctx context.Context, campaignID int, dryRun bool) (*StatsRebuildReport, error) {
+	campaign, err := b.campaignRepo.GetByID(ctx, campaignID)
+	if errors.Is(err, repository.ErrCampaignNotFound) {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	if !dryRun && (campaign.Status == models.CampaignStatusSending || campaign.Status == models.CampaignStatusCanary ||
+		campaign.Status == models.CampaignStatusCancelling) {
+		return nil, &BusinessLogicError{
+			Message: fmt.Sprintf("campaign is %s; wait for it to stop or use a dry run", campaign.Status),
+		}
+	}
+
+	replay, err := b.replay(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := b.statsRepo.ListMessageSummaries(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StatsRebuildReport{
+		CampaignID:        campaignID,
+		DryRun:            dryRun,
+		EventsReplayed:    replay.Events,
+		Messages:          len(replay.Messages),
+		Discrepancies:     []StatsDiscrepancy{},
+		UntrackedMessages: []int{},
+		MissingMessages:   []int{},
+	}
+
+	storedIDs := make(map[int]bool, len(stored))
+	for _, recorded := range stored {
+		storedIDs[recorded.ID] = true
+		replayed, ok := replay.Messages[recorded.ID]
+		if !ok {
+			report.UntrackedMessages = append(report.UntrackedMessages, recorded.ID)
+			continue
+		}
+		found := compareMessage(recorded, replayed)
+		if len(found) == 0 {
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, found...)
+		if !dryRun {
+			if err := b.statsRepo.CorrectMessage(ctx, campaignID, replayed); err != nil {
+				return report, fmt.Errorf("failed to correct message %d: %w", recorded.ID, err)
+			}
+			report.Corrected++
+		}
+	}
+	for id := range replay.Messages {
+		if !storedIDs[id] {
+			report.MissingMessages = append(report.MissingMessages, id)
+		}
+	}
+	sort.Ints(report.MissingMessages)
+
+	if replay.Status != nil && *replay.Status != campaign.Status {
+		report.Discrepancies = append(report.Discrepancies, StatsDiscrepancy{
+			Field:    "campaign.status",
+			Recorded: string(campaign.Status),
+			Replayed: string(*replay.Status),
+		})
+		if !dryRun {
+			if err := b.statsRepo.CorrectCampaignStatus(ctx, campaignID, *replay.Status); err != nil {
+				return report, fmt.Errorf("failed to correct campaign status: %w", err)
+			}
+			report.Corrected++
+		}
+	}
+
+	if campaign.Budget != nil {
+		spend := replayedSpend(replay, costPerMessage(b.sending, campaign.Channel))
+		recorded := 0.0
+		if campaign.Spend != nil {
+			recorded = *campaign.Spend
+		}
+		if math.Abs(spend-recorded) >= 0.005 {
+			report.Discrepancies = append(report.Discrepancies, StatsDiscrepancy{
+				Field:    "campaign.spend",
+				Recorded: fmt.Sprintf("%.2f", recorded),
+				Replayed: fmt.Sprintf("%.2f", spend),
+			})
+			if !dryRun {
+				if err := b.statsRepo.CorrectSpend(ctx, campaignID, spend); err != nil {
+					return report, fmt.Errorf("failed to correct campaign spend: %w", err)
+				}
+				report.Corrected++
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// replay reads a campaign's history a page at a time and folds it
+func (b *StatsRebuilder) replay(ctx context.Context, campaignID int) (*CampaignReplay, error) {
+	replay := NewCampaignReplay()
+	var afterID int64
+	for {
+		events, err := b.eventRepo.ListReplay(ctx, campaignID, afterID, StatsReplayPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if err := replay.Apply(event); err != nil {
+				return nil, fmt.Errorf("failed to replay campaign %d: %w", campaignID, err)
+			}
+			afterID = event.ID
+		}
+		if len(events) < StatsReplayPageSize {
+			return replay, nil
+		}
+	}
+}
+
+// compareMessage lists the summary fields of a stored message that differ from its replay
+func compareMessage(recorded, replayed *models.MessageSummary) []StatsDiscrepancy {
+	id := recorded.ID
+	found := []StatsDiscrepancy{}
+	add := func(field, recordedValue, replayedValue string) {
+		if recordedValue != replayedValue {
+			found = append(found, StatsDiscrepancy{MessageID: &id, Field: field, Recorded: recordedValue, Replayed: replayedValue})
+		}
+	}
+
+	add("status", string(recorded.Status), string(replayed.Status))
+	add("retry_count", strconv.Itoa(recorded.RetryCount), strconv.Itoa(replayed.RetryCount))
+	add("last_error", formatLastError(recorded.LastError), formatLastError(replayed.LastError))
+	add("simulated", strconv.FormatBool(recorded.Simulated), strconv.FormatBool(replayed.Simulated))
+	return found
+}
+
+func formatLastError(lastError *string) string {
+	if lastError == nil {
+		return "<none>"
+	}
+	return *lastError
+}
+
+// replayedSpend prices the replayed campaign's sent messages, leaving out simulated ones
+func replayedSpend(replay *CampaignReplay, cost float64) float64 {
+	sent := 0
+	for _, message := range replay.Messages {
+		if message.Status == models.MessageStatusSent && !message.Simulated {
+			sent++
+		}
+	}
+	return math.Round(float64(sent)*cost*100) / 100
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// RetryEffectivenessWindow is the period reported when no start is given
+const RetryEffectivenessWindow = 30 * 24 * time.Hour
+
+// StatsService reports delivery statistics across campaigns
+type StatsService struct {
+	messageRepo repository.MessageRepository
+}
+
+// NewStatsService creates a new stats service
+func NewStatsService(messageRepo repository.MessageRepository) *StatsService {
+	return &StatsService{messageRepo: messageRepo}
+}
+
+// RetryEffectiveness reports per channel what share of the messages sent in [from, to) needed retries
+// to defaults to now and from to RetryEffectivenessWindow before to
+func (s *StatsService) RetryEffectiveness(ctx context.Context, from, to *time.Time) (*RetryEffectivenessReport, error) {
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-RetryEffectivenessWindow)
+	if from != nil {
+		start = *from
+	}
+	if !start.Before(end) {
+		return nil, &ValidationError{Message: "from must be before to"}
+	}
+
+	channels, err := s.messageRepo.GetRetryEffectiveness(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retry effectiveness: %w", err)
+	}
+
+	for _, channel := range channels {
+		if channel.Sent > 0 {
+			channel.RetriedPercent = math.Round(float64(channel.SentAfterRetry)/float64(channel.Sent)*10000) / 100
+		}
+	}
+
+	return &RetryEffectivenessReport{
+		From:     start,
+		To:       end,
+		Channels: channels,
+	}, nil
+}
+
+// RetryEffectivenessReport is the retry effectiveness of each channel over a period
+type RetryEffectivenessReport struct {
+	From     time.Time                           `json:"from"`
+	To       time.Time                           `json:"to"`
+	Channels []*models.ChannelRetryEffectiveness `json:"channels"`
+}
+
+// PendingBacklog splits pending messages into those queued for the worker, those never
+// published to the broker, and those deferred, per campaign and in total
+func (s *StatsService) PendingBacklog(ctx context.Context) (*PendingBacklogReport, error) {
+	campaigns, err := s.messageRepo.GetPendingBacklog(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending backlog: %w", err)
+	}
+
+	report := &PendingBacklogReport{Campaigns: campaigns}
+	for _, campaign := range campaigns {
+		report.Queued += campaign.Queued
+		report.Unpublished += campaign.Unpublished
+		report.Deferred += campaign.Deferred
+		if campaign.OldestUnpublishedAt != nil && (report.OldestUnpublishedAt == nil || campaign.OldestUnpublishedAt.Before(*report.OldestUnpublishedAt)) {
+			report.OldestUnpublishedAt = campaign.OldestUnpublishedAt
+		}
+	}
+
+	return report, nil
+}
+
+// PendingBacklogReport is the pending message backlog across campaigns
+// Unpublished is the actionable figure: those messages will not be sent until republished
+type PendingBacklogReport struct {
+	Queued              int                              `json:"queued"`
+	Unpublished         int                              `json:"unpublished"`
+	Deferred            int                              `json:"deferred"`
+	OldestUnpublishedAt *time.Time                       `json:"oldest_unpublished_at,omitempty"`
+	Campaigns           []*models.CampaignPendingBacklog `json:"campaigns"`
+}
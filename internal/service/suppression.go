@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"smsleopard/internal/csvimport"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// MaxSuppressionsPerUpload caps the phones added to a campaign's suppression list in one request
+const MaxSuppressionsPerUpload = 100000
+
+// SetSuppressions sets the per-campaign suppression lists applied when sending
+// Suppressions are not applied until this is called
+func (s *CampaignService) SetSuppressions(suppressions repository.SuppressionRepository) {
+	s.suppressions = suppressions
+}
+
+// AddSuppressions normalizes phones and adds them to a campaign's suppression list
+// Every phone must be valid; nothing is added otherwise
+func (s *CampaignService) AddSuppressions(ctx context.Context, campaignID int, phones []string, addedBy string) (*SuppressionResult, error) {
+	if _, err := s.campaignRepo.GetByID(ctx, campaignID); err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	if len(phones) == 0 {
+		return nil, &ValidationError{Message: "at least one phone required"}
+	}
+	if len(phones) > MaxSuppressionsPerUpload {
+		return nil, &ValidationError{Message: fmt.Sprintf("at most %d phones per upload", MaxSuppressionsPerUpload)}
+	}
+
+	normalized := make([]string, 0, len(phones))
+	for i, phone := range phones {
+		n, err := models.NormalizePhone(phone)
+		if err != nil {
+			return nil, &ValidationError{Message: fmt.Sprintf("phones[%d]: %v", i, err)}
+		}
+		normalized = append(normalized, n)
+	}
+
+	return s.addSuppressions(ctx, campaignID, normalized, addedBy)
+}
+
+// AddSuppressionsCSV adds the phones of an uploaded CSV with a phone column to a campaign's
+// suppression list; rows with a malformed phone are reported by line and nothing is added
+func (s *CampaignService) AddSuppressionsCSV(ctx context.Context, campaignID int, file io.Reader, contentEncoding, addedBy string) (*SuppressionResult, error) {
+	if _, err := s.campaignRepo.GetByID(ctx, campaignID); err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	parsed, err := csvimport.Parse(file, csvimport.Options{
+		ContentEncoding: contentEncoding,
+		RequiredColumns: []string{"phone"},
+	})
+	if err != nil {
+		return nil, &ValidationError{Message: err.Error()}
+	}
+
+	rowErrors := parsed.Errors
+	phones := make([]string, 0, len(parsed.Records))
+	for _, record := range parsed.Records {
+		phone, err := models.NormalizePhone(record.Fields["phone"])
+		if err != nil {
+			rowErrors = append(rowErrors, &csvimport.RowError{Line: record.Line, Problem: err.Error()})
+			continue
+		}
+		phones = append(phones, phone)
+	}
+
+	if len(rowErrors) > 0 {
+		first := rowErrors[0]
+		return nil, &ValidationError{
+			Message: fmt.Sprintf("%d invalid rows, first at line %d: %s", len(rowErrors), first.Line, first.Problem),
+		}
+	}
+	if len(phones) == 0 {
+		return nil, &ValidationError{Message: "uploaded file has no phones"}
+	}
+	if len(phones) > MaxSuppressionsPerUpload {
+		return nil, &ValidationError{Message: fmt.Sprintf("at most %d phones per upload", MaxSuppressionsPerUpload)}
+	}
+
+	return s.addSuppressions(ctx, campaignID, phones, addedBy)
+}
+
+// addSuppressions stores normalized phones once each
+func (s *CampaignService) addSuppressions(ctx context.Context, campaignID int, phones []string, addedBy string) (*SuppressionResult, error) {
+	unique := make([]string, 0, len(phones))
+	seen := make(map[string]bool, len(phones))
+	for _, phone := range phones {
+		if !seen[phone] {
+			seen[phone] = true
+			unique = append(unique, phone)
+		}
+	}
+
+	var by *string
+	if addedBy != "" {
+		by = &addedBy
+	}
+
+	added, err := s.suppressions.Add(ctx, campaignID, unique, by)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SuppressionResult{
+		CampaignID: campaignID,
+		Received:   len(phones),
+		Added:      added,
+	}, nil
+}
+
+// ListSuppressions returns the phones a campaign will not message
+func (s *CampaignService) ListSuppressions(ctx context.Context, campaignID int) (*SuppressionList, error) {
+	if _, err := s.campaignRepo.GetByID(ctx, campaignID); err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	suppressions, err := s.suppressions.List(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SuppressionList{
+		CampaignID:   campaignID,
+		Total:        len(suppressions),
+		Suppressions: suppressions,
+	}, nil
+}
+
+// ClearSuppressions empties a campaign's suppression list and returns how many phones were removed
+func (s *CampaignService) ClearSuppressions(ctx context.Context, campaignID int) (*SuppressionResult, error) {
+	if _, err := s.campaignRepo.GetByID(ctx, campaignID); err != nil {
+		return nil, &NotFoundError{Resource: "campaign", ID: campaignID}
+	}
+
+	removed, err := s.suppressions.Clear(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SuppressionResult{CampaignID: campaignID, Removed: removed}, nil
+}
+
+// applySuppressions drops customers whose phone the campaign suppresses and returns the rest
+// with how many were skipped; suppression wins over customers named explicitly in the send
+// Phones are compared in normalized form, so "0712345678" matches "+254712345678"
+func (s *CampaignService) applySuppressions(ctx context.Context, campaign *models.Campaign, customers []*models.Customer) ([]*models.Customer, int, error) {
+	if s.suppressions == nil {
+		return customers, 0, nil
+	}
+
+	phones := make([]string, len(customers))
+	unnormalized := 0
+	for i, customer := range customers {
+		phones[i] = models.PhoneKey(customer.Phone)
+		if phones[i] != customer.Phone {
+			unnormalized++
+		}
+	}
+	if unnormalized > 0 {
+		log.Printf("Warning: %d customers of campaign %d have phones that are not normalized; run cmd/normalize-phones", unnormalized, campaign.ID)
+	}
+
+	suppressedPhones, err := s.suppressions.FilterSuppressed(ctx, campaign.ID, phones)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to check suppressions: %w", err)
+	}
+	if len(suppressedPhones) == 0 {
+		return customers, 0, nil
+	}
+
+	suppressed := make(map[string]bool, len(suppressedPhones))
+	for _, phone := range suppressedPhones {
+		suppressed[phone] = true
+	}
+	allowed := make([]*models.Customer, 0, len(customers))
+	for i, customer := range customers {
+		if !suppressed[phones[i]] {
+			allowed = append(allowed, customer)
+		}
+	}
+
+	skipped := len(customers) - len(allowed)
+	if len(allowed) == 0 {
+		return nil, skipped, &BusinessLogicError{
+			Message: fmt.Sprintf("all %d customers are on the campaign's suppression list", skipped),
+		}
+	}
+	return allowed, skipped, nil
+}
+
+// SuppressionResult reports a change to a campaign's suppression list
+type SuppressionResult struct {
+	CampaignID int `json:"campaign_id"`
+	Received   int `json:"received,omitempty"` // Phones in the upload, including repeats
+	Added      int `json:"added"`              // Phones not already suppressed
+	Removed    int `json:"removed,omitempty"`
+}
+
+// SuppressionList is the phones a campaign will not message
+type SuppressionList struct {
+	CampaignID   int                           `json:"campaign_id"`
+	Total        int                           `json:"total"`
+	Suppressions []*models.CampaignSuppression `json:"suppressions"`
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Template lint rules
+const (
+	LintDoubleBraces        = "double_braces"
+	LintMisspelledField     = "misspelled_placeholder"
+	LintMissingClosingBrace = "missing_closing_brace"
+	LintMissingOpeningBrace = "missing_opening_brace"
+)
+
+// TemplateWarning is a likely mistake in a template with the fix to apply
+type TemplateWarning struct {
+	Rule       string `json:"rule"`
+	Text       string `json:"text"`       // What the template says, e.g. "{first name}"
+	Suggestion string `json:"suggestion"` // What it probably meant, e.g. "{first_name}"
+	Message    string `json:"message"`
+}
+
+// placeholderFields are the customer fields a template can use
+var placeholderFields = map[string]bool{
+	"first_name":        true,
+	"last_name":         true,
+	"location":          true,
+	"preferred_product": true,
+	"phone":             true,
+}
+
+// placeholderAliases are names people write for a field that do not normalize to it
+var placeholderAliases = map[string]string{
+	"firstname":        "first_name",
+	"lastname":         "last_name",
+	"surname":          "last_name",
+	"product":          "preferred_product",
+	"preferredproduct": "preferred_product",
+	"phone_number":     "phone",
+	"phonenumber":      "phone",
+	"mobile":           "phone",
+}
+
+var (
+	doubleBracesPattern   = regexp.MustCompile(`\{\{([^{}\n]{1,40})\}\}`)
+	bracedPattern         = regexp.MustCompile(`\{([^{}\n]{1,40})\}`)
+	openedWordPattern     = regexp.MustCompile(`\{([A-Za-z][A-Za-z_]*)`)
+	closedWordPattern     = regexp.MustCompile(`([A-Za-z][A-Za-z_]*)\}`)
+	placeholderSeparators = regexp.MustCompile(`[\s\-.]+`)
+)
+
+// LintTemplate finds common placeholder mistakes: double braces, misspelled or
+// differently cased field names, and a missing opening or closing brace
+// Each warning suggests the placeholder that was probably meant; unknown
+// placeholders that resemble no field are left alone
+func LintTemplate(template string) []TemplateWarning {
+	type finding struct {
+		start, end int
+		warning    TemplateWarning
+	}
+	findings := []finding{}
+
+	// Rules run in priority order; a later match overlapping an earlier one is the same mistake
+	add := func(start, end int, warning TemplateWarning) {
+		for _, f := range findings {
+			if start < f.end && f.start < end {
+				return
+			}
+		}
+		findings = append(findings, finding{start, end, warning})
+	}
+
+	for _, m := range doubleBracesPattern.FindAllStringSubmatchIndex(template, -1) {
+		name := strings.TrimSpace(template[m[2]:m[3]])
+		if field, ok := normalizePlaceholder(name); ok {
+			name = field
+		}
+		text := template[m[0]:m[1]]
+		add(m[0], m[1], newTemplateWarning(LintDoubleBraces, text, "{"+name+"}", "%s uses double braces; did you mean %s?"))
+	}
+
+	for _, m := range bracedPattern.FindAllStringSubmatchIndex(template, -1) {
+		name := template[m[2]:m[3]]
+		if placeholderFields[name] {
+			continue
+		}
+		if field, ok := normalizePlaceholder(name); ok {
+			text := template[m[0]:m[1]]
+			add(m[0], m[1], newTemplateWarning(LintMisspelledField, text, "{"+field+"}", "%s is not a placeholder; did you mean %s?"))
+		}
+	}
+
+	for _, m := range openedWordPattern.FindAllStringSubmatchIndex(template, -1) {
+		if m[1] < len(template) && template[m[1]] == '}' {
+			continue
+		}
+		if field, ok := normalizePlaceholder(template[m[2]:m[3]]); ok {
+			text := template[m[0]:m[1]]
+			add(m[0], m[1], newTemplateWarning(LintMissingClosingBrace, text, "{"+field+"}", "%s is missing its closing brace; did you mean %s?"))
+		}
+	}
+
+	for _, m := range closedWordPattern.FindAllStringSubmatchIndex(template, -1) {
+		if m[0] > 0 && template[m[0]-1] == '{' {
+			continue
+		}
+		if field, ok := normalizePlaceholder(template[m[2]:m[3]]); ok {
+			text := template[m[0]:m[1]]
+			add(m[0], m[1], newTemplateWarning(LintMissingOpeningBrace, text, "{"+field+"}", "%s is missing its opening brace; did you mean %s?"))
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool { return findings[i].start < findings[j].start })
+
+	warnings := make([]TemplateWarning, 0, len(findings))
+	for _, f := range findings {
+		warnings = append(warnings, f.warning)
+	}
+	return warnings
+}
+
+// newTemplateWarning builds a warning whose message names the text and the suggestion
+func newTemplateWarning(rule, text, suggestion, format string) TemplateWarning {
+	return TemplateWarning{
+		Rule:       rule,
+		Text:       text,
+		Suggestion: suggestion,
+		Message:    fmt.Sprintf(format, text, suggestion),
+	}
+}
+
+// normalizePlaceholder maps a written name such as "First Name" or "firstName" to the field it means
+func normalizePlaceholder(name string) (string, bool) {
+	var snake strings.Builder
+	runes := []rune(strings.TrimSpace(name))
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			snake.WriteByte('_')
+		}
+		snake.WriteRune(unicode.ToLower(r))
+	}
+
+	normalized := placeholderSeparators.ReplaceAllString(snake.String(), "_")
+	if placeholderFields[normalized] {
+		return normalized, true
+	}
+	if field, ok := placeholderAliases[normalized]; ok {
+		return field, true
+	}
+	if field, ok := placeholderAliases[strings.ReplaceAll(normalized, "_", "")]; ok {
+		return field, true
+	}
+	return "", false
+}
+
+// lintMessages joins the messages of lint warnings for an error
+func lintMessages(warnings []TemplateWarning) string {
+	messages := make([]string, len(warnings))
+	for i, warning := range warnings {
+		messages[i] = warning.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// checkTemplate validates a template for a request field, adding lint suggestions to syntax errors
+// and, when strict, failing on lint warnings too; otherwise the warnings are returned
+func checkTemplate(templateSvc *TemplateService, field, template string, strict bool) ([]TemplateWarning, error) {
+	warnings := templateSvc.Lint(template)
+
+	if err := templateSvc.ValidateTemplate(template); err != nil {
+		if len(warnings) > 0 {
+			return nil, &ValidationError{Message: fmt.Sprintf("%s: %v; %s", field, err, lintMessages(warnings))}
+		}
+		return nil, &ValidationError{Message: fmt.Sprintf("%s: %v", field, err)}
+	}
+
+	if strict && len(warnings) > 0 {
+		return nil, &ValidationError{Message: fmt.Sprintf("%s: %s", field, lintMessages(warnings))}
+	}
+
+	return warnings, nil
+}
@@ -4,21 +4,101 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 
+	"smsleopard/internal/config"
 	"smsleopard/internal/models"
 )
 
+// Default length limits used when none are configured
+const (
+	DefaultMaxTemplateLength = 2000
+	DefaultMaxRenderedLength = 1600
+)
+
 // TemplateService handles message template rendering
-type TemplateService struct{}
+type TemplateService struct {
+	maxTemplateLength int
+	maxRenderedLength int
+
+	// Placeholder delimiters, e.g. "{" and "}" for {first_name}
+	syntax             models.TemplateSyntax
+	open, close        string
+	placeholderPattern *regexp.Regexp
+}
 
-// NewTemplateService creates a new template service
+// NewTemplateService creates a new template service with the default length limits and {field} placeholders
 func NewTemplateService() *TemplateService {
-	return &TemplateService{}
+	svc := &TemplateService{
+		maxTemplateLength: DefaultMaxTemplateLength,
+		maxRenderedLength: DefaultMaxRenderedLength,
+	}
+	svc.setSyntax(models.TemplateSyntaxBraces)
+	return svc
+}
+
+// NewTemplateServiceWithLimits creates a template service with configured length limits
+// Zero limits fall back to the defaults
+func NewTemplateServiceWithLimits(limits config.LimitsConfig) *TemplateService {
+	svc := NewTemplateService()
+	if limits.MaxTemplateLength > 0 {
+		svc.maxTemplateLength = limits.MaxTemplateLength
+	}
+	if limits.MaxRenderedLength > 0 {
+		svc.maxRenderedLength = limits.MaxRenderedLength
+	}
+	return svc
+}
+
+// SetSyntax sets the placeholder syntax of templates that do not declare one (unknown syntaxes are ignored)
+func (s *TemplateService) SetSyntax(syntax models.TemplateSyntax) {
+	if syntax.IsValid() {
+		s.setSyntax(syntax)
+	}
+}
+
+// setSyntax switches the delimiters placeholders are matched with
+func (s *TemplateService) setSyntax(syntax models.TemplateSyntax) {
+	s.syntax = syntax
+	s.open, s.close, _ = syntax.Delimiters()
+	s.placeholderPattern = regexp.MustCompile(regexp.QuoteMeta(s.open) + `[a-zA-Z_]+` + regexp.QuoteMeta(s.close))
+}
+
+// Syntax returns the placeholder syntax templates are read in
+func (s *TemplateService) Syntax() models.TemplateSyntax {
+	return s.syntax
+}
+
+// ForSyntax returns a template service with the same limits reading templates in syntax
+// A nil or unknown syntax returns s, so campaigns without one use the configured default
+func (s *TemplateService) ForSyntax(syntax *models.TemplateSyntax) *TemplateService {
+	if syntax == nil || !syntax.IsValid() || *syntax == s.syntax {
+		return s
+	}
+	svc := *s
+	svc.setSyntax(*syntax)
+	return &svc
+}
+
+// ForCampaign returns a template service reading templates in the campaign's syntax
+func (s *TemplateService) ForCampaign(campaign *models.Campaign) *TemplateService {
+	return s.ForSyntax(campaign.TemplateSyntax)
+}
+
+// Placeholder writes a customer field as a placeholder, e.g. "first_name" as "[[first_name]]"
+func (s *TemplateService) Placeholder(field string) string {
+	return s.open + field + s.close
+}
+
+// PlaceholderField returns the field name of a placeholder found by GetPlaceholders
+func (s *TemplateService) PlaceholderField(placeholder string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(placeholder, s.open), s.close)
 }
 
 // Render renders a template with customer data
-// Replaces {field_name} placeholders with actual customer values
-// Strategy for missing fields: replace with empty string
+// Replaces {field_name} placeholders (or those of the service's syntax) with actual customer values
+// Strategy for missing fields: replace with empty string; unknown placeholders are left as-is
+// The template is scanned once, so customer values are never themselves expanded
 func (s *TemplateService) Render(template string, customer *models.Customer) (string, error) {
 	if template == "" {
 		return "", fmt.Errorf("template cannot be empty")
@@ -28,48 +108,66 @@ func (s *TemplateService) Render(template string, customer *models.Customer) (st
 		return "", fmt.Errorf("customer cannot be nil")
 	}
 
-	rendered := template
+	// Runs once per message in the worker, so the output is sized up front
+	var rendered strings.Builder
+	rendered.Grow(len(template) + len(customer.Phone) + fieldLength(customer.FirstName) +
+		fieldLength(customer.LastName) + fieldLength(customer.Location) + fieldLength(customer.PreferredProduct))
 
-	// Replace {first_name}
-	if customer.FirstName != nil && *customer.FirstName != "" {
-		rendered = strings.ReplaceAll(rendered, "{first_name}", *customer.FirstName)
-	} else {
-		rendered = strings.ReplaceAll(rendered, "{first_name}", "")
-	}
+	for {
+		open := strings.Index(template, s.open)
+		if open < 0 {
+			break
+		}
+		rendered.WriteString(template[:open])
+		template = template[open:]
 
-	// Replace {last_name}
-	if customer.LastName != nil && *customer.LastName != "" {
-		rendered = strings.ReplaceAll(rendered, "{last_name}", *customer.LastName)
-	} else {
-		rendered = strings.ReplaceAll(rendered, "{last_name}", "")
-	}
+		if closing := strings.Index(template[len(s.open):], s.close); closing >= 0 {
+			end := len(s.open) + closing
+			if value, ok := placeholderValue(template[len(s.open):end], customer); ok {
+				rendered.WriteString(value)
+				template = template[end+len(s.close):]
+				continue
+			}
+		}
 
-	// Replace {location}
-	if customer.Location != nil && *customer.Location != "" {
-		rendered = strings.ReplaceAll(rendered, "{location}", *customer.Location)
-	} else {
-		rendered = strings.ReplaceAll(rendered, "{location}", "")
+		// Not a known placeholder; keep the delimiter's first character and look for one after it
+		rendered.WriteByte(template[0])
+		template = template[1:]
 	}
+	rendered.WriteString(template)
 
-	// Replace {preferred_product}
-	if customer.PreferredProduct != nil && *customer.PreferredProduct != "" {
-		rendered = strings.ReplaceAll(rendered, "{preferred_product}", *customer.PreferredProduct)
-	} else {
-		rendered = strings.ReplaceAll(rendered, "{preferred_product}", "")
-	}
+	return rendered.String(), nil
+}
 
-	// Replace {phone}
-	rendered = strings.ReplaceAll(rendered, "{phone}", customer.Phone)
+// placeholderValue returns the customer value for a known placeholder field
+func placeholderValue(field string, customer *models.Customer) (string, bool) {
+	switch field {
+	case "first_name":
+		return fieldValue(customer.FirstName), true
+	case "last_name":
+		return fieldValue(customer.LastName), true
+	case "location":
+		return fieldValue(customer.Location), true
+	case "preferred_product":
+		return fieldValue(customer.PreferredProduct), true
+	case "phone":
+		return customer.Phone, true
+	default:
+		return "", false
+	}
+}
 
-	// Clean up any remaining placeholders (warn about unknown fields)
-	re := regexp.MustCompile(`\{[a-zA-Z_]+\}`)
-	if matches := re.FindAllString(rendered, -1); len(matches) > 0 {
-		// Log warning but continue - unknown placeholders left as-is
-		// In production, you might want to log this
-		_ = matches // Keep unknown placeholders in the text
+// fieldValue returns an optional customer field, or "" when it is missing
+func fieldValue(field *string) string {
+	if field == nil {
+		return ""
 	}
+	return *field
+}
 
-	return rendered, nil
+// fieldLength returns the length of an optional customer field
+func fieldLength(field *string) int {
+	return len(fieldValue(field))
 }
 
 // ValidateTemplate checks if template has valid syntax
@@ -78,29 +176,26 @@ func (s *TemplateService) ValidateTemplate(template string) error {
 		return fmt.Errorf("template cannot be empty")
 	}
 
-	// Check for balanced braces
-	openCount := strings.Count(template, "{")
-	closeCount := strings.Count(template, "}")
-
-	if openCount != closeCount {
-		return fmt.Errorf("template has unbalanced braces: %d open, %d close", openCount, closeCount)
+	if length := utf8.RuneCountInString(template); length > s.maxTemplateLength {
+		return fmt.Errorf("template is %d characters, maximum is %d", length, s.maxTemplateLength)
 	}
 
-	// Check for valid placeholder format
-	re := regexp.MustCompile(`\{[a-zA-Z_]+\}`)
-	placeholders := re.FindAllString(template, -1)
+	// Check for balanced delimiters; a lone % cannot be told apart from text, so percent templates skip this
+	if s.open != s.close {
+		openCount := strings.Count(template, s.open)
+		closeCount := strings.Count(template, s.close)
 
-	validFields := map[string]bool{
-		"{first_name}":        true,
-		"{last_name}":         true,
-		"{location}":          true,
-		"{preferred_product}": true,
-		"{phone}":             true,
+		if openCount != closeCount {
+			return fmt.Errorf("template has unbalanced %s: %d open, %d close", s.delimiterName(), openCount, closeCount)
+		}
 	}
 
+	// Check for valid placeholder format
+	placeholders := s.placeholderPattern.FindAllString(template, -1)
+
 	unknownFields := []string{}
 	for _, placeholder := range placeholders {
-		if !validFields[placeholder] {
+		if !models.IsCustomerTemplateField(s.PlaceholderField(placeholder)) {
 			unknownFields = append(unknownFields, placeholder)
 		}
 	}
@@ -114,10 +209,70 @@ func (s *TemplateService) ValidateTemplate(template string) error {
 	return nil
 }
 
+// delimiterName names the delimiters in validation errors
+func (s *TemplateService) delimiterName() string {
+	if s.open == "[[" {
+		return "brackets"
+	}
+	return "braces"
+}
+
+// Lint finds common placeholder mistakes with LintTemplate
+// Its rules are written for {field} placeholders, so templates in other syntaxes get no warnings
+func (s *TemplateService) Lint(template string) []TemplateWarning {
+	if s.syntax != models.TemplateSyntaxBraces {
+		return []TemplateWarning{}
+	}
+	return LintTemplate(template)
+}
+
+// TemplateCheck is the outcome of validating and linting a template
+type TemplateCheck struct {
+	Valid        bool              `json:"valid"`
+	Errors       []string          `json:"errors"`
+	Warnings     []TemplateWarning `json:"warnings"`
+	Placeholders []string          `json:"placeholders"`
+	LinkWarning  *LinkWarning      `json:"link_warning,omitempty"` // Links are not mistakes, so strict leaves this alone
+}
+
+// Check validates a template and lints it for common mistakes, listing its links
+// With strict, lint warnings are reported as errors and make the template invalid
+func (s *TemplateService) Check(template string, strict bool) *TemplateCheck {
+	check := &TemplateCheck{
+		Errors:       []string{},
+		Warnings:     s.Lint(template),
+		Placeholders: s.GetPlaceholders(template),
+		LinkWarning:  CheckLinks(template, false),
+	}
+	if check.Placeholders == nil {
+		check.Placeholders = []string{}
+	}
+
+	if err := s.ValidateTemplate(template); err != nil {
+		check.Errors = append(check.Errors, err.Error())
+	}
+	if strict {
+		for _, warning := range check.Warnings {
+			check.Errors = append(check.Errors, warning.Message)
+		}
+		check.Warnings = []TemplateWarning{}
+	}
+
+	check.Valid = len(check.Errors) == 0
+	return check
+}
+
+// CheckRenderedLength rejects a rendered message too long for providers to accept
+func (s *TemplateService) CheckRenderedLength(rendered string) error {
+	if length := utf8.RuneCountInString(rendered); length > s.maxRenderedLength {
+		return fmt.Errorf("rendered message is %d characters, over the %d character limit", length, s.maxRenderedLength)
+	}
+	return nil
+}
+
 // GetPlaceholders extracts all placeholders from a template
 func (s *TemplateService) GetPlaceholders(template string) []string {
-	re := regexp.MustCompile(`\{[a-zA-Z_]+\}`)
-	return re.FindAllString(template, -1)
+	return s.placeholderPattern.FindAllString(template, -1)
 }
 
 // Preview renders a template for preview purposes (without saving)
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+)
+
+// WorkerHeartbeatInterval is how often a worker records that it is alive
+const WorkerHeartbeatInterval = 15 * time.Second
+
+// Worker activity listing limits
+const (
+	DefaultWorkerActivityClaims = 50
+	MaxWorkerActivityClaims     = 500
+)
+
+// WorkerActivityService records worker heartbeats and reports each worker's recent claims
+type WorkerActivityService struct {
+	repo repository.WorkerRepository
+}
+
+// NewWorkerActivityService creates a new worker activity service
+func NewWorkerActivityService(repo repository.WorkerRepository) *WorkerActivityService {
+	return &WorkerActivityService{repo: repo}
+}
+
+// RunHeartbeat records workerID as alive every interval until ctx is cancelled
+// A failed heartbeat is logged; the worker carries on processing
+func (s *WorkerActivityService) RunHeartbeat(ctx context.Context, workerID string, interval time.Duration) {
+	startedAt := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.repo.Heartbeat(ctx, workerID, startedAt); err != nil && ctx.Err() == nil {
+			log.Printf("Warning: Failed to record heartbeat: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetActivity returns workerID's liveness, throughput and up to limit of its most recent
+// claims (DefaultWorkerActivityClaims when limit is 0)
+func (s *WorkerActivityService) GetActivity(ctx context.Context, workerID string, limit int) (*models.WorkerActivity, error) {
+	if limit == 0 {
+		limit = DefaultWorkerActivityClaims
+	}
+	if limit < 0 || limit > MaxWorkerActivityClaims {
+		return nil, &ValidationError{Message: fmt.Sprintf("limit must be between 1 and %d", MaxWorkerActivityClaims)}
+	}
+
+	activity, err := s.repo.GetActivity(ctx, workerID, limit)
+	if errors.Is(err, repository.ErrWorkerNotFound) {
+		return nil, &WorkerNotFoundError{WorkerID: workerID}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worker activity: %w", err)
+	}
+
+	return activity, nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"smsleopard/internal/metrics"
+)
+
+// dependencyCheckTimeout bounds one round of dependency checks
+const dependencyCheckTimeout = 5 * time.Second
+
+// DependencyCheck returns an error while a dependency the worker needs is unreachable
+type DependencyCheck func(ctx context.Context) error
+
+// namedCheck is a dependency check with the name it is logged under
+type namedCheck struct {
+	name  string
+	check DependencyCheck
+}
+
+// WaitUntilReady runs check every interval until it passes, giving up after timeout
+// (0 tries once). Each failure is logged so a slow dependency is visible at boot
+func WaitUntilReady(ctx context.Context, name string, timeout, interval time.Duration, check DependencyCheck) error {
+	deadline := time.Now().Add(timeout)
+	for attempt := 1; ; attempt++ {
+		checkCtx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+		err := check(checkCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if !time.Now().Add(interval).Before(deadline) {
+			return fmt.Errorf("%s not ready after %d attempt(s): %w", name, attempt, err)
+		}
+
+		log.Printf("⏳ Waiting for %s (attempt %d): %v", name, attempt, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// WorkerHealth stops the worker consuming when its dependencies fail and resumes it once they recover
+// After threshold consecutive jobs fail with infrastructure errors it drains: hooks pause
+// consumption, leaving messages queued instead of burning their retries, while Run checks
+// the dependencies every interval and undrains when they all pass
+// A nil WorkerHealth never drains
+type WorkerHealth struct {
+	threshold int
+	interval  time.Duration
+	checks    []namedCheck
+	tripped   chan struct{}
+
+	mu       sync.Mutex
+	streak   int
+	draining bool
+	hooks    []func(draining bool)
+}
+
+// NewWorkerHealth creates a health gate draining after threshold consecutive infrastructure
+// failures (0 disables draining) and checking dependencies every interval while drained
+func NewWorkerHealth(threshold int, interval time.Duration) *WorkerHealth {
+	return &WorkerHealth{
+		threshold: threshold,
+		interval:  interval,
+		tripped:   make(chan struct{}, 1),
+	}
+}
+
+// AddCheck adds a dependency that must be reachable before a drained worker resumes
+func (h *WorkerHealth) AddCheck(name string, check DependencyCheck) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// OnChange adds a hook called with the new state whenever the worker drains or resumes
+// Hooks run on the Run goroutine, never on the one handling a job
+func (h *WorkerHealth) OnChange(hook func(draining bool)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, hook)
+}
+
+// Draining reports whether consumption is stopped until dependencies recover
+func (h *WorkerHealth) Draining() bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.draining
+}
+
+// Observe records the outcome of a handled job
+// Infrastructure errors extend the failure streak; any other outcome shows the
+// dependencies answered and ends it
+func (h *WorkerHealth) Observe(err error) {
+	if h == nil || h.threshold <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err == nil || ClassifyProcessingError(err) != ErrorClassInfra {
+		h.streak = 0
+		return
+	}
+
+	h.streak++
+	if h.streak < h.threshold || h.draining {
+		return
+	}
+
+	h.draining = true
+	log.Printf("🚧 %d consecutive infrastructure failures (last: %v); draining until dependencies recover", h.streak, err)
+	metrics.WorkerDrains.Inc()
+	metrics.WorkerDraining.Set(1)
+	select {
+	case h.tripped <- struct{}{}:
+	default:
+	}
+}
+
+// Check runs every dependency check, returning their failures joined
+func (h *WorkerHealth) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, c := range h.checks {
+		if err := c.check(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run applies drains as they happen and, while drained, checks dependencies every
+// interval, resuming once they all pass; it returns when ctx is cancelled
+func (h *WorkerHealth) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.tripped:
+			// A check may already have resumed the worker
+			if h.Draining() {
+				h.notify(true)
+			}
+		case <-ticker.C:
+			if !h.Draining() {
+				continue
+			}
+			if err := h.Check(ctx); err != nil {
+				log.Printf("🚧 Still draining, dependencies unhealthy: %v", err)
+				continue
+			}
+			h.resume()
+		}
+	}
+}
+
+// resume clears the drain and the failure streak
+func (h *WorkerHealth) resume() {
+	h.mu.Lock()
+	h.draining = false
+	h.streak = 0
+	h.mu.Unlock()
+
+	log.Printf("✅ Dependencies healthy again; resuming consumption")
+	metrics.WorkerDraining.Set(0)
+	h.notify(false)
+}
+
+// notify runs the change hooks in the order added
+func (h *WorkerHealth) notify(draining bool) {
+	h.mu.Lock()
+	hooks := append([]func(bool){}, h.hooks...)
+	h.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(draining)
+	}
+}
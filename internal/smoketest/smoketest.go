@@ -0,0 +1,371 @@
+// Package smoketest runs a scripted scenario against a deployed API to check the stack end to end
+// It talks to the API over HTTP only, as a client would, so it needs nothing but a base URL and a key
+package smoketest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Tag marks the campaigns the smoke test creates, so leftovers of an interrupted run can be found
+const Tag = "smoketest"
+
+// Template is the throwaway campaign's template
+const Template = "Hi {first_name}, this is an SMSLeopard smoke test"
+
+// APIKeyHeader is the header carrying the API key
+const APIKeyHeader = "X-API-Key"
+
+// Step names, in the order the scenario runs them
+const (
+	StepHealth   = "health"
+	StepCreate   = "create_campaign"
+	StepPreview  = "preview"
+	StepDryRun   = "dry_run"
+	StepRealSend = "real_send"
+	StepList     = "verify_list"
+	StepCleanup  = "cleanup"
+)
+
+// Step outcomes
+const (
+	StepPassed  = "passed"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// Options configures a smoke test run
+type Options struct {
+	BaseURL string // API base URL, e.g. https://api.example.com
+	APIKey  string // Sent as X-API-Key; empty when the API runs without keys
+
+	// TestPhone receives a real message when set, unless SkipRealSend is set
+	TestPhone    string
+	SkipRealSend bool
+
+	Timeout time.Duration // Per request; zero uses DefaultTimeout
+}
+
+// DefaultTimeout bounds each request when Options.Timeout is zero
+const DefaultTimeout = 10 * time.Second
+
+// StepResult is the outcome of one step
+type StepResult struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+	Detail     string  `json:"detail,omitempty"` // What was checked, or why it was skipped
+	Error      string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of a run
+type Report struct {
+	BaseURL      string        `json:"base_url"`
+	CampaignName string        `json:"campaign_name"`
+	CampaignID   int           `json:"campaign_id,omitempty"`
+	Passed       bool          `json:"passed"`
+	StartedAt    time.Time     `json:"started_at"`
+	DurationMS   float64       `json:"duration_ms"`
+	Steps        []*StepResult `json:"steps"`
+}
+
+// Runner runs the smoke test scenario
+type Runner struct {
+	opts   Options
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewRunner creates a runner for opts
+func NewRunner(opts Options) *Runner {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	return &Runner{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		now:    time.Now,
+	}
+}
+
+// SetClock replaces the clock used for timings and the campaign name (for testing)
+func (r *Runner) SetClock(now func() time.Time) {
+	r.now = now
+}
+
+// campaign is the part of a campaign the scenario checks
+type campaign struct {
+	ID           int      `json:"id"`
+	Name         string   `json:"name"`
+	Channel      string   `json:"channel"`
+	BaseTemplate string   `json:"base_template"`
+	Status       string   `json:"status"`
+	Tags         []string `json:"tags"`
+}
+
+// Run runs every step in order and reports each one
+// After a failure the remaining steps are skipped, except cleanup, which runs whenever a
+// campaign was created so a failed run leaves nothing behind
+func (r *Runner) Run(ctx context.Context) *Report {
+	started := r.now()
+	report := &Report{
+		BaseURL:      r.opts.BaseURL,
+		CampaignName: fmt.Sprintf("%s-%s", Tag, started.UTC().Format("20060102T150405Z")),
+		StartedAt:    started,
+		Steps:        []*StepResult{},
+	}
+
+	failed := false
+	sent := false
+	step := func(name string, run func() (string, error)) {
+		result := &StepResult{Name: name}
+		report.Steps = append(report.Steps, result)
+		if failed {
+			result.Status = StepSkipped
+			result.Detail = "an earlier step failed"
+			return
+		}
+
+		begin := r.now()
+		detail, err := run()
+		result.DurationMS = float64(r.now().Sub(begin).Microseconds()) / 1000
+		result.Detail = detail
+		if err != nil {
+			result.Status = StepFailed
+			result.Error = err.Error()
+			failed = true
+			return
+		}
+		result.Status = StepPassed
+	}
+	skip := func(name, reason string) {
+		report.Steps = append(report.Steps, &StepResult{Name: name, Status: StepSkipped, Detail: reason})
+	}
+
+	step(StepHealth, func() (string, error) {
+		var health struct {
+			Status string `json:"status"`
+		}
+		if err := r.do(ctx, http.MethodGet, "/health", nil, http.StatusOK, &health); err != nil {
+			return "", err
+		}
+		if health.Status != "healthy" {
+			return "", fmt.Errorf("status is %q, want healthy", health.Status)
+		}
+		return "API healthy", nil
+	})
+
+	step(StepCreate, func() (string, error) {
+		body := map[string]interface{}{
+			"name":          report.CampaignName,
+			"channel":       "sms",
+			"base_template": Template,
+			"tags":          []string{Tag},
+		}
+		var created campaign
+		if err := r.do(ctx, http.MethodPost, "/campaigns", body, http.StatusCreated, &created); err != nil {
+			return "", err
+		}
+		if created.ID <= 0 {
+			return "", fmt.Errorf("response has no campaign id")
+		}
+		report.CampaignID = created.ID
+		if created.Status != "draft" {
+			return "", fmt.Errorf("campaign %d status is %q, want draft", created.ID, created.Status)
+		}
+		return fmt.Sprintf("campaign %d", created.ID), nil
+	})
+
+	step(StepPreview, func() (string, error) {
+		body := map[string]interface{}{
+			"customer": map[string]interface{}{"phone": r.previewPhone(), "first_name": "Smoke"},
+		}
+		var preview struct {
+			RenderedMessage string `json:"rendered_message"`
+		}
+		if err := r.do(ctx, http.MethodPost, r.campaignPath(report.CampaignID, "/personalized-preview"), body, http.StatusOK, &preview); err != nil {
+			return "", err
+		}
+		want := strings.Replace(Template, "{first_name}", "Smoke", 1)
+		if preview.RenderedMessage != want {
+			return "", fmt.Errorf("rendered %q, want %q", preview.RenderedMessage, want)
+		}
+		return "rendered for an inline customer", nil
+	})
+
+	step(StepDryRun, func() (string, error) {
+		var simulation struct {
+			AudienceSize int `json:"audience_size"`
+		}
+		body := map[string]interface{}{"audience_size": 1}
+		if err := r.do(ctx, http.MethodPost, r.campaignPath(report.CampaignID, "/simulate"), body, http.StatusOK, &simulation); err != nil {
+			return "", err
+		}
+		if simulation.AudienceSize != 1 {
+			return "", fmt.Errorf("simulated audience is %d, want 1", simulation.AudienceSize)
+		}
+		return "simulated a send to 1 customer", nil
+	})
+
+	switch {
+	case r.opts.SkipRealSend:
+		skip(StepRealSend, "real send skipped by flag")
+	case r.opts.TestPhone == "":
+		skip(StepRealSend, "no test phone configured")
+	default:
+		step(StepRealSend, func() (string, error) {
+			body := map[string]interface{}{
+				"customers": []map[string]interface{}{{"phone": r.opts.TestPhone, "first_name": "Smoke"}},
+			}
+			var result struct {
+				MessagesQueued int    `json:"messages_queued"`
+				Status         string `json:"status"`
+			}
+			if err := r.do(ctx, http.MethodPost, r.campaignPath(report.CampaignID, "/send"), body, http.StatusOK, &result); err != nil {
+				return "", err
+			}
+			if result.MessagesQueued != 1 {
+				return "", fmt.Errorf("%d messages queued, want 1", result.MessagesQueued)
+			}
+			sent = true
+			return fmt.Sprintf("queued 1 message to %s", r.opts.TestPhone), nil
+		})
+	}
+
+	step(StepList, func() (string, error) {
+		var list struct {
+			Campaigns []*campaign `json:"campaigns"`
+		}
+		path := "/campaigns?tag=" + Tag + "&per_page=100"
+		if err := r.do(ctx, http.MethodGet, path, nil, http.StatusOK, &list); err != nil {
+			return "", err
+		}
+		for _, listed := range list.Campaigns {
+			if listed.ID == report.CampaignID {
+				return "listed with the fields it was created with", r.checkListed(listed, report.CampaignName, sent)
+			}
+		}
+		return "", fmt.Errorf("campaign %d is not in the list", report.CampaignID)
+	})
+
+	// Cleanup runs even after a failure, as long as there is something to clean up
+	if report.CampaignID > 0 {
+		wasFailed := failed
+		failed = false
+		step(StepCleanup, func() (string, error) {
+			if err := r.do(ctx, http.MethodDelete, r.campaignPath(report.CampaignID, "?force=true"), nil, http.StatusOK, nil); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("deleted campaign %d", report.CampaignID), nil
+		})
+		failed = failed || wasFailed
+	} else {
+		skip(StepCleanup, "no campaign was created")
+	}
+
+	report.Passed = !failed
+	report.DurationMS = float64(r.now().Sub(started).Microseconds()) / 1000
+	return report
+}
+
+// checkListed compares a listed campaign with what the scenario created
+func (r *Runner) checkListed(listed *campaign, name string, sent bool) error {
+	mismatches := []string{}
+	if listed.Name != name {
+		mismatches = append(mismatches, fmt.Sprintf("name %q, want %q", listed.Name, name))
+	}
+	if listed.Channel != "sms" {
+		mismatches = append(mismatches, fmt.Sprintf("channel %q, want sms", listed.Channel))
+	}
+	if listed.BaseTemplate != Template {
+		mismatches = append(mismatches, fmt.Sprintf("base_template %q, want %q", listed.BaseTemplate, Template))
+	}
+	// A real send moves the campaign on; its exact status depends on the worker
+	if !sent && listed.Status != "draft" {
+		mismatches = append(mismatches, fmt.Sprintf("status %q, want draft", listed.Status))
+	}
+	if sent && listed.Status == "draft" {
+		mismatches = append(mismatches, "status is still draft after the real send")
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("campaign %d has %s", listed.ID, strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
+// previewPhone is the inline customer's phone for the preview; nothing is sent to it
+func (r *Runner) previewPhone() string {
+	if r.opts.TestPhone != "" {
+		return r.opts.TestPhone
+	}
+	return "+254700000000"
+}
+
+func (r *Runner) campaignPath(id int, suffix string) string {
+	return fmt.Sprintf("/campaigns/%d%s", id, suffix)
+}
+
+// do sends a JSON request and decodes the response into out, failing unless the status is want
+func (r *Runner) do(ctx context.Context, method, path string, body interface{}, want int, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.opts.BaseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.opts.APIKey != "" {
+		req.Header.Set(APIKeyHeader, r.opts.APIKey)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%s %s: failed to read response: %w", method, path, err)
+	}
+	if resp.StatusCode != want {
+		return fmt.Errorf("%s %s: status %d, want %d%s", method, path, resp.StatusCode, want, errorMessage(raw))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("%s %s: invalid response: %w", method, path, err)
+	}
+	return nil
+}
+
+// errorMessage extracts the message of a standard error response, if the body is one
+func errorMessage(raw []byte) string {
+	var resp struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(raw, &resp) != nil || resp.Error.Message == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s: %s)", resp.Error.Code, resp.Error.Message)
+}
@@ -0,0 +1,232 @@
+package snapshot
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Result counts what a load wrote
+type Result struct {
+	Customers       int
+	Campaigns       int
+	Messages        int
+	MessagesRemoved int // Messages of snapshot campaigns not in the snapshot
+}
+
+// Load upserts the snapshot in one transaction, with relative times resolved against now
+// Rows are matched on their natural keys and overwritten, and messages of the snapshot's
+// campaigns that it does not list are removed, so loading the same snapshot again leaves
+// the same state. A row whose natural key exists under another ID fails the load, since
+// fixtures rely on the IDs
+func Load(ctx context.Context, db *sql.DB, snapshot *Snapshot, now time.Time) (*Result, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &Result{}
+	customerIDs := make(map[string]int, len(snapshot.Customers))
+	for _, customer := range snapshot.Customers {
+		if err := upsertCustomer(ctx, tx, customer, now); err != nil {
+			return nil, err
+		}
+		customerIDs[customer.Phone] = customer.ID
+		result.Customers++
+	}
+
+	for _, campaign := range snapshot.Campaigns {
+		if err := upsertCampaign(ctx, tx, campaign, now); err != nil {
+			return nil, err
+		}
+		result.Campaigns++
+
+		recipients := make([]int64, 0, len(campaign.Messages))
+		for _, message := range campaign.Messages {
+			customerID := customerIDs[message.Customer]
+			if err := upsertMessage(ctx, tx, campaign.ID, customerID, message, now); err != nil {
+				return nil, err
+			}
+			recipients = append(recipients, int64(customerID))
+			result.Messages++
+		}
+
+		removed, err := tx.ExecContext(ctx, `
+			DELETE FROM outbound_messages
+			WHERE campaign_id = $1 AND NOT (customer_id = ANY($2))
+		`, campaign.ID, pq.Array(recipients))
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove messages of campaign %q: %w", campaign.Name, err)
+		}
+		rows, err := removed.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		result.MessagesRemoved += int(rows)
+	}
+
+	// Explicit IDs bypass the sequences, so move them past the loaded rows
+	for _, table := range []string{"customers", "campaigns", "outbound_messages"} {
+		query := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), GREATEST((SELECT MAX(id) FROM %[1]s), 1))`, table)
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return nil, fmt.Errorf("failed to reset %s id sequence: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// upsertCustomer writes a customer, matched on phone
+func upsertCustomer(ctx context.Context, tx *sql.Tx, customer Customer, now time.Time) error {
+	query := `
+		INSERT INTO customers (id, phone, first_name, last_name, location, preferred_product, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (phone) DO UPDATE SET
+			first_name = EXCLUDED.first_name,
+			last_name = EXCLUDED.last_name,
+			location = EXCLUDED.location,
+			preferred_product = EXCLUDED.preferred_product,
+			created_at = EXCLUDED.created_at
+		RETURNING id
+	`
+
+	var id int
+	err := tx.QueryRowContext(ctx, query,
+		customer.ID,
+		customer.Phone,
+		customer.FirstName,
+		customer.LastName,
+		customer.Location,
+		customer.PreferredProduct,
+		customer.CreatedAt.Resolve(now),
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to upsert customer %s: %w", customer.Phone, err)
+	}
+	if id != customer.ID {
+		return fmt.Errorf("customer %s already exists with ID %d, not %d; delete it or load into an empty database", customer.Phone, id, customer.ID)
+	}
+
+	return nil
+}
+
+// upsertCampaign writes a campaign, matched on name
+func upsertCampaign(ctx context.Context, tx *sql.Tx, campaign Campaign, now time.Time) error {
+	query := `
+		INSERT INTO campaigns (id, name, channel, status, base_template, scheduled_at, tags, paused_reason, cancel_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+		ON CONFLICT (name) DO UPDATE SET
+			channel = EXCLUDED.channel,
+			status = EXCLUDED.status,
+			base_template = EXCLUDED.base_template,
+			scheduled_at = EXCLUDED.scheduled_at,
+			tags = EXCLUDED.tags,
+			paused_reason = EXCLUDED.paused_reason,
+			cancel_at = EXCLUDED.cancel_at,
+			created_at = EXCLUDED.created_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id
+	`
+
+	var scheduledAt *time.Time
+	if campaign.ScheduledAt != nil {
+		at := campaign.ScheduledAt.Resolve(now)
+		scheduledAt = &at
+	}
+	var cancelAt *time.Time
+	if campaign.CancelAt != nil {
+		at := campaign.CancelAt.Resolve(now)
+		cancelAt = &at
+	}
+	tags := campaign.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	var id int
+	err := tx.QueryRowContext(ctx, query,
+		campaign.ID,
+		campaign.Name,
+		campaign.Channel,
+		campaign.Status,
+		campaign.Template,
+		scheduledAt,
+		pq.Array(tags),
+		campaign.PausedReason,
+		cancelAt,
+		campaign.CreatedAt.Resolve(now),
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to upsert campaign %q: %w", campaign.Name, err)
+	}
+	if id != campaign.ID {
+		return fmt.Errorf("campaign %q already exists with ID %d, not %d; delete it or load into an empty database", campaign.Name, id, campaign.ID)
+	}
+
+	return nil
+}
+
+// upsertMessage writes a campaign's message to a customer, matched on the two
+// outbound_messages has no unique key on them, so it updates first and inserts if nothing matched
+func upsertMessage(ctx context.Context, tx *sql.Tx, campaignID, customerID int, message Message, now time.Time) error {
+	createdAt := message.CreatedAt.Resolve(now)
+	updatedAt := createdAt
+	if message.UpdatedAt != nil {
+		updatedAt = message.UpdatedAt.Resolve(now)
+	}
+	var publishedAt *time.Time
+	if message.PublishedAt != nil {
+		at := message.PublishedAt.Resolve(now)
+		publishedAt = &at
+	}
+	args := []interface{}{
+		message.ID,
+		campaignID,
+		customerID,
+		message.Status,
+		message.Content,
+		message.LastError,
+		message.RetryCount,
+		publishedAt,
+		createdAt,
+		updatedAt,
+	}
+
+	// The update takes every argument but the ID
+	update := `
+		UPDATE outbound_messages
+		SET status = $3, rendered_content = $4, last_error = $5, retry_count = $6,
+			published_at = $7, created_at = $8, updated_at = $9
+		WHERE campaign_id = $1 AND customer_id = $2
+		RETURNING id
+	`
+	var id int
+	err := tx.QueryRowContext(ctx, update, args[1:]...).Scan(&id)
+	if err == nil {
+		if id != message.ID {
+			return fmt.Errorf("message of campaign %d to customer %d already exists with ID %d, not %d; delete it or load into an empty database", campaignID, customerID, id, message.ID)
+		}
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to update message %d: %w", message.ID, err)
+	}
+
+	insert := `
+		INSERT INTO outbound_messages (id, campaign_id, customer_id, status, rendered_content, last_error, retry_count, published_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	if _, err := tx.ExecContext(ctx, insert, args...); err != nil {
+		return fmt.Errorf("failed to insert message %d: %w", message.ID, err)
+	}
+
+	return nil
+}
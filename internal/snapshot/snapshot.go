@@ -0,0 +1,209 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"smsleopard/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Snapshot is a fixed dataset of customers and campaigns with their messages
+// IDs are explicit so fixtures built against it can refer to them; rows are matched on
+// their natural keys (customer phone, campaign name, message campaign and customer)
+type Snapshot struct {
+	Customers []Customer `yaml:"customers"`
+	Campaigns []Campaign `yaml:"campaigns"`
+}
+
+// Customer is a customer in a snapshot, matched on Phone
+type Customer struct {
+	ID               int          `yaml:"id"`
+	Phone            string       `yaml:"phone"`
+	FirstName        *string      `yaml:"first_name"`
+	LastName         *string      `yaml:"last_name"`
+	Location         *string      `yaml:"location"`
+	PreferredProduct *string      `yaml:"preferred_product"`
+	CreatedAt        RelativeTime `yaml:"created_at"`
+}
+
+// Campaign is a campaign in a snapshot, matched on Name
+type Campaign struct {
+	ID           int                   `yaml:"id"`
+	Name         string                `yaml:"name"`
+	Channel      models.Channel        `yaml:"channel"`
+	Status       models.CampaignStatus `yaml:"status"`
+	Template     string                `yaml:"template"`
+	ScheduledAt  *RelativeTime         `yaml:"scheduled_at"`
+	Tags         []string              `yaml:"tags"`
+	PausedReason *string               `yaml:"paused_reason"`
+	CancelAt     *RelativeTime         `yaml:"cancel_at"` // Deadline of a cancelling or cancelled campaign
+	CreatedAt    RelativeTime          `yaml:"created_at"`
+	Messages     []Message             `yaml:"messages"`
+}
+
+// Message is a campaign's message to one customer, matched on the campaign and Customer (a phone)
+type Message struct {
+	ID          int                  `yaml:"id"`
+	Customer    string               `yaml:"customer"`
+	Status      models.MessageStatus `yaml:"status"`
+	Content     *string              `yaml:"content"`
+	LastError   *string              `yaml:"last_error"`
+	RetryCount  int                  `yaml:"retry_count"`
+	PublishedAt *RelativeTime        `yaml:"published_at"`
+	CreatedAt   RelativeTime         `yaml:"created_at"`
+	UpdatedAt   *RelativeTime        `yaml:"updated_at"` // Defaults to CreatedAt
+}
+
+// RelativeTime is a time given as an offset from when the snapshot is loaded: "now", or a
+// signed number of days and/or a Go duration, e.g. "-3d", "+48h" or "-1d6h30m"
+// Unset times resolve to now
+type RelativeTime struct {
+	Offset time.Duration
+}
+
+// UnmarshalYAML parses a relative time from a YAML scalar
+func (t *RelativeTime) UnmarshalYAML(node *yaml.Node) error {
+	offset, err := ParseOffset(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	t.Offset = offset
+	return nil
+}
+
+// Resolve returns the time the offset points to from now
+func (t RelativeTime) Resolve(now time.Time) time.Time {
+	return now.Add(t.Offset)
+}
+
+// ParseOffset parses the offset of a relative time
+func ParseOffset(value string) (time.Duration, error) {
+	s := strings.TrimSpace(value)
+	if s == "now" {
+		return 0, nil
+	}
+
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	var offset time.Duration
+	days, rest, hasDays := strings.Cut(s, "d")
+	if hasDays {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid relative time %q: days must be a whole number", value)
+		}
+		offset, s = time.Duration(n)*24*time.Hour, rest
+	}
+	if s != "" || !hasDays {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid relative time %q: use now, -3d, +48h or -1d6h", value)
+		}
+		offset += d
+	}
+
+	return sign * offset, nil
+}
+
+// ReadFile reads and validates a snapshot file
+func ReadFile(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Parse reads and validates a snapshot, rejecting unknown fields
+func Parse(r io.Reader) (*Snapshot, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var snapshot Snapshot
+	if err := decoder.Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+	if err := snapshot.Validate(); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Validate checks that IDs and natural keys are unique and every message's customer is listed
+func (s *Snapshot) Validate() error {
+	customerIDs := make(map[int]bool)
+	phones := make(map[string]bool)
+	for _, customer := range s.Customers {
+		if customer.ID <= 0 || customer.Phone == "" {
+			return fmt.Errorf("customer %q: id and phone are required", customer.Phone)
+		}
+		if customerIDs[customer.ID] || phones[customer.Phone] {
+			return fmt.Errorf("customer %q: id %d or phone listed twice", customer.Phone, customer.ID)
+		}
+		customerIDs[customer.ID], phones[customer.Phone] = true, true
+	}
+
+	campaignIDs := make(map[int]bool)
+	names := make(map[string]bool)
+	messageIDs := make(map[int]bool)
+	for _, campaign := range s.Campaigns {
+		if campaign.ID <= 0 || campaign.Name == "" || campaign.Template == "" {
+			return fmt.Errorf("campaign %q: id, name and template are required", campaign.Name)
+		}
+		if campaignIDs[campaign.ID] || names[campaign.Name] {
+			return fmt.Errorf("campaign %q: id %d or name listed twice", campaign.Name, campaign.ID)
+		}
+		campaignIDs[campaign.ID], names[campaign.Name] = true, true
+
+		if campaign.Channel != models.ChannelSMS && campaign.Channel != models.ChannelWhatsApp {
+			return fmt.Errorf("campaign %q: channel must be sms or whatsapp", campaign.Name)
+		}
+		if _, ok := models.CampaignTransitions[campaign.Status]; !ok {
+			return fmt.Errorf("campaign %q: unknown status %q", campaign.Name, campaign.Status)
+		}
+		if campaign.Status == models.CampaignStatusScheduled && campaign.ScheduledAt == nil {
+			return fmt.Errorf("campaign %q: a scheduled campaign needs scheduled_at", campaign.Name)
+		}
+		cancelled := campaign.Status == models.CampaignStatusCancelling || campaign.Status == models.CampaignStatusCancelled
+		if cancelled != (campaign.CancelAt != nil) {
+			return fmt.Errorf("campaign %q: cancel_at is required for cancelling and cancelled campaigns, and only for them", campaign.Name)
+		}
+
+		recipients := make(map[string]bool)
+		for _, message := range campaign.Messages {
+			if message.ID <= 0 || messageIDs[message.ID] {
+				return fmt.Errorf("campaign %q: message id %d missing or listed twice", campaign.Name, message.ID)
+			}
+			messageIDs[message.ID] = true
+			if !phones[message.Customer] {
+				return fmt.Errorf("campaign %q: message %d is to unknown customer %q", campaign.Name, message.ID, message.Customer)
+			}
+			if recipients[message.Customer] {
+				return fmt.Errorf("campaign %q: customer %q has more than one message", campaign.Name, message.Customer)
+			}
+			recipients[message.Customer] = true
+			switch message.Status {
+			case models.MessageStatusPending, models.MessageStatusSent, models.MessageStatusFailed, models.MessageStatusCancelled,
+				models.MessageStatusSkippedDuplicateContent, models.MessageStatusExpired:
+			default:
+				return fmt.Errorf("campaign %q: message %d has unknown status %q", campaign.Name, message.ID, message.Status)
+			}
+		}
+	}
+
+	return nil
+}
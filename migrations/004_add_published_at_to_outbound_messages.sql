@@ -0,0 +1,5 @@
+-- Track when a message job was last published to the queue
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS published_at TIMESTAMP;
+
+-- Add comment for documentation
+COMMENT ON COLUMN outbound_messages.published_at IS 'When the message job was last (re)published to the queue, used for queue latency tracking';
@@ -0,0 +1,10 @@
+-- Allow campaigns to wait for approval before large sends
+ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS campaigns_status_check;
+ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+    CHECK (status IN ('draft', 'scheduled', 'pending_approval', 'sending', 'sent', 'failed'));
+
+-- Store the planned targeting of a send awaiting approval
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS send_plan JSONB;
+
+-- Add comment for documentation
+COMMENT ON COLUMN campaigns.send_plan IS 'Planned targeting stored while a send awaits approval, cleared once approved or rejected';
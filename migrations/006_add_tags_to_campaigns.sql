@@ -0,0 +1,8 @@
+-- Lightweight grouping of campaigns, e.g. 'q3-promo', 'retention'
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- GIN index supports the containment filter used by GET /campaigns?tag=
+CREATE INDEX IF NOT EXISTS idx_campaigns_tags ON campaigns USING GIN (tags);
+
+-- Add comment for documentation
+COMMENT ON COLUMN campaigns.tags IS 'Lowercase tags for organizing and filtering campaigns';
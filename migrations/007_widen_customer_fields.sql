@@ -0,0 +1,6 @@
+-- Allow customer string fields up to MAX_CUSTOMER_FIELD_LENGTH (default 255)
+ALTER TABLE customers
+    ALTER COLUMN first_name TYPE VARCHAR(255),
+    ALTER COLUMN last_name TYPE VARCHAR(255),
+    ALTER COLUMN location TYPE VARCHAR(255),
+    ALTER COLUMN preferred_product TYPE VARCHAR(255);
@@ -0,0 +1,10 @@
+-- Campaign ownership for API key authentication
+-- Campaigns created before this migration have no owner and can only be modified by admins
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS created_by VARCHAR(100);
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS team VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_created_by ON campaigns(created_by);
+
+-- Add comments for documentation
+COMMENT ON COLUMN campaigns.created_by IS 'User ID of the API key that created the campaign';
+COMMENT ON COLUMN campaigns.team IS 'Team whose members may also modify the campaign';
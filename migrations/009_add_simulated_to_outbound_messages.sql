@@ -0,0 +1,5 @@
+-- Messages marked sent by a worker in simulate mode (WORKER_MODE=simulate)
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS simulated BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Add comment for documentation
+COMMENT ON COLUMN outbound_messages.simulated IS 'Marked sent without calling a provider; excluded from delivery stats';
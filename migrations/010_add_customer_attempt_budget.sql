@@ -0,0 +1,17 @@
+-- Per-customer daily send attempt counter (CUSTOMER_DAILY_ATTEMPT_BUDGET)
+-- The primary key is the lookup the worker makes before every send
+CREATE TABLE IF NOT EXISTS customer_daily_attempts (
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (customer_id, day)
+);
+
+-- Messages held back because their customer's budget was spent
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS deliver_after TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_deliver_after ON outbound_messages(deliver_after) WHERE deliver_after IS NOT NULL;
+
+-- Add comments for documentation
+COMMENT ON TABLE customer_daily_attempts IS 'Send attempts per customer per UTC day, capped by the worker';
+COMMENT ON COLUMN outbound_messages.deliver_after IS 'Deferred message is requeued once this time (UTC) has passed';
@@ -0,0 +1,9 @@
+-- Deleting a campaign or customer must not silently destroy message history
+-- Messages now block the delete; the API deletes them explicitly only when forced
+ALTER TABLE outbound_messages DROP CONSTRAINT IF EXISTS outbound_messages_campaign_id_fkey;
+ALTER TABLE outbound_messages ADD CONSTRAINT outbound_messages_campaign_id_fkey
+    FOREIGN KEY (campaign_id) REFERENCES campaigns(id) ON DELETE RESTRICT;
+
+ALTER TABLE outbound_messages DROP CONSTRAINT IF EXISTS outbound_messages_customer_id_fkey;
+ALTER TABLE outbound_messages ADD CONSTRAINT outbound_messages_customer_id_fkey
+    FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE RESTRICT;
@@ -0,0 +1,8 @@
+-- Fingerprint of the campaign template and channel a message was sent from
+-- Lets a send find customers who already got the same content recently
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS content_fingerprint VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_fingerprint ON outbound_messages(customer_id, content_fingerprint, created_at) WHERE content_fingerprint IS NOT NULL;
+
+-- Add comment for documentation
+COMMENT ON COLUMN outbound_messages.content_fingerprint IS 'SHA-256 of the channel and normalized campaign template (DUPLICATE_CONTENT_WINDOW)';
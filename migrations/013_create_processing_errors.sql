@@ -0,0 +1,16 @@
+-- Create processing_errors table
+-- Worker errors that are not provider send failures (those are kept on the message as last_error)
+CREATE TABLE IF NOT EXISTS processing_errors (
+    id SERIAL PRIMARY KEY,
+    message_id INTEGER,
+    error_class VARCHAR(20) NOT NULL,
+    error TEXT NOT NULL,
+    worker_id VARCHAR(255) NOT NULL,
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create index for triage by time
+CREATE INDEX IF NOT EXISTS idx_processing_errors_occurred_at ON processing_errors(occurred_at);
+
+-- Add comment for documentation
+COMMENT ON TABLE processing_errors IS 'Worker processing errors (infra, render, panic) for triage; message_id has no foreign key so errors outlive deleted messages';
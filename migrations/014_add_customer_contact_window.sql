@@ -0,0 +1,17 @@
+-- Local time of day a customer agreed to be messaged (HH:MM, end may be before start to cross midnight)
+-- Messages processed outside the window are deferred until it next opens
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS contact_window_start VARCHAR(5);
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS contact_window_end VARCHAR(5);
+
+ALTER TABLE customers ADD CONSTRAINT customers_contact_window_check CHECK (
+    (contact_window_start IS NULL AND contact_window_end IS NULL)
+    OR (
+        contact_window_start ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'
+        AND contact_window_end ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'
+        AND contact_window_start <> contact_window_end
+    )
+);
+
+-- Add comment for documentation
+COMMENT ON COLUMN customers.contact_window_start IS 'Start of the contact window, local time in QUIET_HOURS_TZ';
+COMMENT ON COLUMN customers.contact_window_end IS 'End of the contact window, local time in QUIET_HOURS_TZ';
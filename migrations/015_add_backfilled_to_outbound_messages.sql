@@ -0,0 +1,6 @@
+-- Rendered content reconstructed after the fact by cmd/backfill-rendered-content
+-- It uses the campaign template and customer data at backfill time, not at send time
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS backfilled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Add comment for documentation
+COMMENT ON COLUMN outbound_messages.backfilled IS 'rendered_content was reconstructed by the backfill command, not recorded at send time';
@@ -0,0 +1,14 @@
+-- Campaigns paused by the worker, e.g. when their budget is spent
+ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS campaigns_status_check;
+ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+    CHECK (status IN ('draft', 'scheduled', 'pending_approval', 'sending', 'paused', 'sent', 'failed'));
+
+-- Spend cap and the running cost of sends, kept as a counter so the worker never sums messages
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS budget NUMERIC(12, 2) CHECK (budget > 0);
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS spend NUMERIC(12, 2) NOT NULL DEFAULT 0;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS paused_reason VARCHAR(50);
+
+-- Add comments for documentation
+COMMENT ON COLUMN campaigns.budget IS 'Most the campaign may spend on sends; NULL means no cap';
+COMMENT ON COLUMN campaigns.spend IS 'Cost of the campaign''s sends so far, reserved by the worker before each send';
+COMMENT ON COLUMN campaigns.paused_reason IS 'Why a paused campaign stopped, e.g. budget_exceeded';
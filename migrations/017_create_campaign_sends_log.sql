@@ -0,0 +1,20 @@
+-- Create campaign_sends_log table
+-- One row per send request, so the targeting behind a campaign's messages can be audited later
+CREATE TABLE IF NOT EXISTS campaign_sends_log (
+    id SERIAL PRIMARY KEY,
+    campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    requested_by VARCHAR(255),
+    source VARCHAR(20) NOT NULL CHECK (source IN ('api', 'csv')),
+    request JSONB NOT NULL,
+    audience_size INTEGER NOT NULL,
+    messages_queued INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create index for a campaign's history, newest first
+CREATE INDEX IF NOT EXISTS idx_campaign_sends_log_campaign_id ON campaign_sends_log(campaign_id, created_at DESC);
+
+-- Add comments for documentation
+COMMENT ON TABLE campaign_sends_log IS 'Send requests per campaign with who made them and the resulting counts';
+COMMENT ON COLUMN campaign_sends_log.request IS 'Sanitized request: customer ID count and first 100 IDs, inline customer count, flags';
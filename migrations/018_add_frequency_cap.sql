@@ -0,0 +1,8 @@
+-- Transactional-style campaigns (OTPs, receipts) that the per-customer frequency cap does not apply to
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS frequency_cap_exempt BOOLEAN NOT NULL DEFAULT false;
+
+-- Counting a customer's recent sent messages for the frequency cap
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_frequency_cap ON outbound_messages(customer_id, updated_at, status);
+
+-- Add comment for documentation
+COMMENT ON COLUMN campaigns.frequency_cap_exempt IS 'Sends skip the per-customer frequency cap (FREQUENCY_CAP_MAX_MESSAGES)';
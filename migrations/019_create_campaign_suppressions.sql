@@ -0,0 +1,13 @@
+-- Create campaign_suppressions table
+-- Phones that must not be messaged by one campaign only, e.g. a one-off list from legal
+CREATE TABLE IF NOT EXISTS campaign_suppressions (
+    campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    phone VARCHAR(20) NOT NULL,
+    added_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (campaign_id, phone)
+);
+
+-- Add comments for documentation
+COMMENT ON TABLE campaign_suppressions IS 'Per-campaign phone exclusions applied when sending and again by the worker';
+COMMENT ON COLUMN campaign_suppressions.phone IS 'Normalized E.164 phone, matched against customers.phone';
@@ -0,0 +1,14 @@
+-- Create activity_digests table
+-- One row per day summarized by the nightly digest, so reruns never send a day twice
+CREATE TABLE IF NOT EXISTS activity_digests (
+    digest_date DATE PRIMARY KEY,
+    payload JSONB,
+    claimed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP
+);
+
+-- Add comments for documentation
+COMMENT ON TABLE activity_digests IS 'Daily sending activity digests delivered to the digest webhook';
+COMMENT ON COLUMN activity_digests.digest_date IS 'Day summarized, in DIGEST_TZ';
+COMMENT ON COLUMN activity_digests.claimed_at IS 'When a worker started sending the digest; stale unsent claims may be retaken';
+COMMENT ON COLUMN activity_digests.sent_at IS 'When the digest was delivered (NULL while being sent)';
@@ -0,0 +1,28 @@
+-- Create export_jobs table
+-- Background exports of a campaign's messages, generated in batches and downloaded once complete
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id SERIAL PRIMARY KEY,
+    campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    total_rows INTEGER,
+    rows_written INTEGER NOT NULL DEFAULT 0,
+    file_name VARCHAR(255),
+    size_bytes BIGINT,
+    error TEXT,
+    requested_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+-- Pending jobs are claimed oldest first; expired files are purged by expiry
+CREATE INDEX IF NOT EXISTS idx_export_jobs_status ON export_jobs(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_export_jobs_expires_at ON export_jobs(expires_at) WHERE file_name IS NOT NULL;
+
+-- Add comments for documentation
+COMMENT ON TABLE export_jobs IS 'Campaign message exports generated in the background';
+COMMENT ON COLUMN export_jobs.total_rows IS 'Messages in the campaign when generation started';
+COMMENT ON COLUMN export_jobs.updated_at IS 'Last progress update; a running job not updated for a while is retaken';
+COMMENT ON COLUMN export_jobs.file_name IS 'Name of the generated file in EXPORT_DIR (NULL until complete and after expiry)';
+COMMENT ON COLUMN export_jobs.expires_at IS 'When the file is deleted and the download link stops working';
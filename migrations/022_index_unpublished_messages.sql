@@ -0,0 +1,8 @@
+-- Distinguish pending messages that were never published from those queued for the worker
+-- published_at is the discriminator: NULL while a pending message has never reached the broker
+COMMENT ON COLUMN outbound_messages.published_at IS 'When the message job was last (re)published to the queue; NULL on a pending message means it never reached the broker';
+
+-- The publish reconciler and the pending backlog report look up never-published pending messages
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_unpublished
+    ON outbound_messages(created_at)
+    WHERE status = 'pending' AND published_at IS NULL AND deliver_after IS NULL;
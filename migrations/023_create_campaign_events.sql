@@ -0,0 +1,36 @@
+-- Create campaign_events table
+-- Send progress of each campaign for external consumers, read by long-polling /campaigns/{id}/events
+CREATE TABLE IF NOT EXISTS campaign_events (
+    id BIGSERIAL PRIMARY KEY,
+    campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('queued', 'progress', 'completed')),
+    payload JSONB NOT NULL DEFAULT '{}',
+    dedupe_key VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Consumers read a campaign's events after the last ID they saw
+CREATE INDEX IF NOT EXISTS idx_campaign_events_campaign ON campaign_events(campaign_id, id);
+
+-- Every worker reports progress, so the same milestone is only written once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_campaign_events_dedupe
+    ON campaign_events(campaign_id, dedupe_key)
+    WHERE dedupe_key IS NOT NULL;
+
+-- Wake waiting API requests as soon as an event is written
+CREATE OR REPLACE FUNCTION notify_campaign_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('campaign_events', NEW.campaign_id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS campaign_events_notify ON campaign_events;
+CREATE TRIGGER campaign_events_notify
+    AFTER INSERT ON campaign_events
+    FOR EACH ROW EXECUTE FUNCTION notify_campaign_event();
+
+-- Add comments for documentation
+COMMENT ON TABLE campaign_events IS 'Campaign send events (batches queued, every 1000 sends, completion) for external consumers';
+COMMENT ON COLUMN campaign_events.payload IS 'Event details, e.g. messages queued or sent/failed counts';
+COMMENT ON COLUMN campaign_events.dedupe_key IS 'Identifies a milestone so workers reporting it concurrently write one event';
@@ -0,0 +1,31 @@
+-- Click tracking for links in campaign messages
+-- Campaigns with track_links have each link in a message rewritten to /r/{token}, which logs the click
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS track_links BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- One row per link in a message; retries reuse the row, so a message keeps its tokens
+CREATE TABLE IF NOT EXISTS tracked_links (
+    id SERIAL PRIMARY KEY,
+    message_id INTEGER NOT NULL REFERENCES outbound_messages(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    token VARCHAR(32) NOT NULL UNIQUE,
+    url TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (message_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS link_clicks (
+    id BIGSERIAL PRIMARY KEY,
+    link_id INTEGER NOT NULL REFERENCES tracked_links(id) ON DELETE CASCADE,
+    message_id INTEGER NOT NULL REFERENCES outbound_messages(id) ON DELETE CASCADE,
+    campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    clicked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_agent TEXT
+);
+
+-- Campaign stats count clicks per campaign
+CREATE INDEX IF NOT EXISTS idx_link_clicks_campaign ON link_clicks(campaign_id);
+
+-- Add comments for documentation
+COMMENT ON COLUMN campaigns.track_links IS 'Rewrite links in sent messages to tracked redirects';
+COMMENT ON TABLE tracked_links IS 'Links rewritten to /r/{token} redirects, by message and position in the message';
+COMMENT ON TABLE link_clicks IS 'Each visit to a tracked link redirect';
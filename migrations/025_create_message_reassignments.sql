@@ -0,0 +1,22 @@
+-- Create message_reassignments table
+-- One row per move of messages from one campaign to another, e.g. to a campaign with a
+-- corrected template, so the move can be audited later
+CREATE TABLE IF NOT EXISTS message_reassignments (
+    id SERIAL PRIMARY KEY,
+    from_campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    to_campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    messages_moved INTEGER NOT NULL DEFAULT 0,
+    requested_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create index for a campaign's moves, newest first
+CREATE INDEX IF NOT EXISTS idx_message_reassignments_from ON message_reassignments(from_campaign_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_message_reassignments_to ON message_reassignments(to_campaign_id, created_at DESC);
+
+-- Add comments for documentation
+COMMENT ON TABLE message_reassignments IS 'Messages moved between campaigns by operators, with who moved them';
+COMMENT ON COLUMN message_reassignments.status IS 'Status of the messages moved (pending or failed)';
+COMMENT ON COLUMN message_reassignments.messages_moved IS 'Raised in the same transaction as each batch of messages is moved';
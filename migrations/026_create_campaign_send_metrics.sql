@@ -0,0 +1,19 @@
+-- Create campaign_send_metrics table
+-- How long a campaign's send took, recorded when it completes, for capacity planning
+CREATE TABLE IF NOT EXISTS campaign_send_metrics (
+    campaign_id INTEGER PRIMARY KEY REFERENCES campaigns(id) ON DELETE CASCADE,
+    started_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP NOT NULL,
+    duration_seconds NUMERIC(12, 2) NOT NULL,
+    messages INTEGER NOT NULL,
+    throughput_per_minute NUMERIC(12, 2),
+    estimated_duration_seconds NUMERIC(12, 2),
+    recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Add comments for documentation
+COMMENT ON TABLE campaign_send_metrics IS 'Actual send duration and throughput of completed campaigns; rewritten if the campaign completes again';
+COMMENT ON COLUMN campaign_send_metrics.started_at IS 'When the first message was published (or created, if never published)';
+COMMENT ON COLUMN campaign_send_metrics.completed_at IS 'When the last message was sent or failed';
+COMMENT ON COLUMN campaign_send_metrics.throughput_per_minute IS 'Messages sent or failed per minute (NULL when started and completed at once)';
+COMMENT ON COLUMN campaign_send_metrics.estimated_duration_seconds IS 'Duration estimated for the same messages at the configured send rate';
@@ -0,0 +1,4 @@
+-- Placeholder syntax of a campaign's template, for templates imported from other systems
+-- NULL uses the server's TEMPLATE_SYNTAX
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS template_syntax VARCHAR(20)
+    CHECK (template_syntax IN ('braces', 'double_braces', 'brackets', 'percent'));
@@ -0,0 +1,16 @@
+-- Campaigns cancelled with a grace period sit in cancelling until cancel_at, when the
+-- reconciler finalizes them; their unsent messages are then marked cancelled
+ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS campaigns_status_check;
+ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+    CHECK (status IN ('draft', 'scheduled', 'pending_approval', 'sending', 'paused', 'sent', 'failed', 'cancelling', 'cancelled'));
+
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS cancel_at TIMESTAMP;
+
+ALTER TABLE outbound_messages DROP CONSTRAINT IF EXISTS outbound_messages_status_check;
+ALTER TABLE outbound_messages ADD CONSTRAINT outbound_messages_status_check
+    CHECK (status IN ('pending', 'sent', 'failed', 'cancelled'));
+
+-- The reconciler looks for cancellations past their deadline
+CREATE INDEX IF NOT EXISTS idx_campaigns_cancelling ON campaigns(cancel_at) WHERE status = 'cancelling';
+
+COMMENT ON COLUMN campaigns.cancel_at IS 'When a cancelling campaign''s cancellation becomes final; kept once cancelled';
@@ -0,0 +1,22 @@
+-- Phones stored before normalization on write (e.g. "0712345678") must still match the
+-- normalized form ("+254712345678") used by suppression and customer lookups, so lookups
+-- compare normalize_phone(phone). It mirrors models.NormalizePhone, including the default
+-- country code for local numbers, and returns NULL for a phone that cannot be normalized
+CREATE OR REPLACE FUNCTION normalize_phone(raw TEXT) RETURNS TEXT AS $$
+    SELECT CASE WHEN digits ~ '^[1-9][0-9]{7,14}$' THEN '+' || digits END
+    FROM (
+        SELECT CASE
+            WHEN stripped LIKE '+%' THEN substr(stripped, 2)
+            WHEN stripped LIKE '00%' THEN substr(stripped, 3)
+            WHEN stripped LIKE '0%' AND length(stripped) = 10 THEN '254' || substr(stripped, 2)
+            ELSE stripped
+        END AS digits
+        FROM (SELECT translate(btrim(raw), ' -.()', '') AS stripped) AS formatted
+    ) AS prefixed
+$$ LANGUAGE sql IMMUTABLE STRICT;
+
+CREATE INDEX IF NOT EXISTS idx_customers_normalized_phone ON customers(normalize_phone(phone));
+CREATE INDEX IF NOT EXISTS idx_campaign_suppressions_normalized_phone
+    ON campaign_suppressions(campaign_id, normalize_phone(phone));
+
+COMMENT ON FUNCTION normalize_phone(TEXT) IS 'Phone in +<country><number> form as models.NormalizePhone gives it, or NULL if invalid';
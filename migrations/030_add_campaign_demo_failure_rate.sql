@@ -0,0 +1,6 @@
+-- Demo campaigns can show failures while everything else succeeds: the worker passes a
+-- campaign's demo_failure_rate to the mock sender in place of its own, outside production only
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS demo_failure_rate NUMERIC(4, 3)
+    CHECK (demo_failure_rate BETWEEN 0 AND 1);
+
+COMMENT ON COLUMN campaigns.demo_failure_rate IS 'Mock sender failure rate for this campaign''s sends (0-1); ignored in production';
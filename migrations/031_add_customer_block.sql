@@ -0,0 +1,28 @@
+-- Administrative freeze of a customer, e.g. in fraud or abuse cases: a blocked customer is
+-- left out of every send and refused by the worker. Unlike an opt-out it is set by an operator,
+-- with the reason and who set it
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS blocked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS blocked_reason TEXT;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS blocked_by VARCHAR(255);
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS blocked_at TIMESTAMP;
+
+-- Create index for the few blocked customers a send is checked against
+CREATE INDEX IF NOT EXISTS idx_customers_blocked ON customers(id) WHERE blocked;
+
+-- Create customer_block_events table
+-- One row per block or unblock, so the history survives the customer being unblocked
+CREATE TABLE IF NOT EXISTS customer_block_events (
+    id SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('blocked', 'unblocked')),
+    reason TEXT,
+    actor VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_block_events_customer ON customer_block_events(customer_id, created_at DESC);
+
+-- Add comments for documentation
+COMMENT ON COLUMN customers.blocked IS 'Set by an operator; blocked customers are never messaged';
+COMMENT ON COLUMN customers.blocked_by IS 'Authenticated caller that blocked the customer';
+COMMENT ON TABLE customer_block_events IS 'Blocks and unblocks of customers, with who made them';
@@ -0,0 +1,135 @@
+-- Make campaign_events the append-only history of every campaign, so stats can be rebuilt
+-- from it: each message's changes and each campaign status transition are recorded by
+-- triggers, whichever code path (planner, worker, reconciler, cancellation, reassignment)
+-- made them. The send progress events for external consumers are unchanged
+
+-- Widen the event types
+ALTER TABLE campaign_events DROP CONSTRAINT IF EXISTS campaign_events_type_check;
+ALTER TABLE campaign_events ADD CONSTRAINT campaign_events_type_check
+    CHECK (type IN (
+        'queued', 'progress', 'completed',
+        'message_queued', 'message_sent', 'message_failed', 'message_cancelled',
+        'message_updated', 'message_moved', 'message_deleted', 'campaign_status'
+    ));
+
+-- Payload format, so replay can read events written before a format change
+ALTER TABLE campaign_events ADD COLUMN IF NOT EXISTS version SMALLINT NOT NULL DEFAULT 1;
+-- The message of a message event; not a foreign key, as the history outlives the message
+ALTER TABLE campaign_events ADD COLUMN IF NOT EXISTS message_id INTEGER;
+
+-- Only the consumer events wake long-polling API requests
+DROP TRIGGER IF EXISTS campaign_events_notify ON campaign_events;
+CREATE TRIGGER campaign_events_notify
+    AFTER INSERT ON campaign_events
+    FOR EACH ROW
+    WHEN (NEW.type IN ('queued', 'progress', 'completed'))
+    EXECUTE FUNCTION notify_campaign_event();
+
+-- Events are never changed or removed, except with their campaign
+-- A cascade from deleting the campaign runs inside the foreign key's trigger
+CREATE OR REPLACE FUNCTION guard_campaign_events() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND pg_trigger_depth() > 1 THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'campaign_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS campaign_events_append_only ON campaign_events;
+CREATE TRIGGER campaign_events_append_only
+    BEFORE UPDATE OR DELETE ON campaign_events
+    FOR EACH ROW EXECUTE FUNCTION guard_campaign_events();
+
+-- message_event_type names the event for a message now in status
+CREATE OR REPLACE FUNCTION message_event_type(status TEXT) RETURNS TEXT AS $$
+    SELECT CASE status
+        WHEN 'sent' THEN 'message_sent'
+        WHEN 'failed' THEN 'message_failed'
+        WHEN 'cancelled' THEN 'message_cancelled'
+        ELSE 'message_queued'
+    END;
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- message_event_payload is a message's summary fields; failures carry the attempt
+CREATE OR REPLACE FUNCTION message_event_payload(status TEXT, retry_count INTEGER, last_error TEXT, simulated BOOLEAN) RETURNS JSONB AS $$
+    SELECT jsonb_build_object('status', status, 'retry_count', retry_count, 'last_error', last_error, 'simulated', simulated)
+        || CASE WHEN status = 'failed' THEN jsonb_build_object('attempt', retry_count) ELSE '{}'::jsonb END;
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION record_message_event() RETURNS trigger AS $$
+DECLARE
+    event_type TEXT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO campaign_events (campaign_id, type, message_id)
+        SELECT OLD.campaign_id, 'message_deleted', OLD.id
+        WHERE EXISTS (SELECT 1 FROM campaigns WHERE id = OLD.campaign_id);
+        RETURN OLD;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        event_type := 'message_queued';
+    ELSIF NEW.campaign_id IS DISTINCT FROM OLD.campaign_id THEN
+        -- Moved: it leaves one campaign's history and joins the other's
+        INSERT INTO campaign_events (campaign_id, type, message_id, payload)
+        VALUES (OLD.campaign_id, 'message_moved', OLD.id, jsonb_build_object('to_campaign_id', NEW.campaign_id));
+        event_type := 'message_queued';
+    ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+        event_type := message_event_type(NEW.status);
+    ELSIF NEW.retry_count IS DISTINCT FROM OLD.retry_count OR NEW.last_error IS DISTINCT FROM OLD.last_error
+        OR NEW.simulated IS DISTINCT FROM OLD.simulated THEN
+        event_type := CASE WHEN NEW.status = 'failed' THEN 'message_failed' ELSE 'message_updated' END;
+    ELSE
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO campaign_events (campaign_id, type, message_id, payload)
+    VALUES (NEW.campaign_id, event_type, NEW.id, message_event_payload(NEW.status, NEW.retry_count, NEW.last_error, NEW.simulated));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS outbound_messages_events ON outbound_messages;
+CREATE TRIGGER outbound_messages_events
+    AFTER INSERT OR DELETE OR UPDATE OF status, retry_count, last_error, simulated, campaign_id ON outbound_messages
+    FOR EACH ROW EXECUTE FUNCTION record_message_event();
+
+CREATE OR REPLACE FUNCTION record_campaign_status_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.status IS NOT DISTINCT FROM OLD.status THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO campaign_events (campaign_id, type, payload)
+    VALUES (NEW.id, 'campaign_status', jsonb_build_object(
+        'from', CASE WHEN TG_OP = 'UPDATE' THEN OLD.status END,
+        'to', NEW.status
+    ));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS campaigns_status_events ON campaigns;
+CREATE TRIGGER campaigns_status_events
+    AFTER INSERT OR UPDATE OF status ON campaigns
+    FOR EACH ROW EXECUTE FUNCTION record_campaign_status_event();
+
+-- Baseline: the state of existing campaigns and messages, which replay starts from
+INSERT INTO campaign_events (campaign_id, type, payload)
+SELECT id, 'campaign_status', jsonb_build_object('from', NULL, 'to', status, 'baseline', true)
+FROM campaigns
+ORDER BY id;
+
+INSERT INTO campaign_events (campaign_id, type, message_id, payload)
+SELECT campaign_id, message_event_type(status), id,
+    message_event_payload(status, retry_count, last_error, simulated) || jsonb_build_object('baseline', true)
+FROM outbound_messages
+ORDER BY id;
+
+CREATE INDEX IF NOT EXISTS idx_campaign_events_message ON campaign_events(campaign_id, message_id) WHERE message_id IS NOT NULL;
+
+-- Add comments for documentation
+COMMENT ON TABLE campaign_events IS 'Append-only campaign history: consumer send events, every message change and campaign status transition';
+COMMENT ON COLUMN campaign_events.version IS 'Payload format version; replay refuses versions it does not know';
+COMMENT ON COLUMN campaign_events.message_id IS 'Message of a message_* event';
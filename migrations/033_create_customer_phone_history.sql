@@ -0,0 +1,17 @@
+-- Create customer_phone_history table
+-- One row per change of a customer's number, so messages sent to an earlier number can still
+-- be traced to the customer. Reformatting the same number is not a change
+CREATE TABLE IF NOT EXISTS customer_phone_history (
+    id SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    old_phone VARCHAR(20) NOT NULL,
+    new_phone VARCHAR(20) NOT NULL,
+    changed_by VARCHAR(255),
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_phone_history_customer ON customer_phone_history(customer_id, changed_at DESC);
+
+-- Add comments for documentation
+COMMENT ON TABLE customer_phone_history IS 'Changes of customers'' phone numbers, with who made them';
+COMMENT ON COLUMN customer_phone_history.changed_by IS 'Authenticated caller that changed the number';
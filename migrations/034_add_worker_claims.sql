@@ -0,0 +1,22 @@
+-- Track which worker is handling each message, and when each worker was last alive
+-- claimed_by and claimed_at stay as the record of the last worker to handle the message;
+-- claim_expires_at is cleared when it finishes, so a claim only blocks other workers while
+-- its holder is working on the message (or until it expires if the holder died)
+ALTER TABLE outbound_messages
+    ADD COLUMN IF NOT EXISTS claimed_by VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS claim_expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_claimed_by ON outbound_messages(claimed_by, claimed_at DESC)
+    WHERE claimed_by IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS worker_heartbeats (
+    worker_id VARCHAR(255) PRIMARY KEY,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Add comments for documentation
+COMMENT ON COLUMN outbound_messages.claimed_by IS 'WORKER_ID of the worker that last claimed the message';
+COMMENT ON COLUMN outbound_messages.claim_expires_at IS 'When an unfinished claim lapses; NULL once the claim is released';
+COMMENT ON TABLE worker_heartbeats IS 'Last time each worker identity reported itself alive';
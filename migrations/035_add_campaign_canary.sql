@@ -0,0 +1,13 @@
+-- A canary send queues a subset of its audience first and holds the campaign in canary,
+-- with the rest of the audience in send_plan, until it is continued or aborted
+ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS campaigns_status_check;
+ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+    CHECK (status IN ('draft', 'scheduled', 'pending_approval', 'sending', 'canary', 'paused', 'sent', 'failed', 'cancelling', 'cancelled'));
+
+-- Canary messages stay marked once the rest of the audience is sent, so their results can
+-- still be told apart
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS canary BOOLEAN NOT NULL DEFAULT false;
+
+-- Add comments for documentation
+COMMENT ON COLUMN outbound_messages.canary IS 'Sent as the canary of a canary send, before the rest of the audience';
+COMMENT ON COLUMN campaigns.send_plan IS 'Planned targeting stored while a send awaits approval, or the audience a canary holds back; cleared once used';
@@ -0,0 +1,6 @@
+-- An ordered campaign's jobs go to a queue of their own with a single active consumer, so its
+-- messages are processed in the order they were created
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS ordered BOOLEAN NOT NULL DEFAULT false;
+
+-- Add comments for documentation
+COMMENT ON COLUMN campaigns.ordered IS 'Process messages in creation order through a dedicated single-consumer queue';
@@ -0,0 +1,18 @@
+-- API keys issued through the admin endpoints, so keys can be added and revoked without a deploy
+-- Only a SHA-256 hash of each key is stored; the key itself is shown once when it is created
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    label VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'member')),
+    team VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+-- Add comments for documentation
+COMMENT ON TABLE api_keys IS 'API keys accepted in the X-API-Key header alongside the API_KEYS bootstrap keys';
+COMMENT ON COLUMN api_keys.key_hash IS 'Hex SHA-256 of the key';
+COMMENT ON COLUMN api_keys.last_used_at IS 'Last authenticated request, recorded in batches so it may lag by up to 30 seconds';
@@ -0,0 +1,11 @@
+-- Engagement score computed from each customer's message history by the engagement scorer
+-- (cmd/score-engagement, or the worker every ENGAGEMENT_SCORE_INTERVAL). NULL until first scored
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS engagement_score DOUBLE PRECISION;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS engagement_scored_at TIMESTAMP;
+
+-- Create index for listing and targeting customers above a score
+CREATE INDEX IF NOT EXISTS idx_customers_engagement_score ON customers(engagement_score) WHERE engagement_score IS NOT NULL;
+
+-- Add comments for documentation
+COMMENT ON COLUMN customers.engagement_score IS 'Recency-weighted sent messages less a penalty per failed message';
+COMMENT ON COLUMN customers.engagement_scored_at IS 'When engagement_score was last computed';
@@ -0,0 +1,29 @@
+-- Runtime settings shared by the API and every worker, so a change survives restarts and
+-- applies everywhere at once. sending_enabled is the kill switch for all outbound sending
+CREATE TABLE IF NOT EXISTS settings (
+    key VARCHAR(100) PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_by VARCHAR(255),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO settings (key, value) VALUES ('sending_enabled', 'true')
+ON CONFLICT (key) DO NOTHING;
+
+-- Create settings_audit_log table
+-- One row per change of a setting, with why it was changed and by whom
+CREATE TABLE IF NOT EXISTS settings_audit_log (
+    id SERIAL PRIMARY KEY,
+    key VARCHAR(100) NOT NULL,
+    value TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    actor VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_settings_audit_log_key ON settings_audit_log(key, created_at DESC);
+
+-- Add comments for documentation
+COMMENT ON TABLE settings IS 'Runtime settings read by the API and workers';
+COMMENT ON COLUMN settings.updated_by IS 'Authenticated caller that last changed the setting';
+COMMENT ON TABLE settings_audit_log IS 'Changes of runtime settings, with the reason and who made them';
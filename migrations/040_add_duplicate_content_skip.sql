@@ -0,0 +1,28 @@
+-- Messages the worker skips because the customer got the same content from another campaign
+-- within DUPLICATE_SEND_WINDOW are left in skipped_duplicate_content, never to be sent
+-- The status is 25 characters, longer than the column allowed until now
+ALTER TABLE outbound_messages ALTER COLUMN status TYPE VARCHAR(32);
+ALTER TABLE outbound_messages DROP CONSTRAINT IF EXISTS outbound_messages_status_check;
+ALTER TABLE outbound_messages ADD CONSTRAINT outbound_messages_status_check
+    CHECK (status IN ('pending', 'sent', 'failed', 'cancelled', 'skipped_duplicate_content'));
+
+ALTER TABLE campaign_events DROP CONSTRAINT IF EXISTS campaign_events_type_check;
+ALTER TABLE campaign_events ADD CONSTRAINT campaign_events_type_check
+    CHECK (type IN (
+        'queued', 'progress', 'completed',
+        'message_queued', 'message_sent', 'message_failed', 'message_cancelled', 'message_skipped',
+        'message_updated', 'message_moved', 'message_deleted', 'campaign_status'
+    ));
+
+CREATE OR REPLACE FUNCTION message_event_type(status TEXT) RETURNS TEXT AS $$
+    SELECT CASE status
+        WHEN 'sent' THEN 'message_sent'
+        WHEN 'failed' THEN 'message_failed'
+        WHEN 'cancelled' THEN 'message_cancelled'
+        WHEN 'skipped_duplicate_content' THEN 'message_skipped'
+        ELSE 'message_queued'
+    END;
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- The worker looks for a send of the same content to the customer before every send
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_fingerprint_sent ON outbound_messages(customer_id, content_fingerprint, updated_at) WHERE status = 'sent';
@@ -0,0 +1,17 @@
+-- A campaign with a part delimiter splits its template into parts, each sent to a customer as
+-- a message of its own, in order; the parts of one customer's send form a group
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS part_delimiter VARCHAR(20);
+
+-- group_id is the ID of the group's first part; messages sent whole have none
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS group_id INTEGER;
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS part SMALLINT NOT NULL DEFAULT 1;
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS part_count SMALLINT NOT NULL DEFAULT 1;
+
+-- The worker and reconciler find a group's next part, and its previous one, by position
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_group ON outbound_messages(group_id, part) WHERE group_id IS NOT NULL;
+
+-- Add comments for documentation
+COMMENT ON COLUMN campaigns.part_delimiter IS 'Splits the template into parts sent as separate messages in order; NULL sends it whole';
+COMMENT ON COLUMN outbound_messages.group_id IS 'ID of the first part of the multi-part send this message belongs to';
+COMMENT ON COLUMN outbound_messages.part IS 'Position of the message in its group, from 1';
+COMMENT ON COLUMN outbound_messages.part_count IS 'Number of parts in the message''s group';
@@ -0,0 +1,9 @@
+-- A message resent as a fresh attempt is a new row pointing at the one it was resent from;
+-- the original is left as it was, for audit
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS resent_from_message_id INTEGER REFERENCES outbound_messages(id) ON DELETE SET NULL;
+
+-- Campaign stats count resends in a bucket of their own, apart from the send's messages
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_resends ON outbound_messages(campaign_id, resent_from_message_id) WHERE resent_from_message_id IS NOT NULL;
+
+-- Add comments for documentation
+COMMENT ON COLUMN outbound_messages.resent_from_message_id IS 'Message this one was resent from (POST /messages/{id}/resend); NULL for messages of the send itself';
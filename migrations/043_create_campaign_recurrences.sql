@@ -0,0 +1,37 @@
+-- A recurring campaign is the definition its occurrences are cloned from; it stays recurring
+-- while each occurrence is sent as a campaign of its own, to the audience in its send_plan
+ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS campaigns_status_check;
+ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+    CHECK (status IN ('draft', 'scheduled', 'pending_approval', 'sending', 'canary', 'paused', 'sent', 'failed', 'cancelling', 'cancelled', 'recurring'));
+
+-- Create campaign_recurrences table
+CREATE TABLE IF NOT EXISTS campaign_recurrences (
+    campaign_id INTEGER PRIMARY KEY REFERENCES campaigns(id) ON DELETE CASCADE,
+    rule JSONB NOT NULL,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    next_occurrence_at TIMESTAMP,
+    occurrences INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create index for the scheduler's search for due occurrences
+CREATE INDEX IF NOT EXISTS idx_campaign_recurrences_due ON campaign_recurrences(next_occurrence_at)
+    WHERE NOT paused AND next_occurrence_at IS NOT NULL;
+
+-- Create campaign_occurrences table
+-- One row per occurrence sent; the unique occurrence time keeps two API instances from both
+-- cloning the same one
+CREATE TABLE IF NOT EXISTS campaign_occurrences (
+    id SERIAL PRIMARY KEY,
+    parent_campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    campaign_id INTEGER NOT NULL UNIQUE REFERENCES campaigns(id) ON DELETE CASCADE,
+    occurrence_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (parent_campaign_id, occurrence_at)
+);
+
+-- Add comments for documentation
+COMMENT ON COLUMN campaign_recurrences.rule IS 'Frequency, day, local time, time zone and end of the recurrence (models.RecurrenceRule)';
+COMMENT ON COLUMN campaign_recurrences.next_occurrence_at IS 'UTC time of the next occurrence; NULL once the rule has ended';
+COMMENT ON TABLE campaign_occurrences IS 'Campaigns cloned from a recurring campaign, one per occurrence';
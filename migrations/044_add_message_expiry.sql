@@ -0,0 +1,24 @@
+-- Messages the worker finds past their send's process_by deadline are left in expired, never
+-- to be sent; late delivery of a one-time code is worse than none
+ALTER TABLE outbound_messages DROP CONSTRAINT IF EXISTS outbound_messages_status_check;
+ALTER TABLE outbound_messages ADD CONSTRAINT outbound_messages_status_check
+    CHECK (status IN ('pending', 'sent', 'failed', 'cancelled', 'skipped_duplicate_content', 'expired'));
+
+ALTER TABLE campaign_events DROP CONSTRAINT IF EXISTS campaign_events_type_check;
+ALTER TABLE campaign_events ADD CONSTRAINT campaign_events_type_check
+    CHECK (type IN (
+        'queued', 'progress', 'completed',
+        'message_queued', 'message_sent', 'message_failed', 'message_cancelled', 'message_skipped', 'message_expired',
+        'message_updated', 'message_moved', 'message_deleted', 'campaign_status'
+    ));
+
+CREATE OR REPLACE FUNCTION message_event_type(status TEXT) RETURNS TEXT AS $$
+    SELECT CASE status
+        WHEN 'sent' THEN 'message_sent'
+        WHEN 'failed' THEN 'message_failed'
+        WHEN 'cancelled' THEN 'message_cancelled'
+        WHEN 'skipped_duplicate_content' THEN 'message_skipped'
+        WHEN 'expired' THEN 'message_expired'
+        ELSE 'message_queued'
+    END;
+$$ LANGUAGE SQL IMMUTABLE;
@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// failingNotifier fails every notification
+type failingNotifier struct{}
+
+func (n *failingNotifier) Notify(ctx context.Context, subject string, payload interface{}) error {
+	return errors.New("webhook returned status 502")
+}
+
+// setupActivityDigestTest creates a digest service for Nairobi over a day of seeded activity
+func setupActivityDigestTest(t *testing.T, notifier *recordingNotifier) (*service.ActivityDigestService, *MockDigestRepository) {
+	t.Helper()
+
+	nairobi, err := time.LoadLocation("Africa/Nairobi")
+	AssertNoError(t, err)
+
+	digestRepo := NewMockDigestRepository()
+	digestRepo.CountFinishedCampaignsFunc = func(ctx context.Context, from, to time.Time) (int, int, error) {
+		return 3, 1, nil
+	}
+	digestRepo.ListChannelActivityFunc = func(ctx context.Context, from, to time.Time) ([]*models.ChannelActivity, error) {
+		return []*models.ChannelActivity{
+			{Channel: models.ChannelSMS, Sent: 1180, Failed: 20, Simulated: 100},
+			{Channel: models.ChannelWhatsApp, Sent: 40, Failed: 2},
+		}, nil
+	}
+	digestRepo.ListTopErrorsFunc = func(ctx context.Context, from, to time.Time, limit int) ([]*models.ErrorCount, error) {
+		return []*models.ErrorCount{{Error: "provider timeout", Count: 12}, {Error: "invalid number", Count: 8}}, nil
+	}
+
+	svc := service.NewActivityDigestService(
+		digestRepo,
+		notifier,
+		config.SendingConfig{CostPerSMS: 0.8, CostPerWhatsApp: 0.5},
+		config.DigestConfig{WebhookURL: "http://digest.example", SendHour: 7, Location: nairobi},
+	)
+	return svc, digestRepo
+}
+
+// TestActivityDigest_Build tests the aggregates, spend and local day boundaries of a digest
+func TestActivityDigest_Build(t *testing.T) {
+	svc, digestRepo := setupActivityDigestTest(t, &recordingNotifier{})
+	var from, to time.Time
+	digestRepo.CountFinishedCampaignsFunc = func(ctx context.Context, f, tt time.Time) (int, int, error) {
+		from, to = f, tt
+		return 3, 1, nil
+	}
+
+	// 22:00 UTC on the 14th is already the 15th in Nairobi
+	digest, err := svc.Build(context.Background(), time.Date(2026, 1, 14, 22, 0, 0, 0, time.UTC))
+	AssertNoError(t, err)
+
+	AssertEqual(t, digest.Date, "2026-01-15")
+	AssertEqual(t, digest.TimeZone, "Africa/Nairobi")
+	AssertEqual(t, from.UTC(), time.Date(2026, 1, 14, 21, 0, 0, 0, time.UTC))
+	AssertEqual(t, to.Sub(from), 24*time.Hour)
+	AssertEqual(t, digest.CampaignsCompleted, 3)
+	AssertEqual(t, digest.CampaignsFailed, 1)
+	AssertEqual(t, digest.Channels[0].Spend, 864.0) // 1080 billable SMS at 0.80
+	AssertEqual(t, digest.Channels[1].Spend, 20.0)
+	AssertEqual(t, digest.Spend, 884.0)
+	AssertEqual(t, len(digest.TopErrors), 2)
+	AssertEqual(t, digest.TopErrors[0].Error, "provider timeout")
+}
+
+// TestActivityDigest_RerunSendsOnce tests that sending a day again delivers nothing
+func TestActivityDigest_RerunSendsOnce(t *testing.T) {
+	notifier := &recordingNotifier{}
+	svc, digestRepo := setupActivityDigestTest(t, notifier)
+	day := time.Date(2026, 1, 14, 12, 0, 0, 0, time.UTC)
+
+	sent, err := svc.Send(context.Background(), day)
+	AssertNoError(t, err)
+	AssertEqual(t, sent, true)
+
+	sent, err = svc.Send(context.Background(), day)
+	AssertNoError(t, err)
+	AssertEqual(t, sent, false)
+
+	AssertEqual(t, len(notifier.payloads), 1)
+	AssertEqual(t, notifier.subjects[0], "Sending digest for 2026-01-14: 1220 sent, 22 failed")
+	AssertEqual(t, digestRepo.Calls["CountFinishedCampaigns"], 1)
+
+	var recorded models.ActivityDigest
+	AssertNoError(t, json.Unmarshal(digestRepo.Sent["2026-01-14"], &recorded))
+	AssertEqual(t, recorded.Spend, 884.0)
+
+	// The next day is its own digest
+	sent, err = svc.Send(context.Background(), day.AddDate(0, 0, 1))
+	AssertNoError(t, err)
+	AssertEqual(t, sent, true)
+	AssertEqual(t, len(notifier.payloads), 2)
+}
+
+// TestActivityDigest_FailedDeliveryRetried tests that a failed delivery releases the day for a retry
+func TestActivityDigest_FailedDeliveryRetried(t *testing.T) {
+	notifier := &recordingNotifier{}
+	svc, digestRepo := setupActivityDigestTest(t, notifier)
+	failing := service.NewActivityDigestService(digestRepo, &failingNotifier{}, config.SendingConfig{}, config.DigestConfig{Location: time.UTC})
+	day := time.Date(2026, 1, 14, 12, 0, 0, 0, time.UTC)
+
+	_, err := failing.Send(context.Background(), day)
+	AssertError(t, err, "failed to send activity digest: webhook returned status 502")
+	AssertEqual(t, digestRepo.Calls["Release"], 1)
+	AssertEqual(t, len(digestRepo.Sent), 0)
+
+	sent, err := svc.Send(context.Background(), day)
+	AssertNoError(t, err)
+	AssertEqual(t, sent, true)
+	AssertEqual(t, len(notifier.payloads), 1)
+}
+
+// TestDigestRepository_Queries tests the aggregation queries and the once-per-day claim
+func TestDigestRepository_Queries(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewDigestRepository(db)
+	from := time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FILTER \(WHERE status = 'sent'\), COUNT\(\*\) FILTER \(WHERE status = 'failed'\) FROM campaigns WHERE updated_at >= \$1 AND updated_at < \$2`).
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"completed", "failed"}).AddRow(3, 1))
+	completed, failed, err := repo.CountFinishedCampaigns(context.Background(), from, to)
+	AssertNoError(t, err)
+	AssertEqual(t, completed, 3)
+	AssertEqual(t, failed, 1)
+
+	mock.ExpectQuery(`FROM outbound_messages m JOIN campaigns c ON c.id = m.campaign_id WHERE m.status IN \('sent', 'failed'\) AND m.updated_at >= \$1 AND m.updated_at < \$2 GROUP BY c.channel`).
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"channel", "sent", "failed", "simulated"}).AddRow("sms", 1180, 20, 100))
+	channels, err := repo.ListChannelActivity(context.Background(), from, to)
+	AssertNoError(t, err)
+	AssertEqual(t, channels[0].Channel, models.ChannelSMS)
+	AssertEqual(t, channels[0].Simulated, 100)
+
+	mock.ExpectQuery(`SELECT last_error, COUNT\(\*\) FROM outbound_messages WHERE status = 'failed'(.+)GROUP BY last_error ORDER BY COUNT\(\*\) DESC, last_error LIMIT \$3`).
+		WithArgs(from, to, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"last_error", "count"}).AddRow("provider timeout", 12))
+	topErrors, err := repo.ListTopErrors(context.Background(), from, to, 5)
+	AssertNoError(t, err)
+	AssertEqual(t, topErrors[0].Count, 12)
+
+	// A date already sent is not claimed again
+	mock.ExpectExec(`INSERT INTO activity_digests \(digest_date\) VALUES \(\$1\) ON CONFLICT \(digest_date\) DO UPDATE SET claimed_at = CURRENT_TIMESTAMP WHERE activity_digests.sent_at IS NULL`).
+		WithArgs("2026-01-14", 900.0).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	claimed, err := repo.Claim(context.Background(), "2026-01-14", 15*time.Minute)
+	AssertNoError(t, err)
+	AssertEqual(t, claimed, false)
+
+	mock.ExpectExec(`UPDATE activity_digests SET payload = \$2, sent_at = CURRENT_TIMESTAMP WHERE digest_date = \$1`).
+		WithArgs("2026-01-14", []byte(`{}`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	AssertNoError(t, repo.MarkSent(context.Background(), "2026-01-14", []byte(`{}`)))
+
+	mock.ExpectExec(`DELETE FROM activity_digests WHERE digest_date = \$1 AND sent_at IS NULL`).
+		WithArgs("2026-01-15").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	AssertNoError(t, repo.Release(context.Background(), "2026-01-15"))
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestLoadDigestConfig tests DIGEST_SEND_HOUR and DIGEST_TZ parsing and the next send time
+func TestLoadDigestConfig(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Digest.Enabled(), false)
+	AssertEqual(t, cfg.Digest.SendHour, 7)
+	AssertEqual(t, cfg.Digest.Location, time.UTC)
+
+	t.Setenv("DIGEST_WEBHOOK_URL", "http://digest.example")
+	t.Setenv("DIGEST_SEND_HOUR", "6")
+	t.Setenv("DIGEST_TZ", "Africa/Nairobi")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Digest.Enabled(), true)
+	// 04:00 UTC is 07:00 in Nairobi, past the send hour, so the next send is tomorrow at 06:00 (03:00 UTC)
+	next := cfg.Digest.NextRun(time.Date(2026, 1, 14, 4, 0, 0, 0, time.UTC))
+	AssertEqual(t, next.UTC(), time.Date(2026, 1, 15, 3, 0, 0, 0, time.UTC))
+
+	t.Setenv("DIGEST_SEND_HOUR", "24")
+	_, err = config.Load()
+	AssertError(t, err, "DIGEST_SEND_HOUR must be an hour from 0 to 23")
+
+	t.Setenv("DIGEST_SEND_HOUR", "7")
+	t.Setenv("DIGEST_TZ", "Mars/Olympus")
+	_, err = config.Load()
+	if err == nil {
+		t.Error("Expected an error for an unknown DIGEST_TZ")
+	}
+}
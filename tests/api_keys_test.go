@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/middleware"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/reqctx"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// issueAPIKey issues a key through the admin endpoint and returns it with its ID
+func issueAPIKey(t *testing.T, f *routerFixture, body string) (string, int) {
+	t.Helper()
+
+	rr := f.serve("POST", "/admin/api-keys", "application/json", body, routerAdminKey, routerAdminKey)
+	AssertStatusCode(t, rr, http.StatusCreated)
+	var created struct {
+		ID  int    `json:"id"`
+		Key string `json:"key"`
+	}
+	ParseJSONResponse(t, rr, &created)
+	if created.Key == "" {
+		t.Fatal("Expected the key in the response")
+	}
+	return created.Key, created.ID
+}
+
+// TestAPIKeys_IssueAndAuthenticate tests that an issued key authenticates as its user and role,
+// that only its hash is stored, and that the listing never shows a key
+func TestAPIKeys_IssueAndAuthenticate(t *testing.T) {
+	f := newRouterFixture(t)
+
+	key, id := issueAPIKey(t, f, `{"label": "crm sync", "user_id": "crm", "role": "member", "team": "growth"}`)
+	AssertEqual(t, id, 1)
+	AssertStatusCode(t, f.serve("GET", "/campaigns/1", "", "", key, ""), http.StatusOK)
+
+	// A member key does not open admin endpoints
+	rr := f.serve("POST", "/admin/read-only", "application/json", `{"read_only": true}`, key, "")
+	AssertStatusCode(t, rr, http.StatusUnauthorized)
+
+	stored := f.apiKeyRepo.Keys[0]
+	if stored.KeyHash == key || len(stored.KeyHash) != 64 {
+		t.Fatalf("Expected a SHA-256 hash to be stored, got %q", stored.KeyHash)
+	}
+	AssertEqual(t, *stored.Team, "growth")
+
+	rr = f.serve("GET", "/admin/api-keys", "", "", routerAdminKey, routerAdminKey)
+	AssertStatusCode(t, rr, http.StatusOK)
+	if strings.Contains(rr.Body.String(), key) || strings.Contains(rr.Body.String(), stored.KeyHash) {
+		t.Fatalf("Expected the listing to leave out keys and hashes: %s", rr.Body.String())
+	}
+	var listed []*models.APIKey
+	ParseJSONResponse(t, rr, &listed)
+	AssertEqual(t, len(listed), 1)
+	AssertEqual(t, listed[0].Label, "crm sync")
+
+	// Bootstrap keys keep working alongside
+	AssertStatusCode(t, f.serve("GET", "/campaigns/1", "", "", routerMemberKey, ""), http.StatusOK)
+}
+
+// TestAPIKeys_RevokeWithinCacheWindow tests that a key revoked here is refused straight away,
+// and one revoked by another API process once this one's cache refreshes
+func TestAPIKeys_RevokeWithinCacheWindow(t *testing.T) {
+	f := newRouterFixture(t)
+	local, localID := issueAPIKey(t, f, `{"label": "local", "user_id": "ops", "role": "member"}`)
+	remote, remoteID := issueAPIKey(t, f, `{"label": "remote", "user_id": "ops", "role": "member"}`)
+
+	rr := f.serve("DELETE", "/admin/api-keys/"+strconv.Itoa(localID), "", "", routerAdminKey, routerAdminKey)
+	AssertStatusCode(t, rr, http.StatusOK)
+	var revoked models.APIKey
+	ParseJSONResponse(t, rr, &revoked)
+	AssertEqual(t, revoked.IsRevoked(), true)
+	AssertStatusCode(t, f.serve("GET", "/campaigns/1", "", "", local, ""), http.StatusUnauthorized)
+
+	// Another process sharing the database revokes the other key
+	other := service.NewAPIKeyService(f.apiKeyRepo)
+	AssertNoError(t, other.Refresh(context.Background()))
+	_, err := other.Revoke(context.Background(), remoteID)
+	AssertNoError(t, err)
+
+	// This process still accepts it until its cache is refreshed
+	AssertStatusCode(t, f.serve("GET", "/campaigns/1", "", "", remote, ""), http.StatusOK)
+	AssertNoError(t, f.apiKeys.Refresh(context.Background()))
+	AssertStatusCode(t, f.serve("GET", "/campaigns/1", "", "", remote, ""), http.StatusUnauthorized)
+
+	// Unknown keys are reported
+	rr = f.serve("DELETE", "/admin/api-keys/99", "", "", routerAdminKey, routerAdminKey)
+	AssertStatusCode(t, rr, http.StatusNotFound)
+}
+
+// TestAPIKeys_LastUsedOnRefresh tests that uses are written in one batch on refresh, not per request
+func TestAPIKeys_LastUsedOnRefresh(t *testing.T) {
+	repo := NewMockAPIKeyRepository()
+	svc := service.NewAPIKeyService(repo)
+	created, err := svc.Create(context.Background(), &service.CreateAPIKeyRequest{Label: "crm", UserID: "crm", Role: config.RoleMember})
+	AssertNoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		if svc.Lookup(created.Key) == nil {
+			t.Fatal("Expected the issued key to be found")
+		}
+	}
+	if svc.Lookup("wrong-key") != nil {
+		t.Fatal("Expected an unknown key not to be found")
+	}
+	AssertEqual(t, repo.Calls["MarkUsed"], 0)
+
+	// A failed write is retried on the next refresh
+	repo.MarkUsedFunc = func(ctx context.Context, ids []int, usedAt time.Time) error {
+		return errors.New("database unavailable")
+	}
+	AssertNoError(t, svc.Refresh(context.Background()))
+	if repo.Keys[0].LastUsedAt != nil {
+		t.Fatal("Expected no use recorded after a failed write")
+	}
+
+	var marked []int
+	repo.MarkUsedFunc = func(ctx context.Context, ids []int, usedAt time.Time) error {
+		marked = append(marked, ids...)
+		return nil
+	}
+	AssertNoError(t, svc.Refresh(context.Background()))
+	AssertEqual(t, len(marked), 1)
+	AssertEqual(t, marked[0], created.ID)
+
+	// Nothing new to record
+	marked = nil
+	AssertNoError(t, svc.Refresh(context.Background()))
+	AssertEqual(t, len(marked), 0)
+}
+
+// TestAPIKeys_CreateValidation tests that keys need a label, user and known role
+func TestAPIKeys_CreateValidation(t *testing.T) {
+	svc := service.NewAPIKeyService(NewMockAPIKeyRepository())
+
+	tests := []struct {
+		name string
+		req  service.CreateAPIKeyRequest
+		want string
+	}{
+		{"no label", service.CreateAPIKeyRequest{UserID: "crm", Role: config.RoleMember}, "label is required"},
+		{"no user", service.CreateAPIKeyRequest{Label: "crm", Role: config.RoleMember}, "user_id is required"},
+		{"unknown role", service.CreateAPIKeyRequest{Label: "crm", UserID: "crm", Role: "owner"}, `role must be "admin" or "member"`},
+		{"long label", service.CreateAPIKeyRequest{Label: strings.Repeat("a", 256), UserID: "crm", Role: config.RoleMember}, "at most 255 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Create(context.Background(), &tt.req)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected error containing %q but got %v", tt.want, err)
+			}
+		})
+	}
+	AssertEqual(t, svc.HasKeys(), false)
+}
+
+// TestAuthenticate_EnabledByBootstrapKeys tests that authentication is on only while bootstrap
+// keys are configured: issued keys are accepted alongside them, and issuing or revoking keys
+// never turns it on or off
+func TestAuthenticate_EnabledByBootstrapKeys(t *testing.T) {
+	svc := service.NewAPIKeyService(NewMockAPIKeyRepository())
+	team := "growth"
+	created, err := svc.Create(context.Background(), &service.CreateAPIKeyRequest{Label: "ops", UserID: "amina", Role: config.RoleAdmin, Team: &team})
+	AssertNoError(t, err)
+
+	var identity *reqctx.Identity
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ = reqctx.IdentityFrom(r.Context())
+	})
+	serve := func(handler http.Handler, key string) *httptest.ResponseRecorder {
+		identity = nil
+		req := httptest.NewRequest("GET", "/campaigns", nil)
+		if key != "" {
+			req.Header.Set(middleware.APIKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Without bootstrap keys an issued key neither turns authentication on nor identifies
+	disabled := middleware.Authenticate(nil, svc)(next)
+	AssertStatusCode(t, serve(disabled, ""), http.StatusOK)
+	AssertStatusCode(t, serve(disabled, created.Key), http.StatusOK)
+	if identity != nil {
+		t.Fatal("Expected no identity while authentication is disabled")
+	}
+
+	enabled := middleware.Authenticate([]config.APIKey{{Key: "k-bootstrap", UserID: "ops", Role: config.RoleAdmin}}, svc)(next)
+	AssertStatusCode(t, serve(enabled, ""), http.StatusUnauthorized)
+	AssertStatusCode(t, serve(enabled, created.Key), http.StatusOK)
+	AssertEqual(t, identity.UserID, "amina")
+	AssertEqual(t, identity.Team, "growth")
+	AssertEqual(t, identity.IsAdmin(), true)
+
+	// Revoking every issued key leaves authentication on
+	_, err = svc.Revoke(context.Background(), created.ID)
+	AssertNoError(t, err)
+	AssertStatusCode(t, serve(enabled, ""), http.StatusUnauthorized)
+	AssertStatusCode(t, serve(enabled, created.Key), http.StatusUnauthorized)
+}
+
+// TestAPIKeyRepository_Queries tests the revoke and last-used queries
+func TestAPIKeyRepository_Queries(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewAPIKeyRepository(db)
+
+	mock.ExpectQuery(`UPDATE api_keys SET revoked_at = COALESCE\(revoked_at, CURRENT_TIMESTAMP\) WHERE id = \$1 RETURNING`).
+		WithArgs(9).
+		WillReturnError(sql.ErrNoRows)
+	_, err := repo.Revoke(context.Background(), 9)
+	if !errors.Is(err, repository.ErrAPIKeyNotFound) {
+		t.Fatalf("Expected ErrAPIKeyNotFound but got %v", err)
+	}
+
+	usedAt := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at = \$2 WHERE id = ANY\(\$1\) AND \(last_used_at IS NULL OR last_used_at < \$2\)`).
+		WithArgs("{1,2}", usedAt).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	AssertNoError(t, repo.MarkUsed(context.Background(), []int{1, 2}, usedAt))
+
+	// Nothing to record runs no query
+	AssertNoError(t, repo.MarkUsed(context.Background(), nil, usedAt))
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
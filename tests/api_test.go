@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"smsleopard/internal/config"
 	"smsleopard/internal/handler"
 	"smsleopard/internal/models"
 	"smsleopard/internal/repository"
@@ -32,8 +33,9 @@ func setupAPITestHandler(t *testing.T, db *sql.DB) *handler.CampaignHandler {
 		customerRepo,
 		messageRepo,
 		templateSvc,
-		nil, // No queue publisher needed for these tests
+		NewMockPublisher(),
 		db,
+		config.ApprovalConfig{},
 	)
 
 	return handler.NewCampaignHandler(campaignSvc)
@@ -57,7 +59,7 @@ func TestAPI_CreateCampaign_Success(t *testing.T) {
 	db, mock := NewMockDB(t)
 	defer db.Close()
 
-	// Mock the INSERT query - only 5 params, RETURNING 3 columns
+	// Mock the INSERT query - 10 params, RETURNING 3 columns
 	mock.ExpectQuery("INSERT INTO campaigns").
 		WithArgs(
 			"Test Campaign",
@@ -65,6 +67,16 @@ func TestAPI_CreateCampaign_Success(t *testing.T) {
 			models.CampaignStatusDraft,
 			"Hello {first_name}!",
 			sqlmock.AnyArg(), // scheduled_at
+			sqlmock.AnyArg(), // tags
+			nil,              // created_by
+			nil,              // team
+			nil,              // budget
+			false,            // frequency_cap_exempt
+			false,            // track_links
+			nil,              // template_syntax
+			nil,              // demo_failure_rate
+			false,            // ordered
+			nil,              // part_delimiter
 		).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
 			AddRow(1, time.Now(), time.Now()))
@@ -111,7 +123,7 @@ func TestAPI_CreateCampaign_Scheduled(t *testing.T) {
 
 	scheduledAt := time.Now().Add(24 * time.Hour)
 
-	// Mock the INSERT query - only 5 params, RETURNING 3 columns
+	// Mock the INSERT query - 10 params, RETURNING 3 columns
 	mock.ExpectQuery("INSERT INTO campaigns").
 		WithArgs(
 			"Scheduled Campaign",
@@ -119,6 +131,16 @@ func TestAPI_CreateCampaign_Scheduled(t *testing.T) {
 			models.CampaignStatusScheduled, // Should be scheduled, not draft
 			"Welcome {first_name}!",
 			sqlmock.AnyArg(), // scheduled_at
+			sqlmock.AnyArg(), // tags
+			nil,              // created_by
+			nil,              // team
+			nil,              // budget
+			false,            // frequency_cap_exempt
+			false,            // track_links
+			nil,              // template_syntax
+			nil,              // demo_failure_rate
+			false,            // ordered
+			nil,              // part_delimiter
 		).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
 			AddRow(1, time.Now(), time.Now()))
@@ -284,7 +306,7 @@ func TestAPI_SendCampaign_Success(t *testing.T) {
 
 	// Mock campaign query
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team", "budget", "spend", "paused_reason", "frequency_cap_exempt", "track_links", "template_syntax", "cancel_at", "demo_failure_rate", "ordered", "part_delimiter",
 	}).AddRow(
 		campaign.ID,
 		campaign.Name,
@@ -294,6 +316,7 @@ func TestAPI_SendCampaign_Success(t *testing.T) {
 		campaign.ScheduledAt,
 		campaign.CreatedAt,
 		campaign.UpdatedAt,
+		"{}", nil, nil, nil, 0, nil, false, false, nil, nil, nil, false, nil,
 	)
 	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE id").
 		WithArgs(campaign.ID).
@@ -301,7 +324,7 @@ func TestAPI_SendCampaign_Success(t *testing.T) {
 
 	// Mock customers query
 	customerRows := sqlmock.NewRows([]string{
-		"id", "phone", "first_name", "last_name", "location", "preferred_product", "created_at",
+		"id", "phone", "first_name", "last_name", "location", "preferred_product", "contact_window_start", "contact_window_end", "created_at",
 	})
 	for _, customer := range customers {
 		customerRows.AddRow(
@@ -311,6 +334,8 @@ func TestAPI_SendCampaign_Success(t *testing.T) {
 			customer.LastName,
 			customer.Location,
 			customer.PreferredProduct,
+			customer.ContactWindowStart,
+			customer.ContactWindowEnd,
 			customer.CreatedAt,
 		)
 	}
@@ -318,26 +343,42 @@ func TestAPI_SendCampaign_Success(t *testing.T) {
 		WithArgs(sqlmock.AnyArg()).
 		WillReturnRows(customerRows)
 
-	// Mock transaction for batch message insert
+	// Mock blocked customers check (none blocked)
+	mock.ExpectQuery("SELECT id FROM customers WHERE blocked").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// Mock the send's transaction: the guarded status change, then the batch insert
 	mock.ExpectBegin()
 
+	// Mock campaign status update
+	mock.ExpectExec("UPDATE campaigns").
+		WithArgs(models.CampaignStatusSending, campaign.ID, models.CampaignStatusDraft).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
 	// Mock prepare statement for batch insert
 	mock.ExpectPrepare("INSERT INTO outbound_messages")
 
 	// Mock each individual insert query (3 customers)
 	for i := 1; i <= 3; i++ {
 		mock.ExpectQuery("INSERT INTO outbound_messages").
-			WithArgs(campaign.ID, i, models.MessageStatusPending, sqlmock.AnyArg()).
+			WithArgs(campaign.ID, i, models.MessageStatusPending, sqlmock.AnyArg(), sqlmock.AnyArg()).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
 				AddRow(i, time.Now(), time.Now()))
 	}
 
 	mock.ExpectCommit()
 
-	// Mock campaign status update (separate transaction)
-	mock.ExpectExec("UPDATE campaigns SET status").
-		WithArgs(models.CampaignStatusSending, campaign.ID).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Mock each message's publish time, recorded before its job is published
+	for i := 1; i <= 3; i++ {
+		mock.ExpectExec("UPDATE outbound_messages SET published_at").
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	// Mock the send log entry
+	mock.ExpectQuery("INSERT INTO campaign_sends_log").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
 
 	// Setup handler and router
 	campaignHandler := setupAPITestHandler(t, db)
@@ -362,8 +403,8 @@ func TestAPI_SendCampaign_Success(t *testing.T) {
 	ParseJSONResponse(t, resp, &result)
 
 	// Verify result
-	AssertEqual(t, int(result["campaign_id"].(float64)), campaign.ID)
-	AssertEqual(t, int(result["messages_queued"].(float64)), 3)
+	AssertEqual(t, result["campaign_id"], float64(campaign.ID))
+	AssertEqual(t, result["messages_queued"], float64(3))
 	AssertEqual(t, result["status"], "sending")
 
 	// Verify expectations met
@@ -466,7 +507,7 @@ func TestAPI_SendCampaign_InvalidStatus(t *testing.T) {
 
 	// Mock campaign query
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team", "budget", "spend", "paused_reason", "frequency_cap_exempt", "track_links", "template_syntax", "cancel_at", "demo_failure_rate", "ordered", "part_delimiter",
 	}).AddRow(
 		campaign.ID,
 		campaign.Name,
@@ -476,6 +517,7 @@ func TestAPI_SendCampaign_InvalidStatus(t *testing.T) {
 		campaign.ScheduledAt,
 		campaign.CreatedAt,
 		campaign.UpdatedAt,
+		"{}", nil, nil, nil, 0, nil, false, false, nil, nil, nil, false, nil,
 	)
 	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE id").
 		WithArgs(campaign.ID).
@@ -495,8 +537,8 @@ func TestAPI_SendCampaign_InvalidStatus(t *testing.T) {
 	resp := httptest.NewRecorder()
 	router.ServeHTTP(resp, req)
 
-	// Verify 422 response
-	AssertStatusCode(t, resp, http.StatusUnprocessableEntity)
+	// Verify 400 response
+	AssertStatusCode(t, resp, http.StatusBadRequest)
 
 	var errorResp map[string]interface{}
 	ParseJSONResponse(t, resp, &errorResp)
@@ -564,7 +606,7 @@ func TestAPI_ListCampaigns_Pagination(t *testing.T) {
 
 	// Mock campaigns query
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team",
 	})
 	for _, campaign := range campaigns {
 		campaignRows.AddRow(
@@ -576,6 +618,7 @@ func TestAPI_ListCampaigns_Pagination(t *testing.T) {
 			campaign.ScheduledAt,
 			campaign.CreatedAt,
 			campaign.UpdatedAt,
+			"{}", nil, nil,
 		)
 	}
 	mock.ExpectQuery("SELECT (.+) FROM campaigns").
@@ -634,7 +677,7 @@ func TestAPI_ListCampaigns_ChannelFilter(t *testing.T) {
 
 	// Mock campaigns query with channel filter
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team",
 	})
 	for _, campaign := range campaigns {
 		campaignRows.AddRow(
@@ -646,14 +689,15 @@ func TestAPI_ListCampaigns_ChannelFilter(t *testing.T) {
 			campaign.ScheduledAt,
 			campaign.CreatedAt,
 			campaign.UpdatedAt,
+			"{}", nil, nil,
 		)
 	}
-	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE channel").
+	mock.ExpectQuery(`SELECT (.+) FROM campaigns\s+WHERE 1=1 AND channel = \$1`).
 		WithArgs(models.ChannelSMS, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(campaignRows)
 
 	// Mock total count query
-	mock.ExpectQuery("SELECT COUNT(.+) FROM campaigns WHERE channel").
+	mock.ExpectQuery(`SELECT COUNT(.+) FROM campaigns WHERE 1=1 AND channel = \$1`).
 		WithArgs(models.ChannelSMS).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 
@@ -695,7 +739,7 @@ func TestAPI_ListCampaigns_StatusFilter(t *testing.T) {
 
 	// Mock campaigns query with status filter
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team",
 	})
 	for _, campaign := range campaigns {
 		campaignRows.AddRow(
@@ -707,14 +751,15 @@ func TestAPI_ListCampaigns_StatusFilter(t *testing.T) {
 			campaign.ScheduledAt,
 			campaign.CreatedAt,
 			campaign.UpdatedAt,
+			"{}", nil, nil,
 		)
 	}
-	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE status").
+	mock.ExpectQuery(`SELECT (.+) FROM campaigns\s+WHERE 1=1 AND status = \$1`).
 		WithArgs(models.CampaignStatusDraft, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(campaignRows)
 
 	// Mock total count query
-	mock.ExpectQuery("SELECT COUNT(.+) FROM campaigns WHERE status").
+	mock.ExpectQuery(`SELECT COUNT(.+) FROM campaigns WHERE 1=1 AND status = \$1`).
 		WithArgs(models.CampaignStatusDraft).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 
@@ -757,7 +802,7 @@ func TestAPI_ListCampaigns_CombinedFilters(t *testing.T) {
 
 	// Mock campaigns query with combined filters
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team",
 	})
 	for _, campaign := range campaigns {
 		campaignRows.AddRow(
@@ -769,15 +814,16 @@ func TestAPI_ListCampaigns_CombinedFilters(t *testing.T) {
 			campaign.ScheduledAt,
 			campaign.CreatedAt,
 			campaign.UpdatedAt,
+			"{}", nil, nil,
 		)
 	}
 	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE").
-		WithArgs(models.CampaignStatusDraft, models.ChannelSMS, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(models.ChannelSMS, models.CampaignStatusDraft, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(campaignRows)
 
 	// Mock total count query
 	mock.ExpectQuery("SELECT COUNT(.+) FROM campaigns WHERE").
-		WithArgs(models.CampaignStatusDraft, models.ChannelSMS).
+		WithArgs(models.ChannelSMS, models.CampaignStatusDraft).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 
 	// Setup handler and router
@@ -813,7 +859,7 @@ func TestAPI_ListCampaigns_EmptyResults(t *testing.T) {
 
 	// Mock campaigns query (empty result)
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team",
 	})
 	mock.ExpectQuery("SELECT (.+) FROM campaigns").
 		WillReturnRows(campaignRows)
@@ -911,27 +957,10 @@ func TestAPI_GetCampaign_Success(t *testing.T) {
 
 	campaign := NewTestCampaign()
 
-	// Mock campaign with stats query
-	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
-		"total_messages", "pending", "sent", "failed",
-	}).AddRow(
-		campaign.ID,
-		campaign.Name,
-		campaign.Channel,
-		campaign.Status,
-		campaign.BaseTemplate,
-		campaign.ScheduledAt,
-		campaign.CreatedAt,
-		campaign.UpdatedAt,
-		100, // total_messages
-		20,  // pending
-		70,  // sent
-		10,  // failed
-	)
-	mock.ExpectQuery("SELECT (.+) FROM campaigns (.+) LEFT JOIN").
+	// Mock the campaign with stats query
+	mock.ExpectQuery(CampaignWithStatsQuery).
 		WithArgs(campaign.ID).
-		WillReturnRows(campaignRows)
+		WillReturnRows(NewCampaignWithStatsRows(campaign, 100, 20, 70, 10, 20, 0, 0, nil, 0, []byte(`[[0,70,10]]`)))
 
 	// Setup handler and router
 	campaignHandler := setupAPITestHandler(t, db)
@@ -959,7 +988,7 @@ func TestAPI_GetCampaign_Success(t *testing.T) {
 	// Verify stats are included
 	AssertNotNil(t, result["stats"])
 	stats := result["stats"].(map[string]interface{})
-	AssertEqual(t, int(stats["total_messages"].(float64)), 100)
+	AssertEqual(t, int(stats["total"].(float64)), 100)
 	AssertEqual(t, int(stats["pending"].(float64)), 20)
 	AssertEqual(t, int(stats["sent"].(float64)), 70)
 	AssertEqual(t, int(stats["failed"].(float64)), 10)
@@ -968,6 +997,35 @@ func TestAPI_GetCampaign_Success(t *testing.T) {
 	AssertNoError(t, mock.ExpectationsWereMet())
 }
 
+// TestAPI_GetCampaign_NoMessages tests that a campaign without messages gets zero counts from
+// the same single query
+func TestAPI_GetCampaign_NoMessages(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaign := NewTestCampaign()
+	mock.ExpectQuery(CampaignWithStatsQuery).
+		WithArgs(campaign.ID).
+		WillReturnRows(NewCampaignWithStatsRows(campaign, 0, 0, 0, 0, 0, 0, 0, nil, 0, nil))
+
+	router := setupAPITestRouter(setupAPITestHandler(t, db))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest("GET", fmt.Sprintf("/campaigns/%d", campaign.ID), nil))
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var result map[string]interface{}
+	ParseJSONResponse(t, resp, &result)
+	stats := result["stats"].(map[string]interface{})
+	for _, field := range []string{"total", "pending", "sent", "failed", "queued", "unpublished"} {
+		AssertEqual(t, stats[field], 0.0)
+	}
+	distribution := stats["retry_distribution"].(map[string]interface{})
+	AssertEqual(t, len(distribution["sent"].(map[string]interface{})), 0)
+	AssertEqual(t, len(distribution["failed"].(map[string]interface{})), 0)
+
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
 // TestAPI_GetCampaign_NotFound tests 404 error for non-existent campaign
 func TestAPI_GetCampaign_NotFound(t *testing.T) {
 	// Setup mock DB
@@ -977,7 +1035,7 @@ func TestAPI_GetCampaign_NotFound(t *testing.T) {
 	nonExistentID := 999
 
 	// Mock campaign query (not found)
-	mock.ExpectQuery("SELECT (.+) FROM campaigns (.+) LEFT JOIN").
+	mock.ExpectQuery(CampaignWithStatsQuery).
 		WithArgs(nonExistentID).
 		WillReturnError(sql.ErrNoRows)
 
@@ -1054,6 +1112,7 @@ func TestAPI_GetCampaign_InvalidIDFormat(t *testing.T) {
 			AssertNotNil(t, errorResp["error"])
 			errorDetail := errorResp["error"].(map[string]interface{})
 			AssertEqual(t, errorDetail["code"], "VALIDATION_ERROR")
+			AssertEqual(t, errorDetail["message"], "id must be a positive integer")
 		})
 	}
 }
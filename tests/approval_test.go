@@ -0,0 +1,212 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/middleware"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// setupApprovalTest creates a campaign service with an approval threshold of 2 customers
+func setupApprovalTest(t *testing.T) (*service.CampaignService, *MockCampaignRepository, *MockMessageRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	campaignRepo := NewMockCampaignRepository()
+	messageRepo := NewMockMessageRepository()
+
+	svc := service.NewCampaignService(
+		campaignRepo,
+		NewMockCustomerRepository(),
+		messageRepo,
+		service.NewTemplateService(),
+		NewMockPublisher(),
+		db,
+		config.ApprovalConfig{RequiredAbove: 2},
+	)
+	return svc, campaignRepo, messageRepo, mock
+}
+
+// TestApproval_BelowThresholdSends tests that sends within the threshold go out immediately
+func TestApproval_BelowThresholdSends(t *testing.T) {
+	svc, campaignRepo, messageRepo, mock := setupApprovalTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Status, models.CampaignStatusSending)
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 1)
+	AssertEqual(t, campaignRepo.Calls["AwaitApproval"], 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestApproval_AboveThresholdStoresPlan tests that large sends wait for approval with the plan stored
+func TestApproval_AboveThresholdStoresPlan(t *testing.T) {
+	svc, campaignRepo, messageRepo, _ := setupApprovalTest(t)
+
+	var savedPlan *models.SendPlan
+	var fromStatus models.CampaignStatus
+	campaignRepo.AwaitApprovalFunc = func(ctx context.Context, id int, from models.CampaignStatus, plan *models.SendPlan) error {
+		savedPlan, fromStatus = plan, from
+		return nil
+	}
+
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2, 3}, service.SendOptions{})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Status, models.CampaignStatusPendingApproval)
+	AssertEqual(t, result.MessagesQueued, 0)
+	AssertEqual(t, result.AudienceSize, 3)
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 0)
+	AssertNotNil(t, savedPlan)
+	AssertEqual(t, len(savedPlan.CustomerIDs), 3)
+	AssertEqual(t, fromStatus, models.CampaignStatusDraft)
+	AssertEqual(t, campaignRepo.Calls["UpdateStatusIf"], 0)
+}
+
+// TestAwaitApproval_GuardsPlan tests that the plan and status are stored in one guarded update,
+// so a send losing the race to another stores no plan
+func TestAwaitApproval_GuardsPlan(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewCampaignRepository(db)
+	plan := &models.SendPlan{CustomerIDs: []int{1, 2, 3}, AudienceSize: 3}
+
+	mock.ExpectExec(`UPDATE campaigns SET status = \$2, send_plan = \$3, updated_at = CURRENT_TIMESTAMP WHERE id = \$1 AND status = \$4`).
+		WithArgs(1, models.CampaignStatusPendingApproval, sqlmock.AnyArg(), models.CampaignStatusDraft).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	AssertNoError(t, repo.AwaitApproval(context.Background(), 1, models.CampaignStatusDraft, plan))
+
+	// Another send moved the campaign on first
+	mock.ExpectExec("UPDATE campaigns SET status").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT status FROM campaigns WHERE id").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.CampaignStatusSending))
+	err := repo.AwaitApproval(context.Background(), 1, models.CampaignStatusDraft, plan)
+	AssertError(t, err, "invalid campaign status transition from sending to pending_approval")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestApproval_ApproveExecutesPlan tests that approving sends to the stored plan and clears it
+func TestApproval_ApproveExecutesPlan(t *testing.T) {
+	svc, campaignRepo, messageRepo, mock := setupApprovalTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusPendingApproval), nil
+	}
+	var from, to models.CampaignStatus
+	campaignRepo.UpdateStatusIfFunc = func(ctx context.Context, id int, f, t models.CampaignStatus) error {
+		from, to = f, t
+		return nil
+	}
+
+	result, err := svc.ApproveCampaign(context.Background(), 1)
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Status, models.CampaignStatusSending)
+	AssertEqual(t, result.MessagesQueued, 3)
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 1)
+	AssertEqual(t, from, models.CampaignStatusPendingApproval)
+	AssertEqual(t, to, models.CampaignStatusSending)
+	AssertEqual(t, campaignRepo.Calls["ClearSendPlan"], 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestApproval_RejectReturnsToDraft tests that rejecting discards the plan and returns the campaign to draft
+func TestApproval_RejectReturnsToDraft(t *testing.T) {
+	svc, campaignRepo, messageRepo, _ := setupApprovalTest(t)
+
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusPendingApproval), nil
+	}
+	var newStatus models.CampaignStatus
+	campaignRepo.UpdateStatusIfFunc = func(ctx context.Context, id int, from, to models.CampaignStatus) error {
+		newStatus = to
+		return nil
+	}
+
+	campaign, err := svc.RejectCampaign(context.Background(), 1)
+	AssertNoError(t, err)
+
+	AssertEqual(t, campaign.Status, models.CampaignStatusDraft)
+	AssertEqual(t, newStatus, models.CampaignStatusDraft)
+	AssertEqual(t, campaignRepo.Calls["ClearSendPlan"], 1)
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 0)
+}
+
+// TestApproval_RequiresPendingStatus tests that only campaigns awaiting approval can be approved or rejected
+func TestApproval_RequiresPendingStatus(t *testing.T) {
+	svc, campaignRepo, _, _ := setupApprovalTest(t)
+
+	_, err := svc.ApproveCampaign(context.Background(), 1)
+	if _, ok := err.(*service.BusinessLogicError); !ok {
+		t.Fatalf("Expected BusinessLogicError but got %v", err)
+	}
+
+	_, err = svc.RejectCampaign(context.Background(), 1)
+	if _, ok := err.(*service.BusinessLogicError); !ok {
+		t.Fatalf("Expected BusinessLogicError but got %v", err)
+	}
+
+	AssertEqual(t, campaignRepo.Calls["GetSendPlan"], 0)
+	AssertEqual(t, campaignRepo.Calls["ClearSendPlan"], 0)
+}
+
+// TestApprovalEndpoints tests the admin key check and status codes of the approval endpoints
+func TestApprovalEndpoints(t *testing.T) {
+	svc, campaignRepo, _, _ := setupApprovalTest(t)
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusPendingApproval), nil
+	}
+
+	campaignHandler := handler.NewCampaignHandler(svc)
+	requireAdmin := middleware.RequireAdminKey("secret")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/send", campaignHandler.Send).Methods("POST")
+	router.Handle("/campaigns/{id}/reject", requireAdmin(http.HandlerFunc(campaignHandler.Reject))).Methods("POST")
+
+	// Missing admin key
+	req := httptest.NewRequest("POST", "/campaigns/1/reject", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusUnauthorized)
+	AssertEqual(t, campaignRepo.Calls["ClearSendPlan"], 0)
+
+	// Valid admin key
+	req = httptest.NewRequest("POST", "/campaigns/1/reject", nil)
+	req.Header.Set(middleware.AdminKeyHeader, "secret")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertEqual(t, campaignRepo.Calls["ClearSendPlan"], 1)
+
+	// Send above threshold is accepted for approval
+	campaignRepo.GetByIDFunc = nil
+	req = NewJSONRequest(t, "POST", "/campaigns/1/send", map[string]interface{}{"customer_ids": []int{1, 2, 3}})
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusAccepted)
+
+	var result service.SendCampaignResult
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, result.Status, models.CampaignStatusPendingApproval)
+}
@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// fakeClock is a settable clock for the attempt budget
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// deferral records a DeferUntil call
+type deferral struct {
+	id     int
+	until  time.Time
+	reason string
+}
+
+// newBudgetTest wires an attempt budget to a mock repository that counts attempts in memory
+func newBudgetTest(t *testing.T, limit int, start time.Time) (*service.AttemptBudget, *MockMessageRepository, *fakeClock, *[]deferral) {
+	t.Helper()
+
+	attempts := make(map[string]int)
+	deferrals := []deferral{}
+
+	messageRepo := NewMockMessageRepository()
+	messageRepo.ReserveAttemptFunc = func(ctx context.Context, customerID int, day time.Time, budget int) (bool, error) {
+		key := fmt.Sprintf("%d/%s", customerID, day.Format("2006-01-02"))
+		if attempts[key] >= budget {
+			return false, nil
+		}
+		attempts[key]++
+		return true, nil
+	}
+	messageRepo.DeferUntilFunc = func(ctx context.Context, id int, until time.Time, reason string) error {
+		deferrals = append(deferrals, deferral{id: id, until: until, reason: reason})
+		return nil
+	}
+
+	clock := &fakeClock{now: start}
+	budget := service.NewAttemptBudget(messageRepo, limit)
+	budget.SetClock(clock.Now)
+	return budget, messageRepo, clock, &deferrals
+}
+
+// TestAttemptBudget_DefersPastMidnight tests that a spent budget defers to the next day and resets at midnight
+func TestAttemptBudget_DefersPastMidnight(t *testing.T) {
+	budget, _, clock, deferrals := newBudgetTest(t, 2, time.Date(2026, 3, 1, 23, 58, 0, 0, time.UTC))
+	message := &models.OutboundMessage{ID: 11, CustomerID: 5}
+
+	for i := 0; i < 2; i++ {
+		allowed, err := budget.Reserve(context.Background(), message)
+		AssertNoError(t, err)
+		AssertEqual(t, allowed, true)
+	}
+
+	clock.now = time.Date(2026, 3, 1, 23, 59, 59, 0, time.UTC)
+	allowed, err := budget.Reserve(context.Background(), message)
+	AssertNoError(t, err)
+	AssertEqual(t, allowed, false)
+	AssertEqual(t, len(*deferrals), 1)
+	AssertEqual(t, (*deferrals)[0].id, 11)
+	AssertEqual(t, (*deferrals)[0].until, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC))
+	AssertEqual(t, (*deferrals)[0].reason, "Deferred to 2026-03-02: customer reached daily attempt budget (2)")
+
+	// Another customer has their own budget
+	allowed, err = budget.Reserve(context.Background(), &models.OutboundMessage{ID: 12, CustomerID: 6})
+	AssertNoError(t, err)
+	AssertEqual(t, allowed, true)
+
+	// A second after midnight the budget starts again
+	clock.now = time.Date(2026, 3, 2, 0, 0, 1, 0, time.UTC)
+	allowed, err = budget.Reserve(context.Background(), message)
+	AssertNoError(t, err)
+	AssertEqual(t, allowed, true)
+	AssertEqual(t, len(*deferrals), 1)
+}
+
+// TestAttemptBudget_UsesUTCDay tests that the day boundary is UTC whatever the clock's zone
+func TestAttemptBudget_UsesUTCDay(t *testing.T) {
+	nairobi := time.FixedZone("EAT", 3*60*60)
+
+	// 01:30 on 2 March in Nairobi is still 1 March in UTC
+	budget, messageRepo, _, deferrals := newBudgetTest(t, 1, time.Date(2026, 3, 2, 1, 30, 0, 0, nairobi))
+	var day time.Time
+	reserve := messageRepo.ReserveAttemptFunc
+	messageRepo.ReserveAttemptFunc = func(ctx context.Context, customerID int, d time.Time, limit int) (bool, error) {
+		day = d
+		return reserve(ctx, customerID, d, limit)
+	}
+
+	message := &models.OutboundMessage{ID: 3, CustomerID: 9}
+	_, err := budget.Reserve(context.Background(), message)
+	AssertNoError(t, err)
+	AssertEqual(t, day, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	allowed, err := budget.Reserve(context.Background(), message)
+	AssertNoError(t, err)
+	AssertEqual(t, allowed, false)
+	AssertEqual(t, (*deferrals)[0].until, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC))
+}
+
+// TestAttemptBudget_Disabled tests that a zero budget never touches the counter
+func TestAttemptBudget_Disabled(t *testing.T) {
+	budget, messageRepo, _, _ := newBudgetTest(t, 0, time.Now())
+
+	allowed, err := budget.Reserve(context.Background(), &models.OutboundMessage{ID: 1, CustomerID: 1})
+	AssertNoError(t, err)
+	AssertEqual(t, allowed, true)
+	AssertEqual(t, messageRepo.Calls["ReserveAttempt"], 0)
+}
+
+// TestAttemptBudget_CounterError tests that a failed count is returned so the job is retried
+func TestAttemptBudget_CounterError(t *testing.T) {
+	budget, messageRepo, _, deferrals := newBudgetTest(t, 5, time.Now())
+	messageRepo.ReserveAttemptFunc = func(ctx context.Context, customerID int, day time.Time, budget int) (bool, error) {
+		return false, errors.New("failed to reserve send attempt: connection reset")
+	}
+
+	allowed, err := budget.Reserve(context.Background(), &models.OutboundMessage{ID: 1, CustomerID: 1})
+	AssertError(t, err, "failed to reserve send attempt: connection reset")
+	AssertEqual(t, allowed, false)
+	AssertEqual(t, len(*deferrals), 0)
+}
+
+// TestAttemptBudget_RequeueDue tests that due messages are published and failed publishes deferred again
+func TestAttemptBudget_RequeueDue(t *testing.T) {
+	now := time.Date(2026, 3, 2, 0, 1, 0, 0, time.UTC)
+	budget, messageRepo, _, deferrals := newBudgetTest(t, 5, now)
+
+	var claimedAt time.Time
+	messageRepo.ClaimDueDeferredFunc = func(ctx context.Context, at time.Time, limit int) ([]*models.OutboundMessage, error) {
+		claimedAt = at
+		return []*models.OutboundMessage{
+			{ID: 1, CampaignID: 7, CustomerID: 5},
+			{ID: 2, CampaignID: 7, CustomerID: 6},
+		}, nil
+	}
+
+	published := []int{}
+	count, err := budget.RequeueDue(context.Background(), func(message *models.OutboundMessage) error {
+		if message.ID == 2 {
+			return errors.New("channel closed")
+		}
+		published = append(published, message.ID)
+		return nil
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, count, 1)
+	AssertEqual(t, claimedAt, now)
+	AssertEqual(t, len(published), 1)
+	AssertEqual(t, published[0], 1)
+	AssertEqual(t, len(*deferrals), 1)
+	AssertEqual(t, (*deferrals)[0].id, 2)
+	AssertEqual(t, (*deferrals)[0].until, now)
+}
+
+// TestReserveAttempt_Query tests the conditional upsert that enforces the budget
+func TestReserveAttempt_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	messageRepo := repository.NewMessageRepository(db)
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	query := `INSERT INTO customer_daily_attempts (.+) ON CONFLICT \(customer_id, day\) DO UPDATE (.+) WHERE customer_daily_attempts.attempts < \$3 RETURNING attempts`
+	mock.ExpectQuery(query).
+		WithArgs(5, "2026-03-01", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(3))
+	mock.ExpectQuery(query).
+		WithArgs(5, "2026-03-01", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"attempts"}))
+
+	allowed, err := messageRepo.ReserveAttempt(context.Background(), 5, day, 3)
+	AssertNoError(t, err)
+	AssertEqual(t, allowed, true)
+
+	allowed, err = messageRepo.ReserveAttempt(context.Background(), 5, day, 3)
+	AssertNoError(t, err)
+	AssertEqual(t, allowed, false)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestClaimDueDeferred_Query tests that due messages are claimed with SKIP LOCKED and cleared
+func TestClaimDueDeferred_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	now := time.Date(2026, 3, 2, 0, 1, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`UPDATE outbound_messages SET deliver_after = NULL, published_at = CURRENT_TIMESTAMP WHERE id IN \( SELECT id FROM outbound_messages WHERE deliver_after <= \$1 (.+) FOR UPDATE SKIP LOCKED \) RETURNING id, campaign_id, customer_id`).
+		WithArgs(now, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "customer_id"}).AddRow(4, 2, 9))
+
+	messages, err := repository.NewMessageRepository(db).ClaimDueDeferred(context.Background(), now, 100)
+	AssertNoError(t, err)
+	AssertEqual(t, len(messages), 1)
+	AssertEqual(t, messages[0].CustomerID, 9)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestLoadAttemptBudget tests CUSTOMER_DAILY_ATTEMPT_BUDGET parsing
+func TestLoadAttemptBudget(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Worker.DailyAttemptBudget, 5)
+
+	t.Setenv("CUSTOMER_DAILY_ATTEMPT_BUDGET", "0")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Worker.DailyAttemptBudget, 0)
+
+	t.Setenv("CUSTOMER_DAILY_ATTEMPT_BUDGET", "-1")
+	_, err = config.Load()
+	AssertError(t, err, "CUSTOMER_DAILY_ATTEMPT_BUDGET cannot be negative")
+}
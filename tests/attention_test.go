@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+)
+
+// recordingNotifier captures notifications instead of sending them
+type recordingNotifier struct {
+	subjects []string
+	payloads []interface{}
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, subject string, payload interface{}) error {
+	n.subjects = append(n.subjects, subject)
+	n.payloads = append(n.payloads, payload)
+	return nil
+}
+
+// TestAttentionDigest_SendsWhenCampaignsFlagged tests that flagged campaigns are sent to the notifier
+func TestAttentionDigest_SendsWhenCampaignsFlagged(t *testing.T) {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.ListNeedingAttentionFunc = func(ctx context.Context) ([]*models.CampaignAttention, error) {
+		return []*models.CampaignAttention{
+			{Campaign: *NewTestCampaignWithStatus(models.CampaignStatusSending), Reason: models.AttentionStalledSending},
+			{Campaign: *NewTestCampaignWithStatus(models.CampaignStatusScheduled), Reason: models.AttentionOverdueSchedule},
+		}, nil
+	}
+	notifier := &recordingNotifier{}
+
+	svc := service.NewAttentionService(campaignRepo, notifier)
+	AssertNoError(t, svc.SendDigest(context.Background()))
+
+	AssertEqual(t, len(notifier.subjects), 1)
+	AssertContains(t, notifier.subjects[0], "2 campaign issue(s)")
+	digest, ok := notifier.payloads[0].(*service.AttentionDigest)
+	if !ok {
+		t.Fatalf("Expected *service.AttentionDigest payload but got %T", notifier.payloads[0])
+	}
+	AssertEqual(t, digest.Count, 2)
+}
+
+// TestAttentionDigest_SkipsWhenNothingFlagged tests that no notification is sent for an empty list
+func TestAttentionDigest_SkipsWhenNothingFlagged(t *testing.T) {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.ListNeedingAttentionFunc = func(ctx context.Context) ([]*models.CampaignAttention, error) {
+		return []*models.CampaignAttention{}, nil
+	}
+	notifier := &recordingNotifier{}
+
+	svc := service.NewAttentionService(campaignRepo, notifier)
+	AssertNoError(t, svc.SendDigest(context.Background()))
+
+	AssertEqual(t, len(notifier.subjects), 0)
+}
+
+// TestAttentionEndpoint tests GET /admin/campaigns/attention with mocks
+func TestAttentionEndpoint(t *testing.T) {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.ListNeedingAttentionFunc = func(ctx context.Context) ([]*models.CampaignAttention, error) {
+		return []*models.CampaignAttention{
+			{Campaign: *NewTestCampaign(), Reason: models.AttentionHighFailureRate, Processed: 10, Failed: 5},
+		}, nil
+	}
+	h := handler.NewAdminHandler(service.NewAttentionService(campaignRepo, nil), nil)
+
+	req := httptest.NewRequest("GET", "/admin/campaigns/attention", nil)
+	resp := httptest.NewRecorder()
+	h.CampaignsNeedingAttention(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertJSONContentType(t, resp)
+
+	var digest service.AttentionDigest
+	ParseJSONResponse(t, resp, &digest)
+	AssertEqual(t, digest.Count, 1)
+	AssertEqual(t, digest.Campaigns[0].Reason, models.AttentionHighFailureRate)
+
+	// Repository failure
+	campaignRepo.ListNeedingAttentionFunc = func(ctx context.Context) ([]*models.CampaignAttention, error) {
+		return nil, fmt.Errorf("connection refused")
+	}
+	resp = httptest.NewRecorder()
+	h.CampaignsNeedingAttention(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusInternalServerError)
+}
+
+// TestListNeedingAttention_Integration constructs each condition in a real database
+func TestListNeedingAttention_Integration(t *testing.T) {
+	db := SetupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer db.Close()
+	CleanupTestDB(t, db)
+	defer CleanupTestDB(t, db)
+
+	ctx := context.Background()
+	campaignRepo := repository.NewCampaignRepository(db)
+
+	var customerID int
+	err := db.QueryRow(`INSERT INTO customers (phone, first_name) VALUES ('+254700000001', 'Ops') RETURNING id`).Scan(&customerID)
+	AssertNoError(t, err)
+
+	createCampaign := func(name string, status models.CampaignStatus, scheduledAt *time.Time) int {
+		campaign := &models.Campaign{
+			Name:         name,
+			Channel:      models.ChannelSMS,
+			Status:       status,
+			BaseTemplate: "Hi {first_name}",
+			ScheduledAt:  scheduledAt,
+		}
+		AssertNoError(t, campaignRepo.Create(ctx, campaign))
+		return campaign.ID
+	}
+	insertMessage := func(campaignID int, status models.MessageStatus) {
+		_, err := db.Exec(`INSERT INTO outbound_messages (campaign_id, customer_id, status) VALUES ($1, $2, $3)`,
+			campaignID, customerID, status)
+		AssertNoError(t, err)
+	}
+
+	// Sending for two hours with no progress
+	stalledID := createCampaign("Stalled", models.CampaignStatusSending, nil)
+	_, err = db.Exec(`UPDATE campaigns SET updated_at = NOW() - INTERVAL '2 hours' WHERE id = $1`, stalledID)
+	AssertNoError(t, err)
+
+	// Scheduled time already passed
+	past := time.Now().Add(-30 * time.Minute)
+	overdueID := createCampaign("Overdue", models.CampaignStatusScheduled, &past)
+
+	// Half of processed messages failed
+	failingID := createCampaign("Failing", models.CampaignStatusSent, nil)
+	insertMessage(failingID, models.MessageStatusSent)
+	insertMessage(failingID, models.MessageStatusFailed)
+
+	// Healthy campaign must not be listed
+	healthyID := createCampaign("Healthy", models.CampaignStatusSent, nil)
+	insertMessage(healthyID, models.MessageStatusSent)
+
+	items, err := campaignRepo.ListNeedingAttention(ctx)
+	AssertNoError(t, err)
+
+	reasons := map[int]models.AttentionReason{}
+	for _, item := range items {
+		reasons[item.Campaign.ID] = item.Reason
+	}
+
+	AssertEqual(t, len(items), 3)
+	AssertEqual(t, reasons[stalledID], models.AttentionStalledSending)
+	AssertEqual(t, reasons[overdueID], models.AttentionOverdueSchedule)
+	AssertEqual(t, reasons[failingID], models.AttentionHighFailureRate)
+	if _, ok := reasons[healthyID]; ok {
+		t.Error("Expected healthy campaign not to need attention")
+	}
+}
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/reqctx"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// backpressureFixture is a campaign service admitting sends under stubbed backlog figures
+type backpressureFixture struct {
+	svc          *service.CampaignService
+	campaignRepo *MockCampaignRepository
+	messageRepo  *MockMessageRepository
+	mock         sqlmock.Sqlmock
+	admission    *service.SendAdmission
+	depth        int
+	depthErr     error
+	unpublished  int
+}
+
+// newBackpressureFixture allows 100 queued jobs and 50 never-published messages
+func newBackpressureFixture(t *testing.T) *backpressureFixture {
+	t.Helper()
+
+	f := &backpressureFixture{}
+	f.svc, f.campaignRepo, f.messageRepo, f.mock = setupApprovalTest(t)
+	f.messageRepo.CountUnpublishedFunc = func(ctx context.Context) (int, error) {
+		return f.unpublished, nil
+	}
+	f.admission = service.NewSendAdmission(func() (int, error) {
+		return f.depth, f.depthErr
+	}, f.messageRepo, config.BackpressureConfig{MaxQueueDepth: 100, MaxUnpublished: 50, RetryAfter: 2 * time.Minute})
+	f.svc.SetAdmission(f.admission)
+	return f
+}
+
+// TestBackpressure_Thresholds tests that sends are admitted at the thresholds and refused over either
+func TestBackpressure_Thresholds(t *testing.T) {
+	testCases := []struct {
+		name        string
+		depth       int
+		unpublished int
+		wantErr     string
+	}{
+		{name: "below both", depth: 10, unpublished: 5},
+		{name: "at both", depth: 100, unpublished: 50},
+		{
+			name: "queue over", depth: 101, unpublished: 0,
+			wantErr: "send queue is saturated: 101 jobs queued (limit 100), 0 messages never published (limit 50); retry in 2m0s",
+		},
+		{
+			name: "unpublished over", depth: 0, unpublished: 51,
+			wantErr: "send queue is saturated: 0 jobs queued (limit 100), 51 messages never published (limit 50); retry in 2m0s",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newBackpressureFixture(t)
+			f.depth, f.unpublished = tc.depth, tc.unpublished
+			if tc.wantErr == "" {
+				f.mock.ExpectBegin()
+				f.mock.ExpectCommit()
+			}
+
+			result, err := f.svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+			if tc.wantErr != "" {
+				AssertError(t, err, tc.wantErr)
+				var saturated *service.QueueSaturatedError
+				if !errors.As(err, &saturated) {
+					t.Fatalf("Expected a QueueSaturatedError, got %T", err)
+				}
+				AssertEqual(t, saturated.RetryAfter, 2*time.Minute)
+				AssertEqual(t, f.messageRepo.Calls["CreateBatch"], 0)
+				return
+			}
+			AssertNoError(t, err)
+			AssertEqual(t, result.MessagesQueued, 2)
+			AssertNoError(t, f.mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestBackpressure_Override tests that an override sends past a saturated backlog
+func TestBackpressure_Override(t *testing.T) {
+	f := newBackpressureFixture(t)
+	f.depth = 500
+	f.mock.ExpectBegin()
+	f.mock.ExpectCommit()
+
+	result, err := f.svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{OverrideSaturation: true})
+	AssertNoError(t, err)
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertEqual(t, f.messageRepo.Calls["CountUnpublished"], 0)
+}
+
+// TestBackpressure_UnknownDepth tests that a broker that cannot be asked leaves only the
+// database backlog checked, and that a disabled threshold is never exceeded
+func TestBackpressure_UnknownDepth(t *testing.T) {
+	f := newBackpressureFixture(t)
+	f.depthErr = errors.New("connection refused")
+
+	status, err := f.admission.Status(context.Background())
+	AssertNoError(t, err)
+	if status.QueueDepth != nil {
+		t.Errorf("Expected an unknown queue depth, got %d", *status.QueueDepth)
+	}
+	AssertEqual(t, status.QueueError, "connection refused")
+	AssertEqual(t, status.Saturated, false)
+
+	f.unpublished = 51
+	AssertError(t, f.admission.Check(context.Background()),
+		"send queue is saturated: unknown jobs queued (limit 100), 51 messages never published (limit 50); retry in 2m0s")
+
+	disabled := service.NewSendAdmission(func() (int, error) { return 1000000, nil }, f.messageRepo, config.BackpressureConfig{RetryAfter: time.Minute})
+	AssertNoError(t, disabled.Check(context.Background()))
+}
+
+// TestBackpressure_SendEndpoint tests the 503 response and that only admins may override
+func TestBackpressure_SendEndpoint(t *testing.T) {
+	f := newBackpressureFixture(t)
+	f.depth = 150
+
+	router := NewTestRouter(map[string]http.HandlerFunc{
+		"POST /campaigns/{id}/send": handler.NewCampaignHandler(f.svc).Send,
+		"GET /admin/queue-status":   handler.NewQueueStatusHandler(f.admission).Get,
+	})
+	send := func(body string, identity *reqctx.Identity) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/campaigns/1/send", strings.NewReader(body))
+		if identity != nil {
+			req = req.WithContext(reqctx.WithIdentity(req.Context(), identity))
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send(`{"customer_ids": [1, 2]}`, nil)
+	AssertStatusCode(t, rr, http.StatusServiceUnavailable)
+	AssertEqual(t, rr.Header().Get("Retry-After"), "120")
+	var resp handler.ErrorResponse
+	ParseJSONResponse(t, rr, &resp)
+	AssertEqual(t, resp.Error.Code, "QUEUE_SATURATED")
+	AssertEqual(t, resp.Error.Message, "send queue is saturated: 150 jobs queued (limit 100), 0 messages never published (limit 50); retry in 2m0s")
+
+	// A member may send the campaign but not past saturation
+	owner := "kip"
+	campaign := NewTestCampaign()
+	campaign.CreatedBy = &owner
+	f.campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return campaign, nil
+	}
+	member := &reqctx.Identity{UserID: owner, Role: config.RoleMember}
+	rr = send(`{"customer_ids": [1, 2], "override_saturation": true}`, member)
+	AssertStatusCode(t, rr, http.StatusForbidden)
+
+	f.mock.ExpectBegin()
+	f.mock.ExpectCommit()
+	admin := &reqctx.Identity{UserID: "amina", Role: config.RoleAdmin}
+	rr = send(`{"customer_ids": [1, 2], "override_saturation": true}`, admin)
+	AssertStatusCode(t, rr, http.StatusOK)
+
+	// The admin status endpoint reports the same figures
+	rr = ServeTestRequest(router, http.MethodGet, "/admin/queue-status", "")
+	AssertStatusCode(t, rr, http.StatusOK)
+	var status service.QueueStatus
+	ParseJSONResponse(t, rr, &status)
+	AssertEqual(t, *status.QueueDepth, 150)
+	AssertEqual(t, status.MaxQueueDepth, 100)
+	AssertEqual(t, status.Saturated, true)
+}
@@ -0,0 +1,370 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// budgetFixture is a message processor whose campaign spend counter behaves like the campaigns table
+type budgetFixture struct {
+	processor    *service.MessageProcessor
+	db           *sql.DB
+	mock         sqlmock.Sqlmock
+	campaignRepo *MockCampaignRepository
+	messageRepo  *MockMessageRepository
+	budget       float64
+	spend        float64
+	status       models.CampaignStatus
+	held         []int
+}
+
+// newBudgetFixture creates a processor for a sending SMS campaign with the given budget, at 0.80 per SMS
+func newBudgetFixture(t *testing.T, budget float64, sender service.Sender) *budgetFixture {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	f := &budgetFixture{
+		db:           db,
+		mock:         mock,
+		campaignRepo: NewMockCampaignRepository(),
+		messageRepo:  NewMockMessageRepository(),
+		budget:       budget,
+		status:       models.CampaignStatusSending,
+	}
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		message := NewTestMessageWithStatus(models.MessageStatusPending)
+		message.ID = id
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *message,
+			Campaign:        *NewTestCampaignWithStatus(f.status),
+			Customer:        *NewTestCustomer(),
+		}, nil
+	}
+	f.messageRepo.HoldFunc = func(ctx context.Context, id int, reason string) error {
+		AssertEqual(t, reason, "Held: campaign budget exceeded")
+		f.held = append(f.held, id)
+		return nil
+	}
+	f.campaignRepo.ReserveSpendFunc = func(ctx context.Context, id int, cost float64) (bool, error) {
+		if f.spend+cost > f.budget+1e-9 {
+			return false, nil
+		}
+		f.spend += cost
+		return true, nil
+	}
+	f.campaignRepo.ReleaseSpendFunc = func(ctx context.Context, id int, cost float64) error {
+		f.spend = math.Max(f.spend-cost, 0)
+		return nil
+	}
+	f.campaignRepo.PauseFunc = func(ctx context.Context, id int, reason string) (bool, error) {
+		AssertEqual(t, reason, models.PausedReasonBudgetExceeded)
+		if f.status != models.CampaignStatusSending {
+			return false, nil
+		}
+		f.status = models.CampaignStatusPaused
+		return true, nil
+	}
+
+	f.setAttemptLimit(sender, 0)
+	return f
+}
+
+// setAttemptLimit rebuilds the processor with a daily attempt budget of limit per customer
+func (f *budgetFixture) setAttemptLimit(sender service.Sender, limit int) {
+	f.processor = service.NewMessageProcessor(f.db, f.messageRepo, service.NewTemplateService(), sender, service.NewAttemptBudget(f.messageRepo, limit), nil)
+	f.processor.SetCampaignBudget(service.NewCampaignBudget(f.campaignRepo, f.messageRepo, config.SendingConfig{CostPerSMS: 0.80, CostPerWhatsApp: 0.50}))
+}
+
+// handle processes the job for a message ID
+func (f *budgetFixture) handle(id int) error {
+	return f.processor.Handle(&queue.MessageJob{MessageID: id, CampaignID: 1, CustomerID: 1})
+}
+
+// TestCampaignBudget_CrossingMidCampaign tests that the send that would exceed the budget pauses the
+// campaign and it and every later message are held instead of sent
+func TestCampaignBudget_CrossingMidCampaign(t *testing.T) {
+	sender := &countingSender{}
+	f := newBudgetFixture(t, 2.00, sender)
+
+	for id := 1; id <= 2; id++ {
+		f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+			WithArgs(id, false).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	for id := 1; id <= 4; id++ {
+		AssertNoError(t, f.handle(id))
+	}
+
+	// 2 x 0.80 fits in 2.00; a third SMS would take spend to 2.40
+	AssertEqual(t, sender.calls, 2)
+	AssertEqual(t, math.Round(f.spend*100)/100, 1.60)
+	AssertEqual(t, f.status, models.CampaignStatusPaused)
+	AssertEqual(t, f.campaignRepo.Calls["Pause"], 2)
+	AssertEqual(t, len(f.held), 2)
+	AssertEqual(t, f.held[0], 3)
+	AssertEqual(t, f.held[1], 4)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestCampaignBudget_ExactBudget tests that a send landing exactly on the budget still goes out
+func TestCampaignBudget_ExactBudget(t *testing.T) {
+	sender := &countingSender{}
+	f := newBudgetFixture(t, 1.60, sender)
+
+	for id := 1; id <= 2; id++ {
+		f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+			WithArgs(id, false).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	for id := 1; id <= 3; id++ {
+		AssertNoError(t, f.handle(id))
+	}
+
+	AssertEqual(t, sender.calls, 2)
+	AssertEqual(t, len(f.held), 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestCampaignBudget_FailedSendReleasesSpend tests that a send the provider rejected costs nothing
+func TestCampaignBudget_FailedSendReleasesSpend(t *testing.T) {
+	f := newBudgetFixture(t, 0.80, &failingSender{})
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'failed'").
+		WithArgs(1, "provider unavailable").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.handle(1)
+	var sendErr *service.SendError
+	AssertEqual(t, errors.As(err, &sendErr), true)
+	AssertEqual(t, f.spend, 0.0)
+	AssertEqual(t, f.campaignRepo.Calls["ReleaseSpend"], 1)
+	AssertEqual(t, f.status, models.CampaignStatusSending)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestCampaignBudget_HeldUsesNoAttempt tests that a message held by the campaign budget is not
+// counted against its customer's daily attempt budget, and that one deferred by the attempt
+// budget gives its reserved spend back
+func TestCampaignBudget_HeldUsesNoAttempt(t *testing.T) {
+	sender := &countingSender{}
+	f := newBudgetFixture(t, 0.00, sender)
+	f.setAttemptLimit(sender, 5)
+
+	AssertNoError(t, f.handle(1))
+	AssertEqual(t, len(f.held), 1)
+	AssertEqual(t, f.messageRepo.Calls["ReserveAttempt"], 0)
+
+	f.budget = 0.80
+	f.status = models.CampaignStatusSending
+	f.messageRepo.ReserveAttemptFunc = func(ctx context.Context, customerID int, day time.Time, budget int) (bool, error) {
+		return false, nil
+	}
+	AssertNoError(t, f.handle(2))
+	AssertEqual(t, sender.calls, 0)
+	AssertEqual(t, f.messageRepo.Calls["DeferUntil"], 1)
+	AssertEqual(t, f.spend, 0.0)
+	AssertEqual(t, f.campaignRepo.Calls["ReleaseSpend"], 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestCampaignBudget_Disabled tests that a processor without the check never touches the spend counter
+func TestCampaignBudget_Disabled(t *testing.T) {
+	f := newBudgetFixture(t, 0.80, &countingSender{})
+	f.processor.SetCampaignBudget(nil)
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WithArgs(1, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	AssertNoError(t, f.handle(1))
+	AssertEqual(t, f.campaignRepo.Calls["ReserveSpend"], 0)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestRemainingBudget tests the remaining budget is rounded to cents and never negative
+func TestRemainingBudget(t *testing.T) {
+	budget, spend := 500.0, 123.456
+	campaign := &models.Campaign{Budget: &budget, Spend: &spend}
+	AssertEqual(t, *campaign.RemainingBudget(), 376.54)
+
+	spend = 600
+	AssertEqual(t, *campaign.RemainingBudget(), 0.0)
+
+	campaign.Budget = nil
+	if campaign.RemainingBudget() != nil {
+		t.Error("Expected no remaining budget without a budget")
+	}
+}
+
+// setupResumeTest creates a campaign service over a paused campaign that has spent 480 of a 500 budget
+func setupResumeTest(t *testing.T) (*service.CampaignService, *MockCampaignRepository, *MockMessageRepository) {
+	t.Helper()
+
+	svc, campaignRepo, messageRepo, _ := setupApprovalTest(t)
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		campaign := NewTestCampaignWithStatus(models.CampaignStatusPaused)
+		budget, spend, reason := 500.0, 480.0, models.PausedReasonBudgetExceeded
+		campaign.Budget, campaign.Spend, campaign.PausedReason = &budget, &spend, &reason
+		return campaign, nil
+	}
+	messageRepo.ReleaseHeldFunc = func(ctx context.Context, campaignID int) (int, error) {
+		return 25, nil
+	}
+	return svc, campaignRepo, messageRepo
+}
+
+// TestResumeCampaign tests resuming with a raised budget and the checks before resuming
+func TestResumeCampaign(t *testing.T) {
+	svc, campaignRepo, messageRepo := setupResumeTest(t)
+
+	var resumedBudget *float64
+	campaignRepo.ResumeFunc = func(ctx context.Context, id int, budget *float64) error {
+		resumedBudget = budget
+		return nil
+	}
+
+	raised := 800.0
+	result, err := svc.ResumeCampaign(context.Background(), 1, &service.ResumeCampaignRequest{Budget: &raised})
+	AssertNoError(t, err)
+	AssertEqual(t, result.Status, models.CampaignStatusSending)
+	AssertEqual(t, result.MessagesReleased, 25)
+	AssertEqual(t, *result.Budget, 800.0)
+	AssertEqual(t, *resumedBudget, 800.0)
+	AssertEqual(t, messageRepo.Calls["ReleaseHeld"], 1)
+
+	// Without a raise the 20 left would be enough for more sends, so resuming is allowed
+	_, err = svc.ResumeCampaign(context.Background(), 1, &service.ResumeCampaignRequest{})
+	AssertNoError(t, err)
+
+	lowered := 450.0
+	_, err = svc.ResumeCampaign(context.Background(), 1, &service.ResumeCampaignRequest{Budget: &lowered})
+	AssertError(t, err, "business logic error: campaign has spent 480.00 of its 450.00 budget; resume with a higher budget")
+
+	negative := -5.0
+	_, err = svc.ResumeCampaign(context.Background(), 1, &service.ResumeCampaignRequest{Budget: &negative})
+	AssertError(t, err, "validation error: budget must be greater than 0 and at most 9999999999.99")
+
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusSending), nil
+	}
+	_, err = svc.ResumeCampaign(context.Background(), 1, &service.ResumeCampaignRequest{})
+	AssertError(t, err, "business logic error: campaign is not paused: status is sending")
+	AssertEqual(t, campaignRepo.Calls["Resume"], 2)
+	AssertEqual(t, messageRepo.Calls["ReleaseHeld"], 2)
+}
+
+// TestResumeCampaign_Endpoint tests the resume endpoint's responses
+func TestResumeCampaign_Endpoint(t *testing.T) {
+	svc, _, _ := setupResumeTest(t)
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/resume", handler.NewCampaignHandler(svc).Resume).Methods("POST")
+
+	req := httptest.NewRequest("POST", "/campaigns/1/resume", strings.NewReader(`{"budget": 1000}`))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	var result service.ResumeCampaignResult
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, result.MessagesReleased, 25)
+
+	for body, status := range map[string]int{
+		"":                  http.StatusOK,
+		`{"budget": 100}`:   http.StatusBadRequest,
+		`{"budget": "lots"`: http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("POST", "/campaigns/1/resume", strings.NewReader(body))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		AssertStatusCode(t, resp, status)
+	}
+}
+
+// TestCreateCampaign_Budget tests that a budget is stored at create and must be positive
+func TestCreateCampaign_Budget(t *testing.T) {
+	svc, campaignRepo, _, _ := setupApprovalTest(t)
+
+	var created *models.Campaign
+	campaignRepo.CreateFunc = func(ctx context.Context, campaign *models.Campaign) error {
+		created = campaign
+		return nil
+	}
+
+	budget := 500.0
+	_, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+		Name: "Capped", Channel: models.ChannelSMS, BaseTemplate: "Hi {first_name}", Budget: &budget,
+	})
+	AssertNoError(t, err)
+	AssertEqual(t, *created.Budget, 500.0)
+
+	zero := 0.0
+	_, err = svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+		Name: "Free", Channel: models.ChannelSMS, BaseTemplate: "Hi {first_name}", Budget: &zero,
+	})
+	AssertError(t, err, "validation error: budget must be greater than 0 and at most 9999999999.99")
+}
+
+// TestCampaignBudget_Queries tests the spend reservation, pause, resume and hold queries
+func TestCampaignBudget_Queries(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaignRepo := repository.NewCampaignRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectExec(`UPDATE campaigns SET spend = spend \+ \$2 WHERE id = \$1 AND \(budget IS NULL OR spend \+ \$2 <= budget\)`).
+		WithArgs(7, 0.8).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	reserved, err := campaignRepo.ReserveSpend(ctx, 7, 0.8)
+	AssertNoError(t, err)
+	AssertEqual(t, reserved, false)
+
+	mock.ExpectExec(`UPDATE campaigns SET status = \$2, paused_reason = \$3, updated_at = CURRENT_TIMESTAMP WHERE id = \$1 AND status = \$4`).
+		WithArgs(7, models.CampaignStatusPaused, models.PausedReasonBudgetExceeded, models.CampaignStatusSending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	paused, err := campaignRepo.Pause(ctx, 7, models.PausedReasonBudgetExceeded)
+	AssertNoError(t, err)
+	AssertEqual(t, paused, true)
+
+	// Resuming a campaign that is no longer paused is an invalid transition
+	mock.ExpectExec(`UPDATE campaigns SET status = \$2, paused_reason = NULL, budget = COALESCE\(\$3, budget\)`).
+		WithArgs(7, models.CampaignStatusSending, nil, models.CampaignStatusPaused).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT status FROM campaigns WHERE id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("sent"))
+	err = campaignRepo.Resume(ctx, 7, nil)
+	var transitionErr *models.InvalidTransitionError
+	AssertEqual(t, errors.As(err, &transitionErr), true)
+
+	messageRepo := repository.NewMessageRepository(db)
+	mock.ExpectExec(`UPDATE outbound_messages SET deliver_after = 'infinity', last_error = \$2`).
+		WithArgs(11, "Held: campaign budget exceeded").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	AssertNoError(t, messageRepo.Hold(ctx, 11, "Held: campaign budget exceeded"))
+
+	mock.ExpectExec(`UPDATE outbound_messages SET deliver_after = CURRENT_TIMESTAMP WHERE campaign_id = \$1 AND deliver_after = 'infinity'`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 40))
+	released, err := messageRepo.ReleaseHeld(ctx, 7)
+	AssertNoError(t, err)
+	AssertEqual(t, released, 40)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,295 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// setupCanaryTest creates a campaign service without an approval threshold
+func setupCanaryTest(t *testing.T) (*service.CampaignService, *MockCampaignRepository, *MockMessageRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	campaignRepo := NewMockCampaignRepository()
+	messageRepo := NewMockMessageRepository()
+
+	svc := service.NewCampaignService(
+		campaignRepo,
+		NewMockCustomerRepository(),
+		messageRepo,
+		service.NewTemplateService(),
+		NewMockPublisher(),
+		db,
+		config.ApprovalConfig{},
+	)
+	return svc, campaignRepo, messageRepo, mock
+}
+
+// canaryAudience returns customer IDs 1 to n
+func canaryAudience(n int) []int {
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	return ids
+}
+
+// TestCanary_SendQueuesCanaryAndHoldsRest tests that a canary send queues only the canary,
+// spread through the audience, and holds the rest back in the send plan
+func TestCanary_SendQueuesCanaryAndHoldsRest(t *testing.T) {
+	svc, campaignRepo, messageRepo, mock := setupCanaryTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	queued := map[int]bool{}
+	messageRepo.CreateBatchFunc = func(ctx context.Context, messages []*models.OutboundMessage) error {
+		for _, message := range messages {
+			queued[message.CustomerID] = true
+		}
+		return nil
+	}
+	var held *models.SendPlan
+	campaignRepo.HoldCanaryFunc = func(ctx context.Context, id int, plan *models.SendPlan) error {
+		held = plan
+		return nil
+	}
+
+	result, err := svc.SendCampaign(context.Background(), 1, canaryAudience(200), service.SendOptions{
+		Canary: &models.CanaryOptions{Percent: 1, Min: 50},
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Status, models.CampaignStatusCanary)
+	AssertEqual(t, result.MessagesQueued, 50)
+	AssertEqual(t, result.CanaryHeldBack, 150)
+	AssertEqual(t, len(queued), 50)
+	AssertEqual(t, queued[1], true)
+	AssertEqual(t, queued[197], true)
+	AssertEqual(t, queued[200], false)
+
+	AssertNotNil(t, held)
+	AssertEqual(t, held.AudienceSize, 150)
+	AssertEqual(t, len(held.CustomerIDs), 150)
+	for _, id := range held.CustomerIDs {
+		if queued[id] {
+			t.Errorf("Expected customer %d to be held back or queued, not both", id)
+		}
+	}
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCanary_AudienceSmallerThanMin tests that an audience no bigger than the canary is sent
+// in full with nothing held back
+func TestCanary_AudienceSmallerThanMin(t *testing.T) {
+	svc, campaignRepo, messageRepo, mock := setupCanaryTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	result, err := svc.SendCampaign(context.Background(), 1, canaryAudience(10), service.SendOptions{
+		Canary: &models.CanaryOptions{Percent: 1, Min: 50},
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Status, models.CampaignStatusSending)
+	AssertEqual(t, result.MessagesQueued, 10)
+	AssertEqual(t, result.CanaryHeldBack, 0)
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 1)
+	AssertEqual(t, campaignRepo.Calls["HoldCanary"], 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCanary_InvalidOptions tests that canary options out of range are refused before anything is sent
+func TestCanary_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		canary models.CanaryOptions
+	}{
+		{name: "min without percent", canary: models.CanaryOptions{Min: 50}},
+		{name: "whole audience", canary: models.CanaryOptions{Percent: 100}},
+		{name: "negative percent", canary: models.CanaryOptions{Percent: -5}},
+		{name: "negative min", canary: models.CanaryOptions{Percent: 1, Min: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, messageRepo, _ := setupCanaryTest(t)
+			_, err := svc.SendCampaign(context.Background(), 1, canaryAudience(10), service.SendOptions{Canary: &tt.canary})
+			if _, ok := err.(*service.ValidationError); !ok {
+				t.Fatalf("Expected ValidationError but got %v", err)
+			}
+			AssertEqual(t, messageRepo.Calls["CreateBatch"], 0)
+		})
+	}
+}
+
+// TestCanary_ApprovalKeepsCanary tests that a canary send waiting for approval holds its
+// canary once approved
+func TestCanary_ApprovalKeepsCanary(t *testing.T) {
+	svc, campaignRepo, messageRepo, mock := setupApprovalTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusPendingApproval), nil
+	}
+	campaignRepo.GetSendPlanFunc = func(ctx context.Context, id int) (*models.SendPlan, error) {
+		return &models.SendPlan{
+			CustomerIDs:  canaryAudience(10),
+			AudienceSize: 10,
+			Canary:       &models.CanaryOptions{Percent: 20},
+		}, nil
+	}
+
+	result, err := svc.ApproveCampaign(context.Background(), 1)
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Status, models.CampaignStatusCanary)
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertEqual(t, result.CanaryHeldBack, 8)
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 1)
+	AssertEqual(t, campaignRepo.Calls["HoldCanary"], 1)
+	// The held back audience replaces the approved plan, so it is not cleared
+	AssertEqual(t, campaignRepo.Calls["ClearSendPlan"], 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCanary_Continue tests that continuing sends the held back audience and moves the
+// campaign back to sending
+func TestCanary_Continue(t *testing.T) {
+	svc, campaignRepo, messageRepo, mock := setupCanaryTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusCanary), nil
+	}
+	var from, to models.CampaignStatus
+	campaignRepo.UpdateStatusIfFunc = func(ctx context.Context, id int, f, t models.CampaignStatus) error {
+		from, to = f, t
+		return nil
+	}
+
+	result, err := svc.ContinueCampaign(context.Background(), 1)
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Status, models.CampaignStatusSending)
+	AssertEqual(t, result.MessagesQueued, 3)
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 1)
+	AssertEqual(t, from, models.CampaignStatusCanary)
+	AssertEqual(t, to, models.CampaignStatusSending)
+	AssertEqual(t, campaignRepo.Calls["ClearSendPlan"], 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCanary_ContinueNobodyLeft tests that continuing a canary whose held back customers are
+// all gone still moves the campaign back to sending
+func TestCanary_ContinueNobodyLeft(t *testing.T) {
+	svc, campaignRepo, messageRepo, _ := setupCanaryTest(t)
+
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusCanary), nil
+	}
+	campaignRepo.GetSendPlanFunc = func(ctx context.Context, id int) (*models.SendPlan, error) {
+		return &models.SendPlan{CustomerIDs: []int{}}, nil
+	}
+	var to models.CampaignStatus
+	campaignRepo.UpdateStatusIfFunc = func(ctx context.Context, id int, f, t models.CampaignStatus) error {
+		to = t
+		return nil
+	}
+
+	result, err := svc.ContinueCampaign(context.Background(), 1)
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Status, models.CampaignStatusSending)
+	AssertEqual(t, result.MessagesQueued, 0)
+	AssertEqual(t, to, models.CampaignStatusSending)
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 0)
+	AssertEqual(t, campaignRepo.Calls["ClearSendPlan"], 1)
+}
+
+// TestCanary_Abort tests that aborting cancels the campaign at once and discards the held
+// back audience
+func TestCanary_Abort(t *testing.T) {
+	svc, campaignRepo, messageRepo, _ := setupCanaryTest(t)
+
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusCanary), nil
+	}
+	graceSeconds := -1.0
+	campaignRepo.BeginCancelFunc = func(ctx context.Context, id int, grace time.Duration) (time.Time, error) {
+		graceSeconds = grace.Seconds()
+		return time.Now(), nil
+	}
+	campaignRepo.FinalizeCancellationFunc = func(ctx context.Context, id int) (int, error) {
+		return 4, nil
+	}
+
+	result, err := svc.AbortCampaign(context.Background(), 1)
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Status, models.CampaignStatusCancelled)
+	AssertEqual(t, result.MessagesCancelled, 4)
+	AssertEqual(t, graceSeconds, 0.0)
+	AssertEqual(t, campaignRepo.Calls["ClearSendPlan"], 1)
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 0)
+}
+
+// TestCanary_RequiresCanaryStatus tests that only campaigns holding a canary can be continued or aborted
+func TestCanary_RequiresCanaryStatus(t *testing.T) {
+	svc, campaignRepo, _, _ := setupCanaryTest(t)
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusSending), nil
+	}
+
+	_, err := svc.ContinueCampaign(context.Background(), 1)
+	if _, ok := err.(*service.BusinessLogicError); !ok {
+		t.Fatalf("Expected BusinessLogicError but got %v", err)
+	}
+
+	_, err = svc.AbortCampaign(context.Background(), 1)
+	if _, ok := err.(*service.BusinessLogicError); !ok {
+		t.Fatalf("Expected BusinessLogicError but got %v", err)
+	}
+
+	AssertEqual(t, campaignRepo.Calls["GetSendPlan"], 0)
+	AssertEqual(t, campaignRepo.Calls["BeginCancel"], 0)
+	actions := models.CampaignStatusCanary.AllowedActions()
+	AssertEqual(t, len(actions), 2)
+	AssertEqual(t, actions[0], models.CampaignActionContinue)
+	AssertEqual(t, actions[1], models.CampaignActionAbort)
+}
+
+// TestCanary_Stats tests that a campaign's canary results are read apart from its overall stats
+func TestCanary_Stats(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaign := NewTestCampaignWithStatus(models.CampaignStatusCanary)
+	mock.ExpectQuery(CampaignWithStatsQuery).
+		WithArgs(campaign.ID).
+		WillReturnRows(NewCampaignWithStatsRows(campaign,
+			50, 5, 40, 5, 5, 0, 0, nil, 0, nil,
+			nil, nil, nil, nil, nil, nil,
+			50, 5, 40, 5, 150))
+
+	result, err := repository.NewCampaignRepository(db).GetWithStats(context.Background(), campaign.ID)
+	AssertNoError(t, err)
+
+	AssertNotNil(t, result.Canary)
+	AssertEqual(t, result.Canary.Messages, 50)
+	AssertEqual(t, result.Canary.Sent, 40)
+	AssertEqual(t, result.Canary.Failed, 5)
+	AssertEqual(t, *result.Canary.HeldBack, 150)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
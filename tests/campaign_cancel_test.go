@@ -0,0 +1,332 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// cancelFixture is a campaign service whose campaign 1 moves through cancellation like the
+// campaigns table would, against a clock the test advances
+type cancelFixture struct {
+	svc          *service.CampaignService
+	campaignRepo *MockCampaignRepository
+	messageRepo  *MockMessageRepository
+	now          time.Time
+	status       models.CampaignStatus
+	cancelAt     *time.Time
+	pending      int
+}
+
+// newCancelFixture creates a sending campaign with 40 pending messages
+func newCancelFixture(t *testing.T) *cancelFixture {
+	t.Helper()
+
+	campaignRepo := NewMockCampaignRepository()
+	messageRepo := NewMockMessageRepository()
+	svc, _ := NewMockCampaignService(t, campaignRepo, messageRepo)
+	f := &cancelFixture{
+		svc:          svc,
+		campaignRepo: campaignRepo,
+		messageRepo:  messageRepo,
+		now:          time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+		status:       models.CampaignStatusSending,
+		pending:      40,
+	}
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		campaign := NewTestCampaignWithStatus(f.status)
+		campaign.CancelAt = f.cancelAt
+		return campaign, nil
+	}
+	campaignRepo.BeginCancelFunc = func(ctx context.Context, id int, grace time.Duration) (time.Time, error) {
+		cancelAt := f.now.Add(grace)
+		f.status, f.cancelAt = models.CampaignStatusCancelling, &cancelAt
+		return cancelAt, nil
+	}
+	campaignRepo.UndoCancelFunc = func(ctx context.Context, id int) error {
+		if !f.now.Before(*f.cancelAt) {
+			return repository.ErrCancelFinal
+		}
+		f.status, f.cancelAt = models.CampaignStatusSending, nil
+		return nil
+	}
+	campaignRepo.ListDueCancellationsFunc = func(ctx context.Context) ([]int, error) {
+		if f.status == models.CampaignStatusCancelling && !f.now.Before(*f.cancelAt) {
+			return []int{1}, nil
+		}
+		return []int{}, nil
+	}
+	campaignRepo.FinalizeCancellationFunc = func(ctx context.Context, id int) (int, error) {
+		if f.status != models.CampaignStatusCancelling || f.now.Before(*f.cancelAt) {
+			return 0, repository.ErrCancelNotDue
+		}
+		cancelled := f.pending
+		f.status, f.pending = models.CampaignStatusCancelled, 0
+		return cancelled, nil
+	}
+	messageRepo.ReleaseHeldFunc = func(ctx context.Context, campaignID int) (int, error) {
+		return 3, nil
+	}
+	return f
+}
+
+// reconcile runs one reconciler check with cancellations enabled
+func (f *cancelFixture) reconcile(t *testing.T) {
+	t.Helper()
+
+	reconciler := service.NewPublishReconciler(NewMockMessageRepository())
+	reconciler.SetCancellations(f.campaignRepo)
+	_, err := reconciler.Reconcile(context.Background(), func(message *models.OutboundMessage) error { return nil })
+	AssertNoError(t, err)
+}
+
+// TestCancelCampaign_UndoInsideWindow tests that a cancellation undone within its grace period
+// restores sending and releases the held messages, and that the reconciler leaves it alone
+func TestCancelCampaign_UndoInsideWindow(t *testing.T) {
+	f := newCancelFixture(t)
+
+	result, err := f.svc.CancelCampaign(context.Background(), 1, time.Minute)
+	AssertNoError(t, err)
+	AssertEqual(t, result.Status, models.CampaignStatusCancelling)
+	AssertEqual(t, result.CancelAt, f.now.Add(time.Minute))
+	AssertEqual(t, f.campaignRepo.Calls["FinalizeCancellation"], 0)
+
+	f.now = f.now.Add(30 * time.Second)
+	f.reconcile(t)
+	AssertEqual(t, f.status, models.CampaignStatusCancelling)
+
+	undone, err := f.svc.UndoCancel(context.Background(), 1)
+	AssertNoError(t, err)
+	AssertEqual(t, undone.Status, models.CampaignStatusSending)
+	AssertEqual(t, undone.MessagesReleased, 3)
+	AssertEqual(t, f.status, models.CampaignStatusSending)
+
+	// Past the original deadline nothing is finalized
+	f.now = f.now.Add(time.Hour)
+	f.reconcile(t)
+	AssertEqual(t, f.status, models.CampaignStatusSending)
+	AssertEqual(t, f.pending, 40)
+	AssertEqual(t, f.campaignRepo.Calls["FinalizeCancellation"], 0)
+}
+
+// TestCancelCampaign_UndoAfterWindow tests that an undo after the grace period is refused and
+// the reconciler finalizes the cancellation, cancelling the pending messages
+func TestCancelCampaign_UndoAfterWindow(t *testing.T) {
+	f := newCancelFixture(t)
+
+	_, err := f.svc.CancelCampaign(context.Background(), 1, time.Minute)
+	AssertNoError(t, err)
+
+	f.now = f.now.Add(61 * time.Second)
+	_, err = f.svc.UndoCancel(context.Background(), 1)
+	AssertError(t, err, "business logic error: the cancellation grace period has ended; the campaign can no longer be restored")
+	AssertEqual(t, f.messageRepo.Calls["ReleaseHeld"], 0)
+
+	f.reconcile(t)
+	AssertEqual(t, f.status, models.CampaignStatusCancelled)
+	AssertEqual(t, f.pending, 0)
+
+	_, err = f.svc.UndoCancel(context.Background(), 1)
+	AssertError(t, err, "business logic error: campaign is not cancelling: status is cancelled")
+	_, err = f.svc.CancelCampaign(context.Background(), 1, time.Minute)
+	AssertError(t, err, "business logic error: campaign cannot be cancelled: status is cancelled")
+}
+
+// TestCancelCampaign_NoGrace tests that a zero grace period cancels at once
+func TestCancelCampaign_NoGrace(t *testing.T) {
+	f := newCancelFixture(t)
+
+	result, err := f.svc.CancelCampaign(context.Background(), 1, 0)
+	AssertNoError(t, err)
+	AssertEqual(t, result.Status, models.CampaignStatusCancelled)
+	AssertEqual(t, result.MessagesCancelled, 40)
+	AssertEqual(t, f.status, models.CampaignStatusCancelled)
+}
+
+// TestCancelCampaign_Checks tests the grace bounds and which statuses can be cancelled
+func TestCancelCampaign_Checks(t *testing.T) {
+	f := newCancelFixture(t)
+
+	_, err := f.svc.CancelCampaign(context.Background(), 1, -time.Second)
+	AssertError(t, err, "validation error: grace_seconds must be between 0 and 3600")
+	_, err = f.svc.CancelCampaign(context.Background(), 1, 2*time.Hour)
+	AssertError(t, err, "validation error: grace_seconds must be between 0 and 3600")
+
+	f.status = models.CampaignStatusDraft
+	_, err = f.svc.CancelCampaign(context.Background(), 1, time.Minute)
+	AssertError(t, err, "business logic error: campaign cannot be cancelled: status is draft")
+	_, err = f.svc.UndoCancel(context.Background(), 1)
+	AssertError(t, err, "business logic error: campaign is not cancelling: status is draft")
+
+	// A paused campaign can be cancelled too
+	f.status = models.CampaignStatusPaused
+	_, err = f.svc.CancelCampaign(context.Background(), 1, time.Minute)
+	AssertNoError(t, err)
+	AssertEqual(t, f.campaignRepo.Calls["BeginCancel"], 1)
+}
+
+// TestCancelCampaign_Endpoints tests the cancel and undo-cancel endpoints
+func TestCancelCampaign_Endpoints(t *testing.T) {
+	f := newCancelFixture(t)
+	h := handler.NewCampaignHandler(f.svc)
+	router := NewTestRouter(map[string]http.HandlerFunc{
+		"POST /campaigns/{id}/cancel":      h.Cancel,
+		"POST /campaigns/{id}/undo-cancel": h.UndoCancel,
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		return ServeTestRequest(router, "POST", path, "")
+	}
+
+	AssertStatusCode(t, serve("/campaigns/1/cancel?grace_seconds=abc"), http.StatusBadRequest)
+	AssertStatusCode(t, serve("/campaigns/1/cancel?grace_seconds=7200"), http.StatusBadRequest)
+
+	// Without grace_seconds the default grace period applies
+	resp := serve("/campaigns/1/cancel")
+	AssertStatusCode(t, resp, http.StatusOK)
+	var result service.CancelCampaignResult
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, result.Status, models.CampaignStatusCancelling)
+	AssertEqual(t, result.CancelAt, f.now.Add(service.DefaultCancelGrace))
+
+	AssertStatusCode(t, serve("/campaigns/1/cancel"), http.StatusBadRequest)
+
+	resp = serve("/campaigns/1/undo-cancel")
+	AssertStatusCode(t, resp, http.StatusOK)
+	var undone service.UndoCancelResult
+	ParseJSONResponse(t, resp, &undone)
+	AssertEqual(t, undone.Status, models.CampaignStatusSending)
+
+	AssertStatusCode(t, serve("/campaigns/1/undo-cancel"), http.StatusBadRequest)
+}
+
+// TestMessageProcessor_CancellingCampaign tests that the worker holds messages of a cancelling
+// campaign and skips those of a cancelled one, sending neither
+func TestMessageProcessor_CancellingCampaign(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	status := models.CampaignStatusCancelling
+	messageRepo := NewMockMessageRepository()
+	messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		message := NewTestMessageWithStatus(models.MessageStatusPending)
+		message.ID = id
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *message,
+			Campaign:        *NewTestCampaignWithStatus(status),
+			Customer:        *NewTestCustomer(),
+		}, nil
+	}
+	var held []int
+	messageRepo.HoldFunc = func(ctx context.Context, id int, reason string) error {
+		AssertEqual(t, reason, "Held: campaign cancelling")
+		held = append(held, id)
+		return nil
+	}
+
+	sender := &countingSender{}
+	processor := service.NewMessageProcessor(db, messageRepo, service.NewTemplateService(), sender, service.NewAttemptBudget(messageRepo, 0), nil)
+
+	AssertNoError(t, processor.Handle(&queue.MessageJob{MessageID: 5, CampaignID: 1, CustomerID: 1}))
+	AssertEqual(t, len(held), 1)
+	AssertEqual(t, held[0], 5)
+
+	status = models.CampaignStatusCancelled
+	AssertNoError(t, processor.Handle(&queue.MessageJob{MessageID: 6, CampaignID: 1, CustomerID: 1}))
+	AssertEqual(t, len(held), 1)
+
+	AssertEqual(t, sender.calls, 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPublishReconciler_FinalizeFailures tests that a cancellation undone since it was listed,
+// or one that fails, does not stop the others or the publish check
+func TestPublishReconciler_FinalizeFailures(t *testing.T) {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.ListDueCancellationsFunc = func(ctx context.Context) ([]int, error) {
+		return []int{3, 4, 5}, nil
+	}
+	var finalized []int
+	campaignRepo.FinalizeCancellationFunc = func(ctx context.Context, id int) (int, error) {
+		switch id {
+		case 3:
+			return 0, repository.ErrCancelNotDue
+		case 4:
+			return 0, errors.New("connection reset")
+		}
+		finalized = append(finalized, id)
+		return 12, nil
+	}
+	messageRepo := NewMockMessageRepository()
+
+	reconciler := service.NewPublishReconciler(messageRepo)
+	reconciler.SetCancellations(campaignRepo)
+	_, err := reconciler.Reconcile(context.Background(), func(message *models.OutboundMessage) error { return nil })
+	AssertNoError(t, err)
+
+	AssertEqual(t, campaignRepo.Calls["FinalizeCancellation"], 3)
+	AssertEqual(t, len(finalized), 1)
+	AssertEqual(t, finalized[0], 5)
+	AssertEqual(t, messageRepo.Calls["ClaimUnpublished"], 1)
+}
+
+// TestCampaignCancellation_Queries tests the begin, undo and finalize queries
+func TestCampaignCancellation_Queries(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaignRepo := repository.NewCampaignRepository(db)
+	ctx := context.Background()
+	cancelAt := time.Date(2026, 3, 2, 9, 1, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`UPDATE campaigns SET status = \$2, cancel_at = CURRENT_TIMESTAMP \+ make_interval\(secs => \$3\)`).
+		WithArgs(7, models.CampaignStatusCancelling, 60.0, models.CampaignStatusSending, models.CampaignStatusPaused, models.CampaignStatusCanary).
+		WillReturnRows(sqlmock.NewRows([]string{"cancel_at"}).AddRow(cancelAt))
+	got, err := campaignRepo.BeginCancel(ctx, 7, time.Minute)
+	AssertNoError(t, err)
+	AssertEqual(t, got, cancelAt)
+
+	// Still cancelling but past the deadline: the cancellation is final
+	mock.ExpectExec(`UPDATE campaigns SET status = \$2, cancel_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = \$1 AND status = \$3 AND cancel_at > CURRENT_TIMESTAMP`).
+		WithArgs(7, models.CampaignStatusSending, models.CampaignStatusCancelling).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT status FROM campaigns WHERE id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("cancelling"))
+	err = campaignRepo.UndoCancel(ctx, 7)
+	AssertEqual(t, errors.Is(err, repository.ErrCancelFinal), true)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE campaigns SET status = \$2, updated_at = CURRENT_TIMESTAMP WHERE id = \$1 AND status = \$3 AND cancel_at <= CURRENT_TIMESTAMP`).
+		WithArgs(7, models.CampaignStatusCancelled, models.CampaignStatusCancelling).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE outbound_messages SET status = \$2, deliver_after = NULL, last_error = 'Campaign cancelled'`).
+		WithArgs(7, models.MessageStatusCancelled, models.MessageStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 18))
+	mock.ExpectCommit()
+	cancelled, err := campaignRepo.FinalizeCancellation(ctx, 7)
+	AssertNoError(t, err)
+	AssertEqual(t, cancelled, 18)
+
+	// Undone since it was listed
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE campaigns SET status = \$2, updated_at = CURRENT_TIMESTAMP`).
+		WithArgs(7, models.CampaignStatusCancelled, models.CampaignStatusCancelling).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	_, err = campaignRepo.FinalizeCancellation(ctx, 7)
+	AssertEqual(t, errors.Is(err, repository.ErrCancelNotDue), true)
+
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// setupComparisonTest seeds an SMS campaign (4), its WhatsApp variant (9) and an unsent repeat (13)
+func setupComparisonTest(t *testing.T) (*mux.Router, *MockCampaignRepository) {
+	t.Helper()
+
+	campaigns := map[int]*models.Campaign{
+		4:  {ID: 4, Name: "Promo A", Channel: models.ChannelSMS, Status: models.CampaignStatusSent},
+		9:  {ID: 9, Name: "Promo B", Channel: models.ChannelWhatsApp, Status: models.CampaignStatusSent},
+		13: {ID: 13, Name: "Promo A (repeat)", Channel: models.ChannelSMS, Status: models.CampaignStatusDraft},
+	}
+	first := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	last := first.Add(10 * time.Minute)
+	audience := 100
+	none := 0
+
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		if campaign, ok := campaigns[id]; ok {
+			return campaign, nil
+		}
+		return nil, errors.New("campaign not found")
+	}
+	campaignRepo.GetStatsByIDsFunc = func(ctx context.Context, ids []int) (map[int]*models.CampaignStats, error) {
+		return map[int]*models.CampaignStats{
+			4:  {Total: 100, Sent: 90, Failed: 10, Audience: &audience, FirstMessageAt: &first, LastFinishedAt: &last},
+			9:  {Total: 100, Sent: 95, Failed: 5, Audience: &audience, FirstMessageAt: &first, LastFinishedAt: &first},
+			13: {Audience: &none},
+		}, nil
+	}
+	campaignRepo.GetFailureBreakdownByIDsFunc = func(ctx context.Context, ids []int) (map[int][]*models.ErrorCount, error) {
+		return map[int][]*models.ErrorCount{
+			4:  {{Error: "invalid number", Count: 7}, {Error: "timeout", Count: 3}},
+			9:  {{Error: "not on whatsapp", Count: 5}},
+			13: {},
+		}, nil
+	}
+
+	router := mux.NewRouter()
+	h := handler.NewExportHandler(service.NewExportService(campaignRepo, testSendingConfig()))
+	router.HandleFunc("/campaigns/compare", h.Compare).Methods("GET")
+	return router, campaignRepo
+}
+
+// TestCompareCampaigns tests per-campaign metrics and deltas against the first campaign
+func TestCompareCampaigns(t *testing.T) {
+	router, _ := setupComparisonTest(t)
+
+	req := httptest.NewRequest("GET", "/campaigns/compare?ids=4,9,13", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var comparison service.CampaignComparison
+	ParseJSONResponse(t, resp, &comparison)
+	AssertEqual(t, comparison.BaselineID, 4)
+	AssertEqual(t, len(comparison.Campaigns), 3)
+
+	a, b, repeat := comparison.Campaigns[0], comparison.Campaigns[1], comparison.Campaigns[2]
+	AssertEqual(t, a.ID, 4)
+	AssertEqual(t, a.AudienceSize, 100)
+	AssertEqual(t, a.Cost, 72.0)
+	AssertEqual(t, *a.CostPerSent, 0.8)
+	AssertEqual(t, *a.SuccessRate, 0.9)
+	AssertEqual(t, *a.DurationSeconds, 600.0)
+	AssertEqual(t, len(a.FailureBreakdown), 2)
+	AssertEqual(t, a.FailureBreakdown[0].Error, "invalid number")
+	if a.Delta != nil {
+		t.Errorf("Expected no delta for the baseline, got %+v", a.Delta)
+	}
+
+	AssertEqual(t, b.Cost, 47.5)
+	AssertEqual(t, *b.SuccessRate, 0.95)
+	AssertNotNil(t, b.Delta)
+	AssertEqual(t, *b.Delta.SuccessRate, 0.05)
+	AssertEqual(t, *b.Delta.CostPerSent, -0.3)
+
+	// Nothing sent yet, so nothing to compare
+	AssertEqual(t, repeat.Cost, 0.0)
+	AssertEqual(t, repeat.AudienceSize, 0)
+	if repeat.SuccessRate != nil || repeat.CostPerSent != nil || repeat.DurationSeconds != nil {
+		t.Errorf("Expected no rates or duration for an unsent campaign, got %+v", repeat)
+	}
+	AssertNotNil(t, repeat.Delta)
+	if repeat.Delta.SuccessRate != nil || repeat.Delta.CostPerSent != nil {
+		t.Errorf("Expected empty deltas for an unsent campaign, got %+v", repeat.Delta)
+	}
+	AssertEqual(t, len(repeat.FailureBreakdown), 0)
+}
+
+// TestCompareCampaigns_BaselineIsFirstID tests that deltas follow the order of the ids
+func TestCompareCampaigns_BaselineIsFirstID(t *testing.T) {
+	router, _ := setupComparisonTest(t)
+
+	req := httptest.NewRequest("GET", "/campaigns/compare?ids=9&ids=4", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var comparison service.CampaignComparison
+	ParseJSONResponse(t, resp, &comparison)
+	AssertEqual(t, comparison.BaselineID, 9)
+	AssertEqual(t, *comparison.Campaigns[1].Delta.SuccessRate, -0.05)
+	AssertEqual(t, *comparison.Campaigns[1].Delta.CostPerSent, 0.3)
+}
+
+// TestCompareCampaigns_InvalidIDs tests validation of the id list
+func TestCompareCampaigns_InvalidIDs(t *testing.T) {
+	router, campaignRepo := setupComparisonTest(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"missing", "", "ids must list 2 to 5 campaigns"},
+		{"one", "?ids=4", "ids must list 2 to 5 campaigns"},
+		{"six", "?ids=1,2,3,4,5,6", "ids must list 2 to 5 campaigns"},
+		{"duplicate", "?ids=4,9,4", "campaign 4 is listed more than once"},
+		{"not a number", "?ids=4,abc", "ids must be positive integers"},
+		{"zero", "?ids=4,0", "ids must be positive integers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/campaigns/compare"+tt.query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			AssertStatusCode(t, resp, http.StatusBadRequest)
+			if !strings.Contains(resp.Body.String(), tt.want) {
+				t.Errorf("Expected %q in the response, got %s", tt.want, resp.Body.String())
+			}
+		})
+	}
+	AssertEqual(t, campaignRepo.Calls["GetStatsByIDs"], 0)
+}
+
+// TestCompareCampaigns_UnknownCampaign tests that a missing campaign is reported by ID
+func TestCompareCampaigns_UnknownCampaign(t *testing.T) {
+	router, _ := setupComparisonTest(t)
+
+	req := httptest.NewRequest("GET", "/campaigns/compare?ids=4,77", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusNotFound)
+}
+
+// TestGetStatsByIDs_ComparisonFields tests that bulk stats carry audience size and send span
+func TestGetStatsByIDs_ComparisonFields(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	first := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	last := first.Add(time.Minute)
+
+	mock.ExpectQuery(`COUNT\(DISTINCT customer_id\) as audience`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"campaign_id", "total", "pending", "sent", "failed", "queued", "unpublished", "simulated", "p95_queue_latency", "audience", "first_message_at", "last_finished_at", "skipped_duplicate_content", "expired"}).
+			AddRow(4, 12, 0, 10, 2, 0, 0, 0, nil, 11, first, last, 0, 0))
+
+	stats, err := repository.NewCampaignRepository(db).GetStatsByIDs(context.Background(), []int{4, 9})
+	AssertNoError(t, err)
+
+	AssertEqual(t, *stats[4].Audience, 11)
+	AssertEqual(t, stats[4].LastFinishedAt.Sub(*stats[4].FirstMessageAt), time.Minute)
+	AssertEqual(t, *stats[9].Audience, 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetFailureBreakdownByIDs tests grouping failed messages by campaign and error
+func TestGetFailureBreakdownByIDs(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT campaign_id, COALESCE\(last_error, ''\), COUNT\(\*\)`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"campaign_id", "last_error", "count"}).
+			AddRow(4, "invalid number", 7).
+			AddRow(4, "timeout", 3))
+
+	breakdown, err := repository.NewCampaignRepository(db).GetFailureBreakdownByIDs(context.Background(), []int{4, 9})
+	AssertNoError(t, err)
+
+	AssertEqual(t, len(breakdown[4]), 2)
+	AssertEqual(t, breakdown[4][1].Error, "timeout")
+	AssertEqual(t, breakdown[4][1].Count, 3)
+	AssertNotNil(t, breakdown[9])
+	AssertEqual(t, len(breakdown[9]), 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,306 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// campaignEventFixture is the campaign events endpoint over in-memory events
+type campaignEventFixture struct {
+	events *MockCampaignEventRepository
+	hub    *service.CampaignEventHub
+	svc    *service.CampaignEventService
+	router *mux.Router
+}
+
+// newCampaignEventFixture creates a fixture where only campaign 1 exists
+func newCampaignEventFixture(timeout, pollInterval time.Duration) *campaignEventFixture {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		if id != 1 {
+			return nil, errors.New("campaign not found")
+		}
+		return NewTestCampaign(), nil
+	}
+
+	f := &campaignEventFixture{
+		events: NewMockCampaignEventRepository(),
+		hub:    service.NewCampaignEventHub(),
+	}
+	f.svc = service.NewCampaignEventService(f.events, campaignRepo, f.hub)
+	f.svc.SetWaitTimeout(timeout, pollInterval)
+
+	f.router = mux.NewRouter()
+	f.router.HandleFunc("/campaigns/{id:[0-9]+}/events", handler.NewCampaignEventHandler(f.svc).Events).Methods("GET")
+	return f
+}
+
+func (f *campaignEventFixture) get(target string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	f.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	return rr
+}
+
+func (f *campaignEventFixture) record(t *testing.T, campaignID int, eventType models.CampaignEventType, payload string) {
+	t.Helper()
+	_, err := f.events.Create(context.Background(), &models.CampaignEvent{CampaignID: campaignID, Type: eventType, Payload: json.RawMessage(payload)})
+	AssertNoError(t, err)
+}
+
+// TestCampaignEvents_ReturnsExisting tests that events past since_id are returned at once, oldest first
+func TestCampaignEvents_ReturnsExisting(t *testing.T) {
+	f := newCampaignEventFixture(time.Minute, time.Minute)
+	f.record(t, 1, models.CampaignEventQueued, `{"messages_queued": 2500}`)
+	f.record(t, 2, models.CampaignEventQueued, `{"messages_queued": 10}`)
+	f.record(t, 1, models.CampaignEventProgress, `{"sent": 1000}`)
+
+	rr := f.get("/campaigns/1/events?since_id=0")
+	AssertStatusCode(t, rr, http.StatusOK)
+
+	var events []models.CampaignEvent
+	ParseJSONResponse(t, rr, &events)
+	AssertEqual(t, len(events), 2)
+	AssertEqual(t, events[0].ID, int64(1))
+	AssertEqual(t, events[0].Type, models.CampaignEventQueued)
+	AssertEqual(t, string(events[0].Payload), `{"messages_queued":2500}`)
+	AssertEqual(t, events[1].ID, int64(3))
+
+	rr = f.get("/campaigns/1/events?since_id=1")
+	ParseJSONResponse(t, rr, &events)
+	AssertEqual(t, len(events), 1)
+	AssertEqual(t, events[0].Type, models.CampaignEventProgress)
+}
+
+// TestCampaignEvents_WakeOnEvent tests that a waiting request returns as soon as it is
+// notified of a new event, long before its timeout
+func TestCampaignEvents_WakeOnEvent(t *testing.T) {
+	f := newCampaignEventFixture(time.Minute, time.Minute)
+	f.record(t, 1, models.CampaignEventQueued, `{"messages_queued": 5}`)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- f.get("/campaigns/1/events?since_id=1")
+	}()
+
+	// Another campaign's event does not wake the request
+	time.Sleep(50 * time.Millisecond)
+	f.record(t, 2, models.CampaignEventQueued, `{"messages_queued": 1}`)
+	f.hub.Notify(2)
+	select {
+	case <-done:
+		t.Fatal("Request returned for another campaign's event")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	f.record(t, 1, models.CampaignEventCompleted, `{"sent": 5}`)
+	f.hub.Notify(1)
+
+	select {
+	case rr := <-done:
+		AssertStatusCode(t, rr, http.StatusOK)
+		var events []models.CampaignEvent
+		ParseJSONResponse(t, rr, &events)
+		AssertEqual(t, len(events), 1)
+		AssertEqual(t, events[0].Type, models.CampaignEventCompleted)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Request was not woken by the event")
+	}
+}
+
+// TestCampaignEvents_PollsWithoutNotification tests that a missed notification only delays
+// the request until the next poll
+func TestCampaignEvents_PollsWithoutNotification(t *testing.T) {
+	f := newCampaignEventFixture(time.Minute, 20*time.Millisecond)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- f.get("/campaigns/1/events")
+	}()
+	time.Sleep(10 * time.Millisecond)
+	f.record(t, 1, models.CampaignEventQueued, `{"messages_queued": 5}`)
+
+	select {
+	case rr := <-done:
+		var events []models.CampaignEvent
+		ParseJSONResponse(t, rr, &events)
+		AssertEqual(t, len(events), 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Request did not find the event by polling")
+	}
+}
+
+// TestCampaignEvents_TimeoutEmpty tests that a request with nothing new returns an empty array
+// once the wait times out
+func TestCampaignEvents_TimeoutEmpty(t *testing.T) {
+	f := newCampaignEventFixture(50*time.Millisecond, time.Minute)
+	f.record(t, 1, models.CampaignEventQueued, `{"messages_queued": 5}`)
+
+	start := time.Now()
+	rr := f.get("/campaigns/1/events?since_id=1")
+	elapsed := time.Since(start)
+
+	AssertStatusCode(t, rr, http.StatusOK)
+	AssertEqual(t, strings.TrimSpace(rr.Body.String()), "[]")
+	if elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the request to wait out its timeout, returned after %s", elapsed)
+	}
+}
+
+// TestCampaignEvents_ContextCancelled tests that a request stops waiting when its caller goes away
+func TestCampaignEvents_ContextCancelled(t *testing.T) {
+	f := newCampaignEventFixture(time.Minute, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := f.svc.Wait(ctx, 1, 0)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not stop when its context was cancelled")
+	}
+}
+
+// TestCampaignEvents_InvalidRequests tests the endpoint's validation and missing campaigns
+func TestCampaignEvents_InvalidRequests(t *testing.T) {
+	f := newCampaignEventFixture(time.Minute, time.Minute)
+
+	testCases := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{name: "negative since_id", target: "/campaigns/1/events?since_id=-1", status: http.StatusBadRequest},
+		{name: "non-numeric since_id", target: "/campaigns/1/events?since_id=abc", status: http.StatusBadRequest},
+		{name: "zero campaign ID", target: "/campaigns/0/events", status: http.StatusBadRequest},
+		{name: "missing campaign", target: "/campaigns/99/events", status: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			AssertStatusCode(t, f.get(tc.target), tc.status)
+		})
+	}
+}
+
+// TestCampaignProgressReporter tests that progress events are written every 1000 sends and a
+// completed event once nothing is left, each only once however often it is reported
+func TestCampaignProgressReporter(t *testing.T) {
+	events := NewMockCampaignEventRepository()
+	reporter := service.NewCampaignProgressReporter(events)
+
+	report := func(progress ...*models.CampaignProgress) int {
+		t.Helper()
+		events.Progress = progress
+		written, err := reporter.Report(context.Background())
+		AssertNoError(t, err)
+		return written
+	}
+
+	// Under the first milestone nothing is written
+	AssertEqual(t, report(&models.CampaignProgress{CampaignID: 1, Sent: 999, Outstanding: 1500}), 0)
+
+	// Past 2000 sends one event reports the latest milestone, and reporting again adds nothing
+	AssertEqual(t, report(&models.CampaignProgress{CampaignID: 1, Sent: 2100, Outstanding: 400}), 1)
+	AssertEqual(t, report(&models.CampaignProgress{CampaignID: 1, Sent: 2300, Outstanding: 200}), 0)
+
+	// Once nothing is outstanding the campaign completes
+	AssertEqual(t, report(&models.CampaignProgress{CampaignID: 1, Sent: 2480, Failed: 20}), 1)
+	AssertEqual(t, report(&models.CampaignProgress{CampaignID: 1, Sent: 2480, Failed: 20}), 0)
+
+	AssertEqual(t, len(events.Events), 2)
+	AssertEqual(t, events.Events[0].Type, models.CampaignEventProgress)
+	AssertEqual(t, *events.Events[0].DedupeKey, "progress:2000")
+	AssertEqual(t, events.Events[1].Type, models.CampaignEventCompleted)
+
+	var completed models.CampaignProgress
+	AssertNoError(t, json.Unmarshal(events.Events[1].Payload, &completed))
+	AssertEqual(t, completed.Sent, 2480)
+	AssertEqual(t, completed.Failed, 20)
+}
+
+// TestPublishReconciler_RecordsQueuedEvents tests that reconciled messages are recorded as
+// one queued event per campaign
+func TestPublishReconciler_RecordsQueuedEvents(t *testing.T) {
+	messageRepo := NewMockMessageRepository()
+	messageRepo.ClaimUnpublishedFunc = func(ctx context.Context, before time.Time, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error) {
+		return []*models.OutboundMessage{
+			{ID: 1, CampaignID: 7, CustomerID: 5},
+			{ID: 2, CampaignID: 7, CustomerID: 6},
+			{ID: 3, CampaignID: 8, CustomerID: 5},
+		}, nil
+	}
+	events := NewMockCampaignEventRepository()
+	reconciler := service.NewPublishReconciler(messageRepo)
+	reconciler.SetCampaignEvents(events)
+
+	_, err := reconciler.Reconcile(context.Background(), func(message *models.OutboundMessage) error {
+		if message.ID == 3 {
+			return errors.New("channel closed")
+		}
+		return nil
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, events.Count(7), 1)
+	AssertEqual(t, events.Count(8), 0)
+	AssertEqual(t, events.Events[0].Type, models.CampaignEventQueued)
+	AssertEqual(t, string(events.Events[0].Payload), `{"messages_queued":2,"reconciled":true}`)
+}
+
+// TestCampaignProgressReporter_SendMetrics tests that a completed campaign has its send metrics
+// recorded at the configured rate before the completed event, and a failed write is retried
+func TestCampaignProgressReporter_SendMetrics(t *testing.T) {
+	events := NewMockCampaignEventRepository()
+	reporter := service.NewCampaignProgressReporter(events)
+	reporter.SetSendRate(service.EffectiveRatePerSecond(config.SendingConfig{WorkerConcurrency: 4, AvgSendLatencyMs: 200, RateLimitPerSecond: 15}))
+
+	recorded := []float64{}
+	events.RecordSendMetricsFunc = func(ctx context.Context, campaignID int, ratePerSecond float64) error {
+		if len(recorded) == 0 {
+			recorded = append(recorded, 0)
+			return errors.New("connection refused")
+		}
+		AssertEqual(t, campaignID, 7)
+		recorded = append(recorded, ratePerSecond)
+		return nil
+	}
+
+	// A campaign still sending records nothing
+	events.Progress = []*models.CampaignProgress{{CampaignID: 7, Sent: 10, Outstanding: 5}}
+	_, err := reporter.Report(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, events.Calls["RecordSendMetrics"], 0)
+
+	events.Progress = []*models.CampaignProgress{{CampaignID: 7, Sent: 14, Failed: 1}}
+	_, err = reporter.Report(context.Background())
+	AssertError(t, err, "connection refused")
+	AssertEqual(t, len(events.Events), 0)
+
+	written, err := reporter.Report(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, written, 1)
+	AssertEqual(t, events.Events[0].Type, models.CampaignEventCompleted)
+	AssertEqual(t, recorded[1], 15.0) // Four workers manage 20/s, capped by the rate limit
+}
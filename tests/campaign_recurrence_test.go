@@ -0,0 +1,350 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// weekendSaleRule repeats every Friday at 09:00 Nairobi time (06:00 UTC)
+func weekendSaleRule() models.RecurrenceRule {
+	return models.RecurrenceRule{
+		Frequency: models.RecurrenceWeekly,
+		DayOfWeek: IntPtr(int(time.Friday)),
+		Time:      "09:00",
+		Timezone:  "Africa/Nairobi",
+	}
+}
+
+// utc parses an RFC 3339 time for expectations
+func utc(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	AssertNoError(t, err)
+	return parsed.UTC()
+}
+
+// assertNext checks the occurrence a rule gives after a time
+func assertNext(t *testing.T, rule models.RecurrenceRule, after, want string) {
+	t.Helper()
+	next, ok := rule.Next(utc(t, after))
+	if !ok {
+		t.Fatalf("Expected an occurrence after %s, rule has ended", after)
+	}
+	AssertEqual(t, next.Format(time.RFC3339), want)
+}
+
+// TestRecurrenceRule_Validate tests each field of a recurrence rule
+func TestRecurrenceRule_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(rule *models.RecurrenceRule)
+		err    string
+	}{
+		{"weekly", func(rule *models.RecurrenceRule) {}, ""},
+		{"monthly", func(rule *models.RecurrenceRule) {
+			rule.Frequency, rule.DayOfWeek, rule.DayOfMonth = models.RecurrenceMonthly, nil, IntPtr(31)
+		}, ""},
+		{"daily", func(rule *models.RecurrenceRule) { rule.Frequency = "daily" }, `recurrence frequency must be "weekly" or "monthly"`},
+		{"weekly without a day", func(rule *models.RecurrenceRule) { rule.DayOfWeek = nil }, "weekly recurrence requires day_of_week from 0 (Sunday) to 6 (Saturday)"},
+		{"day of week out of range", func(rule *models.RecurrenceRule) { rule.DayOfWeek = IntPtr(7) }, "weekly recurrence requires day_of_week from 0 (Sunday) to 6 (Saturday)"},
+		{"weekly with a day of month", func(rule *models.RecurrenceRule) { rule.DayOfMonth = IntPtr(1) }, "weekly recurrence does not take day_of_month"},
+		{"day of month out of range", func(rule *models.RecurrenceRule) {
+			rule.Frequency, rule.DayOfWeek, rule.DayOfMonth = models.RecurrenceMonthly, nil, IntPtr(32)
+		}, "monthly recurrence requires day_of_month from 1 to 31"},
+		{"bad time", func(rule *models.RecurrenceRule) { rule.Time = "9am" }, `recurrence time must be HH:MM, e.g. "09:00"`},
+		{"missing timezone", func(rule *models.RecurrenceRule) { rule.Timezone = "" }, `recurrence timezone is required, e.g. "Africa/Nairobi"`},
+		{"unknown timezone", func(rule *models.RecurrenceRule) { rule.Timezone = "Mars/Olympus" }, `unknown recurrence timezone "Mars/Olympus"`},
+		{"bad end date", func(rule *models.RecurrenceRule) { rule.EndDate = StringPtr("31/12/2026") }, "recurrence end_date must be YYYY-MM-DD"},
+		{"no occurrences", func(rule *models.RecurrenceRule) { rule.MaxOccurrences = IntPtr(0) }, "recurrence max_occurrences must be at least 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := weekendSaleRule()
+			tt.modify(&rule)
+			err := rule.Validate()
+			if tt.err == "" {
+				AssertNoError(t, err)
+				return
+			}
+			AssertError(t, err, tt.err)
+		})
+	}
+}
+
+// TestRecurrenceRule_NextWeekly tests that weekly occurrences fall on the rule's day and local time
+func TestRecurrenceRule_NextWeekly(t *testing.T) {
+	rule := weekendSaleRule()
+
+	assertNext(t, rule, "2026-10-14T12:00:00Z", "2026-10-16T06:00:00Z")
+	assertNext(t, rule, "2026-10-16T05:59:00Z", "2026-10-16T06:00:00Z")
+	// Strictly after: an occurrence is never returned twice
+	assertNext(t, rule, "2026-10-16T06:00:00Z", "2026-10-23T06:00:00Z")
+}
+
+// TestRecurrenceRule_NextMonthly tests that a day missing from a month falls on its last day
+func TestRecurrenceRule_NextMonthly(t *testing.T) {
+	rule := models.RecurrenceRule{
+		Frequency:  models.RecurrenceMonthly,
+		DayOfMonth: IntPtr(31),
+		Time:       "18:30",
+		Timezone:   "UTC",
+	}
+
+	assertNext(t, rule, "2027-01-31T18:30:00Z", "2027-02-28T18:30:00Z")
+	assertNext(t, rule, "2027-04-02T00:00:00Z", "2027-04-30T18:30:00Z")
+	assertNext(t, rule, "2026-12-31T19:00:00Z", "2027-01-31T18:30:00Z")
+}
+
+// TestRecurrenceRule_NextAcrossDST tests that occurrences keep their local time when the
+// clocks change, and land once on a time the change skips or repeats
+func TestRecurrenceRule_NextAcrossDST(t *testing.T) {
+	rule := models.RecurrenceRule{
+		Frequency: models.RecurrenceWeekly,
+		DayOfWeek: IntPtr(int(time.Sunday)),
+		Time:      "09:00",
+		Timezone:  "America/New_York",
+	}
+
+	// 09:00 EDT, then 09:00 EST once the clocks go back on November 1st
+	assertNext(t, rule, "2026-10-24T12:00:00Z", "2026-10-25T13:00:00Z")
+	assertNext(t, rule, "2026-10-25T13:00:00Z", "2026-11-01T14:00:00Z")
+
+	// 02:30 does not exist on March 8th, when the clocks go forward at 02:00; it runs at 03:30 EDT
+	rule.Time = "02:30"
+	assertNext(t, rule, "2026-03-07T12:00:00Z", "2026-03-08T07:30:00Z")
+	assertNext(t, rule, "2026-03-08T07:30:00Z", "2026-03-15T06:30:00Z")
+
+	// 01:30 happens twice on November 1st; only the first, 01:30 EDT, is an occurrence
+	rule.Time = "01:30"
+	assertNext(t, rule, "2026-10-31T12:00:00Z", "2026-11-01T05:30:00Z")
+	assertNext(t, rule, "2026-11-01T05:30:00Z", "2026-11-08T06:30:00Z")
+}
+
+// TestRecurrenceRule_EndDate tests that the end date is the last local date an occurrence may fall on
+func TestRecurrenceRule_EndDate(t *testing.T) {
+	rule := weekendSaleRule()
+	rule.EndDate = StringPtr("2026-10-23")
+
+	assertNext(t, rule, "2026-10-16T06:00:00Z", "2026-10-23T06:00:00Z")
+	_, ok := rule.Next(utc(t, "2026-10-23T06:00:00Z"))
+	AssertEqual(t, ok, false)
+}
+
+// recurrenceFixture is a recurrence service over mock repositories, with the clock at now;
+// campaign 1 is the Weekend Sale draft and every other ID an occurrence's draft clone
+type recurrenceFixture struct {
+	recurrences    *service.RecurrenceService
+	campaigns      *service.CampaignService
+	recurrenceRepo *MockRecurrenceRepository
+	campaignRepo   *MockCampaignRepository
+	messageRepo    *MockMessageRepository
+	mock           sqlmock.Sqlmock
+	campaign       *models.Campaign
+	now            time.Time
+}
+
+func newRecurrenceFixture(t *testing.T) *recurrenceFixture {
+	t.Helper()
+
+	f := &recurrenceFixture{
+		recurrenceRepo: NewMockRecurrenceRepository(),
+		campaignRepo:   NewMockCampaignRepository(),
+		messageRepo:    NewMockMessageRepository(),
+		campaign:       NewTestCampaignWithStatus(models.CampaignStatusDraft),
+		now:            utc(t, "2026-10-14T12:00:00Z"),
+	}
+	f.campaign.Name = "Weekend Sale"
+	f.campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		if id == f.campaign.ID {
+			return f.campaign, nil
+		}
+		clone := NewTestCampaignWithStatus(models.CampaignStatusDraft)
+		clone.ID = id
+		return clone, nil
+	}
+
+	f.campaigns, f.mock = NewMockCampaignService(t, f.campaignRepo, f.messageRepo)
+	f.recurrences = service.NewRecurrenceService(f.recurrenceRepo, f.campaignRepo, f.campaigns)
+	f.recurrences.SetClock(func() time.Time { return f.now })
+	return f
+}
+
+// recur makes the Weekend Sale recurring with rule, to customers 1 to 3
+func (f *recurrenceFixture) recur(t *testing.T, rule models.RecurrenceRule) *models.CampaignRecurrence {
+	t.Helper()
+	recurrence, err := f.recurrences.Recur(context.Background(), f.campaign.ID, &service.RecurCampaignRequest{Rule: rule, CustomerIDs: []int{1, 2, 3}})
+	AssertNoError(t, err)
+	f.campaign.Status = models.CampaignStatusRecurring
+	return recurrence
+}
+
+// TestRecurrence_SendsDueOccurrence tests that a due occurrence is cloned into a campaign of
+// its own and sent, and the recurrence moves on a week
+func TestRecurrence_SendsDueOccurrence(t *testing.T) {
+	f := newRecurrenceFixture(t)
+	recurrence := f.recur(t, weekendSaleRule())
+	AssertEqual(t, recurrence.NextOccurrenceAt.Format(time.RFC3339), "2026-10-16T06:00:00Z")
+
+	// Nothing is due before Friday
+	sent, err := f.recurrences.RunDue(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, sent, 0)
+
+	f.now = utc(t, "2026-10-16T06:00:30Z")
+	f.mock.ExpectBegin()
+	f.mock.ExpectCommit()
+	sent, err = f.recurrences.RunDue(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, sent, 1)
+
+	AssertEqual(t, len(f.recurrenceRepo.Occurrences), 1)
+	occurrence := f.recurrenceRepo.Occurrences[0]
+	AssertEqual(t, occurrence.CampaignID, 100)
+	AssertEqual(t, occurrence.Name, "Weekend Sale (2026-10-16)")
+	AssertEqual(t, f.messageRepo.Calls["CreateBatch"], 1)
+	AssertEqual(t, f.campaignRepo.Calls["GetSendPlan"], 1)
+
+	stored := f.recurrenceRepo.Recurrences[f.campaign.ID]
+	AssertEqual(t, stored.Occurrences, 1)
+	AssertEqual(t, stored.NextOccurrenceAt.Format(time.RFC3339), "2026-10-23T06:00:00Z")
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+
+	// A second pass finds nothing due
+	sent, err = f.recurrences.RunDue(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, sent, 0)
+}
+
+// TestRecurrence_SkipsMissedOccurrences tests that occurrences missed while the scheduler was
+// down are sent once, not in a burst
+func TestRecurrence_SkipsMissedOccurrences(t *testing.T) {
+	f := newRecurrenceFixture(t)
+	f.recur(t, weekendSaleRule())
+
+	f.now = utc(t, "2026-11-04T12:00:00Z")
+	f.mock.ExpectBegin()
+	f.mock.ExpectCommit()
+	sent, err := f.recurrences.RunDue(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, sent, 1)
+	AssertEqual(t, f.recurrenceRepo.Recurrences[f.campaign.ID].NextOccurrenceAt.Format(time.RFC3339), "2026-11-06T06:00:00Z")
+}
+
+// TestRecurrence_MaxOccurrences tests that the recurrence ends with its last occurrence
+func TestRecurrence_MaxOccurrences(t *testing.T) {
+	f := newRecurrenceFixture(t)
+	rule := weekendSaleRule()
+	rule.MaxOccurrences = IntPtr(1)
+	f.recur(t, rule)
+
+	f.now = utc(t, "2026-10-16T06:01:00Z")
+	f.mock.ExpectBegin()
+	f.mock.ExpectCommit()
+	_, err := f.recurrences.RunDue(context.Background())
+	AssertNoError(t, err)
+
+	recurrence, err := f.recurrences.GetRecurrence(context.Background(), f.campaign.ID)
+	AssertNoError(t, err)
+	AssertEqual(t, recurrence.Occurrences, 1)
+	AssertEqual(t, recurrence.Ended(), true)
+}
+
+// TestRecurrence_PauseAndResume tests that a paused recurrence sends nothing, and resumes
+// from the next occurrence after it is resumed
+func TestRecurrence_PauseAndResume(t *testing.T) {
+	f := newRecurrenceFixture(t)
+	f.recur(t, weekendSaleRule())
+
+	_, err := f.recurrences.Pause(context.Background(), f.campaign.ID)
+	AssertNoError(t, err)
+	f.now = utc(t, "2026-10-24T12:00:00Z")
+	sent, err := f.recurrences.RunDue(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, sent, 0)
+
+	recurrence, err := f.recurrences.Resume(context.Background(), f.campaign.ID)
+	AssertNoError(t, err)
+	AssertEqual(t, recurrence.Paused, false)
+	AssertEqual(t, recurrence.NextOccurrenceAt.Format(time.RFC3339), "2026-10-30T06:00:00Z")
+
+	_, err = f.recurrences.Pause(context.Background(), 9)
+	AssertError(t, err, "recurrence with ID 9 not found")
+}
+
+// TestRecurrence_RecurRefused tests the requests that cannot make a campaign recurring
+func TestRecurrence_RecurRefused(t *testing.T) {
+	f := newRecurrenceFixture(t)
+	ctx := context.Background()
+
+	_, err := f.recurrences.Recur(ctx, 1, &service.RecurCampaignRequest{Rule: weekendSaleRule()})
+	AssertError(t, err, "validation error: customer_ids must list the audience of every occurrence")
+
+	rule := weekendSaleRule()
+	rule.EndDate = StringPtr("2026-10-01")
+	_, err = f.recurrences.Recur(ctx, 1, &service.RecurCampaignRequest{Rule: rule, CustomerIDs: []int{1}})
+	AssertError(t, err, "validation error: recurrence ends before its first occurrence")
+
+	f.recurrenceRepo.CreateFunc = func(ctx context.Context, campaignID int, rule *models.RecurrenceRule, plan *models.SendPlan, next time.Time) (*models.CampaignRecurrence, error) {
+		return nil, &models.InvalidTransitionError{From: models.CampaignStatusSent, To: models.CampaignStatusRecurring}
+	}
+	_, err = f.recurrences.Recur(ctx, 1, &service.RecurCampaignRequest{Rule: weekendSaleRule(), CustomerIDs: []int{1}})
+	AssertError(t, err, "invalid campaign status transition from sent to recurring")
+
+	// Recurring campaigns are not sent themselves
+	AssertEqual(t, NewTestCampaignWithStatus(models.CampaignStatusRecurring).CanSend(), false)
+}
+
+// TestRecurrenceEndpoints tests making a campaign recurring and listing its occurrences over HTTP
+func TestRecurrenceEndpoints(t *testing.T) {
+	f := newRecurrenceFixture(t)
+	h := handler.NewRecurrenceHandler(f.recurrences, f.campaigns)
+	router := NewTestRouter(map[string]http.HandlerFunc{
+		"POST /campaigns/{id:[0-9]+}/recurrence":       h.Recur,
+		"POST /campaigns/{id:[0-9]+}/recurrence/pause": h.Pause,
+		"GET /campaigns/{id:[0-9]+}/occurrences":       h.Occurrences,
+	})
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("POST", "/campaigns/1/recurrence", `{"rule": {"frequency": "weekly", "day_of_week": 5, "time": "09:00", "timezone": "Africa/Nairobi"}, "customer_ids": [1, 2]}`)
+	AssertStatusCode(t, rr, http.StatusCreated)
+	AssertContains(t, rr.Body.String(), `"next_occurrence_at":"2026-10-16T06:00:00Z"`)
+
+	rr = serve("POST", "/campaigns/1/recurrence", `{"rule": {"frequency": "daily", "time": "09:00", "timezone": "UTC"}, "customer_ids": [1]}`)
+	AssertStatusCode(t, rr, http.StatusBadRequest)
+
+	f.recurrenceRepo.Occurrences = append(f.recurrenceRepo.Occurrences, &models.CampaignOccurrence{
+		CampaignID: 100, ParentCampaignID: 1, OccurrenceAt: utc(t, "2026-10-16T06:00:00Z"), Name: "Weekend Sale (2026-10-16)", Status: models.CampaignStatusSent,
+	})
+	rr = serve("GET", "/campaigns/1/occurrences", "")
+	AssertStatusCode(t, rr, http.StatusOK)
+	var list service.OccurrenceList
+	ParseJSONResponse(t, rr, &list)
+	AssertEqual(t, list.Total, 1)
+	AssertEqual(t, list.Occurrences[0].CampaignID, 100)
+	AssertEqual(t, list.Occurrences[0].Status, models.CampaignStatusSent)
+
+	AssertStatusCode(t, serve("POST", "/campaigns/1/recurrence/pause", ""), http.StatusOK)
+	AssertStatusCode(t, serve("GET", "/campaigns/2/occurrences", ""), http.StatusNotFound)
+
+	_, err := f.recurrenceRepo.Get(context.Background(), 2)
+	AssertEqual(t, err, repository.ErrRecurrenceNotFound)
+}
@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/service"
+)
+
+// TestLoadPrefixLimits tests SEND_RATE_LIMITS_BY_PREFIX parsing and validation
+func TestLoadPrefixLimits(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, len(cfg.Sending.PrefixLimits), 0)
+
+	t.Setenv("SEND_RATE_LIMITS_BY_PREFIX", "+25470, +25471: 50/s; +25473: 20/s max 5;")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, len(cfg.Sending.PrefixLimits), 2)
+	first, second := cfg.Sending.PrefixLimits[0], cfg.Sending.PrefixLimits[1]
+	AssertEqual(t, len(first.Prefixes), 2)
+	AssertEqual(t, first.Prefixes[1], "+25471")
+	AssertEqual(t, first.PerSecond, 50)
+	AssertEqual(t, first.MaxInFlight, 0)
+	AssertEqual(t, second.Prefixes[0], "+25473")
+	AssertEqual(t, second.PerSecond, 20)
+	AssertEqual(t, second.MaxInFlight, 5)
+
+	invalid := map[string]string{
+		"+25470 50/s":               "SEND_RATE_LIMITS_BY_PREFIX is invalid: entry 1 must be prefix[,prefix...]: N/s [max M]",
+		"25470: 50/s":               `SEND_RATE_LIMITS_BY_PREFIX is invalid: entry 1 prefix "25470" must be + followed by 1 to 15 digits`,
+		"+2547x: 50/s":              `SEND_RATE_LIMITS_BY_PREFIX is invalid: entry 1 prefix "+2547x" must be + followed by 1 to 15 digits`,
+		"+25470: 50/s; +25470: 5/s": "SEND_RATE_LIMITS_BY_PREFIX is invalid: entry 2 repeats prefix +25470",
+		"+25470: 50":                `SEND_RATE_LIMITS_BY_PREFIX is invalid: entry 1 rate "50" must be a positive number per second, e.g. 20/s`,
+		"+25470: 0/s":               `SEND_RATE_LIMITS_BY_PREFIX is invalid: entry 1 rate "0/s" must be a positive number per second, e.g. 20/s`,
+		"+25470: 50/s max 0":        "SEND_RATE_LIMITS_BY_PREFIX is invalid: entry 1 must end in max M with M a positive number",
+		"+25470: 50/s cap 5":        "SEND_RATE_LIMITS_BY_PREFIX is invalid: entry 1 must end in max M with M a positive number",
+	}
+	for value, want := range invalid {
+		t.Setenv("SEND_RATE_LIMITS_BY_PREFIX", value)
+		_, err := config.Load()
+		AssertError(t, err, want)
+	}
+}
+
+// TestCarrierThrottle_Buckets tests longest-prefix matching, normalization and the fallback bucket
+func TestCarrierThrottle_Buckets(t *testing.T) {
+	throttle := service.NewCarrierThrottle(config.SendingConfig{
+		RateLimitPerSecond: 10,
+		PrefixLimits: []config.PrefixLimit{
+			{Prefixes: []string{"+254"}, PerSecond: 100},
+			{Prefixes: []string{"+25470", "+25471"}, PerSecond: 50},
+		},
+	})
+
+	AssertEqual(t, throttle.Bucket("+254701234567"), "+25470,+25471")
+	AssertEqual(t, throttle.Bucket("0711234567"), "+25470,+25471")
+	AssertEqual(t, throttle.Bucket("+254731234567"), "+254")
+	AssertEqual(t, throttle.Bucket("+14155550100"), service.CarrierThrottleFallback)
+
+	// Without a global limit unmatched phones are not paced
+	throttle = service.NewCarrierThrottle(config.SendingConfig{
+		PrefixLimits: []config.PrefixLimit{{Prefixes: []string{"+25470"}, PerSecond: 50}},
+	})
+	AssertEqual(t, throttle.Bucket("+14155550100"), "")
+
+	// Without prefix limits nothing is throttled
+	none := service.NewCarrierThrottle(config.SendingConfig{RateLimitPerSecond: 10})
+	if none != nil {
+		t.Fatal("Expected no throttle without prefix limits")
+	}
+	release, err := none.Acquire(context.Background(), "+254701234567")
+	AssertNoError(t, err)
+	release()
+}
+
+// TestCarrierThrottle_MixedPrefixPacing tests that a burst of mixed-prefix traffic is spaced
+// at each bucket's own rate, with busy buckets not slowing the others
+func TestCarrierThrottle_MixedPrefixPacing(t *testing.T) {
+	throttle := service.NewCarrierThrottle(config.SendingConfig{
+		RateLimitPerSecond: 10,
+		PrefixLimits: []config.PrefixLimit{
+			{Prefixes: []string{"+25470", "+25471"}, PerSecond: 50},
+			{Prefixes: []string{"+25473"}, PerSecond: 20},
+		},
+	})
+
+	// The whole burst arrives at once; each send's start is its arrival plus its wait
+	arrival := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	var wait time.Duration
+	throttle.SetClock(func() time.Time { return arrival }, func(ctx context.Context, d time.Duration) error {
+		wait = d
+		return nil
+	})
+
+	phones := []string{
+		"+254701000001", "+254731000001", "+254711000001", "+14155550101",
+		"+254701000002", "+254731000002", "+254711000002", "+14155550102",
+		"+254731000003",
+	}
+	starts := map[string][]time.Duration{}
+	for _, phone := range phones {
+		wait = 0
+		release, err := throttle.Acquire(context.Background(), phone)
+		AssertNoError(t, err)
+		release()
+		bucket := throttle.Bucket(phone)
+		starts[bucket] = append(starts[bucket], wait)
+	}
+
+	ms := time.Millisecond
+	AssertEqual(t, fmt.Sprint(starts["+25470,+25471"]), fmt.Sprint([]time.Duration{0, 20 * ms, 40 * ms, 60 * ms}))
+	AssertEqual(t, fmt.Sprint(starts["+25473"]), fmt.Sprint([]time.Duration{0, 50 * ms, 100 * ms}))
+	AssertEqual(t, fmt.Sprint(starts[service.CarrierThrottleFallback]), fmt.Sprint([]time.Duration{0, 100 * ms}))
+
+	// Once the schedule has passed, a send goes straight out
+	arrival = arrival.Add(time.Second)
+	wait = 0
+	release, err := throttle.Acquire(context.Background(), "+254731000004")
+	AssertNoError(t, err)
+	release()
+	AssertEqual(t, wait, time.Duration(0))
+}
+
+// TestCarrierThrottle_MaxInFlight tests that concurrent sends to a bucket never exceed its cap
+// while other buckets are not held up by it
+func TestCarrierThrottle_MaxInFlight(t *testing.T) {
+	throttle := service.NewCarrierThrottle(config.SendingConfig{
+		PrefixLimits: []config.PrefixLimit{
+			{Prefixes: []string{"+25473"}, PerSecond: 1000, MaxInFlight: 2},
+			{Prefixes: []string{"+25470"}, PerSecond: 1000},
+		},
+	})
+	throttle.SetClock(time.Now, func(ctx context.Context, d time.Duration) error { return nil })
+
+	var inFlight, peak, otherSent atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			release, err := throttle.Acquire(context.Background(), "+254731000001")
+			if err != nil {
+				t.Errorf("Acquire failed: %v", err)
+				return
+			}
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			inFlight.Add(-1)
+			release()
+		}()
+		go func() {
+			defer wg.Done()
+			release, err := throttle.Acquire(context.Background(), "+254701000001")
+			if err != nil {
+				t.Errorf("Acquire failed: %v", err)
+				return
+			}
+			otherSent.Add(1)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 sends in flight, got %d", peak.Load())
+	}
+	AssertEqual(t, otherSent.Load(), int32(10))
+
+	// A send waiting for a slot gives up when its context ends
+	hold1, _ := throttle.Acquire(context.Background(), "+254731000001")
+	hold2, _ := throttle.Acquire(context.Background(), "+254731000001")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := throttle.Acquire(ctx, "+254731000001")
+	AssertEqual(t, err, context.DeadlineExceeded)
+	hold1()
+	hold2()
+}
+
+// TestCarrierThrottle_FailedWaitUsesNoAttempt tests that a send whose throttle wait fails is
+// requeued without counting an attempt against the customer or keeping its reserved spend
+func TestCarrierThrottle_FailedWaitUsesNoAttempt(t *testing.T) {
+	sender := &countingSender{}
+	f := newBudgetFixture(t, 0.80, sender)
+	f.setAttemptLimit(sender, 5)
+	throttle := service.NewCarrierThrottle(config.SendingConfig{
+		PrefixLimits: []config.PrefixLimit{{Prefixes: []string{"+25470"}, PerSecond: 1}},
+	})
+	throttle.SetClock(time.Now, func(ctx context.Context, d time.Duration) error { return context.DeadlineExceeded })
+	f.processor.SetCarrierThrottle(throttle)
+
+	// The first send takes the bucket's only slot this second
+	release, err := throttle.Acquire(context.Background(), "+254700000001")
+	AssertNoError(t, err)
+	release()
+
+	AssertEqual(t, f.handle(1), context.DeadlineExceeded)
+	AssertEqual(t, sender.calls, 0)
+	AssertEqual(t, f.messageRepo.Calls["ReserveAttempt"], 0)
+	AssertEqual(t, f.spend, 0.0)
+}
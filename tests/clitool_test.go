@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"smsleopard/internal/clitool"
+)
+
+// newTestPrinter creates a printer that writes to in-memory buffers
+func newTestPrinter() (*clitool.Printer, *bytes.Buffer, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	return &clitool.Printer{Out: out, Err: errOut}, out, errOut
+}
+
+// TestPrinter_ColoredOutput tests that messages are wrapped in their colors
+func TestPrinter_ColoredOutput(t *testing.T) {
+	p, out, errOut := newTestPrinter()
+
+	p.Success("done")
+	p.Info("info")
+	p.Warning("careful")
+	p.Error("boom")
+
+	expectedOut := clitool.ColorGreen + "done" + clitool.ColorReset + "\n" +
+		clitool.ColorCyan + "info" + clitool.ColorReset + "\n" +
+		clitool.ColorYellow + "careful" + clitool.ColorReset + "\n"
+	AssertEqual(t, out.String(), expectedOut)
+	AssertEqual(t, errOut.String(), clitool.ColorRed+"boom"+clitool.ColorReset+"\n")
+}
+
+// TestPrinter_NoColor tests that NoColor strips ANSI codes
+func TestPrinter_NoColor(t *testing.T) {
+	p, out, errOut := newTestPrinter()
+	p.NoColor = true
+
+	p.Success("done")
+	p.Error("boom")
+
+	AssertEqual(t, out.String(), "done\n")
+	AssertEqual(t, errOut.String(), "boom\n")
+	AssertEqual(t, p.Colorize(clitool.ColorBold, "VERSION"), "VERSION")
+}
+
+// TestPrinter_Quiet tests that Quiet suppresses everything except errors
+func TestPrinter_Quiet(t *testing.T) {
+	p, out, errOut := newTestPrinter()
+	p.Quiet = true
+
+	p.Success("done")
+	p.Info("info")
+	p.Warning("careful")
+	p.Error("boom")
+
+	AssertEqual(t, out.String(), "")
+	AssertContains(t, errOut.String(), "boom")
+}
+
+// TestPrinter_RegisterFlags tests parsing of the shared --no-color and --quiet flags
+func TestPrinter_RegisterFlags(t *testing.T) {
+	p, _, _ := newTestPrinter()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	p.RegisterFlags(fs)
+
+	err := fs.Parse([]string{"--no-color", "--quiet", "status"})
+	AssertNoError(t, err)
+
+	AssertEqual(t, p.NoColor, true)
+	AssertEqual(t, p.Quiet, true)
+	AssertEqual(t, fs.Arg(0), "status")
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// expectDBNow expects a SELECT NOW() returning now
+func expectDBNow(mock sqlmock.Sqlmock, now time.Time) {
+	mock.ExpectQuery(`SELECT NOW\(\)`).WillReturnRows(sqlmock.NewRows([]string{"now"}).AddRow(now))
+}
+
+// setupClockTest creates a campaign service whose clock reads a mocked database time
+func setupClockTest(t *testing.T) (*service.CampaignService, *MockCampaignRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	svc, campaignRepo, _, _ := setupApprovalTest(t)
+	clockDB, clockMock := NewMockDB(t)
+	t.Cleanup(func() { clockDB.Close() })
+	svc.SetClock(repository.NewClockRepository(clockDB))
+	return svc, campaignRepo, clockMock
+}
+
+// TestCreateCampaign_ScheduledUsesDatabaseTime tests that a schedule in the app's future but the
+// database's past creates a draft
+func TestCreateCampaign_ScheduledUsesDatabaseTime(t *testing.T) {
+	svc, _, clockMock := setupClockTest(t)
+	expectDBNow(clockMock, time.Now().Add(2*time.Hour))
+
+	scheduledAt := time.Now().Add(time.Hour)
+	campaign, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+		Name:         "Skewed",
+		Channel:      models.ChannelSMS,
+		BaseTemplate: "Hi {first_name}",
+		ScheduledAt:  &scheduledAt,
+	})
+
+	AssertNoError(t, err)
+	AssertEqual(t, campaign.Status, models.CampaignStatusDraft)
+	AssertNoError(t, clockMock.ExpectationsWereMet())
+}
+
+// TestCreateCampaign_ScheduledBehindAppClock tests that a schedule in the app's past but the
+// database's future creates a scheduled campaign
+func TestCreateCampaign_ScheduledBehindAppClock(t *testing.T) {
+	svc, _, clockMock := setupClockTest(t)
+	expectDBNow(clockMock, time.Now().Add(-2*time.Hour))
+
+	scheduledAt := time.Now().Add(-time.Hour)
+	campaign, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+		Name:         "Skewed",
+		Channel:      models.ChannelSMS,
+		BaseTemplate: "Hi {first_name}",
+		ScheduledAt:  &scheduledAt,
+	})
+
+	AssertNoError(t, err)
+	AssertEqual(t, campaign.Status, models.CampaignStatusScheduled)
+}
+
+// TestCreateCampaign_UnscheduledSkipsDatabaseTime tests that no time is read without a schedule
+func TestCreateCampaign_UnscheduledSkipsDatabaseTime(t *testing.T) {
+	svc, _, clockMock := setupClockTest(t)
+
+	campaign, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+		Name:         "Now",
+		Channel:      models.ChannelSMS,
+		BaseTemplate: "Hi {first_name}",
+	})
+
+	AssertNoError(t, err)
+	AssertEqual(t, campaign.Status, models.CampaignStatusDraft)
+	AssertNoError(t, clockMock.ExpectationsWereMet())
+}
+
+// TestCreateCampaign_DatabaseTimeError tests that the campaign is not created when the time cannot be read
+func TestCreateCampaign_DatabaseTimeError(t *testing.T) {
+	svc, campaignRepo, clockMock := setupClockTest(t)
+	clockMock.ExpectQuery(`SELECT NOW\(\)`).WillReturnError(errors.New("connection reset"))
+	created := false
+	campaignRepo.CreateFunc = func(ctx context.Context, campaign *models.Campaign) error {
+		created = true
+		return nil
+	}
+
+	scheduledAt := time.Now().Add(time.Hour)
+	_, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+		Name:         "Skewed",
+		Channel:      models.ChannelSMS,
+		BaseTemplate: "Hi {first_name}",
+		ScheduledAt:  &scheduledAt,
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "failed to read database time") {
+		t.Errorf("Expected a database time error, got %v", err)
+	}
+	if created {
+		t.Error("Expected no campaign to be created")
+	}
+}
+
+// TestCheckClockSkew_WarnsAboveThreshold tests that a database clock an hour ahead is reported and logged
+func TestCheckClockSkew_WarnsAboveThreshold(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	buf := captureLog(t)
+	expectDBNow(mock, time.Now().Add(time.Hour))
+
+	skew, err := service.CheckClockSkew(context.Background(), repository.NewClockRepository(db), 5*time.Second)
+
+	AssertNoError(t, err)
+	if skew < 59*time.Minute || skew > 61*time.Minute {
+		t.Errorf("Expected skew of about 1h, got %s", skew)
+	}
+	if !strings.Contains(buf.String(), "clocks differ") {
+		t.Errorf("Expected a clock skew warning, got %q", buf.String())
+	}
+}
+
+// TestCheckClockSkew_WithinThreshold tests that a small difference, either way, is not logged
+func TestCheckClockSkew_WithinThreshold(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	buf := captureLog(t)
+	expectDBNow(mock, time.Now().Add(-time.Second))
+
+	skew, err := service.CheckClockSkew(context.Background(), repository.NewClockRepository(db), 5*time.Second)
+
+	AssertNoError(t, err)
+	if skew > 0 {
+		t.Errorf("Expected the database clock to be behind, got %s", skew)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no warning, got %q", buf.String())
+	}
+}
+
+// TestCheckClockSkew_DisabledThreshold tests that a zero threshold never warns
+func TestCheckClockSkew_DisabledThreshold(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	buf := captureLog(t)
+	expectDBNow(mock, time.Now().Add(-24*time.Hour))
+
+	_, err := service.CheckClockSkew(context.Background(), repository.NewClockRepository(db), 0)
+
+	AssertNoError(t, err)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no warning, got %q", buf.String())
+	}
+}
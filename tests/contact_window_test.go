@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// nairobi is a fixed UTC+3 zone, so tests do not depend on the system time zone database
+var nairobi = time.FixedZone("EAT", 3*60*60)
+
+// customerWithWindow creates a test customer messaged only between start and end
+func customerWithWindow(start, end string) *models.Customer {
+	customer := NewTestCustomer()
+	customer.ContactWindowStart, customer.ContactWindowEnd = StringPtr(start), StringPtr(end)
+	return customer
+}
+
+// TestCustomerContactWindow_Validation tests HH:MM parsing and that start and end go together
+func TestCustomerContactWindow_Validation(t *testing.T) {
+	window, err := NewTestCustomer().ContactWindow()
+	AssertNoError(t, err)
+	if window != nil {
+		t.Error("Expected no window for a customer without one")
+	}
+
+	window, err = customerWithWindow("20:00", "08:30").ContactWindow()
+	AssertNoError(t, err)
+	AssertEqual(t, window.Start, 20*60)
+	AssertEqual(t, window.End, 8*60+30)
+	AssertEqual(t, window.String(), "20:00-08:30")
+
+	tests := []struct {
+		customer *models.Customer
+		message  string
+	}{
+		{customerWithWindow("8:00", "17:00"), `contact_window_start "8:00" must be a time in HH:MM format`},
+		{customerWithWindow("08:00", "24:00"), `contact_window_end "24:00" must be a time in HH:MM format`},
+		{customerWithWindow("08:00", "17:60"), `contact_window_end "17:60" must be a time in HH:MM format`},
+		{customerWithWindow("0a:00", "17:00"), `contact_window_start "0a:00" must be a time in HH:MM format`},
+		{customerWithWindow("09:00", "09:00"), "contact_window_start and contact_window_end must differ"},
+		{&models.Customer{ContactWindowStart: StringPtr("09:00")}, "contact_window_start and contact_window_end must be set together"},
+	}
+
+	for _, tt := range tests {
+		_, err := tt.customer.ContactWindow()
+		AssertError(t, err, tt.message)
+	}
+}
+
+// TestContactWindow_NextOpen tests the deferral time for same-day and midnight-crossing windows
+func TestContactWindow_NextOpen(t *testing.T) {
+	evenings := &models.ContactWindow{Start: 20 * 60, End: 8 * 60}
+	office := &models.ContactWindow{Start: 8 * 60, End: 17 * 60}
+
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, nairobi)
+	}
+
+	tests := []struct {
+		name   string
+		window *models.ContactWindow
+		now    time.Time
+		want   time.Time
+	}{
+		{"before an evening window", evenings, at(10, 19, 0), at(10, 20, 0)},
+		{"inside before midnight", evenings, at(10, 23, 30), at(10, 23, 30)},
+		{"inside after midnight", evenings, at(11, 2, 0), at(11, 2, 0)},
+		{"end is exclusive", evenings, at(11, 8, 0), at(11, 20, 0)},
+		{"just before the end", evenings, at(11, 7, 59), at(11, 7, 59)},
+		{"start is inclusive", office, at(10, 8, 0), at(10, 8, 0)},
+		{"after a day window", office, at(10, 18, 0), at(11, 8, 0)},
+		{"day window crossing month end", office, time.Date(2026, 3, 31, 23, 0, 0, 0, nairobi), time.Date(2026, 4, 1, 8, 0, 0, 0, nairobi)},
+		{"before a day window", office, at(10, 6, 15), at(10, 8, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.window.NextOpen(tt.now, nairobi)
+			if !got.Equal(tt.want) {
+				t.Errorf("Expected %v but got %v", tt.want, got)
+			}
+			AssertEqual(t, tt.window.Contains(tt.now, nairobi), got.Equal(tt.now))
+		})
+	}
+
+	// The window is read in the given zone: 18:00 UTC is 21:00 in Nairobi
+	utcEvening := time.Date(2026, 3, 10, 18, 0, 0, 0, time.UTC)
+	AssertEqual(t, evenings.Contains(utcEvening, nairobi), true)
+	AssertEqual(t, evenings.Contains(utcEvening, time.UTC), false)
+}
+
+// contactWindowFixture is a message processor whose stored customer has a contact window
+type contactWindowFixture struct {
+	processor *service.MessageProcessor
+	mock      sqlmock.Sqlmock
+	sender    *countingSender
+	deferred  []time.Time
+	reasons   []string
+}
+
+// newContactWindowFixture creates a processor at now in Nairobi time for customer
+func newContactWindowFixture(t *testing.T, customer *models.Customer, now time.Time) *contactWindowFixture {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	f := &contactWindowFixture{mock: mock, sender: &countingSender{}}
+	messageRepo := NewMockMessageRepository()
+	messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *NewTestMessageWithStatus(models.MessageStatusPending),
+			Campaign:        *NewTestCampaignWithStatus(models.CampaignStatusSending),
+			Customer:        *customer,
+		}, nil
+	}
+	messageRepo.DeferUntilFunc = func(ctx context.Context, id int, until time.Time, reason string) error {
+		f.deferred = append(f.deferred, until)
+		f.reasons = append(f.reasons, reason)
+		return nil
+	}
+
+	f.processor = service.NewMessageProcessor(db, messageRepo, service.NewTemplateService(), f.sender, service.NewAttemptBudget(messageRepo, 0), nil)
+	f.processor.SetContactWindowZone(nairobi)
+	f.processor.SetClock(func() time.Time { return now })
+	return f
+}
+
+// TestMessageProcessor_ContactWindow tests that messages outside the window are deferred to its opening
+func TestMessageProcessor_ContactWindow(t *testing.T) {
+	job := &queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1}
+
+	// 10:00 in Nairobi is outside an evening window; the job is acknowledged and held until 20:00
+	f := newContactWindowFixture(t, customerWithWindow("20:00", "08:00"), time.Date(2026, 3, 10, 10, 0, 0, 0, nairobi))
+	AssertNoError(t, f.processor.Handle(job))
+	AssertEqual(t, f.sender.calls, 0)
+	AssertEqual(t, len(f.deferred), 1)
+	AssertEqual(t, f.deferred[0].Equal(time.Date(2026, 3, 10, 17, 0, 0, 0, time.UTC)), true)
+	AssertContains(t, f.reasons[0], "outside customer contact window (20:00-08:00 EAT)")
+
+	// 01:00 is inside the same window after midnight, so the message is sent
+	f = newContactWindowFixture(t, customerWithWindow("20:00", "08:00"), time.Date(2026, 3, 11, 1, 0, 0, 0, nairobi))
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WithArgs(1, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	AssertNoError(t, f.processor.Handle(job))
+	AssertEqual(t, f.sender.calls, 1)
+	AssertEqual(t, len(f.deferred), 0)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+
+	// Customers without a window are sent to at any time
+	f = newContactWindowFixture(t, NewTestCustomer(), time.Date(2026, 3, 11, 3, 0, 0, 0, nairobi))
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WithArgs(1, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	AssertNoError(t, f.processor.Handle(job))
+	AssertEqual(t, f.sender.calls, 1)
+	AssertEqual(t, len(f.deferred), 0)
+}
+
+// TestContactWindow_SetThroughCustomerAPIs tests that windows are validated on create, inline sends and CSV import
+func TestContactWindow_SetThroughCustomerAPIs(t *testing.T) {
+	customerSvc := service.NewCustomerService(NewMockCustomerRepository(), config.LimitsConfig{MaxCustomerFieldLength: 255})
+	_, err := customerSvc.CreateCustomer(context.Background(), customerWithWindow("20:00", "8pm"))
+	AssertError(t, err, `validation error: contact_window_end "8pm" must be a time in HH:MM format`)
+
+	svc, customerRepo, mock := setupInlineCustomersTest(t)
+	_, err = svc.SendCampaign(context.Background(), 1, nil, service.SendOptions{
+		Customers: []service.InlineCustomer{{Phone: "+254712345678", ContactWindowStart: StringPtr("20:00")}},
+	})
+	AssertError(t, err, "validation error: customers[0]: contact_window_start and contact_window_end must be set together")
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	var upserted *models.Customer
+	customerRepo.UpsertByPhoneFunc = func(ctx context.Context, customer *models.Customer) (bool, error) {
+		upserted = customer
+		customer.ID = 9
+		return true, nil
+	}
+	_, err = svc.SendCampaign(context.Background(), 1, nil, service.SendOptions{
+		Customers: []service.InlineCustomer{{Phone: "+254712345678", ContactWindowStart: StringPtr("20:00"), ContactWindowEnd: StringPtr("08:00")}},
+	})
+	AssertNoError(t, err)
+	AssertEqual(t, *upserted.ContactWindowStart, "20:00")
+	AssertEqual(t, *upserted.ContactWindowEnd, "08:00")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSendCampaignCSV_ContactWindowColumns tests that customers created from a CSV keep their window
+// and rows with an invalid window are reported
+func TestSendCampaignCSV_ContactWindowColumns(t *testing.T) {
+	svc, customerRepo, mock := setupSendCSVTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var created []*models.Customer
+	customerRepo.CreateFunc = func(ctx context.Context, customer *models.Customer) error {
+		created = append(created, customer)
+		customer.ID = 100 + len(created)
+		return nil
+	}
+
+	csv := "phone,contact_window_start,contact_window_end\n" +
+		"+254711111111,20:00,08:00\n" +
+		"+254722222222,,\n" +
+		"+254733333333,25:00,08:00\n"
+	result, err := svc.SendCampaignCSV(context.Background(), 1, strings.NewReader(csv), &service.SendCampaignCSVRequest{CreateUnknown: true})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Created, 2)
+	AssertEqual(t, result.Invalid, 1)
+	AssertEqual(t, result.Errors[0].Line, 4)
+	AssertContains(t, result.Errors[0].Problem, `contact_window_start "25:00"`)
+	AssertEqual(t, *created[0].ContactWindowStart, "20:00")
+	AssertEqual(t, *created[0].ContactWindowEnd, "08:00")
+	if created[1].ContactWindowStart != nil {
+		t.Error("Expected no window for a row with empty window columns")
+	}
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// missingContentMessage is a sent message without content for the given campaign template and customer
+func missingContentMessage(id int, template string, customer *models.Customer) *models.OutboundMessageWithDetails {
+	message := NewTestMessageWithStatus(models.MessageStatusSent)
+	message.ID = id
+	message.CustomerID = customer.ID
+	campaign := NewTestCampaign()
+	campaign.BaseTemplate = template
+	return &models.OutboundMessageWithDetails{OutboundMessage: *message, Campaign: *campaign, Customer: *customer}
+}
+
+// TestContentBackfill_CurrentData tests that content is rendered from the customer's data as it is now
+func TestContentBackfill_CurrentData(t *testing.T) {
+	messageRepo := NewMockMessageRepository()
+
+	// The customer was "Amina" when the message went out and has since been renamed
+	renamed := NewTestCustomerWithID(4)
+	renamed.FirstName = StringPtr("Wanjiru")
+	renamed.PreferredProduct = StringPtr("Home Fibre")
+
+	var afterID, limit int
+	messageRepo.ListMissingContentFunc = func(ctx context.Context, filters repository.ContentBackfillFilters, after, l int) ([]*models.OutboundMessageWithDetails, error) {
+		afterID, limit = after, l
+		return []*models.OutboundMessageWithDetails{
+			missingContentMessage(11, "Hi {first_name}, try {preferred_product}", renamed),
+			missingContentMessage(12, "Hi {first_name}", NewTestCustomerWithID(5)),
+		}, nil
+	}
+	var stored map[int]string
+	messageRepo.StoreBackfilledContentFunc = func(ctx context.Context, contents map[int]string) (int, error) {
+		stored = contents
+		return len(contents), nil
+	}
+
+	backfill := service.NewContentBackfill(messageRepo, service.NewTemplateService(), 100)
+	batch, err := backfill.RunBatch(context.Background(), repository.ContentBackfillFilters{}, 10)
+	AssertNoError(t, err)
+
+	AssertEqual(t, afterID, 10)
+	AssertEqual(t, limit, 100)
+	AssertEqual(t, batch.Scanned, 2)
+	AssertEqual(t, batch.Updated, 2)
+	AssertEqual(t, batch.LastID, 12)
+	AssertEqual(t, stored[11], "Hi Wanjiru, try Home Fibre")
+	AssertEqual(t, stored[12], "Hi "+*NewTestCustomerWithID(5).FirstName)
+}
+
+// TestContentBackfill_RenderFailure tests that a message whose template cannot render is reported and skipped
+func TestContentBackfill_RenderFailure(t *testing.T) {
+	messageRepo := NewMockMessageRepository()
+	messageRepo.ListMissingContentFunc = func(ctx context.Context, filters repository.ContentBackfillFilters, after, limit int) ([]*models.OutboundMessageWithDetails, error) {
+		return []*models.OutboundMessageWithDetails{
+			missingContentMessage(21, "", NewTestCustomerWithID(1)),
+			missingContentMessage(22, "Hello {first_name}", NewTestCustomerWithID(2)),
+		}, nil
+	}
+	var stored map[int]string
+	messageRepo.StoreBackfilledContentFunc = func(ctx context.Context, contents map[int]string) (int, error) {
+		stored = contents
+		return len(contents), nil
+	}
+
+	backfill := service.NewContentBackfill(messageRepo, service.NewTemplateService(), 100)
+	batch, err := backfill.RunBatch(context.Background(), repository.ContentBackfillFilters{}, 0)
+	AssertNoError(t, err)
+
+	AssertEqual(t, batch.Updated, 1)
+	AssertEqual(t, len(batch.Failed), 1)
+	AssertEqual(t, batch.Failed[0], "message 21: template cannot be empty")
+	AssertEqual(t, batch.LastID, 22)
+	AssertEqual(t, len(stored), 1)
+	AssertEqual(t, stored[22], "Hello "+*NewTestCustomerWithID(2).FirstName)
+}
+
+// TestContentBackfill_Resume tests that an empty batch keeps the resume ID and a failed write does not advance it
+func TestContentBackfill_Resume(t *testing.T) {
+	messageRepo := NewMockMessageRepository()
+	backfill := service.NewContentBackfill(messageRepo, service.NewTemplateService(), 100)
+
+	batch, err := backfill.RunBatch(context.Background(), repository.ContentBackfillFilters{}, 500)
+	AssertNoError(t, err)
+	AssertEqual(t, batch.Scanned, 0)
+	AssertEqual(t, batch.LastID, 500)
+	AssertEqual(t, messageRepo.Calls["StoreBackfilledContent"], 1)
+
+	messageRepo.ListMissingContentFunc = func(ctx context.Context, filters repository.ContentBackfillFilters, after, limit int) ([]*models.OutboundMessageWithDetails, error) {
+		return []*models.OutboundMessageWithDetails{missingContentMessage(501, "Hello {first_name}", NewTestCustomer())}, nil
+	}
+	messageRepo.StoreBackfilledContentFunc = func(ctx context.Context, contents map[int]string) (int, error) {
+		return 0, errors.New("failed to store backfilled content: connection reset")
+	}
+	batch, err = backfill.RunBatch(context.Background(), repository.ContentBackfillFilters{}, 500)
+	AssertError(t, err, "failed to store backfilled content: connection reset")
+	if batch != nil {
+		t.Error("Expected no batch when the write fails")
+	}
+}
+
+// TestListMissingContent_Query tests the keyset query and its campaign and date filters
+func TestListMissingContent_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaignID := 3
+	before := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM outbound_messages m JOIN campaigns c ON m.campaign_id = c.id JOIN customers cu ON m.customer_id = cu.id WHERE m.id > \$1 AND m.status = 'sent' AND m.rendered_content IS NULL AND \(\$2::int IS NULL OR m.campaign_id = \$2::int\) AND \(\$3::timestamp IS NULL OR m.created_at < \$3::timestamp\) ORDER BY m.id ASC LIMIT \$4`).
+		WithArgs(40, &campaignID, &before, 2).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "campaign_id", "customer_id", "status", "created_at",
+			"c_id", "channel", "base_template", "template_syntax",
+			"cu_id", "phone", "first_name", "last_name", "location", "preferred_product",
+		}).AddRow(41, 3, 8, "sent", time.Now(), 3, "sms", "Hi {first_name}", nil, 8, "+254712345678", "Wanjiru", nil, "Nairobi", nil))
+
+	messages, err := repository.NewMessageRepository(db).ListMissingContent(context.Background(),
+		repository.ContentBackfillFilters{CampaignID: &campaignID, Before: &before}, 40, 2)
+	AssertNoError(t, err)
+	AssertEqual(t, len(messages), 1)
+	AssertEqual(t, messages[0].ID, 41)
+	AssertEqual(t, messages[0].Campaign.BaseTemplate, "Hi {first_name}")
+	AssertEqual(t, *messages[0].Customer.FirstName, "Wanjiru")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestStoreBackfilledContent_Query tests that content is written only where none exists and flagged backfilled
+func TestStoreBackfilledContent_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE outbound_messages m SET rendered_content = backfill.content, backfilled = TRUE FROM unnest\(\$1::int\[\], \$2::text\[\]\) AS backfill\(id, content\) WHERE m.id = backfill.id AND m.rendered_content IS NULL`).
+		WithArgs("{7,9}", `{"Hi Amina","Hi Wanjiru"}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	messageRepo := repository.NewMessageRepository(db)
+	updated, err := messageRepo.StoreBackfilledContent(context.Background(), map[int]string{9: "Hi Wanjiru", 7: "Hi Amina"})
+	AssertNoError(t, err)
+	AssertEqual(t, updated, 1)
+
+	// Nothing rendered needs no query
+	updated, err = messageRepo.StoreBackfilledContent(context.Background(), nil)
+	AssertNoError(t, err)
+	AssertEqual(t, updated, 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
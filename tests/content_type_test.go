@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/httpjson"
+	"smsleopard/internal/maintenance"
+	"smsleopard/internal/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// newContentTypeRouter builds a router with the production middleware order and routes that
+// succeed, fail, panic or take a CSV upload
+func newContentTypeRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.Use(middleware.Recovery)
+	router.Use(middleware.RequireJSONBody("/campaigns/{id:[0-9]+}/send-csv"))
+
+	api := router.PathPrefix("/").Subrouter()
+	api.HandleFunc("/campaigns", func(w http.ResponseWriter, r *http.Request) {
+		handler.WriteCreated(w, map[string]int{"id": 1})
+	}).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		handler.WriteError(w, http.StatusNotFound, "NOT_FOUND", "campaign not found")
+	}).Methods("GET")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/send-csv", func(w http.ResponseWriter, r *http.Request) {
+		handler.WriteOK(w, map[string]bool{"accepted": true})
+	}).Methods("POST")
+	api.HandleFunc("/campaigns/{id:[0-9]+}/resume", func(w http.ResponseWriter, r *http.Request) {
+		handler.WriteOK(w, map[string]bool{"resumed": true})
+	}).Methods("POST")
+	api.HandleFunc("/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}).Methods("GET")
+	return router
+}
+
+// serveContentType sends a request with the given body and Content-Type through the router
+func serveContentType(router http.Handler, method, path, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+// TestRequireJSONBody_RejectsOtherMediaTypes tests that write bodies in other formats get a 415
+func TestRequireJSONBody_RejectsOtherMediaTypes(t *testing.T) {
+	router := newContentTypeRouter()
+
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded", "text/csv", "application/json-patch+json", "not a media type"} {
+		t.Run(contentType, func(t *testing.T) {
+			resp := serveContentType(router, "POST", "/campaigns", contentType, `{"name": "Promo"}`)
+			AssertStatusCode(t, resp, http.StatusUnsupportedMediaType)
+			AssertJSONContentType(t, resp)
+
+			var errResp handler.ErrorResponse
+			ParseJSONResponse(t, resp, &errResp)
+			AssertEqual(t, errResp.Error.Code, "UNSUPPORTED_MEDIA_TYPE")
+		})
+	}
+}
+
+// TestRequireJSONBody_Allowed tests JSON bodies, bodyless writes, reads and exempt uploads
+func TestRequireJSONBody_Allowed(t *testing.T) {
+	router := newContentTypeRouter()
+
+	AssertStatusCode(t, serveContentType(router, "POST", "/campaigns", "application/json", `{}`), http.StatusCreated)
+	AssertStatusCode(t, serveContentType(router, "POST", "/campaigns", "Application/JSON; charset=UTF-8", `{}`), http.StatusCreated)
+	AssertStatusCode(t, serveContentType(router, "POST", "/campaigns/1/resume", "", ""), http.StatusOK)
+	AssertStatusCode(t, serveContentType(router, "GET", "/campaigns/1", "text/plain", "ignored"), http.StatusNotFound)
+	AssertStatusCode(t, serveContentType(router, "POST", "/campaigns/7/send-csv", "text/csv", "customer_id\n1\n"), http.StatusOK)
+	AssertStatusCode(t, serveContentType(router, "POST", "/campaigns/7/send-csv", "multipart/form-data; boundary=x", "--x--"), http.StatusOK)
+}
+
+// TestContentType_ConsistentAcrossPaths tests that success, error, panic and middleware responses
+// all carry the same JSON Content-Type
+func TestContentType_ConsistentAcrossPaths(t *testing.T) {
+	router := newContentTypeRouter()
+
+	tests := []struct {
+		name   string
+		resp   *httptest.ResponseRecorder
+		status int
+	}{
+		{"success", serveContentType(router, "POST", "/campaigns", "application/json", `{}`), http.StatusCreated},
+		{"error", serveContentType(router, "GET", "/campaigns/9", "", ""), http.StatusNotFound},
+		{"panic", serveContentType(router, "GET", "/boom", "", ""), http.StatusInternalServerError},
+		{"unsupported media type", serveContentType(router, "POST", "/campaigns", "text/plain", "hi"), http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AssertStatusCode(t, tt.resp, tt.status)
+			AssertJSONContentType(t, tt.resp)
+		})
+	}
+
+	var errResp handler.ErrorResponse
+	ParseJSONResponse(t, tests[2].resp, &errResp)
+	AssertEqual(t, errResp.Error.Code, "INTERNAL_ERROR")
+	AssertEqual(t, errResp.Error.Message, "Internal server error")
+}
+
+// TestContentType_MiddlewareErrors tests that errors written by other middleware use the JSON Content-Type
+func TestContentType_MiddlewareErrors(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for name, guarded := range map[string]http.Handler{
+		"admin key": middleware.RequireAdminKey("secret")(next),
+		"read-only": middleware.RejectWritesWhenReadOnly(maintenance.NewReadOnly(true))(next),
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			guarded.ServeHTTP(resp, httptest.NewRequest("POST", "/campaigns", nil))
+			AssertEqual(t, resp.Header().Get("Content-Type"), httpjson.ContentType)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/csvimport"
+)
+
+// customerColumns are the columns a customer import must have
+var customerColumns = []string{"phone", "first_name", "last_name", "location", "preferred_product"}
+
+// parseFixture parses a file from testdata/csvimport
+func parseFixture(t *testing.T, name string, opts csvimport.Options) (*csvimport.Result, error) {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join("testdata", "csvimport", name))
+	AssertNoError(t, err)
+	t.Cleanup(func() { file.Close() })
+
+	return csvimport.Parse(file, opts)
+}
+
+// TestCSVImport_ValidVariants tests that every supported file variant yields the same clean records
+func TestCSVImport_ValidVariants(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		opts       csvimport.Options
+		delimiter  rune
+		compressed bool
+		records    int
+	}{
+		{name: "plain", file: "basic.csv", delimiter: ',', records: 2},
+		{name: "excel BOM and CRLF", file: "excel_bom_crlf.csv", delimiter: ',', records: 2},
+		{name: "semicolon", file: "semicolon.csv", delimiter: ';', records: 2},
+		{name: "quoted newlines", file: "multiline.csv", delimiter: ',', records: 2},
+		{name: "gzip by magic bytes", file: "gzipped.csv.gz", delimiter: ',', compressed: true, records: 2},
+		{
+			name:       "gzip by content encoding",
+			file:       "gzipped.csv.gz",
+			opts:       csvimport.Options{ContentEncoding: "gzip"},
+			delimiter:  ',',
+			compressed: true,
+			records:    2,
+		},
+		{name: "gzip with BOM and semicolons", file: "gzipped_bom_semicolon.csv.gz", delimiter: ';', compressed: true, records: 1},
+		{name: "header only", file: "header_only.csv", delimiter: ',', records: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.RequiredColumns = customerColumns
+			result, err := parseFixture(t, tt.file, tt.opts)
+			AssertNoError(t, err)
+
+			AssertEqual(t, result.Delimiter, tt.delimiter)
+			AssertEqual(t, result.Compressed, tt.compressed)
+			AssertEqual(t, len(result.Records), tt.records)
+			AssertEqual(t, len(result.Errors), 0)
+			AssertEqual(t, strings.Join(result.Header, ","), strings.Join(customerColumns, ","))
+
+			if tt.records > 0 {
+				first := result.Records[0]
+				AssertEqual(t, first.Fields["phone"], "+254700000001")
+				AssertEqual(t, first.Fields["first_name"], "Alice")
+				AssertEqual(t, first.Line, 2)
+			}
+		})
+	}
+}
+
+// TestCSVImport_FieldContents tests quoted delimiters, embedded newlines and escaped quotes
+func TestCSVImport_FieldContents(t *testing.T) {
+	result, err := parseFixture(t, "semicolon.csv", csvimport.Options{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.Records[0].Fields["location"], "Nairobi, CBD")
+
+	result, err = parseFixture(t, "multiline.csv", csvimport.Options{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.Records[0].Fields["location"], "Nairobi\nWestlands")
+	AssertEqual(t, result.Records[1].Fields["preferred_product"], `Phones, "smart" ones`)
+
+	// The second record starts after the two physical lines of the first
+	AssertEqual(t, result.Records[1].Line, 4)
+
+	// CRLF does not leak into the last column
+	result, err = parseFixture(t, "excel_bom_crlf.csv", csvimport.Options{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.Records[0].Fields["preferred_product"], "Shoes")
+}
+
+// TestCSVImport_RowErrors tests that bad rows are reported by line and skipped
+func TestCSVImport_RowErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		records int
+		line    int
+		problem string
+	}{
+		{name: "field count", file: "field_count.csv", records: 2, line: 3, problem: "expected 5 fields but found 4"},
+		{name: "bare quote", file: "bare_quote.csv", records: 1, line: 2, problem: "stray quote in unquoted field"},
+		{name: "unterminated quote", file: "unterminated_quote.csv", records: 1, line: 3, problem: "quoted field is never closed"},
+		{name: "latin-1", file: "latin1.csv", records: 1, line: 2, problem: "invalid UTF-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseFixture(t, tt.file, csvimport.Options{RequiredColumns: customerColumns})
+			AssertNoError(t, err)
+
+			AssertEqual(t, len(result.Records), tt.records)
+			AssertEqual(t, len(result.Errors), 1)
+			AssertEqual(t, result.Errors[0].Line, tt.line)
+			AssertContains(t, result.Errors[0].Problem, tt.problem)
+			AssertContains(t, result.Errors[0].Error(), "line ")
+		})
+	}
+}
+
+// TestCSVImport_FileErrors tests problems that reject the whole file
+func TestCSVImport_FileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		opts    csvimport.Options
+		message string
+	}{
+		{name: "empty", file: "empty.csv", message: "file is empty"},
+		{name: "utf-16", file: "utf16.csv", message: "UTF-16"},
+		{name: "duplicate header", file: "duplicate_header.csv", message: `"phone" appears more than once`},
+		{
+			name:    "missing columns",
+			file:    "missing_column.csv",
+			opts:    csvimport.Options{RequiredColumns: customerColumns},
+			message: "missing required columns: last_name, location, preferred_product",
+		},
+		{
+			name:    "gzip header on plain file",
+			file:    "basic.csv",
+			opts:    csvimport.Options{ContentEncoding: "gzip"},
+			message: "could not be decompressed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseFixture(t, tt.file, tt.opts)
+			if err == nil {
+				t.Fatalf("Expected error but got result %+v", result)
+			}
+			AssertContains(t, err.Error(), tt.message)
+		})
+	}
+}
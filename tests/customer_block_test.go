@@ -0,0 +1,235 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/reqctx"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// setupBlockTest creates a campaign service whose customers 2 and 3 are blocked and whose
+// suppression list has customer 4's phone
+func setupBlockTest(t *testing.T) (*service.CampaignService, *MockCustomerRepository, *MockMessageRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	customerRepo := NewMockCustomerRepository()
+	customerRepo.ListBlockedFunc = func(ctx context.Context, ids []int) ([]int, error) {
+		blocked := []int{}
+		for _, id := range ids {
+			if id == 2 || id == 3 {
+				blocked = append(blocked, id)
+			}
+		}
+		return blocked, nil
+	}
+	messageRepo := NewMockMessageRepository()
+
+	svc := service.NewCampaignService(NewMockCampaignRepository(), customerRepo, messageRepo, service.NewTemplateService(), NewMockPublisher(), db, config.ApprovalConfig{})
+	suppressions := NewMockSuppressionRepository()
+	suppressions.Phones[1] = []string{"+254700000004"}
+	svc.SetSuppressions(suppressions)
+	return svc, customerRepo, messageRepo, mock
+}
+
+// TestBlock_PlannerSkipsBlocked tests that blocked customers are left out of a send and
+// counted apart from suppressed ones
+func TestBlock_PlannerSkipsBlocked(t *testing.T) {
+	svc, _, messageRepo, mock := setupBlockTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var queued []*models.OutboundMessage
+	messageRepo.CreateBatchFunc = func(ctx context.Context, messages []*models.OutboundMessage) error {
+		queued = messages
+		return nil
+	}
+
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2, 3, 4}, service.SendOptions{})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.MessagesQueued, 1)
+	AssertEqual(t, result.SkippedBlocked, 2)
+	AssertEqual(t, result.SkippedSuppressed, 1)
+	AssertEqual(t, queued[0].CustomerID, 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestBlock_AllBlocked tests that a send whose whole audience is blocked is refused
+func TestBlock_AllBlocked(t *testing.T) {
+	svc, _, messageRepo, _ := setupBlockTest(t)
+
+	_, err := svc.SendCampaign(context.Background(), 1, []int{2, 3}, service.SendOptions{})
+	AssertError(t, err, "business logic error: all 2 customers are blocked")
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 0)
+}
+
+// TestBlock_InlineCustomersRefused tests that inline customers cannot update or target a
+// blocked customer, and nothing is written when one does
+func TestBlock_InlineCustomersRefused(t *testing.T) {
+	svc, customerRepo, _, _ := setupBlockTest(t)
+	customerRepo.ListBlockedPhonesFunc = func(ctx context.Context, phones []string) ([]string, error) {
+		AssertEqual(t, strings.Join(phones, ","), "+254711000001,+254700000002")
+		return []string{"+254700000002"}, nil
+	}
+
+	_, err := svc.SendCampaign(context.Background(), 1, nil, service.SendOptions{
+		Customers: []service.InlineCustomer{{Phone: "0711 000 001"}, {Phone: "0700000002"}},
+	})
+	AssertError(t, err, "validation error: inline customers include blocked customers: +254700000002")
+	AssertEqual(t, customerRepo.Calls["UpsertByPhone"], 0)
+}
+
+// TestBlock_WorkerRefuses tests that the worker refuses a message to a customer blocked after
+// it was queued, with its own reason rather than a suppression's
+func TestBlock_WorkerRefuses(t *testing.T) {
+	sender := &countingSender{}
+	f := newProcessingErrorFixture(t, sender)
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		message := NewTestMessageWithStatus(models.MessageStatusPending)
+		message.ID = id
+		customer := NewTestCustomer()
+		customer.Blocked = true
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *message,
+			Campaign:        *NewTestCampaignWithStatus(models.CampaignStatusSending),
+			Customer:        *customer,
+		}, nil
+	}
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'failed'").
+		WithArgs(9, "Customer blocked").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 9})
+	AssertNoError(t, err)
+	AssertEqual(t, sender.calls, 0)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestBlockEndpoints tests blocking, viewing and unblocking a customer over HTTP
+func TestBlockEndpoints(t *testing.T) {
+	customerRepo := NewMockCustomerRepository()
+	customer := NewTestCustomerWithID(5)
+	events := []*models.CustomerBlockEvent{}
+	customerRepo.GetDetailFunc = func(ctx context.Context, id int) (*models.Customer, error) {
+		if id != 5 {
+			return nil, repository.ErrCustomerNotFound
+		}
+		found := *customer
+		return &found, nil
+	}
+	customerRepo.BlockFunc = func(ctx context.Context, id int, reason string, actor *string) error {
+		if id != 5 {
+			return repository.ErrCustomerNotFound
+		}
+		customer.Blocked, customer.BlockedReason, customer.BlockedBy = true, &reason, actor
+		events = append([]*models.CustomerBlockEvent{{CustomerID: id, Action: models.CustomerBlocked, Reason: &reason, Actor: actor}}, events...)
+		return nil
+	}
+	customerRepo.UnblockFunc = func(ctx context.Context, id int, actor *string) error {
+		customer.Blocked, customer.BlockedReason, customer.BlockedBy = false, nil, nil
+		events = append([]*models.CustomerBlockEvent{{CustomerID: id, Action: models.CustomerUnblocked, Actor: actor}}, events...)
+		return nil
+	}
+	customerRepo.ListBlockEventsFunc = func(ctx context.Context, id int) ([]*models.CustomerBlockEvent, error) {
+		return events, nil
+	}
+
+	h := handler.NewCustomerHandler(service.NewCustomerService(customerRepo, config.LimitsConfig{}))
+	router := mux.NewRouter()
+	router.HandleFunc("/customers/{id}", h.Get).Methods("GET")
+	router.HandleFunc("/customers/{id}/block", h.Block).Methods("POST")
+	router.HandleFunc("/customers/{id}/block", h.Unblock).Methods("DELETE")
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(reqctx.WithIdentity(req.Context(), &reqctx.Identity{UserID: "amina", Role: config.RoleAdmin}))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	AssertStatusCode(t, serve("POST", "/customers/5/block", `{"reason": "  "}`), http.StatusBadRequest)
+	AssertStatusCode(t, serve("POST", "/customers/6/block", `{"reason": "Fraud"}`), http.StatusNotFound)
+	AssertStatusCode(t, serve("DELETE", "/customers/5/block", ""), http.StatusConflict)
+
+	rr := serve("POST", "/customers/5/block", `{"reason": " Chargeback fraud "}`)
+	AssertStatusCode(t, rr, http.StatusOK)
+	var detail service.CustomerDetail
+	ParseJSONResponse(t, rr, &detail)
+	AssertEqual(t, detail.Blocked, true)
+	AssertEqual(t, *detail.BlockedReason, "Chargeback fraud")
+	AssertEqual(t, *detail.BlockedBy, "amina")
+	AssertEqual(t, len(detail.BlockHistory), 1)
+
+	rr = serve("DELETE", "/customers/5/block", "")
+	AssertStatusCode(t, rr, http.StatusOK)
+	rr = serve("GET", "/customers/5", "")
+	AssertStatusCode(t, rr, http.StatusOK)
+	detail = service.CustomerDetail{}
+	ParseJSONResponse(t, rr, &detail)
+	AssertEqual(t, detail.Blocked, false)
+	AssertEqual(t, len(detail.BlockHistory), 2)
+	AssertEqual(t, detail.BlockHistory[0].Action, models.CustomerUnblocked)
+	AssertEqual(t, *detail.BlockHistory[0].Actor, "amina")
+	AssertEqual(t, *detail.BlockHistory[1].Reason, "Chargeback fraud")
+}
+
+// TestCustomerRepository_Block tests that blocks and unblocks update the customer and log the
+// change in one transaction
+func TestCustomerRepository_Block(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewCustomerRepository(db)
+	actor := "amina"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE customers SET blocked = \$2, blocked_reason = \$3, blocked_by = \$4`).
+		WithArgs(5, true, "Fraud", "amina").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO customer_block_events`).
+		WithArgs(5, models.CustomerBlocked, "Fraud", "amina").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	AssertNoError(t, repo.Block(context.Background(), 5, "Fraud", &actor))
+
+	// The customer keeps no reason or blocker once unblocked; the log keeps who unblocked them
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE customers SET blocked = \$2`).
+		WithArgs(5, false, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO customer_block_events`).
+		WithArgs(5, models.CustomerUnblocked, nil, "amina").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+	AssertNoError(t, repo.Unblock(context.Background(), 5, &actor))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE customers SET blocked = \$2`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	err := repo.Block(context.Background(), 6, "Fraud", nil)
+	AssertEqual(t, err, repository.ErrCustomerNotFound)
+
+	mock.ExpectQuery(`SELECT id FROM customers WHERE blocked AND id = ANY\(\$1\)`).
+		WithArgs("{1,2,3}").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	blocked, err := repo.ListBlocked(context.Background(), []int{1, 2, 3})
+	AssertNoError(t, err)
+	AssertEqual(t, len(blocked), 1)
+	AssertEqual(t, blocked[0], 2)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
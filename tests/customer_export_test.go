@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestCustomerExport_FilterParity tests that the count checked against the cap and the
+// streamed rows are selected by the same WHERE clause and arguments
+func TestCustomerExport_FilterParity(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	where := regexp.QuoteMeta("FROM customers WHERE 1=1 AND (phone ILIKE $1 OR first_name ILIKE $1 OR last_name ILIKE $1) AND LOWER(location) = LOWER($2) AND LOWER(preferred_product) = LOWER($3)")
+	args := []driver.Value{`%50\%\_off%`, "Nairobi", "Premium Plan"}
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) " + where + "$").
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT id, phone, .* " + where + " ORDER BY id LIMIT \\$4$").
+		WithArgs(append(args, 10)...).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "phone", "first_name", "last_name", "location", "preferred_product",
+			"contact_window_start", "contact_window_end", "created_at", "blocked", "blocked_reason",
+		}).
+			AddRow(1, "+254700000001", "Amina", nil, "Nairobi", "Premium Plan", nil, nil, NewTestCustomer().CreatedAt, false, nil).
+			AddRow(2, "+254700000002", "Otieno", nil, "nairobi", "premium plan", nil, nil, NewTestCustomer().CreatedAt, true, "Fraud"))
+
+	repo := repository.NewCustomerRepository(db)
+	filters := repository.CustomerFilters{Query: "50%_off", Location: "Nairobi", Product: "Premium Plan"}
+
+	count, err := repo.CountFiltered(context.Background(), filters)
+	AssertNoError(t, err)
+
+	streamed := 0
+	err = repo.StreamFiltered(context.Background(), filters, 10, func(customer *models.Customer) error {
+		streamed++
+		return nil
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, streamed, count)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCustomerExport_Endpoint tests that the export streams the filtered customers as CSV
+func TestCustomerExport_Endpoint(t *testing.T) {
+	customerRepo := NewMockCustomerRepository()
+	var gotFilters repository.CustomerFilters
+	var gotLimit int
+	customerRepo.CountFilteredFunc = func(ctx context.Context, filters repository.CustomerFilters) (int, error) {
+		return 2, nil
+	}
+	customerRepo.StreamFilteredFunc = func(ctx context.Context, filters repository.CustomerFilters, limit int, fn func(customer *models.Customer) error) error {
+		gotFilters, gotLimit = filters, limit
+		first := NewTestCustomerWithID(1)
+		formula := "=HYPERLINK(\"x\")"
+		first.FirstName = &formula
+		second := NewTestCustomerWithID(2)
+		second.Location = nil
+		reason := "Chargeback fraud"
+		second.Blocked, second.BlockedReason = true, &reason
+		for _, customer := range []*models.Customer{first, second} {
+			if err := fn(customer); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	h := handler.NewCustomerHandler(service.NewCustomerService(customerRepo, config.LimitsConfig{}))
+
+	rr := httptest.NewRecorder()
+	h.Export(rr, httptest.NewRequest(http.MethodGet, "/customers/export.csv?q=+ami+&location=Nairobi&product=Premium", nil))
+	AssertStatusCode(t, rr, http.StatusOK)
+	AssertEqual(t, rr.Header().Get("Content-Type"), "text/csv; charset=utf-8")
+	AssertEqual(t, rr.Header().Get("Content-Disposition"), `attachment; filename="customers.csv"`)
+	AssertEqual(t, gotFilters, repository.CustomerFilters{Query: "ami", Location: "Nairobi", Product: "Premium"})
+	AssertEqual(t, gotLimit, service.DefaultMaxCustomerExportRows)
+
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	AssertNoError(t, err)
+	AssertEqual(t, len(records), 3)
+	AssertEqual(t, strings.Join(records[0], ","), "id,phone,first_name,last_name,location,preferred_product,contact_window_start,contact_window_end,created_at,blocked,blocked_reason")
+	AssertEqual(t, records[1][0], "1")
+	AssertEqual(t, records[1][2], "'=HYPERLINK(\"x\")")
+	AssertEqual(t, records[1][9], "false")
+	AssertEqual(t, records[2][4], "")
+	AssertEqual(t, records[2][9], "true")
+	AssertEqual(t, records[2][10], "Chargeback fraud")
+}
+
+// TestCustomerExport_OverCap tests that an export over the row cap is refused before any row is sent
+func TestCustomerExport_OverCap(t *testing.T) {
+	customerRepo := NewMockCustomerRepository()
+	customerRepo.CountFilteredFunc = func(ctx context.Context, filters repository.CustomerFilters) (int, error) {
+		return 1001, nil
+	}
+	h := handler.NewCustomerHandler(service.NewCustomerService(customerRepo, config.LimitsConfig{MaxCustomerExportRows: 1000}))
+
+	rr := httptest.NewRecorder()
+	h.Export(rr, httptest.NewRequest(http.MethodGet, "/customers/export.csv", nil))
+	AssertStatusCode(t, rr, http.StatusBadRequest)
+	AssertEqual(t, rr.Header().Get("Content-Disposition"), "")
+	AssertEqual(t, customerRepo.Calls["StreamFiltered"], 0)
+
+	var resp handler.ErrorResponse
+	ParseJSONResponse(t, rr, &resp)
+	AssertEqual(t, resp.Error.Message, "export matches 1001 customers, over the limit of 1000; narrow the filters")
+}
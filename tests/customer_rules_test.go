@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+)
+
+// testCustomerRules requires a last name, limits and restricts location, bans a product and
+// patterns first names
+const testCustomerRules = `
+fields:
+  last_name:
+    required: true
+  location:
+    max_length: 7
+    allowed: [Nairobi, Kisumu]
+  preferred_product:
+    forbidden: [Test]
+  first_name:
+    pattern: '^[A-Za-z]+$'
+`
+
+// mustParseCustomerRules parses rules that are known to be valid
+func mustParseCustomerRules(t *testing.T, data string) *config.CustomerRules {
+	t.Helper()
+	rules, err := config.ParseCustomerRules([]byte(data))
+	AssertNoError(t, err)
+	return rules
+}
+
+// brokenRules lists the field and rule of each violation
+func brokenRules(violations []*config.CustomerRuleViolation) string {
+	broken := make([]string, len(violations))
+	for i, violation := range violations {
+		broken[i] = violation.Field + ":" + violation.Rule
+	}
+	return strings.Join(broken, ",")
+}
+
+// TestCustomerRules_Check tests each rule type against passing and failing values
+func TestCustomerRules_Check(t *testing.T) {
+	rules := mustParseCustomerRules(t, testCustomerRules)
+
+	tests := []struct {
+		name     string
+		customer *models.Customer
+		want     string
+	}{
+		{"passes", &models.Customer{LastName: StringPtr("Otieno"), Location: StringPtr("nairobi"), FirstName: StringPtr("Amina")}, ""},
+		{"required missing", &models.Customer{}, "last_name:required"},
+		{"required blank", &models.Customer{LastName: StringPtr("  ")}, "last_name:required"},
+		{"max length", &models.Customer{LastName: StringPtr("O"), Location: StringPtr("Nairobi West")}, "location:max_length,location:allowed"},
+		{"allowed", &models.Customer{LastName: StringPtr("O"), Location: StringPtr("Mombasa")}, "location:allowed"},
+		{"forbidden ignores case", &models.Customer{LastName: StringPtr("O"), PreferredProduct: StringPtr("TEST")}, "preferred_product:forbidden"},
+		{"pattern", &models.Customer{LastName: StringPtr("O"), FirstName: StringPtr("Amina 2")}, "first_name:pattern"},
+		{"absent optional fields pass", &models.Customer{LastName: StringPtr("O")}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AssertEqual(t, brokenRules(rules.Check(tt.customer)), tt.want)
+		})
+	}
+
+	// Without rules everything passes
+	var none *config.CustomerRules
+	AssertEqual(t, len(none.Check(&models.Customer{})), 0)
+}
+
+// TestParseCustomerRules_Invalid tests that malformed rules are refused with the problem named
+func TestParseCustomerRules_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"unknown field", "fields:\n  email: {required: true}", `unknown field "email"`},
+		{"unknown rule", "fields:\n  last_name: {mandatory: true}", "field mandatory not found"},
+		{"negative length", "fields:\n  location: {max_length: -1}", "fields.location.max_length cannot be negative"},
+		{"invalid pattern", "fields:\n  first_name: {pattern: '[a-'}", "fields.first_name.pattern is invalid"},
+		{"allowed and forbidden", "fields:\n  location: {allowed: [A], forbidden: [B]}", "sets both allowed and forbidden"},
+		{"empty value", "fields:\n  location: {allowed: ['']}", "fields.location lists an empty value"},
+		{"no rules", "fields:\n  location:", "fields.location has no rules"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.ParseCustomerRules([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected error containing %q but got %v", tt.want, err)
+			}
+		})
+	}
+
+	// JSON is YAML too
+	rules := mustParseCustomerRules(t, `{"fields": {"last_name": {"required": true}}}`)
+	AssertEqual(t, brokenRules(rules.Check(&models.Customer{})), "last_name:required")
+}
+
+// TestLoad_CustomerRules tests loading rules from a file or inline, and that bad rules fail startup
+func TestLoad_CustomerRules(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	if cfg.CustomerRules != nil {
+		t.Fatalf("Expected no customer rules by default")
+	}
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "rules.yaml")
+	AssertNoError(t, os.WriteFile(valid, []byte(testCustomerRules), 0o600))
+	invalid := filepath.Join(dir, "invalid.yaml")
+	AssertNoError(t, os.WriteFile(invalid, []byte("fields:\n  first_name: {pattern: '[a-'}"), 0o600))
+
+	t.Setenv("CUSTOMER_VALIDATION_RULES_FILE", valid)
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, len(cfg.CustomerRules.Fields), 4)
+
+	t.Setenv("CUSTOMER_VALIDATION_RULES_FILE", invalid)
+	_, err = config.Load()
+	if err == nil || !strings.HasPrefix(err.Error(), "CUSTOMER_VALIDATION_RULES_FILE "+invalid+" is invalid: fields.first_name.pattern is invalid") {
+		t.Fatalf("Expected invalid rules file error but got %v", err)
+	}
+
+	t.Setenv("CUSTOMER_VALIDATION_RULES_FILE", filepath.Join(dir, "missing.yaml"))
+	_, err = config.Load()
+	if err == nil || !strings.HasPrefix(err.Error(), "CUSTOMER_VALIDATION_RULES_FILE could not be read") {
+		t.Fatalf("Expected unreadable rules file error but got %v", err)
+	}
+
+	t.Setenv("CUSTOMER_VALIDATION_RULES", "fields: {last_name: {required: true}}")
+	_, err = config.Load()
+	AssertError(t, err, "set CUSTOMER_VALIDATION_RULES or CUSTOMER_VALIDATION_RULES_FILE, not both")
+
+	t.Setenv("CUSTOMER_VALIDATION_RULES_FILE", "")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, len(cfg.CustomerRules.Fields), 1)
+}
+
+// TestCreateCustomer_CustomerRules tests that a customer breaking the rules is not created
+func TestCreateCustomer_CustomerRules(t *testing.T) {
+	customerRepo := NewMockCustomerRepository()
+	svc := service.NewCustomerService(customerRepo, config.LimitsConfig{})
+	svc.SetCustomerRules(mustParseCustomerRules(t, testCustomerRules))
+
+	_, err := svc.CreateCustomer(context.Background(), &models.Customer{Phone: "+254700000001", Location: StringPtr("Mombasa")})
+	AssertError(t, err, "validation error: customer breaks validation rules: last_name is required; location must be one of Nairobi, Kisumu")
+	AssertEqual(t, brokenRules(err.(*service.ValidationError).Violations), "last_name:required,location:allowed")
+	AssertEqual(t, customerRepo.Calls["Create"], 0)
+
+	_, err = svc.CreateCustomer(context.Background(), &models.Customer{Phone: "+254700000001", LastName: StringPtr("Otieno")})
+	AssertNoError(t, err)
+	AssertEqual(t, customerRepo.Calls["Create"], 1)
+}
+
+// TestInlineCustomers_CustomerRules tests that inline customers are checked with their stored
+// fields filled in, and violations are listed in the error response
+func TestInlineCustomers_CustomerRules(t *testing.T) {
+	svc, customerRepo, _ := setupInlineCustomersTest(t)
+	svc.SetCustomerRules(mustParseCustomerRules(t, testCustomerRules))
+
+	// The stored customer already has a last name, so an update leaving it out passes
+	customerRepo.GetByPhonesFunc = func(ctx context.Context, phones []string) ([]*models.Customer, error) {
+		stored := NewTestCustomerWithID(7)
+		stored.Phone = "+254712345678"
+		stored.LastName = StringPtr("Otieno")
+		return []*models.Customer{stored}, nil
+	}
+
+	_, err := svc.SendCampaign(context.Background(), 1, nil, service.SendOptions{
+		Customers: []service.InlineCustomer{
+			{Phone: "+254712345678", FirstName: StringPtr("Amina")},
+			{Phone: "+254733000111", Location: StringPtr("Mombasa")},
+		},
+	})
+	if err == nil {
+		t.Fatal("Expected inline customers breaking the rules to be refused")
+	}
+	AssertEqual(t, customerRepo.Calls["UpsertByPhone"], 0)
+
+	resp := httptest.NewRecorder()
+	handler.HandleServiceError(resp, err)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+
+	var body struct {
+		Error struct {
+			Code   string `json:"code"`
+			Errors []struct {
+				Field string `json:"field"`
+				Rule  string `json:"rule"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	AssertNoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	AssertEqual(t, body.Error.Code, "VALIDATION_ERROR")
+	AssertEqual(t, len(body.Error.Errors), 2)
+	AssertEqual(t, body.Error.Errors[0].Field, "customers[1].last_name")
+	AssertEqual(t, body.Error.Errors[0].Rule, "required")
+	AssertEqual(t, body.Error.Errors[1].Field, "customers[1].location")
+	AssertEqual(t, body.Error.Errors[1].Rule, "allowed")
+}
+
+// TestSendCampaignCSV_CustomerRules tests that unknown phones breaking the rules are reported
+// by line and rule instead of created
+func TestSendCampaignCSV_CustomerRules(t *testing.T) {
+	svc, customerRepo, mock := setupSendCSVTest(t)
+	svc.SetCustomerRules(mustParseCustomerRules(t, testCustomerRules))
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var created []*models.Customer
+	customerRepo.CreateFunc = func(ctx context.Context, customer *models.Customer) error {
+		created = append(created, customer)
+		customer.ID = 100 + len(created)
+		return nil
+	}
+
+	csv := "phone,last_name,location\n" +
+		"+254700000001,,\n" + // Known, so not checked
+		"+254711111111,Otieno,Kisumu\n" +
+		"+254722222222,,Mombasa\n"
+	result, err := svc.SendCampaignCSV(context.Background(), 1, strings.NewReader(csv), &service.SendCampaignCSVRequest{CreateUnknown: true})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Matched, 1)
+	AssertEqual(t, result.Created, 1)
+	AssertEqual(t, result.Invalid, 1)
+	AssertEqual(t, len(created), 1)
+	AssertEqual(t, *created[0].LastName, "Otieno")
+	AssertEqual(t, *created[0].Location, "Kisumu")
+	AssertEqual(t, len(result.Errors), 2)
+	AssertEqual(t, result.Errors[0].Line, 4)
+	AssertEqual(t, result.Errors[0].Rule, "required")
+	AssertEqual(t, result.Errors[1].Rule, "allowed")
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestCustomerStats_GroupsRows tests that aggregate rows are split into total and breakdowns
+func TestCustomerStats_GroupsRows(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("WITH buckets AS").
+		WithArgs(models.CustomerStatsUnknown, "Nairobi", models.CustomerStatsTopN).
+		WillReturnRows(sqlmock.NewRows([]string{"dimension", "value", "count"}).
+			AddRow("location", "Nairobi", 6).
+			AddRow("location", "unknown", 2).
+			AddRow("product", "Premium Plan", 4).
+			AddRow("product", "unknown", 2).
+			AddRow("total", "", 8))
+
+	location := "Nairobi"
+	stats, err := repository.NewCustomerRepository(db).GetStats(context.Background(), &location)
+	AssertNoError(t, err)
+
+	AssertEqual(t, stats.TotalCustomers, 8)
+	AssertEqual(t, len(stats.ByLocation), 2)
+	AssertEqual(t, len(stats.ByProduct), 2)
+	AssertEqual(t, stats.ByProduct[0].Value, "Premium Plan")
+	AssertEqual(t, stats.ByLocation[1].Value, models.CustomerStatsUnknown)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCustomerStatsEndpoint tests GET /customers/stats passes the location scope through
+func TestCustomerStatsEndpoint(t *testing.T) {
+	customerRepo := NewMockCustomerRepository()
+	var gotLocation *string
+	customerRepo.GetStatsFunc = func(ctx context.Context, location *string) (*models.CustomerStats, error) {
+		gotLocation = location
+		return &models.CustomerStats{TotalCustomers: 3}, nil
+	}
+	h := handler.NewCustomerHandler(service.NewCustomerService(customerRepo, config.LimitsConfig{}))
+
+	// No scope
+	resp := httptest.NewRecorder()
+	h.Stats(resp, httptest.NewRequest("GET", "/customers/stats", nil))
+	AssertStatusCode(t, resp, http.StatusOK)
+	if gotLocation != nil {
+		t.Errorf("Expected no location scope but got %q", *gotLocation)
+	}
+
+	var stats models.CustomerStats
+	ParseJSONResponse(t, resp, &stats)
+	AssertEqual(t, stats.TotalCustomers, 3)
+
+	// Scoped to a location
+	resp = httptest.NewRecorder()
+	h.Stats(resp, httptest.NewRequest("GET", "/customers/stats?location=Mombasa", nil))
+	AssertStatusCode(t, resp, http.StatusOK)
+	if gotLocation == nil || *gotLocation != "Mombasa" {
+		t.Errorf("Expected location scope Mombasa but got %v", gotLocation)
+	}
+
+	// Repository failure
+	customerRepo.GetStatsFunc = func(ctx context.Context, location *string) (*models.CustomerStats, error) {
+		return nil, fmt.Errorf("connection refused")
+	}
+	resp = httptest.NewRecorder()
+	h.Stats(resp, httptest.NewRequest("GET", "/customers/stats", nil))
+	AssertStatusCode(t, resp, http.StatusInternalServerError)
+}
+
+// TestCustomerStats_Integration tests the aggregate over seeded customers
+func TestCustomerStats_Integration(t *testing.T) {
+	db := SetupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer db.Close()
+	CleanupTestDB(t, db)
+	defer CleanupTestDB(t, db)
+
+	ctx := context.Background()
+	customerRepo := repository.NewCustomerRepository(db)
+
+	seed := []struct {
+		location *string
+		product  *string
+	}{
+		{StringPtr("Nairobi"), StringPtr("Loans")},
+		{StringPtr("Nairobi"), StringPtr("Loans")},
+		{StringPtr("Nairobi"), StringPtr("Savings")},
+		{StringPtr("Mombasa"), StringPtr("Savings")},
+		{nil, nil},
+	}
+	for i, c := range seed {
+		AssertNoError(t, customerRepo.Create(ctx, &models.Customer{
+			Phone:            fmt.Sprintf("+2547100000%02d", i),
+			Location:         c.location,
+			PreferredProduct: c.product,
+		}))
+	}
+
+	stats, err := customerRepo.GetStats(ctx, nil)
+	AssertNoError(t, err)
+	AssertEqual(t, stats.TotalCustomers, 5)
+	AssertEqual(t, stats.ByLocation[0].Value, "Nairobi")
+	AssertEqual(t, stats.ByLocation[0].Count, 3)
+	AssertEqual(t, len(stats.ByProduct), 3)
+
+	nairobi := "Nairobi"
+	stats, err = customerRepo.GetStats(ctx, &nairobi)
+	AssertNoError(t, err)
+	AssertEqual(t, stats.TotalCustomers, 5)
+	AssertEqual(t, stats.ByProduct[0].Value, "Loans")
+	AssertEqual(t, stats.ByProduct[0].Count, 2)
+	AssertEqual(t, len(stats.ByProduct), 2)
+}
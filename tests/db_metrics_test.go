@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"smsleopard/internal/metrics"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestDBStatsSampler tests that pool state is copied into the gauges and that waits for a
+// free connection advance the wait counters by the change since the last sample
+func TestDBStatsSampler(t *testing.T) {
+	db, _ := NewMockDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	sampler := metrics.NewDBStatsSampler("sampler_test", db)
+
+	waitsBefore := testutil.ToFloat64(metrics.DBConnectionWaits.WithLabelValues("sampler_test"))
+	waitSecondsBefore := testutil.ToFloat64(metrics.DBConnectionWaitSeconds.WithLabelValues("sampler_test"))
+
+	held, err := db.Conn(context.Background())
+	AssertNoError(t, err)
+	sampler.Sample()
+	AssertEqual(t, testutil.ToFloat64(metrics.DBConnectionsInUse.WithLabelValues("sampler_test")), float64(1))
+	AssertEqual(t, testutil.ToFloat64(metrics.DBConnectionsMaxOpen.WithLabelValues("sampler_test")), float64(1))
+	AssertEqual(t, testutil.ToFloat64(metrics.DBConnectionWaits.WithLabelValues("sampler_test")), waitsBefore)
+
+	// A second caller waits until the only connection is returned
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		held.Close()
+	}()
+	waited, err := db.Conn(context.Background())
+	AssertNoError(t, err)
+	waited.Close()
+
+	sampler.Sample()
+	sampler.Sample()
+	AssertEqual(t, testutil.ToFloat64(metrics.DBConnectionsInUse.WithLabelValues("sampler_test")), float64(0))
+	AssertEqual(t, testutil.ToFloat64(metrics.DBConnectionsIdle.WithLabelValues("sampler_test")), float64(1))
+	AssertEqual(t, testutil.ToFloat64(metrics.DBConnectionWaits.WithLabelValues("sampler_test")), waitsBefore+1)
+	if seconds := testutil.ToFloat64(metrics.DBConnectionWaitSeconds.WithLabelValues("sampler_test")) - waitSecondsBefore; seconds < 0.01 {
+		t.Errorf("Expected the wait of about 20ms to be counted, got %fs", seconds)
+	}
+}
+
+// repositoryCallBuckets returns the cumulative bucket counts, by upper bound, of the
+// repository call histogram for repository and method
+func repositoryCallBuckets(t *testing.T, repository, method string) map[float64]uint64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	AssertNoError(t, err)
+	buckets := map[float64]uint64{}
+	for _, family := range families {
+		if family.GetName() != "smsleopard_repository_call_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["repository"] != repository || labels["method"] != method {
+				continue
+			}
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				buckets[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+			}
+		}
+	}
+	return buckets
+}
+
+// TestTimedRepository_SlowCallBucket tests that a slow repository call is recorded under its
+// repository and method, in the bucket covering its duration
+func TestTimedRepository_SlowCallBucket(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewTimedCustomerRepository(repository.NewCustomerRepository(db))
+
+	before := repositoryCallBuckets(t, "customer", "GetByID")
+	mock.ExpectQuery("SELECT (.+) FROM customers WHERE id").
+		WithArgs(1).
+		WillDelayFor(20 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	repo.GetByID(context.Background(), 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+
+	// Buckets double from 1ms: 20ms lands above 16ms and within 32ms
+	after := repositoryCallBuckets(t, "customer", "GetByID")
+	AssertEqual(t, after[0.016]-before[0.016], uint64(0))
+	AssertEqual(t, after[0.032]-before[0.032], uint64(1))
+	AssertEqual(t, after[8.192]-before[8.192], uint64(1))
+}
+
+// TestTimedRepositories_LabelEveryMethod tests that each decorated repository records calls
+// under its own label, and passes calls and results through unchanged
+func TestTimedRepositories_LabelEveryMethod(t *testing.T) {
+	campaigns := NewMockCampaignRepository()
+	campaigns.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		campaign := NewTestCampaign()
+		campaign.ID = id
+		return campaign, nil
+	}
+	messages := NewMockMessageRepository()
+	messages.CountUnpublishedFunc = func(ctx context.Context) (int, error) {
+		return 7, nil
+	}
+
+	campaign, err := repository.NewTimedCampaignRepository(campaigns).GetByID(context.Background(), 4)
+	AssertNoError(t, err)
+	AssertEqual(t, campaign.ID, 4)
+	unpublished, err := repository.NewTimedMessageRepository(messages).CountUnpublished(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, unpublished, 7)
+
+	AssertEqual(t, len(repositoryCallBuckets(t, "campaign", "GetByID")) > 0, true)
+	AssertEqual(t, len(repositoryCallBuckets(t, "message", "CountUnpublished")) > 0, true)
+}
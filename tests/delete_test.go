@@ -0,0 +1,218 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// TestRepositoryDelete_ForeignKeyViolation tests that a restricted delete reports the blocking messages
+func TestRepositoryDelete_ForeignKeyViolation(t *testing.T) {
+	tests := []struct {
+		name     string
+		table    string
+		column   string
+		message  string
+		deleteFn func(db *sql.DB) error
+	}{
+		{
+			name:    "campaign",
+			table:   "campaigns",
+			column:  "campaign_id",
+			message: "campaign has 12 messages",
+			deleteFn: func(db *sql.DB) error {
+				return repository.NewCampaignRepository(db).Delete(context.Background(), 7)
+			},
+		},
+		{
+			name:    "customer",
+			table:   "customers",
+			column:  "customer_id",
+			message: "customer has 12 messages",
+			deleteFn: func(db *sql.DB) error {
+				return repository.NewCustomerRepository(db).Delete(context.Background(), 7)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := NewMockDB(t)
+			defer db.Close()
+
+			mock.ExpectExec("DELETE FROM " + tt.table + " WHERE id = \\$1").
+				WithArgs(7).
+				WillReturnError(&pq.Error{Code: "23503", Message: "update or delete violates foreign key constraint"})
+			mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM outbound_messages WHERE " + tt.column + " = \\$1").
+				WithArgs(7).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+
+			err := tt.deleteFn(db)
+			AssertError(t, err, tt.message)
+			AssertEqual(t, errors.Is(err, repository.ErrHasDependents), true)
+			AssertNoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestRepositoryDelete_NotFound tests that deleting a missing campaign returns the sentinel error
+func TestRepositoryDelete_NotFound(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM campaigns WHERE id = \\$1").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repository.NewCampaignRepository(db).Delete(context.Background(), 7)
+	AssertEqual(t, errors.Is(err, repository.ErrCampaignNotFound), true)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepositoryDeleteWithMessages tests that a forced delete removes messages first in one transaction
+func TestRepositoryDeleteWithMessages(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM outbound_messages WHERE customer_id = \\$1").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("DELETE FROM customers WHERE id = \\$1").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	deleted, err := repository.NewCustomerRepository(db).DeleteWithMessages(context.Background(), 3)
+	AssertNoError(t, err)
+	AssertEqual(t, deleted, 4)
+
+	// Nothing is kept when the record itself is gone
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM outbound_messages WHERE campaign_id = \\$1").
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM campaigns WHERE id = \\$1").
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err = repository.NewCampaignRepository(db).DeleteWithMessages(context.Background(), 9)
+	AssertEqual(t, errors.Is(err, repository.ErrCampaignNotFound), true)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// setupDeleteTest serves the campaign and customer delete endpoints over mock repositories
+func setupDeleteTest(t *testing.T) (*mux.Router, *MockCampaignRepository, *MockCustomerRepository) {
+	campaignSvc, campaignRepo, _, _ := setupApprovalTest(t)
+	customerRepo := NewMockCustomerRepository()
+	customerSvc := service.NewCustomerService(customerRepo, config.LimitsConfig{})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}", handler.NewCampaignHandler(campaignSvc).Delete).Methods("DELETE")
+	router.HandleFunc("/customers/{id}", handler.NewCustomerHandler(customerSvc).Delete).Methods("DELETE")
+	return router, campaignRepo, customerRepo
+}
+
+// TestDeleteEndpoints_Conflict tests that deleting a record with messages is a 409 and nothing is removed
+func TestDeleteEndpoints_Conflict(t *testing.T) {
+	router, campaignRepo, customerRepo := setupDeleteTest(t)
+	campaignRepo.DeleteFunc = func(ctx context.Context, id int) error {
+		return &repository.HasDependentsError{Resource: "campaign", Messages: 250}
+	}
+	customerRepo.DeleteFunc = func(ctx context.Context, id int) error {
+		return &repository.HasDependentsError{Resource: "customer", Messages: 3}
+	}
+
+	tests := []struct {
+		path    string
+		message string
+	}{
+		{"/campaigns/1", "campaign has 250 messages; delete with force=true to remove them too"},
+		{"/customers/1", "customer has 3 messages; delete with force=true to remove them too"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("DELETE", tt.path, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		AssertStatusCode(t, resp, http.StatusConflict)
+		var errResp handler.ErrorResponse
+		ParseJSONResponse(t, resp, &errResp)
+		AssertEqual(t, errResp.Error.Code, "CONFLICT")
+		AssertEqual(t, errResp.Error.Message, tt.message)
+	}
+
+	AssertEqual(t, campaignRepo.Calls["DeleteWithMessages"], 0)
+	AssertEqual(t, customerRepo.Calls["DeleteWithMessages"], 0)
+}
+
+// TestDeleteEndpoints_Force tests that force=true deletes the messages too and reports how many
+func TestDeleteEndpoints_Force(t *testing.T) {
+	router, campaignRepo, customerRepo := setupDeleteTest(t)
+	campaignRepo.DeleteWithMessagesFunc = func(ctx context.Context, id int) (int, error) {
+		return 250, nil
+	}
+	customerRepo.DeleteWithMessagesFunc = func(ctx context.Context, id int) (int, error) {
+		return 3, nil
+	}
+
+	for path, deleted := range map[string]int{"/campaigns/1?force=true": 250, "/customers/4?force=true": 3} {
+		req := httptest.NewRequest("DELETE", path, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		AssertStatusCode(t, resp, http.StatusOK)
+		var result service.DeleteResult
+		ParseJSONResponse(t, resp, &result)
+		AssertEqual(t, result.MessagesDeleted, deleted)
+	}
+
+	AssertEqual(t, campaignRepo.Calls["Delete"], 0)
+	AssertEqual(t, customerRepo.Calls["Delete"], 0)
+}
+
+// TestDeleteEndpoints_Errors tests not found and invalid force values
+func TestDeleteEndpoints_Errors(t *testing.T) {
+	router, campaignRepo, customerRepo := setupDeleteTest(t)
+	campaignRepo.DeleteFunc = func(ctx context.Context, id int) error {
+		return repository.ErrCampaignNotFound
+	}
+	customerRepo.DeleteFunc = func(ctx context.Context, id int) error {
+		return repository.ErrCustomerNotFound
+	}
+
+	for path, status := range map[string]int{
+		"/campaigns/1":             http.StatusNotFound,
+		"/customers/1":             http.StatusNotFound,
+		"/campaigns/1?force=maybe": http.StatusBadRequest,
+		"/customers/0":             http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("DELETE", path, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		AssertStatusCode(t, resp, status)
+	}
+
+	// Without a database error type the failure stays a 500
+	customerRepo.DeleteFunc = func(ctx context.Context, id int) error {
+		return errors.New("failed to delete customer: connection reset")
+	}
+	req := httptest.NewRequest("DELETE", "/customers/1", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusInternalServerError)
+}
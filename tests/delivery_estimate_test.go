@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+)
+
+// segmentsOf returns an estimate's SMS part count, or 0 when it has none
+func segmentsOf(estimate *service.DeliveryEstimate) int {
+	if estimate.Segments == nil {
+		return 0
+	}
+	return *estimate.Segments
+}
+
+// TestDeliveryEstimate_SMSSegments tests encodings and part counts at the part boundaries
+func TestDeliveryEstimate_SMSSegments(t *testing.T) {
+	estimator := service.NewDeliveryEstimator(service.NewTemplateService(), testSendingConfig())
+
+	cases := []struct {
+		name      string
+		content   string
+		encoding  string
+		charCount int
+		segments  int
+	}{
+		{"gsm7 single part", strings.Repeat("a", 160), service.EncodingGSM7, 160, 1},
+		{"gsm7 two parts", strings.Repeat("a", 161), service.EncodingGSM7, 161, 2},
+		{"gsm7 three parts", strings.Repeat("a", 307), service.EncodingGSM7, 307, 3},
+		{"extension characters take two septets", strings.Repeat("€", 80), service.EncodingGSM7, 80, 1},
+		{"extension characters overflow a part", strings.Repeat("€", 80) + "a", service.EncodingGSM7, 81, 2},
+		{"ucs2 single part", strings.Repeat("ш", 70), service.EncodingUCS2, 70, 1},
+		{"ucs2 two parts", strings.Repeat("ш", 71), service.EncodingUCS2, 71, 2},
+		{"emoji take two code units", strings.Repeat("🎉", 35), service.EncodingUCS2, 35, 1},
+		{"accents in the gsm7 alphabet", "Café à Zürich", service.EncodingGSM7, 13, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			estimate := estimator.Estimate(models.ChannelSMS, tc.content, 1)
+			AssertEqual(t, estimate.Encoding, tc.encoding)
+			AssertEqual(t, estimate.CharCount, tc.charCount)
+			AssertEqual(t, segmentsOf(estimate), tc.segments)
+		})
+	}
+}
+
+// TestDeliveryEstimate_Warnings tests the UCS-2, multi-part and WhatsApp length warnings
+func TestDeliveryEstimate_Warnings(t *testing.T) {
+	estimator := service.NewDeliveryEstimator(service.NewTemplateService(), testSendingConfig())
+
+	estimate := estimator.Estimate(models.ChannelSMS, "Hi John", 1)
+	AssertEqual(t, len(estimate.Warnings), 0)
+
+	estimate = estimator.Estimate(models.ChannelSMS, "Hi John 🎉"+strings.Repeat("a", 70), 1)
+	AssertEqual(t, len(estimate.Warnings), 2)
+	AssertEqual(t, estimate.Warnings[0], `"🎉" is outside the GSM-7 alphabet, so the message is sent as UCS-2 with 70 characters per part`)
+	AssertEqual(t, estimate.Warnings[1], "message is sent as 2 SMS parts")
+
+	estimate = estimator.Estimate(models.ChannelWhatsApp, strings.Repeat("🎉", 4097), 1)
+	AssertEqual(t, estimate.Encoding, service.EncodingUTF8)
+	AssertEqual(t, estimate.Segments == nil, true)
+	AssertEqual(t, len(estimate.Warnings), 1)
+	AssertEqual(t, estimate.Warnings[0], "message is 4097 characters; WhatsApp allows at most 4096")
+}
+
+// TestDeliveryEstimate_Cost tests per-channel pricing per message, whatever its part count
+func TestDeliveryEstimate_Cost(t *testing.T) {
+	estimator := service.NewDeliveryEstimator(service.NewTemplateService(), testSendingConfig())
+
+	estimate := estimator.Estimate(models.ChannelSMS, strings.Repeat("a", 400), 3)
+	AssertEqual(t, segmentsOf(estimate), 3)
+	AssertEqual(t, estimate.Recipients, 3)
+	AssertEqual(t, estimate.UnitCost, 0.80)
+	AssertEqual(t, estimate.TotalCost, 2.4)
+
+	estimate = estimator.Estimate(models.ChannelWhatsApp, "Hi", 3)
+	AssertEqual(t, estimate.UnitCost, 0.50)
+	AssertEqual(t, estimate.TotalCost, 1.5)
+
+	var none *service.DeliveryEstimator
+	AssertEqual(t, none.Estimate(models.ChannelSMS, "Hi", 1) == nil, true)
+}
+
+// deliveryEstimateFixture wires the four estimating services to one campaign and estimator
+type deliveryEstimateFixture struct {
+	campaign   *models.Campaign
+	campaigns  *service.CampaignService
+	simulation *service.SimulationService
+	readiness  *service.ReadinessService
+}
+
+func newDeliveryEstimateFixture(campaign *models.Campaign) *deliveryEstimateFixture {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return campaign, nil
+	}
+	customerRepo := NewMockCustomerRepository()
+	messageRepo := NewMockMessageRepository()
+	templates := service.NewTemplateService()
+	estimator := service.NewDeliveryEstimator(templates, testSendingConfig())
+
+	f := &deliveryEstimateFixture{
+		campaign:   campaign,
+		campaigns:  service.NewCampaignService(campaignRepo, customerRepo, messageRepo, templates, NewMockPublisher(), nil, config.ApprovalConfig{}),
+		simulation: service.NewSimulationService(campaignRepo, customerRepo, messageRepo, testSendingConfig(), config.QuietHoursConfig{}),
+		readiness:  service.NewReadinessService(campaignRepo, customerRepo, messageRepo, templates, &stubQueue{status: service.StatusConnected}, config.QuietHoursConfig{}),
+	}
+	f.campaigns.SetDeliveryEstimator(estimator)
+	f.simulation.SetDeliveryEstimator(estimator)
+	f.readiness.SetDeliveryEstimator(estimator)
+	return f
+}
+
+// estimates returns customer 1's estimate from preview, the re-render dry run, simulation and readiness
+func (f *deliveryEstimateFixture) estimates(t *testing.T) map[string]*service.DeliveryEstimate {
+	t.Helper()
+	ctx := context.Background()
+
+	preview, err := f.campaigns.PreviewMessage(ctx, &service.PreviewMessageRequest{CampaignID: 1, CustomerID: 1})
+	AssertNoError(t, err)
+
+	dryRun, err := f.campaigns.ReRenderCampaign(ctx, 1, &service.ReRenderRequest{DryRun: true})
+	AssertNoError(t, err)
+	AssertEqual(t, dryRun.Sample[0].CustomerID, 1)
+
+	simulation, err := f.simulation.Simulate(ctx, 1, &service.SimulateCampaignRequest{CustomerIDs: []int{1}})
+	AssertNoError(t, err)
+
+	readiness, err := f.readiness.CheckReadiness(ctx, 1, []int{1})
+	AssertNoError(t, err)
+
+	return map[string]*service.DeliveryEstimate{
+		"preview":   preview.DeliveryEstimate,
+		"dry_run":   dryRun.Sample[0].DeliveryEstimate,
+		"simulate":  simulation.DeliveryEstimate,
+		"readiness": readiness.DeliveryEstimate,
+	}
+}
+
+// TestDeliveryEstimate_ConsistentAcrossEndpoints tests that every endpoint estimating the same
+// message for the same customer returns the identical estimate
+func TestDeliveryEstimate_ConsistentAcrossEndpoints(t *testing.T) {
+	cases := []struct {
+		name     string
+		channel  models.Channel
+		template string
+	}{
+		{"gsm7", models.ChannelSMS, "Hello {first_name}, welcome to {preferred_product}!"},
+		{"ucs2", models.ChannelSMS, "Habari {first_name} 🎉 karibu {preferred_product}"},
+		{"multi-part", models.ChannelSMS, "Hello {first_name}, " + strings.Repeat("your {preferred_product} renewal is due. ", 5)},
+		{"whatsapp", models.ChannelWhatsApp, "Habari {first_name} 🎉"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			campaign := NewTestCampaignWithStatus(models.CampaignStatusSending)
+			campaign.Channel = tc.channel
+			campaign.BaseTemplate = tc.template
+			f := newDeliveryEstimateFixture(campaign)
+
+			estimates := f.estimates(t)
+			want := estimates["preview"]
+			AssertNotNil(t, want)
+			AssertEqual(t, want.Channel, campaign.Channel)
+			AssertEqual(t, want.Recipients, 1)
+			for endpoint, got := range estimates {
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s estimate %+v differs from preview %+v", endpoint, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestDeliveryEstimate_SimulationAudience tests that simulation prices the whole audience and
+// estimates the template as written when no customers are given
+func TestDeliveryEstimate_SimulationAudience(t *testing.T) {
+	f := newDeliveryEstimateFixture(NewTestCampaign())
+
+	result, err := f.simulation.Simulate(context.Background(), 1, &service.SimulateCampaignRequest{CustomerIDs: []int{1, 2, 3}})
+	AssertNoError(t, err)
+	AssertEqual(t, result.DeliveryEstimate.Recipients, 3)
+	AssertEqual(t, result.DeliveryEstimate.TotalCost, result.EstimatedCost)
+	AssertEqual(t, result.DeliveryEstimate.UnitCost, result.CostPerMessage)
+
+	result, err = f.simulation.Simulate(context.Background(), 1, &service.SimulateCampaignRequest{AudienceSize: 500})
+	AssertNoError(t, err)
+	AssertEqual(t, result.DeliveryEstimate.CharCount, len([]rune(f.campaign.BaseTemplate)))
+	AssertEqual(t, result.DeliveryEstimate.TotalCost, result.EstimatedCost)
+	AssertEqual(t, result.DeliveryEstimate.Warnings[0], "estimated from the template as written; rendered length varies by customer")
+}
+
+// TestDeliveryEstimate_OmittedWithoutEstimator tests that results carry no estimate until one is set
+func TestDeliveryEstimate_OmittedWithoutEstimator(t *testing.T) {
+	svc := service.NewCampaignService(NewMockCampaignRepository(), NewMockCustomerRepository(), NewMockMessageRepository(), service.NewTemplateService(), NewMockPublisher(), nil, config.ApprovalConfig{})
+
+	preview, err := svc.PreviewMessage(context.Background(), &service.PreviewMessageRequest{CampaignID: 1, CustomerID: 1})
+	AssertNoError(t, err)
+	AssertEqual(t, preview.DeliveryEstimate == nil, true)
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// optionsSender succeeds and keeps the options of each send
+type optionsSender struct {
+	opts []service.SenderOptions
+}
+
+func (s *optionsSender) Send(channel models.Channel, phone string, content string, opts service.SenderOptions) *service.SendResult {
+	s.opts = append(s.opts, opts)
+	return &service.SendResult{Success: true}
+}
+
+func (s *optionsSender) Capabilities() []models.Channel {
+	return []models.Channel{models.ChannelSMS, models.ChannelWhatsApp}
+}
+
+// sendDemoCampaignMessage has the processor send one message of a campaign with a demo failure rate
+func sendDemoCampaignMessage(t *testing.T, demoOverrides bool) *optionsSender {
+	t.Helper()
+	sender := &optionsSender{}
+	f := newProcessingErrorFixture(t, sender)
+	rate := 0.4
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		message := NewTestMessageWithStatus(models.MessageStatusPending)
+		message.ID = id
+		campaign := NewTestCampaignWithStatus(models.CampaignStatusSending)
+		campaign.DemoFailureRate = &rate
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *message,
+			Campaign:        *campaign,
+			Customer:        *NewTestCustomer(),
+		}, nil
+	}
+	f.processor.SetDemoOverrides(demoOverrides)
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WithArgs(1, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	AssertNoError(t, f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1}))
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+	AssertEqual(t, len(sender.opts), 1)
+	return sender
+}
+
+// TestDemoFailureRate_PassedToSender tests that the campaign's rate reaches the sender when
+// demo overrides are enabled
+func TestDemoFailureRate_PassedToSender(t *testing.T) {
+	sender := sendDemoCampaignMessage(t, true)
+
+	AssertNotNil(t, sender.opts[0].FailureRate)
+	AssertEqual(t, *sender.opts[0].FailureRate, 0.4)
+}
+
+// TestDemoFailureRate_IgnoredWhenDisabled tests that the sender gets no override unless the
+// worker enabled demo overrides, as in production
+func TestDemoFailureRate_IgnoredWhenDisabled(t *testing.T) {
+	sender := sendDemoCampaignMessage(t, false)
+
+	AssertEqual(t, sender.opts[0].FailureRate == nil, true)
+}
+
+// TestDemoOverridesEnabled tests that demo overrides follow ENV and SENDER_PROVIDER
+func TestDemoOverridesEnabled(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Worker.SenderProvider, config.SenderProviderMock)
+	AssertEqual(t, cfg.DemoOverridesEnabled(), true)
+
+	t.Setenv("ENV", "staging")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.DemoOverridesEnabled(), true)
+
+	// Production ignores demo_failure_rate even with the mock provider
+	t.Setenv("ENV", "production")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.DemoOverridesEnabled(), false)
+
+	t.Setenv("SENDER_PROVIDER", "twilio")
+	_, err = config.Load()
+	AssertError(t, err, `SENDER_PROVIDER must be "mock"`)
+}
+
+// TestCreateCampaign_DemoFailureRate tests that the demo failure rate is stored at create and
+// must be between 0 and 1
+func TestCreateCampaign_DemoFailureRate(t *testing.T) {
+	svc, campaignRepo, _, _ := setupApprovalTest(t)
+
+	var created *models.Campaign
+	campaignRepo.CreateFunc = func(ctx context.Context, campaign *models.Campaign) error {
+		created = campaign
+		return nil
+	}
+
+	for _, rate := range []float64{0, 0.3, 1} {
+		rate := rate
+		_, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+			Name: "Demo", Channel: models.ChannelSMS, BaseTemplate: "Hi {first_name}", DemoFailureRate: &rate,
+		})
+		AssertNoError(t, err)
+		AssertEqual(t, *created.DemoFailureRate, rate)
+	}
+
+	for _, rate := range []float64{-0.1, 1.5} {
+		rate := rate
+		_, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+			Name: "Demo", Channel: models.ChannelSMS, BaseTemplate: "Hi {first_name}", DemoFailureRate: &rate,
+		})
+		AssertError(t, err, "validation error: demo_failure_rate must be between 0 and 1")
+	}
+}
@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestClaimUnpublished_DispatchQuery tests the order each dispatch policy claims messages in
+func TestClaimUnpublished_DispatchQuery(t *testing.T) {
+	before := time.Now()
+	tests := []struct {
+		name  string
+		order models.DispatchOrder
+		query string
+		args  []driver.Value
+	}{
+		{
+			name:  "fifo",
+			order: models.DispatchOrder{Policy: models.DispatchFIFO},
+			query: `SELECT m.id FROM outbound_messages m JOIN campaigns c ON c.id = m.campaign_id WHERE .+ ORDER BY m.created_at, m.id LIMIT \$2 FOR UPDATE OF m SKIP LOCKED`,
+			args:  []driver.Value{sqlmock.AnyArg(), 50},
+		},
+		{
+			name:  "fair",
+			order: models.DispatchOrder{Policy: models.DispatchFair, FairShare: 5},
+			query: `ROW_NUMBER\(\) OVER \(PARTITION BY m.campaign_id ORDER BY m.created_at, m.id\) as campaign_rank .+ WHERE ranked.campaign_rank <= \$3 ORDER BY ranked.campaign_rank, m.created_at, m.id LIMIT \$2 FOR UPDATE OF m SKIP LOCKED`,
+			args:  []driver.Value{sqlmock.AnyArg(), 50, 5},
+		},
+		{
+			name:  "newest campaign first",
+			order: models.DispatchOrder{Policy: models.DispatchNewestCampaignFirst},
+			query: `ORDER BY c.created_at DESC, c.id DESC, m.created_at, m.id LIMIT \$2 FOR UPDATE OF m SKIP LOCKED`,
+			args:  []driver.Value{sqlmock.AnyArg(), 50},
+		},
+		{
+			name:  "unset is fifo",
+			query: `ORDER BY m.created_at, m.id LIMIT \$2`,
+			args:  []driver.Value{sqlmock.AnyArg(), 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := NewMockDB(t)
+			defer db.Close()
+
+			mock.ExpectQuery(tt.query).
+				WithArgs(tt.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "customer_id"}).AddRow(1, 2, 3))
+
+			messages, err := repository.NewMessageRepository(db).ClaimUnpublished(context.Background(), before, 50, tt.order)
+			AssertNoError(t, err)
+			AssertEqual(t, len(messages), 1)
+			AssertNoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestPublishReconciler_DispatchOrder tests that the reconciler claims in the configured order
+func TestPublishReconciler_DispatchOrder(t *testing.T) {
+	messageRepo := NewMockMessageRepository()
+	var claimed models.DispatchOrder
+	messageRepo.ClaimUnpublishedFunc = func(ctx context.Context, before time.Time, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error) {
+		claimed = order
+		return nil, nil
+	}
+
+	reconciler := service.NewPublishReconciler(messageRepo)
+	reconciler.SetDispatchOrder(models.DispatchOrder{Policy: models.DispatchFair, FairShare: 4})
+	_, err := reconciler.Reconcile(context.Background(), func(message *models.OutboundMessage) error { return nil })
+	AssertNoError(t, err)
+	AssertEqual(t, claimed.Policy, models.DispatchFair)
+	AssertEqual(t, claimed.FairShare, 4)
+}
+
+// TestLoad_DispatchPolicy tests the dispatch policy default and that bad settings fail startup
+func TestLoad_DispatchPolicy(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Dispatch.Policy, models.DispatchFIFO)
+	AssertEqual(t, cfg.Dispatch.FairShare, 10)
+
+	t.Setenv("DISPATCH_POLICY", "fair")
+	t.Setenv("DISPATCH_FAIR_SHARE", "25")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Dispatch.Policy, models.DispatchFair)
+	AssertEqual(t, cfg.Dispatch.FairShare, 25)
+
+	t.Setenv("DISPATCH_FAIR_SHARE", "0")
+	_, err = config.Load()
+	AssertError(t, err, "DISPATCH_FAIR_SHARE must be positive")
+
+	t.Setenv("DISPATCH_POLICY", "lifo")
+	_, err = config.Load()
+	AssertError(t, err, "DISPATCH_POLICY must be fifo, fair or newest_campaign_first")
+}
+
+// TestQueueStatus_Dispatch tests that the admin queue status reports the active dispatch policy
+func TestQueueStatus_Dispatch(t *testing.T) {
+	f := newBackpressureFixture(t)
+
+	status, err := f.admission.Status(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, status.Dispatch.Policy, models.DispatchFIFO)
+
+	f.admission.SetDispatchOrder(models.DispatchOrder{Policy: models.DispatchFair, FairShare: 10})
+	status, err = f.admission.Status(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, status.Dispatch.Policy, models.DispatchFair)
+	AssertEqual(t, status.Dispatch.FairShare, 10)
+}
+
+// TestDispatchPolicy_Interleaving tests against a database the sequence each policy dispatches a
+// large older campaign's and a small newer campaign's messages in
+func TestDispatchPolicy_Interleaving(t *testing.T) {
+	_, msgRepo, campRepo, custRepo, _, cleanup := setupWorkerTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	customer := &models.Customer{Phone: "+254700000020", FirstName: StringPtr("Amina")}
+	AssertNoError(t, custRepo.Create(ctx, customer))
+
+	// Large (L) is created first with 20 messages, small (S) after it with 3
+	campaignMessages := func(name string, count int) *models.Campaign {
+		campaign := &models.Campaign{Name: name, Channel: models.ChannelSMS, Status: models.CampaignStatusSending, BaseTemplate: "Hi"}
+		AssertNoError(t, campRepo.Create(ctx, campaign))
+		messages := make([]*models.OutboundMessage, count)
+		for i := range messages {
+			messages[i] = &models.OutboundMessage{CampaignID: campaign.ID, CustomerID: customer.ID, Status: models.MessageStatusPending}
+		}
+		AssertNoError(t, msgRepo.CreateBatch(ctx, messages))
+		return campaign
+	}
+	large := campaignMessages("Large", 20)
+	small := campaignMessages("Small", 3)
+
+	sequence := func(messages []*models.OutboundMessage) string {
+		var b strings.Builder
+		for _, message := range messages {
+			if message.CampaignID == large.ID {
+				b.WriteString("L")
+			} else if message.CampaignID == small.ID {
+				b.WriteString("S")
+			}
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name  string
+		order models.DispatchOrder
+		want  string
+	}{
+		// The small campaign waits behind the whole large one
+		{"fifo", models.DispatchOrder{Policy: models.DispatchFIFO}, "LLLLLLLL"},
+		// Campaigns take turns, at most the fair share each
+		{"fair", models.DispatchOrder{Policy: models.DispatchFair, FairShare: 4}, "LSLSLSL"},
+		// The small campaign goes first in full
+		{"newest campaign first", models.DispatchOrder{Policy: models.DispatchNewestCampaignFirst}, "SSSLLLLL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending, err := msgRepo.GetPendingMessages(ctx, 8, tt.order)
+			AssertNoError(t, err)
+			AssertEqual(t, sequence(pending), tt.want)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// setupDuplicateContentTest creates a campaign service that blocks a send when more than 10% of the
+// audience got the same content in the last 24h
+func setupDuplicateContentTest(t *testing.T) (*service.CampaignService, *MockMessageRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	svc, _, messageRepo, mock := setupApprovalTest(t)
+	svc.SetDuplicateContent(config.DuplicateContentConfig{Window: 24 * time.Hour, Threshold: 0.1})
+	return svc, messageRepo, mock
+}
+
+// TestContentFingerprint tests that cosmetic template edits keep the fingerprint and real changes do not
+func TestContentFingerprint(t *testing.T) {
+	campaign := NewTestCampaign()
+	fingerprint := campaign.ContentFingerprint()
+	AssertEqual(t, len(fingerprint), 64)
+
+	edited := NewTestCampaign()
+	edited.ID = 2
+	edited.Name = "Copy of Test Campaign"
+	edited.BaseTemplate = "  hello {first_name},\nwelcome   to {preferred_product}! "
+	AssertEqual(t, edited.ContentFingerprint(), fingerprint)
+
+	reworded := NewTestCampaign()
+	reworded.BaseTemplate = "Hello {first_name}, welcome back to {preferred_product}!"
+	if reworded.ContentFingerprint() == fingerprint {
+		t.Error("Expected a different template to change the fingerprint")
+	}
+
+	whatsapp := NewTestCampaign()
+	whatsapp.Channel = models.ChannelWhatsApp
+	if whatsapp.ContentFingerprint() == fingerprint {
+		t.Error("Expected a different channel to change the fingerprint")
+	}
+}
+
+// TestDuplicateContent_OverlappingAudienceBlocked tests that a send is refused when the audience already got the content
+func TestDuplicateContent_OverlappingAudienceBlocked(t *testing.T) {
+	svc, messageRepo, _ := setupDuplicateContentTest(t)
+
+	var fingerprint string
+	var exclude int
+	var since time.Time
+	messageRepo.CountRecentFingerprintRecipientsFunc = func(ctx context.Context, ids []int, fp string, excludeCampaignID int, s time.Time) (int, error) {
+		fingerprint, exclude, since = fp, excludeCampaignID, s
+		return 2, nil
+	}
+
+	_, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertError(t, err, "2 of 2 customers already received this content from another campaign in the last 24h; set allow_duplicate_content to send anyway")
+
+	var duplicateErr *service.DuplicateContentError
+	AssertEqual(t, errors.As(err, &duplicateErr), true)
+	AssertEqual(t, duplicateErr.Recipients, 2)
+	AssertEqual(t, fingerprint, NewTestCampaign().ContentFingerprint())
+	AssertEqual(t, exclude, 1)
+	if age := time.Since(since); age < 24*time.Hour || age > 24*time.Hour+time.Minute {
+		t.Errorf("Expected the window to start 24h ago, got %v", age)
+	}
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 0)
+}
+
+// TestDuplicateContent_DisjointAudienceSends tests that a fresh audience is sent to with the fingerprint stored
+func TestDuplicateContent_DisjointAudienceSends(t *testing.T) {
+	svc, messageRepo, mock := setupDuplicateContentTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var created []*models.OutboundMessage
+	messageRepo.CreateBatchFunc = func(ctx context.Context, messages []*models.OutboundMessage) error {
+		created = messages
+		return nil
+	}
+
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Status, models.CampaignStatusSending)
+	AssertEqual(t, messageRepo.Calls["CountRecentFingerprintRecipients"], 1)
+	AssertEqual(t, len(created), 2)
+	for _, message := range created {
+		AssertNotNil(t, message.ContentFingerprint)
+		AssertEqual(t, *message.ContentFingerprint, NewTestCampaign().ContentFingerprint())
+	}
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestDuplicateContent_Threshold tests that overlap up to the threshold fraction is tolerated
+func TestDuplicateContent_Threshold(t *testing.T) {
+	audience := make([]int, 20)
+	for i := range audience {
+		audience[i] = i + 1
+	}
+
+	tests := []struct {
+		overlap int
+		blocked bool
+	}{
+		{0, false},
+		{2, false},
+		{3, true},
+	}
+
+	for _, tt := range tests {
+		svc, messageRepo, _ := setupDuplicateContentTest(t)
+		messageRepo.CountRecentFingerprintRecipientsFunc = func(ctx context.Context, ids []int, fp string, excludeCampaignID int, since time.Time) (int, error) {
+			return tt.overlap, nil
+		}
+
+		// 20 customers is above the approval threshold, so an allowed send waits for approval
+		result, err := svc.SendCampaign(context.Background(), 1, audience, service.SendOptions{})
+		if tt.blocked {
+			AssertContains(t, err.Error(), "3 of 20 customers already received this content")
+			continue
+		}
+		AssertNoError(t, err)
+		AssertEqual(t, result.Status, models.CampaignStatusPendingApproval)
+	}
+}
+
+// TestDuplicateContent_Skipped tests that the check is not run when allowed or disabled
+func TestDuplicateContent_Skipped(t *testing.T) {
+	svc, messageRepo, mock := setupDuplicateContentTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	messageRepo.CountRecentFingerprintRecipientsFunc = func(ctx context.Context, ids []int, fp string, excludeCampaignID int, since time.Time) (int, error) {
+		return len(ids), nil
+	}
+
+	_, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{AllowDuplicateContent: true})
+	AssertNoError(t, err)
+	AssertEqual(t, messageRepo.Calls["CountRecentFingerprintRecipients"], 0)
+
+	// A service without the check configured never looks
+	svc, messageRepo, mock = setupDuplicateContentTest(t)
+	svc.SetDuplicateContent(config.DuplicateContentConfig{})
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	_, err = svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, messageRepo.Calls["CountRecentFingerprintRecipients"], 0)
+}
+
+// TestDuplicateContent_Endpoint tests the 422 response and the allow_duplicate_content override over HTTP
+func TestDuplicateContent_Endpoint(t *testing.T) {
+	svc, messageRepo, mock := setupDuplicateContentTest(t)
+	messageRepo.CountRecentFingerprintRecipientsFunc = func(ctx context.Context, ids []int, fp string, excludeCampaignID int, since time.Time) (int, error) {
+		return 1, nil
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/send", handler.NewCampaignHandler(svc).Send).Methods("POST")
+
+	req := httptest.NewRequest("POST", "/campaigns/1/send", strings.NewReader(`{"customer_ids": [1, 2]}`))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusUnprocessableEntity)
+	var errResp handler.ErrorResponse
+	ParseJSONResponse(t, resp, &errResp)
+	AssertEqual(t, errResp.Error.Code, "DUPLICATE_CONTENT")
+	AssertContains(t, errResp.Error.Message, "1 of 2 customers already received this content")
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	req = httptest.NewRequest("POST", "/campaigns/1/send", strings.NewReader(`{"customer_ids": [1, 2], "allow_duplicate_content": true}`))
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCountRecentFingerprintRecipients_Query tests the EXISTS overlap query
+func TestCountRecentFingerprintRecipients_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	since := time.Now().Add(-24 * time.Hour)
+	fingerprint := NewTestCampaign().ContentFingerprint()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM unnest\(\$1::int\[\]\) AS target\(customer_id\) WHERE EXISTS \( SELECT 1 FROM outbound_messages m WHERE m.customer_id = target.customer_id AND m.content_fingerprint = \$2 AND m.campaign_id <> \$3 AND m.created_at >= \$4 \)`).
+		WithArgs("{1,2,3}", fingerprint, 7, since).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	messageRepo := repository.NewMessageRepository(db)
+	count, err := messageRepo.CountRecentFingerprintRecipients(context.Background(), []int{1, 2, 3}, fingerprint, 7, since)
+	AssertNoError(t, err)
+	AssertEqual(t, count, 2)
+
+	// No audience needs no query
+	count, err = messageRepo.CountRecentFingerprintRecipients(context.Background(), nil, fingerprint, 7, since)
+	AssertNoError(t, err)
+	AssertEqual(t, count, 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestLoadDuplicateContent tests DUPLICATE_CONTENT_WINDOW and DUPLICATE_CONTENT_THRESHOLD parsing
+func TestLoadDuplicateContent(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Duplicate.Window, 24*time.Hour)
+	AssertEqual(t, cfg.Duplicate.Threshold, 0.1)
+
+	t.Setenv("DUPLICATE_CONTENT_WINDOW", "90m")
+	t.Setenv("DUPLICATE_CONTENT_THRESHOLD", "0")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Duplicate.Window, 90*time.Minute)
+	AssertEqual(t, cfg.Duplicate.Threshold, 0.0)
+
+	t.Setenv("DUPLICATE_CONTENT_WINDOW", "-1h")
+	_, err = config.Load()
+	AssertError(t, err, "DUPLICATE_CONTENT_WINDOW cannot be negative")
+
+	t.Setenv("DUPLICATE_CONTENT_WINDOW", "24h")
+	t.Setenv("DUPLICATE_CONTENT_THRESHOLD", "1.5")
+	_, err = config.Load()
+	AssertError(t, err, "DUPLICATE_CONTENT_THRESHOLD must be between 0 and 1")
+}
@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// recordedSend is a message the duplicate send fixture holds as sent
+type recordedSend struct {
+	messageID   int
+	customerID  int
+	fingerprint string
+	sentAt      time.Time
+}
+
+// duplicateSendFixture is a processor with a 6h duplicate send window whose message repository
+// serves one message per campaign to customer 1 and answers the duplicate check from sends
+type duplicateSendFixture struct {
+	processor   *service.MessageProcessor
+	messageRepo *MockMessageRepository
+	mock        sqlmock.Sqlmock
+	sender      *countingSender
+	campaigns   map[int]*models.Campaign
+	sends       []recordedSend
+	now         time.Time
+}
+
+func newDuplicateSendFixture(t *testing.T) *duplicateSendFixture {
+	t.Helper()
+
+	f := &duplicateSendFixture{
+		messageRepo: NewMockMessageRepository(),
+		sender:      &countingSender{},
+		campaigns:   make(map[int]*models.Campaign),
+		now:         time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC),
+	}
+	// Message IDs are campaign IDs, so each campaign has one message, to customer 1
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		campaign, ok := f.campaigns[id]
+		if !ok {
+			return nil, repository.ErrMessageNotFound
+		}
+		message := NewTestMessage(campaign.ID, 1)
+		message.ID = id
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *message,
+			Campaign:        *campaign,
+			Customer:        *NewTestCustomer(),
+		}, nil
+	}
+	f.messageRepo.HasRecentFingerprintSendFunc = func(ctx context.Context, customerID int, fingerprint string, excludeMessageID int, since time.Time) (bool, error) {
+		for _, send := range f.sends {
+			if send.customerID == customerID && send.fingerprint == fingerprint && send.messageID != excludeMessageID && !send.sentAt.Before(since) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	f.processor, f.mock = NewMockMessageProcessor(t, f.messageRepo, f.sender)
+	f.processor.SetClock(func() time.Time { return f.now })
+	f.processor.SetDuplicateSendWindow(6 * time.Hour)
+	return f
+}
+
+// addCampaign adds a sending campaign with the given template
+func (f *duplicateSendFixture) addCampaign(id int, template string) *models.Campaign {
+	campaign := NewTestCampaignWithStatus(models.CampaignStatusSending)
+	campaign.ID = id
+	campaign.BaseTemplate = template
+	f.campaigns[id] = campaign
+	return campaign
+}
+
+// recordSent records campaign's message to customer 1 as sent at sentAt
+func (f *duplicateSendFixture) recordSent(campaign *models.Campaign, sentAt time.Time) {
+	f.sends = append(f.sends, recordedSend{messageID: campaign.ID, customerID: 1, fingerprint: campaign.ContentFingerprint(), sentAt: sentAt})
+}
+
+// TestWorker_SkipsDuplicateContentAcrossCampaigns tests that a customer sent a template by one
+// campaign is not sent the same template by another campaign within the window
+func TestWorker_SkipsDuplicateContentAcrossCampaigns(t *testing.T) {
+	f := newDuplicateSendFixture(t)
+	first := f.addCampaign(1, "Hi {first_name}, our sale ends Sunday")
+	f.addCampaign(2, "Hi {first_name},  our SALE ends Sunday")
+	f.recordSent(first, f.now.Add(-2*time.Hour))
+
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'skipped_duplicate_content'").
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 2, CampaignID: 2, CustomerID: 1})
+	AssertNoError(t, err)
+	AssertEqual(t, f.sender.calls, 0)
+	AssertEqual(t, f.messageRepo.Calls["HasRecentFingerprintSend"], 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestWorker_SendsDifferingContentAcrossCampaigns tests that another campaign's send of a
+// different template does not stop the message
+func TestWorker_SendsDifferingContentAcrossCampaigns(t *testing.T) {
+	f := newDuplicateSendFixture(t)
+	first := f.addCampaign(1, "Hi {first_name}, our sale ends Sunday")
+	f.addCampaign(2, "Hi {first_name}, new stock arrives Monday")
+	f.recordSent(first, f.now.Add(-2*time.Hour))
+
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 2, CampaignID: 2, CustomerID: 1})
+	AssertNoError(t, err)
+	AssertEqual(t, f.sender.calls, 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestWorker_SendsDuplicateContentOutsideWindow tests that a send of the same template before the
+// window does not stop the message
+func TestWorker_SendsDuplicateContentOutsideWindow(t *testing.T) {
+	f := newDuplicateSendFixture(t)
+	first := f.addCampaign(1, "Hi {first_name}, our sale ends Sunday")
+	f.addCampaign(2, "Hi {first_name}, our sale ends Sunday")
+	f.recordSent(first, f.now.Add(-7*time.Hour))
+
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 2, CampaignID: 2, CustomerID: 1})
+	AssertNoError(t, err)
+	AssertEqual(t, f.sender.calls, 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestWorker_DuplicateSendWindowDisabled tests that a zero window sends without checking
+func TestWorker_DuplicateSendWindowDisabled(t *testing.T) {
+	f := newDuplicateSendFixture(t)
+	f.processor.SetDuplicateSendWindow(0)
+	first := f.addCampaign(1, "Hi {first_name}, our sale ends Sunday")
+	f.addCampaign(2, "Hi {first_name}, our sale ends Sunday")
+	f.recordSent(first, f.now.Add(-time.Hour))
+
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 2, CampaignID: 2, CustomerID: 1})
+	AssertNoError(t, err)
+	AssertEqual(t, f.sender.calls, 1)
+	AssertEqual(t, f.messageRepo.Calls["HasRecentFingerprintSend"], 0)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestWorker_DuplicateSendCheckFails tests that a failed check returns the error for a retry
+// without sending
+func TestWorker_DuplicateSendCheckFails(t *testing.T) {
+	f := newDuplicateSendFixture(t)
+	f.addCampaign(2, "Hi {first_name}, our sale ends Sunday")
+	f.messageRepo.HasRecentFingerprintSendFunc = func(ctx context.Context, customerID int, fingerprint string, excludeMessageID int, since time.Time) (bool, error) {
+		return false, errors.New("failed to check for a recent duplicate send: connection refused")
+	}
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 2, CampaignID: 2, CustomerID: 1})
+	AssertError(t, err, "failed to check for a recent duplicate send: connection refused")
+	AssertEqual(t, f.sender.calls, 0)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestHasRecentFingerprintSend_Query tests the lookup of a recent send of the same content
+func TestHasRecentFingerprintSend_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	since := time.Date(2026, 5, 4, 6, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT EXISTS \( SELECT 1 FROM outbound_messages WHERE customer_id = \$1 AND content_fingerprint = \$2 AND updated_at >= \$3 AND status = 'sent' AND id <> \$4 \)`).
+		WithArgs(1, "abc123", since, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := repository.NewMessageRepository(db).HasRecentFingerprintSend(context.Background(), 1, "abc123", 2, since)
+	AssertNoError(t, err)
+	AssertEqual(t, exists, true)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetStatsByIDs_SkippedDuplicateContent tests that messages skipped as duplicates are counted in stats
+func TestGetStatsByIDs_SkippedDuplicateContent(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`COUNT\(\*\) FILTER \(WHERE status = 'skipped_duplicate_content'\) as skipped_duplicate_content`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"campaign_id", "total", "pending", "sent", "failed", "queued", "unpublished", "simulated", "p95_queue_latency", "audience", "first_message_at", "last_finished_at", "skipped_duplicate_content", "expired"}).
+			AddRow(2, 5, 0, 3, 0, 0, 0, 0, nil, 5, nil, nil, 2, 0))
+
+	stats, err := repository.NewCampaignRepository(db).GetStatsByIDs(context.Background(), []int{2})
+	AssertNoError(t, err)
+	AssertEqual(t, stats[2].Sent, 3)
+	AssertEqual(t, stats[2].SkippedDuplicateContent, 2)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestMessageStatuses_FitStatusColumn tests that every message status fits outbound_messages.status
+// as the migrations last declared it, which sqlmock tests cannot catch
+func TestMessageStatuses_FitStatusColumn(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.sql"))
+	AssertNoError(t, err)
+
+	// The last migration declaring the column's type wins; files sort in version order
+	declared := regexp.MustCompile(`(?s)(?:CREATE TABLE IF NOT EXISTS outbound_messages \(.*?\bstatus|ALTER TABLE outbound_messages ALTER COLUMN status TYPE) VARCHAR\((\d+)\)`)
+	width := 0
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		AssertNoError(t, err)
+		for _, match := range declared.FindAllStringSubmatch(string(content), -1) {
+			width, _ = strconv.Atoi(match[1])
+		}
+	}
+	AssertEqual(t, width, 32)
+
+	for _, status := range []models.MessageStatus{
+		models.MessageStatusPending, models.MessageStatusSent, models.MessageStatusFailed,
+		models.MessageStatusCancelled, models.MessageStatusSkippedDuplicateContent, models.MessageStatusExpired,
+	} {
+		if len(status) > width {
+			t.Errorf("status %q is longer than VARCHAR(%d)", status, width)
+		}
+	}
+}
+
+// TestLoadDuplicateSendWindow tests DUPLICATE_SEND_WINDOW parsing
+func TestLoadDuplicateSendWindow(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Duplicate.SendWindow, 6*time.Hour)
+
+	t.Setenv("DUPLICATE_SEND_WINDOW", "0")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Duplicate.SendWindow, time.Duration(0))
+
+	t.Setenv("DUPLICATE_SEND_WINDOW", "-1h")
+	_, err = config.Load()
+	AssertError(t, err, "DUPLICATE_SEND_WINDOW cannot be negative")
+}
@@ -0,0 +1,306 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/crypto"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// vectorKey is bytes 0x00..0x1f; the vectors below were sealed with nonce 0x000102030405060708090a0b
+const vectorKey = "2025-01:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+
+// otherKey is a second key for rotation tests
+const otherKey = "2025-06:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+// newTestKeyring builds a keyring from key specs; the first is current
+func newTestKeyring(t *testing.T, specs ...string) *crypto.Keyring {
+	t.Helper()
+
+	keys := make([]crypto.Key, len(specs))
+	for i, spec := range specs {
+		key, err := crypto.ParseKey(spec)
+		AssertNoError(t, err)
+		keys[i] = key
+	}
+
+	keyring, err := crypto.NewKeyring(keys[0], keys[1:]...)
+	AssertNoError(t, err)
+	return keyring
+}
+
+// encryptedAs matches a query argument that decrypts to the expected plaintext under the current key
+type encryptedAs struct {
+	keyring   *crypto.Keyring
+	plaintext string
+}
+
+func (e encryptedAs) Match(v driver.Value) bool {
+	value, ok := v.(string)
+	if !ok || e.keyring.NeedsRotation(value) {
+		return false
+	}
+	plaintext, err := e.keyring.Decrypt(value)
+	return err == nil && plaintext == e.plaintext
+}
+
+// TestCrypto_Vectors tests decryption of values sealed independently with known key and nonce
+func TestCrypto_Vectors(t *testing.T) {
+	keyring := newTestKeyring(t, vectorKey)
+
+	vectors := []struct {
+		sealed    string
+		plaintext string
+	}{
+		{"enc:v1:2025-01:AAECAwQFBgcICQoLD2v2UaSLpzetOPj+w8kXH+ez9RSZCH8OXQaB/NRpK4ks6BbjPeuvMLV7HqA=", "Hi Jane, your order is ready"},
+		{"enc:v1:2025-01:AAECAwQFBgcICQoL4Ymg46tVdDH7wcY3oWqpvA==", ""},
+	}
+
+	for _, v := range vectors {
+		plaintext, err := keyring.Decrypt(v.sealed)
+		AssertNoError(t, err)
+		AssertEqual(t, plaintext, v.plaintext)
+	}
+}
+
+// TestCrypto_RoundTrip tests encryption format, random nonces and plaintext passthrough
+func TestCrypto_RoundTrip(t *testing.T) {
+	keyring := newTestKeyring(t, vectorKey)
+
+	first, err := keyring.Encrypt("Hi {first_name} ✓")
+	AssertNoError(t, err)
+	second, err := keyring.Encrypt("Hi {first_name} ✓")
+	AssertNoError(t, err)
+
+	AssertContains(t, first, "enc:v1:2025-01:")
+	if first == second {
+		t.Error("Expected a fresh nonce for every encryption")
+	}
+
+	plaintext, err := keyring.Decrypt(first)
+	AssertNoError(t, err)
+	AssertEqual(t, plaintext, "Hi {first_name} ✓")
+
+	// Rows written before encryption was enabled read back unchanged
+	plaintext, err = keyring.Decrypt("Hello Ann")
+	AssertNoError(t, err)
+	AssertEqual(t, plaintext, "Hello Ann")
+	AssertEqual(t, crypto.IsEncrypted("Hello Ann"), false)
+}
+
+// TestCrypto_Tampering tests that modified ciphertext and relabeled key IDs are rejected
+func TestCrypto_Tampering(t *testing.T) {
+	keyring := newTestKeyring(t, vectorKey, otherKey)
+	sealed := "enc:v1:2025-01:AAECAwQFBgcICQoLD2v2UaSLpzetOPj+w8kXH+ez9RSZCH8OXQaB/NRpK4ks6BbjPeuvMLV7HqA="
+
+	tampered := strings.Replace(sealed, "D2v2", "D2v3", 1)
+	_, err := keyring.Decrypt(tampered)
+	AssertContains(t, err.Error(), "failed authentication")
+
+	// The key ID is authenticated, so pointing a value at another key fails too
+	relabeled := strings.Replace(sealed, "2025-01", "2025-06", 1)
+	_, err = keyring.Decrypt(relabeled)
+	AssertContains(t, err.Error(), "failed authentication")
+
+	_, err = keyring.Decrypt("enc:v1:retired:AAAA")
+	if !errors.Is(err, crypto.ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey but got %v", err)
+	}
+
+	_, err = keyring.Decrypt("enc:v1:2025-01:AAAA")
+	AssertError(t, err, "encrypted value is too short")
+}
+
+// TestCrypto_Rotation tests decrypting under an old key and detecting rows to rewrite
+func TestCrypto_Rotation(t *testing.T) {
+	old := newTestKeyring(t, vectorKey)
+	sealedOld, err := old.Encrypt("Your code is 4821")
+	AssertNoError(t, err)
+
+	rotated := newTestKeyring(t, otherKey, vectorKey)
+	AssertEqual(t, rotated.CurrentKeyID(), "2025-06")
+
+	plaintext, err := rotated.Decrypt(sealedOld)
+	AssertNoError(t, err)
+	AssertEqual(t, plaintext, "Your code is 4821")
+
+	sealedNew, err := rotated.Encrypt(plaintext)
+	AssertNoError(t, err)
+	AssertContains(t, sealedNew, "enc:v1:2025-06:")
+
+	AssertEqual(t, rotated.NeedsRotation(sealedOld), true)
+	AssertEqual(t, rotated.NeedsRotation("plaintext"), true)
+	AssertEqual(t, rotated.NeedsRotation(sealedNew), false)
+
+	// Once the old key is dropped its rows can no longer be read
+	_, err = newTestKeyring(t, otherKey).Decrypt(sealedOld)
+	if !errors.Is(err, crypto.ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey but got %v", err)
+	}
+}
+
+// TestCrypto_ParseKey tests key spec validation
+func TestCrypto_ParseKey(t *testing.T) {
+	key, err := crypto.ParseKey(" " + vectorKey + " ")
+	AssertNoError(t, err)
+	AssertEqual(t, key.ID, "2025-01")
+	AssertEqual(t, len(key.Secret), crypto.KeySize)
+
+	invalid := map[string]string{
+		"AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=":        "must be written as",
+		"bad id:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=": "must be 1-32 letters",
+		"k1:not-base64!":              "not valid base64",
+		"k1:AAECAwQFBgcICQoLDA0ODw==": "is 16 bytes, must be 32",
+	}
+	for spec, message := range invalid {
+		_, err := crypto.ParseKey(spec)
+		if err == nil {
+			t.Errorf("Expected error for %q", spec)
+			continue
+		}
+		AssertContains(t, err.Error(), message)
+	}
+
+	key, _ = crypto.ParseKey(vectorKey)
+	_, err = crypto.NewKeyring(key, key)
+	AssertContains(t, err.Error(), "used more than once")
+}
+
+// TestLoadEncryptionKeys tests MESSAGE_ENCRYPTION_KEY and MESSAGE_ENCRYPTION_OLD_KEYS parsing
+func TestLoadEncryptionKeys(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	if cfg.Encryption.Keyring != nil {
+		t.Error("Expected encryption to be disabled without a key")
+	}
+
+	t.Setenv("MESSAGE_ENCRYPTION_KEY", otherKey)
+	t.Setenv("MESSAGE_ENCRYPTION_OLD_KEYS", vectorKey)
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Encryption.Keyring.CurrentKeyID(), "2025-06")
+
+	plaintext, err := cfg.Encryption.Keyring.Decrypt("enc:v1:2025-01:AAECAwQFBgcICQoL4Ymg46tVdDH7wcY3oWqpvA==")
+	AssertNoError(t, err)
+	AssertEqual(t, plaintext, "")
+
+	t.Setenv("MESSAGE_ENCRYPTION_KEY", "")
+	_, err = config.Load()
+	AssertError(t, err, "MESSAGE_ENCRYPTION_OLD_KEYS requires MESSAGE_ENCRYPTION_KEY")
+
+	t.Setenv("MESSAGE_ENCRYPTION_KEY", "k1:short")
+	t.Setenv("MESSAGE_ENCRYPTION_OLD_KEYS", "")
+	_, err = config.Load()
+	AssertContains(t, err.Error(), "MESSAGE_ENCRYPTION_KEY is invalid")
+}
+
+// TestEncryptedMessageRepository_WriteAndRead tests encrypt-on-write and decrypt-on-read
+func TestEncryptedMessageRepository_WriteAndRead(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	keyring := newTestKeyring(t, vectorKey)
+	messageRepo := repository.NewEncryptedMessageRepository(db, nil, keyring)
+	content := "Hi Jane, your order is ready"
+
+	mock.ExpectQuery("INSERT INTO outbound_messages").
+		WithArgs(1, 2, models.MessageStatusPending, encryptedAs{keyring: keyring, plaintext: content}, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(7, time.Now(), time.Now()))
+
+	message := &models.OutboundMessage{CampaignID: 1, CustomerID: 2, Status: models.MessageStatusPending, RenderedContent: &content}
+	AssertNoError(t, messageRepo.Create(context.Background(), message))
+
+	// The caller's message keeps its plaintext
+	AssertEqual(t, *message.RenderedContent, content)
+
+	sealed := "enc:v1:2025-01:AAECAwQFBgcICQoLD2v2UaSLpzetOPj+w8kXH+ez9RSZCH8OXQaB/NRpK4ks6BbjPeuvMLV7HqA="
+	mock.ExpectQuery("SELECT (.+) FROM outbound_messages WHERE id").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(messageColumns).
+			AddRow(7, 1, 2, "pending", sealed, nil, 0, nil, time.Now(), time.Now()))
+
+	fetched, err := messageRepo.GetByID(context.Background(), 7)
+	AssertNoError(t, err)
+	AssertEqual(t, *fetched.RenderedContent, content)
+
+	// Rows not yet backfilled are still readable
+	mock.ExpectQuery("SELECT (.+) FROM outbound_messages WHERE campaign_id").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(messageColumns).
+			AddRow(8, 1, 3, "sent", "Hello Ann", nil, 0, nil, time.Now(), time.Now()).
+			AddRow(7, 1, 2, "pending", sealed, nil, 0, nil, time.Now(), time.Now()))
+
+	messages, err := messageRepo.GetByCampaignID(context.Background(), 1)
+	AssertNoError(t, err)
+	AssertEqual(t, *messages[0].RenderedContent, "Hello Ann")
+	AssertEqual(t, *messages[1].RenderedContent, content)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestMessageRepository_EncryptedRowWithoutKey tests that ciphertext is never returned as content
+func TestMessageRepository_EncryptedRowWithoutKey(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM outbound_messages WHERE id").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(messageColumns).
+			AddRow(7, 1, 2, "pending", "enc:v1:2025-01:AAECAwQFBgcICQoL4Ymg46tVdDH7wcY3oWqpvA==", nil, 0, nil, time.Now(), time.Now()))
+
+	_, err := repository.NewMessageRepository(db).GetByID(context.Background(), 7)
+	AssertError(t, err, "message 7 content is encrypted but MESSAGE_ENCRYPTION_KEY is not set")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestReencryptContent tests that a backfill batch encrypts plaintext and rotates old-key rows
+func TestReencryptContent(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	old := newTestKeyring(t, vectorKey)
+	oldSealed, err := old.Encrypt("Your code is 4821")
+	AssertNoError(t, err)
+
+	keyring := newTestKeyring(t, otherKey, vectorKey)
+	messageRepo := repository.NewEncryptedMessageRepository(db, nil, keyring)
+
+	mock.ExpectQuery("SELECT id, rendered_content FROM outbound_messages WHERE id > (.+) AND rendered_content NOT LIKE").
+		WithArgs(100, "enc:v1:2025-06:%", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rendered_content"}).
+			AddRow(101, "Hello Ann").
+			AddRow(104, oldSealed).
+			AddRow(105, "Hello Bob"))
+
+	// updated_at is not touched so latency stats stay correct
+	mock.ExpectExec(`UPDATE outbound_messages SET rendered_content = \$2 WHERE id = \$1 AND rendered_content = \$3`).
+		WithArgs(101, encryptedAs{keyring: keyring, plaintext: "Hello Ann"}, "Hello Ann").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE outbound_messages").
+		WithArgs(104, encryptedAs{keyring: keyring, plaintext: "Your code is 4821"}, oldSealed).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Row 105 changed since it was read, so the compare-and-set skips it
+	mock.ExpectExec("UPDATE outbound_messages").
+		WithArgs(105, encryptedAs{keyring: keyring, plaintext: "Hello Bob"}, "Hello Bob").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	batch, err := messageRepo.ReencryptContent(context.Background(), 100, 3)
+	AssertNoError(t, err)
+	AssertEqual(t, batch.Scanned, 3)
+	AssertEqual(t, batch.Updated, 2)
+	AssertEqual(t, batch.LastID, 105)
+	AssertNoError(t, mock.ExpectationsWereMet())
+
+	_, err = repository.NewMessageRepository(db).ReencryptContent(context.Background(), 0, 10)
+	AssertError(t, err, "message encryption is not configured")
+}
@@ -0,0 +1,339 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// engagementNow is when the engagement tests score customers
+var engagementNow = time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+// testEngagementWeights are the default weights, scoring two customers per batch
+var testEngagementWeights = config.EngagementConfig{
+	SentWeight:     1,
+	HalfLife:       30 * 24 * time.Hour,
+	FailurePenalty: 2,
+	Lookback:       180 * 24 * time.Hour,
+	BatchSize:      2,
+}
+
+// daysAgo returns the times the given number of days before engagementNow
+func daysAgo(days ...int) []time.Time {
+	times := make([]time.Time, len(days))
+	for i, d := range days {
+		times[i] = engagementNow.Add(-time.Duration(d) * 24 * time.Hour)
+	}
+	return times
+}
+
+// TestScoreEngagement_Weights tests the decay of sent messages and the failure penalty
+func TestScoreEngagement_Weights(t *testing.T) {
+	tests := []struct {
+		name    string
+		history models.EngagementHistory
+		want    float64
+	}{
+		{"no messages", models.EngagementHistory{}, 0},
+		{"sent just now", models.EngagementHistory{SentAt: daysAgo(0)}, 1},
+		{"sent one half-life ago", models.EngagementHistory{SentAt: daysAgo(30)}, 0.5},
+		{"sent two half-lives ago", models.EngagementHistory{SentAt: daysAgo(60)}, 0.25},
+		{"sends add up", models.EngagementHistory{SentAt: daysAgo(0, 30, 60)}, 1.75},
+		{"failures are penalised", models.EngagementHistory{SentAt: daysAgo(0), Failed: 2}, -3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AssertEqual(t, service.ScoreEngagement(&tt.history, testEngagementWeights, engagementNow), tt.want)
+		})
+	}
+
+	// Weights come from config
+	weights := testEngagementWeights
+	weights.SentWeight, weights.FailurePenalty = 10, 0
+	AssertEqual(t, service.ScoreEngagement(&models.EngagementHistory{SentAt: daysAgo(30), Failed: 5}, weights, engagementNow), 5.0)
+}
+
+// TestEngagementScorer_Orderings tests that scoring constructed histories ranks recent, clean
+// engagement first and failing customers last, batch by batch through every customer
+func TestEngagementScorer_Orderings(t *testing.T) {
+	const (
+		recentRegular = 1 // Sent three messages this week
+		lapsed        = 2 // Sent as many, months ago
+		failing       = 3 // As recent as the regular, but with two permanent failures
+		singleRecent  = 4 // One message today
+		silent        = 5 // Never messaged
+		ancient       = 6 // Only messaged before the lookback
+	)
+	repo := NewMockEngagementRepository(
+		&models.EngagementHistory{CustomerID: failing, SentAt: daysAgo(1, 3, 5), Failed: 2},
+		&models.EngagementHistory{CustomerID: recentRegular, SentAt: daysAgo(1, 3, 5)},
+		&models.EngagementHistory{CustomerID: lapsed, SentAt: daysAgo(100, 120, 150)},
+		&models.EngagementHistory{CustomerID: singleRecent, SentAt: daysAgo(0)},
+		&models.EngagementHistory{CustomerID: silent},
+		&models.EngagementHistory{CustomerID: ancient, SentAt: daysAgo(400)},
+	)
+	scorer := service.NewEngagementScorer(repo, testEngagementWeights)
+	scorer.SetClock(func() time.Time { return engagementNow })
+
+	scored, err := scorer.ScoreAll(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, scored, 6)
+	AssertEqual(t, repo.Calls["ListEngagementHistory"], 4) // Three batches of two, then an empty one
+	AssertEqual(t, repo.ScoredAt, engagementNow)
+
+	ids := []int{}
+	for id := range repo.Scores {
+		ids = append(ids, id)
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		if repo.Scores[ids[i]] != repo.Scores[ids[j]] {
+			return repo.Scores[ids[i]] > repo.Scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	want := []int{recentRegular, singleRecent, lapsed, silent, ancient, failing}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("Expected customers ranked %v, got %v (scores %v)", want, ids, repo.Scores)
+		}
+	}
+
+	AssertEqual(t, repo.Scores[silent], 0.0)
+	AssertEqual(t, repo.Scores[ancient], 0.0)
+	if repo.Scores[failing] >= 0 {
+		t.Fatalf("Expected failures to outweigh recent sends, got %v", repo.Scores[failing])
+	}
+}
+
+// TestEngagementScorer_ResumesAfterID tests that a batch starts after the given customer
+func TestEngagementScorer_ResumesAfterID(t *testing.T) {
+	repo := NewMockEngagementRepository(
+		&models.EngagementHistory{CustomerID: 10, SentAt: daysAgo(0)},
+		&models.EngagementHistory{CustomerID: 20, SentAt: daysAgo(0)},
+		&models.EngagementHistory{CustomerID: 30, SentAt: daysAgo(0)},
+	)
+	scorer := service.NewEngagementScorer(repo, testEngagementWeights)
+	scorer.SetClock(func() time.Time { return engagementNow })
+
+	batch, err := scorer.RunBatch(context.Background(), 10)
+	AssertNoError(t, err)
+	AssertEqual(t, batch.Scored, 2)
+	AssertEqual(t, batch.LastID, 30)
+	if _, ok := repo.Scores[10]; ok {
+		t.Fatal("Expected customer 10 to be skipped")
+	}
+
+	batch, err = scorer.RunBatch(context.Background(), batch.LastID)
+	AssertNoError(t, err)
+	AssertEqual(t, batch.Scored, 0)
+	AssertEqual(t, batch.LastID, 30)
+}
+
+// setupEngagementSendTest creates a campaign service with an approval threshold of 2 customers
+// and returns its customer repository
+func setupEngagementSendTest(t *testing.T) (*service.CampaignService, *MockCampaignRepository, *MockCustomerRepository, *MockMessageRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	campaignRepo := NewMockCampaignRepository()
+	customerRepo := NewMockCustomerRepository()
+	messageRepo := NewMockMessageRepository()
+
+	svc := service.NewCampaignService(
+		campaignRepo,
+		customerRepo,
+		messageRepo,
+		service.NewTemplateService(),
+		NewMockPublisher(),
+		db,
+		config.ApprovalConfig{RequiredAbove: 2},
+	)
+	return svc, campaignRepo, customerRepo, messageRepo, mock
+}
+
+// TestSendCampaign_MinEngagementScore tests that a send can target customers at or above a score
+func TestSendCampaign_MinEngagementScore(t *testing.T) {
+	svc, _, customerRepo, messageRepo, mock := setupEngagementSendTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var minScore float64
+	customerRepo.ListBelowScoreFunc = func(ctx context.Context, ids []int, min float64) ([]int, error) {
+		minScore = min
+		return []int{2}, nil
+	}
+	var queued []*models.OutboundMessage
+	messageRepo.CreateBatchFunc = func(ctx context.Context, messages []*models.OutboundMessage) error {
+		queued = messages
+		return nil
+	}
+
+	threshold := 1.5
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{MinEngagementScore: &threshold})
+	AssertNoError(t, err)
+	AssertEqual(t, minScore, 1.5)
+	AssertEqual(t, result.MessagesQueued, 1)
+	AssertEqual(t, result.SkippedLowEngagement, 1)
+	AssertEqual(t, queued[0].CustomerID, 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+
+	// Without a threshold scores are not checked
+	svc, _, customerRepo, _, mock = setupEngagementSendTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	_, err = svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, customerRepo.Calls["ListBelowScore"], 0)
+
+	// A send whose whole audience scores too low is refused
+	customerRepo.ListBelowScoreFunc = func(ctx context.Context, ids []int, min float64) ([]int, error) {
+		return ids, nil
+	}
+	_, err = svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{MinEngagementScore: &threshold})
+	AssertError(t, err, "business logic error: all 2 customers score below 1.5 or are not yet scored")
+}
+
+// TestSendCampaign_MinEngagementScoreApproval tests that the threshold is kept with a send
+// awaiting approval and checked again once approved
+func TestSendCampaign_MinEngagementScoreApproval(t *testing.T) {
+	svc, campaignRepo, customerRepo, _, mock := setupEngagementSendTest(t)
+
+	var savedPlan *models.SendPlan
+	campaignRepo.AwaitApprovalFunc = func(ctx context.Context, id int, from models.CampaignStatus, plan *models.SendPlan) error {
+		savedPlan = plan
+		return nil
+	}
+
+	threshold := 2.0
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2, 3, 4}, service.SendOptions{MinEngagementScore: &threshold})
+	AssertNoError(t, err)
+	AssertEqual(t, result.Status, models.CampaignStatusPendingApproval)
+	AssertEqual(t, *savedPlan.MinEngagementScore, 2.0)
+
+	// A customer's score dropped while the send waited
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusPendingApproval), nil
+	}
+	campaignRepo.GetSendPlanFunc = func(ctx context.Context, id int) (*models.SendPlan, error) {
+		return savedPlan, nil
+	}
+	customerRepo.ListBelowScoreFunc = func(ctx context.Context, ids []int, min float64) ([]int, error) {
+		AssertEqual(t, min, 2.0)
+		return []int{4}, nil
+	}
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	result, err = svc.ApproveCampaign(context.Background(), 1)
+	AssertNoError(t, err)
+	AssertEqual(t, result.MessagesQueued, 3)
+	AssertEqual(t, result.SkippedLowEngagement, 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCustomerHandler_ListMinScore tests GET /customers passes min_score through and shows scores
+func TestCustomerHandler_ListMinScore(t *testing.T) {
+	customerRepo := NewMockCustomerRepository()
+	var listed, counted repository.CustomerFilters
+	customerRepo.CountFilteredFunc = func(ctx context.Context, filters repository.CustomerFilters) (int, error) {
+		counted = filters
+		return 41, nil
+	}
+	var limit, offset int
+	customerRepo.ListFilteredFunc = func(ctx context.Context, filters repository.CustomerFilters, l, o int) ([]*models.Customer, error) {
+		listed, limit, offset = filters, l, o
+		customer := NewTestCustomer()
+		score := 3.25
+		customer.EngagementScore = &score
+		return []*models.Customer{customer}, nil
+	}
+	h := handler.NewCustomerHandler(service.NewCustomerService(customerRepo, config.LimitsConfig{}))
+
+	rr := httptest.NewRecorder()
+	h.List(rr, httptest.NewRequest("GET", "/customers?min_score=2.5&location=Nairobi&page=3&per_page=10", nil))
+	AssertStatusCode(t, rr, http.StatusOK)
+	AssertEqual(t, *listed.MinScore, 2.5)
+	AssertEqual(t, *counted.MinScore, 2.5)
+	AssertEqual(t, listed.Location, "Nairobi")
+	AssertEqual(t, limit, 10)
+	AssertEqual(t, offset, 20)
+
+	var response struct {
+		Customers []struct {
+			EngagementScore *float64 `json:"engagement_score"`
+		} `json:"customers"`
+		Pagination service.PaginationInfo `json:"pagination"`
+	}
+	ParseJSONResponse(t, rr, &response)
+	AssertEqual(t, *response.Customers[0].EngagementScore, 3.25)
+	AssertEqual(t, response.Pagination.TotalPages, 5)
+
+	for _, bad := range []string{"high", "NaN", "Inf"} {
+		rr = httptest.NewRecorder()
+		h.List(rr, httptest.NewRequest("GET", "/customers?min_score="+bad, nil))
+		AssertStatusCode(t, rr, http.StatusBadRequest)
+	}
+}
+
+// TestCustomerRepository_EngagementQueries tests the min_score filter and below-score lookup
+func TestCustomerRepository_EngagementQueries(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewCustomerRepository(db)
+
+	mock.ExpectQuery(`SELECT id FROM customers WHERE id = ANY\(\$1\) AND \(engagement_score IS NULL OR engagement_score < \$2\)`).
+		WithArgs("{1,2,3}", 1.5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	below, err := repo.ListBelowScore(context.Background(), []int{1, 2, 3}, 1.5)
+	AssertNoError(t, err)
+	AssertEqual(t, len(below), 2)
+
+	minScore := 2.0
+	mock.ExpectQuery(`FROM customers WHERE 1=1 AND engagement_score >= \$1 ORDER BY id DESC LIMIT \$2 OFFSET \$3`).
+		WithArgs([]driver.Value{minScore, 20, 40}...).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "phone", "first_name", "last_name", "location", "preferred_product", "contact_window_start", "contact_window_end", "created_at",
+			"engagement_score", "engagement_scored_at",
+		}).AddRow(7, "+254700000007", nil, nil, nil, nil, nil, nil, engagementNow, 2.75, engagementNow))
+	customers, err := repo.ListFiltered(context.Background(), repository.CustomerFilters{MinScore: &minScore}, 20, 40)
+	AssertNoError(t, err)
+	AssertEqual(t, *customers[0].EngagementScore, 2.75)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestLoad_Engagement tests the engagement defaults and that bad weights fail startup
+func TestLoad_Engagement(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Engagement.SentWeight, 1.0)
+	AssertEqual(t, cfg.Engagement.HalfLife, 30*24*time.Hour)
+	AssertEqual(t, cfg.Engagement.FailurePenalty, 2.0)
+	AssertEqual(t, cfg.Engagement.Interval, time.Duration(0))
+	AssertEqual(t, cfg.Engagement.Lookback, 180*24*time.Hour)
+
+	t.Setenv("ENGAGEMENT_FAILURE_PENALTY", "-1")
+	_, err = config.Load()
+	AssertError(t, err, "ENGAGEMENT_SENT_WEIGHT and ENGAGEMENT_FAILURE_PENALTY cannot be negative")
+
+	t.Setenv("ENGAGEMENT_FAILURE_PENALTY", "2")
+	t.Setenv("ENGAGEMENT_HALF_LIFE", "0s")
+	_, err = config.Load()
+	AssertError(t, err, "ENGAGEMENT_HALF_LIFE and ENGAGEMENT_LOOKBACK must be positive")
+}
@@ -0,0 +1,252 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// TestProjectCompletion tests completion projections across rates and quiet-hour windows
+func TestProjectCompletion(t *testing.T) {
+	night := config.QuietHoursConfig{Enabled: true, Start: 21, End: 8, Location: time.UTC}
+	eat := time.FixedZone("EAT", 3*60*60)
+	earlyMorning := config.QuietHoursConfig{Enabled: true, Start: 1, End: 5, Location: eat}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		start      time.Time
+		pending    int
+		rate       float64
+		quiet      config.QuietHoursConfig
+		firstSend  time.Time
+		completion time.Time
+	}{
+		{
+			name:       "no quiet hours",
+			start:      at(1, 22, 0),
+			pending:    36000,
+			rate:       10,
+			quiet:      config.QuietHoursConfig{},
+			firstSend:  at(1, 22, 0),
+			completion: at(1, 23, 0),
+		},
+		{
+			name:       "finishes before quiet hours",
+			start:      at(1, 12, 0),
+			pending:    36000,
+			rate:       10,
+			quiet:      night,
+			firstSend:  at(1, 12, 0),
+			completion: at(1, 13, 0),
+		},
+		{
+			name:       "slower rate runs into quiet hours",
+			start:      at(1, 20, 0),
+			pending:    36000,
+			rate:       5,
+			quiet:      night,
+			firstSend:  at(1, 20, 0),
+			completion: at(2, 9, 0),
+		},
+		{
+			name:       "pauses overnight and resumes",
+			start:      at(1, 20, 30),
+			pending:    36000,
+			rate:       10,
+			quiet:      night,
+			firstSend:  at(1, 20, 30),
+			completion: at(2, 8, 30),
+		},
+		{
+			name:       "already inside quiet hours before midnight",
+			start:      at(1, 23, 0),
+			pending:    36000,
+			rate:       10,
+			quiet:      night,
+			firstSend:  at(2, 8, 0),
+			completion: at(2, 9, 0),
+		},
+		{
+			name:       "already inside quiet hours after midnight",
+			start:      at(2, 2, 0),
+			pending:    36000,
+			rate:       10,
+			quiet:      night,
+			firstSend:  at(2, 8, 0),
+			completion: at(2, 9, 0),
+		},
+		{
+			name:       "ends exactly when quiet hours begin",
+			start:      at(1, 20, 0),
+			pending:    36000,
+			rate:       10,
+			quiet:      night,
+			firstSend:  at(1, 20, 0),
+			completion: at(1, 21, 0),
+		},
+		{
+			name:       "spans several sending days",
+			start:      at(1, 8, 0),
+			pending:    10 * 3600 * 26,
+			rate:       10,
+			quiet:      night,
+			firstSend:  at(1, 8, 0),
+			completion: at(2, 21, 0),
+		},
+		{
+			name:       "quiet hours in another time zone",
+			start:      time.Date(2026, 3, 2, 0, 30, 0, 0, eat),
+			pending:    36000,
+			rate:       10,
+			quiet:      earlyMorning,
+			firstSend:  time.Date(2026, 3, 2, 0, 30, 0, 0, eat),
+			completion: time.Date(2026, 3, 2, 5, 30, 0, 0, eat),
+		},
+		{
+			name:       "nothing pending",
+			start:      at(1, 23, 0),
+			pending:    0,
+			rate:       10,
+			quiet:      night,
+			firstSend:  at(2, 8, 0),
+			completion: at(2, 8, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			firstSend, completion := service.ProjectCompletion(tt.start, tt.pending, tt.rate, tt.quiet)
+			if !firstSend.Equal(tt.firstSend) {
+				t.Errorf("Expected first send at %v but got %v", tt.firstSend, firstSend)
+			}
+			if !completion.Equal(tt.completion) {
+				t.Errorf("Expected completion at %v but got %v", tt.completion, completion)
+			}
+		})
+	}
+}
+
+// setupETATest creates a simulation service whose effective rate is 10 messages per second
+func setupETATest(quiet config.QuietHoursConfig) (*service.SimulationService, *MockCampaignRepository) {
+	campaignRepo := NewMockCampaignRepository()
+	svc := service.NewSimulationService(campaignRepo, NewMockCustomerRepository(), NewMockMessageRepository(), testSendingConfig(), quiet)
+	return svc, campaignRepo
+}
+
+// withStats makes the campaign repository return a campaign with the given status and pending count
+func withStats(campaignRepo *MockCampaignRepository, status models.CampaignStatus, scheduledAt *time.Time, pending int) {
+	campaignRepo.GetWithStatsFunc = func(ctx context.Context, id int) (*models.CampaignWithStats, error) {
+		campaign := NewTestCampaignWithStatus(status)
+		campaign.ScheduledAt = scheduledAt
+		return &models.CampaignWithStats{Campaign: *campaign, Stats: models.CampaignStats{Pending: pending}}, nil
+	}
+}
+
+// TestEstimateCompletion_Sending tests the ETA of a campaign that is sending
+func TestEstimateCompletion_Sending(t *testing.T) {
+	svc, campaignRepo := setupETATest(config.QuietHoursConfig{})
+	withStats(campaignRepo, models.CampaignStatusSending, nil, 3600)
+
+	before := time.Now()
+	eta, err := svc.EstimateCompletion(context.Background(), 1, 0)
+	AssertNoError(t, err)
+
+	AssertEqual(t, eta.Pending, 3600)
+	AssertEqual(t, eta.EffectiveRatePerSecond, 10.0)
+	AssertEqual(t, eta.QuietHoursDelaySeconds, 0.0)
+	AssertNotNil(t, eta.EstimatedCompletionAt)
+	if eta.NextDispatchAt != nil {
+		t.Error("Expected no next dispatch for a campaign already sending")
+	}
+	if remaining := eta.EstimatedCompletionAt.Sub(before); remaining < 6*time.Minute || remaining > 6*time.Minute+time.Second {
+		t.Errorf("Expected completion about 6 minutes away but got %v", remaining)
+	}
+}
+
+// TestEstimateCompletion_ScheduledInQuietHours tests that a send scheduled in quiet hours dispatches when they end
+func TestEstimateCompletion_ScheduledInQuietHours(t *testing.T) {
+	svc, campaignRepo := setupETATest(config.QuietHoursConfig{Enabled: true, Start: 21, End: 8, Location: time.UTC})
+
+	later := time.Now().UTC().AddDate(0, 0, 2)
+	scheduledAt := time.Date(later.Year(), later.Month(), later.Day(), 23, 0, 0, 0, time.UTC)
+	withStats(campaignRepo, models.CampaignStatusScheduled, &scheduledAt, 0)
+
+	eta, err := svc.EstimateCompletion(context.Background(), 1, 36000)
+	AssertNoError(t, err)
+
+	dispatch := scheduledAt.Add(9 * time.Hour)
+	AssertEqual(t, eta.Pending, 36000)
+	AssertEqual(t, eta.NextDispatchAt.Equal(dispatch), true)
+	AssertEqual(t, eta.EstimatedCompletionAt.Equal(dispatch.Add(time.Hour)), true)
+	AssertEqual(t, eta.QuietHoursDelaySeconds, float64(9*60*60))
+
+	// Without an audience size there is nothing to project yet
+	eta, err = svc.EstimateCompletion(context.Background(), 1, 0)
+	AssertNoError(t, err)
+	AssertNotNil(t, eta.NextDispatchAt)
+	if eta.EstimatedCompletionAt != nil {
+		t.Error("Expected no completion estimate without an audience size")
+	}
+}
+
+// TestEstimateCompletion_PendingApprovalUsesPlan tests that a held send is projected from its plan
+func TestEstimateCompletion_PendingApprovalUsesPlan(t *testing.T) {
+	svc, campaignRepo := setupETATest(config.QuietHoursConfig{})
+	withStats(campaignRepo, models.CampaignStatusPendingApproval, nil, 0)
+
+	eta, err := svc.EstimateCompletion(context.Background(), 1, 0)
+	AssertNoError(t, err)
+	AssertEqual(t, eta.Pending, 3)
+	AssertNotNil(t, eta.EstimatedCompletionAt)
+}
+
+// TestEstimateCompletion_Finished tests that finished campaigns have no estimate
+func TestEstimateCompletion_Finished(t *testing.T) {
+	svc, campaignRepo := setupETATest(config.QuietHoursConfig{})
+	withStats(campaignRepo, models.CampaignStatusSent, nil, 0)
+
+	eta, err := svc.EstimateCompletion(context.Background(), 1, 500)
+	AssertNoError(t, err)
+	AssertEqual(t, eta.Pending, 0)
+	if eta.EstimatedCompletionAt != nil {
+		t.Error("Expected no completion estimate for a sent campaign")
+	}
+}
+
+// TestETAEndpoint tests GET /campaigns/{id}/eta
+func TestETAEndpoint(t *testing.T) {
+	svc, campaignRepo := setupETATest(config.QuietHoursConfig{})
+	withStats(campaignRepo, models.CampaignStatusSending, nil, 20)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/eta", handler.NewSimulationHandler(svc).ETA).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/campaigns/1/eta", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	var eta service.CampaignETA
+	ParseJSONResponse(t, resp, &eta)
+	AssertEqual(t, eta.Pending, 20)
+	AssertNotNil(t, eta.EstimatedCompletionAt)
+
+	for _, query := range []string{"?audience_size=many", "?audience_size=-1"} {
+		req = httptest.NewRequest("GET", "/campaigns/1/eta"+query, nil)
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		AssertStatusCode(t, resp, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,218 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/notify"
+	"smsleopard/internal/service"
+)
+
+// fakeKafkaWriter records written messages, or fails every write when err is set
+type fakeKafkaWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	err      error
+	closed   bool
+}
+
+func (w *fakeKafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+// blockingNotifier holds every delivery until release is closed
+type blockingNotifier struct {
+	release   chan struct{}
+	mu        sync.Mutex
+	delivered int
+}
+
+func (n *blockingNotifier) Notify(ctx context.Context, subject string, payload interface{}) error {
+	<-n.release
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.delivered++
+	return nil
+}
+
+// TestKafkaNotifier_PublishesKeyedJSON tests the message layout consumers rely on
+func TestKafkaNotifier_PublishesKeyedJSON(t *testing.T) {
+	writer := &fakeKafkaWriter{}
+	events := notify.NewEvents(notify.NewKafkaNotifierWithWriter(writer))
+
+	events.Publish(context.Background(), notify.Event{
+		Type:       notify.EventMessageFailed,
+		CampaignID: 42,
+		MessageID:  7,
+		CustomerID: 3,
+		Channel:    "sms",
+		Status:     "failed",
+		RetryCount: 1,
+		Error:      "provider timeout",
+	})
+	AssertNoError(t, events.Close())
+
+	AssertEqual(t, len(writer.messages), 1)
+	AssertEqual(t, writer.closed, true)
+
+	message := writer.messages[0]
+	AssertEqual(t, string(message.Key), "42")
+	AssertEqual(t, message.Headers[0].Key, "type")
+	AssertEqual(t, string(message.Headers[0].Value), "message.failed")
+
+	var body struct {
+		Subject string       `json:"subject"`
+		Payload notify.Event `json:"payload"`
+	}
+	AssertNoError(t, json.Unmarshal(message.Value, &body))
+	AssertEqual(t, body.Subject, "message.failed")
+	AssertEqual(t, body.Payload.MessageID, 7)
+	AssertEqual(t, body.Payload.Error, "provider timeout")
+	if body.Payload.OccurredAt.IsZero() {
+		t.Error("Expected occurred_at to be stamped")
+	}
+}
+
+// TestAsyncNotifier_CountsDropsWhenBrokerDown tests that failed writes are dropped, not returned
+func TestAsyncNotifier_CountsDropsWhenBrokerDown(t *testing.T) {
+	writer := &fakeKafkaWriter{err: errors.New("dial tcp: connection refused")}
+	sink := notify.NewAsyncNotifier("kafka", notify.NewKafkaNotifierWithWriter(writer), 10)
+	events := notify.NewEvents(sink)
+
+	for i := 1; i <= 3; i++ {
+		events.Publish(context.Background(), notify.Event{Type: notify.EventMessageSent, CampaignID: 1, MessageID: i})
+	}
+	AssertNoError(t, events.Close())
+
+	AssertEqual(t, sink.Dropped(), int64(3))
+	AssertEqual(t, len(writer.messages), 0)
+
+	// Events after shutdown are dropped too
+	events.Publish(context.Background(), notify.Event{Type: notify.EventMessageSent, CampaignID: 1})
+	AssertEqual(t, sink.Dropped(), int64(4))
+}
+
+// TestAsyncNotifier_NeverBlocksCaller tests that a stuck sink fills its queue and then drops
+func TestAsyncNotifier_NeverBlocksCaller(t *testing.T) {
+	stuck := &blockingNotifier{release: make(chan struct{})}
+	sink := notify.NewAsyncNotifier("webhook", stuck, 2)
+
+	// One event may be taken by the delivery goroutine, two fit in the queue
+	for i := 0; i < 10; i++ {
+		AssertNoError(t, sink.Notify(context.Background(), notify.EventCampaignCreated, notify.Event{CampaignID: i}))
+	}
+	if sink.Dropped() < 7 {
+		t.Errorf("Expected at least 7 dropped events but got %d", sink.Dropped())
+	}
+
+	close(stuck.release)
+	AssertNoError(t, sink.Close())
+	AssertEqual(t, int64(stuck.delivered)+sink.Dropped(), int64(10))
+}
+
+// TestEvents_NilPublishesNothing tests that services can publish without a sink configured
+func TestEvents_NilPublishesNothing(t *testing.T) {
+	var events *notify.Events
+	events.Publish(context.Background(), notify.Event{Type: notify.EventCampaignCreated})
+	AssertNoError(t, events.Close())
+
+	events, err := notify.NewEventsFromConfig(config.EventsConfig{}, config.NotifyConfig{})
+	AssertNoError(t, err)
+	if events != nil {
+		t.Error("Expected no publisher when no sink is enabled")
+	}
+}
+
+// TestCampaignService_PublishesLifecycleEvents tests the campaign.created and campaign.sending events
+func TestCampaignService_PublishesLifecycleEvents(t *testing.T) {
+	svc, _, _, mock := setupApprovalTest(t)
+	notifier := &recordingNotifier{}
+	svc.SetEvents(notify.NewEvents(notifier))
+
+	_, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+		Name:         "Launch",
+		Channel:      models.ChannelSMS,
+		BaseTemplate: "Hi {first_name}",
+	})
+	AssertNoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	_, err = svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+
+	AssertEqual(t, len(notifier.subjects), 2)
+	AssertEqual(t, notifier.subjects[0], notify.EventCampaignCreated)
+	AssertEqual(t, notifier.subjects[1], notify.EventCampaignSending)
+
+	created := notifier.payloads[0].(notify.Event)
+	AssertEqual(t, created.Channel, "sms")
+	AssertEqual(t, created.Status, "draft")
+	sending := notifier.payloads[1].(notify.Event)
+	AssertEqual(t, sending.CampaignID, 1)
+	AssertEqual(t, sending.MessagesQueued, 2)
+}
+
+// TestCampaignService_NoEventWhenApprovalNeeded tests that a held send does not report sending
+func TestCampaignService_NoEventWhenApprovalNeeded(t *testing.T) {
+	svc, _, _, _ := setupApprovalTest(t)
+	notifier := &recordingNotifier{}
+	svc.SetEvents(notify.NewEvents(notifier))
+
+	_, err := svc.SendCampaign(context.Background(), 1, []int{1, 2, 3}, service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, len(notifier.subjects), 0)
+}
+
+// TestLoadEventSinks tests EVENT_SINK, KAFKA_BROKERS and KAFKA_TOPIC parsing
+func TestLoadEventSinks(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, len(cfg.Events.Sinks), 0)
+
+	t.Setenv("EVENT_SINK", "kafka, webhook")
+	t.Setenv("KAFKA_BROKERS", "kafka-1:9092, kafka-2:9092")
+	t.Setenv("NOTIFY_WEBHOOK_URL", "https://hooks.example.com/ops")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, len(cfg.Events.Sinks), 2)
+	AssertEqual(t, cfg.Events.Sinks[1], config.EventSinkWebhook)
+	AssertEqual(t, len(cfg.Events.KafkaBrokers), 2)
+	AssertEqual(t, cfg.Events.KafkaBrokers[1], "kafka-2:9092")
+	AssertEqual(t, cfg.Events.KafkaTopic, "smsleopard.events")
+
+	t.Setenv("NOTIFY_WEBHOOK_URL", "")
+	_, err = config.Load()
+	AssertError(t, err, "EVENT_SINK=webhook requires NOTIFY_WEBHOOK_URL")
+
+	t.Setenv("EVENT_SINK", "kafka")
+	t.Setenv("KAFKA_BROKERS", "")
+	_, err = config.Load()
+	AssertError(t, err, "EVENT_SINK=kafka requires KAFKA_BROKERS")
+
+	t.Setenv("EVENT_SINK", "sqs")
+	_, err = config.Load()
+	AssertError(t, err, `EVENT_SINK entries must be "none", "kafka" or "webhook"`)
+}
@@ -0,0 +1,288 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// exportJobFixture is an export job service over in-memory jobs and messages, with its routes
+type exportJobFixture struct {
+	svc         *service.ExportJobService
+	jobs        *MockExportJobRepository
+	messageRepo *MockMessageRepository
+	router      *mux.Router
+	dir         string
+	now         time.Time
+}
+
+// newExportJobFixture creates an export job fixture whose campaign 1 has count messages
+func newExportJobFixture(t *testing.T, count int) *exportJobFixture {
+	t.Helper()
+
+	messages := make([]*models.OutboundMessageWithDetails, count)
+	for i := range messages {
+		content := fmt.Sprintf("Hello customer %d", i+1)
+		message := &models.OutboundMessageWithDetails{}
+		message.ID = i + 1
+		message.CampaignID = 1
+		message.CustomerID = i + 1
+		message.Status = models.MessageStatusSent
+		message.RenderedContent = &content
+		message.CreatedAt = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		message.UpdatedAt = message.CreatedAt
+		message.Customer.Phone = fmt.Sprintf("+25470000%04d", i+1)
+		messages[i] = message
+	}
+
+	messageRepo := NewMockMessageRepository()
+	messageRepo.ListForExportFunc = func(ctx context.Context, campaignID, afterID, limit int) ([]*models.OutboundMessageWithDetails, error) {
+		batch := []*models.OutboundMessageWithDetails{}
+		for _, message := range messages {
+			if message.CampaignID == campaignID && message.ID > afterID && len(batch) < limit {
+				batch = append(batch, message)
+			}
+		}
+		return batch, nil
+	}
+
+	dir := t.TempDir()
+	store, err := service.NewDirExportStore(dir)
+	AssertNoError(t, err)
+
+	jobs := NewMockExportJobRepository()
+	jobs.MessageCounts[1] = count
+
+	f := &exportJobFixture{jobs: jobs, messageRepo: messageRepo, dir: dir, now: time.Now()}
+	f.svc = service.NewExportJobService(jobs, NewMockCampaignRepository(), messageRepo, store, config.ExportConfig{Dir: dir, Retention: time.Hour})
+	f.svc.SetClock(func() time.Time { return f.now })
+
+	h := handler.NewExportJobHandler(f.svc)
+	f.router = mux.NewRouter()
+	f.router.HandleFunc("/campaigns/{id:[0-9]+}/exports", h.Create).Methods("POST")
+	f.router.HandleFunc("/campaigns/{id:[0-9]+}/exports/{job_id:[0-9]+}", h.Get).Methods("GET")
+	f.router.HandleFunc("/campaigns/{id:[0-9]+}/exports/{job_id:[0-9]+}/download", h.Download).Methods("GET", "HEAD")
+	return f
+}
+
+// serve sends a request to the fixture's routes
+func (f *exportJobFixture) serve(method, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rr := httptest.NewRecorder()
+	f.router.ServeHTTP(rr, req)
+	return rr
+}
+
+// completeJob creates an export of campaign 1 and generates it
+func (f *exportJobFixture) completeJob(t *testing.T) *models.ExportJob {
+	t.Helper()
+
+	job, err := f.svc.CreateJob(context.Background(), 1, "")
+	AssertNoError(t, err)
+
+	ran, err := f.svc.RunNext(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, ran, true)
+	return f.jobs.Jobs[job.ID]
+}
+
+// TestExportJob_GeneratesInBatches tests that a job reads messages a batch at a time and records progress
+func TestExportJob_GeneratesInBatches(t *testing.T) {
+	f := newExportJobFixture(t, 2500)
+
+	job := f.completeJob(t)
+
+	AssertEqual(t, job.Status, models.ExportJobCompleted)
+	AssertEqual(t, f.messageRepo.Calls["ListForExport"], 3)
+	AssertEqual(t, f.jobs.Calls["UpdateProgress"], 3)
+	AssertEqual(t, job.RowsWritten, 2500)
+
+	data, err := os.ReadFile(filepath.Join(f.dir, *job.FileName))
+	AssertNoError(t, err)
+	AssertEqual(t, *job.SizeBytes, int64(len(data)))
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	AssertEqual(t, len(lines), 2501)
+	AssertEqual(t, lines[0], "message_id,customer_id,phone,status,retry_count,last_error,rendered_content,created_at,updated_at")
+	AssertEqual(t, lines[2500], "2500,2500,+254700002500,sent,0,,Hello customer 2500,2025-01-01T12:00:00Z,2025-01-01T12:00:00Z")
+
+	ran, err := f.svc.RunNext(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, ran, false)
+}
+
+// TestExportJob_StatusAndDownloadURL tests that a job reports progress and, once complete, a download URL
+func TestExportJob_StatusAndDownloadURL(t *testing.T) {
+	f := newExportJobFixture(t, 10)
+
+	rr := f.serve("POST", "/campaigns/1/exports", nil)
+	AssertStatusCode(t, rr, http.StatusAccepted)
+	AssertEqual(t, rr.Header().Get("Location"), "/campaigns/1/exports/1")
+
+	var pending handler.ExportJobResponse
+	ParseJSONResponse(t, rr, &pending)
+	AssertEqual(t, pending.Status, models.ExportJobPending)
+	AssertEqual(t, pending.DownloadURL == nil, true)
+
+	_, err := f.svc.RunNext(context.Background())
+	AssertNoError(t, err)
+
+	rr = f.serve("GET", "/campaigns/1/exports/1", nil)
+	AssertStatusCode(t, rr, http.StatusOK)
+
+	var completed handler.ExportJobResponse
+	ParseJSONResponse(t, rr, &completed)
+	AssertEqual(t, completed.Status, models.ExportJobCompleted)
+	AssertEqual(t, completed.Progress, 1.0)
+	AssertEqual(t, *completed.DownloadURL, "/campaigns/1/exports/1/download")
+	AssertEqual(t, completed.ExpiresAt != nil, true)
+
+	// Another campaign's job is not found
+	rr = f.serve("GET", "/campaigns/2/exports/1", nil)
+	AssertStatusCode(t, rr, http.StatusNotFound)
+}
+
+// TestExportJob_RangeResumedDownload tests that an interrupted download resumes from where it stopped
+func TestExportJob_RangeResumedDownload(t *testing.T) {
+	f := newExportJobFixture(t, 200)
+	f.completeJob(t)
+
+	rr := f.serve("GET", "/campaigns/1/exports/1/download", nil)
+	AssertStatusCode(t, rr, http.StatusOK)
+	AssertEqual(t, rr.Header().Get("Accept-Ranges"), "bytes")
+	AssertEqual(t, rr.Header().Get("Content-Type"), "text/csv; charset=utf-8")
+	full := rr.Body.Bytes()
+	etag := rr.Header().Get("ETag")
+
+	// The client received the first 1000 bytes before the connection dropped
+	received := append([]byte{}, full[:1000]...)
+	rr = f.serve("GET", "/campaigns/1/exports/1/download", http.Header{
+		"Range":    {"bytes=1000-"},
+		"If-Range": {etag},
+	})
+	AssertStatusCode(t, rr, http.StatusPartialContent)
+	AssertEqual(t, rr.Header().Get("Content-Range"), fmt.Sprintf("bytes 1000-%d/%d", len(full)-1, len(full)))
+
+	resumed := append(received, rr.Body.Bytes()...)
+	AssertEqual(t, string(resumed), string(full))
+
+	// A resume against a different file gets the whole file again
+	rr = f.serve("GET", "/campaigns/1/exports/1/download", http.Header{
+		"Range":    {"bytes=1000-"},
+		"If-Range": {`"export-1-0"`},
+	})
+	AssertStatusCode(t, rr, http.StatusOK)
+	AssertEqual(t, rr.Body.Len(), len(full))
+
+	// A range past the end cannot be satisfied
+	rr = f.serve("GET", "/campaigns/1/exports/1/download", http.Header{
+		"Range": {fmt.Sprintf("bytes=%d-", len(full)+10)},
+	})
+	AssertStatusCode(t, rr, http.StatusRequestedRangeNotSatisfiable)
+}
+
+// TestExportJob_DownloadBeforeComplete tests that a job still pending cannot be downloaded
+func TestExportJob_DownloadBeforeComplete(t *testing.T) {
+	f := newExportJobFixture(t, 10)
+
+	_, err := f.svc.CreateJob(context.Background(), 1, "user-1")
+	AssertNoError(t, err)
+	AssertEqual(t, *f.jobs.Jobs[1].RequestedBy, "user-1")
+
+	rr := f.serve("GET", "/campaigns/1/exports/1/download", nil)
+	AssertStatusCode(t, rr, http.StatusConflict)
+}
+
+// TestExportJob_Expiry tests that an expired export loses its download URL and its file is purged
+func TestExportJob_Expiry(t *testing.T) {
+	f := newExportJobFixture(t, 10)
+	job := f.completeJob(t)
+	path := filepath.Join(f.dir, *job.FileName)
+
+	f.now = f.now.Add(2 * time.Hour)
+
+	rr := f.serve("GET", "/campaigns/1/exports/1/download", nil)
+	AssertStatusCode(t, rr, http.StatusConflict)
+
+	purged, err := f.svc.PurgeExpired(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, purged, 1)
+	_, err = os.Stat(path)
+	AssertEqual(t, os.IsNotExist(err), true)
+
+	rr = f.serve("GET", "/campaigns/1/exports/1", nil)
+	var response handler.ExportJobResponse
+	ParseJSONResponse(t, rr, &response)
+	AssertEqual(t, response.DownloadURL == nil, true)
+}
+
+// TestExportJob_GenerationFailure tests that a job whose generation fails is recorded failed without a file
+func TestExportJob_GenerationFailure(t *testing.T) {
+	f := newExportJobFixture(t, 10)
+	f.messageRepo.ListForExportFunc = func(ctx context.Context, campaignID, afterID, limit int) ([]*models.OutboundMessageWithDetails, error) {
+		return nil, errors.New("connection reset")
+	}
+
+	_, err := f.svc.CreateJob(context.Background(), 1, "")
+	AssertNoError(t, err)
+
+	ran, err := f.svc.RunNext(context.Background())
+	AssertEqual(t, ran, true)
+	AssertError(t, err, "export job 1 failed: connection reset")
+
+	job := f.jobs.Jobs[1]
+	AssertEqual(t, job.Status, models.ExportJobFailed)
+	AssertEqual(t, *job.Error, "connection reset")
+
+	entries, err := os.ReadDir(f.dir)
+	AssertNoError(t, err)
+	AssertEqual(t, len(entries), 0)
+}
+
+// TestExportJob_Run tests that the runner generates queued jobs and stops when cancelled
+func TestExportJob_Run(t *testing.T) {
+	f := newExportJobFixture(t, 10)
+	_, err := f.svc.CreateJob(context.Background(), 1, "")
+	AssertNoError(t, err)
+
+	listed := make(chan struct{})
+	list := f.messageRepo.ListForExportFunc
+	f.messageRepo.ListForExportFunc = func(ctx context.Context, campaignID, afterID, limit int) ([]*models.OutboundMessageWithDetails, error) {
+		defer close(listed)
+		return list(ctx, campaignID, afterID, limit)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		f.svc.Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	select {
+	case <-listed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("runner did not pick up the job")
+	}
+	cancel()
+	<-done
+
+	AssertEqual(t, f.jobs.Jobs[1].Status, models.ExportJobCompleted)
+}
@@ -0,0 +1,266 @@
+package tests
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// exportRows returns two campaigns as streamed by the repository
+func exportRows() []*models.CampaignExportRow {
+	created := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	first := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	last := first.Add(90 * time.Second)
+
+	sms := &models.CampaignExportRow{FirstMessageAt: &first, LastMessageAt: &last}
+	sms.ID = 1
+	sms.Name = `=HYPERLINK("x"), "January" promo`
+	sms.Channel = models.ChannelSMS
+	sms.Status = models.CampaignStatusSent
+	sms.Tags = []string{"promo", "q1"}
+	sms.CreatedAt = created
+	sms.Stats = models.CampaignStats{Total: 10, Sent: 8, Failed: 2, Simulated: 3}
+	throughput, estimated := 6.67, 2.6
+	sms.SendMetrics = &models.CampaignSendMetrics{
+		StartedAt:                first,
+		CompletedAt:              last,
+		DurationSeconds:          90,
+		Messages:                 10,
+		ThroughputPerMinute:      &throughput,
+		EstimatedDurationSeconds: &estimated,
+	}
+
+	whatsapp := &models.CampaignExportRow{}
+	whatsapp.ID = 2
+	whatsapp.Name = "Draft"
+	whatsapp.Channel = models.ChannelWhatsApp
+	whatsapp.Status = models.CampaignStatusDraft
+	whatsapp.CreatedAt = created.Add(time.Hour)
+
+	return []*models.CampaignExportRow{sms, whatsapp}
+}
+
+// setupExportTest serves GET /campaigns/export over a repository that streams exportRows
+func setupExportTest() (*mux.Router, *MockCampaignRepository, *repository.CampaignExportFilters) {
+	campaignRepo := NewMockCampaignRepository()
+	filters := &repository.CampaignExportFilters{}
+	campaignRepo.StreamWithStatsFunc = func(ctx context.Context, f repository.CampaignExportFilters, fn func(row *models.CampaignExportRow) error) error {
+		*filters = f
+		for _, row := range exportRows() {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	svc := service.NewExportService(campaignRepo, testSendingConfig())
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/export", handler.NewExportHandler(svc).Campaigns).Methods("GET")
+	return router, campaignRepo, filters
+}
+
+// TestExportCampaigns_CSV tests that a CSV export parses back with stats, cost and duration
+func TestExportCampaigns_CSV(t *testing.T) {
+	router, _, filters := setupExportTest()
+
+	req := httptest.NewRequest("GET", "/campaigns/export?format=csv&from=2026-01-01&to=2026-01-31", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertEqual(t, resp.Header().Get("Content-Type"), "text/csv; charset=utf-8")
+	AssertEqual(t, resp.Header().Get("Content-Disposition"), "attachment; filename=campaigns_2026-01-01_2026-01-31.csv")
+
+	// The to date is inclusive
+	AssertEqual(t, filters.From.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)), true)
+	AssertEqual(t, filters.To.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)), true)
+
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	AssertNoError(t, err)
+	AssertEqual(t, len(records), 3)
+	AssertEqual(t, strings.Join(records[0], ","), "id,name,channel,status,tags,created_at,scheduled_at,total,pending,sent,failed,simulated,cost,duration_seconds,throughput_per_minute,estimated_duration_seconds")
+
+	sms := records[1]
+	AssertEqual(t, sms[0], "1")
+	AssertEqual(t, sms[1], `'=HYPERLINK("x"), "January" promo`)
+	AssertEqual(t, sms[2], "sms")
+	AssertEqual(t, sms[4], "promo;q1")
+	AssertEqual(t, sms[5], "2026-01-05T09:00:00Z")
+	AssertEqual(t, sms[6], "")
+	AssertEqual(t, sms[9], "8")
+	AssertEqual(t, sms[11], "3")
+	AssertEqual(t, sms[12], "4.00")
+	AssertEqual(t, sms[13], "90")
+	AssertEqual(t, sms[14], "6.67")
+	AssertEqual(t, sms[15], "3")
+
+	// A campaign without messages has zero counts and no duration
+	draft := records[2]
+	AssertEqual(t, draft[2], "whatsapp")
+	AssertEqual(t, draft[4], "")
+	AssertEqual(t, draft[7], "0")
+	AssertEqual(t, draft[12], "0.00")
+	AssertEqual(t, draft[13], "")
+	AssertEqual(t, draft[14], "")
+	AssertEqual(t, draft[15], "")
+}
+
+// TestExportCampaigns_NDJSON tests that each NDJSON line decodes to one campaign
+func TestExportCampaigns_NDJSON(t *testing.T) {
+	router, _, filters := setupExportTest()
+
+	req := httptest.NewRequest("GET", "/campaigns/export?format=ndjson&from=2026-01-01", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertEqual(t, resp.Header().Get("Content-Type"), "application/x-ndjson")
+	AssertEqual(t, resp.Header().Get("Content-Disposition"), "attachment; filename=campaigns_2026-01-01_onward.ndjson")
+	if filters.To != nil {
+		t.Error("Expected no upper bound without a to date")
+	}
+
+	AssertEqual(t, strings.Count(resp.Body.String(), "\n"), 2)
+
+	decoder := json.NewDecoder(resp.Body)
+	exports := []service.CampaignExport{}
+	for {
+		var export service.CampaignExport
+		if err := decoder.Decode(&export); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Failed to decode export line: %v", err)
+		}
+		exports = append(exports, export)
+	}
+
+	AssertEqual(t, len(exports), 2)
+	AssertEqual(t, exports[0].Name, `=HYPERLINK("x"), "January" promo`)
+	AssertEqual(t, exports[0].Stats.Failed, 2)
+	AssertEqual(t, exports[0].Cost, 4.0)
+	AssertEqual(t, *exports[0].DurationSeconds, 90.0)
+	AssertEqual(t, len(exports[1].Tags), 0)
+	if exports[1].DurationSeconds != nil {
+		t.Error("Expected no duration for a campaign without messages")
+	}
+}
+
+// TestExportCampaigns_Negotiation tests that the Accept header picks the format when none is given
+func TestExportCampaigns_Negotiation(t *testing.T) {
+	router, _, _ := setupExportTest()
+
+	tests := []struct {
+		accept      string
+		contentType string
+		filename    string
+	}{
+		{"", "text/csv; charset=utf-8", "campaigns_all.csv"},
+		{"application/x-ndjson", "application/x-ndjson", "campaigns_all.ndjson"},
+		{"text/html, text/csv;q=0.9", "text/csv; charset=utf-8", "campaigns_all.csv"},
+		{"*/*", "text/csv; charset=utf-8", "campaigns_all.csv"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/campaigns/export?to=2026-03-31", nil)
+		req.Header.Set("Accept", tt.accept)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		AssertStatusCode(t, resp, http.StatusOK)
+		AssertEqual(t, resp.Header().Get("Content-Type"), tt.contentType)
+		AssertContains(t, resp.Header().Get("Content-Disposition"), strings.Replace(tt.filename, "all", "until_2026-03-31", 1))
+	}
+}
+
+// TestExportCampaigns_InvalidQuery tests that bad parameters are rejected before streaming
+func TestExportCampaigns_InvalidQuery(t *testing.T) {
+	router, campaignRepo, _ := setupExportTest()
+
+	for _, query := range []string{
+		"?format=xlsx",
+		"?from=01/01/2026",
+		"?to=2026-13-01",
+		"?from=2026-02-01&to=2026-01-31",
+	} {
+		req := httptest.NewRequest("GET", "/campaigns/export"+query, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		AssertStatusCode(t, resp, http.StatusBadRequest)
+	}
+	AssertEqual(t, campaignRepo.Calls["StreamWithStats"], 0)
+}
+
+// TestExportCampaigns_QueryError tests that a failure before any row is an error response, not an empty file
+func TestExportCampaigns_QueryError(t *testing.T) {
+	router, campaignRepo, _ := setupExportTest()
+	campaignRepo.StreamWithStatsFunc = func(ctx context.Context, f repository.CampaignExportFilters, fn func(row *models.CampaignExportRow) error) error {
+		return errors.New("failed to export campaigns: connection refused")
+	}
+
+	req := httptest.NewRequest("GET", "/campaigns/export", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusInternalServerError)
+	AssertEqual(t, resp.Header().Get("Content-Disposition"), "")
+}
+
+// TestStreamWithStats_Query tests the joined grouped export query
+func TestStreamWithStats_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	first := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	columns := []string{
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team",
+		"total", "pending", "sent", "failed", "queued", "unpublished", "simulated", "first_message_at", "last_message_at",
+		"started_at", "completed_at", "duration_seconds", "messages", "throughput_per_minute", "estimated_duration_seconds",
+	}
+	mock.ExpectQuery(`FROM campaigns c LEFT JOIN outbound_messages m ON m.campaign_id = c.id LEFT JOIN campaign_send_metrics sm ON sm.campaign_id = c.id WHERE 1=1 AND c.created_at >= \$1 AND c.created_at < \$2 GROUP BY c.id, sm.campaign_id ORDER BY c.created_at, c.id`).
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "Promo", "sms", "sent", "Hi", nil, from, from, "{promo,q1}", nil, nil, 10, 0, 8, 2, 0, 0, 3, first, first.Add(time.Minute),
+				first, first.Add(time.Minute), 60.0, 10, 10.0, 1.25).
+			AddRow(2, "Draft", "whatsapp", "draft", "Hi", nil, from, from, "{}", nil, nil, 0, 0, 0, 0, 0, 0, 0, nil, nil,
+				nil, nil, nil, nil, nil, nil))
+
+	rows := []*models.CampaignExportRow{}
+	err := repository.NewCampaignRepository(db).StreamWithStats(context.Background(), repository.CampaignExportFilters{From: &from, To: &to}, func(row *models.CampaignExportRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, len(rows), 2)
+	AssertEqual(t, rows[0].Stats.Sent, 8)
+	AssertEqual(t, rows[0].Tags[1], "q1")
+	AssertEqual(t, rows[0].LastMessageAt.Sub(*rows[0].FirstMessageAt), time.Minute)
+	AssertEqual(t, rows[1].Stats.Total, 0)
+	if rows[1].FirstMessageAt != nil {
+		t.Error("Expected no first message time for a campaign without messages")
+	}
+	AssertEqual(t, rows[0].SendMetrics.Messages, 10)
+	AssertEqual(t, *rows[0].SendMetrics.ThroughputPerMinute, 10.0)
+	if rows[1].SendMetrics != nil {
+		t.Error("Expected no send metrics for a campaign that has not completed")
+	}
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
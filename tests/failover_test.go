@@ -0,0 +1,212 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+// readOnlyError is what the old primary returns for writes while a managed Postgres fails over
+func readOnlyError() error {
+	return &pq.Error{Code: "25006", Message: "cannot execute UPDATE in a read-only transaction"}
+}
+
+// TestIsTransient tests which database errors are classified as clearing once a failover completes
+func TestIsTransient(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{"read-only transaction", readOnlyError(), true},
+		{"wrapped read-only transaction", fmt.Errorf("failed to update message status: %w", readOnlyError()), true},
+		{"admin shutdown", &pq.Error{Code: "57P01", Message: "terminating connection due to administrator command"}, true},
+		{"cannot connect now", &pq.Error{Code: "57P03", Message: "the database system is starting up"}, true},
+		{"connection failure", &pq.Error{Code: "08006"}, true},
+		{"connection refused", fmt.Errorf("failed to claim message: %w", refused), true},
+		{"bad connection", driver.ErrBadConn, true},
+		{"classified", &repository.TransientError{Condition: "admin_shutdown", Err: errors.New("gone")}, true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"foreign key violation", &pq.Error{Code: "23503"}, false},
+		{"not found", repository.ErrMessageNotFound, false},
+		{"plain error", errors.New("failed to get message"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AssertEqual(t, repository.IsTransient(tt.err), tt.transient)
+		})
+	}
+}
+
+// TestExecWithRetry_FailoverThenSuccess tests that a write refused by a read-only primary is run
+// again after the backoff and succeeds once the failover completes
+func TestExecWithRetry_FailoverThenSuccess(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE outbound_messages SET status").
+		WillReturnError(readOnlyError())
+	mock.ExpectExec("UPDATE outbound_messages SET status").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	start := time.Now()
+	err := repository.NewMessageRepository(db).UpdateStatus(context.Background(), 7, models.MessageStatusSent, nil)
+	AssertNoError(t, err)
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Fatalf("Expected the retry to wait for the backoff, took %v", elapsed)
+	}
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestExecWithRetry_CreateFailoverThenSuccess tests that an INSERT refused by a read-only
+// primary is retried and returns the stored row
+func TestExecWithRetry_CreateFailoverThenSuccess(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery("INSERT INTO outbound_messages").
+		WillReturnError(&pq.Error{Code: "25006", Message: "cannot execute INSERT in a read-only transaction"})
+	mock.ExpectQuery("INSERT INTO outbound_messages").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(42, now, now))
+
+	message := NewTestMessage(1, 1)
+	err := repository.NewMessageRepository(db).Create(context.Background(), message)
+	AssertNoError(t, err)
+	AssertEqual(t, message.ID, 42)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestExecWithRetry_GivesUp tests that a failover outlasting the retries returns an error
+// matching ErrTransient after two retries
+func TestExecWithRetry_GivesUp(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	for i := 0; i < 3; i++ {
+		mock.ExpectExec("UPDATE outbound_messages SET status").
+			WillReturnError(readOnlyError())
+	}
+
+	err := repository.NewMessageRepository(db).UpdateStatus(context.Background(), 7, models.MessageStatusSent, nil)
+	if !errors.Is(err, repository.ErrTransient) {
+		t.Fatalf("Expected ErrTransient but got %v", err)
+	}
+	AssertContains(t, err.Error(), "read_only_sql_transaction")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestExecWithRetry_PermanentErrorNotRetried tests that errors outside the failover family are
+// returned at once
+func TestExecWithRetry_PermanentErrorNotRetried(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE outbound_messages SET status").
+		WillReturnError(&pq.Error{Code: "23514", Message: "violates check constraint"})
+
+	err := repository.NewMessageRepository(db).UpdateStatus(context.Background(), 7, models.MessageStatusSent, nil)
+	AssertError(t, err, "failed to update message status: pq: violates check constraint")
+	AssertEqual(t, errors.Is(err, repository.ErrTransient), false)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestExecWithRetry_OnlyUnappliedRetried tests that only errors raised before the statement ran
+// are retried; one that may have been applied is returned at once, still matching ErrTransient,
+// so a write like retry_count + 1 is never applied twice
+func TestExecWithRetry_OnlyUnappliedRetried(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	tests := []struct {
+		name    string
+		err     error
+		retried bool
+	}{
+		{"read-only transaction", readOnlyError(), true},
+		{"cannot connect now", &pq.Error{Code: "57P03", Message: "the database system is starting up"}, true},
+		{"connection refused", refused, true},
+		{"admin shutdown", &pq.Error{Code: "57P01", Message: "terminating connection due to administrator command"}, false},
+		{"connection failure", &pq.Error{Code: "08006"}, false},
+		{"connection reset", reset, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := NewMockDB(t)
+			defer db.Close()
+
+			mock.ExpectExec("UPDATE outbound_messages SET status = 'failed'").
+				WillReturnError(tt.err)
+			if tt.retried {
+				mock.ExpectExec("UPDATE outbound_messages SET status = 'failed'").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			_, err := repository.ExecWithRetry(context.Background(), db,
+				"UPDATE outbound_messages SET status = 'failed', retry_count = retry_count + 1 WHERE id = $1", 7)
+			if tt.retried {
+				AssertNoError(t, err)
+			} else if !errors.Is(err, repository.ErrTransient) {
+				t.Fatalf("Expected ErrTransient but got %v", err)
+			}
+			AssertNoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestWorker_SentDuringFailover tests that recording a send through a brief failover succeeds
+// and the job is acknowledged
+func TestWorker_SentDuringFailover(t *testing.T) {
+	sender := &countingSender{}
+	f := newProcessingErrorFixture(t, sender)
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WillReturnError(readOnlyError())
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1})
+	AssertNoError(t, err)
+	AssertEqual(t, sender.calls, 1)
+	AssertEqual(t, len(f.errorRepo.Created), 0)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestWorker_SendFailureDuringFailover tests that a send failure that cannot be recorded while
+// the database fails over is requeued as an infrastructure error, so no retry is used up
+func TestWorker_SendFailureDuringFailover(t *testing.T) {
+	f := newProcessingErrorFixture(t, &failingSender{})
+	for i := 0; i < 3; i++ {
+		f.mock.ExpectExec("UPDATE outbound_messages SET status = 'failed'").
+			WillReturnError(readOnlyError())
+	}
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1})
+	if !errors.Is(err, repository.ErrTransient) {
+		t.Fatalf("Expected ErrTransient but got %v", err)
+	}
+	var sendErr *service.SendError
+	AssertEqual(t, errors.As(err, &sendErr), false)
+	AssertEqual(t, service.ClassifyProcessingError(err), service.ErrorClassInfra)
+	AssertEqual(t, len(f.errorRepo.Created), 1)
+	AssertEqual(t, f.errorRepo.Created[0].ErrorClass, service.ErrorClassInfra)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/faults"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestParseFaults tests the FAULTS spec format
+func TestParseFaults(t *testing.T) {
+	injector, err := faults.Parse("")
+	AssertNoError(t, err)
+	if injector != nil {
+		t.Error("Expected no injector for an empty spec")
+	}
+
+	injector, err = faults.Parse(" panic_before_ack:0.01, fail_db_after_send:0.1 ")
+	AssertNoError(t, err)
+	AssertEqual(t, injector.String(), "fail_db_after_send:0.1,panic_before_ack:0.01")
+
+	for spec, message := range map[string]string{
+		"fail_db_after_send":         `fault "fail_db_after_send" must be <point>:<probability>`,
+		"drop_table:0.5":             `unknown fault point "drop_table"`,
+		"fail_db_after_send:1.5":     "fault fail_db_after_send probability must be between 0 and 1",
+		"panic_before_ack:sometimes": "fault panic_before_ack probability must be between 0 and 1",
+	} {
+		_, err := faults.Parse(spec)
+		AssertError(t, err, message)
+	}
+}
+
+// TestInjector_Probability tests that points fire at their probability and a nil injector never fires
+func TestInjector_Probability(t *testing.T) {
+	injector := faults.New(map[faults.Point]float64{
+		faults.FailDBAfterSend: 0.5,
+		faults.PanicBeforeAck:  1,
+	}, rand.NewSource(1))
+
+	fired := 0
+	for i := 0; i < 1000; i++ {
+		if injector.Fires(faults.FailDBAfterSend) {
+			fired++
+		}
+	}
+	if fired < 400 || fired > 600 {
+		t.Errorf("Expected about half of 1000 checks to fire, got %d", fired)
+	}
+
+	err := injector.Fail(faults.PanicBeforeAck)
+	AssertEqual(t, errors.Is(err, faults.ErrInjected), true)
+	AssertContains(t, err.Error(), "panic_before_ack")
+
+	var off *faults.Injector
+	for _, point := range faults.Points {
+		AssertEqual(t, off.Fires(point), false)
+		AssertNoError(t, off.Fail(point))
+		off.Panic(point)
+	}
+}
+
+// TestLoadFaults tests FAULTS parsing and that it is refused in production
+func TestLoadFaults(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	if cfg.Worker.Faults != nil {
+		t.Error("Expected fault injection to be off by default")
+	}
+
+	t.Setenv("FAULTS", "fail_db_after_send:0.1")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Worker.Faults.String(), "fail_db_after_send:0.1")
+
+	t.Setenv("FAULTS", "fail_db_after_send")
+	_, err = config.Load()
+	AssertContains(t, err.Error(), "FAULTS is invalid")
+
+	t.Setenv("FAULTS", "panic_before_ack:0.01")
+	t.Setenv("ENV", "production")
+	_, err = config.Load()
+	AssertError(t, err, "FAULTS cannot be set in production")
+}
+
+// faultFixture is a message processor over one stored message whose status the test controls
+type faultFixture struct {
+	processor *service.MessageProcessor
+	mock      sqlmock.Sqlmock
+	sender    *countingSender
+	status    models.MessageStatus
+}
+
+// newFaultFixture creates a processor with every listed point firing on each check
+func newFaultFixture(t *testing.T, points ...faults.Point) *faultFixture {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	f := &faultFixture{mock: mock, sender: &countingSender{}, status: models.MessageStatusPending}
+	messageRepo := NewMockMessageRepository()
+	messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		message := NewTestMessageWithStatus(f.status)
+		message.ID = id
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *message,
+			Campaign:        *NewTestCampaignWithStatus(models.CampaignStatusSending),
+			Customer:        *NewTestCustomer(),
+		}, nil
+	}
+
+	f.processor = service.NewMessageProcessor(db, messageRepo, service.NewTemplateService(), f.sender, service.NewAttemptBudget(messageRepo, 0), nil)
+
+	probabilities := make(map[faults.Point]float64)
+	for _, point := range points {
+		probabilities[point] = 1
+	}
+	f.processor.SetFaults(faults.New(probabilities, rand.NewSource(1)))
+	return f
+}
+
+// expectMarkedSent expects the worker's status update after a successful send
+func (f *faultFixture) expectMarkedSent() {
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WithArgs(1, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// TestFault_FailDBAfterSend tests that a failed status update requeues the job and the retry completes it
+func TestFault_FailDBAfterSend(t *testing.T) {
+	f := newFaultFixture(t, faults.FailDBAfterSend)
+	job := &queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1}
+
+	// The provider accepted the message but the update failed, so the job is nacked
+	err := f.processor.Handle(job)
+	AssertEqual(t, errors.Is(err, faults.ErrInjected), true)
+	AssertEqual(t, f.sender.calls, 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+
+	// The requeued job sends again and is recorded; delivery is at-least-once
+	f.processor.SetFaults(nil)
+	f.expectMarkedSent()
+	AssertNoError(t, f.processor.Handle(job))
+	AssertEqual(t, f.sender.calls, 2)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestFault_PanicBeforeAck tests that a job redelivered after a panic before ACK is not sent twice
+func TestFault_PanicBeforeAck(t *testing.T) {
+	f := newFaultFixture(t, faults.PanicBeforeAck)
+	job := &queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1}
+
+	// The handler panics after recording the send; the panic is recovered and the job nacked
+	f.expectMarkedSent()
+	err := f.processor.Handle(job)
+	var panicErr *service.PanicError
+	AssertEqual(t, errors.As(err, &panicErr), true)
+	AssertContains(t, err.Error(), "panic_before_ack")
+	AssertEqual(t, f.sender.calls, 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+
+	// RabbitMQ redelivers the nacked job; the sent status guards against a second send
+	f.status = models.MessageStatusSent
+	AssertNoError(t, f.processor.Handle(job))
+	AssertEqual(t, f.sender.calls, 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestFault_NoneConfigured tests that the processor sends and acknowledges normally without faults
+func TestFault_NoneConfigured(t *testing.T) {
+	f := newFaultFixture(t)
+	f.expectMarkedSent()
+
+	AssertNoError(t, f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1}))
+	AssertEqual(t, f.sender.calls, 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// setupFrequencyCapTest creates a campaign service capping customers at 2 sent messages a week
+func setupFrequencyCapTest(t *testing.T) (*service.CampaignService, *MockCampaignRepository, *MockMessageRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	svc, campaignRepo, messageRepo, mock := setupApprovalTest(t)
+	svc.SetFrequencyCap(config.FrequencyCapConfig{MaxMessages: 2, Window: 7 * 24 * time.Hour})
+	return svc, campaignRepo, messageRepo, mock
+}
+
+// TestFrequencyCap_SkipsCappedCustomers tests that customers at the cap are left out and counted
+func TestFrequencyCap_SkipsCappedCustomers(t *testing.T) {
+	svc, _, messageRepo, mock := setupFrequencyCapTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var checked []int
+	var since time.Time
+	var maxMessages int
+	messageRepo.ListFrequencyCappedFunc = func(ctx context.Context, ids []int, s time.Time, max int) ([]int, error) {
+		checked, since, maxMessages = ids, s, max
+		return []int{2}, nil
+	}
+	var queued []*models.OutboundMessage
+	messageRepo.CreateBatchFunc = func(ctx context.Context, messages []*models.OutboundMessage) error {
+		queued = messages
+		return nil
+	}
+
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.MessagesQueued, 1)
+	AssertEqual(t, result.SkippedFrequencyCap, 1)
+	AssertEqual(t, queued[0].CustomerID, 1)
+	AssertEqual(t, len(checked), 2)
+	AssertEqual(t, maxMessages, 2)
+	if age := time.Since(since); age < 7*24*time.Hour || age > 7*24*time.Hour+time.Minute {
+		t.Errorf("Expected the window to start 7 days ago, got %v", age)
+	}
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestFrequencyCap_AllCapped tests that a send whose whole audience is capped is refused
+func TestFrequencyCap_AllCapped(t *testing.T) {
+	svc, _, messageRepo, _ := setupFrequencyCapTest(t)
+	messageRepo.ListFrequencyCappedFunc = func(ctx context.Context, ids []int, since time.Time, max int) ([]int, error) {
+		return ids, nil
+	}
+
+	_, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertError(t, err, "business logic error: all 2 customers already received 2 messages in the last 7 days")
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 0)
+
+	// A failed check is not a business rule
+	messageRepo.ListFrequencyCappedFunc = func(ctx context.Context, ids []int, since time.Time, max int) ([]int, error) {
+		return nil, errors.New("connection reset")
+	}
+	_, err = svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertError(t, err, "failed to check frequency cap: connection reset")
+}
+
+// TestFrequencyCap_ExemptAndDisabled tests that exempt campaigns and a disabled cap skip the check
+func TestFrequencyCap_ExemptAndDisabled(t *testing.T) {
+	svc, campaignRepo, messageRepo, mock := setupFrequencyCapTest(t)
+	messageRepo.ListFrequencyCappedFunc = func(ctx context.Context, ids []int, since time.Time, max int) ([]int, error) {
+		return ids, nil
+	}
+
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		campaign := NewTestCampaign()
+		campaign.FrequencyCapExempt = true
+		return campaign, nil
+	}
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertEqual(t, messageRepo.Calls["ListFrequencyCapped"], 0)
+
+	campaignRepo.GetByIDFunc = nil
+	svc.SetFrequencyCap(config.FrequencyCapConfig{})
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	result, err = svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertEqual(t, messageRepo.Calls["ListFrequencyCapped"], 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestFrequencyCap_ApprovalRechecks tests that customers capped while a send awaited approval are skipped
+func TestFrequencyCap_ApprovalRechecks(t *testing.T) {
+	svc, campaignRepo, messageRepo, mock := setupFrequencyCapTest(t)
+
+	// Three customers are over the approval threshold; none is capped yet
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2, 3}, service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.Status, models.CampaignStatusPendingApproval)
+	AssertEqual(t, result.SkippedFrequencyCap, 0)
+
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusPendingApproval), nil
+	}
+	campaignRepo.GetSendPlanFunc = func(ctx context.Context, id int) (*models.SendPlan, error) {
+		return &models.SendPlan{CustomerIDs: []int{1, 2, 3}, AudienceSize: 3}, nil
+	}
+	messageRepo.ListFrequencyCappedFunc = func(ctx context.Context, ids []int, since time.Time, max int) ([]int, error) {
+		return []int{3}, nil
+	}
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	result, err = svc.ApproveCampaign(context.Background(), 1)
+	AssertNoError(t, err)
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertEqual(t, result.SkippedFrequencyCap, 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestListFrequencyCapped_Query tests that customers with exactly the maximum sent messages are capped
+func TestListFrequencyCapped_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	since := time.Now().Add(-7 * 24 * time.Hour)
+	mock.ExpectQuery(`SELECT customer_id FROM outbound_messages WHERE customer_id = ANY\(\$1\) AND status = 'sent' AND updated_at >= \$2 GROUP BY customer_id HAVING COUNT\(\*\) >= \$3`).
+		WithArgs("{1,2,3}", since, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"customer_id"}).AddRow(2))
+
+	capped, err := repository.NewMessageRepository(db).ListFrequencyCapped(context.Background(), []int{1, 2, 3}, since, 2)
+	AssertNoError(t, err)
+	AssertEqual(t, len(capped), 1)
+	AssertEqual(t, capped[0], 2)
+	AssertNoError(t, mock.ExpectationsWereMet())
+
+	// An empty audience needs no query
+	capped, err = repository.NewMessageRepository(db).ListFrequencyCapped(context.Background(), nil, since, 2)
+	AssertNoError(t, err)
+	AssertEqual(t, len(capped), 0)
+}
+
+// TestLoadFrequencyCap tests FREQUENCY_CAP_MAX_MESSAGES and FREQUENCY_CAP_WINDOW_DAYS parsing
+func TestLoadFrequencyCap(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.FrequencyCap.MaxMessages, 2)
+	AssertEqual(t, cfg.FrequencyCap.Window, 7*24*time.Hour)
+
+	t.Setenv("FREQUENCY_CAP_MAX_MESSAGES", "3")
+	t.Setenv("FREQUENCY_CAP_WINDOW_DAYS", "14")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.FrequencyCap.MaxMessages, 3)
+	AssertEqual(t, cfg.FrequencyCap.Window, 14*24*time.Hour)
+
+	t.Setenv("FREQUENCY_CAP_WINDOW_DAYS", "0")
+	_, err = config.Load()
+	AssertError(t, err, "FREQUENCY_CAP_WINDOW_DAYS must be positive")
+
+	t.Setenv("FREQUENCY_CAP_MAX_MESSAGES", "-1")
+	_, err = config.Load()
+	AssertError(t, err, "FREQUENCY_CAP_MAX_MESSAGES cannot be negative")
+}
+
+// TestFrequencyCap_CreateExempt tests that the exemption flag is stored when the campaign is created
+func TestFrequencyCap_CreateExempt(t *testing.T) {
+	svc, campaignRepo, _, _ := setupFrequencyCapTest(t)
+	var created *models.Campaign
+	campaignRepo.CreateFunc = func(ctx context.Context, campaign *models.Campaign) error {
+		created = campaign
+		return nil
+	}
+
+	_, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+		Name:               "One-time codes",
+		Channel:            models.ChannelSMS,
+		BaseTemplate:       "Your code is ready, {first_name}",
+		FrequencyCapExempt: true,
+	})
+	AssertNoError(t, err)
+	AssertEqual(t, created.FrequencyCapExempt, true)
+}
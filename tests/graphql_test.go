@@ -0,0 +1,324 @@
+package tests
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/graph"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// graphQLResponse is the decoded body of a /graphql response
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// runGraphQL executes a query and decodes the response
+func runGraphQL(t *testing.T, executor *graph.Executor, query string, variables map[string]interface{}) graphQLResponse {
+	t.Helper()
+
+	raw, err := json.Marshal(executor.Execute(context.Background(), query, "", variables))
+	AssertNoError(t, err)
+
+	var resp graphQLResponse
+	AssertNoError(t, json.Unmarshal(raw, &resp))
+	return resp
+}
+
+// graphQLErrors joins the error messages of a response
+func graphQLErrors(resp graphQLResponse) string {
+	messages := []string{}
+	for _, e := range resp.Errors {
+		messages = append(messages, e.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// campaignColumns are the columns of a campaign row
+var campaignColumns = []string{
+	"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team",
+}
+
+// messageColumns are the columns of an outbound message row
+var messageColumns = []string{
+	"id", "campaign_id", "customer_id", "status", "rendered_content", "last_error", "retry_count", "published_at", "created_at", "updated_at",
+}
+
+// TestGraphQL_NestedQueryIsBatched tests that campaign stats, messages and message customers
+// each cost one query for the whole response rather than one per row
+func TestGraphQL_NestedQueryIsBatched(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	// Sibling fields resolve concurrently, so the batched queries may arrive in any order
+	mock.MatchExpectationsInOrder(false)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE 1=1 AND status = (.+) ORDER BY id DESC").
+		WithArgs(models.CampaignStatusSent, 20, 0).
+		WillReturnRows(sqlmock.NewRows(campaignColumns).
+			AddRow(2, "Second", "sms", "sent", "Hi {first_name}", nil, now, now, "{promo}", nil, nil).
+			AddRow(1, "First", "sms", "sent", "Hello {first_name}", nil, now, now, "{}", nil, nil))
+	mock.ExpectQuery("SELECT COUNT(.+) FROM campaigns").
+		WithArgs(models.CampaignStatusSent).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	mock.ExpectQuery(`SELECT campaign_id, (.+) FROM outbound_messages WHERE campaign_id = ANY\(\$1\) AND resent_from_message_id IS NULL GROUP BY campaign_id`).
+		WithArgs("{2,1}").
+		WillReturnRows(sqlmock.NewRows([]string{"campaign_id", "total", "pending", "sent", "failed", "queued", "unpublished", "simulated", "p95_queue_latency", "audience", "first_message_at", "last_finished_at", "skipped_duplicate_content", "expired"}).
+			AddRow(2, 3, 0, 3, 0, 0, 0, 1, 1.5, 3, nil, nil, 0, 0))
+
+	// first: 2 fetches 3 per campaign to detect a next page
+	mock.ExpectQuery(`PARTITION BY campaign_id (.+) WHERE campaign_id = ANY\(\$1\) \) m WHERE rn <= \$2`).
+		WithArgs("{2,1}", 3).
+		WillReturnRows(sqlmock.NewRows(messageColumns).
+			AddRow(13, 2, 10, "sent", "Hi Ann", nil, 0, nil, now, now).
+			AddRow(12, 2, 11, "sent", "Hi Bob", nil, 0, nil, now, now).
+			AddRow(11, 2, 10, "sent", "Hi Ann", nil, 0, nil, now, now).
+			AddRow(5, 1, 12, "sent", "Hello Cy", nil, 0, nil, now, now))
+
+	mock.ExpectQuery(`SELECT (.+) FROM customers WHERE id = ANY\(\$1\)`).
+		WithArgs("{10,11,12}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "phone", "first_name", "last_name", "location", "preferred_product", "contact_window_start", "contact_window_end", "created_at"}).
+			AddRow(10, "+254700000010", "Ann", nil, nil, nil, nil, nil, now).
+			AddRow(11, "+254700000011", "Bob", nil, nil, nil, nil, nil, now).
+			AddRow(12, "+254700000012", "Cy", nil, nil, nil, nil, nil, now))
+
+	executor := graph.NewExecutor(
+		repository.NewCampaignRepository(db),
+		repository.NewCustomerRepository(db),
+		repository.NewMessageRepository(db),
+	)
+
+	resp := runGraphQL(t, executor, `{
+		campaigns(status: sent) {
+			totalCount
+			campaigns {
+				id
+				tags
+				stats { total simulated p95QueueLatencySeconds }
+				messages(first: 2) {
+					edges { node { id customer { firstName } } }
+					pageInfo { hasNextPage endCursor }
+				}
+			}
+		}
+	}`, nil)
+	AssertEqual(t, graphQLErrors(resp), "")
+	AssertNoError(t, mock.ExpectationsWereMet())
+
+	var data struct {
+		Campaigns struct {
+			TotalCount int
+			Campaigns  []struct {
+				ID    string
+				Tags  []string
+				Stats struct {
+					Total                  int
+					Simulated              int
+					P95QueueLatencySeconds *float64
+				}
+				Messages struct {
+					Edges []struct {
+						Node struct {
+							ID       string
+							Customer *struct{ FirstName string }
+						}
+					}
+					PageInfo struct {
+						HasNextPage bool
+						EndCursor   *string
+					}
+				}
+			}
+		}
+	}
+	AssertNoError(t, json.Unmarshal(resp.Data, &data))
+
+	AssertEqual(t, data.Campaigns.TotalCount, 2)
+	second, first := data.Campaigns.Campaigns[0], data.Campaigns.Campaigns[1]
+
+	AssertEqual(t, second.ID, "2")
+	AssertEqual(t, strings.Join(second.Tags, ","), "promo")
+	AssertEqual(t, second.Stats.Total, 3)
+	AssertEqual(t, second.Stats.Simulated, 1)
+	AssertEqual(t, *second.Stats.P95QueueLatencySeconds, 1.5)
+	AssertEqual(t, len(second.Messages.Edges), 2)
+	AssertEqual(t, second.Messages.Edges[0].Node.Customer.FirstName, "Ann")
+	AssertEqual(t, second.Messages.Edges[1].Node.Customer.FirstName, "Bob")
+	AssertEqual(t, second.Messages.PageInfo.HasNextPage, true)
+	AssertEqual(t, *second.Messages.PageInfo.EndCursor, base64.StdEncoding.EncodeToString([]byte("message:12")))
+
+	// Campaigns without messages get zero stats rather than an error
+	AssertEqual(t, first.Stats.Total, 0)
+	if first.Stats.P95QueueLatencySeconds != nil {
+		t.Errorf("Expected no p95 latency, got %v", *first.Stats.P95QueueLatencySeconds)
+	}
+	AssertEqual(t, len(first.Messages.Edges), 1)
+	AssertEqual(t, first.Messages.Edges[0].Node.Customer.FirstName, "Cy")
+	AssertEqual(t, first.Messages.PageInfo.HasNextPage, false)
+}
+
+// TestGraphQL_MessagesArguments tests that status and cursor arguments reach the repository
+func TestGraphQL_MessagesArguments(t *testing.T) {
+	campaignRepo := NewMockCampaignRepository()
+	messageRepo := NewMockMessageRepository()
+
+	var filters repository.MessageFilters
+	messageRepo.ListByCampaignIDsFunc = func(ctx context.Context, ids []int, f repository.MessageFilters) ([]*models.OutboundMessage, error) {
+		filters = f
+		return []*models.OutboundMessage{}, nil
+	}
+
+	executor := graph.NewExecutor(campaignRepo, NewMockCustomerRepository(), messageRepo)
+	cursor := base64.StdEncoding.EncodeToString([]byte("message:40"))
+
+	resp := runGraphQL(t, executor, `query($after: String) {
+		campaign(id: 1) { messages(status: failed, first: 5, after: $after) { edges { cursor } } }
+	}`, map[string]interface{}{"after": cursor})
+	AssertEqual(t, graphQLErrors(resp), "")
+
+	AssertEqual(t, string(*filters.Status), "failed")
+	AssertEqual(t, filters.AfterID, 40)
+	AssertEqual(t, filters.Limit, 6)
+
+	// Bad cursors and page sizes are field errors
+	resp = runGraphQL(t, executor, `{ campaign(id: 1) { messages(after: "bm9wZQ==") { edges { cursor } } } }`, nil)
+	AssertContains(t, graphQLErrors(resp), "invalid cursor")
+
+	resp = runGraphQL(t, executor, `{ campaign(id: 1) { messages(first: 500) { edges { cursor } } } }`, nil)
+	AssertContains(t, graphQLErrors(resp), "first must be between 1 and 100")
+}
+
+// TestGraphQL_RootLookups tests missing rows, invalid IDs, filters and hidden internal errors
+func TestGraphQL_RootLookups(t *testing.T) {
+	campaignRepo := NewMockCampaignRepository()
+	customerRepo := NewMockCustomerRepository()
+	executor := graph.NewExecutor(campaignRepo, customerRepo, NewMockMessageRepository())
+
+	// Unknown campaigns resolve to null without an error
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return nil, fmt.Errorf("campaign not found")
+	}
+	resp := runGraphQL(t, executor, `{ campaign(id: 99) { id } }`, nil)
+	AssertEqual(t, graphQLErrors(resp), "")
+	AssertEqual(t, string(resp.Data), `{"campaign":null}`)
+
+	resp = runGraphQL(t, executor, `{ campaign(id: "abc") { id } }`, nil)
+	AssertContains(t, graphQLErrors(resp), `invalid campaign ID: "abc"`)
+
+	// Database errors are logged, not returned
+	customerRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Customer, error) {
+		return nil, fmt.Errorf("failed to get customer: pq: connection refused")
+	}
+	resp = runGraphQL(t, executor, `{ customer(id: 1) { phone } }`, nil)
+	AssertEqual(t, graphQLErrors(resp), "internal error")
+
+	// List filters mirror GET /campaigns
+	var filters repository.CampaignFilters
+	campaignRepo.ListFunc = func(ctx context.Context, f repository.CampaignFilters) ([]*models.Campaign, int, error) {
+		filters = f
+		return []*models.Campaign{}, 0, nil
+	}
+	resp = runGraphQL(t, executor, `{ campaigns(channel: whatsapp, tags: ["Retention"], page: 2, pageSize: 10) { totalPages } }`, nil)
+	AssertEqual(t, graphQLErrors(resp), "")
+	AssertEqual(t, string(*filters.Channel), "whatsapp")
+	AssertEqual(t, strings.Join(filters.Tags, ","), "retention")
+	AssertEqual(t, filters.Page, 2)
+	AssertEqual(t, filters.PageSize, 10)
+
+	resp = runGraphQL(t, executor, `{ campaigns(status: archived) { totalCount } }`, nil)
+	if len(resp.Errors) == 0 {
+		t.Error("Expected error for unknown status enum value")
+	}
+}
+
+// TestGraphQL_ReadOnly tests that mutations and over-deep queries are rejected
+func TestGraphQL_ReadOnly(t *testing.T) {
+	executor := graph.NewExecutor(NewMockCampaignRepository(), NewMockCustomerRepository(), NewMockMessageRepository())
+
+	resp := runGraphQL(t, executor, `mutation { deleteCampaign(id: 1) }`, nil)
+	if len(resp.Errors) == 0 {
+		t.Fatal("Expected mutation to be rejected")
+	}
+
+	deep := `{ customer(id: 1) { messages { edges { node { customer { messages { edges { node { customer { id } } } } } } } } } }`
+	resp = runGraphQL(t, executor, deep, nil)
+	AssertContains(t, graphQLErrors(resp), "exceeds max depth")
+}
+
+// TestGraphQLEndpoint tests GET and POST /graphql through the handler
+func TestGraphQLEndpoint(t *testing.T) {
+	campaignRepo := NewMockCampaignRepository()
+	executor := graph.NewExecutor(campaignRepo, NewMockCustomerRepository(), NewMockMessageRepository())
+	graphqlHandler := handler.NewGraphQLHandler(executor)
+
+	// POST with variables
+	req := NewJSONRequest(t, "POST", "/graphql", map[string]interface{}{
+		"query":     `query($id: ID!) { campaign(id: $id) { name channel } }`,
+		"variables": map[string]interface{}{"id": "1"},
+	})
+	resp := httptest.NewRecorder()
+	graphqlHandler.Query(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertJSONContentType(t, resp)
+	AssertContains(t, resp.Body.String(), `"campaign":{"name":"Test Campaign","channel":"sms"}`)
+
+	// GET with URL parameters
+	req = httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape(`{ campaign(id: 1) { id } }`), nil)
+	resp = httptest.NewRecorder()
+	graphqlHandler.Query(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertContains(t, resp.Body.String(), `"campaign":{"id":"1"}`)
+
+	// Missing query
+	req = NewJSONRequest(t, "POST", "/graphql", map[string]interface{}{})
+	resp = httptest.NewRecorder()
+	graphqlHandler.Query(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+
+	// Malformed variables
+	req = httptest.NewRequest("GET", "/graphql?query=%7Bcampaign(id:1)%7Bid%7D%7D&variables=nope", nil)
+	resp = httptest.NewRecorder()
+	graphqlHandler.Query(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+}
+
+// TestListByCampaignIDs_Query tests the per-campaign limit with status and cursor filters
+func TestListByCampaignIDs_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	status := models.MessageStatusFailed
+	mock.ExpectQuery(`ROW_NUMBER\(\) OVER \(PARTITION BY campaign_id ORDER BY id DESC\) (.+) `+
+		`WHERE campaign_id = ANY\(\$1\) AND status = \$2 AND id < \$3\) m WHERE rn <= \$4 ORDER BY campaign_id, id DESC`).
+		WithArgs("{1,2}", status, 50, 100).
+		WillReturnRows(sqlmock.NewRows(messageColumns))
+
+	messageRepo := repository.NewMessageRepository(db)
+	messages, err := messageRepo.ListByCampaignIDs(context.Background(), []int{1, 2}, repository.MessageFilters{
+		Status:  &status,
+		AfterID: 50,
+		Limit:   500,
+	})
+	AssertNoError(t, err)
+	AssertEqual(t, len(messages), 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// setupInlineCustomersTest creates a campaign service whose customer repository the test can inspect
+func setupInlineCustomersTest(t *testing.T) (*service.CampaignService, *MockCustomerRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	customerRepo := NewMockCustomerRepository()
+	svc := service.NewCampaignService(
+		NewMockCampaignRepository(),
+		customerRepo,
+		NewMockMessageRepository(),
+		service.NewTemplateService(),
+		NewMockPublisher(),
+		db,
+		config.ApprovalConfig{},
+	)
+	return svc, customerRepo, mock
+}
+
+// TestInlineCustomers_New tests that brand-new inline customers are created and sent to with the listed IDs
+func TestInlineCustomers_New(t *testing.T) {
+	svc, customerRepo, mock := setupInlineCustomersTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	upserted := []*models.Customer{}
+	customerRepo.UpsertByPhoneFunc = func(ctx context.Context, customer *models.Customer) (bool, error) {
+		customer.ID = 100 + len(upserted)
+		upserted = append(upserted, customer)
+		return true, nil
+	}
+	var audience []int
+	customerRepo.GetByIDsFunc = func(ctx context.Context, ids []int) ([]*models.Customer, error) {
+		audience = ids
+		customers := make([]*models.Customer, len(ids))
+		for i, id := range ids {
+			customers[i] = NewTestCustomerWithID(id)
+		}
+		return customers, nil
+	}
+
+	result, err := svc.SendCampaign(context.Background(), 1, []int{5}, service.SendOptions{
+		Customers: []service.InlineCustomer{
+			{Phone: "0712 345 678", FirstName: StringPtr("Amina")},
+			{Phone: "+254733000111"},
+		},
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.MessagesQueued, 3)
+	AssertEqual(t, result.InlineCustomers.Created, 2)
+	AssertEqual(t, result.InlineCustomers.Matched, 0)
+	AssertEqual(t, len(upserted), 2)
+	AssertEqual(t, upserted[0].Phone, "+254712345678")
+	AssertEqual(t, *upserted[0].FirstName, "Amina")
+	AssertEqual(t, len(audience), 3)
+	AssertEqual(t, audience[0], 5)
+	AssertEqual(t, audience[2], 101)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestInlineCustomers_Existing tests that inline customers already stored are matched, and repeats upserted once
+func TestInlineCustomers_Existing(t *testing.T) {
+	svc, customerRepo, mock := setupInlineCustomersTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	customerRepo.UpsertByPhoneFunc = func(ctx context.Context, customer *models.Customer) (bool, error) {
+		customer.ID = 7
+		return false, nil
+	}
+
+	result, err := svc.SendCampaign(context.Background(), 1, nil, service.SendOptions{
+		Customers: []service.InlineCustomer{{Phone: "+254712345678"}, {Phone: "254712345678"}},
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, customerRepo.Calls["UpsertByPhone"], 1)
+	AssertEqual(t, result.InlineCustomers.Created, 0)
+	AssertEqual(t, result.InlineCustomers.Matched, 1)
+	AssertEqual(t, result.MessagesQueued, 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestInlineCustomers_Invalid tests that a bad phone or too many inline customers are rejected before anything is written
+func TestInlineCustomers_Invalid(t *testing.T) {
+	svc, customerRepo, _ := setupInlineCustomersTest(t)
+
+	_, err := svc.SendCampaign(context.Background(), 1, []int{1}, service.SendOptions{
+		Customers: []service.InlineCustomer{{Phone: "+254712345678"}, {Phone: "not a phone"}},
+	})
+	AssertError(t, err, `validation error: customers[1]: invalid phone number "not a phone"`)
+
+	tooMany := make([]service.InlineCustomer, service.MaxInlineCustomers+1)
+	for i := range tooMany {
+		tooMany[i] = service.InlineCustomer{Phone: "+254712345678"}
+	}
+	_, err = svc.SendCampaign(context.Background(), 1, nil, service.SendOptions{Customers: tooMany})
+	AssertError(t, err, "validation error: at most 1000 inline customers per send")
+
+	_, err = svc.SendCampaign(context.Background(), 1, nil, service.SendOptions{})
+	AssertError(t, err, "validation error: at least one customer ID or inline customer required")
+
+	AssertEqual(t, customerRepo.Calls["UpsertByPhone"], 0)
+	AssertEqual(t, customerRepo.Calls["GetByIDs"], 0)
+
+	// A storage failure is not a validation error
+	customerRepo.UpsertByPhoneFunc = func(ctx context.Context, customer *models.Customer) (bool, error) {
+		return false, errors.New("failed to upsert customer: connection reset")
+	}
+	_, err = svc.SendCampaign(context.Background(), 1, nil, service.SendOptions{
+		Customers: []service.InlineCustomer{{Phone: "+254712345678"}},
+	})
+	var validationErr *service.ValidationError
+	AssertEqual(t, errors.As(err, &validationErr), false)
+}
+
+// TestInlineCustomers_Endpoint tests sending with only inline customers over HTTP
+func TestInlineCustomers_Endpoint(t *testing.T) {
+	svc, _, mock := setupInlineCustomersTest(t)
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/send", handler.NewCampaignHandler(svc).Send).Methods("POST")
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	req := httptest.NewRequest("POST", "/campaigns/1/send", strings.NewReader(`{"customers": [{"phone": "0712345678", "first_name": "Amina"}]}`))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	var result service.SendCampaignResult
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, result.MessagesQueued, 1)
+	AssertEqual(t, result.InlineCustomers.Created, 1)
+
+	for body, status := range map[string]int{
+		`{"customer_ids": [], "customers": []}`:    http.StatusBadRequest,
+		`{"customers": [{"first_name": "Amina"}]}`: http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("POST", "/campaigns/1/send", strings.NewReader(body))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		AssertStatusCode(t, resp, status)
+	}
+}
+
+// TestUpsertByPhone_Query tests the insert-or-fill-in query, matching stored phones in normalized form, and the created flag
+func TestUpsertByPhone_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`WITH existing AS \( UPDATE customers SET first_name = COALESCE\(\$2, first_name\)(.+)WHERE id = \( SELECT id FROM customers WHERE normalize_phone\(phone\) = \$1 ORDER BY created_at, id LIMIT 1 \)(.+)INSERT INTO customers \(phone, first_name, last_name, location, preferred_product, contact_window_start, contact_window_end\) SELECT \$1, \$2, \$3, \$4, \$5, \$6, \$7 WHERE NOT EXISTS \(SELECT 1 FROM existing\) ON CONFLICT \(phone\) DO UPDATE SET first_name = COALESCE\(EXCLUDED.first_name, customers.first_name\)(.+)RETURNING id, created_at, xmax = 0 AS created`).
+		WithArgs("+254712345678", "Amina", nil, nil, nil, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "created"}).AddRow(42, time.Now(), false))
+
+	customer := &models.Customer{Phone: "+254712345678", FirstName: StringPtr("Amina")}
+	created, err := repository.NewCustomerRepository(db).UpsertByPhone(context.Background(), customer)
+	AssertNoError(t, err)
+	AssertEqual(t, created, false)
+	AssertEqual(t, customer.ID, 42)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/queue"
+)
+
+// TestNewMessageJob_StampsVersion tests that published jobs carry the current version as "v"
+func TestNewMessageJob_StampsVersion(t *testing.T) {
+	job := queue.NewMessageJob(1001, 7, 42)
+	AssertEqual(t, job.Version, queue.CurrentJobVersion)
+
+	body, err := json.Marshal(job)
+	AssertNoError(t, err)
+	want := fmt.Sprintf(`{"v":%d,"message_id":1001,"campaign_id":7,"customer_id":42}`, queue.CurrentJobVersion)
+	AssertEqual(t, string(body), want)
+}
+
+// TestDecodeMessageJob_UnversionedPayload tests that jobs published before versioning, with no
+// "v", are read as v1 by the current consumer
+func TestDecodeMessageJob_UnversionedPayload(t *testing.T) {
+	job, err := queue.DecodeMessageJob([]byte(`{"message_id":1001,"campaign_id":7,"customer_id":42}`))
+	AssertNoError(t, err)
+	AssertEqual(t, job.Version, queue.JobVersion1)
+	AssertEqual(t, job.MessageID, 1001)
+	AssertEqual(t, job.CampaignID, 7)
+	AssertEqual(t, job.CustomerID, 42)
+	AssertNoError(t, job.CheckVersion())
+}
+
+// TestDecodeMessageJob_OldConsumerReadsCurrentPayload tests that a consumer built before
+// versioning still reads the IDs from a job published now, ignoring "v"
+func TestDecodeMessageJob_OldConsumerReadsCurrentPayload(t *testing.T) {
+	// The job schema as it was before versioning
+	type unversionedJob struct {
+		MessageID  int `json:"message_id"`
+		CampaignID int `json:"campaign_id"`
+		CustomerID int `json:"customer_id"`
+	}
+
+	body, err := json.Marshal(queue.NewMessageJob(1001, 7, 42))
+	AssertNoError(t, err)
+
+	var old unversionedJob
+	AssertNoError(t, json.Unmarshal(body, &old))
+	AssertEqual(t, old, unversionedJob{MessageID: 1001, CampaignID: 7, CustomerID: 42})
+}
+
+// TestDecodeMessageJob_NewerPayloadWithinTolerance tests that a job from a newer publisher, with
+// fields this build does not know, is decoded and accepted while within the tolerance
+func TestDecodeMessageJob_NewerPayloadWithinTolerance(t *testing.T) {
+	newer := queue.CurrentJobVersion + queue.JobVersionTolerance
+	body := fmt.Sprintf(`{"v":%d,"message_id":1001,"campaign_id":7,"customer_id":42,"priority":"high","sender":{"id":"LEOPARD"}}`, newer)
+
+	job, err := queue.DecodeMessageJob([]byte(body))
+	AssertNoError(t, err)
+	AssertEqual(t, job.Version, newer)
+	AssertEqual(t, job.MessageID, 1001)
+	AssertEqual(t, job.CustomerID, 42)
+	AssertNoError(t, job.CheckVersion())
+}
+
+// TestMessageJob_CheckVersionOutsideTolerance tests that jobs too new, or with a version that
+// was never published, are refused so the consumer moves them to the poison queue
+func TestMessageJob_CheckVersionOutsideTolerance(t *testing.T) {
+	tests := []struct {
+		name    string
+		version queue.JobVersion
+	}{
+		{"past the tolerance", queue.CurrentJobVersion + queue.JobVersionTolerance + 1},
+		{"far future", 99},
+		{"negative", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := queue.DecodeMessageJob([]byte(fmt.Sprintf(`{"v":%d,"message_id":1001}`, tt.version)))
+			AssertNoError(t, err)
+
+			err = job.CheckVersion()
+			if !errors.Is(err, queue.ErrUnsupportedJobVersion) {
+				t.Fatalf("Expected ErrUnsupportedJobVersion but got %v", err)
+			}
+			if !strings.Contains(err.Error(), fmt.Sprintf("v%d", tt.version)) {
+				t.Fatalf("Expected the version in the error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestMessageLatency_Published tests queue and total latency with controlled timestamps
+func TestMessageLatency_Published(t *testing.T) {
+	createdAt := time.Date(2025, 12, 10, 10, 0, 0, 0, time.UTC)
+	publishedAt := createdAt.Add(2 * time.Second)
+	now := createdAt.Add(5 * time.Second)
+
+	message := NewTestMessage(1, 1)
+	message.CreatedAt = createdAt
+	message.PublishedAt = &publishedAt
+
+	queueLatency, ok := message.QueueLatency(now)
+	AssertEqual(t, ok, true)
+	AssertEqual(t, queueLatency, 3*time.Second)
+	AssertEqual(t, message.TotalLatency(now), 5*time.Second)
+}
+
+// TestMessageLatency_NeverPublished tests that queue latency is unavailable without a publish timestamp
+func TestMessageLatency_NeverPublished(t *testing.T) {
+	createdAt := time.Date(2025, 12, 10, 10, 0, 0, 0, time.UTC)
+	now := createdAt.Add(time.Minute)
+
+	message := NewTestMessage(1, 1)
+	message.CreatedAt = createdAt
+
+	_, ok := message.QueueLatency(now)
+	AssertEqual(t, ok, false)
+	AssertEqual(t, message.TotalLatency(now), time.Minute)
+}
+
+// TestMarkPublished_UpdatesTimestamp tests that publish time is recorded for all published IDs in one query
+func TestMarkPublished_UpdatesTimestamp(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE outbound_messages SET published_at").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	messageRepo := repository.NewMessageRepository(db)
+	AssertNoError(t, messageRepo.MarkPublished(context.Background(), []int{1, 2, 3}))
+
+	// Empty input must not hit the database
+	AssertNoError(t, messageRepo.MarkPublished(context.Background(), []int{}))
+
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// setupPublishTest creates a campaign service whose repository and publisher record, in order,
+// each message stamped published and each job published
+func setupPublishTest(t *testing.T) (*service.CampaignService, *MockMessageRepository, *MockPublisher, *[]string) {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	steps := []string{}
+	messageRepo := NewMockMessageRepository()
+	messageRepo.MarkPublishedFunc = func(ctx context.Context, ids []int) error {
+		steps = append(steps, fmt.Sprintf("stamp %v", ids))
+		return nil
+	}
+	publisher := NewMockPublisher()
+	publisher.PublishMessageFunc = func(messageID, campaignID, customerID int) error {
+		steps = append(steps, fmt.Sprintf("publish %d", messageID))
+		return nil
+	}
+
+	svc := service.NewCampaignService(NewMockCampaignRepository(), NewMockCustomerRepository(), messageRepo, service.NewTemplateService(), publisher, db, config.ApprovalConfig{})
+	return svc, messageRepo, publisher, &steps
+}
+
+// TestSend_StampsEachMessageBeforePublishing tests that a message's publish time is recorded
+// before its job is published, so a queued message is never taken for unpublished
+func TestSend_StampsEachMessageBeforePublishing(t *testing.T) {
+	svc, _, _, steps := setupPublishTest(t)
+
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertEqual(t, strings.Join(*steps, ", "), "stamp [1], publish 1, stamp [2], publish 2")
+}
+
+// TestSend_PublishFailureDeferred tests that a message stamped but not published is deferred
+// for the deferred requeue, and the rest of the batch still published
+func TestSend_PublishFailureDeferred(t *testing.T) {
+	svc, messageRepo, publisher, steps := setupPublishTest(t)
+	publisher.PublishMessageFunc = func(messageID, campaignID, customerID int) error {
+		if messageID == 1 {
+			return errors.New("broker unavailable")
+		}
+		*steps = append(*steps, fmt.Sprintf("publish %d", messageID))
+		return nil
+	}
+	var deferred []string
+	messageRepo.DeferUntilFunc = func(ctx context.Context, id int, until time.Time, reason string) error {
+		deferred = append(deferred, fmt.Sprintf("%d: %s", id, reason))
+		return nil
+	}
+
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertEqual(t, strings.Join(*steps, ", "), "stamp [1], stamp [2], publish 2")
+	AssertEqual(t, strings.Join(deferred, ", "), "1: Publish failed: broker unavailable")
+}
+
+// TestSend_StampFailureInterrupts tests that a publish time that cannot be recorded stops the
+// send before the message is published, leaving it and the rest for the reconciler
+func TestSend_StampFailureInterrupts(t *testing.T) {
+	svc, messageRepo, _, steps := setupPublishTest(t)
+	messageRepo.MarkPublishedFunc = func(ctx context.Context, ids []int) error {
+		if ids[0] == 2 {
+			return errors.New("connection reset")
+		}
+		*steps = append(*steps, fmt.Sprintf("stamp %v", ids))
+		return nil
+	}
+
+	_, err := svc.SendCampaign(context.Background(), 1, []int{1, 2, 3}, service.SendOptions{})
+	var interrupted *service.SendInterruptedError
+	if !errors.As(err, &interrupted) {
+		t.Fatalf("Expected a SendInterruptedError, got %v", err)
+	}
+	AssertContains(t, err.Error(), "failed to record publish time for message 2: connection reset")
+	AssertEqual(t, interrupted.MessagesQueued, 3)
+	AssertEqual(t, strings.Join(*steps, ", "), "stamp [1], publish 1")
+}
+
+// TestCampaignStats_P95QueueLatency tests that p95 queue latency is read with the campaign
+func TestCampaignStats_P95QueueLatency(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaign := NewTestCampaignWithStatus(models.CampaignStatusSending)
+
+	mock.ExpectQuery(CampaignWithStatsQuery).
+		WithArgs(campaign.ID).
+		WillReturnRows(NewCampaignWithStatsRows(campaign, 10, 2, 7, 1, 2, 0, 0, 4.25, 0, nil))
+
+	campaignRepo := repository.NewCampaignRepository(db)
+	result, err := campaignRepo.GetWithStats(context.Background(), campaign.ID)
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Stats.Sent, 7)
+	if result.Stats.P95QueueLatencySeconds == nil {
+		t.Fatal("Expected p95 queue latency to be set")
+	}
+	AssertEqual(t, *result.Stats.P95QueueLatencySeconds, 4.25)
+
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
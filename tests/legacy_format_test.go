@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/httpjson"
+	"smsleopard/internal/middleware"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// legacyFixedTime keeps timestamps in golden files stable
+var legacyFixedTime = time.Date(2026, 1, 15, 9, 30, 0, 0, time.UTC)
+
+// newLegacyFormatRouter serves the campaign detail and list endpoints behind LegacyFormat
+func newLegacyFormatRouter(t *testing.T) *mux.Router {
+	t.Helper()
+
+	campaign := func(id int, name string, status models.CampaignStatus) *models.Campaign {
+		return &models.Campaign{
+			ID:           id,
+			Name:         name,
+			Channel:      models.ChannelSMS,
+			Status:       status,
+			BaseTemplate: "Hi {first_name}, {preferred_product} is on offer",
+			Tags:         []string{"q3-promo"},
+			CreatedAt:    legacyFixedTime,
+			UpdatedAt:    legacyFixedTime,
+		}
+	}
+
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetWithStatsFunc = func(ctx context.Context, id int) (*models.CampaignWithStats, error) {
+		if id != 1 {
+			return nil, errors.New("campaign not found")
+		}
+		return &models.CampaignWithStats{
+			Campaign: *campaign(1, "Weekend Sale", models.CampaignStatusSending),
+			Stats: models.CampaignStats{
+				Total:   3,
+				Pending: 1,
+				Sent:    2,
+				Queued:  1,
+				RetryDistribution: &models.RetryDistribution{
+					Sent:   map[int]int{0: 2},
+					Failed: map[int]int{},
+				},
+			},
+		}, nil
+	}
+	campaignRepo.ListFunc = func(ctx context.Context, filters repository.CampaignFilters) ([]*models.Campaign, int, error) {
+		return []*models.Campaign{
+			campaign(2, "Loyalty Reminder", models.CampaignStatusDraft),
+			campaign(1, "Weekend Sale", models.CampaignStatusSending),
+		}, 2, nil
+	}
+
+	svc := service.NewCampaignService(campaignRepo, NewMockCustomerRepository(), NewMockMessageRepository(), service.NewTemplateService(), NewMockPublisher(), nil, config.ApprovalConfig{})
+	h := handler.NewCampaignHandler(svc)
+
+	router := mux.NewRouter()
+	router.Use(middleware.LegacyFormat)
+	router.HandleFunc("/campaigns", h.List).Methods("GET")
+	router.HandleFunc("/campaigns/{id}", h.GetByID).Methods("GET")
+	return router
+}
+
+// assertGolden compares a response body with a file in testdata/legacy, rewriting it with -update
+func assertGolden(t *testing.T, name string, body []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "legacy", name)
+	if *updateGolden {
+		AssertNoError(t, os.WriteFile(path, body, 0o644))
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	AssertNoError(t, err)
+	if !bytes.Equal(bytes.TrimSpace(body), bytes.TrimSpace(want)) {
+		t.Errorf("%s does not match the golden file\ngot:  %s\nwant: %s", name, body, want)
+	}
+}
+
+// TestLegacyFormat_Golden tests the campaign detail and list endpoints in the standard and legacy formats
+func TestLegacyFormat_Golden(t *testing.T) {
+	router := newLegacyFormatRouter(t)
+
+	tests := []struct {
+		golden      string
+		target      string
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"campaign_detail.json", "/campaigns/1", "", http.StatusOK, httpjson.ContentType},
+		{"campaign_detail.legacy.json", "/campaigns/1", middleware.LegacyMediaType, http.StatusOK, middleware.LegacyMediaType},
+		{"campaign_list.json", "/campaigns", "", http.StatusOK, httpjson.ContentType},
+		{"campaign_list.legacy.json", "/campaigns?format=legacy", "", http.StatusOK, middleware.LegacyMediaType},
+		{"campaign_not_found.json", "/campaigns/9", "", http.StatusNotFound, httpjson.ContentType},
+		{"campaign_not_found.legacy.json", "/campaigns/9", "application/json, " + middleware.LegacyMediaType + ";q=0.9", http.StatusNotFound, middleware.LegacyMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			AssertStatusCode(t, resp, tt.status)
+			AssertEqual(t, resp.Header().Get("Content-Type"), tt.contentType)
+			assertGolden(t, tt.golden, resp.Body.Bytes())
+		})
+	}
+}
+
+// TestCamelCaseKeys tests that only object keys are rewritten and order and values are kept
+func TestCamelCaseKeys(t *testing.T) {
+	out, err := middleware.CamelCaseKeys([]byte(`{"skipped_frequency_cap":1,"status":"pending_approval","retry_distribution":{"sent":{"0":2}},"tags":["q3_promo"],"p95_queue_latency_seconds":1.50,"budget":null,"ok":true}`))
+	AssertNoError(t, err)
+	AssertEqual(t, string(out), `{"skippedFrequencyCap":1,"status":"pending_approval","retryDistribution":{"sent":{"0":2}},"tags":["q3_promo"],"p95QueueLatencySeconds":1.50,"budget":null,"ok":true}`)
+
+	_, err = middleware.CamelCaseKeys([]byte(`{"a":`))
+	if err == nil {
+		t.Error("Expected an error for truncated JSON")
+	}
+}
+
+// TestLegacyFormat_NonJSONPassesThrough tests that responses other than JSON, like CSV exports, are left alone
+func TestLegacyFormat_NonJSONPassesThrough(t *testing.T) {
+	h := middleware.LegacyFormat(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("id,name\n1,Weekend Sale\n"))
+	}))
+
+	req := httptest.NewRequest("GET", "/campaigns/export", nil)
+	req.Header.Set("Accept", middleware.LegacyMediaType)
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+
+	AssertEqual(t, resp.Header().Get("Content-Type"), "text/csv")
+	AssertEqual(t, resp.Body.String(), "id,name\n1,Weekend Sale\n")
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// TestValidateTemplate_LengthBoundary tests templates at and just over the default limit
+func TestValidateTemplate_LengthBoundary(t *testing.T) {
+	svc := service.NewTemplateService()
+
+	AssertNoError(t, svc.ValidateTemplate(strings.Repeat("a", service.DefaultMaxTemplateLength)))
+
+	err := svc.ValidateTemplate(strings.Repeat("a", service.DefaultMaxTemplateLength+1))
+	AssertError(t, err, "template is 2001 characters, maximum is 2000")
+
+	// Characters are counted, not bytes
+	AssertNoError(t, svc.ValidateTemplate(strings.Repeat("é", service.DefaultMaxTemplateLength)))
+}
+
+// TestValidateTemplate_ConfiguredLimit tests that a configured limit replaces the default
+func TestValidateTemplate_ConfiguredLimit(t *testing.T) {
+	svc := service.NewTemplateServiceWithLimits(config.LimitsConfig{MaxTemplateLength: 10})
+
+	AssertNoError(t, svc.ValidateTemplate("Hi {name}!"))
+	AssertError(t, svc.ValidateTemplate("Hi {name}!!"), "template is 11 characters, maximum is 10")
+
+	// Zero keeps the default
+	svc = service.NewTemplateServiceWithLimits(config.LimitsConfig{})
+	AssertNoError(t, svc.ValidateTemplate(strings.Repeat("a", service.DefaultMaxTemplateLength)))
+}
+
+// TestCheckRenderedLength tests the rendered message limit used by the worker
+func TestCheckRenderedLength(t *testing.T) {
+	svc := service.NewTemplateService()
+
+	AssertNoError(t, svc.CheckRenderedLength(strings.Repeat("a", service.DefaultMaxRenderedLength)))
+	AssertError(t, svc.CheckRenderedLength(strings.Repeat("a", service.DefaultMaxRenderedLength+1)),
+		"rendered message is 1601 characters, over the 1600 character limit")
+
+	// A short template can still render too long with long customer data
+	long := strings.Repeat("x", 300)
+	customer := &models.Customer{FirstName: &long}
+	svc = service.NewTemplateServiceWithLimits(config.LimitsConfig{MaxRenderedLength: 160})
+	rendered, err := svc.Render("Hi {first_name}", customer)
+	AssertNoError(t, err)
+	if err := svc.CheckRenderedLength(rendered); err == nil {
+		t.Fatal("Expected rendered length error")
+	}
+}
+
+// TestCreateCampaign_TemplateTooLong tests that an over-long template is a 400 from the API
+func TestCreateCampaign_TemplateTooLong(t *testing.T) {
+	db, _ := NewMockDB(t)
+	defer db.Close()
+
+	campaignRepo := NewMockCampaignRepository()
+	svc := service.NewCampaignService(
+		campaignRepo,
+		NewMockCustomerRepository(),
+		NewMockMessageRepository(),
+		service.NewTemplateServiceWithLimits(config.LimitsConfig{MaxTemplateLength: 50}),
+		NewMockPublisher(),
+		db,
+		config.ApprovalConfig{},
+	)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns", handler.NewCampaignHandler(svc).Create).Methods("POST")
+
+	req := NewJSONRequest(t, "POST", "/campaigns", map[string]interface{}{
+		"name":          "Long",
+		"channel":       "sms",
+		"base_template": strings.Repeat("a", 51),
+	})
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+	AssertContains(t, resp.Body.String(), "maximum is 50")
+	AssertEqual(t, campaignRepo.Calls["Create"], 0)
+}
+
+// TestEnforceFieldLengths tests reject and truncate modes on customer fields
+func TestEnforceFieldLengths(t *testing.T) {
+	atLimit := strings.Repeat("a", 10)
+	overLimit := strings.Repeat("b", 11)
+
+	customer := &models.Customer{FirstName: &atLimit, Location: &overLimit}
+	_, err := customer.EnforceFieldLengths(10, false)
+	AssertError(t, err, "location is 11 characters, maximum is 10")
+	AssertEqual(t, *customer.Location, overLimit)
+
+	// Multibyte values are cut on character boundaries
+	product := strings.Repeat("ñ", 12)
+	customer = &models.Customer{FirstName: &atLimit, PreferredProduct: &product}
+	warnings, err := customer.EnforceFieldLengths(10, true)
+	AssertNoError(t, err)
+
+	AssertEqual(t, *customer.FirstName, atLimit)
+	AssertEqual(t, *customer.PreferredProduct, strings.Repeat("ñ", 10))
+	AssertEqual(t, len(warnings), 1)
+	AssertEqual(t, warnings[0], "preferred_product truncated from 12 to 10 characters")
+
+	// Unset fields are ignored
+	warnings, err = (&models.Customer{}).EnforceFieldLengths(1, false)
+	AssertNoError(t, err)
+	AssertEqual(t, len(warnings), 0)
+}
+
+// TestCreateCustomer_OverflowPolicy tests the configured reject and truncate policies
+func TestCreateCustomer_OverflowPolicy(t *testing.T) {
+	customerRepo := NewMockCustomerRepository()
+	var saved *models.Customer
+	customerRepo.CreateFunc = func(ctx context.Context, customer *models.Customer) error {
+		saved = customer
+		return nil
+	}
+
+	newCustomer := func() *models.Customer {
+		name := strings.Repeat("n", service.DefaultMaxCustomerFieldLength+1)
+		return &models.Customer{Phone: "+254700000001", FirstName: &name}
+	}
+
+	// Reject is the default
+	svc := service.NewCustomerService(customerRepo, config.LimitsConfig{})
+	_, err := svc.CreateCustomer(context.Background(), newCustomer())
+	if _, ok := err.(*service.ValidationError); !ok {
+		t.Fatalf("Expected ValidationError but got %v", err)
+	}
+	AssertEqual(t, customerRepo.Calls["Create"], 0)
+
+	svc = service.NewCustomerService(customerRepo, config.LimitsConfig{CustomerFieldOverflow: config.OverflowTruncate})
+	warnings, err := svc.CreateCustomer(context.Background(), newCustomer())
+	AssertNoError(t, err)
+	AssertEqual(t, len(warnings), 1)
+	AssertEqual(t, len(*saved.FirstName), service.DefaultMaxCustomerFieldLength)
+	AssertEqual(t, customerRepo.Calls["Create"], 1)
+
+	_, err = svc.CreateCustomer(context.Background(), &models.Customer{})
+	AssertError(t, err, "validation error: phone is required")
+}
@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// redirectPattern matches a tracked link redirect under the test base URL
+var redirectPattern = regexp.MustCompile(`https://go\.example\.com/r/([A-Za-z0-9_-]{11})`)
+
+// recordingSender succeeds and keeps the content of each send
+type recordingSender struct {
+	sent []string
+}
+
+func (s *recordingSender) Send(channel models.Channel, phone string, content string, opts service.SenderOptions) *service.SendResult {
+	s.sent = append(s.sent, content)
+	return &service.SendResult{Success: true}
+}
+
+func (s *recordingSender) Capabilities() []models.Channel {
+	return []models.Channel{models.ChannelSMS, models.ChannelWhatsApp}
+}
+
+// TestLinkTracker_Rewrite tests that every http and https link is replaced in place, with
+// trailing punctuation and other schemes left alone
+func TestLinkTracker_Rewrite(t *testing.T) {
+	links := NewMockLinkRepository()
+	tracker := service.NewLinkTracker(links, "https://go.example.com/")
+
+	rendered := "Hi Amina, shop at https://shop.example.com/sale?ref=amina. Terms: HTTP://example.com/terms) " +
+		"Email mailto:help@example.com, call tel:+254700000000 or ftp://files.example.com"
+	rewritten, err := tracker.Rewrite(context.Background(), 7, rendered)
+	AssertNoError(t, err)
+
+	tokens := redirectPattern.FindAllStringSubmatch(rewritten, -1)
+	AssertEqual(t, len(tokens), 2)
+	want := "Hi Amina, shop at https://go.example.com/r/" + tokens[0][1] + ". Terms: https://go.example.com/r/" + tokens[1][1] + ") " +
+		"Email mailto:help@example.com, call tel:+254700000000 or ftp://files.example.com"
+	AssertEqual(t, rewritten, want)
+
+	AssertEqual(t, len(links.Links), 2)
+	AssertEqual(t, links.Links[0].URL, "https://shop.example.com/sale?ref=amina")
+	AssertEqual(t, links.Links[0].Position, 0)
+	AssertEqual(t, links.Links[1].URL, "HTTP://example.com/terms")
+	AssertEqual(t, links.Links[1].MessageID, 7)
+	if tokens[0][1] == tokens[1][1] {
+		t.Error("Expected each link to get its own token")
+	}
+
+	// A retry of the message reuses its links
+	again, err := tracker.Rewrite(context.Background(), 7, rendered)
+	AssertNoError(t, err)
+	AssertEqual(t, again, rewritten)
+	AssertEqual(t, len(links.Links), 2)
+
+	// Content without links is untouched and stores nothing
+	plain, err := tracker.Rewrite(context.Background(), 8, "No links here, just https:// and www.example.com")
+	AssertNoError(t, err)
+	AssertEqual(t, plain, "No links here, just https:// and www.example.com")
+	AssertEqual(t, links.Calls["EnsureLinks"], 2)
+}
+
+// TestCheckLinks tests the warning listing a template's links
+func TestCheckLinks(t *testing.T) {
+	if warning := service.CheckLinks("Hi {first_name}, email mailto:help@example.com", false); warning != nil {
+		t.Errorf("Expected no warning for a template without links, got %+v", warning)
+	}
+
+	warning := service.CheckLinks("Hi {first_name}! See https://shop.example.com/{preferred_product}, or http://example.com.", false)
+	AssertNotNil(t, warning)
+	AssertEqual(t, strings.Join(warning.URLs, " "), "https://shop.example.com/{preferred_product} http://example.com")
+	AssertEqual(t, warning.Message, "template contains 2 link(s): https://shop.example.com/{preferred_product}, http://example.com; set track_links to count clicks")
+
+	warning = service.CheckLinks("See https://shop.example.com", true)
+	AssertEqual(t, warning.Message, "template contains 1 link(s): https://shop.example.com; each is sent as a tracked redirect")
+}
+
+// TestLinkTracking_WorkerSendsRedirects tests that the worker sends tracked redirects only
+// for campaigns with track_links
+func TestLinkTracking_WorkerSendsRedirects(t *testing.T) {
+	for _, trackLinks := range []bool{true, false} {
+		db, mock := NewMockDB(t)
+		defer db.Close()
+
+		messageRepo := NewMockMessageRepository()
+		messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+			message := NewTestMessageWithStatus(models.MessageStatusPending)
+			message.ID = id
+			campaign := NewTestCampaignWithStatus(models.CampaignStatusSending)
+			campaign.BaseTemplate = "Hi {first_name}, see https://shop.example.com/{preferred_product}"
+			campaign.TrackLinks = trackLinks
+			customer := NewTestCustomer()
+			product := "premium"
+			customer.PreferredProduct = &product
+			return &models.OutboundMessageWithDetails{
+				OutboundMessage: *message,
+				Campaign:        *campaign,
+				Customer:        *customer,
+			}, nil
+		}
+		links := NewMockLinkRepository()
+		sender := &recordingSender{}
+
+		processor := service.NewMessageProcessor(db, messageRepo, service.NewTemplateService(), sender, service.NewAttemptBudget(messageRepo, 0), nil)
+		processor.SetLinkTracker(service.NewLinkTracker(links, "https://go.example.com"))
+		mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		AssertNoError(t, processor.Handle(&queue.MessageJob{MessageID: 5, CampaignID: 1, CustomerID: 1}))
+		AssertEqual(t, len(sender.sent), 1)
+		if trackLinks {
+			AssertEqual(t, len(links.Links), 1)
+			AssertEqual(t, sender.sent[0], "Hi John, see https://go.example.com/r/"+links.Links[0].Token)
+			AssertEqual(t, links.Links[0].URL, "https://shop.example.com/premium")
+		} else {
+			AssertEqual(t, len(links.Links), 0)
+			AssertEqual(t, sender.sent[0], "Hi John, see https://shop.example.com/premium")
+		}
+		AssertNoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+// TestLinkRedirect tests that a visit records the click and redirects, and unknown tokens 404
+func TestLinkRedirect(t *testing.T) {
+	links := NewMockLinkRepository()
+	links.CampaignID = 3
+	links.Links = append(links.Links, &models.TrackedLink{ID: 1, MessageID: 9, Token: "abcDEF123_-", URL: "https://shop.example.com/sale"})
+
+	router := mux.NewRouter()
+	router.HandleFunc(service.LinkRedirectPrefix+"{token}", handler.NewLinkHandler(service.NewLinkService(links)).Redirect).Methods("GET")
+	visit := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 14)")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := visit("/r/abcDEF123_-")
+	AssertStatusCode(t, rr, http.StatusFound)
+	AssertEqual(t, rr.Header().Get("Location"), "https://shop.example.com/sale")
+	AssertEqual(t, len(links.Clicks), 1)
+	AssertEqual(t, links.Clicks[0].LinkID, 1)
+	AssertEqual(t, links.Clicks[0].MessageID, 9)
+	AssertEqual(t, links.Clicks[0].CampaignID, 3)
+	AssertEqual(t, *links.Clicks[0].UserAgent, "Mozilla/5.0 (Linux; Android 14)")
+
+	AssertStatusCode(t, visit("/r/unknown"), http.StatusNotFound)
+
+	// A click that cannot be recorded still reaches the link
+	links.RecordClickFunc = func(ctx context.Context, click *models.LinkClick) error {
+		return errors.New("connection refused")
+	}
+	rr = visit("/r/abcDEF123_-")
+	AssertStatusCode(t, rr, http.StatusFound)
+	AssertEqual(t, rr.Header().Get("Location"), "https://shop.example.com/sale")
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// customerLookupColumns are the columns the customer lookups select
+var customerLookupColumns = []string{
+	"id", "phone", "first_name", "last_name", "location", "preferred_product", "contact_window_start", "contact_window_end", "created_at",
+}
+
+// arrayOf matches an array parameter with exactly the given elements, as pq sends it
+type arrayOf struct {
+	size int
+	seen *[]int
+}
+
+func (a arrayOf) Match(value driver.Value) bool {
+	literal, ok := value.(string)
+	if !ok {
+		return false
+	}
+	elements := 0
+	if inner := strings.Trim(literal, "{}"); inner != "" {
+		elements = strings.Count(inner, ",") + 1
+	}
+	if a.seen != nil {
+		*a.seen = append(*a.seen, elements)
+	}
+	return a.size < 0 || elements == a.size
+}
+
+// customerLookupRows returns customer rows for the given IDs
+func customerLookupRows(ids ...int) *sqlmock.Rows {
+	rows := sqlmock.NewRows(customerLookupColumns)
+	for _, id := range ids {
+		rows.AddRow(id, fmt.Sprintf("+25470000%04d", id), nil, nil, nil, nil, nil, nil, time.Now())
+	}
+	return rows
+}
+
+// sequentialIDs returns the IDs from 1 to n
+func sequentialIDs(n int) []int {
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	return ids
+}
+
+// TestGetByIDs_ChunkBoundaries tests the number and size of queries around the default chunk size
+func TestGetByIDs_ChunkBoundaries(t *testing.T) {
+	tests := []struct {
+		name   string
+		ids    []int
+		chunks []int
+	}{
+		{"empty", []int{}, nil},
+		{"exactly one chunk", sequentialIDs(1000), []int{1000}},
+		{"one over", sequentialIDs(1001), []int{1000, 1}},
+		{"repeats are looked up once", append(sequentialIDs(1000), 1, 500), []int{1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := NewMockDB(t)
+			defer db.Close()
+
+			for _, size := range tt.chunks {
+				mock.ExpectQuery(`SELECT (.+) FROM customers WHERE id = ANY\(\$1\)`).
+					WithArgs(arrayOf{size: size}).
+					WillReturnRows(customerLookupRows())
+			}
+
+			customers, err := repository.NewCustomerRepository(db).GetByIDs(context.Background(), tt.ids)
+			AssertNoError(t, err)
+			AssertEqual(t, len(customers), 0)
+			AssertNoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestGetByIDs_ConfiguredChunkSize tests that customers of every chunk are returned, in chunk
+// order, with missing IDs absent
+func TestGetByIDs_ConfiguredChunkSize(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM customers WHERE id = ANY`).WithArgs(arrayOf{size: 2}).WillReturnRows(customerLookupRows(1, 2))
+	mock.ExpectQuery(`FROM customers WHERE id = ANY`).WithArgs(arrayOf{size: 2}).WillReturnRows(customerLookupRows(4))
+	mock.ExpectQuery(`FROM customers WHERE id = ANY`).WithArgs(arrayOf{size: 1}).WillReturnRows(customerLookupRows(5))
+
+	repo := repository.NewCustomerRepositoryWithChunkSize(db, nil, 2)
+	customers, err := repo.GetByIDs(context.Background(), []int{1, 2, 3, 4, 5})
+	AssertNoError(t, err)
+
+	AssertEqual(t, len(customers), 4)
+	for i, id := range []int{1, 2, 4, 5} {
+		AssertEqual(t, customers[i].ID, id)
+	}
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetByIDs_ChunkError tests that a failed chunk fails the whole lookup
+func TestGetByIDs_ChunkError(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM customers WHERE id = ANY`).WillReturnRows(customerLookupRows(1, 2))
+	mock.ExpectQuery(`FROM customers WHERE id = ANY`).WillReturnError(fmt.Errorf("connection reset"))
+
+	_, err := repository.NewCustomerRepositoryWithChunkSize(db, nil, 2).GetByIDs(context.Background(), []int{1, 2, 3})
+	AssertError(t, err, "failed to get customers: connection reset")
+}
+
+// TestGetByPhones_Chunked tests that phone lookups are chunked like ID lookups
+func TestGetByPhones_Chunked(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM customers WHERE normalize_phone\(phone\) = ANY\(\$1\)`).
+		WithArgs(arrayOf{size: 2}).
+		WillReturnRows(customerLookupRows(1))
+	mock.ExpectQuery(`FROM customers WHERE normalize_phone\(phone\) = ANY\(\$1\)`).
+		WithArgs(arrayOf{size: 1}).
+		WillReturnRows(customerLookupRows(3))
+
+	repo := repository.NewCustomerRepositoryWithChunkSize(db, nil, 2)
+	customers, err := repo.GetByPhones(context.Background(), []string{"+254700000001", "+254700000002", "+254700000001", "+254700000003"})
+	AssertNoError(t, err)
+
+	AssertEqual(t, len(customers), 2)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// BenchmarkGetByIDs_10k compares looking up 10,000 customers in one query with the default
+// chunks; largest-query-ids shows how much each query holds at once
+// Run with: go test ./tests -run '^$' -bench GetByIDs -benchmem
+func BenchmarkGetByIDs_10k(b *testing.B) {
+	ids := sequentialIDs(10000)
+
+	for _, chunkSize := range []int{10000, repository.DefaultLookupChunkSize} {
+		b.Run(fmt.Sprintf("chunk=%d", chunkSize), func(b *testing.B) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer db.Close()
+			repo := repository.NewCustomerRepositoryWithChunkSize(db, nil, chunkSize)
+
+			seen := []int{}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				for start := 0; start < len(ids); start += chunkSize {
+					mock.ExpectQuery(`FROM customers WHERE id = ANY`).
+						WithArgs(arrayOf{size: -1, seen: &seen}).
+						WillReturnRows(customerLookupRows(ids[start : start+chunkSize]...))
+				}
+				b.StartTimer()
+
+				customers, err := repo.GetByIDs(context.Background(), ids)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(customers) != len(ids) {
+					b.Fatalf("expected %d customers, got %d", len(ids), len(customers))
+				}
+			}
+
+			largest := 0
+			for _, size := range seen {
+				largest = max(largest, size)
+			}
+			b.ReportMetric(float64(largest), "largest-query-ids")
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+// threePartTemplate is a WhatsApp announcement sent to each customer as three messages
+const threePartTemplate = "Hi {first_name}, big news! --- Our new store opens Monday. --- See you there, {first_name}"
+
+// messageGroupFixture is a processor whose message repository serves the three parts, IDs 11
+// to 13, of group 11, recording each part published after the part before it is sent
+type messageGroupFixture struct {
+	processor   *service.MessageProcessor
+	messageRepo *MockMessageRepository
+	mock        sqlmock.Sqlmock
+	sender      *recordingSender
+	parts       map[int]*models.OutboundMessage
+	published   []*models.OutboundMessage
+}
+
+func newMessageGroupFixture(t *testing.T) *messageGroupFixture {
+	t.Helper()
+
+	campaign := NewTestCampaignWithStatus(models.CampaignStatusSending)
+	campaign.Channel = models.ChannelWhatsApp
+	campaign.BaseTemplate = threePartTemplate
+	campaign.PartDelimiter = StringPtr("---")
+
+	f := &messageGroupFixture{
+		messageRepo: NewMockMessageRepository(),
+		sender:      &recordingSender{},
+		parts:       make(map[int]*models.OutboundMessage),
+	}
+	groupID := 11
+	for part := 1; part <= 3; part++ {
+		message := NewTestMessage(campaign.ID, 1)
+		message.ID = 10 + part
+		message.RenderedContent = nil
+		message.GroupID = &groupID
+		message.Part = part
+		message.PartCount = 3
+		f.parts[message.ID] = message
+	}
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		message, ok := f.parts[id]
+		if !ok {
+			return nil, repository.ErrMessageNotFound
+		}
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *message,
+			Campaign:        *campaign,
+			Customer:        *NewTestCustomer(),
+		}, nil
+	}
+	f.messageRepo.ClaimNextPartFunc = func(ctx context.Context, groupID, part int) (*models.OutboundMessage, error) {
+		for _, message := range f.parts {
+			if *message.GroupID == groupID && message.Part == part {
+				return &models.OutboundMessage{ID: message.ID, CampaignID: message.CampaignID, CustomerID: message.CustomerID, GroupID: message.GroupID, Part: part}, nil
+			}
+		}
+		return nil, nil
+	}
+
+	f.processor, f.mock = NewMockMessageProcessor(t, f.messageRepo, f.sender)
+	f.processor.SetNextPartPublisher(func(message *models.OutboundMessage) error {
+		f.published = append(f.published, message)
+		return nil
+	})
+	return f
+}
+
+func (f *messageGroupFixture) handle(messageID int) error {
+	return f.processor.Handle(&queue.MessageJob{MessageID: messageID, CampaignID: f.parts[messageID].CampaignID, CustomerID: 1})
+}
+
+// TestMessageGroup_FailureOnPartTwo tests a three-part send whose second part fails for good:
+// part one is sent and publishes part two, and part two's failure fails part three unsent
+func TestMessageGroup_FailureOnPartTwo(t *testing.T) {
+	f := newMessageGroupFixture(t)
+
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WithArgs(11, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	AssertNoError(t, f.handle(11))
+	AssertEqual(t, strings.Join(f.sender.sent, "|"), "Hi John, big news!")
+	AssertEqual(t, len(f.published), 1)
+	AssertEqual(t, f.published[0].ID, 12)
+	AssertEqual(t, f.published[0].Part, 2)
+
+	// Part two exhausted its retries
+	f.parts[12].Status = models.MessageStatusFailed
+	f.parts[12].RetryCount = 3
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'failed', last_error = 'Exceeded maximum retry attempts \\(3\\)'").
+		WithArgs(12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	f.mock.ExpectExec(`UPDATE outbound_messages SET status = \$3, retry_count = GREATEST\(retry_count, 3\), last_error = \$4, updated_at = NOW\(\) WHERE group_id = \$1 AND part > \$2 AND status = 'pending'`).
+		WithArgs(11, 2, models.MessageStatusFailed, "Part 2 of the message group failed").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	AssertNoError(t, f.handle(12))
+	AssertEqual(t, len(f.sender.sent), 1)
+	AssertEqual(t, len(f.published), 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestMessageGroup_SendsEachPartInOrder tests that each part renders its own piece of the
+// template and the last part publishes nothing further
+func TestMessageGroup_SendsEachPartInOrder(t *testing.T) {
+	f := newMessageGroupFixture(t)
+
+	for id := 11; id <= 13; id++ {
+		f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+			WithArgs(id, false).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		AssertNoError(t, f.handle(id))
+	}
+
+	AssertEqual(t, strings.Join(f.sender.sent, "|"), "Hi John, big news!|Our new store opens Monday.|See you there, John")
+	AssertEqual(t, len(f.published), 2)
+	AssertEqual(t, f.published[0].ID, 12)
+	AssertEqual(t, f.published[1].ID, 13)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestMessageGroup_DefersPartThatFailsToPublish tests that a next part the broker refuses is
+// handed to the deferred requeue rather than left claimed
+func TestMessageGroup_DefersPartThatFailsToPublish(t *testing.T) {
+	f := newMessageGroupFixture(t)
+	f.processor.SetNextPartPublisher(func(message *models.OutboundMessage) error {
+		return errors.New("connection closed")
+	})
+	var deferred []int
+	f.messageRepo.DeferUntilFunc = func(ctx context.Context, id int, until time.Time, reason string) error {
+		deferred = append(deferred, id)
+		return nil
+	}
+
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	AssertNoError(t, f.handle(11))
+	AssertEqual(t, len(deferred), 1)
+	AssertEqual(t, deferred[0], 12)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestMessageGroup_CreateBatchGroupsParts tests that the parts of each customer's send are
+// grouped under the ID of their first part
+func TestMessageGroup_CreateBatchGroupsParts(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	messages := make([]*models.OutboundMessage, 0, 4)
+	for _, customerID := range []int{1, 2} {
+		for part := 1; part <= 2; part++ {
+			messages = append(messages, &models.OutboundMessage{CampaignID: 5, CustomerID: customerID, Status: models.MessageStatusPending, Part: part, PartCount: 2})
+		}
+	}
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO outbound_messages")
+	for id := 21; id <= 24; id++ {
+		mock.ExpectQuery("INSERT INTO outbound_messages").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(id, now, now))
+	}
+	mock.ExpectExec(`UPDATE outbound_messages m SET group_id = parts.group_id, part = parts.part, part_count = parts.part_count FROM unnest`).
+		WithArgs(pq.Array([]int64{21, 22, 23, 24}), pq.Array([]int64{21, 21, 23, 23}), pq.Array([]int64{1, 2, 1, 2}), pq.Array([]int64{2, 2, 2, 2})).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	err := repository.NewMessageRepository(db).CreateBatch(context.Background(), messages)
+	AssertNoError(t, err)
+	AssertEqual(t, *messages[1].GroupID, 21)
+	AssertEqual(t, *messages[3].GroupID, 23)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestMessageGroup_Stats tests that campaign stats count complete and partial groups
+func TestMessageGroup_Stats(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaign := NewTestCampaignWithStatus(models.CampaignStatusSending)
+	stats := []driver.Value{
+		9, 2, 5, 2, 0, 2, 0, nil, 0, nil,
+		nil, nil, nil, nil, nil, nil,
+		0, 0, 0, 0, nil,
+		0,
+		3, 1, 2,
+	}
+	mock.ExpectQuery(CampaignWithStatsQuery).
+		WithArgs(campaign.ID).
+		WillReturnRows(NewCampaignWithStatsRows(campaign, stats...))
+
+	result, err := repository.NewCampaignRepository(db).GetWithStats(context.Background(), campaign.ID)
+	AssertNoError(t, err)
+	AssertNotNil(t, result.Stats.Groups)
+	AssertEqual(t, *result.Stats.Groups, models.GroupStats{Total: 3, Complete: 1, Partial: 2})
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestMessageGroup_ValidatePartDelimiter tests the part_delimiter campaign option
+func TestMessageGroup_ValidatePartDelimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		delimiter string
+		err       string
+	}{
+		{"three parts", threePartTemplate, "---", ""},
+		{"blank delimiter", threePartTemplate, "  ", "part_delimiter must be 1 to 20 characters, not only whitespace"},
+		{"one part", "Hi {first_name}", "---", "base_template must split into 2 to 5 parts on part_delimiter, not 1"},
+		{"too many parts", "a---b---c---d---e---f", "---", "base_template must split into 2 to 5 parts on part_delimiter, not 6"},
+		{"empty part", "Hi {first_name} --- ---  Bye", "---", "part 2 of base_template is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &service.CreateCampaignRequest{
+				Name:          "Launch",
+				Channel:       models.ChannelWhatsApp,
+				BaseTemplate:  tt.template,
+				PartDelimiter: StringPtr(tt.delimiter),
+			}
+			err := req.Validate()
+			if tt.err == "" {
+				AssertNoError(t, err)
+				return
+			}
+			AssertError(t, err, tt.err)
+		})
+	}
+}
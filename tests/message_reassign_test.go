@@ -0,0 +1,231 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/middleware"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// newReassignFixture returns a reassigner over campaigns 1 and 2, both sending SMS, and an
+// in-memory repository holding the given messages
+func newReassignFixture(campaigns map[int]*models.Campaign, messages []*models.OutboundMessage, batchSize int) (*service.MessageReassigner, *MockMessageReassignmentRepository) {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		campaign, ok := campaigns[id]
+		if !ok {
+			return nil, repository.ErrCampaignNotFound
+		}
+		return campaign, nil
+	}
+	reassignments := NewMockMessageReassignmentRepository()
+	reassignments.Messages = messages
+	return service.NewMessageReassigner(campaignRepo, reassignments, batchSize), reassignments
+}
+
+// reassignCampaigns returns a sending source campaign and a draft target with a corrected template
+func reassignCampaigns() map[int]*models.Campaign {
+	source := NewTestCampaignWithStatus(models.CampaignStatusSending)
+	target := NewTestCampaignWithTemplate("Hello {first_name}, try {preferred_product} today!")
+	target.ID = 2
+	return map[int]*models.Campaign{1: source, 2: target}
+}
+
+// countByCampaign counts messages per campaign and status
+func countByCampaign(messages []*models.OutboundMessage, campaignID int, status models.MessageStatus) int {
+	count := 0
+	for _, message := range messages {
+		if message.CampaignID == campaignID && message.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// TestReassign_MovesInBatches tests that every matching message moves, other statuses stay,
+// and the audit entry counts what moved
+func TestReassign_MovesInBatches(t *testing.T) {
+	messages := NewTestMessages(1, customerIDs(25))
+	for _, message := range messages[20:] {
+		message.Status = models.MessageStatusSent
+	}
+	campaigns := reassignCampaigns()
+	reassigner, repo := newReassignFixture(campaigns, messages, 8)
+
+	var progress []int
+	reassignment, err := reassigner.Reassign(context.Background(), &service.ReassignMessagesRequest{
+		FromCampaignID: 1,
+		ToCampaignID:   2,
+		RequestedBy:    "ops@example.com",
+	}, func(moved int) { progress = append(progress, moved) })
+	AssertNoError(t, err)
+
+	AssertEqual(t, reassignment.MessagesMoved, 20)
+	AssertEqual(t, reassignment.Status, models.MessageStatusPending)
+	AssertEqual(t, *reassignment.RequestedBy, "ops@example.com")
+	AssertEqual(t, len(repo.Reassignments), 1)
+	AssertEqual(t, repo.Calls["MoveBatch"], 4) // 8 + 8 + 4, then none left
+	AssertEqual(t, len(progress), 3)
+	AssertEqual(t, progress[2], 20)
+
+	AssertEqual(t, countByCampaign(messages, 1, models.MessageStatusPending), 0)
+	AssertEqual(t, countByCampaign(messages, 2, models.MessageStatusPending), 20)
+	AssertEqual(t, countByCampaign(messages, 1, models.MessageStatusSent), 5)
+	for _, message := range messages[:20] {
+		AssertEqual(t, *message.ContentFingerprint, campaigns[2].ContentFingerprint())
+	}
+
+	// Running it again finds nothing left to move
+	again, err := reassigner.Reassign(context.Background(), &service.ReassignMessagesRequest{FromCampaignID: 1, ToCampaignID: 2}, nil)
+	AssertNoError(t, err)
+	AssertEqual(t, again.MessagesMoved, 0)
+	AssertEqual(t, countByCampaign(messages, 2, models.MessageStatusPending), 20)
+}
+
+// TestReassign_FailedBatchKeepsCount tests that a batch failing part way leaves the earlier
+// batches moved and counted
+func TestReassign_FailedBatchKeepsCount(t *testing.T) {
+	reassigner, repo := newReassignFixture(reassignCampaigns(), NewTestMessages(1, customerIDs(10)), 4)
+	repo.MoveBatchFunc = func(ctx context.Context, reassignment *models.MessageReassignment, fingerprint string, limit int) (int, error) {
+		if repo.Calls["MoveBatch"] == 2 {
+			return 0, errors.New("connection reset")
+		}
+		reassignment.MessagesMoved += limit
+		return limit, nil
+	}
+
+	reassignment, err := reassigner.Reassign(context.Background(), &service.ReassignMessagesRequest{FromCampaignID: 1, ToCampaignID: 2}, nil)
+	AssertError(t, err, "reassignment failed after moving 4 messages: connection reset")
+	AssertEqual(t, reassignment.MessagesMoved, 4)
+}
+
+// TestReassign_Validation tests that moves between channels, to finished campaigns or of
+// unsupported statuses are refused before anything is logged
+func TestReassign_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(campaigns map[int]*models.Campaign)
+		req     service.ReassignMessagesRequest
+		wantErr string
+	}{
+		{
+			name:    "same campaign",
+			req:     service.ReassignMessagesRequest{FromCampaignID: 1, ToCampaignID: 1},
+			wantErr: "validation error: from_campaign_id and to_campaign_id must differ",
+		},
+		{
+			name:    "missing target",
+			req:     service.ReassignMessagesRequest{FromCampaignID: 1},
+			wantErr: "validation error: from_campaign_id and to_campaign_id are required",
+		},
+		{
+			name:    "sent status",
+			req:     service.ReassignMessagesRequest{FromCampaignID: 1, ToCampaignID: 2, Status: models.MessageStatusSent},
+			wantErr: "validation error: status must be pending or failed",
+		},
+		{
+			name:    "unknown campaign",
+			req:     service.ReassignMessagesRequest{FromCampaignID: 1, ToCampaignID: 3},
+			wantErr: "campaign with ID 3 not found",
+		},
+		{
+			name:    "channel mismatch",
+			setup:   func(campaigns map[int]*models.Campaign) { campaigns[2].Channel = models.ChannelWhatsApp },
+			req:     service.ReassignMessagesRequest{FromCampaignID: 1, ToCampaignID: 2},
+			wantErr: "business logic error: campaigns must share a channel: campaign 1 is sms, campaign 2 is whatsapp",
+		},
+		{
+			name:    "terminal target",
+			setup:   func(campaigns map[int]*models.Campaign) { campaigns[2].Status = models.CampaignStatusSent },
+			req:     service.ReassignMessagesRequest{FromCampaignID: 1, ToCampaignID: 2},
+			wantErr: "business logic error: messages cannot be moved to campaign 2: status is sent",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			campaigns := reassignCampaigns()
+			if tt.setup != nil {
+				tt.setup(campaigns)
+			}
+			messages := NewTestMessages(1, customerIDs(3))
+			reassigner, repo := newReassignFixture(campaigns, messages, 0)
+
+			_, err := reassigner.Reassign(context.Background(), &tt.req, nil)
+			AssertError(t, err, tt.wantErr)
+			AssertEqual(t, repo.Calls["Create"], 0)
+			AssertEqual(t, countByCampaign(messages, 1, models.MessageStatusPending), 3)
+		})
+	}
+}
+
+// TestReassignmentRepository_MoveBatch tests that a batch moves messages and raises the
+// audit count in one transaction
+func TestReassignmentRepository_MoveBatch(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewMessageReassignmentRepository(db)
+	reassignment := &models.MessageReassignment{ID: 7, FromCampaignID: 1, ToCampaignID: 2, Status: models.MessageStatusPending, MessagesMoved: 500}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE outbound_messages SET campaign_id = \\$2").
+		WithArgs(1, 2, "fp", models.MessageStatusPending, 500).
+		WillReturnResult(sqlmock.NewResult(0, 120))
+	mock.ExpectExec("UPDATE message_reassignments SET messages_moved = messages_moved \\+ \\$2").
+		WithArgs(7, int64(120)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	moved, err := repo.MoveBatch(context.Background(), reassignment, "fp", 500)
+	AssertNoError(t, err)
+	AssertEqual(t, moved, 120)
+	AssertEqual(t, reassignment.MessagesMoved, 620)
+
+	// A failed audit update rolls the batch back
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE outbound_messages").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("UPDATE message_reassignments").WillReturnError(errors.New("deadlock detected"))
+	mock.ExpectRollback()
+
+	_, err = repo.MoveBatch(context.Background(), reassignment, "fp", 500)
+	AssertError(t, err, "failed to update message reassignment: deadlock detected")
+	AssertEqual(t, reassignment.MessagesMoved, 620)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestReassignEndpoint tests that the endpoint needs the admin key and returns the audit entry
+func TestReassignEndpoint(t *testing.T) {
+	messages := NewTestMessages(1, customerIDs(3))
+	reassigner, _ := newReassignFixture(reassignCampaigns(), messages, 0)
+	router := mux.NewRouter()
+	requireAdmin := middleware.RequireAdminKey("secret")
+	router.Handle("/admin/messages/reassign", requireAdmin(http.HandlerFunc(handler.NewMessageReassignHandler(reassigner).Reassign))).Methods("POST")
+	body := map[string]interface{}{"from_campaign_id": 1, "to_campaign_id": 2}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, NewJSONRequest(t, "POST", "/admin/messages/reassign", body))
+	AssertStatusCode(t, rr, http.StatusUnauthorized)
+	AssertEqual(t, countByCampaign(messages, 2, models.MessageStatusPending), 0)
+
+	req := NewJSONRequest(t, "POST", "/admin/messages/reassign", body)
+	req.Header.Set(middleware.AdminKeyHeader, "secret")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	AssertStatusCode(t, rr, http.StatusOK)
+
+	var reassignment models.MessageReassignment
+	ParseJSONResponse(t, rr, &reassignment)
+	AssertEqual(t, reassignment.MessagesMoved, 3)
+	AssertEqual(t, reassignment.Status, models.MessageStatusPending)
+	AssertEqual(t, countByCampaign(messages, 2, models.MessageStatusPending), 3)
+}
@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// resendFixture serves POST /messages/{id}/resend over mock repositories holding sent message 7
+// of sent campaign 1, with its content stored
+type resendFixture struct {
+	router      *mux.Router
+	campaign    *models.Campaign
+	original    *models.OutboundMessage
+	messageRepo *MockMessageRepository
+	verbatim    *bool
+	fingerprint *string
+}
+
+func newResendFixture(t *testing.T) *resendFixture {
+	t.Helper()
+
+	f := &resendFixture{
+		campaign:    NewTestCampaignWithStatus(models.CampaignStatusSent),
+		original:    NewTestMessageWithStatus(models.MessageStatusSent),
+		messageRepo: NewMockMessageRepository(),
+	}
+	f.original.ID = 7
+
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return f.campaign, nil
+	}
+	f.messageRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.OutboundMessage, error) {
+		if id != f.original.ID {
+			return nil, repository.ErrMessageNotFound
+		}
+		return f.original, nil
+	}
+	f.messageRepo.CreateResendFunc = func(ctx context.Context, originalID int, verbatim bool, fingerprint *string) (*models.OutboundMessage, error) {
+		f.verbatim, f.fingerprint = &verbatim, fingerprint
+		resend := &models.OutboundMessage{ID: 8, CampaignID: f.original.CampaignID, CustomerID: f.original.CustomerID, Status: models.MessageStatusPending, ResentFromMessageID: &originalID}
+		if verbatim {
+			resend.RenderedContent = f.original.RenderedContent
+		}
+		return resend, nil
+	}
+
+	svc, _ := NewMockCampaignService(t, campaignRepo, f.messageRepo)
+	f.router = NewTestRouter(map[string]http.HandlerFunc{
+		"POST /messages/{id:[0-9]+}/resend": handler.NewCampaignHandler(svc).ResendMessage,
+	})
+	return f
+}
+
+func (f *resendFixture) resend(id, body string) *httptest.ResponseRecorder {
+	return ServeTestRequest(f.router, "POST", "/messages/"+id+"/resend", body)
+}
+
+// TestResend_Verbatim tests that a sent message is resent as a new message with the
+// original's stored content, pointing back at the original
+func TestResend_Verbatim(t *testing.T) {
+	f := newResendFixture(t)
+
+	rr := f.resend("7", "")
+	AssertStatusCode(t, rr, http.StatusCreated)
+	var resend models.OutboundMessage
+	ParseJSONResponse(t, rr, &resend)
+	AssertEqual(t, resend.ID, 8)
+	AssertEqual(t, *resend.ResentFromMessageID, 7)
+	AssertEqual(t, resend.Status, models.MessageStatusPending)
+	AssertEqual(t, *resend.RenderedContent, *f.original.RenderedContent)
+	AssertEqual(t, *f.verbatim, true)
+	AssertEqual(t, f.fingerprint == nil, true)
+	// The original is untouched
+	AssertEqual(t, f.original.Status, models.MessageStatusSent)
+}
+
+// TestResend_Rerender tests that a re-rendered resend leaves rendering to the worker and
+// carries the current template's fingerprint
+func TestResend_Rerender(t *testing.T) {
+	f := newResendFixture(t)
+	f.original.Status = models.MessageStatusFailed
+	f.original.RenderedContent = nil
+
+	rr := f.resend("7", `{"rerender": true}`)
+	AssertStatusCode(t, rr, http.StatusCreated)
+	var resend models.OutboundMessage
+	ParseJSONResponse(t, rr, &resend)
+	AssertEqual(t, resend.RenderedContent == nil, true)
+	AssertEqual(t, *f.verbatim, false)
+	AssertEqual(t, *f.fingerprint, f.campaign.ContentFingerprint())
+}
+
+// TestResend_UnstampedDeferred tests that a resend whose publish time cannot be recorded is
+// deferred rather than left unpublished, as the reconciler skips campaigns no longer sending
+func TestResend_UnstampedDeferred(t *testing.T) {
+	f := newResendFixture(t)
+	f.messageRepo.MarkPublishedFunc = func(ctx context.Context, ids []int) error {
+		return driver.ErrBadConn
+	}
+	deferred := 0
+	f.messageRepo.DeferUntilFunc = func(ctx context.Context, id int, until time.Time, reason string) error {
+		AssertEqual(t, id, 8)
+		AssertEqual(t, reason, "Deferred: failed to publish")
+		deferred++
+		return nil
+	}
+
+	rr := f.resend("7", "")
+	AssertStatusCode(t, rr, http.StatusCreated)
+	AssertEqual(t, deferred, 1)
+}
+
+// TestResend_Refused tests the messages that cannot be resent
+func TestResend_Refused(t *testing.T) {
+	f := newResendFixture(t)
+	AssertStatusCode(t, f.resend("9", ""), http.StatusNotFound)
+
+	f.original.RenderedContent = nil
+	rr := f.resend("7", "")
+	AssertStatusCode(t, rr, http.StatusBadRequest)
+	AssertContains(t, rr.Body.String(), `message 7 has no stored content to resend verbatim`)
+
+	f.original.Status = models.MessageStatusPending
+	rr = f.resend("7", `{"rerender": true}`)
+	AssertStatusCode(t, rr, http.StatusBadRequest)
+	AssertContains(t, rr.Body.String(), "only sent or failed messages can be resent; message 7 is pending")
+
+	f.original.Status = models.MessageStatusFailed
+	f.campaign.Status = models.CampaignStatusCancelled
+	AssertStatusCode(t, f.resend("7", `{"rerender": true}`), http.StatusBadRequest)
+	AssertEqual(t, f.messageRepo.Calls["CreateResend"], 0)
+}
+
+// TestCreateResend_Query tests that the copy is inserted from the original in one statement
+func TestCreateResend_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO outbound_messages \(campaign_id, customer_id, status, rendered_content, content_fingerprint, part, part_count, resent_from_message_id\) SELECT campaign_id, customer_id, 'pending', CASE WHEN \$2 THEN rendered_content END, COALESCE\(\$3, content_fingerprint\), part, part_count, id FROM outbound_messages WHERE id = \$1`).
+		WithArgs(7, true, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "customer_id", "status", "rendered_content", "retry_count", "part", "part_count", "resent_from_message_id", "created_at", "updated_at"}).
+			AddRow(8, 1, 2, "pending", "Hello Ann", 0, 1, 1, 7, now, now))
+
+	resend, err := repository.NewMessageRepository(db).CreateResend(context.Background(), 7, true, nil)
+	AssertNoError(t, err)
+	AssertEqual(t, resend.ID, 8)
+	AssertEqual(t, *resend.ResentFromMessageID, 7)
+	AssertEqual(t, *resend.RenderedContent, "Hello Ann")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestWorker_ResendVerbatim tests that the worker sends a verbatim resend's stored content,
+// without rendering or a duplicate content check
+func TestWorker_ResendVerbatim(t *testing.T) {
+	f := newDuplicateSendFixture(t)
+	campaign := f.addCampaign(2, "Hi {first_name}, our sale ends Sunday")
+	f.recordSent(campaign, f.now.Add(-time.Hour))
+	sender := &recordingSender{}
+	stored := "Hi John, our sale ends Sunday (sent May 3)"
+	originalID := 2
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		message := NewTestMessage(campaign.ID, 1)
+		message.ID = id
+		message.RenderedContent = &stored
+		message.ResentFromMessageID = &originalID
+		return &models.OutboundMessageWithDetails{OutboundMessage: *message, Campaign: *campaign, Customer: *NewTestCustomer()}, nil
+	}
+	processor, mock := NewMockMessageProcessor(t, f.messageRepo, sender)
+	processor.SetDuplicateSendWindow(6 * time.Hour)
+
+	mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WithArgs(8, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	AssertNoError(t, processor.Handle(&queue.MessageJob{MessageID: 8, CampaignID: 2, CustomerID: 1}))
+	AssertEqual(t, strings.Join(sender.sent, "|"), stored)
+	AssertEqual(t, f.messageRepo.Calls["HasRecentFingerprintSend"], 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestResend_Stats tests that resends are counted in their own bucket
+func TestResend_Stats(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaign := NewTestCampaignWithStatus(models.CampaignStatusSent)
+	stats := []driver.Value{
+		10, 0, 8, 2, 0, 0, 0, nil, 0, nil,
+		nil, nil, nil, nil, nil, nil,
+		0, 0, 0, 0, nil,
+		0,
+		0, 0, 0,
+		3, 1, 2, 0,
+	}
+	mock.ExpectQuery(CampaignWithStatsQuery).
+		WithArgs(campaign.ID).
+		WillReturnRows(NewCampaignWithStatsRows(campaign, stats...))
+
+	result, err := repository.NewCampaignRepository(db).GetWithStats(context.Background(), campaign.ID)
+	AssertNoError(t, err)
+	AssertEqual(t, result.Stats.Total, 10)
+	AssertEqual(t, result.Stats.Groups == nil, true)
+	AssertNotNil(t, result.Stats.Resent)
+	AssertEqual(t, *result.Stats.Resent, models.ResentStats{Total: 3, Pending: 1, Sent: 2})
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
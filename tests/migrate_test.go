@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"smsleopard/internal/migrate"
+)
+
+// migrationsDir is the repository's migrations, relative to the tests package
+const migrationsDir = "../migrations"
+
+// TestMigrate_Files tests that migration files are found in version order and other files ignored
+func TestMigrate_Files(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"010_add_index.sql", "002_create_users.sql", "001_init.sql", "README.md", "03_short.sql"} {
+		AssertNoError(t, os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o644))
+	}
+	AssertNoError(t, os.Mkdir(filepath.Join(dir, "004_directory.sql"), 0o755))
+
+	files, err := migrate.Files(dir)
+	AssertNoError(t, err)
+	AssertEqual(t, len(files), 3)
+	AssertEqual(t, files[0].Version, 1)
+	AssertEqual(t, files[0].Name, "init")
+	AssertEqual(t, files[1].Version, 2)
+	AssertEqual(t, files[2].Version, 10)
+	AssertEqual(t, files[2].FilePath, filepath.Join(dir, "010_add_index.sql"))
+
+	missing, err := migrate.Files(filepath.Join(dir, "missing"))
+	AssertNoError(t, err)
+	AssertEqual(t, len(missing), 0)
+}
+
+// TestMigrate_UpDownStatus tests applying, rolling back and reapplying the newest migration
+// against the integration database
+func TestMigrate_UpDownStatus(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	files, err := migrate.Files(migrationsDir)
+	AssertNoError(t, err)
+	newest := files[len(files)-1]
+
+	_, err = migrate.Up(db, migrationsDir)
+	AssertNoError(t, err)
+
+	migrations, err := migrate.Status(db, migrationsDir)
+	AssertNoError(t, err)
+	AssertEqual(t, len(migrations), len(files))
+	for _, migration := range migrations {
+		if !migration.Applied || migration.AppliedAt == nil {
+			t.Errorf("Expected migration %03d to be applied", migration.Version)
+		}
+	}
+
+	// Nothing is left to apply
+	applied, err := migrate.Up(db, migrationsDir)
+	AssertNoError(t, err)
+	AssertEqual(t, len(applied), 0)
+
+	rolledBack, err := migrate.Down(db, migrationsDir, 1)
+	AssertNoError(t, err)
+	AssertEqual(t, len(rolledBack), 1)
+	AssertEqual(t, rolledBack[0].Version, newest.Version)
+
+	migrations, err = migrate.Status(db, migrationsDir)
+	AssertNoError(t, err)
+	AssertEqual(t, migrations[len(migrations)-1].Applied, false)
+
+	applied, err = migrate.Up(db, migrationsDir)
+	AssertNoError(t, err)
+	AssertEqual(t, len(applied), 1)
+	AssertEqual(t, applied[0].Version, newest.Version)
+}
+
+// TestMigrate_ConcurrentUp tests that replicas migrating at once apply each migration only once
+func TestMigrate_ConcurrentUp(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	_, err := migrate.Up(db, migrationsDir)
+	AssertNoError(t, err)
+	_, err = migrate.Down(db, migrationsDir, 2)
+	AssertNoError(t, err)
+
+	var wg sync.WaitGroup
+	results := make([][]migrate.Migration, 4)
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = migrate.Up(db, migrationsDir)
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range results {
+		AssertNoError(t, errs[i])
+		total += len(results[i])
+	}
+	AssertEqual(t, total, 2)
+}
@@ -2,31 +2,68 @@ package tests
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
 	"smsleopard/internal/repository"
+	"sort"
+	"sync"
 	"time"
 )
 
+// callLog counts a mock's method calls. Services call some mocks from several goroutines at
+// once, so counting is guarded; tests read Calls once those calls have returned
+type callLog struct {
+	callsMu sync.Mutex
+	Calls   map[string]int // Track method calls
+}
+
+// record counts a call to method and returns how many there have been
+func (l *callLog) record(method string) int {
+	l.callsMu.Lock()
+	defer l.callsMu.Unlock()
+	l.Calls[method]++
+	return l.Calls[method]
+}
+
 // MockCustomerRepository mocks CustomerRepository
 type MockCustomerRepository struct {
-	CreateFunc   func(ctx context.Context, customer *models.Customer) error
-	GetByIDFunc  func(ctx context.Context, id int) (*models.Customer, error)
-	GetByIDsFunc func(ctx context.Context, ids []int) ([]*models.Customer, error)
-	ListFunc     func(ctx context.Context, limit, offset int) ([]*models.Customer, error)
-	UpdateFunc   func(ctx context.Context, customer *models.Customer) error
-	DeleteFunc   func(ctx context.Context, id int) error
+	CreateFunc             func(ctx context.Context, customer *models.Customer) error
+	UpsertByPhoneFunc      func(ctx context.Context, customer *models.Customer) (bool, error)
+	GetByIDFunc            func(ctx context.Context, id int) (*models.Customer, error)
+	GetByIDsFunc           func(ctx context.Context, ids []int) ([]*models.Customer, error)
+	GetByPhonesFunc        func(ctx context.Context, phones []string) ([]*models.Customer, error)
+	ListFunc               func(ctx context.Context, limit, offset int) ([]*models.Customer, error)
+	ListFilteredFunc       func(ctx context.Context, filters repository.CustomerFilters, limit, offset int) ([]*models.Customer, error)
+	UpdateFunc             func(ctx context.Context, customer *models.Customer, changedBy *string) error
+	DeleteFunc             func(ctx context.Context, id int) error
+	DeleteWithMessagesFunc func(ctx context.Context, id int) (int, error)
 
-	Calls map[string]int // Track method calls
+	GetStatsFunc           func(ctx context.Context, location *string) (*models.CustomerStats, error)
+	GetTimelineFunc        func(ctx context.Context, customerID int, after *models.TimelineCursor, limit int) ([]*models.TimelineEvent, error)
+	CountMissingFieldsFunc func(ctx context.Context, ids []int, fields []string) (*models.FieldCompleteness, error)
+	CountFilteredFunc      func(ctx context.Context, filters repository.CustomerFilters) (int, error)
+	StreamFilteredFunc     func(ctx context.Context, filters repository.CustomerFilters, limit int, fn func(customer *models.Customer) error) error
+	GetDetailFunc          func(ctx context.Context, id int) (*models.Customer, error)
+	BlockFunc              func(ctx context.Context, id int, reason string, actor *string) error
+	UnblockFunc            func(ctx context.Context, id int, actor *string) error
+	ListBlockedFunc        func(ctx context.Context, ids []int) ([]int, error)
+	ListBelowScoreFunc     func(ctx context.Context, ids []int, minScore float64) ([]int, error)
+	ListBlockedPhonesFunc  func(ctx context.Context, phones []string) ([]string, error)
+	ListBlockEventsFunc    func(ctx context.Context, id int) ([]*models.CustomerBlockEvent, error)
+	ListPhoneHistoryFunc   func(ctx context.Context, id int) ([]*models.CustomerPhoneChange, error)
+	callLog
 }
 
 func NewMockCustomerRepository() *MockCustomerRepository {
 	return &MockCustomerRepository{
-		Calls: make(map[string]int),
+		callLog: callLog{Calls: make(map[string]int)},
 	}
 }
 
 func (m *MockCustomerRepository) Create(ctx context.Context, customer *models.Customer) error {
-	m.Calls["Create"]++
+	m.record("Create")
 	if m.CreateFunc != nil {
 		return m.CreateFunc(ctx, customer)
 	}
@@ -35,8 +72,18 @@ func (m *MockCustomerRepository) Create(ctx context.Context, customer *models.Cu
 	return nil
 }
 
+func (m *MockCustomerRepository) UpsertByPhone(ctx context.Context, customer *models.Customer) (bool, error) {
+	calls := m.record("UpsertByPhone")
+	if m.UpsertByPhoneFunc != nil {
+		return m.UpsertByPhoneFunc(ctx, customer)
+	}
+	customer.ID = calls
+	customer.CreatedAt = time.Now()
+	return true, nil
+}
+
 func (m *MockCustomerRepository) GetByID(ctx context.Context, id int) (*models.Customer, error) {
-	m.Calls["GetByID"]++
+	m.record("GetByID")
 	if m.GetByIDFunc != nil {
 		return m.GetByIDFunc(ctx, id)
 	}
@@ -44,7 +91,7 @@ func (m *MockCustomerRepository) GetByID(ctx context.Context, id int) (*models.C
 }
 
 func (m *MockCustomerRepository) GetByIDs(ctx context.Context, ids []int) ([]*models.Customer, error) {
-	m.Calls["GetByIDs"]++
+	m.record("GetByIDs")
 	if m.GetByIDsFunc != nil {
 		return m.GetByIDsFunc(ctx, ids)
 	}
@@ -55,50 +102,206 @@ func (m *MockCustomerRepository) GetByIDs(ctx context.Context, ids []int) ([]*mo
 	return customers, nil
 }
 
+func (m *MockCustomerRepository) GetByPhones(ctx context.Context, phones []string) ([]*models.Customer, error) {
+	m.record("GetByPhones")
+	if m.GetByPhonesFunc != nil {
+		return m.GetByPhonesFunc(ctx, phones)
+	}
+	return []*models.Customer{}, nil
+}
+
 func (m *MockCustomerRepository) List(ctx context.Context, limit, offset int) ([]*models.Customer, error) {
-	m.Calls["List"]++
+	m.record("List")
 	if m.ListFunc != nil {
 		return m.ListFunc(ctx, limit, offset)
 	}
 	return NewTestCustomers(limit), nil
 }
 
-func (m *MockCustomerRepository) Update(ctx context.Context, customer *models.Customer) error {
-	m.Calls["Update"]++
+func (m *MockCustomerRepository) ListFiltered(ctx context.Context, filters repository.CustomerFilters, limit, offset int) ([]*models.Customer, error) {
+	m.record("ListFiltered")
+	if m.ListFilteredFunc != nil {
+		return m.ListFilteredFunc(ctx, filters, limit, offset)
+	}
+	return NewTestCustomers(limit), nil
+}
+
+func (m *MockCustomerRepository) Update(ctx context.Context, customer *models.Customer, changedBy *string) error {
+	m.record("Update")
 	if m.UpdateFunc != nil {
-		return m.UpdateFunc(ctx, customer)
+		return m.UpdateFunc(ctx, customer, changedBy)
 	}
 	return nil
 }
 
 func (m *MockCustomerRepository) Delete(ctx context.Context, id int) error {
-	m.Calls["Delete"]++
+	m.record("Delete")
 	if m.DeleteFunc != nil {
 		return m.DeleteFunc(ctx, id)
 	}
 	return nil
 }
 
+func (m *MockCustomerRepository) DeleteWithMessages(ctx context.Context, id int) (int, error) {
+	m.record("DeleteWithMessages")
+	if m.DeleteWithMessagesFunc != nil {
+		return m.DeleteWithMessagesFunc(ctx, id)
+	}
+	return 0, nil
+}
+
+func (m *MockCustomerRepository) GetStats(ctx context.Context, location *string) (*models.CustomerStats, error) {
+	m.record("GetStats")
+	if m.GetStatsFunc != nil {
+		return m.GetStatsFunc(ctx, location)
+	}
+	return &models.CustomerStats{
+		ByProduct:  []*models.CustomerStatsBucket{},
+		ByLocation: []*models.CustomerStatsBucket{},
+	}, nil
+}
+
+func (m *MockCustomerRepository) GetTimeline(ctx context.Context, customerID int, after *models.TimelineCursor, limit int) ([]*models.TimelineEvent, error) {
+	m.record("GetTimeline")
+	if m.GetTimelineFunc != nil {
+		return m.GetTimelineFunc(ctx, customerID, after, limit)
+	}
+	return []*models.TimelineEvent{}, nil
+}
+
+func (m *MockCustomerRepository) CountMissingFields(ctx context.Context, ids []int, fields []string) (*models.FieldCompleteness, error) {
+	m.record("CountMissingFields")
+	if m.CountMissingFieldsFunc != nil {
+		return m.CountMissingFieldsFunc(ctx, ids, fields)
+	}
+	missing := make(map[string]int, len(fields))
+	for _, field := range fields {
+		missing[field] = 0
+	}
+	return &models.FieldCompleteness{Total: len(ids), Missing: missing}, nil
+}
+
+func (m *MockCustomerRepository) CountFiltered(ctx context.Context, filters repository.CustomerFilters) (int, error) {
+	m.record("CountFiltered")
+	if m.CountFilteredFunc != nil {
+		return m.CountFilteredFunc(ctx, filters)
+	}
+	return 0, nil
+}
+
+func (m *MockCustomerRepository) StreamFiltered(ctx context.Context, filters repository.CustomerFilters, limit int, fn func(customer *models.Customer) error) error {
+	m.record("StreamFiltered")
+	if m.StreamFilteredFunc != nil {
+		return m.StreamFilteredFunc(ctx, filters, limit, fn)
+	}
+	return nil
+}
+
+func (m *MockCustomerRepository) GetDetail(ctx context.Context, id int) (*models.Customer, error) {
+	m.record("GetDetail")
+	if m.GetDetailFunc != nil {
+		return m.GetDetailFunc(ctx, id)
+	}
+	return NewTestCustomerWithID(id), nil
+}
+
+func (m *MockCustomerRepository) Block(ctx context.Context, id int, reason string, actor *string) error {
+	m.record("Block")
+	if m.BlockFunc != nil {
+		return m.BlockFunc(ctx, id, reason, actor)
+	}
+	return nil
+}
+
+func (m *MockCustomerRepository) Unblock(ctx context.Context, id int, actor *string) error {
+	m.record("Unblock")
+	if m.UnblockFunc != nil {
+		return m.UnblockFunc(ctx, id, actor)
+	}
+	return nil
+}
+
+func (m *MockCustomerRepository) ListBlocked(ctx context.Context, ids []int) ([]int, error) {
+	m.record("ListBlocked")
+	if m.ListBlockedFunc != nil {
+		return m.ListBlockedFunc(ctx, ids)
+	}
+	return nil, nil
+}
+
+func (m *MockCustomerRepository) ListBelowScore(ctx context.Context, ids []int, minScore float64) ([]int, error) {
+	m.record("ListBelowScore")
+	if m.ListBelowScoreFunc != nil {
+		return m.ListBelowScoreFunc(ctx, ids, minScore)
+	}
+	return nil, nil
+}
+
+func (m *MockCustomerRepository) ListBlockedPhones(ctx context.Context, phones []string) ([]string, error) {
+	m.record("ListBlockedPhones")
+	if m.ListBlockedPhonesFunc != nil {
+		return m.ListBlockedPhonesFunc(ctx, phones)
+	}
+	return nil, nil
+}
+
+func (m *MockCustomerRepository) ListBlockEvents(ctx context.Context, id int) ([]*models.CustomerBlockEvent, error) {
+	m.record("ListBlockEvents")
+	if m.ListBlockEventsFunc != nil {
+		return m.ListBlockEventsFunc(ctx, id)
+	}
+	return []*models.CustomerBlockEvent{}, nil
+}
+
+func (m *MockCustomerRepository) ListPhoneHistory(ctx context.Context, id int) ([]*models.CustomerPhoneChange, error) {
+	m.record("ListPhoneHistory")
+	if m.ListPhoneHistoryFunc != nil {
+		return m.ListPhoneHistoryFunc(ctx, id)
+	}
+	return []*models.CustomerPhoneChange{}, nil
+}
+
 // MockCampaignRepository mocks CampaignRepository
 type MockCampaignRepository struct {
-	CreateFunc       func(ctx context.Context, campaign *models.Campaign) error
-	GetByIDFunc      func(ctx context.Context, id int) (*models.Campaign, error)
-	GetWithStatsFunc func(ctx context.Context, id int) (*models.CampaignWithStats, error)
-	ListFunc         func(ctx context.Context, filters repository.CampaignFilters) ([]*models.Campaign, int, error)
-	UpdateStatusFunc func(ctx context.Context, id int, status models.CampaignStatus) error
-	DeleteFunc       func(ctx context.Context, id int) error
+	CreateFunc                   func(ctx context.Context, campaign *models.Campaign) error
+	GetByIDFunc                  func(ctx context.Context, id int) (*models.Campaign, error)
+	GetWithStatsFunc             func(ctx context.Context, id int) (*models.CampaignWithStats, error)
+	ListFunc                     func(ctx context.Context, filters repository.CampaignFilters) ([]*models.Campaign, int, error)
+	UpdateStatusIfFunc           func(ctx context.Context, id int, from, to models.CampaignStatus) error
+	AwaitApprovalFunc            func(ctx context.Context, id int, from models.CampaignStatus, plan *models.SendPlan) error
+	GetSendPlanFunc              func(ctx context.Context, id int) (*models.SendPlan, error)
+	ClearSendPlanFunc            func(ctx context.Context, id int) error
+	HoldCanaryFunc               func(ctx context.Context, id int, plan *models.SendPlan) error
+	RecordSendFunc               func(ctx context.Context, record *models.SendRecord) error
+	UpdateSendProgressFunc       func(ctx context.Context, id int, messagesQueued int, status models.CampaignStatus) error
+	ListSendsFunc                func(ctx context.Context, campaignID int) ([]*models.SendRecord, error)
+	DeleteFunc                   func(ctx context.Context, id int) error
+	DeleteWithMessagesFunc       func(ctx context.Context, id int) (int, error)
+	ListNeedingAttentionFunc     func(ctx context.Context) ([]*models.CampaignAttention, error)
+	GetStatsByIDsFunc            func(ctx context.Context, ids []int) (map[int]*models.CampaignStats, error)
+	GetFailureBreakdownByIDsFunc func(ctx context.Context, ids []int) (map[int][]*models.ErrorCount, error)
+	StreamWithStatsFunc          func(ctx context.Context, filters repository.CampaignExportFilters, fn func(row *models.CampaignExportRow) error) error
+	ReserveSpendFunc             func(ctx context.Context, id int, cost float64) (bool, error)
+	ReleaseSpendFunc             func(ctx context.Context, id int, cost float64) error
+	PauseFunc                    func(ctx context.Context, id int, reason string) (bool, error)
+	BeginCancelFunc              func(ctx context.Context, id int, grace time.Duration) (time.Time, error)
+	UndoCancelFunc               func(ctx context.Context, id int) error
+	ListDueCancellationsFunc     func(ctx context.Context) ([]int, error)
+	FinalizeCancellationFunc     func(ctx context.Context, id int) (int, error)
+	ResumeFunc                   func(ctx context.Context, id int, budget *float64) error
+	ListOrderedOutstandingFunc   func(ctx context.Context) ([]int, error)
 
-	Calls map[string]int
+	callLog
 }
 
 func NewMockCampaignRepository() *MockCampaignRepository {
 	return &MockCampaignRepository{
-		Calls: make(map[string]int),
+		callLog: callLog{Calls: make(map[string]int)},
 	}
 }
 
 func (m *MockCampaignRepository) Create(ctx context.Context, campaign *models.Campaign) error {
-	m.Calls["Create"]++
+	m.record("Create")
 	if m.CreateFunc != nil {
 		return m.CreateFunc(ctx, campaign)
 	}
@@ -109,7 +312,7 @@ func (m *MockCampaignRepository) Create(ctx context.Context, campaign *models.Ca
 }
 
 func (m *MockCampaignRepository) GetByID(ctx context.Context, id int) (*models.Campaign, error) {
-	m.Calls["GetByID"]++
+	m.record("GetByID")
 	if m.GetByIDFunc != nil {
 		return m.GetByIDFunc(ctx, id)
 	}
@@ -117,7 +320,7 @@ func (m *MockCampaignRepository) GetByID(ctx context.Context, id int) (*models.C
 }
 
 func (m *MockCampaignRepository) GetWithStats(ctx context.Context, id int) (*models.CampaignWithStats, error) {
-	m.Calls["GetWithStats"]++
+	m.record("GetWithStats")
 	if m.GetWithStatsFunc != nil {
 		return m.GetWithStatsFunc(ctx, id)
 	}
@@ -134,7 +337,7 @@ func (m *MockCampaignRepository) GetWithStats(ctx context.Context, id int) (*mod
 }
 
 func (m *MockCampaignRepository) List(ctx context.Context, filters repository.CampaignFilters) ([]*models.Campaign, int, error) {
-	m.Calls["List"]++
+	m.record("List")
 	if m.ListFunc != nil {
 		return m.ListFunc(ctx, filters)
 	}
@@ -142,43 +345,259 @@ func (m *MockCampaignRepository) List(ctx context.Context, filters repository.Ca
 	return campaigns, len(campaigns), nil
 }
 
-func (m *MockCampaignRepository) UpdateStatus(ctx context.Context, id int, status models.CampaignStatus) error {
-	m.Calls["UpdateStatus"]++
-	if m.UpdateStatusFunc != nil {
-		return m.UpdateStatusFunc(ctx, id, status)
+func (m *MockCampaignRepository) UpdateStatusIf(ctx context.Context, id int, from, to models.CampaignStatus) error {
+	m.record("UpdateStatusIf")
+	if m.UpdateStatusIfFunc != nil {
+		return m.UpdateStatusIfFunc(ctx, id, from, to)
+	}
+	// Enforce the transition table like the real repository
+	if !from.CanTransition(to) {
+		return &models.InvalidTransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+// UpdateStatusIfTx behaves like UpdateStatusIf; tx is not used
+func (m *MockCampaignRepository) UpdateStatusIfTx(ctx context.Context, tx *sql.Tx, id int, from, to models.CampaignStatus) error {
+	return m.UpdateStatusIf(ctx, id, from, to)
+}
+
+func (m *MockCampaignRepository) AwaitApproval(ctx context.Context, id int, from models.CampaignStatus, plan *models.SendPlan) error {
+	m.record("AwaitApproval")
+	if m.AwaitApprovalFunc != nil {
+		return m.AwaitApprovalFunc(ctx, id, from, plan)
+	}
+	// Enforce the transition table like the real repository
+	if !from.CanTransition(models.CampaignStatusPendingApproval) {
+		return &models.InvalidTransitionError{From: from, To: models.CampaignStatusPendingApproval}
+	}
+	return nil
+}
+
+func (m *MockCampaignRepository) GetSendPlan(ctx context.Context, id int) (*models.SendPlan, error) {
+	m.record("GetSendPlan")
+	if m.GetSendPlanFunc != nil {
+		return m.GetSendPlanFunc(ctx, id)
+	}
+	return &models.SendPlan{CustomerIDs: []int{1, 2, 3}, AudienceSize: 3, RequestedAt: time.Now()}, nil
+}
+
+func (m *MockCampaignRepository) ClearSendPlan(ctx context.Context, id int) error {
+	m.record("ClearSendPlan")
+	if m.ClearSendPlanFunc != nil {
+		return m.ClearSendPlanFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockCampaignRepository) HoldCanary(ctx context.Context, id int, plan *models.SendPlan) error {
+	m.record("HoldCanary")
+	if m.HoldCanaryFunc != nil {
+		return m.HoldCanaryFunc(ctx, id, plan)
+	}
+	return nil
+}
+
+func (m *MockCampaignRepository) RecordSend(ctx context.Context, record *models.SendRecord) error {
+	m.record("RecordSend")
+	if m.RecordSendFunc != nil {
+		return m.RecordSendFunc(ctx, record)
+	}
+	return nil
+}
+
+func (m *MockCampaignRepository) UpdateSendProgress(ctx context.Context, id int, messagesQueued int, status models.CampaignStatus) error {
+	m.record("UpdateSendProgress")
+	if m.UpdateSendProgressFunc != nil {
+		return m.UpdateSendProgressFunc(ctx, id, messagesQueued, status)
 	}
 	return nil
 }
 
+func (m *MockCampaignRepository) ListSends(ctx context.Context, campaignID int) ([]*models.SendRecord, error) {
+	m.record("ListSends")
+	if m.ListSendsFunc != nil {
+		return m.ListSendsFunc(ctx, campaignID)
+	}
+	return []*models.SendRecord{}, nil
+}
+
 func (m *MockCampaignRepository) Delete(ctx context.Context, id int) error {
-	m.Calls["Delete"]++
+	m.record("Delete")
 	if m.DeleteFunc != nil {
 		return m.DeleteFunc(ctx, id)
 	}
 	return nil
 }
 
+func (m *MockCampaignRepository) DeleteWithMessages(ctx context.Context, id int) (int, error) {
+	m.record("DeleteWithMessages")
+	if m.DeleteWithMessagesFunc != nil {
+		return m.DeleteWithMessagesFunc(ctx, id)
+	}
+	return 0, nil
+}
+
+func (m *MockCampaignRepository) ListNeedingAttention(ctx context.Context) ([]*models.CampaignAttention, error) {
+	m.record("ListNeedingAttention")
+	if m.ListNeedingAttentionFunc != nil {
+		return m.ListNeedingAttentionFunc(ctx)
+	}
+	return []*models.CampaignAttention{}, nil
+}
+
+func (m *MockCampaignRepository) GetFailureBreakdownByIDs(ctx context.Context, ids []int) (map[int][]*models.ErrorCount, error) {
+	m.record("GetFailureBreakdownByIDs")
+	if m.GetFailureBreakdownByIDsFunc != nil {
+		return m.GetFailureBreakdownByIDsFunc(ctx, ids)
+	}
+	breakdown := make(map[int][]*models.ErrorCount, len(ids))
+	for _, id := range ids {
+		breakdown[id] = []*models.ErrorCount{}
+	}
+	return breakdown, nil
+}
+
+func (m *MockCampaignRepository) GetStatsByIDs(ctx context.Context, ids []int) (map[int]*models.CampaignStats, error) {
+	m.record("GetStatsByIDs")
+	if m.GetStatsByIDsFunc != nil {
+		return m.GetStatsByIDsFunc(ctx, ids)
+	}
+	stats := make(map[int]*models.CampaignStats, len(ids))
+	for _, id := range ids {
+		stats[id] = &models.CampaignStats{}
+	}
+	return stats, nil
+}
+
+func (m *MockCampaignRepository) StreamWithStats(ctx context.Context, filters repository.CampaignExportFilters, fn func(row *models.CampaignExportRow) error) error {
+	m.record("StreamWithStats")
+	if m.StreamWithStatsFunc != nil {
+		return m.StreamWithStatsFunc(ctx, filters, fn)
+	}
+	return nil
+}
+
+func (m *MockCampaignRepository) ReserveSpend(ctx context.Context, id int, cost float64) (bool, error) {
+	m.record("ReserveSpend")
+	if m.ReserveSpendFunc != nil {
+		return m.ReserveSpendFunc(ctx, id, cost)
+	}
+	return true, nil
+}
+
+func (m *MockCampaignRepository) ReleaseSpend(ctx context.Context, id int, cost float64) error {
+	m.record("ReleaseSpend")
+	if m.ReleaseSpendFunc != nil {
+		return m.ReleaseSpendFunc(ctx, id, cost)
+	}
+	return nil
+}
+
+func (m *MockCampaignRepository) Pause(ctx context.Context, id int, reason string) (bool, error) {
+	m.record("Pause")
+	if m.PauseFunc != nil {
+		return m.PauseFunc(ctx, id, reason)
+	}
+	return true, nil
+}
+
+func (m *MockCampaignRepository) BeginCancel(ctx context.Context, id int, grace time.Duration) (time.Time, error) {
+	m.record("BeginCancel")
+	if m.BeginCancelFunc != nil {
+		return m.BeginCancelFunc(ctx, id, grace)
+	}
+	return time.Now().Add(grace), nil
+}
+
+func (m *MockCampaignRepository) UndoCancel(ctx context.Context, id int) error {
+	m.record("UndoCancel")
+	if m.UndoCancelFunc != nil {
+		return m.UndoCancelFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockCampaignRepository) ListDueCancellations(ctx context.Context) ([]int, error) {
+	m.record("ListDueCancellations")
+	if m.ListDueCancellationsFunc != nil {
+		return m.ListDueCancellationsFunc(ctx)
+	}
+	return []int{}, nil
+}
+
+func (m *MockCampaignRepository) ListOrderedOutstanding(ctx context.Context) ([]int, error) {
+	m.record("ListOrderedOutstanding")
+	if m.ListOrderedOutstandingFunc != nil {
+		return m.ListOrderedOutstandingFunc(ctx)
+	}
+	return []int{}, nil
+}
+
+func (m *MockCampaignRepository) FinalizeCancellation(ctx context.Context, id int) (int, error) {
+	m.record("FinalizeCancellation")
+	if m.FinalizeCancellationFunc != nil {
+		return m.FinalizeCancellationFunc(ctx, id)
+	}
+	return 0, nil
+}
+
+func (m *MockCampaignRepository) Resume(ctx context.Context, id int, budget *float64) error {
+	m.record("Resume")
+	if m.ResumeFunc != nil {
+		return m.ResumeFunc(ctx, id, budget)
+	}
+	return nil
+}
+
 // MockMessageRepository mocks MessageRepository
 type MockMessageRepository struct {
-	CreateFunc             func(ctx context.Context, message *models.OutboundMessage) error
-	CreateBatchFunc        func(ctx context.Context, messages []*models.OutboundMessage) error
-	GetByIDFunc            func(ctx context.Context, id int) (*models.OutboundMessage, error)
-	GetWithDetailsFunc     func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error)
-	UpdateStatusFunc       func(ctx context.Context, id int, status models.MessageStatus, lastError *string) error
-	GetPendingMessagesFunc func(ctx context.Context, limit int) ([]*models.OutboundMessage, error)
-	GetByCampaignIDFunc    func(ctx context.Context, campaignID int) ([]*models.OutboundMessage, error)
+	CreateFunc                           func(ctx context.Context, message *models.OutboundMessage) error
+	CreateBatchFunc                      func(ctx context.Context, messages []*models.OutboundMessage) error
+	GetByIDFunc                          func(ctx context.Context, id int) (*models.OutboundMessage, error)
+	GetWithDetailsFunc                   func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error)
+	UpdateStatusFunc                     func(ctx context.Context, id int, status models.MessageStatus, lastError *string) error
+	MarkPublishedFunc                    func(ctx context.Context, ids []int) error
+	ReserveAttemptFunc                   func(ctx context.Context, customerID int, day time.Time, budget int) (bool, error)
+	DeferUntilFunc                       func(ctx context.Context, id int, until time.Time, reason string) error
+	ClaimDueDeferredFunc                 func(ctx context.Context, now time.Time, limit int) ([]*models.OutboundMessage, error)
+	HoldFunc                             func(ctx context.Context, id int, reason string) error
+	ReleaseHeldFunc                      func(ctx context.Context, campaignID int) (int, error)
+	GetPendingMessagesFunc               func(ctx context.Context, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error)
+	GetByCampaignIDFunc                  func(ctx context.Context, campaignID int) ([]*models.OutboundMessage, error)
+	GetDeliveryStatsByChannelFunc        func(ctx context.Context, since time.Time) ([]*models.ChannelDeliveryStats, error)
+	GetRetryEffectivenessFunc            func(ctx context.Context, from, to time.Time) ([]*models.ChannelRetryEffectiveness, error)
+	CountRecentRecipientsFunc            func(ctx context.Context, customerIDs []int, excludeCampaignID int, since time.Time) (int, error)
+	CountRecentFingerprintRecipientsFunc func(ctx context.Context, customerIDs []int, fingerprint string, excludeCampaignID int, since time.Time) (int, error)
+	HasRecentFingerprintSendFunc         func(ctx context.Context, customerID int, fingerprint string, excludeMessageID int, since time.Time) (bool, error)
+	ClaimNextPartFunc                    func(ctx context.Context, groupID, part int) (*models.OutboundMessage, error)
+	CreateResendFunc                     func(ctx context.Context, originalID int, verbatim bool, fingerprint *string) (*models.OutboundMessage, error)
+	ListFrequencyCappedFunc              func(ctx context.Context, customerIDs []int, since time.Time, maxMessages int) ([]int, error)
+	ListByCampaignIDsFunc                func(ctx context.Context, campaignIDs []int, filters repository.MessageFilters) ([]*models.OutboundMessage, error)
+	ListByCustomerIDsFunc                func(ctx context.Context, customerIDs []int, filters repository.MessageFilters) ([]*models.OutboundMessage, error)
+	ReencryptContentFunc                 func(ctx context.Context, afterID, limit int) (*repository.ReencryptBatch, error)
 
-	Calls map[string]int
+	GetPendingByCampaignIDFunc      func(ctx context.Context, campaignID, limit int) ([]*models.OutboundMessage, error)
+	ClearPendingRenderedContentFunc func(ctx context.Context, campaignID int) (int, error)
+	ListMissingContentFunc          func(ctx context.Context, filters repository.ContentBackfillFilters, afterID, limit int) ([]*models.OutboundMessageWithDetails, error)
+	StoreBackfilledContentFunc      func(ctx context.Context, contents map[int]string) (int, error)
+	ListForExportFunc               func(ctx context.Context, campaignID, afterID, limit int) ([]*models.OutboundMessageWithDetails, error)
+	ClaimUnpublishedFunc            func(ctx context.Context, createdBefore time.Time, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error)
+	GetPendingBacklogFunc           func(ctx context.Context) ([]*models.CampaignPendingBacklog, error)
+	CountUnpublishedFunc            func(ctx context.Context) (int, error)
+	GetStatusesFunc                 func(ctx context.Context, ids []int) (map[int]models.MessageStatus, error)
+	ListQueuedFunc                  func(ctx context.Context, publishedBefore time.Time) ([]*models.OutboundMessage, error)
+	callLog
 }
 
 func NewMockMessageRepository() *MockMessageRepository {
 	return &MockMessageRepository{
-		Calls: make(map[string]int),
+		callLog: callLog{Calls: make(map[string]int)},
 	}
 }
 
 func (m *MockMessageRepository) Create(ctx context.Context, message *models.OutboundMessage) error {
-	m.Calls["Create"]++
+	m.record("Create")
 	if m.CreateFunc != nil {
 		return m.CreateFunc(ctx, message)
 	}
@@ -189,7 +608,7 @@ func (m *MockMessageRepository) Create(ctx context.Context, message *models.Outb
 }
 
 func (m *MockMessageRepository) CreateBatch(ctx context.Context, messages []*models.OutboundMessage) error {
-	m.Calls["CreateBatch"]++
+	m.record("CreateBatch")
 	if m.CreateBatchFunc != nil {
 		return m.CreateBatchFunc(ctx, messages)
 	}
@@ -201,8 +620,13 @@ func (m *MockMessageRepository) CreateBatch(ctx context.Context, messages []*mod
 	return nil
 }
 
+// CreateBatchTx behaves like CreateBatch; tx is not used
+func (m *MockMessageRepository) CreateBatchTx(ctx context.Context, tx *sql.Tx, messages []*models.OutboundMessage) error {
+	return m.CreateBatch(ctx, messages)
+}
+
 func (m *MockMessageRepository) GetByID(ctx context.Context, id int) (*models.OutboundMessage, error) {
-	m.Calls["GetByID"]++
+	m.record("GetByID")
 	if m.GetByIDFunc != nil {
 		return m.GetByIDFunc(ctx, id)
 	}
@@ -210,7 +634,7 @@ func (m *MockMessageRepository) GetByID(ctx context.Context, id int) (*models.Ou
 }
 
 func (m *MockMessageRepository) GetWithDetails(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
-	m.Calls["GetWithDetails"]++
+	m.record("GetWithDetails")
 	if m.GetWithDetailsFunc != nil {
 		return m.GetWithDetailsFunc(ctx, id)
 	}
@@ -218,63 +642,1326 @@ func (m *MockMessageRepository) GetWithDetails(ctx context.Context, id int) (*mo
 }
 
 func (m *MockMessageRepository) UpdateStatus(ctx context.Context, id int, status models.MessageStatus, lastError *string) error {
-	m.Calls["UpdateStatus"]++
+	m.record("UpdateStatus")
 	if m.UpdateStatusFunc != nil {
 		return m.UpdateStatusFunc(ctx, id, status, lastError)
 	}
 	return nil
 }
 
-func (m *MockMessageRepository) GetPendingMessages(ctx context.Context, limit int) ([]*models.OutboundMessage, error) {
-	m.Calls["GetPendingMessages"]++
+func (m *MockMessageRepository) MarkPublished(ctx context.Context, ids []int) error {
+	m.record("MarkPublished")
+	if m.MarkPublishedFunc != nil {
+		return m.MarkPublishedFunc(ctx, ids)
+	}
+	return nil
+}
+
+func (m *MockMessageRepository) ReserveAttempt(ctx context.Context, customerID int, day time.Time, budget int) (bool, error) {
+	m.record("ReserveAttempt")
+	if m.ReserveAttemptFunc != nil {
+		return m.ReserveAttemptFunc(ctx, customerID, day, budget)
+	}
+	return true, nil
+}
+
+func (m *MockMessageRepository) DeferUntil(ctx context.Context, id int, until time.Time, reason string) error {
+	m.record("DeferUntil")
+	if m.DeferUntilFunc != nil {
+		return m.DeferUntilFunc(ctx, id, until, reason)
+	}
+	return nil
+}
+
+func (m *MockMessageRepository) ClaimDueDeferred(ctx context.Context, now time.Time, limit int) ([]*models.OutboundMessage, error) {
+	m.record("ClaimDueDeferred")
+	if m.ClaimDueDeferredFunc != nil {
+		return m.ClaimDueDeferredFunc(ctx, now, limit)
+	}
+	return []*models.OutboundMessage{}, nil
+}
+
+func (m *MockMessageRepository) Hold(ctx context.Context, id int, reason string) error {
+	m.record("Hold")
+	if m.HoldFunc != nil {
+		return m.HoldFunc(ctx, id, reason)
+	}
+	return nil
+}
+
+func (m *MockMessageRepository) ReleaseHeld(ctx context.Context, campaignID int) (int, error) {
+	m.record("ReleaseHeld")
+	if m.ReleaseHeldFunc != nil {
+		return m.ReleaseHeldFunc(ctx, campaignID)
+	}
+	return 0, nil
+}
+
+func (m *MockMessageRepository) GetPendingMessages(ctx context.Context, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error) {
+	m.record("GetPendingMessages")
 	if m.GetPendingMessagesFunc != nil {
-		return m.GetPendingMessagesFunc(ctx, limit)
+		return m.GetPendingMessagesFunc(ctx, limit, order)
 	}
 	return []*models.OutboundMessage{}, nil
 }
 
 func (m *MockMessageRepository) GetByCampaignID(ctx context.Context, campaignID int) ([]*models.OutboundMessage, error) {
-	m.Calls["GetByCampaignID"]++
+	m.record("GetByCampaignID")
 	if m.GetByCampaignIDFunc != nil {
 		return m.GetByCampaignIDFunc(ctx, campaignID)
 	}
 	return NewTestMessages(campaignID, []int{1, 2, 3}), nil
 }
 
-// MockPublisher mocks queue.Publisher
-type MockPublisher struct {
-	PublishMessageFunc func(messageID, campaignID, customerID int) error
-	Published          []PublishedJob
+func (m *MockMessageRepository) GetDeliveryStatsByChannel(ctx context.Context, since time.Time) ([]*models.ChannelDeliveryStats, error) {
+	m.record("GetDeliveryStatsByChannel")
+	if m.GetDeliveryStatsByChannelFunc != nil {
+		return m.GetDeliveryStatsByChannelFunc(ctx, since)
+	}
+	return []*models.ChannelDeliveryStats{}, nil
 }
 
-type PublishedJob struct {
-	MessageID  int
-	CampaignID int
-	CustomerID int
+func (m *MockMessageRepository) GetRetryEffectiveness(ctx context.Context, from, to time.Time) ([]*models.ChannelRetryEffectiveness, error) {
+	m.record("GetRetryEffectiveness")
+	if m.GetRetryEffectivenessFunc != nil {
+		return m.GetRetryEffectivenessFunc(ctx, from, to)
+	}
+	return []*models.ChannelRetryEffectiveness{}, nil
 }
 
-func NewMockPublisher() *MockPublisher {
-	return &MockPublisher{
-		Published: []PublishedJob{},
+func (m *MockMessageRepository) CountRecentRecipients(ctx context.Context, customerIDs []int, excludeCampaignID int, since time.Time) (int, error) {
+	m.record("CountRecentRecipients")
+	if m.CountRecentRecipientsFunc != nil {
+		return m.CountRecentRecipientsFunc(ctx, customerIDs, excludeCampaignID, since)
 	}
+	return 0, nil
 }
 
-func (m *MockPublisher) PublishMessage(messageID, campaignID, customerID int) error {
-	if m.PublishMessageFunc != nil {
-		return m.PublishMessageFunc(messageID, campaignID, customerID)
+func (m *MockMessageRepository) CountRecentFingerprintRecipients(ctx context.Context, customerIDs []int, fingerprint string, excludeCampaignID int, since time.Time) (int, error) {
+	m.record("CountRecentFingerprintRecipients")
+	if m.CountRecentFingerprintRecipientsFunc != nil {
+		return m.CountRecentFingerprintRecipientsFunc(ctx, customerIDs, fingerprint, excludeCampaignID, since)
 	}
-	m.Published = append(m.Published, PublishedJob{
-		MessageID:  messageID,
-		CampaignID: campaignID,
-		CustomerID: customerID,
-	})
-	return nil
+	return 0, nil
 }
 
-func (m *MockPublisher) GetPublishedCount() int {
-	return len(m.Published)
+func (m *MockMessageRepository) ClaimNextPart(ctx context.Context, groupID, part int) (*models.OutboundMessage, error) {
+	m.record("ClaimNextPart")
+	if m.ClaimNextPartFunc != nil {
+		return m.ClaimNextPartFunc(ctx, groupID, part)
+	}
+	return nil, nil
 }
 
-func (m *MockPublisher) Reset() {
-	m.Published = []PublishedJob{}
+func (m *MockMessageRepository) CreateResend(ctx context.Context, originalID int, verbatim bool, fingerprint *string) (*models.OutboundMessage, error) {
+	m.record("CreateResend")
+	if m.CreateResendFunc != nil {
+		return m.CreateResendFunc(ctx, originalID, verbatim, fingerprint)
+	}
+	return nil, repository.ErrMessageNotFound
+}
+
+func (m *MockMessageRepository) HasRecentFingerprintSend(ctx context.Context, customerID int, fingerprint string, excludeMessageID int, since time.Time) (bool, error) {
+	m.record("HasRecentFingerprintSend")
+	if m.HasRecentFingerprintSendFunc != nil {
+		return m.HasRecentFingerprintSendFunc(ctx, customerID, fingerprint, excludeMessageID, since)
+	}
+	return false, nil
+}
+
+func (m *MockMessageRepository) ListFrequencyCapped(ctx context.Context, customerIDs []int, since time.Time, maxMessages int) ([]int, error) {
+	m.record("ListFrequencyCapped")
+	if m.ListFrequencyCappedFunc != nil {
+		return m.ListFrequencyCappedFunc(ctx, customerIDs, since, maxMessages)
+	}
+	return []int{}, nil
+}
+
+func (m *MockMessageRepository) ListByCampaignIDs(ctx context.Context, campaignIDs []int, filters repository.MessageFilters) ([]*models.OutboundMessage, error) {
+	m.record("ListByCampaignIDs")
+	if m.ListByCampaignIDsFunc != nil {
+		return m.ListByCampaignIDsFunc(ctx, campaignIDs, filters)
+	}
+	return []*models.OutboundMessage{}, nil
+}
+
+func (m *MockMessageRepository) ListByCustomerIDs(ctx context.Context, customerIDs []int, filters repository.MessageFilters) ([]*models.OutboundMessage, error) {
+	m.record("ListByCustomerIDs")
+	if m.ListByCustomerIDsFunc != nil {
+		return m.ListByCustomerIDsFunc(ctx, customerIDs, filters)
+	}
+	return []*models.OutboundMessage{}, nil
+}
+
+func (m *MockMessageRepository) ReencryptContent(ctx context.Context, afterID, limit int) (*repository.ReencryptBatch, error) {
+	m.record("ReencryptContent")
+	if m.ReencryptContentFunc != nil {
+		return m.ReencryptContentFunc(ctx, afterID, limit)
+	}
+	return &repository.ReencryptBatch{LastID: afterID}, nil
+}
+
+func (m *MockMessageRepository) GetPendingByCampaignID(ctx context.Context, campaignID, limit int) ([]*models.OutboundMessage, error) {
+	m.record("GetPendingByCampaignID")
+	if m.GetPendingByCampaignIDFunc != nil {
+		return m.GetPendingByCampaignIDFunc(ctx, campaignID, limit)
+	}
+	return NewTestMessages(campaignID, []int{1, 2, 3}), nil
+}
+
+func (m *MockMessageRepository) ClearPendingRenderedContent(ctx context.Context, campaignID int) (int, error) {
+	m.record("ClearPendingRenderedContent")
+	if m.ClearPendingRenderedContentFunc != nil {
+		return m.ClearPendingRenderedContentFunc(ctx, campaignID)
+	}
+	return 0, nil
+}
+
+func (m *MockMessageRepository) ListMissingContent(ctx context.Context, filters repository.ContentBackfillFilters, afterID, limit int) ([]*models.OutboundMessageWithDetails, error) {
+	m.record("ListMissingContent")
+	if m.ListMissingContentFunc != nil {
+		return m.ListMissingContentFunc(ctx, filters, afterID, limit)
+	}
+	return []*models.OutboundMessageWithDetails{}, nil
+}
+
+func (m *MockMessageRepository) StoreBackfilledContent(ctx context.Context, contents map[int]string) (int, error) {
+	m.record("StoreBackfilledContent")
+	if m.StoreBackfilledContentFunc != nil {
+		return m.StoreBackfilledContentFunc(ctx, contents)
+	}
+	return len(contents), nil
+}
+
+func (m *MockMessageRepository) ListForExport(ctx context.Context, campaignID, afterID, limit int) ([]*models.OutboundMessageWithDetails, error) {
+	m.record("ListForExport")
+	if m.ListForExportFunc != nil {
+		return m.ListForExportFunc(ctx, campaignID, afterID, limit)
+	}
+	return []*models.OutboundMessageWithDetails{}, nil
+}
+
+func (m *MockMessageRepository) ClaimUnpublished(ctx context.Context, createdBefore time.Time, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error) {
+	m.record("ClaimUnpublished")
+	if m.ClaimUnpublishedFunc != nil {
+		return m.ClaimUnpublishedFunc(ctx, createdBefore, limit, order)
+	}
+	return []*models.OutboundMessage{}, nil
+}
+
+func (m *MockMessageRepository) GetPendingBacklog(ctx context.Context) ([]*models.CampaignPendingBacklog, error) {
+	m.record("GetPendingBacklog")
+	if m.GetPendingBacklogFunc != nil {
+		return m.GetPendingBacklogFunc(ctx)
+	}
+	return []*models.CampaignPendingBacklog{}, nil
+}
+
+func (m *MockMessageRepository) CountUnpublished(ctx context.Context) (int, error) {
+	m.record("CountUnpublished")
+	if m.CountUnpublishedFunc != nil {
+		return m.CountUnpublishedFunc(ctx)
+	}
+	return 0, nil
+}
+
+func (m *MockMessageRepository) GetStatuses(ctx context.Context, ids []int) (map[int]models.MessageStatus, error) {
+	m.record("GetStatuses")
+	if m.GetStatusesFunc != nil {
+		return m.GetStatusesFunc(ctx, ids)
+	}
+	return map[int]models.MessageStatus{}, nil
+}
+
+func (m *MockMessageRepository) ListQueued(ctx context.Context, publishedBefore time.Time) ([]*models.OutboundMessage, error) {
+	m.record("ListQueued")
+	if m.ListQueuedFunc != nil {
+		return m.ListQueuedFunc(ctx, publishedBefore)
+	}
+	return []*models.OutboundMessage{}, nil
+}
+
+// MockProcessingErrorRepository mocks ProcessingErrorRepository
+type MockProcessingErrorRepository struct {
+	CreateFunc    func(ctx context.Context, processingError *models.ProcessingError) error
+	ListSinceFunc func(ctx context.Context, since time.Time, limit int) ([]*models.ProcessingError, error)
+	Created       []*models.ProcessingError
+	callLog
+}
+
+func NewMockProcessingErrorRepository() *MockProcessingErrorRepository {
+	return &MockProcessingErrorRepository{
+		callLog: callLog{Calls: make(map[string]int)},
+	}
+}
+
+func (m *MockProcessingErrorRepository) Create(ctx context.Context, processingError *models.ProcessingError) error {
+	m.record("Create")
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, processingError)
+	}
+	processingError.ID = len(m.Created) + 1
+	processingError.OccurredAt = time.Now()
+	m.Created = append(m.Created, processingError)
+	return nil
+}
+
+func (m *MockProcessingErrorRepository) ListSince(ctx context.Context, since time.Time, limit int) ([]*models.ProcessingError, error) {
+	m.record("ListSince")
+	if m.ListSinceFunc != nil {
+		return m.ListSinceFunc(ctx, since, limit)
+	}
+	return []*models.ProcessingError{}, nil
+}
+
+// MockSuppressionRepository mocks SuppressionRepository with an in-memory list per campaign
+type MockSuppressionRepository struct {
+	AddFunc              func(ctx context.Context, campaignID int, phones []string, addedBy *string) (int, error)
+	FilterSuppressedFunc func(ctx context.Context, campaignID int, phones []string) ([]string, error)
+	Phones               map[int][]string
+	callLog
+}
+
+func NewMockSuppressionRepository() *MockSuppressionRepository {
+	return &MockSuppressionRepository{
+		Phones:  make(map[int][]string),
+		callLog: callLog{Calls: make(map[string]int)},
+	}
+}
+
+func (m *MockSuppressionRepository) Add(ctx context.Context, campaignID int, phones []string, addedBy *string) (int, error) {
+	m.record("Add")
+	if m.AddFunc != nil {
+		return m.AddFunc(ctx, campaignID, phones, addedBy)
+	}
+	added := 0
+	for _, phone := range phones {
+		if !containsString(m.Phones[campaignID], phone) {
+			m.Phones[campaignID] = append(m.Phones[campaignID], phone)
+			added++
+		}
+	}
+	return added, nil
+}
+
+func (m *MockSuppressionRepository) List(ctx context.Context, campaignID int) ([]*models.CampaignSuppression, error) {
+	m.record("List")
+	suppressions := []*models.CampaignSuppression{}
+	for _, phone := range m.Phones[campaignID] {
+		suppressions = append(suppressions, &models.CampaignSuppression{CampaignID: campaignID, Phone: phone, CreatedAt: time.Now()})
+	}
+	return suppressions, nil
+}
+
+func (m *MockSuppressionRepository) Clear(ctx context.Context, campaignID int) (int, error) {
+	m.record("Clear")
+	removed := len(m.Phones[campaignID])
+	delete(m.Phones, campaignID)
+	return removed, nil
+}
+
+func (m *MockSuppressionRepository) FilterSuppressed(ctx context.Context, campaignID int, phones []string) ([]string, error) {
+	m.record("FilterSuppressed")
+	if m.FilterSuppressedFunc != nil {
+		return m.FilterSuppressedFunc(ctx, campaignID, phones)
+	}
+	// Stored phones are compared in normalized form, as normalize_phone does
+	stored := make([]string, 0, len(m.Phones[campaignID]))
+	for _, phone := range m.Phones[campaignID] {
+		stored = append(stored, models.PhoneKey(phone))
+	}
+	suppressed := []string{}
+	for _, phone := range phones {
+		if containsString(stored, phone) {
+			suppressed = append(suppressed, phone)
+		}
+	}
+	return suppressed, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// MockDigestRepository mocks DigestRepository, remembering claimed and sent dates
+type MockDigestRepository struct {
+	CountFinishedCampaignsFunc func(ctx context.Context, from, to time.Time) (int, int, error)
+	ListChannelActivityFunc    func(ctx context.Context, from, to time.Time) ([]*models.ChannelActivity, error)
+	ListTopErrorsFunc          func(ctx context.Context, from, to time.Time, limit int) ([]*models.ErrorCount, error)
+	Claimed                    map[string]bool
+	Sent                       map[string][]byte
+	callLog
+}
+
+func NewMockDigestRepository() *MockDigestRepository {
+	return &MockDigestRepository{
+		Claimed: make(map[string]bool),
+		Sent:    make(map[string][]byte),
+		callLog: callLog{Calls: make(map[string]int)},
+	}
+}
+
+func (m *MockDigestRepository) CountFinishedCampaigns(ctx context.Context, from, to time.Time) (int, int, error) {
+	m.record("CountFinishedCampaigns")
+	if m.CountFinishedCampaignsFunc != nil {
+		return m.CountFinishedCampaignsFunc(ctx, from, to)
+	}
+	return 0, 0, nil
+}
+
+func (m *MockDigestRepository) ListChannelActivity(ctx context.Context, from, to time.Time) ([]*models.ChannelActivity, error) {
+	m.record("ListChannelActivity")
+	if m.ListChannelActivityFunc != nil {
+		return m.ListChannelActivityFunc(ctx, from, to)
+	}
+	return []*models.ChannelActivity{}, nil
+}
+
+func (m *MockDigestRepository) ListTopErrors(ctx context.Context, from, to time.Time, limit int) ([]*models.ErrorCount, error) {
+	m.record("ListTopErrors")
+	if m.ListTopErrorsFunc != nil {
+		return m.ListTopErrorsFunc(ctx, from, to, limit)
+	}
+	return []*models.ErrorCount{}, nil
+}
+
+func (m *MockDigestRepository) Claim(ctx context.Context, date string, staleAfter time.Duration) (bool, error) {
+	m.record("Claim")
+	if m.Claimed[date] {
+		return false, nil
+	}
+	m.Claimed[date] = true
+	return true, nil
+}
+
+func (m *MockDigestRepository) MarkSent(ctx context.Context, date string, payload []byte) error {
+	m.record("MarkSent")
+	m.Sent[date] = payload
+	return nil
+}
+
+func (m *MockDigestRepository) Release(ctx context.Context, date string) error {
+	m.record("Release")
+	if _, sent := m.Sent[date]; !sent {
+		delete(m.Claimed, date)
+	}
+	return nil
+}
+
+// MockExportJobRepository mocks ExportJobRepository, keeping jobs in memory
+// MessageCounts is the total a claimed job records for its campaign
+type MockExportJobRepository struct {
+	Jobs          map[int]*models.ExportJob
+	MessageCounts map[int]int
+	callLog
+	nextID int
+}
+
+func NewMockExportJobRepository() *MockExportJobRepository {
+	return &MockExportJobRepository{
+		Jobs:          make(map[int]*models.ExportJob),
+		MessageCounts: make(map[int]int),
+		callLog:       callLog{Calls: make(map[string]int)},
+	}
+}
+
+func (m *MockExportJobRepository) Create(ctx context.Context, job *models.ExportJob) error {
+	m.record("Create")
+	m.nextID++
+	job.ID = m.nextID
+	job.Status = models.ExportJobPending
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	stored := *job
+	m.Jobs[job.ID] = &stored
+	return nil
+}
+
+func (m *MockExportJobRepository) GetByID(ctx context.Context, id int) (*models.ExportJob, error) {
+	m.record("GetByID")
+	job, ok := m.Jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("export job not found")
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (m *MockExportJobRepository) ClaimNext(ctx context.Context, staleAfter time.Duration) (*models.ExportJob, error) {
+	m.record("ClaimNext")
+	for id := 1; id <= m.nextID; id++ {
+		job, ok := m.Jobs[id]
+		if !ok || job.Status != models.ExportJobPending {
+			continue
+		}
+		total := m.MessageCounts[job.CampaignID]
+		job.Status = models.ExportJobRunning
+		job.TotalRows = &total
+		job.RowsWritten = 0
+		copied := *job
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (m *MockExportJobRepository) UpdateProgress(ctx context.Context, id, rowsWritten int) error {
+	m.record("UpdateProgress")
+	if job, ok := m.Jobs[id]; ok {
+		job.RowsWritten = rowsWritten
+	}
+	return nil
+}
+
+func (m *MockExportJobRepository) Complete(ctx context.Context, id int, fileName string, sizeBytes int64, expiresAt time.Time) error {
+	m.record("Complete")
+	if job, ok := m.Jobs[id]; ok {
+		now := time.Now()
+		job.Status = models.ExportJobCompleted
+		job.FileName = &fileName
+		job.SizeBytes = &sizeBytes
+		job.ExpiresAt = &expiresAt
+		job.CompletedAt = &now
+	}
+	return nil
+}
+
+func (m *MockExportJobRepository) Fail(ctx context.Context, id int, message string) error {
+	m.record("Fail")
+	if job, ok := m.Jobs[id]; ok {
+		job.Status = models.ExportJobFailed
+		job.Error = &message
+	}
+	return nil
+}
+
+func (m *MockExportJobRepository) ListExpired(ctx context.Context, now time.Time) ([]*models.ExportJob, error) {
+	m.record("ListExpired")
+	jobs := []*models.ExportJob{}
+	for id := 1; id <= m.nextID; id++ {
+		job, ok := m.Jobs[id]
+		if ok && job.FileName != nil && job.Expired(now) {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	return jobs, nil
+}
+
+func (m *MockExportJobRepository) ClearFile(ctx context.Context, id int) error {
+	m.record("ClearFile")
+	if job, ok := m.Jobs[id]; ok {
+		job.FileName = nil
+	}
+	return nil
+}
+
+// MockQueueDrain mocks queue.Drain over job bodies held off an in-memory queue
+type MockQueueDrain struct {
+	Held        []*queue.QueuedJob
+	Requeued    [][]byte
+	Quarantined map[string]string // Reason by body
+}
+
+func NewMockQueueDrain(bodies ...[]byte) *MockQueueDrain {
+	m := &MockQueueDrain{Quarantined: map[string]string{}}
+	for _, body := range bodies {
+		job := &queue.QueuedJob{Body: body}
+		if decoded, err := queue.DecodeMessageJob(body); err == nil {
+			job.Job = decoded
+		}
+		m.Held = append(m.Held, job)
+	}
+	return m
+}
+
+func (m *MockQueueDrain) Jobs() []*queue.QueuedJob {
+	return m.Held
+}
+
+func (m *MockQueueDrain) Requeue(job *queue.QueuedJob) error {
+	m.Requeued = append(m.Requeued, job.Body)
+	return nil
+}
+
+func (m *MockQueueDrain) Quarantine(job *queue.QueuedJob, reason string) error {
+	m.Quarantined[string(job.Body)] = reason
+	return nil
+}
+
+// MockPublisher mocks queue.Publisher
+type MockPublisher struct {
+	PublishMessageFunc func(messageID, campaignID, customerID int) error
+	Published          []PublishedJob
+}
+
+type PublishedJob struct {
+	MessageID  int
+	CampaignID int
+	CustomerID int
+	Ordered    bool       // Published to the campaign's ordered queue
+	ProcessBy  *time.Time // The job's deadline, when it has one
+}
+
+func NewMockPublisher() *MockPublisher {
+	return &MockPublisher{
+		Published: []PublishedJob{},
+	}
+}
+
+func (m *MockPublisher) PublishMessage(messageID, campaignID, customerID int) error {
+	if m.PublishMessageFunc != nil {
+		return m.PublishMessageFunc(messageID, campaignID, customerID)
+	}
+	m.Published = append(m.Published, PublishedJob{
+		MessageID:  messageID,
+		CampaignID: campaignID,
+		CustomerID: customerID,
+	})
+	return nil
+}
+
+// PublishJob records job like PublishMessage, keeping its deadline
+func (m *MockPublisher) PublishJob(job queue.MessageJob) error {
+	return m.publishJob(job, false)
+}
+
+// PublishOrderedJob records job like PublishJob, marked as published to the ordered queue
+func (m *MockPublisher) PublishOrderedJob(job queue.MessageJob) error {
+	return m.publishJob(job, true)
+}
+
+func (m *MockPublisher) publishJob(job queue.MessageJob, ordered bool) error {
+	if m.PublishMessageFunc != nil {
+		return m.PublishMessageFunc(job.MessageID, job.CampaignID, job.CustomerID)
+	}
+	m.Published = append(m.Published, PublishedJob{
+		MessageID:  job.MessageID,
+		CampaignID: job.CampaignID,
+		CustomerID: job.CustomerID,
+		Ordered:    ordered,
+		ProcessBy:  job.ProcessBy,
+	})
+	return nil
+}
+
+func (m *MockPublisher) GetPublishedCount() int {
+	return len(m.Published)
+}
+
+func (m *MockPublisher) Reset() {
+	m.Published = []PublishedJob{}
+}
+
+// MockCampaignEventRepository mocks CampaignEventRepository, keeping events in memory
+// It is safe for concurrent use, as waiting requests read while tests write
+// Progress is what ListSendingProgress returns
+type MockCampaignEventRepository struct {
+	mu       sync.Mutex
+	Events   []*models.CampaignEvent
+	Progress []*models.CampaignProgress
+	Calls    map[string]int
+
+	RecordSendMetricsFunc func(ctx context.Context, campaignID int, ratePerSecond float64) error
+}
+
+// NewMockCampaignEventRepository creates the repository holding history, recorded in order
+// as Create would; seeding it is not counted as calls
+func NewMockCampaignEventRepository(history ...*models.CampaignEvent) *MockCampaignEventRepository {
+	m := &MockCampaignEventRepository{
+		Calls: make(map[string]int),
+	}
+	for _, event := range history {
+		m.Create(context.Background(), event)
+	}
+	m.Calls["Create"] = 0
+	return m
+}
+
+func (m *MockCampaignEventRepository) Create(ctx context.Context, event *models.CampaignEvent) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["Create"]++
+	if event.DedupeKey != nil {
+		for _, existing := range m.Events {
+			if existing.CampaignID == event.CampaignID && existing.DedupeKey != nil && *existing.DedupeKey == *event.DedupeKey {
+				return false, nil
+			}
+		}
+	}
+	event.ID = int64(len(m.Events) + 1)
+	event.CreatedAt = time.Now()
+	stored := *event
+	m.Events = append(m.Events, &stored)
+	return true, nil
+}
+
+func (m *MockCampaignEventRepository) ListSince(ctx context.Context, campaignID int, sinceID int64, limit int) ([]*models.CampaignEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["ListSince"]++
+	events := []*models.CampaignEvent{}
+	for _, event := range m.Events {
+		if event.CampaignID == campaignID && event.ID > sinceID && len(events) < limit {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockCampaignEventRepository) ListReplay(ctx context.Context, campaignID int, afterID int64, limit int) ([]*models.CampaignEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["ListReplay"]++
+	events := []*models.CampaignEvent{}
+	for _, event := range m.Events {
+		if event.CampaignID == campaignID && event.ID > afterID && len(events) < limit {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockCampaignEventRepository) ListSendingProgress(ctx context.Context) ([]*models.CampaignProgress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["ListSendingProgress"]++
+	return m.Progress, nil
+}
+
+func (m *MockCampaignEventRepository) RecordSendMetrics(ctx context.Context, campaignID int, ratePerSecond float64) error {
+	m.mu.Lock()
+	m.Calls["RecordSendMetrics"]++
+	m.mu.Unlock()
+	if m.RecordSendMetricsFunc != nil {
+		return m.RecordSendMetricsFunc(ctx, campaignID, ratePerSecond)
+	}
+	return nil
+}
+
+// Count returns how many events the campaign has
+func (m *MockCampaignEventRepository) Count(campaignID int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, event := range m.Events {
+		if event.CampaignID == campaignID {
+			count++
+		}
+	}
+	return count
+}
+
+// MockStatsRebuildRepository mocks StatsRebuildRepository, keeping one campaign's messages,
+// status and spend in memory
+// Corrections are recorded on Events, as the database triggers record them
+type MockStatsRebuildRepository struct {
+	Messages map[int]*models.MessageSummary
+	Status   models.CampaignStatus
+	Spend    float64
+	Events   *MockCampaignEventRepository
+	callLog
+}
+
+func NewMockStatsRebuildRepository(events *MockCampaignEventRepository) *MockStatsRebuildRepository {
+	return &MockStatsRebuildRepository{
+		Messages: make(map[int]*models.MessageSummary),
+		Events:   events,
+		callLog:  callLog{Calls: make(map[string]int)},
+	}
+}
+
+func (m *MockStatsRebuildRepository) ListMessageSummaries(ctx context.Context, campaignID int) ([]*models.MessageSummary, error) {
+	m.record("ListMessageSummaries")
+	ids := make([]int, 0, len(m.Messages))
+	for id := range m.Messages {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	summaries := make([]*models.MessageSummary, len(ids))
+	for i, id := range ids {
+		copied := *m.Messages[id]
+		summaries[i] = &copied
+	}
+	return summaries, nil
+}
+
+func (m *MockStatsRebuildRepository) CorrectMessage(ctx context.Context, campaignID int, summary *models.MessageSummary) error {
+	m.record("CorrectMessage")
+	if m.Messages[summary.ID] == nil {
+		return repository.ErrMessageNotFound
+	}
+	copied := *summary
+	m.Messages[summary.ID] = &copied
+	m.Events.Create(ctx, NewTestMessageEvent(campaignID, &copied))
+	return nil
+}
+
+func (m *MockStatsRebuildRepository) CorrectCampaignStatus(ctx context.Context, campaignID int, status models.CampaignStatus) error {
+	m.record("CorrectCampaignStatus")
+	m.Events.Create(ctx, NewTestCampaignStatusEvent(campaignID, m.Status, status))
+	m.Status = status
+	return nil
+}
+
+func (m *MockStatsRebuildRepository) CorrectSpend(ctx context.Context, campaignID int, spend float64) error {
+	m.record("CorrectSpend")
+	m.Spend = spend
+	return nil
+}
+
+// MockLinkRepository mocks LinkRepository, keeping links and clicks in memory
+type MockLinkRepository struct {
+	Links           []*models.TrackedLink
+	Clicks          []*models.LinkClick
+	CampaignID      int // Campaign of every link's message
+	EnsureLinksFunc func(ctx context.Context, links []*models.TrackedLink) error
+	RecordClickFunc func(ctx context.Context, click *models.LinkClick) error
+	callLog
+}
+
+func NewMockLinkRepository() *MockLinkRepository {
+	return &MockLinkRepository{
+		Links:   []*models.TrackedLink{},
+		Clicks:  []*models.LinkClick{},
+		callLog: callLog{Calls: make(map[string]int)},
+	}
+}
+
+func (m *MockLinkRepository) EnsureLinks(ctx context.Context, links []*models.TrackedLink) error {
+	m.record("EnsureLinks")
+	if m.EnsureLinksFunc != nil {
+		return m.EnsureLinksFunc(ctx, links)
+	}
+	for _, link := range links {
+		stored := false
+		for _, existing := range m.Links {
+			if existing.MessageID == link.MessageID && existing.Position == link.Position {
+				link.ID, link.Token, link.URL = existing.ID, existing.Token, existing.URL
+				stored = true
+			}
+		}
+		if !stored {
+			link.ID = len(m.Links) + 1
+			copied := *link
+			m.Links = append(m.Links, &copied)
+		}
+	}
+	return nil
+}
+
+func (m *MockLinkRepository) GetByToken(ctx context.Context, token string) (*models.TrackedLink, error) {
+	m.record("GetByToken")
+	for _, link := range m.Links {
+		if link.Token == token {
+			found := *link
+			found.CampaignID = m.CampaignID
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockLinkRepository) RecordClick(ctx context.Context, click *models.LinkClick) error {
+	m.record("RecordClick")
+	if m.RecordClickFunc != nil {
+		return m.RecordClickFunc(ctx, click)
+	}
+	click.ID = int64(len(m.Clicks) + 1)
+	m.Clicks = append(m.Clicks, click)
+	return nil
+}
+
+// MockMessageReassignmentRepository mocks MessageReassignmentRepository, moving messages
+// held in memory and keeping the audit log
+type MockMessageReassignmentRepository struct {
+	Messages      []*models.OutboundMessage
+	Reassignments []*models.MessageReassignment
+	MoveBatchFunc func(ctx context.Context, reassignment *models.MessageReassignment, fingerprint string, limit int) (int, error)
+	callLog
+}
+
+func NewMockMessageReassignmentRepository() *MockMessageReassignmentRepository {
+	return &MockMessageReassignmentRepository{
+		Messages:      []*models.OutboundMessage{},
+		Reassignments: []*models.MessageReassignment{},
+		callLog:       callLog{Calls: make(map[string]int)},
+	}
+}
+
+func (m *MockMessageReassignmentRepository) Create(ctx context.Context, reassignment *models.MessageReassignment) error {
+	m.record("Create")
+	reassignment.ID = len(m.Reassignments) + 1
+	m.Reassignments = append(m.Reassignments, reassignment)
+	return nil
+}
+
+func (m *MockMessageReassignmentRepository) MoveBatch(ctx context.Context, reassignment *models.MessageReassignment, fingerprint string, limit int) (int, error) {
+	m.record("MoveBatch")
+	if m.MoveBatchFunc != nil {
+		return m.MoveBatchFunc(ctx, reassignment, fingerprint, limit)
+	}
+	moved := 0
+	for _, message := range m.Messages {
+		if moved == limit {
+			break
+		}
+		if message.CampaignID == reassignment.FromCampaignID && message.Status == reassignment.Status {
+			message.CampaignID = reassignment.ToCampaignID
+			message.ContentFingerprint = &fingerprint
+			moved++
+		}
+	}
+	reassignment.MessagesMoved += moved
+	return moved, nil
+}
+
+// MockPhoneNormalizationRepository mocks PhoneNormalizationRepository over phones held in memory,
+// listed in the order given
+type MockPhoneNormalizationRepository struct {
+	Customers    []*repository.StoredPhone
+	Suppressions []*repository.StoredPhone
+	callLog
+}
+
+func NewMockPhoneNormalizationRepository() *MockPhoneNormalizationRepository {
+	return &MockPhoneNormalizationRepository{
+		Customers:    []*repository.StoredPhone{},
+		Suppressions: []*repository.StoredPhone{},
+		callLog:      callLog{Calls: make(map[string]int)},
+	}
+}
+
+func (m *MockPhoneNormalizationRepository) ListCustomerPhones(ctx context.Context) ([]*repository.StoredPhone, error) {
+	m.record("ListCustomerPhones")
+	return m.Customers, nil
+}
+
+func (m *MockPhoneNormalizationRepository) ListSuppressionPhones(ctx context.Context) ([]*repository.StoredPhone, error) {
+	m.record("ListSuppressionPhones")
+	return m.Suppressions, nil
+}
+
+func (m *MockPhoneNormalizationRepository) RewriteCustomerPhones(ctx context.Context, rewrites []*repository.PhoneRewrite) (int, error) {
+	m.record("RewriteCustomerPhones")
+	for _, rewrite := range rewrites {
+		rewrite.Row.Phone = rewrite.To
+	}
+	return len(rewrites), nil
+}
+
+func (m *MockPhoneNormalizationRepository) RewriteSuppressionPhones(ctx context.Context, rewrites []*repository.PhoneRewrite, duplicates []*repository.StoredPhone) (int, int, error) {
+	m.record("RewriteSuppressionPhones")
+	kept := []*repository.StoredPhone{}
+	for _, row := range m.Suppressions {
+		duplicate := false
+		for _, d := range duplicates {
+			duplicate = duplicate || d == row
+		}
+		if !duplicate {
+			kept = append(kept, row)
+		}
+	}
+	m.Suppressions = kept
+	for _, rewrite := range rewrites {
+		rewrite.Row.Phone = rewrite.To
+	}
+	return len(rewrites), len(duplicates), nil
+}
+
+// MockWorkerClaim is a message claim held by MockWorkerRepository
+type MockWorkerClaim struct {
+	WorkerID  string
+	ExpiresAt *time.Time // nil once released
+}
+
+// MockWorkerRepository mocks WorkerRepository, keeping heartbeats and message claims in
+// memory; it is safe for concurrent workers
+type MockWorkerRepository struct {
+	mu              sync.Mutex
+	Claims          map[int]*MockWorkerClaim
+	Heartbeats      map[string]time.Time
+	Now             func() time.Time
+	GetActivityFunc func(ctx context.Context, workerID string, limit int) (*models.WorkerActivity, error)
+	Calls           map[string]int
+}
+
+func NewMockWorkerRepository() *MockWorkerRepository {
+	return &MockWorkerRepository{
+		Claims:     make(map[int]*MockWorkerClaim),
+		Heartbeats: make(map[string]time.Time),
+		Now:        time.Now,
+		Calls:      make(map[string]int),
+	}
+}
+
+func (m *MockWorkerRepository) Heartbeat(ctx context.Context, workerID string, startedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["Heartbeat"]++
+	m.Heartbeats[workerID] = m.Now()
+	return nil
+}
+
+func (m *MockWorkerRepository) ReleaseAll(ctx context.Context, workerID string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["ReleaseAll"]++
+	var released int64
+	for _, claim := range m.Claims {
+		if claim.WorkerID == workerID && claim.ExpiresAt != nil {
+			claim.ExpiresAt = nil
+			released++
+		}
+	}
+	return released, nil
+}
+
+func (m *MockWorkerRepository) Claim(ctx context.Context, messageID int, workerID string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["Claim"]++
+	now := m.Now()
+	if claim, ok := m.Claims[messageID]; ok && claim.ExpiresAt != nil && !claim.ExpiresAt.Before(now) {
+		return false, nil
+	}
+	expiresAt := now.Add(ttl)
+	m.Claims[messageID] = &MockWorkerClaim{WorkerID: workerID, ExpiresAt: &expiresAt}
+	return true, nil
+}
+
+func (m *MockWorkerRepository) Release(ctx context.Context, messageID int, workerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["Release"]++
+	if claim, ok := m.Claims[messageID]; ok && claim.WorkerID == workerID {
+		claim.ExpiresAt = nil
+	}
+	return nil
+}
+
+func (m *MockWorkerRepository) GetActivity(ctx context.Context, workerID string, limit int) (*models.WorkerActivity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["GetActivity"]++
+	if m.GetActivityFunc != nil {
+		return m.GetActivityFunc(ctx, workerID, limit)
+	}
+	return nil, repository.ErrWorkerNotFound
+}
+
+// ClaimedBy returns the worker that last claimed messageID, or "" if none has
+func (m *MockWorkerRepository) ClaimedBy(messageID int) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if claim, ok := m.Claims[messageID]; ok {
+		return claim.WorkerID
+	}
+	return ""
+}
+
+// MockAPIKeyRepository mocks APIKeyRepository, keeping issued keys in memory; it is safe for
+// concurrent use, so several API processes' key services can share one
+type MockAPIKeyRepository struct {
+	mu           sync.Mutex
+	Keys         []*models.APIKey
+	MarkUsedFunc func(ctx context.Context, ids []int, usedAt time.Time) error
+	Calls        map[string]int
+}
+
+func NewMockAPIKeyRepository() *MockAPIKeyRepository {
+	return &MockAPIKeyRepository{Calls: make(map[string]int)}
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["Create"]++
+	key.ID = len(m.Keys) + 1
+	key.CreatedAt = time.Now()
+	stored := *key
+	m.Keys = append(m.Keys, &stored)
+	return nil
+}
+
+func (m *MockAPIKeyRepository) List(ctx context.Context) ([]*models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["List"]++
+	keys := []*models.APIKey{}
+	for i := len(m.Keys) - 1; i >= 0; i-- {
+		key := *m.Keys[i]
+		keys = append(keys, &key)
+	}
+	return keys, nil
+}
+
+func (m *MockAPIKeyRepository) ListActive(ctx context.Context) ([]*models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["ListActive"]++
+	keys := []*models.APIKey{}
+	for _, stored := range m.Keys {
+		if !stored.IsRevoked() {
+			key := *stored
+			keys = append(keys, &key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, id int) (*models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["Revoke"]++
+	if id < 1 || id > len(m.Keys) {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	stored := m.Keys[id-1]
+	if stored.RevokedAt == nil {
+		now := time.Now()
+		stored.RevokedAt = &now
+	}
+	key := *stored
+	return &key, nil
+}
+
+func (m *MockAPIKeyRepository) MarkUsed(ctx context.Context, ids []int, usedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["MarkUsed"]++
+	if m.MarkUsedFunc != nil {
+		return m.MarkUsedFunc(ctx, ids, usedAt)
+	}
+	for _, id := range ids {
+		if id >= 1 && id <= len(m.Keys) {
+			at := usedAt
+			m.Keys[id-1].LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+// MockEngagementRepository mocks EngagementRepository over histories held in memory, listed
+// in customer ID order; stored scores are kept in Scores
+type MockEngagementRepository struct {
+	Histories []*models.EngagementHistory
+	Scores    map[int]float64
+	ScoredAt  time.Time
+
+	StoreEngagementScoresFunc func(ctx context.Context, scores map[int]float64, scoredAt time.Time) error
+	callLog
+}
+
+func NewMockEngagementRepository(histories ...*models.EngagementHistory) *MockEngagementRepository {
+	sort.Slice(histories, func(i, j int) bool { return histories[i].CustomerID < histories[j].CustomerID })
+	return &MockEngagementRepository{
+		Histories: histories,
+		Scores:    map[int]float64{},
+		callLog:   callLog{Calls: make(map[string]int)},
+	}
+}
+
+func (m *MockEngagementRepository) ListEngagementHistory(ctx context.Context, afterID, limit int, since time.Time) ([]*models.EngagementHistory, error) {
+	m.record("ListEngagementHistory")
+	page := []*models.EngagementHistory{}
+	for _, history := range m.Histories {
+		if history.CustomerID <= afterID {
+			continue
+		}
+		if len(page) == limit {
+			break
+		}
+		recent := &models.EngagementHistory{CustomerID: history.CustomerID, Failed: history.Failed}
+		for _, sentAt := range history.SentAt {
+			if !sentAt.Before(since) {
+				recent.SentAt = append(recent.SentAt, sentAt)
+			}
+		}
+		page = append(page, recent)
+	}
+	return page, nil
+}
+
+func (m *MockEngagementRepository) StoreEngagementScores(ctx context.Context, scores map[int]float64, scoredAt time.Time) error {
+	m.record("StoreEngagementScores")
+	if m.StoreEngagementScoresFunc != nil {
+		return m.StoreEngagementScoresFunc(ctx, scores, scoredAt)
+	}
+	for id, score := range scores {
+		m.Scores[id] = score
+	}
+	m.ScoredAt = scoredAt
+	return nil
+}
+
+// MockSettingsRepository mocks SettingsRepository, keeping settings and their audit log in
+// memory; it is safe for concurrent use, so the API's and workers' switches can share one
+type MockSettingsRepository struct {
+	mu       sync.Mutex
+	Settings map[string]*models.Setting
+	Changes  []*models.SettingChange
+	GetFunc  func(ctx context.Context, key string) (*models.Setting, error)
+	Calls    map[string]int
+}
+
+func NewMockSettingsRepository() *MockSettingsRepository {
+	return &MockSettingsRepository{
+		Settings: make(map[string]*models.Setting),
+		Calls:    make(map[string]int),
+	}
+}
+
+func (m *MockSettingsRepository) Get(ctx context.Context, key string) (*models.Setting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["Get"]++
+	if m.GetFunc != nil {
+		return m.GetFunc(ctx, key)
+	}
+	stored, ok := m.Settings[key]
+	if !ok {
+		return nil, repository.ErrSettingNotFound
+	}
+	setting := *stored
+	return &setting, nil
+}
+
+func (m *MockSettingsRepository) Set(ctx context.Context, key, value, reason string, actor *string) (*models.Setting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["Set"]++
+	now := time.Now()
+	m.Settings[key] = &models.Setting{Key: key, Value: value, UpdatedBy: actor, UpdatedAt: now}
+	m.Changes = append(m.Changes, &models.SettingChange{
+		ID: len(m.Changes) + 1, Key: key, Value: value, Reason: reason, Actor: actor, CreatedAt: now,
+	})
+	setting := *m.Settings[key]
+	return &setting, nil
+}
+
+func (m *MockSettingsRepository) ListChanges(ctx context.Context, key string, limit int) ([]*models.SettingChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls["ListChanges"]++
+	changes := []*models.SettingChange{}
+	for i := len(m.Changes) - 1; i >= 0 && len(changes) < limit; i-- {
+		if m.Changes[i].Key == key {
+			change := *m.Changes[i]
+			changes = append(changes, &change)
+		}
+	}
+	return changes, nil
+}
+
+// MockRecurrenceRepository mocks RecurrenceRepository with recurrences and occurrences in memory
+// Occurrences are cloned into campaigns numbered from NextCampaignID
+type MockRecurrenceRepository struct {
+	Recurrences    map[int]*models.CampaignRecurrence
+	Occurrences    []*models.CampaignOccurrence
+	NextCampaignID int
+	callLog
+
+	CreateFunc func(ctx context.Context, campaignID int, rule *models.RecurrenceRule, plan *models.SendPlan, next time.Time) (*models.CampaignRecurrence, error)
+}
+
+func NewMockRecurrenceRepository() *MockRecurrenceRepository {
+	return &MockRecurrenceRepository{
+		Recurrences:    make(map[int]*models.CampaignRecurrence),
+		NextCampaignID: 100,
+		callLog:        callLog{Calls: make(map[string]int)},
+	}
+}
+
+func (m *MockRecurrenceRepository) Create(ctx context.Context, campaignID int, rule *models.RecurrenceRule, plan *models.SendPlan, next time.Time) (*models.CampaignRecurrence, error) {
+	m.record("Create")
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, campaignID, rule, plan, next)
+	}
+	now := time.Now()
+	m.Recurrences[campaignID] = &models.CampaignRecurrence{
+		CampaignID: campaignID, Rule: *rule, NextOccurrenceAt: &next, CreatedAt: now, UpdatedAt: now,
+	}
+	recurrence := *m.Recurrences[campaignID]
+	return &recurrence, nil
+}
+
+func (m *MockRecurrenceRepository) Get(ctx context.Context, campaignID int) (*models.CampaignRecurrence, error) {
+	m.record("Get")
+	stored, ok := m.Recurrences[campaignID]
+	if !ok {
+		return nil, repository.ErrRecurrenceNotFound
+	}
+	recurrence := *stored
+	return &recurrence, nil
+}
+
+func (m *MockRecurrenceRepository) Pause(ctx context.Context, campaignID int) (*models.CampaignRecurrence, error) {
+	m.record("Pause")
+	stored, ok := m.Recurrences[campaignID]
+	if !ok {
+		return nil, repository.ErrRecurrenceNotFound
+	}
+	stored.Paused = true
+	recurrence := *stored
+	return &recurrence, nil
+}
+
+func (m *MockRecurrenceRepository) Resume(ctx context.Context, campaignID int, next *time.Time) (*models.CampaignRecurrence, error) {
+	m.record("Resume")
+	stored, ok := m.Recurrences[campaignID]
+	if !ok {
+		return nil, repository.ErrRecurrenceNotFound
+	}
+	stored.Paused = false
+	stored.NextOccurrenceAt = next
+	recurrence := *stored
+	return &recurrence, nil
+}
+
+func (m *MockRecurrenceRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*models.CampaignRecurrence, error) {
+	m.record("ListDue")
+	due := []*models.CampaignRecurrence{}
+	for _, stored := range m.Recurrences {
+		if !stored.Paused && stored.NextOccurrenceAt != nil && !stored.NextOccurrenceAt.After(now) && len(due) < limit {
+			recurrence := *stored
+			due = append(due, &recurrence)
+		}
+	}
+	return due, nil
+}
+
+func (m *MockRecurrenceRepository) CreateOccurrence(ctx context.Context, parentID int, occurrenceAt time.Time, name string, next *time.Time) (int, error) {
+	m.record("CreateOccurrence")
+	stored, ok := m.Recurrences[parentID]
+	if !ok || stored.Paused || stored.NextOccurrenceAt == nil || !stored.NextOccurrenceAt.Equal(occurrenceAt) {
+		return 0, nil
+	}
+	stored.NextOccurrenceAt = next
+	stored.Occurrences++
+	campaignID := m.NextCampaignID
+	m.NextCampaignID++
+	m.Occurrences = append(m.Occurrences, &models.CampaignOccurrence{
+		CampaignID: campaignID, ParentCampaignID: parentID, OccurrenceAt: occurrenceAt,
+		Name: name, Status: models.CampaignStatusDraft, CreatedAt: time.Now(),
+	})
+	return campaignID, nil
+}
+
+func (m *MockRecurrenceRepository) ListOccurrences(ctx context.Context, parentID int) ([]*models.CampaignOccurrence, error) {
+	m.record("ListOccurrences")
+	occurrences := []*models.CampaignOccurrence{}
+	for i := len(m.Occurrences) - 1; i >= 0; i-- {
+		if m.Occurrences[i].ParentCampaignID == parentID {
+			occurrence := *m.Occurrences[i]
+			occurrences = append(occurrences, &occurrence)
+		}
+	}
+	return occurrences, nil
 }
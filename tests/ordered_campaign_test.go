@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestOrderedQueueName tests that each ordered campaign gets a queue of its own
+func TestOrderedQueueName(t *testing.T) {
+	AssertEqual(t, queue.OrderedQueueName("campaign_sends", 42), "campaign_sends.campaign.42")
+	AssertEqual(t, queue.OrderedQueueName("campaign_sends", 7), "campaign_sends.campaign.7")
+}
+
+// TestCreateCampaign_Ordered tests that the ordered flag is stored at create
+func TestCreateCampaign_Ordered(t *testing.T) {
+	svc, campaignRepo, _, _ := setupApprovalTest(t)
+
+	var created *models.Campaign
+	campaignRepo.CreateFunc = func(ctx context.Context, campaign *models.Campaign) error {
+		created = campaign
+		return nil
+	}
+
+	for _, ordered := range []bool{true, false} {
+		_, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+			Name: "Part one", Channel: models.ChannelSMS, BaseTemplate: "Hi {first_name}", Ordered: ordered,
+		})
+		AssertNoError(t, err)
+		AssertEqual(t, created.Ordered, ordered)
+	}
+}
+
+// TestListOrderedOutstanding_Query tests that only ordered campaigns with messages left are listed
+func TestListOrderedOutstanding_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT c.id FROM campaigns c WHERE c.ordered AND EXISTS \(.+m.status = 'pending' OR \(m.status = 'failed' AND m.retry_count < 3\).+\) ORDER BY c.id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(5))
+
+	ids, err := repository.NewCampaignRepository(db).ListOrderedOutstanding(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, fmt.Sprint(ids), "[3 5]")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestOrderedQueues_Sync tests that the ordered campaigns with messages left are passed on
+func TestOrderedQueues_Sync(t *testing.T) {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.ListOrderedOutstandingFunc = func(ctx context.Context) ([]int, error) {
+		return []int{3, 5}, nil
+	}
+	syncer := service.NewOrderedQueues(campaignRepo)
+
+	var consumed []int
+	err := syncer.Sync(context.Background(), func(campaignIDs []int) error {
+		consumed = campaignIDs
+		return nil
+	})
+	AssertNoError(t, err)
+	AssertEqual(t, fmt.Sprint(consumed), "[3 5]")
+
+	err = syncer.Sync(context.Background(), func(campaignIDs []int) error {
+		return errors.New("broker unavailable")
+	})
+	AssertError(t, err, "failed to sync ordered queues: broker unavailable")
+
+	campaignRepo.ListOrderedOutstandingFunc = func(ctx context.Context) ([]int, error) {
+		return nil, errors.New("database unavailable")
+	}
+	err = syncer.Sync(context.Background(), func(campaignIDs []int) error {
+		t.Fatal("Expected nothing to consume when listing fails")
+		return nil
+	})
+	AssertError(t, err, "database unavailable")
+}
+
+// TestOrderedCampaign_EndToEnd tests against a broker that an ordered campaign's jobs are
+// processed in creation order by two workers, each running several consumers, while an
+// unordered campaign is processed alongside it; the ordered queue is deleted on completion
+func TestOrderedCampaign_EndToEnd(t *testing.T) {
+	conns := []*queue.Connection{SetupTestRabbitMQ(t), SetupTestRabbitMQ(t)}
+	queueName := fmt.Sprintf("test_campaign_sends_%d", time.Now().UnixNano())
+	const unorderedID, orderedID, jobs = 1, 2, 50
+
+	var mu sync.Mutex
+	processed := map[int][]int{}
+	done := make(chan struct{})
+	handle := func(job *queue.MessageJob) error {
+		// Uneven handling times scramble anything not kept in order
+		time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		processed[job.CampaignID] = append(processed[job.CampaignID], job.MessageID)
+		if len(processed[unorderedID])+len(processed[orderedID]) == 2*jobs {
+			close(done)
+		}
+		return nil
+	}
+
+	publisher, err := queue.NewPublisher(conns[0], queueName)
+	AssertNoError(t, err)
+	for i := 1; i <= jobs; i++ {
+		AssertNoError(t, publisher.PublishMessage(i, unorderedID, i))
+		AssertNoError(t, publisher.PublishOrderedMessage(1000+i, orderedID, i))
+	}
+
+	groups := []*queue.OrderedConsumers{}
+	for _, conn := range conns {
+		for i := 0; i < 3; i++ {
+			consumer, err := queue.NewConsumer(conn, queueName, handle)
+			AssertNoError(t, err)
+			AssertNoError(t, consumer.Start())
+			t.Cleanup(func() { consumer.Stop() })
+		}
+
+		group := queue.NewOrderedConsumers(conn, queueName, handle)
+		AssertNoError(t, group.Sync([]int{orderedID}))
+		AssertEqual(t, group.Has(orderedID), true)
+		groups = append(groups, group)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("Timed out waiting for jobs to be processed")
+	}
+
+	mu.Lock()
+	ordered, unordered := processed[orderedID], len(processed[unorderedID])
+	mu.Unlock()
+	AssertEqual(t, unordered, jobs)
+	AssertEqual(t, len(ordered), jobs)
+	for i, messageID := range ordered {
+		if messageID != 1001+i {
+			t.Fatalf("Expected ordered campaign's job %d to be message %d but got %d (order %v)", i, 1001+i, messageID, ordered)
+		}
+	}
+
+	// The campaign has completed: its queue stops being consumed and is deleted
+	for _, group := range groups {
+		AssertNoError(t, group.Sync(nil))
+		AssertEqual(t, group.Has(orderedID), false)
+	}
+	if _, err := conns[0].QueueDepth(queue.OrderedQueueName(queueName, orderedID)); err == nil {
+		t.Fatal("Expected the ordered queue to be deleted")
+	}
+	AssertNoError(t, conns[0].DeleteQueue(queueName))
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"smsleopard/internal/metrics"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestGetWithDetails_MissingReferences tests that not-found cases map to sentinel errors
+func TestGetWithDetails_MissingReferences(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rows     *sqlmock.Rows
+		expected error
+	}{
+		{
+			name:     "message deleted",
+			rows:     sqlmock.NewRows([]string{"campaign_exists", "customer_exists"}),
+			expected: repository.ErrMessageNotFound,
+		},
+		{
+			name:     "campaign deleted",
+			rows:     sqlmock.NewRows([]string{"campaign_exists", "customer_exists"}).AddRow(false, true),
+			expected: repository.ErrCampaignNotFound,
+		},
+		{
+			name:     "customer deleted",
+			rows:     sqlmock.NewRows([]string{"campaign_exists", "customer_exists"}).AddRow(true, false),
+			expected: repository.ErrCustomerNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := NewMockDB(t)
+			defer db.Close()
+
+			mock.ExpectQuery("FROM outbound_messages m JOIN campaigns").
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			mock.ExpectQuery("SELECT EXISTS").
+				WithArgs(1).
+				WillReturnRows(tc.rows)
+
+			_, err := repository.NewMessageRepository(db).GetWithDetails(context.Background(), 1)
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("Expected %v but got %v", tc.expected, err)
+			}
+			AssertNoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestGetWithDetails_TransientError tests that connection errors are not reported as not-found
+func TestGetWithDetails_TransientError(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM outbound_messages m JOIN campaigns").
+		WithArgs(1).
+		WillReturnError(errors.New("connection reset by peer"))
+
+	_, err := repository.NewMessageRepository(db).GetWithDetails(context.Background(), 1)
+	AssertError(t, err, "failed to get message with details: connection reset by peer")
+	for _, sentinel := range []error{repository.ErrMessageNotFound, repository.ErrCampaignNotFound, repository.ErrCustomerNotFound} {
+		if errors.Is(err, sentinel) {
+			t.Fatalf("Expected transient error but got sentinel %v", sentinel)
+		}
+	}
+}
+
+// TestWorker_CampaignDeletedBeforeProcessing tests deleting the campaign between enqueue and processing
+func TestWorker_CampaignDeletedBeforeProcessing(t *testing.T) {
+	db, msgRepo, campRepo, custRepo, _, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	customer := &models.Customer{Phone: "+254700000099", FirstName: StringPtr("Jane")}
+	AssertNoError(t, custRepo.Create(ctx, customer))
+
+	campaign := &models.Campaign{
+		Name:         "Deleted Campaign",
+		Channel:      models.ChannelSMS,
+		Status:       models.CampaignStatusSending,
+		BaseTemplate: "Hi {first_name}",
+	}
+	AssertNoError(t, campRepo.Create(ctx, campaign))
+
+	message := &models.OutboundMessage{CampaignID: campaign.ID, CustomerID: customer.ID, Status: models.MessageStatusPending}
+	AssertNoError(t, msgRepo.CreateBatch(ctx, []*models.OutboundMessage{message}))
+
+	// Campaign is force-deleted after the job was enqueued, taking the message with it
+	_, err := campRepo.DeleteWithMessages(ctx, campaign.ID)
+	AssertNoError(t, err)
+
+	_, err = msgRepo.GetWithDetails(ctx, message.ID)
+	if !errors.Is(err, repository.ErrMessageNotFound) {
+		t.Fatalf("Expected ErrMessageNotFound but got %v", err)
+	}
+
+	var remaining int
+	AssertNoError(t, db.QueryRow("SELECT COUNT(*) FROM outbound_messages WHERE id = $1", message.ID).Scan(&remaining))
+	AssertEqual(t, remaining, 0)
+}
+
+// TestUpdateStatus_MessageDeleted tests that updating a deleted message reports ErrMessageNotFound
+func TestUpdateStatus_MessageDeleted(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE outbound_messages").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repository.NewMessageRepository(db).UpdateStatus(context.Background(), 1, models.MessageStatusSent, nil)
+	if !errors.Is(err, repository.ErrMessageNotFound) {
+		t.Fatalf("Expected ErrMessageNotFound but got %v", err)
+	}
+}
+
+// TestWorker_MessageDeletedBeforeSend tests that a job whose message is gone before the send
+// is acknowledged and dropped without sending
+func TestWorker_MessageDeletedBeforeSend(t *testing.T) {
+	sender := &recordingSender{}
+	f := newProcessingErrorFixture(t, sender)
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		return nil, repository.ErrMessageNotFound
+	}
+	before := testutil.ToFloat64(metrics.SkippedMessages.WithLabelValues("message_deleted"))
+
+	AssertNoError(t, f.processor.Handle(&queue.MessageJob{MessageID: 7, CampaignID: 1, CustomerID: 1}))
+	AssertEqual(t, len(sender.sent), 0)
+	AssertEqual(t, testutil.ToFloat64(metrics.SkippedMessages.WithLabelValues("message_deleted")), before+1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestWorker_MessageDeletedAfterSend tests that a message deleted while it was being sent is
+// acknowledged rather than requeued, which would send it again
+func TestWorker_MessageDeletedAfterSend(t *testing.T) {
+	sender := &recordingSender{}
+	f := newProcessingErrorFixture(t, sender)
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	before := testutil.ToFloat64(metrics.UnrecordedSends.WithLabelValues("sent"))
+
+	AssertNoError(t, f.processor.Handle(&queue.MessageJob{MessageID: 7, CampaignID: 1, CustomerID: 1}))
+	AssertEqual(t, len(sender.sent), 1)
+	AssertEqual(t, testutil.ToFloat64(metrics.UnrecordedSends.WithLabelValues("sent")), before+1)
+	AssertEqual(t, f.errorRepo.Calls["Create"], 0)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestWorker_MessageDeletedAfterFailedSend tests that a failed send of a deleted message is
+// acknowledged, as there is nothing left to retry
+func TestWorker_MessageDeletedAfterFailedSend(t *testing.T) {
+	f := newProcessingErrorFixture(t, &failingSender{})
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'failed'").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	before := testutil.ToFloat64(metrics.UnrecordedSends.WithLabelValues("failed"))
+
+	AssertNoError(t, f.processor.Handle(&queue.MessageJob{MessageID: 7, CampaignID: 1, CustomerID: 1}))
+	AssertEqual(t, testutil.ToFloat64(metrics.UnrecordedSends.WithLabelValues("failed")), before+1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/middleware"
+	"smsleopard/internal/models"
+	"smsleopard/internal/reqctx"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// ownershipKeys are the API keys used by the ownership tests
+var ownershipKeys = []config.APIKey{
+	{Key: "k-admin", UserID: "alice", Role: config.RoleAdmin},
+	{Key: "k-owner", UserID: "bob", Role: config.RoleMember},
+	{Key: "k-teammate", UserID: "carol", Role: config.RoleMember, Team: "growth"},
+	{Key: "k-other", UserID: "dave", Role: config.RoleMember, Team: "support"},
+}
+
+// setupOwnershipTest creates an authenticated router whose campaign 1 is owned by bob on the growth team
+func setupOwnershipTest(t *testing.T) (*mux.Router, *MockCampaignRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	owner, team := "bob", "growth"
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		campaign := NewTestCampaign()
+		campaign.CreatedBy = &owner
+		campaign.Team = &team
+		return campaign, nil
+	}
+
+	svc := service.NewCampaignService(
+		campaignRepo,
+		NewMockCustomerRepository(),
+		NewMockMessageRepository(),
+		service.NewTemplateService(),
+		NewMockPublisher(),
+		db,
+		config.ApprovalConfig{},
+	)
+	campaignHandler := handler.NewCampaignHandler(svc)
+
+	router := mux.NewRouter()
+	router.Use(middleware.Authenticate(ownershipKeys, nil))
+	router.HandleFunc("/campaigns", campaignHandler.Create).Methods("POST")
+	router.HandleFunc("/campaigns/{id}", campaignHandler.GetByID).Methods("GET")
+	router.HandleFunc("/campaigns/{id}/send", campaignHandler.Send).Methods("POST")
+	router.HandleFunc("/campaigns/{id}/re-render", campaignHandler.ReRender).Methods("POST")
+	return router, campaignRepo, mock
+}
+
+// sendAs posts a send for campaign 1 with the given API key
+func sendAs(t *testing.T, router *mux.Router, key string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := NewJSONRequest(t, "POST", "/campaigns/1/send", map[string]interface{}{"customer_ids": []int{1, 2}})
+	if key != "" {
+		req.Header.Set(middleware.APIKeyHeader, key)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+// TestOwnership_SendAllowed tests that the owner, a teammate and an admin can send
+func TestOwnership_SendAllowed(t *testing.T) {
+	for _, key := range []string{"k-owner", "k-teammate", "k-admin"} {
+		t.Run(key, func(t *testing.T) {
+			router, _, mock := setupOwnershipTest(t)
+			mock.ExpectBegin()
+			mock.ExpectCommit()
+
+			resp := sendAs(t, router, key)
+			AssertStatusCode(t, resp, http.StatusOK)
+			AssertNoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestOwnership_SendForbidden tests that other members get 403 before anything is sent
+func TestOwnership_SendForbidden(t *testing.T) {
+	router, campaignRepo, mock := setupOwnershipTest(t)
+
+	resp := sendAs(t, router, "k-other")
+	AssertStatusCode(t, resp, http.StatusForbidden)
+
+	var errResp handler.ErrorResponse
+	ParseJSONResponse(t, resp, &errResp)
+	AssertEqual(t, errResp.Error.Code, "FORBIDDEN")
+	AssertEqual(t, campaignRepo.Calls["UpdateStatusIf"], 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+
+	// Re-render is guarded the same way
+	req := NewJSONRequest(t, "POST", "/campaigns/1/re-render", map[string]interface{}{"dry_run": true})
+	req.Header.Set(middleware.APIKeyHeader, "k-other")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusForbidden)
+}
+
+// TestOwnership_UnownedCampaign tests that campaigns without an owner are admin-only
+func TestOwnership_UnownedCampaign(t *testing.T) {
+	router, campaignRepo, _ := setupOwnershipTest(t)
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaign(), nil
+	}
+
+	AssertStatusCode(t, sendAs(t, router, "k-owner"), http.StatusForbidden)
+}
+
+// TestOwnership_ReadsOpen tests that any valid key can read a campaign it does not own
+func TestOwnership_ReadsOpen(t *testing.T) {
+	router, _, _ := setupOwnershipTest(t)
+
+	req := httptest.NewRequest("GET", "/campaigns/1", nil)
+	req.Header.Set(middleware.APIKeyHeader, "k-other")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+}
+
+// TestOwnership_Unauthenticated tests that a missing or unknown key is a 401
+func TestOwnership_Unauthenticated(t *testing.T) {
+	router, campaignRepo, _ := setupOwnershipTest(t)
+
+	AssertStatusCode(t, sendAs(t, router, ""), http.StatusUnauthorized)
+	AssertStatusCode(t, sendAs(t, router, "k-unknown"), http.StatusUnauthorized)
+	AssertEqual(t, campaignRepo.Calls["GetByID"], 0)
+}
+
+// TestOwnership_CreateRecordsOwner tests that the caller and their team are stored on create
+func TestOwnership_CreateRecordsOwner(t *testing.T) {
+	router, campaignRepo, _ := setupOwnershipTest(t)
+
+	var created *models.Campaign
+	campaignRepo.CreateFunc = func(ctx context.Context, campaign *models.Campaign) error {
+		created = campaign
+		return nil
+	}
+
+	body := map[string]interface{}{"name": "Promo", "channel": "sms", "base_template": "Hi"}
+	req := NewJSONRequest(t, "POST", "/campaigns", body)
+	req.Header.Set(middleware.APIKeyHeader, "k-teammate")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusCreated)
+	AssertEqual(t, *created.CreatedBy, "carol")
+	AssertEqual(t, *created.Team, "growth")
+
+	// An explicit team wins over the caller's
+	body["team"] = "retention"
+	req = NewJSONRequest(t, "POST", "/campaigns", body)
+	req.Header.Set(middleware.APIKeyHeader, "k-teammate")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusCreated)
+	AssertEqual(t, *created.Team, "retention")
+}
+
+// TestOwnership_AuthDisabled tests that everything is allowed without configured keys
+func TestOwnership_AuthDisabled(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if _, ok := reqctx.IdentityFrom(r.Context()); ok {
+			t.Error("Expected no identity when authentication is disabled")
+		}
+	})
+
+	resp := httptest.NewRecorder()
+	middleware.Authenticate(nil, nil)(next).ServeHTTP(resp, httptest.NewRequest("GET", "/campaigns", nil))
+	AssertEqual(t, called, true)
+}
+
+// TestLoadAPIKeys tests parsing of API_KEYS
+func TestLoadAPIKeys(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+	t.Setenv("API_KEYS", "k1:alice:admin, k2:bob:member:growth")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, len(cfg.Auth.APIKeys), 2)
+	AssertEqual(t, cfg.Auth.APIKeys[1].UserID, "bob")
+	AssertEqual(t, cfg.Auth.APIKeys[1].Team, "growth")
+
+	for _, invalid := range []string{"k1:alice", "k1:alice:owner", "k1:alice:admin,k1:bob:member", ":alice:admin"} {
+		t.Setenv("API_KEYS", invalid)
+		if _, err := config.Load(); err == nil {
+			t.Errorf("Expected error for API_KEYS=%q", invalid)
+		}
+	}
+}
@@ -482,8 +482,14 @@ func TestPagination_OrderStability(t *testing.T) {
 
 	// Perform an update operation on a different campaign (not in first page)
 	// This simulates concurrent database operations
-	err = repo.UpdateStatus(ctx, page1Before[5].ID, models.CampaignStatusSent)
-	AssertNoError(t, err)
+	// Pick a campaign whose status can still move so the transition table allows the update
+	for _, campaign := range page1Before[1:] {
+		if next := models.CampaignTransitions[campaign.Status]; len(next) > 0 {
+			err = repo.UpdateStatusIf(ctx, campaign.ID, campaign.Status, next[0])
+			AssertNoError(t, err)
+			break
+		}
+	}
 
 	// Fetch first page again
 	page1After, _, err := repo.List(ctx, filters)
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestPublishReconciler_PublishesUnpublished tests that never-published messages past the grace period are published
+func TestPublishReconciler_PublishesUnpublished(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	messageRepo := NewMockMessageRepository()
+	reconciler := service.NewPublishReconciler(messageRepo)
+	reconciler.SetClock(func() time.Time { return now })
+
+	var createdBefore time.Time
+	messageRepo.ClaimUnpublishedFunc = func(ctx context.Context, before time.Time, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error) {
+		createdBefore = before
+		return []*models.OutboundMessage{
+			{ID: 1, CampaignID: 7, CustomerID: 5},
+			{ID: 2, CampaignID: 7, CustomerID: 6},
+		}, nil
+	}
+	var deferred []int
+	messageRepo.DeferUntilFunc = func(ctx context.Context, id int, until time.Time, reason string) error {
+		deferred = append(deferred, id)
+		AssertEqual(t, until, now)
+		AssertEqual(t, reason, "Publish failed: channel closed")
+		return nil
+	}
+
+	published := []int{}
+	count, err := reconciler.Reconcile(context.Background(), func(message *models.OutboundMessage) error {
+		if message.ID == 2 {
+			return errors.New("channel closed")
+		}
+		published = append(published, message.ID)
+		return nil
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, count, 1)
+	AssertEqual(t, createdBefore, now.Add(-service.UnpublishedGracePeriod))
+	AssertEqual(t, len(published), 1)
+	AssertEqual(t, published[0], 1)
+	AssertEqual(t, len(deferred), 1)
+	AssertEqual(t, deferred[0], 2)
+}
+
+// TestPublishReconciler_Paused tests that nothing is published while the reconciler is paused
+func TestPublishReconciler_Paused(t *testing.T) {
+	messageRepo := NewMockMessageRepository()
+	reconciler := service.NewPublishReconciler(messageRepo)
+	reconciler.SetPaused(func() bool { return true })
+
+	count, err := reconciler.Reconcile(context.Background(), func(message *models.OutboundMessage) error { return nil })
+	AssertNoError(t, err)
+	AssertEqual(t, count, 0)
+	AssertEqual(t, messageRepo.Calls["ClaimUnpublished"], 0)
+}
+
+// TestClaimUnpublished_SkipsQueuedAndDeferred tests that the reconciler only claims messages never published
+// and not deferred, of campaigns still sending
+func TestClaimUnpublished_SkipsQueuedAndDeferred(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	before := time.Date(2026, 3, 2, 8, 55, 0, 0, time.UTC)
+	mock.ExpectQuery(`SET published_at = CURRENT_TIMESTAMP .+ WHERE m.status = 'pending' AND m.published_at IS NULL AND m.deliver_after IS NULL AND m.created_at < \$1 AND c.status IN \('sending', 'canary'\)`).
+		WithArgs(before, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "customer_id"}).AddRow(3, 7, 9))
+
+	messages, err := repository.NewMessageRepository(db).ClaimUnpublished(context.Background(), before, 100, models.DispatchOrder{})
+	AssertNoError(t, err)
+	AssertEqual(t, len(messages), 1)
+	AssertEqual(t, messages[0].ID, 3)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCampaignStats_QueuedAndUnpublished tests that campaign stats report queued and never-published pending messages apart
+func TestCampaignStats_QueuedAndUnpublished(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`published_at IS NOT NULL\) as queued, .+ published_at IS NULL\) as unpublished`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"campaign_id", "total", "pending", "sent", "failed", "queued", "unpublished", "simulated", "p95_queue_latency", "audience", "first_message_at", "last_finished_at", "skipped_duplicate_content", "expired"}).
+			AddRow(1, 10, 6, 4, 0, 2, 3, 0, nil, 10, nil, nil, 0, 0))
+
+	stats, err := repository.NewCampaignRepository(db).GetStatsByIDs(context.Background(), []int{1})
+	AssertNoError(t, err)
+
+	AssertEqual(t, stats[1].Pending, 6)
+	AssertEqual(t, stats[1].Queued, 2)
+	AssertEqual(t, stats[1].Unpublished, 3)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestAdmin_PendingBacklog tests that the admin pending endpoint totals each population across campaigns
+func TestAdmin_PendingBacklog(t *testing.T) {
+	older := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	messageRepo := NewMockMessageRepository()
+	messageRepo.GetPendingBacklogFunc = func(ctx context.Context) ([]*models.CampaignPendingBacklog, error) {
+		return []*models.CampaignPendingBacklog{
+			{CampaignID: 1, Queued: 40, Unpublished: 5, OldestUnpublishedAt: &newer},
+			{CampaignID: 2, Queued: 0, Unpublished: 2, Deferred: 3, OldestUnpublishedAt: &older},
+			{CampaignID: 3, Queued: 10},
+		}, nil
+	}
+
+	h := handler.NewStatsHandler(service.NewStatsService(messageRepo))
+	req := httptest.NewRequest("GET", "/admin/messages/pending", nil)
+	rr := httptest.NewRecorder()
+	h.PendingBacklog(rr, req)
+
+	AssertStatusCode(t, rr, http.StatusOK)
+
+	var report service.PendingBacklogReport
+	ParseJSONResponse(t, rr, &report)
+	AssertEqual(t, report.Queued, 50)
+	AssertEqual(t, report.Unpublished, 7)
+	AssertEqual(t, report.Deferred, 3)
+	AssertEqual(t, report.OldestUnpublishedAt.Equal(older), true)
+	AssertEqual(t, len(report.Campaigns), 3)
+}
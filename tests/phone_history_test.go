@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// expectCustomerUpdate expects an update of customer 5 from oldPhone to newPhone and the
+// conditional insert of the change
+func expectCustomerUpdate(mock sqlmock.Sqlmock, oldPhone, newPhone string, changedBy interface{}) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT phone FROM customers WHERE id = \$1 FOR UPDATE`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"phone"}).AddRow(oldPhone))
+	mock.ExpectExec(`UPDATE customers`).
+		WithArgs(newPhone, nil, nil, nil, nil, nil, nil, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO customer_phone_history .* WHERE normalize_phone\(\$2\) IS DISTINCT FROM normalize_phone\(\$3\)`).
+		WithArgs(5, oldPhone, newPhone, changedBy).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+}
+
+// TestCustomerRepository_UpdateLogsPhoneChanges tests that two phone changes are each logged
+// with the old and new number and who changed it, in the update's transaction
+func TestCustomerRepository_UpdateLogsPhoneChanges(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewCustomerRepository(db)
+	actor := "amina"
+
+	expectCustomerUpdate(mock, "+254700000001", "+254711000002", "amina")
+	AssertNoError(t, repo.Update(context.Background(), &models.Customer{ID: 5, Phone: "+254711000002"}, &actor))
+
+	expectCustomerUpdate(mock, "+254711000002", "+254722000003", nil)
+	AssertNoError(t, repo.Update(context.Background(), &models.Customer{ID: 5, Phone: "+254722000003"}, nil))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT phone FROM customers WHERE id = \$1 FOR UPDATE`).
+		WithArgs(6).
+		WillReturnRows(sqlmock.NewRows([]string{"phone"}))
+	mock.ExpectRollback()
+	err := repo.Update(context.Background(), &models.Customer{ID: 6, Phone: "+254722000003"}, nil)
+	AssertEqual(t, err, repository.ErrCustomerNotFound)
+
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCustomerPhoneHistoryEndpoint tests that a customer's earlier numbers are returned with
+// the customer, newest change first
+func TestCustomerPhoneHistoryEndpoint(t *testing.T) {
+	customerRepo := NewMockCustomerRepository()
+	customer := NewTestCustomerWithID(5)
+	customer.Phone = "+254722000003"
+	customerRepo.GetDetailFunc = func(ctx context.Context, id int) (*models.Customer, error) {
+		if id != 5 {
+			return nil, repository.ErrCustomerNotFound
+		}
+		return customer, nil
+	}
+	amina := "amina"
+	changedAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	customerRepo.ListPhoneHistoryFunc = func(ctx context.Context, id int) ([]*models.CustomerPhoneChange, error) {
+		return []*models.CustomerPhoneChange{
+			{ID: 2, CustomerID: id, OldPhone: "+254711000002", NewPhone: "+254722000003", ChangedAt: changedAt.AddDate(0, 1, 0)},
+			{ID: 1, CustomerID: id, OldPhone: "+254700000001", NewPhone: "+254711000002", ChangedBy: &amina, ChangedAt: changedAt},
+		}, nil
+	}
+
+	h := handler.NewCustomerHandler(service.NewCustomerService(customerRepo, config.LimitsConfig{}))
+	router := mux.NewRouter()
+	router.HandleFunc("/customers/{id}", h.Get).Methods("GET")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/customers/5", nil))
+	AssertStatusCode(t, rr, http.StatusOK)
+	AssertContains(t, rr.Body.String(), `"phone_history":[{"id":2`)
+
+	var detail service.CustomerDetail
+	ParseJSONResponse(t, rr, &detail)
+	AssertEqual(t, detail.Phone, "+254722000003")
+	AssertEqual(t, len(detail.PhoneHistory), 2)
+	AssertEqual(t, detail.PhoneHistory[0].OldPhone, "+254711000002")
+	AssertEqual(t, detail.PhoneHistory[0].ChangedBy == nil, true)
+	AssertEqual(t, detail.PhoneHistory[1].OldPhone, "+254700000001")
+	AssertEqual(t, *detail.PhoneHistory[1].ChangedBy, "amina")
+}
@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// mixedFormatCustomers are customers stored before phones were normalized on write
+// Customer 4 alone is in normalized form and is not suppressed
+func mixedFormatCustomers() []*models.Customer {
+	phones := map[int]string{
+		1: "0712345678",
+		2: "+254 733 000 111",
+		3: "254722000333",
+		4: "+254700000004",
+	}
+	customers := []*models.Customer{}
+	for id := 1; id <= 4; id++ {
+		customer := NewTestCustomerWithID(id)
+		customer.Phone = phones[id]
+		customers = append(customers, customer)
+	}
+	return customers
+}
+
+// mixedFormatSuppressions are the same numbers as customers 1-3, stored in other formats
+var mixedFormatSuppressions = []string{"+254712345678", "0733-000-111", "00254722000333"}
+
+// TestPhoneKey tests that every format of a number compares equal and invalid phones compare as given
+func TestPhoneKey(t *testing.T) {
+	for _, phone := range []string{"0712345678", "0712 345 678", "+254712345678", "254712345678", "00254712345678", "(0712) 345-678"} {
+		AssertEqual(t, models.PhoneKey(phone), "+254712345678")
+	}
+	AssertEqual(t, models.PhoneKey("not a phone"), "not a phone")
+}
+
+// TestSuppression_MixedFormats tests that customers and suppressions stored in different
+// formats of the same number match when sending
+func TestSuppression_MixedFormats(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	customerRepo := NewMockCustomerRepository()
+	customerRepo.GetByIDsFunc = func(ctx context.Context, ids []int) ([]*models.Customer, error) {
+		return mixedFormatCustomers(), nil
+	}
+	messageRepo := NewMockMessageRepository()
+	var queued []*models.OutboundMessage
+	messageRepo.CreateBatchFunc = func(ctx context.Context, messages []*models.OutboundMessage) error {
+		queued = messages
+		return nil
+	}
+	svc := service.NewCampaignService(NewMockCampaignRepository(), customerRepo, messageRepo, service.NewTemplateService(), NewMockPublisher(), db, config.ApprovalConfig{})
+	suppressions := NewMockSuppressionRepository()
+	suppressions.Phones[1] = mixedFormatSuppressions
+	svc.SetSuppressions(suppressions)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2, 3, 4}, service.SendOptions{})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.SkippedSuppressed, 3)
+	AssertEqual(t, result.MessagesQueued, 1)
+	AssertEqual(t, queued[0].CustomerID, 4)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSuppression_WorkerMixedFormats tests that the worker rejects a customer stored in local
+// format whose number is suppressed in normalized form
+func TestSuppression_WorkerMixedFormats(t *testing.T) {
+	sender := &countingSender{}
+	f := newProcessingErrorFixture(t, sender)
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		message := NewTestMessageWithStatus(models.MessageStatusPending)
+		message.ID = id
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *message,
+			Campaign:        *NewTestCampaignWithStatus(models.CampaignStatusSending),
+			Customer:        *mixedFormatCustomers()[0],
+		}, nil
+	}
+	suppressions := NewMockSuppressionRepository()
+	suppressions.Phones[1] = mixedFormatSuppressions
+	f.processor.SetSuppressions(suppressions)
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'failed'").
+		WithArgs(sqlmock.AnyArg(), "Phone suppressed for this campaign").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	AssertNoError(t, f.processor.Handle(&queue.MessageJob{MessageID: 9}))
+	AssertEqual(t, sender.calls, 0)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestCreateCustomer_NormalizesPhone tests that customers are stored with normalized phones
+func TestCreateCustomer_NormalizesPhone(t *testing.T) {
+	customerRepo := NewMockCustomerRepository()
+	svc := service.NewCustomerService(customerRepo, config.LimitsConfig{})
+
+	customer := &models.Customer{Phone: "0712 345 678"}
+	_, err := svc.CreateCustomer(context.Background(), customer)
+	AssertNoError(t, err)
+	AssertEqual(t, customer.Phone, "+254712345678")
+
+	_, err = svc.CreateCustomer(context.Background(), &models.Customer{Phone: "12ab"})
+	AssertError(t, err, `validation error: invalid phone number "12ab"`)
+	AssertEqual(t, customerRepo.Calls["Create"], 1)
+}
+
+// storedPhone builds a stored phone created the given number of days ago
+func storedPhone(customerID, campaignID int, phone string, daysAgo int) *repository.StoredPhone {
+	return &repository.StoredPhone{
+		CustomerID: customerID,
+		CampaignID: campaignID,
+		Phone:      phone,
+		CreatedAt:  time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC).AddDate(0, 0, -daysAgo),
+	}
+}
+
+// TestNormalizeCustomers tests rewrites, keeping the oldest of colliding customers and
+// reporting the rest and invalid phones, with and without a dry run
+func TestNormalizeCustomers(t *testing.T) {
+	repo := NewMockPhoneNormalizationRepository()
+	repo.Customers = []*repository.StoredPhone{
+		storedPhone(3, 0, "0712345678", 30),
+		storedPhone(1, 0, "0733 000 111", 20),
+		storedPhone(7, 0, "+254712345678", 10),
+		storedPhone(8, 0, "+254700000008", 5),
+		storedPhone(9, 0, "n/a", 1),
+	}
+	normalizer := service.NewPhoneNormalizer(repo)
+
+	report, err := normalizer.NormalizeCustomers(context.Background(), true)
+	AssertNoError(t, err)
+	AssertEqual(t, report.Scanned, 5)
+	AssertEqual(t, report.Rewritten, 1)
+	AssertEqual(t, len(report.Invalid), 1)
+	AssertEqual(t, report.Invalid[0], `customer 9 ("n/a"): invalid phone number "n/a"`)
+	AssertEqual(t, len(report.Collisions), 1)
+	AssertEqual(t, report.Collisions[0].Phone, "+254712345678")
+	AssertEqual(t, report.Collisions[0].Kept.CustomerID, 3)
+	AssertEqual(t, report.Collisions[0].Others[0].CustomerID, 7)
+	AssertEqual(t, repo.Calls["RewriteCustomerPhones"], 0)
+	AssertEqual(t, repo.Customers[1].Phone, "0733 000 111")
+
+	report, err = normalizer.NormalizeCustomers(context.Background(), false)
+	AssertNoError(t, err)
+	AssertEqual(t, report.Rewritten, 1)
+	AssertEqual(t, repo.Customers[1].Phone, "+254733000111")
+	// The newer customer already holds the number, so both stay as stored for a manual merge
+	AssertEqual(t, repo.Customers[0].Phone, "0712345678")
+	AssertEqual(t, repo.Customers[2].Phone, "+254712345678")
+	AssertEqual(t, repo.Customers[4].Phone, "n/a")
+
+	// Nothing left to do on a rerun
+	report, err = normalizer.NormalizeCustomers(context.Background(), false)
+	AssertNoError(t, err)
+	AssertEqual(t, report.Rewritten, 0)
+	AssertEqual(t, repo.Calls["RewriteCustomerPhones"], 1)
+}
+
+// TestNormalizeSuppressions tests that colliding suppressions of a campaign keep the oldest,
+// normalized, and remove the rest, while other campaigns' lists are separate
+func TestNormalizeSuppressions(t *testing.T) {
+	repo := NewMockPhoneNormalizationRepository()
+	repo.Suppressions = []*repository.StoredPhone{
+		storedPhone(0, 1, "0712345678", 30),
+		storedPhone(0, 1, "+254712345678", 10),
+		storedPhone(0, 1, "254712345678", 2),
+		storedPhone(0, 2, "0712 345 678", 5),
+	}
+	normalizer := service.NewPhoneNormalizer(repo)
+
+	report, err := normalizer.NormalizeSuppressions(context.Background(), false)
+	AssertNoError(t, err)
+	AssertEqual(t, report.Rewritten, 2)
+	AssertEqual(t, report.Removed, 2)
+	AssertEqual(t, len(report.Collisions), 1)
+	AssertEqual(t, report.Collisions[0].Kept.CreatedAt, repo.Suppressions[0].CreatedAt)
+	AssertEqual(t, len(report.Collisions[0].Others), 2)
+
+	AssertEqual(t, len(repo.Suppressions), 2)
+	AssertEqual(t, repo.Suppressions[0].CampaignID, 1)
+	AssertEqual(t, repo.Suppressions[0].Phone, "+254712345678")
+	AssertEqual(t, repo.Suppressions[1].CampaignID, 2)
+	AssertEqual(t, repo.Suppressions[1].Phone, "+254712345678")
+}
+
+// TestPhoneNormalizationRepository_Queries tests the list and rewrite queries
+func TestPhoneNormalizationRepository_Queries(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	repo := repository.NewPhoneNormalizationRepository(db)
+	ctx := context.Background()
+	created := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT id, phone, created_at FROM customers ORDER BY created_at, id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "phone", "created_at"}).AddRow(3, "0712345678", created))
+	customers, err := repo.ListCustomerPhones(ctx)
+	AssertNoError(t, err)
+	AssertEqual(t, customers[0].CustomerID, 3)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE customers SET phone = \$3 WHERE id = \$1 AND phone = \$2`).
+		WithArgs(3, "0712345678", "+254712345678").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	rewritten, err := repo.RewriteCustomerPhones(ctx, []*repository.PhoneRewrite{{Row: customers[0], To: "+254712345678"}})
+	AssertNoError(t, err)
+	AssertEqual(t, rewritten, 1)
+
+	// Duplicates are removed before the kept phone takes the normalized form
+	kept := &repository.StoredPhone{CampaignID: 1, Phone: "0712345678"}
+	duplicate := &repository.StoredPhone{CampaignID: 1, Phone: "+254712345678"}
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM campaign_suppressions WHERE campaign_id = \$1 AND phone = \$2`).
+		WithArgs(1, "+254712345678").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE campaign_suppressions SET phone = \$3 WHERE campaign_id = \$1 AND phone = \$2`).
+		WithArgs(1, "0712345678", "+254712345678").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	rewritten, removed, err := repo.RewriteSuppressionPhones(ctx, []*repository.PhoneRewrite{{Row: kept, To: "+254712345678"}}, []*repository.StoredPhone{duplicate})
+	AssertNoError(t, err)
+	AssertEqual(t, rewritten, 1)
+	AssertEqual(t, removed, 1)
+
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// setupCoverageTest creates a campaign service whose campaign uses the given template
+// and whose audience has the given number of customers missing each field
+func setupCoverageTest(t *testing.T, template string, total int, missing map[string]int) (*service.CampaignService, *MockCampaignRepository, *MockCustomerRepository) {
+	t.Helper()
+
+	db, _ := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		campaign := NewTestCampaign()
+		campaign.BaseTemplate = template
+		return campaign, nil
+	}
+
+	customerRepo := NewMockCustomerRepository()
+	customerRepo.CountMissingFieldsFunc = func(ctx context.Context, ids []int, fields []string) (*models.FieldCompleteness, error) {
+		result := &models.FieldCompleteness{Total: total, Missing: map[string]int{}}
+		for _, field := range fields {
+			result.Missing[field] = missing[field]
+		}
+		return result, nil
+	}
+
+	svc := service.NewCampaignService(
+		campaignRepo,
+		customerRepo,
+		NewMockMessageRepository(),
+		service.NewTemplateService(),
+		NewMockPublisher(),
+		db,
+		config.ApprovalConfig{},
+	)
+	return svc, campaignRepo, customerRepo
+}
+
+// TestPlaceholderCoverage_PartialAudience tests percentages and warnings for a partly complete audience
+func TestPlaceholderCoverage_PartialAudience(t *testing.T) {
+	svc, _, customerRepo := setupCoverageTest(t,
+		"Hi {first_name}, {preferred_product} is back in {location}. Bye {first_name}",
+		100, map[string]int{"first_name": 5, "preferred_product": 23, "location": 0})
+
+	result, err := svc.PlaceholderCoverage(context.Background(), 1, &service.PlaceholderCoverageRequest{CustomerIDs: []int{1, 2, 3}})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.AudienceSize, 100)
+	AssertEqual(t, result.WarnBelowPercent, service.DefaultCoverageWarnBelow)
+	AssertEqual(t, customerRepo.Calls["CountMissingFields"], 1)
+
+	// Repeated placeholders are checked once, in order of first use
+	AssertEqual(t, len(result.Placeholders), 3)
+	AssertEqual(t, result.Placeholders[0].Placeholder, "{first_name}")
+	AssertEqual(t, result.Placeholders[0].CoveragePercent, 95.0)
+	AssertEqual(t, result.Placeholders[1].Placeholder, "{preferred_product}")
+	AssertEqual(t, result.Placeholders[1].Missing, 23)
+	AssertEqual(t, result.Placeholders[1].CoveragePercent, 77.0)
+	AssertEqual(t, result.Placeholders[2].CoveragePercent, 100.0)
+
+	AssertEqual(t, len(result.Warnings), 1)
+	AssertEqual(t, result.Warnings[0], "{preferred_product} is empty for 23.0% of the audience (23 of 100 customers)")
+}
+
+// TestPlaceholderCoverage_CompleteAudience tests that a fully populated audience has no warnings
+func TestPlaceholderCoverage_CompleteAudience(t *testing.T) {
+	svc, _, _ := setupCoverageTest(t, "Hi {first_name} {last_name}", 40, map[string]int{})
+
+	result, err := svc.PlaceholderCoverage(context.Background(), 1, &service.PlaceholderCoverageRequest{CustomerIDs: []int{1}})
+	AssertNoError(t, err)
+
+	for _, coverage := range result.Placeholders {
+		AssertEqual(t, coverage.CoveragePercent, 100.0)
+	}
+	AssertEqual(t, len(result.Warnings), 0)
+}
+
+// TestPlaceholderCoverage_EmptyFieldEverywhere tests an audience where nobody has the field
+func TestPlaceholderCoverage_EmptyFieldEverywhere(t *testing.T) {
+	svc, _, _ := setupCoverageTest(t, "Hi from {location}", 3, map[string]int{"location": 3})
+
+	result, err := svc.PlaceholderCoverage(context.Background(), 1, &service.PlaceholderCoverageRequest{CustomerIDs: []int{1, 2, 3}})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Placeholders[0].CoveragePercent, 0.0)
+	AssertEqual(t, result.Warnings[0], "{location} is empty for 100.0% of the audience (3 of 3 customers)")
+}
+
+// TestPlaceholderCoverage_CustomThreshold tests the per-request warning threshold
+func TestPlaceholderCoverage_CustomThreshold(t *testing.T) {
+	svc, _, _ := setupCoverageTest(t, "Hi {first_name}", 3, map[string]int{"first_name": 1})
+
+	// 66.7% coverage passes a 50% threshold
+	threshold := 50.0
+	result, err := svc.PlaceholderCoverage(context.Background(), 1, &service.PlaceholderCoverageRequest{
+		CustomerIDs:      []int{1, 2, 3},
+		WarnBelowPercent: &threshold,
+	})
+	AssertNoError(t, err)
+	AssertEqual(t, result.Placeholders[0].CoveragePercent, 66.7)
+	AssertEqual(t, len(result.Warnings), 0)
+
+	invalid := 150.0
+	_, err = svc.PlaceholderCoverage(context.Background(), 1, &service.PlaceholderCoverageRequest{
+		CustomerIDs:      []int{1},
+		WarnBelowPercent: &invalid,
+	})
+	if _, ok := err.(*service.ValidationError); !ok {
+		t.Fatalf("Expected ValidationError but got %v", err)
+	}
+}
+
+// TestPlaceholderCoverage_UnknownPlaceholder tests that non-customer placeholders are warned about, not queried
+func TestPlaceholderCoverage_UnknownPlaceholder(t *testing.T) {
+	svc, _, customerRepo := setupCoverageTest(t, "Use code {promo_code}, {first_name}", 10, map[string]int{})
+
+	var queried []string
+	customerRepo.CountMissingFieldsFunc = func(ctx context.Context, ids []int, fields []string) (*models.FieldCompleteness, error) {
+		queried = fields
+		return &models.FieldCompleteness{Total: 10, Missing: map[string]int{"first_name": 0}}, nil
+	}
+
+	result, err := svc.PlaceholderCoverage(context.Background(), 1, &service.PlaceholderCoverageRequest{CustomerIDs: []int{1}})
+	AssertNoError(t, err)
+
+	AssertEqual(t, len(queried), 1)
+	AssertEqual(t, queried[0], "first_name")
+	AssertEqual(t, len(result.Placeholders), 1)
+	AssertEqual(t, result.Warnings[0], "{promo_code} is not a customer field and will be sent as written")
+}
+
+// TestPlaceholderCoverage_Validation tests empty and unresolvable audiences
+func TestPlaceholderCoverage_Validation(t *testing.T) {
+	svc, campaignRepo, customerRepo := setupCoverageTest(t, "Hi {first_name}", 0, map[string]int{})
+
+	_, err := svc.PlaceholderCoverage(context.Background(), 1, &service.PlaceholderCoverageRequest{})
+	if _, ok := err.(*service.ValidationError); !ok {
+		t.Fatalf("Expected ValidationError but got %v", err)
+	}
+	AssertEqual(t, campaignRepo.Calls["GetByID"], 0)
+
+	// None of the IDs exist
+	_, err = svc.PlaceholderCoverage(context.Background(), 1, &service.PlaceholderCoverageRequest{CustomerIDs: []int{999}})
+	if _, ok := err.(*service.ValidationError); !ok {
+		t.Fatalf("Expected ValidationError but got %v", err)
+	}
+	AssertEqual(t, customerRepo.Calls["CountMissingFields"], 1)
+}
+
+// TestCountMissingFields_Query tests the single aggregate query with whitelisted columns
+func TestCountMissingFields_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\), ` +
+		`COUNT\(\*\) FILTER \(WHERE first_name IS NULL OR first_name = ''\), ` +
+		`COUNT\(\*\) FILTER \(WHERE preferred_product IS NULL OR preferred_product = ''\) ` +
+		`FROM customers WHERE id = ANY`).
+		WithArgs("{1,2,3}").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "first_name", "preferred_product"}).AddRow(3, 0, 2))
+
+	customerRepo := repository.NewCustomerRepository(db)
+	result, err := customerRepo.CountMissingFields(context.Background(), []int{1, 2, 3}, []string{"first_name", "preferred_product"})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Total, 3)
+	AssertEqual(t, result.Missing["first_name"], 0)
+	AssertEqual(t, result.Missing["preferred_product"], 2)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCountMissingFields_RejectsUnknownColumn tests that only whitelisted columns reach SQL
+func TestCountMissingFields_RejectsUnknownColumn(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	customerRepo := repository.NewCustomerRepository(db)
+	_, err := customerRepo.CountMissingFields(context.Background(), []int{1}, []string{"first_name", "id; DROP TABLE customers"})
+	AssertError(t, err, "unsupported customer field: id; DROP TABLE customers")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPlaceholderCoverageEndpoint tests POST /campaigns/{id}/placeholder-coverage end to end with mocks
+func TestPlaceholderCoverageEndpoint(t *testing.T) {
+	svc, campaignRepo, _ := setupCoverageTest(t, "Hi {first_name}", 4, map[string]int{"first_name": 1})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/placeholder-coverage", handler.NewCampaignHandler(svc).PlaceholderCoverage).Methods("POST")
+
+	req := NewJSONRequest(t, "POST", "/campaigns/1/placeholder-coverage", map[string]interface{}{"customer_ids": []int{1, 2, 3, 4}})
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertJSONContentType(t, resp)
+
+	var result service.PlaceholderCoverageResult
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, result.Placeholders[0].CoveragePercent, 75.0)
+	AssertEqual(t, len(result.Warnings), 1)
+
+	// Missing audience
+	req = NewJSONRequest(t, "POST", "/campaigns/1/placeholder-coverage", map[string]interface{}{})
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+
+	// Unknown campaign
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return nil, fmt.Errorf("campaign not found")
+	}
+	req = NewJSONRequest(t, "POST", "/campaigns/99/placeholder-coverage", map[string]interface{}{"customer_ids": []int{1}})
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusNotFound)
+}
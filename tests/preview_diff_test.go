@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// setupPreviewDiffTest creates a campaign service whose campaign uses the given template
+func setupPreviewDiffTest(template string) *service.CampaignService {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithTemplate(template), nil
+	}
+
+	return service.NewCampaignService(
+		campaignRepo,
+		NewMockCustomerRepository(),
+		NewMockMessageRepository(),
+		service.NewTemplateService(),
+		NewMockPublisher(),
+		nil,
+		config.ApprovalConfig{},
+	)
+}
+
+// TestPreviewDiff_PlaceholderChanges tests templates that add and remove placeholders
+func TestPreviewDiff_PlaceholderChanges(t *testing.T) {
+	svc := setupPreviewDiffTest("Hi {first_name} from {location}")
+
+	result, err := svc.PreviewDiff(context.Background(), &service.PreviewDiffRequest{
+		CampaignID:  1,
+		CustomerID:  1,
+		NewTemplate: "Hi {first_name}, try {preferred_product}",
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.CurrentRender, "Hi John from Nairobi")
+	AssertEqual(t, result.NewRender, "Hi John, try Premium Plan")
+	AssertEqual(t, result.Changed, true)
+	AssertEqual(t, len(result.PlaceholderChanges), 2)
+	AssertEqual(t, result.PlaceholderChanges[0], service.PlaceholderChange{Placeholder: "{preferred_product}", Change: service.PlaceholderAdded})
+	AssertEqual(t, result.PlaceholderChanges[1], service.PlaceholderChange{Placeholder: "{location}", Change: service.PlaceholderRemoved})
+}
+
+// TestPreviewDiff_Unchanged tests that identical renders are reported as unchanged
+func TestPreviewDiff_Unchanged(t *testing.T) {
+	svc := setupPreviewDiffTest("Hi {first_name}")
+
+	result, err := svc.PreviewDiff(context.Background(), &service.PreviewDiffRequest{
+		CampaignID:  1,
+		CustomerID:  1,
+		NewTemplate: "Hi {first_name}",
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Changed, false)
+	AssertEqual(t, len(result.PlaceholderChanges), 0)
+}
+
+// TestPreviewDiff_RepeatedPlaceholders tests that placeholders used more than once are reported once
+func TestPreviewDiff_RepeatedPlaceholders(t *testing.T) {
+	svc := setupPreviewDiffTest("Hello")
+
+	result, err := svc.PreviewDiff(context.Background(), &service.PreviewDiffRequest{
+		CampaignID:  1,
+		CustomerID:  1,
+		NewTemplate: "{first_name}! {first_name}!",
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, len(result.PlaceholderChanges), 1)
+	AssertEqual(t, result.NewRender, "John! John!")
+}
+
+// TestPreviewDiffEndpoint tests POST /campaigns/{id}/preview-diff validation and success
+func TestPreviewDiffEndpoint(t *testing.T) {
+	svc := setupPreviewDiffTest("Hi {first_name}")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/preview-diff", handler.NewPreviewHandler(svc).PreviewDiff).Methods("POST")
+
+	// Success
+	req := NewJSONRequest(t, "POST", "/campaigns/1/preview-diff", map[string]interface{}{
+		"customer_id":  1,
+		"new_template": "Hey {first_name}",
+	})
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var result service.PreviewDiffResult
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, result.NewRender, "Hey John")
+
+	// Missing new template
+	req = NewJSONRequest(t, "POST", "/campaigns/1/preview-diff", map[string]interface{}{"customer_id": 1})
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+
+	// Missing customer
+	req = NewJSONRequest(t, "POST", "/campaigns/1/preview-diff", map[string]interface{}{"new_template": "Hey"})
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+}
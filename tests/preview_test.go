@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"smsleopard/internal/config"
 	"smsleopard/internal/handler"
 	"smsleopard/internal/models"
 	"smsleopard/internal/repository"
@@ -32,8 +33,9 @@ func setupPreviewTestHandler(t *testing.T, db *sql.DB) *handler.PreviewHandler {
 		customerRepo,
 		messageRepo,
 		templateSvc,
-		nil, // No queue publisher needed for preview
+		NewMockPublisher(),
 		db,
+		config.ApprovalConfig{},
 	)
 
 	return handler.NewPreviewHandler(campaignSvc)
@@ -58,7 +60,7 @@ func TestPreviewEndpoint_Success(t *testing.T) {
 
 	// Mock campaign query
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team", "budget", "spend", "paused_reason", "frequency_cap_exempt", "track_links", "template_syntax", "cancel_at", "demo_failure_rate", "ordered", "part_delimiter",
 	}).AddRow(
 		campaign.ID,
 		campaign.Name,
@@ -68,6 +70,7 @@ func TestPreviewEndpoint_Success(t *testing.T) {
 		campaign.ScheduledAt,
 		campaign.CreatedAt,
 		campaign.UpdatedAt,
+		"{}", nil, nil, nil, 0, nil, false, false, nil, nil, nil, false, nil,
 	)
 	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE id").
 		WithArgs(campaign.ID).
@@ -75,7 +78,7 @@ func TestPreviewEndpoint_Success(t *testing.T) {
 
 	// Mock customer query
 	customerRows := sqlmock.NewRows([]string{
-		"id", "phone", "first_name", "last_name", "location", "preferred_product", "created_at",
+		"id", "phone", "first_name", "last_name", "location", "preferred_product", "contact_window_start", "contact_window_end", "created_at",
 	}).AddRow(
 		customer.ID,
 		customer.Phone,
@@ -83,6 +86,8 @@ func TestPreviewEndpoint_Success(t *testing.T) {
 		customer.LastName,
 		customer.Location,
 		customer.PreferredProduct,
+		customer.ContactWindowStart,
+		customer.ContactWindowEnd,
 		customer.CreatedAt,
 	)
 	mock.ExpectQuery("SELECT (.+) FROM customers WHERE id").
@@ -116,6 +121,7 @@ func TestPreviewEndpoint_Success(t *testing.T) {
 	renderedMsg := result["rendered_message"].(string)
 	AssertContains(t, renderedMsg, "John")         // first_name
 	AssertContains(t, renderedMsg, "Premium Plan") // preferred_product
+	AssertEqual(t, result["customer_source"], service.PreviewCustomerStored)
 
 	// Verify expectations met
 	AssertNoError(t, mock.ExpectationsWereMet())
@@ -166,7 +172,7 @@ func TestPreviewEndpoint_DifferentCustomers(t *testing.T) {
 
 			// Mock campaign query
 			campaignRows := sqlmock.NewRows([]string{
-				"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+				"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team", "budget", "spend", "paused_reason", "frequency_cap_exempt", "track_links", "template_syntax", "cancel_at", "demo_failure_rate", "ordered", "part_delimiter",
 			}).AddRow(
 				campaign.ID,
 				campaign.Name,
@@ -176,6 +182,7 @@ func TestPreviewEndpoint_DifferentCustomers(t *testing.T) {
 				campaign.ScheduledAt,
 				campaign.CreatedAt,
 				campaign.UpdatedAt,
+				"{}", nil, nil, nil, 0, nil, false, false, nil, nil, nil, false, nil,
 			)
 			mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE id").
 				WithArgs(campaign.ID).
@@ -183,7 +190,7 @@ func TestPreviewEndpoint_DifferentCustomers(t *testing.T) {
 
 			// Mock customer query
 			customerRows := sqlmock.NewRows([]string{
-				"id", "phone", "first_name", "last_name", "location", "preferred_product", "created_at",
+				"id", "phone", "first_name", "last_name", "location", "preferred_product", "contact_window_start", "contact_window_end", "created_at",
 			}).AddRow(
 				tc.customer.ID,
 				tc.customer.Phone,
@@ -191,6 +198,8 @@ func TestPreviewEndpoint_DifferentCustomers(t *testing.T) {
 				tc.customer.LastName,
 				tc.customer.Location,
 				tc.customer.PreferredProduct,
+				tc.customer.ContactWindowStart,
+				tc.customer.ContactWindowEnd,
 				tc.customer.CreatedAt,
 			)
 			mock.ExpectQuery("SELECT (.+) FROM customers WHERE id").
@@ -238,7 +247,7 @@ func TestPreviewEndpoint_OverrideTemplate(t *testing.T) {
 
 	// Mock campaign query
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team", "budget", "spend", "paused_reason", "frequency_cap_exempt", "track_links", "template_syntax", "cancel_at", "demo_failure_rate", "ordered", "part_delimiter",
 	}).AddRow(
 		campaign.ID,
 		campaign.Name,
@@ -248,6 +257,7 @@ func TestPreviewEndpoint_OverrideTemplate(t *testing.T) {
 		campaign.ScheduledAt,
 		campaign.CreatedAt,
 		campaign.UpdatedAt,
+		"{}", nil, nil, nil, 0, nil, false, false, nil, nil, nil, false, nil,
 	)
 	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE id").
 		WithArgs(campaign.ID).
@@ -255,7 +265,7 @@ func TestPreviewEndpoint_OverrideTemplate(t *testing.T) {
 
 	// Mock customer query
 	customerRows := sqlmock.NewRows([]string{
-		"id", "phone", "first_name", "last_name", "location", "preferred_product", "created_at",
+		"id", "phone", "first_name", "last_name", "location", "preferred_product", "contact_window_start", "contact_window_end", "created_at",
 	}).AddRow(
 		customer.ID,
 		customer.Phone,
@@ -263,6 +273,8 @@ func TestPreviewEndpoint_OverrideTemplate(t *testing.T) {
 		customer.LastName,
 		customer.Location,
 		customer.PreferredProduct,
+		customer.ContactWindowStart,
+		customer.ContactWindowEnd,
 		customer.CreatedAt,
 	)
 	mock.ExpectQuery("SELECT (.+) FROM customers WHERE id").
@@ -314,7 +326,7 @@ func TestPreviewEndpoint_MissingCustomer(t *testing.T) {
 
 	// Mock campaign query (campaign exists)
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team", "budget", "spend", "paused_reason", "frequency_cap_exempt", "track_links", "template_syntax", "cancel_at", "demo_failure_rate", "ordered", "part_delimiter",
 	}).AddRow(
 		campaign.ID,
 		campaign.Name,
@@ -324,6 +336,7 @@ func TestPreviewEndpoint_MissingCustomer(t *testing.T) {
 		campaign.ScheduledAt,
 		campaign.CreatedAt,
 		campaign.UpdatedAt,
+		"{}", nil, nil, nil, 0, nil, false, false, nil, nil, nil, false, nil,
 	)
 	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE id").
 		WithArgs(campaign.ID).
@@ -466,6 +479,7 @@ func TestPreviewEndpoint_InvalidCampaignID(t *testing.T) {
 			AssertNotNil(t, errorResp["error"])
 			errorDetail := errorResp["error"].(map[string]interface{})
 			AssertEqual(t, errorDetail["code"], "VALIDATION_ERROR")
+			AssertEqual(t, errorDetail["message"], "id must be a positive integer")
 		})
 	}
 }
@@ -556,6 +570,109 @@ func TestPreviewEndpoint_InvalidCustomerID(t *testing.T) {
 	}
 }
 
+// expectPreviewCampaign expects the campaign query of a preview
+func expectPreviewCampaign(mock sqlmock.Sqlmock, campaign *models.Campaign) {
+	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE id").
+		WithArgs(campaign.ID).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team", "budget", "spend", "paused_reason", "frequency_cap_exempt", "track_links", "template_syntax", "cancel_at", "demo_failure_rate", "ordered", "part_delimiter",
+		}).AddRow(
+			campaign.ID, campaign.Name, campaign.Channel, campaign.Status, campaign.BaseTemplate,
+			campaign.ScheduledAt, campaign.CreatedAt, campaign.UpdatedAt,
+			"{}", nil, nil, nil, 0, nil, false, false, nil, nil, nil, false, nil,
+		))
+}
+
+// TestPreviewEndpoint_InlineCustomer tests rendering for a customer given inline, which is
+// never looked up or stored
+func TestPreviewEndpoint_InlineCustomer(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaign := NewTestCampaignWithTemplate("Hi {first_name}, {preferred_product} is on offer")
+	expectPreviewCampaign(mock, campaign)
+
+	router := setupPreviewTestRouter(setupPreviewTestHandler(t, db))
+	requestBody := map[string]interface{}{
+		"customer": map[string]interface{}{
+			"phone":      "0712345678",
+			"first_name": "Ngʼangʼa",
+		},
+	}
+	req := NewJSONRequest(t, "POST", fmt.Sprintf("/campaigns/%d/personalized-preview", campaign.ID), requestBody)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	var result map[string]interface{}
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, result["rendered_message"], "Hi Ngʼangʼa,  is on offer")
+	AssertEqual(t, result["customer_source"], service.PreviewCustomerInline)
+	AssertEqual(t, result["customer"].(map[string]interface{})["id"], float64(0))
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPreviewEndpoint_InlineCustomerInvalid tests that an inline customer is validated like
+// one given in a send
+func TestPreviewEndpoint_InlineCustomerInvalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		customer map[string]interface{}
+		message  string
+	}{
+		{"missing phone", map[string]interface{}{"first_name": "Amina"}, "customer: "},
+		{"invalid phone", map[string]interface{}{"phone": "not-a-phone"}, "customer: "},
+		{"half a contact window", map[string]interface{}{"phone": "+254712345678", "contact_window_start": "08:00"}, "customer: "},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := NewMockDB(t)
+			defer db.Close()
+
+			campaign := NewTestCampaign()
+			expectPreviewCampaign(mock, campaign)
+
+			router := setupPreviewTestRouter(setupPreviewTestHandler(t, db))
+			req := NewJSONRequest(t, "POST", fmt.Sprintf("/campaigns/%d/personalized-preview", campaign.ID), map[string]interface{}{"customer": tc.customer})
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			AssertStatusCode(t, resp, http.StatusBadRequest)
+			var errorResp map[string]interface{}
+			ParseJSONResponse(t, resp, &errorResp)
+			errorDetail := errorResp["error"].(map[string]interface{})
+			AssertEqual(t, errorDetail["code"], "VALIDATION_ERROR")
+			AssertContains(t, errorDetail["message"].(string), tc.message)
+			AssertNoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestPreviewEndpoint_CustomerIDAndInlineCustomer tests that giving both customer_id and an
+// inline customer is refused before anything is read
+func TestPreviewEndpoint_CustomerIDAndInlineCustomer(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	router := setupPreviewTestRouter(setupPreviewTestHandler(t, db))
+	requestBody := map[string]interface{}{
+		"customer_id": 1,
+		"customer":    map[string]interface{}{"phone": "+254712345678"},
+	}
+	req := NewJSONRequest(t, "POST", "/campaigns/1/personalized-preview", requestBody)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+	var errorResp map[string]interface{}
+	ParseJSONResponse(t, resp, &errorResp)
+	errorDetail := errorResp["error"].(map[string]interface{})
+	AssertEqual(t, errorDetail["code"], "VALIDATION_ERROR")
+	AssertEqual(t, errorDetail["message"], "give either customer_id or customer, not both")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
 // TestPreviewEndpoint_InvalidJSONBody tests error handling for malformed JSON
 func TestPreviewEndpoint_InvalidJSONBody(t *testing.T) {
 	// Setup mock DB (won't be queried)
@@ -602,7 +719,7 @@ func TestPreviewEndpoint_EmptyOverrideTemplate(t *testing.T) {
 
 	// Mock campaign query
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team", "budget", "spend", "paused_reason", "frequency_cap_exempt", "track_links", "template_syntax", "cancel_at", "demo_failure_rate", "ordered", "part_delimiter",
 	}).AddRow(
 		campaign.ID,
 		campaign.Name,
@@ -612,6 +729,7 @@ func TestPreviewEndpoint_EmptyOverrideTemplate(t *testing.T) {
 		campaign.ScheduledAt,
 		campaign.CreatedAt,
 		campaign.UpdatedAt,
+		"{}", nil, nil, nil, 0, nil, false, false, nil, nil, nil, false, nil,
 	)
 	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE id").
 		WithArgs(campaign.ID).
@@ -619,7 +737,7 @@ func TestPreviewEndpoint_EmptyOverrideTemplate(t *testing.T) {
 
 	// Mock customer query
 	customerRows := sqlmock.NewRows([]string{
-		"id", "phone", "first_name", "last_name", "location", "preferred_product", "created_at",
+		"id", "phone", "first_name", "last_name", "location", "preferred_product", "contact_window_start", "contact_window_end", "created_at",
 	}).AddRow(
 		customer.ID,
 		customer.Phone,
@@ -627,6 +745,8 @@ func TestPreviewEndpoint_EmptyOverrideTemplate(t *testing.T) {
 		customer.LastName,
 		customer.Location,
 		customer.PreferredProduct,
+		customer.ContactWindowStart,
+		customer.ContactWindowEnd,
 		customer.CreatedAt,
 	)
 	mock.ExpectQuery("SELECT (.+) FROM customers WHERE id").
@@ -675,7 +795,7 @@ func TestPreviewEndpoint_ComplexTemplate(t *testing.T) {
 
 	// Mock campaign query
 	campaignRows := sqlmock.NewRows([]string{
-		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at",
+		"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team", "budget", "spend", "paused_reason", "frequency_cap_exempt", "track_links", "template_syntax", "cancel_at", "demo_failure_rate", "ordered", "part_delimiter",
 	}).AddRow(
 		campaign.ID,
 		campaign.Name,
@@ -685,6 +805,7 @@ func TestPreviewEndpoint_ComplexTemplate(t *testing.T) {
 		campaign.ScheduledAt,
 		campaign.CreatedAt,
 		campaign.UpdatedAt,
+		"{}", nil, nil, nil, 0, nil, false, false, nil, nil, nil, false, nil,
 	)
 	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE id").
 		WithArgs(campaign.ID).
@@ -692,7 +813,7 @@ func TestPreviewEndpoint_ComplexTemplate(t *testing.T) {
 
 	// Mock customer query
 	customerRows := sqlmock.NewRows([]string{
-		"id", "phone", "first_name", "last_name", "location", "preferred_product", "created_at",
+		"id", "phone", "first_name", "last_name", "location", "preferred_product", "contact_window_start", "contact_window_end", "created_at",
 	}).AddRow(
 		customer.ID,
 		customer.Phone,
@@ -700,6 +821,8 @@ func TestPreviewEndpoint_ComplexTemplate(t *testing.T) {
 		customer.LastName,
 		customer.Location,
 		customer.PreferredProduct,
+		customer.ContactWindowStart,
+		customer.ContactWindowEnd,
 		customer.CreatedAt,
 	)
 	mock.ExpectQuery("SELECT (.+) FROM customers WHERE id").
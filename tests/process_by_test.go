@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// processByFixture is a processor with a 5s skew tolerance, against a clock the test advances,
+// whose message repository serves message 1 of a sending campaign, with no duplicate
+type processByFixture struct {
+	processor   *service.MessageProcessor
+	messageRepo *MockMessageRepository
+	mock        sqlmock.Sqlmock
+	sender      *countingSender
+	now         time.Time
+}
+
+func newProcessByFixture(t *testing.T) *processByFixture {
+	t.Helper()
+
+	f := &processByFixture{
+		messageRepo: NewMockMessageRepository(),
+		sender:      &countingSender{},
+		now:         time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC),
+	}
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		campaign := NewTestCampaignWithStatus(models.CampaignStatusSending)
+		campaign.BaseTemplate = "Your code is 4821"
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *NewTestMessage(campaign.ID, 1),
+			Campaign:        *campaign,
+			Customer:        *NewTestCustomer(),
+		}, nil
+	}
+
+	f.processor, f.mock = NewMockMessageProcessor(t, f.messageRepo, f.sender)
+	f.processor.SetClock(func() time.Time { return f.now })
+	f.processor.SetProcessBySkewTolerance(5 * time.Second)
+	// With a duplicate send window, an expired message is seen to skip the duplicate check
+	f.processor.SetDuplicateSendWindow(6 * time.Hour)
+	return f
+}
+
+// TestWorker_ProcessBy_OnTime tests that a job taken before its deadline is sent
+func TestWorker_ProcessBy_OnTime(t *testing.T) {
+	f := newProcessByFixture(t)
+	processBy := f.now.Add(time.Minute)
+
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1, ProcessBy: &processBy})
+	AssertNoError(t, err)
+	AssertEqual(t, f.sender.calls, 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestWorker_ProcessBy_Late tests that a job taken past its deadline and the skew tolerance
+// is marked expired, not sent
+func TestWorker_ProcessBy_Late(t *testing.T) {
+	f := newProcessByFixture(t)
+	processBy := f.now.Add(-6 * time.Second)
+
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'expired'").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1, ProcessBy: &processBy})
+	AssertNoError(t, err)
+	AssertEqual(t, f.sender.calls, 0)
+	AssertEqual(t, f.messageRepo.Calls["HasRecentFingerprintSend"], 0)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestWorker_ProcessBy_WithinSkew tests that a job only just past its deadline, within the
+// skew tolerance, is still sent
+func TestWorker_ProcessBy_WithinSkew(t *testing.T) {
+	f := newProcessByFixture(t)
+	processBy := f.now.Add(-4 * time.Second)
+
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1, ProcessBy: &processBy})
+	AssertNoError(t, err)
+	AssertEqual(t, f.sender.calls, 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestWorker_ProcessBy_Absent tests that a job without a deadline never expires
+func TestWorker_ProcessBy_Absent(t *testing.T) {
+	f := newProcessByFixture(t)
+	f.now = f.now.Add(365 * 24 * time.Hour)
+
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1})
+	AssertNoError(t, err)
+	AssertEqual(t, f.sender.calls, 1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestDecodeDelivery_ProcessByHeader tests reading a job's deadline from its headers
+func TestDecodeDelivery_ProcessByHeader(t *testing.T) {
+	body := []byte(`{"v":1,"message_id":7,"campaign_id":2,"customer_id":3}`)
+
+	job, err := queue.DecodeDelivery(amqp.Delivery{
+		Headers: amqp.Table{queue.ProcessByHeader: "2026-05-04T12:00:30.5Z"},
+		Body:    body,
+	})
+	AssertNoError(t, err)
+	AssertEqual(t, job.MessageID, 7)
+	AssertNotNil(t, job.ProcessBy)
+	AssertEqual(t, job.ProcessBy.Equal(time.Date(2026, 5, 4, 12, 0, 30, 500_000_000, time.UTC)), true)
+
+	// The deadline is never written to the body, which workers predating it decode as before
+	encoded, err := json.Marshal(job)
+	AssertNoError(t, err)
+	AssertEqual(t, string(encoded), string(body))
+
+	job, err = queue.DecodeDelivery(amqp.Delivery{Body: body})
+	AssertNoError(t, err)
+	AssertEqual(t, job.ProcessBy == nil, true)
+
+	// A deadline that cannot be read leaves the job without one
+	job, err = queue.DecodeDelivery(amqp.Delivery{Headers: amqp.Table{queue.ProcessByHeader: "soon"}, Body: body})
+	AssertNotNil(t, err)
+	AssertContains(t, err.Error(), "message 7: invalid x-process-by header")
+	AssertEqual(t, job.MessageID, 7)
+	AssertEqual(t, job.ProcessBy == nil, true)
+}
+
+// TestSendCampaign_ProcessByMustBeInFuture tests that a send with a deadline already past is refused
+func TestSendCampaign_ProcessByMustBeInFuture(t *testing.T) {
+	svc, _, _, mock := setupCanaryTest(t)
+	processBy := time.Now().Add(-time.Minute)
+
+	_, err := svc.SendCampaign(context.Background(), 1, []int{1}, service.SendOptions{ProcessBy: &processBy})
+	validationErr, ok := err.(*service.ValidationError)
+	if !ok {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+	AssertEqual(t, validationErr.Message, "process_by must be in the future")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSendCampaign_ProcessByKeptForHeldCanary tests that the audience a canary holds back
+// keeps the send's deadline for when it is continued
+func TestSendCampaign_ProcessByKeptForHeldCanary(t *testing.T) {
+	svc, campaignRepo, _, mock := setupCanaryTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var held *models.SendPlan
+	campaignRepo.HoldCanaryFunc = func(ctx context.Context, id int, plan *models.SendPlan) error {
+		held = plan
+		return nil
+	}
+	processBy := time.Now().Add(time.Hour).UTC()
+
+	_, err := svc.SendCampaign(context.Background(), 1, canaryAudience(100), service.SendOptions{
+		Canary:    &models.CanaryOptions{Percent: 10},
+		ProcessBy: &processBy,
+	})
+	AssertNoError(t, err)
+	AssertNotNil(t, held)
+	AssertEqual(t, *held.ProcessBy, processBy)
+}
+
+// TestGetStatsByIDs_Expired tests that messages expired past their deadline are counted in stats
+func TestGetStatsByIDs_Expired(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`COUNT\(\*\) FILTER \(WHERE status = 'expired'\) as expired`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"campaign_id", "total", "pending", "sent", "failed", "queued", "unpublished", "simulated", "p95_queue_latency", "audience", "first_message_at", "last_finished_at", "skipped_duplicate_content", "expired"}).
+			AddRow(3, 4, 0, 1, 0, 0, 0, 0, nil, 4, nil, nil, 0, 3))
+
+	stats, err := repository.NewCampaignRepository(db).GetStatsByIDs(context.Background(), []int{3})
+	AssertNoError(t, err)
+	AssertEqual(t, stats[3].Sent, 1)
+	AssertEqual(t, stats[3].Expired, 3)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
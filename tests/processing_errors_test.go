@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/metrics"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// failingSender fails every send with a provider error
+type failingSender struct{}
+
+func (s *failingSender) Send(channel models.Channel, phone string, content string, opts service.SenderOptions) *service.SendResult {
+	return &service.SendResult{Success: false, Error: errors.New("provider unavailable")}
+}
+
+func (s *failingSender) Capabilities() []models.Channel {
+	return []models.Channel{models.ChannelSMS, models.ChannelWhatsApp}
+}
+
+// panickingSender panics on every send
+type panickingSender struct{}
+
+func (s *panickingSender) Send(channel models.Channel, phone string, content string, opts service.SenderOptions) *service.SendResult {
+	panic("sender blew up")
+}
+
+func (s *panickingSender) Capabilities() []models.Channel {
+	return []models.Channel{models.ChannelSMS, models.ChannelWhatsApp}
+}
+
+// processingErrorFixture is a message processor over one stored message that records errors to a mock
+type processingErrorFixture struct {
+	processor   *service.MessageProcessor
+	mock        sqlmock.Sqlmock
+	messageRepo *MockMessageRepository
+	errorRepo   *MockProcessingErrorRepository
+	template    string
+}
+
+// newProcessingErrorFixture creates a processor sending through sender
+func newProcessingErrorFixture(t *testing.T, sender service.Sender) *processingErrorFixture {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	f := &processingErrorFixture{
+		mock:        mock,
+		messageRepo: NewMockMessageRepository(),
+		errorRepo:   NewMockProcessingErrorRepository(),
+		template:    NewTestCampaign().BaseTemplate,
+	}
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		message := NewTestMessageWithStatus(models.MessageStatusPending)
+		message.ID = id
+		campaign := NewTestCampaignWithStatus(models.CampaignStatusSending)
+		campaign.BaseTemplate = f.template
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *message,
+			Campaign:        *campaign,
+			Customer:        *NewTestCustomer(),
+		}, nil
+	}
+
+	f.processor = service.NewMessageProcessor(db, f.messageRepo, service.NewTemplateService(), sender, service.NewAttemptBudget(f.messageRepo, 0), nil)
+	f.processor.SetProcessingErrors(service.NewProcessingErrorService(f.errorRepo, "worker-1:42"))
+	return f
+}
+
+// expectMarkedFailed expects the worker's status update after a failed render or send
+func (f *processingErrorFixture) expectMarkedFailed() {
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'failed'").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// TestClassifyProcessingError tests the error class of each kind of processor error
+func TestClassifyProcessingError(t *testing.T) {
+	tests := []struct {
+		err   error
+		class string
+	}{
+		{errors.New("failed to get message: connection refused"), service.ErrorClassInfra},
+		{&service.SendError{Reason: "provider unavailable"}, service.ErrorClassSend},
+		{&service.RenderError{Err: errors.New("template cannot be empty")}, service.ErrorClassRender},
+		{fmt.Errorf("handler failed: %w", &service.RenderError{Err: errors.New("template cannot be empty")}), service.ErrorClassRender},
+		{&service.PanicError{Value: "boom"}, service.ErrorClassPanic},
+	}
+
+	for _, tt := range tests {
+		AssertEqual(t, service.ClassifyProcessingError(tt.err), tt.class)
+	}
+}
+
+// TestProcessingErrors_Infra tests that a failed fetch is recorded against the message and requeued
+func TestProcessingErrors_Infra(t *testing.T) {
+	f := newProcessingErrorFixture(t, &countingSender{})
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		return nil, errors.New("failed to get message: connection refused")
+	}
+	before := testutil.ToFloat64(metrics.ProcessingErrors.WithLabelValues(service.ErrorClassInfra))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 7, CampaignID: 1, CustomerID: 1})
+	AssertError(t, err, "failed to get message: connection refused")
+
+	AssertEqual(t, len(f.errorRepo.Created), 1)
+	recorded := f.errorRepo.Created[0]
+	AssertEqual(t, recorded.ErrorClass, service.ErrorClassInfra)
+	AssertEqual(t, *recorded.MessageID, 7)
+	AssertEqual(t, recorded.WorkerID, "worker-1:42")
+	AssertEqual(t, recorded.Error, "failed to get message: connection refused")
+	AssertEqual(t, testutil.ToFloat64(metrics.ProcessingErrors.WithLabelValues(service.ErrorClassInfra)), before+1)
+}
+
+// TestProcessingErrors_Render tests that a template that cannot be rendered is recorded as a render error
+func TestProcessingErrors_Render(t *testing.T) {
+	sender := &countingSender{}
+	f := newProcessingErrorFixture(t, sender)
+	f.template = ""
+	f.expectMarkedFailed()
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1})
+	AssertError(t, err, "template cannot be empty")
+
+	AssertEqual(t, sender.calls, 0)
+	AssertEqual(t, len(f.errorRepo.Created), 1)
+	AssertEqual(t, f.errorRepo.Created[0].ErrorClass, service.ErrorClassRender)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestProcessingErrors_Send tests that a provider failure is counted but not recorded, as it is on the message
+func TestProcessingErrors_Send(t *testing.T) {
+	f := newProcessingErrorFixture(t, &failingSender{})
+	f.expectMarkedFailed()
+	before := testutil.ToFloat64(metrics.ProcessingErrors.WithLabelValues(service.ErrorClassSend))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1})
+	AssertError(t, err, "send failed: provider unavailable")
+
+	AssertEqual(t, f.errorRepo.Calls["Create"], 0)
+	AssertEqual(t, testutil.ToFloat64(metrics.ProcessingErrors.WithLabelValues(service.ErrorClassSend)), before+1)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestProcessingErrors_Panic tests that a panic is recovered, recorded with its stack and the job requeued
+func TestProcessingErrors_Panic(t *testing.T) {
+	f := newProcessingErrorFixture(t, &panickingSender{})
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1})
+	AssertError(t, err, "panic: sender blew up")
+
+	AssertEqual(t, len(f.errorRepo.Created), 1)
+	recorded := f.errorRepo.Created[0]
+	AssertEqual(t, recorded.ErrorClass, service.ErrorClassPanic)
+	AssertContains(t, recorded.Error, "panic: sender blew up\n")
+	AssertContains(t, recorded.Error, "goroutine")
+}
+
+// TestProcessingErrors_RecordFailureSwallowed tests that a failure to record never changes the job outcome
+func TestProcessingErrors_RecordFailureSwallowed(t *testing.T) {
+	f := newProcessingErrorFixture(t, &countingSender{})
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		return nil, errors.New("failed to get message: connection refused")
+	}
+	f.errorRepo.CreateFunc = func(ctx context.Context, processingError *models.ProcessingError) error {
+		return errors.New("failed to create processing error: connection refused")
+	}
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1})
+	AssertError(t, err, "failed to get message: connection refused")
+	AssertEqual(t, f.errorRepo.Calls["Create"], 1)
+
+	// Without a recorder configured the error is still returned
+	f.processor.SetProcessingErrors(nil)
+	err = f.processor.Handle(&queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1})
+	AssertError(t, err, "failed to get message: connection refused")
+}
+
+// TestProcessingErrorsEndpoint tests GET /admin/processing-errors and its since parameter
+func TestProcessingErrorsEndpoint(t *testing.T) {
+	errorRepo := NewMockProcessingErrorRepository()
+	var since time.Time
+	var limit int
+	errorRepo.ListSinceFunc = func(ctx context.Context, s time.Time, l int) ([]*models.ProcessingError, error) {
+		since, limit = s, l
+		messageID := 7
+		return []*models.ProcessingError{
+			{ID: 1, MessageID: &messageID, ErrorClass: service.ErrorClassInfra, Error: "connection refused", WorkerID: "worker-1:42"},
+		}, nil
+	}
+	h := handler.NewAdminHandler(nil, service.NewProcessingErrorService(errorRepo, ""))
+
+	req := httptest.NewRequest("GET", "/admin/processing-errors", nil)
+	resp := httptest.NewRecorder()
+	h.ProcessingErrors(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	var processingErrors []models.ProcessingError
+	ParseJSONResponse(t, resp, &processingErrors)
+	AssertEqual(t, len(processingErrors), 1)
+	AssertEqual(t, processingErrors[0].ErrorClass, "infra")
+	AssertEqual(t, limit, service.MaxProcessingErrors)
+	if age := time.Since(since); age < 24*time.Hour || age > 24*time.Hour+time.Minute {
+		t.Errorf("Expected the default window to start 24h ago, got %v", age)
+	}
+
+	req = httptest.NewRequest("GET", "/admin/processing-errors?since=2026-10-01T08:00:00Z", nil)
+	resp = httptest.NewRecorder()
+	h.ProcessingErrors(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertEqual(t, since.Equal(time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)), true)
+
+	req = httptest.NewRequest("GET", "/admin/processing-errors?since=yesterday", nil)
+	resp = httptest.NewRecorder()
+	h.ProcessingErrors(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+	AssertEqual(t, errorRepo.Calls["ListSince"], 2)
+}
+
+// TestProcessingErrorRepository_Queries tests the insert and the since query
+func TestProcessingErrorRepository_Queries(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	occurredAt := time.Now()
+	mock.ExpectQuery(`INSERT INTO processing_errors \(message_id, error_class, error, worker_id\) VALUES \(\$1, \$2, \$3, \$4\) RETURNING id, occurred_at`).
+		WithArgs(nil, "infra", "connection refused", "worker-1:42").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "occurred_at"}).AddRow(3, occurredAt))
+	mock.ExpectQuery(`SELECT id, message_id, error_class, error, worker_id, occurred_at FROM processing_errors WHERE occurred_at >= \$1 ORDER BY occurred_at DESC, id DESC LIMIT \$2`).
+		WithArgs(occurredAt, 500).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message_id", "error_class", "error", "worker_id", "occurred_at"}).
+			AddRow(3, nil, "infra", "connection refused", "worker-1:42", occurredAt))
+
+	repo := repository.NewProcessingErrorRepository(db)
+	processingError := &models.ProcessingError{ErrorClass: "infra", Error: "connection refused", WorkerID: "worker-1:42"}
+	AssertNoError(t, repo.Create(context.Background(), processingError))
+	AssertEqual(t, processingError.ID, 3)
+
+	listed, err := repo.ListSince(context.Background(), occurredAt, 500)
+	AssertNoError(t, err)
+	AssertEqual(t, len(listed), 1)
+	if listed[0].MessageID != nil {
+		t.Error("Expected no message ID")
+	}
+	AssertEqual(t, listed[0].Error, "connection refused")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/service"
+)
+
+// benchmarkTemplate uses every placeholder, like a typical personalized campaign
+const benchmarkTemplate = "Hi {first_name} {last_name}, your {preferred_product} offer in {location} ends Friday. " +
+	"Questions? Call us from {phone}. Reply STOP to opt out."
+
+// benchmarkCustomer returns a customer with every field filled
+func benchmarkCustomer() *models.Customer {
+	return &models.Customer{
+		ID:               42,
+		Phone:            "+254712345678",
+		FirstName:        StringPtr("Amina"),
+		LastName:         StringPtr("Wanjiru"),
+		Location:         StringPtr("Nairobi"),
+		PreferredProduct: StringPtr("Solar Lamp"),
+	}
+}
+
+// sequentialRender is the previous Render: one ReplaceAll pass per placeholder
+func sequentialRender(template string, customer *models.Customer) string {
+	value := func(field *string) string {
+		if field == nil {
+			return ""
+		}
+		return *field
+	}
+	rendered := strings.ReplaceAll(template, "{first_name}", value(customer.FirstName))
+	rendered = strings.ReplaceAll(rendered, "{last_name}", value(customer.LastName))
+	rendered = strings.ReplaceAll(rendered, "{location}", value(customer.Location))
+	rendered = strings.ReplaceAll(rendered, "{preferred_product}", value(customer.PreferredProduct))
+	return strings.ReplaceAll(rendered, "{phone}", customer.Phone)
+}
+
+// TestRender_MatchesSequentialReplace tests that the single-pass renderer produces the same messages
+func TestRender_MatchesSequentialReplace(t *testing.T) {
+	templates := []string{
+		benchmarkTemplate,
+		"No placeholders at all",
+		"{first_name}{first_name}{first_name}",
+		"Hi {first_name}, {unknown} and {FIRST_NAME} stay",
+		"Braces { alone } and {{first_name}} doubled",
+		"Unclosed {first_name and {last_name}",
+		"{phone}",
+		"Trailing brace {",
+		"Habari {first_name}, karibu {location} ☀️ {preferred_product}",
+	}
+	customers := []*models.Customer{
+		benchmarkCustomer(),
+		{Phone: "+254700000001"},
+		{Phone: "+254700000002", FirstName: StringPtr(""), Location: StringPtr("Kisumu")},
+	}
+
+	templateSvc := service.NewTemplateService()
+	for _, template := range templates {
+		for _, customer := range customers {
+			rendered, err := templateSvc.Render(template, customer)
+			AssertNoError(t, err)
+			AssertEqual(t, rendered, sequentialRender(template, customer))
+		}
+	}
+}
+
+// TestRender_DoesNotExpandCustomerValues tests that a value containing a placeholder is inserted literally
+func TestRender_DoesNotExpandCustomerValues(t *testing.T) {
+	customer := &models.Customer{Phone: "+254700000003", FirstName: StringPtr("{phone}")}
+
+	rendered, err := service.NewTemplateService().Render("Hi {first_name}", customer)
+	AssertNoError(t, err)
+	AssertEqual(t, rendered, "Hi {phone}")
+}
+
+// TestRender_Allocations tests that rendering allocates only the output string
+func TestRender_Allocations(t *testing.T) {
+	templateSvc := service.NewTemplateService()
+	customer := benchmarkCustomer()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := templateSvc.Render(benchmarkTemplate, customer); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("Expected at most 1 allocation per render but got %.0f", allocs)
+	}
+}
+
+// BenchmarkProcessMessage measures the worker's per-message CPU work: decoding the job,
+// rendering the template and checking its length (database and provider calls excluded)
+// Run with: go test ./tests -run '^$' -bench ProcessMessage -benchmem
+func BenchmarkProcessMessage(b *testing.B) {
+	templateSvc := service.NewTemplateService()
+	customer := benchmarkCustomer()
+	body, err := json.Marshal(queue.MessageJob{MessageID: 1001, CampaignID: 7, CustomerID: 42})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := queue.DecodeMessageJob(body); err != nil {
+			b.Fatal(err)
+		}
+		rendered, err := templateSvc.Render(benchmarkTemplate, customer)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := templateSvc.CheckRenderedLength(rendered); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+)
+
+// fakePacingClock is a clock that only moves when the pacer sleeps
+type fakePacingClock struct {
+	now time.Time
+}
+
+func newFakePacingClock() *fakePacingClock {
+	return &fakePacingClock{now: time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakePacingClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakePacingClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+// newTestPacer creates a pacer on a fake clock with seeded jitter
+func newTestPacer(t *testing.T, rate, jitter float64) (*service.PublishPacer, *fakePacingClock) {
+	t.Helper()
+	pacer := service.NewPublishPacer(config.PublishPacingConfig{Enabled: true, Rate: rate, Jitter: jitter}, 1000)
+	if pacer == nil {
+		t.Fatal("Expected a pacer")
+	}
+	clock := newFakePacingClock()
+	pacer.SetClock(clock.Now, clock.Sleep)
+	pacer.SetRandom(rand.New(rand.NewSource(42)).Float64)
+	return pacer, clock
+}
+
+// TestPublishPacer_SpacingDistribution tests that a batch is spread evenly over its interval,
+// each publish within the jitter of its slot, with gaps scattered either side of the spacing
+func TestPublishPacer_SpacingDistribution(t *testing.T) {
+	const rate, jitter, n = 10.0, 0.2, 600
+	spacing := 100 * time.Millisecond
+	pacer, clock := newTestPacer(t, rate, jitter)
+	AssertEqual(t, pacer.BatchLimit(time.Minute), n)
+
+	start := clock.Now()
+	at := make([]time.Duration, 0, n)
+	reached, err := pacer.Pace(context.Background(), n, func(i int) {
+		at = append(at, clock.Now().Sub(start))
+	})
+	AssertNoError(t, err)
+	AssertEqual(t, reached, n)
+	AssertEqual(t, at[0], time.Duration(0))
+
+	maxShift := time.Duration(jitter * float64(spacing))
+	var shorter, longer int
+	var sum, sumSquares float64
+	for i := 1; i < n; i++ {
+		slot := time.Duration(i) * spacing
+		if at[i] < slot-maxShift || at[i] > slot+maxShift {
+			t.Fatalf("Publish %d at %v, expected within %v of %v", i, at[i], maxShift, slot)
+		}
+
+		gap := at[i] - at[i-1]
+		if gap <= 0 {
+			t.Fatalf("Publish %d at %v is not after the one before at %v", i, at[i], at[i-1])
+		}
+		if gap < spacing {
+			shorter++
+		} else if gap > spacing {
+			longer++
+		}
+		sum += gap.Seconds()
+		sumSquares += gap.Seconds() * gap.Seconds()
+	}
+
+	// The whole batch fits in the interval it was sized for
+	if last := at[n-1]; last >= time.Minute {
+		t.Fatalf("Expected the batch to finish within a minute, last publish at %v", last)
+	}
+
+	// Gaps average the spacing, and jitter scatters them both ways
+	gaps := float64(n - 1)
+	mean := sum / gaps
+	stddev := math.Sqrt(sumSquares/gaps - mean*mean)
+	if math.Abs(mean-spacing.Seconds()) > 0.001 {
+		t.Fatalf("Expected gaps to average %v, got %.4fs", spacing, mean)
+	}
+	if stddev < 0.01 || stddev > 0.04 {
+		t.Fatalf("Expected jitter to spread the gaps, got a standard deviation of %.4fs", stddev)
+	}
+	if shorter < n/3 || longer < n/3 {
+		t.Fatalf("Expected gaps either side of the spacing, got %d shorter and %d longer", shorter, longer)
+	}
+}
+
+// TestPublishPacer_NoJitter tests that without jitter publishes are exactly evenly spaced
+func TestPublishPacer_NoJitter(t *testing.T) {
+	pacer, clock := newTestPacer(t, 4, 0)
+
+	start := clock.Now()
+	var at []time.Duration
+	_, err := pacer.Pace(context.Background(), 5, func(i int) {
+		at = append(at, clock.Now().Sub(start))
+	})
+	AssertNoError(t, err)
+	for i, offset := range at {
+		AssertEqual(t, offset, time.Duration(i)*250*time.Millisecond)
+	}
+}
+
+// TestPublishPacer_Rate tests that the pacing rate defaults to and is capped by the send rate
+func TestPublishPacer_Rate(t *testing.T) {
+	tests := []struct {
+		name     string
+		pacing   config.PublishPacingConfig
+		sendRate float64
+		want     float64 // 0 for no pacer
+	}{
+		{"disabled", config.PublishPacingConfig{Rate: 5}, 10, 0},
+		{"send rate by default", config.PublishPacingConfig{Enabled: true}, 10, 10},
+		{"slower than sending", config.PublishPacingConfig{Enabled: true, Rate: 5}, 10, 5},
+		{"capped at the send rate", config.PublishPacingConfig{Enabled: true, Rate: 50}, 10, 10},
+		{"no rate at all", config.PublishPacingConfig{Enabled: true}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pacer := service.NewPublishPacer(tt.pacing, tt.sendRate)
+			if tt.want == 0 {
+				if pacer != nil {
+					t.Fatalf("Expected no pacer, got one at %.1f/s", pacer.Rate())
+				}
+				return
+			}
+			AssertEqual(t, pacer.Rate(), tt.want)
+		})
+	}
+
+	// A batch is always at least one publish
+	pacer, _ := newTestPacer(t, 0.001, 0)
+	AssertEqual(t, pacer.BatchLimit(time.Minute), 1)
+}
+
+// TestPublishReconciler_Paced tests that the reconciler claims what fits in its interval and
+// publishes it on the pacer's schedule
+func TestPublishReconciler_Paced(t *testing.T) {
+	pacer, clock := newTestPacer(t, 2, 0)
+	messageRepo := NewMockMessageRepository()
+	var claimLimit int
+	messageRepo.ClaimUnpublishedFunc = func(ctx context.Context, before time.Time, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error) {
+		claimLimit = limit
+		return []*models.OutboundMessage{{ID: 1, CampaignID: 1}, {ID: 2, CampaignID: 1}, {ID: 3, CampaignID: 2}}, nil
+	}
+
+	reconciler := service.NewPublishReconciler(messageRepo)
+	reconciler.SetPacing(pacer, time.Minute)
+
+	start := clock.Now()
+	var at []time.Duration
+	published, err := reconciler.Reconcile(context.Background(), func(message *models.OutboundMessage) error {
+		at = append(at, clock.Now().Sub(start))
+		return nil
+	})
+	AssertNoError(t, err)
+	AssertEqual(t, published, 3)
+	AssertEqual(t, claimLimit, 120)
+	AssertEqual(t, len(at), 3)
+	AssertEqual(t, at[1], 500*time.Millisecond)
+	AssertEqual(t, at[2], time.Second)
+}
+
+// TestPublishReconciler_PacingInterrupted tests that messages claimed but not yet published
+// when the reconciler is stopped are deferred for the requeue rather than lost
+func TestPublishReconciler_PacingInterrupted(t *testing.T) {
+	pacer, clock := newTestPacer(t, 1, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pacer.SetClock(clock.Now, func(sleepCtx context.Context, d time.Duration) error {
+		cancel() // Stopped while waiting for the second slot
+		return sleepCtx.Err()
+	})
+
+	messageRepo := NewMockMessageRepository()
+	messageRepo.ClaimUnpublishedFunc = func(ctx context.Context, before time.Time, limit int, order models.DispatchOrder) ([]*models.OutboundMessage, error) {
+		return []*models.OutboundMessage{{ID: 1, CampaignID: 1}, {ID: 2, CampaignID: 1}, {ID: 3, CampaignID: 1}}, nil
+	}
+	deferred := map[int]string{}
+	messageRepo.DeferUntilFunc = func(ctx context.Context, id int, until time.Time, reason string) error {
+		AssertNoError(t, ctx.Err())
+		deferred[id] = reason
+		return nil
+	}
+
+	reconciler := service.NewPublishReconciler(messageRepo)
+	reconciler.SetPacing(pacer, time.Minute)
+
+	published, err := reconciler.Reconcile(ctx, func(message *models.OutboundMessage) error { return nil })
+	AssertNoError(t, err)
+	AssertEqual(t, published, 1)
+	AssertEqual(t, len(deferred), 2)
+	AssertEqual(t, deferred[2], "Publish interrupted")
+	AssertEqual(t, deferred[3], "Publish interrupted")
+}
+
+// TestLoad_PublishPacing tests the pacing defaults and that bad settings fail startup
+func TestLoad_PublishPacing(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Pacing.Enabled, true)
+	AssertEqual(t, cfg.Pacing.Rate, 0.0)
+	AssertEqual(t, cfg.Pacing.Jitter, 0.2)
+
+	t.Setenv("PUBLISH_PACING_JITTER", "0.5")
+	_, err = config.Load()
+	AssertError(t, err, "PUBLISH_PACING_JITTER must be at least 0 and below 0.5")
+
+	t.Setenv("PUBLISH_PACING_JITTER", "0.1")
+	t.Setenv("PUBLISH_PACING_RATE", "-1")
+	_, err = config.Load()
+	AssertError(t, err, "PUBLISH_PACING_RATE cannot be negative")
+}
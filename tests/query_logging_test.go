@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// captureLog redirects the standard logger into a buffer for the duration of a test
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+// TestInstrumentedDB_LogsSlowQuery tests that a statement over the threshold is logged with redacted text
+func TestInstrumentedDB_LogsSlowQuery(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	buf := captureLog(t)
+
+	mock.ExpectQuery("SELECT id FROM customers").
+		WithArgs("secret-phone").
+		WillDelayFor(30 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	instrumented := repository.NewInstrumentedDB(db, 10*time.Millisecond)
+	rows, err := instrumented.QueryContext(context.Background(),
+		"SELECT id\n\t\tFROM customers\n\t\tWHERE phone = $1 AND location = 'Nairobi'", "secret-phone")
+	AssertNoError(t, err)
+	rows.Close()
+
+	output := buf.String()
+	if !strings.Contains(output, "Slow query") {
+		t.Fatalf("Expected slow query log record, got %q", output)
+	}
+	if !strings.Contains(output, "1 args") {
+		t.Errorf("Expected arg count in log record, got %q", output)
+	}
+	if !strings.Contains(output, "SELECT id FROM customers WHERE phone = $1 AND location = '?'") {
+		t.Errorf("Expected normalized, redacted query text, got %q", output)
+	}
+	if strings.Contains(output, "Nairobi") || strings.Contains(output, "secret-phone") {
+		t.Errorf("Expected literals and args to be redacted, got %q", output)
+	}
+
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestInstrumentedDB_FastQueryNotLogged tests that statements under the threshold are not logged
+func TestInstrumentedDB_FastQueryNotLogged(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	buf := captureLog(t)
+
+	mock.ExpectExec("UPDATE campaigns").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	instrumented := repository.NewInstrumentedDB(db, time.Second)
+	_, err := instrumented.ExecContext(context.Background(), "UPDATE campaigns SET status = 'sent' WHERE id = $1", 1)
+	AssertNoError(t, err)
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected no log output, got %q", buf.String())
+	}
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestInstrumentedDB_WorksWithRepositories tests that repositories accept the wrapper
+func TestInstrumentedDB_WorksWithRepositories(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE outbound_messages SET published_at").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	messageRepo := repository.NewMessageRepository(repository.NewInstrumentedDB(db, time.Second))
+	AssertNoError(t, messageRepo.MarkPublished(context.Background(), []int{1, 2}))
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestQueryFingerprint tests that fingerprints are short, stable and distinguish queries
+func TestQueryFingerprint(t *testing.T) {
+	selectCampaign := repository.NormalizeQuery("SELECT * FROM campaigns WHERE id = $1")
+	fingerprint := repository.QueryFingerprint(selectCampaign)
+
+	if !strings.HasPrefix(fingerprint, "select_campaigns_") {
+		t.Errorf("Expected verb_table prefix, got %q", fingerprint)
+	}
+	AssertEqual(t, repository.QueryFingerprint(selectCampaign), fingerprint)
+
+	// Literal values do not create new label values
+	AssertEqual(t,
+		repository.QueryFingerprint(repository.NormalizeQuery("UPDATE campaigns SET status = 'sent'")),
+		repository.QueryFingerprint(repository.NormalizeQuery("UPDATE campaigns SET status = 'failed'")),
+	)
+
+	other := repository.QueryFingerprint(repository.NormalizeQuery("SELECT name FROM campaigns WHERE id = $1"))
+	if other == fingerprint {
+		t.Errorf("Expected different queries to have different fingerprints")
+	}
+}
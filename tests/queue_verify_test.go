@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/service"
+)
+
+// queueVerifyStore is the outbound_messages table as far as the queue verifier sees it
+type queueVerifyStore struct {
+	messages  map[int]*models.OutboundMessage
+	published map[int]time.Time
+	deferred  map[int]bool // deliver_after is set
+	now       time.Time
+}
+
+func (s *queueVerifyStore) repo() *MockMessageRepository {
+	repo := NewMockMessageRepository()
+	repo.GetStatusesFunc = func(ctx context.Context, ids []int) (map[int]models.MessageStatus, error) {
+		statuses := map[int]models.MessageStatus{}
+		for _, id := range ids {
+			if message, ok := s.messages[id]; ok {
+				statuses[id] = message.Status
+			}
+		}
+		return statuses, nil
+	}
+	repo.ListQueuedFunc = func(ctx context.Context, publishedBefore time.Time) ([]*models.OutboundMessage, error) {
+		ids := []int{}
+		for id := range s.messages {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		queued := []*models.OutboundMessage{}
+		for _, id := range ids {
+			message := s.messages[id]
+			if message.Status == models.MessageStatusPending && !s.deferred[id] && s.published[id].Before(publishedBefore) {
+				queued = append(queued, message)
+			}
+		}
+		return queued, nil
+	}
+	repo.MarkPublishedFunc = func(ctx context.Context, ids []int) error {
+		for _, id := range ids {
+			s.published[id] = s.now
+		}
+		return nil
+	}
+	return repo
+}
+
+func jobBody(t *testing.T, messageID int) []byte {
+	t.Helper()
+	body, err := json.Marshal(queue.MessageJob{MessageID: messageID, CampaignID: 1, CustomerID: messageID})
+	AssertNoError(t, err)
+	return body
+}
+
+// TestQueueVerifier_ResolvesOrphans plants jobs without rows and rows without jobs, checks a dry
+// run only reports them, a real run resolves them, and a rerun finds queue and database agreeing
+func TestQueueVerifier_ResolvesOrphans(t *testing.T) {
+	now := time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+	message := func(id int, status models.MessageStatus) *models.OutboundMessage {
+		return &models.OutboundMessage{ID: id, CampaignID: 1, CustomerID: id, Status: status}
+	}
+	store := &queueVerifyStore{
+		messages: map[int]*models.OutboundMessage{
+			1: message(1, models.MessageStatusPending), // Queued
+			2: message(2, models.MessageStatusSent),    // Sent, its job still queued
+			3: message(3, models.MessageStatusPending), // Job lost
+			4: message(4, models.MessageStatusPending), // Published moments ago, job on its way
+			5: message(5, models.MessageStatusPending), // Deferred, no job expected
+		},
+		published: map[int]time.Time{
+			1: now.Add(-time.Hour),
+			2: now.Add(-time.Hour),
+			3: now.Add(-time.Hour),
+			4: now.Add(-2 * time.Minute),
+			5: now.Add(-time.Hour),
+		},
+		deferred: map[int]bool{5: true},
+		now:      now,
+	}
+
+	broker := [][]byte{jobBody(t, 1), jobBody(t, 2), jobBody(t, 99), []byte("not a job")}
+	verifier := service.NewQueueVerifier(store.repo(), service.QueueVerifyGracePeriod)
+	verifier.SetClock(func() time.Time { return now })
+	publisher := NewMockPublisher()
+	publish := func(message *models.OutboundMessage) error {
+		return publisher.PublishMessage(message.ID, message.CampaignID, message.CustomerID)
+	}
+
+	// A dry run reports both kinds of orphan and gives every job back
+	drain := NewMockQueueDrain(broker...)
+	report, err := verifier.Verify(context.Background(), drain, publish, true)
+	AssertNoError(t, err)
+	AssertEqual(t, report.DryRun, true)
+	AssertEqual(t, report.Jobs, 4)
+	AssertEqual(t, report.Matched, 1)
+	AssertEqual(t, fmt.Sprint(report.Stale), "[2]")
+	AssertEqual(t, fmt.Sprint(report.OrphanJobs), "[99]")
+	AssertEqual(t, report.Undecodable, 1)
+	AssertEqual(t, fmt.Sprint(report.OrphanRows), "[3]")
+	AssertEqual(t, len(drain.Requeued), 4)
+	AssertEqual(t, len(drain.Quarantined), 0)
+	AssertEqual(t, publisher.GetPublishedCount(), 0)
+	AssertEqual(t, store.published[3], now.Add(-time.Hour))
+
+	// A real run moves orphan jobs to the poison queue and republishes orphan rows
+	drain = NewMockQueueDrain(broker...)
+	report, err = verifier.Verify(context.Background(), drain, publish, false)
+	AssertNoError(t, err)
+	AssertEqual(t, report.Failed, 0)
+	AssertEqual(t, len(drain.Requeued), 2)
+	AssertEqual(t, drain.Quarantined[string(jobBody(t, 99))], "message 99 does not exist")
+	AssertEqual(t, drain.Quarantined["not a job"], "not a message job")
+	AssertEqual(t, publisher.GetPublishedCount(), 1)
+	AssertEqual(t, publisher.Published[0].MessageID, 3)
+	AssertEqual(t, store.published[3], now)
+
+	// The broker now holds the requeued and republished jobs, and a rerun finds nothing to fix
+	broker = append(drain.Requeued, jobBody(t, 3))
+	store.now = now.Add(time.Hour)
+	verifier.SetClock(func() time.Time { return store.now })
+	drain = NewMockQueueDrain(broker...)
+	report, err = verifier.Verify(context.Background(), drain, publish, false)
+	AssertNoError(t, err)
+	AssertEqual(t, report.Matched, 2)
+	AssertEqual(t, len(report.OrphanJobs), 0)
+	AssertEqual(t, report.Undecodable, 0)
+	// Message 4's job never arrived; now past the grace period it is republished
+	AssertEqual(t, fmt.Sprint(report.OrphanRows), "[4]")
+	AssertEqual(t, len(drain.Quarantined), 0)
+}
+
+// TestQueueVerifier_PublishFailure tests that a message failing to republish is counted and not
+// marked published, so the next run tries it again
+func TestQueueVerifier_PublishFailure(t *testing.T) {
+	now := time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+	store := &queueVerifyStore{
+		messages:  map[int]*models.OutboundMessage{7: {ID: 7, CampaignID: 1, CustomerID: 7, Status: models.MessageStatusPending}},
+		published: map[int]time.Time{7: now.Add(-time.Hour)},
+		now:       now,
+	}
+	verifier := service.NewQueueVerifier(store.repo(), service.QueueVerifyGracePeriod)
+	verifier.SetClock(func() time.Time { return now })
+
+	report, err := verifier.Verify(context.Background(), NewMockQueueDrain(), func(message *models.OutboundMessage) error {
+		return fmt.Errorf("broker unavailable")
+	}, false)
+	AssertNoError(t, err)
+	AssertEqual(t, report.Failed, 1)
+	AssertEqual(t, fmt.Sprint(report.OrphanRows), "[7]")
+	AssertEqual(t, store.published[7], now.Add(-time.Hour))
+}
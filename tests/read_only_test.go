@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/maintenance"
+	"smsleopard/internal/middleware"
+	"smsleopard/internal/models"
+)
+
+// serveReadOnly sends a request through the read-only guard to a handler that always succeeds
+func serveReadOnly(mode *maintenance.ReadOnly, method, path string) *httptest.ResponseRecorder {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	guard := middleware.RejectWritesWhenReadOnly(mode, "/health", "/admin/read-only")(next)
+
+	resp := httptest.NewRecorder()
+	guard.ServeHTTP(resp, httptest.NewRequest(method, path, nil))
+	return resp
+}
+
+// TestReadOnly_RejectsWrites tests that writes get a 503 with Retry-After while reads and exempt paths pass
+func TestReadOnly_RejectsWrites(t *testing.T) {
+	mode := maintenance.NewReadOnly(true)
+
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		resp := serveReadOnly(mode, method, "/campaigns/1")
+		AssertStatusCode(t, resp, http.StatusServiceUnavailable)
+		AssertEqual(t, resp.Header().Get("Retry-After"), "120")
+
+		var errResp handler.ErrorResponse
+		ParseJSONResponse(t, resp, &errResp)
+		AssertEqual(t, errResp.Error.Code, "SERVICE_READ_ONLY")
+	}
+
+	AssertStatusCode(t, serveReadOnly(mode, "GET", "/campaigns/1"), http.StatusOK)
+	AssertStatusCode(t, serveReadOnly(mode, "HEAD", "/campaigns"), http.StatusOK)
+	AssertStatusCode(t, serveReadOnly(mode, "POST", "/admin/read-only"), http.StatusOK)
+
+	// Turning the mode off lets writes through again without a restart
+	mode.Set(false)
+	AssertStatusCode(t, serveReadOnly(mode, "POST", "/campaigns"), http.StatusOK)
+}
+
+// TestReadOnly_OnChange tests that hooks run only when the mode actually changes, and a nil mode is off
+func TestReadOnly_OnChange(t *testing.T) {
+	mode := maintenance.NewReadOnly(false)
+	changes := []bool{}
+	mode.OnChange(func(enabled bool) {
+		changes = append(changes, enabled)
+	})
+
+	AssertEqual(t, mode.Set(true), true)
+	AssertEqual(t, mode.Set(true), false)
+	AssertEqual(t, mode.Set(false), true)
+	AssertEqual(t, len(changes), 2)
+	AssertEqual(t, changes[0], true)
+	AssertEqual(t, changes[1], false)
+
+	var off *maintenance.ReadOnly
+	AssertEqual(t, off.Enabled(), false)
+}
+
+// TestReadOnlyHandler tests showing and toggling the mode over HTTP
+func TestReadOnlyHandler(t *testing.T) {
+	mode := maintenance.NewReadOnly(false)
+	h := handler.NewReadOnlyHandler(mode)
+
+	resp := httptest.NewRecorder()
+	h.Set(resp, httptest.NewRequest("POST", "/admin/read-only", strings.NewReader(`{"read_only": true}`)))
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertEqual(t, mode.Enabled(), true)
+
+	resp = httptest.NewRecorder()
+	h.Get(resp, httptest.NewRequest("GET", "/admin/read-only", nil))
+	var state handler.ReadOnlyState
+	ParseJSONResponse(t, resp, &state)
+	AssertEqual(t, state.ReadOnly, true)
+
+	for _, body := range []string{"", "{", "{}"} {
+		resp := httptest.NewRecorder()
+		h.Set(resp, httptest.NewRequest("POST", "/admin/read-only", strings.NewReader(body)))
+		AssertStatusCode(t, resp, http.StatusBadRequest)
+	}
+	AssertEqual(t, mode.Enabled(), true)
+}
+
+// TestReadOnly_RequeuePaused tests that deferred messages are not requeued while read-only
+func TestReadOnly_RequeuePaused(t *testing.T) {
+	budget, messageRepo, _, _ := newBudgetTest(t, 5, time.Date(2026, 3, 2, 0, 1, 0, 0, time.UTC))
+	messageRepo.ClaimDueDeferredFunc = func(ctx context.Context, at time.Time, limit int) ([]*models.OutboundMessage, error) {
+		return []*models.OutboundMessage{{ID: 1, CampaignID: 7, CustomerID: 5}}, nil
+	}
+
+	mode := maintenance.NewReadOnly(true)
+	budget.SetRequeuePaused(mode.Enabled)
+	publish := func(message *models.OutboundMessage) error { return nil }
+
+	count, err := budget.RequeueDue(context.Background(), publish)
+	AssertNoError(t, err)
+	AssertEqual(t, count, 0)
+	AssertEqual(t, messageRepo.Calls["ClaimDueDeferred"], 0)
+
+	mode.Set(false)
+	count, err = budget.RequeueDue(context.Background(), publish)
+	AssertNoError(t, err)
+	AssertEqual(t, count, 1)
+}
+
+// TestLoadReadOnly tests READ_ONLY parsing
+func TestLoadReadOnly(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Server.ReadOnly, false)
+
+	t.Setenv("READ_ONLY", "true")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Server.ReadOnly, true)
+}
@@ -0,0 +1,381 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// stubQueue reports a fixed queue status, optionally after a delay
+type stubQueue struct {
+	status string
+	delay  time.Duration
+}
+
+func (q *stubQueue) CheckQueue() string {
+	time.Sleep(q.delay)
+	return q.status
+}
+
+// readinessFixture holds the mocks behind a readiness service
+type readinessFixture struct {
+	campaign     *models.Campaign
+	campaignRepo *MockCampaignRepository
+	customerRepo *MockCustomerRepository
+	messageRepo  *MockMessageRepository
+	queue        *stubQueue
+	quiet        config.QuietHoursConfig
+}
+
+// newReadinessFixture creates mocks for a campaign that passes every check
+func newReadinessFixture() *readinessFixture {
+	f := &readinessFixture{
+		campaign:     NewTestCampaign(),
+		campaignRepo: NewMockCampaignRepository(),
+		customerRepo: NewMockCustomerRepository(),
+		messageRepo:  NewMockMessageRepository(),
+		queue:        &stubQueue{status: service.StatusConnected},
+	}
+	f.campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return f.campaign, nil
+	}
+	return f
+}
+
+// service builds the readiness service from the fixture's current state
+func (f *readinessFixture) service() *service.ReadinessService {
+	var queue service.QueueChecker
+	if f.queue != nil {
+		queue = f.queue
+	}
+	return service.NewReadinessService(
+		f.campaignRepo,
+		f.customerRepo,
+		f.messageRepo,
+		service.NewTemplateService(),
+		queue,
+		f.quiet,
+	)
+}
+
+// checkStatus returns the status of the named check
+func checkStatus(t *testing.T, result *service.ReadinessResult, name string) *service.ReadinessCheck {
+	t.Helper()
+	for _, check := range result.Checks {
+		if check.Check == name {
+			return check
+		}
+	}
+	t.Fatalf("check %s missing from result", name)
+	return nil
+}
+
+// TestReadiness_AllPass tests a campaign ready to send
+func TestReadiness_AllPass(t *testing.T) {
+	f := newReadinessFixture()
+
+	result, err := f.service().CheckReadiness(context.Background(), 1, []int{1, 2, 3})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Ready, true)
+	AssertEqual(t, len(result.Checks), 7)
+	for _, check := range result.Checks {
+		if check.Status != service.CheckPass {
+			t.Errorf("Expected %s to pass but got %s: %s", check.Check, check.Status, check.Detail)
+		}
+	}
+
+	// Checks come back in a fixed order regardless of completion order
+	AssertEqual(t, result.Checks[0].Check, service.CheckTemplateValid)
+	AssertEqual(t, result.Checks[5].Check, service.CheckRecentOverlap)
+	AssertEqual(t, result.Checks[6].Check, service.CheckContactWindows)
+}
+
+// TestReadiness_CheckOutcomes tests each check's failing or warning outcome
+func TestReadiness_CheckOutcomes(t *testing.T) {
+	scheduled := time.Date(2025, 1, 6, 22, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		setup  func(f *readinessFixture)
+		ids    []int
+		check  string
+		status string
+		detail string
+		ready  bool
+	}{
+		{
+			name:   "invalid template",
+			setup:  func(f *readinessFixture) { f.campaign.BaseTemplate = "Hi {first_name" },
+			check:  service.CheckTemplateValid,
+			status: service.CheckFail,
+			detail: "unbalanced braces",
+		},
+		{
+			name:   "placeholders only",
+			setup:  func(f *readinessFixture) { f.campaign.BaseTemplate = "{first_name}, {location}!" },
+			check:  service.CheckTemplateLiteralText,
+			status: service.CheckWarn,
+			ready:  true,
+		},
+		{
+			name:   "no audience given",
+			ids:    []int{},
+			check:  service.CheckAudience,
+			status: service.CheckWarn,
+			ready:  true,
+		},
+		{
+			name: "audience missing",
+			setup: func(f *readinessFixture) {
+				f.customerRepo.GetByIDsFunc = func(ctx context.Context, ids []int) ([]*models.Customer, error) {
+					return []*models.Customer{}, nil
+				}
+			},
+			check:  service.CheckAudience,
+			status: service.CheckFail,
+		},
+		{
+			name: "audience partly missing",
+			setup: func(f *readinessFixture) {
+				f.customerRepo.GetByIDsFunc = func(ctx context.Context, ids []int) ([]*models.Customer, error) {
+					return []*models.Customer{NewTestCustomer()}, nil
+				}
+			},
+			check:  service.CheckAudience,
+			status: service.CheckWarn,
+			detail: "1 of 3 customers exist",
+			ready:  true,
+		},
+		{
+			name: "scheduled in quiet hours",
+			setup: func(f *readinessFixture) {
+				f.campaign.ScheduledAt = &scheduled
+				f.quiet = config.QuietHoursConfig{Enabled: true, Start: 21, End: 8, Location: time.UTC}
+			},
+			check:  service.CheckQuietHours,
+			status: service.CheckWarn,
+			detail: "scheduled_at falls within quiet hours",
+			ready:  true,
+		},
+		{
+			name:   "queue down",
+			setup:  func(f *readinessFixture) { f.queue.status = service.StatusDisconnected },
+			check:  service.CheckSenderPipeline,
+			status: service.CheckFail,
+		},
+		{
+			name:   "queue not checked",
+			setup:  func(f *readinessFixture) { f.queue = nil },
+			check:  service.CheckSenderPipeline,
+			status: service.CheckWarn,
+			ready:  true,
+		},
+		{
+			name: "recent overlap",
+			setup: func(f *readinessFixture) {
+				f.messageRepo.CountRecentRecipientsFunc = func(ctx context.Context, ids []int, exclude int, since time.Time) (int, error) {
+					return 2, nil
+				}
+			},
+			check:  service.CheckRecentOverlap,
+			status: service.CheckWarn,
+			detail: "2 of 3 customers were messaged by another campaign in the last 24h",
+			ready:  true,
+		},
+		{
+			name: "scheduled outside contact windows",
+			setup: func(f *readinessFixture) {
+				f.campaign.ScheduledAt = &scheduled
+				f.customerRepo.GetByIDsFunc = func(ctx context.Context, ids []int) ([]*models.Customer, error) {
+					evenings := NewTestCustomerWithID(1)
+					evenings.ContactWindowStart, evenings.ContactWindowEnd = StringPtr("18:00"), StringPtr("23:00")
+					office := NewTestCustomerWithID(2)
+					office.ContactWindowStart, office.ContactWindowEnd = StringPtr("08:00"), StringPtr("17:00")
+					return []*models.Customer{evenings, office, NewTestCustomerWithID(3)}, nil
+				}
+			},
+			check:  service.CheckContactWindows,
+			status: service.CheckWarn,
+			detail: "1 of 3 customers are outside their contact window at scheduled_at and will be deferred",
+			ready:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newReadinessFixture()
+			if tt.setup != nil {
+				tt.setup(f)
+			}
+			ids := tt.ids
+			if ids == nil {
+				ids = []int{1, 2, 3}
+			}
+
+			result, err := f.service().CheckReadiness(context.Background(), 1, ids)
+			AssertNoError(t, err)
+
+			check := checkStatus(t, result, tt.check)
+			AssertEqual(t, check.Status, tt.status)
+			AssertContains(t, check.Detail, tt.detail)
+			AssertEqual(t, result.Ready, tt.ready)
+		})
+	}
+}
+
+// TestReadiness_AudienceLoadedOnce tests that the checks sharing the audience load it once
+func TestReadiness_AudienceLoadedOnce(t *testing.T) {
+	f := newReadinessFixture()
+
+	result, err := f.service().CheckReadiness(context.Background(), 1, []int{1, 2, 3})
+	AssertNoError(t, err)
+	AssertEqual(t, checkStatus(t, result, service.CheckAudience).Status, service.CheckPass)
+	AssertEqual(t, checkStatus(t, result, service.CheckContactWindows).Status, service.CheckPass)
+	AssertEqual(t, f.customerRepo.Calls["GetByIDs"], 1)
+}
+
+// TestReadiness_Timeout tests that a slow check is reported instead of holding up the rest
+func TestReadiness_Timeout(t *testing.T) {
+	f := newReadinessFixture()
+	f.queue.delay = 200 * time.Millisecond
+
+	svc := f.service()
+	svc.SetTimeout(20 * time.Millisecond)
+
+	start := time.Now()
+	result, err := svc.CheckReadiness(context.Background(), 1, []int{1})
+	AssertNoError(t, err)
+
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Expected result within the timeout but took %v", elapsed)
+	}
+	check := checkStatus(t, result, service.CheckSenderPipeline)
+	AssertEqual(t, check.Status, service.CheckWarn)
+	AssertContains(t, check.Detail, "did not finish")
+	AssertEqual(t, checkStatus(t, result, service.CheckTemplateValid).Status, service.CheckPass)
+}
+
+// TestReadiness_PendingApprovalUsesPlan tests that the stored send plan is the default audience
+func TestReadiness_PendingApprovalUsesPlan(t *testing.T) {
+	f := newReadinessFixture()
+	f.campaign.Status = models.CampaignStatusPendingApproval
+	f.campaignRepo.GetSendPlanFunc = func(ctx context.Context, id int) (*models.SendPlan, error) {
+		return &models.SendPlan{CustomerIDs: []int{4, 5}}, nil
+	}
+
+	result, err := f.service().CheckReadiness(context.Background(), 1, nil)
+	AssertNoError(t, err)
+	AssertEqual(t, checkStatus(t, result, service.CheckAudience).Detail, "2 customers")
+
+	// Drafts have no stored plan
+	f = newReadinessFixture()
+	_, err = f.service().CheckReadiness(context.Background(), 1, nil)
+	AssertNoError(t, err)
+	AssertEqual(t, f.campaignRepo.Calls["GetSendPlan"], 0)
+}
+
+// TestQuietHours_Contains tests windows within a day and across midnight
+func TestQuietHours_Contains(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2025, 1, 6, hour, 0, 0, 0, time.UTC) }
+
+	overnight := config.QuietHoursConfig{Enabled: true, Start: 21, End: 8, Location: time.UTC}
+	AssertEqual(t, overnight.Contains(at(22)), true)
+	AssertEqual(t, overnight.Contains(at(3)), true)
+	AssertEqual(t, overnight.Contains(at(8)), false)
+	AssertEqual(t, overnight.Contains(at(12)), false)
+
+	midday := config.QuietHoursConfig{Enabled: true, Start: 12, End: 14, Location: time.UTC}
+	AssertEqual(t, midday.Contains(at(13)), true)
+	AssertEqual(t, midday.Contains(at(14)), false)
+
+	// The window is read in its own time zone
+	nairobi := time.FixedZone("EAT", 3*60*60)
+	local := config.QuietHoursConfig{Enabled: true, Start: 21, End: 8, Location: nairobi}
+	AssertEqual(t, local.Contains(at(19)), true)
+
+	AssertEqual(t, config.QuietHoursConfig{}.Contains(at(22)), false)
+}
+
+// TestLoadQuietHours tests QUIET_HOURS parsing
+func TestLoadQuietHours(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+	t.Setenv("QUIET_HOURS", "21-8")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Quiet.Enabled, true)
+	AssertEqual(t, cfg.Quiet.Start, 21)
+	AssertEqual(t, cfg.Quiet.End, 8)
+
+	for _, invalid := range []string{"21", "25-8", "8-8", "night-day"} {
+		t.Setenv("QUIET_HOURS", invalid)
+		if _, err := config.Load(); err == nil {
+			t.Errorf("Expected error for QUIET_HOURS=%q", invalid)
+		}
+	}
+}
+
+// TestCountRecentRecipients_Query tests the overlap query excludes the campaign itself
+func TestCountRecentRecipients_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	since := time.Now().Add(-service.RecentOverlapWindow)
+	mock.ExpectQuery(`SELECT COUNT\(DISTINCT customer_id\) FROM outbound_messages WHERE customer_id = ANY\(\$1\) AND campaign_id <> \$2`).
+		WithArgs("{1,2,3}", 7, since).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	count, err := repository.NewMessageRepository(db).CountRecentRecipients(context.Background(), []int{1, 2, 3}, 7, since)
+	AssertNoError(t, err)
+	AssertEqual(t, count, 2)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestReadinessEndpoint tests GET /campaigns/{id}/readiness end to end with mocks
+func TestReadinessEndpoint(t *testing.T) {
+	f := newReadinessFixture()
+	var requested []int
+	f.customerRepo.GetByIDsFunc = func(ctx context.Context, ids []int) ([]*models.Customer, error) {
+		requested = ids
+		return []*models.Customer{}, nil
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/readiness", handler.NewReadinessHandler(f.service()).Readiness).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/campaigns/1/readiness?customer_ids=1,2&customer_ids=3", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	var result service.ReadinessResult
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, result.Ready, false)
+	AssertEqual(t, fmt.Sprint(requested), "[1 2 3]")
+
+	req = httptest.NewRequest("GET", "/campaigns/1/readiness?customer_ids=1,x", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+
+	f.campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return nil, fmt.Errorf("campaign not found")
+	}
+	req = httptest.NewRequest("GET", "/campaigns/9/readiness", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusNotFound)
+}
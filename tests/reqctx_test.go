@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/middleware"
+	"smsleopard/internal/reqctx"
+)
+
+// TestReqctx_Accessors tests that each value set is read back, and reported missing otherwise
+func TestReqctx_Accessors(t *testing.T) {
+	empty := context.Background()
+
+	_, ok := reqctx.RequestID(empty)
+	AssertEqual(t, ok, false)
+	id, ok := reqctx.RequestID(reqctx.WithRequestID(empty, "req-1"))
+	AssertEqual(t, ok, true)
+	AssertEqual(t, id, "req-1")
+	_, ok = reqctx.RequestID(reqctx.WithRequestID(empty, ""))
+	AssertEqual(t, ok, false)
+
+	_, ok = reqctx.IdentityFrom(empty)
+	AssertEqual(t, ok, false)
+	identity, ok := reqctx.IdentityFrom(reqctx.WithIdentity(empty, &reqctx.Identity{UserID: "amina", Role: config.RoleAdmin}))
+	AssertEqual(t, ok, true)
+	AssertEqual(t, identity.UserID, "amina")
+	_, ok = reqctx.IdentityFrom(reqctx.WithIdentity(empty, nil))
+	AssertEqual(t, ok, false)
+
+	_, ok = reqctx.OrgID(empty)
+	AssertEqual(t, ok, false)
+	orgID, ok := reqctx.OrgID(reqctx.WithOrgID(empty, 42))
+	AssertEqual(t, ok, true)
+	AssertEqual(t, orgID, 42)
+
+	_, ok = reqctx.Locale(empty)
+	AssertEqual(t, ok, false)
+	locale, ok := reqctx.Locale(reqctx.WithLocale(empty, "sw-KE"))
+	AssertEqual(t, ok, true)
+	AssertEqual(t, locale, "sw-KE")
+}
+
+// TestReqctx_KeysDoNotCollide tests that values set under a plain string key of the same name
+// are not read back as request-scoped values
+func TestReqctx_KeysDoNotCollide(t *testing.T) {
+	type key string
+	ctx := context.WithValue(context.Background(), key("request_id"), 7)
+	ctx = context.WithValue(ctx, key("locale"), "sw-KE")
+
+	_, ok := reqctx.RequestID(ctx)
+	AssertEqual(t, ok, false)
+	_, ok = reqctx.Locale(ctx)
+	AssertEqual(t, ok, false)
+}
+
+// TestReqctx_RequireLogsMissing tests that a missing required value is logged with the
+// request ID rather than panicking
+func TestReqctx_RequireLogsMissing(t *testing.T) {
+	logs := captureLog(t)
+	ctx := reqctx.WithRequestID(context.Background(), "req-7")
+
+	orgID, ok := reqctx.OrgID(ctx)
+	AssertEqual(t, reqctx.Require(ctx, "org ID", ok), false)
+	AssertEqual(t, orgID, 0)
+	AssertContains(t, logs.String(), "Request req-7 has no org ID in its context")
+
+	logs.Reset()
+	_, ok = reqctx.RequestID(ctx)
+	AssertEqual(t, reqctx.Require(ctx, "request ID", ok), true)
+	AssertEqual(t, logs.String(), "")
+}
+
+// TestReqctx_ConcurrentReads tests that one context is safe to read from many goroutines
+func TestReqctx_ConcurrentReads(t *testing.T) {
+	ctx := reqctx.WithRequestID(context.Background(), "req-1")
+	ctx = reqctx.WithIdentity(ctx, &reqctx.Identity{UserID: "amina"})
+	ctx = reqctx.WithOrgID(ctx, 3)
+	ctx = reqctx.WithLocale(ctx, "en-KE")
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, _ := reqctx.RequestID(ctx)
+			identity, _ := reqctx.IdentityFrom(ctx)
+			orgID, _ := reqctx.OrgID(ctx)
+			locale, _ := reqctx.Locale(ctx)
+			if id != "req-1" || identity.UserID != "amina" || orgID != 3 || locale != "en-KE" || reqctx.Prefix(ctx) != "[req-1] " {
+				errs <- "unexpected value read concurrently"
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+// TestRequestIDMiddleware tests that a valid caller ID is kept, and a missing or invalid one
+// replaced, in both the context and the response
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = reqctx.RequestID(r.Context())
+	}))
+
+	serve := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/campaigns", nil)
+		if id != "" {
+			req.Header.Set(middleware.RequestIDHeader, id)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("abc-123")
+	AssertEqual(t, seen, "abc-123")
+	AssertEqual(t, rr.Header().Get(middleware.RequestIDHeader), "abc-123")
+
+	rr = serve("")
+	AssertEqual(t, len(seen), 32)
+	AssertEqual(t, rr.Header().Get(middleware.RequestIDHeader), seen)
+
+	rr = serve("bad id\nwith newline" + strings.Repeat("x", 200))
+	AssertEqual(t, len(seen), 32)
+	AssertEqual(t, rr.Header().Get(middleware.RequestIDHeader), seen)
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/handler"
+
+	"github.com/gorilla/mux"
+)
+
+// TestParseIDParam tests route ID parsing and its single error wording
+func TestParseIDParam(t *testing.T) {
+	testCases := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "positive", value: "42", want: 42},
+		{name: "zero", value: "0", wantErr: true},
+		{name: "negative", value: "-1", wantErr: true},
+		{name: "non-numeric", value: "abc", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+		{name: "overflow", value: "99999999999999999999", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"job_id": tc.value})
+			id, err := handler.ParseIDParam(req, "job_id")
+			if tc.wantErr {
+				AssertError(t, err, "job_id must be a positive integer")
+				return
+			}
+			AssertNoError(t, err)
+			AssertEqual(t, id, tc.want)
+		})
+	}
+}
+
+// TestDecodeJSONBody tests body decoding and the error response written for each failure
+func TestDecodeJSONBody(t *testing.T) {
+	type body struct {
+		Name string `json:"name"`
+	}
+
+	testCases := []struct {
+		name        string
+		body        string
+		optional    bool
+		wantErr     bool
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+		wantName    string
+	}{
+		{name: "valid", body: `{"name": "promo"}`, wantName: "promo"},
+		{name: "empty", body: "", wantErr: true, wantStatus: http.StatusBadRequest, wantCode: "INVALID_JSON", wantMessage: "Request body is empty"},
+		{name: "malformed", body: `{"name": `, wantErr: true, wantStatus: http.StatusBadRequest, wantCode: "INVALID_JSON", wantMessage: "Invalid JSON format"},
+		{name: "wrong type", body: `{"name": 7}`, wantErr: true, wantStatus: http.StatusBadRequest, wantCode: "INVALID_JSON", wantMessage: "Invalid JSON format"},
+		{name: "too large", body: `{"name": "` + strings.Repeat("x", 64) + `"}`, wantErr: true, wantStatus: http.StatusRequestEntityTooLarge, wantCode: "REQUEST_TOO_LARGE", wantMessage: "Request body is larger than 32 bytes"},
+		{name: "optional empty", body: "", optional: true},
+		{name: "optional malformed", body: "{", optional: true, wantErr: true, wantStatus: http.StatusBadRequest, wantCode: "INVALID_JSON", wantMessage: "Invalid JSON format"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			var dst body
+			var err error
+			if tc.optional {
+				err = handler.DecodeOptionalJSONBody(rr, req, &dst, 32)
+			} else {
+				err = handler.DecodeJSONBody(rr, req, &dst, 32)
+			}
+
+			if !tc.wantErr {
+				AssertNoError(t, err)
+				AssertEqual(t, dst.Name, tc.wantName)
+				// Nothing is written, so the handler can respond
+				AssertEqual(t, rr.Body.Len(), 0)
+				return
+			}
+
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if tc.wantMessage == "Request body is empty" && !errors.Is(err, handler.ErrEmptyBody) {
+				t.Errorf("Expected ErrEmptyBody, got %v", err)
+			}
+			AssertStatusCode(t, rr, tc.wantStatus)
+			var resp handler.ErrorResponse
+			ParseJSONResponse(t, rr, &resp)
+			AssertEqual(t, resp.Error.Code, tc.wantCode)
+			AssertEqual(t, resp.Error.Message, tc.wantMessage)
+		})
+	}
+}
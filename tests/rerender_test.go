@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// setupReRenderTest creates a campaign service whose campaign template can be changed by the test
+func setupReRenderTest(template *string, status models.CampaignStatus) (*service.CampaignService, *MockMessageRepository) {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		campaign := NewTestCampaignWithStatus(status)
+		campaign.BaseTemplate = *template
+		return campaign, nil
+	}
+	messageRepo := NewMockMessageRepository()
+
+	svc := service.NewCampaignService(
+		campaignRepo,
+		NewMockCustomerRepository(),
+		messageRepo,
+		service.NewTemplateService(),
+		NewMockPublisher(),
+		nil,
+		config.ApprovalConfig{},
+	)
+	return svc, messageRepo
+}
+
+// TestReRender_DryRunUsesCurrentTemplate tests that a template fixed after send is used for the sample
+func TestReRender_DryRunUsesCurrentTemplate(t *testing.T) {
+	template := "Hi {first_nme}"
+	svc, messageRepo := setupReRenderTest(&template, models.CampaignStatusSending)
+
+	// Template is fixed between send and processing
+	template = "Hi {first_name}"
+
+	result, err := svc.ReRenderCampaign(context.Background(), 1, &service.ReRenderRequest{DryRun: true})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.DryRun, true)
+	AssertEqual(t, len(result.Sample), 3)
+	AssertEqual(t, result.Sample[0].RenderedContent, "Hi John")
+	AssertEqual(t, messageRepo.Calls["ClearPendingRenderedContent"], 0)
+}
+
+// TestReRender_ClearsPendingContent tests that pending messages are cleared for the worker
+func TestReRender_ClearsPendingContent(t *testing.T) {
+	template := "Hi {first_name}"
+	svc, messageRepo := setupReRenderTest(&template, models.CampaignStatusSending)
+	messageRepo.ClearPendingRenderedContentFunc = func(ctx context.Context, campaignID int) (int, error) {
+		return 42, nil
+	}
+
+	result, err := svc.ReRenderCampaign(context.Background(), 1, &service.ReRenderRequest{})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.MessagesCleared, 42)
+	AssertEqual(t, len(result.Sample), 0)
+	AssertEqual(t, messageRepo.Calls["GetPendingByCampaignID"], 0)
+}
+
+// TestReRender_RefusesTerminalCampaign tests that finished campaigns are not re-rendered
+func TestReRender_RefusesTerminalCampaign(t *testing.T) {
+	template := "Hi {first_name}"
+	for _, status := range []models.CampaignStatus{models.CampaignStatusSent, models.CampaignStatusFailed} {
+		svc, messageRepo := setupReRenderTest(&template, status)
+
+		_, err := svc.ReRenderCampaign(context.Background(), 1, &service.ReRenderRequest{})
+		if _, ok := err.(*service.BusinessLogicError); !ok {
+			t.Fatalf("Expected BusinessLogicError for %s but got %v", status, err)
+		}
+		AssertEqual(t, messageRepo.Calls["ClearPendingRenderedContent"], 0)
+	}
+}
+
+// TestClearPendingRenderedContent tests the bulk update only targets pending messages of the campaign
+func TestClearPendingRenderedContent(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE outbound_messages SET rendered_content = NULL(.+)WHERE campaign_id = \\$1 AND status = 'pending'").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+
+	cleared, err := repository.NewMessageRepository(db).ClearPendingRenderedContent(context.Background(), 7)
+	AssertNoError(t, err)
+	AssertEqual(t, cleared, 5)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestReRenderEndpoint tests POST /campaigns/{id}/re-render with and without a body
+func TestReRenderEndpoint(t *testing.T) {
+	template := "Hi {first_name}"
+	svc, messageRepo := setupReRenderTest(&template, models.CampaignStatusSending)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/re-render", handler.NewCampaignHandler(svc).ReRender).Methods("POST")
+
+	// Empty body clears pending messages
+	req := httptest.NewRequest("POST", "/campaigns/1/re-render", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertEqual(t, messageRepo.Calls["ClearPendingRenderedContent"], 1)
+
+	// Dry run returns a sample
+	req = NewJSONRequest(t, "POST", "/campaigns/1/re-render", map[string]interface{}{"dry_run": true})
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var result service.ReRenderResult
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, len(result.Sample), 3)
+	AssertEqual(t, messageRepo.Calls["ClearPendingRenderedContent"], 1)
+}
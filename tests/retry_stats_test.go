@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestCampaignStats_RetryDistribution tests that sent and failed messages are counted by retry count
+func TestCampaignStats_RetryDistribution(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaign := NewTestCampaignWithStatus(models.CampaignStatusSent)
+	// 5 sent first time, 2 sent and 1 failed after one retry, 2 failed after exhausting retries
+	mock.ExpectQuery(CampaignWithStatsQuery).
+		WithArgs(campaign.ID).
+		WillReturnRows(NewCampaignWithStatsRows(campaign, 10, 0, 7, 3, 0, 0, 0, nil, 0, []byte(`[[0,5,0],[1,2,1],[3,0,2]]`)))
+
+	result, err := repository.NewCampaignRepository(db).GetWithStats(context.Background(), campaign.ID)
+	AssertNoError(t, err)
+
+	distribution := result.Stats.RetryDistribution
+	AssertNotNil(t, distribution)
+	AssertEqual(t, len(distribution.Sent), 2)
+	AssertEqual(t, distribution.Sent[0], 5)
+	AssertEqual(t, distribution.Sent[1], 2)
+	AssertEqual(t, len(distribution.Failed), 2)
+	AssertEqual(t, distribution.Failed[1], 1)
+	AssertEqual(t, distribution.Failed[3], 2)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetRetryEffectiveness_Query tests the per-channel sent and retried counts over a period
+func TestGetRetryEffectiveness_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`COUNT\(\*\) FILTER \(WHERE m.retry_count > 0\) as sent_after_retry (.+) WHERE m.status = 'sent' AND NOT m.simulated AND m.updated_at >= \$1 AND m.updated_at < \$2 GROUP BY c.channel`).
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"channel", "sent", "sent_after_retry"}).
+			AddRow(models.ChannelSMS, 200, 30).
+			AddRow(models.ChannelWhatsApp, 50, 0))
+
+	stats, err := repository.NewMessageRepository(db).GetRetryEffectiveness(context.Background(), from, to)
+	AssertNoError(t, err)
+
+	AssertEqual(t, len(stats), 2)
+	AssertEqual(t, stats[0].Channel, models.ChannelSMS)
+	AssertEqual(t, stats[0].Sent, 200)
+	AssertEqual(t, stats[0].SentAfterRetry, 30)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRetryEffectiveness_Percentages tests the retried share per channel and the default period
+func TestRetryEffectiveness_Percentages(t *testing.T) {
+	messageRepo := NewMockMessageRepository()
+	var gotFrom, gotTo time.Time
+	messageRepo.GetRetryEffectivenessFunc = func(ctx context.Context, from, to time.Time) ([]*models.ChannelRetryEffectiveness, error) {
+		gotFrom, gotTo = from, to
+		return []*models.ChannelRetryEffectiveness{
+			{Channel: models.ChannelSMS, Sent: 300, SentAfterRetry: 41},
+			{Channel: models.ChannelWhatsApp, Sent: 0, SentAfterRetry: 0},
+		}, nil
+	}
+
+	report, err := service.NewStatsService(messageRepo).RetryEffectiveness(context.Background(), nil, nil)
+	AssertNoError(t, err)
+
+	AssertEqual(t, report.Channels[0].RetriedPercent, 13.67)
+	AssertEqual(t, report.Channels[1].RetriedPercent, 0.0)
+	AssertEqual(t, gotTo.Sub(gotFrom), service.RetryEffectivenessWindow)
+	if age := time.Since(gotTo); age < 0 || age > time.Minute {
+		t.Errorf("Expected the period to end now, got %v ago", age)
+	}
+
+	to := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	_, err = service.NewStatsService(messageRepo).RetryEffectiveness(context.Background(), &to, &to)
+	AssertError(t, err, "validation error: from must be before to")
+}
+
+// TestRetryEffectiveness_Endpoint tests the report and parameter validation over HTTP
+func TestRetryEffectiveness_Endpoint(t *testing.T) {
+	messageRepo := NewMockMessageRepository()
+	messageRepo.GetRetryEffectivenessFunc = func(ctx context.Context, from, to time.Time) ([]*models.ChannelRetryEffectiveness, error) {
+		return []*models.ChannelRetryEffectiveness{{Channel: models.ChannelSMS, Sent: 4, SentAfterRetry: 1}}, nil
+	}
+	h := handler.NewStatsHandler(service.NewStatsService(messageRepo))
+
+	resp := httptest.NewRecorder()
+	h.RetryEffectiveness(resp, httptest.NewRequest("GET", "/stats/retry-effectiveness?from=2026-09-01T00:00:00Z&to=2026-10-01T00:00:00Z", nil))
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var report service.RetryEffectivenessReport
+	ParseJSONResponse(t, resp, &report)
+	AssertEqual(t, report.From, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+	AssertEqual(t, report.Channels[0].RetriedPercent, 25.0)
+
+	for _, query := range []string{"from=yesterday", "to=2026-10-01", "from=2026-10-01T00:00:00Z&to=2026-09-01T00:00:00Z"} {
+		resp := httptest.NewRecorder()
+		h.RetryEffectiveness(resp, httptest.NewRequest("GET", "/stats/retry-effectiveness?"+query, nil))
+		AssertStatusCode(t, resp, http.StatusBadRequest)
+	}
+}
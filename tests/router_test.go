@@ -0,0 +1,330 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/graph"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/maintenance"
+	"smsleopard/internal/middleware"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	routerMemberKey = "member-key"
+	routerAdminKey  = "admin-key"
+	// routerPanicID is the campaign whose lookup panics
+	routerPanicID = 13
+)
+
+// routerFixture is the production router over mock repositories
+type routerFixture struct {
+	router       *mux.Router
+	campaignRepo *MockCampaignRepository
+	readOnly     *maintenance.ReadOnly
+	apiKeys      *service.APIKeyService
+	apiKeyRepo   *MockAPIKeyRepository
+	settingsRepo *MockSettingsRepository
+}
+
+func newRouterFixture(t *testing.T) *routerFixture {
+	t.Helper()
+
+	db, _ := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		if id == routerPanicID {
+			panic("campaign lookup exploded")
+		}
+		campaign := NewTestCampaign()
+		campaign.ID = id
+		return campaign, nil
+	}
+	campaignRepo.GetWithStatsFunc = func(ctx context.Context, id int) (*models.CampaignWithStats, error) {
+		if id == routerPanicID {
+			panic("campaign lookup exploded")
+		}
+		return &models.CampaignWithStats{Campaign: *NewTestCampaign()}, nil
+	}
+	customerRepo := NewMockCustomerRepository()
+	messageRepo := NewMockMessageRepository()
+
+	// The events endpoint long-polls until there is an event, so campaign 1 has one
+	eventRepo := NewMockCampaignEventRepository(&models.CampaignEvent{CampaignID: 1, Type: models.CampaignEventQueued})
+
+	// Link redirects are walked with the token "1"
+	linkRepo := NewMockLinkRepository()
+	linkRepo.Links = []*models.TrackedLink{{ID: 1, MessageID: 1, Token: "1", URL: "https://example.com"}}
+
+	templateService := service.NewTemplateService()
+	healthService := service.NewHealthService(db, nil, "", "test")
+	campaignService, _ := NewMockCampaignService(t, campaignRepo, messageRepo)
+	admission := service.NewSendAdmission(func() (int, error) { return 0, nil }, messageRepo, config.BackpressureConfig{})
+	exportStore, err := service.NewDirExportStore(t.TempDir())
+	AssertNoError(t, err)
+	readOnly := maintenance.NewReadOnly(false)
+	apiKeyRepo := NewMockAPIKeyRepository()
+	apiKeys := service.NewAPIKeyService(apiKeyRepo)
+	settingsRepo := NewMockSettingsRepository()
+	sendingSwitch := service.NewSendingSwitch(settingsRepo)
+	campaignService.SetSendingSwitch(sendingSwitch)
+
+	deps := &handler.RouterDeps{
+		Health:     handler.NewHealthHandler(healthService),
+		Campaign:   handler.NewCampaignHandler(campaignService),
+		Preview:    handler.NewPreviewHandler(campaignService),
+		Template:   handler.NewTemplateHandler(templateService),
+		Webhook:    handler.NewWebhookHandler(),
+		Simulation: handler.NewSimulationHandler(service.NewSimulationService(campaignRepo, customerRepo, messageRepo, config.SendingConfig{RateLimitPerSecond: 10}, config.QuietHoursConfig{})),
+		Readiness:  handler.NewReadinessHandler(service.NewReadinessService(campaignRepo, customerRepo, messageRepo, templateService, healthService, config.QuietHoursConfig{})),
+		Customer:   handler.NewCustomerHandler(service.NewCustomerService(customerRepo, config.LimitsConfig{})),
+		Admin: handler.NewAdminHandler(
+			service.NewAttentionService(campaignRepo, nil),
+			service.NewProcessingErrorService(NewMockProcessingErrorRepository(), ""),
+		),
+		Export:          handler.NewExportHandler(service.NewExportService(campaignRepo, config.SendingConfig{})),
+		ExportJob:       handler.NewExportJobHandler(service.NewExportJobService(NewMockExportJobRepository(), campaignRepo, messageRepo, exportStore, config.ExportConfig{})),
+		CampaignEvent:   handler.NewCampaignEventHandler(service.NewCampaignEventService(eventRepo, campaignRepo, service.NewCampaignEventHub())),
+		Stats:           handler.NewStatsHandler(service.NewStatsService(messageRepo)),
+		ReadOnly:        handler.NewReadOnlyHandler(readOnly),
+		QueueStatus:     handler.NewQueueStatusHandler(admission),
+		Link:            handler.NewLinkHandler(service.NewLinkService(linkRepo)),
+		MessageReassign: handler.NewMessageReassignHandler(service.NewMessageReassigner(campaignRepo, NewMockMessageReassignmentRepository(), 10)),
+		GraphQL:         handler.NewGraphQLHandler(graph.NewExecutor(campaignRepo, customerRepo, messageRepo)),
+		Worker:          handler.NewWorkerHandler(service.NewWorkerActivityService(NewMockWorkerRepository())),
+		APIKey:          handler.NewAPIKeyHandler(apiKeys),
+		Sending:         handler.NewSendingHandler(sendingSwitch),
+		ReadOnlyMode:    readOnly,
+		IssuedKeys:      apiKeys,
+	}
+
+	cfg := &config.Config{
+		Auth: config.AuthConfig{APIKeys: []config.APIKey{
+			{Key: routerMemberKey, UserID: "wanjiru", Role: config.RoleMember},
+			{Key: routerAdminKey, UserID: "amina", Role: config.RoleAdmin},
+		}},
+		Admin: config.AdminConfig{APIKey: routerAdminKey},
+	}
+
+	return &routerFixture{
+		router:       handler.BuildRouter(deps, cfg),
+		campaignRepo: campaignRepo,
+		readOnly:     readOnly,
+		apiKeys:      apiKeys,
+		apiKeyRepo:   apiKeyRepo,
+		settingsRepo: settingsRepo,
+	}
+}
+
+// serve sends a request through the router with the given API and admin keys (empty for none)
+func (f *routerFixture) serve(method, path, contentType, body, apiKey, adminKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if apiKey != "" {
+		req.Header.Set(middleware.APIKeyHeader, apiKey)
+	}
+	if adminKey != "" {
+		req.Header.Set(middleware.AdminKeyHeader, adminKey)
+	}
+	rr := httptest.NewRecorder()
+	f.router.ServeHTTP(rr, req)
+	return rr
+}
+
+// routerEndpoint is one method of a registered route, with a path that matches it
+type routerEndpoint struct {
+	Method   string
+	Template string
+	Path     string
+}
+
+var routeVariable = regexp.MustCompile(`\{[a-z_]+(:[^}]*)?\}`)
+
+// routerEndpoints lists every method of every route the router registers
+func routerEndpoints(t *testing.T, router *mux.Router) []routerEndpoint {
+	t.Helper()
+	endpoints := []routerEndpoint{}
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // The authenticated subrouter's prefix, not an endpoint
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			endpoints = append(endpoints, routerEndpoint{
+				Method:   method,
+				Template: template,
+				Path:     routeVariable.ReplaceAllString(template, "1"),
+			})
+		}
+		return nil
+	})
+	AssertNoError(t, err)
+	return endpoints
+}
+
+// routerPublic lists the routes served without an API key
+var routerPublic = map[string]bool{
+	"/health":                              true,
+	"/metrics":                             true,
+	service.LinkRedirectPrefix + "{token}": true,
+}
+
+// routerAdminOnly lists the endpoints that also need the admin key
+var routerAdminOnly = map[string]bool{
+	"POST /campaigns/{id:[0-9]+}/approve": true,
+	"POST /campaigns/{id:[0-9]+}/reject":  true,
+	"POST /customers/{id:[0-9]+}/block":   true,
+	"DELETE /customers/{id:[0-9]+}/block": true,
+	"POST /admin/read-only":               true,
+	"POST /admin/messages/reassign":       true,
+	"POST /admin/api-keys":                true,
+	"GET /admin/api-keys":                 true,
+	"DELETE /admin/api-keys/{id:[0-9]+}":  true,
+	"POST /admin/sending/enable":          true,
+	"POST /admin/sending/disable":         true,
+}
+
+// TestRouter_EveryEndpointIsRouted tests that every registered endpoint is reached through
+// the full middleware stack: with both keys the handler answers rather than the router
+func TestRouter_EveryEndpointIsRouted(t *testing.T) {
+	f := newRouterFixture(t)
+	endpoints := routerEndpoints(t, f.router)
+	if len(endpoints) < 50 {
+		t.Fatalf("Expected every API endpoint, walked only %d", len(endpoints))
+	}
+
+	for _, e := range endpoints {
+		t.Run(e.Method+" "+e.Template, func(t *testing.T) {
+			rr := f.serve(e.Method, e.Path, "application/json", "{}", routerAdminKey, routerAdminKey)
+			if rr.Code == http.StatusMethodNotAllowed || rr.Body.String() == "404 page not found\n" {
+				t.Fatalf("Expected the handler to answer, router returned %d", rr.Code)
+			}
+			if rr.Code == http.StatusUnauthorized || rr.Code == http.StatusUnsupportedMediaType {
+				t.Fatalf("Expected the middleware to let the request through, got %d: %s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+// TestRouter_MissingAPIKey tests that every endpoint but the public ones refuses a request
+// without a valid API key, before its handler runs
+func TestRouter_MissingAPIKey(t *testing.T) {
+	f := newRouterFixture(t)
+
+	for _, e := range routerEndpoints(t, f.router) {
+		if routerPublic[e.Template] {
+			continue
+		}
+		t.Run(e.Method+" "+e.Template, func(t *testing.T) {
+			for _, key := range []string{"", "wrong-key"} {
+				rr := f.serve(e.Method, e.Path, "application/json", "{}", key, routerAdminKey)
+				AssertStatusCode(t, rr, http.StatusUnauthorized)
+				AssertJSONContentType(t, rr)
+				AssertContains(t, rr.Body.String(), `"code":"UNAUTHORIZED"`)
+			}
+		})
+	}
+	AssertEqual(t, f.campaignRepo.Calls["GetByID"], 0)
+
+	// Public routes answer without one
+	AssertStatusCode(t, f.serve("GET", "/metrics", "", "", "", ""), http.StatusOK)
+	rr := f.serve("GET", "/health", "", "", "", "")
+	if rr.Code == http.StatusUnauthorized {
+		t.Fatal("Expected /health to be public")
+	}
+}
+
+// TestRouter_AdminKeyRequired tests that admin endpoints refuse a member's API key without
+// the admin key, and that the set of admin endpoints is the expected one
+func TestRouter_AdminKeyRequired(t *testing.T) {
+	f := newRouterFixture(t)
+
+	admin := 0
+	for _, e := range routerEndpoints(t, f.router) {
+		if routerPublic[e.Template] {
+			continue
+		}
+		rr := f.serve(e.Method, e.Path, "application/json", "{}", routerMemberKey, "")
+		isAdmin := rr.Code == http.StatusUnauthorized
+		if isAdmin != routerAdminOnly[e.Method+" "+e.Template] {
+			t.Errorf("%s %s: admin only = %v, expected %v", e.Method, e.Template, isAdmin, !isAdmin)
+		}
+		if isAdmin {
+			admin++
+			AssertContains(t, rr.Body.String(), "Valid admin key required")
+		}
+	}
+	AssertEqual(t, admin, len(routerAdminOnly))
+}
+
+// TestRouter_PanicRecovery tests that a panicking service is turned into the 500 error
+// envelope, and the server keeps answering
+func TestRouter_PanicRecovery(t *testing.T) {
+	f := newRouterFixture(t)
+
+	rr := f.serve("GET", "/campaigns/13", "", "", routerMemberKey, "")
+	AssertStatusCode(t, rr, http.StatusInternalServerError)
+	AssertJSONContentType(t, rr)
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	ParseJSONResponse(t, rr, &body)
+	AssertEqual(t, body.Error.Code, "INTERNAL_ERROR")
+	AssertEqual(t, body.Error.Message, "Internal server error")
+	if strings.Contains(rr.Body.String(), "exploded") {
+		t.Error("Expected the panic value to stay out of the response")
+	}
+
+	AssertStatusCode(t, f.serve("GET", "/campaigns/1", "", "", routerMemberKey, ""), http.StatusOK)
+}
+
+// TestRouter_BodyLimits tests that over-limit and non-JSON bodies are refused by the stack
+func TestRouter_BodyLimits(t *testing.T) {
+	f := newRouterFixture(t)
+
+	large := `{"name": "` + strings.Repeat("a", int(handler.MaxJSONBodyBytes)) + `"}`
+	rr := f.serve("POST", "/campaigns", "application/json", large, routerMemberKey, "")
+	AssertStatusCode(t, rr, http.StatusRequestEntityTooLarge)
+	AssertContains(t, rr.Body.String(), `"code":"REQUEST_TOO_LARGE"`)
+	AssertEqual(t, f.campaignRepo.Calls["Create"], 0)
+
+	rr = f.serve("POST", "/campaigns", "text/plain", `{"name": "x"}`, routerMemberKey, "")
+	AssertStatusCode(t, rr, http.StatusUnsupportedMediaType)
+	AssertContains(t, rr.Body.String(), `"code":"UNSUPPORTED_MEDIA_TYPE"`)
+}
+
+// TestRouter_ReadOnly tests that writes are refused in read-only mode while reads, health
+// and the read-only toggle keep working
+func TestRouter_ReadOnly(t *testing.T) {
+	f := newRouterFixture(t)
+	f.readOnly.Set(true)
+
+	rr := f.serve("POST", "/campaigns", "application/json", `{"name": "x"}`, routerMemberKey, "")
+	AssertStatusCode(t, rr, http.StatusServiceUnavailable)
+	AssertContains(t, rr.Body.String(), `"code":"SERVICE_READ_ONLY"`)
+
+	AssertStatusCode(t, f.serve("GET", "/campaigns/1", "", "", routerMemberKey, ""), http.StatusOK)
+	rr = f.serve("POST", "/admin/read-only", "application/json", `{"read_only": false}`, routerAdminKey, routerAdminKey)
+	AssertStatusCode(t, rr, http.StatusOK)
+	AssertEqual(t, f.readOnly.Enabled(), false)
+}
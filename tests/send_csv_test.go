@@ -0,0 +1,252 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// knownPhones are the customers that exist for the CSV send tests
+var knownPhones = map[string]int{"+254700000001": 1, "+254700000002": 2}
+
+// setupSendCSVTest creates a campaign service whose customer repository knows knownPhones
+func setupSendCSVTest(t *testing.T) (*service.CampaignService, *MockCustomerRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	customerRepo := NewMockCustomerRepository()
+	customerRepo.GetByPhonesFunc = func(ctx context.Context, phones []string) ([]*models.Customer, error) {
+		customers := []*models.Customer{}
+		for _, phone := range phones {
+			if id, ok := knownPhones[phone]; ok {
+				customer := NewTestCustomerWithID(id)
+				customer.Phone = phone
+				customers = append(customers, customer)
+			}
+		}
+		return customers, nil
+	}
+
+	svc := service.NewCampaignService(
+		NewMockCampaignRepository(),
+		customerRepo,
+		NewMockMessageRepository(),
+		service.NewTemplateService(),
+		NewMockPublisher(),
+		db,
+		config.ApprovalConfig{},
+	)
+	return svc, customerRepo, mock
+}
+
+// mixedPhonesCSV has two known phones in different formats, a duplicate, an unknown and a malformed phone
+const mixedPhonesCSV = "phone,note\n" +
+	"0700 000 001,local format\n" +
+	"254700000002,no plus\n" +
+	"+254700000001,duplicate\n" +
+	"+254711111111,unknown\n" +
+	"not-a-phone,malformed\n"
+
+// TestNormalizePhone tests the accepted phone formats
+func TestNormalizePhone(t *testing.T) {
+	valid := map[string]string{
+		"+254700000001":       "+254700000001",
+		"0700000001":          "+254700000001",
+		"254700000001":        "+254700000001",
+		"00254700000001":      "+254700000001",
+		" +254 (700) 000-001": "+254700000001",
+		"+14155550100":        "+14155550100",
+	}
+	for raw, expected := range valid {
+		phone, err := models.NormalizePhone(raw)
+		AssertNoError(t, err)
+		AssertEqual(t, phone, expected)
+	}
+
+	for _, raw := range []string{"", "12345", "not-a-phone", "+2547000000011234567", "07000000a1"} {
+		if _, err := models.NormalizePhone(raw); err == nil {
+			t.Errorf("Expected error for %q", raw)
+		}
+	}
+}
+
+// TestSendCampaignCSV_MixedPhones tests matching known phones and reporting unknown and malformed ones
+func TestSendCampaignCSV_MixedPhones(t *testing.T) {
+	svc, customerRepo, mock := setupSendCSVTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var sentTo []int
+	customerRepo.GetByIDsFunc = func(ctx context.Context, ids []int) ([]*models.Customer, error) {
+		sentTo = ids
+		customers := []*models.Customer{}
+		for _, id := range ids {
+			customers = append(customers, NewTestCustomerWithID(id))
+		}
+		return customers, nil
+	}
+
+	result, err := svc.SendCampaignCSV(context.Background(), 1, strings.NewReader(mixedPhonesCSV), &service.SendCampaignCSVRequest{})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Rows, 5)
+	AssertEqual(t, result.Matched, 2)
+	AssertEqual(t, result.Unmatched, 1)
+	AssertEqual(t, result.Created, 0)
+	AssertEqual(t, result.Invalid, 1)
+	AssertEqual(t, strings.Join(result.UnmatchedPhones, ","), "+254711111111")
+	AssertEqual(t, result.Errors[0].Line, 6)
+	AssertContains(t, result.Errors[0].Problem, "invalid phone number")
+
+	AssertEqual(t, len(sentTo), 2)
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertEqual(t, result.Status, models.CampaignStatusSending)
+	AssertEqual(t, customerRepo.Calls["Create"], 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSendCampaignCSV_CreateUnknown tests that unknown phones become customers when requested
+func TestSendCampaignCSV_CreateUnknown(t *testing.T) {
+	svc, customerRepo, mock := setupSendCSVTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var created []string
+	customerRepo.CreateFunc = func(ctx context.Context, customer *models.Customer) error {
+		created = append(created, customer.Phone)
+		customer.ID = 100
+		return nil
+	}
+
+	result, err := svc.SendCampaignCSV(context.Background(), 1, strings.NewReader(mixedPhonesCSV), &service.SendCampaignCSVRequest{CreateUnknown: true})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Matched, 2)
+	AssertEqual(t, result.Created, 1)
+	AssertEqual(t, result.Unmatched, 0)
+	AssertEqual(t, result.Invalid, 1)
+	AssertEqual(t, strings.Join(created, ","), "+254711111111")
+	AssertEqual(t, result.MessagesQueued, 3)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSendCampaignCSV_Batches tests that phones are looked up in batches
+func TestSendCampaignCSV_Batches(t *testing.T) {
+	svc, customerRepo, mock := setupSendCSVTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var csv strings.Builder
+	csv.WriteString("phone\n")
+	for i := 0; i < service.CSVPhoneBatchSize+1; i++ {
+		fmt.Fprintf(&csv, "+2547100%05d\n", i)
+	}
+	csv.WriteString("+254700000001\n")
+
+	result, err := svc.SendCampaignCSV(context.Background(), 1, strings.NewReader(csv.String()), &service.SendCampaignCSVRequest{})
+	AssertNoError(t, err)
+
+	AssertEqual(t, customerRepo.Calls["GetByPhones"], 2)
+	AssertEqual(t, result.Matched, 1)
+	AssertEqual(t, result.Unmatched, service.CSVPhoneBatchSize+1)
+	AssertEqual(t, len(result.UnmatchedPhones), service.CSVUnmatchedSampleSize)
+}
+
+// TestSendCampaignCSV_NothingMatched tests that an upload with no matches is rejected without sending
+func TestSendCampaignCSV_NothingMatched(t *testing.T) {
+	svc, customerRepo, mock := setupSendCSVTest(t)
+
+	_, err := svc.SendCampaignCSV(context.Background(), 1, strings.NewReader("phone\n+254711111111\nbad\n"), &service.SendCampaignCSVRequest{})
+	AssertError(t, err, "validation error: no customers matched the uploaded phones")
+	AssertEqual(t, customerRepo.Calls["GetByIDs"], 0)
+
+	// A file without a phone column is rejected before any lookup
+	_, err = svc.SendCampaignCSV(context.Background(), 1, strings.NewReader("mobile\n+254700000001\n"), &service.SendCampaignCSVRequest{})
+	AssertContains(t, err.Error(), "missing required columns: phone")
+	AssertEqual(t, customerRepo.Calls["GetByPhones"], 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetByPhones_Query tests the phone lookup query
+func TestGetByPhones_Query(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM customers WHERE normalize_phone\(phone\) = ANY`).
+		WithArgs(`{"+254700000001","+254711111111"}`).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "phone", "first_name", "last_name", "location", "preferred_product", "contact_window_start", "contact_window_end", "created_at",
+		}).AddRow(1, "+254700000001", "Alice", nil, nil, nil, nil, nil, NewTestCustomer().CreatedAt))
+
+	customers, err := repository.NewCustomerRepository(db).GetByPhones(context.Background(), []string{"+254700000001", "+254711111111"})
+	AssertNoError(t, err)
+	AssertEqual(t, len(customers), 1)
+	AssertEqual(t, customers[0].ID, 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSendCSVEndpoint tests the multipart upload end to end with mocks
+func TestSendCSVEndpoint(t *testing.T) {
+	svc, _, mock := setupSendCSVTest(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/send-csv", handler.NewCampaignHandler(svc).SendCSV).Methods("POST")
+
+	newUpload := func(csv string, fields map[string]string) *http.Request {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		if csv != "" {
+			part, err := writer.CreateFormFile("file", "audience.csv")
+			AssertNoError(t, err)
+			part.Write([]byte(csv))
+		}
+		for name, value := range fields {
+			writer.WriteField(name, value)
+		}
+		writer.Close()
+
+		req := httptest.NewRequest("POST", "/campaigns/1/send-csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, newUpload(mixedPhonesCSV, nil))
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertContains(t, resp.Body.String(), `"campaign_id":1`)
+	var result service.SendCampaignCSVResult
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, result.Matched, 2)
+	AssertEqual(t, result.Unmatched, 1)
+	AssertEqual(t, result.MessagesQueued, 2)
+
+	// Missing file
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, newUpload("", map[string]string{"create_unknown": "true"}))
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+
+	// Malformed flag
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, newUpload(mixedPhonesCSV, map[string]string{"create_unknown": "maybe"}))
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,203 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/reqctx"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// TestSendHistory_MultipleSends tests that each send against a campaign is logged with its targeting and outcome
+func TestSendHistory_MultipleSends(t *testing.T) {
+	svc, campaignRepo, _, mock := setupApprovalTest(t)
+
+	records := []*models.SendRecord{}
+	campaignRepo.RecordSendFunc = func(ctx context.Context, record *models.SendRecord) error {
+		records = append(records, record)
+		return nil
+	}
+
+	// Within the approval threshold, so it goes out
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	_, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{RequestedBy: "amina"})
+	AssertNoError(t, err)
+
+	// Above it, so it waits; the duplicate content flag is kept
+	_, err = svc.SendCampaign(context.Background(), 1, []int{1, 2, 3}, service.SendOptions{AllowDuplicateContent: true})
+	AssertNoError(t, err)
+
+	AssertEqual(t, len(records), 2)
+
+	first := records[0]
+	AssertEqual(t, first.CampaignID, 1)
+	AssertEqual(t, *first.RequestedBy, "amina")
+	AssertEqual(t, first.Source, models.SendSourceAPI)
+	AssertEqual(t, first.Status, models.CampaignStatusSending)
+	AssertEqual(t, first.MessagesQueued, 2)
+	AssertEqual(t, first.AudienceSize, 2)
+	AssertEqual(t, first.Request.CustomerIDCount, 2)
+	AssertEqual(t, first.Request.AllowDuplicateContent, false)
+
+	second := records[1]
+	if second.RequestedBy != nil {
+		t.Error("Expected no requester without authentication")
+	}
+	AssertEqual(t, second.Status, models.CampaignStatusPendingApproval)
+	AssertEqual(t, second.MessagesQueued, 0)
+	AssertEqual(t, second.AudienceSize, 3)
+	AssertEqual(t, second.Request.AllowDuplicateContent, true)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSendHistory_Sanitized tests that a long ID list is kept as a count and sample, and inline customers as a count
+func TestSendHistory_Sanitized(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaignRepo := NewMockCampaignRepository()
+	customerRepo := NewMockCustomerRepository()
+	customerRepo.UpsertByPhoneFunc = func(ctx context.Context, customer *models.Customer) (bool, error) {
+		customer.ID = 9000
+		return true, nil
+	}
+	var record *models.SendRecord
+	campaignRepo.RecordSendFunc = func(ctx context.Context, r *models.SendRecord) error {
+		record = r
+		return nil
+	}
+	svc := service.NewCampaignService(campaignRepo, customerRepo, NewMockMessageRepository(), service.NewTemplateService(), NewMockPublisher(), db, config.ApprovalConfig{})
+
+	ids := make([]int, 150)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	_, err := svc.SendCampaign(context.Background(), 1, ids, service.SendOptions{
+		Customers: []service.InlineCustomer{{Phone: "+254712345678"}},
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, record.Request.CustomerIDCount, 150)
+	AssertEqual(t, len(record.Request.CustomerIDSample), service.SendLogIDSampleSize)
+	AssertEqual(t, record.Request.CustomerIDSample[99], 100)
+	AssertEqual(t, record.Request.InlineCustomers, 1)
+	AssertEqual(t, record.AudienceSize, 151)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSendHistory_RecordFailure tests that a send that went out is not failed by the log write
+func TestSendHistory_RecordFailure(t *testing.T) {
+	svc, campaignRepo, _, mock := setupApprovalTest(t)
+	campaignRepo.RecordSendFunc = func(ctx context.Context, record *models.SendRecord) error {
+		return errors.New("failed to record send: connection reset")
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1}, service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.MessagesQueued, 1)
+}
+
+// TestSendHistory_Endpoint tests that sends over HTTP record the caller and the history is listed
+func TestSendHistory_Endpoint(t *testing.T) {
+	svc, campaignRepo, _, mock := setupApprovalTest(t)
+
+	records := []*models.SendRecord{}
+	campaignRepo.RecordSendFunc = func(ctx context.Context, record *models.SendRecord) error {
+		record.ID = len(records) + 1
+		records = append([]*models.SendRecord{record}, records...)
+		return nil
+	}
+	campaignRepo.ListSendsFunc = func(ctx context.Context, campaignID int) ([]*models.SendRecord, error) {
+		return records, nil
+	}
+
+	h := handler.NewCampaignHandler(svc)
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/send", h.Send).Methods("POST")
+	router.HandleFunc("/campaigns/{id}/send-history", h.SendHistory).Methods("GET")
+
+	identity := &reqctx.Identity{UserID: "amina", Role: config.RoleAdmin}
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		req := httptest.NewRequest("POST", "/campaigns/1/send", strings.NewReader(`{"customer_ids": [1, 2]}`))
+		req = req.WithContext(reqctx.WithIdentity(req.Context(), identity))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		AssertStatusCode(t, resp, http.StatusOK)
+	}
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest("GET", "/campaigns/1/send-history", nil))
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var history service.SendHistory
+	ParseJSONResponse(t, resp, &history)
+	AssertEqual(t, history.CampaignID, 1)
+	AssertEqual(t, len(history.Sends), 2)
+	AssertEqual(t, history.Sends[0].ID, 2)
+	AssertEqual(t, *history.Sends[0].RequestedBy, "amina")
+	AssertEqual(t, history.Sends[0].Request.CustomerIDSample[1], 2)
+
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return nil, repository.ErrCampaignNotFound
+	}
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest("GET", "/campaigns/9/send-history", nil))
+	AssertStatusCode(t, resp, http.StatusNotFound)
+}
+
+// TestSendLog_Queries tests storing a send with its JSONB request and reading the history back
+func TestSendLog_Queries(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	createdAt := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`INSERT INTO campaign_sends_log \(campaign_id, requested_by, source, request, audience_size, messages_queued, status\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\) RETURNING id, created_at`).
+		WithArgs(1, "amina", "csv", []byte(`{"customer_id_count":2,"customer_id_sample":[4,5],"inline_customers":0,"allow_duplicate_content":false}`), 2, 2, "sending").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, createdAt))
+
+	campaignRepo := repository.NewCampaignRepository(db)
+	record := &models.SendRecord{
+		CampaignID:     1,
+		RequestedBy:    StringPtr("amina"),
+		Source:         models.SendSourceCSV,
+		Request:        models.SendRequestInfo{CustomerIDCount: 2, CustomerIDSample: []int{4, 5}},
+		AudienceSize:   2,
+		MessagesQueued: 2,
+		Status:         models.CampaignStatusSending,
+	}
+	AssertNoError(t, campaignRepo.RecordSend(context.Background(), record))
+	AssertEqual(t, record.ID, 7)
+
+	mock.ExpectQuery(`SELECT (.+) FROM campaign_sends_log WHERE campaign_id = \$1 ORDER BY created_at DESC, id DESC`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "requested_by", "source", "request", "audience_size", "messages_queued", "status", "created_at"}).
+			AddRow(7, 1, "amina", "csv", []byte(`{"customer_id_count":2,"customer_id_sample":[4,5]}`), 2, 2, "sending", createdAt).
+			AddRow(6, 1, nil, "api", []byte(`{"customer_id_count":60000,"customer_id_sample":[1]}`), 60000, 0, "pending_approval", createdAt.Add(-time.Hour)))
+
+	sends, err := campaignRepo.ListSends(context.Background(), 1)
+	AssertNoError(t, err)
+	AssertEqual(t, len(sends), 2)
+	AssertEqual(t, sends[0].Request.CustomerIDSample[1], 5)
+	AssertEqual(t, sends[1].Request.CustomerIDCount, 60000)
+	AssertEqual(t, sends[1].Status, models.CampaignStatusPendingApproval)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
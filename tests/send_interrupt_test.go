@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// sendInterruptFixture is a campaign service whose repositories keep the messages and send
+// log a send leaves behind
+type sendInterruptFixture struct {
+	svc          *service.CampaignService
+	campaignRepo *MockCampaignRepository
+	messageRepo  *MockMessageRepository
+	mock         sqlmock.Sqlmock
+	messages     []*models.OutboundMessage
+	records      []*models.SendRecord
+	status       models.CampaignStatus
+}
+
+func newSendInterruptFixture(t *testing.T) *sendInterruptFixture {
+	t.Helper()
+
+	f := &sendInterruptFixture{
+		campaignRepo: NewMockCampaignRepository(),
+		messageRepo:  NewMockMessageRepository(),
+		status:       models.CampaignStatusDraft,
+	}
+	f.campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(f.status), nil
+	}
+	f.campaignRepo.UpdateStatusIfFunc = func(ctx context.Context, id int, from, to models.CampaignStatus) error {
+		f.status = to
+		return nil
+	}
+	f.campaignRepo.RecordSendFunc = func(ctx context.Context, record *models.SendRecord) error {
+		record.ID = len(f.records) + 1
+		f.records = append(f.records, record)
+		return nil
+	}
+	f.campaignRepo.UpdateSendProgressFunc = func(ctx context.Context, id int, messagesQueued int, status models.CampaignStatus) error {
+		record := f.records[id-1]
+		record.MessagesQueued, record.Status = messagesQueued, status
+		return nil
+	}
+	f.campaignRepo.ListSendsFunc = func(ctx context.Context, campaignID int) ([]*models.SendRecord, error) {
+		newestFirst := make([]*models.SendRecord, 0, len(f.records))
+		for i := len(f.records) - 1; i >= 0; i-- {
+			newestFirst = append(newestFirst, f.records[i])
+		}
+		return newestFirst, nil
+	}
+	f.messageRepo.CreateBatchFunc = func(ctx context.Context, messages []*models.OutboundMessage) error {
+		for _, message := range messages {
+			message.ID = len(f.messages) + 1
+			f.messages = append(f.messages, message)
+		}
+		return nil
+	}
+	f.messageRepo.GetByCampaignIDFunc = func(ctx context.Context, campaignID int) ([]*models.OutboundMessage, error) {
+		return f.messages, nil
+	}
+
+	f.svc, f.mock = NewMockCampaignService(t, f.campaignRepo, f.messageRepo)
+	return f
+}
+
+// expectBatches expects the transaction of each of n batches
+func (f *sendInterruptFixture) expectBatches(n int) {
+	for i := 0; i < n; i++ {
+		f.mock.ExpectBegin()
+		f.mock.ExpectCommit()
+	}
+}
+
+// customerIDs returns the IDs 1 to n
+func customerIDs(n int) []int {
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	return ids
+}
+
+// TestSendInterrupt_CancelledBetweenBatches tests that a send whose request is cancelled stops
+// after the batch in flight, leaving an interrupted log entry with its progress
+func TestSendInterrupt_CancelledBetweenBatches(t *testing.T) {
+	f := newSendInterruptFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The client goes away while the first batch is being created
+	createBatch := f.messageRepo.CreateBatchFunc
+	f.messageRepo.CreateBatchFunc = func(ctx context.Context, messages []*models.OutboundMessage) error {
+		cancel()
+		return createBatch(ctx, messages)
+	}
+	f.expectBatches(1)
+
+	_, err := f.svc.SendCampaign(ctx, 1, customerIDs(1200), service.SendOptions{})
+	var interrupted *service.SendInterruptedError
+	if !errors.As(err, &interrupted) {
+		t.Fatalf("Expected a SendInterruptedError, got %v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the interruption to wrap context.Canceled, got %v", err)
+	}
+	AssertEqual(t, interrupted.MessagesQueued, service.DispatchBatchSize)
+	AssertEqual(t, interrupted.AudienceSize, 1200)
+
+	AssertEqual(t, len(f.messages), service.DispatchBatchSize)
+	AssertEqual(t, f.status, models.CampaignStatusSending)
+	AssertEqual(t, len(f.records), 1)
+	AssertEqual(t, f.records[0].Status, models.SendStatusInterrupted)
+	AssertEqual(t, f.records[0].MessagesQueued, service.DispatchBatchSize)
+	AssertEqual(t, f.records[0].AudienceSize, 1200)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestSendInterrupt_Resume tests that sending again after an interruption queues only the
+// customers without a message, so nobody is messaged twice
+func TestSendInterrupt_Resume(t *testing.T) {
+	f := newSendInterruptFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	createBatch := f.messageRepo.CreateBatchFunc
+	f.messageRepo.CreateBatchFunc = func(batchCtx context.Context, messages []*models.OutboundMessage) error {
+		if len(f.messages) == service.DispatchBatchSize {
+			cancel()
+		}
+		return createBatch(batchCtx, messages)
+	}
+	f.expectBatches(2)
+
+	_, err := f.svc.SendCampaign(ctx, 1, customerIDs(1200), service.SendOptions{})
+	AssertError(t, err, "send to campaign 1 interrupted after queuing 1000 of 1200 messages: context canceled; send again to resume")
+	AssertEqual(t, f.records[0].Status, models.SendStatusInterrupted)
+	AssertEqual(t, f.records[0].MessagesQueued, 2*service.DispatchBatchSize)
+	created := append([]*models.OutboundMessage{}, f.messages...)
+
+	f.campaignRepo.Calls["UpdateStatusIf"] = 0
+	f.expectBatches(1)
+	result, err := f.svc.SendCampaign(context.Background(), 1, customerIDs(1200), service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.MessagesQueued, 200)
+	AssertEqual(t, result.SkippedAlreadyQueued, 2*service.DispatchBatchSize)
+	AssertEqual(t, result.Status, models.CampaignStatusSending)
+	AssertEqual(t, f.campaignRepo.Calls["UpdateStatusIf"], 0)
+
+	// The messages created before the interruption are untouched
+	for i, message := range created {
+		AssertEqual(t, message.ID, i+1)
+		AssertEqual(t, message.Status, models.MessageStatusPending)
+	}
+
+	seen := make(map[int]int)
+	for _, message := range f.messages {
+		seen[message.CustomerID]++
+	}
+	AssertEqual(t, len(seen), 1200)
+	for customerID, count := range seen {
+		if count != 1 {
+			t.Errorf("Expected customer %d to get one message, got %d", customerID, count)
+		}
+	}
+
+	AssertEqual(t, len(f.records), 2)
+	AssertEqual(t, f.records[1].Status, models.CampaignStatusSending)
+	AssertEqual(t, f.records[1].MessagesQueued, 200)
+
+	// Once resumed, the campaign is sending and cannot be sent again
+	_, err = f.svc.SendCampaign(context.Background(), 1, customerIDs(1200), service.SendOptions{})
+	if _, ok := err.(*service.BusinessLogicError); !ok {
+		t.Errorf("Expected a BusinessLogicError for a completed send, got %v", err)
+	}
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestSendInterrupt_ResumeNothingLeft tests that resuming a send that had queued everyone is refused
+func TestSendInterrupt_ResumeNothingLeft(t *testing.T) {
+	f := newSendInterruptFixture(t)
+	f.status = models.CampaignStatusSending
+	f.records = append(f.records, &models.SendRecord{ID: 1, Status: models.SendStatusInterrupted})
+	for _, id := range customerIDs(3) {
+		f.messages = append(f.messages, &models.OutboundMessage{ID: id, CustomerID: id})
+	}
+
+	_, err := f.svc.SendCampaign(context.Background(), 1, customerIDs(3), service.SendOptions{})
+	validationErr, ok := err.(*service.ValidationError)
+	if !ok {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+	AssertEqual(t, validationErr.Message, "every customer already has a message from this campaign")
+	AssertEqual(t, f.messageRepo.Calls["CreateBatch"], 0)
+}
+
+// TestSendInterrupt_CancelledRequest tests that a send whose request is already cancelled
+// creates nothing and logs nothing
+func TestSendInterrupt_CancelledRequest(t *testing.T) {
+	f := newSendInterruptFixture(t)
+	router := NewTestRouter(map[string]http.HandlerFunc{"POST /campaigns/{id}/send": handler.NewCampaignHandler(f.svc).Send})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("POST", "/campaigns/1/send", strings.NewReader(`{"customer_ids": [1, 2, 3]}`)).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	AssertStatusCode(t, rr, http.StatusInternalServerError)
+	AssertEqual(t, len(f.messages), 0)
+	AssertEqual(t, len(f.records), 0)
+	AssertEqual(t, f.status, models.CampaignStatusDraft)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestRecordSendMetrics tests the send metrics upsert for a campaign at a given rate
+func TestRecordSendMetrics(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewCampaignEventRepository(db)
+
+	mock.ExpectExec(`INSERT INTO campaign_send_metrics (.+) FROM outbound_messages m WHERE m.campaign_id = \$1 AND m.status IN \('sent', 'failed'\)`).
+		WithArgs(42, 17.5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	AssertNoError(t, repo.RecordSendMetrics(context.Background(), 42, 17.5))
+
+	mock.ExpectExec("INSERT INTO campaign_send_metrics").WillReturnError(errors.New("connection refused"))
+	AssertError(t, repo.RecordSendMetrics(context.Background(), 42, 0), "failed to record send metrics: connection refused")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestAPI_GetCampaign_SendMetrics tests that a completed campaign's detail includes its send
+// duration and throughput next to the estimate
+func TestAPI_GetCampaign_SendMetrics(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	// 40,000 messages sent over 38 minutes, estimated at 40 minutes
+	campaign := NewTestCampaignWithStatus(models.CampaignStatusSent)
+	started := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	completed := started.Add(38 * time.Minute)
+	mock.ExpectQuery(CampaignWithStatsQuery).
+		WithArgs(campaign.ID).
+		WillReturnRows(NewCampaignWithStatsRows(campaign, 40000, 0, 39950, 50, 0, 0, 0, nil, 0, nil,
+			started, completed, 2280.0, 40000, 1052.63, 2400.0))
+
+	router := setupAPITestRouter(setupAPITestHandler(t, db))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest("GET", fmt.Sprintf("/campaigns/%d", campaign.ID), nil))
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var result struct {
+		SendMetrics *models.CampaignSendMetrics `json:"send_metrics"`
+	}
+	ParseJSONResponse(t, resp, &result)
+	AssertNotNil(t, result.SendMetrics)
+	AssertEqual(t, result.SendMetrics.StartedAt.Equal(started), true)
+	AssertEqual(t, result.SendMetrics.CompletedAt.Sub(result.SendMetrics.StartedAt), 38*time.Minute)
+	AssertEqual(t, result.SendMetrics.DurationSeconds, 2280.0)
+	AssertEqual(t, result.SendMetrics.Messages, 40000)
+	AssertEqual(t, *result.SendMetrics.ThroughputPerMinute, 1052.63)
+	AssertEqual(t, *result.SendMetrics.EstimatedDurationSeconds, 2400.0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestAPI_GetCampaign_NoSendMetrics tests that a campaign whose send has not completed has no
+// send_metrics field
+func TestAPI_GetCampaign_NoSendMetrics(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaign := NewTestCampaignWithStatus(models.CampaignStatusSending)
+	mock.ExpectQuery(CampaignWithStatsQuery).
+		WithArgs(campaign.ID).
+		WillReturnRows(NewCampaignWithStatsRows(campaign, 10, 10, 0, 0, 10, 0, 0, nil, 0, nil))
+
+	router := setupAPITestRouter(setupAPITestHandler(t, db))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest("GET", fmt.Sprintf("/campaigns/%d", campaign.ID), nil))
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var result map[string]interface{}
+	ParseJSONResponse(t, resp, &result)
+	if _, ok := result["send_metrics"]; ok {
+		t.Errorf("Expected no send_metrics, got %v", result["send_metrics"])
+	}
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRecordSendMetrics_Integration tests the recorded span and throughput of messages seeded
+// over a known 40 minutes
+func TestRecordSendMetrics_Integration(t *testing.T) {
+	db := SetupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer db.Close()
+	CleanupTestDB(t, db)
+	defer CleanupTestDB(t, db)
+
+	ctx := context.Background()
+	var campaignID, customerID int
+	AssertNoError(t, db.QueryRowContext(ctx, `INSERT INTO campaigns (name, channel, status, base_template) VALUES ('Metrics', 'sms', 'sending', 'Hi') RETURNING id`).Scan(&campaignID))
+	AssertNoError(t, db.QueryRowContext(ctx, `INSERT INTO customers (phone) VALUES ('+254710000099') RETURNING id`).Scan(&customerID))
+
+	// Published at 08:00; the last of 80 messages finishes at 08:40; one is still pending
+	published := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < 80; i++ {
+		status := "sent"
+		if i%20 == 0 {
+			status = "failed"
+		}
+		finished := published.Add(time.Duration(i+1) * 30 * time.Second)
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO outbound_messages (campaign_id, customer_id, status, published_at, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`, campaignID, customerID, status, published, published.Add(-time.Minute), finished)
+		AssertNoError(t, err)
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO outbound_messages (campaign_id, customer_id, status, created_at, updated_at) VALUES ($1, $2, 'pending', $3, $3)`,
+		campaignID, customerID, published.Add(-2*time.Minute))
+	AssertNoError(t, err)
+
+	AssertNoError(t, repository.NewCampaignEventRepository(db).RecordSendMetrics(ctx, campaignID, 0.05))
+
+	campaign, err := repository.NewCampaignRepository(db).GetWithStats(ctx, campaignID)
+	AssertNoError(t, err)
+	metrics := campaign.SendMetrics
+	AssertNotNil(t, metrics)
+	AssertEqual(t, metrics.StartedAt.Equal(published), true)
+	AssertEqual(t, metrics.CompletedAt.Equal(published.Add(40*time.Minute)), true)
+	AssertEqual(t, metrics.DurationSeconds, 2400.0)
+	AssertEqual(t, metrics.Messages, 80)
+	AssertEqual(t, *metrics.ThroughputPerMinute, 2.0)
+	AssertEqual(t, *metrics.EstimatedDurationSeconds, 1600.0)
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestScriptedSender_Sequence tests that a scripted sender returns its results in order
+func TestScriptedSender_Sequence(t *testing.T) {
+	sender := service.NewSenderServiceScripted(
+		service.SendResult{Error: errors.New("rate limit exceeded")},
+		service.SendResult{Success: true},
+		service.SendResult{},
+	)
+
+	first := sender.Send(models.ChannelSMS, "+254700000001", "Hi", service.SenderOptions{})
+	AssertEqual(t, first.Success, false)
+	AssertError(t, first.Error, "failed to send SMS to +254700000001: rate limit exceeded")
+
+	second := sender.Send(models.ChannelWhatsApp, "+254700000001", "Hi", service.SenderOptions{})
+	AssertEqual(t, second.Success, true)
+	AssertNil(t, second.Error)
+
+	third := sender.Send(models.ChannelWhatsApp, "+254700000001", "Hi", service.SenderOptions{})
+	AssertError(t, third.Error, "failed to send WhatsApp to +254700000001: scripted failure")
+
+	// Sends beyond the script fail rather than guessing
+	extra := sender.Send(models.ChannelSMS, "+254700000001", "Hi", service.SenderOptions{})
+	AssertEqual(t, extra.Success, false)
+	AssertEqual(t, errors.Is(extra.Error, service.ErrSenderScriptExhausted), true)
+	AssertEqual(t, sender.Calls(), 4)
+}
+
+// TestSenderService_NoLatency tests that the random sender can skip its simulated latency
+func TestSenderService_NoLatency(t *testing.T) {
+	sender := service.NewSenderService(1.0).WithNoLatency()
+
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		AssertEqual(t, sender.Send(models.ChannelSMS, "+254700000001", "Hi", service.SenderOptions{}).Success, true)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Expected sends without latency, took %v", elapsed)
+	}
+	AssertEqual(t, sender.Calls(), 20)
+
+	AssertEqual(t, service.NewSenderService(0).WithNoLatency().Send(models.ChannelSMS, "+254700000001", "Hi", service.SenderOptions{}).Success, false)
+}
+
+// TestScriptedSender_RetryThenSend tests that a rate-limited send is marked failed and requeued, and the retry is sent
+func TestScriptedSender_RetryThenSend(t *testing.T) {
+	sender := service.NewSenderServiceScripted(
+		service.SendResult{Error: errors.New("rate limit exceeded")},
+		service.SendResult{Success: true},
+	)
+	f := newProcessingErrorFixture(t, sender)
+	job := &queue.MessageJob{MessageID: 1, CampaignID: 1, CustomerID: 1}
+
+	f.expectMarkedFailed()
+	err := f.processor.Handle(job)
+	AssertError(t, err, "send failed: failed to send SMS to +254700000001: rate limit exceeded")
+
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+		WithArgs(1, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	AssertNoError(t, f.processor.Handle(job))
+
+	AssertEqual(t, sender.Calls(), 2)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+}
+
+// TestSenderService_FailureRateOverride tests that a send's failure rate replaces the configured rate
+func TestSenderService_FailureRateOverride(t *testing.T) {
+	alwaysFail, neverFail := 1.0, 0.0
+
+	succeeding := service.NewSenderService(1.0).WithNoLatency()
+	for i := 0; i < 20; i++ {
+		AssertEqual(t, succeeding.Send(models.ChannelSMS, "+254700000001", "Hi", service.SenderOptions{FailureRate: &alwaysFail}).Success, false)
+		AssertEqual(t, succeeding.Send(models.ChannelSMS, "+254700000001", "Hi", service.SenderOptions{}).Success, true)
+	}
+
+	failing := service.NewSenderService(0).WithNoLatency()
+	for i := 0; i < 20; i++ {
+		AssertEqual(t, failing.Send(models.ChannelWhatsApp, "+254700000001", "Hi", service.SenderOptions{FailureRate: &neverFail}).Success, true)
+	}
+	AssertEqual(t, failing.GetSuccessRate(), 0.0)
+}
+
+// TestSenderService_ConcurrentSetSuccessRate tests changing the success rate while sends run
+// (run with -race to check for data races)
+func TestSenderService_ConcurrentSetSuccessRate(t *testing.T) {
+	sender := service.NewSenderService(0.5).WithNoLatency()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				sender.SetSuccessRate(float64(j%3) / 2)
+				sender.Send(models.ChannelSMS, "+254700000001", "Hi", service.SenderOptions{})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	AssertEqual(t, sender.Calls(), 800)
+	sender.SetSuccessRate(1.5)
+	AssertEqual(t, sender.GetSuccessRate(), 1.0)
+}
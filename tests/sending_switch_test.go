@@ -0,0 +1,280 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// stepSender sends successfully, and while gated signals started and waits for release,
+// so a test can act while a send is in flight
+type stepSender struct {
+	phones  []string
+	gated   bool
+	started chan struct{}
+	release chan struct{}
+}
+
+func newStepSender() *stepSender {
+	return &stepSender{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (s *stepSender) Send(channel models.Channel, phone string, content string, opts service.SenderOptions) *service.SendResult {
+	s.phones = append(s.phones, phone)
+	if s.gated {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	return &service.SendResult{Success: true}
+}
+
+func (s *stepSender) Capabilities() []models.Channel {
+	return []models.Channel{models.ChannelSMS, models.ChannelWhatsApp}
+}
+
+// TestSendingSwitch_CachedAcrossProcesses tests that a switch turned off through one process
+// reaches another once its cached read is older than the TTL, and that the change is audited
+func TestSendingSwitch_CachedAcrossProcesses(t *testing.T) {
+	settings := NewMockSettingsRepository()
+	api := service.NewSendingSwitch(settings)
+	worker := service.NewSendingSwitch(settings)
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	worker.SetClock(func() time.Time { return now })
+	ctx := context.Background()
+
+	// Never stored: sending is on
+	AssertEqual(t, worker.Enabled(ctx), true)
+
+	status, err := api.Disable(ctx, &service.SetSendingRequest{Reason: "  wrong template sent to production  ", Actor: "amina"})
+	AssertNoError(t, err)
+	AssertEqual(t, status.SendingEnabled, false)
+	AssertEqual(t, *status.UpdatedBy, "amina")
+	AssertEqual(t, len(status.History), 1)
+	AssertEqual(t, status.History[0].Reason, "wrong template sent to production")
+	AssertEqual(t, status.History[0].Value, "false")
+	AssertEqual(t, *status.History[0].Actor, "amina")
+
+	// The process that changed it sees it at once; the worker within the TTL
+	AssertEqual(t, api.Enabled(ctx), false)
+	now = now.Add(service.SendingSwitchCacheTTL - time.Second)
+	AssertEqual(t, worker.Enabled(ctx), true)
+	AssertEqual(t, settings.Calls["Get"], 3)
+	now = now.Add(time.Second)
+	AssertEqual(t, worker.Enabled(ctx), false)
+
+	err = worker.Check(ctx)
+	var disabled *service.SendingDisabledError
+	if !errors.As(err, &disabled) {
+		t.Fatalf("Expected a SendingDisabledError but got %v", err)
+	}
+	AssertEqual(t, err.Error(), "all outbound sending is disabled by amina: wrong template sent to production")
+}
+
+// TestSendingSwitch_ReadFailureKeepsLastState tests that a setting that cannot be read leaves
+// the switch as last read rather than flipping it
+func TestSendingSwitch_ReadFailureKeepsLastState(t *testing.T) {
+	settings := NewMockSettingsRepository()
+	settings.Set(context.Background(), models.SettingSendingEnabled, "false", "incident", nil)
+	sending := service.NewSendingSwitch(settings)
+	now := time.Now()
+	sending.SetClock(func() time.Time { return now })
+
+	AssertEqual(t, sending.Enabled(context.Background()), false)
+
+	settings.GetFunc = func(ctx context.Context, key string) (*models.Setting, error) {
+		return nil, errors.New("connection refused")
+	}
+	now = now.Add(time.Minute)
+	AssertEqual(t, sending.Enabled(context.Background()), false)
+}
+
+// TestSendingSwitch_Validation tests that a reason is required and a change to the state the
+// switch is already in is refused
+func TestSendingSwitch_Validation(t *testing.T) {
+	settings := NewMockSettingsRepository()
+	sending := service.NewSendingSwitch(settings)
+	ctx := context.Background()
+
+	_, err := sending.Disable(ctx, &service.SetSendingRequest{Reason: "   "})
+	AssertError(t, err, "validation error: reason is required")
+
+	_, err = sending.Enable(ctx, &service.SetSendingRequest{Reason: "all clear"})
+	AssertError(t, err, "conflict with sending: sending is already enabled")
+
+	_, err = sending.Disable(ctx, &service.SetSendingRequest{Reason: "incident"})
+	AssertNoError(t, err)
+	_, err = sending.Disable(ctx, &service.SetSendingRequest{Reason: "incident again"})
+	AssertError(t, err, "conflict with sending: sending is already disabled")
+	AssertEqual(t, len(settings.Changes), 1)
+}
+
+// TestSendingSwitch_WorkerDefersMidStream tests toggling the switch while a batch is being
+// worked through: the send in flight completes, sends within the TTL still go out, later
+// messages are deferred without using a retry, and they are sent once sending is enabled
+func TestSendingSwitch_WorkerDefersMidStream(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	settings := NewMockSettingsRepository()
+	api := service.NewSendingSwitch(settings)
+	workerSwitch := service.NewSendingSwitch(settings)
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	workerSwitch.SetClock(clock)
+
+	messageRepo := NewMockMessageRepository()
+	messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		message := NewTestMessageWithStatus(models.MessageStatusPending)
+		message.ID = id
+		return &models.OutboundMessageWithDetails{
+			OutboundMessage: *message,
+			Campaign:        *NewTestCampaignWithStatus(models.CampaignStatusSending),
+			Customer:        *NewTestCustomer(),
+		}, nil
+	}
+	type deferral struct {
+		id     int
+		until  time.Time
+		reason string
+	}
+	deferred := []deferral{}
+	messageRepo.DeferUntilFunc = func(ctx context.Context, id int, until time.Time, reason string) error {
+		deferred = append(deferred, deferral{id, until, reason})
+		return nil
+	}
+
+	sender := newStepSender()
+	processor := service.NewMessageProcessor(db, messageRepo, service.NewTemplateService(), sender, service.NewAttemptBudget(messageRepo, 0), nil)
+	processor.SetClock(clock)
+	processor.SetSendingSwitch(workerSwitch)
+	handle := func(id int) error {
+		return processor.Handle(&queue.MessageJob{MessageID: id, CampaignID: 1, CustomerID: 1})
+	}
+	expectSent := func() {
+		mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	// Message 1 is sent normally
+	expectSent()
+	AssertNoError(t, handle(1))
+
+	// Sending is disabled while message 2 is with the provider: it completes and is recorded
+	expectSent()
+	sender.gated = true
+	done := make(chan error, 1)
+	go func() { done <- handle(2) }()
+	<-sender.started
+	_, err := api.Disable(context.Background(), &service.SetSendingRequest{Reason: "wrong template", Actor: "amina"})
+	AssertNoError(t, err)
+	sender.release <- struct{}{}
+	AssertNoError(t, <-done)
+	sender.gated = false
+
+	// Message 3 is picked up within the worker's cache TTL and still sent
+	expectSent()
+	now = now.Add(2 * time.Second)
+	AssertNoError(t, handle(3))
+
+	// Once the TTL passes, messages 4 and 5 are deferred, not sent, and no retry is used
+	now = now.Add(service.SendingSwitchCacheTTL)
+	AssertNoError(t, handle(4))
+	AssertNoError(t, handle(5))
+	AssertEqual(t, len(sender.phones), 3)
+	AssertEqual(t, len(deferred), 2)
+	AssertEqual(t, deferred[0].id, 4)
+	AssertEqual(t, deferred[0].until, now.Add(service.SendingDisabledDeferral))
+	AssertEqual(t, deferred[0].reason, "Deferred: outbound sending disabled")
+
+	// Enabled again, the deferred messages are sent when redelivered
+	_, err = api.Enable(context.Background(), &service.SetSendingRequest{Reason: "template fixed"})
+	AssertNoError(t, err)
+	now = now.Add(service.SendingDisabledDeferral)
+	expectSent()
+	expectSent()
+	AssertNoError(t, handle(4))
+	AssertNoError(t, handle(5))
+	AssertEqual(t, len(sender.phones), 5)
+	AssertEqual(t, len(deferred), 2)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSendingSwitch_Endpoints tests disabling and enabling sending through the admin endpoints,
+// new sends being refused with 503 in between
+func TestSendingSwitch_Endpoints(t *testing.T) {
+	f := newRouterFixture(t)
+	f.campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusDraft), nil
+	}
+
+	// A reason is required
+	rr := f.serve("POST", "/admin/sending/disable", "application/json", `{}`, routerAdminKey, routerAdminKey)
+	AssertStatusCode(t, rr, http.StatusBadRequest)
+	AssertContains(t, rr.Body.String(), "reason is required")
+
+	rr = f.serve("POST", "/admin/sending/disable", "application/json", `{"reason": "wrong template sent"}`, routerAdminKey, routerAdminKey)
+	AssertStatusCode(t, rr, http.StatusOK)
+	var status service.SendingStatus
+	ParseJSONResponse(t, rr, &status)
+	AssertEqual(t, status.SendingEnabled, false)
+	AssertEqual(t, *status.UpdatedBy, "amina")
+	AssertEqual(t, status.History[0].Reason, "wrong template sent")
+
+	// Sends are refused before anything is planned
+	rr = f.serve("POST", "/campaigns/1/send", "application/json", `{"customer_ids": [1, 2]}`, routerAdminKey, "")
+	AssertStatusCode(t, rr, http.StatusServiceUnavailable)
+	var errResp handler.ErrorResponse
+	ParseJSONResponse(t, rr, &errResp)
+	AssertEqual(t, errResp.Error.Code, "SENDING_DISABLED")
+	AssertEqual(t, errResp.Error.Message, "all outbound sending is disabled by amina: wrong template sent")
+	AssertEqual(t, f.campaignRepo.Calls["UpdateStatusIf"], 0)
+
+	// Disabling twice conflicts; the status is readable by members
+	rr = f.serve("POST", "/admin/sending/disable", "application/json", `{"reason": "again"}`, routerAdminKey, routerAdminKey)
+	AssertStatusCode(t, rr, http.StatusConflict)
+	rr = f.serve("GET", "/admin/sending", "", "", routerMemberKey, "")
+	AssertStatusCode(t, rr, http.StatusOK)
+	AssertContains(t, rr.Body.String(), `"sending_enabled":false`)
+
+	rr = f.serve("POST", "/admin/sending/enable", "application/json", `{"reason": "template fixed"}`, routerAdminKey, routerAdminKey)
+	AssertStatusCode(t, rr, http.StatusOK)
+	ParseJSONResponse(t, rr, &status)
+	AssertEqual(t, status.SendingEnabled, true)
+	AssertEqual(t, len(status.History), 2)
+	AssertEqual(t, status.History[0].Reason, "template fixed")
+
+	rr = f.serve("POST", "/campaigns/1/send", "application/json", `{"customer_ids": [1, 2]}`, routerAdminKey, "")
+	if rr.Code == http.StatusServiceUnavailable {
+		t.Fatalf("Expected the send to be let through once enabled: %s", rr.Body.String())
+	}
+}
+
+// TestSettingsRepository_Set tests that a setting and its audit entry are written by one statement
+func TestSettingsRepository_Set(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	actor := "amina"
+	updatedAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`WITH updated AS \(\s*INSERT INTO settings .+ ON CONFLICT \(key\) DO UPDATE .+\), logged AS \(\s*INSERT INTO settings_audit_log \(key, value, reason, actor\)`).
+		WithArgs(models.SettingSendingEnabled, "false", "incident", &actor).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value", "updated_by", "updated_at"}).
+			AddRow(models.SettingSendingEnabled, "false", actor, updatedAt))
+
+	setting, err := repository.NewSettingsRepository(db).Set(context.Background(), models.SettingSendingEnabled, "false", "incident", &actor)
+	AssertNoError(t, err)
+	AssertEqual(t, setting.Value, "false")
+	AssertEqual(t, *setting.UpdatedBy, "amina")
+	AssertEqual(t, setting.UpdatedAt, updatedAt)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
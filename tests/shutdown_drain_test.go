@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/queue"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// drainRecorder records the order a drain's steps happen in
+type drainRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *drainRecorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *drainRecorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+// recordingConsumer is a consumer whose pause is recorded
+type recordingConsumer struct {
+	recorder *drainRecorder
+}
+
+func (c *recordingConsumer) Pause() error {
+	c.recorder.record("paused")
+	return nil
+}
+
+// recordingAcknowledger records acks and nacks alongside the drain's other steps
+type recordingAcknowledger struct {
+	*fakeAcknowledger
+	recorder *drainRecorder
+}
+
+func (a *recordingAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.recorder.record(fmt.Sprintf("ack %d", tag))
+	return a.fakeAcknowledger.Ack(tag, multiple)
+}
+
+func (a *recordingAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.recorder.record(fmt.Sprintf("nack %d", tag))
+	return a.fakeAcknowledger.Nack(tag, multiple, requeue)
+}
+
+// handleSlowly handles a tracked delivery in the background like a consumer does, finishing
+// when release is closed
+func handleSlowly(inFlight *queue.InFlight, d amqp.Delivery, release <-chan struct{}) {
+	d, done := inFlight.Track(d)
+	go func() {
+		defer done()
+		<-release
+		d.Ack(false)
+	}()
+}
+
+// assertEvents checks the recorded steps
+func assertEvents(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Expected steps %v, got %v", want, got)
+	}
+}
+
+// TestShutdownDrain_WaitsForInFlight tests that a drain stops consuming first, then waits for
+// slow handlers to acknowledge their deliveries, and only then finishes
+func TestShutdownDrain_WaitsForInFlight(t *testing.T) {
+	recorder := &drainRecorder{}
+	ack := &recordingAcknowledger{fakeAcknowledger: newFakeAcknowledger(), recorder: recorder}
+	inFlight := queue.NewInFlight()
+	drain := queue.NewShutdownDrain(inFlight, time.Second, &recordingConsumer{recorder}, &recordingConsumer{recorder})
+
+	first, second := make(chan struct{}), make(chan struct{})
+	handleSlowly(inFlight, newTestDelivery(t, ack, 1, 1), first)
+	handleSlowly(inFlight, newTestDelivery(t, ack, 2, 2), second)
+	AssertEqual(t, inFlight.Count(), 2)
+
+	AssertEqual(t, drain.Draining(), false)
+	AssertEqual(t, drain.Start(), true)
+	AssertEqual(t, drain.Start(), false)
+	AssertEqual(t, drain.Draining(), true)
+
+	// Both consumers stop before anything in flight is resolved
+	for len(recorder.list()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(second)
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case <-drain.Done():
+		t.Fatal("Expected the drain to wait for the delivery still in flight")
+	default:
+	}
+	close(first)
+
+	select {
+	case <-drain.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the drain to finish once nothing was in flight")
+	}
+	recorder.record("drained")
+
+	assertEvents(t, recorder.list(), "paused", "paused", "ack 2", "ack 1", "drained")
+	summary := drain.Summary()
+	AssertEqual(t, summary.InFlight, 2)
+	AssertEqual(t, summary.Completed, 2)
+	AssertEqual(t, summary.Requeued, 0)
+	AssertEqual(t, summary.TimedOut, false)
+	if summary.FinishedAt == nil {
+		t.Error("Expected the summary to record when the drain finished")
+	}
+}
+
+// TestShutdownDrain_TimeoutRequeues tests that a delivery still being handled at the timeout
+// is requeued, and its handler's late ack is discarded
+func TestShutdownDrain_TimeoutRequeues(t *testing.T) {
+	recorder := &drainRecorder{}
+	ack := &recordingAcknowledger{fakeAcknowledger: newFakeAcknowledger(), recorder: recorder}
+	inFlight := queue.NewInFlight()
+	drain := queue.NewShutdownDrain(inFlight, 20*time.Millisecond, &recordingConsumer{recorder})
+
+	fast, stuck := make(chan struct{}), make(chan struct{})
+	handleSlowly(inFlight, newTestDelivery(t, ack, 1, 1), fast)
+	handleSlowly(inFlight, newTestDelivery(t, ack, 2, 2), stuck)
+	close(fast)
+
+	drain.Start()
+	<-drain.Done()
+	recorder.record("drained")
+
+	// The stuck handler finally returns; its delivery was already given back
+	close(stuck)
+	for inFlight.Count() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	events := recorder.list()
+	AssertEqual(t, events[len(events)-2], "nack 2")
+	AssertEqual(t, events[len(events)-1], "drained")
+	acks, nacks := ack.resolutions(2)
+	AssertEqual(t, acks, 0)
+	AssertEqual(t, nacks, 1)
+	AssertEqual(t, ack.requeued[2], true)
+	acks, _ = ack.resolutions(1)
+	AssertEqual(t, acks, 1)
+
+	summary := drain.Summary()
+	AssertEqual(t, summary.Requeued, 1)
+	AssertEqual(t, summary.TimedOut, true)
+	AssertEqual(t, inFlight.Completed(), 1)
+}
+
+// TestShutdownDrain_Idle tests that a drain with nothing in flight finishes at once
+func TestShutdownDrain_Idle(t *testing.T) {
+	drain := queue.NewShutdownDrain(queue.NewInFlight(), time.Minute)
+	AssertEqual(t, drain.Summary() == nil, true)
+
+	drain.Start()
+	select {
+	case <-drain.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected an idle drain to finish at once")
+	}
+	AssertEqual(t, drain.Summary().InFlight, 0)
+
+	// Without a counter nothing is tracked and nothing waited for
+	var off *queue.InFlight
+	_, done := off.Track(newTestDelivery(t, newFakeAcknowledger(), 1, 1))
+	done()
+	AssertEqual(t, off.Wait(context.Background()), true)
+}
+
+// TestDrainEndpoints tests the readiness probe turning 503 once a drain is requested over HTTP
+func TestDrainEndpoints(t *testing.T) {
+	inFlight := queue.NewInFlight()
+	release := make(chan struct{})
+	handleSlowly(inFlight, newTestDelivery(t, newFakeAcknowledger(), 1, 1), release)
+	h := handler.NewDrainHandler(queue.NewShutdownDrain(inFlight, time.Second))
+
+	serve := func(handle http.HandlerFunc, method string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handle(rr, httptest.NewRequest(method, "/", nil))
+		return rr
+	}
+
+	AssertStatusCode(t, serve(h.Ready, "GET"), http.StatusOK)
+
+	rr := serve(h.Start, "POST")
+	AssertStatusCode(t, rr, http.StatusAccepted)
+	var state handler.DrainState
+	ParseJSONResponse(t, rr, &state)
+	AssertEqual(t, state.Draining, true)
+	AssertEqual(t, state.Summary.InFlight, 1)
+
+	rr = serve(h.Ready, "GET")
+	AssertStatusCode(t, rr, http.StatusServiceUnavailable)
+	AssertContains(t, rr.Body.String(), `"ready":false`)
+
+	close(release)
+	for {
+		ParseJSONResponse(t, serve(h.Get, "GET"), &state)
+		if state.Drained {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	AssertEqual(t, state.Draining, false)
+	AssertEqual(t, state.Summary.Completed, 1)
+	AssertStatusCode(t, serve(h.Ready, "GET"), http.StatusServiceUnavailable)
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// sentinelSender fails the test if the worker ever hands it a message
+type sentinelSender struct {
+	t     *testing.T
+	calls int
+}
+
+func (s *sentinelSender) Send(channel models.Channel, phone string, content string, opts service.SenderOptions) *service.SendResult {
+	s.calls++
+	s.t.Errorf("provider called in simulate mode for %s via %s", phone, channel)
+	return &service.SendResult{Success: true}
+}
+
+func (s *sentinelSender) Capabilities() []models.Channel {
+	return []models.Channel{models.ChannelSMS, models.ChannelWhatsApp}
+}
+
+// countingSender records live sends
+type countingSender struct {
+	calls int
+}
+
+func (s *countingSender) Send(channel models.Channel, phone string, content string, opts service.SenderOptions) *service.SendResult {
+	s.calls++
+	return &service.SendResult{Success: true}
+}
+
+func (s *countingSender) Capabilities() []models.Channel {
+	return []models.Channel{models.ChannelSMS, models.ChannelWhatsApp}
+}
+
+// TestSimulateMode_NeverCallsProvider tests that simulate mode marks messages sent without the live sender
+func TestSimulateMode_NeverCallsProvider(t *testing.T) {
+	live := &sentinelSender{t: t}
+	sender := service.NewSender(config.WorkerConfig{Mode: config.WorkerModeSimulate}, live)
+
+	for _, channel := range []models.Channel{models.ChannelSMS, models.ChannelWhatsApp} {
+		for i := 0; i < 50; i++ {
+			result := sender.Send(channel, "+254700000001", "Hi", service.SenderOptions{})
+			AssertEqual(t, result.Success, true)
+			AssertEqual(t, result.Simulated, true)
+			if result.Error != nil {
+				t.Fatalf("Expected no error but got %v", result.Error)
+			}
+		}
+	}
+	AssertEqual(t, live.calls, 0)
+}
+
+// TestLiveMode_UsesProvider tests that live mode passes messages to the live sender unchanged
+func TestLiveMode_UsesProvider(t *testing.T) {
+	live := &countingSender{}
+	sender := service.NewSender(config.WorkerConfig{Mode: config.WorkerModeLive}, live)
+
+	result := sender.Send(models.ChannelSMS, "+254700000001", "Hi", service.SenderOptions{})
+	AssertEqual(t, live.calls, 1)
+	AssertEqual(t, result.Simulated, false)
+
+	// The real sender never reports simulated results
+	real := service.NewSenderService(1.0).WithNoLatency()
+	AssertEqual(t, real.Send(models.ChannelSMS, "+254700000001", "Hi", service.SenderOptions{}).Simulated, false)
+}
+
+// TestSimulatedSender_Latency tests the synthetic latency distribution
+func TestSimulatedSender_Latency(t *testing.T) {
+	// Without jitter every send takes the mean
+	sender := service.NewSimulatedSender(2*time.Millisecond, 0)
+	AssertEqual(t, sender.Send(models.ChannelSMS, "+254700000001", "Hi", service.SenderOptions{}).Latency, 2*time.Millisecond)
+
+	// Jitter larger than the mean never produces a negative latency
+	sender = service.NewSimulatedSender(0, time.Millisecond)
+	for i := 0; i < 100; i++ {
+		if latency := sender.Send(models.ChannelSMS, "+254700000001", "Hi", service.SenderOptions{}).Latency; latency < 0 {
+			t.Fatalf("Expected non-negative latency but got %v", latency)
+		}
+	}
+}
+
+// TestLoadWorkerMode tests WORKER_MODE parsing
+func TestLoadWorkerMode(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Worker.Mode, config.WorkerModeLive)
+
+	t.Setenv("WORKER_MODE", "simulate")
+	t.Setenv("SIMULATED_LATENCY_MS", "300")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Worker.Mode, config.WorkerModeSimulate)
+	AssertEqual(t, cfg.Worker.SimulatedLatencyMs, 300)
+
+	t.Setenv("WORKER_MODE", "dry-run")
+	_, err = config.Load()
+	AssertError(t, err, `WORKER_MODE must be "live" or "simulate"`)
+}
+
+// TestDeliveryStats_ExcludeSimulated tests that simulated sends do not count toward delivery rates
+func TestDeliveryStats_ExcludeSimulated(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`WHERE m.status IN \('sent', 'failed'\) AND NOT m.simulated`).
+		WillReturnRows(sqlmock.NewRows([]string{"channel", "total", "failed"}).AddRow("sms", 10, 1))
+
+	stats, err := repository.NewMessageRepository(db).GetDeliveryStatsByChannel(context.Background(), time.Now().Add(-time.Hour))
+	AssertNoError(t, err)
+	AssertEqual(t, len(stats), 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// testSendingConfig returns a sending config with round numbers for easy assertions
+func testSendingConfig() config.SendingConfig {
+	return config.SendingConfig{
+		RateLimitPerSecond: 10,
+		WorkerConcurrency:  4,
+		AvgSendLatencyMs:   100,
+		CostPerSMS:         0.80,
+		CostPerWhatsApp:    0.50,
+	}
+}
+
+// setupSimulationTest creates a simulation service backed by mock repositories
+func setupSimulationTest(history []*models.ChannelDeliveryStats) (*service.SimulationService, *MockCampaignRepository, *MockMessageRepository) {
+	campaignRepo := NewMockCampaignRepository()
+	customerRepo := NewMockCustomerRepository()
+	messageRepo := NewMockMessageRepository()
+	messageRepo.GetDeliveryStatsByChannelFunc = func(ctx context.Context, since time.Time) ([]*models.ChannelDeliveryStats, error) {
+		return history, nil
+	}
+
+	svc := service.NewSimulationService(campaignRepo, customerRepo, messageRepo, testSendingConfig(), config.QuietHoursConfig{})
+	return svc, campaignRepo, messageRepo
+}
+
+// TestSimulation_UsesChannelHistory tests that the trailing failure rate of the campaign channel is used
+func TestSimulation_UsesChannelHistory(t *testing.T) {
+	history := []*models.ChannelDeliveryStats{
+		{Channel: models.ChannelSMS, Total: 200, Failed: 20},
+		{Channel: models.ChannelWhatsApp, Total: 100, Failed: 50},
+	}
+	svc, _, _ := setupSimulationTest(history)
+
+	result, err := svc.Simulate(context.Background(), 1, &service.SimulateCampaignRequest{AudienceSize: 1000})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.Channel, models.ChannelSMS)
+	AssertEqual(t, result.AudienceSize, 1000)
+	AssertEqual(t, result.FailureRate, 0.1)
+	AssertEqual(t, result.FailureRateSampleSize, 200)
+	AssertEqual(t, result.ExpectedFailures, 100)
+	AssertEqual(t, result.ExpectedDelivered, 900)
+	AssertEqual(t, result.EstimatedCost, 800.0)
+}
+
+// TestSimulation_DefaultFailureRate tests the fallback when there is no history for the channel
+func TestSimulation_DefaultFailureRate(t *testing.T) {
+	svc, _, _ := setupSimulationTest([]*models.ChannelDeliveryStats{})
+
+	result, err := svc.Simulate(context.Background(), 1, &service.SimulateCampaignRequest{AudienceSize: 100})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.FailureRate, service.DefaultFailureRate)
+	AssertEqual(t, result.FailureRateSampleSize, 0)
+	AssertEqual(t, result.ExpectedFailures, 5)
+}
+
+// TestSimulation_DurationRespectsRateLimit tests that duration uses the slower of rate limit and worker capacity
+func TestSimulation_DurationRespectsRateLimit(t *testing.T) {
+	svc, _, _ := setupSimulationTest(nil)
+
+	// 4 workers at 100ms = 40/s, capped by the 10/s rate limit
+	result, err := svc.Simulate(context.Background(), 1, &service.SimulateCampaignRequest{AudienceSize: 600})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.EffectiveRatePerSecond, 10.0)
+	AssertEqual(t, result.EstimatedDurationSeconds, 60.0)
+}
+
+// TestSimulation_ResolvesAudienceFromCustomerIDs tests audience resolution from the planned customer list
+func TestSimulation_ResolvesAudienceFromCustomerIDs(t *testing.T) {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		campaign := NewTestCampaign()
+		campaign.Channel = models.ChannelWhatsApp
+		return campaign, nil
+	}
+	customerRepo := NewMockCustomerRepository()
+	customerRepo.GetByIDsFunc = func(ctx context.Context, ids []int) ([]*models.Customer, error) {
+		// Only two of the three IDs exist
+		return NewTestCustomers(2), nil
+	}
+	messageRepo := NewMockMessageRepository()
+
+	svc := service.NewSimulationService(campaignRepo, customerRepo, messageRepo, testSendingConfig(), config.QuietHoursConfig{})
+
+	result, err := svc.Simulate(context.Background(), 1, &service.SimulateCampaignRequest{CustomerIDs: []int{1, 2, 999}})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.AudienceSize, 2)
+	AssertEqual(t, result.CostPerMessage, 0.50)
+	AssertEqual(t, result.EstimatedCost, 1.0)
+	AssertEqual(t, customerRepo.Calls["GetByIDs"], 1)
+}
+
+// TestSimulation_Validation tests request validation
+func TestSimulation_Validation(t *testing.T) {
+	svc, _, messageRepo := setupSimulationTest(nil)
+
+	_, err := svc.Simulate(context.Background(), 1, &service.SimulateCampaignRequest{})
+	if _, ok := err.(*service.ValidationError); !ok {
+		t.Fatalf("Expected ValidationError but got %v", err)
+	}
+
+	_, err = svc.Simulate(context.Background(), 1, &service.SimulateCampaignRequest{AudienceSize: -5})
+	if _, ok := err.(*service.ValidationError); !ok {
+		t.Fatalf("Expected ValidationError but got %v", err)
+	}
+
+	AssertEqual(t, messageRepo.Calls["GetDeliveryStatsByChannel"], 0)
+}
+
+// TestSimulationEndpoint tests POST /campaigns/{id}/simulate end to end with mocks
+func TestSimulationEndpoint(t *testing.T) {
+	history := []*models.ChannelDeliveryStats{
+		{Channel: models.ChannelSMS, Total: 50, Failed: 10},
+	}
+	svc, campaignRepo, _ := setupSimulationTest(history)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/simulate", handler.NewSimulationHandler(svc).Simulate).Methods("POST")
+
+	// Successful simulation
+	req := NewJSONRequest(t, "POST", "/campaigns/1/simulate", map[string]interface{}{"audience_size": 10})
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertJSONContentType(t, resp)
+
+	var result service.SimulationResult
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, result.ExpectedFailures, 2)
+
+	// Unknown campaign
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return nil, fmt.Errorf("campaign not found")
+	}
+	req = NewJSONRequest(t, "POST", "/campaigns/99/simulate", map[string]interface{}{"audience_size": 10})
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusNotFound)
+}
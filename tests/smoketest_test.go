@@ -0,0 +1,219 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/httpjson"
+	"smsleopard/internal/smoketest"
+)
+
+// fakeSmokeAPI serves the endpoints the smoke test calls, failing the one named by failAt
+type fakeSmokeAPI struct {
+	t        *testing.T
+	failAt   string
+	campaign map[string]interface{}
+	deleted  bool
+	sentTo   string
+	keys     []string
+}
+
+func newFakeSmokeAPI(t *testing.T, failAt string) (*fakeSmokeAPI, *httptest.Server) {
+	api := &fakeSmokeAPI{t: t, failAt: failAt}
+	server := httptest.NewServer(http.HandlerFunc(api.serve))
+	t.Cleanup(server.Close)
+	return api, server
+}
+
+func (a *fakeSmokeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	a.keys = append(a.keys, r.Header.Get(smoketest.APIKeyHeader))
+	var body map[string]interface{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	step := ""
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/health":
+		step = smoketest.StepHealth
+	case r.Method == http.MethodPost && r.URL.Path == "/campaigns":
+		step = smoketest.StepCreate
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/personalized-preview"):
+		step = smoketest.StepPreview
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/simulate"):
+		step = smoketest.StepDryRun
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/send"):
+		step = smoketest.StepRealSend
+	case r.Method == http.MethodGet && r.URL.Path == "/campaigns":
+		step = smoketest.StepList
+	case r.Method == http.MethodDelete && r.URL.Path == "/campaigns/42":
+		step = smoketest.StepCleanup
+	default:
+		a.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		httpjson.WriteError(w, http.StatusNotFound, "NOT_FOUND", "no such route")
+		return
+	}
+	if step == a.failAt {
+		httpjson.WriteError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "injected failure")
+		return
+	}
+
+	switch step {
+	case smoketest.StepHealth:
+		httpjson.Write(w, http.StatusOK, map[string]string{"status": "healthy"})
+	case smoketest.StepCreate:
+		a.campaign = body
+		a.campaign["id"] = 42
+		a.campaign["status"] = "draft"
+		httpjson.Write(w, http.StatusCreated, a.campaign)
+	case smoketest.StepPreview:
+		customer := body["customer"].(map[string]interface{})
+		httpjson.Write(w, http.StatusOK, map[string]string{
+			"rendered_message": strings.Replace(smoketest.Template, "{first_name}", customer["first_name"].(string), 1),
+		})
+	case smoketest.StepDryRun:
+		httpjson.Write(w, http.StatusOK, map[string]interface{}{"audience_size": body["audience_size"]})
+	case smoketest.StepRealSend:
+		a.sentTo = body["customers"].([]interface{})[0].(map[string]interface{})["phone"].(string)
+		a.campaign["status"] = "sending"
+		httpjson.Write(w, http.StatusOK, map[string]interface{}{"messages_queued": 1, "status": "sending"})
+	case smoketest.StepList:
+		if r.URL.Query().Get("tag") != smoketest.Tag {
+			a.t.Errorf("list not filtered by the smoketest tag: %s", r.URL)
+		}
+		httpjson.Write(w, http.StatusOK, map[string]interface{}{"campaigns": []interface{}{a.campaign}})
+	case smoketest.StepCleanup:
+		if r.URL.Query().Get("force") != "true" {
+			a.t.Errorf("cleanup did not force the delete: %s", r.URL)
+		}
+		a.deleted = true
+		httpjson.Write(w, http.StatusOK, map[string]interface{}{"id": 42, "messages_deleted": 0})
+	}
+}
+
+func runSmokeTest(t *testing.T, server *httptest.Server, opts smoketest.Options) *smoketest.Report {
+	t.Helper()
+	opts.BaseURL = server.URL + "/"
+	runner := smoketest.NewRunner(opts)
+	runner.SetClock(func() time.Time { return time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC) })
+	return runner.Run(context.Background())
+}
+
+func stepStatuses(report *smoketest.Report) map[string]string {
+	statuses := map[string]string{}
+	for _, step := range report.Steps {
+		statuses[step.Name] = step.Status
+	}
+	return statuses
+}
+
+// TestSmokeTest_Passes tests a run where every step passes, with a real send to the test phone
+func TestSmokeTest_Passes(t *testing.T) {
+	api, server := newFakeSmokeAPI(t, "")
+
+	report := runSmokeTest(t, server, smoketest.Options{APIKey: "key-1", TestPhone: "+254712345678"})
+	AssertEqual(t, report.Passed, true)
+	AssertEqual(t, report.CampaignID, 42)
+	AssertEqual(t, report.CampaignName, "smoketest-20260504T120000Z")
+	AssertEqual(t, len(report.Steps), 7)
+	for _, step := range report.Steps {
+		AssertEqual(t, step.Status, smoketest.StepPassed)
+	}
+	AssertEqual(t, api.sentTo, "+254712345678")
+	AssertEqual(t, api.deleted, true)
+	AssertEqual(t, api.campaign["tags"].([]interface{})[0], smoketest.Tag)
+	for _, key := range api.keys {
+		AssertEqual(t, key, "key-1")
+	}
+
+	// The report is written as JSON with the -json flag
+	encoded, err := json.Marshal(report)
+	AssertNoError(t, err)
+	AssertContains(t, string(encoded), `"name":"real_send","status":"passed"`)
+}
+
+// TestSmokeTest_SkipsRealSend tests that the real send is skipped by flag or without a test phone
+func TestSmokeTest_SkipsRealSend(t *testing.T) {
+	for name, opts := range map[string]smoketest.Options{
+		"flag":          {TestPhone: "+254712345678", SkipRealSend: true},
+		"no test phone": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api, server := newFakeSmokeAPI(t, "")
+
+			report := runSmokeTest(t, server, opts)
+			AssertEqual(t, report.Passed, true)
+			AssertEqual(t, stepStatuses(report)[smoketest.StepRealSend], smoketest.StepSkipped)
+			AssertEqual(t, api.sentTo, "")
+			AssertEqual(t, api.deleted, true)
+		})
+	}
+}
+
+// TestSmokeTest_FailureAtEachStep tests that a failing step fails the run, skips the steps after
+// it, and still cleans up the campaign once one was created
+func TestSmokeTest_FailureAtEachStep(t *testing.T) {
+	steps := []string{
+		smoketest.StepHealth, smoketest.StepCreate, smoketest.StepPreview, smoketest.StepDryRun,
+		smoketest.StepRealSend, smoketest.StepList, smoketest.StepCleanup,
+	}
+	for i, failAt := range steps {
+		t.Run(failAt, func(t *testing.T) {
+			api, server := newFakeSmokeAPI(t, failAt)
+
+			report := runSmokeTest(t, server, smoketest.Options{TestPhone: "+254712345678"})
+			AssertEqual(t, report.Passed, false)
+
+			statuses := stepStatuses(report)
+			for j, name := range steps {
+				want := smoketest.StepPassed
+				switch {
+				case j == i:
+					want = smoketest.StepFailed
+				case name == smoketest.StepCleanup && i > 1:
+					// The campaign exists, so cleanup runs regardless
+				case j > i:
+					want = smoketest.StepSkipped
+				}
+				AssertEqual(t, statuses[name], want)
+			}
+			AssertEqual(t, api.deleted, i > 1 && failAt != smoketest.StepCleanup)
+
+			for _, step := range report.Steps {
+				if step.Status == smoketest.StepFailed {
+					AssertContains(t, step.Error, "status 500, want")
+					AssertContains(t, step.Error, "INTERNAL_ERROR: injected failure")
+				}
+			}
+		})
+	}
+}
+
+// TestSmokeTest_ListedFieldsChecked tests that a listed campaign not matching what was created
+// fails the list step
+func TestSmokeTest_ListedFieldsChecked(t *testing.T) {
+	api, server := newFakeSmokeAPI(t, "")
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/campaigns" {
+			listed := map[string]interface{}{}
+			for key, value := range api.campaign {
+				listed[key] = value
+			}
+			listed["base_template"] = "Something else"
+			httpjson.Write(w, http.StatusOK, map[string]interface{}{"campaigns": []interface{}{listed}})
+			return
+		}
+		api.serve(w, r)
+	})
+
+	report := runSmokeTest(t, server, smoketest.Options{SkipRealSend: true})
+	AssertEqual(t, report.Passed, false)
+	AssertEqual(t, stepStatuses(report)[smoketest.StepList], smoketest.StepFailed)
+	AssertContains(t, report.Steps[5].Error, `base_template "Something else"`)
+	AssertEqual(t, api.deleted, true)
+}
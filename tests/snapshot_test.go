@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/models"
+	"smsleopard/internal/snapshot"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// devSnapshot is the checked-in frontend fixture, relative to the tests package
+const devSnapshot = "../fixtures/dev.yaml"
+
+// argRecorder keeps the arguments of each statement it was asked to match
+type argRecorder struct {
+	rows []*[]driver.Value
+}
+
+// recordedArg is a sqlmock argument matcher that accepts anything and keeps it
+type recordedArg struct {
+	row *[]driver.Value
+}
+
+func (a recordedArg) Match(v driver.Value) bool {
+	*a.row = append(*a.row, v)
+	return true
+}
+
+// args returns matchers for a statement with n arguments
+func (r *argRecorder) args(n int) []driver.Value {
+	row := &[]driver.Value{}
+	r.rows = append(r.rows, row)
+	matchers := make([]driver.Value, n)
+	for i := range matchers {
+		matchers[i] = recordedArg{row: row}
+	}
+	return matchers
+}
+
+// values returns the recorded arguments in statement order
+func (r *argRecorder) values() [][]driver.Value {
+	values := make([][]driver.Value, len(r.rows))
+	for i, row := range r.rows {
+		values[i] = *row
+	}
+	return values
+}
+
+// expectSnapshotLoad expects one load of snap, recording the state each statement writes
+// With existing set the messages are found by the update; otherwise each is inserted
+func expectSnapshotLoad(mock sqlmock.Sqlmock, snap *snapshot.Snapshot, existing bool) *argRecorder {
+	recorder := &argRecorder{}
+	mock.ExpectBegin()
+	for _, customer := range snap.Customers {
+		mock.ExpectQuery("INSERT INTO customers").WithArgs(recorder.args(7)...).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(customer.ID))
+	}
+	for _, campaign := range snap.Campaigns {
+		mock.ExpectQuery("INSERT INTO campaigns").WithArgs(recorder.args(10)...).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(campaign.ID))
+		for _, message := range campaign.Messages {
+			if existing {
+				mock.ExpectQuery("UPDATE outbound_messages").WithArgs(recorder.args(9)...).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(message.ID))
+				continue
+			}
+			mock.ExpectQuery("UPDATE outbound_messages").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			mock.ExpectExec("INSERT INTO outbound_messages").WithArgs(recorder.args(10)...).
+				WillReturnResult(sqlmock.NewResult(int64(message.ID), 1))
+		}
+		mock.ExpectExec("DELETE FROM outbound_messages").WithArgs(campaign.ID, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	for i := 0; i < 3; i++ {
+		mock.ExpectExec("SELECT setval").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectCommit()
+	return recorder
+}
+
+// TestSnapshot_ParseOffset tests relative time offsets
+func TestSnapshot_ParseOffset(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"now", 0},
+		{"-3d", -72 * time.Hour},
+		{"+48h", 48 * time.Hour},
+		{"-1d6h30m", -(30*time.Hour + 30*time.Minute)},
+		{"90m", 90 * time.Minute},
+		{"0d", 0},
+	}
+	for _, tt := range tests {
+		got, err := snapshot.ParseOffset(tt.value)
+		AssertNoError(t, err)
+		AssertEqual(t, got, tt.want)
+	}
+
+	for _, value := range []string{"", "yesterday", "3", "-xd", "+-2h", "-d"} {
+		if _, err := snapshot.ParseOffset(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+// TestSnapshot_DevFixture tests that the checked-in fixture parses and covers what the
+// frontend needs: every campaign status, both channels, customers with missing fields and a
+// campaign with mixed message outcomes
+func TestSnapshot_DevFixture(t *testing.T) {
+	snap, err := snapshot.ReadFile(devSnapshot)
+	AssertNoError(t, err)
+
+	statuses := make(map[models.CampaignStatus]bool)
+	channels := make(map[models.Channel]bool)
+	mixed := false
+	for _, campaign := range snap.Campaigns {
+		statuses[campaign.Status] = true
+		channels[campaign.Channel] = true
+		outcomes := make(map[models.MessageStatus]bool)
+		for _, message := range campaign.Messages {
+			outcomes[message.Status] = true
+		}
+		mixed = mixed || len(outcomes) == 3
+	}
+	for status := range models.CampaignTransitions {
+		if !statuses[status] {
+			t.Errorf("Expected a campaign with status %s", status)
+		}
+	}
+	AssertEqual(t, len(channels), 2)
+	if !mixed {
+		t.Error("Expected a campaign with pending, sent and failed messages")
+	}
+
+	nullFields := 0
+	for _, customer := range snap.Customers {
+		if customer.FirstName == nil || customer.LastName == nil || customer.Location == nil || customer.PreferredProduct == nil {
+			nullFields++
+		}
+	}
+	if nullFields == 0 {
+		t.Error("Expected customers with missing fields")
+	}
+}
+
+// TestSnapshot_Invalid tests that snapshots with broken references or unknown fields are refused
+func TestSnapshot_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "unknown customer",
+			yaml: `
+customers: [{id: 1, phone: "+254700000001"}]
+campaigns:
+  - {id: 1, name: A, channel: sms, status: sending, template: Hi, messages: [{id: 1, customer: "+254700000002", status: sent}]}`,
+			wantErr: `campaign "A": message 1 is to unknown customer "+254700000002"`,
+		},
+		{
+			name: "duplicate campaign id",
+			yaml: `
+campaigns:
+  - {id: 1, name: A, channel: sms, status: draft, template: Hi}
+  - {id: 1, name: B, channel: sms, status: draft, template: Hi}`,
+			wantErr: `campaign "B": id 1 or name listed twice`,
+		},
+		{
+			name:    "scheduled without time",
+			yaml:    `campaigns: [{id: 1, name: A, channel: sms, status: scheduled, template: Hi}]`,
+			wantErr: `campaign "A": a scheduled campaign needs scheduled_at`,
+		},
+		{
+			name:    "unknown status",
+			yaml:    `campaigns: [{id: 1, name: A, channel: sms, status: archived, template: Hi}]`,
+			wantErr: `campaign "A": unknown status "archived"`,
+		},
+		{
+			name:    "unknown field",
+			yaml:    `customers: [{id: 1, phone: "+254700000001", email: a@example.com}]`,
+			wantErr: "field email not found",
+		},
+		{
+			name:    "bad relative time",
+			yaml:    `customers: [{id: 1, phone: "+254700000001", created_at: yesterday}]`,
+			wantErr: `invalid relative time "yesterday"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := snapshot.Parse(strings.NewReader(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestSnapshot_LoadTwice tests that loading the fixture into a database that already has it
+// writes exactly the state of the first load
+func TestSnapshot_LoadTwice(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	snap, err := snapshot.ReadFile(devSnapshot)
+	AssertNoError(t, err)
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	first := expectSnapshotLoad(mock, snap, false)
+	result, err := snapshot.Load(context.Background(), db, snap, now)
+	AssertNoError(t, err)
+	AssertEqual(t, result.Customers, 7)
+	AssertEqual(t, result.Campaigns, 11)
+	AssertEqual(t, result.Messages, 17)
+
+	second := expectSnapshotLoad(mock, snap, true)
+	_, err = snapshot.Load(context.Background(), db, snap, now)
+	AssertNoError(t, err)
+	AssertNoError(t, mock.ExpectationsWereMet())
+
+	// Inserted messages carry their ID first; the update matches on it instead
+	firstRows, secondRows := first.values(), second.values()
+	AssertEqual(t, len(firstRows), len(secondRows))
+	for i := range firstRows {
+		want := firstRows[i]
+		if len(want) == len(secondRows[i])+1 {
+			want = want[1:]
+		}
+		if !reflect.DeepEqual(want, secondRows[i]) {
+			t.Errorf("Statement %d wrote %v, then %v", i, want, secondRows[i])
+		}
+	}
+
+	// Times resolve against the load time
+	sending := firstRows[len(snap.Customers)+3]
+	AssertEqual(t, sending[1], "Dev: Sending Flash Sale")
+	AssertEqual(t, sending[9], now.Add(-2*time.Hour))
+	failedMessage := firstRows[len(snap.Customers)+3+3]
+	AssertEqual(t, failedMessage[3], "failed")
+	AssertEqual(t, failedMessage[9], now.Add(-time.Hour))
+}
+
+// TestSnapshot_IDTaken tests that a customer existing under another ID fails the whole load
+func TestSnapshot_IDTaken(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	snap, err := snapshot.Parse(strings.NewReader(`customers: [{id: 9001, phone: "+254700900001"}]`))
+	AssertNoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO customers").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(14))
+	mock.ExpectRollback()
+
+	_, err = snapshot.Load(context.Background(), db, snap, time.Now())
+	AssertError(t, err, "customer +254700900001 already exists with ID 14, not 9001; delete it or load into an empty database")
+	AssertNoError(t, mock.ExpectationsWereMet())
+
+	// A failing statement rolls everything back too
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO customers").WillReturnError(errors.New("connection refused"))
+	mock.ExpectRollback()
+
+	_, err = snapshot.Load(context.Background(), db, snap, time.Now())
+	AssertError(t, err, "failed to upsert customer +254700900001: connection refused")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// statsRebuildFixture is a sent campaign whose event history says:
+// message 1 was sent, message 2 failed for good on its third attempt, message 3 moved to
+// another campaign, message 4 was sent in simulation and message 6 was sent
+type statsRebuildFixture struct {
+	events    *MockCampaignEventRepository
+	stats     *MockStatsRebuildRepository
+	rebuilder *service.StatsRebuilder
+}
+
+func newStatsRebuildFixture(t *testing.T) *statsRebuildFixture {
+	t.Helper()
+	timeout := "provider timeout"
+	moved := 3
+
+	history := []*models.CampaignEvent{NewTestCampaignStatusEvent(1, models.CampaignStatusDraft, models.CampaignStatusSending)}
+	for _, id := range []int{1, 2, 3, 4, 6} {
+		history = append(history, NewTestMessageEvent(1, &models.MessageSummary{ID: id, Status: models.MessageStatusPending}))
+	}
+	events := NewMockCampaignEventRepository(append(history,
+		&models.CampaignEvent{CampaignID: 1, Type: models.CampaignEventQueued, Payload: json.RawMessage(`{"messages_queued": 5}`), Version: 1},
+		NewTestMessageEvent(1, &models.MessageSummary{ID: 1, Status: models.MessageStatusSent}),
+		NewTestMessageEvent(1, &models.MessageSummary{ID: 2, Status: models.MessageStatusFailed, RetryCount: 1, LastError: &timeout}),
+		NewTestMessageEvent(1, &models.MessageSummary{ID: 2, Status: models.MessageStatusFailed, RetryCount: 3, LastError: &timeout}),
+		&models.CampaignEvent{CampaignID: 1, Type: models.CampaignEventMessageMoved, MessageID: &moved, Payload: json.RawMessage(`{"to_campaign_id": 2}`), Version: 1},
+		NewTestMessageEvent(1, &models.MessageSummary{ID: 4, Status: models.MessageStatusSent, Simulated: true}),
+		NewTestMessageEvent(1, &models.MessageSummary{ID: 6, Status: models.MessageStatusSent}),
+		NewTestCampaignStatusEvent(1, models.CampaignStatusSending, models.CampaignStatusSent),
+	)...)
+
+	// What is stored agrees with the history, apart from message 6 being deleted behind its back
+	stats := NewMockStatsRebuildRepository(events)
+	stats.Messages[1] = &models.MessageSummary{ID: 1, Status: models.MessageStatusSent}
+	stats.Messages[2] = &models.MessageSummary{ID: 2, Status: models.MessageStatusFailed, RetryCount: 3, LastError: &timeout}
+	stats.Messages[4] = &models.MessageSummary{ID: 4, Status: models.MessageStatusSent, Simulated: true}
+	stats.Status = models.CampaignStatusSent
+	stats.Spend = 1.0
+
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		if id != 1 {
+			return nil, repository.ErrCampaignNotFound
+		}
+		campaign := NewTestCampaignWithStatus(stats.Status)
+		budget, spend := 100.0, stats.Spend
+		campaign.Budget, campaign.Spend = &budget, &spend
+		return campaign, nil
+	}
+
+	return &statsRebuildFixture{
+		events:    events,
+		stats:     stats,
+		rebuilder: service.NewStatsRebuilder(campaignRepo, events, stats, config.SendingConfig{CostPerSMS: 0.5}),
+	}
+}
+
+// TestStatsRebuild_NothingToCorrect tests that a campaign whose stored state agrees with its
+// history has no discrepancies, and only what cannot be rebuilt is reported
+func TestStatsRebuild_NothingToCorrect(t *testing.T) {
+	f := newStatsRebuildFixture(t)
+	// Messages 1 and 6 were sent at 0.50; the simulated send cost nothing
+	f.stats.Messages[5] = &models.MessageSummary{ID: 5, Status: models.MessageStatusPending}
+
+	report, err := f.rebuilder.Rebuild(context.Background(), 1, false)
+	AssertNoError(t, err)
+
+	AssertEqual(t, report.EventsReplayed, 14)
+	AssertEqual(t, report.Messages, 4)
+	AssertEqual(t, len(report.Discrepancies), 0)
+	AssertEqual(t, report.Corrected, 0)
+	AssertEqual(t, len(report.UntrackedMessages), 1)
+	AssertEqual(t, report.UntrackedMessages[0], 5)
+	AssertEqual(t, len(report.MissingMessages), 1)
+	AssertEqual(t, report.MissingMessages[0], 6)
+}
+
+// TestStatsRebuild_RestoresCorruptedStats tests that message summaries, the campaign status
+// and the spend counter corrupted behind the event log are restored, and that a second
+// rebuild finds nothing left to correct
+func TestStatsRebuild_RestoresCorruptedStats(t *testing.T) {
+	f := newStatsRebuildFixture(t)
+	f.stats.Messages[1].Status = models.MessageStatusPending
+	f.stats.Messages[2].RetryCount, f.stats.Messages[2].LastError = 0, nil
+	f.stats.Messages[4].Simulated = false
+	f.stats.Status = models.CampaignStatusPaused
+	f.stats.Spend = 7.5
+
+	report, err := f.rebuilder.Rebuild(context.Background(), 1, false)
+	AssertNoError(t, err)
+
+	byField := map[string]service.StatsDiscrepancy{}
+	for _, d := range report.Discrepancies {
+		key := d.Field
+		if d.MessageID != nil {
+			key = fmt.Sprintf("%d:%s", *d.MessageID, d.Field)
+		}
+		byField[key] = d
+	}
+	AssertEqual(t, len(report.Discrepancies), 6)
+	AssertEqual(t, byField["1:status"].Replayed, "sent")
+	AssertEqual(t, byField["2:retry_count"].Recorded, "0")
+	AssertEqual(t, byField["2:retry_count"].Replayed, "3")
+	AssertEqual(t, byField["2:last_error"].Replayed, "provider timeout")
+	AssertEqual(t, byField["4:simulated"].Replayed, "true")
+	AssertEqual(t, byField["campaign.status"].Recorded, "paused")
+	AssertEqual(t, byField["campaign.spend"].Recorded, "7.50")
+	AssertEqual(t, byField["campaign.spend"].Replayed, "1.00")
+	AssertEqual(t, report.Corrected, 5)
+
+	AssertEqual(t, f.stats.Messages[1].Status, models.MessageStatusSent)
+	AssertEqual(t, f.stats.Messages[2].RetryCount, 3)
+	AssertEqual(t, *f.stats.Messages[2].LastError, "provider timeout")
+	AssertEqual(t, f.stats.Messages[4].Simulated, true)
+	AssertEqual(t, f.stats.Status, models.CampaignStatusSent)
+	AssertEqual(t, f.stats.Spend, 1.0)
+
+	// The corrections were recorded, so replaying again agrees with them
+	report, err = f.rebuilder.Rebuild(context.Background(), 1, false)
+	AssertNoError(t, err)
+	AssertEqual(t, len(report.Discrepancies), 0)
+	AssertEqual(t, report.Corrected, 0)
+}
+
+// TestStatsRebuild_DryRun tests that a dry run reports discrepancies without correcting them,
+// and is the only rebuild allowed while the campaign is sending
+func TestStatsRebuild_DryRun(t *testing.T) {
+	f := newStatsRebuildFixture(t)
+	f.stats.Messages[1].Status = models.MessageStatusFailed
+	f.stats.Status = models.CampaignStatusSending
+
+	_, err := f.rebuilder.Rebuild(context.Background(), 1, false)
+	AssertError(t, err, "business logic error: campaign is sending; wait for it to stop or use a dry run")
+
+	report, err := f.rebuilder.Rebuild(context.Background(), 1, true)
+	AssertNoError(t, err)
+	AssertEqual(t, report.DryRun, true)
+	AssertEqual(t, len(report.Discrepancies), 2)
+	AssertEqual(t, report.Corrected, 0)
+	AssertEqual(t, f.stats.Calls["CorrectMessage"], 0)
+	AssertEqual(t, f.stats.Calls["CorrectCampaignStatus"], 0)
+	AssertEqual(t, f.stats.Messages[1].Status, models.MessageStatusFailed)
+
+	_, err = f.rebuilder.Rebuild(context.Background(), 2, true)
+	AssertError(t, err, "campaign with ID 2 not found")
+}
+
+// TestReplayCampaignEvents_RejectsUnknownEvents tests that replay stops at an event it cannot
+// read rather than rebuilding the wrong state
+func TestReplayCampaignEvents_RejectsUnknownEvents(t *testing.T) {
+	event := NewTestMessageEvent(1, &models.MessageSummary{ID: 1, Status: models.MessageStatusSent})
+	event.ID = 7
+
+	replay, err := service.ReplayCampaignEvents([]*models.CampaignEvent{event})
+	AssertNoError(t, err)
+	AssertEqual(t, replay.Messages[1].Status, models.MessageStatusSent)
+
+	event.Version = 2
+	_, err = service.ReplayCampaignEvents([]*models.CampaignEvent{event})
+	AssertError(t, err, "event 7 has unsupported version 2")
+
+	event.Version, event.Type = 1, "message_bounced"
+	_, err = service.ReplayCampaignEvents([]*models.CampaignEvent{event})
+	AssertError(t, err, `event 7 has unknown type "message_bounced"`)
+}
+
+// TestCampaignEventRepository_ListSinceSendEventsOnly tests that consumers polling a campaign's
+// events do not see its history events
+func TestCampaignEventRepository_ListSinceSendEventsOnly(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewCampaignEventRepository(db)
+
+	mock.ExpectQuery(`WHERE campaign_id = \$1 AND id > \$2 AND type IN \('queued', 'progress', 'completed'\)`).
+		WithArgs(1, int64(0), 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "type", "payload", "dedupe_key", "created_at"}))
+	_, err := repo.ListSince(context.Background(), 1, 0, 100)
+	AssertNoError(t, err)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// joinActions renders allowed actions as a comma-separated list for comparison
+func joinActions(actions []models.CampaignAction) string {
+	names := make([]string, len(actions))
+	for i, action := range actions {
+		names[i] = string(action)
+	}
+	return strings.Join(names, ",")
+}
+
+// TestStatusInfo_EveryStatus tests the label, terminal flag and allowed actions of every status
+func TestStatusInfo_EveryStatus(t *testing.T) {
+	tests := []struct {
+		status   models.CampaignStatus
+		label    string
+		terminal bool
+		actions  string
+	}{
+		{models.CampaignStatusDraft, "Draft", false, "send,re_render"},
+		{models.CampaignStatusScheduled, "Scheduled", false, "send,re_render"},
+		{models.CampaignStatusPendingApproval, "Pending approval", false, "approve,reject,re_render"},
+		{models.CampaignStatusSending, "Sending", false, "re_render,cancel"},
+		{models.CampaignStatusPaused, "Paused", false, "resume,cancel"},
+		{models.CampaignStatusCancelling, "Cancelling", false, "undo_cancel"},
+		{models.CampaignStatusCancelled, "Cancelled", true, ""},
+		{models.CampaignStatusSent, "Sent", true, ""},
+		{models.CampaignStatusFailed, "Failed", true, ""},
+		{models.CampaignStatus("archived"), "archived", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			info := handler.NewStatusInfo(tt.status)
+			AssertEqual(t, info.Value, tt.status)
+			AssertEqual(t, info.Label, tt.label)
+			AssertEqual(t, info.Terminal, tt.terminal)
+			AssertEqual(t, joinActions(info.AllowedActions), tt.actions)
+			AssertNotNil(t, info.AllowedActions)
+		})
+	}
+}
+
+// TestCampaignActions_FollowTransitions tests that no action is offered whose status change the transition table forbids
+func TestCampaignActions_FollowTransitions(t *testing.T) {
+	targets := map[models.CampaignAction]models.CampaignStatus{
+		models.CampaignActionSend:       models.CampaignStatusSending,
+		models.CampaignActionApprove:    models.CampaignStatusSending,
+		models.CampaignActionReject:     models.CampaignStatusDraft,
+		models.CampaignActionCancel:     models.CampaignStatusCancelling,
+		models.CampaignActionUndoCancel: models.CampaignStatusSending,
+	}
+
+	for status := range models.CampaignTransitions {
+		for _, action := range status.AllowedActions() {
+			if to, ok := targets[action]; ok && !status.CanTransition(to) {
+				t.Errorf("%s is offered on %s but %s to %s is not a valid transition", action, status, status, to)
+			}
+		}
+	}
+
+	// The service checks use the same rules
+	for status := range models.CampaignTransitions {
+		campaign := NewTestCampaignWithStatus(status)
+		AssertEqual(t, campaign.CanSend(), status.Allows(models.CampaignActionSend))
+	}
+	AssertEqual(t, models.CampaignStatusDraft.Allows(models.CampaignAction("pause")), false)
+}
+
+// TestCampaignEndpoints_IncludeStatusInfo tests that campaign responses carry status_info
+func TestCampaignEndpoints_IncludeStatusInfo(t *testing.T) {
+	svc, campaignRepo, _, _ := setupApprovalTest(t)
+	campaignRepo.GetWithStatsFunc = func(ctx context.Context, id int) (*models.CampaignWithStats, error) {
+		return &models.CampaignWithStats{Campaign: *NewTestCampaignWithStatus(models.CampaignStatusPendingApproval)}, nil
+	}
+	campaignRepo.ListFunc = func(ctx context.Context, filters repository.CampaignFilters) ([]*models.Campaign, int, error) {
+		return []*models.Campaign{NewTestCampaignWithStatus(models.CampaignStatusSent)}, 1, nil
+	}
+
+	campaignHandler := handler.NewCampaignHandler(svc)
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns", campaignHandler.List).Methods("GET")
+	router.HandleFunc("/campaigns/{id}", campaignHandler.GetByID).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/campaigns/1", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var campaign handler.CampaignWithStatsResponse
+	ParseJSONResponse(t, resp, &campaign)
+	AssertEqual(t, campaign.Status, models.CampaignStatusPendingApproval)
+	AssertEqual(t, campaign.StatusInfo.Label, "Pending approval")
+	AssertEqual(t, joinActions(campaign.StatusInfo.AllowedActions), "approve,reject,re_render")
+
+	req = httptest.NewRequest("GET", "/campaigns", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var list struct {
+		Campaigns []struct {
+			Status     string             `json:"status"`
+			StatusInfo handler.StatusInfo `json:"status_info"`
+		} `json:"campaigns"`
+	}
+	ParseJSONResponse(t, resp, &list)
+	AssertEqual(t, len(list.Campaigns), 1)
+	AssertEqual(t, list.Campaigns[0].StatusInfo.Terminal, true)
+	AssertEqual(t, len(list.Campaigns[0].StatusInfo.AllowedActions), 0)
+}
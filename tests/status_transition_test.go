@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// TestCampaignStatus_TransitionMatrix tests every from/to pair against the expected table
+func TestCampaignStatus_TransitionMatrix(t *testing.T) {
+	draft := models.CampaignStatusDraft
+	scheduled := models.CampaignStatusScheduled
+	pending := models.CampaignStatusPendingApproval
+	sending := models.CampaignStatusSending
+	sent := models.CampaignStatusSent
+	failed := models.CampaignStatusFailed
+
+	statuses := []models.CampaignStatus{draft, scheduled, pending, sending, sent, failed}
+
+	allowed := map[models.CampaignStatus]map[models.CampaignStatus]bool{
+		draft:     {scheduled: true, pending: true, sending: true},
+		scheduled: {draft: true, pending: true, sending: true},
+		pending:   {draft: true, sending: true},
+		sending:   {sent: true, failed: true},
+		sent:      {},
+		failed:    {},
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			t.Run(string(from)+"_to_"+string(to), func(t *testing.T) {
+				AssertEqual(t, from.CanTransition(to), allowed[from][to])
+			})
+		}
+	}
+
+	// Unknown statuses cannot move anywhere
+	AssertEqual(t, models.CampaignStatus("archived").CanTransition(draft), false)
+}
+
+// TestUpdateStatusIf_RejectsInvalidTransition tests that forbidden transitions never reach the database
+func TestUpdateStatusIf_RejectsInvalidTransition(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaignRepo := repository.NewCampaignRepository(db)
+	err := campaignRepo.UpdateStatusIf(context.Background(), 1, models.CampaignStatusSent, models.CampaignStatusSending)
+
+	var transitionErr *models.InvalidTransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("Expected InvalidTransitionError but got %v", err)
+	}
+	AssertEqual(t, transitionErr.From, models.CampaignStatusSent)
+	AssertEqual(t, transitionErr.To, models.CampaignStatusSending)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestUpdateStatusIf_StatusChanged tests that a campaign moved by someone else reports its actual status
+func TestUpdateStatusIf_StatusChanged(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE campaigns SET status = (.+) WHERE id = (.+) AND status = ").
+		WithArgs(models.CampaignStatusSending, 1, models.CampaignStatusDraft).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT status FROM campaigns WHERE id").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.CampaignStatusSent))
+
+	campaignRepo := repository.NewCampaignRepository(db)
+	err := campaignRepo.UpdateStatusIf(context.Background(), 1, models.CampaignStatusDraft, models.CampaignStatusSending)
+
+	var transitionErr *models.InvalidTransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("Expected InvalidTransitionError but got %v", err)
+	}
+	AssertEqual(t, transitionErr.From, models.CampaignStatusSent)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestUpdateStatusIf_NotFound tests that a missing campaign is reported as not found
+func TestUpdateStatusIf_NotFound(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE campaigns").
+		WithArgs(models.CampaignStatusSending, 99, models.CampaignStatusDraft).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT status FROM campaigns WHERE id").
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}))
+
+	campaignRepo := repository.NewCampaignRepository(db)
+	err := campaignRepo.UpdateStatusIf(context.Background(), 99, models.CampaignStatusDraft, models.CampaignStatusSending)
+	AssertError(t, err, "campaign not found")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestStatusTransitionEndpoints tests that invalid transitions surface as 422 from the handlers
+func TestStatusTransitionEndpoints(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaignRepo := NewMockCampaignRepository()
+	messageRepo := NewMockMessageRepository()
+	svc := service.NewCampaignService(
+		campaignRepo,
+		NewMockCustomerRepository(),
+		messageRepo,
+		service.NewTemplateService(),
+		NewMockPublisher(),
+		db,
+		config.ApprovalConfig{},
+	)
+	campaignHandler := handler.NewCampaignHandler(svc)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/send", campaignHandler.Send).Methods("POST")
+	router.HandleFunc("/campaigns/{id}/reject", campaignHandler.Reject).Methods("POST")
+
+	// Campaign was sent by another request between the read and the update
+	campaignRepo.UpdateStatusIfFunc = func(ctx context.Context, id int, from, to models.CampaignStatus) error {
+		return &models.InvalidTransitionError{From: models.CampaignStatusSent, To: to}
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	req := NewJSONRequest(t, "POST", "/campaigns/1/send", map[string]interface{}{"customer_ids": []int{1, 2}})
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusUnprocessableEntity)
+	var errResp handler.ErrorResponse
+	ParseJSONResponse(t, resp, &errResp)
+	AssertEqual(t, errResp.Error.Code, "INVALID_STATUS_TRANSITION")
+	AssertContains(t, errResp.Error.Message, "from sent to sending")
+	// The losing send's transaction rolled back before creating any messages
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 0)
+
+	// Pending campaign approved by another admin before the reject landed
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusPendingApproval), nil
+	}
+	campaignRepo.UpdateStatusIfFunc = func(ctx context.Context, id int, from, to models.CampaignStatus) error {
+		return &models.InvalidTransitionError{From: models.CampaignStatusSending, To: to}
+	}
+
+	req = httptest.NewRequest("POST", "/campaigns/1/reject", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusUnprocessableEntity)
+	AssertEqual(t, campaignRepo.Calls["ClearSendPlan"], 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
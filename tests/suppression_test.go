@@ -0,0 +1,263 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// setupSuppressionTest creates a campaign service with an in-memory suppression list
+func setupSuppressionTest(t *testing.T) (*service.CampaignService, *MockSuppressionRepository, *MockMessageRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	svc, _, messageRepo, mock := setupApprovalTest(t)
+	suppressions := NewMockSuppressionRepository()
+	svc.SetSuppressions(suppressions)
+	return svc, suppressions, messageRepo, mock
+}
+
+// TestSuppression_OverridesExplicitCustomerIDs tests that a suppressed phone is skipped even when its customer is named in the send
+func TestSuppression_OverridesExplicitCustomerIDs(t *testing.T) {
+	svc, suppressions, messageRepo, mock := setupSuppressionTest(t)
+	suppressions.Phones[1] = []string{"+254700000002"}
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	var queued []*models.OutboundMessage
+	messageRepo.CreateBatchFunc = func(ctx context.Context, messages []*models.OutboundMessage) error {
+		queued = messages
+		return nil
+	}
+
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+
+	AssertEqual(t, result.MessagesQueued, 1)
+	AssertEqual(t, result.SkippedSuppressed, 1)
+	AssertEqual(t, queued[0].CustomerID, 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+
+	// Another campaign's list does not apply
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	suppressions.Phones = map[int][]string{2: {"+254700000001", "+254700000002"}}
+	result, err = svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertEqual(t, result.SkippedSuppressed, 0)
+}
+
+// TestSuppression_AllSuppressed tests that a send whose whole audience is suppressed is refused
+func TestSuppression_AllSuppressed(t *testing.T) {
+	svc, suppressions, messageRepo, _ := setupSuppressionTest(t)
+	suppressions.Phones[1] = []string{"+254700000001", "+254700000002"}
+
+	_, err := svc.SendCampaign(context.Background(), 1, []int{1, 2}, service.SendOptions{})
+	AssertError(t, err, "business logic error: all 2 customers are on the campaign's suppression list")
+	AssertEqual(t, messageRepo.Calls["CreateBatch"], 0)
+}
+
+// TestSuppression_ApprovalRechecks tests that phones suppressed while a send awaits approval are skipped
+func TestSuppression_ApprovalRechecks(t *testing.T) {
+	svc, campaignRepo, _, mock := setupApprovalTest(t)
+	svc.SetSuppressions(NewMockSuppressionRepository())
+
+	result, err := svc.SendCampaign(context.Background(), 1, []int{1, 2, 3}, service.SendOptions{})
+	AssertNoError(t, err)
+	AssertEqual(t, result.Status, models.CampaignStatusPendingApproval)
+
+	_, err = svc.AddSuppressions(context.Background(), 1, []string{"0700 000 003"}, "")
+	AssertNoError(t, err)
+
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		return NewTestCampaignWithStatus(models.CampaignStatusPendingApproval), nil
+	}
+	campaignRepo.GetSendPlanFunc = func(ctx context.Context, id int) (*models.SendPlan, error) {
+		return &models.SendPlan{CustomerIDs: []int{1, 2, 3}, AudienceSize: 3}, nil
+	}
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	result, err = svc.ApproveCampaign(context.Background(), 1)
+	AssertNoError(t, err)
+	AssertEqual(t, result.MessagesQueued, 2)
+	AssertEqual(t, result.SkippedSuppressed, 1)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestAddSuppressions_Normalizes tests that phones are normalized and de-duplicated on ingest and invalid ones rejected
+func TestAddSuppressions_Normalizes(t *testing.T) {
+	svc, suppressions, _, _ := setupSuppressionTest(t)
+
+	result, err := svc.AddSuppressions(context.Background(), 1, []string{"0712 345 678", "+254712345678", "254733000111"}, "user-1")
+	AssertNoError(t, err)
+	AssertEqual(t, result.Received, 3)
+	AssertEqual(t, result.Added, 2)
+	AssertEqual(t, len(suppressions.Phones[1]), 2)
+	AssertEqual(t, suppressions.Phones[1][0], "+254712345678")
+	AssertEqual(t, suppressions.Phones[1][1], "+254733000111")
+
+	// Phones already on the list are not added again
+	result, err = svc.AddSuppressions(context.Background(), 1, []string{"0712345678"}, "")
+	AssertNoError(t, err)
+	AssertEqual(t, result.Added, 0)
+
+	_, err = svc.AddSuppressions(context.Background(), 1, []string{"+254712345678", "not a phone"}, "")
+	AssertError(t, err, `validation error: phones[1]: invalid phone number "not a phone"`)
+	_, err = svc.AddSuppressions(context.Background(), 1, []string{}, "")
+	AssertError(t, err, "validation error: at least one phone required")
+	AssertEqual(t, suppressions.Calls["Add"], 2)
+}
+
+// TestAddSuppressionsCSV tests CSV uploads, rejecting the whole file when a row is invalid
+func TestAddSuppressionsCSV(t *testing.T) {
+	svc, suppressions, _, _ := setupSuppressionTest(t)
+
+	result, err := svc.AddSuppressionsCSV(context.Background(), 1, strings.NewReader("phone,name\n0712345678,Amina\n+254733000111,Baraka\n"), "", "")
+	AssertNoError(t, err)
+	AssertEqual(t, result.Added, 2)
+
+	_, err = svc.AddSuppressionsCSV(context.Background(), 1, strings.NewReader("phone\n0700000009\n12\n"), "", "")
+	AssertError(t, err, `validation error: 1 invalid rows, first at line 3: invalid phone number "12"`)
+	AssertEqual(t, len(suppressions.Phones[1]), 2)
+
+	_, err = svc.AddSuppressionsCSV(context.Background(), 1, strings.NewReader("name\nAmina\n"), "", "")
+	AssertError(t, err, "validation error: header is missing required columns: phone")
+}
+
+// TestSuppression_WorkerRejects tests that the worker does not send to a phone suppressed after queueing
+func TestSuppression_WorkerRejects(t *testing.T) {
+	sender := &countingSender{}
+	f := newProcessingErrorFixture(t, sender)
+	suppressions := NewMockSuppressionRepository()
+	suppressions.Phones[1] = []string{"+254700000001"}
+	f.processor.SetSuppressions(suppressions)
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'failed'").
+		WithArgs(sqlmock.AnyArg(), "Phone suppressed for this campaign").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := f.processor.Handle(&queue.MessageJob{MessageID: 9})
+	AssertNoError(t, err)
+	AssertEqual(t, sender.calls, 0)
+	AssertNoError(t, f.mock.ExpectationsWereMet())
+
+	// Other phones are sent
+	suppressions.Phones[1] = []string{"+254711111111"}
+	f.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").WillReturnResult(sqlmock.NewResult(0, 1))
+	err = f.processor.Handle(&queue.MessageJob{MessageID: 10})
+	AssertNoError(t, err)
+	AssertEqual(t, sender.calls, 1)
+}
+
+// TestSuppressionEndpoints tests adding by JSON and CSV, listing and clearing over HTTP
+func TestSuppressionEndpoints(t *testing.T) {
+	svc, _, _, _ := setupSuppressionTest(t)
+	h := handler.NewCampaignHandler(svc)
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/suppressions", h.AddSuppressions).Methods("POST")
+	router.HandleFunc("/campaigns/{id}/suppressions", h.ListSuppressions).Methods("GET")
+	router.HandleFunc("/campaigns/{id}/suppressions", h.ClearSuppressions).Methods("DELETE")
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := serve(httptest.NewRequest("POST", "/campaigns/1/suppressions", strings.NewReader(`["0712 345 678"]`)))
+	AssertStatusCode(t, resp, http.StatusOK)
+	var added service.SuppressionResult
+	ParseJSONResponse(t, resp, &added)
+	AssertEqual(t, added.Added, 1)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "suppressions.csv")
+	AssertNoError(t, err)
+	part.Write([]byte("phone\n+254733000111\n"))
+	writer.Close()
+	req := httptest.NewRequest("POST", "/campaigns/1/suppressions", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	AssertStatusCode(t, serve(req), http.StatusOK)
+
+	req = httptest.NewRequest("POST", "/campaigns/1/suppressions", strings.NewReader("phone\n+254744000222\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	AssertStatusCode(t, serve(req), http.StatusOK)
+
+	resp = serve(httptest.NewRequest("GET", "/campaigns/1/suppressions", nil))
+	AssertStatusCode(t, resp, http.StatusOK)
+	var list service.SuppressionList
+	ParseJSONResponse(t, resp, &list)
+	AssertEqual(t, list.Total, 3)
+	AssertEqual(t, list.Suppressions[0].Phone, "+254712345678")
+
+	resp = serve(httptest.NewRequest("DELETE", "/campaigns/1/suppressions", nil))
+	AssertStatusCode(t, resp, http.StatusOK)
+	var cleared service.SuppressionResult
+	ParseJSONResponse(t, resp, &cleared)
+	AssertEqual(t, cleared.Removed, 3)
+
+	for body, status := range map[string]int{
+		`{"phones": ["0712345678"]}`: http.StatusBadRequest,
+		`["nope"]`:                   http.StatusBadRequest,
+		``:                           http.StatusBadRequest,
+	} {
+		AssertStatusCode(t, serve(httptest.NewRequest("POST", "/campaigns/1/suppressions", strings.NewReader(body))), status)
+	}
+}
+
+// TestSuppressionRepository_Queries tests the insert, lookup and clear queries
+func TestSuppressionRepository_Queries(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewSuppressionRepository(db)
+
+	mock.ExpectExec(`INSERT INTO campaign_suppressions \(campaign_id, phone, added_by\) SELECT \$1, phone, \$3 FROM unnest\(\$2::text\[\]\) AS phone ON CONFLICT \(campaign_id, phone\) DO NOTHING`).
+		WithArgs(1, "{\"+254712345678\"}", "user-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	added, err := repo.Add(context.Background(), 1, []string{"+254712345678"}, StringPtr("user-1"))
+	AssertNoError(t, err)
+	AssertEqual(t, added, 1)
+
+	mock.ExpectQuery(`SELECT DISTINCT normalize_phone\(phone\) FROM campaign_suppressions WHERE campaign_id = \$1 AND normalize_phone\(phone\) = ANY\(\$2\)`).
+		WithArgs(1, "{\"+254712345678\",\"+254733000111\"}").
+		WillReturnRows(sqlmock.NewRows([]string{"phone"}).AddRow("+254733000111"))
+	suppressed, err := repo.FilterSuppressed(context.Background(), 1, []string{"+254712345678", "+254733000111"})
+	AssertNoError(t, err)
+	AssertEqual(t, len(suppressed), 1)
+	AssertEqual(t, suppressed[0], "+254733000111")
+
+	mock.ExpectQuery(`SELECT campaign_id, phone, added_by, created_at FROM campaign_suppressions WHERE campaign_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"campaign_id", "phone", "added_by", "created_at"}).AddRow(1, "+254733000111", nil, time.Now()))
+	list, err := repo.List(context.Background(), 1)
+	AssertNoError(t, err)
+	AssertEqual(t, len(list), 1)
+
+	mock.ExpectExec(`DELETE FROM campaign_suppressions WHERE campaign_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	removed, err := repo.Clear(context.Background(), 1)
+	AssertNoError(t, err)
+	AssertEqual(t, removed, 4)
+	AssertNoError(t, mock.ExpectationsWereMet())
+
+	// Nothing to add or check needs no query
+	added, err = repo.Add(context.Background(), 1, nil, nil)
+	AssertNoError(t, err)
+	AssertEqual(t, added, 0)
+}
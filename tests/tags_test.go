@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// setupTagsTest creates a campaign handler backed by a mock campaign repository
+func setupTagsTest(t *testing.T) (*mux.Router, *MockCampaignRepository) {
+	t.Helper()
+
+	db, _ := NewMockDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	campaignRepo := NewMockCampaignRepository()
+	svc := service.NewCampaignService(
+		campaignRepo,
+		NewMockCustomerRepository(),
+		NewMockMessageRepository(),
+		service.NewTemplateService(),
+		NewMockPublisher(),
+		db,
+		config.ApprovalConfig{},
+	)
+	campaignHandler := handler.NewCampaignHandler(svc)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns", campaignHandler.Create).Methods("POST")
+	router.HandleFunc("/campaigns", campaignHandler.List).Methods("GET")
+	return router, campaignRepo
+}
+
+// TestNormalizeTags tests trimming, lowercasing and de-duplication
+func TestNormalizeTags(t *testing.T) {
+	AssertEqual(t, strings.Join(models.NormalizeTags([]string{" Retention", "q3-promo", "retention", ""}), ","), "retention,q3-promo")
+
+	// Never nil so untagged campaigns serialize as []
+	tags := models.NormalizeTags(nil)
+	if tags == nil {
+		t.Fatal("Expected empty slice, got nil")
+	}
+	AssertEqual(t, len(tags), 0)
+}
+
+// TestValidateTags tests tag format and count limits
+func TestValidateTags(t *testing.T) {
+	AssertNoError(t, models.ValidateTags([]string{"q3-promo", "retention_2025"}))
+	AssertNoError(t, models.ValidateTags(nil))
+
+	if err := models.ValidateTags([]string{"has space"}); err == nil {
+		t.Error("Expected error for tag with a space")
+	}
+	if err := models.ValidateTags([]string{strings.Repeat("a", 51)}); err == nil {
+		t.Error("Expected error for tag over 50 characters")
+	}
+
+	tooMany := make([]string, 0, models.MaxCampaignTags+1)
+	for i := 0; i <= models.MaxCampaignTags; i++ {
+		tooMany = append(tooMany, "tag-"+string(rune('a'+i)))
+	}
+	if err := models.ValidateTags(tooMany); err == nil {
+		t.Error("Expected error for too many tags")
+	}
+}
+
+// TestCampaignList_MultiTagFilter tests that repeated tags are ANDed with array containment
+func TestCampaignList_MultiTagFilter(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaign := NewTestCampaign()
+	campaign.Tags = []string{"retention", "q3-promo", "kenya"}
+
+	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE 1=1 AND tags @> (.+) ORDER BY id DESC").
+		WithArgs(`{"retention","q3-promo"}`, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team",
+		}).AddRow(
+			campaign.ID, campaign.Name, campaign.Channel, campaign.Status,
+			campaign.BaseTemplate, campaign.ScheduledAt, campaign.CreatedAt, campaign.UpdatedAt,
+			"{retention,q3-promo,kenya}", nil, nil,
+		))
+	mock.ExpectQuery("SELECT COUNT(.+) FROM campaigns WHERE 1=1 AND tags @> ").
+		WithArgs(`{"retention","q3-promo"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	campaignRepo := repository.NewCampaignRepository(db)
+	campaigns, total, err := campaignRepo.List(context.Background(), repository.CampaignFilters{
+		Page:     1,
+		PageSize: 20,
+		Tags:     []string{"retention", "q3-promo"},
+	})
+	AssertNoError(t, err)
+
+	AssertEqual(t, total, 1)
+	AssertEqual(t, len(campaigns), 1)
+	AssertEqual(t, strings.Join(campaigns[0].Tags, ","), "retention,q3-promo,kenya")
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCampaignGetByID_EmptyTags tests that an empty array scans to an empty, non-nil slice
+func TestCampaignGetByID_EmptyTags(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	campaign := NewTestCampaign()
+	mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE id").
+		WithArgs(campaign.ID).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "channel", "status", "base_template", "scheduled_at", "created_at", "updated_at", "tags", "created_by", "team", "budget", "spend", "paused_reason", "frequency_cap_exempt", "track_links", "template_syntax", "cancel_at", "demo_failure_rate", "ordered", "part_delimiter",
+		}).AddRow(
+			campaign.ID, campaign.Name, campaign.Channel, campaign.Status,
+			campaign.BaseTemplate, campaign.ScheduledAt, campaign.CreatedAt, campaign.UpdatedAt, "{}", nil, nil, nil, 0, nil, false, false, nil, nil, nil, false, nil,
+		))
+
+	result, err := repository.NewCampaignRepository(db).GetByID(context.Background(), campaign.ID)
+	AssertNoError(t, err)
+
+	if result.Tags == nil {
+		t.Fatal("Expected empty tags slice, got nil")
+	}
+	AssertEqual(t, len(result.Tags), 0)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCampaignCreate_StoresEmptyTagsArray tests that an untagged insert sends '{}' instead of NULL
+func TestCampaignCreate_StoresEmptyTagsArray(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("INSERT INTO campaigns").
+		WithArgs("Untagged", models.ChannelSMS, models.CampaignStatusDraft, "Hi", nil, "{}", nil, nil, nil, false, false, nil, nil, false, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
+			AddRow(1, time.Now(), time.Now()))
+
+	campaign := &models.Campaign{
+		Name:         "Untagged",
+		Channel:      models.ChannelSMS,
+		Status:       models.CampaignStatusDraft,
+		BaseTemplate: "Hi",
+	}
+	AssertNoError(t, repository.NewCampaignRepository(db).Create(context.Background(), campaign))
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCampaignTagsEndpoints tests tags on create and the repeatable tag filter on list
+func TestCampaignTagsEndpoints(t *testing.T) {
+	router, campaignRepo := setupTagsTest(t)
+
+	var created *models.Campaign
+	campaignRepo.CreateFunc = func(ctx context.Context, campaign *models.Campaign) error {
+		campaign.ID = 1
+		created = campaign
+		return nil
+	}
+
+	// Tags are normalized on create and returned
+	req := NewJSONRequest(t, "POST", "/campaigns", map[string]interface{}{
+		"name":          "Promo",
+		"channel":       "sms",
+		"base_template": "Hi {first_name}",
+		"tags":          []string{"Q3-Promo", "retention", "retention"},
+	})
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusCreated)
+	AssertEqual(t, strings.Join(created.Tags, ","), "q3-promo,retention")
+	var campaign models.Campaign
+	ParseJSONResponse(t, resp, &campaign)
+	AssertEqual(t, strings.Join(campaign.Tags, ","), "q3-promo,retention")
+
+	// Omitted tags are returned as an empty array
+	req = NewJSONRequest(t, "POST", "/campaigns", map[string]interface{}{
+		"name":          "Plain",
+		"channel":       "sms",
+		"base_template": "Hi",
+	})
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusCreated)
+	AssertContains(t, resp.Body.String(), `"tags":[]`)
+
+	// Invalid tags are rejected
+	req = NewJSONRequest(t, "POST", "/campaigns", map[string]interface{}{
+		"name":          "Bad",
+		"channel":       "sms",
+		"base_template": "Hi",
+		"tags":          []string{"not valid!"},
+	})
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+
+	// Repeated tag params reach the repository together
+	var filters repository.CampaignFilters
+	campaignRepo.ListFunc = func(ctx context.Context, f repository.CampaignFilters) ([]*models.Campaign, int, error) {
+		filters = f
+		return []*models.Campaign{}, 0, nil
+	}
+
+	req = httptest.NewRequest("GET", "/campaigns?tag=retention&tag=Q3-Promo", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertEqual(t, strings.Join(filters.Tags, ","), "retention,q3-promo")
+
+	// Malformed tag filter
+	req = httptest.NewRequest("GET", "/campaigns?tag=bad%20tag", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+}
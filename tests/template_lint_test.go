@@ -0,0 +1,235 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// TestLintTemplate tests each lint rule, the suggestions and templates that must stay clean
+func TestLintTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     []service.TemplateWarning
+	}{
+		{
+			name:     "valid placeholders",
+			template: "Hi {first_name} {last_name}, {preferred_product} is in {location}. Call {phone}",
+		},
+		{
+			name:     "no placeholders",
+			template: "Flash sale today only!",
+		},
+		{
+			name:     "unknown placeholder left alone",
+			template: "Hi {first_name}, your code is {discount_code}",
+		},
+		{
+			name:     "space in name",
+			template: "Hi {first name}!",
+			want: []service.TemplateWarning{
+				{Rule: service.LintMisspelledField, Text: "{first name}", Suggestion: "{first_name}", Message: "{first name} is not a placeholder; did you mean {first_name}?"},
+			},
+		},
+		{
+			name:     "camel case",
+			template: "Hi {FirstName}",
+			want: []service.TemplateWarning{
+				{Rule: service.LintMisspelledField, Text: "{FirstName}", Suggestion: "{first_name}", Message: "{FirstName} is not a placeholder; did you mean {first_name}?"},
+			},
+		},
+		{
+			name:     "upper case",
+			template: "Hi {FIRST_NAME}",
+			want: []service.TemplateWarning{
+				{Rule: service.LintMisspelledField, Text: "{FIRST_NAME}", Suggestion: "{first_name}", Message: "{FIRST_NAME} is not a placeholder; did you mean {first_name}?"},
+			},
+		},
+		{
+			name:     "hyphen and alias",
+			template: "{last-name}, your {product} is ready. Reply to {phone number}",
+			want: []service.TemplateWarning{
+				{Rule: service.LintMisspelledField, Text: "{last-name}", Suggestion: "{last_name}", Message: "{last-name} is not a placeholder; did you mean {last_name}?"},
+				{Rule: service.LintMisspelledField, Text: "{product}", Suggestion: "{preferred_product}", Message: "{product} is not a placeholder; did you mean {preferred_product}?"},
+				{Rule: service.LintMisspelledField, Text: "{phone number}", Suggestion: "{phone}", Message: "{phone number} is not a placeholder; did you mean {phone}?"},
+			},
+		},
+		{
+			name:     "double braces",
+			template: "Hi {{first_name}}, welcome",
+			want: []service.TemplateWarning{
+				{Rule: service.LintDoubleBraces, Text: "{{first_name}}", Suggestion: "{first_name}", Message: "{{first_name}} uses double braces; did you mean {first_name}?"},
+			},
+		},
+		{
+			name:     "double braces with spaces and a misspelling",
+			template: "Hi {{ First Name }}",
+			want: []service.TemplateWarning{
+				{Rule: service.LintDoubleBraces, Text: "{{ First Name }}", Suggestion: "{first_name}", Message: "{{ First Name }} uses double braces; did you mean {first_name}?"},
+			},
+		},
+		{
+			name:     "double braces around an unknown name",
+			template: "Code: {{code}}",
+			want: []service.TemplateWarning{
+				{Rule: service.LintDoubleBraces, Text: "{{code}}", Suggestion: "{code}", Message: "{{code}} uses double braces; did you mean {code}?"},
+			},
+		},
+		{
+			name:     "missing closing brace",
+			template: "Hi {first_name, welcome",
+			want: []service.TemplateWarning{
+				{Rule: service.LintMissingClosingBrace, Text: "{first_name", Suggestion: "{first_name}", Message: "{first_name is missing its closing brace; did you mean {first_name}?"},
+			},
+		},
+		{
+			name:     "missing closing brace at the end",
+			template: "Visit us in {location",
+			want: []service.TemplateWarning{
+				{Rule: service.LintMissingClosingBrace, Text: "{location", Suggestion: "{location}", Message: "{location is missing its closing brace; did you mean {location}?"},
+			},
+		},
+		{
+			name:     "missing opening brace",
+			template: "Hi first_name}, welcome",
+			want: []service.TemplateWarning{
+				{Rule: service.LintMissingOpeningBrace, Text: "first_name}", Suggestion: "{first_name}", Message: "first_name} is missing its opening brace; did you mean {first_name}?"},
+			},
+		},
+		{
+			name:     "ordinary words next to braces",
+			template: "Hi {first_name}! {Sale} ends soon}",
+		},
+		{
+			name:     "several mistakes in order",
+			template: "{{last_name}} and {Location} and {phone",
+			want: []service.TemplateWarning{
+				{Rule: service.LintDoubleBraces, Text: "{{last_name}}", Suggestion: "{last_name}", Message: "{{last_name}} uses double braces; did you mean {last_name}?"},
+				{Rule: service.LintMisspelledField, Text: "{Location}", Suggestion: "{location}", Message: "{Location} is not a placeholder; did you mean {location}?"},
+				{Rule: service.LintMissingClosingBrace, Text: "{phone", Suggestion: "{phone}", Message: "{phone is missing its closing brace; did you mean {phone}?"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := service.LintTemplate(tt.template)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d warnings, got %d: %+v", len(tt.want), len(got), got)
+			}
+			for i := range tt.want {
+				AssertEqual(t, got[i], tt.want[i])
+			}
+		})
+	}
+}
+
+// TestTemplateValidateEndpoint tests that suggestions are returned as warnings, or as errors when strict
+func TestTemplateValidateEndpoint(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/templates/validate", handler.NewTemplateHandler(service.NewTemplateService()).Validate).Methods("POST")
+
+	validate := func(body string) *service.TemplateCheck {
+		req := httptest.NewRequest("POST", "/templates/validate", strings.NewReader(body))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		AssertStatusCode(t, resp, http.StatusOK)
+
+		var check service.TemplateCheck
+		ParseJSONResponse(t, resp, &check)
+		return &check
+	}
+
+	check := validate(`{"template": "Hi {first name}, enjoy {preferred_product}"}`)
+	AssertEqual(t, check.Valid, true)
+	AssertEqual(t, len(check.Errors), 0)
+	AssertEqual(t, len(check.Warnings), 1)
+	AssertEqual(t, check.Warnings[0].Message, "{first name} is not a placeholder; did you mean {first_name}?")
+
+	check = validate(`{"template": "Hi {first name}", "strict": true}`)
+	AssertEqual(t, check.Valid, false)
+	AssertEqual(t, len(check.Warnings), 0)
+	AssertEqual(t, check.Errors[0], "{first name} is not a placeholder; did you mean {first_name}?")
+
+	check = validate(`{"template": "Hi {first_name, welcome"}`)
+	AssertEqual(t, check.Valid, false)
+	AssertEqual(t, check.Errors[0], "template has unbalanced braces: 1 open, 0 close")
+	AssertEqual(t, check.Warnings[0].Suggestion, "{first_name}")
+
+	req := httptest.NewRequest("POST", "/templates/validate", strings.NewReader(""))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+}
+
+// TestTemplateLint_CampaignCreate tests warnings on a created campaign and rejection when strict
+func TestTemplateLint_CampaignCreate(t *testing.T) {
+	svc, campaignRepo, _, _ := setupApprovalTest(t)
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns", handler.NewCampaignHandler(svc).Create).Methods("POST")
+
+	req := httptest.NewRequest("POST", "/campaigns", strings.NewReader(`{"name": "Sale", "channel": "sms", "base_template": "Hi {{first_name}}!"}`))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusCreated)
+
+	var created handler.CampaignResponse
+	ParseJSONResponse(t, resp, &created)
+	AssertEqual(t, len(created.TemplateWarnings), 1)
+	AssertEqual(t, created.TemplateWarnings[0].Message, "{{first_name}} uses double braces; did you mean {first_name}?")
+
+	req = httptest.NewRequest("POST", "/campaigns", strings.NewReader(`{"name": "Sale", "channel": "sms", "base_template": "Hi {{first_name}}!", "strict_template": true}`))
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+
+	var errResp handler.ErrorResponse
+	ParseJSONResponse(t, resp, &errResp)
+	AssertEqual(t, errResp.Error.Message, "invalid template: {{first_name}} uses double braces; did you mean {first_name}?")
+	AssertEqual(t, campaignRepo.Calls["Create"], 1)
+
+	// A syntax error carries the suggestion too
+	_, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+		Name: "Sale", Channel: models.ChannelSMS, BaseTemplate: "Hi {first_name, welcome",
+	})
+	AssertError(t, err, "validation error: invalid template: template has unbalanced braces: 1 open, 0 close; {first_name is missing its closing brace; did you mean {first_name}?")
+}
+
+// TestTemplateLint_Preview tests warnings in a preview response and rejection when strict
+func TestTemplateLint_Preview(t *testing.T) {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		campaign := NewTestCampaign()
+		campaign.BaseTemplate = "Hi {FirstName}, welcome"
+		return campaign, nil
+	}
+	svc := service.NewCampaignService(campaignRepo, NewMockCustomerRepository(), NewMockMessageRepository(), service.NewTemplateService(), NewMockPublisher(), nil, config.ApprovalConfig{})
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns/{id}/personalized-preview", handler.NewPreviewHandler(svc).Preview).Methods("POST")
+
+	req := httptest.NewRequest("POST", "/campaigns/1/personalized-preview", strings.NewReader(`{"customer_id": 1}`))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var result service.PreviewMessageResult
+	ParseJSONResponse(t, resp, &result)
+	AssertEqual(t, result.RenderedMessage, "Hi {FirstName}, welcome")
+	AssertEqual(t, len(result.TemplateWarnings), 1)
+	AssertEqual(t, result.TemplateWarnings[0].Message, "{FirstName} is not a placeholder; did you mean {first_name}?")
+
+	req = httptest.NewRequest("POST", "/campaigns/1/personalized-preview", strings.NewReader(`{"customer_id": 1, "strict": true}`))
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+}
@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// syntaxTemplates is one logical template written in every syntax
+var syntaxTemplates = map[models.TemplateSyntax]string{
+	models.TemplateSyntaxBraces:       "Hi {first_name} {last_name} from {location}! Check out our {preferred_product}. Contact: {phone}",
+	models.TemplateSyntaxDoubleBraces: "Hi {{first_name}} {{last_name}} from {{location}}! Check out our {{preferred_product}}. Contact: {{phone}}",
+	models.TemplateSyntaxBrackets:     "Hi [[first_name]] [[last_name]] from [[location]]! Check out our [[preferred_product]]. Contact: [[phone]]",
+	models.TemplateSyntaxPercent:      "Hi %first_name% %last_name% from %location%! Check out our %preferred_product%. Contact: %phone%",
+}
+
+// TestTemplateSyntax_RendersIdentically tests that the same template renders the same in every syntax
+func TestTemplateSyntax_RendersIdentically(t *testing.T) {
+	customer := NewTestCustomer()
+	expected := "Hi John Doe from Nairobi! Check out our Premium Plan. Contact: +254700000001"
+
+	for _, syntax := range models.TemplateSyntaxes {
+		t.Run(string(syntax), func(t *testing.T) {
+			templateSvc := service.NewTemplateService().ForSyntax(&syntax)
+			template := syntaxTemplates[syntax]
+
+			AssertNoError(t, templateSvc.ValidateTemplate(template))
+			result, err := templateSvc.Render(template, customer)
+			AssertNoError(t, err)
+			AssertEqual(t, result, expected)
+
+			placeholders := templateSvc.GetPlaceholders(template)
+			AssertEqual(t, len(placeholders), 5)
+			AssertEqual(t, placeholders[0], templateSvc.Placeholder("first_name"))
+			AssertEqual(t, templateSvc.PlaceholderField(placeholders[4]), "phone")
+		})
+	}
+}
+
+// TestTemplateSyntax_OtherDelimitersAreText tests that only the configured delimiters are placeholders
+func TestTemplateSyntax_OtherDelimitersAreText(t *testing.T) {
+	customer := NewTestCustomer()
+	brackets := models.TemplateSyntaxBrackets
+	templateSvc := service.NewTemplateService().ForSyntax(&brackets)
+
+	result, err := templateSvc.Render("Hi [[first_name]], {first_name} and [first_name] stay; [[nickname]] too", customer)
+	AssertNoError(t, err)
+	AssertEqual(t, result, "Hi John, {first_name} and [first_name] stay; [[nickname]] too")
+	AssertEqual(t, len(templateSvc.GetPlaceholders("{first_name}")), 0)
+}
+
+// TestTemplateSyntax_PercentLiteral tests that a literal % sign does not swallow a placeholder
+func TestTemplateSyntax_PercentLiteral(t *testing.T) {
+	percent := models.TemplateSyntaxPercent
+	templateSvc := service.NewTemplateService().ForSyntax(&percent)
+
+	template := "50% off for %first_name%, 100% guaranteed"
+	AssertNoError(t, templateSvc.ValidateTemplate(template))
+	result, err := templateSvc.Render(template, NewTestCustomer())
+	AssertNoError(t, err)
+	AssertEqual(t, result, "50% off for John, 100% guaranteed")
+}
+
+// TestTemplateSyntax_UnbalancedDelimiters tests validation in the syntax's own delimiters
+func TestTemplateSyntax_UnbalancedDelimiters(t *testing.T) {
+	brackets := models.TemplateSyntaxBrackets
+	err := service.NewTemplateService().ForSyntax(&brackets).ValidateTemplate("Hi [[first_name, welcome")
+	AssertError(t, err, "template has unbalanced brackets: 1 open, 0 close")
+
+	doubleBraces := models.TemplateSyntaxDoubleBraces
+	err = service.NewTemplateService().ForSyntax(&doubleBraces).ValidateTemplate("Hi {{first_name}, welcome")
+	AssertError(t, err, "template has unbalanced braces: 1 open, 0 close")
+}
+
+// TestTemplateSyntax_DefaultSyntax tests that the configured syntax applies to campaigns without one
+func TestTemplateSyntax_DefaultSyntax(t *testing.T) {
+	templateSvc := service.NewTemplateService()
+	AssertEqual(t, templateSvc.Syntax(), models.TemplateSyntaxBraces)
+
+	templateSvc.SetSyntax(models.TemplateSyntaxPercent)
+	result, err := templateSvc.ForCampaign(&models.Campaign{}).Render("Hi %first_name%", NewTestCustomer())
+	AssertNoError(t, err)
+	AssertEqual(t, result, "Hi John")
+
+	// A campaign's own syntax wins over the default
+	braces := models.TemplateSyntaxBraces
+	result, err = templateSvc.ForCampaign(&models.Campaign{TemplateSyntax: &braces}).Render("Hi {first_name}", NewTestCustomer())
+	AssertNoError(t, err)
+	AssertEqual(t, result, "Hi John")
+
+	templateSvc.SetSyntax("angle_brackets")
+	AssertEqual(t, templateSvc.Syntax(), models.TemplateSyntaxPercent)
+}
+
+// TestTemplateSyntax_LintOnlyBraces tests that brace lint rules do not flag other syntaxes
+func TestTemplateSyntax_LintOnlyBraces(t *testing.T) {
+	doubleBraces := models.TemplateSyntaxDoubleBraces
+	AssertEqual(t, len(service.NewTemplateService().ForSyntax(&doubleBraces).Lint("Hi {{first_name}}")), 0)
+	AssertEqual(t, len(service.NewTemplateService().Lint("Hi {{first_name}}")), 1)
+}
+
+// TestTemplateSyntax_CampaignCreate tests that an imported campaign keeps its syntax and is not linted as braces
+func TestTemplateSyntax_CampaignCreate(t *testing.T) {
+	svc, campaignRepo, _, _ := setupApprovalTest(t)
+	var stored *models.Campaign
+	campaignRepo.CreateFunc = func(ctx context.Context, campaign *models.Campaign) error {
+		stored = campaign
+		campaign.ID = 1
+		return nil
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/campaigns", handler.NewCampaignHandler(svc).Create).Methods("POST")
+
+	req := httptest.NewRequest("POST", "/campaigns", strings.NewReader(`{"name": "Imported", "channel": "sms", "base_template": "Hi {{first_name}}!", "template_syntax": "double_braces", "strict_template": true}`))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusCreated)
+
+	var created handler.CampaignResponse
+	ParseJSONResponse(t, resp, &created)
+	AssertEqual(t, len(created.TemplateWarnings), 0)
+	AssertNotNil(t, created.TemplateSyntax)
+	AssertEqual(t, *created.TemplateSyntax, models.TemplateSyntaxDoubleBraces)
+	AssertEqual(t, *stored.TemplateSyntax, models.TemplateSyntaxDoubleBraces)
+
+	_, err := svc.CreateCampaign(context.Background(), &service.CreateCampaignRequest{
+		Name: "Imported", Channel: models.ChannelSMS, BaseTemplate: "Hi [[first_name]]",
+		TemplateSyntax: func() *models.TemplateSyntax { s := models.TemplateSyntax("angle"); return &s }(),
+	})
+	AssertError(t, err, "validation error: invalid template_syntax: must be one of 'braces', 'double_braces', 'brackets', 'percent'")
+}
+
+// TestTemplateSyntax_Preview tests that previews read a campaign's template in its syntax
+func TestTemplateSyntax_Preview(t *testing.T) {
+	campaignRepo := NewMockCampaignRepository()
+	campaignRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Campaign, error) {
+		brackets := models.TemplateSyntaxBrackets
+		campaign := NewTestCampaign()
+		campaign.BaseTemplate = "Hi [[first_name]] from [[location]]"
+		campaign.TemplateSyntax = &brackets
+		return campaign, nil
+	}
+	svc := service.NewCampaignService(campaignRepo, NewMockCustomerRepository(), NewMockMessageRepository(), service.NewTemplateService(), NewMockPublisher(), nil, config.ApprovalConfig{})
+
+	result, err := svc.PreviewMessage(context.Background(), &service.PreviewMessageRequest{CampaignID: 1, CustomerID: 1})
+	AssertNoError(t, err)
+	AssertEqual(t, result.RenderedMessage, "Hi John from Nairobi")
+}
+
+// TestTemplateSyntax_ValidateEndpoint tests checking a template in a given syntax
+func TestTemplateSyntax_ValidateEndpoint(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/templates/validate", handler.NewTemplateHandler(service.NewTemplateService()).Validate).Methods("POST")
+
+	req := httptest.NewRequest("POST", "/templates/validate", strings.NewReader(`{"template": "Hi %first_name%, 20% off", "syntax": "percent"}`))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+
+	var check service.TemplateCheck
+	ParseJSONResponse(t, resp, &check)
+	AssertEqual(t, check.Valid, true)
+	AssertEqual(t, len(check.Placeholders), 1)
+	AssertEqual(t, check.Placeholders[0], "%first_name%")
+
+	req = httptest.NewRequest("POST", "/templates/validate", strings.NewReader(`{"template": "Hi", "syntax": "angle"}`))
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+}
@@ -0,0 +1,212 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// TestTimeline_NextCursor tests that a full page returns the cursor for the next page, naming
+// its last event
+func TestTimeline_NextCursor(t *testing.T) {
+	now := time.Date(2025, 12, 10, 12, 0, 0, 0, time.UTC)
+	customerRepo := NewMockCustomerRepository()
+	var gotCursor *models.TimelineCursor
+	customerRepo.GetTimelineFunc = func(ctx context.Context, customerID int, after *models.TimelineCursor, limit int) ([]*models.TimelineEvent, error) {
+		gotCursor = after
+		events := []*models.TimelineEvent{}
+		for i := 0; i < limit; i++ {
+			events = append(events, &models.TimelineEvent{
+				Type:       models.TimelineMessageQueued,
+				OccurredAt: now.Add(-time.Duration(i) * time.Minute),
+				MessageID:  IntPtr(10 - i),
+			})
+		}
+		return events, nil
+	}
+	svc := service.NewCustomerService(customerRepo, config.LimitsConfig{})
+
+	cursor := &models.TimelineCursor{OccurredAt: now.Add(time.Hour), MessageID: 11, Type: models.TimelineMessageSent}
+	page, err := svc.GetTimeline(context.Background(), 1, cursor, 3)
+	AssertNoError(t, err)
+
+	AssertEqual(t, gotCursor, cursor)
+	AssertEqual(t, len(page.Events), 3)
+	next, err := models.ParseTimelineCursor(page.NextCursor)
+	AssertNoError(t, err)
+	AssertEqual(t, *next, models.TimelineCursor{OccurredAt: now.Add(-2 * time.Minute), MessageID: 8, Type: models.TimelineMessageQueued})
+
+	// Partial page is the last one
+	customerRepo.GetTimelineFunc = func(ctx context.Context, customerID int, after *models.TimelineCursor, limit int) ([]*models.TimelineEvent, error) {
+		return []*models.TimelineEvent{{Type: models.TimelineMessageSent, OccurredAt: now}}, nil
+	}
+	page, err = svc.GetTimeline(context.Background(), 1, nil, 3)
+	AssertNoError(t, err)
+	AssertEqual(t, page.NextCursor, "")
+}
+
+// TestTimelineCursor_Parse tests that a cursor survives encoding and that tokens not issued as
+// cursors are refused
+func TestTimelineCursor_Parse(t *testing.T) {
+	cursor := &models.TimelineCursor{
+		OccurredAt: time.Date(2025, 12, 10, 12, 0, 0, 123456000, time.UTC),
+		MessageID:  42,
+		Type:       models.TimelineMessageFailed,
+	}
+	parsed, err := models.ParseTimelineCursor(cursor.String())
+	AssertNoError(t, err)
+	AssertEqual(t, *parsed, *cursor)
+
+	for _, token := range []string{"2025-12-10T12:00:00Z", "not base64!", "bWVzc2FnZTo0Mg"} {
+		if _, err := models.ParseTimelineCursor(token); err == nil {
+			t.Errorf("Expected %q to be refused", token)
+		}
+	}
+}
+
+// TestGetTimeline_KeysetCursor tests that a page after a cursor continues from the exact event,
+// so events sharing its timestamp are not dropped
+func TestGetTimeline_KeysetCursor(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	customerRepo := repository.NewCustomerRepository(db)
+	columns := []string{"type", "occurred_at", "message_id", "campaign_id", "campaign_name", "detail"}
+
+	// The first page has no cursor
+	mock.ExpectQuery(`\) events ORDER BY occurred_at DESC, message_id DESC, type DESC LIMIT \$2`).
+		WithArgs(1, 50).
+		WillReturnRows(sqlmock.NewRows(columns))
+	_, err := customerRepo.GetTimeline(context.Background(), 1, nil, 50)
+	AssertNoError(t, err)
+
+	at := time.Date(2025, 12, 10, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`\) events WHERE \(occurred_at, message_id, type\) < \(\$3, \$4, \$5::text\) ORDER BY occurred_at DESC, message_id DESC, type DESC LIMIT \$2`).
+		WithArgs(1, 50, at, 7, "message_sent").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("message_queued", at, 7, 3, "Promo", nil).
+			AddRow("message_sent", at, 6, 3, "Promo", nil))
+	events, err := customerRepo.GetTimeline(context.Background(), 1, &models.TimelineCursor{OccurredAt: at, MessageID: 7, Type: models.TimelineMessageSent}, 50)
+	AssertNoError(t, err)
+	AssertEqual(t, len(events), 2)
+	AssertEqual(t, *events[0].MessageID, 7)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestTimelineEndpoint tests GET /customers/{id}/timeline validation and not-found handling
+func TestTimelineEndpoint(t *testing.T) {
+	customerRepo := NewMockCustomerRepository()
+	router := mux.NewRouter()
+	router.HandleFunc("/customers/{id}/timeline", handler.NewCustomerHandler(service.NewCustomerService(customerRepo, config.LimitsConfig{})).Timeline).Methods("GET")
+
+	// Success
+	cursor := &models.TimelineCursor{OccurredAt: time.Date(2025, 12, 10, 12, 0, 0, 0, time.UTC), MessageID: 7, Type: models.TimelineMessageQueued}
+	req := httptest.NewRequest("GET", "/customers/1/timeline?cursor="+cursor.String()+"&limit=5", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+	AssertEqual(t, customerRepo.Calls["GetTimeline"], 1)
+
+	// Invalid cursor
+	req = httptest.NewRequest("GET", "/customers/1/timeline?cursor=yesterday", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+
+	// Unknown customer
+	customerRepo.GetByIDFunc = func(ctx context.Context, id int) (*models.Customer, error) {
+		return nil, fmt.Errorf("customer not found")
+	}
+	req = httptest.NewRequest("GET", "/customers/99/timeline", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	AssertStatusCode(t, resp, http.StatusNotFound)
+}
+
+// TestTimeline_Integration tests ordering of interleaved events across campaigns
+func TestTimeline_Integration(t *testing.T) {
+	db := SetupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer db.Close()
+	CleanupTestDB(t, db)
+	defer CleanupTestDB(t, db)
+
+	ctx := context.Background()
+	customerRepo := repository.NewCustomerRepository(db)
+	campaignRepo := repository.NewCampaignRepository(db)
+
+	customer := &models.Customer{Phone: "+254700000123", FirstName: StringPtr("Ann")}
+	AssertNoError(t, customerRepo.Create(ctx, customer))
+
+	base := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+	insertMessage := func(campaignName string, status models.MessageStatus, createdAt, updatedAt time.Time) {
+		campaign := &models.Campaign{Name: campaignName, Channel: models.ChannelSMS, Status: models.CampaignStatusSending, BaseTemplate: "Hi"}
+		AssertNoError(t, campaignRepo.Create(ctx, campaign))
+		_, err := db.Exec(`INSERT INTO outbound_messages (campaign_id, customer_id, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)`,
+			campaign.ID, customer.ID, status, createdAt, updatedAt)
+		AssertNoError(t, err)
+	}
+
+	// A queued 09:00, sent 09:30; B queued 09:10, failed 09:20; C queued 09:40, still pending
+	insertMessage("A", models.MessageStatusSent, base, base.Add(30*time.Minute))
+	insertMessage("B", models.MessageStatusFailed, base.Add(10*time.Minute), base.Add(20*time.Minute))
+	insertMessage("C", models.MessageStatusPending, base.Add(40*time.Minute), base.Add(40*time.Minute))
+
+	events, err := customerRepo.GetTimeline(ctx, customer.ID, nil, 10)
+	AssertNoError(t, err)
+
+	expected := []struct {
+		eventType models.TimelineEventType
+		campaign  string
+	}{
+		{models.TimelineMessageQueued, "C"},
+		{models.TimelineMessageSent, "A"},
+		{models.TimelineMessageFailed, "B"},
+		{models.TimelineMessageQueued, "B"},
+		{models.TimelineMessageQueued, "A"},
+	}
+	AssertEqual(t, len(events), len(expected))
+	for i, want := range expected {
+		AssertEqual(t, events[i].Type, want.eventType)
+		AssertEqual(t, *events[i].CampaignName, want.campaign)
+	}
+
+	// Paging one event at a time visits every event once, in the same order
+	var cursor *models.TimelineCursor
+	for i, want := range expected {
+		page, err := customerRepo.GetTimeline(ctx, customer.ID, cursor, 1)
+		AssertNoError(t, err)
+		AssertEqual(t, len(page), 1)
+		AssertEqual(t, page[0].Type, want.eventType)
+		AssertEqual(t, *page[0].CampaignName, want.campaign)
+		cursor = page[0].CursorAfter()
+		if i == len(expected)-1 {
+			page, err = customerRepo.GetTimeline(ctx, customer.ID, cursor, 1)
+			AssertNoError(t, err)
+			AssertEqual(t, len(page), 0)
+		}
+	}
+
+	// Events sharing a timestamp are split across pages without loss
+	insertMessage("D", models.MessageStatusPending, base.Add(50*time.Minute), base.Add(50*time.Minute))
+	insertMessage("E", models.MessageStatusPending, base.Add(50*time.Minute), base.Add(50*time.Minute))
+	first, err := customerRepo.GetTimeline(ctx, customer.ID, nil, 1)
+	AssertNoError(t, err)
+	second, err := customerRepo.GetTimeline(ctx, customer.ID, first[0].CursorAfter(), 1)
+	AssertNoError(t, err)
+	AssertEqual(t, *first[0].CampaignName, "E")
+	AssertEqual(t, *second[0].CampaignName, "D")
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/service"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeAcknowledger records how each delivery was resolved
+type fakeAcknowledger struct {
+	mu       sync.Mutex
+	acks     map[uint64]int
+	nacks    map[uint64]int
+	requeued map[uint64]bool
+}
+
+func newFakeAcknowledger() *fakeAcknowledger {
+	return &fakeAcknowledger{
+		acks:     make(map[uint64]int),
+		nacks:    make(map[uint64]int),
+		requeued: make(map[uint64]bool),
+	}
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acks[tag]++
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nacks[tag]++
+	a.requeued[tag] = requeue
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+// resolutions returns how many times the delivery was acked and nacked
+func (a *fakeAcknowledger) resolutions(tag uint64) (int, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.acks[tag], a.nacks[tag]
+}
+
+// newTestDelivery creates a delivery carrying a job for messageID
+func newTestDelivery(t *testing.T, ack amqp.Acknowledger, tag uint64, messageID int) amqp.Delivery {
+	t.Helper()
+	return amqp.Delivery{
+		Acknowledger: ack,
+		DeliveryTag:  tag,
+		Body:         []byte(fmt.Sprintf(`{"message_id":%d,"campaign_id":1,"customer_id":1}`, messageID)),
+	}
+}
+
+// TestWatchdog_HandlerResolves tests that a handler finishing in time acks or requeues its delivery once
+func TestWatchdog_HandlerResolves(t *testing.T) {
+	ack := newFakeAcknowledger()
+	watchdog := queue.NewWatchdog(time.Second, func(job *queue.MessageJob, err error) {
+		t.Errorf("Expected no expiry, got %v", err)
+	})
+
+	expired := watchdog.Dispatch(newTestDelivery(t, ack, 1, 1), func(d amqp.Delivery) error { return nil })
+	AssertEqual(t, expired, false)
+	acks, nacks := ack.resolutions(1)
+	AssertEqual(t, acks, 1)
+	AssertEqual(t, nacks, 0)
+
+	expired = watchdog.Dispatch(newTestDelivery(t, ack, 2, 2), func(d amqp.Delivery) error { return errors.New("send failed") })
+	AssertEqual(t, expired, false)
+	acks, nacks = ack.resolutions(2)
+	AssertEqual(t, acks, 0)
+	AssertEqual(t, nacks, 1)
+	AssertEqual(t, ack.requeued[2], true)
+}
+
+// TestWatchdog_Expires tests that a hanging handler's delivery is requeued at the deadline and its late result discarded
+func TestWatchdog_Expires(t *testing.T) {
+	ack := newFakeAcknowledger()
+	var expiredJob *queue.MessageJob
+	var expiredErr error
+	watchdog := queue.NewWatchdog(20*time.Millisecond, func(job *queue.MessageJob, err error) {
+		expiredJob, expiredErr = job, err
+	})
+
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	expired := watchdog.Dispatch(newTestDelivery(t, ack, 7, 42), func(d amqp.Delivery) error {
+		defer close(returned)
+		<-release
+		return nil
+	})
+
+	AssertEqual(t, expired, true)
+	acks, nacks := ack.resolutions(7)
+	AssertEqual(t, acks, 0)
+	AssertEqual(t, nacks, 1)
+	AssertEqual(t, ack.requeued[7], true)
+	AssertEqual(t, expiredJob.MessageID, 42)
+	AssertEqual(t, errors.Is(expiredErr, queue.ErrAckDeadlineExceeded), true)
+	AssertError(t, expiredErr, "ack deadline exceeded: handler still running after 20ms")
+
+	// The handler finally returns; the delivery was already requeued so nothing else happens
+	close(release)
+	<-returned
+	acks, nacks = ack.resolutions(7)
+	AssertEqual(t, acks, 0)
+	AssertEqual(t, nacks, 1)
+}
+
+// TestWatchdog_ExactlyOnce tests that every delivery is resolved exactly once when handlers finish around the deadline
+func TestWatchdog_ExactlyOnce(t *testing.T) {
+	ack := newFakeAcknowledger()
+	watchdog := queue.NewWatchdog(time.Millisecond, nil)
+
+	for tag := uint64(1); tag <= 200; tag++ {
+		watchdog.Dispatch(newTestDelivery(t, ack, tag, int(tag)), func(d amqp.Delivery) error {
+			time.Sleep(time.Duration(d.DeliveryTag%3) * 500 * time.Microsecond)
+			return nil
+		})
+	}
+
+	// Let any handlers still running after their deadline return
+	time.Sleep(10 * time.Millisecond)
+	for tag := uint64(1); tag <= 200; tag++ {
+		acks, nacks := ack.resolutions(tag)
+		if acks+nacks != 1 {
+			t.Errorf("Expected delivery %d to be resolved once, got %d acks and %d nacks", tag, acks, nacks)
+		}
+	}
+}
+
+// TestWatchdog_Disabled tests that a zero deadline or nil watchdog runs the handler to completion
+func TestWatchdog_Disabled(t *testing.T) {
+	ack := newFakeAcknowledger()
+	slow := func(d amqp.Delivery) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+
+	AssertEqual(t, queue.NewWatchdog(0, nil).Dispatch(newTestDelivery(t, ack, 1, 1), slow), false)
+	var off *queue.Watchdog
+	AssertEqual(t, off.Dispatch(newTestDelivery(t, ack, 2, 2), slow), false)
+
+	for _, tag := range []uint64{1, 2} {
+		acks, nacks := ack.resolutions(tag)
+		AssertEqual(t, acks, 1)
+		AssertEqual(t, nacks, 0)
+	}
+}
+
+// TestWatchdog_RecordsTimeout tests that an expired job is recorded as a timeout processing error
+func TestWatchdog_RecordsTimeout(t *testing.T) {
+	f := newProcessingErrorFixture(t, &countingSender{})
+	err := fmt.Errorf("%w: handler still running after 2m0s", queue.ErrAckDeadlineExceeded)
+	AssertEqual(t, service.ClassifyProcessingError(err), service.ErrorClassTimeout)
+
+	f.processor.HandleExpired(&queue.MessageJob{MessageID: 9, CampaignID: 1, CustomerID: 1}, err)
+
+	AssertEqual(t, len(f.errorRepo.Created), 1)
+	AssertEqual(t, f.errorRepo.Created[0].ErrorClass, service.ErrorClassTimeout)
+	AssertEqual(t, *f.errorRepo.Created[0].MessageID, 9)
+}
+
+// TestLoadAckDeadline tests WORKER_ACK_DEADLINE parsing
+func TestLoadAckDeadline(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Worker.AckDeadline, config.DefaultAckDeadline)
+
+	t.Setenv("WORKER_ACK_DEADLINE", "30s")
+	cfg, err = config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Worker.AckDeadline, 30*time.Second)
+
+	t.Setenv("WORKER_ACK_DEADLINE", "-1s")
+	_, err = config.Load()
+	AssertError(t, err, "WORKER_ACK_DEADLINE cannot be negative")
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smsleopard/internal/config"
+	"smsleopard/internal/handler"
+	"smsleopard/internal/notify"
+)
+
+// campaignPayloadTemplate is a consumer's own payload shape
+const campaignPayloadTemplate = `{"eventName": {{json .Type}}, "campaignRef": "cmp-{{.CampaignID}}", "queued": {{.MessagesQueued}}, "at": {{json (rfc3339 .OccurredAt)}}, "channel": {{json (upper .Channel)}}}`
+
+// newWebhookReceiver starts a server recording the bodies posted to it
+func newWebhookReceiver(t *testing.T) (*httptest.Server, *[][]byte) {
+	t.Helper()
+	bodies := [][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+// TestPayloadTemplate_RendersEvent tests that a templated webhook posts the consumer's field names
+func TestPayloadTemplate_RendersEvent(t *testing.T) {
+	server, bodies := newWebhookReceiver(t)
+	webhook, err := notify.NewWebhookNotifier(server.URL)
+	AssertNoError(t, err)
+	payloadTemplate, err := notify.ParsePayloadTemplate(campaignPayloadTemplate)
+	AssertNoError(t, err)
+	webhook.SetPayloadTemplate(payloadTemplate)
+
+	AssertNoError(t, webhook.Notify(context.Background(), notify.EventCampaignSending, notify.Event{
+		Type:           notify.EventCampaignSending,
+		CampaignID:     42,
+		Channel:        "sms",
+		MessagesQueued: 1500,
+		OccurredAt:     time.Date(2025, 3, 1, 8, 30, 0, 0, time.UTC),
+	}))
+
+	AssertEqual(t, len(*bodies), 1)
+	var payload map[string]interface{}
+	AssertNoError(t, json.Unmarshal((*bodies)[0], &payload))
+	AssertEqual(t, payload["eventName"], "campaign.sending")
+	AssertEqual(t, payload["campaignRef"], "cmp-42")
+	AssertEqual(t, payload["queued"], float64(1500))
+	AssertEqual(t, payload["at"], "2025-03-01T08:30:00Z")
+	AssertEqual(t, payload["channel"], "SMS")
+
+	// Digests are not events and keep the default envelope
+	AssertNoError(t, webhook.Notify(context.Background(), "Campaigns needing attention", []string{"campaign 1"}))
+	var envelope notify.Notification
+	AssertNoError(t, json.Unmarshal((*bodies)[1], &envelope))
+	AssertEqual(t, envelope.Subject, "Campaigns needing attention")
+}
+
+// TestPayloadTemplate_DefaultEnvelope tests that without a template events are posted in the envelope
+func TestPayloadTemplate_DefaultEnvelope(t *testing.T) {
+	server, bodies := newWebhookReceiver(t)
+	webhook, err := notify.NewWebhookNotifier(server.URL)
+	AssertNoError(t, err)
+
+	AssertNoError(t, webhook.Notify(context.Background(), notify.EventCampaignCreated, notify.Event{Type: notify.EventCampaignCreated, CampaignID: 1}))
+
+	var envelope notify.Notification
+	AssertNoError(t, json.Unmarshal((*bodies)[0], &envelope))
+	AssertEqual(t, envelope.Subject, notify.EventCampaignCreated)
+}
+
+// TestPayloadTemplate_Invalid tests that mistakes are caught when the template is parsed, not when an event is delivered
+func TestPayloadTemplate_Invalid(t *testing.T) {
+	for text, message := range map[string]string{
+		"":                                "payload template cannot be empty",
+		`{"id": {{.CampaignID}`:           "invalid payload template",
+		`{"campaign": {{.CampaignName}}}`: "can't evaluate field CampaignName",
+		`{"home": {{env "HOME"}}}`:        `function "env" not defined`,
+		`campaign {{.CampaignID}} done`:   "payload template must render valid JSON",
+	} {
+		_, err := notify.ParsePayloadTemplate(text)
+		if err == nil {
+			t.Errorf("Expected %q to be rejected", text)
+			continue
+		}
+		AssertContains(t, err.Error(), message)
+	}
+}
+
+// TestPayloadTemplate_Cached tests that the same template text is compiled once
+func TestPayloadTemplate_Cached(t *testing.T) {
+	first, err := notify.ParsePayloadTemplate(campaignPayloadTemplate)
+	AssertNoError(t, err)
+	second, err := notify.ParsePayloadTemplate(campaignPayloadTemplate)
+	AssertNoError(t, err)
+	if first != second {
+		t.Error("Expected the compiled template to be reused")
+	}
+}
+
+// TestPayloadTemplate_Config tests that an invalid EVENT_WEBHOOK_TEMPLATE stops startup
+func TestPayloadTemplate_Config(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+	t.Setenv("EVENT_WEBHOOK_TEMPLATE", campaignPayloadTemplate)
+	_, err := config.Load()
+	AssertError(t, err, "EVENT_WEBHOOK_TEMPLATE requires EVENT_SINK=webhook")
+
+	t.Setenv("EVENT_SINK", "webhook")
+	t.Setenv("NOTIFY_WEBHOOK_URL", "https://hooks.example.com/ops")
+	cfg, err := config.Load()
+	AssertNoError(t, err)
+	AssertEqual(t, cfg.Events.WebhookTemplate, campaignPayloadTemplate)
+
+	events, err := notify.NewEventsFromConfig(cfg.Events, cfg.Notify)
+	AssertNoError(t, err)
+	AssertNoError(t, events.Close())
+
+	cfg.Events.WebhookTemplate = `{"campaign": {{.CampaignName}}}`
+	_, err = notify.NewEventsFromConfig(cfg.Events, cfg.Notify)
+	AssertContains(t, err.Error(), "EVENT_WEBHOOK_TEMPLATE is invalid")
+}
+
+// TestWebhookHandler_ValidateTemplate tests POST /webhooks/validate-template
+func TestWebhookHandler_ValidateTemplate(t *testing.T) {
+	h := handler.NewWebhookHandler()
+
+	req := httptest.NewRequest("POST", "/webhooks/validate-template", strings.NewReader(`{"payload_template": "{\"ref\": \"cmp-{{.CampaignID}}\"}"}`))
+	resp := httptest.NewRecorder()
+	h.ValidateTemplate(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+	var check handler.PayloadTemplateCheck
+	ParseJSONResponse(t, resp, &check)
+	AssertEqual(t, check.Valid, true)
+	AssertEqual(t, string(check.Sample), `{"ref":"cmp-42"}`)
+
+	req = httptest.NewRequest("POST", "/webhooks/validate-template", strings.NewReader(`{"payload_template": "{\"ref\": {{.Campaign.Name}}}"}`))
+	resp = httptest.NewRecorder()
+	h.ValidateTemplate(resp, req)
+	AssertStatusCode(t, resp, http.StatusOK)
+	check = handler.PayloadTemplateCheck{}
+	ParseJSONResponse(t, resp, &check)
+	AssertEqual(t, check.Valid, false)
+	AssertEqual(t, len(check.Errors), 1)
+	AssertContains(t, check.Errors[0], "can't evaluate field Campaign")
+
+	req = httptest.NewRequest("POST", "/webhooks/validate-template", strings.NewReader(""))
+	resp = httptest.NewRecorder()
+	h.ValidateTemplate(resp, req)
+	AssertStatusCode(t, resp, http.StatusBadRequest)
+}
@@ -0,0 +1,316 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"smsleopard/internal/handler"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/repository"
+	"smsleopard/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// claimPool is the pending messages two simulated workers consume; like the database, a
+// message reads as sent once either worker has sent it
+type claimPool struct {
+	mu     sync.Mutex
+	sentBy map[string]string // Phone to the worker that sent to it
+}
+
+func newClaimPool() *claimPool {
+	return &claimPool{sentBy: make(map[string]string)}
+}
+
+// details returns message id of a sending campaign, to a customer whose phone identifies it
+func (p *claimPool) details(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+	customer := NewTestCustomer()
+	customer.Phone = fmt.Sprintf("+2547000000%02d", id)
+	status := models.MessageStatusPending
+	p.mu.Lock()
+	if _, sent := p.sentBy[customer.Phone]; sent {
+		status = models.MessageStatusSent
+	}
+	p.mu.Unlock()
+	message := NewTestMessageWithStatus(status)
+	message.ID = id
+	return &models.OutboundMessageWithDetails{
+		OutboundMessage: *message,
+		Campaign:        *NewTestCampaignWithStatus(models.CampaignStatusSending),
+		Customer:        *customer,
+	}, nil
+}
+
+// send records workerID sending to phone and reports whether another worker already had
+func (p *claimPool) send(workerID, phone string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if earlier, sent := p.sentBy[phone]; sent {
+		return earlier, true
+	}
+	p.sentBy[phone] = workerID
+	return "", false
+}
+
+// gatedSender sends into the pool as one worker; while gated, each send waits for release
+// after signalling started, so a test can act while the send is in flight
+type gatedSender struct {
+	t        *testing.T
+	pool     *claimPool
+	workerID string
+	phones   []string
+	gated    bool
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (s *gatedSender) Send(channel models.Channel, phone string, content string, opts service.SenderOptions) *service.SendResult {
+	if earlier, twice := s.pool.send(s.workerID, phone); twice {
+		s.t.Errorf("%s sent to %s, already sent by %s", s.workerID, phone, earlier)
+	}
+	s.phones = append(s.phones, phone)
+	if s.gated {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	return &service.SendResult{Success: true}
+}
+
+func (s *gatedSender) Capabilities() []models.Channel {
+	return []models.Channel{models.ChannelSMS, models.ChannelWhatsApp}
+}
+
+// claimPoolWorker is one simulated worker identity with its own connection and sender
+type claimPoolWorker struct {
+	processor *service.MessageProcessor
+	sender    *gatedSender
+	db        *sql.DB
+	mock      sqlmock.Sqlmock
+}
+
+func newClaimPoolWorker(t *testing.T, pool *claimPool, claims repository.WorkerRepository, workerID string) *claimPoolWorker {
+	db, mock := NewMockDB(t)
+	messageRepo := NewMockMessageRepository()
+	messageRepo.GetWithDetailsFunc = pool.details
+	sender := &gatedSender{t: t, pool: pool, workerID: workerID, started: make(chan struct{}), release: make(chan struct{})}
+	processor := service.NewMessageProcessor(db, messageRepo, service.NewTemplateService(), sender, service.NewAttemptBudget(messageRepo, 0), nil)
+	processor.SetClaims(claims, workerID, time.Minute)
+	return &claimPoolWorker{processor: processor, sender: sender, db: db, mock: mock}
+}
+
+// TestMessageClaims_TwoWorkersNeverOverlap tests that two worker identities consuming the
+// same pending pool each send a message only while they hold its claim: a redelivery to the
+// other worker, or the same one, while the send is in flight is skipped, and every message is
+// sent exactly once
+func TestMessageClaims_TwoWorkersNeverOverlap(t *testing.T) {
+	pool := newClaimPool()
+	claims := NewMockWorkerRepository()
+	workers := map[string]*claimPoolWorker{
+		"worker-a": newClaimPoolWorker(t, pool, claims, "worker-a"),
+		"worker-b": newClaimPoolWorker(t, pool, claims, "worker-b"),
+	}
+	for _, w := range workers {
+		defer w.db.Close()
+		w.sender.gated = true
+		// Each worker records the sends of the two messages it owns, and nothing else
+		for i := 0; i < 2; i++ {
+			w.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+	}
+
+	for id := 1; id <= 4; id++ {
+		owner, other := workers["worker-a"], workers["worker-b"]
+		ownerID := "worker-a"
+		if id%2 == 0 {
+			owner, other = other, owner
+			ownerID = "worker-b"
+		}
+		job := &queue.MessageJob{MessageID: id, CampaignID: 1, CustomerID: 1}
+
+		done := make(chan error, 1)
+		go func() { done <- owner.processor.Handle(job) }()
+		<-owner.sender.started
+
+		// The same job redelivered to the other worker while the owner is still sending, and
+		// to the owner itself (its ack deadline passed)
+		AssertNoError(t, other.processor.Handle(job))
+		AssertNoError(t, owner.processor.Handle(job))
+		AssertEqual(t, claims.ClaimedBy(id), ownerID)
+
+		owner.sender.release <- struct{}{}
+		AssertNoError(t, <-done)
+	}
+
+	AssertEqual(t, fmt.Sprint(workers["worker-a"].sender.phones), "[+254700000001 +254700000003]")
+	AssertEqual(t, fmt.Sprint(workers["worker-b"].sender.phones), "[+254700000002 +254700000004]")
+	for id := 1; id <= 4; id++ {
+		AssertEqual(t, claims.Claims[id].ExpiresAt == nil, true)
+	}
+	AssertEqual(t, claims.Calls["Claim"], 12)
+	AssertEqual(t, claims.Calls["Release"], 4)
+	for _, w := range workers {
+		AssertNoError(t, w.mock.ExpectationsWereMet())
+	}
+}
+
+// TestMessageClaims_ConcurrentPool tests that two workers draining one pool concurrently, with
+// every job delivered to both, send each message once between them
+func TestMessageClaims_ConcurrentPool(t *testing.T) {
+	const size = 20
+	pool := newClaimPool()
+	claims := NewMockWorkerRepository()
+	workers := []*claimPoolWorker{
+		newClaimPoolWorker(t, pool, claims, "worker-a"),
+		newClaimPoolWorker(t, pool, claims, "worker-b"),
+	}
+	for _, w := range workers {
+		defer w.db.Close()
+		for i := 0; i < size; i++ {
+			w.mock.ExpectExec("UPDATE outbound_messages SET status = 'sent'").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *claimPoolWorker) {
+			defer wg.Done()
+			for id := 1; id <= size; id++ {
+				AssertNoError(t, w.processor.Handle(&queue.MessageJob{MessageID: id, CampaignID: 1, CustomerID: 1}))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	AssertEqual(t, len(pool.sentBy), size)
+	AssertEqual(t, len(workers[0].sender.phones)+len(workers[1].sender.phones), size)
+	for id := 1; id <= size; id++ {
+		AssertEqual(t, claims.Claims[id].ExpiresAt == nil, true)
+	}
+}
+
+// TestWorkerRepository_Claim tests that a claim only succeeds over a released or expired
+// claim, and that releasing keeps the claiming worker on record
+func TestWorkerRepository_Claim(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewWorkerRepository(db)
+
+	claimSQL := `UPDATE outbound_messages SET claimed_by = \$2, claimed_at = CURRENT_TIMESTAMP, ` +
+		`claim_expires_at = CURRENT_TIMESTAMP \+ make_interval\(secs => \$3\) WHERE id = \$1 ` +
+		`AND \(claim_expires_at IS NULL OR claim_expires_at < CURRENT_TIMESTAMP\)`
+	mock.ExpectExec(claimSQL).WithArgs(7, "worker-a", float64(300)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(claimSQL).WithArgs(7, "worker-b", float64(300)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE outbound_messages SET claim_expires_at = NULL WHERE id = \$1 AND claimed_by = \$2`).
+		WithArgs(7, "worker-a").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE outbound_messages SET claim_expires_at = NULL WHERE claimed_by = \$1 AND claim_expires_at IS NOT NULL`).
+		WithArgs("worker-b").WillReturnResult(sqlmock.NewResult(0, 3))
+
+	claimed, err := repo.Claim(context.Background(), 7, "worker-a", 5*time.Minute)
+	AssertNoError(t, err)
+	AssertEqual(t, claimed, true)
+	claimed, err = repo.Claim(context.Background(), 7, "worker-b", 5*time.Minute)
+	AssertNoError(t, err)
+	AssertEqual(t, claimed, false)
+	AssertNoError(t, repo.Release(context.Background(), 7, "worker-a"))
+	released, err := repo.ReleaseAll(context.Background(), "worker-b")
+	AssertNoError(t, err)
+	AssertEqual(t, released, int64(3))
+
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestWorkerRepository_GetActivity tests the heartbeat, throughput and recent claims of a
+// worker, and that a worker never seen is not found
+func TestWorkerRepository_GetActivity(t *testing.T) {
+	db, mock := NewMockDB(t)
+	defer db.Close()
+	repo := repository.NewWorkerRepository(db)
+	seen := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT started_at, last_seen_at FROM worker_heartbeats WHERE worker_id = \$1`).
+		WithArgs("worker-a").
+		WillReturnRows(sqlmock.NewRows([]string{"started_at", "last_seen_at"}).AddRow(seen.Add(-time.Hour), seen))
+	mock.ExpectQuery(`FROM outbound_messages WHERE claimed_by = \$1 AND claimed_at >= CURRENT_TIMESTAMP - INTERVAL '1 day'`).
+		WithArgs("worker-a").
+		WillReturnRows(sqlmock.NewRows([]string{"h", "hs", "hf", "d", "ds", "df"}).AddRow(2, 1, 1, 40, 35, 3))
+	mock.ExpectQuery(`FROM outbound_messages WHERE claimed_by = \$1 ORDER BY claimed_at DESC, id DESC LIMIT \$2`).
+		WithArgs("worker-a", 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "status", "claimed_at", "in_flight"}).
+			AddRow(12, 3, "pending", seen, true).
+			AddRow(11, 3, "sent", seen.Add(-time.Minute), false))
+
+	activity, err := repo.GetActivity(context.Background(), "worker-a", 50)
+	AssertNoError(t, err)
+	AssertEqual(t, *activity.LastSeenAt, seen)
+	AssertEqual(t, activity.LastHour, models.WorkerThroughput{Claimed: 2, Sent: 1, Failed: 1})
+	AssertEqual(t, activity.LastDay, models.WorkerThroughput{Claimed: 40, Sent: 35, Failed: 3})
+	AssertEqual(t, len(activity.RecentClaims), 2)
+	AssertEqual(t, activity.RecentClaims[0].InFlight, true)
+	AssertEqual(t, activity.RecentClaims[1].Status, "sent")
+
+	mock.ExpectQuery(`FROM worker_heartbeats`).WithArgs("worker-z").
+		WillReturnRows(sqlmock.NewRows([]string{"started_at", "last_seen_at"}))
+	mock.ExpectQuery(`claimed_at >= CURRENT_TIMESTAMP - INTERVAL '1 day'`).WithArgs("worker-z").
+		WillReturnRows(sqlmock.NewRows([]string{"h", "hs", "hf", "d", "ds", "df"}).AddRow(0, 0, 0, 0, 0, 0))
+	mock.ExpectQuery(`ORDER BY claimed_at DESC`).WithArgs("worker-z", 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "status", "claimed_at", "in_flight"}))
+
+	_, err = repo.GetActivity(context.Background(), "worker-z", 50)
+	AssertEqual(t, err, repository.ErrWorkerNotFound)
+	AssertNoError(t, mock.ExpectationsWereMet())
+}
+
+// TestWorkerActivityEndpoint tests GET /admin/workers/{id}/activity for a known worker, an
+// unknown one and an invalid limit
+func TestWorkerActivityEndpoint(t *testing.T) {
+	repo := NewMockWorkerRepository()
+	repo.GetActivityFunc = func(ctx context.Context, workerID string, limit int) (*models.WorkerActivity, error) {
+		if workerID != "api-7f9c:1" {
+			return nil, repository.ErrWorkerNotFound
+		}
+		return &models.WorkerActivity{
+			WorkerID:     workerID,
+			LastHour:     models.WorkerThroughput{Claimed: limit},
+			RecentClaims: []*models.WorkerClaim{{MessageID: 12, CampaignID: 3, Status: "sent"}},
+		}, nil
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/workers/{id}/activity", handler.NewWorkerHandler(service.NewWorkerActivityService(repo)).Activity).Methods("GET")
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr
+	}
+
+	rr := get("/admin/workers/api-7f9c:1/activity?limit=5")
+	AssertStatusCode(t, rr, http.StatusOK)
+	var activity models.WorkerActivity
+	ParseJSONResponse(t, rr, &activity)
+	AssertEqual(t, activity.WorkerID, "api-7f9c:1")
+	AssertEqual(t, activity.LastHour.Claimed, 5)
+	AssertEqual(t, activity.RecentClaims[0].MessageID, 12)
+
+	rr = get("/admin/workers/api-7f9c:1/activity")
+	ParseJSONResponse(t, rr, &activity)
+	AssertEqual(t, activity.LastHour.Claimed, service.DefaultWorkerActivityClaims)
+
+	rr = get("/admin/workers/worker-z/activity")
+	AssertStatusCode(t, rr, http.StatusNotFound)
+	AssertContains(t, rr.Body.String(), `worker \"worker-z\" not found`)
+
+	AssertStatusCode(t, get("/admin/workers/api-7f9c:1/activity?limit=0"), http.StatusBadRequest)
+	AssertStatusCode(t, get("/admin/workers/api-7f9c:1/activity?limit=501"), http.StatusBadRequest)
+}
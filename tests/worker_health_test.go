@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"smsleopard/internal/metrics"
+	"smsleopard/internal/models"
+	"smsleopard/internal/queue"
+	"smsleopard/internal/service"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// scriptedCheck fails the first failures calls and passes after
+func scriptedCheck(failures int32) (service.DependencyCheck, *atomic.Int32) {
+	var calls atomic.Int32
+	return func(ctx context.Context) error {
+		if calls.Add(1) <= failures {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, &calls
+}
+
+// TestWorkerHealth_DrainsAfterInfraStreak tests that jobs failing to reach the database drain the worker
+func TestWorkerHealth_DrainsAfterInfraStreak(t *testing.T) {
+	f := newProcessingErrorFixture(t, service.NewSenderService(1.0))
+	var fetches atomic.Int32
+	f.messageRepo.GetWithDetailsFunc = func(ctx context.Context, id int) (*models.OutboundMessageWithDetails, error) {
+		fetches.Add(1)
+		return nil, errors.New("dial tcp 10.0.0.5:5432: connection refused")
+	}
+	health := service.NewWorkerHealth(3, time.Hour)
+	f.processor.SetHealth(health)
+	drainsBefore := testutil.ToFloat64(metrics.WorkerDrains)
+
+	for i := 1; i <= 2; i++ {
+		AssertNotNil(t, f.processor.Handle(&queue.MessageJob{MessageID: i}))
+	}
+	AssertEqual(t, health.Draining(), false)
+
+	AssertNotNil(t, f.processor.Handle(&queue.MessageJob{MessageID: 3}))
+	AssertEqual(t, health.Draining(), true)
+	AssertEqual(t, testutil.ToFloat64(metrics.WorkerDrains), drainsBefore+1)
+	AssertEqual(t, testutil.ToFloat64(metrics.WorkerDraining), 1.0)
+
+	// Further failures while drained do not drain again
+	AssertNotNil(t, f.processor.Handle(&queue.MessageJob{MessageID: 4}))
+	AssertEqual(t, testutil.ToFloat64(metrics.WorkerDrains), drainsBefore+1)
+	AssertEqual(t, fetches.Load(), int32(4))
+}
+
+// TestWorkerHealth_OtherOutcomesEndStreak tests that only consecutive infrastructure failures count
+func TestWorkerHealth_OtherOutcomesEndStreak(t *testing.T) {
+	health := service.NewWorkerHealth(3, time.Hour)
+	infra := errors.New("connection refused")
+
+	health.Observe(infra)
+	health.Observe(infra)
+	health.Observe(&service.SendError{Reason: "provider unavailable"})
+	health.Observe(infra)
+	health.Observe(infra)
+	AssertEqual(t, health.Draining(), false)
+
+	health.Observe(nil)
+	health.Observe(&service.RenderError{Err: errors.New("bad template")})
+	health.Observe(infra)
+	AssertEqual(t, health.Draining(), false)
+}
+
+// TestWorkerHealth_DisabledNeverDrains tests that a zero threshold turns draining off
+func TestWorkerHealth_DisabledNeverDrains(t *testing.T) {
+	health := service.NewWorkerHealth(0, time.Hour)
+	for i := 0; i < 100; i++ {
+		health.Observe(errors.New("connection refused"))
+	}
+	AssertEqual(t, health.Draining(), false)
+
+	var nilHealth *service.WorkerHealth
+	nilHealth.Observe(errors.New("connection refused"))
+	AssertEqual(t, nilHealth.Draining(), false)
+}
+
+// TestWorkerHealth_ResumesWhenHealthy tests that a drained worker pauses, keeps checking and resumes
+func TestWorkerHealth_ResumesWhenHealthy(t *testing.T) {
+	health := service.NewWorkerHealth(2, 10*time.Millisecond)
+	check, calls := scriptedCheck(2)
+	health.AddCheck("database", check)
+	changes := make(chan bool, 4)
+	health.OnChange(func(draining bool) { changes <- draining })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go health.Run(ctx)
+
+	health.Observe(errors.New("connection refused"))
+	health.Observe(errors.New("connection refused"))
+
+	for _, expected := range []bool{true, false} {
+		select {
+		case draining := <-changes:
+			AssertEqual(t, draining, expected)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a change to draining=%t", expected)
+		}
+	}
+	AssertEqual(t, health.Draining(), false)
+	AssertEqual(t, testutil.ToFloat64(metrics.WorkerDraining), 0.0)
+	if calls.Load() < 3 {
+		t.Errorf("Expected the check to run until it passed, ran %d times", calls.Load())
+	}
+
+	// The streak starts over after resuming
+	health.Observe(errors.New("connection refused"))
+	AssertEqual(t, health.Draining(), false)
+}
+
+// TestWorkerHealth_CheckNamesFailures tests that every failing dependency is reported
+func TestWorkerHealth_CheckNamesFailures(t *testing.T) {
+	health := service.NewWorkerHealth(1, time.Hour)
+	health.AddCheck("database", func(ctx context.Context) error { return errors.New("connection refused") })
+	health.AddCheck("rabbitmq", func(ctx context.Context) error { return errors.New("connection closed") })
+
+	err := health.Check(context.Background())
+	AssertError(t, err, "database: connection refused\nrabbitmq: connection closed")
+}
+
+// TestWaitUntilReady tests bounded waiting for a dependency at startup
+func TestWaitUntilReady(t *testing.T) {
+	check, calls := scriptedCheck(2)
+	err := service.WaitUntilReady(context.Background(), "database", time.Second, time.Millisecond, check)
+	AssertNoError(t, err)
+	AssertEqual(t, calls.Load(), int32(3))
+
+	check, _ = scriptedCheck(1000)
+	err = service.WaitUntilReady(context.Background(), "database", 20*time.Millisecond, 5*time.Millisecond, check)
+	if err == nil || !strings.Contains(err.Error(), "database not ready after") {
+		t.Errorf("Expected a not ready error, got %v", err)
+	}
+
+	// Without a timeout the check runs once
+	check, calls = scriptedCheck(1000)
+	err = service.WaitUntilReady(context.Background(), "RabbitMQ", 0, time.Second, check)
+	AssertError(t, err, "RabbitMQ not ready after 1 attempt(s): connection refused")
+	AssertEqual(t, calls.Load(), int32(1))
+}
@@ -3,6 +3,7 @@ package tests
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 
 	"smsleopard/internal/models"
@@ -11,14 +12,15 @@ import (
 )
 
 // setupWorkerTest creates test database and services for worker tests
-func setupWorkerTest(t *testing.T) (*sql.DB, repository.MessageRepository, repository.CampaignRepository, repository.CustomerRepository, *service.TemplateService, *service.SenderService, func()) {
+// Tests script their own sender so every send outcome is fixed
+func setupWorkerTest(t *testing.T) (*sql.DB, repository.MessageRepository, repository.CampaignRepository, repository.CustomerRepository, *service.TemplateService, func()) {
 	t.Helper()
 
 	// Setup test database
 	db := SetupTestDB(t)
 	if db == nil {
 		t.Skip("Test database not available")
-		return nil, nil, nil, nil, nil, nil, nil
+		return nil, nil, nil, nil, nil, nil
 	}
 
 	// Clean up test data
@@ -31,19 +33,18 @@ func setupWorkerTest(t *testing.T) (*sql.DB, repository.MessageRepository, repos
 
 	// Create services
 	templateSvc := service.NewTemplateService()
-	senderSvc := service.NewSenderService(0.95) // Default 95% success
 
 	cleanup := func() {
 		CleanupTestDB(t, db)
 		db.Close()
 	}
 
-	return db, messageRepo, campaignRepo, customerRepo, templateSvc, senderSvc, cleanup
+	return db, messageRepo, campaignRepo, customerRepo, templateSvc, cleanup
 }
 
 // TestWorker_SuccessfulProcessing tests complete successful message processing flow
 func TestWorker_SuccessfulProcessing(t *testing.T) {
-	_, msgRepo, campRepo, custRepo, templateSvc, senderSvc, cleanup := setupWorkerTest(t)
+	_, msgRepo, campRepo, custRepo, templateSvc, cleanup := setupWorkerTest(t)
 	defer cleanup()
 
 	ctx := context.Background()
@@ -84,8 +85,8 @@ func TestWorker_SuccessfulProcessing(t *testing.T) {
 	AssertEqual(t, renderedContent, "Hi John, welcome!")
 
 	// 3. Send message
-	senderSvc.SetSuccessRate(1.0) // 100% success for this test
-	result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent)
+	senderSvc := service.NewSenderServiceScripted(service.SendResult{Success: true})
+	result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent, service.SenderOptions{})
 	AssertEqual(t, result.Success, true)
 	AssertNil(t, result.Error)
 
@@ -103,7 +104,7 @@ func TestWorker_SuccessfulProcessing(t *testing.T) {
 
 // TestWorker_FailureScenario tests worker handling of sender service failures
 func TestWorker_FailureScenario(t *testing.T) {
-	_, msgRepo, campRepo, custRepo, templateSvc, senderSvc, cleanup := setupWorkerTest(t)
+	_, msgRepo, campRepo, custRepo, templateSvc, cleanup := setupWorkerTest(t)
 	defer cleanup()
 
 	ctx := context.Background()
@@ -135,12 +136,12 @@ func TestWorker_FailureScenario(t *testing.T) {
 	fetchedMsg, _ := msgRepo.GetWithDetails(ctx, message.ID)
 	renderedContent, _ := templateSvc.Render(campaign.BaseTemplate, &fetchedMsg.Customer)
 
-	senderSvc.SetSuccessRate(0.0) // 0% success - always fail
-	result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent)
+	senderSvc := service.NewSenderServiceScripted(service.SendResult{Error: errors.New("invalid phone number")})
+	result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent, service.SenderOptions{})
 
 	// Verify: Error occurred
 	AssertEqual(t, result.Success, false)
-	AssertNotNil(t, result.Error)
+	AssertError(t, result.Error, "failed to send SMS to +254700000002: invalid phone number")
 
 	// Update status to failed
 	errorMsg := result.Error.Error()
@@ -156,7 +157,7 @@ func TestWorker_FailureScenario(t *testing.T) {
 
 // TestWorker_RetryLogic tests message retry mechanism
 func TestWorker_RetryLogic(t *testing.T) {
-	db, msgRepo, campRepo, custRepo, templateSvc, senderSvc, cleanup := setupWorkerTest(t)
+	db, msgRepo, campRepo, custRepo, templateSvc, cleanup := setupWorkerTest(t)
 	defer cleanup()
 
 	ctx := context.Background()
@@ -188,11 +189,15 @@ func TestWorker_RetryLogic(t *testing.T) {
 	renderedContent, _ := templateSvc.Render(campaign.BaseTemplate, &fetchedMsg.Customer)
 
 	// Simulate 3 failures (max retries)
-	senderSvc.SetSuccessRate(0.0)
+	senderSvc := service.NewSenderServiceScripted(
+		service.SendResult{Error: errors.New("rate limit exceeded")},
+		service.SendResult{Error: errors.New("network timeout")},
+		service.SendResult{Error: errors.New("service temporarily unavailable")},
+	)
 
 	for retry := 0; retry < 3; retry++ {
 		// Try to send
-		result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent)
+		result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent, service.SenderOptions{})
 		AssertEqual(t, result.Success, false)
 		AssertNotNil(t, result.Error)
 
@@ -224,7 +229,7 @@ func TestWorker_RetryLogic(t *testing.T) {
 
 // TestWorker_StatusTransitions tests various status transition scenarios
 func TestWorker_StatusTransitions(t *testing.T) {
-	_, msgRepo, campRepo, custRepo, templateSvc, senderSvc, cleanup := setupWorkerTest(t)
+	_, msgRepo, campRepo, custRepo, templateSvc, cleanup := setupWorkerTest(t)
 	defer cleanup()
 
 	ctx := context.Background()
@@ -256,8 +261,8 @@ func TestWorker_StatusTransitions(t *testing.T) {
 		// Process and send successfully
 		fetchedMsg, _ := msgRepo.GetWithDetails(ctx, message.ID)
 		renderedContent, _ := templateSvc.Render(campaign.BaseTemplate, &fetchedMsg.Customer)
-		senderSvc.SetSuccessRate(1.0)
-		result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent)
+		senderSvc := service.NewSenderServiceScripted(service.SendResult{Success: true})
+		result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent, service.SenderOptions{})
 		AssertEqual(t, result.Success, true)
 
 		// Update to sent
@@ -295,10 +300,14 @@ func TestWorker_StatusTransitions(t *testing.T) {
 		fetchedMsg, _ := msgRepo.GetWithDetails(ctx, message.ID)
 		renderedContent, _ := templateSvc.Render(campaign.BaseTemplate, &fetchedMsg.Customer)
 
-		// First attempt fails
-		senderSvc.SetSuccessRate(0.0)
-		result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent)
+		// First attempt fails with a rate limit, the retry succeeds
+		senderSvc := service.NewSenderServiceScripted(
+			service.SendResult{Error: errors.New("rate limit exceeded")},
+			service.SendResult{Success: true},
+		)
+		result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent, service.SenderOptions{})
 		AssertEqual(t, result.Success, false)
+		AssertContains(t, result.Error.Error(), "rate limit exceeded")
 
 		errorMsg := result.Error.Error()
 		err := msgRepo.UpdateStatus(ctx, message.ID, models.MessageStatusFailed, &errorMsg)
@@ -315,8 +324,7 @@ func TestWorker_StatusTransitions(t *testing.T) {
 		AssertEqual(t, msg.Status, models.MessageStatusPending)
 
 		// Second attempt succeeds
-		senderSvc.SetSuccessRate(1.0)
-		result = senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent)
+		result = senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent, service.SenderOptions{})
 		AssertEqual(t, result.Success, true)
 
 		err = msgRepo.UpdateStatus(ctx, message.ID, models.MessageStatusSent, nil)
@@ -329,7 +337,7 @@ func TestWorker_StatusTransitions(t *testing.T) {
 
 // TestWorker_TemplateRenderingError tests worker handling of template errors
 func TestWorker_TemplateRenderingError(t *testing.T) {
-	_, msgRepo, campRepo, custRepo, templateSvc, _, cleanup := setupWorkerTest(t)
+	_, msgRepo, campRepo, custRepo, templateSvc, cleanup := setupWorkerTest(t)
 	defer cleanup()
 
 	ctx := context.Background()
@@ -366,7 +374,7 @@ func TestWorker_TemplateRenderingError(t *testing.T) {
 
 // TestWorker_MessageWithDetails tests fetching message with campaign and customer details
 func TestWorker_MessageWithDetails(t *testing.T) {
-	_, msgRepo, campRepo, custRepo, _, _, cleanup := setupWorkerTest(t)
+	_, msgRepo, campRepo, custRepo, _, cleanup := setupWorkerTest(t)
 	defer cleanup()
 
 	ctx := context.Background()
@@ -418,7 +426,7 @@ func TestWorker_MessageWithDetails(t *testing.T) {
 
 // TestWorker_PendingMessagesQuery tests retrieval of pending messages
 func TestWorker_PendingMessagesQuery(t *testing.T) {
-	_, msgRepo, campRepo, custRepo, _, _, cleanup := setupWorkerTest(t)
+	_, msgRepo, campRepo, custRepo, _, cleanup := setupWorkerTest(t)
 	defer cleanup()
 
 	ctx := context.Background()
@@ -449,7 +457,7 @@ func TestWorker_PendingMessagesQuery(t *testing.T) {
 	AssertNoError(t, msgRepo.CreateBatch(ctx, messages))
 
 	// Query pending messages (should exclude messages with retry_count >= 3)
-	pendingMsgs, err := msgRepo.GetPendingMessages(ctx, 10)
+	pendingMsgs, err := msgRepo.GetPendingMessages(ctx, 10, models.DispatchOrder{})
 	AssertNoError(t, err)
 
 	// Should get 3 pending messages (retry_count < 3)
@@ -466,11 +474,11 @@ func TestWorker_PendingMessagesQuery(t *testing.T) {
 
 // TestWorker_MultipleChannels tests worker processing for different channels
 func TestWorker_MultipleChannels(t *testing.T) {
-	_, msgRepo, campRepo, custRepo, templateSvc, senderSvc, cleanup := setupWorkerTest(t)
+	_, msgRepo, campRepo, custRepo, templateSvc, cleanup := setupWorkerTest(t)
 	defer cleanup()
 
 	ctx := context.Background()
-	senderSvc.SetSuccessRate(1.0)
+	senderSvc := service.NewSenderServiceScripted(service.SendResult{Success: true}, service.SendResult{Success: true})
 
 	channels := []models.Channel{models.ChannelSMS, models.ChannelWhatsApp}
 
@@ -500,7 +508,7 @@ func TestWorker_MultipleChannels(t *testing.T) {
 		// Process message
 		fetchedMsg, _ := msgRepo.GetWithDetails(ctx, message.ID)
 		renderedContent, _ := templateSvc.Render(campaign.BaseTemplate, &fetchedMsg.Customer)
-		result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent)
+		result := senderSvc.Send(fetchedMsg.Campaign.Channel, fetchedMsg.Customer.Phone, renderedContent, service.SenderOptions{})
 
 		// Verify success
 		AssertEqual(t, result.Success, true)